@@ -0,0 +1,82 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""CLI verification for `soup-go harness assert`.
+
+It must pass as soon as a polled command's result becomes consistent, and
+fail with the last attempt's mismatch once the timeout has passed."""
+
+import json
+from pathlib import Path
+import subprocess
+import threading
+
+import pytest
+
+
+@pytest.fixture
+def soup_go_path() -> Path:
+    """Get path to soup-go executable."""
+    candidates = [Path("bin/soup-go"), Path("harnesses/bin/soup-go")]
+    for path in candidates:
+        if path.exists():
+            return path.resolve()
+    pytest.skip("soup-go not found")
+
+
+def _assert(soup_go: Path, *args: str) -> tuple[int, dict]:
+    result = subprocess.run(
+        [str(soup_go), "harness", "assert", "--output-format", "json", *args],
+        capture_output=True,
+        text=True,
+        timeout=30,
+    )
+    return result.returncode, json.loads(result.stdout)
+
+
+def test_assert_passes_once_consistent(soup_go_path: Path, tmp_path: Path) -> None:
+    value = tmp_path / "value"
+    writer = threading.Timer(0.5, value.write_text, args=("bar\n",))
+    writer.start()
+    try:
+        code, report = _assert(
+            soup_go_path,
+            *("--command", f"cat {value}", "--equals", "bar"),
+            *("--timeout", "10s", "--interval", "100ms"),
+        )
+    finally:
+        writer.join()
+
+    assert code == 0, report
+    assert report["passed"] is True
+    assert report["attempts"] > 1
+    assert report["elapsed_ms"] < 10_000
+
+
+def test_assert_fails_after_timeout(soup_go_path: Path, tmp_path: Path) -> None:
+    value = tmp_path / "value"
+    value.write_text("bar\n")
+
+    code, report = _assert(
+        soup_go_path,
+        *("--command", f"cat {value}", "--equals", "baz"),
+        *("--timeout", "500ms", "--interval", "100ms"),
+    )
+
+    assert code != 0
+    assert report["passed"] is False
+    assert report["elapsed_ms"] >= 400
+    assert report["last_result"]["mismatch"] == 'stdout "bar", want "baz"'
+
+
+def test_assert_exit_code(soup_go_path: Path, tmp_path: Path) -> None:
+    code, report = _assert(soup_go_path, "--command", f"cat {tmp_path / 'missing'}", "--exit-code", "1")
+
+    assert code == 0, report
+    assert report["attempts"] == 1
+    assert report["last_result"]["exit_code"] == 1
+
+
+# 🥣🔬🔚
@@ -0,0 +1,68 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""CLI verification for `soup-go harness cases list`.
+
+Every listed selftest case must run alone through the command it is listed
+with, so a scheduler can shard the suite by case."""
+
+import json
+from pathlib import Path
+import subprocess
+
+import pytest
+
+
+@pytest.fixture
+def soup_go_path() -> Path:
+    """Get path to soup-go executable."""
+    candidates = [Path("bin/soup-go"), Path("harnesses/bin/soup-go")]
+    for path in candidates:
+        if path.exists():
+            return path.resolve()
+    pytest.skip("soup-go not found")
+
+
+def _run(soup_go: Path, *args: str) -> subprocess.CompletedProcess:
+    return subprocess.run([str(soup_go), *args], capture_output=True, text=True, timeout=60)
+
+
+def test_cases_list_describes_every_case(soup_go_path: Path) -> None:
+    result = _run(soup_go_path, "harness", "cases", "list", "--json")
+    assert result.returncode == 0, result.stderr
+    listing = json.loads(result.stdout)
+
+    ids = [case["id"] for case in listing["cases"]]
+    assert len(ids) == len(set(ids))
+    assert {"selftest/cty/roundtrip/string", "selftest/rpc/loopback/tls", "scenario/deadline"} <= set(ids)
+    for case in listing["cases"]:
+        assert case["description"], case["id"]
+        assert case["expected_duration_ms"] > 0, case["id"]
+        assert set(case["prerequisites"]) <= set(listing["prerequisites"]), case["id"]
+
+
+def test_cases_list_filters_by_subsystem(soup_go_path: Path) -> None:
+    result = _run(soup_go_path, "harness", "cases", "list", "--subsystem", "rpc", "--json")
+    assert result.returncode == 0, result.stderr
+    cases = json.loads(result.stdout)["cases"]
+    assert cases and all(case["subsystem"] == "rpc" for case in cases)
+
+    result = _run(soup_go_path, "harness", "cases", "list", "--subsystem", "tf")
+    assert result.returncode != 0
+    assert 'unknown subsystem "tf"' in result.stderr
+
+
+def test_listed_selftest_command_runs_one_case(soup_go_path: Path) -> None:
+    cases = json.loads(_run(soup_go_path, "harness", "cases", "list", "--subsystem", "wire", "--json").stdout)
+    case = cases["cases"][0]
+    assert case["command"][0] == "soup-go"
+
+    result = _run(soup_go_path, *case["command"][1:])
+    assert result.returncode == 0, result.stderr
+    report = json.loads(result.stdout)
+    assert [f"selftest/{r['subsystem']}/{r['name']}" for r in report["results"]] == [case["id"]]
+
+
+# 🥣🔬🔚
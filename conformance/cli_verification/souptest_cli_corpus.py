@@ -0,0 +1,162 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""CLI verification for `soup-go harness corpus sync`.
+
+A local HTTP server publishes a corpus by digest. It cuts the first
+download of every object short, so each file must be resumed with a Range
+request, and a second sync must download nothing."""
+
+from collections.abc import Iterator
+import hashlib
+from http.server import BaseHTTPRequestHandler, ThreadingHTTPServer
+import json
+from pathlib import Path
+import subprocess
+import threading
+
+import pytest
+
+FILES = {
+    "cty/large.json": json.dumps({"values": list(range(20000))}).encode(),
+    "hcl/simple.hcl": b'name = "soup"\n',
+    "wire/empty.bin": b"",
+}
+
+
+@pytest.fixture
+def soup_go_path() -> Path:
+    """Get path to soup-go executable."""
+    candidates = [Path("bin/soup-go"), Path("harnesses/bin/soup-go")]
+    for path in candidates:
+        if path.exists():
+            return path.resolve()
+    pytest.skip("soup-go not found")
+
+
+class _CorpusServer(ThreadingHTTPServer):
+    def __init__(self, index: bytes, objects: dict[str, bytes]) -> None:
+        super().__init__(("127.0.0.1", 0), _CorpusHandler)
+        self.index = index
+        self.objects = objects
+        self.cut: set[str] = set()
+        self.ranges: list[str] = []
+
+
+class _CorpusHandler(BaseHTTPRequestHandler):
+    server: _CorpusServer
+
+    def log_message(self, format: str, *args: object) -> None:
+        pass
+
+    def do_GET(self) -> None:
+        if self.path == "/v1/corpus.json":
+            self._send(200, self.server.index)
+            return
+        digest = self.path.removeprefix("/v1/objects/")
+        body = self.server.objects.get(digest)
+        if body is None:
+            self._send(404, b"")
+            return
+        start = 0
+        if rng := self.headers.get("Range"):
+            self.server.ranges.append(digest)
+            start = int(rng.removeprefix("bytes=").rstrip("-"))
+        if start == 0 and len(body) > 1 and digest not in self.server.cut:
+            # Promise the whole object, then hang up halfway through it
+            self.server.cut.add(digest)
+            self.send_response(200)
+            self.send_header("Content-Length", str(len(body)))
+            self.end_headers()
+            self.wfile.write(body[: len(body) // 2])
+            self.close_connection = True
+            return
+        if start:
+            self.send_response(206)
+            self.send_header("Content-Range", f"bytes {start}-{len(body) - 1}/{len(body)}")
+        else:
+            self.send_response(200)
+        self.send_header("Content-Length", str(len(body) - start))
+        self.end_headers()
+        self.wfile.write(body[start:])
+
+    def _send(self, status: int, body: bytes) -> None:
+        self.send_response(status)
+        self.send_header("Content-Length", str(len(body)))
+        self.end_headers()
+        self.wfile.write(body)
+
+
+@pytest.fixture
+def corpus_server() -> Iterator[_CorpusServer]:
+    objects = {hashlib.sha256(data).hexdigest(): data for data in FILES.values()}
+    files = [
+        {"path": path, "size": len(data), "sha256": hashlib.sha256(data).hexdigest()}
+        for path, data in FILES.items()
+    ]
+    index = json.dumps({"version": 1, "name": "test", "corpus_version": "2025.1", "files": files}).encode()
+    server = _CorpusServer(index, objects)
+    thread = threading.Thread(target=server.serve_forever, daemon=True)
+    thread.start()
+    try:
+        yield server
+    finally:
+        server.shutdown()
+        server.server_close()
+
+
+def _sync(soup_go: Path, server: _CorpusServer, out: Path, *args: str) -> subprocess.CompletedProcess:
+    url = f"http://127.0.0.1:{server.server_address[1]}/v1/corpus.json"
+    return subprocess.run(
+        [str(soup_go), "harness", "corpus", "sync", "--url", url, "--dir", str(out), "--output-format", "json", *args],
+        capture_output=True,
+        text=True,
+        timeout=60,
+    )
+
+
+def test_sync_resumes_and_verifies(soup_go_path: Path, corpus_server: _CorpusServer, tmp_path: Path) -> None:
+    out = tmp_path / "fixtures"
+    result = _sync(soup_go_path, corpus_server, out)
+    assert result.returncode == 0, result.stderr
+    report = json.loads(result.stdout)
+
+    assert report["corpus_version"] == "2025.1"
+    assert sorted(report["fetched"]) == sorted(FILES)
+    assert sorted(report["resumed"]) == ["cty/large.json", "hcl/simple.hcl"]
+    assert sorted(corpus_server.ranges) == sorted(corpus_server.cut)
+    for path, data in FILES.items():
+        assert (out / path).read_bytes() == data
+    assert not (out / ".corpus-partial").exists()
+    assert json.loads((out / "corpus.json").read_text())["corpus_version"] == "2025.1"
+
+    again = json.loads(_sync(soup_go_path, corpus_server, out).stdout)
+    assert again["up_to_date"] == len(FILES)
+    assert again["fetched"] == []
+    assert again["bytes_downloaded"] == 0
+
+
+def test_sync_refuses_unpinned_index(soup_go_path: Path, corpus_server: _CorpusServer, tmp_path: Path) -> None:
+    result = _sync(soup_go_path, corpus_server, tmp_path / "fixtures", "--sha256", "00" * 32)
+    assert result.returncode != 0
+    assert "corpus index sha256 is " + hashlib.sha256(corpus_server.index).hexdigest() in result.stderr
+    assert not (tmp_path / "fixtures").exists()
+
+
+def test_sync_rejects_corrupt_object(soup_go_path: Path, corpus_server: _CorpusServer, tmp_path: Path) -> None:
+    digest = hashlib.sha256(FILES["hcl/simple.hcl"]).hexdigest()
+    corpus_server.objects[digest] = b'name = "tampered"\n'[: len(FILES["hcl/simple.hcl"])]
+    corpus_server.cut.add(digest)
+
+    result = _sync(soup_go_path, corpus_server, tmp_path / "fixtures", "--retries", "1")
+    assert result.returncode != 0
+    report = json.loads(result.stdout)
+    assert [f["path"] for f in report["failed"]] == ["hcl/simple.hcl"]
+    assert "expected 14 bytes with sha256 " + digest in report["failed"][0]["error"]
+    assert not (tmp_path / "fixtures" / "hcl" / "simple.hcl").exists()
+    assert not (tmp_path / "fixtures" / "corpus.json").exists()
+
+
+# 🥣🔬🔚
@@ -0,0 +1,81 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""CLI verification for `soup-go --digest-alg`.
+
+Every fingerprint soup-go prints names the algorithm it was computed with,
+so runners never compare a SHA-512 fingerprint against a SHA-256 one."""
+
+import json
+from pathlib import Path
+import subprocess
+
+import pytest
+
+from tofusoup.rpc.trust import certificate_fingerprint
+
+# The certificate from tests/harness/test_rpc_trust.py
+CA_PEM = """-----BEGIN CERTIFICATE-----
+MIIBsDCCATagAwIBAgIUV0bTxZN+WUA8D6hjPEEx9YTSSpUwCgYIKoZIzj0EAwIw
+DzENMAsGA1UEAwwEdGVzdDAeFw0yNjEwMTYwMzQyMjBaFw0yNjEwMTgwMzQyMjBa
+MA8xDTALBgNVBAMMBHRlc3QwdjAQBgcqhkjOPQIBBgUrgQQAIgNiAARyUwPoL9nn
+y48c9lGkBSqIwdYxb9k4ynJu6YTXhbrEhBUSP58ZahEETAMQ8JgXRRuvqIwErdef
+Y/X+QkOUVCC/glN7lu5XhhReIWPB1qEolYQupxSZMkm4xbkGWhkr9MujUzBRMB0G
+A1UdDgQWBBREcKXimgHFPapfDvWL5Mp4DtMYdzAfBgNVHSMEGDAWgBREcKXimgHF
+PapfDvWL5Mp4DtMYdzAPBgNVHRMBAf8EBTADAQH/MAoGCCqGSM49BAMCA2gAMGUC
+MQCTVY2V1gcKFm4ymuuoGV/wlKimBN0x8bpnL90wB8tSI58PlrjyKw5v7cT7cUjt
+4iYCMHNt3CbP+6Nc3T+X/XpWt+BU8H93LAya1GBxzdTKGR0dklSHdCxDf+Dx4b9E
+kNSG9g==
+-----END CERTIFICATE-----
+"""
+
+
+@pytest.fixture
+def soup_go_path() -> Path:
+    """Get path to soup-go executable."""
+    candidates = [Path("bin/soup-go"), Path("harnesses/bin/soup-go")]
+    for path in candidates:
+        if path.exists():
+            return path.resolve()
+    pytest.skip("soup-go not found")
+
+
+def _soup_go(soup_go: Path, *args: str) -> subprocess.CompletedProcess:
+    return subprocess.run([str(soup_go), *args], capture_output=True, text=True, timeout=30)
+
+
+def test_config_show_lists_algorithms(soup_go_path: Path) -> None:
+    result = _soup_go(soup_go_path, "config", "show", "--json")
+    assert result.returncode == 0, result.stderr
+    config = json.loads(result.stdout)
+
+    assert config["digest_algorithm"] == "sha256"
+    assert {"sha256", "sha512"} <= set(config["digest_algorithms"])
+
+    unknown = _soup_go(soup_go_path, "config", "show", "--digest-alg", "md5")
+    assert unknown.returncode != 0
+    assert 'unknown digest algorithm "md5"' in unknown.stderr
+
+
+@pytest.mark.parametrize("algorithm", ["sha256", "sha512"])
+def test_trust_bundle_fingerprint_names_its_algorithm(
+    soup_go_path: Path, tmp_path: Path, algorithm: str
+) -> None:
+    cert = tmp_path / "ca.pem"
+    cert.write_text(CA_PEM)
+    result = _soup_go(
+        soup_go_path,
+        *("rpc", "trust", "export", "--cert-file", str(cert), "--dir", str(tmp_path)),
+        *("--digest-alg", algorithm, "--output-format", "json"),
+    )
+    assert result.returncode == 0, result.stderr
+    exported = json.loads(result.stdout)
+
+    assert exported["fingerprint_algorithm"] == algorithm
+    assert exported["fingerprint"] == certificate_fingerprint(CA_PEM, algorithm)
+    assert exported["fingerprint_sha256"] == certificate_fingerprint(CA_PEM)
+
+
+# 🥣🔬🔚
@@ -0,0 +1,75 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""CLI verification for `soup-go doctor`.
+
+A misconfigured environment must fail the named check with a fix, and a
+sound one must pass every check doctor can fail on."""
+
+import json
+import os
+from pathlib import Path
+import socket
+import subprocess
+
+import pytest
+
+
+@pytest.fixture
+def soup_go_path() -> Path:
+    """Get path to soup-go executable."""
+    candidates = [Path("bin/soup-go"), Path("harnesses/bin/soup-go")]
+    for path in candidates:
+        if path.exists():
+            return path.resolve()
+    pytest.skip("soup-go not found")
+
+
+def _doctor(soup_go: Path, env: dict[str, str], *args: str) -> tuple[int, dict]:
+    result = subprocess.run(
+        [str(soup_go), "doctor", "--output-format", "json", *args],
+        env={**os.environ, **env},
+        capture_output=True,
+        text=True,
+        timeout=30,
+    )
+    return result.returncode, json.loads(result.stdout)
+
+
+def test_doctor_passes_sound_environment(soup_go_path: Path, tmp_path: Path) -> None:
+    env = {"PLUGIN_SERVER_PATH": str(soup_go_path), "KV_STORAGE_DIR": str(tmp_path / "kv"), "TLS_MODE": "auto"}
+    code, report = _doctor(soup_go_path, env, "--port", "0")
+
+    assert code == 0, report
+    assert report["failures"] == 0
+    checks = {c["name"]: c for c in report["checks"]}
+    assert checks["env.PLUGIN_SERVER_PATH"]["status"] == "ok"
+    assert checks["storage.kv"]["status"] == "ok"
+    assert not (tmp_path / "kv").exists(), "doctor must not create the storage directory"
+
+
+def test_doctor_reports_fixes(soup_go_path: Path, tmp_path: Path) -> None:
+    occupied = socket.socket()
+    occupied.bind(("127.0.0.1", 0))
+    occupied.listen()
+    port = occupied.getsockname()[1]
+    try:
+        env = {
+            "PLUGIN_SERVER_PATH": str(tmp_path / "missing-server"),
+            "KV_STORAGE_DIR": str(tmp_path),
+            "TLS_CURVE": "secp999r1",
+        }
+        code, report = _doctor(soup_go_path, env, "--port", str(port))
+    finally:
+        occupied.close()
+
+    assert code != 0
+    failed = {c["name"]: c for c in report["checks"] if c["status"] == "fail"}
+    assert set(failed) == {"env.PLUGIN_SERVER_PATH", "env.TLS", f"port.{port}"}
+    assert all(check["fix"] for check in failed.values())
+    assert report["failures"] == 3
+
+
+# 🥣🔬🔚
@@ -0,0 +1,82 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""CLI verification for `soup-go features`.
+
+Experimental behaviors are off by default, gated with an error that names
+the feature, and enabled by --enable-feature or SOUP_GO_FEATURES. Runners
+rely on `features list --json` to detect them."""
+
+import json
+import os
+from pathlib import Path
+import subprocess
+
+import pytest
+
+
+@pytest.fixture
+def soup_go_path() -> Path:
+    """Get path to soup-go executable."""
+    candidates = [Path("bin/soup-go"), Path("harnesses/bin/soup-go")]
+    for path in candidates:
+        if path.exists():
+            return path.resolve()
+    pytest.skip("soup-go not found")
+
+
+def _soup_go(soup_go: Path, *args: str, env: dict[str, str] | None = None) -> subprocess.CompletedProcess:
+    return subprocess.run(
+        [str(soup_go), *args],
+        env={**os.environ, **(env or {})},
+        capture_output=True,
+        text=True,
+        timeout=30,
+    )
+
+
+def _features(soup_go: Path, *args: str, env: dict[str, str] | None = None) -> dict[str, dict]:
+    result = _soup_go(soup_go, "features", "list", "--json", *args, env=env)
+    assert result.returncode == 0, result.stderr
+    return {f["name"]: f for f in json.loads(result.stdout)["features"]}
+
+
+def test_features_are_off_by_default(soup_go_path: Path) -> None:
+    features = _features(soup_go_path)
+
+    assert {"wire-v2", "hcl-schema"} <= set(features)
+    for feature in features.values():
+        assert set(feature) == {"name", "subsystem", "stage", "description", "enabled"}
+        assert not feature["enabled"]
+
+
+def test_flag_and_environment_enable_features(soup_go_path: Path) -> None:
+    features = _features(soup_go_path, "--enable-feature", "wire-v2", env={"SOUP_GO_FEATURES": "hcl-schema"})
+
+    assert features["wire-v2"]["enabled"]
+    assert features["hcl-schema"]["enabled"]
+
+
+def test_unknown_feature_is_rejected(soup_go_path: Path) -> None:
+    result = _soup_go(soup_go_path, "features", "list", "--enable-feature", "no-such-feature")
+
+    assert result.returncode != 0
+    assert 'unknown feature "no-such-feature"' in result.stderr
+
+
+def test_gated_behavior_names_its_feature(soup_go_path: Path, tmp_path: Path) -> None:
+    value = tmp_path / "value.json"
+    value.write_text('"x"')
+    args = ["wire", "encode", str(value), str(tmp_path / "value.tfw"), "--wire-version", "2", "--type", '"string"']
+
+    gated = _soup_go(soup_go_path, *args)
+    assert gated.returncode != 0
+    assert "--enable-feature wire-v2" in gated.stderr
+
+    enabled = _soup_go(soup_go_path, *args, "--enable-feature", "wire-v2")
+    assert enabled.returncode == 0, enabled.stderr
+
+
+# 🥣🔬🔚
@@ -0,0 +1,126 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""CLI verification for `soup-go harness fixtures serve`.
+
+The fixture server publishes a directory on loopback so scenarios can fetch
+inputs without the network. Every request it serves, including ranged and
+missing ones, must show up in its access log and report."""
+
+import hashlib
+import json
+from pathlib import Path
+import subprocess
+import time
+import urllib.error
+import urllib.request
+
+import pytest
+
+
+@pytest.fixture
+def soup_go_path() -> Path:
+    """Get path to soup-go executable."""
+    candidates = [Path("bin/soup-go"), Path("harnesses/bin/soup-go")]
+    for path in candidates:
+        if path.exists():
+            return path.resolve()
+    pytest.skip("soup-go not found")
+
+
+def _serve(soup_go_path: Path, root: Path, tmp_path: Path, duration: str) -> tuple[subprocess.Popen, str]:
+    url_file = tmp_path / "url.txt"
+    proc = subprocess.Popen(
+        [str(soup_go_path), "harness", "fixtures", "serve", "--dir", str(root), "--addr", ":0"]
+        + ["--duration", duration, "--url-file", str(url_file), "--output-format", "json"]
+        + ["--access-log", str(tmp_path / "access.ndjson")],
+        stdout=subprocess.PIPE,
+        stderr=subprocess.PIPE,
+        text=True,
+    )
+    deadline = time.monotonic() + 10
+    while not url_file.exists() or not url_file.read_text().strip():
+        if proc.poll() is not None or time.monotonic() > deadline:
+            proc.kill()
+            pytest.fail(f"fixture server did not start: {proc.communicate()[1]}")
+        time.sleep(0.05)
+    return proc, url_file.read_text().strip()
+
+
+def _report(proc: subprocess.Popen) -> dict:
+    stdout, stderr = proc.communicate(timeout=30)
+    assert proc.returncode == 0, stderr
+    return json.loads(stdout)
+
+
+def test_fixtures_serve_logs_every_request(soup_go_path: Path, tmp_path: Path) -> None:
+    root = tmp_path / "fixtures"
+    (root / "modules").mkdir(parents=True)
+    (root / "modules" / "main.tf").write_text('variable "name" {}\n')
+    proc, url = _serve(soup_go_path, root, tmp_path, "3s")
+    assert url.startswith("http://127.0.0.1:")
+
+    with urllib.request.urlopen(url + "modules/main.tf") as resp:
+        assert resp.read() == b'variable "name" {}\n'
+    ranged = urllib.request.Request(url + "modules/main.tf", headers={"Range": "bytes=0-7"})
+    with urllib.request.urlopen(ranged) as resp:
+        assert resp.status == 206
+        assert resp.read() == b"variable"
+    with pytest.raises(urllib.error.HTTPError) as missing:
+        urllib.request.urlopen(url + "modules/absent.tf")
+    assert missing.value.code == 404
+
+    report = _report(proc)
+    assert report["url"] == url
+    assert report["requests"] == 3
+    assert report["not_found"] == 1
+    assert [(a["path"], a["status"]) for a in report["access"]] == [
+        ("/modules/main.tf", 200),
+        ("/modules/main.tf", 206),
+        ("/modules/absent.tf", 404),
+    ]
+    assert report["access"][1]["range"] == "bytes=0-7"
+    logged = [json.loads(line) for line in (tmp_path / "access.ndjson").read_text().splitlines()]
+    assert logged == report["access"]
+
+
+def test_corpus_sync_from_fixture_server(soup_go_path: Path, tmp_path: Path) -> None:
+    body = b'name = "soup"\n'
+    digest = hashlib.sha256(body).hexdigest()
+    root = tmp_path / "fixtures"
+    (root / "objects").mkdir(parents=True)
+    (root / "objects" / digest).write_bytes(body)
+    entry = {"path": "hcl/simple.hcl", "size": len(body), "sha256": digest}
+    index = {"version": 1, "name": "hermetic", "files": [entry]}
+    (root / "corpus.json").write_text(json.dumps(index))
+    proc, url = _serve(soup_go_path, root, tmp_path, "3s")
+
+    result = subprocess.run(
+        [str(soup_go_path), "harness", "corpus", "sync", "--url", url + "corpus.json"]
+        + ["--dir", str(tmp_path / "corpus")],
+        capture_output=True,
+        text=True,
+        timeout=30,
+    )
+    assert result.returncode == 0, result.stderr
+    assert (tmp_path / "corpus" / "hcl" / "simple.hcl").read_bytes() == body
+
+    report = _report(proc)
+    assert [a["path"] for a in report["access"]] == ["/corpus.json", f"/objects/{digest}"]
+    assert report["bytes_served"] == len(json.dumps(index)) + len(body)
+
+
+def test_fixtures_serve_requires_a_directory(soup_go_path: Path, tmp_path: Path) -> None:
+    result = subprocess.run(
+        [str(soup_go_path), "harness", "fixtures", "serve", "--dir", str(tmp_path / "missing")],
+        capture_output=True,
+        text=True,
+        timeout=30,
+    )
+    assert result.returncode != 0
+    assert "fixtures directory" in result.stderr
+
+
+# 🥣🔬🔚
@@ -65,4 +65,65 @@ def test_hcl_cli_parse(
             assert actual_output == expected_json_output
 
 
+HCL_FRAGMENT_CASES = [
+    pytest.param("expr", '[for s in ["a", "b"] : upper(s)]', None, id="fragment_expr"),
+    pytest.param("expr", "1 +\n", "2:1", id="fragment_expr_incomplete"),
+    pytest.param("template", "Hello, ${name}!%{ if loud }!!%{ endif }", None, id="fragment_template"),
+    pytest.param("template", "%{ if x }unclosed", "1:18", id="fragment_template_unclosed"),
+    pytest.param("body", 'a = 1\nb "x" {\n  c = a\n}\n', None, id="fragment_body"),
+    pytest.param("body", "a = 1\nb = \n", "2:5", id="fragment_body_missing_value"),
+]
+
+
+@pytest.mark.parametrize("go_harness_executable", [HARNESS_NAME], indirect=True)
+@pytest.mark.parametrize("fragment, source, error_start", HCL_FRAGMENT_CASES)
+def test_hcl_cli_validate_fragment(
+    go_harness_executable: Path,
+    project_root: Path,
+    request: pytest.FixtureRequest,
+    fragment: str,
+    source: str,
+    error_start: str | None,
+) -> None:
+    """Snippets validate as what they are, with ranges relative to the snippet."""
+    exit_code, stdout, stderr = run_harness_cli(
+        go_harness_executable,
+        ["hcl", "validate", "--fragment", fragment, "-"],
+        project_root=project_root,
+        harness_artifact_name=HARNESS_NAME,
+        test_id=request.node.callspec.id,
+        stdin_input=source,
+    )
+    assert exit_code == 0, stderr
+    result = json.loads(stdout)
+    assert result["fragment"] == fragment
+    assert result["valid"] is (error_start is None), result
+    if error_start is not None:
+        start = result["errors"][0]["range"]["start"]
+        assert f"{start['line']}:{start['column']}" == error_start
+        assert result["errors"][0]["range"]["filename"] == "<stdin>"
+
+
+@pytest.mark.parametrize("go_harness_executable", [HARNESS_NAME], indirect=True)
+def test_hcl_cli_view_expr_fragment(
+    go_harness_executable: Path, project_root: Path, request: pytest.FixtureRequest
+) -> None:
+    """An expr fragment's tree is the one classified, evaluated expression."""
+    exit_code, stdout, stderr = run_harness_cli(
+        go_harness_executable,
+        ["hcl", "view", "--fragment", "expr", "-"],
+        project_root=project_root,
+        harness_artifact_name=HARNESS_NAME,
+        test_id=request.node.callspec.id,
+        stdin_input='merge({a = 1}, {b = "two"})',
+    )
+    assert exit_code == 0, stderr
+    result = json.loads(stdout)
+    assert result["body"] is None
+    [node] = result["tree"]["children"]
+    assert node["kind"] == "expression"
+    assert node["expression"]["class"] == "function-call"
+    assert node["expression"]["value"] == {"a": 1, "b": "two"}
+
+
 # 🥣🔬🔚
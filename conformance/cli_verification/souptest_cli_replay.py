@@ -0,0 +1,100 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""CLI verification for `soup-go rpc --transcript` and `soup-go harness replay`.
+
+A recorded session must replay cleanly against the same server, secrets
+must not reach the transcript, and a transcript that no longer matches the
+server must fail the replay."""
+
+import json
+import os
+from pathlib import Path
+import subprocess
+
+import pytest
+
+
+@pytest.fixture
+def soup_go_path() -> Path:
+    """Get path to soup-go executable."""
+    candidates = [Path("bin/soup-go"), Path("harnesses/bin/soup-go")]
+    for path in candidates:
+        if path.exists():
+            return path.resolve()
+    pytest.skip("soup-go not found")
+
+
+@pytest.fixture
+def env(soup_go_path: Path, tmp_path: Path) -> dict[str, str]:
+    storage = tmp_path / "store"
+    storage.mkdir()
+    env = os.environ.copy()
+    env.update(PLUGIN_SERVER_PATH=str(soup_go_path), KV_STORAGE_DIR=str(storage), KV_AUTH_TOKEN="s3cret")
+    return env
+
+
+def _record(soup_go: Path, env: dict[str, str], transcript: Path, *args: str) -> None:
+    subprocess.run(
+        [str(soup_go), "rpc", "kv", *args, "--transcript", str(transcript)],
+        env=env,
+        capture_output=True,
+        timeout=60,
+    )
+
+
+def _replay(soup_go: Path, env: dict[str, str], transcript: Path) -> tuple[int, dict]:
+    result = subprocess.run(
+        [str(soup_go), "harness", "replay", str(transcript), "--against", str(soup_go), "--output-format", "json"],
+        env=env,
+        capture_output=True,
+        text=True,
+        timeout=60,
+    )
+    return result.returncode, json.loads(result.stdout)
+
+
+def _session(soup_go: Path, env: dict[str, str], tmp_path: Path) -> Path:
+    session = tmp_path / "session.ndjson"
+    parts = []
+    for i, args in enumerate([("put", "foo", "bar"), ("get", "foo"), ("get", "missing")]):
+        part = tmp_path / f"part-{i}.ndjson"
+        _record(soup_go, env, part, *args)
+        parts.append(part.read_text())
+    session.write_text("".join(parts))
+    return session
+
+
+def test_transcript_redacts_token(soup_go_path: Path, env: dict[str, str], tmp_path: Path) -> None:
+    session = _session(soup_go_path, env, tmp_path)
+    text = session.read_text()
+    assert "s3cret" not in text
+    entries = [json.loads(line) for line in text.splitlines()]
+    assert [e["method"] for e in entries] == ["/proto.KV/Put", "/proto.KV/Get", "/proto.KV/Get"]
+    assert [e["status"]["code"] for e in entries] == ["OK", "OK", "NotFound"]
+    assert all(e["metadata"]["authorization"] == ["[REDACTED]"] for e in entries)
+
+
+def test_replay_matches_same_server(soup_go_path: Path, env: dict[str, str], tmp_path: Path) -> None:
+    session = _session(soup_go_path, env, tmp_path)
+    code, report = _replay(soup_go_path, env, session)
+    assert code == 0, report
+    assert report["calls"] == 3
+    assert report["mismatches"] == 0
+
+
+def test_replay_reports_changed_result(soup_go_path: Path, env: dict[str, str], tmp_path: Path) -> None:
+    session = _session(soup_go_path, env, tmp_path)
+    entries = [json.loads(line) for line in session.read_text().splitlines()]
+    entries[2]["status"] = {"code": "OK"}
+    session.write_text("".join(json.dumps(e) + "\n" for e in entries))
+
+    code, report = _replay(soup_go_path, env, session)
+    assert code != 0
+    assert report["mismatches"] == 1
+    assert report["results"][2]["mismatch"] == "status NotFound, recorded OK"
+
+
+# 🥣🔬🔚
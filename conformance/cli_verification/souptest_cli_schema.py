@@ -0,0 +1,114 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""CLI verification for `soup-go schema`.
+
+The shipped output schemas must match the report types soup-go encodes,
+real output must validate, and a document with a property its schema
+doesn't list must be rejected at that property's path."""
+
+import json
+from pathlib import Path
+import subprocess
+
+import pytest
+
+SCHEMAS_DIR = Path(__file__).resolve().parents[2] / "src/tofusoup/harness/go/soup-go/schemas"
+
+
+@pytest.fixture
+def soup_go_path() -> Path:
+    """Get path to soup-go executable."""
+    candidates = [Path("bin/soup-go"), Path("harnesses/bin/soup-go")]
+    for path in candidates:
+        if path.exists():
+            return path.resolve()
+    pytest.skip("soup-go not found")
+
+
+def _soup_go(soup_go: Path, *args: str, stdin: str | None = None) -> subprocess.CompletedProcess:
+    return subprocess.run(
+        [str(soup_go), *args],
+        input=stdin,
+        capture_output=True,
+        text=True,
+        timeout=60,
+    )
+
+
+def _validate(soup_go: Path, command: str, output: str, *args: str) -> tuple[int, dict]:
+    result = _soup_go(
+        soup_go,
+        "schema",
+        "validate-output",
+        "--command",
+        command,
+        "--output-format",
+        "json",
+        *args,
+        stdin=output,
+    )
+    return result.returncode, json.loads(result.stdout)
+
+
+def test_shipped_schemas_are_current(soup_go_path: Path) -> None:
+    result = _soup_go(
+        soup_go_path,
+        "schema",
+        "validate-output",
+        "--self-check",
+        "--schemas-dir",
+        str(SCHEMAS_DIR),
+        "--output-format",
+        "json",
+    )
+    report = json.loads(result.stdout)
+
+    assert result.returncode == 0, report
+    assert report["ok"]
+    assert {c["shipped"] for c in report["commands"]} == {"match"}
+    listed = _soup_go(soup_go_path, "schema", "list").stdout.splitlines()
+    assert len(listed) == len(report["commands"]) + 1  # cty convert has no shipped file
+
+
+def test_fsck_output_validates(soup_go_path: Path, tmp_path: Path) -> None:
+    fsck = _soup_go(
+        soup_go_path, "rpc", "kv", "admin", "fsck", "--storage-dir", str(tmp_path), "--output-format", "json"
+    )
+    assert fsck.returncode == 0, fsck.stderr
+
+    code, report = _validate(soup_go_path, "rpc kv admin fsck", fsck.stdout)
+    assert code == 0, report
+    assert (report["documents"], report["valid"], report["violations"]) == (1, True, [])
+
+    # An unannounced field is a shape change
+    tampered = json.loads(fsck.stdout)
+    tampered["issues"] = [{"key": "k", "file": "k", "kind": "corrupt", "detail": "", "action": "", "extra": 1}]
+    code, report = _validate(soup_go_path, "rpc kv admin fsck", json.dumps(tampered))
+    assert code != 0
+    assert {(v["path"], v["message"]) for v in report["violations"]} == {
+        ("$.issues[0]", 'missing required property "repaired"'),
+        ("$.issues[0].extra", 'unexpected property "extra"'),
+    }
+
+
+def test_cty_convert_output_validates_against_type(soup_go_path: Path, tmp_path: Path) -> None:
+    cty_type = '["object",{"name":"string","tags":["list","string"]}]'
+    source = tmp_path / "in.json"
+    source.write_text('{"name": "soup", "tags": ["a", "b"]}')
+    converted = _soup_go(soup_go_path, "cty", "convert", str(source), "-", "--type", cty_type)
+    assert converted.returncode == 0, converted.stderr
+
+    code, report = _validate(soup_go_path, "cty convert", converted.stdout, "--type", cty_type)
+    assert code == 0, report
+
+    code, report = _validate(soup_go_path, "cty convert", '{"name": "soup", "tags": [1]}', "--type", cty_type)
+    assert code != 0
+    assert [(v["path"], v["message"]) for v in report["violations"]] == [
+        ("$.tags[0]", "expected string or null, got integer"),
+    ]
+
+
+# 🥣🔬🔚
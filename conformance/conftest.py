@@ -6,14 +6,103 @@
 """Common conftest for tests under 'tofusoup/conformance'.
 Provides shared fixtures and test collection modifications."""
 
+import datetime
 import os
 from pathlib import Path
 import shutil
+from typing import Any
 
 import pytest
 
 from tofusoup.common.config import load_tofusoup_config
 from tofusoup.harness.logic import GO_HARNESS_CONFIG, TofuSoupError, ensure_go_harness_build
+from tofusoup.testing.tags import case_tags, parse_tag_filters, skip_reason
+from tofusoup.testing.xfail import (
+    DEFAULT_MANIFEST,
+    XfailEntry,
+    expired_entries,
+    find_entry,
+    load_xfail_manifest,
+)
+
+xfail_entries_key = pytest.StashKey[list[XfailEntry]]()
+
+
+def pytest_addoption(parser: pytest.Parser) -> None:
+    group = parser.getgroup(
+        "soup-conformance", "conformance case selection (see tofusoup.testing.tags and tofusoup.testing.xfail)"
+    )
+    group.addoption("--include-tags", help="Run only cases matching one of these key[:value] tags.")
+    group.addoption("--exclude-tags", help="Skip cases matching any of these key[:value] tags.")
+    group.addoption(
+        "--allow-known-failures",
+        action="store_true",
+        help="Run cases tagged known-failure as non-strict xfails instead of skipping them.",
+    )
+    group.addoption(
+        "--xfail-manifest",
+        type=Path,
+        help=f"Known failures with owners and expiry dates (default: {DEFAULT_MANIFEST} if present).",
+    )
+
+
+def pytest_configure(config: pytest.Config) -> None:
+    path = config.getoption("xfail_manifest")
+    if path is None and (config.rootpath / DEFAULT_MANIFEST).exists():
+        path = config.rootpath / DEFAULT_MANIFEST
+    try:
+        config.stash[xfail_entries_key] = load_xfail_manifest(path) if path is not None else []
+    except TofuSoupError as e:
+        raise pytest.UsageError(str(e)) from e
+
+
+def pytest_collection_modifyitems(config: pytest.Config, items: list[pytest.Item]) -> None:
+    """Skip cases by their tags, and mark known failures that do run and
+    xfail manifest entries as xfail."""
+    try:
+        include = parse_tag_filters(config.getoption("include_tags"))
+        exclude = parse_tag_filters(config.getoption("exclude_tags"))
+    except ValueError as e:
+        raise pytest.UsageError(str(e)) from e
+    allow_known_failures = config.getoption("allow_known_failures")
+    today = datetime.date.today()
+    active = [entry for entry in config.stash[xfail_entries_key] if not entry.expired(today)]
+    for item in items:
+        try:
+            tags = case_tags(item.path, [marker.kwargs for marker in item.iter_markers("tags")])
+        except ValueError as e:
+            raise pytest.UsageError(f"{item.nodeid}: {e}") from e
+        if reason := skip_reason(tags, include, exclude, allow_known_failures):
+            item.add_marker(pytest.mark.skip(reason=reason))
+        elif "known-failure" in tags:
+            item.add_marker(pytest.mark.xfail(reason=tags["known-failure"], strict=False))
+        elif entry := find_entry(active, item.nodeid):
+            item.add_marker(pytest.mark.xfail(reason=entry.describe(), strict=False))
+
+
+@pytest.hookimpl(tryfirst=True)
+def pytest_sessionfinish(session: pytest.Session) -> None:
+    """Expired xfail manifest entries fail the run."""
+    if expired_entries(session.config.stash[xfail_entries_key], datetime.date.today()):
+        session.exitstatus = pytest.ExitCode.TESTS_FAILED
+
+
+def pytest_terminal_summary(terminalreporter: Any, config: pytest.Config) -> None:
+    """Report xfails that passed, and expired xfail manifest entries."""
+    entries = config.stash[xfail_entries_key]
+    xpassed = terminalreporter.stats.get("xpassed", [])
+    if xpassed:
+        terminalreporter.section("unexpected passes")
+        for report in xpassed:
+            if entry := find_entry(entries, report.nodeid):
+                fix = f"remove its xfail manifest entry (owner {entry.owner})"
+            else:
+                fix = "remove its known_failure tag"
+            terminalreporter.line(f"{report.nodeid}: unexpected pass, {fix}")
+    if expired := expired_entries(entries, datetime.date.today()):
+        terminalreporter.section("expired xfail entries")
+        for entry in expired:
+            terminalreporter.line(f"{entry.case}: {entry.describe()}")
 
 
 @pytest.fixture(scope="session")
@@ -0,0 +1,79 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""Collection conversion verdicts from `soup-go cty collections probe`.
+
+The probe runs a documented suite of tuple, set and empty-collection
+conversions through go-cty. The golden verdicts below pin down the rules
+other runtimes most often get wrong, so a go-cty upgrade that changes one
+shows up here first.
+"""
+
+import json
+from pathlib import Path
+
+import pytest
+
+from ..cli_verification.shared_cli_utils import run_harness_cli
+
+# case -> (ok, result type, result value)
+GOLDEN = {
+    "tuple-to-list-string-number": (True, ["list", "string"], ["a", "1"]),
+    "tuple-to-list-number-bool": (False, None, None),
+    "tuple-to-list-objects-differing-attrs": (True, ["list", ["map", "string"]], [{"a": "x"}, {"b": "y"}]),
+    "tuple-to-list-dynamic-null-element": (True, ["list", "number"], [None, 1]),
+    "set-of-unknown-to-list": (True, ["list", "string"], {"unknown": ["list", "string"]}),
+    "set-of-unknown-length": (True, "number", {"unknown": "number"}),
+    "tuple-to-set-dedupe-after-unify": (True, ["set", "string"], ["1"]),
+    "tuple-to-set-number-dedupe": (True, ["set", "number"], [1]),
+    "empty-tuple-to-list-dynamic": (True, ["list", "dynamic"], []),
+    "empty-list-to-empty-tuple": (False, None, None),
+    "empty-json-array": (True, ["tuple", []], []),
+}
+
+
+def _probe(executable: Path, project_root: Path, *args: str) -> tuple[int, str, str]:
+    return run_harness_cli(
+        executable=executable,
+        args=["cty", "collections", "probe", *args],
+        project_root=project_root,
+        harness_artifact_name="soup-go",
+        test_id="cty_collections_probe",
+    )
+
+
+@pytest.mark.integration_cty
+@pytest.mark.harness_go
+@pytest.mark.parametrize("go_harness_executable", ["soup-go"], indirect=True)
+def test_go_collection_verdicts(go_harness_executable: Path, project_root: Path) -> None:
+    exit_code, stdout, stderr = _probe(go_harness_executable, project_root)
+    assert exit_code == 0, f"soup-go cty collections probe failed: {stderr}"
+    report = json.loads(stdout)
+    assert (report["probe"], report["harness"]) == ("cty-collections", "soup-go")
+
+    cases = {case["name"]: case for case in report["cases"]}
+    assert len(cases) == len(report["cases"]), "case names must be unique"
+    assert {case["category"] for case in cases.values()} == {"tuple-to-list", "set", "empty", "unify"}
+    for name, (ok, ty, value) in GOLDEN.items():
+        verdict = cases[name]["verdict"]
+        assert (verdict["ok"], verdict.get("type"), verdict.get("value")) == (ok, ty, value), name
+        assert ok or verdict["error"], name
+
+
+@pytest.mark.integration_cty
+@pytest.mark.harness_go
+@pytest.mark.parametrize("go_harness_executable", ["soup-go"], indirect=True)
+def test_go_collection_probe_filters(go_harness_executable: Path, project_root: Path) -> None:
+    exit_code, stdout, stderr = _probe(go_harness_executable, project_root, "--category", "unify")
+    assert exit_code == 0, stderr
+    cases = json.loads(stdout)["cases"]
+    assert cases and all(case["op"] == "unify" for case in cases)
+
+    exit_code, _, stderr = _probe(go_harness_executable, project_root, "--case", "no-such-case")
+    assert exit_code != 0
+    assert 'unknown case "no-such-case"' in stderr
+
+
+# 🥣🔬🔚
@@ -0,0 +1,94 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""Mark propagation results from `soup-go cty marks propagate`.
+
+The probe runs concat, merge and HCL indexing on marked values through
+go-cty. The golden results below pin down where each mark ends up,
+including the cases where Go drops a mark, so a runtime that carries marks
+differently shows up here.
+"""
+
+import json
+from pathlib import Path
+
+import pytest
+
+from ..cli_verification.shared_cli_utils import run_harness_cli
+
+S, E = ["sensitive"], ["ephemeral"]
+
+# case -> (result value, {path: marks}); a value of None means the op fails
+GOLDEN: dict[str, tuple[object, dict[str, list[str]]]] = {
+    "concat-marked-list": (["a", "b"], {"": S}),
+    "concat-marked-element": (["a", "b"], {"[0]": S}),
+    "concat-marked-empty-list": (["b"], {"": S}),
+    "concat-two-marks": (["a", "b"], {"": E + S}),
+    "concat-marked-tuple-element": (["a", 1, True], {"[1]": E}),
+    "concat-marked-unknown": ({"unknown": ["list", "string"]}, {}),
+    "merge-marked-object": ({"a": "x", "b": "y"}, {"": S}),
+    "merge-marked-attribute": ({"a": "x", "b": "y"}, {"a": S}),
+    "merge-overridden-marked-attribute": ({"a": "y"}, {}),
+    "merge-overridden-marked-object": ({"a": "y"}, {"": S}),
+    "merge-marked-map-element": ({"a": "x", "b": "y"}, {'["a"]': E}),
+    "merge-marked-null": ({"b": "y"}, {}),
+    "index-marked-list": ("b", {"": S}),
+    "index-marked-element": ("b", {"": S}),
+    "index-unmarked-sibling": ("a", {}),
+    "index-marked-key": ("a", {"": S}),
+    "index-marked-key-and-collection": ("a", {"": E + S}),
+    "index-marked-map": ("v", {"": S}),
+    "index-nested-marks": ({"p": "x"}, {"": E, "p": S}),
+    "index-marked-out-of-range": (None, {}),
+    "index-unknown-marked-list": ({"unknown": "string"}, {}),
+}
+
+
+def _propagate(executable: Path, project_root: Path, *args: str) -> tuple[int, str, str]:
+    return run_harness_cli(
+        executable=executable,
+        args=["cty", "marks", "propagate", *args],
+        project_root=project_root,
+        harness_artifact_name="soup-go",
+        test_id="cty_marks_propagate",
+    )
+
+
+@pytest.mark.integration_cty
+@pytest.mark.harness_go
+@pytest.mark.parametrize("go_harness_executable", ["soup-go"], indirect=True)
+def test_go_mark_propagation(go_harness_executable: Path, project_root: Path) -> None:
+    exit_code, stdout, stderr = _propagate(go_harness_executable, project_root)
+    assert exit_code == 0, f"soup-go cty marks propagate failed: {stderr}"
+    report = json.loads(stdout)
+    assert (report["probe"], report["harness"]) == ("cty-marks", "soup-go")
+
+    cases = {case["name"]: case for case in report["cases"]}
+    assert set(cases) == set(GOLDEN)
+    for name, (value, marks) in GOLDEN.items():
+        verdict = cases[name]["verdict"]
+        if value is None:
+            assert not verdict["ok"] and verdict["error"], name
+            continue
+        result = verdict["result"]
+        assert result["value"] == value, name
+        assert {m["path"]: m["marks"] for m in result["marks"]} == marks, name
+
+
+@pytest.mark.integration_cty
+@pytest.mark.harness_go
+@pytest.mark.parametrize("go_harness_executable", ["soup-go"], indirect=True)
+def test_go_mark_probe_filters(go_harness_executable: Path, project_root: Path) -> None:
+    exit_code, stdout, stderr = _propagate(go_harness_executable, project_root, "--op", "merge")
+    assert exit_code == 0, stderr
+    cases = json.loads(stdout)["cases"]
+    assert cases and all(case["op"] == "merge" for case in cases)
+
+    exit_code, _, stderr = _propagate(go_harness_executable, project_root, "--op", "slice")
+    assert exit_code != 0
+    assert 'unknown op "slice"' in stderr
+
+
+# 🥣🔬🔚
@@ -0,0 +1,128 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""Golden output for `soup-go cty convert --redact-marked`.
+
+Sensitive-marked leaves are replaced by "(sensitive value sha256:<16 hex>)"
+and listed in a separate redaction map with the full SHA-256 of each leaf's
+msgpack encoding. Other harnesses that redact marked values should produce
+the same output and digests.
+"""
+
+import hashlib
+import json
+from pathlib import Path
+
+import pytest
+
+from pyvider.cty import CtyNumber, CtyString
+from pyvider.cty.codec import cty_to_msgpack
+
+from ..cli_verification.shared_cli_utils import run_harness_cli
+
+VALUE = {
+    "name": "web",
+    "db": {"user": "admin", "password": "hunter2", "port": 5432},
+    "tokens": ["a", "b"],
+    "tags": {"env": "prod", "secret": "x"},
+}
+TYPE = [
+    "object",
+    {
+        "name": "string",
+        "db": ["object", {"user": "string", "password": "string", "port": "number"}],
+        "tokens": ["list", "string"],
+        "tags": ["map", "string"],
+    },
+]
+# Terraform state sensitive_attributes for db (every leaf in it) and tokens[1]
+SENSITIVE_PATHS = [
+    [{"type": "get_attr", "value": "db"}],
+    [{"type": "get_attr", "value": "tokens"}, {"type": "index", "value": {"value": 1, "type": "number"}}],
+]
+
+DIGESTS = {
+    "db.password": "sha256:1acc4c9c6a0487e3c6cc560807d21d20060bbaff13e11d52a0feeacfa33004c1",
+    "db.port": "sha256:a3334d939bfa4cd3687cb83a0d407b1f40c97d67e042f991befc497cc62e8a2e",
+    "db.user": "sha256:ecce04899f54c10aa7cd1551f42634abbe37355cf996ac4010cfc29618bc4efd",
+    "tokens[1]": "sha256:8fc35dbc8f42a1e9ef6d5c634273b92e5e1b1475c00ffc5581db116d485f3e88",
+}
+
+
+def _placeholder(path: str) -> str:
+    return f"(sensitive value {DIGESTS[path][: len('sha256:') + 16]})"
+
+
+EXPECTED_OUTPUT = {
+    "name": "web",
+    "db": {
+        "user": _placeholder("db.user"),
+        "password": _placeholder("db.password"),
+        "port": _placeholder("db.port"),
+    },
+    "tokens": ["a", _placeholder("tokens[1]")],
+    "tags": {"env": "prod", "secret": "x"},
+}
+
+
+def test_digests_are_sha256_of_python_msgpack() -> None:
+    """pyvider's msgpack encoding of each leaf digests to soup-go's value."""
+    leaves = {
+        "db.password": CtyString().validate("hunter2"),
+        "db.port": CtyNumber().validate(5432),
+        "db.user": CtyString().validate("admin"),
+        "tokens[1]": CtyString().validate("b"),
+    }
+    for path, leaf in leaves.items():
+        digest = "sha256:" + hashlib.sha256(cty_to_msgpack(leaf, leaf.type)).hexdigest()
+        assert digest == DIGESTS[path], path
+
+
+@pytest.mark.integration_cty
+@pytest.mark.harness_go
+@pytest.mark.parametrize("go_harness_executable", ["soup-go"], indirect=True)
+@pytest.mark.parametrize("mark_with", ["flags", "state_paths"])
+def test_go_redaction_matches_golden(
+    go_harness_executable: Path, project_root: Path, tmp_path: Path, mark_with: str
+) -> None:
+    output_file = tmp_path / "redacted.json"
+    map_file = tmp_path / "redactions.json"
+    if mark_with == "flags":
+        marks = ["--sensitive", "db", "--sensitive", "tokens[1]"]
+    else:
+        paths_file = tmp_path / "sensitive_attributes.json"
+        paths_file.write_text(json.dumps(SENSITIVE_PATHS))
+        marks = ["--sensitive-paths", str(paths_file)]
+
+    exit_code, _, stderr = run_harness_cli(
+        executable=go_harness_executable,
+        args=[
+            "cty",
+            "convert",
+            "-",
+            str(output_file),
+            "--type",
+            json.dumps(TYPE),
+            "--redact-marked",
+            "--redaction-map",
+            str(map_file),
+            *marks,
+        ],
+        project_root=project_root,
+        harness_artifact_name="soup-go",
+        test_id=f"redact_marked_{mark_with}",
+        stdin_input=json.dumps(VALUE),
+    )
+    assert exit_code == 0, f"soup-go cty convert --redact-marked failed: {stderr}"
+
+    assert json.loads(output_file.read_text()) == EXPECTED_OUTPUT
+    redactions = json.loads(map_file.read_text())
+    assert redactions["digest_algorithm"] == "sha256(msgpack)"
+    assert redactions["count"] == len(DIGESTS)
+    assert {r["path"]: r["digest"] for r in redactions["redactions"]} == DIGESTS
+    assert all(r["placeholder"] == _placeholder(r["path"]) for r in redactions["redactions"])
+
+
+# 🥣🔬🔚
@@ -0,0 +1,83 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""Golden conversion trace for `soup-go cty convert --trace-steps`.
+
+Each step names the JSON at a path, the cty type it became and the rule
+that got it there, parents before their elements and object attributes in
+sorted order. Another implementation can log the same steps and compare
+them to find where its conversion takes a different route, even when the
+final value matches.
+"""
+
+import json
+from pathlib import Path
+
+import pytest
+
+from ..cli_verification.shared_cli_utils import run_harness_cli
+
+VALUE = {"ports": ["80", 443, "80"], "tags": {"env": "prod"}, "note": None}
+TYPE = ["object", {"ports": ["set", "number"], "tags": ["map", "string"], "note": "string"}]
+
+# (path, from, rule, detail)
+EXPECTED_STEPS = [
+    ("", "object", "object_from_object", None),
+    ("note", "null", "null", None),
+    ("ports", "array", "set_from_array", "3 elements collapsed to 2 distinct"),
+    ("ports[0]", "string", "number_from_string", None),
+    ("ports[1]", "number", "number", None),
+    ("ports[2]", "string", "number_from_string", None),
+    ("tags", "object", "map_from_object", None),
+    ("tags.env", "string", "string", None),
+]
+
+
+@pytest.mark.integration_cty
+@pytest.mark.harness_go
+@pytest.mark.parametrize("go_harness_executable", ["soup-go"], indirect=True)
+def test_go_conversion_trace_matches_golden(
+    go_harness_executable: Path, project_root: Path, tmp_path: Path
+) -> None:
+    trace_file = tmp_path / "trace.ndjson"
+    exit_code, stdout, stderr = run_harness_cli(
+        executable=go_harness_executable,
+        args=["cty", "convert", "-", "-", "--type", json.dumps(TYPE), "--trace-steps", str(trace_file)],
+        project_root=project_root,
+        harness_artifact_name="soup-go",
+        test_id="cty_convert_trace",
+        stdin_input=json.dumps(VALUE),
+    )
+    assert exit_code == 0, f"soup-go cty convert --trace-steps failed: {stderr}"
+    assert json.loads(stdout) == {"ports": [80, 443], "tags": {"env": "prod"}, "note": None}
+
+    steps = [json.loads(line) for line in trace_file.read_text().splitlines()]
+    assert [s["seq"] for s in steps] == list(range(1, len(steps) + 1))
+    assert [(s["path"], s["from"], s["rule"], s.get("detail")) for s in steps] == EXPECTED_STEPS
+    assert steps[2]["to"] == ["set", "number"]
+
+
+@pytest.mark.integration_cty
+@pytest.mark.harness_go
+@pytest.mark.parametrize("go_harness_executable", ["soup-go"], indirect=True)
+def test_go_conversion_trace_ends_at_failure(
+    go_harness_executable: Path, project_root: Path, tmp_path: Path
+) -> None:
+    trace_file = tmp_path / "trace.ndjson"
+    exit_code, _, _ = run_harness_cli(
+        executable=go_harness_executable,
+        args=["cty", "convert", "-", "-", "--type", '["list", "number"]', "--trace-steps", str(trace_file)],
+        project_root=project_root,
+        harness_artifact_name="soup-go",
+        test_id="cty_convert_trace_failure",
+        stdin_input='[1, "x"]',
+    )
+    assert exit_code != 0
+    last = json.loads(trace_file.read_text().splitlines()[-1])
+    assert (last["path"], last["rule"]) == ("[1]", "number_from_string")
+    assert last["error"] == "invalid number string at [1]"
+
+
+# 🥣🔬🔚
@@ -0,0 +1,111 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""Terraform's typed JSON envelope in `soup-go cty convert --encoding ctyjson-typed`.
+
+The envelope pairs a value with its type, {"value": ..., "type": ...}. It
+must survive a round trip through msgpack, keep its own type when --type is
+left out, and be converted when --type is given.
+"""
+
+import json
+from pathlib import Path
+
+import pytest
+
+from ..cli_verification.shared_cli_utils import run_harness_cli
+
+ENVELOPE = {
+    "value": {"ports": [80, 443], "tags": {"env": "prod"}, "note": None},
+    "type": ["object", {"ports": ["list", "number"], "tags": ["map", "string"], "note": "string"}],
+}
+
+
+def _convert(executable: Path, project_root: Path, test_id: str, stdin: str | bytes, *args: str) -> tuple:
+    return run_harness_cli(
+        executable=executable,
+        args=["cty", "convert", "-", "-", "--encoding", "ctyjson-typed", *args],
+        project_root=project_root,
+        harness_artifact_name="soup-go",
+        test_id=test_id,
+        stdin_input=stdin,
+    )
+
+
+@pytest.mark.integration_cty
+@pytest.mark.harness_go
+@pytest.mark.parametrize("go_harness_executable", ["soup-go"], indirect=True)
+def test_envelope_keeps_its_type(go_harness_executable: Path, project_root: Path) -> None:
+    exit_code, stdout, stderr = _convert(
+        go_harness_executable, project_root, "cty_typed_json_identity", json.dumps(ENVELOPE)
+    )
+    assert exit_code == 0, stderr
+    assert json.loads(stdout) == ENVELOPE
+
+
+@pytest.mark.integration_cty
+@pytest.mark.harness_go
+@pytest.mark.parametrize("go_harness_executable", ["soup-go"], indirect=True)
+def test_envelope_is_converted_to_type(go_harness_executable: Path, project_root: Path) -> None:
+    target = ["object", {"ports": ["set", "string"], "tags": ["map", "string"], "note": "string"}]
+    exit_code, stdout, stderr = _convert(
+        go_harness_executable,
+        project_root,
+        "cty_typed_json_convert",
+        json.dumps(ENVELOPE),
+        "--type",
+        json.dumps(target),
+    )
+    assert exit_code == 0, stderr
+    assert json.loads(stdout) == {
+        "value": {"ports": ["443", "80"], "tags": {"env": "prod"}, "note": None},
+        "type": target,
+    }
+
+    exit_code, _, stderr = _convert(
+        go_harness_executable,
+        project_root,
+        "cty_typed_json_unconvertible",
+        json.dumps({"value": "abc", "type": "string"}),
+        "--type",
+        '"number"',
+    )
+    assert exit_code != 0
+    assert "doesn't convert to --type number" in stderr
+
+
+@pytest.mark.integration_cty
+@pytest.mark.harness_go
+@pytest.mark.parametrize("go_harness_executable", ["soup-go"], indirect=True)
+def test_envelope_round_trips_through_msgpack(
+    go_harness_executable: Path, project_root: Path, tmp_path: Path
+) -> None:
+    typed, packed = tmp_path / "typed.json", tmp_path / "value.msgpack"
+    typed.write_text(json.dumps(ENVELOPE))
+    exit_code, _, stderr = run_harness_cli(
+        executable=go_harness_executable,
+        args=["cty", "convert", str(typed), str(packed), "--encoding", "ctyjson-typed"]
+        + ["--output-format", "msgpack"],
+        project_root=project_root,
+        harness_artifact_name="soup-go",
+        test_id="cty_typed_json_to_msgpack",
+    )
+    assert exit_code == 0, stderr
+    # Without --type the msgpack is Terraform's dynamic form: [type JSON, value]
+    assert packed.read_bytes()[:2] == b"\x92\xc4"
+
+    exit_code, stdout, stderr = run_harness_cli(
+        executable=go_harness_executable,
+        args=["cty", "convert", str(packed), "-", "--encoding", "ctyjson-typed", "--input-format", "msgpack"]
+        + ["--type", '"dynamic"'],
+        project_root=project_root,
+        harness_artifact_name="soup-go",
+        test_id="cty_typed_json_from_msgpack",
+    )
+    assert exit_code == 0, stderr
+    assert json.loads(stdout) == ENVELOPE
+
+
+# 🥣🔬🔚
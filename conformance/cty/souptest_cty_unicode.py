@@ -0,0 +1,76 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""Unicode normalization and length conformance for cty.
+
+`soup-go cty unicode` and soup's tofusoup.common.unicode_probe run the same
+suite and must decode and measure it identically, apart from the documented
+runtime differences: Python keeps unpaired surrogates where Go substitutes
+U+FFFD, and only Go counts grapheme clusters. go-cty normalizes strings to
+NFC and its strlen and substr count grapheme clusters; the golden values
+below pin that down.
+"""
+
+import json
+from pathlib import Path
+
+import pytest
+
+from tofusoup.common.unicode_probe import (
+    UNICODE_PROBE_SUITE,
+    ascii_escape,
+    decode_probe_input,
+    measure_unicode,
+)
+
+from ..cli_verification.shared_cli_utils import run_harness_cli
+
+# Measures only soup-go reports, or that differ for unpaired surrogates
+RUNTIME_MEASURES = {"utf8_bytes", "graphemes", "replacement_chars", "lone_surrogates"}
+
+FLAG = "\\ud83c\\uddfa\\ud83c\\uddf8"
+
+# case -> (value, strlen, substr(0, 1), upper) from go-cty
+GOLDEN = {
+    "nfd-e-acute": ("caf\\u00e9", 4, "c", "CAF\\u00c9"),
+    "nfd-hangul": ("\\ud55c", 1, "\\ud55c", "\\ud55c"),
+    "stacked-marks": ("\\u01df", 1, "\\u01df", "\\u01de"),
+    "emoji-flag": (FLAG, 1, FLAG, FLAG),
+    "lone-high-surrogate": ("\\ufffd", 1, "\\ufffd", "\\ufffd"),
+    "case-sharp-s": ("stra\\u00dfe", 6, "s", "STRA\\u00dfE"),
+}
+
+
+@pytest.mark.integration_cty
+@pytest.mark.harness_go
+@pytest.mark.parametrize("go_harness_executable", ["soup-go"], indirect=True)
+def test_go_cty_unicode_matches_python_suite(go_harness_executable: Path, project_root: Path) -> None:
+    exit_code, stdout, stderr = run_harness_cli(
+        executable=go_harness_executable,
+        args=["cty", "unicode"],
+        project_root=project_root,
+        harness_artifact_name="soup-go",
+        test_id="cty_unicode",
+    )
+    assert exit_code == 0, f"soup-go cty unicode failed: {stderr}"
+    report = json.loads(stdout)
+
+    assert [(c["name"], c["category"], c["input"]) for c in report["cases"]] == UNICODE_PROBE_SUITE
+    for case in report["cases"]:
+        decoded = decode_probe_input(case["input"])
+        measures = measure_unicode(decoded)
+        for key in measures.keys() - RUNTIME_MEASURES:
+            assert case["measures"][key] == measures[key], f"{case['name']} {key}"
+        if not measures["lone_surrogates"]:
+            assert case["decoded"] == ascii_escape(decoded), case["name"]
+            assert case["measures"]["utf8_bytes"] == measures["utf8_bytes"], case["name"]
+        assert case["cty"]["json_roundtrip"] and case["cty"]["msgpack_roundtrip"], case["name"]
+
+        if case["name"] in GOLDEN:
+            cty = case["cty"]
+            assert (cty["value"], cty["length"], cty["first"], cty["upper"]) == GOLDEN[case["name"]]
+
+
+# 🥣🔬🔚
@@ -0,0 +1,92 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""`soup-go generate time-vectors`: date and time function reference results.
+
+Each vector is a formatdate, timeadd or timecmp call and the result or
+error Go gives for it. The pinned results below are the edge cases where
+harness languages most often diverge; a harness implementing these
+functions should reproduce every vector's result and fail every call that
+has an error.
+"""
+
+from datetime import datetime
+import json
+from pathlib import Path
+
+import pytest
+
+from ..cli_verification.shared_cli_utils import run_harness_cli
+
+CATEGORIES = {"rfc3339", "format", "timezone", "leap", "duration", "compare"}
+
+# name -> Go's result, or None where Go must fail
+PINNED = {
+    "rfc3339-lowercase": None,
+    "rfc3339-space-separator": None,
+    "rfc3339-year-0000": "0000-01-01",
+    "format-long": "Tuesday, 02-Jan-18 23:12:01 UTC",
+    "format-12-hour-am": "0:05 am",
+    "format-quoted": "Day 2 o'clock 09",
+    "zone-quarter-hour": "23:12 +0545 +0545 +05:45",
+    "zone-minus-zero": "23:12 UTC +00:00",
+    "zone-add-keeps-offset": "2017-11-23T01:00:00-05:00",
+    "leap-second-format": None,
+    "leap-second-cmp": None,
+    "leap-day-century": None,
+    "duration-fractional": "2017-11-22T01:30:00Z",
+    "duration-drops-fraction": "2017-11-22T00:00:00Z",
+    "duration-days": None,
+    "duration-year-overflow": "10000-01-01T00:00:00Z",
+    "cmp-same-instant": 0,
+    "cmp-fraction": 1,
+}
+
+
+def _generate(executable: Path, project_root: Path, *args: str) -> dict:
+    exit_code, stdout, stderr = run_harness_cli(
+        executable=executable,
+        args=["generate", "time-vectors", *args],
+        project_root=project_root,
+        harness_artifact_name="soup-go",
+        test_id="generate_time_vectors",
+    )
+    assert exit_code == 0, f"soup-go generate time-vectors failed: {stderr}"
+    return json.loads(stdout)
+
+
+@pytest.mark.integration_cty
+@pytest.mark.harness_go
+@pytest.mark.parametrize("go_harness_executable", ["soup-go"], indirect=True)
+def test_time_vectors_pin_go_results(go_harness_executable: Path, project_root: Path) -> None:
+    vectors = _generate(go_harness_executable, project_root)["vectors"]
+    by_name = {v["name"]: v for v in vectors}
+
+    assert len(by_name) == len(vectors)
+    assert {v["category"] for v in vectors} == CATEGORIES
+    for vector in vectors:
+        assert ("result" in vector) != ("error" in vector), vector["name"]
+    for name, result in PINNED.items():
+        if result is None:
+            assert "error" in by_name[name], f"{name}: Go accepted {by_name[name].get('result')!r}"
+        else:
+            assert by_name[name].get("result") == result, name
+
+
+@pytest.mark.integration_cty
+@pytest.mark.harness_go
+@pytest.mark.parametrize("go_harness_executable", ["soup-go"], indirect=True)
+def test_timecmp_agrees_with_python_datetime(go_harness_executable: Path, project_root: Path) -> None:
+    vectors = _generate(go_harness_executable, project_root, "--function", "timecmp")["vectors"]
+
+    assert {v["function"] for v in vectors} == {"timecmp"}
+    for vector in vectors:
+        if "error" in vector:
+            continue
+        a, b = (datetime.fromisoformat(ts.replace("Z", "+00:00")) for ts in vector["args"])
+        assert vector["result"] == (a > b) - (a < b), vector["name"]
+
+
+# 🥣🔬🔚
@@ -0,0 +1,128 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""Dependency graph golden tests.
+
+`soup-go hcl deps graph` builds the reference graph of testdata/deps/main.hcl,
+an acyclic Terraform-style configuration, and of testdata/deps/cycle.hcl,
+which has a cycle through three locals and a resource that refers to
+itself. Harnesses building graphs should produce the same node and edge
+sets, cycles and order.
+"""
+
+import json
+from pathlib import Path
+
+import pytest
+
+from ..cli_verification.shared_cli_utils import run_harness_cli
+
+TESTDATA = Path(__file__).parent / "testdata" / "deps"
+
+NODES = {
+    "aws_instance.web": "resource",
+    "aws_security_group.web": "resource",
+    "data.aws_ami.ubuntu": "data",
+    "local.name": "local",
+    "local.prefix": "local",
+    "module.vpc": "module",
+    "output.ips": "output",
+    "provider.aws.west": "provider",
+    "var.cidrs": "variable",
+    "var.region": "variable",
+}
+
+EDGES = {
+    ("aws_instance.web", "aws_security_group.web", "depends_on"),
+    ("aws_instance.web", "aws_security_group.web", "reference"),
+    ("aws_instance.web", "data.aws_ami.ubuntu", "reference"),
+    ("aws_instance.web", "local.name", "reference"),
+    ("aws_instance.web", "module.vpc", "reference"),
+    ("aws_instance.web", "provider.aws.west", "provider"),
+    ("aws_security_group.web", "local.prefix", "reference"),
+    ("aws_security_group.web", "module.vpc", "reference"),
+    ("aws_security_group.web", "var.cidrs", "reference"),
+    ("local.name", "var.region", "reference"),
+    ("local.prefix", "local.name", "reference"),
+    ("module.vpc", "provider.aws.west", "provider"),
+    ("module.vpc", "var.cidrs", "reference"),
+    ("output.ips", "aws_instance.web", "reference"),
+    ("provider.aws.west", "var.region", "reference"),
+}
+
+
+def _graph(go_harness_executable: Path, project_root: Path, name: str) -> dict:
+    exit_code, stdout, stderr = run_harness_cli(
+        executable=go_harness_executable,
+        args=["hcl", "deps", "graph", str(TESTDATA / name)],
+        project_root=project_root,
+        harness_artifact_name="soup-go",
+        test_id="hcl_deps_graph",
+    )
+    assert exit_code == 0, f"soup-go hcl deps graph failed: {stderr}"
+    return json.loads(stdout)
+
+
+@pytest.mark.integration_hcl
+@pytest.mark.harness_go
+@pytest.mark.parametrize("go_harness_executable", ["soup-go"], indirect=True)
+def test_go_graph_matches_golden(go_harness_executable: Path, project_root: Path) -> None:
+    graph = _graph(go_harness_executable, project_root, "main.hcl")
+
+    assert graph["success"] and graph["acyclic"]
+    assert {node["address"]: node["kind"] for node in graph["nodes"]} == NODES
+    assert {(edge["from"], edge["to"], edge["kind"]) for edge in graph["edges"]} == EDGES
+    assert graph["order"] == [
+        "data.aws_ami.ubuntu",
+        "var.cidrs",
+        "var.region",
+        "local.name",
+        "local.prefix",
+        "provider.aws.west",
+        "module.vpc",
+        "aws_security_group.web",
+        "aws_instance.web",
+        "output.ips",
+    ]
+
+    # References keep where they were made; a nested block's attribute is
+    # named by its path in the node
+    [ami] = [e for e in graph["edges"] if e["to"] == "data.aws_ami.ubuntu"]
+    assert [(r["attribute"], r["traversal"]) for r in ami["references"]] == [("ami", "data.aws_ami.ubuntu.id")]
+    [name] = [e for e in graph["edges"] if (e["from"], e["to"]) == ("aws_instance.web", "local.name")]
+    assert name["references"][0]["attribute"] == "ebs.volume_size"
+
+    # The dynamic block's iterator is bound; count is Terraform's own
+    unresolved = {(u["from"], u["reference"]["traversal"], u["reason"]) for u in graph["unresolved"]}
+    assert unresolved == {
+        ("aws_instance.web", "count.index", "builtin"),
+        ("aws_instance.web", "var.user_data", "undeclared"),
+    }
+
+
+@pytest.mark.integration_hcl
+@pytest.mark.harness_go
+@pytest.mark.parametrize("go_harness_executable", ["soup-go"], indirect=True)
+def test_go_graph_cycles(go_harness_executable: Path, project_root: Path) -> None:
+    graph = _graph(go_harness_executable, project_root, "cycle.hcl")
+
+    assert not graph["acyclic"]
+    assert graph["cycles"] == [["local.a", "local.b", "local.c"], ["null_resource.loop"]]
+    assert graph["order"] == []
+
+    exit_code, stdout, _ = run_harness_cli(
+        executable=go_harness_executable,
+        args=["hcl", "deps", "graph", str(TESTDATA / "cycle.hcl")]
+        + ["--output-format", "dot", "--fail-on-cycle"],
+        project_root=project_root,
+        harness_artifact_name="soup-go",
+        test_id="hcl_deps_graph_dot",
+    )
+    assert exit_code != 0
+    assert '"local.c" -> "local.a" [color = "red"];' in stdout
+    assert '"null_resource.after" -> "local.a";' in stdout
+
+
+# 🥣🔬🔚
@@ -0,0 +1,84 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""`soup-go generate hcl` fixtures: deterministic, valid, and true to their manifest.
+
+Every harness stress-tests its parser against the same generated bytes, so
+a seed and knobs must always yield the same file, and the manifest's
+statistics must describe it. Harnesses compare their own parse against
+manifest["stats"].
+"""
+
+import hashlib
+import json
+from pathlib import Path
+
+import pytest
+
+from ..cli_verification.shared_cli_utils import run_harness_cli
+
+KNOBS = ["--blocks", "12", "--depth", "4", "--exprs", "for,conditional,heredoc"]
+
+
+def _generate(executable: Path, project_root: Path, out: Path, *args: str) -> dict:
+    exit_code, _, stderr = run_harness_cli(
+        executable=executable,
+        args=["generate", "hcl", "-o", str(out), *args],
+        project_root=project_root,
+        harness_artifact_name="soup-go",
+        test_id=f"generate_hcl_{out.stem}",
+    )
+    assert exit_code == 0, f"soup-go generate hcl failed: {stderr}"
+    return json.loads(Path(f"{out}.manifest.json").read_text())
+
+
+@pytest.mark.integration_hcl
+@pytest.mark.harness_go
+@pytest.mark.parametrize("go_harness_executable", ["soup-go"], indirect=True)
+def test_same_seed_same_bytes(go_harness_executable: Path, project_root: Path, tmp_path: Path) -> None:
+    first = _generate(go_harness_executable, project_root, tmp_path / "a.hcl", *KNOBS, "--seed", "42")
+    again = _generate(go_harness_executable, project_root, tmp_path / "b.hcl", *KNOBS, "--seed", "42")
+    other = _generate(go_harness_executable, project_root, tmp_path / "c.hcl", *KNOBS, "--seed", "43")
+
+    assert (tmp_path / "a.hcl").read_bytes() == (tmp_path / "b.hcl").read_bytes()
+    assert first["sha256"] == again["sha256"] != other["sha256"]
+
+
+@pytest.mark.integration_hcl
+@pytest.mark.harness_go
+@pytest.mark.parametrize("go_harness_executable", ["soup-go"], indirect=True)
+@pytest.mark.parametrize("seed", [1, 7, 1234])
+def test_manifest_describes_output(
+    go_harness_executable: Path, project_root: Path, tmp_path: Path, seed: int
+) -> None:
+    out = tmp_path / f"seed{seed}.hcl"
+    manifest = _generate(go_harness_executable, project_root, out, *KNOBS, "--seed", str(seed))
+    src = out.read_bytes()
+    stats = manifest["stats"]
+
+    assert manifest["file"] == out.name
+    assert manifest["sha256"] == hashlib.sha256(src).hexdigest()
+    assert manifest["bytes"] == len(src)
+    assert stats["top_level_blocks"] == 12
+    assert stats["max_depth"] == 4 == len(stats["blocks_by_depth"])
+    assert sum(stats["blocks_by_depth"]) == stats["blocks"]
+    assert set(stats["expressions"]) == {"literal", "for", "conditional", "heredoc"}
+    assert sum(stats["expressions"].values()) == stats["attributes"]
+    # Textual cross-checks that don't need a parser
+    assert src.count(b"<<-EOT") == stats["expressions"]["heredoc"]
+    assert src.count(b"[for ") + src.count(b"{ for ") == stats["expressions"]["for"]
+    assert b"${" not in src
+
+    exit_code, _, stderr = run_harness_cli(
+        executable=go_harness_executable,
+        args=["hcl", "validate", str(out)],
+        project_root=project_root,
+        harness_artifact_name="soup-go",
+        test_id=f"generate_hcl_validate_{seed}",
+    )
+    assert exit_code == 0, f"generated configuration failed validation: {stderr}"
+
+
+# 🥣🔬🔚
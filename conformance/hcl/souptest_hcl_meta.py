@@ -0,0 +1,120 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""count/for_each expansion golden tests.
+
+`soup-go hcl meta expand` expands the resource, data and module blocks in
+testdata/meta/main.hcl with the variables in testdata/meta/vars.json. The
+instance keys, evaluated bodies and errors below follow Terraform's
+expansion rules. Harnesses building plan-like tooling should match them.
+"""
+
+import json
+from pathlib import Path
+
+import pytest
+
+from ..cli_verification.shared_cli_utils import run_harness_cli
+
+TESTDATA = Path(__file__).parent / "testdata" / "meta"
+
+# address -> (mode, {instance address: {attribute: value}})
+EXPANDED: dict[str, tuple[str, dict[str, dict[str, object]]]] = {
+    "aws_instance.web": (
+        "count",
+        {
+            "aws_instance.web[0]": {"name": "web-0", "tags.zone": "us-east-1a"},
+            "aws_instance.web[1]": {"name": "web-1", "tags.zone": "us-east-1b"},
+        },
+    ),
+    "aws_iam_user.users": (
+        "for_each",
+        {
+            'aws_iam_user.users["alice"]': {"name": "ALICE"},
+            'aws_iam_user.users["bob"]': {"name": "BOB"},
+        },
+    ),
+    "module.buckets": (
+        "for_each",
+        {
+            'module.buckets["assets"]': {"source": "./bucket", "name": "assets-us-east-1"},
+            'module.buckets["logs"]': {"source": "./bucket", "name": "logs-eu-west-1"},
+        },
+    ),
+    "data.aws_ami.ubuntu": ("single", {"data.aws_ami.ubuntu": {"owners": ["canonical"]}}),
+    "null_resource.none": ("count", {}),
+    "null_resource.string_count": (
+        "count",
+        {"null_resource.string_count[0]": {}, "null_resource.string_count[1]": {}},
+    ),
+}
+
+# address -> the start of Terraform's error for the block
+BLOCK_ERRORS = {
+    "null_resource.list": 'Invalid for_each argument: The given "for_each" argument value is unsuitable: '
+    'the "for_each" argument must be a map, or set of strings, and you have provided a value of type tuple.',
+    "null_resource.both": 'Invalid combination of "count" and "for_each"',
+    "null_resource.negative": "Invalid count argument: The given \"count\" argument value is unsuitable: "
+    "must be greater than or equal to zero.",
+    "null_resource.fraction": 'Invalid count argument: The given "count" argument value is unsuitable: '
+    "value must be a whole number",
+}
+
+
+def _expand(go_harness_executable: Path, project_root: Path) -> dict:
+    exit_code, stdout, stderr = run_harness_cli(
+        executable=go_harness_executable,
+        args=["hcl", "meta", "expand", str(TESTDATA / "main.hcl"), "--vars", str(TESTDATA / "vars.json")],
+        project_root=project_root,
+        harness_artifact_name="soup-go",
+        test_id="hcl_meta_expand",
+    )
+    assert exit_code == 0, f"soup-go hcl meta expand failed: {stderr}"
+    return json.loads(stdout)
+
+
+@pytest.mark.integration_hcl
+@pytest.mark.harness_go
+@pytest.mark.parametrize("go_harness_executable", ["soup-go"], indirect=True)
+def test_go_expansion_matches_golden(go_harness_executable: Path, project_root: Path) -> None:
+    blocks = {b["address"]: b for b in _expand(go_harness_executable, project_root)["blocks"]}
+
+    # Only resource, data and module blocks expand
+    assert "variable.ignored" not in blocks
+    for address, (mode, instances) in EXPANDED.items():
+        block = blocks[address]
+        assert (block["mode"], block.get("error")) == (mode, None), address
+        got = {
+            inst["address"]: {attr["name"]: attr["value"] for attr in inst["attributes"]}
+            for inst in block["instances"]
+        }
+        assert got == instances, address
+
+    # Instances are keyed by index or by map key / set element, in order
+    users = blocks["aws_iam_user.users"]["instances"]
+    assert [(i["key"], i["each"]["value"]) for i in users] == [("alice", "alice"), ("bob", "bob")]
+    assert [i["key"] for i in blocks["aws_instance.web"]["instances"]] == [0, 1]
+    assert "key" not in blocks["data.aws_ami.ubuntu"]["instances"][0]
+    # lifecycle is a meta-argument, not part of the body
+    bucket = blocks["module.buckets"]["instances"][0]
+    assert not any(attr["name"].startswith("lifecycle") for attr in bucket["attributes"])
+
+
+@pytest.mark.integration_hcl
+@pytest.mark.harness_go
+@pytest.mark.parametrize("go_harness_executable", ["soup-go"], indirect=True)
+def test_go_expansion_errors_match_terraform(go_harness_executable: Path, project_root: Path) -> None:
+    blocks = {b["address"]: b for b in _expand(go_harness_executable, project_root)["blocks"]}
+
+    for address, error in BLOCK_ERRORS.items():
+        assert blocks[address]["error"].startswith(error), address
+        assert blocks[address]["instances"] == [], address
+
+    (stray,) = blocks["null_resource.stray"]["instances"]
+    (name,) = stray["attributes"]
+    assert name["error"].startswith('Reference to "each" in context without for_each')
+
+
+# 🥣🔬🔚
@@ -0,0 +1,95 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""`soup-go generate hcl-operators`: operator precedence reference vectors.
+
+Every vector is an operator combination, its parse with every operation
+parenthesized and Go's result. The groupings must follow HCL's documented
+precedence table below, operators of equal precedence associating to the
+left, so the vectors can be trusted as the reference for other parsers.
+"""
+
+import json
+from pathlib import Path
+
+import pytest
+
+from ..cli_verification.shared_cli_utils import run_harness_cli
+
+# HCL's binary operators, lowest precedence first
+PRECEDENCE = [["||"], ["&&"], ["==", "!="], ["<", "<=", ">", ">="], ["+", "-"], ["*", "/", "%"]]
+LEVEL = {op: level for level, ops in enumerate(PRECEDENCE) for op in ops}
+
+# name -> (expression, Go's result)
+PINNED = {
+    "add-mul": ("8 + 8 * 8", 72),
+    "sub-sub": ("8 - 8 - 8", -8),
+    "div-div": ("8 / 8 / 8", 0.125),
+    "eq-eq": ("8 == 8 == true", True),
+    "neg-sub": ("-8 - 8", -16),
+    "not-and": ("!true && false", False),
+    "cond-else-add": ("true ? 8 : 8 + 8", 8),
+}
+
+
+def _generate(executable: Path, project_root: Path, *args: str) -> dict:
+    exit_code, stdout, stderr = run_harness_cli(
+        executable=executable,
+        args=["generate", "hcl-operators", *args],
+        project_root=project_root,
+        harness_artifact_name="soup-go",
+        test_id="generate_hcl_operators",
+    )
+    assert exit_code == 0, f"soup-go generate hcl-operators failed: {stderr}"
+    return json.loads(stdout)
+
+
+@pytest.mark.integration_hcl
+@pytest.mark.harness_go
+@pytest.mark.parametrize("go_harness_executable", ["soup-go"], indirect=True)
+def test_operator_vectors_follow_precedence(go_harness_executable: Path, project_root: Path) -> None:
+    vectors = _generate(go_harness_executable, project_root)["vectors"]
+    by_name = {v["name"]: v for v in vectors}
+
+    assert len(by_name) == len(vectors) == 13 * 13 + 2 * 13 + 13 + 13
+    for vector in vectors:
+        name, shape = vector["name"], vector["shape"]
+        if shape == "binary":
+            first, second = vector["operators"]
+            expected = "left" if LEVEL[first] >= LEVEL[second] else "right"
+        elif shape == "unary":
+            expected = "unary-first"
+        else:
+            expected = "binary-first"
+        assert vector["grouping"] == expected, name
+
+        # The parsed grouping's variant evaluates exactly as the expression
+        (chosen,) = [v for v in vector["variants"] if v["grouping"] == vector["grouping"]]
+        assert chosen["parsed"] == vector["parsed"], name
+        assert (chosen.get("result"), chosen.get("error") is None) == (
+            vector.get("result"),
+            vector.get("error") is None,
+        ), name
+
+    for name, (expr, result) in PINNED.items():
+        assert (by_name[name]["expr"], by_name[name].get("result")) == (expr, result), name
+        assert by_name[name]["discriminating"], name
+
+
+@pytest.mark.integration_hcl
+@pytest.mark.harness_go
+@pytest.mark.parametrize("go_harness_executable", ["soup-go"], indirect=True)
+def test_operator_vectors_by_shape(go_harness_executable: Path, project_root: Path) -> None:
+    vectors = _generate(go_harness_executable, project_root, "--shape", "condition,else")["vectors"]
+    assert {v["shape"] for v in vectors} == {"condition", "else"}
+    # A conditional's condition must be bool, so arithmetic there never
+    # evaluates, but the conditional still binds last
+    cond_if_add = next(v for v in vectors if v["name"] == "cond-if-add")
+    assert "error" in cond_if_add
+    assert cond_if_add["parsed"] == "((8 + true) ? 8 : 8)"
+    assert cond_if_add["discriminating"]
+
+
+# 🥣🔬🔚
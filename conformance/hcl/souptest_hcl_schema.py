@@ -0,0 +1,106 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""Spec inference golden tests.
+
+`soup-go hcl schema infer` infers one hcldec spec from testdata/schema/*.hcl.
+The two files disagree in the ways real configs do: attributes and blocks
+only one of them sets, a list that is empty in one, an attribute that is a
+number in one and a string in the other. The spec must decode both files.
+"""
+
+import json
+from pathlib import Path
+
+import pytest
+
+from ..cli_verification.shared_cli_utils import run_harness_cli
+
+TESTDATA = Path(__file__).parent / "testdata" / "schema"
+EXAMPLES = [TESTDATA / "web.hcl", TESTDATA / "db.hcl"]
+
+
+def _body(attributes: dict, blocks: dict | None = None) -> dict:
+    body: dict = {"attributes": attributes}
+    if blocks:
+        body["blocks"] = blocks
+    return body
+
+
+EXPECTED_SPEC = _body(
+    {
+        "port": {"type": "number"},
+        "region": {"type": "string"},
+        "zones": {"type": ["list", "string"], "required": True},
+    },
+    {
+        "resource": {
+            "nesting": "map",
+            "labels": ["label1", "label2"],
+            "body": _body(
+                {
+                    # 2 in one file, "3" in the other
+                    "count": {"type": "string", "required": True},
+                    "image": {"type": "string", "required": True},
+                    "meta": {"type": ["object", {"owner": "string", "replicas": "number"}]},
+                    "tags": {"type": ["map", "string"], "required": True},
+                },
+                {
+                    "disk": {"nesting": "list", "body": _body({"size": {"type": "number", "required": True}})},
+                    "lifecycle": {
+                        "nesting": "single",
+                        "required": True,
+                        "body": _body({"create_before_destroy": {"type": "bool", "required": True}}),
+                    },
+                },
+            ),
+        }
+    },
+)
+
+
+@pytest.mark.integration_hcl
+@pytest.mark.harness_go
+@pytest.mark.parametrize("go_harness_executable", ["soup-go"], indirect=True)
+def test_go_inferred_spec_matches_golden(go_harness_executable: Path, project_root: Path) -> None:
+    exit_code, stdout, stderr = run_harness_cli(
+        executable=go_harness_executable,
+        args=["hcl", "schema", "infer", *map(str, EXAMPLES), "--enable-feature", "hcl-schema"],
+        project_root=project_root,
+        harness_artifact_name="soup-go",
+        test_id="hcl_schema_infer",
+    )
+    assert exit_code == 0, f"soup-go hcl schema infer failed: {stderr}"
+    assert json.loads(stdout) == EXPECTED_SPEC
+
+
+@pytest.mark.integration_hcl
+@pytest.mark.harness_go
+@pytest.mark.parametrize("go_harness_executable", ["soup-go"], indirect=True)
+@pytest.mark.parametrize("example", EXAMPLES, ids=lambda p: p.stem)
+def test_go_inferred_spec_decodes_examples(
+    go_harness_executable: Path, project_root: Path, tmp_path: Path, example: Path
+) -> None:
+    spec = tmp_path / "spec.json"
+    spec.write_text(json.dumps(EXPECTED_SPEC))
+    exit_code, stdout, stderr = run_harness_cli(
+        executable=go_harness_executable,
+        args=["hcl", "decode", str(example), "--spec", str(spec), "--enable-feature", "hcl-schema"],
+        project_root=project_root,
+        harness_artifact_name="soup-go",
+        test_id=f"hcl_decode_{example.stem}",
+    )
+    assert exit_code == 0, f"soup-go hcl decode failed: {stderr}"
+    result = json.loads(stdout)
+
+    assert result["success"]
+    (name, instance), *_ = result["value"]["resource"]["instance"].items()
+    assert name == example.stem
+    # Absent attributes decode as null, absent list blocks as empty lists
+    assert set(result["value"]) == {"port", "region", "resource", "zones"}
+    assert isinstance(instance["disk"], list)
+
+
+# 🥣🔬🔚
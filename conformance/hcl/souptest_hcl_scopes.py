@@ -0,0 +1,142 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""Evaluation context scoping golden tests.
+
+`soup-go hcl eval --scopes` evaluates testdata/scopes/main.hcl in each scope
+of testdata/scopes/scopes.json: a root, a child shadowing `region` and adding
+`lower`, and a grandchild shadowing `env` and `upper`. The expected values
+and resolutions below follow HCL's EvalContext rules. Harnesses that
+implement nested contexts should match them.
+"""
+
+import json
+from pathlib import Path
+
+import pytest
+
+from ..cli_verification.shared_cli_utils import run_harness_cli
+
+TESTDATA = Path(__file__).parent / "testdata" / "scopes"
+
+# scope -> attribute -> (value, {reference or function: resolved_from}); a
+# value of None means evaluation must fail
+EXPECTED: dict[str, dict[str, tuple[object, dict[str, str]]]] = {
+    "root": {
+        "name": ("app-prod", {"env": "root"}),
+        "where": ("US-EAST-1", {"region": "root", "upper": "root"}),
+        "small": (None, {"region": "root", "lower": ""}),
+        "count": (2, {"zones": "root", "length": "root"}),
+        "settings.db.host": ("us-east-1.db", {"region": "root"}),
+    },
+    "root/module": {
+        "name": ("app-prod", {"env": "root"}),
+        "where": ("EU-WEST-1", {"region": "root/module", "upper": "root"}),
+        "small": ("eu-west-1", {"region": "root/module", "lower": "root/module"}),
+        "count": (2, {"zones": "root", "length": "root"}),
+        "settings.db.host": ("eu-west-1.db", {"region": "root/module"}),
+    },
+    "root/module/nested": {
+        "name": ("app-dev", {"env": "root/module/nested"}),
+        "where": ("EU-WEST-1", {"region": "root/module", "upper": "root/module/nested"}),
+        "small": ("eu-west-1", {"region": "root/module", "lower": "root/module"}),
+        "count": (2, {"zones": "root", "length": "root"}),
+        "settings.db.host": ("eu-west-1.db", {"region": "root/module"}),
+    },
+}
+
+
+@pytest.mark.integration_hcl
+@pytest.mark.harness_go
+@pytest.mark.parametrize("go_harness_executable", ["soup-go"], indirect=True)
+def test_go_scope_resolution_matches_golden(go_harness_executable: Path, project_root: Path) -> None:
+    exit_code, stdout, stderr = run_harness_cli(
+        executable=go_harness_executable,
+        args=["hcl", "eval", str(TESTDATA / "main.hcl"), "--scopes", str(TESTDATA / "scopes.json")],
+        project_root=project_root,
+        harness_artifact_name="soup-go",
+        test_id="hcl_eval_scopes",
+    )
+    assert exit_code == 0, f"soup-go hcl eval failed: {stderr}"
+    report = json.loads(stdout)
+
+    assert [s["scope"] for s in report["scopes"]] == list(EXPECTED)
+    for scope in report["scopes"]:
+        for attr in scope["attributes"]:
+            value, resolutions = EXPECTED[scope["scope"]][attr["name"]]
+            where = f"{scope['scope']} {attr['name']}"
+            if value is None:
+                assert attr.get("error"), f"{where}: expected an evaluation error"
+            else:
+                assert attr["value"] == value, where
+            got = {r["name"]: r["resolved_from"] for r in attr.get("references", []) + attr.get("functions", [])}
+            assert got == resolutions, where
+
+
+@pytest.mark.integration_hcl
+@pytest.mark.harness_go
+@pytest.mark.parametrize("go_harness_executable", ["soup-go"], indirect=True)
+def test_go_reports_shadowed_scopes(go_harness_executable: Path, project_root: Path) -> None:
+    exit_code, stdout, stderr = run_harness_cli(
+        executable=go_harness_executable,
+        args=[
+            "hcl",
+            "eval",
+            str(TESTDATA / "main.hcl"),
+            "--scopes",
+            str(TESTDATA / "scopes.json"),
+            "--scope",
+            "root/module/nested",
+        ],
+        project_root=project_root,
+        harness_artifact_name="soup-go",
+        test_id="hcl_eval_scopes_nested",
+    )
+    assert exit_code == 0, f"soup-go hcl eval --scope failed: {stderr}"
+    (nested,) = json.loads(stdout)["scopes"]
+    where = next(a for a in nested["attributes"] if a["name"] == "where")
+    shadows = {r["name"]: r.get("shadows", []) for r in where["references"] + where["functions"]}
+    assert shadows == {"region": ["root"], "upper": ["root"]}
+
+
+@pytest.mark.integration_hcl
+@pytest.mark.harness_go
+@pytest.mark.parametrize("go_harness_executable", ["soup-go"], indirect=True)
+def test_go_traces_function_calls(go_harness_executable: Path, project_root: Path, tmp_path: Path) -> None:
+    trace = tmp_path / "calls.ndjson"
+    exit_code, _, stderr = run_harness_cli(
+        executable=go_harness_executable,
+        args=[
+            "hcl",
+            "eval",
+            str(TESTDATA / "main.hcl"),
+            "--scopes",
+            str(TESTDATA / "scopes.json"),
+            "--scope",
+            "root/module",
+            "--trace-calls",
+            str(trace),
+        ],
+        project_root=project_root,
+        harness_artifact_name="soup-go",
+        test_id="hcl_eval_trace_calls",
+    )
+    assert exit_code == 0, f"soup-go hcl eval --trace-calls failed: {stderr}"
+    calls = [json.loads(line) for line in trace.read_text().splitlines()]
+
+    assert [c["seq"] for c in calls] == list(range(1, len(calls) + 1))
+    got = [
+        (c["attribute"], c["function"], [a["value"] for a in c["args"]], c["result"]["value"])
+        for c in calls
+    ]
+    assert got == [
+        ("where", "upper", ["eu-west-1"], "EU-WEST-1"),
+        ("small", "lower", ["eu-west-1"], "eu-west-1"),
+        ("count", "length", [["a", "b"]], 2),
+    ]
+    assert all(c["scope"] == "root/module" and c["duration_ms"] >= 0 for c in calls)
+
+
+# 🥣🔬🔚
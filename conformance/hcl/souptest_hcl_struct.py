@@ -0,0 +1,140 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""gohcl tag-driven decoding golden tests.
+
+`soup-go hcl decode-struct` decodes testdata/struct/app.hcl into the structs
+declared in testdata/struct/schema.go and reports how gohcl mapped each
+field. The structs cover the cases decoders disagree on: pointer attributes
+that are optional without ",optional", expressions that are never required,
+block cardinality by field type, labels and remain bodies.
+"""
+
+import json
+from pathlib import Path
+
+import pytest
+
+from ..cli_verification.shared_cli_utils import run_harness_cli
+
+TESTDATA = Path(__file__).parent / "testdata" / "struct"
+
+# kind, required and cty_type for every field of Config, by Go field name
+EXPECTED_CONFIG = {
+    "Name": ("attr", True, "string"),
+    "Region": ("attr", False, "string"),
+    "Replicas": ("optional", False, "number"),
+    "Tags": ("optional", False, ["map", "string"]),
+    "Extra": ("optional", False, "dynamic"),
+    "Check": ("attr", False, None),
+    "Services": ("block", False, None),
+    "Backend": ("block", False, None),
+    "Notes": ("ignored", False, None),
+}
+
+EXPECTED_VALUE = {
+    "Name": "app",
+    "Region": None,
+    # "3" converted to the field's number type
+    "Replicas": 3,
+    "Tags": {"team": "core"},
+    "Extra": [1, "two"],
+    "Services": [
+        {"Name": "web", "Image": "nginx", "Ports": [80, 443], "Rest": {"attributes": ["healthy"]}},
+        {"Name": "worker", "Image": "busybox", "Ports": None, "Rest": {"attributes": []}},
+    ],
+    "Backend": None,
+}
+
+
+def _decode_struct(executable: Path, project_root: Path, test_id: str, *args: str) -> tuple[int, dict, str]:
+    exit_code, stdout, stderr = run_harness_cli(
+        executable=executable,
+        args=["hcl", "decode-struct", *args],
+        project_root=project_root,
+        harness_artifact_name="soup-go",
+        test_id=test_id,
+    )
+    return exit_code, json.loads(stdout) if stdout.strip() else {}, stderr
+
+
+@pytest.mark.integration_hcl
+@pytest.mark.harness_go
+@pytest.mark.parametrize("go_harness_executable", ["soup-go"], indirect=True)
+def test_go_decode_struct_matches_golden(go_harness_executable: Path, project_root: Path) -> None:
+    exit_code, result, stderr = _decode_struct(
+        go_harness_executable,
+        project_root,
+        "hcl_decode_struct",
+        str(TESTDATA / "app.hcl"),
+        "--schema-from-tags",
+        str(TESTDATA / "schema.go"),
+    )
+    assert exit_code == 0, f"soup-go hcl decode-struct failed: {stderr}"
+
+    assert result["root"] == "Config"
+    assert set(result["types"]) == {"Config", "Service", "Backend"}
+    config = {f["field"]: (f["kind"], f["required"], f.get("cty_type")) for f in result["types"]["Config"]}
+    assert config == EXPECTED_CONFIG
+
+    value = result["value"]
+    # An absent hcl.Expression is a null expression, not a missing field
+    assert "expression" in value.pop("Check")
+    assert value == EXPECTED_VALUE
+
+
+@pytest.mark.integration_hcl
+@pytest.mark.harness_go
+@pytest.mark.parametrize("go_harness_executable", ["soup-go"], indirect=True)
+def test_go_decode_struct_description_round_trips(
+    go_harness_executable: Path, project_root: Path, tmp_path: Path
+) -> None:
+    app, schema = str(TESTDATA / "app.hcl"), str(TESTDATA / "schema.go")
+    exit_code, description, stderr = _decode_struct(
+        go_harness_executable,
+        project_root,
+        "hcl_decode_struct_describe",
+        "--schema-from-tags",
+        schema,
+        "--describe",
+    )
+    assert exit_code == 0, f"soup-go hcl decode-struct --describe failed: {stderr}"
+    assert description["types"]["Service"][-1] == {"name": "Rest", "type": "hcl.Body", "tag": ",remain"}
+
+    structs = tmp_path / "structs.json"
+    structs.write_text(json.dumps(description))
+    from_tags = _decode_struct(
+        go_harness_executable, project_root, "hcl_decode_struct_tags", app, "--schema-from-tags", schema
+    )
+    from_json = _decode_struct(
+        go_harness_executable, project_root, "hcl_decode_struct_json", app, "--struct", str(structs)
+    )
+    assert from_json[0] == 0, from_json[2]
+    assert from_json[1] == from_tags[1]
+
+
+@pytest.mark.integration_hcl
+@pytest.mark.harness_go
+@pytest.mark.parametrize("go_harness_executable", ["soup-go"], indirect=True)
+def test_go_decode_struct_reports_missing_required(
+    go_harness_executable: Path, project_root: Path, tmp_path: Path
+) -> None:
+    config = tmp_path / "missing.hcl"
+    config.write_text('name = "app"\nservice "web" {}\n')
+    exit_code, result, _ = _decode_struct(
+        go_harness_executable,
+        project_root,
+        "hcl_decode_struct_missing",
+        str(config),
+        "--schema-from-tags",
+        str(TESTDATA / "schema.go"),
+    )
+    assert exit_code != 0
+    assert not result["success"]
+    assert [e["summary"] for e in result["errors"]] == ["Missing required argument"]
+    assert '"image"' in result["errors"][0]["detail"]
+
+
+# 🥣🔬🔚
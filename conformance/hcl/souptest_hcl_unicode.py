@@ -0,0 +1,54 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""Unicode escapes and normalization in HCL string literals.
+
+`soup-go hcl unicode` writes each case of the shared unicode suite as an
+HCL literal with escapes and as raw UTF-8. HCL has no surrogate pairs, so
+unpaired surrogates are escape errors, and every other case must evaluate
+to the same NFC-normalized value both ways.
+"""
+
+import json
+from pathlib import Path
+
+import pytest
+
+from tofusoup.common.unicode_probe import UNICODE_PROBE_SUITE, ascii_escape, hcl_escaped_source
+
+from ..cli_verification.shared_cli_utils import run_harness_cli
+
+LONE_SURROGATES = {"lone-high-surrogate", "lone-low-surrogate"}
+NORMALIZED = {"nfd-e-acute", "nfd-hangul", "stacked-marks"}
+
+
+@pytest.mark.integration_hcl
+@pytest.mark.harness_go
+@pytest.mark.parametrize("go_harness_executable", ["soup-go"], indirect=True)
+def test_go_hcl_unicode_literals(go_harness_executable: Path, project_root: Path) -> None:
+    exit_code, stdout, stderr = run_harness_cli(
+        executable=go_harness_executable,
+        args=["hcl", "unicode"],
+        project_root=project_root,
+        harness_artifact_name="soup-go",
+        test_id="hcl_unicode",
+    )
+    assert exit_code == 0, f"soup-go hcl unicode failed: {stderr}"
+    cases = json.loads(stdout)["cases"]
+
+    assert [c["name"] for c in cases] == [name for name, _, _ in UNICODE_PROBE_SUITE]
+    for case in cases:
+        hcl = case["hcl"]
+        assert hcl["escaped"]["source"] == ascii_escape(hcl_escaped_source(case["input"])), case["name"]
+        if case["name"] in LONE_SURROGATES:
+            assert "Invalid escape sequence" in hcl["escaped"]["error"]
+            assert not hcl["forms_match"]
+        else:
+            assert hcl["forms_match"], case["name"]
+        assert hcl["normalized"] == (case["name"] in NORMALIZED), case["name"]
+        assert "error" not in hcl, case["name"]
+
+
+# 🥣🔬🔚
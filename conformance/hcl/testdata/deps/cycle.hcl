@@ -0,0 +1,17 @@
+locals {
+  a = local.b
+  b = "${local.c}-b"
+  c = local.a
+}
+
+resource "null_resource" "loop" {
+  triggers = {
+    id = null_resource.loop.id
+  }
+}
+
+resource "null_resource" "after" {
+  triggers = {
+    a = local.a
+  }
+}
@@ -0,0 +1,60 @@
+variable "region" {}
+
+variable "cidrs" {
+  default = ["10.0.0.0/16"]
+}
+
+locals {
+  name   = "app-${var.region}"
+  prefix = upper(local.name)
+}
+
+provider "aws" {
+  alias  = "west"
+  region = var.region
+}
+
+data "aws_ami" "ubuntu" {
+  owners = ["canonical"]
+}
+
+module "vpc" {
+  source = "./vpc"
+  cidrs  = var.cidrs
+
+  providers = {
+    aws = aws.west
+  }
+}
+
+resource "aws_security_group" "web" {
+  name   = local.prefix
+  vpc_id = module.vpc.vpc_id
+
+  dynamic "ingress" {
+    for_each = var.cidrs
+    iterator = rule
+    content {
+      cidr_blocks = [rule.value]
+    }
+  }
+}
+
+resource "aws_instance" "web" {
+  count           = 2
+  provider        = aws.west
+  ami             = data.aws_ami.ubuntu.id
+  subnet_id       = module.vpc.subnet_ids[count.index]
+  security_groups = [for sg in [aws_security_group.web] : sg.id]
+  user_data       = var.user_data
+
+  ebs {
+    volume_size = local.name == "" ? 8 : 16
+  }
+
+  depends_on = [aws_security_group.web]
+}
+
+output "ips" {
+  value = aws_instance.web[*].private_ip
+}
@@ -0,0 +1,60 @@
+resource "aws_instance" "web" {
+  count = var.web_count
+  name  = "web-${count.index}"
+
+  tags {
+    zone = var.zones[count.index]
+  }
+}
+
+resource "aws_iam_user" "users" {
+  for_each = toset(var.users)
+  name     = upper(each.key)
+}
+
+module "buckets" {
+  for_each = var.buckets
+  source   = "./bucket"
+  name     = "${each.key}-${each.value.region}"
+
+  lifecycle {
+    prevent_destroy = true
+  }
+}
+
+data "aws_ami" "ubuntu" {
+  owners = ["canonical"]
+}
+
+resource "null_resource" "none" {
+  count = 0
+}
+
+resource "null_resource" "list" {
+  for_each = var.users
+}
+
+resource "null_resource" "both" {
+  count    = 1
+  for_each = {}
+}
+
+resource "null_resource" "negative" {
+  count = -1
+}
+
+resource "null_resource" "fraction" {
+  count = 1.5
+}
+
+resource "null_resource" "string_count" {
+  count = "2"
+}
+
+resource "null_resource" "stray" {
+  name = each.key
+}
+
+variable "ignored" {
+  count = 2
+}
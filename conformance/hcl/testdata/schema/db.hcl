@@ -0,0 +1,13 @@
+zones = []
+port  = 80
+
+resource "instance" "db" {
+  image = "ami-2"
+  count = "3"
+  tags  = {}
+  meta  = { owner = "ops", replicas = 1 }
+
+  lifecycle {
+    create_before_destroy = false
+  }
+}
@@ -0,0 +1,19 @@
+region = "us-east-1"
+zones  = ["a", "b"]
+
+resource "instance" "web" {
+  image = "ami-1"
+  count = 2
+  tags  = { Name = "web" }
+
+  disk {
+    size = 10
+  }
+  disk {
+    size = 20
+  }
+
+  lifecycle {
+    create_before_destroy = true
+  }
+}
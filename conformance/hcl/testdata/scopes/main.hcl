@@ -0,0 +1,8 @@
+name   = "app-${env}"
+where  = upper(region)
+small  = lower(region)
+count  = length(zones)
+
+settings "db" {
+  host = "${region}.db"
+}
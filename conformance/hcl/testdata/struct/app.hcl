@@ -0,0 +1,14 @@
+name     = "app"
+replicas = "3"
+tags     = { team = "core" }
+extra    = [1, "two"]
+
+service "web" {
+  image   = "nginx"
+  ports   = [80, 443]
+  healthy = true
+}
+
+service "worker" {
+  image = "busybox"
+}
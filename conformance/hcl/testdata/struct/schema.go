@@ -0,0 +1,27 @@
+// Structs for souptest_hcl_struct.py, read by soup-go hcl decode-struct
+// --schema-from-tags without being compiled.
+package config
+
+type Config struct {
+	Name     string            `hcl:"name"`
+	Region   *string           `hcl:"region"`
+	Replicas int               `hcl:"replicas,optional"`
+	Tags     map[string]string `hcl:"tags,optional"`
+	Extra    cty.Value         `hcl:"extra,optional"`
+	Check    hcl.Expression    `hcl:"check"`
+	Services []Service         `hcl:"service,block"`
+	Backend  *Backend          `hcl:"backend,block"`
+	Notes    string
+}
+
+type Service struct {
+	Name  string   `hcl:"name,label"`
+	Image string   `hcl:"image"`
+	Ports []int    `hcl:"ports,optional"`
+	Rest  hcl.Body `hcl:",remain"`
+}
+
+type Backend struct {
+	Kind string `hcl:"kind,label"`
+	Path string `hcl:"path"`
+}
@@ -11,6 +11,8 @@ matrix parameters. Handles certificate configuration and process management."""
 import asyncio
 from collections.abc import AsyncGenerator
 from contextlib import asynccontextmanager
+import functools
+import json
 import os
 from pathlib import Path
 import subprocess
@@ -443,6 +445,43 @@ async def create_kv_client(
         yield client
 
 
+@functools.cache
+def transport_support(language: str, modes: tuple[str, ...] = ("tcp", "unix")) -> dict[str, str]:
+    """Transport statuses (supported/unsupported/failed) a harness reports
+    from `rpc validate transport`, keyed by transport. Cached per session."""
+    if language == "go":
+        project_root = Path(__file__).parent.parent.parent
+        config = load_tofusoup_config(project_root)
+        command = [str(ensure_go_harness_build("soup-go", project_root, config))]
+    elif language == "pyvider":
+        import shutil
+
+        soup_path = shutil.which("soup")
+        if not soup_path:
+            raise RuntimeError("soup command not found in PATH. Please ensure TofuSoup is properly installed.")
+        command = [soup_path]
+    else:
+        raise ValueError(f"Unsupported language: {language}")
+
+    command += ["rpc", "validate", "transport", "--modes", ",".join(modes), "--output-format", "json"]
+    # Exits non-zero when any transport is unsupported; the report is still printed
+    result = subprocess.run(command, capture_output=True, text=True, timeout=60)
+    try:
+        report = json.loads(result.stdout)
+    except json.JSONDecodeError as e:
+        raise RuntimeError(f"{language} harness gave no transport report: {result.stderr.strip()}") from e
+    return {entry["transport"]: entry["status"] for entry in report["transports"]}
+
+
+def skip_unless_transport_supported(language: str, transport: str) -> None:
+    """Skip the current test if a harness can't serve or connect over transport."""
+    import pytest
+
+    status = transport_support(language).get(transport, "unknown")
+    if status != "supported":
+        pytest.skip(f"{language} harness reports transport {transport} as {status}")
+
+
 def get_factory_info() -> dict[str, Any]:
     """Get information about supported factory configurations."""
     return {
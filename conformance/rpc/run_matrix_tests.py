@@ -5,11 +5,76 @@
 
 """RPC K/V Matrix Test Runner
 
-Convenience script for running the RPC K/V matrix tests with various configurations."""
+Convenience script for running the RPC K/V matrix tests with various configurations.
 
+Each collected combination runs in its own pytest process, up to ``--jobs`` at a
+time. Every job gets an isolated environment (its own plugin port range, socket
+and temp directories, K/V storage and pytest basetemp) and writes its output to
+its own log file, so combinations can run side by side without sharing a port,
+socket or storage file."""
+
+import argparse
+import asyncio
+from dataclasses import asdict, dataclass
+import json
+import os
 from pathlib import Path
+import re
 import subprocess
 import sys
+import tempfile
+import time
+
+from tofusoup.config.defaults import MATRIX_PARALLEL_JOBS, MATRIX_TIMEOUT_MINUTES
+
+# Each job slot gets its own go-plugin port range, starting here
+JOB_PORT_BASE = 30000
+JOB_PORT_SPAN = 200
+
+# Relative cost of a combination, used to start the slowest ones first so a
+# single RSA-4096 job isn't left running alone at the end of the run
+CRYPTO_COSTS = {"rsa_4096": 4, "rsa_2048": 2, "ec_521": 2}
+SERVER_COSTS = {"python": 2, "pyvider": 2}
+
+TEST_CONFIGS = {
+    "quick": {
+        "description": "Quick Matrix Test (5 combinations + crypto validation)",
+        "args": ["--quick-matrix", "-v"],
+    },
+    "crypto": {"description": "Crypto Validation Only", "args": ["--crypto-only", "-v"]},
+    "go-go": {
+        "description": "Go Client → Go Server (all crypto configs)",
+        "args": ["--language-pair", "go-go", "-v"],
+    },
+    "pyvider-pyvider": {
+        "description": "Python Client → Python Server (all crypto configs)",
+        "args": ["--language-pair", "pyvider-pyvider", "-v"],
+    },
+    "go-pyvider": {
+        "description": "Go Client → Python Server (all crypto configs)",
+        "args": ["--language-pair", "go-pyvider", "-v"],
+    },
+    "pyvider-go": {
+        "description": "Python Client → Go Server (all crypto configs)",
+        "args": ["--language-pair", "pyvider-go", "-v"],
+    },
+    "full": {"description": "Full Matrix Test (all 20 combinations)", "args": ["-v"]},
+    "basic": {
+        "description": "Basic Operations Only",
+        "args": ["-k", "test_rpc_kv_basic_operations", "-v"],
+    },
+}
+
+
+@dataclass
+class JobResult:
+    """Outcome of running one combination."""
+
+    node_id: str
+    status: str  # "passed", "failed", "timeout" or "cancelled"
+    duration_seconds: float
+    log_file: Path
+    returncode: int | None = None
 
 
 def run_command(cmd: list[str], description: str) -> bool:
@@ -30,70 +95,292 @@ def run_command(cmd: list[str], description: str) -> bool:
     return result.returncode == 0
 
 
+def collect_node_ids(test_file: str, pytest_args: list[str], rootdir: Path) -> list[str]:
+    """Collect the test node IDs the given pytest arguments select, relative to rootdir."""
+    # -v would switch --collect-only from node IDs to the collection tree
+    args = [arg for arg in pytest_args if arg not in ("-v", "--verbose")]
+    cmd = [sys.executable, "-m", "pytest", test_file, *args, "--collect-only", "-q", "-p", "no:cacheprovider"]
+    result = subprocess.run(cmd, cwd=rootdir, capture_output=True, text=True)
+    if result.returncode not in (0, 5):  # 5: nothing collected
+        print(result.stdout)
+        print(result.stderr, file=sys.stderr)
+        raise RuntimeError(f"test collection failed with exit code {result.returncode}")
+    return [line.strip() for line in result.stdout.splitlines() if "::" in line]
+
+
+def combination_cost(node_id: str) -> int:
+    """Estimate how expensive a combination is to run, from its parameter ID."""
+    params = node_id.rpartition("[")[2]
+    cost = 1
+    for name, weight in CRYPTO_COSTS.items():
+        if name in params:
+            cost *= weight
+    for name, weight in SERVER_COSTS.items():
+        if name in params:
+            cost *= weight
+            break
+    return cost
+
+
+def job_log_name(node_id: str) -> str:
+    """Turn a node ID into a file name for its log."""
+    name = node_id.rpartition("/")[2]
+    return re.sub(r"[^A-Za-z0-9_.-]+", "_", name).strip("_") + ".log"
+
+
+def job_environment(slot: int, job_dir: Path) -> dict[str, str]:
+    """Build the isolated environment for a job running in the given slot."""
+    tmp_dir = job_dir / "tmp"
+    socket_dir = job_dir / "sockets"
+    storage_dir = job_dir / "kv-storage"
+    for path in (tmp_dir, socket_dir, storage_dir):
+        path.mkdir(parents=True, exist_ok=True)
+
+    min_port = JOB_PORT_BASE + slot * JOB_PORT_SPAN
+    env = dict(os.environ)
+    env.update(
+        {
+            "PLUGIN_MIN_PORT": str(min_port),
+            "PLUGIN_MAX_PORT": str(min_port + JOB_PORT_SPAN - 1),
+            "PLUGIN_UNIX_SOCKET_DIR": str(socket_dir),
+            "TMPDIR": str(tmp_dir),
+            "KV_STORAGE_DIR": str(storage_dir),
+            "MATRIX_JOB_SLOT": str(slot),
+        }
+    )
+    return env
+
+
+def prebuild_go_harness(project_root: Path) -> None:
+    """Build soup-go once up front so parallel jobs don't race to build it."""
+    try:
+        from tofusoup.common.config import load_tofusoup_config
+        from tofusoup.harness.logic import ensure_go_harness_build
+
+        config = load_tofusoup_config(project_root)
+        path = ensure_go_harness_build("soup-go", project_root, config)
+        print(f"🔧 Go harness ready: {path}")
+    except Exception as e:
+        print(f"⚠️  Could not pre-build the Go harness, jobs will build it themselves: {e}")
+
+
+async def run_matrix_parallel(
+    rootdir: Path,
+    node_ids: list[str],
+    pytest_args: list[str],
+    jobs: int,
+    fail_fast: bool,
+    log_dir: Path,
+    timeout_seconds: float,
+) -> list[JobResult]:
+    """Run each node ID in its own pytest process, at most `jobs` at a time."""
+    slots: asyncio.Queue[int] = asyncio.Queue()
+    for slot in range(jobs):
+        slots.put_nowait(slot)
+    stop = asyncio.Event()
+    running: set[asyncio.subprocess.Process] = set()
+
+    async def run_one(index: int, node_id: str) -> JobResult:
+        log_file = log_dir / job_log_name(node_id)
+        slot = await slots.get()
+        try:
+            if stop.is_set():
+                return JobResult(node_id, "cancelled", 0.0, log_file)
+
+            job_dir = log_dir / "jobs" / f"{index:03d}"
+            env = job_environment(slot, job_dir)
+            cmd = [
+                sys.executable,
+                "-m",
+                "pytest",
+                node_id,
+                *pytest_args,
+                "--basetemp",
+                str(job_dir / "pytest"),
+                "-p",
+                "no:cacheprovider",
+            ]
+
+            start = time.monotonic()
+            with log_file.open("w") as log:
+                ports = f"{env['PLUGIN_MIN_PORT']}-{env['PLUGIN_MAX_PORT']}"
+                log.write(f"# {' '.join(cmd)}\n# slot {slot}, ports {ports}\n\n")
+                log.flush()
+                process = await asyncio.create_subprocess_exec(
+                    *cmd, cwd=rootdir, env=env, stdout=log, stderr=subprocess.STDOUT
+                )
+                running.add(process)
+                try:
+                    await asyncio.wait_for(process.wait(), timeout=timeout_seconds)
+                    status = "passed" if process.returncode == 0 else "failed"
+                except asyncio.TimeoutError:
+                    process.kill()
+                    await process.wait()
+                    status = "timeout"
+                finally:
+                    running.discard(process)
+            duration = time.monotonic() - start
+
+            if stop.is_set() and status != "passed":
+                status = "cancelled"
+            result = JobResult(node_id, status, duration, log_file, process.returncode)
+
+            icon = {"passed": "✅", "cancelled": "⏹️ "}.get(status, "❌")
+            print(f"{icon} [{slot}] {node_id} ({duration:.1f}s) → {log_file.name}")
+
+            if status != "passed" and fail_fast and not stop.is_set():
+                stop.set()
+                for other in running:
+                    other.terminate()
+            return result
+        finally:
+            slots.put_nowait(slot)
+
+    # Slowest combinations first; ties keep collection order
+    order = sorted(range(len(node_ids)), key=lambda i: -combination_cost(node_ids[i]))
+    results = await asyncio.gather(*(run_one(i, node_ids[i]) for i in order))
+    by_node = {r.node_id: r for r in results}
+    return [by_node[node_id] for node_id in node_ids]
+
+
+def print_summary(results: list[JobResult], wall_seconds: float, log_dir: Path) -> None:
+    """Print per-status counts, failures and timing for a parallel run."""
+    counts: dict[str, int] = {}
+    for result in results:
+        counts[result.status] = counts.get(result.status, 0) + 1
+    serial_seconds = sum(r.duration_seconds for r in results)
+
+    print(f"\n{'=' * 60}")
+    print("📊 Matrix summary")
+    print(f"{'=' * 60}")
+    print("  " + ", ".join(f"{status}: {count}" for status, count in sorted(counts.items())))
+    print(f"  wall time {wall_seconds:.1f}s, combined job time {serial_seconds:.1f}s")
+    for result in results:
+        if result.status in ("failed", "timeout"):
+            print(f"  ❌ {result.node_id} ({result.status}) → {result.log_file}")
+    print(f"  Logs: {log_dir}")
+
+
+def write_results(results: list[JobResult], started: float, wall_seconds: float, log_dir: Path) -> Path:
+    """Write the run's results to results.json in the log directory.
+
+    The file can be recorded into a results database with `soup harness report`."""
+    path = log_dir / "results.json"
+    document = {
+        "kind": "rpc-matrix",
+        "created": started,
+        "duration": wall_seconds,
+        "results": [{**asdict(r), "log_file": str(r.log_file)} for r in results],
+    }
+    path.write_text(json.dumps(document, indent=2))
+    return path
+
+
+def parse_args(argv: list[str]) -> tuple[argparse.Namespace, list[str]]:
+    """Parse runner options; anything unrecognised is passed through to pytest."""
+    parser = argparse.ArgumentParser(
+        description="🍲 TofuSoup RPC K/V Matrix Test Runner",
+        epilog="Available configurations:\n"
+        + "\n".join(f"  {name:15} - {config['description']}" for name, config in TEST_CONFIGS.items()),
+        formatter_class=argparse.RawDescriptionHelpFormatter,
+    )
+    parser.add_argument("config", choices=TEST_CONFIGS.keys(), help="Test configuration to run")
+    parser.add_argument(
+        "-j",
+        "--jobs",
+        type=int,
+        default=MATRIX_PARALLEL_JOBS,
+        help=f"Combinations to run at once (default {MATRIX_PARALLEL_JOBS}; 1 runs a single serial pytest)",
+    )
+    mode = parser.add_mutually_exclusive_group()
+    mode.add_argument(
+        "--fail-fast",
+        dest="fail_fast",
+        action="store_true",
+        help="Stop scheduling and terminate running jobs after the first failure",
+    )
+    mode.add_argument(
+        "--continue",
+        dest="fail_fast",
+        action="store_false",
+        help="Run every combination regardless of failures (default)",
+    )
+    parser.add_argument(
+        "--log-dir",
+        type=Path,
+        help="Directory for per-job log files (default: a new temporary directory)",
+    )
+    parser.add_argument(
+        "--job-timeout",
+        type=float,
+        default=MATRIX_TIMEOUT_MINUTES * 60,
+        help=f"Seconds before a single job is killed (default {MATRIX_TIMEOUT_MINUTES * 60})",
+    )
+    return parser.parse_known_args(argv)
+
+
 def main() -> None:
     """Main test runner."""
 
     # Get script directory
     script_dir = Path(__file__).parent
 
-    # Available test configurations
-    test_configs = {
-        "quick": {
-            "description": "Quick Matrix Test (5 combinations + crypto validation)",
-            "args": ["--quick-matrix", "-v"],
-        },
-        "crypto": {"description": "Crypto Validation Only", "args": ["--crypto-only", "-v"]},
-        "go-go": {
-            "description": "Go Client → Go Server (all crypto configs)",
-            "args": ["--language-pair", "go-go", "-v"],
-        },
-        "pyvider-pyvider": {
-            "description": "Python Client → Python Server (all crypto configs)",
-            "args": ["--language-pair", "pyvider-pyvider", "-v"],
-        },
-        "go-pyvider": {
-            "description": "Go Client → Python Server (all crypto configs)",
-            "args": ["--language-pair", "go-pyvider", "-v"],
-        },
-        "pyvider-go": {
-            "description": "Python Client → Go Server (all crypto configs)",
-            "args": ["--language-pair", "pyvider-go", "-v"],
-        },
-        "full": {"description": "Full Matrix Test (all 20 combinations)", "args": ["-v"]},
-        "basic": {
-            "description": "Basic Operations Only",
-            "args": ["-k", "test_rpc_kv_basic_operations", "-v"],
-        },
-    }
-
-    # Parse command line arguments
-    if len(sys.argv) < 2 or sys.argv[1] not in test_configs:
+    if len(sys.argv) < 2 or sys.argv[1] not in TEST_CONFIGS:
         print("🍲 TofuSoup RPC K/V Matrix Test Runner")
         print("\nAvailable test configurations:")
-        for config_name, config in test_configs.items():
+        for config_name, config in TEST_CONFIGS.items():
             print(f"  {config_name:15} - {config['description']}")
-        print(f"\nUsage: {sys.argv[0]} <config>")
-        print(f"Example: {sys.argv[0]} quick")
+        print(f"\nUsage: {sys.argv[0]} <config> [--jobs N] [--fail-fast | --continue] [pytest args...]")
+        print(f"Example: {sys.argv[0]} full --jobs 8")
         sys.exit(1)
 
-    config_name = sys.argv[1]
-    config = test_configs[config_name]
+    args, extra_args = parse_args(sys.argv[1:])
+    if args.jobs < 1:
+        print("❌ --jobs must be at least 1")
+        sys.exit(1)
+
+    config_name = args.config
+    config = TEST_CONFIGS[config_name]
 
     print("🍲 TofuSoup RPC K/V Matrix Test Runner")
     print(f"Configuration: {config_name}")
     print(f"Description: {config['description']}")
 
-    # Build command
-    base_cmd = ["python", "-m", "pytest"]
     test_file = str(script_dir / "souptest_rpc_kv_matrix.py")
-    cmd = base_cmd + [test_file] + config["args"]
+    pytest_args = config["args"] + extra_args
+
+    if args.jobs == 1:
+        # Serial run: one pytest process for the whole selection, as before
+        cmd = ["python", "-m", "pytest", test_file, *pytest_args]
+        if args.fail_fast:
+            cmd.append("-x")
+        success = run_command(cmd, f"Running {config['description']}")
+    else:
+        project_root = script_dir.resolve().parents[1]
+        node_ids = collect_node_ids(test_file, pytest_args, project_root)
+        if not node_ids:
+            print("⚠️  No tests selected")
+            sys.exit(1)
+
+        log_dir = args.log_dir or Path(tempfile.mkdtemp(prefix=f"soup-matrix-{config_name}-"))
+        log_dir.mkdir(parents=True, exist_ok=True)
+        jobs = min(args.jobs, len(node_ids))
+        mode = "fail-fast" if args.fail_fast else "continue"
+        print(f"\n🏃 Running {len(node_ids)} combinations with {jobs} jobs ({mode}), logs in {log_dir}")
 
-    # Add any additional arguments from command line
-    if len(sys.argv) > 2:
-        cmd.extend(sys.argv[2:])
+        prebuild_go_harness(project_root)
 
-    # Run the tests
-    success = run_command(cmd, f"Running {config['description']}")
+        started = time.time()
+        start = time.monotonic()
+        results = asyncio.run(
+            run_matrix_parallel(
+                project_root, node_ids, pytest_args, jobs, args.fail_fast, log_dir, args.job_timeout
+            )
+        )
+        wall_seconds = time.monotonic() - start
+        print_summary(results, wall_seconds, log_dir)
+        print(f"  Results: {write_results(results, started, wall_seconds, log_dir)}")
+        success = all(r.status == "passed" for r in results)
 
     if success:
         print(f"\n🎉 All tests in '{config_name}' configuration passed!")
@@ -0,0 +1,102 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""--cert-ttl: short-lived generated certificates.
+
+A standalone server started with --cert-ttl 3s accepts clients while its
+certificate is fresh. Once the certificate has expired, every new handshake
+fails, without waiting a year or touching the system clock.
+"""
+
+from collections.abc import Iterator
+import contextlib
+import os
+from pathlib import Path
+import socket
+import subprocess
+import time
+
+import pytest
+
+from tofusoup.common.config import load_tofusoup_config
+from tofusoup.harness.logic import ensure_go_harness_build
+
+TTL_SECONDS = 3
+
+
+def _free_port() -> int:
+    with socket.socket() as sock:
+        sock.bind(("127.0.0.1", 0))
+        return sock.getsockname()[1]
+
+
+@contextlib.contextmanager
+def _short_lived_server(soup_go: str, tmp_path: Path, handoff: Path) -> Iterator[int]:
+    port = _free_port()
+    server = subprocess.Popen(
+        [soup_go, "rpc", "kv", "server", "--standalone", "--port", str(port), "--tls-mode", "auto"]
+        + ["--tls-curve", "secp256r1", "--client-cert-handoff", str(handoff), "--cert-ttl", f"{TTL_SECONDS}s"],
+        env={**os.environ, "KV_STORAGE_DIR": str(tmp_path)},
+        stdout=subprocess.DEVNULL,
+        stderr=subprocess.DEVNULL,
+    )
+    try:
+        deadline = time.monotonic() + 30
+        while not Path(f"{handoff}.server").exists():
+            assert time.monotonic() < deadline, "server never published its certificate"
+            time.sleep(0.1)
+        with socket.create_connection(("127.0.0.1", port), timeout=30):
+            pass
+        yield port
+    finally:
+        server.terminate()
+        server.wait(timeout=10)
+
+
+def _put(soup_go: str, port: int, handoff: Path) -> subprocess.CompletedProcess:
+    return subprocess.run(
+        [soup_go, "rpc", "kv", "put", "k", "v", "--address", f"127.0.0.1:{port}"]
+        + ["--client-cert-handoff", str(handoff)],
+        capture_output=True,
+        text=True,
+        timeout=60,
+    )
+
+
+@pytest.fixture
+def soup_go(project_root: Path) -> str:
+    return str(ensure_go_harness_build("soup-go", project_root, load_tofusoup_config(project_root)))
+
+
+@pytest.mark.integration_rpc
+@pytest.mark.harness_go
+def test_certificate_expires_after_ttl(soup_go: str, tmp_path: Path) -> None:
+    handoff = tmp_path / "client.pem"
+    with _short_lived_server(soup_go, tmp_path, handoff) as port:
+        fresh = _put(soup_go, port, handoff)
+        time.sleep(TTL_SECONDS + 2)
+        expired = _put(soup_go, port, handoff)
+
+    assert fresh.returncode == 0, fresh.stderr
+    assert expired.returncode != 0
+    assert "certificate has expired or is not yet valid: current time" in expired.stderr
+    assert " is after " in expired.stderr
+
+
+@pytest.mark.integration_rpc
+@pytest.mark.harness_go
+def test_sub_second_ttl_is_rejected(soup_go: str) -> None:
+    result = subprocess.run(
+        [soup_go, "rpc", "kv", "get", "k", "--cert-ttl", "500ms"],
+        capture_output=True,
+        text=True,
+        timeout=60,
+    )
+
+    assert result.returncode != 0
+    assert "must be at least 1s" in result.stderr
+
+
+# 🥣🔬🔚
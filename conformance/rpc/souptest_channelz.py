@@ -0,0 +1,69 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""channelz: soup-go servers serve it and clients can print their own.
+
+`rpc channelz dump` must show the server's connections with their stream
+counts, and --channelz must show the channel a client command called on,
+even though the command has closed it by the time the line is printed.
+"""
+
+import json
+import os
+from pathlib import Path
+import subprocess
+
+import pytest
+
+from tofusoup.common.config import load_tofusoup_config
+from tofusoup.harness.logic import ensure_go_harness_build
+
+
+@pytest.fixture
+def soup_go(project_root: Path) -> str:
+    return str(ensure_go_harness_build("soup-go", project_root, load_tofusoup_config(project_root)))
+
+
+def _soup_go(soup_go: str, tmp_path: Path, *args: str) -> subprocess.CompletedProcess:
+    return subprocess.run(
+        [soup_go, "rpc", *args],
+        env={**os.environ, "PLUGIN_SERVER_PATH": soup_go, "KV_STORAGE_DIR": str(tmp_path)},
+        capture_output=True,
+        text=True,
+        timeout=60,
+    )
+
+
+@pytest.mark.integration_rpc
+@pytest.mark.harness_go
+def test_channelz_dump_shows_server_connections(soup_go: str, tmp_path: Path) -> None:
+    result = _soup_go(soup_go, tmp_path, "channelz", "dump")
+    assert result.returncode == 0, result.stderr
+    report = json.loads(result.stdout)
+
+    [server] = report["servers"]
+    assert server["listen_sockets"]
+    # The dump's own connection, which carried go-plugin's streams and the
+    # channelz queries
+    assert server["sockets"]
+    assert sum(socket["streams_started"] for socket in server["sockets"]) > 0
+    assert server["calls"]["started"] > 0
+
+
+@pytest.mark.integration_rpc
+@pytest.mark.harness_go
+def test_client_channelz_line(soup_go: str, tmp_path: Path) -> None:
+    result = _soup_go(soup_go, tmp_path, "kv", "put", "k", "v", "--channelz")
+    assert result.returncode == 0, result.stderr
+    report = json.loads(result.stdout.splitlines()[-1])["channelz"]
+
+    assert report["servers"] == []
+    [channel] = report["channels"]
+    assert channel["calls"]["succeeded"] >= 1
+    events = [event["description"] for sub in channel["subchannels"] for event in sub["trace"]]
+    assert "Subchannel Connectivity change to READY" in events
+
+
+# 🥣🔬🔚
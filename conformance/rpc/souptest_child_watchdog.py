@@ -0,0 +1,108 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""`--child-watchdog`: a hung spawned server is killed rather than stalling
+the client.
+
+A server that never completes the handshake is caught in the handshake
+phase. A server that takes too long over its first call, because
+KV_FAULT_DELAY holds it up, is caught in the first_rpc phase with its
+goroutine stacks. Either way the client reports a watchdog event and fails
+quickly.
+"""
+
+import json
+import os
+from pathlib import Path
+import subprocess
+import sys
+import time
+
+import pytest
+
+from tofusoup.common.config import load_tofusoup_config
+from tofusoup.harness.logic import ensure_go_harness_build
+
+pytestmark = pytest.mark.skipif(sys.platform == "win32", reason="stack dumps need SIGUSR1")
+
+
+@pytest.fixture
+def soup_go(project_root: Path) -> str:
+    return str(ensure_go_harness_build("soup-go", project_root, load_tofusoup_config(project_root)))
+
+
+def _get(soup_go: str, tmp_path: Path, **env: str) -> tuple[subprocess.CompletedProcess, dict, float]:
+    storage = tmp_path / "store"
+    storage.mkdir()
+    start = time.monotonic()
+    result = subprocess.run(
+        [soup_go, "rpc", "kv", "get", "app", "--child-watchdog", "1s"],
+        env={**os.environ, "KV_STORAGE_DIR": str(storage), **env},
+        capture_output=True,
+        text=True,
+        timeout=60,
+    )
+    elapsed = time.monotonic() - start
+    events = [json.loads(line)["watchdog"] for line in result.stdout.splitlines() if '"watchdog"' in line]
+    assert len(events) == 1, result.stdout
+    return result, events[0], elapsed
+
+
+@pytest.mark.integration_rpc
+@pytest.mark.harness_go
+def test_handshake_hang(soup_go: str, tmp_path: Path) -> None:
+    server = tmp_path / "hang.sh"
+    server.write_text('#!/bin/sh\necho "booting slowly" >&2\nexec sleep 600\n')
+    server.chmod(0o755)
+
+    result, event, elapsed = _get(soup_go, tmp_path, PLUGIN_SERVER_PATH=str(server))
+
+    assert result.returncode != 0
+    assert "child watchdog killed the spawned server after 1s in handshake" in result.stderr
+    assert elapsed < 30
+    assert event["phase"] == "handshake"
+    assert event["server"][0] == str(server)
+    assert event["stack_dump_requested"] is True
+    # sh doesn't handle SIGUSR1, so the dump request ends it
+    assert event["exited"] in ("exited", "killed")
+    assert "booting slowly" in event["output"]
+
+
+@pytest.mark.integration_rpc
+@pytest.mark.harness_go
+def test_first_rpc_hang(soup_go: str, tmp_path: Path) -> None:
+    result, event, elapsed = _get(soup_go, tmp_path, PLUGIN_SERVER_PATH=soup_go, KV_FAULT_DELAY="30s")
+
+    assert result.returncode != 0
+    assert "in first_rpc (/proto.KV/Get)" in result.stderr
+    assert elapsed < 30
+    assert event["phase"] == "first_rpc"
+    assert event["method"] == "/proto.KV/Get"
+    assert event["timeout_ms"] == 1000
+    assert event["exited"] == "killed"
+    # The soup-go server answers SIGUSR1 with its diagnostic dump
+    assert any("soup-go diagnostic dump" in line for line in event["output"])
+    assert any(line.startswith("goroutine ") for line in event["output"])
+
+
+@pytest.mark.integration_rpc
+@pytest.mark.harness_go
+def test_healthy_server_is_left_alone(soup_go: str, tmp_path: Path) -> None:
+    storage = tmp_path / "store"
+    storage.mkdir()
+    env = {**os.environ, "PLUGIN_SERVER_PATH": soup_go, "KV_STORAGE_DIR": str(storage)}
+    put = subprocess.run(
+        [soup_go, "rpc", "kv", "put", "app", "v1", "--child-watchdog", "30s"],
+        env=env,
+        capture_output=True,
+        text=True,
+        timeout=60,
+    )
+    assert put.returncode == 0, put.stderr
+    assert '"watchdog"' not in put.stdout
+    assert (storage / "kv-data-app").read_text() == "v1"
+
+
+# 🥣🔬🔚
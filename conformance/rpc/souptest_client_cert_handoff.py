@@ -0,0 +1,100 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""Client certificate handoff to soup-go servers started out of band.
+
+With --client-cert-handoff a plugin-mode server publishes its certificate
+beside the handoff file and requires the client certificate a reattaching
+client writes there, so RequireAndVerifyClientCert is exercised without
+go-plugin's PLUGIN_CLIENT_CERT.
+"""
+
+from collections.abc import Iterator
+import contextlib
+import os
+from pathlib import Path
+import subprocess
+import sys
+
+import pytest
+
+from tofusoup.common.config import load_tofusoup_config
+from tofusoup.harness.logic import ensure_go_harness_build
+
+pytestmark = pytest.mark.skipif(sys.platform == "win32", reason="plugin sockets are tested on unix only")
+
+
+@contextlib.contextmanager
+def _plugin_server(soup_go: str, tmp_path: Path, handoff: Path) -> Iterator[str]:
+    """Start a plugin-mode server the way a host would, outside go-plugin."""
+    env = {**os.environ, "BASIC_PLUGIN": "hello", "KV_STORAGE_DIR": str(tmp_path)}
+    env.pop("PLUGIN_CLIENT_CERT", None)
+    server = subprocess.Popen(
+        [soup_go, "rpc", "kv", "server", "--tls-mode", "auto", "--tls-curve", "secp384r1"]
+        + ["--client-cert-handoff", str(handoff)],
+        env=env,
+        stdout=subprocess.PIPE,
+        stderr=subprocess.DEVNULL,
+        text=True,
+    )
+    try:
+        assert server.stdout is not None
+        yield server.stdout.readline().strip()
+    finally:
+        server.terminate()
+        server.wait(timeout=10)
+
+
+def _put(soup_go: str, handshake: str, handoff: Path) -> subprocess.CompletedProcess:
+    return subprocess.run(
+        [soup_go, "rpc", "kv", "put", "k", "v", "--address", handshake, "--client-cert-handoff", str(handoff)],
+        capture_output=True,
+        text=True,
+        timeout=60,
+    )
+
+
+@pytest.fixture
+def soup_go(project_root: Path) -> str:
+    return str(ensure_go_harness_build("soup-go", project_root, load_tofusoup_config(project_root)))
+
+
+@pytest.mark.integration_rpc
+@pytest.mark.harness_go
+def test_handed_off_client_cert_is_accepted(soup_go: str, tmp_path: Path) -> None:
+    handoff = tmp_path / "client.pem"
+    with _plugin_server(soup_go, tmp_path, handoff) as handshake:
+        # go-plugin leaves the certificate out of the handshake line
+        assert handshake.endswith("|grpc|")
+        assert Path(f"{handoff}.server").read_text().startswith("-----BEGIN CERTIFICATE-----")
+        result = _put(soup_go, handshake, handoff)
+
+    assert result.returncode == 0, result.stderr
+    assert handoff.read_text().startswith("-----BEGIN CERTIFICATE-----")
+
+
+@pytest.mark.integration_rpc
+@pytest.mark.harness_go
+def test_other_client_cert_is_rejected(soup_go: str, tmp_path: Path) -> None:
+    handoff = tmp_path / "client.pem"
+    other = tmp_path / "other.pem"
+    with _plugin_server(soup_go, tmp_path, handoff) as handshake:
+        # This client trusts the server but hands its certificate off elsewhere
+        Path(f"{other}.server").write_text(Path(f"{handoff}.server").read_text())
+
+        # Before any handoff the server has no client certificate to trust
+        assert _put(soup_go, handshake, other).returncode != 0
+
+        # Once one is handed off, a client presenting another is rejected
+        handoff.write_text(other.read_text())
+        result = _put(soup_go, handshake, other)
+
+    # The client sees either the TLS alert or the closed connection,
+    # depending on which arrives first
+    assert result.returncode != 0
+    assert "code = Unavailable" in result.stderr
+
+
+# 🥣🔬🔚
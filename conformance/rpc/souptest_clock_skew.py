@@ -0,0 +1,109 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""--clock-skew: certificate validity windows without touching the system clock.
+
+A standalone server started with --clock-skew +2h issues a certificate that
+only becomes valid two hours from now. A client on the real clock must
+reject it as not yet valid, a client skewed by the same amount accepts it,
+and one skewed past the certificate's year of validity rejects it as expired.
+A client issues its own certificate on its skewed clock too, so one far
+enough ahead is turned away by the server instead.
+"""
+
+from collections.abc import Iterator
+import contextlib
+import os
+from pathlib import Path
+import socket
+import subprocess
+import time
+
+import pytest
+
+from tofusoup.common.config import load_tofusoup_config
+from tofusoup.harness.logic import ensure_go_harness_build
+
+
+def _free_port() -> int:
+    with socket.socket() as sock:
+        sock.bind(("127.0.0.1", 0))
+        return sock.getsockname()[1]
+
+
+@contextlib.contextmanager
+def _skewed_server(soup_go: str, tmp_path: Path, handoff: Path, skew: str) -> Iterator[int]:
+    port = _free_port()
+    server = subprocess.Popen(
+        [soup_go, "rpc", "kv", "server", "--standalone", "--port", str(port), "--tls-mode", "auto"]
+        + ["--tls-curve", "secp256r1", "--client-cert-handoff", str(handoff), "--clock-skew", skew],
+        env={**os.environ, "KV_STORAGE_DIR": str(tmp_path)},
+        stdout=subprocess.DEVNULL,
+        stderr=subprocess.DEVNULL,
+    )
+    try:
+        deadline = time.monotonic() + 30
+        while not Path(f"{handoff}.server").exists():
+            assert time.monotonic() < deadline, "server never published its certificate"
+            time.sleep(0.1)
+        with socket.create_connection(("127.0.0.1", port), timeout=30):
+            pass
+        yield port
+    finally:
+        server.terminate()
+        server.wait(timeout=10)
+
+
+def _put(soup_go: str, port: int, handoff: Path, *args: str) -> subprocess.CompletedProcess:
+    return subprocess.run(
+        [soup_go, "rpc", "kv", "put", "k", "v", "--address", f"127.0.0.1:{port}"]
+        + ["--client-cert-handoff", str(handoff), *args],
+        capture_output=True,
+        text=True,
+        timeout=60,
+    )
+
+
+@pytest.fixture
+def soup_go(project_root: Path) -> str:
+    return str(ensure_go_harness_build("soup-go", project_root, load_tofusoup_config(project_root)))
+
+
+@pytest.mark.integration_rpc
+@pytest.mark.harness_go
+def test_skewed_certificate_validity(soup_go: str, tmp_path: Path) -> None:
+    handoff = tmp_path / "client.pem"
+    with _skewed_server(soup_go, tmp_path, handoff, "+2h") as port:
+        unskewed = _put(soup_go, port, handoff)
+        matching = _put(soup_go, port, handoff, "--clock-skew", "+2h")
+        ahead = _put(soup_go, port, handoff, "--clock-skew", "+364d")
+        expired = _put(soup_go, port, handoff, "--clock-skew", "+367d")
+
+    assert unskewed.returncode != 0
+    assert "certificate has expired or is not yet valid: current time" in unskewed.stderr
+    assert " is before " in unskewed.stderr
+    assert matching.returncode == 0, matching.stderr
+    assert ahead.returncode != 0
+    assert "remote error: tls: expired certificate" in ahead.stderr
+    assert expired.returncode != 0
+    assert " is after " in expired.stderr
+
+
+@pytest.mark.integration_rpc
+@pytest.mark.harness_go
+def test_clock_skew_from_environment(soup_go: str) -> None:
+    result = subprocess.run(
+        [soup_go, "rpc", "kv", "get", "k", "--address", "127.0.0.1:1"],
+        env={**os.environ, "SOUP_GO_CLOCK_SKEW": "two hours"},
+        capture_output=True,
+        text=True,
+        timeout=60,
+    )
+
+    assert result.returncode != 0
+    assert "invalid SOUP_GO_CLOCK_SKEW" in result.stderr
+
+
+# 🥣🔬🔚
@@ -76,7 +76,10 @@ async def test_python_to_python_all_curves(soup_path: Path | None, curve: str) -
         await client.close()
 
 
-@pytest.mark.skip(reason="Python client → Go server is not supported (pyvider-rpcplugin limitation)")
+@pytest.mark.tags(
+    feature="pyclient-goserver",
+    known_failure="Python client → Go server is not supported (pyvider-rpcplugin limitation)",
+)
 @pytest.mark.asyncio
 async def test_python_to_go_all_curves(soup_go_path: Path | None) -> None:
     """Test Python client → Go server with supported curves."""
@@ -103,7 +106,10 @@ async def test_python_to_go_all_curves(soup_go_path: Path | None) -> None:
 
 
 @pytest.mark.asyncio
-@pytest.mark.skip(reason="Python KVClient → Go server has TLS handshake issues (pyvider-rpcplugin limitation)")
+@pytest.mark.tags(
+    feature="pyclient-goserver",
+    known_failure="Python KVClient → Go server has TLS handshake issues (pyvider-rpcplugin limitation)",
+)
 async def test_go_to_go_connection(soup_go_path: Path | None) -> None:
     """Test Go client → Go server (managed via Python KVClient).
 
@@ -0,0 +1,98 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""KV compaction: soup-go and the Python store expire the same entries.
+
+Both harnesses share the file layout (kv-data-<key>), so the same storage
+directory compacted with the same entry TTL must give the same report.
+"""
+
+import json
+import os
+from pathlib import Path
+import time
+
+import pytest
+
+from tofusoup.rpc.compaction import compact_storage
+
+from ..cli_verification.shared_cli_utils import run_harness_cli
+
+HOUR = 3600
+# key -> (value, age in seconds)
+ENTRIES = {
+    "fresh": (b"new value", 0),
+    "stale-a": (b"expired long ago", 3 * HOUR),
+    "stale-b": (b"x" * 100, 2 * HOUR),
+    "recent": (b"still live", HOUR // 2),
+}
+
+
+def _populate(directory: Path) -> None:
+    directory.mkdir()
+    now = time.time()
+    for key, (value, age) in ENTRIES.items():
+        path = directory / f"kv-data-{key}"
+        path.write_bytes(value)
+        os.utime(path, (now - age, now - age))
+
+
+def _go_compact(executable: Path, project_root: Path, storage: Path, *args: str) -> dict:
+    exit_code, stdout, stderr = run_harness_cli(
+        executable=executable,
+        args=[
+            "--storage-dir",
+            str(storage),
+            "rpc",
+            "kv",
+            "admin",
+            "compact",
+            "--entry-ttl",
+            "1h",
+            "--output-format",
+            "json",
+            *args,
+        ],
+        project_root=project_root,
+        harness_artifact_name="soup-go",
+        test_id=f"kv_compact_{storage.name}",
+    )
+    assert exit_code == 0, f"soup-go rpc kv admin compact failed: {stderr}"
+    return json.loads(stdout)
+
+
+@pytest.mark.integration_rpc
+@pytest.mark.harness_go
+@pytest.mark.parametrize("go_harness_executable", ["soup-go"], indirect=True)
+def test_go_and_python_compact_alike(go_harness_executable: Path, project_root: Path, tmp_path: Path) -> None:
+    _populate(tmp_path / "go")
+    _populate(tmp_path / "py")
+
+    go = _go_compact(go_harness_executable, project_root, tmp_path / "go")
+    py = compact_storage(tmp_path / "py", HOUR)
+
+    assert go == py
+    assert go["removed_keys"] == ["stale-a", "stale-b"]
+    assert go["reclaimed_bytes"] == len(ENTRIES["stale-a"][0]) + len(ENTRIES["stale-b"][0])
+    assert (go["scanned_keys"], go["live_keys"]) == (4, 2)
+    assert sorted(p.name for p in (tmp_path / "go").iterdir()) == ["kv-data-fresh", "kv-data-recent"]
+
+
+@pytest.mark.integration_rpc
+@pytest.mark.harness_go
+@pytest.mark.parametrize("go_harness_executable", ["soup-go"], indirect=True)
+def test_dry_run_removes_nothing(go_harness_executable: Path, project_root: Path, tmp_path: Path) -> None:
+    storage = tmp_path / "dry"
+    _populate(storage)
+
+    report = _go_compact(go_harness_executable, project_root, storage, "--dry-run")
+
+    assert report["dry_run"] is True
+    assert report["removed_keys"] == ["stale-a", "stale-b"]
+    assert len(list(storage.iterdir())) == len(ENTRIES)
+    assert _go_compact(go_harness_executable, project_root, storage)["removed_keys"] == ["stale-a", "stale-b"]
+
+
+# 🥣🔬🔚
@@ -0,0 +1,113 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""`soup-go rpc kv cp`: two plugin clients in one process.
+
+Keys under a prefix are copied from one standalone server to another and
+read back from the destination. JSON objects arrive without the source's
+server_handshake enrichment, and --no-clobber leaves existing keys alone.
+"""
+
+from collections.abc import Iterator
+import contextlib
+import json
+import os
+from pathlib import Path
+import subprocess
+import sys
+import time
+
+import pytest
+
+from tofusoup.common.config import load_tofusoup_config
+from tofusoup.harness.logic import ensure_go_harness_build
+
+pytestmark = pytest.mark.skipif(sys.platform == "win32", reason="standalone --socket is unix only")
+
+
+@contextlib.contextmanager
+def _server(soup_go: str, storage: Path) -> Iterator[str]:
+    storage.mkdir()
+    socket = storage / "kv.sock"
+    server = subprocess.Popen(
+        [soup_go, "rpc", "kv", "server", "--standalone", "--socket", str(socket)],
+        env={**os.environ, "KV_STORAGE_DIR": str(storage)},
+        stdout=subprocess.DEVNULL,
+        stderr=subprocess.DEVNULL,
+    )
+    try:
+        deadline = time.monotonic() + 10
+        while not socket.exists():
+            assert server.poll() is None and time.monotonic() < deadline, "server did not create its socket"
+            time.sleep(0.05)
+        yield f"unix://{socket}"
+    finally:
+        server.terminate()
+        server.wait(timeout=10)
+
+
+def _kv(soup_go: str, *args: str) -> subprocess.CompletedProcess:
+    return subprocess.run([soup_go, "rpc", "kv", *args], capture_output=True, text=True, timeout=60)
+
+
+@pytest.fixture
+def soup_go(project_root: Path) -> str:
+    return str(ensure_go_harness_build("soup-go", project_root, load_tofusoup_config(project_root)))
+
+
+@pytest.mark.integration_rpc
+@pytest.mark.harness_go
+def test_copy_between_servers(soup_go: str, tmp_path: Path) -> None:
+    with _server(soup_go, tmp_path / "a") as source, _server(soup_go, tmp_path / "b") as destination:
+        for key, value in {"app.1": "one", "app.2": "two", "app.doc": '{"n":1}', "other": "x"}.items():
+            assert _kv(soup_go, "put", key, value, "--address", source).returncode == 0
+        assert _kv(soup_go, "put", "app.1", "kept", "--address", destination).returncode == 0
+
+        result = _kv(
+            soup_go,
+            *("cp", "app.", "--from", source, "--to", destination),
+            *("--concurrency", "2", "--no-clobber", "--verify", "--progress-every", "1", "--negotiation"),
+        )
+        assert result.returncode == 0, result.stderr
+        lines = [json.loads(line) for line in result.stdout.splitlines()]
+
+        listed = json.loads(_kv(soup_go, "list", "--address", destination).stdout)
+        kept = _kv(soup_go, "get", "app.1", "--address", destination).stdout.strip()
+
+    report, *negotiations = lines
+    assert {k: report[k] for k in ("keys", "copied", "skipped", "failed", "stripped", "verified")} == {
+        "keys": 3,
+        "copied": 2,
+        "skipped": 1,
+        "failed": 0,
+        "stripped": 1,
+        "verified": 2,
+    }
+    # Both clients were open at once, one per server
+    assert [n["negotiation"]["address"] for n in negotiations] == [
+        source.removeprefix("unix://"),
+        destination.removeprefix("unix://"),
+    ]
+    progress = [
+        json.loads(line)["progress"] for line in result.stderr.splitlines() if line.startswith('{"progress"')
+    ]
+    assert progress[-1] == {"done": 3, "total": 3, "copied": 2, "skipped": 1, "failed": 0}
+
+    assert listed["keys"] == ["app.1", "app.2", "app.doc"]
+    assert kept == "kept"
+    stored = json.loads((tmp_path / "b" / "kv-data-app.doc").read_text())
+    assert stored == {"n": 1}
+
+
+@pytest.mark.integration_rpc
+@pytest.mark.harness_go
+def test_copy_refuses_one_server_twice(soup_go: str, tmp_path: Path) -> None:
+    with _server(soup_go, tmp_path / "a") as source:
+        result = _kv(soup_go, "cp", "--from", source, "--to", source)
+    assert result.returncode != 0
+    assert "same server" in result.stderr
+
+
+# 🥣🔬🔚
@@ -0,0 +1,81 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""Crash injection: each server's storage survives a crash at every write point.
+
+A Put is sent to a server spawned with KV_CRASH_AFTER, which exits at that
+point of the write path. fsck then checks the storage directory. Both
+servers claim the same guarantees, so they must leave the same files:
+
+- lock-acquired: nothing written yet, only the empty file the lock created,
+  which fsck flags as an orphaned lock
+- flush: value and metadata written but not fsynced; a process crash loses
+  nothing, so the store is clean
+- put:1: the Put is durable but never acknowledged; the store is clean
+"""
+
+import json
+import os
+from pathlib import Path
+import shutil
+import subprocess
+
+import pytest
+
+from tofusoup.common.config import load_tofusoup_config
+from tofusoup.harness.logic import ensure_go_harness_build
+from tofusoup.rpc.storage import fsck_storage
+
+# crash point -> (files left behind, fsck issue kinds)
+EXPECTED = {
+    "lock-acquired": (["kv-data-crashy"], ["orphaned_lock"]),
+    "flush": (["kv-data-crashy", "kv-meta-crashy"], []),
+    "put:1": (["kv-data-crashy", "kv-meta-crashy"], []),
+}
+
+
+@pytest.mark.integration_rpc
+@pytest.mark.harness_go
+@pytest.mark.parametrize("server_lang", ["go", "python"])
+@pytest.mark.parametrize("crash_after", list(EXPECTED))
+def test_storage_after_crash(server_lang: str, crash_after: str, tmp_path: Path, project_root: Path) -> None:
+    config = load_tofusoup_config(project_root)
+    soup_go = str(ensure_go_harness_build("soup-go", project_root, config))
+    server_path = soup_go if server_lang == "go" else shutil.which("soup")
+    if not server_path:
+        pytest.skip("soup command not found in PATH")
+
+    storage = tmp_path / "store"
+    storage.mkdir()
+    env = os.environ.copy()
+    env.update(PLUGIN_SERVER_PATH=server_path, KV_STORAGE_DIR=str(storage), KV_CRASH_AFTER=crash_after)
+    put = subprocess.run(
+        [soup_go, "rpc", "kv", "put", "crashy", "a value that may not survive"],
+        env=env,
+        capture_output=True,
+        text=True,
+        timeout=60,
+    )
+    assert put.returncode != 0, f"the {server_lang} server acknowledged a Put it should have crashed in"
+
+    files, kinds = EXPECTED[crash_after]
+    assert sorted(p.name for p in storage.iterdir()) == files
+
+    check = subprocess.run(
+        [soup_go, "--storage-dir", str(storage), "rpc", "kv", "admin", "fsck", "--output-format", "json"],
+        capture_output=True,
+        text=True,
+        timeout=30,
+    )
+    report = json.loads(check.stdout)
+    assert [issue["kind"] for issue in report["issues"]] == kinds
+    assert (check.returncode == 0) == (not kinds)
+    assert fsck_storage(storage)["issues"] == report["issues"]
+
+    if not kinds:
+        assert (storage / "kv-data-crashy").read_bytes() == b"a value that may not survive"
+
+
+# 🥣🔬🔚
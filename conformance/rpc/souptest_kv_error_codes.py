@@ -0,0 +1,57 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""KV error codes: both servers classify failures with a KVError detail.
+
+A Get of a missing key and a Put of a key with a path separator fail with
+the gRPC code the KVError mapping table gives, and soup-go's client reports
+the KVError code and key from the detail rather than the message text.
+"""
+
+import json
+import os
+from pathlib import Path
+import shutil
+import subprocess
+
+import pytest
+
+from tofusoup.common.config import load_tofusoup_config
+from tofusoup.harness.logic import ensure_go_harness_build
+
+
+@pytest.mark.integration_rpc
+@pytest.mark.harness_go
+@pytest.mark.parametrize("server_lang", ["go", "python"])
+def test_kv_error_codes(server_lang: str, tmp_path: Path, project_root: Path) -> None:
+    config = load_tofusoup_config(project_root)
+    soup_go = str(ensure_go_harness_build("soup-go", project_root, config))
+    server_path = soup_go if server_lang == "go" else shutil.which("soup")
+    if not server_path:
+        pytest.skip("soup command not found in PATH")
+
+    env = os.environ.copy()
+    env.update(PLUGIN_SERVER_PATH=server_path, KV_STORAGE_DIR=str(tmp_path))
+
+    def error_of(*args: str) -> dict:
+        result = subprocess.run(
+            [soup_go, "rpc", "kv", *args], env=env, capture_output=True, text=True, timeout=60
+        )
+        assert result.returncode != 0, f"the {server_lang} server accepted {args}"
+        return json.loads(result.stdout.splitlines()[0])["error"]
+
+    cases = [
+        (("get", "missing"), "NotFound", "NOT_FOUND", "missing"),
+        (("put", "a/b", "value"), "InvalidArgument", "INVALID_KEY", "a/b"),
+    ]
+    for args, status_code, kv_code, key in cases:
+        error = error_of(*args)
+        assert error["code"] == status_code
+        assert error["kv_code"] == kv_code
+        detail = next(d for d in error["details"] if d["@type"].endswith("proto.KVError"))
+        assert detail == {"@type": "type.googleapis.com/proto.KVError", "code": kv_code, "key": key}
+
+
+# 🥣🔬🔚
@@ -0,0 +1,95 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""KV fsck: soup-go and the Python store agree on crash damage and its repair.
+
+Each storage directory is left the way an interrupted server leaves it: a
+value cut short mid-write, a lock taken on a key that was never written, a
+metadata write that was never renamed into place. Both checkers must give
+the same plan, and after --repair the store must check clean.
+"""
+
+import json
+from pathlib import Path
+
+import pytest
+
+from tofusoup.rpc.storage import fsck_storage, write_entry_metadata
+
+from ..cli_verification.shared_cli_utils import run_harness_cli
+
+EXPECTED = {
+    "cut-short": ("truncated_value", "remove_entry"),
+    "bit-rot": ("digest_mismatch", "remove_entry"),
+    "never-written": ("orphaned_lock", "remove_file"),
+    "pre-index": ("missing_metadata", "reindex"),
+    "deleted": ("orphaned_metadata", "remove_file"),
+    "half-indexed": ("orphaned_temp", "remove_file"),
+}
+
+
+def _crash(directory: Path) -> None:
+    """Write a healthy entry, then the damage in EXPECTED."""
+    directory.mkdir()
+    for key, value in (("healthy", b"fine"), ("cut-short", b"0123456789"), ("bit-rot", b"abc")):
+        write_entry_metadata(directory, key, value)
+        (directory / f"kv-data-{key}").write_bytes(value)
+    (directory / "kv-data-cut-short").write_bytes(b"0123")
+    (directory / "kv-data-bit-rot").write_bytes(b"abd")
+    (directory / "kv-data-never-written").write_bytes(b"")
+    (directory / "kv-data-pre-index").write_bytes(b"legacy")
+    (directory / "kv-meta-deleted").write_text('{"size":1,"sha256":"00"}')
+    (directory / "kv-tmp-half-indexed").write_text('{"size"')
+
+
+def _go_fsck(executable: Path, project_root: Path, storage: Path, *args: str) -> tuple[int, dict]:
+    exit_code, stdout, _ = run_harness_cli(
+        executable=executable,
+        args=["--storage-dir", str(storage), "rpc", "kv", "admin", "fsck", "--output-format", "json", *args],
+        project_root=project_root,
+        harness_artifact_name="soup-go",
+        test_id=f"kv_fsck_{storage.name}{'_repair' if args else ''}",
+    )
+    report = json.loads(stdout)
+    report.pop("storage_dir")
+    return exit_code, report
+
+
+@pytest.mark.integration_rpc
+@pytest.mark.harness_go
+@pytest.mark.parametrize("go_harness_executable", ["soup-go"], indirect=True)
+def test_go_and_python_plan_alike(go_harness_executable: Path, project_root: Path, tmp_path: Path) -> None:
+    _crash(tmp_path / "go")
+    _crash(tmp_path / "py")
+
+    exit_code, go = _go_fsck(go_harness_executable, project_root, tmp_path / "go")
+    py = fsck_storage(tmp_path / "py")
+    py.pop("storage_dir")
+
+    assert exit_code != 0, "fsck must fail while issues remain"
+    assert go == py
+    assert {i["key"]: (i["kind"], i["action"]) for i in go["issues"]} == EXPECTED
+    assert (go["scanned_keys"], go["ok_keys"]) == (5, 1)
+
+
+@pytest.mark.integration_rpc
+@pytest.mark.harness_go
+@pytest.mark.parametrize("go_harness_executable", ["soup-go"], indirect=True)
+def test_repair_restores_integrity(go_harness_executable: Path, project_root: Path, tmp_path: Path) -> None:
+    storage = tmp_path / "store"
+    _crash(storage)
+
+    exit_code, repaired = _go_fsck(go_harness_executable, project_root, storage, "--repair")
+    assert exit_code == 0
+    assert all(issue["repaired"] for issue in repaired["issues"])
+
+    exit_code, after = _go_fsck(go_harness_executable, project_root, storage)
+    assert exit_code == 0
+    assert after["issues"] == []
+    assert after["ok_keys"] == 2, "healthy and the reindexed pre-index value survive"
+    assert fsck_storage(storage)["clean"] is True
+
+
+# 🥣🔬🔚
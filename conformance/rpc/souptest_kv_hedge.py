@@ -0,0 +1,73 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""Hedged calls: `soup-go rpc kv put/get --hedge`.
+
+With the server slowed by KV_FAULT_DELAY, every hedged copy is in flight at
+once. Copies of a Put share an idempotency token, and the server applies
+exactly one and dedupes the rest; copies of a Get return the same value.
+With --hedge-cancel, copies still in flight when the first answers are
+cancelled.
+"""
+
+import json
+import os
+from pathlib import Path
+import shutil
+import subprocess
+
+import pytest
+
+from tofusoup.common.config import load_tofusoup_config
+from tofusoup.harness.logic import ensure_go_harness_build
+
+
+@pytest.mark.integration_rpc
+@pytest.mark.harness_go
+@pytest.mark.parametrize("server_lang", ["go", "python"])
+def test_kv_hedged_calls(server_lang: str, tmp_path: Path, project_root: Path) -> None:
+    config = load_tofusoup_config(project_root)
+    soup_go = str(ensure_go_harness_build("soup-go", project_root, config))
+    server_path = soup_go if server_lang == "go" else shutil.which("soup")
+    if not server_path:
+        pytest.skip("soup command not found in PATH")
+
+    env = os.environ.copy()
+    env.update(PLUGIN_SERVER_PATH=server_path, KV_STORAGE_DIR=str(tmp_path), KV_FAULT_DELAY="500ms")
+    env.pop("KV_IDEMPOTENCY_WINDOW", None)
+
+    def hedged(*args: str) -> tuple[subprocess.CompletedProcess, dict]:
+        result = subprocess.run(
+            [soup_go, "rpc", "kv", *args, "--hedge", "3", "--hedge-delay", "50ms"],
+            env=env,
+            capture_output=True,
+            text=True,
+            timeout=60,
+        )
+        lines = result.stdout.splitlines()
+        return result, json.loads(lines[-1])["hedge"]
+
+    result, put = hedged("put", "greeting", "hello")
+    assert result.returncode == 0, result.stderr
+    assert put["sent"] == 3 and put["winner"] >= 1
+    assert put["token"].startswith("hedge-")
+    assert [attempt["code"] for attempt in put["attempts"]] == ["OK"] * 3
+    assert (put["applied"], put["deduped"]) == (1, 2)
+    assert "duplicate" not in put
+
+    result, get = hedged("get", "greeting")
+    assert result.returncode == 0, result.stderr
+    assert result.stdout.splitlines()[0] == "hello"
+    assert get["consistent"] is True
+    assert len({attempt["sha256"] for attempt in get["attempts"]}) == 1
+
+    result, cancelled = hedged("get", "greeting", "--hedge-cancel")
+    assert result.returncode == 0, result.stderr
+    codes = [attempt["code"] for attempt in cancelled["attempts"]]
+    assert codes[cancelled["winner"] - 1] == "OK"
+    assert "Canceled" in codes
+
+
+# 🥣🔬🔚
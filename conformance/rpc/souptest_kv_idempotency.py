@@ -0,0 +1,72 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""Idempotent Puts: both servers apply a Put with an idempotency token once.
+
+A retry with the same token, key and value inside the idempotency window
+is deduped and leaves later writes alone; reusing the token for another
+value is InvalidArgument. Once the window passes, the token applies again.
+"""
+
+import json
+import os
+from pathlib import Path
+import shutil
+import subprocess
+
+import pytest
+
+from tofusoup.common.config import load_tofusoup_config
+from tofusoup.harness.logic import ensure_go_harness_build
+
+
+@pytest.mark.integration_rpc
+@pytest.mark.harness_go
+@pytest.mark.parametrize("server_lang", ["go", "python"])
+def test_kv_put_idempotency(server_lang: str, tmp_path: Path, project_root: Path) -> None:
+    config = load_tofusoup_config(project_root)
+    soup_go = str(ensure_go_harness_build("soup-go", project_root, config))
+    server_path = soup_go if server_lang == "go" else shutil.which("soup")
+    if not server_path:
+        pytest.skip("soup command not found in PATH")
+
+    env = os.environ.copy()
+    env.update(PLUGIN_SERVER_PATH=server_path, KV_STORAGE_DIR=str(tmp_path))
+    env.pop("KV_IDEMPOTENCY_WINDOW", None)
+
+    def kv(*args: str, **extra_env: str) -> subprocess.CompletedProcess:
+        return subprocess.run(
+            [soup_go, "rpc", "kv", *args],
+            env={**env, **extra_env},
+            capture_output=True,
+            text=True,
+            timeout=60,
+        )
+
+    def put_once(key: str, value: str, token: str, **extra_env: str) -> dict:
+        result = kv("put", key, value, "--idempotency-token", token, **extra_env)
+        return json.loads(result.stdout.splitlines()[0])
+
+    def get(key: str) -> str:
+        return kv("get", key).stdout.strip().splitlines()[-1]
+
+    assert put_once("k", "v1", "req-1")["idempotency"]["result"] == "applied"
+    assert kv("put", "k", "v2").returncode == 0
+
+    # A redelivered Put is acknowledged without overwriting the later value
+    assert put_once("k", "v1", "req-1")["idempotency"]["result"] == "deduped"
+    assert get("k") == "v2"
+
+    # The token belongs to the first Put
+    assert put_once("k", "v3", "req-1")["error"]["code"] == "InvalidArgument"
+    assert get("k") == "v2"
+
+    # Outside the window the token applies again
+    reapplied = put_once("k", "v1", "req-1", KV_IDEMPOTENCY_WINDOW="1ms")
+    assert reapplied["idempotency"]["result"] == "applied"
+    assert get("k") == "v1"
+
+
+# 🥣🔬🔚
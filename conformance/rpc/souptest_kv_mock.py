@@ -0,0 +1,95 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""Mock server: soup-go answers KV calls from a scripted responses file.
+
+The client spawns its server with KV_MOCK set, so the test scripts the
+server's value, error and delay for each key and checks the client reports
+exactly that. Each command spawns a fresh server, so sequences restart.
+"""
+
+import os
+from pathlib import Path
+import subprocess
+
+import pytest
+
+from tofusoup.common.config import load_tofusoup_config
+from tofusoup.harness.logic import ensure_go_harness_build
+
+MOCK = """\
+fallback: unimplemented
+responses:
+  - method: Get
+    key: scripted
+    value: from the script
+  - method: Get
+    key: broken
+    error: {code: UNAVAILABLE, message: scripted outage}
+  - method: Put
+    key: slow
+    delay: 200ms
+"""
+
+
+def _run_with_mock(soup_go: str, mock: Path, *args: str) -> subprocess.CompletedProcess[str]:
+    env = os.environ.copy()
+    env.update(PLUGIN_SERVER_PATH=soup_go, KV_MOCK=str(mock))
+    return subprocess.run(
+        [soup_go, "rpc", "kv", *args],
+        env=env,
+        capture_output=True,
+        text=True,
+        timeout=60,
+    )
+
+
+@pytest.fixture
+def soup_go(project_root: Path) -> str:
+    config = load_tofusoup_config(project_root)
+    return str(ensure_go_harness_build("soup-go", project_root, config))
+
+
+@pytest.fixture
+def mock(tmp_path: Path) -> Path:
+    path = tmp_path / "responses.yaml"
+    path.write_text(MOCK)
+    return path
+
+
+@pytest.mark.integration_rpc
+@pytest.mark.harness_go
+def test_scripted_value(soup_go: str, mock: Path) -> None:
+    result = _run_with_mock(soup_go, mock, "get", "scripted")
+    assert result.returncode == 0, result.stderr
+    assert "from the script" in result.stdout
+
+
+@pytest.mark.integration_rpc
+@pytest.mark.harness_go
+def test_scripted_error(soup_go: str, mock: Path) -> None:
+    result = _run_with_mock(soup_go, mock, "get", "broken")
+    assert result.returncode != 0
+    assert "Unavailable" in result.stderr + result.stdout
+    assert "scripted outage" in result.stderr + result.stdout
+
+
+@pytest.mark.integration_rpc
+@pytest.mark.harness_go
+def test_scripted_delay_hits_deadline(soup_go: str, mock: Path) -> None:
+    result = _run_with_mock(soup_go, mock, "--deadline-ms", "50", "put", "slow", "value")
+    assert result.returncode != 0
+    assert "DeadlineExceeded" in result.stderr + result.stdout
+
+
+@pytest.mark.integration_rpc
+@pytest.mark.harness_go
+def test_unmatched_call_is_unimplemented(soup_go: str, mock: Path) -> None:
+    result = _run_with_mock(soup_go, mock, "get", "unscripted")
+    assert result.returncode != 0
+    assert "Unimplemented" in result.stderr + result.stdout
+
+
+# 🥣🔬🔚
@@ -0,0 +1,124 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""`soup-go rpc kv session`: many operations over one plugin client.
+
+A script runs against a standalone server once over a single connection and
+once with --reconnect, which dials per op. Results and the setup/op timing
+split are checked, not the timings themselves.
+"""
+
+from collections.abc import Iterator
+import contextlib
+import json
+import os
+from pathlib import Path
+import subprocess
+import sys
+import time
+
+import pytest
+
+from tofusoup.common.config import load_tofusoup_config
+from tofusoup.harness.logic import ensure_go_harness_build
+
+pytestmark = pytest.mark.skipif(sys.platform == "win32", reason="standalone --socket is unix only")
+
+SCRIPT = [
+    {"op": "put", "key": "s.a", "value": "1"},
+    {"op": "put", "key": "s.b", "value": "2"},
+    {"op": "get", "key": "s.a"},
+    {"op": "get", "key": "s.missing"},
+    {"op": "list", "prefix": "s."},
+]
+
+
+@contextlib.contextmanager
+def _server(soup_go: str, storage: Path) -> Iterator[str]:
+    storage.mkdir()
+    socket = storage / "kv.sock"
+    server = subprocess.Popen(
+        [soup_go, "rpc", "kv", "server", "--standalone", "--socket", str(socket)],
+        env={**os.environ, "KV_STORAGE_DIR": str(storage)},
+        stdout=subprocess.DEVNULL,
+        stderr=subprocess.DEVNULL,
+    )
+    try:
+        deadline = time.monotonic() + 10
+        while not socket.exists():
+            assert server.poll() is None and time.monotonic() < deadline, "server did not create its socket"
+            time.sleep(0.05)
+        yield f"unix://{socket}"
+    finally:
+        server.terminate()
+        server.wait(timeout=10)
+
+
+def _kv(soup_go: str, *args: str) -> subprocess.CompletedProcess:
+    return subprocess.run([soup_go, "rpc", "kv", *args], capture_output=True, text=True, timeout=60)
+
+
+@pytest.fixture
+def soup_go(project_root: Path) -> str:
+    return str(ensure_go_harness_build("soup-go", project_root, load_tofusoup_config(project_root)))
+
+
+def _session(soup_go: str, address: str, *args: str) -> tuple[subprocess.CompletedProcess, list[dict]]:
+    script = "".join(json.dumps(op) + "\n" for op in SCRIPT)
+    result = subprocess.run(
+        [soup_go, "rpc", "kv", "session", "--address", address, "--script", "-", *args],
+        input=script,
+        capture_output=True,
+        text=True,
+        timeout=60,
+    )
+    return result, [json.loads(line) for line in result.stdout.splitlines()]
+
+
+@pytest.mark.integration_rpc
+@pytest.mark.harness_go
+@pytest.mark.parametrize("reconnect", [False, True], ids=["reuse", "reconnect"])
+def test_session_runs_script(soup_go: str, tmp_path: Path, reconnect: bool) -> None:
+    with _server(soup_go, tmp_path / "kv") as address:
+        result, lines = _session(soup_go, address, *(["--reconnect"] if reconnect else []))
+
+    # The missing key fails the command but not the ops after it
+    assert result.returncode != 0
+    *ops, last = lines
+    assert [(op["index"], op["op"], op["ok"]) for op in ops] == [
+        (0, "put", True),
+        (1, "put", True),
+        (2, "get", True),
+        (3, "get", False),
+        (4, "list", True),
+    ]
+    assert ops[2]["value"] == "1"
+    assert ops[3]["error"]["kv_code"] == "NOT_FOUND"
+    assert ops[4]["keys"] == ["s.a", "s.b"]
+
+    session = last["session"]
+    assert (session["ops"], session["failed"]) == (5, 1)
+    assert session["connections"] == (5 if reconnect else 1)
+    assert session["setup_ms"] > 0
+    assert session["first_op_ms"] == ops[0]["elapsed_ms"]
+    assert abs(session["op_ms"] - sum(op["elapsed_ms"] for op in ops)) < 0.01
+
+
+@pytest.mark.integration_rpc
+@pytest.mark.harness_go
+def test_session_checks_script_before_dialing(soup_go: str, tmp_path: Path) -> None:
+    script = tmp_path / "ops.ndjson"
+    script.write_text('{"op": "put", "key": "k", "value": "v"}\n{"op": "delete", "key": "k"}\n')
+    result = subprocess.run(
+        [soup_go, "rpc", "kv", "session", "--address", "unix:///nonexistent.sock", "--script", str(script)],
+        capture_output=True,
+        text=True,
+        timeout=60,
+    )
+    assert result.returncode != 0
+    assert 'line 2: unknown op "delete"' in result.stderr
+
+
+# 🥣🔬🔚
@@ -0,0 +1,92 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""Streamed Gets of values too large to hold in memory.
+
+Values are written straight into the storage directory, since a Put carries
+its value in one message. With a memory ceiling set, a Get of a larger value
+must fail with OVER_MEMORY_CEILING, and `kv get --stream` must read it
+intact while the client stays far smaller than the value.
+"""
+
+import filecmp
+import json
+import os
+from pathlib import Path
+import subprocess
+
+import pytest
+
+from tofusoup.common.config import load_tofusoup_config
+from tofusoup.harness.logic import ensure_go_harness_build
+
+CEILING = 1 << 20
+BIG = 256 << 20
+
+
+@pytest.fixture
+def soup_go(project_root: Path) -> str:
+    return str(ensure_go_harness_build("soup-go", project_root, load_tofusoup_config(project_root)))
+
+
+def _soup_go(soup_go: str, tmp_path: Path, *args: str) -> subprocess.CompletedProcess:
+    return subprocess.run(
+        [soup_go, "rpc", "kv", *args],
+        env={
+            **os.environ,
+            "PLUGIN_SERVER_PATH": soup_go,
+            "KV_STORAGE_DIR": str(tmp_path),
+            "KV_MAX_VALUE_MEMORY": str(CEILING),
+        },
+        capture_output=True,
+        text=True,
+        timeout=120,
+    )
+
+
+@pytest.mark.integration_rpc
+@pytest.mark.harness_go
+def test_stream_reads_value_over_ceiling(soup_go: str, tmp_path: Path) -> None:
+    value = tmp_path / "kv-data-pattern"
+    value.write_bytes(bytes(range(256)) * 40960 + b"tail")
+
+    result = _soup_go(soup_go, tmp_path, "get", "pattern")
+    assert result.returncode != 0
+    error = json.loads(result.stdout)["error"]
+    assert (error["code"], error["kv_code"]) == ("ResourceExhausted", "OVER_MEMORY_CEILING")
+
+    out = tmp_path / "out"
+    result = _soup_go(
+        soup_go, tmp_path, "get", "pattern", "--stream", "--chunk-size", "100000", "-o", str(out)
+    )
+    assert result.returncode == 0, result.stderr
+    assert filecmp.cmp(out, value, shallow=False)
+
+
+@pytest.mark.integration_rpc
+@pytest.mark.harness_go
+def test_stream_memory_stays_below_value_size(soup_go: str, tmp_path: Path) -> None:
+    with (tmp_path / "kv-data-big").open("wb") as f:
+        f.truncate(BIG)
+
+    out = tmp_path / "out"
+    result = _soup_go(soup_go, tmp_path, "get", "big", "--stream", "-o", str(out), "--stats")
+    assert result.returncode == 0, result.stderr
+    assert out.stat().st_size == BIG
+    stats = json.loads(result.stdout.splitlines()[-1])["stats"]
+    assert stats["max_rss_bytes"] < BIG // 2
+
+
+@pytest.mark.integration_rpc
+@pytest.mark.harness_go
+def test_stream_missing_key(soup_go: str, tmp_path: Path) -> None:
+    result = _soup_go(soup_go, tmp_path, "get", "missing", "--stream")
+    assert result.returncode != 0
+    assert json.loads(result.stdout)["error"]["kv_code"] == "NOT_FOUND"
+    # Streaming a missing key must not create it
+    assert not (tmp_path / "kv-data-missing").exists()
+
+
+# 🥣🔬🔚
@@ -0,0 +1,73 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""Value size limits and Stat: both servers enforce KV_MAX_VALUE_BYTES alike.
+
+A server spawned with KV_MAX_VALUE_BYTES rejects larger Puts with
+InvalidArgument, the same message and a TOO_LARGE KVError. soup-go's
+server also attaches a BadRequest violation and a VALUE_TOO_LARGE
+ErrorInfo; the Python server sends no other details. A Stat from a freshly spawned server has no
+call counters yet, so it must match stat_storage over the same directory.
+"""
+
+import json
+import os
+from pathlib import Path
+import shutil
+import subprocess
+
+import pytest
+
+from tofusoup.common.config import load_tofusoup_config
+from tofusoup.harness.logic import ensure_go_harness_build
+from tofusoup.rpc.stats import stat_storage, value_too_large_message
+
+LIMIT = 16
+SMALL = "within limit"
+LARGE = "x" * (LIMIT + 1)
+
+
+@pytest.mark.integration_rpc
+@pytest.mark.harness_go
+@pytest.mark.parametrize("server_lang", ["go", "python"])
+def test_max_value_bytes(server_lang: str, tmp_path: Path, project_root: Path) -> None:
+    config = load_tofusoup_config(project_root)
+    soup_go = str(ensure_go_harness_build("soup-go", project_root, config))
+    server_path = soup_go if server_lang == "go" else shutil.which("soup")
+    if not server_path:
+        pytest.skip("soup command not found in PATH")
+
+    storage = tmp_path / "store"
+    storage.mkdir()
+    env = os.environ.copy()
+    env.update(PLUGIN_SERVER_PATH=server_path, KV_STORAGE_DIR=str(storage), KV_MAX_VALUE_BYTES=str(LIMIT))
+
+    def run(*args: str) -> subprocess.CompletedProcess:
+        return subprocess.run(
+            [soup_go, "rpc", "kv", *args], env=env, capture_output=True, text=True, timeout=60
+        )
+
+    assert run("put", "small", SMALL).returncode == 0
+
+    rejected = run("put", "large", LARGE)
+    assert rejected.returncode != 0, f"the {server_lang} server accepted a value over the limit"
+    error = json.loads(rejected.stdout.splitlines()[0])["error"]
+    assert error["code"] == "InvalidArgument"
+    assert error["message"] == value_too_large_message(len(LARGE), LIMIT)
+    assert error["kv_code"] == "TOO_LARGE"
+    if server_lang == "go":
+        info = next(d for d in error["details"] if d["@type"].endswith("ErrorInfo"))
+        assert info["reason"] == "VALUE_TOO_LARGE"
+        assert info["metadata"] == {"key": "large", "size": str(len(LARGE)), "max_value_bytes": str(LIMIT)}
+    assert not (storage / "kv-data-large").exists()
+
+    stat = run("stat", "--output-format", "json")
+    assert stat.returncode == 0, f"soup-go rpc kv stat failed: {stat.stderr}"
+    report = json.loads(stat.stdout)
+    assert report == stat_storage(storage, {}, max_value_bytes=LIMIT)
+    assert [(k["key"], k["size"]) for k in report["keys"]] == [("small", len(SMALL))]
+
+
+# 🥣🔬🔚
@@ -0,0 +1,76 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""Waiting Gets: both servers block a Get with wait set until its key exists.
+
+A waiting Get returns as soon as another server sharing the storage
+directory stores the key. If wait_timeout_ms passes first, it fails with
+DeadlineExceeded and a WAIT_TIMEOUT KVError, which a client deadline
+expiring doesn't have.
+"""
+
+import json
+import os
+from pathlib import Path
+import shutil
+import subprocess
+import time
+
+import pytest
+
+from tofusoup.common.config import load_tofusoup_config
+from tofusoup.harness.logic import ensure_go_harness_build
+
+
+@pytest.mark.integration_rpc
+@pytest.mark.harness_go
+@pytest.mark.parametrize("server_lang", ["go", "python"])
+def test_kv_get_wait(server_lang: str, tmp_path: Path, project_root: Path) -> None:
+    config = load_tofusoup_config(project_root)
+    soup_go = str(ensure_go_harness_build("soup-go", project_root, config))
+    server_path = soup_go if server_lang == "go" else shutil.which("soup")
+    if not server_path:
+        pytest.skip("soup command not found in PATH")
+
+    env = os.environ.copy()
+    env.update(PLUGIN_SERVER_PATH=server_path, KV_STORAGE_DIR=str(tmp_path))
+
+    def kv(*args: str) -> subprocess.Popen:
+        return subprocess.Popen(
+            [soup_go, "rpc", "kv", *args], env=env, stdout=subprocess.PIPE, stderr=subprocess.PIPE, text=True
+        )
+
+    def error_of(*args: str) -> tuple[dict, float]:
+        start = time.monotonic()
+        stdout, _ = kv(*args).communicate(timeout=60)
+        return json.loads(stdout.splitlines()[0])["error"], time.monotonic() - start
+
+    # The server's timer expires
+    error, elapsed = error_of("get", "never", "--wait", "--wait-timeout-ms", "500")
+    assert (error["code"], error.get("kv_code")) == ("DeadlineExceeded", "WAIT_TIMEOUT")
+    assert elapsed >= 0.5
+
+    # The client's deadline expires first
+    error, _ = error_of("get", "never", "--wait", "--wait-timeout-ms", "30000", "--deadline-ms", "500")
+    assert error["code"] == "DeadlineExceeded"
+    assert "kv_code" not in error
+
+    # A Put through another server wakes the waiting Get
+    waiting = kv("get", "later", "--wait", "--wait-timeout-ms", "30000")
+    time.sleep(2)
+    assert waiting.poll() is None, "the Get returned before the key had a value"
+    put = kv("put", "later", "hello")
+    assert put.wait(timeout=60) == 0
+    stdout, stderr = waiting.communicate(timeout=30)
+    assert waiting.returncode == 0, stderr
+    assert stdout.strip().splitlines()[-1] == "hello"
+
+    # An existing key returns at once, and a negative timeout is refused
+    assert kv("get", "later", "--wait").communicate(timeout=60)[0].strip() == "hello"
+    error, _ = error_of("get", "later", "--wait", "--wait-timeout-ms", "-1")
+    assert error["code"] == "InvalidArgument"
+
+
+# 🥣🔬🔚
@@ -0,0 +1,87 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""--leak-check: memory trends over soak and bench runs.
+
+restart-under-load with a restart interval longer than its duration soaks
+one plugin server under constant traffic. With --leak-check its resident
+memory is sampled throughout, and a trend is fitted to the samples taken
+after the warmup. wire bench does the same for its own Go heap.
+"""
+
+import json
+from pathlib import Path
+import subprocess
+
+import pytest
+
+from tofusoup.common.config import load_tofusoup_config
+from tofusoup.harness.logic import ensure_go_harness_build
+
+LEAK_FLAGS = ["--leak-check", "--leak-interval", "250ms", "--leak-warmup", "1s"]
+
+
+@pytest.fixture
+def soup_go(project_root: Path) -> str:
+    return str(ensure_go_harness_build("soup-go", project_root, load_tofusoup_config(project_root)))
+
+
+@pytest.mark.integration_rpc
+@pytest.mark.harness_go
+def test_soak_server_is_sampled(soup_go: str) -> None:
+    result = subprocess.run(
+        [soup_go, "harness", "scenario", "restart-under-load", "--server-cmd", f"{soup_go} rpc kv server"]
+        + ["--duration", "5s", "--restart-interval", "1h", "--output-format", "json", *LEAK_FLAGS]
+        + ["--leak-threshold-mb", "64"],
+        capture_output=True,
+        text=True,
+        timeout=120,
+    )
+    assert result.returncode == 0, result.stderr
+    report = json.loads(result.stdout)
+
+    assert report["restarts"] == []
+    [leak] = report["leak_check"]
+    assert leak["source"].startswith("server 0 (pid ")
+    assert leak["metric"] == "rss"
+    assert leak["fitted_samples"] >= 3
+    assert all(sample["bytes"] > 0 for sample in leak["samples"])
+    assert leak["growth_bytes"] < leak["threshold_bytes"]
+    assert leak["leaking"] is False
+
+
+@pytest.mark.integration_rpc
+@pytest.mark.harness_go
+def test_bench_samples_go_heap(soup_go: str) -> None:
+    result = subprocess.run(
+        [soup_go, "wire", "bench", "--case", "medium", "--min-time", "500ms", "--output-format", "json"]
+        + LEAK_FLAGS,
+        capture_output=True,
+        text=True,
+        timeout=120,
+    )
+    assert result.returncode == 0, result.stderr
+    leak = json.loads(result.stdout)["leak_check"]
+
+    assert leak["source"] == "soup-go"
+    assert leak["metric"] == "go_heap_alloc"
+    assert leak["fitted_samples"] >= 3
+    assert leak["leaking"] is False
+
+
+@pytest.mark.integration_rpc
+@pytest.mark.harness_go
+def test_leak_flags_are_validated(soup_go: str) -> None:
+    result = subprocess.run(
+        [soup_go, "wire", "bench", "--leak-check", "--leak-interval", "0s"],
+        capture_output=True,
+        text=True,
+        timeout=60,
+    )
+    assert result.returncode != 0
+    assert "--leak-interval must be positive" in result.stderr
+
+
+# 🥣🔬🔚
@@ -0,0 +1,102 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""Negotiation outcomes reported by `soup-go rpc ... --negotiation`.
+
+A client that spawns soup-go's server negotiates gRPC, plugin version 1 and
+AutoMTLS over a unix socket, and knows the server's PID. A client that
+reattaches by --address takes those from the address instead.
+"""
+
+import json
+import os
+from pathlib import Path
+import subprocess
+import sys
+import time
+
+import pytest
+
+from tofusoup.common.config import load_tofusoup_config
+from tofusoup.harness.logic import ensure_go_harness_build
+
+
+def _negotiations(stdout: str) -> list[dict]:
+    return [
+        json.loads(line)["negotiation"] for line in stdout.splitlines() if line.startswith('{"negotiation"')
+    ]
+
+
+@pytest.fixture
+def soup_go(project_root: Path) -> str:
+    return str(ensure_go_harness_build("soup-go", project_root, load_tofusoup_config(project_root)))
+
+
+@pytest.mark.integration_rpc
+@pytest.mark.harness_go
+def test_spawned_server_negotiation(soup_go: str, tmp_path: Path) -> None:
+    env = {**os.environ, "PLUGIN_SERVER_PATH": soup_go, "KV_STORAGE_DIR": str(tmp_path)}
+    env.pop("TLS_MODE", None)
+    result = subprocess.run(
+        [soup_go, "rpc", "kv", "put", "k", "v", "--negotiation"],
+        env=env,
+        capture_output=True,
+        text=True,
+        timeout=60,
+    )
+    assert result.returncode == 0, result.stderr
+
+    [negotiation] = _negotiations(result.stdout)
+    assert negotiation["mode"] == "spawned"
+    assert negotiation["started"]
+    assert negotiation["plugin_version"] == 1
+    assert negotiation["protocol"] == "grpc"
+    assert negotiation["auto_mtls"] and negotiation["tls"]
+    assert negotiation["server_pid"] > 0
+    if sys.platform != "win32":
+        assert negotiation["network"] == "unix"
+
+
+@pytest.mark.integration_rpc
+@pytest.mark.harness_go
+@pytest.mark.skipif(sys.platform == "win32", reason="standalone --socket is unix only")
+def test_reattach_negotiation(soup_go: str, tmp_path: Path) -> None:
+    socket = tmp_path / "kv.sock"
+    server = subprocess.Popen(
+        [soup_go, "rpc", "kv", "server", "--standalone", "--socket", str(socket)],
+        env={**os.environ, "KV_STORAGE_DIR": str(tmp_path)},
+        stdout=subprocess.DEVNULL,
+        stderr=subprocess.DEVNULL,
+    )
+    try:
+        deadline = time.monotonic() + 10
+        while not socket.exists():
+            assert server.poll() is None and time.monotonic() < deadline, "server did not create its socket"
+            time.sleep(0.05)
+        result = subprocess.run(
+            [soup_go, "rpc", "echo", "info", "--address", f"unix://{socket}", "--negotiation"],
+            capture_output=True,
+            text=True,
+            timeout=60,
+        )
+    finally:
+        server.terminate()
+        server.wait(timeout=10)
+    assert result.returncode == 0, result.stderr
+
+    [negotiation] = _negotiations(result.stdout)
+    assert negotiation == {
+        "mode": "reattach",
+        "started": True,
+        "plugin_version": 1,
+        "protocol": "grpc",
+        "network": "unix",
+        "address": str(socket),
+        "auto_mtls": False,
+        "tls": False,
+    }
+
+
+# 🥣🔬🔚
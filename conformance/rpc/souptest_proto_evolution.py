@@ -0,0 +1,90 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""Proto Schema Evolution Tests
+
+Sends v2 KV messages (kv_v2.proto), which add optional and repeated fields,
+to v1 servers and checks that:
+1. The servers accept them and answer as v1 would
+2. The new fields are reported as unknown and preserved on re-encoding
+3. A v2 client reading a v1 response sees the new optional fields as unset
+"""
+
+import json
+import os
+from pathlib import Path
+import shutil
+import subprocess
+import uuid
+
+import pytest
+
+from tofusoup.common.config import load_tofusoup_config
+from tofusoup.harness.logic import ensure_go_harness_build
+from tofusoup.harness.proto.kv import kv_pb2, kv_v2_pb2
+
+
+def _soup_go(args: list[str], env: dict[str, str], soup_go: str) -> dict:
+    result = subprocess.run(
+        [soup_go, "rpc", "kv", *args, "--proto-v2"],
+        env=env,
+        capture_output=True,
+        text=True,
+        timeout=30,
+    )
+    assert result.returncode == 0, f"soup-go {args[0]} --proto-v2 failed: {result.stderr}"
+    return json.loads(result.stdout)
+
+
+class TestProtoEvolution:
+    """v2 clients against v1 servers."""
+
+    def test_python_runtime_reports_unknown_fields(self) -> None:
+        """The Python server's check sees v2-only fields and keeps them."""
+        from tofusoup.rpc.server import unknown_field_report
+
+        v2 = kv_v2_pb2.PutRequestV2(key="k", value=b"v", content_type="text/plain", ttl_seconds=60, tags=["a"])
+        v1 = kv_pb2.PutRequest.FromString(v2.SerializeToString())
+
+        numbers, preserved = unknown_field_report(v1)
+        assert numbers == [3, 4, 5]
+        assert preserved
+        assert unknown_field_report(kv_pb2.PutRequest(key="k")) == ([], True)
+
+    @pytest.mark.integration_rpc
+    @pytest.mark.harness_go
+    @pytest.mark.parametrize("server_lang", ["go", "python"])
+    async def test_v2_client_against_v1_server(
+        self, server_lang: str, tmp_path: Path, project_root: Path
+    ) -> None:
+        """soup-go --proto-v2 Put and Get round-trip and report the new fields."""
+        config = load_tofusoup_config(project_root)
+        soup_go = str(ensure_go_harness_build("soup-go", project_root, config))
+        if server_lang == "go":
+            server_path = soup_go
+        else:
+            server_path = shutil.which("soup")
+            if not server_path:
+                pytest.skip("soup command not found in PATH")
+
+        env = os.environ.copy()
+        env["PLUGIN_SERVER_PATH"] = server_path
+        env["KV_STORAGE_DIR"] = str(tmp_path)
+        key = f"evolution-{uuid.uuid4().hex[:8]}"
+
+        put = _soup_go(["put", key, "evolved"], env, soup_go)
+        assert put["server_reported"], f"{server_lang} server sent no kv-unknown-fields trailer"
+        assert put["server_unknown_fields"] == put["sent_fields"] == [3, 4, 5]
+        assert put["server_preserved"] is True
+
+        get = _soup_go(["get", key], env, soup_go)
+        assert get["value"] == "evolved"
+        assert get["server_unknown_fields"] == [2]
+        assert get["server_preserved"] is True
+        assert get["response_optional_fields"] == {"content_type": False, "stored_at_unix": False}
+        assert get["response_unknown_fields"] == []
+
+
+# 🥣🔬🔚
@@ -0,0 +1,99 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""--resolve NAME:ADDR: TLS hostname verification against real DNS names.
+
+The client dials ADDR without looking NAME up but verifies the server
+certificate against NAME, so a name in the server's --tls-dns-name SANs is
+accepted and any other is rejected, with no /etc/hosts entry for either.
+"""
+
+from collections.abc import Iterator
+import contextlib
+import os
+from pathlib import Path
+import socket
+import subprocess
+import time
+
+import pytest
+
+from tofusoup.common.config import load_tofusoup_config
+from tofusoup.harness.logic import ensure_go_harness_build
+
+
+def _free_port() -> int:
+    with socket.socket() as sock:
+        sock.bind(("127.0.0.1", 0))
+        return sock.getsockname()[1]
+
+
+@contextlib.contextmanager
+def _standalone_server(soup_go: str, tmp_path: Path, handoff: Path) -> Iterator[int]:
+    port = _free_port()
+    server = subprocess.Popen(
+        [soup_go, "rpc", "kv", "server", "--standalone", "--port", str(port), "--tls-mode", "auto"]
+        + ["--tls-curve", "secp256r1", "--tls-dns-name", "kv.soup.test"]
+        + ["--client-cert-handoff", str(handoff)],
+        env={**os.environ, "KV_STORAGE_DIR": str(tmp_path)},
+        stdout=subprocess.DEVNULL,
+        stderr=subprocess.DEVNULL,
+    )
+    try:
+        deadline = time.monotonic() + 30
+        while not Path(f"{handoff}.server").exists():
+            assert time.monotonic() < deadline, "server never published its certificate"
+            time.sleep(0.1)
+        with socket.create_connection(("127.0.0.1", port), timeout=30):
+            pass
+        yield port
+    finally:
+        server.terminate()
+        server.wait(timeout=10)
+
+
+def _put(soup_go: str, name: str, port: int, handoff: Path) -> subprocess.CompletedProcess:
+    return subprocess.run(
+        [soup_go, "rpc", "kv", "put", "k", "v", "--address", f"{name}:{port}"]
+        + ["--resolve", f"{name}:127.0.0.1", "--client-cert-handoff", str(handoff)],
+        capture_output=True,
+        text=True,
+        timeout=60,
+    )
+
+
+@pytest.fixture
+def soup_go(project_root: Path) -> str:
+    return str(ensure_go_harness_build("soup-go", project_root, load_tofusoup_config(project_root)))
+
+
+@pytest.mark.integration_rpc
+@pytest.mark.harness_go
+def test_resolved_name_is_verified(soup_go: str, tmp_path: Path) -> None:
+    handoff = tmp_path / "client.pem"
+    with _standalone_server(soup_go, tmp_path, handoff) as port:
+        accepted = _put(soup_go, "kv.soup.test", port, handoff)
+        rejected = _put(soup_go, "other.soup.test", port, handoff)
+
+    assert accepted.returncode == 0, accepted.stderr
+    assert rejected.returncode != 0
+    assert "certificate is valid for localhost, kv.soup.test, not other.soup.test" in rejected.stderr
+
+
+@pytest.mark.integration_rpc
+@pytest.mark.harness_go
+def test_malformed_resolve_is_rejected(soup_go: str) -> None:
+    result = subprocess.run(
+        [soup_go, "rpc", "kv", "get", "k", "--address", "kv.soup.test:50051", "--resolve", "kv.soup.test"],
+        capture_output=True,
+        text=True,
+        timeout=60,
+    )
+
+    assert result.returncode != 0
+    assert "expected NAME:ADDR" in result.stderr
+
+
+# 🥣🔬🔚
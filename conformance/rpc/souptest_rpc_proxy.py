@@ -0,0 +1,121 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""`soup-go rpc proxy`: HTTP/2 frame capture between a client and a server.
+
+KV calls made through the proxy reach a plaintext standalone server, and
+the proxy reports the RPCs reassembled from their frames, logs every frame
+as NDJSON with its direction and decoded fields, and writes a pcap file
+whose packets carry the bytes it forwarded.
+"""
+
+import json
+import os
+from pathlib import Path
+import signal
+import socket
+import struct
+import subprocess
+import sys
+import time
+
+import pytest
+
+from tofusoup.common.config import load_tofusoup_config
+from tofusoup.harness.logic import ensure_go_harness_build
+
+pytestmark = pytest.mark.skipif(sys.platform == "win32", reason="the proxy report is printed on SIGINT")
+
+
+def _free_port() -> int:
+    with socket.socket() as s:
+        s.bind(("127.0.0.1", 0))
+        return s.getsockname()[1]
+
+
+def _wait_for(path: Path, process: subprocess.Popen) -> str:
+    deadline = time.monotonic() + 10
+    while not path.exists() or not path.read_text().strip():
+        assert process.poll() is None and time.monotonic() < deadline, f"{path.name} was not written"
+        time.sleep(0.05)
+    return path.read_text().strip()
+
+
+@pytest.fixture
+def soup_go(project_root: Path) -> str:
+    return str(ensure_go_harness_build("soup-go", project_root, load_tofusoup_config(project_root)))
+
+
+@pytest.mark.integration_rpc
+@pytest.mark.harness_go
+def test_proxy_captures_frames_and_rpcs(soup_go: str, tmp_path: Path) -> None:
+    port = _free_port()
+    server = subprocess.Popen(
+        [soup_go, "rpc", "kv", "server", "--standalone", "--port", str(port)],
+        env={**os.environ, "KV_STORAGE_DIR": str(tmp_path)},
+        stdout=subprocess.DEVNULL,
+        stderr=subprocess.DEVNULL,
+    )
+    frame_log, pcap, address_file = tmp_path / "frames.ndjson", tmp_path / "kv.pcap", tmp_path / "addr"
+    proxy = subprocess.Popen(
+        [soup_go, "rpc", "proxy", "--target", f"127.0.0.1:{port}", "--frame-log", str(frame_log)]
+        + ["--pcap", str(pcap), "--address-file", str(address_file), "--output-format", "json"],
+        stdout=subprocess.PIPE,
+        stderr=subprocess.DEVNULL,
+        text=True,
+    )
+    try:
+        address = _wait_for(address_file, proxy)
+        time.sleep(0.5)
+        for args in (["put", "greeting", "hello"], ["get", "greeting"], ["get", "missing"]):
+            subprocess.run(
+                [soup_go, "rpc", "kv", *args, "--address", address], capture_output=True, timeout=60
+            )
+        proxy.send_signal(signal.SIGINT)
+        stdout, _ = proxy.communicate(timeout=30)
+    finally:
+        proxy.kill()
+        server.terminate()
+        server.wait(timeout=10)
+
+    assert proxy.returncode == 0
+    report = json.loads(stdout)
+    assert report["listen"] == address
+    calls = [
+        (rpc["method"], rpc["grpc_code"]) for rpc in report["rpcs"] if rpc["method"].startswith("/proto.KV/")
+    ]
+    assert calls == [("/proto.KV/Put", "OK"), ("/proto.KV/Get", "OK"), ("/proto.KV/Get", "NotFound")]
+    assert all(rpc["complete"] for rpc in report["rpcs"])
+    assert not any(conn["tls"] for conn in report["connections"])
+    assert report["frame_types"]["HEADERS"] > 0 and report["frame_types"]["SETTINGS"] > 0
+
+    frames = [json.loads(line) for line in frame_log.read_text().splitlines()]
+    assert len(frames) == report["frames"]
+    assert {frame["direction"] for frame in frames} == {"c2s", "s2c"}
+    paths = [
+        header["value"]
+        for frame in frames
+        for header in frame.get("headers", [])
+        if frame["direction"] == "c2s" and header["name"] == ":path"
+    ]
+    assert "/proto.KV/Put" in paths
+    assert any(frame["type"] == "SETTINGS" and "settings" in frame for frame in frames)
+
+    # Every byte forwarded is in the capture, as IPv4/TCP payload
+    data = pcap.read_bytes()
+    magic, _, _, _, _, _, linktype = struct.unpack("<IHHiIII", data[:24])
+    assert (magic, linktype) == (0xA1B2C3D4, 101)
+    offset, payload = 24, 0
+    while offset < len(data):
+        length = struct.unpack("<I", data[offset + 8 : offset + 12])[0]
+        packet = data[offset + 16 : offset + 16 + length]
+        assert packet[0] == 0x45 and packet[9] == 6
+        payload += length - 40
+        offset += 16 + length
+    forwarded = sum(conn["bytes_c2s"] + conn["bytes_s2c"] for conn in report["connections"])
+    assert payload == forwarded
+
+
+# 🥣🔬🔚
@@ -72,7 +72,10 @@ def verify_kv_storage(storage_dir: Path, key: str) -> Path | None:
 @pytest.mark.integration_rpc
 @pytest.mark.harness_go
 @pytest.mark.asyncio
-@pytest.mark.skip(reason="Python client → Go server not supported (pyvider-rpcplugin limitation)")
+@pytest.mark.tags(
+    feature="pyclient-goserver",
+    known_failure="Python client → Go server not supported (pyvider-rpcplugin limitation)",
+)
 async def test_pyclient_goserver_no_mtls(project_root: Path, test_artifacts_dir: Path) -> None:
     """Test Python client -> Go server without mTLS (SKIPPED - known limitation)"""
     config = load_tofusoup_config(project_root)
@@ -176,7 +179,10 @@ async def test_pyclient_goserver_no_mtls(project_root: Path, test_artifacts_dir:
 @pytest.mark.integration_rpc
 @pytest.mark.harness_go
 @pytest.mark.asyncio
-@pytest.mark.skip(reason="Python client → Go server not supported (pyvider-rpcplugin limitation)")
+@pytest.mark.tags(
+    feature="pyclient-goserver",
+    known_failure="Python client → Go server not supported (pyvider-rpcplugin limitation)",
+)
 async def test_pyclient_goserver_with_mtls_auto(project_root: Path, test_artifacts_dir: Path) -> None:
     """Test Python client -> Go server with auto mTLS (SKIPPED - known limitation)"""
     config = load_tofusoup_config(project_root)
@@ -283,7 +289,10 @@ async def test_pyclient_goserver_with_mtls_auto(project_root: Path, test_artifac
 @pytest.mark.integration_rpc
 @pytest.mark.harness_go
 @pytest.mark.asyncio
-@pytest.mark.skip(reason="Python client → Go server not supported (pyvider-rpcplugin limitation)")
+@pytest.mark.tags(
+    feature="pyclient-goserver",
+    known_failure="Python client → Go server not supported (pyvider-rpcplugin limitation)",
+)
 async def test_pyclient_goserver_with_mtls_ecdsa(project_root: Path, test_artifacts_dir: Path) -> None:
     """Test Python client -> Go server with auto mTLS using ECDSA (SKIPPED - known limitation)"""
     config = load_tofusoup_config(project_root)
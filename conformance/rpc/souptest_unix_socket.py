@@ -0,0 +1,109 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""Unix socket isolation for a standalone soup-go server on --socket.
+
+Plugin hosts rely on socket permissions to keep other users out, so the
+socket must be created with --socket-mode. On Linux the server also reads
+the caller's SO_PEERCRED: GetServerInfo reports it, and --allowed-uids
+closes connections from any other UID before a call is made.
+"""
+
+from collections.abc import Iterator
+import contextlib
+import json
+import os
+from pathlib import Path
+import stat
+import subprocess
+import sys
+import time
+
+import pytest
+
+from tofusoup.common.config import load_tofusoup_config
+from tofusoup.harness.logic import ensure_go_harness_build
+
+pytestmark = pytest.mark.skipif(sys.platform == "win32", reason="unix sockets are tested on unix only")
+
+
+@contextlib.contextmanager
+def _server(soup_go: str, tmp_path: Path, *args: str) -> Iterator[Path]:
+    socket = tmp_path / "kv.sock"
+    env = {**os.environ, "KV_STORAGE_DIR": str(tmp_path)}
+    server = subprocess.Popen(
+        [soup_go, "rpc", "kv", "server", "--standalone", "--socket", str(socket), *args],
+        env=env,
+        stdout=subprocess.DEVNULL,
+        stderr=subprocess.DEVNULL,
+    )
+    try:
+        deadline = time.monotonic() + 10
+        while not socket.exists():
+            assert server.poll() is None, "soup-go server exited before listening"
+            assert time.monotonic() < deadline, "soup-go server did not create its socket"
+            time.sleep(0.05)
+        yield socket
+    finally:
+        server.terminate()
+        server.wait(timeout=10)
+
+
+def _info(soup_go: str, socket: Path) -> subprocess.CompletedProcess:
+    return subprocess.run(
+        [soup_go, "rpc", "echo", "info", "--address", f"unix://{socket}"],
+        capture_output=True,
+        text=True,
+        timeout=60,
+    )
+
+
+@pytest.fixture
+def soup_go(project_root: Path) -> str:
+    return str(ensure_go_harness_build("soup-go", project_root, load_tofusoup_config(project_root)))
+
+
+@pytest.mark.integration_rpc
+@pytest.mark.harness_go
+@pytest.mark.parametrize("mode", ["0600", "0660"])
+def test_socket_mode(soup_go: str, tmp_path: Path, mode: str) -> None:
+    with _server(soup_go, tmp_path, "--socket-mode", mode) as socket:
+        assert stat.S_IMODE(socket.stat().st_mode) == int(mode, 8)
+        result = _info(soup_go, socket)
+        assert result.returncode == 0, result.stderr
+        info = json.loads(result.stdout)
+
+    assert info["transport"] == "unix"
+    assert info["address"] == str(socket)
+    assert info["socket_mode"] == mode
+    assert not socket.exists(), "the server left its socket behind"
+
+
+@pytest.mark.integration_rpc
+@pytest.mark.harness_go
+@pytest.mark.skipif(sys.platform != "linux", reason="SO_PEERCRED is linux only")
+def test_peer_credentials_reported(soup_go: str, tmp_path: Path) -> None:
+    with _server(soup_go, tmp_path, "--allowed-uids", str(os.getuid())) as socket:
+        result = _info(soup_go, socket)
+
+    assert result.returncode == 0, result.stderr
+    peer = json.loads(result.stdout)["peer"]
+    assert peer["available"]
+    assert (peer["uid"], peer["gid"]) == (os.getuid(), os.getgid())
+    assert peer["pid"] > 0
+
+
+@pytest.mark.integration_rpc
+@pytest.mark.harness_go
+@pytest.mark.skipif(sys.platform != "linux", reason="SO_PEERCRED is linux only")
+def test_disallowed_uid_is_closed(soup_go: str, tmp_path: Path) -> None:
+    with _server(soup_go, tmp_path, "--allowed-uids", str(os.getuid() + 1)) as socket:
+        result = _info(soup_go, socket)
+
+    assert result.returncode != 0
+    assert json.loads(result.stdout.splitlines()[0])["error"]["code"] == "Unavailable"
+
+
+# 🥣🔬🔚
@@ -0,0 +1,108 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""What JSON and msgpack encoding preserve, as `soup-go wire fidelity` reports it.
+
+The goldens document go-cty's behavior: both encodings round-trip known
+values exactly and write object keys sorted, msgpack keeps unknowns and
+their refinements while JSON can't encode them, and msgpack falls back to a
+string for numbers float64 can't hold. Reading JSON input the way
+`wire encode` does (numbers as float64) is where precision is lost.
+"""
+
+import json
+from pathlib import Path
+
+import pytest
+
+from ..cli_verification.shared_cli_utils import run_harness_cli
+
+TYPE = [
+    "object",
+    {
+        "big": "number",
+        "count": "number",
+        "price": "number",
+        "ratio": "number",
+        "tags": ["map", "string"],
+    },
+]
+VALUE = '{"price": 0.1, "ratio": 1.5, "count": 3, "big": 12345678901234567890, "tags": {"z": "1", "a": "2"}}'
+
+UNKNOWN_TYPE = ["object", {"id": "string", "image": "string"}]
+UNKNOWN_VALUE = {"id": "i-1", "image": {"$unknown": True, "refinements": {"string_prefix": "ami-"}}}
+
+
+def _fidelity(
+    executable: Path, project_root: Path, tmp_path: Path, value: str, ty: object, test_id: str
+) -> dict:
+    value_file, type_file = tmp_path / "value.json", tmp_path / "type.json"
+    value_file.write_text(value)
+    type_file.write_text(json.dumps(ty))
+    exit_code, stdout, stderr = run_harness_cli(
+        executable=executable,
+        args=["wire", "fidelity", "--value", str(value_file), "--type", str(type_file)],
+        project_root=project_root,
+        harness_artifact_name="soup-go",
+        test_id=test_id,
+    )
+    assert exit_code == 0, f"soup-go wire fidelity failed: {stderr}"
+    return json.loads(stdout)
+
+
+@pytest.mark.integration_wire
+@pytest.mark.harness_go
+@pytest.mark.parametrize("go_harness_executable", ["soup-go"], indirect=True)
+def test_go_known_values_round_trip_exactly(
+    go_harness_executable: Path, project_root: Path, tmp_path: Path
+) -> None:
+    report = _fidelity(go_harness_executable, project_root, tmp_path, VALUE, TYPE, "wire_fidelity_known")
+
+    assert report["agree"]
+    assert report["json"]["differences"] == report["msgpack"]["differences"] == []
+    assert {n["path"]: n["msgpack"] for n in report["numbers"]} == {
+        "big": "string",
+        "count": "int",
+        "price": "string",
+        "ratio": "float64",
+    }
+    assert [k["path"] for k in report["key_order"]] == ["", "tags"]
+    assert report["key_order"][1]["encoded"] == ["a", "z"]
+    # cty compares numbers by value, so 0.1 survives float64; the 20-digit
+    # integer doesn't
+    assert report["read"]["differences"] == [
+        {
+            "path": "big",
+            "kind": "number_precision",
+            "original": "12345678901234567890",
+            "decoded": "12345678901234567000",
+        }
+    ]
+
+
+@pytest.mark.integration_wire
+@pytest.mark.harness_go
+@pytest.mark.parametrize("go_harness_executable", ["soup-go"], indirect=True)
+def test_go_only_msgpack_carries_unknowns(
+    go_harness_executable: Path, project_root: Path, tmp_path: Path
+) -> None:
+    report = _fidelity(
+        go_harness_executable,
+        project_root,
+        tmp_path,
+        json.dumps(UNKNOWN_VALUE),
+        UNKNOWN_TYPE,
+        "wire_fidelity_unknown",
+    )
+
+    assert not report["agree"]
+    assert report["read"]["skipped"]
+    assert not report["json"]["ok"]
+    assert [(d["path"], d["kind"]) for d in report["json"]["differences"]] == [("image", "unknown_lost")]
+    assert report["msgpack"]["ok"]
+    assert report["msgpack"]["differences"] == []
+
+
+# 🥣🔬🔚
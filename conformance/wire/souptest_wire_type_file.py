@@ -0,0 +1,116 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""Payload and type as separate artifacts: `wire encode --emit-type-file`.
+
+A DynamicValue travels as bare msgpack with its type sent out-of-band in a
+schema. The type file soup-go writes next to the payload records both
+digests, and `wire decode --type-file` must refuse a payload or type that
+doesn't match them.
+"""
+
+import hashlib
+import json
+from pathlib import Path
+
+import msgpack
+import pytest
+
+from ..cli_verification.shared_cli_utils import run_harness_cli
+
+VALUE = {"a": [1, 2], "b": "x"}
+TYPE = ["object", {"a": ["list", "number"], "b": "string"}]
+
+
+def _soup_go(executable: Path, project_root: Path, test_id: str, *args: str) -> tuple:
+    return run_harness_cli(
+        executable=executable,
+        args=["wire", *args],
+        project_root=project_root,
+        harness_artifact_name="soup-go",
+        test_id=test_id,
+    )
+
+
+def _decode(executable: Path, project_root: Path, test_id: str, payload: Path, type_file: Path) -> tuple:
+    return _soup_go(executable, project_root, test_id, "decode", str(payload), "--type-file", str(type_file))
+
+
+def _encode(executable: Path, project_root: Path, tmp_path: Path) -> tuple[Path, Path]:
+    value_file = tmp_path / "value.json"
+    value_file.write_text(json.dumps(VALUE))
+    payload, type_file = tmp_path / "payload" / "value.msgpack", tmp_path / "value.type.json"
+    payload.parent.mkdir()
+    exit_code, _, stderr = _soup_go(
+        executable,
+        project_root,
+        "wire_encode_emit_type_file",
+        "encode",
+        str(value_file),
+        str(payload),
+        "--type",
+        json.dumps(TYPE),
+        "--emit-type-file",
+        str(type_file),
+    )
+    assert exit_code == 0, stderr
+    return payload, type_file
+
+
+@pytest.mark.integration_wire
+@pytest.mark.harness_go
+@pytest.mark.parametrize("go_harness_executable", ["soup-go"], indirect=True)
+def test_type_file_references_payload(go_harness_executable: Path, project_root: Path, tmp_path: Path) -> None:
+    payload, type_file = _encode(go_harness_executable, project_root, tmp_path)
+    artifact = json.loads(type_file.read_text())
+    data = payload.read_bytes()
+
+    assert msgpack.unpackb(data) == VALUE
+    assert artifact["version"] == 1
+    assert artifact["type"] == TYPE
+    compact_type = json.dumps(TYPE, separators=(",", ":")).encode()
+    assert artifact["type_sha256"] == hashlib.sha256(compact_type).hexdigest()
+    assert artifact["payload"] == {
+        "path": "payload/value.msgpack",
+        "format": "msgpack",
+        "wire_version": 1,
+        "size": len(data),
+        "sha256": hashlib.sha256(data).hexdigest(),
+    }
+
+    exit_code, stdout, stderr = _decode(
+        go_harness_executable, project_root, "wire_decode_type_file", payload, type_file
+    )
+    assert exit_code == 0, stderr
+    assert json.loads(stdout) == VALUE
+
+
+@pytest.mark.integration_wire
+@pytest.mark.harness_go
+@pytest.mark.parametrize("go_harness_executable", ["soup-go"], indirect=True)
+def test_mismatched_artifacts_are_refused(
+    go_harness_executable: Path, project_root: Path, tmp_path: Path
+) -> None:
+    payload, type_file = _encode(go_harness_executable, project_root, tmp_path)
+
+    other = tmp_path / "other.msgpack"
+    other.write_bytes(msgpack.packb({"a": [1], "b": "x"}))
+    exit_code, _, stderr = _decode(
+        go_harness_executable, project_root, "wire_decode_wrong_payload", other, type_file
+    )
+    assert exit_code != 0
+    assert "but the type file was written for" in stderr
+
+    artifact = json.loads(type_file.read_text())
+    artifact["type"] = ["object", {"a": ["list", "string"], "b": "string"}]
+    type_file.write_text(json.dumps(artifact))
+    exit_code, _, stderr = _decode(
+        go_harness_executable, project_root, "wire_decode_wrong_type", payload, type_file
+    )
+    assert exit_code != 0
+    assert "but type_sha256 is" in stderr
+
+
+# 🥣🔬🔚
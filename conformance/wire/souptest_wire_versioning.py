@@ -0,0 +1,168 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""Wire format versioning: version 2 headers, detection and future versions.
+
+Version 1 is bare msgpack. Version 2 prefixes an 8-byte header that Python
+(tofusoup.wire.logic) and soup-go must write identically and detect from
+each other. A version newer than the latest known must be rejected with
+UNSUPPORTED_WIRE_VERSION unless the reader opts into skipping its header.
+"""
+
+import json
+from pathlib import Path
+import struct
+
+import msgpack
+import pytest
+
+from tofusoup.wire.logic import (
+    WIRE_HEADER_MAGIC,
+    WireHeader,
+    WireVersionError,
+    frame_wire_payload,
+    parse_wire_header,
+)
+
+from ..cli_verification.shared_cli_utils import run_harness_cli
+
+VALUE = {"a": [1, 2], "b": "x"}
+TYPE = ["object", {"a": ["list", "number"], "b": "string"}]
+# Typed v2 encoding of VALUE: header (flags 0x01), then cty msgpack
+V2_TYPED_GOLDEN = bytes.fromhex("c1545702000801" + "00" + "82a161920102a162a178")
+
+
+def _future_frame(payload: bytes, version: int = 3, extra: bytes = b"\xff\xee") -> bytes:
+    """A header from a later version that appends fields after the v2 ones."""
+    length = 8 + len(extra)
+    return WIRE_HEADER_MAGIC + struct.pack(">BHBB", version, length, 0, 0) + extra + payload
+
+
+def test_python_frames_golden_header() -> None:
+    payload = V2_TYPED_GOLDEN[8:]
+    assert frame_wire_payload(payload, typed=True) == V2_TYPED_GOLDEN
+    header, rest = parse_wire_header(V2_TYPED_GOLDEN)
+    assert header == WireHeader(wire_version=2, framed=True, header_length=8, typed=True)
+    assert rest == payload
+
+
+def test_bare_msgpack_is_version_1() -> None:
+    data = msgpack.packb(VALUE)
+    assert parse_wire_header(data) == (WireHeader(wire_version=1), data)
+
+
+def test_future_version_is_rejected_unless_allowed() -> None:
+    payload = msgpack.packb(VALUE)
+    with pytest.raises(WireVersionError) as exc:
+        parse_wire_header(_future_frame(payload))
+    assert exc.value.code == "UNSUPPORTED_WIRE_VERSION"
+    assert exc.value.version == 3
+
+    header, rest = parse_wire_header(_future_frame(payload), allow_future=True)
+    assert (header.wire_version, header.header_length, header.assumed_version) == (3, 10, 2)
+    assert rest == payload
+
+
+@pytest.mark.parametrize(
+    "data",
+    [
+        b"\xc1",
+        b"\xc1XX\x02\x00\x08\x00\x00",
+        WIRE_HEADER_MAGIC + b"\x01\x00\x08\x00\x00",
+        WIRE_HEADER_MAGIC + b"\x02\x00\x40\x00\x00",
+        WIRE_HEADER_MAGIC + b"\x02\x00\x08\x80\x00",
+    ],
+    ids=["truncated", "bad_magic", "framed_v1", "length_past_end", "unknown_flag"],
+)
+def test_malformed_headers_are_invalid(data: bytes) -> None:
+    with pytest.raises(WireVersionError) as exc:
+        parse_wire_header(data)
+    assert exc.value.code == "INVALID_WIRE_HEADER"
+
+
+@pytest.mark.integration_wire
+@pytest.mark.harness_go
+@pytest.mark.parametrize("go_harness_executable", ["soup-go"], indirect=True)
+def test_go_encodes_golden_v2(go_harness_executable: Path, project_root: Path, tmp_path: Path) -> None:
+    input_file, output_file = tmp_path / "value.json", tmp_path / "value.tfw"
+    input_file.write_text(json.dumps(VALUE))
+    exit_code, _, stderr = run_harness_cli(
+        executable=go_harness_executable,
+        args=[
+            "wire",
+            "encode",
+            str(input_file),
+            str(output_file),
+            "--wire-version",
+            "2",
+            "--type",
+            json.dumps(TYPE),
+            "--enable-feature",
+            "wire-v2",
+        ],
+        project_root=project_root,
+        harness_artifact_name="soup-go",
+        test_id="wire_encode_v2",
+    )
+    assert exit_code == 0, f"soup-go wire encode --wire-version 2 failed: {stderr}"
+    assert output_file.read_bytes() == V2_TYPED_GOLDEN
+
+
+@pytest.mark.integration_wire
+@pytest.mark.harness_go
+@pytest.mark.parametrize("go_harness_executable", ["soup-go"], indirect=True)
+@pytest.mark.parametrize(
+    "framing, expected_report",
+    [
+        ("v1", {"wire_version": 1, "framed": False, "header_length": 0, "typed": False}),
+        ("v2", {"wire_version": 2, "framed": True, "header_length": 8, "typed": False}),
+        (
+            "future",
+            {"wire_version": 3, "framed": True, "header_length": 10, "typed": False, "assumed_version": 2},
+        ),
+    ],
+)
+def test_go_detects_python_framing(
+    go_harness_executable: Path, project_root: Path, tmp_path: Path, framing: str, expected_report: dict
+) -> None:
+    payload = msgpack.packb(VALUE)
+    data = {"v1": payload, "v2": frame_wire_payload(payload), "future": _future_frame(payload)}[framing]
+    input_file = tmp_path / f"{framing}.tfw"
+    input_file.write_bytes(data)
+
+    exit_code, stdout, stderr = run_harness_cli(
+        executable=go_harness_executable,
+        args=["wire", "decode", str(input_file), "--report-version", "--allow-future-versions"],
+        project_root=project_root,
+        harness_artifact_name="soup-go",
+        test_id=f"wire_decode_{framing}",
+    )
+    assert exit_code == 0, f"soup-go wire decode failed: {stderr}"
+    assert json.loads(stdout) == VALUE
+    reports = [json.loads(line) for line in stderr.splitlines() if line.startswith('{"wire_version"')]
+    assert reports == [expected_report]
+
+
+@pytest.mark.integration_wire
+@pytest.mark.harness_go
+@pytest.mark.parametrize("go_harness_executable", ["soup-go"], indirect=True)
+def test_go_rejects_future_version(go_harness_executable: Path, project_root: Path, tmp_path: Path) -> None:
+    input_file = tmp_path / "future.tfw"
+    input_file.write_bytes(_future_frame(msgpack.packb(VALUE), version=9))
+    exit_code, stdout, _ = run_harness_cli(
+        executable=go_harness_executable,
+        args=["wire", "decode", str(input_file)],
+        project_root=project_root,
+        harness_artifact_name="soup-go",
+        test_id="wire_decode_future_rejected",
+    )
+    assert exit_code != 0
+    error = json.loads(stdout.splitlines()[0])["error"]
+    assert error["code"] == "UNSUPPORTED_WIRE_VERSION"
+    assert error["details"]["version"] == 9
+    assert error["details"]["supported"] == [1, 2]
+
+
+# 🥣🔬🔚
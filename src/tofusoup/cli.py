@@ -8,6 +8,7 @@
 import os
 import pathlib
 import sys
+import time
 
 from attrs import evolve
 import click
@@ -15,6 +16,7 @@ from provide.foundation import LoggingConfig, TelemetryConfig, get_hub, logger
 from rich import print as rich_print_direct
 from rich.tree import Tree
 
+from tofusoup.common.command_stats import collect_command_stats, write_command_stats
 from tofusoup.common.config import TofuSoupConfig, TofuSoupConfigError, load_tofusoup_config
 from tofusoup.common.lazy_group import LazyGroup
 from tofusoup.common.rich_utils import build_rich_tree_from_dict
@@ -26,6 +28,9 @@ from tofusoup.config.defaults import LOG_LEVELS
 # NOTE: We delay Foundation initialization until entry_point() to avoid event loop conflicts in plugin mode
 hub = get_hub()
 
+# Start of the --stats wall clock: as early in the process as the CLI gets
+_PROCESS_START = time.perf_counter()
+
 LAZY_COMMANDS = {
     "sui": ("tofusoup.browser.cli", "sui_cli"),
     "registry": ("tofusoup.registry.cli", "registry_cli"),
@@ -61,8 +66,28 @@ LAZY_COMMANDS = {
     default=None,
     help="Path to a specific TofuSoup configuration file.",
 )
-def main_cli(ctx: click.Context, verbose: bool, log_level: str | None, config_file: str | None) -> None:
+@click.option(
+    "--stats",
+    is_flag=True,
+    help='Print wall time, CPU, max RSS and GC stats as a final {"stats": ...} JSON line.',
+)
+@click.option(
+    "--stats-file",
+    type=click.Path(dir_okay=False, path_type=pathlib.Path),
+    default=None,
+    help="Write the --stats line to this file instead of stdout (implies --stats).",
+)
+def main_cli(
+    ctx: click.Context,
+    verbose: bool,
+    log_level: str | None,
+    config_file: str | None,
+    stats: bool,
+    stats_file: pathlib.Path | None,
+) -> None:
     ctx.obj = ctx.obj or {}
+    if stats or stats_file:
+        ctx.call_on_close(lambda: _report_command_stats(ctx, stats_file))
 
     final_log_level = "DEBUG" if verbose else log_level
     if final_log_level:
@@ -119,6 +144,40 @@ def show_config_command(ctx: click.Context) -> None:
 main_cli.add_command(config_cli)
 
 
+def _command_path(ctx: click.Context) -> str:
+    """The subcommand names in argv, e.g. "cty convert", skipping options."""
+    words: list[str] = []
+    command: click.Command = ctx.command
+    for arg in sys.argv[1:]:
+        if arg.startswith("-") or not isinstance(command, click.Group):
+            continue
+        sub = command.get_command(ctx, arg)
+        if sub is None:
+            # An option's value, or the first positional argument of a group
+            if words:
+                break
+            continue
+        words.append(arg)
+        command = sub
+    return " ".join(words)
+
+
+def _report_command_stats(ctx: click.Context, stats_file: pathlib.Path | None) -> None:
+    """Write --stats when the root context closes, after the command's output.
+
+    Runs while any exception from the command is still propagating, so
+    sys.exc_info() tells success from failure.
+    """
+    exc = sys.exc_info()[1]
+    if isinstance(exc, click.exceptions.Exit):
+        success = exc.exit_code == 0
+    elif isinstance(exc, SystemExit):
+        success = exc.code in (0, None)
+    else:
+        success = exc is None
+    write_command_stats(collect_command_stats(_command_path(ctx), success, _PROCESS_START), stats_file)
+
+
 def entry_point() -> None:
     """
     CLI entry point with automatic plugin server detection.
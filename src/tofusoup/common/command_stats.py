@@ -0,0 +1,84 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""Per-command wall time and resource usage for `soup --stats`.
+
+The report has the same shape as soup-go's, so performance comparisons can
+come from the harnesses themselves rather than external `time` wrappers:
+
+    {"stats": {"harness": "soup", "language": "python", "command": "cty convert",
+               "success": true, "wall_ms": ..., "user_cpu_ms": ..., "sys_cpu_ms": ...,
+               "max_rss_bytes": ..., "gc": {"collections": ..., ...}}}
+
+CPU and RSS are null where the platform has no getrusage. The gc keys after
+"collections" are language specific."""
+
+import gc
+import json
+from pathlib import Path
+import sys
+import time
+from typing import Any
+
+try:
+    import resource
+except ImportError:  # windows
+    resource = None  # type: ignore[assignment]
+
+STATS_MARKER = '{"stats":'
+
+
+def collect_command_stats(command: str, success: bool, started: float) -> dict[str, Any]:
+    """Measure the process so far; started is a time.perf_counter() reading."""
+    user_ms = sys_ms = max_rss = None
+    if resource is not None:
+        usage = resource.getrusage(resource.RUSAGE_SELF)
+        user_ms = usage.ru_utime * 1000
+        sys_ms = usage.ru_stime * 1000
+        # ru_maxrss is bytes on darwin and kilobytes elsewhere
+        max_rss = usage.ru_maxrss if sys.platform == "darwin" else usage.ru_maxrss * 1024
+
+    generations = gc.get_stats()
+    return {
+        "harness": "soup",
+        "language": "python",
+        "command": command,
+        "success": success,
+        "wall_ms": (time.perf_counter() - started) * 1000,
+        "user_cpu_ms": user_ms,
+        "sys_cpu_ms": sys_ms,
+        "max_rss_bytes": max_rss,
+        "gc": {
+            "collections": sum(g["collections"] for g in generations),
+            "collected": sum(g["collected"] for g in generations),
+            "uncollectable": sum(g["uncollectable"] for g in generations),
+        },
+    }
+
+
+def write_command_stats(stats: dict[str, Any], stats_file: Path | None) -> None:
+    """Write one {"stats": ...} line to stats_file, or to stdout if None."""
+    line = json.dumps({"stats": stats}) + "\n"
+    if stats_file is None:
+        sys.stdout.write(line)
+        sys.stdout.flush()
+    else:
+        stats_file.write_text(line)
+
+
+def split_command_stats(stdout: str) -> tuple[str, dict[str, Any] | None]:
+    """
+    Separate a --stats line from the output before it.
+
+    The line is always last but may follow output with no trailing newline,
+    so this splits on the marker rather than on lines.
+    """
+    index = stdout.rfind(STATS_MARKER)
+    if index < 0:
+        return stdout, None
+    return stdout[:index], json.loads(stdout[index:])["stats"]
+
+
+# 🥣🔬🔚
@@ -0,0 +1,192 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""Colored, human-friendly diff rendering for the --pretty diff option.
+
+When both sides parse as JSON they are compared structurally and each
+difference is one line naming its path, e.g.
+
+    ~ $.resources[0].values.ami: "ami-123" → "ami-456"
+    + $.resources[0].values.tags: {…} map of 3 keys
+    - $.outputs: […] list of 120 items
+
+Added and removed collections are summarized rather than printed, and long
+strings are truncated, so a large document's diff stays readable.
+Anything else falls back to a unified diff with colored lines.
+
+The render functions return Rich markup lines for rich.print."""
+
+from collections.abc import Callable
+import difflib
+import json
+from typing import Any
+
+from rich.markup import escape
+
+# Strings longer than this many characters are truncated
+MAX_STRING = 80
+
+ADD = "add"
+REMOVE = "remove"
+CHANGE = "change"
+
+_STYLES = {ADD: ("green", "+"), REMOVE: ("red", "-"), CHANGE: ("yellow", "~")}
+
+
+def truncate(text: str, limit: int = MAX_STRING) -> str:
+    """Shorten text to limit characters, noting how many were cut."""
+    if len(text) <= limit:
+        return text
+    return f"{text[:limit]}… (+{len(text) - limit} chars)"
+
+
+def summarize(value: Any, limit: int = MAX_STRING) -> str:
+    """A one-line rendering of value: collections by size, scalars as JSON."""
+    if isinstance(value, dict):
+        return f"{{…}} map of {len(value)} key{'s' * (len(value) != 1)}"
+    if isinstance(value, list):
+        return f"[…] list of {len(value)} item{'s' * (len(value) != 1)}"
+    if isinstance(value, str):
+        return json.dumps(truncate(value, limit), ensure_ascii=False)
+    return json.dumps(value)
+
+
+# A list matcher pairs up the elements of two lists at a path, as (left
+# index, right index) with None for an element the other side lacks, or
+# returns None to leave the lists compared by index
+ListMatcher = Callable[[str, list[Any], list[Any]], list[tuple[int | None, int | None]] | None]
+
+
+def json_diff(
+    left: Any,
+    right: Any,
+    path: str = "$",
+    close: Callable[[float, float], bool] | None = None,
+    match_lists: ListMatcher | None = None,
+) -> list[tuple[str, str, Any, Any]]:
+    """Structural differences between two JSON values as (kind, path, left,
+    right) tuples, in document order. Maps are compared by key, lists by
+    index; a value whose type changed is one change. Numbers where either
+    is a float and close(left, right) holds are treated as equal.
+
+    match_lists may pair list elements some other way, e.g. to compare
+    sets whatever their order. A pair at different indexes has the path
+    [i~j]; an added element's index is on the right."""
+    if isinstance(left, dict) and isinstance(right, dict):
+        changes = []
+        for key in sorted(left.keys() | right.keys()):
+            child = f"{path}.{key}"
+            if key not in right:
+                changes.append((REMOVE, child, left[key], None))
+            elif key not in left:
+                changes.append((ADD, child, None, right[key]))
+            else:
+                changes.extend(json_diff(left[key], right[key], child, close, match_lists))
+        return changes
+    if isinstance(left, list) and isinstance(right, list):
+        pairs = match_lists(path, left, right) if match_lists is not None else None
+        if pairs is None:
+            pairs = [
+                (i if i < len(left) else None, i if i < len(right) else None)
+                for i in range(max(len(left), len(right)))
+            ]
+        changes = []
+        for i, j in pairs:
+            if j is None:
+                changes.append((REMOVE, f"{path}[{i}]", left[i], None))
+            elif i is None:
+                changes.append((ADD, f"{path}[{j}]", None, right[j]))
+            else:
+                child = f"{path}[{i}]" if i == j else f"{path}[{i}~{j}]"
+                changes.extend(json_diff(left[i], right[j], child, close, match_lists))
+        return changes
+    # bool is an int in Python, but true and 1 differ in JSON
+    if left == right and isinstance(left, bool) == isinstance(right, bool):
+        return []
+    if close is not None and _tolerable(left, right) and close(left, right):
+        return []
+    return [(CHANGE, path, left, right)]
+
+
+def _tolerable(left: Any, right: Any) -> bool:
+    """Whether two JSON values are numbers that tolerance applies to."""
+    numbers = all(isinstance(v, int | float) and not isinstance(v, bool) for v in (left, right))
+    return numbers and (isinstance(left, float) or isinstance(right, float))
+
+
+def render_json_diff(changes: list[tuple[str, str, Any, Any]], limit: int = MAX_STRING) -> list[str]:
+    """Markup lines for json_diff's changes, ending with a count of each kind."""
+    lines = []
+    for kind, path, left, right in changes:
+        color, sign = _STYLES[kind]
+        if kind == ADD:
+            detail = summarize(right, limit)
+        elif kind == REMOVE:
+            detail = summarize(left, limit)
+        else:
+            detail = f"{summarize(left, limit)} → {summarize(right, limit)}"
+        lines.append(f"[{color}]{sign} {escape(path)}: {escape(detail)}[/{color}]")
+
+    counts = {kind: sum(1 for change in changes if change[0] == kind) for kind in _STYLES}
+    plural = "s" * (len(changes) != 1)
+    lines.append(
+        f"[bold]{len(changes)} difference{plural}:[/bold] [yellow]{counts[CHANGE]} changed[/yellow], "
+        f"[green]{counts[ADD]} added[/green], [red]{counts[REMOVE]} removed[/red]"
+    )
+    return lines
+
+
+def render_unified_diff(diff_lines: list[str], limit: int = MAX_STRING * 2) -> list[str]:
+    """Markup lines for a unified diff, colored by line type, with long
+    lines truncated."""
+    lines = []
+    for line in diff_lines:
+        text = escape(truncate(line.rstrip("\n"), limit))
+        if line.startswith(("+++", "---")):
+            lines.append(f"[bold]{text}[/bold]")
+        elif line.startswith("@@"):
+            lines.append(f"[cyan]{text}[/cyan]")
+        elif line.startswith("+"):
+            lines.append(f"[green]{text}[/green]")
+        elif line.startswith("-"):
+            lines.append(f"[red]{text}[/red]")
+        else:
+            lines.append(f"[dim]{text}[/dim]")
+    return lines
+
+
+def render_pretty_diff(
+    left_text: str,
+    right_text: str,
+    left_name: str,
+    right_name: str,
+    close: Callable[[float, float], bool] | None = None,
+    match_lists: ListMatcher | None = None,
+) -> list[str]:
+    """Markup lines comparing two outputs: structurally if both are JSON and
+    their values differ, otherwise as a colored unified diff (so outputs
+    differing only in formatting still show up). Empty if they match.
+    close and match_lists are passed to json_diff."""
+    try:
+        left_value, right_value = json.loads(left_text), json.loads(right_text)
+        changes = json_diff(left_value, right_value, close=close, match_lists=match_lists)
+    except ValueError:
+        changes = []
+    if changes:
+        return [
+            f"[bold]--- {escape(left_name)}[/bold]",
+            f"[bold]+++ {escape(right_name)}[/bold]",
+            *render_json_diff(changes),
+        ]
+    diff = difflib.unified_diff(
+        left_text.splitlines(keepends=True),
+        right_text.splitlines(keepends=True),
+        fromfile=left_name,
+        tofile=right_name,
+    )
+    return render_unified_diff(list(diff))
+
+
+# 🥣🔬🔚
@@ -0,0 +1,244 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""Unicode normalization and length conformance probes.
+
+`soup cty unicode` and `soup hcl unicode` run the same suite of strings as
+soup-go's `cty unicode` and `hcl unicode` and emit reports with the same
+shape, so the two can be diffed to find where runtimes disagree. They do
+disagree by design in places, and the report shows where:
+
+- Python strings keep unpaired surrogates (lone_surrogates) where Go
+  substitutes U+FFFD (replacement_chars), and such strings have no UTF-8
+  encoding, so utf8_bytes is null.
+- Python's standard library has no grapheme segmentation, so graphemes is
+  null, and length and first count code points where go-cty's strlen and
+  substr count grapheme clusters.
+- str.upper applies full case mappings (ß becomes SS) where Go maps one
+  rune to one rune.
+
+Inputs are JSON string escapes in ASCII, so every harness decodes the same
+text; strings in reports escape everything outside printable ASCII."""
+
+from collections.abc import Callable
+import json
+from typing import Any
+import unicodedata
+
+# (name, category, input) cases, mirroring unicodeProbeSuite in soup-go's
+# cty_unicode.go
+UNICODE_PROBE_SUITE: list[tuple[str, str, str]] = [
+    ("ascii", "baseline", "hello"),
+    ("nfc-e-acute", "normalization", "caf\\u00e9"),
+    ("nfd-e-acute", "normalization", "cafe\\u0301"),
+    ("nfd-hangul", "normalization", "\\u1112\\u1161\\u11ab"),
+    ("compat-ligature", "normalization", "\\ufb01le"),
+    ("stacked-marks", "grapheme", "a\\u0308\\u0304"),
+    ("emoji-zwj-family", "grapheme", "\\ud83d\\udc68\\u200d\\ud83d\\udc69\\u200d\\ud83d\\udc67"),
+    ("emoji-flag", "grapheme", "\\ud83c\\uddfa\\ud83c\\uddf8"),
+    ("emoji-skin-tone", "grapheme", "\\ud83d\\udc4d\\ud83c\\udffd"),
+    ("astral-clef", "surrogates", "\\ud834\\udd1e"),
+    ("lone-high-surrogate", "surrogates", "\\ud800"),
+    ("lone-low-surrogate", "surrogates", "x\\udc00y"),
+    ("rtl-hebrew", "bidi", "\\u05e9\\u05dc\\u05d5\\u05dd"),
+    ("rtl-mixed", "bidi", "abc \\u05e9\\u05dc\\u05d5\\u05dd 123"),
+    ("rtl-override", "bidi", "\\u202eabc\\u202c"),
+    ("bom-prefix", "invisible", "\\ufeffkey"),
+    ("zero-width-space", "invisible", "a\\u200bb"),
+    ("case-sharp-s", "case", "stra\\u00dfe"),
+    ("case-dotted-i", "case", "\\u0130stanbul"),
+    ("case-final-sigma", "case", "\\u039f\\u0394\\u039f\\u03a3"),
+]
+
+_SURROGATES = range(0xD800, 0xE000)
+
+
+def ascii_escape(text: str) -> str:
+    """text as a JSON string body with everything outside printable ASCII
+    escaped, as soup-go reports strings."""
+    return json.dumps(text)[1:-1]
+
+
+def decode_probe_input(text: str) -> str:
+    """Decode a case's JSON string body, keeping unpaired surrogates."""
+    return json.loads(f'"{text}"')
+
+
+def measure_unicode(text: str) -> dict[str, Any]:
+    """Measure a decoded string the way soup-go's measureUnicode does."""
+    lone = sum(1 for char in text if ord(char) in _SURROGATES)
+    return {
+        "utf8_bytes": None if lone else len(text.encode("utf-8")),
+        "utf16_units": len(text.encode("utf-16-le", "surrogatepass")) // 2,
+        "code_points": len(text),
+        "graphemes": None,
+        "nfc": unicodedata.is_normalized("NFC", text),
+        "nfd": unicodedata.is_normalized("NFD", text),
+        "replacement_chars": text.count("\N{REPLACEMENT CHARACTER}"),
+        "lone_surrogates": lone,
+    }
+
+
+def select_unicode_cases(names: list[str] | tuple[str, ...] = ()) -> list[tuple[str, str, str]]:
+    """The suite cases named, or all of them."""
+    if not names:
+        return list(UNICODE_PROBE_SUITE)
+    by_name = {case[0]: case for case in UNICODE_PROBE_SUITE}
+    cases = []
+    for name in names:
+        if name not in by_name:
+            known = ", ".join(by_name)
+            raise ValueError(f'unknown case "{name}" (expected one of: {known})')
+        cases.append(by_name[name])
+    return cases
+
+
+def run_unicode_probe(
+    probe_name: str,
+    names: list[str] | tuple[str, ...],
+    probe: Callable[[str, str], dict[str, Any]],
+) -> dict[str, Any]:
+    """Decode and measure each case, add probe(input, decoded) under
+    probe_name, and return the report."""
+    reports = []
+    for name, category, text in select_unicode_cases(names):
+        decoded = decode_probe_input(text)
+        reports.append(
+            {
+                "name": name,
+                "category": category,
+                "input": text,
+                "decoded": ascii_escape(decoded),
+                "measures": measure_unicode(decoded),
+                probe_name: probe(text, decoded),
+            }
+        )
+    return {"probe": probe_name, "harness": "soup", "cases": reports}
+
+
+def _string_functions(result: dict[str, Any], value: str) -> None:
+    """Python's length, first character and case mappings, by code point."""
+    result.update(
+        length=len(value),
+        first=ascii_escape(value[:1]),
+        upper=ascii_escape(value.upper()),
+        lower=ascii_escape(value.lower()),
+    )
+
+
+def probe_cty_string(text: str, decoded: str) -> dict[str, Any]:
+    """Run a case through pyvider.cty validation and the JSON and msgpack
+    round trips, as soup-go's probeCtyString does with go-cty."""
+    from pyvider.cty import CtyString
+    from pyvider.cty.codec import cty_from_msgpack, cty_to_msgpack
+
+    result: dict[str, Any] = {
+        "value": "",
+        "normalized": False,
+        "length": 0,
+        "first": "",
+        "upper": "",
+        "lower": "",
+        "json_roundtrip": False,
+        "msgpack_bytes": 0,
+        "msgpack_roundtrip": False,
+    }
+    try:
+        cty_value = CtyString().validate(decoded)
+    except Exception as e:
+        result["error"] = str(e)
+        return result
+    value = cty_value.value
+    result.update(value=ascii_escape(value), normalized=value != decoded)
+    _string_functions(result, value)
+    result["json_roundtrip"] = json.loads(json.dumps(value)) == value
+    try:
+        packed = cty_to_msgpack(cty_value, CtyString())
+    except Exception as e:
+        result["error"] = str(e)
+        return result
+    result["msgpack_bytes"] = len(packed)
+    try:
+        result["msgpack_roundtrip"] = cty_from_msgpack(packed, CtyString()).value == value
+    except Exception:
+        result["msgpack_roundtrip"] = False
+    return result
+
+
+def hcl_escaped_source(text: str) -> str:
+    """A case's JSON string body as an HCL quoted string. HCL has no
+    surrogate pairs, so a pair becomes one \\U escape of the character it
+    encodes; unpaired surrogates stay \\u escapes, which HCL rejects."""
+    out = ['"']
+    i = 0
+    while i < len(text):
+        unit = _json_escape_unit(text, i)
+        if unit is None:
+            out.append(text[i])
+            i += 1
+            continue
+        low = _json_escape_unit(text, i + 6)
+        if 0xD800 <= unit < 0xDC00 and low is not None and 0xDC00 <= low < 0xE000:
+            code_point = 0x10000 + ((unit - 0xD800) << 10) + (low - 0xDC00)
+            out.append(f"\\U{code_point:08x}")
+            i += 12
+            continue
+        out.append(f"\\u{unit:04x}")
+        i += 6
+    out.append('"')
+    return "".join(out)
+
+
+def _json_escape_unit(text: str, i: int) -> int | None:
+    """The UTF-16 unit of a \\u escape at text[i], if there is one."""
+    if text[i : i + 2] != "\\u" or len(text) < i + 6:
+        return None
+    try:
+        return int(text[i + 2 : i + 6], 16)
+    except ValueError:
+        return None
+
+
+def hcl_raw_source(decoded: str) -> str:
+    """decoded as an HCL quoted string of raw characters."""
+    escaped = decoded.replace("\\", "\\\\").replace('"', '\\"').replace("\n", "\\n")
+    return '"' + escaped.replace("${", "$${").replace("%{", "%%{") + '"'
+
+
+def _eval_hcl_form(source: str) -> tuple[dict[str, Any], str | None]:
+    """Parse `value = <source>` with pyvider.hcl, reporting it as a form."""
+    from pyvider.cty.conversion import cty_to_native
+    from pyvider.hcl import parse_hcl_to_cty
+
+    form: dict[str, Any] = {"source": ascii_escape(source)}
+    try:
+        value = cty_to_native(parse_hcl_to_cty(f"value = {source}\n"))["value"]
+    except Exception as e:
+        form["error"] = str(e)
+        return form, None
+    if not isinstance(value, str):
+        form["error"] = f"expected a string, got {type(value).__name__}"
+        return form, None
+    form["value"] = ascii_escape(value)
+    return form, value
+
+
+def probe_hcl_string(text: str, decoded: str) -> dict[str, Any]:
+    """Run a case through pyvider.hcl string literals, as soup-go's
+    probeHCLString does with hclsyntax. pyvider.hcl doesn't evaluate
+    function calls, so length, first, upper and lower are Python's."""
+    result: dict[str, Any] = {}
+    result["escaped"], escaped = _eval_hcl_form(hcl_escaped_source(text))
+    result["raw"], raw = _eval_hcl_form(hcl_raw_source(decoded))
+    result.update(forms_match=False, normalized=False, length=0, first="", upper="", lower="")
+    if raw is None:
+        result["error"] = result["raw"]["error"]
+        return result
+    result.update(forms_match=escaped == raw, normalized=raw != decoded)
+    _string_functions(result, raw)
+    return result
+
+
+# 🥣🔬🔚
@@ -46,6 +46,12 @@ ENV_TF_DATA_DIR = "TF_DATA_DIR"
 ENV_WORKENV_PROFILE = "WORKENV_PROFILE"
 ENV_PYVIDER_PRIVATE_STATE_SHARED_SECRET = "PYVIDER_PRIVATE_STATE_SHARED_SECRET"
 ENV_KV_STORAGE_DIR = "KV_STORAGE_DIR"
+ENV_KV_FAULT_DELAY = "KV_FAULT_DELAY"
+ENV_KV_ENTRY_TTL = "KV_ENTRY_TTL"
+ENV_KV_CRASH_AFTER = "KV_CRASH_AFTER"
+ENV_KV_MAX_VALUE_BYTES = "KV_MAX_VALUE_BYTES"
+ENV_KV_DIAG_FILE = "KV_DIAG_FILE"
+ENV_KV_IDEMPOTENCY_WINDOW = "KV_IDEMPOTENCY_WINDOW"
 
 # GRPC environment variables
 ENV_GRPC_DEFAULT_CLIENT_CERTIFICATE_PATH = "GRPC_DEFAULT_CLIENT_CERTIFICATE_PATH"
@@ -170,4 +170,22 @@ def validate_value_command(value: str, type_spec: str) -> None:
         sys.exit(1)
 
 
+@cty_cli.command("unicode")
+@click.option("--case", "cases", multiple=True, help="Only run these cases (repeatable or comma-separated).")
+def unicode_command(cases: tuple[str, ...]) -> None:
+    """Report how cty normalizes, measures and encodes a suite of unicode strings.
+
+    Runs the same suite as 'soup-go cty unicode' and prints a report of the
+    same shape; see tofusoup.common.unicode_probe for where Python and Go
+    are expected to differ."""
+    from tofusoup.common.unicode_probe import probe_cty_string, run_unicode_probe
+
+    names = [name for case in cases for name in case.split(",") if name]
+    try:
+        report = run_unicode_probe("cty", names, probe_cty_string)
+    except ValueError as e:
+        raise click.BadParameter(str(e), param_hint="--case") from e
+    click.echo(json.dumps(report, indent=2))
+
+
 # 🥣🔬🔚
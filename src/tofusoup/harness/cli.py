@@ -4,8 +4,16 @@
 #
 
 
+import difflib
+import functools
+import json
 import os
+import pathlib
+import shlex
+import subprocess
 import sys
+import tempfile
+import time
 
 import click
 from provide.foundation import logger
@@ -13,6 +21,7 @@ from rich import print as rich_print
 from rich.table import Table
 
 from tofusoup.common.exceptions import TofuSoupError
+from tofusoup.common.pretty_diff import json_diff, render_json_diff, render_pretty_diff
 from tofusoup.common.utils import get_cache_dir
 
 from .logic import (
@@ -21,6 +30,33 @@ from .logic import (
     HarnessBuildError,
     ensure_go_harness_build,
 )
+from .html_report import render_html_report
+from .matrix_runner import (
+    RUNNERS,
+    SIDES,
+    ContainerRunner,
+    LocalRunner,
+    PairResult,
+    Runner,
+    load_profiles,
+    parse_image_map,
+    run_matrix,
+    write_results,
+)
+from .minimize import KINDS, MinimizeError, kind_for_suffix, minimize
+from .normalizers import build_pipeline, load_normalizers, normalize
+from .snapshots import (
+    Snapshot,
+    SnapshotSpec,
+    load_snapshot_specs,
+    matches,
+    render_snapshot_diff,
+    snapshot_path,
+    summarize_change,
+)
+from .results_db import case_history, connect, load_report, record_run, summarize_history
+from .set_match import STRATEGIES, SetMatcher, envelope_set_paths, render_set_matches
+from .tolerance import FloatTolerance, apply_float_tolerance, render_decisions
 
 
 @click.group("harness")
@@ -125,4 +161,755 @@ def build_harness_command(
             sys.exit(1)
 
 
+@harness_cli.command("report")
+@click.argument(
+    "report_files", nargs=-1, type=click.Path(exists=True, dir_okay=False, path_type=pathlib.Path)
+)
+@click.option(
+    "--db",
+    "db_path",
+    type=click.Path(dir_okay=False, path_type=pathlib.Path),
+    help="SQLite results database to append to (created if missing).",
+)
+@click.option("--label", help="Free-form label stored with each run (e.g. a CI job name).")
+@click.option(
+    "--format",
+    "output_format",
+    type=click.Choice(["db", "html"]),
+    default="db",
+    show_default=True,
+    help="db only records the results; html also renders a self-contained report to --out.",
+)
+@click.option(
+    "--out",
+    "out_path",
+    type=click.Path(dir_okay=False, path_type=pathlib.Path),
+    help="Where to write the HTML report (required with --format html).",
+)
+@click.pass_context
+def report_command(
+    ctx: click.Context,
+    report_files: tuple[pathlib.Path, ...],
+    db_path: pathlib.Path | None,
+    label: str | None,
+    output_format: str,
+    out_path: pathlib.Path | None,
+) -> None:
+    """Records and renders matrix/conformance results.
+
+    REPORT_FILES are pytest JSON reports, `soup stir --matrix` results or RPC
+    matrix runner results. Without any, every report in
+    soup/output/test-reports is used.
+
+    With --db the results are appended to a results database. With
+    --format html an HTML report is written to --out; if --db is also given
+    it includes each case's duration trend across recorded runs."""
+    if output_format == "db" and db_path is None:
+        raise click.UsageError("--db is required unless --format html is used.")
+    if output_format == "html" and out_path is None:
+        raise click.UsageError("--out is required with --format html.")
+
+    files = list(report_files)
+    if not files:
+        reports_dir = ctx.obj["PROJECT_ROOT"] / "soup" / "output" / "test-reports"
+        files = sorted(reports_dir.glob("*.json"))
+        if not files:
+            rich_print(f"[yellow]No reports found in {reports_dir}.[/yellow]")
+            return
+
+    try:
+        reports = [load_report(path) for path in files]
+        conn = connect(db_path) if db_path is not None else None
+        try:
+            if conn is not None:
+                for path, report in zip(files, reports, strict=True):
+                    run_id = record_run(conn, report, label)
+                    rich_print(
+                        f"[green]Recorded run {run_id}[/green] "
+                        f"({report.kind}, {len(report.cases)} cases) from {path}"
+                    )
+            if output_format == "html" and out_path is not None:
+                history = functools.partial(case_history, conn, limit=50) if conn is not None else None
+                out_path.write_text(render_html_report(reports, history))
+                rich_print(f"[green]HTML report written to {out_path}[/green]")
+        finally:
+            if conn is not None:
+                conn.close()
+    except (TofuSoupError, OSError) as e:
+        logger.error(f"Failed to report results: {e}")
+        sys.exit(1)
+
+
+@harness_cli.command("history")
+@click.option(
+    "--db",
+    "db_path",
+    required=True,
+    type=click.Path(exists=True, dir_okay=False, path_type=pathlib.Path),
+    help="SQLite results database to query.",
+)
+@click.option("--case", "case", required=True, help="Case ID to look up; * and ? match as a glob.")
+@click.option("--limit", default=20, show_default=True, help="Show at most this many results (0 for all).")
+@click.option("--json", "as_json", is_flag=True, help="Output history and summary as JSON.")
+def history_command(db_path: pathlib.Path, case: str, limit: int, as_json: bool) -> None:
+    """Shows pass/fail and latency trends for a case across recorded runs."""
+    try:
+        conn = connect(db_path)
+    except TofuSoupError as e:
+        logger.error(str(e))
+        sys.exit(1)
+    try:
+        entries = case_history(conn, case, limit or None)
+    finally:
+        conn.close()
+    summary = summarize_history(entries)
+
+    if as_json:
+        print(
+            json.dumps(
+                {"case": case, "summary": summary, "history": [vars(e) for e in entries]},
+                indent=2,
+            )
+        )
+        return
+
+    if not entries:
+        rich_print(f"[yellow]No results recorded for '{case}'.[/yellow]")
+        return
+
+    table = Table(title=f"History: {case}")
+    table.add_column("Run", style="cyan", justify="right")
+    table.add_column("Recorded", style="dim")
+    table.add_column("Commit", style="magenta")
+    table.add_column("Case")
+    table.add_column("Outcome")
+    table.add_column("Duration", style="yellow", justify="right")
+    for e in entries:
+        outcome_style = {"passed": "green", "failed": "red", "error": "red", "timeout": "red"}.get(
+            e.outcome, "yellow"
+        )
+        duration = f"{e.duration_seconds:.2f}s" if e.duration_seconds is not None else "-"
+        table.add_row(
+            str(e.run_id),
+            e.recorded_at[:19],
+            (e.git_commit or "-")[:10],
+            e.case_id,
+            f"[{outcome_style}]{e.outcome}[/{outcome_style}]",
+            duration,
+        )
+    rich_print(table)
+
+    pass_rate = summary["pass_rate"]
+    line = f"{summary['passed']} passed, {summary['failed']} failed"
+    if pass_rate is not None:
+        line += f" ({pass_rate:.0%} pass rate)"
+    if durations := summary.get("duration_seconds"):
+        line += (
+            f"; duration min {durations['min']:.2f}s, median {durations['median']:.2f}s, "
+            f"max {durations['max']:.2f}s"
+        )
+        if ratio := durations.get("latest_vs_median"):
+            line += f", latest {ratio:.2f}x median"
+    rich_print(line)
+
+
+def _capture(
+    command: str, stream: str, stdin: bytes | None, timeout: float, cwd: pathlib.Path | None = None
+) -> tuple[str, int]:
+    """Run a command line and return the selected output and its exit code."""
+    try:
+        result = subprocess.run(
+            shlex.split(command),
+            cwd=cwd,
+            input=stdin,
+            stdout=subprocess.PIPE,
+            stderr=subprocess.STDOUT if stream == "combined" else subprocess.PIPE,
+            timeout=timeout,
+        )
+    except (OSError, ValueError, subprocess.TimeoutExpired) as e:
+        raise TofuSoupError(f"Failed to run '{command}': {e}") from e
+    output = result.stderr if stream == "stderr" else result.stdout
+    return output.decode(errors="replace"), result.returncode
+
+
+def _parse_float_tolerance(
+    ctx: click.Context, param: click.Parameter, value: str | None
+) -> FloatTolerance | None:
+    if value is None:
+        return None
+    try:
+        return FloatTolerance.parse(value)
+    except ValueError as e:
+        raise click.BadParameter(str(e)) from e
+
+
+@harness_cli.command("diff")
+@click.argument("left")
+@click.argument("right")
+@click.option("--files", is_flag=True, help="Treat LEFT and RIGHT as files to compare, not commands.")
+@click.option(
+    "--normalize",
+    "normalize_names",
+    default="",
+    help="Comma-separated normalizers applied in order, e.g. sort-keys,float-format.",
+)
+@click.option(
+    "--normalizers",
+    "normalizers_file",
+    type=click.Path(exists=True, dir_okay=False, path_type=pathlib.Path),
+    help="TOML file declaring custom normalizers under [normalizers.<name>].",
+)
+@click.option(
+    "--stream",
+    type=click.Choice(["stdout", "stderr", "combined"]),
+    default="stdout",
+    show_default=True,
+    help="Command output to compare.",
+)
+@click.option(
+    "--stdin",
+    "stdin_file",
+    type=click.Path(exists=True, dir_okay=False, path_type=pathlib.Path),
+    help="File fed to both commands on stdin.",
+)
+@click.option("--ignore-exit-code", is_flag=True, help="Don't treat differing exit codes as a difference.")
+@click.option(
+    "--pretty",
+    is_flag=True,
+    help="Colored diff; JSON outputs are compared by path, with long strings and collections summarized.",
+)
+@click.option(
+    "--float-tolerance",
+    callback=_parse_float_tolerance,
+    help="Treat floats as equal within abs=<n>,rel=<n> tolerance, e.g. abs=1e-9,rel=1e-6.",
+)
+@click.option(
+    "--set-match",
+    type=click.Choice(STRATEGIES),
+    help="How to pair set elements in JSON outputs: best-effort, positional or digest. [default: best-effort]",
+)
+@click.option(
+    "--set-path",
+    "set_paths",
+    multiple=True,
+    help="Path of a list to compare as a set, e.g. '$.resources[*].values.ingress'. Repeatable.",
+)
+@click.option("--timeout", default=60.0, show_default=True, help="Per-command timeout in seconds.")
+@click.option("--list", "list_normalizers", is_flag=True, help="List available normalizers and exit.")
+@click.pass_context
+def diff_command(
+    ctx: click.Context,
+    left: str,
+    right: str,
+    files: bool,
+    normalize_names: str,
+    normalizers_file: pathlib.Path | None,
+    stream: str,
+    stdin_file: pathlib.Path | None,
+    ignore_exit_code: bool,
+    pretty: bool,
+    float_tolerance: FloatTolerance | None,
+    set_match: str | None,
+    set_paths: tuple[str, ...],
+    timeout: float,
+    list_normalizers: bool,
+) -> None:
+    """Compares two harnesses' output after normalizing it.
+
+    LEFT and RIGHT are command lines (quoted) run with the same stdin, or
+    files with --files. Both outputs go through the --normalize pipeline
+    before a unified diff is printed; the exit status is 1 if they differ.
+
+    Custom normalizers come from [harness.normalizers.<name>] in soup.toml
+    and from --normalizers; see tofusoup.harness.normalizers.
+
+    With --pretty the diff is colored for reading in a terminal. If both
+    outputs are JSON, each differing path is one line, with long strings
+    truncated and added or removed collections summarized by size.
+
+    --float-tolerance lets numbers that different runtimes format or round
+    differently match: lines differing only in numbers match when every
+    differing float is within tolerance. Each float compared is listed as
+    accepted or rejected; see tofusoup.harness.tolerance.
+
+    --set-match and --set-path compare JSON outputs by path, with the
+    elements of sets paired by a strategy rather than by index, so sets
+    written in another order or holding unknowns don't show up as
+    differences. Typed JSON values mark their own sets. The strategy used
+    for each set is listed; see tofusoup.harness.set_match.
+
+    \b
+    Example:
+      soup harness diff --normalize sort-keys,float-format \\
+        "soup-go hcl convert main.tf - --output-format json" \\
+        "soup hcl convert main.tf - --output-format json"
+    """
+    try:
+        available = load_normalizers(ctx.obj.get("TOFUSOUP_CONFIG", {}), normalizers_file)
+        if list_normalizers:
+            for name in sorted(available):
+                print(name)
+            return
+        names = [name.strip() for name in normalize_names.split(",") if name.strip()]
+        pipeline = build_pipeline(names, available)
+
+        if files:
+            outputs = [
+                (pathlib.Path(left).read_text(errors="replace"), 0),
+                (pathlib.Path(right).read_text(errors="replace"), 0),
+            ]
+        else:
+            stdin = stdin_file.read_bytes() if stdin_file is not None else None
+            outputs = [_capture(command, stream, stdin, timeout) for command in (left, right)]
+    except (TofuSoupError, OSError) as e:
+        logger.error(f"Failed to diff harness output: {e}")
+        sys.exit(2)
+
+    (left_text, left_code), (right_text, right_code) = outputs
+    left_text, right_text = normalize(left_text, pipeline), normalize(right_text, pipeline)
+    if float_tolerance is not None:
+        right_text, decisions = apply_float_tolerance(left_text, right_text, float_tolerance)
+        if decisions:
+            for line in render_decisions(float_tolerance, decisions):
+                rich_print(line)
+    codes_differ = left_code != right_code and not ignore_exit_code
+    if set_match is not None or set_paths:
+        strategy = set_match or STRATEGIES[0]
+        differs = _diff_with_sets(left_text, right_text, strategy, set_paths, float_tolerance)
+        if not differs and not codes_differ:
+            rich_print("[green]Outputs match.[/green]")
+            return
+        if codes_differ:
+            rich_print(f"[red]Exit codes differ: {left_code} vs {right_code}[/red]")
+        sys.exit(1)
+    diff = list(
+        difflib.unified_diff(
+            left_text.splitlines(keepends=True),
+            right_text.splitlines(keepends=True),
+            fromfile=left,
+            tofile=right,
+        )
+    )
+
+    if not diff and not codes_differ:
+        rich_print("[green]Outputs match.[/green]")
+        return
+    if codes_differ:
+        rich_print(f"[red]Exit codes differ: {left_code} vs {right_code}[/red]")
+    if pretty:
+        close = float_tolerance.close if float_tolerance is not None else None
+        for line in render_pretty_diff(left_text, right_text, left, right, close):
+            rich_print(line)
+        sys.exit(1)
+    for line in diff:
+        sys.stdout.write(line if line.endswith("\n") else line + "\n")
+    sys.exit(1)
+
+
+def _diff_with_sets(
+    left_text: str,
+    right_text: str,
+    strategy: str,
+    set_paths: tuple[str, ...],
+    float_tolerance: FloatTolerance | None,
+) -> bool:
+    """Compare two JSON outputs by path with set elements paired by
+    strategy, printing how each set was matched and any differences.
+    Returns whether they differ; exits 2 if either isn't JSON."""
+    try:
+        left_value, right_value = json.loads(left_text), json.loads(right_text)
+    except ValueError as e:
+        logger.error(f"--set-match and --set-path need JSON outputs: {e}")
+        sys.exit(2)
+    matcher = SetMatcher(strategy, set_paths)
+    matcher.add_patterns(envelope_set_paths(left_value) + envelope_set_paths(right_value))
+    close = float_tolerance.close if float_tolerance is not None else None
+    changes = json_diff(left_value, right_value, close=close, match_lists=matcher)
+
+    for line in render_set_matches(matcher.matches):
+        rich_print(line)
+    if changes:
+        for line in render_json_diff(changes):
+            rich_print(line)
+    return bool(changes)
+
+
+@harness_cli.command("minimize")
+@click.option(
+    "--case",
+    "case_path",
+    required=True,
+    type=click.Path(exists=True, dir_okay=False, path_type=pathlib.Path),
+    help="Input that makes the harnesses diverge.",
+)
+@click.option(
+    "--check",
+    required=True,
+    help="Command line that exits with --diverged-exit while a candidate still diverges; {case} is its path.",
+)
+@click.option(
+    "--kind",
+    type=click.Choice(["auto", *KINDS]),
+    default="auto",
+    show_default=True,
+    help="How to reduce the case; auto picks from its suffix.",
+)
+@click.option(
+    "-o",
+    "--output",
+    type=click.Path(dir_okay=False, path_type=pathlib.Path),
+    help="Where to write the minimized case. [default: <case>.min<suffix>]",
+)
+@click.option(
+    "--diverged-exit", default=1, show_default=True, help="Exit status of --check meaning it still diverges."
+)
+@click.option(
+    "--max-checks", default=500, show_default=True, help="Give up after running --check this many times."
+)
+@click.option("--timeout", default=60.0, show_default=True, help="Per-check timeout in seconds.")
+def minimize_command(
+    case_path: pathlib.Path,
+    check: str,
+    kind: str,
+    output: pathlib.Path | None,
+    diverged_exit: int,
+    max_checks: int,
+    timeout: float,
+) -> None:
+    """Shrinks a diverging cty, hcl or wire input to a minimal reproducer.
+
+    Each candidate reduction is written to a scratch file named like the
+    case, and --check is run with {case} replaced by its path. The
+    reduction is kept if the check exits with --diverged-exit, which is 1
+    by default, as `soup harness diff` exits when outputs differ. Any other
+    status, including a timeout, means the candidate lost the divergence.
+
+    JSON cases lose object attributes and list elements and have strings
+    truncated; msgpack (wire) cases are reduced the same way once decoded;
+    HCL cases lose whole attributes, blocks and list elements and have
+    string literals truncated. See tofusoup.harness.minimize.
+
+    \b
+    Example:
+      soup harness minimize --case diverging.tf \\
+        --check "soup harness diff --normalize sort-keys \\
+          'soup-go hcl convert {case} - --output-format json' \\
+          'soup hcl convert {case} - --output-format json'"
+    """
+    if kind == "auto":
+        kind = kind_for_suffix(case_path.suffix) or ""
+        if not kind:
+            raise click.UsageError(f"can't tell the kind of {case_path.name} from its suffix; pass --kind")
+    argv = shlex.split(check)
+    if not any("{case}" in arg for arg in argv):
+        raise click.UsageError("--check must include {case} where the candidate's path goes")
+    if output is None:
+        output = case_path.with_name(f"{case_path.stem}.min{case_path.suffix}")
+
+    with tempfile.TemporaryDirectory(prefix="soup-minimize-") as scratch:
+        candidate_path = pathlib.Path(scratch) / case_path.name
+        command = [arg.replace("{case}", str(candidate_path)) for arg in argv]
+
+        def diverges(candidate: bytes) -> bool:
+            candidate_path.write_bytes(candidate)
+            try:
+                result = subprocess.run(command, capture_output=True, timeout=timeout)
+            except subprocess.TimeoutExpired:
+                return False
+            except OSError as e:
+                raise MinimizeError(f"failed to run --check: {e}") from e
+            return result.returncode == diverged_exit
+
+        def reported(description: str, size: int) -> None:
+            logger.info(f"Kept reduction: {description}", size=size)
+
+        try:
+            result = minimize(
+                case_path.read_bytes(), kind, diverges, max_checks=max_checks, on_reduction=reported
+            )
+        except MinimizeError as e:
+            logger.error(f"Failed to minimize {case_path}: {e}")
+            sys.exit(2)
+
+    output.write_bytes(result.data)
+    rich_print(
+        f"Minimized {case_path}: {result.original_size} → {len(result.data)} bytes with "
+        f"{len(result.reductions)} reductions in {result.checks} checks; wrote {output}"
+    )
+    if result.exhausted:
+        rich_print(f"[yellow]Stopped after {max_checks} checks; it may shrink further.[/yellow]")
+
+
+def _parse_sides(ctx: click.Context, param: click.Parameter, value: str) -> tuple[str, ...]:
+    sides = tuple(side.strip() for side in value.split(",") if side.strip())
+    unknown = [side for side in sides if side not in SIDES]
+    if unknown or not sides:
+        raise click.BadParameter(f"expected a comma-separated list of {', '.join(SIDES)}")
+    return sides
+
+
+@harness_cli.command("matrix")
+@click.option(
+    "--runner",
+    type=click.Choice(RUNNERS),
+    default="local",
+    show_default=True,
+    help="local runs binaries from this checkout; docker and podman run each side in its --image-map image.",
+)
+@click.option(
+    "--image-map",
+    default="",
+    help="Image for each side with a container runner, e.g. go=ghcr.io/org/soup-go:1.2,python=....",
+)
+@click.option(
+    "--clients", default=",".join(SIDES), show_default=True, callback=_parse_sides, help="Client sides to run."
+)
+@click.option(
+    "--servers", default=",".join(SIDES), show_default=True, callback=_parse_sides, help="Server sides to run."
+)
+@click.option(
+    "--out",
+    "out_dir",
+    type=click.Path(file_okay=False, path_type=pathlib.Path),
+    help="Directory for results.json and each pair's artifacts (default: a new temporary directory).",
+)
+@click.option(
+    "--profiles",
+    "profiles_file",
+    type=click.Path(exists=True, dir_okay=False, path_type=pathlib.Path),
+    help="TOML file declaring env, cwd and wrapper for sides under [profiles.<side>].",
+)
+@click.option("--timeout", default=120.0, show_default=True, help="Seconds each pair may take.")
+@click.pass_context
+def matrix_command(
+    ctx: click.Context,
+    runner: str,
+    image_map: str,
+    clients: tuple[str, ...],
+    servers: tuple[str, ...],
+    out_dir: pathlib.Path | None,
+    profiles_file: pathlib.Path | None,
+    timeout: float,
+) -> None:
+    """Runs a K/V put and get for every client/server pair of harness sides.
+
+    With --runner docker or podman every side runs inside the image
+    --image-map names for it, so released images are checked against each
+    other rather than local builds. The client's container joins the
+    server's network namespace, and each pair's logs and server storage are
+    collected under --out, next to a results.json that `soup harness report`
+    records. The exit status is 1 if any pair failed.
+
+    A side that needs its runtime set up gets a profile, from
+    [harness.matrix.profiles.<side>] in soup.toml or --profiles: env
+    variables, a working directory, and a wrapper command such as
+    `uv run` put in front of its command line. See
+    tofusoup.harness.matrix_runner.
+
+    \b
+    Example:
+      soup harness matrix --runner docker \\
+        --image-map go=example/soup-go:0.1,python=example/tofusoup:0.1
+    """
+    try:
+        images = parse_image_map(image_map)
+        profiles = load_profiles(ctx.obj.get("TOFUSOUP_CONFIG", {}), ctx.obj["PROJECT_ROOT"], profiles_file)
+        selected: Runner
+        if runner == "local":
+            if images:
+                raise click.UsageError("--image-map only applies to container runners.")
+            commands: dict[str, list[str]] = {"python": [sys.executable, "-m", "tofusoup.cli"]}
+            if "go" in clients + servers:
+                go_harness = ensure_go_harness_build(
+                    "soup-go", ctx.obj["PROJECT_ROOT"], ctx.obj.get("TOFUSOUP_CONFIG", {})
+                )
+                commands["go"] = [str(go_harness)]
+            selected = LocalRunner(commands, profiles)
+        else:
+            missing = sorted(set(clients + servers) - set(images))
+            if missing:
+                raise click.UsageError(f"--image-map has no image for {', '.join(missing)}.")
+            selected = ContainerRunner(runner, images, profiles)
+    except (GoVersionError, HarnessBuildError, TofuSoupError) as e:
+        logger.error(f"Failed to set up the matrix: {e}")
+        sys.exit(2)
+
+    out_dir = out_dir or pathlib.Path(tempfile.mkdtemp(prefix="soup-matrix-"))
+    out_dir.mkdir(parents=True, exist_ok=True)
+    pairs = len(clients) * len(servers)
+    rich_print(f"[bold cyan]Running {pairs} pairs with {runner}, artifacts in {out_dir}[/bold cyan]")
+
+    def reported(result: PairResult) -> None:
+        icon = "✅" if result.status == "passed" else "❌"
+        detail = f": {result.error}" if result.error else ""
+        rich_print(f"{icon} {result.node_id} ({result.duration_seconds:.1f}s){detail}")
+
+    started = time.time()
+    start = time.monotonic()
+    try:
+        results = run_matrix(selected, out_dir, clients, servers, timeout, reported)
+    except OSError as e:
+        logger.error(f"Failed to run the matrix with {runner}: {e}")
+        sys.exit(2)
+    sides = tuple(dict.fromkeys(clients + servers))
+    path = write_results(results, selected, sides, started, time.monotonic() - start, out_dir)
+    rich_print(f"Results: {path}")
+    if any(result.status != "passed" for result in results):
+        sys.exit(1)
+
+
+@harness_cli.group("snapshot")
+def snapshot_cli() -> None:
+    """Record and check golden outputs of harness commands."""
+
+
+snapshots_file_option = click.option(
+    "--snapshots",
+    "snapshots_file",
+    type=click.Path(exists=True, dir_okay=False, path_type=pathlib.Path),
+    help="TOML file declaring snapshots under [snapshots.<name>].",
+)
+
+snapshot_normalizers_option = click.option(
+    "--normalizers",
+    "normalizers_file",
+    type=click.Path(exists=True, dir_okay=False, path_type=pathlib.Path),
+    help="TOML file declaring custom normalizers under [normalizers.<name>].",
+)
+
+snapshot_dir_option = click.option(
+    "--dir",
+    "snapshot_dir",
+    type=click.Path(file_okay=False, path_type=pathlib.Path),
+    help="Directory snapshots are stored in. [default: soup/snapshots]",
+)
+
+
+def _take_snapshots(
+    ctx: click.Context,
+    names: tuple[str, ...],
+    snapshots_file: pathlib.Path | None,
+    normalizers_file: pathlib.Path | None,
+    snapshot_dir: pathlib.Path | None,
+) -> list[tuple[SnapshotSpec, Snapshot | None, Snapshot, pathlib.Path]]:
+    """Run the selected snapshots' commands, returning each spec with its
+    stored snapshot (None if there isn't one), a fresh one and its path."""
+    project_root = ctx.obj.get("PROJECT_ROOT", pathlib.Path.cwd())
+    soup_config = ctx.obj.get("TOFUSOUP_CONFIG", {})
+    try:
+        specs = load_snapshot_specs(soup_config, project_root, snapshots_file)
+        available = load_normalizers(soup_config, normalizers_file)
+    except TofuSoupError as e:
+        raise click.ClickException(str(e)) from e
+    if not specs:
+        raise click.ClickException(
+            "No snapshots configured; declare [harness.snapshots.<name>] or pass --snapshots"
+        )
+    unknown = [name for name in names if name not in specs]
+    if unknown:
+        raise click.UsageError(f"Unknown snapshot(s): {', '.join(unknown)}")
+    directory = snapshot_dir or project_root / "soup" / "snapshots"
+
+    taken = []
+    for name in names or sorted(specs):
+        spec = specs[name]
+        path = snapshot_path(directory, name)
+        try:
+            pipeline = build_pipeline(spec.normalize, available)
+            stored = Snapshot.load(path) if path.exists() else None
+            stdin = spec.stdin.read_bytes() if spec.stdin is not None else None
+            output, exit_code = _capture(spec.command, spec.stream, stdin, spec.timeout, cwd=project_root)
+        except (TofuSoupError, OSError) as e:
+            raise click.ClickException(f"Snapshot '{name}': {e}") from e
+        current = Snapshot.from_output(spec, normalize(output, pipeline), exit_code)
+        taken.append((spec, stored, current, path))
+    return taken
+
+
+@snapshot_cli.command("run")
+@click.argument("names", nargs=-1)
+@snapshots_file_option
+@snapshot_normalizers_option
+@snapshot_dir_option
+@click.pass_context
+def snapshot_run_command(
+    ctx: click.Context,
+    names: tuple[str, ...],
+    snapshots_file: pathlib.Path | None,
+    normalizers_file: pathlib.Path | None,
+    snapshot_dir: pathlib.Path | None,
+) -> None:
+    """Runs snapshot commands and compares their output to the stored snapshots.
+
+    NAMES selects snapshots to run; all configured ones run by default. Each
+    command's output goes through its normalize pipeline first. Differences
+    are shown by JSON path when outputs are JSON. Exits 1 if any snapshot
+    differs or hasn't been recorded yet.
+
+    \b
+    Example:
+      soup harness snapshot run
+      soup harness snapshot run hcl-convert-basic --snapshots snapshots.toml
+    """
+    failed = 0
+    taken = _take_snapshots(ctx, names, snapshots_file, normalizers_file, snapshot_dir)
+    for _, stored, current, path in taken:
+        if stored is None:
+            failed += 1
+            rich_print(f"[red]✗ {current.name}: no snapshot at {path}; record it with snapshot update[/red]")
+        elif matches(stored, current):
+            rich_print(f"[green]✓ {current.name}[/green]")
+        else:
+            failed += 1
+            rich_print(f"[red]✗ {current.name}: {summarize_change(stored, current)}[/red]")
+            for line in render_snapshot_diff(stored, current):
+                rich_print(line)
+    if failed:
+        rich_print(f"[red]{failed} snapshot{'s' * (failed != 1)} failed[/red]")
+        sys.exit(1)
+
+
+@snapshot_cli.command("update")
+@click.argument("names", nargs=-1)
+@snapshots_file_option
+@snapshot_normalizers_option
+@snapshot_dir_option
+@click.pass_context
+def snapshot_update_command(
+    ctx: click.Context,
+    names: tuple[str, ...],
+    snapshots_file: pathlib.Path | None,
+    normalizers_file: pathlib.Path | None,
+    snapshot_dir: pathlib.Path | None,
+) -> None:
+    """Runs snapshot commands and rewrites the snapshots that changed.
+
+    Prints what changed in each rewritten snapshot, summarized by JSON path
+    for JSON outputs, so the update can be reviewed without reading the
+    files. Snapshots that still match are left untouched.
+
+    \b
+    Example:
+      soup harness snapshot update
+      soup harness snapshot update hcl-convert-basic
+    """
+    written = 0
+    taken = _take_snapshots(ctx, names, snapshots_file, normalizers_file, snapshot_dir)
+    for _, stored, current, path in taken:
+        same_spec = stored is not None and (stored.command, stored.normalize) == (
+            current.command,
+            current.normalize,
+        )
+        if stored is not None and same_spec and matches(stored, current):
+            rich_print(f"[dim]= {current.name}: unchanged[/dim]")
+            continue
+        current.save(path)
+        written += 1
+        rich_print(f"[yellow]↻ {current.name}: {summarize_change(stored, current)}[/yellow]")
+        if stored is not None:
+            for line in render_snapshot_diff(stored, current):
+                rich_print(line)
+    rich_print(f"Wrote {written} snapshot{'s' * (written != 1)}")
+
+
 # 🥣🔬🔚
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// certTTLEnv sets --cert-ttl, so plugin servers started by a client issue
+// certificates with the same lifetime
+const certTTLEnv = "SOUP_GO_CERT_TTL"
+
+// defaultCertTTL is how long generated certificates are valid unless
+// --cert-ttl says otherwise
+const defaultCertTTL = 365 * 24 * time.Hour
+
+// certTTLFlag holds --cert-ttl
+var certTTLFlag string
+
+// certTTL is the lifetime chosen by applyCertTTL
+var certTTL = defaultCertTTL
+
+// applyCertTTL parses --cert-ttl or SOUP_GO_CERT_TTL. Certificates record
+// validity in whole seconds, so shorter lifetimes are refused.
+func applyCertTTL() error {
+	value, source := certTTLFlag, "--cert-ttl"
+	if value == "" {
+		value, source = os.Getenv(certTTLEnv), certTTLEnv
+	}
+	if value == "" {
+		certTTL = defaultCertTTL
+		return nil
+	}
+	ttl, err := parseCertDuration(value)
+	if err != nil {
+		return fmt.Errorf("invalid %s %q: %w", source, value, err)
+	}
+	if ttl < time.Second {
+		return fmt.Errorf("invalid %s %q: must be at least 1s", source, value)
+	}
+	certTTL = ttl
+	if ttl != defaultCertTTL {
+		logger.Info("🕰️ Setting generated certificate lifetime", "ttl", ttl.String())
+	}
+	return nil
+}
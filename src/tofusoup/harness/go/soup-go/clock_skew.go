@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clockSkewEnv sets --clock-skew, so plugin servers started by a client
+// can run on the same skewed clock
+const clockSkewEnv = "SOUP_GO_CLOCK_SKEW"
+
+// clockSkewFlag holds --clock-skew
+var clockSkewFlag string
+
+// clockSkew is the offset chosen by applyClockSkew
+var clockSkew time.Duration
+
+// applyClockSkew parses --clock-skew or SOUP_GO_CLOCK_SKEW
+func applyClockSkew() error {
+	value, source := clockSkewFlag, "--clock-skew"
+	if value == "" {
+		value, source = os.Getenv(clockSkewEnv), clockSkewEnv
+	}
+	if value == "" {
+		clockSkew = 0
+		return nil
+	}
+	skew, err := parseCertDuration(value)
+	if err != nil {
+		return fmt.Errorf("invalid %s %q: %w", source, value, err)
+	}
+	clockSkew = skew
+	if skew != 0 {
+		logger.Info("🕰️ Skewing certificate clock", "skew", skew.String())
+	}
+	return nil
+}
+
+// parseCertDuration parses a signed Go duration such as +2h or -90s. A
+// whole number of days (+400d) is accepted too, since certificate lifetimes
+// are counted in days.
+func parseCertDuration(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.ParseInt(days, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("expected a whole number of days before 'd'")
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(value)
+}
+
+// certNow is the time certificates are generated and verified at: the
+// system clock moved by --clock-skew. It fits tls.Config.Time, so handshakes
+// check NotBefore and NotAfter against it.
+func certNow() time.Time {
+	return time.Now().Add(clockSkew)
+}
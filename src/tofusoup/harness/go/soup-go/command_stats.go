@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// processStart is as close to exec as package initialization gets, so
+// wall_ms covers startup the way a `time` wrapper would
+var processStart = time.Now()
+
+// --stats flags (global)
+var (
+	commandStats     bool
+	commandStatsFile string
+)
+
+// commandGCStats is runtime GC and allocation activity for the run
+type commandGCStats struct {
+	Collections     uint32  `json:"collections"`
+	PauseTotalMs    float64 `json:"pause_total_ms"`
+	HeapAllocBytes  uint64  `json:"heap_alloc_bytes"`
+	TotalAllocBytes uint64  `json:"total_alloc_bytes"`
+	Mallocs         uint64  `json:"mallocs"`
+}
+
+// commandStatsReport is the --stats summary. It has the same shape as the
+// Python CLI's, so runs of either harness compare directly. CPU and RSS are
+// null where the platform has no getrusage.
+type commandStatsReport struct {
+	Harness     string         `json:"harness"`
+	Language    string         `json:"language"`
+	Command     string         `json:"command"`
+	Success     bool           `json:"success"`
+	WallMs      float64        `json:"wall_ms"`
+	UserCPUMs   *float64       `json:"user_cpu_ms"`
+	SysCPUMs    *float64       `json:"sys_cpu_ms"`
+	MaxRSSBytes *int64         `json:"max_rss_bytes"`
+	GC          commandGCStats `json:"gc"`
+}
+
+// collectCommandStats measures the process so far
+func collectCommandStats(cmd *cobra.Command, err error) commandStatsReport {
+	report := commandStatsReport{
+		Harness:  "soup-go",
+		Language: "go",
+		Success:  err == nil,
+		WallMs:   sinceMs(processStart),
+	}
+	if cmd != nil {
+		// Without the binary name, to match across harnesses
+		report.Command = strings.TrimPrefix(strings.TrimPrefix(cmd.CommandPath(), rootCmd.Name()), " ")
+	}
+	if usage, ok := processRusage(); ok {
+		report.UserCPUMs = &usage.UserCPUMs
+		report.SysCPUMs = &usage.SysCPUMs
+		report.MaxRSSBytes = &usage.MaxRSSBytes
+	}
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	report.GC = commandGCStats{
+		Collections:     mem.NumGC,
+		PauseTotalMs:    float64(mem.PauseTotalNs) / float64(time.Millisecond),
+		HeapAllocBytes:  mem.HeapAlloc,
+		TotalAllocBytes: mem.TotalAlloc,
+		Mallocs:         mem.Mallocs,
+	}
+	return report
+}
+
+// printCommandStats writes the --stats summary as one {"stats": ...} JSON
+// line: to --stats-file if set, otherwise to stdout after the command's own
+// output and any --timing line. Failed commands are reported too.
+func printCommandStats(cmd *cobra.Command, err error) {
+	if !commandStats && commandStatsFile == "" {
+		return
+	}
+	line, encErr := json.Marshal(map[string]interface{}{"stats": collectCommandStats(cmd, err)})
+	if encErr != nil {
+		fmt.Fprintf(os.Stderr, "failed to encode stats: %v\n", encErr)
+		return
+	}
+	line = append(line, '\n')
+	if commandStatsFile == "" {
+		os.Stdout.Write(line)
+		return
+	}
+	if err := os.WriteFile(commandStatsFile, line, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write stats: %v\n", err)
+	}
+}
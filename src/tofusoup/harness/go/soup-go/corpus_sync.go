@@ -0,0 +1,409 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Remote fixture corpora
+//
+// A corpus is published as an index, corpus.json, listing every fixture by
+// path, size and SHA-256, plus a store of the fixture contents keyed by
+// digest. 'harness corpus sync' fetches whatever a local directory lacks, so
+// harness repos can pin a corpus version instead of vendoring its files.
+const (
+	corpusIndexName  = "corpus.json"
+	corpusIndexVer   = 1
+	corpusPartialDir = ".corpus-partial"
+	// defaultCorpusObjects is where objects are stored when the index
+	// doesn't say, relative to the index URL
+	defaultCorpusObjects = "objects/{sha256}"
+)
+
+// corpusIndex is a published corpus.json. Files have the same shape as a
+// signed vector MANIFEST.json's.
+type corpusIndex struct {
+	Version int `json:"version"`
+	// Name and CorpusVersion identify what was synced in the report
+	Name          string `json:"name,omitempty"`
+	CorpusVersion string `json:"corpus_version,omitempty"`
+	// Objects is the URL template of a file's contents, relative to the
+	// index URL, with {sha256} replaced by its digest
+	Objects string                `json:"objects,omitempty"`
+	Files   []vectorManifestEntry `json:"files"`
+}
+
+// corpusFailure is a file that couldn't be synced
+type corpusFailure struct {
+	Path  string `json:"path"`
+	Error string `json:"error"`
+}
+
+// corpusSyncReport is the result of corpus sync
+type corpusSyncReport struct {
+	URL             string          `json:"url"`
+	Dir             string          `json:"dir"`
+	Name            string          `json:"name,omitempty"`
+	CorpusVersion   string          `json:"corpus_version,omitempty"`
+	IndexSHA256     string          `json:"index_sha256"`
+	Files           int             `json:"files"`
+	UpToDate        int             `json:"up_to_date"`
+	Fetched         []string        `json:"fetched"`
+	Resumed         []string        `json:"resumed"`
+	Failed          []corpusFailure `json:"failed"`
+	BytesDownloaded int64           `json:"bytes_downloaded"`
+	DurationMs      float64         `json:"duration_ms"`
+}
+
+// corpusFetcher downloads corpus objects, resuming partial downloads
+type corpusFetcher struct {
+	client  *http.Client
+	retries int
+}
+
+// get fetches rawURL, asking for the bytes from offset on when it is
+// non-zero. The caller closes the body of a nil-error response, which is
+// 200 or 206.
+func (f *corpusFetcher) get(rawURL string, offset int64) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "soup-go/"+version)
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %s: %s", rawURL, resp.Status)
+	}
+	return resp, nil
+}
+
+// fetchIndex downloads and parses the index, returning it with the hex
+// SHA-256 of its bytes
+func (f *corpusFetcher) fetchIndex(rawURL string) (*corpusIndex, string, error) {
+	resp, err := f.get(rawURL, 0)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to fetch corpus index: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to fetch corpus index: %w", err)
+	}
+	var index corpusIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, "", fmt.Errorf("failed to parse corpus index: %w", err)
+	}
+	if index.Version != corpusIndexVer {
+		return nil, "", fmt.Errorf("unsupported corpus index version %d", index.Version)
+	}
+	return &index, sha256Hex(data), nil
+}
+
+// validateCorpusEntry rejects entries that would be written outside the
+// sync directory or whose digest can't name an object
+func validateCorpusEntry(e vectorManifestEntry) error {
+	if e.Path == "" || path.IsAbs(e.Path) || strings.Contains(e.Path, "\\") ||
+		path.Clean(e.Path) != e.Path || e.Path == ".." || strings.HasPrefix(e.Path, "../") {
+		return fmt.Errorf("unsafe path %q", e.Path)
+	}
+	if e.Path == corpusIndexName || e.Path == corpusPartialDir || strings.HasPrefix(e.Path, corpusPartialDir+"/") {
+		return fmt.Errorf("path %q is reserved", e.Path)
+	}
+	if raw, err := hex.DecodeString(e.SHA256); err != nil || len(raw) != sha256Digest.New().Size() || e.SHA256 != strings.ToLower(e.SHA256) {
+		return fmt.Errorf("invalid sha256 %q", e.SHA256)
+	}
+	if e.Size < 0 {
+		return fmt.Errorf("invalid size %d", e.Size)
+	}
+	return nil
+}
+
+// corpusObjectURL resolves the object URL of digest against the index URL
+func corpusObjectURL(indexURL *url.URL, objects, digest string) (string, error) {
+	if objects == "" {
+		objects = defaultCorpusObjects
+	}
+	if !strings.Contains(objects, "{sha256}") {
+		return "", fmt.Errorf("objects template %q has no {sha256}", objects)
+	}
+	ref, err := url.Parse(strings.ReplaceAll(objects, "{sha256}", digest))
+	if err != nil {
+		return "", fmt.Errorf("invalid objects template %q: %w", objects, err)
+	}
+	return indexURL.ResolveReference(ref).String(), nil
+}
+
+// fetchObject downloads an object into partial, appending to what an
+// earlier attempt left there when the server honors ranges. It returns
+// the bytes downloaded and whether the download was resumed.
+func (f *corpusFetcher) fetchObject(rawURL, partial string, want int64) (int64, bool, error) {
+	var offset int64
+	if info, err := os.Stat(partial); err == nil {
+		offset = info.Size()
+	}
+	if offset > want {
+		// Longer than the file can be: not a prefix of it
+		os.Remove(partial)
+		offset = 0
+	}
+	if offset == want && offset > 0 {
+		return 0, false, nil
+	}
+
+	resp, err := f.get(rawURL, offset)
+	if err != nil {
+		return 0, false, err
+	}
+	defer resp.Body.Close()
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	resumed := offset > 0 && resp.StatusCode == http.StatusPartialContent
+	if resumed {
+		flags = os.O_WRONLY | os.O_APPEND
+	}
+	out, err := os.OpenFile(partial, flags, 0644)
+	if err != nil {
+		return 0, false, err
+	}
+	// Read one byte past the expected size so an oversized object is caught
+	// without downloading all of it
+	remaining := want + 1
+	if resumed {
+		remaining -= offset
+	}
+	n, copyErr := io.Copy(out, io.LimitReader(resp.Body, remaining))
+	closeErr := out.Close()
+	if copyErr != nil {
+		return n, resumed, copyErr
+	}
+	return n, resumed, closeErr
+}
+
+// syncEntry brings one file in dir up to date, reporting whether it
+// already was
+func (f *corpusFetcher) syncEntry(indexURL *url.URL, objects, dir string, e vectorManifestEntry, report *corpusSyncReport) (bool, error) {
+	dest := filepath.Join(dir, filepath.FromSlash(e.Path))
+	if size, sum, err := digestVectorFile(dest); err == nil && size == e.Size && sum == e.SHA256 {
+		return true, nil
+	}
+
+	objectURL, err := corpusObjectURL(indexURL, objects, e.SHA256)
+	if err != nil {
+		return false, err
+	}
+	partial := filepath.Join(dir, corpusPartialDir, e.SHA256)
+	var lastErr error
+	for attempt := 0; attempt <= f.retries; attempt++ {
+		if attempt > 0 {
+			logger.Debug("retrying corpus object", "path", e.Path, "attempt", attempt, "error", lastErr)
+			time.Sleep(time.Duration(attempt) * 500 * time.Millisecond)
+		}
+		n, resumed, err := f.fetchObject(objectURL, partial, e.Size)
+		report.BytesDownloaded += n
+		if resumed && !slices.Contains(report.Resumed, e.Path) {
+			report.Resumed = append(report.Resumed, e.Path)
+		}
+		if err != nil {
+			// Keep the partial file for the next attempt
+			lastErr = err
+			continue
+		}
+
+		size, sum, err := digestVectorFile(partial)
+		if err != nil {
+			return false, err
+		}
+		if size != e.Size || sum != e.SHA256 {
+			// A bad prefix can't be resumed from
+			os.Remove(partial)
+			lastErr = fmt.Errorf("downloaded %d bytes with sha256 %s, expected %d bytes with sha256 %s", size, sum, e.Size, e.SHA256)
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return false, err
+		}
+		if err := os.Rename(partial, dest); err != nil {
+			return false, err
+		}
+		return false, nil
+	}
+	return false, lastErr
+}
+
+// syncCorpus fetches every file the index at rawURL lists that dir lacks
+// or holds a different version of. A matching pin must equal the index's
+// SHA-256.
+func syncCorpus(rawURL, dir, pin string, fetcher *corpusFetcher) (*corpusSyncReport, error) {
+	start := time.Now()
+	indexURL, err := url.Parse(rawURL)
+	if err != nil || (indexURL.Scheme != "http" && indexURL.Scheme != "https") {
+		return nil, fmt.Errorf("--url must be an http or https URL, got %q", rawURL)
+	}
+
+	index, indexSum, err := fetcher.fetchIndex(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if pin != "" && !strings.EqualFold(pin, indexSum) {
+		return nil, fmt.Errorf("corpus index sha256 is %s, pinned %s", indexSum, pin)
+	}
+	seen := map[string]bool{}
+	for _, e := range index.Files {
+		if err := validateCorpusEntry(e); err != nil {
+			return nil, fmt.Errorf("corpus index: %w", err)
+		}
+		if seen[e.Path] {
+			return nil, fmt.Errorf("corpus index: path %q is listed twice", e.Path)
+		}
+		seen[e.Path] = true
+	}
+
+	if err := os.MkdirAll(filepath.Join(dir, corpusPartialDir), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create corpus directory: %w", err)
+	}
+	report := &corpusSyncReport{
+		URL:           rawURL,
+		Dir:           dir,
+		Name:          index.Name,
+		CorpusVersion: index.CorpusVersion,
+		IndexSHA256:   indexSum,
+		Files:         len(index.Files),
+		Fetched:       []string{},
+		Resumed:       []string{},
+		Failed:        []corpusFailure{},
+	}
+	for _, e := range index.Files {
+		upToDate, err := fetcher.syncEntry(indexURL, index.Objects, dir, e, report)
+		switch {
+		case err != nil:
+			logger.Warn("corpus file failed", "path", e.Path, "error", err)
+			report.Failed = append(report.Failed, corpusFailure{Path: e.Path, Error: err.Error()})
+		case upToDate:
+			report.UpToDate++
+		default:
+			logger.Debug("fetched corpus file", "path", e.Path, "size", e.Size)
+			report.Fetched = append(report.Fetched, e.Path)
+		}
+	}
+
+	if len(report.Failed) == 0 {
+		// Partial downloads are only kept while something is still missing
+		if err := os.RemoveAll(filepath.Join(dir, corpusPartialDir)); err != nil {
+			return nil, fmt.Errorf("failed to remove partial downloads: %w", err)
+		}
+		// Record the synced version, so a later run and readers of the
+		// directory can tell which corpus it holds
+		indexData, err := json.MarshalIndent(index, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode corpus index: %w", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, corpusIndexName), append(indexData, '\n'), 0644); err != nil {
+			return nil, fmt.Errorf("failed to write corpus index: %w", err)
+		}
+	}
+	report.DurationMs = float64(time.Since(start).Microseconds()) / 1000
+	return report, nil
+}
+
+func initHarnessCorpusCmd() *cobra.Command {
+	var indexURL string
+	var dir string
+	var pin string
+	var timeout time.Duration
+	var retries int
+	var outputFormat string
+
+	sync := &cobra.Command{
+		Use:   "sync",
+		Short: "Download a pinned fixture corpus by digest",
+		Long: `Fetch the fixture corpus described by a corpus.json index into a
+directory. The index lists each file's path, size and SHA-256; contents are
+fetched by digest from the index's "objects" URL template (default
+objects/{sha256} next to the index).
+
+Files already present with the listed digest are left alone, so a repeated
+sync only downloads what changed. Interrupted downloads are kept in
+.corpus-partial and resumed with a Range request. Every file is verified
+before it replaces the local copy. --sha256 pins the index itself, so a
+repo can require an exact corpus version. After a complete sync the index
+is written to the directory as corpus.json.`,
+		Example: `  soup-go harness corpus sync --url https://fixtures.example.com/v3/corpus.json --dir fixtures/
+  soup-go harness corpus sync --url https://fixtures.example.com/v3/corpus.json --dir fixtures/ \
+    --sha256 4f1c...e2 --output-format json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if indexURL == "" || dir == "" {
+				return fmt.Errorf("--url and --dir are required")
+			}
+			if retries < 0 {
+				return fmt.Errorf("--retries must not be negative")
+			}
+			fetcher := &corpusFetcher{client: &http.Client{Timeout: timeout}, retries: retries}
+			report, err := syncCorpus(indexURL, dir, pin, fetcher)
+			if err != nil {
+				return err
+			}
+
+			if outputFormat == "json" {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				if err := encoder.Encode(report); err != nil {
+					return fmt.Errorf("failed to encode JSON: %w", err)
+				}
+			} else {
+				name := report.Name
+				if report.CorpusVersion != "" {
+					name += " " + report.CorpusVersion
+				}
+				if name == "" {
+					name = report.URL
+				}
+				fmt.Printf("%s: %d files, %d up to date, %d fetched (%d resumed), %d bytes downloaded\n",
+					strings.TrimSpace(name), report.Files, report.UpToDate, len(report.Fetched), len(report.Resumed), report.BytesDownloaded)
+				for _, f := range report.Failed {
+					fmt.Printf("  ❌ %s: %s\n", f.Path, f.Error)
+				}
+				if len(report.Failed) == 0 {
+					fmt.Printf("  ✅ %s is in sync (index sha256 %s)\n", report.Dir, report.IndexSHA256)
+				}
+			}
+
+			if len(report.Failed) > 0 {
+				return fmt.Errorf("%d corpus files failed to sync", len(report.Failed))
+			}
+			return nil
+		},
+	}
+	sync.Flags().StringVar(&indexURL, "url", "", "URL of the corpus.json index (http or https)")
+	sync.Flags().StringVar(&dir, "dir", "", "Directory to sync the corpus into")
+	sync.Flags().StringVar(&pin, "sha256", "", "Expected SHA-256 of the index; refuse any other corpus version")
+	sync.Flags().DurationVar(&timeout, "timeout", 5*time.Minute, "Timeout for each HTTP request")
+	sync.Flags().IntVar(&retries, "retries", 3, "Retries per file after a failed or corrupt download")
+	sync.Flags().StringVar(&outputFormat, "output-format", "text", "Output format (text, json)")
+
+	cmd := &cobra.Command{
+		Use:   "corpus",
+		Short: "Share fixture corpora between harness repos",
+	}
+	cmd.AddCommand(sync)
+	return cmd
+}
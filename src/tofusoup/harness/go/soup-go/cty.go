@@ -2,6 +2,7 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"math/big"
@@ -19,6 +20,7 @@ var (
 	ctyInputFormat  string
 	ctyOutputFormat string
 	ctyTypeJSON     string
+	ctyStream       streamOptions
 )
 
 // Override the convert command with real implementation
@@ -26,17 +28,58 @@ func initCtyConvertCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "convert [input] [output]",
 		Short: "Convert CTY values between formats",
+		Long: `Convert a value of --type between JSON and msgpack.
+
+--encoding ctyjson-typed reads and writes JSON as Terraform's typed
+envelope, {"value": ..., "type": ...}. Typed input carries its type, so
+--type may be left out to keep it; otherwise the value is converted to
+--type. Typed output records the value's type next to it.`,
+		Example: `  soup-go cty convert in.json out.msgpack --type '["list","string"]'
+  soup-go cty convert typed.json out.msgpack --encoding ctyjson-typed
+  soup-go cty convert in.msgpack - --input-format msgpack --type '["map","number"]' --encoding ctyjson-typed`,
 		Args:  cobra.ExactArgs(2),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			inputPath := args[0]
 			outputPath := args[1]
 
+			if err := checkCtyEncoding(ctyEncoding, ctyInputFormat, ctyOutputFormat, ctyStream.Enabled, ctyTracePath != "", ctyRedact.Enabled); err != nil {
+				return err
+			}
+			typed := ctyEncoding == ctyEncodingTyped
+			if ctyTypeJSON == "" {
+				if !typed || ctyInputFormat != "json" {
+					return fmt.Errorf(`required flag(s) "type" not set`)
+				}
+				// The envelope says what the type is
+				ctyTypeJSON = `"dynamic"`
+			}
+
 			// Parse the type specification
 			ctyType, err := parseCtyType(json.RawMessage(ctyTypeJSON))
 			if err != nil {
 				return fmt.Errorf("failed to parse type: %w", err)
 			}
 
+			if err := ctyRedact.check(outputPath, ctyOutputFormat, ctyStream.Enabled); err != nil {
+				return err
+			}
+
+			if ctyTracePath != "" {
+				if ctyStream.Enabled || ctyInputFormat != "json" {
+					return fmt.Errorf("--trace-steps needs whole JSON input: drop --stream-parse and use --input-format json")
+				}
+				ctyTrace = &ctyConvertTracer{}
+			}
+
+			if ctyStream.Enabled {
+				if err := ctyStream.apply(); err != nil {
+					return err
+				}
+				return evalLimit.run(inputPath, func() error {
+					return streamConvert(inputPath, outputPath, ctyType, ctyInputFormat, ctyOutputFormat, false)
+				})
+			}
+
 			// Read input
 			var inputData []byte
 			if inputPath == "-" {
@@ -53,26 +96,63 @@ func initCtyConvertCmd() *cobra.Command {
 
 			// Convert based on formats
 			var value cty.Value
-			switch ctyInputFormat {
-			case "json":
-				value, err = buildCtyValueFromJSON(ctyType, inputData)
-				if err != nil {
-					return fmt.Errorf("failed to parse JSON input: %w", err)
+			err = evalLimit.run(inputPath, func() (err error) {
+				switch ctyInputFormat {
+				case "json":
+					if err := evalLimit.checkJSON(inputPath, inputData); err != nil {
+						return err
+					}
+					if typed {
+						value, err = decodeTypedJSON(inputData, ctyType)
+						if err != nil {
+							return fmt.Errorf("failed to parse typed JSON input: %w", err)
+						}
+						break
+					}
+					value, err = buildCtyValueFromJSON(ctyType, inputData)
+					if err != nil {
+						return fmt.Errorf("failed to parse JSON input: %w", err)
+					}
+				case "msgpack":
+					value, err = msgpack.Unmarshal(inputData, ctyType)
+					if err != nil {
+						return fmt.Errorf("failed to unmarshal msgpack: %w", err)
+					}
+				default:
+					return fmt.Errorf("unsupported input format: %s", ctyInputFormat)
 				}
-			case "msgpack":
-				value, err = msgpack.Unmarshal(inputData, ctyType)
-				if err != nil {
-					return fmt.Errorf("failed to unmarshal msgpack: %w", err)
+				return evalLimit.checkValue(inputPath, value)
+			})
+			// Write the trace even if conversion failed; its last step is
+			// where it did
+			if ctyTrace != nil {
+				if traceErr := ctyTrace.write(ctyTracePath); traceErr != nil && err == nil {
+					err = traceErr
 				}
-			default:
-				return fmt.Errorf("unsupported input format: %s", ctyInputFormat)
+			}
+			if err != nil {
+				return err
+			}
+
+			if ctyRedact.Enabled {
+				if ctyRedact.marksValues() {
+					value, err = ctyRedact.markSensitive(value)
+					if err != nil {
+						return err
+					}
+				}
+				return ctyRedact.writeRedacted(value, outputPath)
 			}
 
 			// Marshal to output format
 			var outputData []byte
 			switch ctyOutputFormat {
 			case "json":
-				outputData, err = ctyjson.Marshal(value, ctyType)
+				if typed {
+					outputData, err = encodeTypedJSON(value)
+				} else {
+					outputData, err = ctyjson.Marshal(value, ctyType)
+				}
 				if err != nil {
 					return fmt.Errorf("failed to marshal to JSON: %w", err)
 				}
@@ -102,20 +182,70 @@ func initCtyConvertCmd() *cobra.Command {
 	// Add flags
 	cmd.Flags().StringVar(&ctyInputFormat, "input-format", "json", "Input format (json, msgpack)")
 	cmd.Flags().StringVar(&ctyOutputFormat, "output-format", "json", "Output format (json, msgpack)")
-	cmd.Flags().StringVar(&ctyTypeJSON, "type", "", "CTY type specification as JSON")
-	cmd.MarkFlagRequired("type")
+	cmd.Flags().StringVar(&ctyTypeJSON, "type", "", "CTY type specification as JSON (optional with --encoding ctyjson-typed JSON input)")
+	cmd.Flags().StringVar(&ctyEncoding, "encoding", ctyEncodingPlain, "JSON encoding: plain, or ctyjson-typed for {\"value\", \"type\"} envelopes")
+	cmd.Flags().StringVar(&ctyTracePath, "trace-steps", "", "Write each conversion step (path, from, to, rule) to this file as NDJSON (- for stderr)")
+	addStreamFlags(cmd, &ctyStream)
+	addEvalLimitFlags(cmd, &evalLimit)
+	addRedactFlags(cmd, &ctyRedact)
 	
 	return cmd
 }
 
 // Override the validate command with real implementation
 func initCtyValidateCmd() *cobra.Command {
+	var schemaPath string
+	var resourceType string
+	var resourceMode string
+	var providerSource string
+	var outputFormat string
+
 	cmd := &cobra.Command{
 		Use:   "validate-value [value]",
 		Short: "Validate a CTY value",
-		Args:  cobra.ExactArgs(1),
+		Long: `Validate a CTY value against a bare type constraint (--type), or against a
+provider-style block schema (--schema) with attribute-path diagnostics.
+With --output-format github, failures are printed as GitHub Actions
+workflow annotations and the command fails.`,
+		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			valueJSON := args[0]
+			if outputFormat != "json" && outputFormat != "github" {
+				return fmt.Errorf("unsupported output format: %s", outputFormat)
+			}
+
+			// Schema mode validates blocks, nesting modes and required/computed attributes
+			if schemaPath != "" {
+				block, err := loadBlockSchema(schemaPath, providerSource, resourceMode, resourceType)
+				if err != nil {
+					return err
+				}
+
+				if err := evalLimit.run("value", func() error { return evalLimit.checkJSON("value", []byte(valueJSON)) }); err != nil {
+					return err
+				}
+
+				raw, err := decodeSchemaValue([]byte(valueJSON))
+				if err != nil {
+					return fmt.Errorf("failed to parse value JSON: %w", err)
+				}
+
+				diags := validateAgainstBlock(block, raw, cty.Path{})
+				if outputFormat == "github" {
+					return writeSchemaDiagnosticsGitHub(os.Stdout, diags, schemaPath)
+				}
+				result := map[string]interface{}{
+					"valid":       len(diags) == 0,
+					"diagnostics": diags,
+				}
+				if diags == nil {
+					result["diagnostics"] = []schemaDiagnostic{}
+				}
+				if err := json.NewEncoder(os.Stdout).Encode(result); err != nil {
+					return fmt.Errorf("failed to encode JSON: %w", err)
+				}
+				return nil
+			}
 
 			// Parse the type specification
 			ctyType, err := parseCtyType(json.RawMessage(ctyTypeJSON))
@@ -124,10 +254,32 @@ func initCtyValidateCmd() *cobra.Command {
 			}
 
 			// Build and validate the value
-			_, err = buildCtyValueFromJSON(ctyType, []byte(valueJSON))
+			err = evalLimit.run("value", func() error {
+				if err := evalLimit.checkJSON("value", []byte(valueJSON)); err != nil {
+					return err
+				}
+				value, err := buildCtyValueFromJSON(ctyType, []byte(valueJSON))
+				if err != nil {
+					return err
+				}
+				return evalLimit.checkValue("value", value)
+			})
+			var limitErr *resourceLimitError
+			if errors.As(err, &limitErr) {
+				return err
+			}
 			if err != nil {
+				if outputFormat == "github" {
+					writeGitHubAnnotation(os.Stdout, githubAnnotation{
+						Title:   "CTY validation failed",
+						Message: err.Error(),
+					})
+				}
 				return fmt.Errorf("validation failed: %w", err)
 			}
+			if outputFormat == "github" {
+				return nil
+			}
 
 			fmt.Println("Validation Succeeded")
 			return nil
@@ -136,7 +288,14 @@ func initCtyValidateCmd() *cobra.Command {
 	
 	// Add flags
 	cmd.Flags().StringVar(&ctyTypeJSON, "type", "", "CTY type specification as JSON")
-	cmd.MarkFlagRequired("type")
+	cmd.Flags().StringVar(&schemaPath, "schema", "", "Validate against a provider schema JSON file instead of a type")
+	cmd.Flags().StringVar(&resourceType, "resource-type", "", "Resource type to select from a provider schema document")
+	cmd.Flags().StringVar(&resourceMode, "mode", "managed", "Resource mode when selecting from a provider schema: managed, data")
+	cmd.Flags().StringVar(&providerSource, "provider", "", "Provider source address to select from a provider schema document")
+	cmd.Flags().StringVar(&outputFormat, "output-format", "json", "Output format (json, github)")
+	cmd.MarkFlagsOneRequired("type", "schema")
+	cmd.MarkFlagsMutuallyExclusive("type", "schema")
+	addEvalLimitFlags(cmd, &evalLimit)
 	
 	return cmd
 }
@@ -231,9 +390,13 @@ func buildCtyValueFromJSON(ty cty.Type, data []byte) (cty.Value, error) {
 		// For dynamic types, infer the type from the JSON
 		inferredType, err := ctyjson.ImpliedType(data)
 		if err != nil {
+			ctyTrace.fail(ctyTrace.step(nil, "json", ty, ruleImpliedType), err)
 			return cty.NilVal, err
 		}
-		return ctyjson.Unmarshal(data, inferredType)
+		step := ctyTrace.step(nil, "json", inferredType, ruleImpliedType)
+		val, err := ctyjson.Unmarshal(data, inferredType)
+		ctyTrace.fail(step, err)
+		return val, err
 	}
 
 	// Parse the JSON to handle special cases
@@ -248,6 +411,7 @@ func buildCtyValueFromJSON(ty cty.Type, data []byte) (cty.Value, error) {
 // buildValueFromInterface recursively builds a cty.Value from an interface{}
 func buildValueFromInterface(ty cty.Type, val interface{}, path []string) (cty.Value, error) {
 	if val == nil {
+		ctyTrace.step(path, "null", ty, ruleNull)
 		return cty.NullVal(ty), nil
 	}
 
@@ -257,14 +421,24 @@ func buildValueFromInterface(ty cty.Type, val interface{}, path []string) (cty.V
 	// "value is not known"
 	// This matches Terraform's behavior exactly
 
+	from := jsonKind(val)
+
 	// Handle primitive types
 	switch ty {
 	case cty.String:
+		step := ctyTrace.step(path, from, ty, ruleString)
 		if s, ok := val.(string); ok {
 			return cty.StringVal(s), nil
 		}
-		return cty.NilVal, fmt.Errorf("expected string at %s", strings.Join(path, "."))
+		err := fmt.Errorf("expected string at %s", strings.Join(path, "."))
+		ctyTrace.fail(step, err)
+		return cty.NilVal, err
 	case cty.Number:
+		rule := ruleNumber
+		if from == "string" {
+			rule = ruleNumberFromString
+		}
+		step := ctyTrace.step(path, from, ty, rule)
 		switch v := val.(type) {
 		case float64:
 			return cty.NumberFloatVal(v), nil
@@ -277,21 +451,37 @@ func buildValueFromInterface(ty cty.Type, val interface{}, path []string) (cty.V
 			if _, ok := bf.SetString(v); ok {
 				return cty.NumberVal(bf), nil
 			}
-			return cty.NilVal, fmt.Errorf("invalid number string at %s", strings.Join(path, "."))
+			err := fmt.Errorf("invalid number string at %s", strings.Join(path, "."))
+			ctyTrace.fail(step, err)
+			return cty.NilVal, err
 		}
-		return cty.NilVal, fmt.Errorf("expected number at %s", strings.Join(path, "."))
+		err := fmt.Errorf("expected number at %s", strings.Join(path, "."))
+		ctyTrace.fail(step, err)
+		return cty.NilVal, err
 	case cty.Bool:
+		step := ctyTrace.step(path, from, ty, ruleBool)
 		if b, ok := val.(bool); ok {
 			return cty.BoolVal(b), nil
 		}
-		return cty.NilVal, fmt.Errorf("expected bool at %s", strings.Join(path, "."))
+		err := fmt.Errorf("expected bool at %s", strings.Join(path, "."))
+		ctyTrace.fail(step, err)
+		return cty.NilVal, err
 	}
 
 	// Handle collection types
 	if ty.IsListType() || ty.IsSetType() || ty.IsTupleType() {
+		rule := ruleTuple
+		if ty.IsListType() {
+			rule = ruleList
+		} else if ty.IsSetType() {
+			rule = ruleSet
+		}
+		step := ctyTrace.step(path, from, ty, rule)
 		slice, ok := val.([]interface{})
 		if !ok {
-			return cty.NilVal, fmt.Errorf("expected array at %s", strings.Join(path, "."))
+			err := fmt.Errorf("expected array at %s", strings.Join(path, "."))
+			ctyTrace.fail(step, err)
+			return cty.NilVal, err
 		}
 
 		vals := make([]cty.Value, len(slice))
@@ -319,20 +509,32 @@ func buildValueFromInterface(ty cty.Type, val interface{}, path []string) (cty.V
 			if len(vals) == 0 {
 				return cty.SetValEmpty(ty.ElementType()), nil
 			}
-			return cty.SetVal(vals), nil
+			set := cty.SetVal(vals)
+			if n := set.LengthInt(); n < len(vals) {
+				ctyTrace.detail(step, "%d elements collapsed to %d distinct", len(vals), n)
+			}
+			return set, nil
 		}
 		return cty.TupleVal(vals), nil
 	}
 
 	// Handle map and object types
 	if ty.IsMapType() || ty.IsObjectType() {
+		rule := ruleObject
+		if ty.IsMapType() {
+			rule = ruleMap
+		}
+		step := ctyTrace.step(path, from, ty, rule)
 		m, ok := val.(map[string]interface{})
 		if !ok {
-			return cty.NilVal, fmt.Errorf("expected object at %s", strings.Join(path, "."))
+			err := fmt.Errorf("expected object at %s", strings.Join(path, "."))
+			ctyTrace.fail(step, err)
+			return cty.NilVal, err
 		}
 
 		vals := make(map[string]cty.Value)
-		for k, v := range m {
+		for _, k := range sortedKeys(m) {
+			v := m[k]
 			var elemTy cty.Type
 			if ty.IsObjectType() {
 				elemTy = ty.AttributeType(k)
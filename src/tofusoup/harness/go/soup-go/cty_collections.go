@@ -0,0 +1,482 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// Operations a collection probe case runs
+const (
+	// collectionOpConvert is convert.Convert of Input to Target
+	collectionOpConvert = "convert"
+	// collectionOpUnify is convert.Unify and convert.UnifyUnsafe of Types
+	collectionOpUnify = "unify"
+	// collectionOpImplied is ctyjson.ImpliedType of JSON, and decoding JSON
+	// as that type
+	collectionOpImplied = "implied"
+	// collectionOpLength is Input's Length
+	collectionOpLength = "length"
+)
+
+// collectionProbeCase is one collection conversion whose result other
+// runtimes tend to get wrong. Only the fields its Op uses are set.
+type collectionProbeCase struct {
+	Name        string
+	Category    string
+	Description string
+	Op          string
+	Input       cty.Value
+	Target      cty.Type
+	Types       []cty.Type
+	JSON        string
+}
+
+// collectionProbeSuite is the documented set of cases run by cty
+// collections probe. Names are stable so reports can be diffed across
+// harnesses and releases.
+var collectionProbeSuite = []collectionProbeCase{
+	{
+		Name: "tuple-to-list-same-types", Category: "tuple-to-list", Op: collectionOpConvert,
+		Description: "a tuple whose elements share a type becomes a list of that type",
+		Input:       cty.TupleVal([]cty.Value{cty.StringVal("a"), cty.StringVal("b")}),
+		Target:      cty.List(cty.DynamicPseudoType),
+	},
+	{
+		Name: "tuple-to-list-string-number", Category: "tuple-to-list", Op: collectionOpConvert,
+		Description: "mixed string and number elements unify to string",
+		Input:       cty.TupleVal([]cty.Value{cty.StringVal("a"), cty.NumberIntVal(1)}),
+		Target:      cty.List(cty.DynamicPseudoType),
+	},
+	{
+		Name: "tuple-to-list-number-bool", Category: "tuple-to-list", Op: collectionOpConvert,
+		Description: "number and bool elements don't unify, though each converts to string",
+		Input:       cty.TupleVal([]cty.Value{cty.NumberIntVal(1), cty.True}),
+		Target:      cty.List(cty.DynamicPseudoType),
+	},
+	{
+		Name: "tuple-to-list-number-numeric-string", Category: "tuple-to-list", Op: collectionOpConvert,
+		Description: "a numeric string converts to an explicit number element type",
+		Input:       cty.TupleVal([]cty.Value{cty.StringVal("1.50"), cty.NumberIntVal(2)}),
+		Target:      cty.List(cty.Number),
+	},
+	{
+		Name: "tuple-to-list-number-word", Category: "tuple-to-list", Op: collectionOpConvert,
+		Description: "a non-numeric string fails the whole conversion",
+		Input:       cty.TupleVal([]cty.Value{cty.StringVal("a"), cty.NumberIntVal(2)}),
+		Target:      cty.List(cty.Number),
+	},
+	{
+		Name: "tuple-to-list-objects-differing-attrs", Category: "tuple-to-list", Op: collectionOpConvert,
+		Description: "objects with different attributes unify to a map when their attributes share a type",
+		Input: cty.TupleVal([]cty.Value{
+			cty.ObjectVal(map[string]cty.Value{"a": cty.StringVal("x")}),
+			cty.ObjectVal(map[string]cty.Value{"b": cty.StringVal("y")}),
+		}),
+		Target: cty.List(cty.DynamicPseudoType),
+	},
+	{
+		Name: "tuple-to-list-objects-mixed-attrs", Category: "tuple-to-list", Op: collectionOpConvert,
+		Description: "objects with different attributes of different types have no common type",
+		Input: cty.TupleVal([]cty.Value{
+			cty.ObjectVal(map[string]cty.Value{"a": cty.StringVal("x")}),
+			cty.ObjectVal(map[string]cty.Value{"b": cty.ListValEmpty(cty.String)}),
+		}),
+		Target: cty.List(cty.DynamicPseudoType),
+	},
+	{
+		Name: "tuple-to-list-nested-tuple-and-list", Category: "tuple-to-list", Op: collectionOpConvert,
+		Description: "a nested tuple and a nested list unify to a list",
+		Input: cty.TupleVal([]cty.Value{
+			cty.ListVal([]cty.Value{cty.StringVal("a")}),
+			cty.TupleVal([]cty.Value{cty.StringVal("b"), cty.NumberIntVal(1)}),
+		}),
+		Target: cty.List(cty.DynamicPseudoType),
+	},
+	{
+		Name: "tuple-to-list-null-element", Category: "tuple-to-list", Op: collectionOpConvert,
+		Description: "a typed null element keeps its place in the list",
+		Input:       cty.TupleVal([]cty.Value{cty.StringVal("a"), cty.NullVal(cty.Number)}),
+		Target:      cty.List(cty.DynamicPseudoType),
+	},
+	{
+		Name: "tuple-to-list-dynamic-null-element", Category: "tuple-to-list", Op: collectionOpConvert,
+		Description: "an untyped null element takes the other elements' type",
+		Input:       cty.TupleVal([]cty.Value{cty.NullVal(cty.DynamicPseudoType), cty.NumberIntVal(1)}),
+		Target:      cty.List(cty.DynamicPseudoType),
+	},
+	{
+		Name: "tuple-to-list-unknown-element", Category: "tuple-to-list", Op: collectionOpConvert,
+		Description: "an unknown element stays unknown in a known list",
+		Input:       cty.TupleVal([]cty.Value{cty.StringVal("a"), cty.UnknownVal(cty.String)}),
+		Target:      cty.List(cty.String),
+	},
+	{
+		Name: "tuple-to-list-dynamic-unknown-element", Category: "tuple-to-list", Op: collectionOpConvert,
+		Description: "an element of unknown type takes the other elements' type",
+		Input:       cty.TupleVal([]cty.Value{cty.StringVal("a"), cty.DynamicVal}),
+		Target:      cty.List(cty.DynamicPseudoType),
+	},
+	{
+		Name: "set-of-unknown-to-list", Category: "set", Op: collectionOpConvert,
+		Description: "a set holding unknowns has no known order, so its list is wholly unknown",
+		Input:       cty.SetVal([]cty.Value{cty.StringVal("a"), cty.UnknownVal(cty.String)}),
+		Target:      cty.List(cty.String),
+	},
+	{
+		Name: "set-of-unknown-length", Category: "set", Op: collectionOpLength,
+		Description: "unknowns may equal other elements, so the length is unknown",
+		Input:       cty.SetVal([]cty.Value{cty.StringVal("a"), cty.UnknownVal(cty.String)}),
+	},
+	{
+		Name: "set-unknown-to-set-dynamic", Category: "set", Op: collectionOpConvert,
+		Description: "a wholly unknown set keeps its element type",
+		Input:       cty.UnknownVal(cty.Set(cty.Number)),
+		Target:      cty.Set(cty.DynamicPseudoType),
+	},
+	{
+		Name: "tuple-to-set-dedupe-after-unify", Category: "set", Op: collectionOpConvert,
+		Description: `"1" and 1 unify to string and then collapse to one element`,
+		Input:       cty.TupleVal([]cty.Value{cty.StringVal("1"), cty.NumberIntVal(1)}),
+		Target:      cty.Set(cty.DynamicPseudoType),
+	},
+	{
+		Name: "tuple-to-set-number-dedupe", Category: "set", Op: collectionOpConvert,
+		Description: `"1.0" converts to the number 1 and collapses with 1`,
+		Input:       cty.TupleVal([]cty.Value{cty.StringVal("1.0"), cty.NumberIntVal(1)}),
+		Target:      cty.Set(cty.Number),
+	},
+	{
+		Name: "list-to-set-order", Category: "set", Op: collectionOpConvert,
+		Description: "duplicates collapse and strings come out in cty's set order",
+		Input:       cty.ListVal([]cty.Value{cty.StringVal("b"), cty.StringVal("a"), cty.StringVal("b"), cty.StringVal("B")}),
+		Target:      cty.Set(cty.String),
+	},
+	{
+		Name: "set-to-tuple", Category: "set", Op: collectionOpConvert,
+		Description: "a set does not convert to a tuple, even of the same length",
+		Input:       cty.SetVal([]cty.Value{cty.StringVal("a"), cty.StringVal("b")}),
+		Target:      cty.Tuple([]cty.Type{cty.String, cty.String}),
+	},
+	{
+		Name: "empty-tuple-to-list-dynamic", Category: "empty", Op: collectionOpConvert,
+		Description: "an empty tuple gives no element type to unify",
+		Input:       cty.EmptyTupleVal,
+		Target:      cty.List(cty.DynamicPseudoType),
+	},
+	{
+		Name: "empty-tuple-to-set-dynamic", Category: "empty", Op: collectionOpConvert,
+		Description: "likewise for a set",
+		Input:       cty.EmptyTupleVal,
+		Target:      cty.Set(cty.DynamicPseudoType),
+	},
+	{
+		Name: "empty-tuple-to-map", Category: "empty", Op: collectionOpConvert,
+		Description: "a tuple never converts to a map, even when empty",
+		Input:       cty.EmptyTupleVal,
+		Target:      cty.Map(cty.String),
+	},
+	{
+		Name: "empty-object-to-map-dynamic", Category: "empty", Op: collectionOpConvert,
+		Description: "an empty object gives no element type to unify",
+		Input:       cty.EmptyObjectVal,
+		Target:      cty.Map(cty.DynamicPseudoType),
+	},
+	{
+		Name: "empty-list-to-empty-tuple", Category: "empty", Op: collectionOpConvert,
+		Description: "a list never converts to a tuple, even an empty one",
+		Input:       cty.ListValEmpty(cty.String),
+		Target:      cty.EmptyTuple,
+	},
+	{
+		Name: "empty-json-array", Category: "empty", Op: collectionOpImplied,
+		Description: "an empty JSON array implies the empty tuple, not a list",
+		JSON:        `[]`,
+	},
+	{
+		Name: "empty-json-object", Category: "empty", Op: collectionOpImplied,
+		Description: "an empty JSON object implies the empty object, not a map",
+		JSON:        `{}`,
+	},
+	{
+		Name: "json-mixed-array", Category: "empty", Op: collectionOpImplied,
+		Description: "a mixed JSON array implies a tuple that keeps each element's type",
+		JSON:        `["a", 1, [], {}]`,
+	},
+	{
+		Name: "unify-empty-tuple-and-list", Category: "unify", Op: collectionOpUnify,
+		Description: "the empty tuple unifies with a list of strings",
+		Types:       []cty.Type{cty.EmptyTuple, cty.List(cty.String)},
+	},
+	{
+		Name: "unify-tuples-of-different-lengths", Category: "unify", Op: collectionOpUnify,
+		Description: "tuples of different lengths unify to a list",
+		Types:       []cty.Type{cty.Tuple([]cty.Type{cty.String}), cty.Tuple([]cty.Type{cty.String, cty.String})},
+	},
+	{
+		Name: "unify-list-and-set", Category: "unify", Op: collectionOpUnify,
+		Description: "a list and a set of the same element type",
+		Types:       []cty.Type{cty.List(cty.String), cty.Set(cty.String)},
+	},
+	{
+		Name: "unify-map-and-object", Category: "unify", Op: collectionOpUnify,
+		Description: "a map and an object whose attributes convert to its element type",
+		Types:       []cty.Type{cty.Map(cty.String), cty.Object(map[string]cty.Type{"n": cty.Number})},
+	},
+}
+
+// collectionVerdict is what go-cty made of a case
+type collectionVerdict struct {
+	OK   bool            `json:"ok"`
+	Type json.RawMessage `json:"type,omitempty"`
+	// Value is the result, with unknowns as {"unknown": type}
+	Value json.RawMessage `json:"value,omitempty"`
+	// WhollyKnown is whether the result has no unknowns in it
+	WhollyKnown *bool `json:"wholly_known,omitempty"`
+	// Safe is whether a convert case needs no unsafe conversion
+	Safe *bool `json:"safe,omitempty"`
+	// UnsafeType is what convert.UnifyUnsafe gives for a unify case
+	UnsafeType json.RawMessage `json:"unsafe_type,omitempty"`
+	Error      string          `json:"error,omitempty"`
+}
+
+// collectionCaseReport is one case of a collections probe report
+type collectionCaseReport struct {
+	Name        string            `json:"name"`
+	Category    string            `json:"category"`
+	Description string            `json:"description"`
+	Op          string            `json:"op"`
+	Input       json.RawMessage   `json:"input,omitempty"`
+	InputType   json.RawMessage   `json:"input_type,omitempty"`
+	Target      json.RawMessage   `json:"target,omitempty"`
+	Types       []json.RawMessage `json:"types,omitempty"`
+	Verdict     collectionVerdict `json:"verdict"`
+}
+
+// probeValueJSON renders val as JSON, unlike ctyjson.Marshal accepting
+// unknowns, which become {"unknown": type}. Set elements come in cty's
+// set order.
+func probeValueJSON(val cty.Value) (json.RawMessage, error) {
+	tree, err := probeValueTree(val)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(tree)
+}
+
+func probeValueTree(val cty.Value) (interface{}, error) {
+	if !val.IsKnown() {
+		ty, err := ctyjson.MarshalType(val.Type())
+		if err != nil {
+			return nil, err
+		}
+		return map[string]json.RawMessage{"unknown": ty}, nil
+	}
+	if val.IsNull() {
+		return nil, nil
+	}
+	ty := val.Type()
+	switch {
+	case ty == cty.String:
+		return val.AsString(), nil
+	case ty == cty.Number:
+		return json.Number(val.AsBigFloat().Text('f', -1)), nil
+	case ty == cty.Bool:
+		return val.True(), nil
+	case ty.IsListType() || ty.IsSetType() || ty.IsTupleType():
+		elems := []interface{}{}
+		for it := val.ElementIterator(); it.Next(); {
+			_, elem := it.Element()
+			tree, err := probeValueTree(elem)
+			if err != nil {
+				return nil, err
+			}
+			elems = append(elems, tree)
+		}
+		return elems, nil
+	case ty.IsMapType() || ty.IsObjectType():
+		attrs := map[string]interface{}{}
+		for it := val.ElementIterator(); it.Next(); {
+			key, elem := it.Element()
+			tree, err := probeValueTree(elem)
+			if err != nil {
+				return nil, err
+			}
+			attrs[key.AsString()] = tree
+		}
+		return attrs, nil
+	}
+	return nil, fmt.Errorf("cannot render a value of type %s", ty.FriendlyName())
+}
+
+// setResult records a successful result in the verdict
+func (v *collectionVerdict) setResult(val cty.Value) error {
+	var err error
+	if v.Type, err = ctyjson.MarshalType(val.Type()); err != nil {
+		return err
+	}
+	if v.Value, err = probeValueJSON(val); err != nil {
+		return err
+	}
+	known := val.IsWhollyKnown()
+	v.OK, v.WhollyKnown = true, &known
+	return nil
+}
+
+// probeCollectionCase runs one case. Errors are from rendering the report;
+// a failed conversion is a verdict.
+func probeCollectionCase(c collectionProbeCase) (collectionCaseReport, error) {
+	report := collectionCaseReport{Name: c.Name, Category: c.Category, Description: c.Description, Op: c.Op}
+	var err error
+	switch c.Op {
+	case collectionOpConvert, collectionOpLength:
+		if report.Input, err = probeValueJSON(c.Input); err != nil {
+			return report, err
+		}
+		if report.InputType, err = ctyjson.MarshalType(c.Input.Type()); err != nil {
+			return report, err
+		}
+	}
+
+	switch c.Op {
+	case collectionOpConvert:
+		if report.Target, err = ctyjson.MarshalType(c.Target); err != nil {
+			return report, err
+		}
+		safe := convert.GetConversion(c.Input.Type(), c.Target) != nil
+		report.Verdict.Safe = &safe
+		out, convErr := convert.Convert(c.Input, c.Target)
+		if convErr != nil {
+			report.Verdict.Error = convErr.Error()
+			return report, nil
+		}
+		err = report.Verdict.setResult(out)
+
+	case collectionOpLength:
+		err = report.Verdict.setResult(c.Input.Length())
+
+	case collectionOpImplied:
+		report.Input = json.RawMessage(c.JSON)
+		ty, impliedErr := ctyjson.ImpliedType([]byte(c.JSON))
+		if impliedErr != nil {
+			report.Verdict.Error = impliedErr.Error()
+			return report, nil
+		}
+		val, decodeErr := ctyjson.Unmarshal([]byte(c.JSON), ty)
+		if decodeErr != nil {
+			report.Verdict.Error = decodeErr.Error()
+			return report, nil
+		}
+		err = report.Verdict.setResult(val)
+
+	case collectionOpUnify:
+		for _, ty := range c.Types {
+			data, err := ctyjson.MarshalType(ty)
+			if err != nil {
+				return report, err
+			}
+			report.Types = append(report.Types, data)
+		}
+		safe, _ := convert.Unify(c.Types)
+		unsafe, _ := convert.UnifyUnsafe(c.Types)
+		report.Verdict.OK = safe != cty.NilType
+		report.Verdict.Type = lawUnifyResult(safe)
+		report.Verdict.UnsafeType = lawUnifyResult(unsafe)
+		if !report.Verdict.OK {
+			report.Verdict.Error = "no common type"
+		}
+	}
+	return report, err
+}
+
+// selectCollectionCases returns the suite cases named, or all of them
+func selectCollectionCases(names []string) ([]collectionProbeCase, error) {
+	if len(names) == 0 {
+		return collectionProbeSuite, nil
+	}
+	byName := map[string]collectionProbeCase{}
+	known := make([]string, len(collectionProbeSuite))
+	for i, c := range collectionProbeSuite {
+		byName[c.Name] = c
+		known[i] = c.Name
+	}
+	var cases []collectionProbeCase
+	for _, name := range names {
+		c, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown case %q (expected one of: %s)", name, strings.Join(known, ", "))
+		}
+		cases = append(cases, c)
+	}
+	return cases, nil
+}
+
+func initCtyCollectionsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "collections",
+		Short: "Probe go-cty's collection conversion rules",
+	}
+	cmd.AddCommand(initCtyCollectionsProbeCmd())
+	return cmd
+}
+
+func initCtyCollectionsProbeCmd() *cobra.Command {
+	var (
+		cases    []string
+		category string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "probe",
+		Short: "Report go-cty's verdicts on a suite of tricky collection conversions",
+		Long: `Run a documented suite of collection conversions through go-cty and
+report each verdict: the result type and value, whether it is wholly known,
+and whether the conversion is safe, or the error. Categories:
+
+  tuple-to-list  tuples of mixed element types, nulls and unknowns
+                 converted to lists
+  set            sets holding unknowns, and element collapsing after
+                 unification
+  empty          typing of empty tuples, objects and JSON collections
+  unify          type unification, safe and unsafe
+
+Unknowns are written as {"unknown": type}. Collection conversion is where
+implementations disagree most after numbers, so diff this report against
+another runtime's verdicts for the same case names.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			selected, err := selectCollectionCases(cases)
+			if err != nil {
+				return err
+			}
+			reports := []collectionCaseReport{}
+			for _, c := range selected {
+				if category != "" && c.Category != category {
+					continue
+				}
+				report, err := probeCollectionCase(c)
+				if err != nil {
+					return fmt.Errorf("case %s: %w", c.Name, err)
+				}
+				reports = append(reports, report)
+			}
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(map[string]interface{}{
+				"probe":   "cty-collections",
+				"harness": "soup-go",
+				"cases":   reports,
+			})
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&cases, "case", nil, "Only run these cases (default all)")
+	cmd.Flags().StringVar(&category, "category", "", "Only run cases in this category: tuple-to-list, set, empty or unify")
+	return cmd
+}
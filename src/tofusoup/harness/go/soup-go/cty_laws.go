@@ -0,0 +1,528 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+	ctymsgpack "github.com/zclconf/go-cty/cty/msgpack"
+)
+
+// Laws checked by cty laws
+const (
+	lawRoundTrip         = "roundtrip"
+	lawIdempotentConvert = "idempotent-convert"
+	lawUnifyCommutes     = "unify-commutes"
+)
+
+// ctyLaw is a property checked against generated inputs. check returns nil
+// when the law holds, errLawSkipped when the input doesn't apply.
+type ctyLaw struct {
+	Name        string
+	Description string
+	check       func(r *rand.Rand) (*lawCounterexample, error)
+}
+
+// lawCounterexample is an input that breaks a law, written as a corpus file
+// that every harness must also satisfy
+type lawCounterexample struct {
+	Law         string          `json:"law"`
+	Description string          `json:"description"`
+	Seed        int64           `json:"seed"`
+	Type        json.RawMessage `json:"type"`
+	Value       json.RawMessage `json:"value,omitempty"`
+	TargetType  json.RawMessage `json:"target_type,omitempty"`
+	Expected    json.RawMessage `json:"expected,omitempty"`
+	Actual      json.RawMessage `json:"actual,omitempty"`
+	Error       string          `json:"error,omitempty"`
+}
+
+// lawReport summarizes the runs of one law
+type lawReport struct {
+	Law             string   `json:"law"`
+	Passed          int      `json:"passed"`
+	Skipped         int      `json:"skipped"`
+	Failed          int      `json:"failed"`
+	Counterexamples []string `json:"counterexamples,omitempty"`
+}
+
+var errLawSkipped = fmt.Errorf("input does not apply to law")
+
+var ctyLaws = []ctyLaw{
+	{
+		Name:        lawRoundTrip,
+		Description: "convert(convert(v, widen(T)), T) == v, and msgpack decode(encode(v)) == v",
+		check:       checkRoundTripLaw,
+	},
+	{
+		Name:        lawIdempotentConvert,
+		Description: "convert(convert(v, T2), T2) == convert(v, T2) whenever the first conversion succeeds",
+		check:       checkIdempotentConvertLaw,
+	},
+	{
+		Name:        lawUnifyCommutes,
+		Description: "unify([A, B]) and unify([B, A]) give the same type, or both fail",
+		check:       checkUnifyCommutesLaw,
+	},
+}
+
+// Generation limits, kept small so counterexamples stay readable
+const (
+	lawMaxDepth    = 3
+	lawMaxElements = 3
+)
+
+var lawStrings = []string{"", "a", "hello", "true", "false", "0", "12", "-1.5", "1e3", "ünïcödé", "☃", " padded ", "null"}
+
+var lawNumbers = []string{"0", "1", "-1", "1.5", "-0.25", "42", "1e20", "123456789012345678901234567890", "0.1"}
+
+var lawAttrNames = []string{"a", "b", "c", "id", "name"}
+
+// genLawType returns a random type, nesting at most depth levels
+func genLawType(r *rand.Rand, depth int) cty.Type {
+	n := 3
+	if depth > 0 {
+		n = 8
+	}
+	switch r.Intn(n) {
+	case 0:
+		return cty.String
+	case 1:
+		return cty.Number
+	case 2:
+		return cty.Bool
+	case 3:
+		return cty.List(genLawType(r, depth-1))
+	case 4:
+		return cty.Set(genLawType(r, depth-1))
+	case 5:
+		return cty.Map(genLawType(r, depth-1))
+	case 6:
+		attrs := map[string]cty.Type{}
+		for _, name := range r.Perm(len(lawAttrNames))[:1+r.Intn(lawMaxElements)] {
+			attrs[lawAttrNames[name]] = genLawType(r, depth-1)
+		}
+		return cty.Object(attrs)
+	default:
+		elems := make([]cty.Type, r.Intn(lawMaxElements+1))
+		for i := range elems {
+			elems[i] = genLawType(r, depth-1)
+		}
+		return cty.Tuple(elems)
+	}
+}
+
+// genLawValue returns a random known value of ty. Nulls appear anywhere
+// except as set elements.
+func genLawValue(r *rand.Rand, ty cty.Type, allowNull bool) cty.Value {
+	if allowNull && r.Intn(8) == 0 {
+		return cty.NullVal(ty)
+	}
+
+	switch {
+	case ty == cty.String:
+		return cty.StringVal(lawStrings[r.Intn(len(lawStrings))])
+	case ty == cty.Number:
+		f, _, _ := big.ParseFloat(lawNumbers[r.Intn(len(lawNumbers))], 10, 512, big.ToNearestEven)
+		return cty.NumberVal(f)
+	case ty == cty.Bool:
+		return cty.BoolVal(r.Intn(2) == 0)
+	case ty.IsListType():
+		elems := genLawElements(r, ty.ElementType(), true)
+		if len(elems) == 0 {
+			return cty.ListValEmpty(ty.ElementType())
+		}
+		return cty.ListVal(elems)
+	case ty.IsSetType():
+		elems := genLawElements(r, ty.ElementType(), false)
+		if len(elems) == 0 {
+			return cty.SetValEmpty(ty.ElementType())
+		}
+		return cty.SetVal(elems)
+	case ty.IsMapType():
+		elems := genLawElements(r, ty.ElementType(), true)
+		if len(elems) == 0 {
+			return cty.MapValEmpty(ty.ElementType())
+		}
+		vals := map[string]cty.Value{}
+		for i, elem := range elems {
+			vals[fmt.Sprintf("k%d", i)] = elem
+		}
+		return cty.MapVal(vals)
+	case ty.IsObjectType():
+		vals := map[string]cty.Value{}
+		for name, aty := range ty.AttributeTypes() {
+			vals[name] = genLawValue(r, aty, true)
+		}
+		return cty.ObjectVal(vals)
+	case ty.IsTupleType():
+		vals := make([]cty.Value, len(ty.TupleElementTypes()))
+		for i, ety := range ty.TupleElementTypes() {
+			vals[i] = genLawValue(r, ety, true)
+		}
+		return cty.TupleVal(vals)
+	}
+	return cty.NullVal(ty)
+}
+
+func genLawElements(r *rand.Rand, ety cty.Type, allowNull bool) []cty.Value {
+	elems := make([]cty.Value, r.Intn(lawMaxElements+1))
+	for i := range elems {
+		elems[i] = genLawValue(r, ety, allowNull)
+	}
+	return elems
+}
+
+// widenLawType replaces every primitive in ty with string, which every
+// primitive converts to and back from
+func widenLawType(ty cty.Type) cty.Type {
+	switch {
+	case ty.IsPrimitiveType():
+		return cty.String
+	case ty.IsListType():
+		return cty.List(widenLawType(ty.ElementType()))
+	case ty.IsSetType():
+		return cty.Set(widenLawType(ty.ElementType()))
+	case ty.IsMapType():
+		return cty.Map(widenLawType(ty.ElementType()))
+	case ty.IsObjectType():
+		attrs := map[string]cty.Type{}
+		for name, aty := range ty.AttributeTypes() {
+			attrs[name] = widenLawType(aty)
+		}
+		return cty.Object(attrs)
+	case ty.IsTupleType():
+		elems := make([]cty.Type, len(ty.TupleElementTypes()))
+		for i, ety := range ty.TupleElementTypes() {
+			elems[i] = widenLawType(ety)
+		}
+		return cty.Tuple(elems)
+	}
+	return ty
+}
+
+// reshapeLawType swaps collection kinds (list, set, tuple; map, object) so
+// conversions between structural and collection types get exercised
+func reshapeLawType(r *rand.Rand, ty cty.Type) cty.Type {
+	switch {
+	case ty.IsListType():
+		return cty.Set(ty.ElementType())
+	case ty.IsSetType():
+		return cty.List(ty.ElementType())
+	case ty.IsTupleType():
+		if r.Intn(2) == 0 {
+			return cty.List(cty.String)
+		}
+		return cty.Set(cty.String)
+	case ty.IsObjectType():
+		return cty.Map(cty.String)
+	case ty.IsMapType():
+		return cty.Object(map[string]cty.Type{"k0": ty.ElementType()})
+	}
+	return cty.String
+}
+
+func checkRoundTripLaw(r *rand.Rand) (*lawCounterexample, error) {
+	ty := genLawType(r, lawMaxDepth)
+	val := genLawValue(r, ty, true)
+	wide := widenLawType(ty)
+
+	cx := &lawCounterexample{}
+	if err := cx.setInput(val, ty, wide); err != nil {
+		return nil, err
+	}
+
+	widened, err := convert.Convert(val, wide)
+	if err != nil {
+		cx.Error = fmt.Sprintf("convert to %s: %s", wide.FriendlyName(), err)
+		return cx, nil
+	}
+	back, err := convert.Convert(widened, ty)
+	if err != nil {
+		cx.Error = fmt.Sprintf("convert back to %s: %s", ty.FriendlyName(), err)
+		return cx, nil
+	}
+	if !back.RawEquals(val) {
+		return cx.mismatch(val, back)
+	}
+
+	packed, err := ctymsgpack.Marshal(val, ty)
+	if err != nil {
+		cx.Error = fmt.Sprintf("msgpack encode: %s", err)
+		return cx, nil
+	}
+	unpacked, err := ctymsgpack.Unmarshal(packed, ty)
+	if err != nil {
+		cx.Error = fmt.Sprintf("msgpack decode: %s", err)
+		return cx, nil
+	}
+	if !unpacked.RawEquals(val) {
+		return cx.mismatch(val, unpacked)
+	}
+	return nil, nil
+}
+
+func checkIdempotentConvertLaw(r *rand.Rand) (*lawCounterexample, error) {
+	ty := genLawType(r, lawMaxDepth)
+	val := genLawValue(r, ty, true)
+
+	var target cty.Type
+	switch r.Intn(3) {
+	case 0:
+		target = widenLawType(ty)
+	case 1:
+		target = reshapeLawType(r, ty)
+	default:
+		target = genLawType(r, lawMaxDepth)
+	}
+
+	once, err := convert.Convert(val, target)
+	if err != nil {
+		return nil, errLawSkipped
+	}
+
+	cx := &lawCounterexample{}
+	if err := cx.setInput(val, ty, target); err != nil {
+		return nil, err
+	}
+	twice, err := convert.Convert(once, target)
+	if err != nil {
+		cx.Error = fmt.Sprintf("second convert to %s: %s", target.FriendlyName(), err)
+		return cx, nil
+	}
+	if !twice.RawEquals(once) {
+		return cx.mismatch(once, twice)
+	}
+	return nil, nil
+}
+
+func checkUnifyCommutesLaw(r *rand.Rand) (*lawCounterexample, error) {
+	a := genLawType(r, lawMaxDepth)
+	var b cty.Type
+	switch r.Intn(3) {
+	case 0:
+		b = widenLawType(a)
+	case 1:
+		b = reshapeLawType(r, a)
+	default:
+		b = genLawType(r, lawMaxDepth)
+	}
+
+	ab, _ := convert.Unify([]cty.Type{a, b})
+	ba, _ := convert.Unify([]cty.Type{b, a})
+	if ab == cty.NilType && ba == cty.NilType {
+		return nil, errLawSkipped
+	}
+	if ab != cty.NilType && ba != cty.NilType && ab.Equals(ba) {
+		return nil, nil
+	}
+
+	cx := &lawCounterexample{}
+	var err error
+	if cx.Type, err = ctyjson.MarshalType(a); err != nil {
+		return nil, err
+	}
+	if cx.TargetType, err = ctyjson.MarshalType(b); err != nil {
+		return nil, err
+	}
+	cx.Expected = lawUnifyResult(ab)
+	cx.Actual = lawUnifyResult(ba)
+	return cx, nil
+}
+
+// lawUnifyResult renders a unified type, or null when unification failed
+func lawUnifyResult(ty cty.Type) json.RawMessage {
+	if ty == cty.NilType {
+		return json.RawMessage("null")
+	}
+	data, err := ctyjson.MarshalType(ty)
+	if err != nil {
+		return json.RawMessage("null")
+	}
+	return data
+}
+
+func (cx *lawCounterexample) setInput(val cty.Value, ty, target cty.Type) error {
+	var err error
+	if cx.Type, err = ctyjson.MarshalType(ty); err != nil {
+		return err
+	}
+	if cx.Value, err = ctyjson.Marshal(val, ty); err != nil {
+		return err
+	}
+	cx.TargetType, err = ctyjson.MarshalType(target)
+	return err
+}
+
+func (cx *lawCounterexample) mismatch(expected, actual cty.Value) (*lawCounterexample, error) {
+	var err error
+	if cx.Expected, err = ctyjson.Marshal(expected, expected.Type()); err != nil {
+		return nil, err
+	}
+	if cx.Actual, err = ctyjson.Marshal(actual, actual.Type()); err != nil {
+		return nil, err
+	}
+	return cx, nil
+}
+
+// runCtyLaw checks law against iterations generated inputs. Iteration i
+// uses seed+i, so any counterexample is reproduced by its own seed with a
+// single iteration.
+func runCtyLaw(law ctyLaw, seed int64, iterations int, corpusDir string) (*lawReport, error) {
+	report := &lawReport{Law: law.Name}
+	for i := 0; i < iterations; i++ {
+		caseSeed := seed + int64(i)
+		cx, err := law.check(rand.New(rand.NewSource(caseSeed)))
+		switch {
+		case err == errLawSkipped:
+			report.Skipped++
+			continue
+		case err != nil:
+			return nil, fmt.Errorf("law %s, seed %d: %w", law.Name, caseSeed, err)
+		case cx == nil:
+			report.Passed++
+			continue
+		}
+
+		report.Failed++
+		cx.Law = law.Name
+		cx.Description = law.Description
+		cx.Seed = caseSeed
+
+		data, err := json.MarshalIndent(cx, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode counterexample: %w", err)
+		}
+		if corpusDir == "" {
+			report.Counterexamples = append(report.Counterexamples, string(data))
+			continue
+		}
+		path := filepath.Join(corpusDir, fmt.Sprintf("%s-%d.json", law.Name, caseSeed))
+		if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+			return nil, fmt.Errorf("failed to write counterexample: %w", err)
+		}
+		report.Counterexamples = append(report.Counterexamples, path)
+	}
+	return report, nil
+}
+
+func initCtyLawsCmd() *cobra.Command {
+	var (
+		laws       []string
+		iterations int
+		seed       int64
+		corpusDir  string
+		signKey    string
+	)
+
+	names := make([]string, len(ctyLaws))
+	for i, law := range ctyLaws {
+		names[i] = law.Name
+	}
+
+	cmd := &cobra.Command{
+		Use:   "laws",
+		Short: "Property-check convert and unify laws against generated values",
+		Long: `Generate random cty types and values from a seed and check algebraic laws
+that every cty implementation should share:
+
+  roundtrip            convert to a widened (all-string) type and back gives
+                       the original value, as does a msgpack round trip
+  idempotent-convert   converting an already converted value again changes
+                       nothing
+  unify-commutes       unify is independent of argument order
+
+Iteration i uses seed+i, so a counterexample is reproduced by running its
+seed with --iterations 1. With --corpus-dir each counterexample is written
+as a JSON corpus file for the other harnesses to check; otherwise they are
+printed inline. With --sign key.pem the corpus files are recorded in a
+signed MANIFEST.json (see 'harness verify-vectors'). Exits non-zero if any
+law is broken.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			known := map[string]bool{}
+			for _, name := range names {
+				known[name] = true
+			}
+			selected := map[string]bool{}
+			for _, name := range laws {
+				if !known[name] {
+					return fmt.Errorf("unknown law %q (expected one of: %s)", name, strings.Join(names, ", "))
+				}
+				selected[name] = true
+			}
+			if iterations < 1 {
+				return fmt.Errorf("--iterations must be at least 1")
+			}
+			if seed == 0 {
+				seed = time.Now().UnixNano()
+			}
+			if signKey != "" && corpusDir == "" {
+				return fmt.Errorf("--sign requires --corpus-dir")
+			}
+			if corpusDir != "" {
+				if err := os.MkdirAll(corpusDir, 0755); err != nil {
+					return fmt.Errorf("failed to create corpus directory: %w", err)
+				}
+			}
+
+			var reports []*lawReport
+			failed := 0
+			for _, law := range ctyLaws {
+				if !selected[law.Name] {
+					continue
+				}
+				logger.Debug("🔍 checking cty law", "law", law.Name, "seed", seed, "iterations", iterations)
+				report, err := runCtyLaw(law, seed, iterations, corpusDir)
+				if err != nil {
+					return err
+				}
+				failed += report.Failed
+				reports = append(reports, report)
+			}
+			sort.Slice(reports, func(i, j int) bool { return reports[i].Law < reports[j].Law })
+
+			if signKey != "" {
+				var written []string
+				for _, report := range reports {
+					written = append(written, report.Counterexamples...)
+				}
+				if err := signVectorFiles(corpusDir, written, signKey); err != nil {
+					return err
+				}
+			}
+
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(map[string]interface{}{
+				"seed":       seed,
+				"iterations": iterations,
+				"laws":       reports,
+			}); err != nil {
+				return fmt.Errorf("failed to encode JSON: %w", err)
+			}
+
+			if failed > 0 {
+				return fmt.Errorf("%d counterexample(s) found", failed)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&laws, "law", names, "Laws to check: "+strings.Join(names, "|"))
+	cmd.Flags().IntVar(&iterations, "iterations", 100, "Generated inputs per law")
+	cmd.Flags().Int64Var(&seed, "seed", 0, "Generation seed (0 picks one from the clock)")
+	cmd.Flags().StringVar(&corpusDir, "corpus-dir", "", "Directory to write counterexamples to as corpus files")
+	cmd.Flags().StringVar(&signKey, "sign", "", "PEM private key to sign the corpus directory's manifest with")
+	return cmd
+}
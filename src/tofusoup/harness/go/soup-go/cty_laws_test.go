@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+func runCtyLawsCmd(t *testing.T, args ...string) ([]lawReport, error) {
+	t.Helper()
+	cmd := initCtyLawsCmd()
+	cmd.SetArgs(args)
+	cmd.SilenceUsage, cmd.SilenceErrors = true, true
+	out, err := captureStdout(t, cmd.Execute)
+	if len(out) == 0 {
+		return nil, err
+	}
+	var output struct {
+		Laws []lawReport `json:"laws"`
+	}
+	if jerr := json.Unmarshal(out, &output); jerr != nil {
+		t.Fatalf("%v: %s", jerr, out)
+	}
+	return output.Laws, err
+}
+
+func TestCtyLawsHold(t *testing.T) {
+	reports, err := runCtyLawsCmd(t, "--seed", "1", "--iterations", "300")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != len(ctyLaws) {
+		t.Fatalf("got %d law reports, want %d", len(reports), len(ctyLaws))
+	}
+	for _, report := range reports {
+		if report.Failed != 0 || report.Passed+report.Skipped != 300 {
+			t.Errorf("%s: got %+v, want 300 inputs and no counterexamples", report.Law, report)
+		}
+		if report.Passed == 0 {
+			t.Errorf("%s: every generated input was skipped", report.Law)
+		}
+	}
+
+	again, err := runCtyLawsCmd(t, "--seed", "1", "--iterations", "300", "--law", lawUnifyCommutes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, report := range reports {
+		if report.Law == lawUnifyCommutes && (len(again) != 1 || again[0].Skipped != report.Skipped) {
+			t.Errorf("the same seed skipped different inputs: %+v then %+v", report, again)
+		}
+	}
+}
+
+func TestCtyLawsCmdRejectsBadFlags(t *testing.T) {
+	for _, args := range [][]string{
+		{"--law", "associativity"},
+		{"--iterations", "0"},
+		{"--sign", "key.pem"},
+	} {
+		if _, err := runCtyLawsCmd(t, args...); err == nil {
+			t.Errorf("%v: got no error", args)
+		}
+	}
+}
+
+func TestRunCtyLawWritesCounterexamples(t *testing.T) {
+	// A law broken by about half of the generated inputs
+	broken := ctyLaw{
+		Name:        "broken",
+		Description: "half of all inputs break this law",
+		check: func(r *rand.Rand) (*lawCounterexample, error) {
+			if r.Int63()%2 == 1 {
+				return nil, nil
+			}
+			cx := &lawCounterexample{}
+			if err := cx.setInput(cty.StringVal("x"), cty.String, cty.Number); err != nil {
+				return nil, err
+			}
+			return cx.mismatch(cty.StringVal("x"), cty.StringVal("y"))
+		},
+	}
+
+	dir := t.TempDir()
+	report, err := runCtyLaw(broken, 10, 20, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Failed == 0 || report.Passed+report.Failed != 20 || len(report.Counterexamples) != report.Failed {
+		t.Fatalf("got %+v, want some of 20 inputs to fail with a file each", report)
+	}
+
+	data, err := os.ReadFile(report.Counterexamples[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cx lawCounterexample
+	if err := json.Unmarshal(data, &cx); err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(report.Counterexamples[0]) != fmt.Sprintf("broken-%d.json", cx.Seed) {
+		t.Errorf("counterexample %s isn't named after its seed %d", report.Counterexamples[0], cx.Seed)
+	}
+	if cx.Law != "broken" || string(cx.Expected) != `"x"` || string(cx.Actual) != `"y"` || string(cx.TargetType) != `"number"` {
+		t.Errorf("got counterexample %s", data)
+	}
+
+	// The counterexample's seed reproduces it on its own
+	rerun, err := runCtyLaw(broken, cx.Seed, 1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rerun.Failed != 1 || len(rerun.Counterexamples) != 1 {
+		t.Errorf("seed %d didn't reproduce its counterexample: %+v", cx.Seed, rerun)
+	}
+}
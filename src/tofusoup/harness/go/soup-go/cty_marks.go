@@ -0,0 +1,379 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/spf13/cobra"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function/stdlib"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// Operations a mark propagation case runs
+const (
+	// markOpConcat is the concat function on Args
+	markOpConcat = "concat"
+	// markOpMerge is the merge function on Args
+	markOpMerge = "merge"
+	// markOpIndex is HCL's index operator, Args[0][Args[1]]
+	markOpIndex = "index"
+)
+
+var markOps = []string{markOpConcat, markOpMerge, markOpIndex}
+
+// Marks the probe puts on inputs. Two are used so cases can show marks
+// being combined rather than just kept.
+const (
+	probeMarkSensitive = sensitiveMark
+	probeMarkEphemeral = "ephemeral"
+)
+
+// markProbeCase is one operation on marked values whose result marks other
+// runtimes tend to get wrong
+type markProbeCase struct {
+	Name        string
+	Description string
+	Op          string
+	Args        []cty.Value
+}
+
+func probeSensitive(val cty.Value) cty.Value { return val.Mark(probeMarkSensitive) }
+func probeEphemeral(val cty.Value) cty.Value { return val.Mark(probeMarkEphemeral) }
+
+func probeStrings(elems ...string) cty.Value {
+	vals := make([]cty.Value, len(elems))
+	for i, elem := range elems {
+		vals[i] = cty.StringVal(elem)
+	}
+	return cty.ListVal(vals)
+}
+
+// markProbeSuite is the documented set of cases run by cty marks propagate.
+// Names are stable so reports can be diffed across harnesses and releases.
+var markProbeSuite = []markProbeCase{
+	{
+		Name: "concat-marked-list", Op: markOpConcat,
+		Description: "a mark on a whole argument moves to the whole result",
+		Args:        []cty.Value{probeSensitive(probeStrings("a")), probeStrings("b")},
+	},
+	{
+		Name: "concat-marked-element", Op: markOpConcat,
+		Description: "a mark on an element stays on that element",
+		Args:        []cty.Value{cty.ListVal([]cty.Value{probeSensitive(cty.StringVal("a"))}), probeStrings("b")},
+	},
+	{
+		Name: "concat-marked-empty-list", Op: markOpConcat,
+		Description: "an empty marked argument still marks the result",
+		Args:        []cty.Value{probeSensitive(cty.ListValEmpty(cty.String)), probeStrings("b")},
+	},
+	{
+		Name: "concat-two-marks", Op: markOpConcat,
+		Description: "marks from different arguments are combined on the result",
+		Args:        []cty.Value{probeSensitive(probeStrings("a")), probeEphemeral(probeStrings("b"))},
+	},
+	{
+		Name: "concat-marked-tuple-element", Op: markOpConcat,
+		Description: "tuple arguments keep their element marks too",
+		Args: []cty.Value{
+			cty.TupleVal([]cty.Value{cty.StringVal("a"), probeEphemeral(cty.NumberIntVal(1))}),
+			cty.TupleVal([]cty.Value{cty.True}),
+		},
+	},
+	{
+		Name: "concat-marked-unknown", Op: markOpConcat,
+		Description: "an unknown marked argument gives an unknown result, and go-cty drops the mark",
+		Args:        []cty.Value{probeSensitive(cty.UnknownVal(cty.List(cty.String))), probeStrings("b")},
+	},
+	{
+		Name: "merge-marked-object", Op: markOpMerge,
+		Description: "a mark on a whole argument moves to the whole result",
+		Args: []cty.Value{
+			probeSensitive(cty.ObjectVal(map[string]cty.Value{"a": cty.StringVal("x")})),
+			cty.ObjectVal(map[string]cty.Value{"b": cty.StringVal("y")}),
+		},
+	},
+	{
+		Name: "merge-marked-attribute", Op: markOpMerge,
+		Description: "a mark on an attribute stays on that attribute",
+		Args: []cty.Value{
+			cty.ObjectVal(map[string]cty.Value{"a": probeSensitive(cty.StringVal("x"))}),
+			cty.ObjectVal(map[string]cty.Value{"b": cty.StringVal("y")}),
+		},
+	},
+	{
+		Name: "merge-overridden-marked-attribute", Op: markOpMerge,
+		Description: "a marked attribute replaced by a later argument loses its mark",
+		Args: []cty.Value{
+			cty.ObjectVal(map[string]cty.Value{"a": probeSensitive(cty.StringVal("x"))}),
+			cty.ObjectVal(map[string]cty.Value{"a": cty.StringVal("y")}),
+		},
+	},
+	{
+		Name: "merge-overridden-marked-object", Op: markOpMerge,
+		Description: "a marked argument whose attributes are all replaced still marks the result",
+		Args: []cty.Value{
+			probeSensitive(cty.ObjectVal(map[string]cty.Value{"a": cty.StringVal("x")})),
+			cty.ObjectVal(map[string]cty.Value{"a": cty.StringVal("y")}),
+		},
+	},
+	{
+		Name: "merge-marked-map-element", Op: markOpMerge,
+		Description: "map elements keep their marks when maps merge into a map",
+		Args: []cty.Value{
+			cty.MapVal(map[string]cty.Value{"a": probeEphemeral(cty.StringVal("x"))}),
+			cty.MapVal(map[string]cty.Value{"b": cty.StringVal("y")}),
+		},
+	},
+	{
+		Name: "merge-marked-null", Op: markOpMerge,
+		Description: "a marked null argument is skipped, and its mark with it",
+		Args: []cty.Value{
+			probeSensitive(cty.NullVal(cty.EmptyObject)),
+			cty.ObjectVal(map[string]cty.Value{"b": cty.StringVal("y")}),
+		},
+	},
+	{
+		Name: "index-marked-list", Op: markOpIndex,
+		Description: "an element of a marked collection is marked",
+		Args:        []cty.Value{probeSensitive(probeStrings("a", "b")), cty.NumberIntVal(1)},
+	},
+	{
+		Name: "index-marked-element", Op: markOpIndex,
+		Description: "indexing a marked element gives a marked result",
+		Args:        []cty.Value{cty.ListVal([]cty.Value{cty.StringVal("a"), probeSensitive(cty.StringVal("b"))}), cty.NumberIntVal(1)},
+	},
+	{
+		Name: "index-unmarked-sibling", Op: markOpIndex,
+		Description: "indexing beside a marked element gives an unmarked result",
+		Args:        []cty.Value{cty.ListVal([]cty.Value{cty.StringVal("a"), probeSensitive(cty.StringVal("b"))}), cty.NumberIntVal(0)},
+	},
+	{
+		Name: "index-marked-key", Op: markOpIndex,
+		Description: "a marked key marks the result",
+		Args:        []cty.Value{probeStrings("a", "b"), probeSensitive(cty.NumberIntVal(0))},
+	},
+	{
+		Name: "index-marked-key-and-collection", Op: markOpIndex,
+		Description: "marks on the key and the collection are combined",
+		Args:        []cty.Value{probeEphemeral(probeStrings("a", "b")), probeSensitive(cty.NumberIntVal(0))},
+	},
+	{
+		Name: "index-marked-map", Op: markOpIndex,
+		Description: "a marked map's element is marked",
+		Args:        []cty.Value{probeSensitive(cty.MapVal(map[string]cty.Value{"k": cty.StringVal("v")})), cty.StringVal("k")},
+	},
+	{
+		Name: "index-nested-marks", Op: markOpIndex,
+		Description: "an element keeps the marks inside it and gains the collection's",
+		Args: []cty.Value{
+			probeEphemeral(cty.TupleVal([]cty.Value{cty.ObjectVal(map[string]cty.Value{"p": probeSensitive(cty.StringVal("x"))})})),
+			cty.NumberIntVal(0),
+		},
+	},
+	{
+		Name: "index-marked-out-of-range", Op: markOpIndex,
+		Description: "an out-of-range index on a marked list is an error, not a marked result",
+		Args:        []cty.Value{probeSensitive(probeStrings("a")), cty.NumberIntVal(3)},
+	},
+	{
+		Name: "index-unknown-marked-list", Op: markOpIndex,
+		Description: "an element of an unknown marked list is unknown, and HCL drops the mark",
+		Args:        []cty.Value{probeSensitive(cty.UnknownVal(cty.List(cty.String))), cty.NumberIntVal(0)},
+	},
+}
+
+// markedPath is the marks found at one path in a value; the empty path is
+// the value itself
+type markedPath struct {
+	Path  string   `json:"path"`
+	Marks []string `json:"marks"`
+}
+
+// markedValue is a probe input or result: the value with its marks removed,
+// and where they were
+type markedValue struct {
+	Type  json.RawMessage `json:"type"`
+	Value json.RawMessage `json:"value"`
+	Marks []markedPath    `json:"marks"`
+}
+
+// markVerdict is what go-cty made of a case
+type markVerdict struct {
+	OK     bool         `json:"ok"`
+	Result *markedValue `json:"result,omitempty"`
+	Error  string       `json:"error,omitempty"`
+}
+
+// markCaseReport is one case of a marks propagate report
+type markCaseReport struct {
+	Name        string        `json:"name"`
+	Op          string        `json:"op"`
+	Description string        `json:"description"`
+	Args        []markedValue `json:"args"`
+	Verdict     markVerdict   `json:"verdict"`
+}
+
+// describeMarked renders val and the paths of its marks, sorted by path
+func describeMarked(val cty.Value) (markedValue, error) {
+	unmarked, pvm := val.UnmarkDeepWithPaths()
+	out := markedValue{Marks: []markedPath{}}
+	var err error
+	if out.Type, err = ctyjson.MarshalType(unmarked.Type()); err != nil {
+		return out, err
+	}
+	if out.Value, err = probeValueJSON(unmarked); err != nil {
+		return out, err
+	}
+	for _, pm := range pvm {
+		marks := make([]string, 0, len(pm.Marks))
+		for mark := range pm.Marks {
+			marks = append(marks, fmt.Sprint(mark))
+		}
+		sort.Strings(marks)
+		out.Marks = append(out.Marks, markedPath{Path: formatCtyPath(pm.Path), Marks: marks})
+	}
+	sort.Slice(out.Marks, func(i, j int) bool { return out.Marks[i].Path < out.Marks[j].Path })
+	return out, nil
+}
+
+// runMarkCase runs one case's operation. The error is the operation's, and
+// is part of the verdict.
+func runMarkCase(c markProbeCase) (cty.Value, error) {
+	switch c.Op {
+	case markOpConcat:
+		return stdlib.ConcatFunc.Call(c.Args)
+	case markOpMerge:
+		return stdlib.MergeFunc.Call(c.Args)
+	case markOpIndex:
+		val, diags := hcl.Index(c.Args[0], c.Args[1], nil)
+		if diags.HasErrors() {
+			// There is no source range, so leave it out of the message
+			return cty.NilVal, fmt.Errorf("%s: %s", diags[0].Summary, diags[0].Detail)
+		}
+		return val, nil
+	}
+	return cty.NilVal, fmt.Errorf("unknown op %q", c.Op)
+}
+
+// probeMarkCase runs one case. Errors are from rendering the report; a
+// failed operation is a verdict.
+func probeMarkCase(c markProbeCase) (markCaseReport, error) {
+	report := markCaseReport{Name: c.Name, Op: c.Op, Description: c.Description, Args: []markedValue{}}
+	for _, arg := range c.Args {
+		described, err := describeMarked(arg)
+		if err != nil {
+			return report, err
+		}
+		report.Args = append(report.Args, described)
+	}
+
+	out, opErr := runMarkCase(c)
+	if opErr != nil {
+		report.Verdict.Error = opErr.Error()
+		return report, nil
+	}
+	result, err := describeMarked(out)
+	if err != nil {
+		return report, err
+	}
+	report.Verdict.OK, report.Verdict.Result = true, &result
+	return report, nil
+}
+
+// selectMarkCases returns the suite cases named, or all of them, limited to
+// ops if any are given
+func selectMarkCases(names, ops []string) ([]markProbeCase, error) {
+	for _, op := range ops {
+		if !slices.Contains(markOps, op) {
+			return nil, fmt.Errorf("unknown op %q (expected one of: %s)", op, strings.Join(markOps, ", "))
+		}
+	}
+	byName := map[string]markProbeCase{}
+	known := make([]string, len(markProbeSuite))
+	for i, c := range markProbeSuite {
+		byName[c.Name] = c
+		known[i] = c.Name
+	}
+	selected := markProbeSuite
+	if len(names) > 0 {
+		selected = nil
+		for _, name := range names {
+			c, ok := byName[name]
+			if !ok {
+				return nil, fmt.Errorf("unknown case %q (expected one of: %s)", name, strings.Join(known, ", "))
+			}
+			selected = append(selected, c)
+		}
+	}
+	var cases []markProbeCase
+	for _, c := range selected {
+		if len(ops) == 0 || slices.Contains(ops, c.Op) {
+			cases = append(cases, c)
+		}
+	}
+	return cases, nil
+}
+
+func initCtyMarksCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "marks",
+		Short: "Probe how go-cty carries value marks",
+	}
+	cmd.AddCommand(initCtyMarksPropagateCmd())
+	return cmd
+}
+
+func initCtyMarksPropagateCmd() *cobra.Command {
+	var (
+		cases []string
+		ops   []string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "propagate",
+		Short: "Report where marks end up after concat, merge and indexing in Go",
+		Long: `Run a documented suite of operations on marked values through go-cty
+and HCL, and report where the marks end up in each result, or the error.
+Inputs are marked "sensitive" or "ephemeral". Ops:
+
+  concat  the concat function: whole-argument and element marks
+  merge   the merge function: object, attribute and overridden marks
+  index   HCL's index operator: marked collections, elements and keys
+
+Each input and result is written with its marks removed, plus a list of
+the paths that carried marks; the path "" is the value itself. Diff this
+report against another runtime's for the same case names.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			selected, err := selectMarkCases(cases, ops)
+			if err != nil {
+				return err
+			}
+			reports := []markCaseReport{}
+			for _, c := range selected {
+				report, err := probeMarkCase(c)
+				if err != nil {
+					return fmt.Errorf("case %s: %w", c.Name, err)
+				}
+				reports = append(reports, report)
+			}
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(map[string]interface{}{
+				"probe":   "cty-marks",
+				"harness": "soup-go",
+				"cases":   reports,
+			})
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&cases, "case", nil, "Only run these cases (default all)")
+	cmd.Flags().StringSliceVar(&ops, "op", nil, "Only run cases for these ops: concat, merge or index (default all)")
+	return cmd
+}
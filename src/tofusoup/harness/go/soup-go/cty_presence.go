@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+	ctymsgpack "github.com/zclconf/go-cty/cty/msgpack"
+)
+
+// Attribute presence states
+const (
+	presenceAbsent     = "absent"
+	presenceNull       = "null"
+	presenceSet        = "set"
+	presenceUnknown    = "unknown"
+	presenceParentNull = "parent_null"
+	presenceError      = "error"
+)
+
+// presenceState is an attribute's state after one normalization pipeline
+type presenceState struct {
+	State string          `json:"state"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// attributePresence reports one attribute through every pipeline
+type attributePresence struct {
+	Path       string                   `json:"path"`
+	Type       json.RawMessage          `json:"type"`
+	Optional   bool                     `json:"optional"`
+	Input      string                   `json:"input"`
+	InputValue json.RawMessage          `json:"input_value,omitempty"`
+	Normalized map[string]presenceState `json:"normalized"`
+	Collapsed  bool                     `json:"absent_collapsed_to_null"`
+}
+
+// presencePipelines are the ways a JSON object is turned into a cty value.
+// Each returns the resulting value, whose attributes are then inspected.
+var presencePipelines = []struct {
+	name string
+	run  func(data []byte, ty cty.Type) (cty.Value, error)
+}{
+	// ctyjson fills every missing attribute with null
+	{"ctyjson", func(data []byte, ty cty.Type) (cty.Value, error) {
+		return ctyjson.Unmarshal(data, ty)
+	}},
+	// Decode with the implied type, then convert: required attributes that
+	// are absent are an error, optional ones become null
+	{"convert", func(data []byte, ty cty.Type) (cty.Value, error) {
+		implied, err := ctyjson.ImpliedType(data)
+		if err != nil {
+			return cty.NilVal, err
+		}
+		val, err := ctyjson.Unmarshal(data, implied)
+		if err != nil {
+			return cty.NilVal, err
+		}
+		return convert.Convert(val, ty)
+	}},
+	// What survives the wire: ctyjson, then a msgpack round trip
+	{"msgpack", func(data []byte, ty cty.Type) (cty.Value, error) {
+		val, err := ctyjson.Unmarshal(data, ty)
+		if err != nil {
+			return cty.NilVal, err
+		}
+		packed, err := ctymsgpack.Marshal(val, ty)
+		if err != nil {
+			return cty.NilVal, err
+		}
+		return ctymsgpack.Unmarshal(packed, ty)
+	}},
+	// The harness's own JSON builder, which leaves absent attributes out
+	{"builder", func(data []byte, ty cty.Type) (cty.Value, error) {
+		return buildCtyValueFromJSON(ty, data)
+	}},
+}
+
+// inputPresence walks the attributes of ty alongside the raw JSON input and
+// records whether each one was absent, null or set. Nested object
+// attributes are reported with dotted paths.
+func inputPresence(ty cty.Type, raw map[string]json.RawMessage, prefix []string, out *[]attributePresence) error {
+	names := make([]string, 0, len(ty.AttributeTypes()))
+	for name := range ty.AttributeTypes() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		aty := ty.AttributeType(name)
+		path := append(append([]string(nil), prefix...), name)
+		typeJSON, err := ctyjson.MarshalType(aty)
+		if err != nil {
+			return err
+		}
+
+		entry := attributePresence{
+			Path:       strings.Join(path, "."),
+			Type:       typeJSON,
+			Optional:   ty.AttributeOptional(name),
+			Normalized: map[string]presenceState{},
+		}
+		value, present := raw[name]
+		switch {
+		case !present:
+			entry.Input = presenceAbsent
+		case bytes.Equal(bytes.TrimSpace(value), []byte("null")):
+			entry.Input = presenceNull
+		default:
+			entry.Input = presenceSet
+			entry.InputValue = value
+		}
+		*out = append(*out, entry)
+
+		if aty.IsObjectType() {
+			var nested map[string]json.RawMessage
+			if entry.Input == presenceSet {
+				if err := json.Unmarshal(value, &nested); err != nil {
+					return fmt.Errorf("%s: expected an object: %w", entry.Path, err)
+				}
+			}
+			if err := inputPresence(aty, nested, path, out); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// valuePresence reports the state of the attribute at path in val
+func valuePresence(val cty.Value, path []string) presenceState {
+	for i, name := range path {
+		if !val.IsKnown() {
+			return presenceState{State: presenceUnknown}
+		}
+		if val.IsNull() {
+			if i == 0 {
+				return presenceState{State: presenceNull}
+			}
+			return presenceState{State: presenceParentNull}
+		}
+		if !val.Type().IsObjectType() || !val.Type().HasAttribute(name) {
+			return presenceState{State: presenceAbsent}
+		}
+		val = val.GetAttr(name)
+	}
+
+	switch {
+	case !val.IsKnown():
+		return presenceState{State: presenceUnknown}
+	case val.IsNull():
+		return presenceState{State: presenceNull}
+	}
+	data, err := ctyjson.Marshal(val, val.Type())
+	if err != nil {
+		return presenceState{State: presenceSet}
+	}
+	return presenceState{State: presenceSet, Value: data}
+}
+
+func initCtyPresenceCmd() *cobra.Command {
+	var typeJSON string
+
+	cmd := &cobra.Command{
+		Use:   "presence [value]",
+		Short: "Report absent vs null vs set for each attribute of an object value",
+		Long: `Given an object type and a JSON object, report for every attribute (nested
+objects included) whether the input left it absent, set it to null or set a
+value, and what state it ends up in after each normalization cty offers:
+
+  ctyjson   ctyjson.Unmarshal against the type
+  convert   decode with the implied type, then convert to the type
+  msgpack   ctyjson, then a msgpack round trip
+  builder   the harness's own JSON value builder
+
+absent_collapsed_to_null marks attributes where the absent/null distinction
+is lost, which is what plan diffs are sensitive to.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ty, err := parseCtyType(json.RawMessage(typeJSON))
+			if err != nil {
+				return fmt.Errorf("failed to parse type: %w", err)
+			}
+			if !ty.IsObjectType() {
+				return fmt.Errorf("--type must be an object type, got %s", ty.FriendlyName())
+			}
+
+			data := []byte(args[0])
+			var raw map[string]json.RawMessage
+			if err := json.Unmarshal(data, &raw); err != nil {
+				return fmt.Errorf("value must be a JSON object: %w", err)
+			}
+
+			var attrs []attributePresence
+			if err := inputPresence(ty, raw, nil, &attrs); err != nil {
+				return err
+			}
+
+			pipelineErrors := map[string]string{}
+			for _, pipeline := range presencePipelines {
+				val, err := pipeline.run(data, ty)
+				for i := range attrs {
+					if err != nil {
+						attrs[i].Normalized[pipeline.name] = presenceState{State: presenceError}
+						continue
+					}
+					attrs[i].Normalized[pipeline.name] = valuePresence(val, strings.Split(attrs[i].Path, "."))
+				}
+				if err != nil {
+					pipelineErrors[pipeline.name] = err.Error()
+				}
+			}
+
+			for i := range attrs {
+				if attrs[i].Input != presenceAbsent {
+					continue
+				}
+				for _, state := range attrs[i].Normalized {
+					if state.State == presenceNull {
+						attrs[i].Collapsed = true
+					}
+				}
+			}
+
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(map[string]interface{}{
+				"type":       json.RawMessage(typeJSON),
+				"attributes": attrs,
+				"errors":     pipelineErrors,
+			})
+		},
+	}
+
+	cmd.Flags().StringVar(&typeJSON, "type", "", "Object type specification as JSON (optional attributes as a third element)")
+	cmd.MarkFlagRequired("type")
+	return cmd
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// presenceStates is an attribute's input state and its state after each
+// pipeline, in presencePipelines order
+type presenceStates struct {
+	input     string
+	collapsed bool
+	states    [4]string
+}
+
+func runCtyPresence(t *testing.T, typeJSON, value string) (map[string]presenceStates, map[string]string) {
+	t.Helper()
+	cmd := initCtyPresenceCmd()
+	cmd.SetArgs([]string{"--type", typeJSON, value})
+	out, err := captureStdout(t, cmd.Execute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var output struct {
+		Attributes []attributePresence `json:"attributes"`
+		Errors     map[string]string   `json:"errors"`
+	}
+	if err := json.Unmarshal(out, &output); err != nil {
+		t.Fatalf("%v: %s", err, out)
+	}
+
+	attrs := map[string]presenceStates{}
+	for _, attr := range output.Attributes {
+		got := presenceStates{input: attr.Input, collapsed: attr.Collapsed}
+		for i, pipeline := range presencePipelines {
+			got.states[i] = attr.Normalized[pipeline.name].State
+		}
+		attrs[attr.Path] = got
+	}
+	return attrs, output.Errors
+}
+
+func TestCtyPresenceOptionalAttributes(t *testing.T) {
+	attrs, errors := runCtyPresence(t,
+		`["object",{"name":"string","size":"number","tags":["map","string"],"net":["object",{"cidr":"string"}]},["size","tags","net"]]`,
+		`{"name":"a","size":null}`)
+	if len(errors) != 0 {
+		t.Errorf("got pipeline errors %v", errors)
+	}
+
+	want := map[string]presenceStates{
+		"name":     {presenceSet, false, [4]string{presenceSet, presenceSet, presenceSet, presenceSet}},
+		"size":     {presenceNull, false, [4]string{presenceNull, presenceNull, presenceNull, presenceNull}},
+		"tags":     {presenceAbsent, true, [4]string{presenceNull, presenceNull, presenceNull, presenceAbsent}},
+		"net":      {presenceAbsent, true, [4]string{presenceNull, presenceNull, presenceNull, presenceAbsent}},
+		"net.cidr": {presenceAbsent, false, [4]string{presenceParentNull, presenceParentNull, presenceParentNull, presenceAbsent}},
+	}
+	if len(attrs) != len(want) {
+		t.Errorf("got %d attributes, want %d", len(attrs), len(want))
+	}
+	for path, w := range want {
+		if attrs[path] != w {
+			t.Errorf("%s: got %+v, want %+v", path, attrs[path], w)
+		}
+	}
+}
+
+func TestCtyPresenceRequiredAttributeAbsent(t *testing.T) {
+	attrs, errors := runCtyPresence(t, `["object",{"name":"string","size":"number"}]`, `{"size":1}`)
+	if errors["convert"] != `attribute "name" is required` || len(errors) != 1 {
+		t.Errorf("got pipeline errors %v, want only convert to fail", errors)
+	}
+	want := presenceStates{presenceAbsent, true, [4]string{presenceNull, presenceError, presenceNull, presenceAbsent}}
+	if attrs["name"] != want {
+		t.Errorf("name: got %+v, want %+v", attrs["name"], want)
+	}
+	if attrs["size"].states[1] != presenceError {
+		t.Errorf("size: got %+v, want every attribute marked when a pipeline fails", attrs["size"])
+	}
+}
+
+func TestCtyPresenceRejectsNonObjects(t *testing.T) {
+	for _, args := range [][]string{
+		{"--type", `"string"`, `{}`},
+		{"--type", `["object",{"a":"string"}]`, `[1]`},
+	} {
+		cmd := initCtyPresenceCmd()
+		cmd.SetArgs(args)
+		cmd.SilenceUsage, cmd.SilenceErrors = true, true
+		if err := cmd.Execute(); err == nil {
+			t.Errorf("%v: got no error", args)
+		}
+	}
+}
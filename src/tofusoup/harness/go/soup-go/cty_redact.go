@@ -0,0 +1,318 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/spf13/cobra"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+	"github.com/zclconf/go-cty/cty/msgpack"
+)
+
+// sensitiveMark is the mark --sensitive and --sensitive-paths put on values
+const sensitiveMark = "sensitive"
+
+// redactOptions configures sensitive marking and redaction for cty convert
+type redactOptions struct {
+	Enabled   bool
+	Paths     []string
+	PathsFile string
+	MapPath   string
+}
+
+var ctyRedact redactOptions
+
+// addRedactFlags registers the marking and --redact-marked flags on cmd
+func addRedactFlags(cmd *cobra.Command, o *redactOptions) {
+	cmd.Flags().BoolVar(&o.Enabled, "redact-marked", false, "Replace sensitive-marked leaves with a placeholder and digest, and write a redaction map (JSON output only)")
+	cmd.Flags().StringArrayVar(&o.Paths, "sensitive", nil, "Mark the value at this path sensitive, in traversal syntax (e.g. db.password, users[0].token); repeatable")
+	cmd.Flags().StringVar(&o.PathsFile, "sensitive-paths", "", "Mark the paths in this file sensitive, in Terraform state sensitive_attributes format")
+	cmd.Flags().StringVar(&o.MapPath, "redaction-map", "", "Write the redaction map here (default <output>.redactions.json)")
+}
+
+// marksValues reports whether any marking flag is set
+func (o *redactOptions) marksValues() bool {
+	return len(o.Paths) > 0 || o.PathsFile != ""
+}
+
+// parseSensitivePath parses a path in HCL traversal syntax
+func parseSensitivePath(expr string) (cty.Path, error) {
+	traversal, diags := hclsyntax.ParseTraversalAbs([]byte(expr), "--sensitive", hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("invalid --sensitive path %q: %s", expr, diags.Error())
+	}
+	path := make(cty.Path, 0, len(traversal))
+	for _, step := range traversal {
+		switch s := step.(type) {
+		case hcl.TraverseRoot:
+			path = path.GetAttr(s.Name)
+		case hcl.TraverseAttr:
+			path = path.GetAttr(s.Name)
+		case hcl.TraverseIndex:
+			path = path.Index(s.Key)
+		default:
+			return nil, fmt.Errorf("invalid --sensitive path %q: unsupported step %T", expr, step)
+		}
+	}
+	return path, nil
+}
+
+// stateSensitiveStep is one step of a path in a Terraform state file's
+// sensitive_attributes, e.g. {"type": "get_attr", "value": "password"}
+type stateSensitiveStep struct {
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+// loadSensitivePaths reads paths in Terraform state sensitive_attributes
+// format: a list of paths, each a list of get_attr and index steps, where
+// an index value is a typed cty JSON value ({"value": 0, "type": "number"})
+func loadSensitivePaths(file string) ([]cty.Path, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sensitive paths: %w", err)
+	}
+	var raw [][]stateSensitiveStep
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse sensitive paths: %w", err)
+	}
+	paths := make([]cty.Path, 0, len(raw))
+	for i, steps := range raw {
+		var path cty.Path
+		for _, step := range steps {
+			switch step.Type {
+			case "get_attr":
+				var name string
+				if err := json.Unmarshal(step.Value, &name); err != nil {
+					return nil, fmt.Errorf("sensitive path %d: get_attr value must be a string: %w", i, err)
+				}
+				path = path.GetAttr(name)
+			case "index":
+				var typed struct {
+					Value json.RawMessage `json:"value"`
+					Type  json.RawMessage `json:"type"`
+				}
+				if err := json.Unmarshal(step.Value, &typed); err != nil {
+					return nil, fmt.Errorf("sensitive path %d: invalid index value: %w", i, err)
+				}
+				ty, err := ctyjson.UnmarshalType(typed.Type)
+				if err != nil {
+					return nil, fmt.Errorf("sensitive path %d: invalid index type: %w", i, err)
+				}
+				key, err := ctyjson.Unmarshal(typed.Value, ty)
+				if err != nil {
+					return nil, fmt.Errorf("sensitive path %d: invalid index value: %w", i, err)
+				}
+				path = path.Index(key)
+			default:
+				return nil, fmt.Errorf("sensitive path %d: unknown step type %q", i, step.Type)
+			}
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// markSensitive marks the values at the --sensitive and --sensitive-paths
+// paths. A path that doesn't resolve in val is an error, so a typo can't
+// leave a secret unredacted.
+func (o *redactOptions) markSensitive(val cty.Value) (cty.Value, error) {
+	var paths []cty.Path
+	for _, expr := range o.Paths {
+		path, err := parseSensitivePath(expr)
+		if err != nil {
+			return cty.NilVal, err
+		}
+		paths = append(paths, path)
+	}
+	if o.PathsFile != "" {
+		filePaths, err := loadSensitivePaths(o.PathsFile)
+		if err != nil {
+			return cty.NilVal, err
+		}
+		paths = append(paths, filePaths...)
+	}
+
+	pvm := make([]cty.PathValueMarks, 0, len(paths))
+	for _, path := range paths {
+		if _, err := path.Apply(val); err != nil {
+			return cty.NilVal, fmt.Errorf("sensitive path %s does not exist in the value: %w", formatCtyPath(path), err)
+		}
+		pvm = append(pvm, cty.PathValueMarks{Path: path, Marks: cty.NewValueMarks(sensitiveMark)})
+	}
+	return val.MarkWithPaths(pvm), nil
+}
+
+// redaction records one redacted leaf in the redaction map
+type redaction struct {
+	Path          string                   `json:"path"`
+	AttributePath []map[string]interface{} `json:"attribute_path"`
+	Type          string                   `json:"type"`
+	Placeholder   string                   `json:"placeholder"`
+	// Digest is the --digest-alg name, a colon and the hex digest of the
+	// leaf's msgpack encoding, so goldens can compare values without
+	// holding them
+	Digest string `json:"digest"`
+}
+
+// redactionMap is written next to the redacted output
+type redactionMap struct {
+	DigestAlgorithm string      `json:"digest_algorithm"`
+	Count           int         `json:"count"`
+	Redactions      []redaction `json:"redactions"`
+}
+
+// redactPlaceholder is what replaces a marked leaf: Terraform's UI text plus
+// enough of the digest to tell differing values apart
+func redactPlaceholder(digest string) string {
+	return fmt.Sprintf("(sensitive value %s)", digest[:strings.Index(digest, ":")+1+16])
+}
+
+// redactLeafDigest digests the msgpack encoding of an unmarked leaf
+func redactLeafDigest(val cty.Value) (string, error) {
+	encoded, err := msgpack.Marshal(val, val.Type())
+	if err != nil {
+		return "", err
+	}
+	return currentDigest.Name() + ":" + fingerprint(encoded), nil
+}
+
+// redactValue returns val as a JSON-encodable tree in which every leaf under
+// a sensitive mark is replaced by its placeholder, appending each to
+// redactions in traversal order (attributes and map keys sorted). Leaves
+// are primitives, nulls and empty collections; a mark on a collection
+// redacts every leaf inside it.
+func redactValue(val cty.Value, path cty.Path, marked bool, redactions *[]redaction) (interface{}, error) {
+	val, marks := val.Unmark()
+	_, sensitive := marks[sensitiveMark]
+	marked = marked || sensitive
+	if !marked && !val.ContainsMarked() {
+		encoded, err := ctyjson.Marshal(val, val.Type())
+		if err != nil {
+			return nil, path.NewError(err)
+		}
+		return json.RawMessage(encoded), nil
+	}
+
+	ty := val.Type()
+	collection := ty.IsListType() || ty.IsSetType() || ty.IsTupleType() || ty.IsMapType() || ty.IsObjectType()
+	if !collection || val.IsNull() || !val.IsKnown() || val.LengthInt() == 0 {
+		if !marked {
+			encoded, err := ctyjson.Marshal(val, val.Type())
+			if err != nil {
+				return nil, path.NewError(err)
+			}
+			return json.RawMessage(encoded), nil
+		}
+		digest, err := redactLeafDigest(val)
+		if err != nil {
+			return nil, path.NewError(err)
+		}
+		leaf := redaction{
+			Path:          formatCtyPath(path),
+			AttributePath: ctyPathToSteps(path),
+			Type:          ty.FriendlyName(),
+			Placeholder:   redactPlaceholder(digest),
+			Digest:        digest,
+		}
+		*redactions = append(*redactions, leaf)
+		return leaf.Placeholder, nil
+	}
+
+	if ty.IsMapType() || ty.IsObjectType() {
+		out := make(map[string]interface{}, val.LengthInt())
+		for it := val.ElementIterator(); it.Next(); {
+			key, elem := it.Element()
+			step := cty.Path{cty.IndexStep{Key: key}}
+			if ty.IsObjectType() {
+				step = cty.Path{cty.GetAttrStep{Name: key.AsString()}}
+			}
+			v, err := redactValue(elem, append(path.Copy(), step...), marked, redactions)
+			if err != nil {
+				return nil, err
+			}
+			out[key.AsString()] = v
+		}
+		return out, nil
+	}
+
+	out := make([]interface{}, 0, val.LengthInt())
+	for it := val.ElementIterator(); it.Next(); {
+		key, elem := it.Element()
+		if ty.IsSetType() {
+			// Set elements are addressed by their value
+			key, _ = elem.UnmarkDeep()
+		}
+		v, err := redactValue(elem, path.Index(key), marked, redactions)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+// writeRedacted writes val as JSON with marked leaves redacted, and the
+// redaction map to its own file
+func (o *redactOptions) writeRedacted(val cty.Value, outputPath string) error {
+	redactions := []redaction{}
+	tree, err := redactValue(val, nil, false, &redactions)
+	if err != nil {
+		return fmt.Errorf("failed to redact value: %w", err)
+	}
+	output, err := json.Marshal(tree)
+	if err != nil {
+		return fmt.Errorf("failed to marshal to JSON: %w", err)
+	}
+
+	mapPath := o.MapPath
+	if mapPath == "" {
+		mapPath = outputPath + ".redactions.json"
+	}
+	mapData, err := json.MarshalIndent(redactionMap{
+		DigestAlgorithm: currentDigest.Name() + "(msgpack)",
+		Count:           len(redactions),
+		Redactions:      redactions,
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode redaction map: %w", err)
+	}
+	if err := os.WriteFile(mapPath, append(mapData, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write redaction map: %w", err)
+	}
+	logger.Debug("🙈 redacted sensitive values", "count", len(redactions), "map", mapPath)
+
+	if outputPath == "-" {
+		_, err = os.Stdout.Write(output)
+	} else {
+		err = os.WriteFile(outputPath, output, 0644)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	return nil
+}
+
+// check rejects flag combinations redaction can't honor
+func (o *redactOptions) check(outputPath, outputFormat string, streaming bool) error {
+	if !o.Enabled {
+		if o.marksValues() {
+			return fmt.Errorf("--sensitive and --sensitive-paths need --redact-marked: marked values can't be serialized")
+		}
+		return nil
+	}
+	switch {
+	case streaming:
+		return fmt.Errorf("--redact-marked can't be combined with --stream-parse")
+	case outputFormat != "json":
+		return fmt.Errorf("--redact-marked writes JSON; use --output-format json")
+	case outputPath == "-" && o.MapPath == "":
+		return fmt.Errorf("--redaction-map is required when writing to stdout")
+	}
+	return nil
+}
@@ -0,0 +1,340 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// schemaDiagnostic is a validation diagnostic with a tfplugin-style attribute path
+type schemaDiagnostic struct {
+	Severity      string                   `json:"severity"`
+	Summary       string                   `json:"summary"`
+	Detail        string                   `json:"detail"`
+	Path          string                   `json:"path"`
+	AttributePath []map[string]interface{} `json:"attribute_path"`
+}
+
+func newSchemaDiagnostic(path cty.Path, summary, detail string) schemaDiagnostic {
+	return schemaDiagnostic{
+		Severity:      "error",
+		Summary:       summary,
+		Detail:        detail,
+		Path:          formatCtyPath(path),
+		AttributePath: ctyPathToSteps(path),
+	}
+}
+
+// formatCtyPath renders a cty.Path in HCL traversal syntax, e.g. ebs[0].size
+func formatCtyPath(path cty.Path) string {
+	var b strings.Builder
+	for _, step := range path {
+		switch s := step.(type) {
+		case cty.GetAttrStep:
+			if b.Len() > 0 {
+				b.WriteString(".")
+			}
+			b.WriteString(s.Name)
+		case cty.IndexStep:
+			switch s.Key.Type() {
+			case cty.Number:
+				fmt.Fprintf(&b, "[%s]", s.Key.AsBigFloat().Text('f', -1))
+			case cty.String:
+				fmt.Fprintf(&b, "[%q]", s.Key.AsString())
+			default:
+				b.WriteString("[?]")
+			}
+		}
+	}
+	return b.String()
+}
+
+// ctyPathToSteps converts a cty.Path to tfplugin AttributePath steps
+func ctyPathToSteps(path cty.Path) []map[string]interface{} {
+	steps := make([]map[string]interface{}, 0, len(path))
+	for _, step := range path {
+		switch s := step.(type) {
+		case cty.GetAttrStep:
+			steps = append(steps, map[string]interface{}{"attribute_name": s.Name})
+		case cty.IndexStep:
+			switch s.Key.Type() {
+			case cty.Number:
+				i, _ := s.Key.AsBigFloat().Int64()
+				steps = append(steps, map[string]interface{}{"element_key_int": i})
+			case cty.String:
+				steps = append(steps, map[string]interface{}{"element_key_string": s.Key.AsString()})
+			}
+		}
+	}
+	return steps
+}
+
+// loadBlockSchema loads the block schema to validate against. The file may be a
+// full provider schema document (in which case typeName selects the resource),
+// a single resource schema ({"version": ..., "block": ...}) or a bare block.
+func loadBlockSchema(path, provider, mode, typeName string) (*schemaBlockJSON, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema file: %w", err)
+	}
+
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, fmt.Errorf("failed to parse schema file: %w", err)
+	}
+
+	if _, ok := probe["provider_schemas"]; ok {
+		if typeName == "" {
+			return nil, fmt.Errorf("--resource-type is required with a provider schema document")
+		}
+		schemas, err := loadProviderSchemas(path)
+		if err != nil {
+			return nil, err
+		}
+		schema, err := schemas.lookupResourceSchema(provider, mode, typeName)
+		if err != nil {
+			return nil, err
+		}
+		return schema.Block, nil
+	}
+
+	if blockData, ok := probe["block"]; ok {
+		data = blockData
+	}
+	var block schemaBlockJSON
+	if err := json.Unmarshal(data, &block); err != nil {
+		return nil, fmt.Errorf("failed to parse block schema: %w", err)
+	}
+	return &block, nil
+}
+
+// decodeSchemaValue decodes a JSON value for validateAgainstBlock. Numbers
+// are kept as json.Number, so they reach go-cty with every digit.
+func decodeSchemaValue(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var raw interface{}
+	if err := dec.Decode(&raw); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("unexpected data after the value")
+	}
+	return raw, nil
+}
+
+// validateAgainstBlock validates a decoded JSON value against a block schema,
+// mirroring the checks Terraform performs on provider configuration.
+func validateAgainstBlock(block *schemaBlockJSON, raw interface{}, path cty.Path) []schemaDiagnostic {
+	var diags []schemaDiagnostic
+
+	obj, ok := raw.(map[string]interface{})
+	if !ok {
+		return append(diags, newSchemaDiagnostic(path, "Incorrect block value type", "A block must be given as an object."))
+	}
+
+	// Arguments that are not part of the schema
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		_, isAttr := block.Attributes[key]
+		_, isBlock := block.BlockTypes[key]
+		if !isAttr && !isBlock {
+			diags = append(diags, newSchemaDiagnostic(path.GetAttr(key), "Unsupported argument",
+				fmt.Sprintf("An argument named %q is not expected here.", key)))
+		}
+	}
+
+	attrNames := make([]string, 0, len(block.Attributes))
+	for name := range block.Attributes {
+		attrNames = append(attrNames, name)
+	}
+	sort.Strings(attrNames)
+	for _, name := range attrNames {
+		diags = append(diags, validateSchemaAttribute(name, block.Attributes[name], obj[name], path.GetAttr(name))...)
+	}
+
+	blockNames := make([]string, 0, len(block.BlockTypes))
+	for name := range block.BlockTypes {
+		blockNames = append(blockNames, name)
+	}
+	sort.Strings(blockNames)
+	for _, name := range blockNames {
+		diags = append(diags, validateSchemaBlockType(name, block.BlockTypes[name], obj[name], path.GetAttr(name))...)
+	}
+
+	return diags
+}
+
+func validateSchemaAttribute(name string, attr *schemaAttributeJSON, raw interface{}, path cty.Path) []schemaDiagnostic {
+	if raw == nil {
+		if attr.Required {
+			return []schemaDiagnostic{newSchemaDiagnostic(path, "Missing required argument",
+				fmt.Sprintf("The argument %q is required, but no definition was found.", name))}
+		}
+		return nil
+	}
+
+	if attr.Computed && !attr.Optional {
+		return []schemaDiagnostic{newSchemaDiagnostic(path, "Value for unconfigurable attribute",
+			fmt.Sprintf("Can't configure a value for %q: its value will be decided automatically based on the result of applying this configuration.", name))}
+	}
+
+	if attr.NestedType != nil {
+		return validateNestedAttribute(name, attr.NestedType, raw, path)
+	}
+
+	ty, err := attr.impliedType()
+	if err != nil {
+		return []schemaDiagnostic{newSchemaDiagnostic(path, "Invalid schema", err.Error())}
+	}
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return []schemaDiagnostic{newSchemaDiagnostic(path, "Invalid value", err.Error())}
+	}
+	if _, err := ctyjson.Unmarshal(data, ty); err != nil {
+		// Point at the nested element when go-cty tells us where the problem is
+		errPath := path
+		var pathErr cty.PathError
+		if errors.As(err, &pathErr) {
+			errPath = append(path.Copy(), pathErr.Path...)
+		}
+		return []schemaDiagnostic{newSchemaDiagnostic(errPath, "Incorrect attribute value type",
+			fmt.Sprintf("Inappropriate value for attribute %q: %s.", name, err))}
+	}
+
+	return nil
+}
+
+// validateNestedAttribute checks a nested attribute's objects against its
+// attributes, as blocks are checked against theirs. raw is not null.
+func validateNestedAttribute(name string, nested *schemaNestedTypeJSON, raw interface{}, path cty.Path) []schemaDiagnostic {
+	nestedBlock := &schemaBlockJSON{Attributes: nested.Attributes}
+	object := func(item interface{}, path cty.Path) []schemaDiagnostic {
+		if _, ok := item.(map[string]interface{}); !ok {
+			return []schemaDiagnostic{newSchemaDiagnostic(path, "Incorrect attribute value type",
+				fmt.Sprintf("Inappropriate value for attribute %q: object required.", name))}
+		}
+		return validateAgainstBlock(nestedBlock, item, path)
+	}
+
+	switch nested.NestingMode {
+	case "single":
+		return object(raw, path)
+
+	case "list", "set":
+		items, ok := raw.([]interface{})
+		if !ok {
+			return []schemaDiagnostic{newSchemaDiagnostic(path, "Incorrect attribute value type",
+				fmt.Sprintf("Inappropriate value for attribute %q: %s of object required.", name, nested.NestingMode))}
+		}
+		var diags []schemaDiagnostic
+		if len(items) < nested.MinItems {
+			diags = append(diags, newSchemaDiagnostic(path, "Insufficient items",
+				fmt.Sprintf("Attribute %q requires at least %d items.", name, nested.MinItems)))
+		}
+		if nested.MaxItems > 0 && len(items) > nested.MaxItems {
+			diags = append(diags, newSchemaDiagnostic(path, "Too many items",
+				fmt.Sprintf("Attribute %q allows no more than %d items.", name, nested.MaxItems)))
+		}
+		for i, item := range items {
+			diags = append(diags, object(item, path.IndexInt(i))...)
+		}
+		return diags
+
+	case "map":
+		items, ok := raw.(map[string]interface{})
+		if !ok {
+			return []schemaDiagnostic{newSchemaDiagnostic(path, "Incorrect attribute value type",
+				fmt.Sprintf("Inappropriate value for attribute %q: map of object required.", name))}
+		}
+		keys := make([]string, 0, len(items))
+		for key := range items {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		var diags []schemaDiagnostic
+		for _, key := range keys {
+			diags = append(diags, object(items[key], path.IndexString(key))...)
+		}
+		return diags
+
+	default:
+		return []schemaDiagnostic{newSchemaDiagnostic(path, "Invalid schema",
+			fmt.Sprintf("Unsupported nesting mode %q for attribute %q.", nested.NestingMode, name))}
+	}
+}
+
+func validateSchemaBlockType(name string, blockType *schemaBlockTypeJSON, raw interface{}, path cty.Path) []schemaDiagnostic {
+	var diags []schemaDiagnostic
+
+	switch blockType.NestingMode {
+	case "single", "group":
+		if raw == nil {
+			if blockType.MinItems > 0 {
+				diags = append(diags, newSchemaDiagnostic(path, fmt.Sprintf("Insufficient %s blocks", name),
+					fmt.Sprintf("At least 1 %q block is required.", name)))
+			}
+			return diags
+		}
+		return validateAgainstBlock(blockType.Block, raw, path)
+
+	case "list", "set":
+		var items []interface{}
+		if raw != nil {
+			var ok bool
+			items, ok = raw.([]interface{})
+			if !ok {
+				return append(diags, newSchemaDiagnostic(path, "Incorrect block value type",
+					fmt.Sprintf("Blocks of type %q must be given as a list.", name)))
+			}
+		}
+		if len(items) < blockType.MinItems {
+			diags = append(diags, newSchemaDiagnostic(path, fmt.Sprintf("Insufficient %s blocks", name),
+				fmt.Sprintf("At least %d %q blocks are required.", blockType.MinItems, name)))
+		}
+		if blockType.MaxItems > 0 && len(items) > blockType.MaxItems {
+			diags = append(diags, newSchemaDiagnostic(path, fmt.Sprintf("Too many %s blocks", name),
+				fmt.Sprintf("No more than %d %q blocks are allowed.", blockType.MaxItems, name)))
+		}
+		for i, item := range items {
+			diags = append(diags, validateAgainstBlock(blockType.Block, item, path.IndexInt(i))...)
+		}
+		return diags
+
+	case "map":
+		if raw == nil {
+			return diags
+		}
+		items, ok := raw.(map[string]interface{})
+		if !ok {
+			return append(diags, newSchemaDiagnostic(path, "Incorrect block value type",
+				fmt.Sprintf("Blocks of type %q must be given as an object keyed by label.", name)))
+		}
+		labels := make([]string, 0, len(items))
+		for label := range items {
+			labels = append(labels, label)
+		}
+		sort.Strings(labels)
+		for _, label := range labels {
+			diags = append(diags, validateAgainstBlock(blockType.Block, items[label], path.IndexString(label))...)
+		}
+		return diags
+
+	default:
+		return append(diags, newSchemaDiagnostic(path, "Invalid schema",
+			fmt.Sprintf("Unsupported nesting mode %q for block %q.", blockType.NestingMode, name)))
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestDecodeSchemaValueKeepsDigits(t *testing.T) {
+	raw, err := decodeSchemaValue([]byte(`{"n": 9007199254740993, "big": 1e400}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj := raw.(map[string]interface{})
+	if got := obj["n"]; got != json.Number("9007199254740993") {
+		t.Errorf("got %#v, want the number's own digits", got)
+	}
+
+	if _, err := decodeSchemaValue([]byte(`{} {}`)); err == nil {
+		t.Error("trailing data was accepted")
+	}
+}
+
+func TestValidateSchemaNumbers(t *testing.T) {
+	block := &schemaBlockJSON{Attributes: map[string]*schemaAttributeJSON{
+		"n":  {Type: json.RawMessage(`"number"`), Optional: true},
+		"id": {Type: json.RawMessage(`"string"`), Optional: true},
+	}}
+
+	for _, value := range []string{
+		`{"n": 9007199254740993}`,
+		`{"n": 1e400}`,
+		`{"id": 123456789012345678901234567890}`,
+	} {
+		raw, err := decodeSchemaValue([]byte(value))
+		if err != nil {
+			t.Fatalf("%s: %v", value, err)
+		}
+		if diags := validateAgainstBlock(block, raw, cty.Path{}); len(diags) > 0 {
+			t.Errorf("%s: got %+v, want it valid", value, diags)
+		}
+	}
+}
+
+func TestValidateNestedAttributes(t *testing.T) {
+	block, err := loadBlockSchema("testdata/tfschema/nested_schema.json", "", "managed", "example_service")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		value   string
+		path    string
+		summary string
+	}{
+		{value: `{"name": "web", "listener": [{"port": 443}], "settings": {"labels": {"a": {"value": "x"}}}}`},
+		{
+			value:   `{"name": "web", "listener": [{"port": 443}, {"protocol": "tcp"}]}`,
+			path:    "listener[1].port",
+			summary: "Missing required argument",
+		},
+		{
+			value:   `{"name": "web", "settings": {"replicas": 1, "bogus": true}}`,
+			path:    "settings.bogus",
+			summary: "Unsupported argument",
+		},
+		{
+			value:   `{"name": "web", "listener": {"port": 443}}`,
+			path:    "listener",
+			summary: "Incorrect attribute value type",
+		},
+		{
+			value:   `{"name": "web", "settings": {"labels": {"a": {"value": ["x"]}}}}`,
+			path:    `settings.labels["a"].value`,
+			summary: "Incorrect attribute value type",
+		},
+		{
+			value:   `{"name": "web", "id": "svc-1"}`,
+			path:    "id",
+			summary: "Value for unconfigurable attribute",
+		},
+	}
+
+	for _, tt := range tests {
+		raw, err := decodeSchemaValue([]byte(tt.value))
+		if err != nil {
+			t.Fatal(err)
+		}
+		diags := validateAgainstBlock(block, raw, cty.Path{})
+		if tt.summary == "" {
+			if len(diags) > 0 {
+				t.Errorf("%s: got %+v, want it valid", tt.value, diags)
+			}
+			continue
+		}
+		if len(diags) != 1 || diags[0].Path != tt.path || diags[0].Summary != tt.summary {
+			t.Errorf("%s: got %+v, want %q at %s", tt.value, diags, tt.summary, tt.path)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// Rules a conversion step can apply, as reported in ctyConvertStep.Rule
+const (
+	ruleNull             = "null"
+	ruleString           = "string"
+	ruleNumber           = "number"
+	ruleNumberFromString = "number_from_string"
+	ruleBool             = "bool"
+	ruleList             = "list_from_array"
+	ruleSet              = "set_from_array"
+	ruleTuple            = "tuple_from_array"
+	ruleMap              = "map_from_object"
+	ruleObject           = "object_from_object"
+	ruleImpliedType      = "implied_type"
+)
+
+// ctyTracePath is the file cty convert --trace-steps writes its steps to
+var ctyTracePath string
+
+// ctyConvertStep is one line of a --trace-steps file: the rule cty convert
+// applied to turn the JSON at Path into a value of type To
+type ctyConvertStep struct {
+	Seq  int             `json:"seq"`
+	Path string          `json:"path"`
+	From string          `json:"from"`
+	To   json.RawMessage `json:"to"`
+	Rule string          `json:"rule"`
+	// Detail notes what the rule did beyond its name, e.g. set elements
+	// that collapsed into one
+	Detail string `json:"detail,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ctyConvertTracer collects the steps of one conversion, parents before
+// their elements. It's nil unless --trace-steps is given, and its methods do
+// nothing then.
+type ctyConvertTracer struct {
+	steps []ctyConvertStep
+}
+
+var ctyTrace *ctyConvertTracer
+
+// jsonKind names the JSON type of a decoded value
+func jsonKind(val interface{}) string {
+	switch val.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case float64, int, int64, json.Number:
+		return "number"
+	case bool:
+		return "bool"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", val)
+}
+
+// step records a rule applied at path, returning its index so the caller
+// can add a detail or error once the elements are built
+func (t *ctyConvertTracer) step(path []string, from string, to cty.Type, rule string) int {
+	if t == nil {
+		return -1
+	}
+	toJSON, _ := ctyjson.MarshalType(to)
+	t.steps = append(t.steps, ctyConvertStep{
+		Seq:  len(t.steps) + 1,
+		Path: traversalPath(path),
+		From: from,
+		To:   toJSON,
+		Rule: rule,
+	})
+	return len(t.steps) - 1
+}
+
+// traversalPath joins path steps in the traversal syntax --sensitive takes,
+// such as b[0].c: attribute steps are separated by dots and index steps
+// follow the step before them directly
+func traversalPath(path []string) string {
+	var b strings.Builder
+	for i, part := range path {
+		if i > 0 && !strings.HasPrefix(part, "[") {
+			b.WriteByte('.')
+		}
+		b.WriteString(part)
+	}
+	return b.String()
+}
+
+func (t *ctyConvertTracer) detail(i int, format string, args ...interface{}) {
+	if t == nil || i < 0 {
+		return
+	}
+	t.steps[i].Detail = fmt.Sprintf(format, args...)
+}
+
+func (t *ctyConvertTracer) fail(i int, err error) {
+	if t == nil || i < 0 || err == nil || t.steps[i].Error != "" {
+		return
+	}
+	t.steps[i].Error = err.Error()
+}
+
+// write writes the steps as NDJSON to path, or to stderr if path is "-"
+func (t *ctyConvertTracer) write(path string) error {
+	out := os.Stderr
+	if path != "-" {
+		file, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("failed to create conversion trace: %w", err)
+		}
+		defer file.Close()
+		out = file
+	}
+	encoder := json.NewEncoder(out)
+	for _, step := range t.steps {
+		if err := encoder.Encode(step); err != nil {
+			return fmt.Errorf("failed to write conversion trace: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCtyConvertTraceStepsPaths(t *testing.T) {
+	dir := t.TempDir()
+	input, output, trace := filepath.Join(dir, "in.json"), filepath.Join(dir, "out.json"), filepath.Join(dir, "trace.ndjson")
+	if err := os.WriteFile(input, []byte(`{"b":[{"c":"1"}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ctyTrace, ctyTracePath = nil, "" })
+
+	cmd := initCtyConvertCmd()
+	cmd.SetArgs([]string{input, output, "--type", `["object",{"b":["list",["object",{"c":"number"}]]}]`, "--trace-steps", trace})
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(trace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var step ctyConvertStep
+		if err := json.Unmarshal([]byte(line), &step); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, step.Path)
+	}
+	if got := strings.Join(paths, ","); got != ",b,b[0],b[0].c" {
+		t.Errorf("got paths %s, want traversal syntax", got)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// JSON encodings for cty convert, chosen with --encoding. ctyjson-typed is
+// the {"value": ..., "type": ...} envelope ctyjson.Marshal writes for a
+// value of dynamic type, as Terraform does when a value's type isn't known
+// ahead; it carries the type, so consumers exchanging values in that shape
+// don't need a --type of their own.
+const (
+	ctyEncodingPlain = "plain"
+	ctyEncodingTyped = "ctyjson-typed"
+)
+
+// ctyEncoding holds --encoding
+var ctyEncoding string
+
+// checkCtyEncoding rejects an unknown --encoding, or ctyjson-typed where
+// nothing is JSON or with options that only handle plain JSON
+func checkCtyEncoding(encoding, inputFormat, outputFormat string, streaming, tracing, redacting bool) error {
+	switch encoding {
+	case ctyEncodingPlain:
+		return nil
+	case ctyEncodingTyped:
+	default:
+		return fmt.Errorf("unknown --encoding %q (expected %s or %s)", encoding, ctyEncodingPlain, ctyEncodingTyped)
+	}
+	switch {
+	case inputFormat != "json" && outputFormat != "json":
+		return fmt.Errorf("--encoding %s applies to JSON; use --input-format or --output-format json", encoding)
+	case streaming:
+		return fmt.Errorf("--encoding %s can't be combined with --stream-parse", encoding)
+	case tracing:
+		return fmt.Errorf("--trace-steps follows plain JSON decoding; drop --encoding %s", encoding)
+	case redacting && outputFormat == "json":
+		return fmt.Errorf("--redact-marked writes plain JSON; drop --encoding %s", encoding)
+	}
+	return nil
+}
+
+// decodeTypedJSON reads a value from a typed envelope and converts it to
+// ty, which may be dynamic to keep the envelope's own type
+func decodeTypedJSON(data []byte, ty cty.Type) (cty.Value, error) {
+	val, err := ctyjson.Unmarshal(data, cty.DynamicPseudoType)
+	if err != nil {
+		return cty.NilVal, fmt.Errorf("not a {\"value\", \"type\"} envelope: %w", err)
+	}
+	if ty == cty.DynamicPseudoType {
+		return val, nil
+	}
+	converted, err := convert.Convert(val, ty)
+	if err != nil {
+		return cty.NilVal, fmt.Errorf("envelope value of type %s doesn't convert to --type %s: %w",
+			val.Type().FriendlyName(), ty.FriendlyName(), err)
+	}
+	return converted, nil
+}
+
+// encodeTypedJSON writes value in a typed envelope with its own type
+func encodeTypedJSON(value cty.Value) ([]byte, error) {
+	return ctyjson.Marshal(value, cty.DynamicPseudoType)
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/apparentlymart/go-textseg/v15/textseg"
+	"github.com/spf13/cobra"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function/stdlib"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+	ctymsgpack "github.com/zclconf/go-cty/cty/msgpack"
+	"golang.org/x/text/unicode/norm"
+)
+
+// unicodeProbeCase is a string the unicode probes run through each layer.
+// Input is the body of a JSON string literal in ASCII, so every harness
+// decodes exactly the same escapes, unpaired surrogates included.
+type unicodeProbeCase struct {
+	Name     string
+	Category string
+	Input    string
+}
+
+// unicodeProbeSuite is shared by cty unicode and hcl unicode, and mirrored
+// by soup's probes in tofusoup.common.unicode_probe
+var unicodeProbeSuite = []unicodeProbeCase{
+	{"ascii", "baseline", `hello`},
+	{"nfc-e-acute", "normalization", `caf\u00e9`},
+	{"nfd-e-acute", "normalization", `cafe\u0301`},
+	{"nfd-hangul", "normalization", `\u1112\u1161\u11ab`},
+	{"compat-ligature", "normalization", `\ufb01le`},
+	{"stacked-marks", "grapheme", `a\u0308\u0304`},
+	{"emoji-zwj-family", "grapheme", `\ud83d\udc68\u200d\ud83d\udc69\u200d\ud83d\udc67`},
+	{"emoji-flag", "grapheme", `\ud83c\uddfa\ud83c\uddf8`},
+	{"emoji-skin-tone", "grapheme", `\ud83d\udc4d\ud83c\udffd`},
+	{"astral-clef", "surrogates", `\ud834\udd1e`},
+	{"lone-high-surrogate", "surrogates", `\ud800`},
+	{"lone-low-surrogate", "surrogates", `x\udc00y`},
+	{"rtl-hebrew", "bidi", `\u05e9\u05dc\u05d5\u05dd`},
+	{"rtl-mixed", "bidi", `abc \u05e9\u05dc\u05d5\u05dd 123`},
+	{"rtl-override", "bidi", `\u202eabc\u202c`},
+	{"bom-prefix", "invisible", `\ufeffkey`},
+	{"zero-width-space", "invisible", `a\u200bb`},
+	{"case-sharp-s", "case", `stra\u00dfe`},
+	{"case-dotted-i", "case", `\u0130stanbul`},
+	{"case-final-sigma", "case", `\u039f\u0394\u039f\u03a3`},
+}
+
+// unicodeMeasures describes a string as the runtime decoded it
+type unicodeMeasures struct {
+	UTF8Bytes  int  `json:"utf8_bytes"`
+	UTF16Units int  `json:"utf16_units"`
+	CodePoints int  `json:"code_points"`
+	Graphemes  int  `json:"graphemes"`
+	NFC        bool `json:"nfc"`
+	NFD        bool `json:"nfd"`
+	// ReplacementChars counts U+FFFD, which Go's decoder substitutes for
+	// unpaired surrogates
+	ReplacementChars int `json:"replacement_chars"`
+	// LoneSurrogates counts unpaired surrogates kept in the string; a Go
+	// string can't hold them, so this is always 0 here
+	LoneSurrogates int `json:"lone_surrogates"`
+}
+
+// ctyUnicodeResult is what cty makes of a string: the value after its
+// NFC normalization, the grapheme-based string functions, and encoding
+// round trips. Strings are reported with non-ASCII escaped.
+type ctyUnicodeResult struct {
+	Value            string `json:"value"`
+	Normalized       bool   `json:"normalized"`
+	Length           int64  `json:"length"`
+	First            string `json:"first"`
+	Upper            string `json:"upper"`
+	Lower            string `json:"lower"`
+	JSONRoundTrip    bool   `json:"json_roundtrip"`
+	MsgpackBytes     int    `json:"msgpack_bytes"`
+	MsgpackRoundTrip bool   `json:"msgpack_roundtrip"`
+	Error            string `json:"error,omitempty"`
+}
+
+// unicodeCaseReport is one case of a unicode probe report
+type unicodeCaseReport struct {
+	Name     string          `json:"name"`
+	Category string          `json:"category"`
+	Input    string          `json:"input"`
+	Decoded  string          `json:"decoded"`
+	Measures unicodeMeasures `json:"measures"`
+	Cty      any             `json:"cty,omitempty"`
+	HCL      any             `json:"hcl,omitempty"`
+}
+
+// asciiEscape renders s with everything outside printable ASCII escaped as
+// JSON \u escapes (UTF-16, so astral characters become surrogate pairs),
+// matching Python's json.dumps with ensure_ascii
+func asciiEscape(s string) string {
+	var quoted strings.Builder
+	encoder := json.NewEncoder(&quoted)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(s)
+	body := strings.TrimSuffix(quoted.String(), "\n")
+
+	var b strings.Builder
+	for _, r := range body[1 : len(body)-1] {
+		if r >= ' ' && r <= '~' {
+			b.WriteRune(r)
+			continue
+		}
+		for _, unit := range utf16.Encode([]rune{r}) {
+			fmt.Fprintf(&b, `\u%04x`, unit)
+		}
+	}
+	return b.String()
+}
+
+// decodeProbeInput decodes a case's JSON string body the way Go decodes
+// JSON
+func decodeProbeInput(input string) (string, error) {
+	var s string
+	if err := json.Unmarshal([]byte(`"`+input+`"`), &s); err != nil {
+		return "", fmt.Errorf("failed to decode input: %w", err)
+	}
+	return s, nil
+}
+
+// measureUnicode measures a decoded string
+func measureUnicode(s string) unicodeMeasures {
+	runes := []rune(s)
+	return unicodeMeasures{
+		UTF8Bytes:        len(s),
+		UTF16Units:       len(utf16.Encode(runes)),
+		CodePoints:       utf8.RuneCountInString(s),
+		Graphemes:        graphemeCount(s),
+		NFC:              norm.NFC.IsNormalString(s),
+		NFD:              norm.NFD.IsNormalString(s),
+		ReplacementChars: strings.Count(s, string(utf8.RuneError)),
+	}
+}
+
+// graphemeCount counts s's extended grapheme clusters
+func graphemeCount(s string) int {
+	n, err := textseg.TokenCount([]byte(s), textseg.ScanGraphemeClusters)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// probeCtyString runs a case through ctyjson decoding, the stdlib string
+// functions and JSON and msgpack round trips
+func probeCtyString(input, decoded string) *ctyUnicodeResult {
+	result := &ctyUnicodeResult{}
+	val, err := ctyjson.Unmarshal([]byte(`"`+input+`"`), cty.String)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Value = asciiEscape(val.AsString())
+	result.Normalized = val.AsString() != decoded
+
+	calls := []struct {
+		out  *string
+		call func() (cty.Value, error)
+	}{
+		{&result.First, func() (cty.Value, error) { return stdlib.Substr(val, cty.NumberIntVal(0), cty.NumberIntVal(1)) }},
+		{&result.Upper, func() (cty.Value, error) { return stdlib.Upper(val) }},
+		{&result.Lower, func() (cty.Value, error) { return stdlib.Lower(val) }},
+	}
+	length, err := stdlib.Strlen(val)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Length, _ = length.AsBigFloat().Int64()
+	for _, c := range calls {
+		out, err := c.call()
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		*c.out = asciiEscape(out.AsString())
+	}
+
+	encoded, err := ctyjson.Marshal(val, cty.String)
+	if err == nil {
+		back, err := ctyjson.Unmarshal(encoded, cty.String)
+		result.JSONRoundTrip = err == nil && back.RawEquals(val)
+	}
+	packed, err := ctymsgpack.Marshal(val, cty.String)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.MsgpackBytes = len(packed)
+	back, err := ctymsgpack.Unmarshal(packed, cty.String)
+	result.MsgpackRoundTrip = err == nil && back.RawEquals(val)
+	return result
+}
+
+// selectUnicodeCases returns the suite cases named, or all of them
+func selectUnicodeCases(names []string) ([]unicodeProbeCase, error) {
+	if len(names) == 0 {
+		return unicodeProbeSuite, nil
+	}
+	byName := map[string]unicodeProbeCase{}
+	known := make([]string, len(unicodeProbeSuite))
+	for i, c := range unicodeProbeSuite {
+		byName[c.Name] = c
+		known[i] = c.Name
+	}
+	var cases []unicodeProbeCase
+	for _, name := range names {
+		c, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown case %q (expected one of: %s)", name, strings.Join(known, ", "))
+		}
+		cases = append(cases, c)
+	}
+	return cases, nil
+}
+
+// runUnicodeProbe decodes and measures each case and adds what probe
+// reports for it
+func runUnicodeProbe(names []string, probe func(c unicodeProbeCase, decoded string, report *unicodeCaseReport)) ([]unicodeCaseReport, error) {
+	cases, err := selectUnicodeCases(names)
+	if err != nil {
+		return nil, err
+	}
+	reports := make([]unicodeCaseReport, 0, len(cases))
+	for _, c := range cases {
+		decoded, err := decodeProbeInput(c.Input)
+		if err != nil {
+			return nil, fmt.Errorf("case %s: %w", c.Name, err)
+		}
+		report := unicodeCaseReport{
+			Name:     c.Name,
+			Category: c.Category,
+			Input:    c.Input,
+			Decoded:  asciiEscape(decoded),
+			Measures: measureUnicode(decoded),
+		}
+		probe(c, decoded, &report)
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+// encodeUnicodeReport prints a probe report as indented JSON
+func encodeUnicodeReport(probe string, cases []unicodeCaseReport) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(map[string]interface{}{
+		"probe":   probe,
+		"harness": "soup-go",
+		"cases":   cases,
+	})
+}
+
+func initCtyUnicodeCmd() *cobra.Command {
+	var cases []string
+
+	cmd := &cobra.Command{
+		Use:   "unicode",
+		Short: "Report how cty normalizes, measures and encodes a suite of unicode strings",
+		Long: `Run a suite of strings (NFC and NFD forms, unpaired surrogates, grapheme
+clusters, right-to-left text, invisible and case-mapping characters)
+through cty and report, for each:
+
+  measures  the decoded string's UTF-8 bytes, UTF-16 units, code points,
+            grapheme clusters and normalization forms
+  cty       the value after cty's NFC normalization; strlen, substr(0, 1),
+            upper and lower, which count grapheme clusters; and JSON and
+            msgpack round trips
+
+Inputs are JSON string escapes, so every harness decodes the same text;
+strings in the report escape everything outside printable ASCII. Compare
+with 'soup cty unicode' to find where runtimes disagree on string length
+and indexing.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			reports, err := runUnicodeProbe(cases, func(c unicodeProbeCase, decoded string, report *unicodeCaseReport) {
+				report.Cty = probeCtyString(c.Input, decoded)
+			})
+			if err != nil {
+				return err
+			}
+			return encodeUnicodeReport("cty", reports)
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&cases, "case", nil, "Only run these cases (default all)")
+	return cmd
+}
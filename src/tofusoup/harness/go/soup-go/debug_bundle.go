@@ -0,0 +1,338 @@
+package main
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// maxBundleLogBytes is how much of the end of each log file is included
+const maxBundleLogBytes = 256 * 1024
+
+// redactedEnvPattern matches environment variable names whose values must not
+// leave the machine.
+var redactedEnvPattern = regexp.MustCompile(`(?i)(TOKEN|SECRET|PASSWORD|PASSWD|CREDENTIAL|AUTH|PRIVATE|SESSION|COOKIE|_KEY$|^KEY$|CERT)`)
+
+// redactEnvironment returns the environment as a map with sensitive values
+// replaced by a marker
+func redactEnvironment(environ []string) map[string]string {
+	env := make(map[string]string, len(environ))
+	for _, kv := range environ {
+		name, value, _ := strings.Cut(kv, "=")
+		if redactedEnvPattern.MatchString(name) && value != "" {
+			value = "[REDACTED]"
+		}
+		env[name] = value
+	}
+	return env
+}
+
+// bundleWriter adds files to a gzipped tar under a common top-level directory
+type bundleWriter struct {
+	tw     *tar.Writer
+	root   string
+	now    time.Time
+	files  []string
+	errors []string
+}
+
+func (b *bundleWriter) addBytes(name string, data []byte) {
+	if redactOn {
+		data = redactLog(data)
+	}
+	hdr := &tar.Header{
+		Name:    b.root + "/" + name,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: b.now,
+	}
+	if err := b.tw.WriteHeader(hdr); err != nil {
+		b.errors = append(b.errors, fmt.Sprintf("%s: %v", name, err))
+		return
+	}
+	if _, err := b.tw.Write(data); err != nil {
+		b.errors = append(b.errors, fmt.Sprintf("%s: %v", name, err))
+		return
+	}
+	b.files = append(b.files, name)
+}
+
+func (b *bundleWriter) addJSON(name string, v interface{}) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		b.errors = append(b.errors, fmt.Sprintf("%s: %v", name, err))
+		return
+	}
+	b.addBytes(name, append(data, '\n'))
+}
+
+// note records a problem collecting part of the bundle without failing it
+func (b *bundleWriter) note(format string, args ...interface{}) {
+	b.errors = append(b.errors, fmt.Sprintf(format, args...))
+}
+
+// collectVersionInfo describes the binary and the platform it runs on
+func collectVersionInfo() map[string]interface{} {
+	info := map[string]interface{}{
+		"version":    version,
+		"go_version": runtime.Version(),
+		"os":         runtime.GOOS,
+		"arch":       runtime.GOARCH,
+		"num_cpu":    runtime.NumCPU(),
+		"pid":        os.Getpid(),
+	}
+	if exe, err := os.Executable(); err == nil {
+		info["executable"] = exe
+	}
+	if host, err := os.Hostname(); err == nil {
+		info["hostname"] = host
+	}
+	if build, ok := debug.ReadBuildInfo(); ok {
+		deps := make(map[string]string, len(build.Deps))
+		for _, dep := range build.Deps {
+			v := dep.Version
+			if dep.Replace != nil {
+				v += " => " + dep.Replace.Path + " " + dep.Replace.Version
+			}
+			deps[dep.Path] = strings.TrimSpace(v)
+		}
+		settings := make(map[string]string, len(build.Settings))
+		for _, s := range build.Settings {
+			settings[s.Key] = s.Value
+		}
+		info["module"] = build.Main.Path
+		info["dependencies"] = deps
+		info["build_settings"] = settings
+	}
+	return info
+}
+
+// collectResolvedConfig reports the effective configuration and paths
+func collectResolvedConfig() map[string]interface{} {
+	overrides := map[string]string{}
+	for _, name := range []string{EnvTofuSoupCacheDir, EnvXDGCacheHome, EnvKVStorageDir, EnvLocalAppData} {
+		if value := os.Getenv(name); value != "" {
+			overrides[name] = value
+		}
+	}
+	return map[string]interface{}{
+		"version":        version,
+		"log_level":      logLevel,
+		"verbose":        verbose,
+		"cache_dir":      GetCacheDir(),
+		"kv_storage_dir": GetKVStorageDir(),
+		"logs_dir":       filepath.Join(GetCacheDir(), LogsDirName),
+		"path_overrides": overrides,
+		"temp_dir":       os.TempDir(),
+		"plugin_server":  os.Getenv("PLUGIN_SERVER_PATH"),
+		"redact":         redactOn,
+		"default_handshake": map[string]interface{}{
+			"protocol_version":   Handshake.ProtocolVersion,
+			"magic_cookie_key":   Handshake.MagicCookieKey,
+			"magic_cookie_value": Handshake.MagicCookieValue,
+		},
+	}
+}
+
+// collectStorageListing lists the KV storage directory
+func collectStorageListing(dir string) (map[string]interface{}, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]map[string]interface{}, 0, len(entries))
+	var total int64
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		total += info.Size()
+		files = append(files, map[string]interface{}{
+			"name":     entry.Name(),
+			"size":     info.Size(),
+			"mode":     info.Mode().String(),
+			"modified": info.ModTime().UTC().Format(time.RFC3339),
+		})
+	}
+	return map[string]interface{}{
+		"path":        dir,
+		"entries":     files,
+		"count":       len(files),
+		"total_bytes": total,
+	}, nil
+}
+
+// tailFile returns up to max bytes from the end of a file
+func tailFile(path string, max int64) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() > max {
+		if _, err := f.Seek(-max, io.SeekEnd); err != nil {
+			return nil, err
+		}
+	}
+	return io.ReadAll(f)
+}
+
+// captureHandshake starts this binary as a plugin server and returns the
+// go-plugin handshake line it prints, then stops it.
+func captureHandshake(timeout time.Duration) (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate executable: %w", err)
+	}
+
+	cmd := exec.Command(exe, "rpc", "kv", "server")
+	cmd.Env = append(os.Environ(), Handshake.MagicCookieKey+"="+Handshake.MagicCookieValue)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return "", err
+	}
+	if err := cmd.Start(); err != nil {
+		return "", fmt.Errorf("failed to start plugin server: %w", err)
+	}
+	defer func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	}()
+
+	lines := make(chan string, 1)
+	go func() {
+		scanner := bufio.NewScanner(stdout)
+		if scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+
+	select {
+	case line, ok := <-lines:
+		if !ok {
+			return "", fmt.Errorf("plugin server exited without a handshake")
+		}
+		return line, nil
+	case <-time.After(timeout):
+		return "", fmt.Errorf("timed out after %s waiting for handshake", timeout)
+	}
+}
+
+func initDebugBundleCmd() *cobra.Command {
+	var outPath string
+	var withHandshake bool
+	var logsDir string
+
+	cmd := &cobra.Command{
+		Use:   "bundle",
+		Short: "Collect diagnostics into a tar.gz for interop bug reports",
+		Long: `Collect version info, resolved configuration, the environment (with
+sensitive values redacted), recent logs and a listing of the KV storage
+directory into a single archive. With --handshake, a plugin server is
+started briefly and its handshake line is captured as well.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			now := time.Now().UTC()
+			if outPath == "" {
+				outPath = fmt.Sprintf("soup-go-debug-%s.tar.gz", now.Format("20060102-150405"))
+			}
+
+			f, err := os.Create(outPath)
+			if err != nil {
+				return fmt.Errorf("failed to create bundle: %w", err)
+			}
+			defer f.Close()
+			gz := gzip.NewWriter(f)
+			b := &bundleWriter{
+				tw:   tar.NewWriter(gz),
+				root: strings.TrimSuffix(strings.TrimSuffix(filepath.Base(outPath), ".gz"), ".tar"),
+				now:  now,
+			}
+
+			b.addJSON("version.json", collectVersionInfo())
+			b.addJSON("config.json", collectResolvedConfig())
+			b.addJSON("environment.json", redactEnvironment(os.Environ()))
+
+			storageDir := GetKVStorageDir()
+			if listing, err := collectStorageListing(storageDir); err != nil {
+				b.note("storage listing: %v", err)
+			} else {
+				b.addJSON("storage.json", listing)
+			}
+
+			if logsDir == "" {
+				logsDir = filepath.Join(GetCacheDir(), LogsDirName)
+			}
+			if logFiles, err := filepath.Glob(filepath.Join(logsDir, "*.log")); err != nil {
+				b.note("logs: %v", err)
+			} else {
+				sort.Strings(logFiles)
+				if len(logFiles) == 0 {
+					b.note("logs: no *.log files in %s", logsDir)
+				}
+				for _, path := range logFiles {
+					data, err := tailFile(path, maxBundleLogBytes)
+					if err != nil {
+						b.note("logs: %s: %v", path, err)
+						continue
+					}
+					b.addBytes("logs/"+filepath.Base(path), data)
+				}
+			}
+
+			if withHandshake {
+				line, err := captureHandshake(10 * time.Second)
+				if err != nil {
+					b.note("handshake: %v", err)
+				} else {
+					b.addBytes("handshake.txt", []byte(line+"\n"))
+				}
+			}
+
+			b.addJSON("manifest.json", map[string]interface{}{
+				"created": now.Format(time.RFC3339),
+				"files":   append(append([]string(nil), b.files...), "manifest.json"),
+				"errors":  b.errors,
+			})
+
+			if err := b.tw.Close(); err != nil {
+				return fmt.Errorf("failed to finish bundle: %w", err)
+			}
+			if err := gz.Close(); err != nil {
+				return fmt.Errorf("failed to finish bundle: %w", err)
+			}
+
+			return json.NewEncoder(os.Stdout).Encode(map[string]interface{}{
+				"bundle": outPath,
+				"files":  len(b.files),
+				"errors": b.errors,
+			})
+		},
+	}
+
+	cmd.Flags().StringVar(&outPath, "out", "", "Output archive path (default soup-go-debug-<timestamp>.tar.gz)")
+	cmd.Flags().BoolVar(&withHandshake, "handshake", false, "Start a plugin server briefly and capture its handshake line")
+	cmd.Flags().StringVar(&logsDir, "logs-dir", "", "Directory of *.log files to include (default <cache-dir>/logs)")
+	return cmd
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// readBundle returns the files in a debug bundle keyed by their path under
+// the bundle's top-level directory
+func readBundle(t *testing.T, path string) map[string][]byte {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string][]byte{}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return files
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		root, name, _ := strings.Cut(hdr.Name, "/")
+		if root != "bundle" {
+			t.Errorf("%s is outside the bundle directory", hdr.Name)
+		}
+		if files[name], err = io.ReadAll(tr); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestDebugBundleCmd(t *testing.T) {
+	dir := t.TempDir()
+	storageDir := filepath.Join(dir, "kv")
+	logsDir := filepath.Join(dir, "logs")
+	t.Setenv(EnvKVStorageDir, storageDir)
+	t.Setenv("SOUP_BUNDLE_TEST_TOKEN", "hunter2")
+	t.Setenv("SOUP_BUNDLE_TEST_COLOR", "blue")
+
+	if err := NewKVImpl(logger, storageDir).Put("bundled", []byte("value")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(logsDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	bigLog := append(bytes.Repeat([]byte("old\n"), maxBundleLogBytes/4), []byte("newest line\n")...)
+	if err := os.WriteFile(filepath.Join(logsDir, "server.log"), bigLog, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(logsDir, "notes.txt"), []byte("skipped"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	outPath := filepath.Join(dir, "bundle.tar.gz")
+	cmd := initDebugBundleCmd()
+	cmd.SetArgs([]string{"--out", outPath, "--logs-dir", logsDir})
+	out, err := captureStdout(t, cmd.Execute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var summary map[string]interface{}
+	if err := json.Unmarshal(out, &summary); err != nil {
+		t.Fatalf("%v: %s", err, out)
+	}
+	if summary["bundle"] != outPath || summary["files"] != 6.0 {
+		t.Errorf("got summary %v, want 6 files in %s", summary, outPath)
+	}
+
+	files := readBundle(t, outPath)
+	for _, name := range []string{"version.json", "config.json", "environment.json", "storage.json", "logs/server.log", "manifest.json"} {
+		if files[name] == nil {
+			t.Errorf("bundle is missing %s", name)
+		}
+	}
+	if files["logs/notes.txt"] != nil {
+		t.Error("bundle includes a file that isn't a *.log")
+	}
+
+	var env map[string]string
+	if err := json.Unmarshal(files["environment.json"], &env); err != nil {
+		t.Fatal(err)
+	}
+	if env["SOUP_BUNDLE_TEST_TOKEN"] != "[REDACTED]" || env["SOUP_BUNDLE_TEST_COLOR"] != "blue" {
+		t.Errorf("got token %q and color %q, want only the token redacted",
+			env["SOUP_BUNDLE_TEST_TOKEN"], env["SOUP_BUNDLE_TEST_COLOR"])
+	}
+	for name, data := range files {
+		if bytes.Contains(data, []byte("hunter2")) {
+			t.Errorf("%s leaks a redacted value", name)
+		}
+	}
+
+	var storage map[string]interface{}
+	if err := json.Unmarshal(files["storage.json"], &storage); err != nil {
+		t.Fatal(err)
+	}
+	if storage["path"] != storageDir || storage["count"] == 0.0 {
+		t.Errorf("got storage listing %v, want the entries of %s", storage, storageDir)
+	}
+
+	serverLog := files["logs/server.log"]
+	if len(serverLog) != maxBundleLogBytes || !bytes.HasSuffix(serverLog, []byte("newest line\n")) {
+		t.Errorf("got %d bytes of server.log, want the last %d", len(serverLog), maxBundleLogBytes)
+	}
+}
+
+func TestDebugBundleCmdNotesMissingInputs(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(EnvKVStorageDir, filepath.Join(dir, "missing"))
+
+	outPath := filepath.Join(dir, "bundle.tar.gz")
+	cmd := initDebugBundleCmd()
+	cmd.SetArgs([]string{"--out", outPath, "--logs-dir", filepath.Join(dir, "no-logs")})
+	if _, err := captureStdout(t, cmd.Execute); err != nil {
+		t.Fatal(err)
+	}
+
+	var manifest struct {
+		Files  []string `json:"files"`
+		Errors []string `json:"errors"`
+	}
+	if err := json.Unmarshal(readBundle(t, outPath)["manifest.json"], &manifest); err != nil {
+		t.Fatal(err)
+	}
+	errors := strings.Join(manifest.Errors, "\n")
+	if !strings.Contains(errors, "storage listing:") || !strings.Contains(errors, "logs: no *.log files") {
+		t.Errorf("got errors %q, want the missing storage and logs noted", manifest.Errors)
+	}
+	for _, name := range manifest.Files {
+		if name == "storage.json" {
+			t.Error("bundle lists storage.json for a missing storage directory")
+		}
+	}
+}
@@ -58,3 +58,14 @@ const (
 	// MacOSCacheSubdir is the macOS cache subdirectory
 	MacOSCacheSubdir = "Caches"
 )
+
+// =================================
+// KV List pagination
+// =================================
+const (
+	// DefaultListPageSize is used when a List request has no page_size
+	DefaultListPageSize = 100
+
+	// MaxListPageSize caps page_size; larger requests are clamped
+	MaxListPageSize = 1000
+)
@@ -0,0 +1,28 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+// notifyDiagSignals calls dump on every SIGUSR1. On SIGQUIT it dumps and
+// exits, taking over the Go runtime's own stack dump.
+func notifyDiagSignals(logger hclog.Logger, dump func(reason string)) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1, syscall.SIGQUIT)
+	logger.Debug("🩺 diagnostic dumps armed", "signals", "SIGUSR1, SIGQUIT", "file", rpcDiagFile)
+	go func() {
+		for sig := range signals {
+			logger.Info("🩺 dumping diagnostics", "signal", sig)
+			dump(sig.String())
+			if sig == syscall.SIGQUIT {
+				os.Exit(diagExitCode)
+			}
+		}
+	}()
+}
@@ -0,0 +1,10 @@
+//go:build windows
+
+package main
+
+import "github.com/hashicorp/go-hclog"
+
+// notifyDiagSignals does nothing on windows, which has no SIGUSR1 or SIGQUIT
+func notifyDiagSignals(logger hclog.Logger, dump func(reason string)) {
+	logger.Debug("🩺 diagnostic dumps need SIGUSR1, which windows lacks")
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"os"
+	"sort"
+	"strings"
+)
+
+// digestAlgEnv sets --digest-alg, so plugin servers started by a client
+// report fingerprints in the same algorithm
+const digestAlgEnv = "SOUP_GO_DIGEST_ALG"
+
+// defaultDigestAlg is what fingerprints use unless --digest-alg says otherwise
+const defaultDigestAlg = "sha256"
+
+// digestAlg is a hash fingerprints and digests can be computed with. The
+// name is reported next to every fingerprint so values from different
+// harnesses are only compared when they used the same algorithm.
+type digestAlg interface {
+	Name() string
+	New() hash.Hash
+}
+
+// stdDigest is a digestAlg backed by a standard library constructor
+type stdDigest struct {
+	name string
+	new  func() hash.Hash
+}
+
+func (d stdDigest) Name() string   { return d.name }
+func (d stdDigest) New() hash.Hash { return d.new() }
+
+// sha256Digest is the fixed algorithm behind fields named *_sha256
+var sha256Digest digestAlg = stdDigest{name: "sha256", new: sha256.New}
+
+// digestAlgs holds every algorithm compiled in. Files behind build tags add
+// theirs with registerDigest from init.
+var digestAlgs = map[string]digestAlg{
+	"sha256": sha256Digest,
+	"sha512": stdDigest{name: "sha512", new: sha512.New},
+}
+
+func registerDigest(alg digestAlg) {
+	digestAlgs[alg.Name()] = alg
+}
+
+// digestAlgName holds --digest-alg
+var digestAlgName string
+
+// currentDigest is the algorithm chosen by applyDigestAlg
+var currentDigest = sha256Digest
+
+// applyDigestAlg selects the algorithm named by --digest-alg or
+// SOUP_GO_DIGEST_ALG, failing on names that aren't compiled in
+func applyDigestAlg() error {
+	name := digestAlgName
+	if name == "" {
+		name = os.Getenv(digestAlgEnv)
+	}
+	if name == "" {
+		name = defaultDigestAlg
+	}
+	alg, ok := digestAlgs[strings.ToLower(name)]
+	if !ok {
+		return fmt.Errorf("unknown digest algorithm %q (compiled in: %s)", name, strings.Join(digestAlgNames(), ", "))
+	}
+	currentDigest = alg
+	return nil
+}
+
+func digestAlgNames() []string {
+	names := make([]string, 0, len(digestAlgs))
+	for name := range digestAlgs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// digestHex returns the hex digest of data
+func digestHex(alg digestAlg, data []byte) string {
+	h := alg.New()
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// fingerprint returns the hex digest of data in the selected algorithm
+func fingerprint(data []byte) string {
+	return digestHex(currentDigest, data)
+}
+
+// sha256Hex returns the hex SHA-256 of data
+func sha256Hex(data []byte) string {
+	return digestHex(sha256Digest, data)
+}
@@ -0,0 +1,15 @@
+//go:build blake3
+
+package main
+
+import (
+	"hash"
+
+	"lukechampine.com/blake3"
+)
+
+// Build with -tags blake3 (after go get lukechampine.com/blake3) to offer
+// --digest-alg blake3. Digests are 256 bits.
+func init() {
+	registerDigest(stdDigest{name: "blake3", new: func() hash.Hash { return blake3.New(32, nil) }})
+}
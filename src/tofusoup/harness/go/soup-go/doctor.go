@@ -0,0 +1,400 @@
+package main
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Doctor check statuses. Only failures make doctor exit non-zero.
+const (
+	doctorOK   = "ok"
+	doctorWarn = "warn"
+	doctorFail = "fail"
+)
+
+// doctorClockSkew is how far the filesystem's clock may drift from the
+// system clock before doctor warns
+const doctorClockSkew = 2 * time.Second
+
+// doctorCheck is one environment check and, unless it passed, how to fix it
+type doctorCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail"`
+	Fix    string `json:"fix,omitempty"`
+}
+
+// doctorReport is the structured output of a doctor run
+type doctorReport struct {
+	Harness  string        `json:"harness"`
+	Version  string        `json:"version"`
+	Platform string        `json:"platform"`
+	OK       int           `json:"ok"`
+	Warnings int           `json:"warnings"`
+	Failures int           `json:"failures"`
+	Checks   []doctorCheck `json:"checks"`
+}
+
+func (r *doctorReport) add(name, status, detail, fix string) {
+	switch status {
+	case doctorOK:
+		r.OK++
+		fix = ""
+	case doctorWarn:
+		r.Warnings++
+	case doctorFail:
+		r.Failures++
+	}
+	r.Checks = append(r.Checks, doctorCheck{Name: name, Status: status, Detail: detail, Fix: fix})
+}
+
+// checkPluginServerPath checks that PLUGIN_SERVER_PATH names a server the
+// rpc client commands can spawn
+func checkPluginServerPath(r *doctorReport) {
+	const name = "env.PLUGIN_SERVER_PATH"
+	path := os.Getenv("PLUGIN_SERVER_PATH")
+	if path == "" {
+		r.add(name, doctorWarn, "not set, so rpc kv client commands can't spawn a server",
+			"export PLUGIN_SERVER_PATH=$(command -v soup-go), or the path to soup for the Python server")
+		return
+	}
+	resolved, err := exec.LookPath(path)
+	if err != nil {
+		fix := "point PLUGIN_SERVER_PATH at an existing soup-go or soup binary"
+		if info, statErr := os.Stat(path); statErr == nil && !info.IsDir() && runtime.GOOS != "windows" {
+			fix = fmt.Sprintf("chmod +x %s", path)
+		}
+		r.add(name, doctorFail, fmt.Sprintf("%s is not an executable: %v", path, err), fix)
+		return
+	}
+	r.add(name, doctorOK, resolved, "")
+}
+
+// checkTLSEnv checks the TLS settings the rpc client passes to the servers
+// it spawns
+func checkTLSEnv(r *doctorReport) {
+	const name = "env.TLS"
+	mode, keyType, curve := os.Getenv("TLS_MODE"), os.Getenv("TLS_KEY_TYPE"), os.Getenv("TLS_CURVE")
+	var problems []string
+	if mode != "" && !slices.Contains([]string{"disabled", "auto", "manual"}, mode) {
+		problems = append(problems, fmt.Sprintf("TLS_MODE %q is not disabled, auto or manual", mode))
+	}
+	if keyType != "" && keyType != "ec" && keyType != "rsa" {
+		problems = append(problems, fmt.Sprintf("TLS_KEY_TYPE %q is not ec or rsa", keyType))
+	}
+	if curve != "" {
+		if _, err := getCurve(curve); err != nil {
+			problems = append(problems, fmt.Sprintf("TLS_CURVE %q is not secp256r1, secp384r1 or secp521r1", curve))
+		}
+	}
+	if len(problems) > 0 {
+		r.add(name, doctorFail, strings.Join(problems, "; "), "unset the variable or set one of the listed values")
+		return
+	}
+	if mode == "" {
+		mode = "disabled"
+	}
+	r.add(name, doctorOK, fmt.Sprintf("TLS_MODE=%s", mode), "")
+}
+
+// probeWritable creates and removes a file in dir, returning how far the
+// file's modification time is from the system clock
+func probeWritable(dir string) (time.Duration, error) {
+	f, err := os.CreateTemp(dir, ".soup-doctor-*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	if _, err := f.WriteString("soup-go doctor\n"); err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	return info.ModTime().Sub(now), nil
+}
+
+// checkWritableDir checks that dir, or the nearest existing directory it
+// would be created in, is writable. It returns the filesystem clock skew
+// seen, if it got that far.
+func checkWritableDir(r *doctorReport, name, dir string) (time.Duration, bool) {
+	existing := dir
+	for {
+		info, err := os.Stat(existing)
+		if err == nil {
+			if !info.IsDir() {
+				r.add(name, doctorFail, fmt.Sprintf("%s is not a directory", existing),
+					fmt.Sprintf("remove %s or choose another directory", existing))
+				return 0, false
+			}
+			break
+		}
+		if !errors.Is(err, os.ErrNotExist) || filepath.Dir(existing) == existing {
+			r.add(name, doctorFail, fmt.Sprintf("cannot inspect %s: %v", existing, err), "check the path and its permissions")
+			return 0, false
+		}
+		existing = filepath.Dir(existing)
+	}
+
+	skew, err := probeWritable(existing)
+	if err != nil {
+		r.add(name, doctorFail, fmt.Sprintf("%s is not writable: %v", existing, err),
+			fmt.Sprintf("chmod u+w %s, or point the directory somewhere writable", existing))
+		return 0, false
+	}
+	if existing != dir {
+		r.add(name, doctorOK, fmt.Sprintf("%s does not exist yet; it will be created in writable %s", dir, existing), "")
+	} else {
+		r.add(name, doctorOK, fmt.Sprintf("%s is writable", dir), "")
+	}
+	return skew, true
+}
+
+// checkStorage checks KV_STORAGE_DIR and the storage and cache directories
+func checkStorage(r *doctorReport) (time.Duration, bool) {
+	if dir := os.Getenv(EnvKVStorageDir); dir == "" {
+		r.add("env."+EnvKVStorageDir, doctorOK, fmt.Sprintf("not set; servers store values in %s", GetKVStorageDir()), "")
+	} else if !filepath.IsAbs(dir) {
+		r.add("env."+EnvKVStorageDir, doctorWarn,
+			fmt.Sprintf("%s is relative, so it resolves against each server's working directory", dir),
+			fmt.Sprintf("export %s=%s", EnvKVStorageDir, absOrSame(dir)))
+	} else {
+		r.add("env."+EnvKVStorageDir, doctorOK, dir, "")
+	}
+
+	skew, ok := checkWritableDir(r, "storage.kv", GetKVStorageDir())
+	checkWritableDir(r, "storage.cache", GetCacheDir())
+	return skew, ok
+}
+
+func absOrSame(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// checkPorts checks that the loopback interface accepts listeners, which
+// go-plugin needs, and that each port is free
+func checkPorts(r *doctorReport, ports []int) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		r.add("port.loopback", doctorFail, fmt.Sprintf("cannot listen on 127.0.0.1: %v", err),
+			"bring up the loopback interface; plugin servers listen on it")
+	} else {
+		listener.Close()
+		r.add("port.loopback", doctorOK, "127.0.0.1 accepts listeners", "")
+	}
+
+	for _, port := range ports {
+		name := fmt.Sprintf("port.%d", port)
+		listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+		if err != nil {
+			r.add(name, doctorFail, fmt.Sprintf("port %d is not available: %v", port, err),
+				fmt.Sprintf("stop whatever listens on it (lsof -i :%d), or pass another --port to rpc kv server", port))
+			continue
+		}
+		listener.Close()
+		r.add(name, doctorOK, fmt.Sprintf("port %d is free", port), "")
+	}
+}
+
+// checkCompanions checks for the binaries cross-language runs use
+func checkCompanions(r *doctorReport) {
+	if path, err := exec.LookPath("soup"); err != nil {
+		r.add("harness.soup", doctorWarn, "soup is not on PATH, so Python servers and clients are unavailable",
+			"install the Python harness: uv tool install tofusoup")
+	} else {
+		r.add("harness.soup", doctorOK, path, "")
+	}
+
+	built := filepath.Join(GetCacheDir(), HarnessesDirName, "soup-go")
+	if runtime.GOOS == "windows" {
+		built += ".exe"
+	}
+	if _, err := os.Stat(built); err != nil {
+		r.add("harness.soup-go", doctorWarn, fmt.Sprintf("no soup-go build at %s for soup to use", built),
+			"soup harness build soup-go")
+	} else {
+		r.add("harness.soup-go", doctorOK, built, "")
+	}
+
+	if path, err := exec.LookPath("go"); err != nil {
+		r.add("harness.go", doctorWarn, "go is not on PATH, so soup can't build soup-go from source",
+			"install Go 1.24 or later")
+	} else {
+		r.add("harness.go", doctorOK, path, "")
+	}
+}
+
+// buildTime is the VCS commit time this binary was built from, if recorded
+func buildTime() (time.Time, bool) {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return time.Time{}, false
+	}
+	for _, s := range info.Settings {
+		if s.Key == "vcs.time" {
+			t, err := time.Parse(time.RFC3339, s.Value)
+			return t, err == nil
+		}
+	}
+	return time.Time{}, false
+}
+
+// checkClock checks the clock against this binary's build time and the
+// filesystem's clock. Generated TLS certificates are valid from the moment
+// they're made, so a peer whose clock is behind rejects them.
+func checkClock(r *doctorReport, now time.Time, skew time.Duration, haveSkew bool) {
+	const name = "tls.clock"
+	const fix = "sync the system clock (e.g. timedatectl set-ntp true); TLS peers reject certificates " +
+		"from a host whose clock is ahead of theirs as not yet valid"
+	if built, ok := buildTime(); ok && now.Before(built) {
+		r.add(name, doctorFail, fmt.Sprintf("the clock reads %s, before this binary's build time %s",
+			now.UTC().Format(time.RFC3339), built.UTC().Format(time.RFC3339)), fix)
+		return
+	}
+	if haveSkew && (skew > doctorClockSkew || skew < -doctorClockSkew) {
+		r.add(name, doctorWarn, fmt.Sprintf("the filesystem's clock is %s off the system clock",
+			skew.Round(time.Millisecond)), fix)
+		return
+	}
+	r.add(name, doctorOK, fmt.Sprintf("the clock reads %s", now.UTC().Format(time.RFC3339)), "")
+}
+
+// checkCertWindow checks that a configured certificate is valid now
+func checkCertWindow(r *doctorReport, name string, pemData []byte, now time.Time) {
+	block, _ := pem.Decode(pemData)
+	if block == nil || block.Type != "CERTIFICATE" {
+		r.add(name, doctorFail, "no PEM certificate", "set it to a PEM-encoded certificate")
+		return
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		r.add(name, doctorFail, fmt.Sprintf("cannot parse certificate: %v", err), "set it to a valid certificate")
+		return
+	}
+	switch {
+	case now.Before(cert.NotBefore):
+		r.add(name, doctorFail, fmt.Sprintf("%s is not valid until %s", cert.Subject.CommonName,
+			cert.NotBefore.UTC().Format(time.RFC3339)),
+			"the clock here or where the certificate was made is wrong; sync both and regenerate it")
+	case now.After(cert.NotAfter):
+		r.add(name, doctorFail, fmt.Sprintf("%s expired at %s", cert.Subject.CommonName,
+			cert.NotAfter.UTC().Format(time.RFC3339)), "regenerate the certificate")
+	default:
+		r.add(name, doctorOK, fmt.Sprintf("%s is valid until %s", cert.Subject.CommonName,
+			cert.NotAfter.UTC().Format(time.RFC3339)), "")
+	}
+}
+
+// checkCerts checks the certificates servers and clients are configured with
+func checkCerts(r *doctorReport, now time.Time) {
+	if path := os.Getenv("PLUGIN_SERVER_CERT"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			r.add("tls.PLUGIN_SERVER_CERT", doctorFail, fmt.Sprintf("cannot read %s: %v", path, err),
+				"point PLUGIN_SERVER_CERT at a readable PEM certificate")
+		} else {
+			checkCertWindow(r, "tls.PLUGIN_SERVER_CERT", data, now)
+		}
+	}
+	if data := os.Getenv("PLUGIN_CLIENT_CERT"); data != "" {
+		checkCertWindow(r, "tls.PLUGIN_CLIENT_CERT", []byte(data), now)
+	}
+}
+
+// runDoctor runs every environment check
+func runDoctor(ports []int) *doctorReport {
+	report := &doctorReport{
+		Harness:  "soup-go",
+		Version:  version,
+		Platform: runtime.GOOS + "/" + runtime.GOARCH,
+		Checks:   []doctorCheck{},
+	}
+	now := time.Now()
+	checkPluginServerPath(report)
+	checkTLSEnv(report)
+	skew, haveSkew := checkStorage(report)
+	checkPorts(report, ports)
+	checkCompanions(report)
+	checkClock(report, now, skew, haveSkew)
+	checkCerts(report, now)
+	return report
+}
+
+// printDoctorReport writes the report as text or JSON and returns an error
+// if any check failed
+func printDoctorReport(report *doctorReport, outputFormat string) error {
+	if outputFormat == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			return fmt.Errorf("failed to encode report: %w", err)
+		}
+	} else {
+		icons := map[string]string{doctorOK: "✅", doctorWarn: "⚠️ ", doctorFail: "❌"}
+		for _, check := range report.Checks {
+			fmt.Printf("%s %s: %s\n", icons[check.Status], check.Name, check.Detail)
+			if check.Fix != "" {
+				fmt.Printf("   fix: %s\n", check.Fix)
+			}
+		}
+		fmt.Printf("\n%d ok, %d warnings, %d failures\n", report.OK, report.Warnings, report.Failures)
+	}
+	if report.Failures > 0 {
+		return fmt.Errorf("%d of %d doctor checks failed", report.Failures, len(report.Checks))
+	}
+	return nil
+}
+
+func initDoctorCmd() *cobra.Command {
+	var ports []int
+	var outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Check the runtime environment and suggest fixes",
+		Long: `Check the environment soup-go runs in and print a fix for each problem:
+
+  env       PLUGIN_SERVER_PATH names an executable server; KV_STORAGE_DIR
+            is absolute; TLS_MODE, TLS_KEY_TYPE and TLS_CURVE are valid
+  storage   the KV storage and cache directories are writable
+  port      the loopback interface accepts listeners and each --port is free
+  harness   soup, a soup-go build for soup, and go are available
+  tls       the clock is sane (not before this binary's build time, and in
+            step with the filesystem) and PLUGIN_SERVER_CERT and
+            PLUGIN_CLIENT_CERT, if set, are valid now
+
+Warnings are problems that only affect some commands. Exits non-zero if
+any check fails.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if outputFormat != "text" && outputFormat != "json" {
+				return fmt.Errorf("unknown --output-format %q (expected text or json)", outputFormat)
+			}
+			return printDoctorReport(runDoctor(ports), outputFormat)
+		},
+	}
+
+	cmd.Flags().IntSliceVar(&ports, "port", []int{50051}, "Ports that must be free for standalone servers")
+	cmd.Flags().StringVar(&outputFormat, "output-format", "text", "Output format: text or json")
+	return cmd
+}
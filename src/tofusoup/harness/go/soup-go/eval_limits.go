@@ -0,0 +1,308 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+)
+
+// Resource limits reported in resourceLimitError.Limit
+const (
+	limitEvalTime       = "max_eval_time"
+	limitCollectionSize = "max_collection_size"
+)
+
+// resourceLimitError reports an evaluation or conversion that exceeded
+// --max-eval-time or --max-collection-size
+type resourceLimitError struct {
+	Limit   string `json:"limit"`
+	Max     string `json:"max"`
+	Actual  string `json:"actual,omitempty"`
+	Subject string `json:"subject,omitempty"`
+	Path    string `json:"path,omitempty"`
+}
+
+func (e *resourceLimitError) Error() string {
+	msg := fmt.Sprintf("resource limit exceeded: %s", e.Limit)
+	if e.Subject != "" {
+		msg += " in " + e.Subject
+	}
+	if e.Path != "" {
+		msg += " at " + e.Path
+	}
+	if e.Actual != "" {
+		return fmt.Sprintf("%s (%s > %s)", msg, e.Actual, e.Max)
+	}
+	return fmt.Sprintf("%s (%s)", msg, e.Max)
+}
+
+// evalLimits bounds HCL expression evaluation and cty conversion so that
+// adversarial inputs fail with a resourceLimitError instead of exhausting
+// the harness. Zero values disable a limit.
+//
+// Collection sizes are checked on JSON input before it is decoded, on
+// arguments to range() before it allocates, and on every function result
+// and evaluated value. A for-expression's result can only be checked once
+// it has been built, so --max-eval-time is the guard against ones that
+// expand too far to finish.
+type evalLimits struct {
+	MaxEvalTime       time.Duration
+	MaxCollectionSize int
+
+	mu      sync.Mutex
+	tripped *resourceLimitError
+}
+
+// evalLimit is shared by the hcl and cty commands that evaluate or convert
+var evalLimit evalLimits
+
+// addEvalLimitFlags registers --max-eval-time and --max-collection-size on cmd
+func addEvalLimitFlags(cmd *cobra.Command, l *evalLimits) {
+	cmd.Flags().DurationVar(&l.MaxEvalTime, "max-eval-time", 0, "Fail with a resource-limit error if evaluation or conversion takes longer than this (0 is unlimited)")
+	cmd.Flags().IntVar(&l.MaxCollectionSize, "max-collection-size", 0, "Fail with a resource-limit error if any list, set, map, tuple or object has more elements than this (0 is unlimited)")
+}
+
+// trip records err as the first limit exceeded and returns it. Callers that
+// carry on past evaluation errors rely on run to surface it afterwards.
+func (l *evalLimits) trip(err *resourceLimitError) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.tripped == nil {
+		l.tripped = err
+	}
+	return err
+}
+
+// run calls fn, failing with a max_eval_time error if it takes longer than
+// --max-eval-time and with the first limit tripped while it ran otherwise.
+// On timeout fn is left running; commands exit straight after. Limit errors
+// are also printed to stdout as JSON, like RPC status errors.
+func (l *evalLimits) run(subject string, fn func() error) error {
+	err := l.runTimed(subject, fn)
+	var limitErr *resourceLimitError
+	if errors.As(err, &limitErr) {
+		printResourceLimitJSON(os.Stdout, limitErr)
+	}
+	return err
+}
+
+func (l *evalLimits) runTimed(subject string, fn func() error) error {
+	if l.MaxEvalTime <= 0 {
+		err := fn()
+		return l.firstErr(err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- fn() }()
+	select {
+	case err := <-done:
+		return l.firstErr(err)
+	case <-time.After(l.MaxEvalTime):
+		return l.trip(&resourceLimitError{Limit: limitEvalTime, Max: l.MaxEvalTime.String(), Subject: subject})
+	}
+}
+
+// firstErr prefers a tripped limit over err, which is often just a
+// consequence of it
+func (l *evalLimits) firstErr(err error) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.tripped != nil {
+		return l.tripped
+	}
+	return err
+}
+
+// printResourceLimitJSON writes err as {"error": {...}} so corpus runners
+// can tell a limit from a conformance failure
+func printResourceLimitJSON(w io.Writer, err *resourceLimitError) {
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":    "RESOURCE_LIMIT",
+			"message": err.Error(),
+			"details": err,
+		},
+	})
+}
+
+// tooLarge returns the error for a collection of n elements, if that's over
+// the limit
+func (l *evalLimits) tooLarge(subject, path string, n int64) error {
+	if l.MaxCollectionSize <= 0 || n <= int64(l.MaxCollectionSize) {
+		return nil
+	}
+	return l.trip(&resourceLimitError{
+		Limit:   limitCollectionSize,
+		Max:     fmt.Sprint(l.MaxCollectionSize),
+		Actual:  fmt.Sprint(n),
+		Subject: subject,
+		Path:    path,
+	})
+}
+
+// checkValue fails if val or any value nested in it is a collection, tuple
+// or object with too many elements
+func (l *evalLimits) checkValue(subject string, val cty.Value) error {
+	if l.MaxCollectionSize <= 0 {
+		return nil
+	}
+	val, _ = val.UnmarkDeep()
+	return cty.Walk(val, func(path cty.Path, v cty.Value) (bool, error) {
+		if !v.IsKnown() || v.IsNull() {
+			return false, nil
+		}
+		ty := v.Type()
+		var n int
+		switch {
+		case ty.IsObjectType():
+			n = len(ty.AttributeTypes())
+		case ty.IsCollectionType() || ty.IsTupleType():
+			n = v.LengthInt()
+		default:
+			return true, nil
+		}
+		if err := l.tooLarge(subject, formatCtyPath(path), int64(n)); err != nil {
+			return false, err
+		}
+		return true, nil
+	})
+}
+
+// checkJSON scans JSON input without decoding it and fails if any array or
+// object has too many elements
+func (l *evalLimits) checkJSON(subject string, data []byte) error {
+	if l.MaxCollectionSize <= 0 {
+		return nil
+	}
+	// Each open container counts the tokens seen directly inside it. Objects
+	// count keys and values, so their member count is half that.
+	type open struct {
+		n      int64
+		object bool
+	}
+	var stack []open
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			// Malformed input is left for the real decoder to report
+			return nil
+		}
+		if len(stack) > 0 {
+			top := &stack[len(stack)-1]
+			if delim, ok := tok.(json.Delim); !ok || delim == '[' || delim == '{' {
+				top.n++
+				n := top.n
+				if top.object {
+					n = (n + 1) / 2
+				}
+				if err := l.tooLarge(subject, fmt.Sprintf("depth %d", len(stack)), n); err != nil {
+					return err
+				}
+			}
+		}
+		if delim, ok := tok.(json.Delim); ok {
+			switch delim {
+			case '[', '{':
+				stack = append(stack, open{object: delim == '{'})
+			case ']', '}':
+				stack = stack[:len(stack)-1]
+			}
+		}
+	}
+}
+
+// functions wraps funcs so that range() refuses to build a list over the
+// limit and any function returning an oversized value fails
+func (l *evalLimits) functions(funcs map[string]function.Function) map[string]function.Function {
+	if l.MaxCollectionSize <= 0 {
+		return funcs
+	}
+	wrapped := make(map[string]function.Function, len(funcs))
+	for name, f := range funcs {
+		wrapped[name] = l.wrapFunction(name, f)
+	}
+	return wrapped
+}
+
+func (l *evalLimits) wrapFunction(name string, f function.Function) function.Function {
+	subject := name + "()"
+	spec := &function.Spec{
+		Description: f.Description(),
+		Params:      f.Params(),
+		VarParam:    f.VarParam(),
+		Type: func(args []cty.Value) (cty.Type, error) {
+			return f.ReturnTypeForValues(args)
+		},
+		Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+			if name == "range" {
+				if err := l.tooLarge(subject, "", rangeLength(args)); err != nil {
+					return cty.DynamicVal, err
+				}
+			}
+			val, err := f.Call(args)
+			if err != nil {
+				return val, err
+			}
+			if err := l.checkValue(subject, val); err != nil {
+				return cty.DynamicVal, err
+			}
+			return val, nil
+		},
+	}
+	return function.New(spec)
+}
+
+// rangeLength returns how many elements range(args...) would produce, or 0
+// if the arguments are unusable (range reports those itself)
+func rangeLength(args []cty.Value) int64 {
+	nums := make([]*big.Float, len(args))
+	for i, arg := range args {
+		arg, _ = arg.Unmark()
+		if !arg.IsKnown() || arg.IsNull() || arg.Type() != cty.Number {
+			return 0
+		}
+		nums[i] = arg.AsBigFloat()
+	}
+	start, step := big.NewFloat(0), big.NewFloat(1)
+	var limit *big.Float
+	switch len(nums) {
+	case 1:
+		limit = nums[0]
+	case 2:
+		start, limit = nums[0], nums[1]
+		if limit.Cmp(start) < 0 {
+			step = big.NewFloat(-1)
+		}
+	case 3:
+		start, limit, step = nums[0], nums[1], nums[2]
+	default:
+		return 0
+	}
+	if step.Sign() == 0 {
+		return 0
+	}
+	span := new(big.Float).Sub(limit, start)
+	if span.Sign() != step.Sign() {
+		return 0
+	}
+	count, acc := new(big.Float).Quo(span, step).Int64()
+	if acc != big.Exact {
+		count++
+	}
+	return count
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// featuresEnv enables features like --enable-feature, so a runner can set
+// them once for every soup-go it starts, plugin servers included
+const featuresEnv = "SOUP_GO_FEATURES"
+
+// Feature stages
+const (
+	featureExperimental = "experimental"
+)
+
+// featureFlag is an experimental behavior that stays off unless enabled.
+// Other harnesses read these from features list --json to find out which
+// experimental surfaces this one has, rather than guessing from its version.
+type featureFlag struct {
+	Name        string `json:"name"`
+	Subsystem   string `json:"subsystem"`
+	Stage       string `json:"stage"`
+	Description string `json:"description"`
+	Enabled     bool   `json:"enabled"`
+}
+
+// features is every feature this harness knows, keyed by name
+var features = map[string]*featureFlag{
+	"wire-v2": {
+		Name:        "wire-v2",
+		Subsystem:   "wire",
+		Stage:       featureExperimental,
+		Description: "wire encode --wire-version 2 (msgpack with a versioned header); decoding v2 is always on",
+	},
+	"hcl-schema": {
+		Name:        "hcl-schema",
+		Subsystem:   "hcl",
+		Stage:       featureExperimental,
+		Description: "hcl schema infer and hcl decode --spec; the spec format may change",
+	},
+}
+
+// enabledFeatureNames holds --enable-feature
+var enabledFeatureNames []string
+
+// applyFeatures enables the features named by --enable-feature and
+// SOUP_GO_FEATURES, failing on names it doesn't know
+func applyFeatures() error {
+	names := append([]string{}, enabledFeatureNames...)
+	if env := os.Getenv(featuresEnv); env != "" {
+		names = append(names, strings.Split(env, ",")...)
+	}
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		f, ok := features[name]
+		if !ok {
+			return fmt.Errorf("unknown feature %q (known: %s)", name, strings.Join(featureNames(), ", "))
+		}
+		f.Enabled = true
+	}
+	return nil
+}
+
+func featureNames() []string {
+	names := make([]string, 0, len(features))
+	for name := range features {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// requireFeature fails unless the named feature is enabled. what names
+// the behavior it gates, for the error message.
+func requireFeature(name, what string) error {
+	if features[name].Enabled {
+		return nil
+	}
+	return fmt.Errorf("%s is experimental; enable it with --enable-feature %s or %s=%s",
+		what, name, featuresEnv, name)
+}
+
+// enabledFeatures lists the names of the enabled features
+func enabledFeatures() []string {
+	enabled := []string{}
+	for _, name := range featureNames() {
+		if features[name].Enabled {
+			enabled = append(enabled, name)
+		}
+	}
+	return enabled
+}
+
+func initFeaturesCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "features",
+		Short: "Experimental feature flags",
+	}
+	cmd.AddCommand(initFeaturesListCmd())
+	return cmd
+}
+
+func initFeaturesListCmd() *cobra.Command {
+	var outputJSON bool
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List experimental features and whether each is enabled",
+		Long: `List the experimental features this harness has, with the subsystem each
+belongs to and whether it's enabled. Features are off unless enabled with
+--enable-feature (comma-separated or repeated) or SOUP_GO_FEATURES.
+
+With --json the list is printed as {"features": [...]}, for other harnesses
+and runners to detect and align on experimental behavior.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			list := make([]*featureFlag, 0, len(features))
+			for _, name := range featureNames() {
+				list = append(list, features[name])
+			}
+
+			if outputJSON {
+				if err := json.NewEncoder(os.Stdout).Encode(map[string]interface{}{"features": list}); err != nil {
+					return fmt.Errorf("failed to encode JSON: %w", err)
+				}
+				return nil
+			}
+			for _, f := range list {
+				state := "off"
+				if f.Enabled {
+					state = "on"
+				}
+				fmt.Printf("%-12s %-4s %-6s %s\n", f.Name, state, f.Subsystem, f.Description)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&outputJSON, "json", false, "Output in JSON format")
+
+	return cmd
+}
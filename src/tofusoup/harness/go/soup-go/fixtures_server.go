@@ -0,0 +1,314 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Fixture file server
+//
+// Scenarios whose inputs are fetched over HTTP, such as remote modules or a
+// corpus for 'harness corpus sync', get them from a directory served on
+// loopback by this process instead of the network. Every request is
+// logged, so the report shows what a run actually fetched.
+
+// fixturesURLEnv passes the fixture server's base URL to servers a
+// scenario starts
+const fixturesURLEnv = "SOUP_FIXTURES_URL"
+
+// fixtureAccess is one request to the fixture server
+type fixtureAccess struct {
+	// AtMs is when the request arrived, from the start of serving
+	AtMs       float64 `json:"at_ms"`
+	Method     string  `json:"method"`
+	Path       string  `json:"path"`
+	Range      string  `json:"range,omitempty"`
+	Status     int     `json:"status"`
+	Bytes      int64   `json:"bytes"`
+	DurationMs float64 `json:"duration_ms"`
+	Remote     string  `json:"remote"`
+}
+
+// fixturesReport is the fixture server's access log and totals
+type fixturesReport struct {
+	Dir         string          `json:"dir"`
+	URL         string          `json:"url"`
+	DurationMs  float64         `json:"duration_ms"`
+	Requests    int             `json:"requests"`
+	NotFound    int             `json:"not_found"`
+	BytesServed int64           `json:"bytes_served"`
+	Access      []fixtureAccess `json:"access"`
+}
+
+// fixturesServer serves a directory read-only and records every request
+type fixturesServer struct {
+	URL string
+
+	dir      string
+	server   *http.Server
+	listener net.Listener
+	start    time.Time
+	// accessLog, if set, gets each request as an NDJSON line as it is
+	// served
+	accessLog io.Writer
+
+	mu     sync.Mutex
+	access []fixtureAccess
+
+	stopOnce sync.Once
+	report   *fixturesReport
+}
+
+// accessRecorder captures the status and size of a response
+type accessRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (r *accessRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *accessRecorder) Write(p []byte) (int, error) {
+	n, err := r.ResponseWriter.Write(p)
+	r.bytes += int64(n)
+	return n, err
+}
+
+// startFixturesServer serves dir on addr. An unspecified host in addr
+// still listens on every interface, but the URL uses loopback.
+func startFixturesServer(dir, addr string, accessLog io.Writer) (*fixturesServer, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("fixtures directory: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("fixtures directory: %s is not a directory", dir)
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	tcpAddr := listener.Addr().(*net.TCPAddr)
+	host := tcpAddr.IP.String()
+	if tcpAddr.IP.IsUnspecified() {
+		host = "127.0.0.1"
+	}
+
+	s := &fixturesServer{
+		URL:       fmt.Sprintf("http://%s/", net.JoinHostPort(host, fmt.Sprint(tcpAddr.Port))),
+		dir:       dir,
+		listener:  listener,
+		start:     time.Now(),
+		accessLog: accessLog,
+		access:    []fixtureAccess{},
+	}
+	files := http.FileServer(http.Dir(dir))
+	s.server = &http.Server{
+		ReadHeaderTimeout: 10 * time.Second,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			arrived := time.Now()
+			rec := &accessRecorder{ResponseWriter: w, status: http.StatusOK}
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				http.Error(rec, "fixtures are read-only", http.StatusMethodNotAllowed)
+			} else {
+				files.ServeHTTP(rec, r)
+			}
+			s.record(fixtureAccess{
+				AtMs:       float64(arrived.Sub(s.start).Microseconds()) / 1000,
+				Method:     r.Method,
+				Path:       r.URL.Path,
+				Range:      r.Header.Get("Range"),
+				Status:     rec.status,
+				Bytes:      rec.bytes,
+				DurationMs: sinceMs(arrived),
+				Remote:     r.RemoteAddr,
+			})
+		}),
+	}
+	go func() {
+		if err := s.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("fixture server stopped", "error", err)
+		}
+	}()
+	logger.Info("📦 serving fixtures", "dir", dir, "url", s.URL)
+	return s, nil
+}
+
+func (s *fixturesServer) record(a fixtureAccess) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.access = append(s.access, a)
+	if s.accessLog != nil {
+		if line, err := json.Marshal(a); err == nil {
+			s.accessLog.Write(append(line, '\n'))
+		}
+	}
+	logger.Debug("fixture request", "method", a.Method, "path", a.Path, "status", a.Status, "bytes", a.Bytes)
+}
+
+// stop shuts the server down, letting requests in flight finish, and
+// returns its report. Later calls return the same report.
+func (s *fixturesServer) stop() *fixturesReport {
+	s.stopOnce.Do(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := s.server.Shutdown(ctx); err != nil {
+			s.server.Close()
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		report := &fixturesReport{
+			Dir:        s.dir,
+			URL:        s.URL,
+			DurationMs: sinceMs(s.start),
+			Requests:   len(s.access),
+			Access:     s.access,
+		}
+		for _, a := range s.access {
+			report.BytesServed += a.Bytes
+			if a.Status == http.StatusNotFound {
+				report.NotFound++
+			}
+		}
+		s.report = report
+	})
+	return s.report
+}
+
+// startScenarioFixtures serves dir for a scenario and adds its URL to env.
+// It returns nil with env unchanged when dir is empty.
+func startScenarioFixtures(dir string, env []string) (*fixturesServer, []string, error) {
+	if dir == "" {
+		return nil, env, nil
+	}
+	fixtures, err := startFixturesServer(dir, "127.0.0.1:0", nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return fixtures, append(env, fixturesURLEnv+"="+fixtures.URL), nil
+}
+
+// fixturesSummary is a one-line account of what the fixture server served
+func fixturesSummary(r *fixturesReport) string {
+	return fmt.Sprintf("Fixtures: %d requests, %d not found, %d bytes served from %s",
+		r.Requests, r.NotFound, r.BytesServed, r.Dir)
+}
+
+func addFixturesDirFlag(cmd *cobra.Command, dir *string) {
+	cmd.Flags().StringVar(dir, "fixtures-dir", "", "Serve this directory over HTTP on loopback for the run, passing its URL to servers as "+fixturesURLEnv+"; the access log is added to the report")
+}
+
+func initHarnessFixturesCmd() *cobra.Command {
+	var dir string
+	var addr string
+	var duration time.Duration
+	var accessLogPath string
+	var urlFile string
+	var outputFormat string
+
+	serve := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve a fixtures directory over HTTP for hermetic scenarios",
+		Long: `Serve --dir read-only over HTTP until interrupted or --duration has passed,
+so inputs a scenario fetches over HTTP, such as remote modules or a fixture
+corpus, come from this process rather than the network. Byte ranges are
+supported, so interrupted downloads can be resumed against it.
+
+The base URL is printed on startup with text output, and written to
+--url-file if given; with --output-format json, stdout holds only the
+report, so read the URL from --url-file. With --addr :0 a free port is
+chosen. Each request is logged with its method, path, range,
+status, size and duration: to --access-log as NDJSON while serving, and in
+the report printed on exit.
+
+'harness scenario' commands take --fixtures-dir to run the same server for
+the length of the scenario and include its access log in their report.`,
+		Example: `  soup-go harness fixtures serve --dir fixtures/ --addr :0
+  soup-go harness fixtures serve --dir fixtures/ --url-file url.txt --access-log access.ndjson --output-format json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if dir == "" {
+				return fmt.Errorf("--dir is required")
+			}
+			if outputFormat != "text" && outputFormat != "json" {
+				return fmt.Errorf("unsupported output format: %s", outputFormat)
+			}
+			var accessLog io.Writer
+			if accessLogPath != "" {
+				f, err := os.Create(accessLogPath)
+				if err != nil {
+					return fmt.Errorf("failed to create access log: %w", err)
+				}
+				defer f.Close()
+				accessLog = f
+			}
+
+			fixtures, err := startFixturesServer(dir, addr, accessLog)
+			if err != nil {
+				return err
+			}
+			if urlFile != "" {
+				if err := os.WriteFile(urlFile, []byte(fixtures.URL+"\n"), 0644); err != nil {
+					fixtures.stop()
+					return fmt.Errorf("failed to write --url-file: %w", err)
+				}
+			}
+			if outputFormat == "text" {
+				fmt.Printf("Serving %s at %s\n", dir, fixtures.URL)
+			}
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			if duration > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, duration)
+				defer cancel()
+			}
+			<-ctx.Done()
+
+			report := fixtures.stop()
+			if outputFormat == "json" {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				if err := encoder.Encode(report); err != nil {
+					return fmt.Errorf("failed to encode JSON: %w", err)
+				}
+				return nil
+			}
+			for _, a := range report.Access {
+				fmt.Printf("  %8.1fms %-4s %3d %9d %s\n", a.AtMs, a.Method, a.Status, a.Bytes, a.Path)
+			}
+			fmt.Println(fixturesSummary(report))
+			return nil
+		},
+	}
+	serve.Flags().StringVar(&dir, "dir", "", "Directory to serve")
+	serve.Flags().StringVar(&addr, "addr", "127.0.0.1:0", "Address to listen on (:0 picks a free port)")
+	serve.Flags().DurationVar(&duration, "duration", 0, "Stop serving after this long (0 serves until interrupted)")
+	serve.Flags().StringVar(&accessLogPath, "access-log", "", "Write each request to this file as NDJSON while serving")
+	serve.Flags().StringVar(&urlFile, "url-file", "", "Write the base URL to this file once listening")
+	serve.Flags().StringVar(&outputFormat, "output-format", "text", "Output format (text, json)")
+
+	cmd := &cobra.Command{
+		Use:   "fixtures",
+		Short: "Serve fixtures to scenarios without the network",
+	}
+	cmd.AddCommand(serve)
+	return cmd
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// TestNoSubcommandShadowsGlobalFlags checks that no subcommand defines a
+// flag of its own under the name of a global flag, which would make
+// --trace, say, mean something different on one command than on all the
+// others
+func TestNoSubcommandShadowsGlobalFlags(t *testing.T) {
+	var walk func(cmd *cobra.Command)
+	walk = func(cmd *cobra.Command) {
+		cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+			if global := rootCmd.PersistentFlags().Lookup(flag.Name); global != nil && global != flag {
+				t.Errorf("%q defines --%s, shadowing the global flag", cmd.CommandPath(), flag.Name)
+			}
+		})
+		for _, sub := range cmd.Commands() {
+			walk(sub)
+		}
+	}
+	for _, sub := range rootCmd.Commands() {
+		walk(sub)
+	}
+}
+
+func TestCtyConvertTraceStepsFlag(t *testing.T) {
+	cmd, _, err := rootCmd.Find([]string{"cty", "convert"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cmd.Flags().Lookup("trace-steps") == nil {
+		t.Error("cty convert has no --trace-steps flag")
+	}
+	if cmd.LocalFlags().Lookup("trace") != nil {
+		t.Error("cty convert defines a local --trace")
+	}
+}
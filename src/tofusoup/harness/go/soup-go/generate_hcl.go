@@ -0,0 +1,317 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/spf13/cobra"
+)
+
+// hclExprKinds are the expression kinds --exprs can enable. Literals are
+// always generated.
+var hclExprKinds = []string{"for", "conditional", "heredoc", "template"}
+
+// hclGenBlockTypes are the block types generate hcl picks from, with their
+// label counts
+var hclGenBlockTypes = []struct {
+	Name   string
+	Labels int
+}{
+	{"resource", 2},
+	{"data", 2},
+	{"module", 1},
+	{"settings", 0},
+}
+
+// hclGenStats are the parse statistics every harness should report for a
+// generated configuration. Expressions counts each attribute's top-level
+// expression by kind.
+type hclGenStats struct {
+	Blocks         int            `json:"blocks"`
+	TopLevelBlocks int            `json:"top_level_blocks"`
+	MaxDepth       int            `json:"max_depth"`
+	BlocksByDepth  []int          `json:"blocks_by_depth"`
+	Labels         int            `json:"labels"`
+	Attributes     int            `json:"attributes"`
+	Expressions    map[string]int `json:"expressions"`
+}
+
+func newHCLGenStats(kinds []string) hclGenStats {
+	stats := hclGenStats{BlocksByDepth: []int{}, Expressions: map[string]int{}}
+	for _, kind := range kinds {
+		stats.Expressions[kind] = 0
+	}
+	return stats
+}
+
+// countBlock records a block at depth (1 for top-level blocks)
+func (s *hclGenStats) countBlock(depth, labels int) {
+	s.Blocks++
+	s.Labels += labels
+	if depth == 1 {
+		s.TopLevelBlocks++
+	}
+	for len(s.BlocksByDepth) < depth {
+		s.BlocksByDepth = append(s.BlocksByDepth, 0)
+	}
+	s.BlocksByDepth[depth-1]++
+	s.MaxDepth = max(s.MaxDepth, depth)
+}
+
+// hclGenManifest describes a generated configuration and what parsing it
+// must yield
+type hclGenManifest struct {
+	Seed   int64       `json:"seed"`
+	Blocks int         `json:"blocks"`
+	Depth  int         `json:"depth"`
+	Exprs  []string    `json:"exprs"`
+	File   string      `json:"file,omitempty"`
+	SHA256 string      `json:"sha256"`
+	Bytes  int         `json:"bytes"`
+	Lines  int         `json:"lines"`
+	Stats  hclGenStats `json:"stats"`
+}
+
+// hclGenerator writes a random configuration. Every expression is
+// self-contained, so the output evaluates with an empty context too.
+type hclGenerator struct {
+	rng   *rand.Rand
+	depth int
+	kinds []string
+	buf   bytes.Buffer
+	stats hclGenStats
+}
+
+// block writes one block at depth. The first top-level block descends to
+// --depth so every configuration reaches it; the rest stop at random.
+func (g *hclGenerator) block(depth int, descend bool) {
+	bt := hclGenBlockTypes[g.rng.Intn(len(hclGenBlockTypes))]
+	g.stats.countBlock(depth, bt.Labels)
+
+	indent := strings.Repeat("  ", depth-1)
+	g.buf.WriteString(indent + bt.Name)
+	for i := 0; i < bt.Labels; i++ {
+		fmt.Fprintf(&g.buf, " \"%s_%d\"", bt.Name, g.rng.Intn(1000))
+	}
+	g.buf.WriteString(" {\n")
+
+	for i, n := 0, 1+g.rng.Intn(4); i < n; i++ {
+		fmt.Fprintf(&g.buf, "%s  attr_%d = ", indent, i)
+		g.expr(indent + "  ")
+		g.buf.WriteString("\n")
+	}
+
+	if depth < g.depth {
+		children := g.rng.Intn(3)
+		if descend {
+			children = max(children, 1)
+		}
+		for i := 0; i < children; i++ {
+			g.buf.WriteString("\n")
+			g.block(depth+1, descend && i == 0)
+		}
+	}
+	g.buf.WriteString(indent + "}\n")
+}
+
+// expr writes one attribute value of a random enabled kind
+func (g *hclGenerator) expr(indent string) {
+	kind := g.kinds[g.rng.Intn(len(g.kinds))]
+	g.stats.Attributes++
+	g.stats.Expressions[kind]++
+
+	n, m := g.rng.Intn(100), g.rng.Intn(100)
+	switch kind {
+	case "for":
+		if g.rng.Intn(2) == 0 {
+			fmt.Fprintf(&g.buf, "[for i, v in [1, 2, 3] : v * %d if v > %d]", n, m%3)
+		} else {
+			fmt.Fprintf(&g.buf, "{ for k, v in { a = 1, b = 2 } : k => v + %d }", n)
+		}
+	case "conditional":
+		fmt.Fprintf(&g.buf, "%d > %d ? \"yes_%d\" : \"no_%d\"", n, m, n, m)
+	case "heredoc":
+		fmt.Fprintf(&g.buf, "<<-EOT\n%s  line %d\n%s  line %d\n%sEOT", indent, n, indent, m, indent)
+	case "template":
+		fmt.Fprintf(&g.buf, "\"prefix-${%d + %d}-suffix\"", n, m)
+	default:
+		switch g.rng.Intn(4) {
+		case 0:
+			fmt.Fprintf(&g.buf, "%d", n)
+		case 1:
+			fmt.Fprintf(&g.buf, "%t", n%2 == 0)
+		case 2:
+			fmt.Fprintf(&g.buf, "\"value_%d\"", n)
+		default:
+			fmt.Fprintf(&g.buf, "[%d, %d]", n, m)
+		}
+	}
+}
+
+// generateHCL returns a configuration of topLevel blocks nested up to depth
+// and its statistics
+func generateHCL(topLevel, depth int, exprs []string, seed int64) ([]byte, hclGenStats) {
+	kinds := append([]string{"literal"}, exprs...)
+	g := &hclGenerator{rng: rand.New(rand.NewSource(seed)), depth: depth, kinds: kinds, stats: newHCLGenStats(kinds)}
+	for i := 0; i < topLevel; i++ {
+		if i > 0 {
+			g.buf.WriteString("\n")
+		}
+		g.block(1, i == 0)
+	}
+	return hclwrite.Format(g.buf.Bytes()), g.stats
+}
+
+// classifyHCLExpr names the kind of an attribute's top-level expression
+func classifyHCLExpr(expr hclsyntax.Expression, src []byte) string {
+	switch e := expr.(type) {
+	case *hclsyntax.ForExpr:
+		return "for"
+	case *hclsyntax.ConditionalExpr:
+		return "conditional"
+	case *hclsyntax.TemplateExpr:
+		if bytes.HasPrefix(src[e.SrcRange.Start.Byte:], []byte("<<")) {
+			return "heredoc"
+		}
+		if e.IsStringLiteral() {
+			return "literal"
+		}
+		return "template"
+	case *hclsyntax.TemplateWrapExpr:
+		return "template"
+	default:
+		return "literal"
+	}
+}
+
+// parseHCLStats parses src and counts what generate hcl promises in its
+// manifest
+func parseHCLStats(src []byte, kinds []string) (hclGenStats, error) {
+	file, diags := hclsyntax.ParseConfig(src, "generated.hcl", hcl.InitialPos)
+	if diags.HasErrors() {
+		return hclGenStats{}, fmt.Errorf("generated configuration doesn't parse: %s", diags.Error())
+	}
+	stats := newHCLGenStats(kinds)
+	var walk func(body *hclsyntax.Body, depth int)
+	walk = func(body *hclsyntax.Body, depth int) {
+		for _, attr := range body.Attributes {
+			stats.Attributes++
+			stats.Expressions[classifyHCLExpr(attr.Expr, src)]++
+		}
+		for _, block := range body.Blocks {
+			stats.countBlock(depth, len(block.Labels))
+			walk(block.Body, depth+1)
+		}
+	}
+	walk(file.Body.(*hclsyntax.Body), 1)
+	return stats, nil
+}
+
+func initGenerateHCLCmd() *cobra.Command {
+	var blocks int
+	var depth int
+	var exprs []string
+	var seed int64
+	var outputPath string
+	var manifestPath string
+
+	cmd := &cobra.Command{
+		Use:   "hcl",
+		Short: "Generate a random HCL configuration with a manifest of its parse statistics",
+		Long: `Generate a syntactically valid random HCL configuration for stress-testing
+parsers. The same --seed and knobs always produce the same bytes, so every
+harness can parse identical input and compare what it finds with the
+manifest: block, label and attribute counts, blocks per nesting depth, and
+each attribute's top-level expression kind.
+
+The configuration is re-parsed before it is written and the command fails
+if the parse disagrees with the manifest.
+
+With --output the manifest is written next to it as <output>.manifest.json
+unless --manifest says otherwise; on stdout only --manifest writes one.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			switch {
+			case blocks < 0:
+				return fmt.Errorf("--blocks must not be negative")
+			case depth < 1:
+				return fmt.Errorf("--depth must be at least 1")
+			}
+			for _, kind := range exprs {
+				if !slices.Contains(hclExprKinds, kind) {
+					return fmt.Errorf("unknown --exprs kind %q: use %s", kind, strings.Join(hclExprKinds, ", "))
+				}
+			}
+			exprs = slices.Compact(slices.Sorted(slices.Values(exprs)))
+
+			src, stats := generateHCL(blocks, depth, exprs, seed)
+			parsed, err := parseHCLStats(src, append([]string{"literal"}, exprs...))
+			if err != nil {
+				return err
+			}
+			if !reflect.DeepEqual(parsed, stats) {
+				return fmt.Errorf("generated configuration parses to %+v, not the generated %+v", parsed, stats)
+			}
+
+			manifest := hclGenManifest{
+				Seed:   seed,
+				Blocks: blocks,
+				Depth:  depth,
+				Exprs:  exprs,
+				SHA256: sha256Hex(src),
+				Bytes:  len(src),
+				Lines:  bytes.Count(src, []byte("\n")),
+				Stats:  stats,
+			}
+
+			if outputPath == "" {
+				if _, err := os.Stdout.Write(src); err != nil {
+					return fmt.Errorf("failed to write output: %w", err)
+				}
+			} else {
+				if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+					return fmt.Errorf("failed to create output directory: %w", err)
+				}
+				if err := os.WriteFile(outputPath, src, 0644); err != nil {
+					return fmt.Errorf("failed to write configuration: %w", err)
+				}
+				manifest.File = filepath.Base(outputPath)
+				if manifestPath == "" {
+					manifestPath = outputPath + ".manifest.json"
+				}
+			}
+			if manifestPath == "" {
+				return nil
+			}
+
+			data, err := json.MarshalIndent(manifest, "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to encode manifest: %w", err)
+			}
+			if err := os.WriteFile(manifestPath, append(data, '\n'), 0644); err != nil {
+				return fmt.Errorf("failed to write manifest: %w", err)
+			}
+			logger.Debug("🎲 generated HCL", "blocks", stats.Blocks, "attributes", stats.Attributes, "bytes", len(src))
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVar(&blocks, "blocks", 10, "Number of top-level blocks")
+	cmd.Flags().IntVar(&depth, "depth", 2, "Maximum block nesting depth (the first block always reaches it)")
+	cmd.Flags().StringSliceVar(&exprs, "exprs", hclExprKinds, "Expression kinds to mix with literals: "+strings.Join(hclExprKinds, ","))
+	cmd.Flags().Int64Var(&seed, "seed", 1, "Random seed for deterministic generation")
+	cmd.Flags().StringVarP(&outputPath, "output", "o", "", "Write the configuration to this file instead of stdout")
+	cmd.Flags().StringVar(&manifestPath, "manifest", "", "Write the manifest here (default <output>.manifest.json)")
+
+	return cmd
+}
@@ -0,0 +1,423 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/spf13/cobra"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// hclOperator is an HCL operator and the name vectors use for it
+type hclOperator struct {
+	Name   string
+	Symbol string
+	op     *hclsyntax.Operation
+}
+
+// hclBinaryOperators are HCL's binary operators, lowest precedence first
+var hclBinaryOperators = []hclOperator{
+	{"or", "||", hclsyntax.OpLogicalOr},
+	{"and", "&&", hclsyntax.OpLogicalAnd},
+	{"eq", "==", hclsyntax.OpEqual},
+	{"ne", "!=", hclsyntax.OpNotEqual},
+	{"lt", "<", hclsyntax.OpLessThan},
+	{"le", "<=", hclsyntax.OpLessThanOrEqual},
+	{"gt", ">", hclsyntax.OpGreaterThan},
+	{"ge", ">=", hclsyntax.OpGreaterThanOrEqual},
+	{"add", "+", hclsyntax.OpAdd},
+	{"sub", "-", hclsyntax.OpSubtract},
+	{"mul", "*", hclsyntax.OpMultiply},
+	{"div", "/", hclsyntax.OpDivide},
+	{"mod", "%", hclsyntax.OpModulo},
+}
+
+// hclUnaryOperators are HCL's prefix operators
+var hclUnaryOperators = []hclOperator{
+	{"not", "!", hclsyntax.OpLogicalNot},
+	{"neg", "-", hclsyntax.OpNegate},
+}
+
+// hclOperandPool is what operands are picked from, in order of preference.
+// Powers of two keep quotients exact.
+var hclOperandPool = []string{"8", "4", "2", "true", "false"}
+
+// Shapes of operator vector, each an expression with two ways to group it
+const (
+	// hclShapeBinary is a op1 b op2 c, grouped left or right
+	hclShapeBinary = "binary"
+	// hclShapeUnary is -a op b or !a op b, with the prefix operator
+	// applied to a alone or to the whole binary expression
+	hclShapeUnary = "unary"
+	// hclShapeCondition is a op b ? c : d, with the binary expression as
+	// the condition or as a ? c : d's right operand
+	hclShapeCondition = "condition"
+	// hclShapeElse is a ? b : c op d, with the binary expression as the
+	// false result or the conditional as its left operand
+	hclShapeElse = "else"
+)
+
+var hclOperatorShapes = []string{hclShapeBinary, hclShapeUnary, hclShapeCondition, hclShapeElse}
+
+// hclOperatorTemplate is an expression with @ for each operand, and the
+// two ways to group it written with explicit parentheses
+type hclOperatorTemplate struct {
+	Name      string
+	Shape     string
+	Operators []string
+	Expr      string
+	Groupings [2]hclOperatorGrouping
+}
+
+type hclOperatorGrouping struct {
+	Name string
+	Expr string
+}
+
+// hclOperatorOutcome is the Go evaluation of one expression
+type hclOperatorOutcome struct {
+	Expr string `json:"expr"`
+	// Parsed is the expression as hclsyntax parsed it, with every
+	// operation in parentheses
+	Parsed string          `json:"parsed"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Type   json.RawMessage `json:"type,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// hclOperatorVariant is one way to group a vector's expression
+type hclOperatorVariant struct {
+	Grouping string `json:"grouping"`
+	hclOperatorOutcome
+}
+
+// hclOperatorVector is an operator combination and its Go results.
+// Grouping names the variant Expr parses as; Discriminating is whether the
+// other variant gives a different result or an error, so a harness that
+// groups the expression wrongly fails the vector.
+type hclOperatorVector struct {
+	Name      string   `json:"name"`
+	Shape     string   `json:"shape"`
+	Operators []string `json:"operators"`
+	hclOperatorOutcome
+	Grouping       string               `json:"grouping"`
+	Discriminating bool                 `json:"discriminating"`
+	Variants       []hclOperatorVariant `json:"variants"`
+}
+
+// hclOperatorFile is the document generate hcl-operators writes
+type hclOperatorFile struct {
+	Generator  string              `json:"generator"`
+	GoVersion  string              `json:"go_version"`
+	HCLVersion string              `json:"hcl_version,omitempty"`
+	Vectors    []hclOperatorVector `json:"vectors"`
+}
+
+// hclOperatorTemplates returns the template of every operator combination
+// of the given shapes
+func hclOperatorTemplates(shapes []string) []hclOperatorTemplate {
+	var templates []hclOperatorTemplate
+	if slices.Contains(shapes, hclShapeBinary) {
+		for _, o1 := range hclBinaryOperators {
+			for _, o2 := range hclBinaryOperators {
+				templates = append(templates, hclOperatorTemplate{
+					Name:      o1.Name + "-" + o2.Name,
+					Shape:     hclShapeBinary,
+					Operators: []string{o1.Symbol, o2.Symbol},
+					Expr:      "@ " + o1.Symbol + " @ " + o2.Symbol + " @",
+					Groupings: [2]hclOperatorGrouping{
+						{"left", "(@ " + o1.Symbol + " @) " + o2.Symbol + " @"},
+						{"right", "@ " + o1.Symbol + " (@ " + o2.Symbol + " @)"},
+					},
+				})
+			}
+		}
+	}
+	if slices.Contains(shapes, hclShapeUnary) {
+		for _, u := range hclUnaryOperators {
+			for _, o := range hclBinaryOperators {
+				templates = append(templates, hclOperatorTemplate{
+					Name:      u.Name + "-" + o.Name,
+					Shape:     hclShapeUnary,
+					Operators: []string{u.Symbol, o.Symbol},
+					Expr:      u.Symbol + "@ " + o.Symbol + " @",
+					Groupings: [2]hclOperatorGrouping{
+						{"unary-first", "(" + u.Symbol + "@) " + o.Symbol + " @"},
+						{"binary-first", u.Symbol + "(@ " + o.Symbol + " @)"},
+					},
+				})
+			}
+		}
+	}
+	if slices.Contains(shapes, hclShapeCondition) {
+		for _, o := range hclBinaryOperators {
+			templates = append(templates, hclOperatorTemplate{
+				Name:      "cond-if-" + o.Name,
+				Shape:     hclShapeCondition,
+				Operators: []string{o.Symbol, "?:"},
+				Expr:      "@ " + o.Symbol + " @ ? @ : @",
+				Groupings: [2]hclOperatorGrouping{
+					{"binary-first", "(@ " + o.Symbol + " @) ? @ : @"},
+					{"conditional-first", "@ " + o.Symbol + " (@ ? @ : @)"},
+				},
+			})
+		}
+	}
+	if slices.Contains(shapes, hclShapeElse) {
+		for _, o := range hclBinaryOperators {
+			templates = append(templates, hclOperatorTemplate{
+				Name:      "cond-else-" + o.Name,
+				Shape:     hclShapeElse,
+				Operators: []string{"?:", o.Symbol},
+				Expr:      "@ ? @ : @ " + o.Symbol + " @",
+				Groupings: [2]hclOperatorGrouping{
+					{"binary-first", "@ ? @ : (@ " + o.Symbol + " @)"},
+					{"conditional-first", "(@ ? @ : @) " + o.Symbol + " @"},
+				},
+			})
+		}
+	}
+	return templates
+}
+
+// hclOperatorSymbol names a parsed operation
+func hclOperatorSymbol(op *hclsyntax.Operation) string {
+	for _, o := range append(slices.Clone(hclBinaryOperators), hclUnaryOperators...) {
+		if o.op == op {
+			return o.Symbol
+		}
+	}
+	return "?"
+}
+
+// parenthesizeHCL renders a parsed operator expression with every
+// operation in parentheses, so two parsers' groupings can be compared
+func parenthesizeHCL(expr hclsyntax.Expression) string {
+	switch e := expr.(type) {
+	case *hclsyntax.ParenthesesExpr:
+		return parenthesizeHCL(e.Expression)
+	case *hclsyntax.BinaryOpExpr:
+		return "(" + parenthesizeHCL(e.LHS) + " " + hclOperatorSymbol(e.Op) + " " + parenthesizeHCL(e.RHS) + ")"
+	case *hclsyntax.UnaryOpExpr:
+		return "(" + hclOperatorSymbol(e.Op) + parenthesizeHCL(e.Val) + ")"
+	case *hclsyntax.ConditionalExpr:
+		return "(" + parenthesizeHCL(e.Condition) + " ? " + parenthesizeHCL(e.TrueResult) + " : " + parenthesizeHCL(e.FalseResult) + ")"
+	case *hclsyntax.LiteralValueExpr:
+		switch {
+		case e.Val.Type() == cty.Number:
+			// Shortest-form Text is slow at cty's precision, and the
+			// operands are all integers
+			if n := e.Val.AsBigFloat(); n.IsInt() {
+				i, _ := n.Int(nil)
+				return i.String()
+			}
+			return e.Val.AsBigFloat().Text('f', -1)
+		case e.Val.Type() == cty.Bool:
+			return strconv.FormatBool(e.Val.True())
+		}
+	}
+	return fmt.Sprintf("<%T>", expr)
+}
+
+// evalHCLOperatorExpr parses and evaluates src with no variables
+func evalHCLOperatorExpr(src string) hclOperatorOutcome {
+	outcome := hclOperatorOutcome{Expr: src}
+	expr, diags := hclsyntax.ParseExpression([]byte(src), "operators.hcl", hcl.InitialPos)
+	if diags.HasErrors() {
+		outcome.Error = diags.Error()
+		return outcome
+	}
+	outcome.Parsed = parenthesizeHCL(expr)
+	val, diags := expr.Value(nil)
+	if diags.HasErrors() {
+		outcome.Error = diags.Error()
+		return outcome
+	}
+	var err error
+	if outcome.Type, err = ctyjson.MarshalType(val.Type()); err == nil {
+		outcome.Result, err = ctyjson.Marshal(val, val.Type())
+	}
+	if err != nil {
+		outcome.Type, outcome.Error = nil, err.Error()
+	}
+	return outcome
+}
+
+// fillHCLOperands substitutes operands for a template's @ slots in order
+func fillHCLOperands(template string, operands []string) string {
+	for _, operand := range operands {
+		template = strings.Replace(template, "@", operand, 1)
+	}
+	return template
+}
+
+// buildHCLOperatorVector picks operands from hclOperandPool for t. Best
+// are operands for which both groupings evaluate and differ; then ones for
+// which only the parsed grouping evaluates, or only the other; then ones
+// for which both evaluate alike. Some combinations, such as a || b + c,
+// are errors whatever the operands, but their parse is still checked.
+func buildHCLOperatorVector(t hclOperatorTemplate) hclOperatorVector {
+	slots := strings.Count(t.Expr, "@")
+	var best hclOperatorVector
+	bestRank := -1
+	operands := make([]string, slots)
+	var search func(slot int)
+	search = func(slot int) {
+		if bestRank == 4 {
+			return
+		}
+		if slot < slots {
+			for _, operand := range hclOperandPool {
+				operands[slot] = operand
+				search(slot + 1)
+			}
+			return
+		}
+		vector := hclOperatorVector{
+			Name:               t.Name,
+			Shape:              t.Shape,
+			Operators:          t.Operators,
+			hclOperatorOutcome: evalHCLOperatorExpr(fillHCLOperands(t.Expr, operands)),
+		}
+		var other hclOperatorOutcome
+		for _, g := range t.Groupings {
+			variant := hclOperatorVariant{Grouping: g.Name, hclOperatorOutcome: evalHCLOperatorExpr(fillHCLOperands(g.Expr, operands))}
+			vector.Variants = append(vector.Variants, variant)
+			if variant.Parsed == vector.Parsed {
+				vector.Grouping = g.Name
+			} else {
+				other = variant.hclOperatorOutcome
+			}
+		}
+		var rank int
+		switch {
+		case vector.Error == "" && other.Error == "" && string(other.Result) != string(vector.Result):
+			rank = 4
+		case vector.Error == "" && other.Error != "":
+			rank = 3
+		case vector.Error != "" && other.Error == "":
+			rank = 2
+		case vector.Error == "":
+			rank = 1
+		}
+		vector.Discriminating = rank > 1
+		if rank > bestRank {
+			best, bestRank = vector, rank
+		}
+	}
+	search(0)
+	return best
+}
+
+// hclModuleVersion is the hcl module version this binary was built with
+func hclModuleVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == "github.com/hashicorp/hcl/v2" {
+			return dep.Version
+		}
+	}
+	return ""
+}
+
+// generateHCLOperatorVectors builds a vector for every operator
+// combination of the given shapes, or of all of them
+func generateHCLOperatorVectors(shapes []string) (*hclOperatorFile, error) {
+	for _, shape := range shapes {
+		if !slices.Contains(hclOperatorShapes, shape) {
+			return nil, fmt.Errorf("unknown --shape %q: use %s", shape, strings.Join(hclOperatorShapes, ", "))
+		}
+	}
+	if len(shapes) == 0 {
+		shapes = hclOperatorShapes
+	}
+	file := &hclOperatorFile{
+		Generator:  "soup-go",
+		GoVersion:  runtime.Version(),
+		HCLVersion: hclModuleVersion(),
+		Vectors:    []hclOperatorVector{},
+	}
+	for _, t := range hclOperatorTemplates(shapes) {
+		file.Vectors = append(file.Vectors, buildHCLOperatorVector(t))
+	}
+	return file, nil
+}
+
+func initGenerateHCLOperatorsCmd() *cobra.Command {
+	var shapes []string
+	var outputPath string
+	var signKey string
+
+	cmd := &cobra.Command{
+		Use:   "hcl-operators",
+		Short: "Generate operator precedence and associativity vectors with Go results",
+		Long: `Generate a vector for every combination of HCL operators, so another
+parser's precedence and associativity can be checked exhaustively:
+
+  binary     a op1 b op2 c for every pair of the 13 binary operators
+  unary      -a op b and !a op b for every binary operator
+  condition  a op b ? c : d
+  else       a ? b : c op d
+
+Each vector has the expression, its parse with every operation in
+parentheses, and Go's result or error; some combinations, such as
+a || b + c, are errors whatever the operands. Its variants are the expression's
+two possible groupings written with explicit parentheses, each evaluated
+too; grouping names the one hclsyntax chose. Operands are picked so that,
+where possible, the other grouping gives a different result or an error
+(discriminating), so a parser that groups wrongly fails the vector.
+
+With --output the vectors are written to a file instead of stdout, and with
+--sign key.pem it is also recorded in a signed MANIFEST.json in that file's
+directory, so 'harness verify-vectors' can detect later edits.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if signKey != "" && outputPath == "" {
+				return fmt.Errorf("--sign requires --output")
+			}
+			file, err := generateHCLOperatorVectors(shapes)
+			if err != nil {
+				return err
+			}
+
+			if outputPath == "" {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(file)
+			}
+
+			data, err := json.MarshalIndent(file, "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to encode vectors: %w", err)
+			}
+			if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+				return fmt.Errorf("failed to create output directory: %w", err)
+			}
+			if err := os.WriteFile(outputPath, append(data, '\n'), 0644); err != nil {
+				return fmt.Errorf("failed to write vectors: %w", err)
+			}
+			if signKey != "" {
+				return signVectorFiles(filepath.Dir(outputPath), []string{outputPath}, signKey)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&shapes, "shape", nil, "Only generate these shapes: "+strings.Join(hclOperatorShapes, ", ")+" (default all)")
+	cmd.Flags().StringVarP(&outputPath, "output", "o", "", "Write the vectors to this file instead of stdout")
+	cmd.Flags().StringVar(&signKey, "sign", "", "PEM private key to sign the output directory's manifest with")
+	return cmd
+}
@@ -0,0 +1,431 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/spf13/cobra"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+	ctymsgpack "github.com/zclconf/go-cty/cty/msgpack"
+)
+
+// nestingBlockName is the block type name used in generated nesting vectors
+const nestingBlockName = "item"
+
+// schemaValuesVector is a generated nesting-mode test vector
+type schemaValuesVector struct {
+	Nesting      string           `json:"nesting"`
+	Count        int              `json:"count"`
+	Seed         int64            `json:"seed"`
+	Schema       *schemaBlockJSON `json:"schema"`
+	Config       json.RawMessage  `json:"config"`
+	Type         json.RawMessage  `json:"type"`
+	ValueJSON    json.RawMessage  `json:"value_json"`
+	ValueMsgpack string           `json:"value_msgpack"`
+	ElementCount int              `json:"element_count"`
+}
+
+// nestingTestSchema returns a block schema with a single nested block type
+// using the given nesting mode
+func nestingTestSchema(nesting string) *schemaBlockJSON {
+	return &schemaBlockJSON{
+		Attributes: map[string]*schemaAttributeJSON{
+			"id": {Type: json.RawMessage(`"string"`), Optional: true},
+		},
+		BlockTypes: map[string]*schemaBlockTypeJSON{
+			nestingBlockName: {
+				NestingMode: nesting,
+				Block: &schemaBlockJSON{
+					Attributes: map[string]*schemaAttributeJSON{
+						"name":    {Type: json.RawMessage(`"string"`), Optional: true},
+						"value":   {Type: json.RawMessage(`"number"`), Optional: true},
+						"enabled": {Type: json.RawMessage(`"bool"`), Optional: true},
+					},
+				},
+			},
+		},
+	}
+}
+
+// configToCtyValue builds the cty value Terraform would decode from a
+// config-shaped JSON body. Absent attributes are null, absent single blocks
+// are null, absent group blocks are objects of nulls, and absent list, set
+// and map blocks are empty collections.
+func configToCtyValue(block *schemaBlockJSON, raw interface{}, path cty.Path) (cty.Value, error) {
+	ty, err := block.impliedType()
+	if err != nil {
+		return cty.NilVal, err
+	}
+
+	obj := map[string]interface{}{}
+	if raw != nil {
+		var ok bool
+		obj, ok = raw.(map[string]interface{})
+		if !ok {
+			return cty.NilVal, path.NewErrorf("block must be an object")
+		}
+	}
+
+	vals := make(map[string]cty.Value)
+	for name := range block.Attributes {
+		attrTy := ty.AttributeType(name)
+		rawAttr, ok := obj[name]
+		if !ok || rawAttr == nil {
+			vals[name] = cty.NullVal(attrTy)
+			continue
+		}
+		data, err := json.Marshal(rawAttr)
+		if err != nil {
+			return cty.NilVal, path.GetAttr(name).NewError(err)
+		}
+		v, err := ctyjson.Unmarshal(data, attrTy)
+		if err != nil {
+			return cty.NilVal, path.GetAttr(name).NewError(err)
+		}
+		vals[name] = v
+	}
+
+	for name, blockType := range block.BlockTypes {
+		v, err := nestedBlockToCtyValue(blockType, obj[name], path.GetAttr(name))
+		if err != nil {
+			return cty.NilVal, err
+		}
+		vals[name] = v
+	}
+
+	return cty.ObjectVal(vals), nil
+}
+
+func nestedBlockToCtyValue(blockType *schemaBlockTypeJSON, raw interface{}, path cty.Path) (cty.Value, error) {
+	elemTy, err := blockType.Block.impliedType()
+	if err != nil {
+		return cty.NilVal, err
+	}
+
+	switch blockType.NestingMode {
+	case "single":
+		if raw == nil {
+			return cty.NullVal(elemTy), nil
+		}
+		return configToCtyValue(blockType.Block, raw, path)
+
+	case "group":
+		// Group blocks are never null: an absent block is an object of nulls
+		return configToCtyValue(blockType.Block, raw, path)
+
+	case "list", "set":
+		var items []interface{}
+		if raw != nil {
+			var ok bool
+			items, ok = raw.([]interface{})
+			if !ok {
+				return cty.NilVal, path.NewErrorf("%s blocks must be given as an array", blockType.NestingMode)
+			}
+		}
+		elems := make([]cty.Value, 0, len(items))
+		for i, item := range items {
+			v, err := configToCtyValue(blockType.Block, item, path.IndexInt(i))
+			if err != nil {
+				return cty.NilVal, err
+			}
+			elems = append(elems, v)
+		}
+		if blockType.NestingMode == "list" {
+			if len(elems) == 0 {
+				return cty.ListValEmpty(elemTy), nil
+			}
+			return cty.ListVal(elems), nil
+		}
+		if len(elems) == 0 {
+			return cty.SetValEmpty(elemTy), nil
+		}
+		return cty.SetVal(elems), nil
+
+	case "map":
+		var items map[string]interface{}
+		if raw != nil {
+			var ok bool
+			items, ok = raw.(map[string]interface{})
+			if !ok {
+				return cty.NilVal, path.NewErrorf("map blocks must be given as an object keyed by label")
+			}
+		}
+		elems := make(map[string]cty.Value, len(items))
+		for label, item := range items {
+			v, err := configToCtyValue(blockType.Block, item, path.IndexString(label))
+			if err != nil {
+				return cty.NilVal, err
+			}
+			elems[label] = v
+		}
+		if len(elems) == 0 {
+			return cty.MapValEmpty(elemTy), nil
+		}
+		return cty.MapVal(elems), nil
+
+	default:
+		return cty.NilVal, path.NewErrorf("unsupported nesting mode %q", blockType.NestingMode)
+	}
+}
+
+// generateNestingConfig produces config-shaped JSON for count nested blocks
+func generateNestingConfig(nesting string, count int, duplicate bool, rng *rand.Rand) (map[string]interface{}, error) {
+	config := map[string]interface{}{
+		"id": fmt.Sprintf("vector-%s-%d", nesting, count),
+	}
+
+	makeItem := func(i int) map[string]interface{} {
+		item := map[string]interface{}{
+			"name":  fmt.Sprintf("item-%d", i),
+			"value": rng.Intn(1000),
+		}
+		// Leave some attributes unset so null handling is exercised
+		if rng.Intn(2) == 0 {
+			item["enabled"] = rng.Intn(2) == 0
+		}
+		return item
+	}
+
+	switch nesting {
+	case "single", "group":
+		if count > 1 {
+			return nil, fmt.Errorf("%s nesting allows at most one block, got --count %d", nesting, count)
+		}
+		if count == 1 {
+			config[nestingBlockName] = makeItem(0)
+		}
+	case "list", "set":
+		items := make([]interface{}, 0, count+1)
+		for i := 0; i < count; i++ {
+			items = append(items, makeItem(i))
+		}
+		// Shuffle so consumers can't rely on generation order for sets
+		rng.Shuffle(len(items), func(i, j int) { items[i], items[j] = items[j], items[i] })
+		if duplicate && len(items) > 0 {
+			items = append(items, items[0])
+		}
+		if count > 0 {
+			config[nestingBlockName] = items
+		}
+	case "map":
+		items := make(map[string]interface{}, count)
+		for i := 0; i < count; i++ {
+			items[fmt.Sprintf("label_%d", i)] = makeItem(i)
+		}
+		if count > 0 {
+			config[nestingBlockName] = items
+		}
+	default:
+		return nil, fmt.Errorf("unsupported nesting mode %q (expected list, set, map, single, group)", nesting)
+	}
+
+	return config, nil
+}
+
+// buildSchemaValuesVector encodes config against schema into a test vector
+func buildSchemaValuesVector(schema *schemaBlockJSON, configJSON []byte) (*schemaValuesVector, error) {
+	var raw interface{}
+	if err := json.Unmarshal(configJSON, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	value, err := configToCtyValue(schema, raw, cty.Path{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to build value: %w", err)
+	}
+	ty := value.Type()
+
+	typeJSON, err := ctyjson.MarshalType(ty)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode type: %w", err)
+	}
+	valueJSON, err := ctyjson.Marshal(value, ty)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode JSON: %w", err)
+	}
+	packed, err := ctymsgpack.Marshal(value, ty)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode msgpack: %w", err)
+	}
+
+	elementCount := 0
+	if nested := value.GetAttr(nestingBlockName); !nested.IsNull() {
+		if nested.Type().IsObjectType() {
+			elementCount = 1
+		} else {
+			elementCount = nested.LengthInt()
+		}
+	}
+
+	return &schemaValuesVector{
+		Schema:       schema,
+		Config:       configJSON,
+		Type:         typeJSON,
+		ValueJSON:    valueJSON,
+		ValueMsgpack: base64.StdEncoding.EncodeToString(packed),
+		ElementCount: elementCount,
+	}, nil
+}
+
+func initGenerateSchemaValuesCmd() *cobra.Command {
+	var nesting string
+	var count int
+	var seed int64
+	var duplicate bool
+	var outputPath string
+	var signKey string
+
+	cmd := &cobra.Command{
+		Use:   "schema-values",
+		Short: "Generate nested block values exercising a schema nesting mode",
+		Long: `Generate a test vector for a block nesting mode (list, set, map, single,
+group): a schema, config-shaped input, and the cty value Go derives from it
+in JSON and msgpack form. Use 'cty verify-nesting' to check another
+harness's encoding of the same vector.
+
+With --output the vector is written to a file instead of stdout, and with
+--sign key.pem it is also recorded in a signed MANIFEST.json in that file's
+directory, so 'harness verify-vectors' can detect later edits.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if signKey != "" && outputPath == "" {
+				return fmt.Errorf("--sign requires --output")
+			}
+			rng := rand.New(rand.NewSource(seed))
+			config, err := generateNestingConfig(nesting, count, duplicate, rng)
+			if err != nil {
+				return err
+			}
+			configJSON, err := json.Marshal(config)
+			if err != nil {
+				return fmt.Errorf("failed to encode config: %w", err)
+			}
+
+			vector, err := buildSchemaValuesVector(nestingTestSchema(nesting), configJSON)
+			if err != nil {
+				return err
+			}
+			vector.Nesting = nesting
+			vector.Count = count
+			vector.Seed = seed
+
+			if outputPath == "" {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(vector)
+			}
+
+			data, err := json.MarshalIndent(vector, "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to encode vector: %w", err)
+			}
+			if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+				return fmt.Errorf("failed to create output directory: %w", err)
+			}
+			if err := os.WriteFile(outputPath, append(data, '\n'), 0644); err != nil {
+				return fmt.Errorf("failed to write vector: %w", err)
+			}
+			if signKey != "" {
+				return signVectorFiles(filepath.Dir(outputPath), []string{outputPath}, signKey)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&nesting, "nesting", "list", "Nesting mode: list, set, map, single, group")
+	cmd.Flags().IntVar(&count, "count", 3, "Number of nested blocks to generate")
+	cmd.Flags().Int64Var(&seed, "seed", 1, "Random seed for deterministic generation")
+	cmd.Flags().BoolVar(&duplicate, "with-duplicate", false, "Repeat one list/set block to exercise set de-duplication")
+	cmd.Flags().StringVarP(&outputPath, "output", "o", "", "Write the vector to this file instead of stdout")
+	cmd.Flags().StringVar(&signKey, "sign", "", "PEM private key to sign the output directory's manifest with")
+	return cmd
+}
+
+func initCtyVerifyNestingCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "verify-nesting [vector]",
+		Short: "Verify a nesting-mode vector's encoding against Go's",
+		Long: `Re-derive the cty value for a schema-values vector from its schema and
+config, and compare the vector's type, value_json and value_msgpack with
+what Go produces. Exits non-zero if any of them differ.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			data, err := os.ReadFile(args[0])
+			if err != nil {
+				return fmt.Errorf("failed to read vector: %w", err)
+			}
+			var vector schemaValuesVector
+			if err := json.Unmarshal(data, &vector); err != nil {
+				return fmt.Errorf("failed to parse vector: %w", err)
+			}
+			if vector.Schema == nil {
+				return fmt.Errorf("vector has no schema")
+			}
+
+			expected, err := buildSchemaValuesVector(vector.Schema, vector.Config)
+			if err != nil {
+				return err
+			}
+
+			mismatches := make([]string, 0)
+			if !jsonEquivalent(vector.Type, expected.Type) {
+				mismatches = append(mismatches, "type")
+			}
+			// Set element order is significant: cty defines a canonical order
+			if !bytes.Equal(compactJSON(vector.ValueJSON), compactJSON(expected.ValueJSON)) {
+				mismatches = append(mismatches, "value_json")
+			}
+			if vector.ValueMsgpack != expected.ValueMsgpack {
+				mismatches = append(mismatches, "value_msgpack")
+			}
+			sort.Strings(mismatches)
+
+			result := map[string]interface{}{
+				"nesting":    vector.Nesting,
+				"match":      len(mismatches) == 0,
+				"mismatches": mismatches,
+				"expected": map[string]interface{}{
+					"type":          expected.Type,
+					"value_json":    expected.ValueJSON,
+					"value_msgpack": expected.ValueMsgpack,
+					"element_count": expected.ElementCount,
+				},
+			}
+			if err := json.NewEncoder(os.Stdout).Encode(result); err != nil {
+				return fmt.Errorf("failed to encode JSON: %w", err)
+			}
+			if len(mismatches) > 0 {
+				return fmt.Errorf("vector does not match Go encoding: %v", mismatches)
+			}
+			return nil
+		},
+	}
+	return cmd
+}
+
+// compactJSON removes insignificant whitespace, returning the input on error
+func compactJSON(data []byte) []byte {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, data); err != nil {
+		return data
+	}
+	return buf.Bytes()
+}
+
+// jsonEquivalent reports whether two JSON documents decode to equal values
+func jsonEquivalent(a, b []byte) bool {
+	var va, vb interface{}
+	if json.Unmarshal(a, &va) != nil || json.Unmarshal(b, &vb) != nil {
+		return false
+	}
+	ca, _ := json.Marshal(va)
+	cb, _ := json.Marshal(vb)
+	return bytes.Equal(ca, cb)
+}
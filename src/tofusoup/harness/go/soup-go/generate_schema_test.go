@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// generateSchemaValues runs 'generate schema-values' into a file and
+// returns the path and the decoded vector
+func generateSchemaValues(t *testing.T, args ...string) (string, *schemaValuesVector) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "vector.json")
+	cmd := initGenerateSchemaValuesCmd()
+	cmd.SetArgs(append([]string{"--output", path}, args...))
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var vector schemaValuesVector
+	if err := json.Unmarshal(data, &vector); err != nil {
+		t.Fatal(err)
+	}
+	return path, &vector
+}
+
+func verifyNesting(t *testing.T, path string) (map[string]interface{}, error) {
+	t.Helper()
+	cmd := initCtyVerifyNestingCmd()
+	cmd.SetArgs([]string{path})
+	cmd.SilenceUsage, cmd.SilenceErrors = true, true
+	out, err := captureStdout(t, cmd.Execute)
+	var result map[string]interface{}
+	if jerr := json.Unmarshal(out, &result); jerr != nil {
+		t.Fatalf("%v: %s", jerr, out)
+	}
+	return result, err
+}
+
+func TestSchemaValuesRoundTrip(t *testing.T) {
+	tests := []struct {
+		nesting  string
+		count    int
+		extra    []string
+		elements int
+	}{
+		{nesting: "list", count: 3, elements: 3},
+		{nesting: "list", count: 0, elements: 0},
+		{nesting: "list", count: 2, extra: []string{"--with-duplicate"}, elements: 3},
+		{nesting: "set", count: 3, elements: 3},
+		{nesting: "set", count: 2, extra: []string{"--with-duplicate"}, elements: 2},
+		{nesting: "map", count: 2, elements: 2},
+		{nesting: "single", count: 1, elements: 1},
+		{nesting: "single", count: 0, elements: 0},
+		{nesting: "group", count: 0, elements: 1},
+	}
+
+	for _, tt := range tests {
+		name := fmt.Sprintf("%s/%d%s", tt.nesting, tt.count, strings.Join(tt.extra, ""))
+		t.Run(name, func(t *testing.T) {
+			args := append([]string{"--nesting", tt.nesting, "--count", fmt.Sprint(tt.count)}, tt.extra...)
+			path, vector := generateSchemaValues(t, args...)
+			if vector.Nesting != tt.nesting || vector.Count != tt.count {
+				t.Errorf("got nesting %q count %d", vector.Nesting, vector.Count)
+			}
+			if vector.ElementCount != tt.elements {
+				t.Errorf("got element_count %d, want %d", vector.ElementCount, tt.elements)
+			}
+
+			result, err := verifyNesting(t, path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result["match"] != true {
+				t.Errorf("verify-nesting: got %v", result)
+			}
+		})
+	}
+}
+
+func TestSchemaValuesSeedIsDeterministic(t *testing.T) {
+	_, first := generateSchemaValues(t, "--nesting", "set", "--seed", "7")
+	_, second := generateSchemaValues(t, "--nesting", "set", "--seed", "7")
+	if string(first.Config) != string(second.Config) || first.ValueMsgpack != second.ValueMsgpack {
+		t.Error("the same seed generated different vectors")
+	}
+}
+
+func TestVerifyNestingReportsMismatches(t *testing.T) {
+	path, vector := generateSchemaValues(t, "--nesting", "map")
+	vector.ValueJSON = json.RawMessage(`{"id":"tampered","item":{}}`)
+	vector.Type = json.RawMessage(`"string"`)
+	data, err := json.Marshal(vector)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := verifyNesting(t, path)
+	if err == nil {
+		t.Fatal("verify-nesting accepted a tampered vector")
+	}
+	mismatches, _ := json.Marshal(result["mismatches"])
+	if string(mismatches) != `["type","value_json"]` || result["match"] != false {
+		t.Errorf("got %v", result)
+	}
+}
+
+func TestSchemaValuesRejectsBadFlags(t *testing.T) {
+	for _, args := range [][]string{
+		{"--nesting", "single", "--count", "2"},
+		{"--nesting", "tuple"},
+		{"--sign", "key.pem"},
+	} {
+		cmd := initGenerateSchemaValuesCmd()
+		cmd.SetArgs(args)
+		cmd.SilenceUsage, cmd.SilenceErrors = true, true
+		if err := cmd.Execute(); err == nil {
+			t.Errorf("%v: got no error", args)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+	"github.com/zclconf/go-cty/cty/function/stdlib"
+)
+
+// timeVectorFunctions are the functions time vectors exercise
+var timeVectorFunctions = map[string]function.Function{
+	"formatdate": stdlib.FormatDateFunc,
+	"timeadd":    stdlib.TimeAddFunc,
+	"timecmp":    timeCmpFunc,
+}
+
+// timeCmpFunc is Terraform's timecmp, which go-cty's stdlib doesn't have:
+// -1, 0 or 1 as the first RFC 3339 timestamp is before, the same instant as
+// or after the second. Unlike formatdate and timeadd it parses with Go's
+// time.RFC3339 layout, as Terraform does.
+var timeCmpFunc = function.New(&function.Spec{
+	Params: []function.Parameter{
+		{Name: "timestamp_a", Type: cty.String},
+		{Name: "timestamp_b", Type: cty.String},
+	},
+	Type: function.StaticReturnType(cty.Number),
+	Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+		var ts [2]time.Time
+		for i, arg := range args {
+			t, err := time.Parse(time.RFC3339, arg.AsString())
+			if err != nil {
+				return cty.UnknownVal(cty.Number), function.NewArgErrorf(i, "not a valid RFC3339 timestamp: %s", err)
+			}
+			ts[i] = t
+		}
+		switch {
+		case ts[0].Before(ts[1]):
+			return cty.NumberIntVal(-1), nil
+		case ts[0].After(ts[1]):
+			return cty.NumberIntVal(1), nil
+		}
+		return cty.NumberIntVal(0), nil
+	},
+})
+
+// timeVectorCase is a function call whose Go result a time vector records
+type timeVectorCase struct {
+	Name     string
+	Category string
+	Function string
+	Args     []string
+}
+
+// timeVectorSuite covers RFC 3339 parsing edge cases, every formatdate
+// verb, UTC offsets (RFC 3339 has no named zones, so these are the time
+// zones a timestamp can carry), leap seconds and leap days, and Go
+// duration syntax
+var timeVectorSuite = []timeVectorCase{
+	// RFC 3339 parsing, through formatdate's strict parser
+	{"rfc3339-utc", "rfc3339", "formatdate", []string{"YYYY-MM-DD'T'hh:mm:ssZ", "2018-01-02T23:12:01Z"}},
+	{"rfc3339-fraction", "rfc3339", "formatdate", []string{"hh:mm:ss", "2018-01-02T23:12:01.987654321Z"}},
+	{"rfc3339-lowercase", "rfc3339", "formatdate", []string{"YYYY-MM-DD", "2018-01-02t23:12:01z"}},
+	{"rfc3339-no-zone", "rfc3339", "formatdate", []string{"YYYY-MM-DD", "2018-01-02T23:12:01"}},
+	{"rfc3339-space-separator", "rfc3339", "formatdate", []string{"YYYY-MM-DD", "2018-01-02 23:12:01Z"}},
+	{"rfc3339-date-only", "rfc3339", "formatdate", []string{"YYYY-MM-DD", "2018-01-02"}},
+	{"rfc3339-one-digit-hour", "rfc3339", "formatdate", []string{"YYYY-MM-DD", "2018-01-02T3:12:01Z"}},
+	{"rfc3339-comma-fraction", "rfc3339", "formatdate", []string{"YYYY-MM-DD", "2018-01-02T23:12:01,5Z"}},
+	{"rfc3339-hour-24", "rfc3339", "formatdate", []string{"YYYY-MM-DD", "2018-01-02T24:00:00Z"}},
+	{"rfc3339-year-0000", "rfc3339", "formatdate", []string{"YYYY-MM-DD", "0000-01-01T00:00:00Z"}},
+	{"rfc3339-year-9999", "rfc3339", "formatdate", []string{"YYYY-MM-DD hh:mm:ss", "9999-12-31T23:59:59Z"}},
+
+	// formatdate verbs
+	{"format-long", "format", "formatdate", []string{"EEEE, DD-MMM-YY hh:mm:ss ZZZ", "2018-01-02T23:12:01Z"}},
+	{"format-short", "format", "formatdate", []string{"EEE, D MMM YYYY H:m:s ZZZZ", "2018-01-02T03:04:05Z"}},
+	{"format-12-hour-am", "format", "formatdate", []string{"h:mm aa", "2018-01-02T00:05:00Z"}},
+	{"format-12-hour-pm", "format", "formatdate", []string{"h:mm AA", "2018-01-02T12:05:00Z"}},
+	{"format-month-names", "format", "formatdate", []string{"MMMM MMM MM M", "2018-09-02T00:00:00Z"}},
+	{"format-quoted", "format", "formatdate", []string{"'Day' D 'o''clock' hh", "2018-01-02T09:00:00Z"}},
+	{"format-unterminated-quote", "format", "formatdate", []string{"'Day D", "2018-01-02T09:00:00Z"}},
+	{"format-unknown-verb", "format", "formatdate", []string{"YYYY Q", "2018-01-02T09:00:00Z"}},
+	{"format-compact", "format", "formatdate", []string{"YYYYMMDDhhmmss", "2018-01-02T23:12:01Z"}},
+
+	// UTC offsets
+	{"zone-negative", "timezone", "formatdate", []string{"hh:mm ZZZ ZZZZ ZZZZZ", "2018-01-02T23:12:01-08:00"}},
+	{"zone-quarter-hour", "timezone", "formatdate", []string{"hh:mm ZZZ ZZZZ ZZZZZ", "2018-01-02T23:12:01+05:45"}},
+	{"zone-plus-14", "timezone", "formatdate", []string{"YYYY-MM-DD hh:mm ZZZZZ", "2018-01-02T23:12:01+14:00"}},
+	{"zone-minus-zero", "timezone", "formatdate", []string{"hh:mm ZZZ ZZZZZ", "2018-01-02T23:12:01-00:00"}},
+	{"zone-out-of-range", "timezone", "formatdate", []string{"hh:mm", "2018-01-02T23:12:01+24:00"}},
+	{"zone-add-keeps-offset", "timezone", "timeadd", []string{"2017-11-22T23:00:00-05:00", "2h"}},
+	{"zone-add-crosses-day", "timezone", "timeadd", []string{"2017-11-22T23:30:00+09:30", "1h"}},
+
+	// Leap seconds and leap days
+	{"leap-second-format", "leap", "formatdate", []string{"hh:mm:ss", "2016-12-31T23:59:60Z"}},
+	{"leap-second-add", "leap", "timeadd", []string{"2016-12-31T23:59:60Z", "1s"}},
+	{"leap-second-cmp", "leap", "timecmp", []string{"2016-12-31T23:59:60Z", "2017-01-01T00:00:00Z"}},
+	{"leap-second-boundary", "leap", "timeadd", []string{"2016-12-31T23:59:59Z", "1s"}},
+	{"leap-day", "leap", "formatdate", []string{"EEEE DD MMM YYYY", "2020-02-29T12:00:00Z"}},
+	{"leap-day-invalid", "leap", "formatdate", []string{"DD MMM YYYY", "2019-02-29T12:00:00Z"}},
+	{"leap-day-century", "leap", "formatdate", []string{"DD MMM YYYY", "1900-02-29T12:00:00Z"}},
+	{"leap-day-add", "leap", "timeadd", []string{"2020-02-28T12:00:00Z", "24h"}},
+
+	// Go duration syntax
+	{"duration-compound", "duration", "timeadd", []string{"2017-11-22T00:00:00Z", "1h30m45s"}},
+	{"duration-negative", "duration", "timeadd", []string{"2017-11-22T00:00:00Z", "-36h"}},
+	{"duration-fractional", "duration", "timeadd", []string{"2017-11-22T00:00:00Z", "1.5h"}},
+	{"duration-subsecond", "duration", "timeadd", []string{"2017-11-22T00:00:00Z", "1500ms"}},
+	{"duration-drops-fraction", "duration", "timeadd", []string{"2017-11-22T00:00:00.75Z", "0s"}},
+	{"duration-days", "duration", "timeadd", []string{"2017-11-22T00:00:00Z", "1d"}},
+	{"duration-no-unit", "duration", "timeadd", []string{"2017-11-22T00:00:00Z", "10"}},
+	{"duration-year-overflow", "duration", "timeadd", []string{"9999-12-31T23:59:59Z", "1s"}},
+
+	// timecmp
+	{"cmp-before", "compare", "timecmp", []string{"2017-11-22T00:00:00Z", "2017-11-22T00:00:01Z"}},
+	{"cmp-same-instant", "compare", "timecmp", []string{"2017-11-22T00:00:00Z", "2017-11-21T19:00:00-05:00"}},
+	{"cmp-fraction", "compare", "timecmp", []string{"2017-11-22T00:00:00.1Z", "2017-11-22T00:00:00.01Z"}},
+	{"cmp-offsets", "compare", "timecmp", []string{"2017-11-22T01:00:00+01:00", "2017-11-22T00:30:00Z"}},
+	{"cmp-invalid", "compare", "timecmp", []string{"2017-11-22", "2017-11-22T00:00:00Z"}},
+}
+
+// timeVector is a function call and Go's result for it. Result is a string
+// for formatdate and timeadd and a number for timecmp; a call that fails
+// has Error instead.
+type timeVector struct {
+	Name     string   `json:"name"`
+	Category string   `json:"category"`
+	Function string   `json:"function"`
+	Args     []string `json:"args"`
+	Result   any      `json:"result,omitempty"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// timeVectorFile is the document generate time-vectors writes
+type timeVectorFile struct {
+	Generator string       `json:"generator"`
+	GoVersion string       `json:"go_version"`
+	Vectors   []timeVector `json:"vectors"`
+}
+
+// runTimeVector calls c's function and records the result or error
+func runTimeVector(c timeVectorCase) timeVector {
+	vector := timeVector{Name: c.Name, Category: c.Category, Function: c.Function, Args: c.Args}
+	args := make([]cty.Value, len(c.Args))
+	for i, arg := range c.Args {
+		args[i] = cty.StringVal(arg)
+	}
+	result, err := timeVectorFunctions[c.Function].Call(args)
+	switch {
+	case err != nil:
+		vector.Error = err.Error()
+	case result.Type() == cty.Number:
+		n, _ := result.AsBigFloat().Int64()
+		vector.Result = n
+	default:
+		vector.Result = result.AsString()
+	}
+	return vector
+}
+
+// generateTimeVectors runs the suite cases for the given functions and
+// categories, or all of them
+func generateTimeVectors(functions, categories []string) (*timeVectorFile, error) {
+	for _, name := range functions {
+		if _, ok := timeVectorFunctions[name]; !ok {
+			return nil, fmt.Errorf("unknown --function %q: use formatdate, timeadd, timecmp", name)
+		}
+	}
+	var known []string
+	for _, c := range timeVectorSuite {
+		if !slices.Contains(known, c.Category) {
+			known = append(known, c.Category)
+		}
+	}
+	for _, category := range categories {
+		if !slices.Contains(known, category) {
+			return nil, fmt.Errorf("unknown --category %q: use %s", category, strings.Join(known, ", "))
+		}
+	}
+
+	file := &timeVectorFile{Generator: "soup-go", GoVersion: runtime.Version(), Vectors: []timeVector{}}
+	for _, c := range timeVectorSuite {
+		if len(functions) > 0 && !slices.Contains(functions, c.Function) {
+			continue
+		}
+		if len(categories) > 0 && !slices.Contains(categories, c.Category) {
+			continue
+		}
+		file.Vectors = append(file.Vectors, runTimeVector(c))
+	}
+	return file, nil
+}
+
+func initGenerateTimeVectorsCmd() *cobra.Command {
+	var functions []string
+	var categories []string
+	var outputPath string
+	var signKey string
+
+	cmd := &cobra.Command{
+		Use:   "time-vectors",
+		Short: "Generate formatdate, timeadd and timecmp vectors with Go reference results",
+		Long: `Generate conformance vectors for the date and time functions: each is a
+function call (formatdate, timeadd or timecmp) and the result or error Go
+gives for it. The suite covers RFC 3339 parsing edge cases, every
+formatdate verb, UTC offsets, leap seconds and leap days, and Go duration
+syntax. Another harness passes if it reproduces each result, and fails
+each call that has an error (error messages are Go's own and needn't
+match).
+
+formatdate and timeadd are go-cty's; timecmp is Terraform's, which go-cty
+lacks, and parses timestamps with Go's time.RFC3339 layout.
+
+With --output the vectors are written to a file instead of stdout, and with
+--sign key.pem it is also recorded in a signed MANIFEST.json in that file's
+directory, so 'harness verify-vectors' can detect later edits.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if signKey != "" && outputPath == "" {
+				return fmt.Errorf("--sign requires --output")
+			}
+			file, err := generateTimeVectors(functions, categories)
+			if err != nil {
+				return err
+			}
+
+			if outputPath == "" {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(file)
+			}
+
+			data, err := json.MarshalIndent(file, "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to encode vectors: %w", err)
+			}
+			if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+				return fmt.Errorf("failed to create output directory: %w", err)
+			}
+			if err := os.WriteFile(outputPath, append(data, '\n'), 0644); err != nil {
+				return fmt.Errorf("failed to write vectors: %w", err)
+			}
+			if signKey != "" {
+				return signVectorFiles(filepath.Dir(outputPath), []string{outputPath}, signKey)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&functions, "function", nil, "Only generate vectors for these functions (default all)")
+	cmd.Flags().StringSliceVar(&categories, "category", nil, "Only generate vectors in these categories (default all)")
+	cmd.Flags().StringVarP(&outputPath, "output", "o", "", "Write the vectors to this file instead of stdout")
+	cmd.Flags().StringVar(&signKey, "sign", "", "PEM private key to sign the output directory's manifest with")
+	return cmd
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+)
+
+// GitHub Actions workflow annotations
+//
+// With --output-format github, validation commands print each diagnostic as
+// a workflow command (::error file=...,line=...::message) so the runner
+// shows it inline on the pull request diff without a converter script.
+
+// githubAnnotation is one ::error or ::warning workflow command
+type githubAnnotation struct {
+	Level     string // "error" or "warning"
+	File      string
+	Line      int
+	EndLine   int
+	Column    int
+	EndColumn int
+	Title     string
+	Message   string
+}
+
+// githubEscapeData escapes an annotation message
+func githubEscapeData(s string) string {
+	s = strings.ReplaceAll(s, "%", "%25")
+	s = strings.ReplaceAll(s, "\r", "%0D")
+	return strings.ReplaceAll(s, "\n", "%0A")
+}
+
+// githubEscapeProperty escapes an annotation property value, which also
+// can't contain the property separators
+func githubEscapeProperty(s string) string {
+	s = githubEscapeData(s)
+	s = strings.ReplaceAll(s, ":", "%3A")
+	return strings.ReplaceAll(s, ",", "%2C")
+}
+
+// writeGitHubAnnotation writes a as a workflow command line
+func writeGitHubAnnotation(w io.Writer, a githubAnnotation) error {
+	var props []string
+	add := func(key, value string) {
+		if value != "" {
+			props = append(props, key+"="+githubEscapeProperty(value))
+		}
+	}
+	addInt := func(key string, value int) {
+		if value > 0 {
+			props = append(props, key+"="+strconv.Itoa(value))
+		}
+	}
+	add("file", a.File)
+	addInt("line", a.Line)
+	addInt("endLine", a.EndLine)
+	// Columns only mean something on a single line
+	if a.EndLine == 0 || a.EndLine == a.Line {
+		addInt("col", a.Column)
+		addInt("endColumn", a.EndColumn)
+	}
+	add("title", a.Title)
+
+	level := a.Level
+	if level != "warning" {
+		level = "error"
+	}
+	line := "::" + level
+	if len(props) > 0 {
+		line += " " + strings.Join(props, ",")
+	}
+	_, err := fmt.Fprintf(w, "%s::%s\n", line, githubEscapeData(a.Message))
+	return err
+}
+
+// hclDiagnosticAnnotation converts an HCL diagnostic to an annotation,
+// falling back to filename when the diagnostic has no source range
+func hclDiagnosticAnnotation(diag *hcl.Diagnostic, filename string) githubAnnotation {
+	a := githubAnnotation{
+		Level:   "error",
+		File:    filename,
+		Title:   diag.Summary,
+		Message: diag.Summary,
+	}
+	if diag.Severity == hcl.DiagWarning {
+		a.Level = "warning"
+	}
+	if diag.Detail != "" {
+		a.Message = diag.Summary + ": " + diag.Detail
+	}
+	if diag.Subject != nil {
+		if diag.Subject.Filename != "" {
+			a.File = diag.Subject.Filename
+		}
+		a.Line = diag.Subject.Start.Line
+		a.Column = diag.Subject.Start.Column
+		a.EndLine = diag.Subject.End.Line
+		a.EndColumn = diag.Subject.End.Column
+	}
+	return a
+}
+
+// writeHCLDiagnosticsGitHub writes every diagnostic in diags as an annotation
+func writeHCLDiagnosticsGitHub(w io.Writer, diags hcl.Diagnostics, filename string) error {
+	for _, diag := range diags {
+		if err := writeGitHubAnnotation(w, hclDiagnosticAnnotation(diag, filename)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeSchemaDiagnosticsGitHub writes schema validation diagnostics as
+// annotations against the schema file, returning an error if any were
+// errors. They carry an attribute path rather than a source position.
+func writeSchemaDiagnosticsGitHub(w io.Writer, diags []schemaDiagnostic, schemaPath string) error {
+	failed := false
+	for _, diag := range diags {
+		message := diag.Summary
+		if diag.Path != "" {
+			message = diag.Path + ": " + message
+		}
+		if diag.Detail != "" {
+			message += ": " + diag.Detail
+		}
+		if err := writeGitHubAnnotation(w, githubAnnotation{
+			Level:   diag.Severity,
+			File:    schemaPath,
+			Title:   diag.Summary,
+			Message: message,
+		}); err != nil {
+			return fmt.Errorf("failed to write annotations: %w", err)
+		}
+		if diag.Severity != "warning" {
+			failed = true
+		}
+	}
+	if failed {
+		return fmt.Errorf("validation failed with %d diagnostics", len(diags))
+	}
+	return nil
+}
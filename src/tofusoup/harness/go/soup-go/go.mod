@@ -3,22 +3,32 @@ module github.com/provide-io/tofusoup/harness/soup-go
 go 1.24.0
 
 require (
+	github.com/Microsoft/go-winio v0.6.2
+	github.com/apparentlymart/go-textseg/v15 v15.0.0
 	github.com/gofrs/flock v0.13.0
 	github.com/hashicorp/go-hclog v1.6.3
 	github.com/hashicorp/go-plugin v1.7.0
 	github.com/hashicorp/hcl/v2 v2.19.1
+	github.com/provide-io/tofusoup/proto/counter v0.0.0-00010101000000-000000000000
+	github.com/provide-io/tofusoup/proto/echo v0.0.0-00010101000000-000000000000
 	github.com/provide-io/tofusoup/proto/kv v0.0.0-00010101000000-000000000000
 	github.com/spf13/cobra v1.10.1
+	github.com/spf13/pflag v1.0.9
 	github.com/vmihailenco/msgpack/v5 v5.4.1
 	github.com/zclconf/go-cty v1.14.1
+	golang.org/x/net v0.38.0
+	golang.org/x/text v0.23.0
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20231106174013-bbf56f31fb17
 	google.golang.org/grpc v1.61.0
+	google.golang.org/protobuf v1.36.6
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/agext/levenshtein v1.2.1 // indirect
-	github.com/apparentlymart/go-textseg/v15 v15.0.0 // indirect
 	github.com/fatih/color v1.13.0 // indirect
 	github.com/golang/protobuf v1.5.4 // indirect
+	github.com/google/go-cmp v0.6.0 // indirect
 	github.com/hashicorp/yamux v0.1.2 // indirect
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/mattn/go-colorable v0.1.12 // indirect
@@ -26,15 +36,14 @@ require (
 	github.com/mitchellh/go-wordwrap v0.0.0-20150314170334-ad45545899c7 // indirect
 	github.com/oklog/run v1.1.0 // indirect
 	github.com/rogpeppe/go-internal v1.14.1 // indirect
-	github.com/spf13/pflag v1.0.9 // indirect
 	github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
-	golang.org/x/net v0.38.0 // indirect
 	golang.org/x/sys v0.37.0 // indirect
-	golang.org/x/text v0.23.0 // indirect
-	google.golang.org/genproto/googleapis/rpc v0.0.0-20231106174013-bbf56f31fb17 // indirect
-	google.golang.org/protobuf v1.36.6 // indirect
 )
 
 replace github.com/provide-io/tofusoup/proto/kv => ../../proto/kv
 
+replace github.com/provide-io/tofusoup/proto/counter => ../../proto/counter
+
+replace github.com/provide-io/tofusoup/proto/echo => ../../proto/echo
+
 replace github.com/hashicorp/go-plugin => /Users/tim/code/gh/hashicorp/go-plugin
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// assertExpectation is what an attempt's result must satisfy
+type assertExpectation struct {
+	Equals   *string `json:"equals,omitempty"`
+	Contains *string `json:"contains,omitempty"`
+	ExitCode int     `json:"exit_code"`
+}
+
+// assertAttempt is the result of one run of the command
+type assertAttempt struct {
+	ExitCode int    `json:"exit_code"`
+	Stdout   string `json:"stdout"`
+	Stderr   string `json:"stderr,omitempty"`
+	Error    string `json:"error,omitempty"`
+	// Mismatch says why the attempt didn't satisfy the expectation
+	Mismatch string `json:"mismatch,omitempty"`
+}
+
+// assertReport is the result of harness assert
+type assertReport struct {
+	Command    []string          `json:"command"`
+	Expect     assertExpectation `json:"expect"`
+	Timeout    string            `json:"timeout"`
+	Interval   string            `json:"interval"`
+	Passed     bool              `json:"passed"`
+	Attempts   int               `json:"attempts"`
+	ElapsedMs  float64           `json:"elapsed_ms"`
+	LastResult assertAttempt     `json:"last_result"`
+}
+
+// check reports why an attempt doesn't satisfy the expectation, or "" if it
+// does. Stdout is compared with trailing newlines trimmed.
+func (e assertExpectation) check(a assertAttempt) string {
+	if a.Error != "" {
+		return a.Error
+	}
+	if a.ExitCode != e.ExitCode {
+		return fmt.Sprintf("exit code %d, want %d", a.ExitCode, e.ExitCode)
+	}
+	out := strings.TrimRight(a.Stdout, "\r\n")
+	if e.Equals != nil && out != *e.Equals {
+		return fmt.Sprintf("stdout %q, want %q", out, *e.Equals)
+	}
+	if e.Contains != nil && !strings.Contains(out, *e.Contains) {
+		return fmt.Sprintf("stdout %q does not contain %q", out, *e.Contains)
+	}
+	return ""
+}
+
+// runAssertAttempt runs the command once. A command that can't be started,
+// or is cut off by ctx, has an Error rather than an exit code.
+func runAssertAttempt(ctx context.Context, argv []string) assertAttempt {
+	var stdout, stderr bytes.Buffer
+	c := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	c.Stdout = &stdout
+	c.Stderr = &stderr
+	err := c.Run()
+
+	attempt := assertAttempt{Stdout: stdout.String(), Stderr: stderr.String()}
+	var exitErr *exec.ExitError
+	switch {
+	case ctx.Err() != nil:
+		attempt.ExitCode = -1
+		attempt.Error = "timed out before the command finished"
+	case errors.As(err, &exitErr):
+		attempt.ExitCode = exitErr.ExitCode()
+	case err != nil:
+		attempt.ExitCode = -1
+		attempt.Error = fmt.Sprintf("failed to run command: %v", err)
+	}
+	return attempt
+}
+
+// pollAssert runs argv every interval until an attempt satisfies expect or
+// timeout passes. Each attempt is cut off at the overall deadline.
+func pollAssert(ctx context.Context, argv []string, expect assertExpectation, timeout, interval time.Duration) assertReport {
+	report := assertReport{
+		Command:  argv,
+		Expect:   expect,
+		Timeout:  timeout.String(),
+		Interval: interval.String(),
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	for {
+		attempt := runAssertAttempt(ctx, argv)
+		attempt.Mismatch = expect.check(attempt)
+		report.Attempts++
+		report.LastResult = attempt
+		report.ElapsedMs = float64(time.Since(start).Microseconds()) / 1000
+		if attempt.Mismatch == "" {
+			report.Passed = true
+			return report
+		}
+		logger.Debug("assertion not yet satisfied", "attempt", report.Attempts, "mismatch", attempt.Mismatch)
+
+		select {
+		case <-ctx.Done():
+			return report
+		case <-time.After(interval):
+		}
+	}
+}
+
+func initHarnessAssertCmd() *cobra.Command {
+	var command, equals, contains, outputFormat string
+	var exitCode int
+	var timeout, interval time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "assert",
+		Short: "Poll a command until its result matches, instead of sleeping",
+		Long: `Run --command every --interval until it exits with --exit-code and its
+stdout satisfies --equals and --contains, or until --timeout passes. Stdout
+is compared with trailing newlines trimmed.
+
+Cross-process scenarios (a write through one harness read back through
+another, a server that must come up before a client connects) use this in
+place of fixed sleeps: it returns as soon as the result is consistent and
+only fails once the whole timeout has passed. Exits non-zero on timeout,
+printing the last attempt's result.`,
+		Example: `  soup-go harness assert --command "soup-go rpc kv get foo" --equals bar --timeout 10s --interval 200ms
+  soup-go harness assert --command "soup rpc kv get foo" --contains bar --output-format json
+  soup-go harness assert --command "soup-go rpc kv get gone" --exit-code 1`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			argv := strings.Fields(command)
+			if len(argv) == 0 {
+				return fmt.Errorf("--command is required")
+			}
+			if timeout <= 0 || interval <= 0 {
+				return fmt.Errorf("--timeout and --interval must be positive")
+			}
+			if outputFormat != "text" && outputFormat != "json" {
+				return fmt.Errorf("unknown --output-format %q (expected text or json)", outputFormat)
+			}
+			expect := assertExpectation{ExitCode: exitCode}
+			if cmd.Flags().Changed("equals") {
+				expect.Equals = &equals
+			}
+			if cmd.Flags().Changed("contains") {
+				expect.Contains = &contains
+			}
+
+			report := pollAssert(cmd.Context(), argv, expect, timeout, interval)
+
+			if outputFormat == "json" {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				if err := encoder.Encode(report); err != nil {
+					return fmt.Errorf("failed to encode JSON: %w", err)
+				}
+			} else if report.Passed {
+				fmt.Printf("✅ passed after %d attempt(s) in %.1fms\n", report.Attempts, report.ElapsedMs)
+			} else {
+				last := report.LastResult
+				fmt.Printf("❌ not satisfied after %d attempt(s) in %.1fms: %s\n", report.Attempts, report.ElapsedMs, last.Mismatch)
+				if stderr := strings.TrimSpace(last.Stderr); stderr != "" {
+					fmt.Printf("   last stderr: %s\n", stderr)
+				}
+			}
+
+			if !report.Passed {
+				return fmt.Errorf("assertion not satisfied within %s", timeout)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&command, "command", "", "Command to poll (split on whitespace)")
+	cmd.Flags().StringVar(&equals, "equals", "", "Stdout must equal this, ignoring trailing newlines")
+	cmd.Flags().StringVar(&contains, "contains", "", "Stdout must contain this")
+	cmd.Flags().IntVar(&exitCode, "exit-code", 0, "Exit code the command must return")
+	cmd.Flags().DurationVar(&timeout, "timeout", 10*time.Second, "Give up after this long")
+	cmd.Flags().DurationVar(&interval, "interval", 200*time.Millisecond, "Time between attempts")
+	cmd.Flags().StringVar(&outputFormat, "output-format", "text", "Output format (text, json)")
+	return cmd
+}
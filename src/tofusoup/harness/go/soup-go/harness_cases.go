@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// Prerequisites a case can declare. A scheduler runs a case only where all
+// of them hold.
+const (
+	prereqLoopbackTCP = "loopback-tcp"
+	prereqServerCmd   = "server-cmd"
+)
+
+// harnessPrerequisites describes each prerequisite in `harness cases list`
+var harnessPrerequisites = map[string]string{
+	prereqLoopbackTCP: "The case listens on and dials 127.0.0.1",
+	prereqServerCmd:   "A command that starts a KV server in plugin mode, substituted for {server_cmd} in the command",
+}
+
+// serverCmdPlaceholder stands for the server-cmd prerequisite in a case's
+// command
+const serverCmdPlaceholder = "{server_cmd}"
+
+// harnessCase is one built-in case as schedulers see it
+type harnessCase struct {
+	ID                 string   `json:"id"`
+	Kind               string   `json:"kind"`
+	Subsystem          string   `json:"subsystem"`
+	Description        string   `json:"description"`
+	Prerequisites      []string `json:"prerequisites"`
+	ExpectedDurationMs int64    `json:"expected_duration_ms"`
+	// Command runs the case alone and exits non-zero if it fails
+	Command []string `json:"command"`
+}
+
+// harnessCaseList is the output of `harness cases list --json`
+type harnessCaseList struct {
+	Harness       string            `json:"harness"`
+	Version       string            `json:"version"`
+	Prerequisites map[string]string `json:"prerequisites"`
+	Cases         []harnessCase     `json:"cases"`
+}
+
+// scenarioCases are the `harness scenario` commands, run with their default
+// flags
+var scenarioCases = []harnessCase{
+	{
+		ID:                 "scenario/restart-under-load",
+		Kind:               "scenario",
+		Subsystem:          "rpc",
+		Description:        "Clients keep calling a KV server that is killed and restarted every 5s for 30s",
+		Prerequisites:      []string{prereqServerCmd},
+		ExpectedDurationMs: 35000,
+		Command:            []string{"soup-go", "harness", "scenario", "restart-under-load", "--server-cmd", serverCmdPlaceholder, "--output-format", "json"},
+	},
+	{
+		ID:                 "scenario/deadline",
+		Kind:               "scenario",
+		Subsystem:          "rpc",
+		Description:        "A KV server receives call deadlines and surfaces DeadlineExceeded without applying late Puts",
+		Prerequisites:      []string{prereqServerCmd},
+		ExpectedDurationMs: 5000,
+		Command:            []string{"soup-go", "harness", "scenario", "deadline", "--server-cmd", serverCmdPlaceholder, "--output-format", "json"},
+	},
+}
+
+// harnessCases lists the selftest cases and scenarios, in the order
+// selftest runs them, then the scenarios
+func harnessCases() []harnessCase {
+	var cases []harnessCase
+	for _, tc := range selftestCases(logger.Named("selftest")) {
+		prereqs := tc.Prerequisites
+		if prereqs == nil {
+			prereqs = []string{}
+		}
+		cases = append(cases, harnessCase{
+			ID:                 "selftest/" + tc.ID(),
+			Kind:               "selftest",
+			Subsystem:          tc.Subsystem,
+			Description:        tc.Description,
+			Prerequisites:      prereqs,
+			ExpectedDurationMs: tc.ExpectedMs,
+			Command:            []string{"soup-go", "selftest", "--case", tc.ID()},
+		})
+	}
+	return append(cases, scenarioCases...)
+}
+
+func initHarnessCasesCmd() *cobra.Command {
+	var subsystems []string
+	var outputJSON bool
+
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "List the built-in selftest cases and scenarios",
+		Long: `List every built-in case with its ID, subsystem, description,
+prerequisites and expected duration, and the command that runs it alone, so
+an external orchestrator can shard and schedule cases instead of running
+the suite as one job.
+
+Selftest cases run with 'soup-go selftest --case'. Scenarios need a server
+to test: {server_cmd} in their command stands for it.`,
+		Example: `  soup-go harness cases list --subsystem rpc --json`,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			for _, s := range subsystems {
+				switch s {
+				case "cty", "hcl", "wire", "rpc":
+				default:
+					return fmt.Errorf("unknown subsystem %q (expected cty, hcl, wire, rpc)", s)
+				}
+			}
+			report := harnessCaseList{Harness: "soup-go", Version: version, Prerequisites: harnessPrerequisites, Cases: []harnessCase{}}
+			for _, c := range harnessCases() {
+				if len(subsystems) == 0 || slices.Contains(subsystems, c.Subsystem) {
+					report.Cases = append(report.Cases, c)
+				}
+			}
+
+			if outputJSON {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(report)
+			}
+			for _, c := range report.Cases {
+				line := fmt.Sprintf("%-40s %-5s %7dms  %s", c.ID, c.Subsystem, c.ExpectedDurationMs, c.Description)
+				if len(c.Prerequisites) > 0 {
+					line += " [needs " + strings.Join(c.Prerequisites, ", ") + "]"
+				}
+				fmt.Println(line)
+			}
+			return nil
+		},
+	}
+	list.Flags().StringSliceVar(&subsystems, "subsystem", nil, "Only list these subsystems: cty, hcl, wire, rpc (default all)")
+	list.Flags().BoolVar(&outputJSON, "json", false, "Output in JSON format")
+
+	cmd := &cobra.Command{
+		Use:   "cases",
+		Short: "Describe the built-in cases for external schedulers",
+	}
+	cmd.AddCommand(list)
+	return cmd
+}
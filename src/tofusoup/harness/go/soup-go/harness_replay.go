@@ -0,0 +1,279 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/go-plugin"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	protobuf "google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+
+	"github.com/provide-io/tofusoup/proto/kv"
+)
+
+// replayResult is the outcome of replaying one transcript entry
+type replayResult struct {
+	Seq      int              `json:"seq"`
+	Method   string           `json:"method"`
+	Passed   bool             `json:"passed"`
+	Status   transcriptStatus `json:"status"`
+	Response json.RawMessage  `json:"response,omitempty"`
+	// Mismatch says how the replayed call differed from the recorded one
+	Mismatch string `json:"mismatch,omitempty"`
+}
+
+// replayReport is the result of harness replay
+type replayReport struct {
+	Transcript string         `json:"transcript"`
+	Against    string         `json:"against"`
+	Calls      int            `json:"calls"`
+	Mismatches int            `json:"mismatches"`
+	Results    []replayResult `json:"results"`
+}
+
+// readTranscript parses an NDJSON transcript, skipping blank lines
+func readTranscript(path string) ([]transcriptEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open transcript: %w", err)
+	}
+	defer file.Close()
+
+	var entries []transcriptEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var entry transcriptEntry
+		if err := json.Unmarshal([]byte(text), &entry); err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid transcript entry: %w", path, line, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read transcript: %w", err)
+	}
+	return entries, nil
+}
+
+// unaryMethod resolves a full method name ("/proto.KV/Put") to its
+// descriptor among the services compiled into soup-go
+func unaryMethod(fullMethod string) (protoreflect.MethodDescriptor, error) {
+	service, name, ok := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	if !ok {
+		return nil, fmt.Errorf("invalid method %q", fullMethod)
+	}
+	desc, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(service))
+	if err != nil {
+		return nil, fmt.Errorf("unknown service %q", service)
+	}
+	sd, ok := desc.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%q is not a service", service)
+	}
+	md := sd.Methods().ByName(protoreflect.Name(name))
+	if md == nil {
+		return nil, fmt.Errorf("service %q has no method %q", service, name)
+	}
+	if md.IsStreamingClient() || md.IsStreamingServer() {
+		return nil, fmt.Errorf("%s is a streaming method", fullMethod)
+	}
+	return md, nil
+}
+
+// newMessage returns an empty message of the registered Go type for desc
+func newMessage(desc protoreflect.MessageDescriptor) (protobuf.Message, error) {
+	mt, err := protoregistry.GlobalTypes.FindMessageByName(desc.FullName())
+	if err != nil {
+		return nil, fmt.Errorf("no Go type for %s", desc.FullName())
+	}
+	return mt.New().Interface(), nil
+}
+
+// replayMetadata is the recorded metadata to send again. Redacted values
+// are left out; the bearer token comes from KV_AUTH_TOKEN instead.
+func replayMetadata(recorded map[string][]string) metadata.MD {
+	md := metadata.MD{}
+	for key, values := range recorded {
+		if len(values) == 1 && values[0] == redactedValue {
+			continue
+		}
+		md[key] = values
+	}
+	return md
+}
+
+// sameResponse compares responses. Get values that are JSON objects are
+// compared without the server_handshake field servers add to them.
+func sameResponse(recorded, replayed protobuf.Message) bool {
+	a, aok := recorded.(*proto.GetResponse)
+	b, bok := replayed.(*proto.GetResponse)
+	if aok && bok {
+		ca, okA := canonicalJSONObject(a.Value, enrichmentField)
+		cb, okB := canonicalJSONObject(b.Value, enrichmentField)
+		if okA && okB {
+			return string(ca) == string(cb)
+		}
+	}
+	return protobuf.Equal(recorded, replayed)
+}
+
+// replayEntry sends one recorded call again and compares the result
+func replayEntry(ctx context.Context, conn grpc.ClientConnInterface, entry transcriptEntry, codesOnly bool) replayResult {
+	result := replayResult{Seq: entry.Seq, Method: entry.Method}
+	md, err := unaryMethod(entry.Method)
+	if err != nil {
+		result.Mismatch = err.Error()
+		return result
+	}
+	req, err := newMessage(md.Input())
+	if err != nil {
+		result.Mismatch = err.Error()
+		return result
+	}
+	if err := protojson.Unmarshal(entry.Request, req); err != nil {
+		result.Mismatch = fmt.Sprintf("invalid recorded request: %v", err)
+		return result
+	}
+	resp, err := newMessage(md.Output())
+	if err != nil {
+		result.Mismatch = err.Error()
+		return result
+	}
+
+	callCtx := metadata.NewOutgoingContext(ctx, replayMetadata(entry.Metadata))
+	callErr := conn.Invoke(callCtx, entry.Method, req, resp)
+	st := status.Convert(callErr)
+	result.Status = transcriptStatus{Code: st.Code().String(), Message: st.Message()}
+	if callErr == nil {
+		result.Response, _ = protojson.Marshal(resp)
+	}
+
+	switch {
+	case result.Status.Code != entry.Status.Code:
+		result.Mismatch = fmt.Sprintf("status %s, recorded %s", result.Status.Code, entry.Status.Code)
+	case codesOnly:
+	case result.Status.Message != entry.Status.Message:
+		result.Mismatch = fmt.Sprintf("message %q, recorded %q", result.Status.Message, entry.Status.Message)
+	case callErr == nil:
+		recorded, _ := newMessage(md.Output())
+		if err := protojson.Unmarshal(entry.Response, recorded); err != nil {
+			result.Mismatch = fmt.Sprintf("invalid recorded response: %v", err)
+		} else if !sameResponse(recorded, resp) {
+			result.Mismatch = fmt.Sprintf("response %s, recorded %s", result.Response, entry.Response)
+		}
+	}
+	result.Passed = result.Mismatch == ""
+	return result
+}
+
+// replayClient connects to against: an address or handshake line to
+// reattach to, or a server executable to spawn
+func replayClient(against, tlsCurve string) (*plugin.Client, error) {
+	if info, err := os.Stat(against); err == nil && !info.IsDir() {
+		os.Setenv("PLUGIN_SERVER_PATH", against)
+		return newRPCClient(logger)
+	}
+	return newReattachClient(against, tlsCurve, logger)
+}
+
+func initHarnessReplayCmd() *cobra.Command {
+	var against, tlsCurve, outputFormat string
+	var codesOnly bool
+
+	cmd := &cobra.Command{
+		Use:   "replay <transcript.ndjson>",
+		Short: "Replay a recorded client session against a server and compare results",
+		Long: `Send every call in a transcript recorded with rpc --transcript to the
+server given by --against, in order, and check each gets the same status
+and response as it did when recorded. --against is a server executable to
+spawn or the address (or handshake line) of a running server.
+
+Redacted metadata isn't sent; give the server's token with KV_AUTH_TOKEN.
+Get values that are JSON objects are compared without the server_handshake
+field servers add. Use --codes-only to compare only status codes, for
+example against a server in another language whose error messages differ.
+Exits non-zero if any call's result differs.`,
+		Example: `  soup-go rpc kv put foo bar --transcript session.ndjson
+  soup-go harness replay session.ndjson --against ./soup-go
+  soup-go harness replay session.ndjson --against 127.0.0.1:50051 --codes-only --output-format json`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if against == "" {
+				return fmt.Errorf("--against is required")
+			}
+			if outputFormat != "text" && outputFormat != "json" {
+				return fmt.Errorf("unknown --output-format %q (expected text or json)", outputFormat)
+			}
+			entries, err := readTranscript(args[0])
+			if err != nil {
+				return err
+			}
+
+			client, err := replayClient(against, tlsCurve)
+			if err != nil {
+				return err
+			}
+			defer client.Kill()
+			rpcClient, err := client.Client()
+			if err != nil {
+				return fmt.Errorf("failed to create RPC client: %w", err)
+			}
+			conn, err := pluginConn(rpcClient)
+			if err != nil {
+				return err
+			}
+
+			report := replayReport{Transcript: args[0], Against: against, Results: []replayResult{}}
+			for _, entry := range entries {
+				result := replayEntry(cmd.Context(), conn, entry, codesOnly)
+				report.Calls++
+				if !result.Passed {
+					report.Mismatches++
+				}
+				report.Results = append(report.Results, result)
+			}
+
+			if outputFormat == "json" {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				if err := encoder.Encode(report); err != nil {
+					return fmt.Errorf("failed to encode JSON: %w", err)
+				}
+			} else {
+				for _, result := range report.Results {
+					if result.Passed {
+						fmt.Printf("✅ %d %s %s\n", result.Seq, result.Method, result.Status.Code)
+					} else {
+						fmt.Printf("❌ %d %s: %s\n", result.Seq, result.Method, result.Mismatch)
+					}
+				}
+				fmt.Printf("replayed %d calls: %d matched, %d differed\n", report.Calls, report.Calls-report.Mismatches, report.Mismatches)
+			}
+
+			if report.Mismatches > 0 {
+				return fmt.Errorf("%d of %d replayed calls differed from the transcript", report.Mismatches, report.Calls)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&against, "against", "", "Server executable to spawn, or address/handshake of a running server")
+	cmd.Flags().StringVar(&tlsCurve, "tls-curve", "auto", "Client cert curve when reattaching: auto (detect from server), secp256r1, secp384r1, secp521r1")
+	cmd.Flags().BoolVar(&codesOnly, "codes-only", false, "Compare only status codes, not messages or responses")
+	cmd.Flags().StringVar(&outputFormat, "output-format", "text", "Output format (text, json)")
+	return cmd
+}
@@ -0,0 +1,496 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/go-plugin"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc/status"
+
+	"github.com/provide-io/tofusoup/proto/kv"
+)
+
+// How restart-under-load stops the server
+const (
+	// scenarioKillGraceful shuts down through the go-plugin controller
+	scenarioKillGraceful = "graceful"
+	// scenarioKillSignal sends SIGKILL, like a crash or OOM kill
+	scenarioKillSignal = "sigkill"
+)
+
+// scenarioErrorBackoff is how long a worker waits after a failed call, so a
+// dead server isn't hammered in a tight loop
+const scenarioErrorBackoff = 20 * time.Millisecond
+
+// restartResult describes one kill and restart of the server
+type restartResult struct {
+	Index int `json:"index"`
+	// AtMs is when the kill started, from the start of the run
+	AtMs   float64 `json:"at_ms"`
+	KillMs float64 `json:"kill_ms"`
+	// StartMs is how long the new server took to handshake and connect
+	StartMs float64 `json:"start_ms"`
+	// RecoveryMs is from the kill to the first successful call on the new
+	// server; absent if no call succeeded before the next restart or the end
+	RecoveryMs *float64 `json:"recovery_ms,omitempty"`
+	// Errors counts failed calls between the kill and recovery
+	Errors int    `json:"errors"`
+	Error  string `json:"error,omitempty"`
+}
+
+// recoveryStats summarizes restartResult.RecoveryMs
+type recoveryStats struct {
+	MinMs float64 `json:"min_ms"`
+	P50Ms float64 `json:"p50_ms"`
+	AvgMs float64 `json:"avg_ms"`
+	MaxMs float64 `json:"max_ms"`
+}
+
+// scenarioReport is the result of restart-under-load
+type scenarioReport struct {
+	Harness         string          `json:"harness"`
+	Version         string          `json:"version"`
+	Scenario        string          `json:"scenario"`
+	ServerCmd       []string        `json:"server_cmd"`
+	KillMode        string          `json:"kill_mode"`
+	Workers         int             `json:"workers"`
+	RestartInterval string          `json:"restart_interval"`
+	DurationMs      float64         `json:"duration_ms"`
+	Requests        int             `json:"requests"`
+	Succeeded       int             `json:"succeeded"`
+	Failed          int             `json:"failed"`
+	Errors          map[string]int  `json:"errors"`
+	Restarts        []restartResult `json:"restarts"`
+	Recovered       int             `json:"recovered"`
+	Recovery        *recoveryStats  `json:"recovery,omitempty"`
+	// LeakCheck has one report per server that ran, with --leak-check
+	LeakCheck []*leakReport `json:"leak_check,omitempty"`
+	// Fixtures is the fixture server's access log, with --fixtures-dir
+	Fixtures *fixturesReport `json:"fixtures,omitempty"`
+}
+
+// scenarioRun is the state shared by the traffic workers and the restart loop
+type scenarioRun struct {
+	start time.Time
+
+	// generation counts restarts; kv is the client for the current server,
+	// nil if the last restart failed
+	connMu     sync.RWMutex
+	generation int
+	kv         proto.KVClient
+
+	mu     sync.Mutex
+	report *scenarioReport
+	// open indexes the restart still waiting for a successful call, or is
+	// -1, and openAt is when that restart's kill started
+	open   int
+	openAt time.Time
+}
+
+func sinceMs(t time.Time) float64 {
+	return float64(time.Since(t).Microseconds()) / 1000
+}
+
+func (r *scenarioRun) current() (int, proto.KVClient) {
+	r.connMu.RLock()
+	defer r.connMu.RUnlock()
+	return r.generation, r.kv
+}
+
+func (r *scenarioRun) swap(kv proto.KVClient) {
+	r.connMu.Lock()
+	defer r.connMu.Unlock()
+	r.generation++
+	r.kv = kv
+}
+
+// record counts a call made on server generation gen. The first success on
+// the generation a restart brought up marks that restart recovered.
+func (r *scenarioRun) record(gen int, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.report.Requests++
+	if err != nil {
+		r.report.Failed++
+		r.report.Errors[status.Code(err).String()]++
+		if r.open >= 0 {
+			r.report.Restarts[r.open].Errors++
+		}
+		return
+	}
+	r.report.Succeeded++
+	if r.open >= 0 && gen == r.open+1 {
+		restart := &r.report.Restarts[r.open]
+		recovery := sinceMs(r.openAt)
+		restart.RecoveryMs = &recovery
+		r.open = -1
+	}
+}
+
+// worker alternates Puts and Gets of its own keys until ctx is done
+func (r *scenarioRun) worker(ctx context.Context, id int, timeout time.Duration) {
+	for i := 0; ctx.Err() == nil; i++ {
+		r.call(ctx, id, i, timeout)
+	}
+}
+
+// call makes worker id's i'th call: a Put for even i and a Get of the key
+// just put for odd i
+func (r *scenarioRun) call(ctx context.Context, id, i int, timeout time.Duration) {
+	gen, kv := r.current()
+	if kv == nil {
+		time.Sleep(scenarioErrorBackoff)
+		return
+	}
+	callCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	var err error
+	if i%2 == 0 {
+		key := fmt.Sprintf("scenario-w%d-%d", id, i%64)
+		_, err = kv.Put(callCtx, &proto.PutRequest{Key: key, Value: []byte(key)})
+	} else {
+		_, err = kv.Get(callCtx, &proto.GetRequest{Key: fmt.Sprintf("scenario-w%d-%d", id, (i-1)%64)})
+	}
+	if ctx.Err() != nil {
+		// Calls cut off by the end of the run aren't the server's fault
+		return
+	}
+	r.record(gen, err)
+	if err != nil {
+		time.Sleep(scenarioErrorBackoff)
+	}
+}
+
+// settle keeps calling the current server until the last restart recovers
+// or ctx is done, so a restart near the end of the run isn't reported as
+// unrecovered just because traffic stopped
+func (r *scenarioRun) settle(ctx context.Context, id int, timeout time.Duration) {
+	for i := 0; ctx.Err() == nil; i += 2 {
+		r.mu.Lock()
+		open := r.open
+		r.mu.Unlock()
+		if open < 0 {
+			return
+		}
+		r.call(ctx, id, i, timeout)
+	}
+}
+
+// stopServer stops client's server the way killMode says
+func stopServer(client *plugin.Client, killMode string) {
+	if killMode == scenarioKillSignal {
+		if reattach := client.ReattachConfig(); reattach != nil && reattach.Pid > 0 {
+			if proc, err := os.FindProcess(reattach.Pid); err == nil {
+				proc.Kill()
+			}
+		}
+	}
+	client.Kill()
+}
+
+type scenarioOptions struct {
+	serverCmd       []string
+	env             []string
+	duration        time.Duration
+	restartInterval time.Duration
+	workers         int
+	killMode        string
+	startTimeout    time.Duration
+	requestTimeout  time.Duration
+	leak            leakOptions
+}
+
+// sampleServer samples the RSS of client's server process for
+// --leak-check, returning nil when leak checks are off
+func sampleServer(client *plugin.Client, index int, opts leakOptions) *leakSampler {
+	if !opts.enabled {
+		return nil
+	}
+	reattach := client.ReattachConfig()
+	if reattach == nil || reattach.Pid <= 0 {
+		return nil
+	}
+	pid := reattach.Pid
+	return startLeakSampler(fmt.Sprintf("server %d (pid %d)", index, pid), "rss",
+		func() (int64, error) { return processRSS(pid) }, opts)
+}
+
+// finishSampler adds a finished sampler's report to the run
+func (r *scenarioRun) finishSampler(sampler *leakSampler) {
+	if sampler == nil {
+		return
+	}
+	report := sampler.finish()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.report.LeakCheck = append(r.report.LeakCheck, report)
+}
+
+// runRestartUnderLoad drives KV traffic from opts.workers goroutines while
+// killing and restarting the server every opts.restartInterval
+func runRestartUnderLoad(ctx context.Context, logger hclog.Logger, opts scenarioOptions) (*scenarioReport, error) {
+	run := &scenarioRun{
+		start: time.Now(),
+		open:  -1,
+		report: &scenarioReport{
+			Harness:         "soup-go",
+			Version:         version,
+			Scenario:        "restart-under-load",
+			ServerCmd:       opts.serverCmd,
+			KillMode:        opts.killMode,
+			Workers:         opts.workers,
+			RestartInterval: opts.restartInterval.String(),
+			Errors:          map[string]int{},
+			Restarts:        []restartResult{},
+		},
+	}
+
+	client, kv, err := startPluginKV(opts.serverCmd, opts.env, opts.startTimeout, logger)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if client != nil {
+			client.Kill()
+		}
+	}()
+	run.kv = kv
+	sampler := sampleServer(client, 0, opts.leak)
+
+	ctx, cancel := context.WithTimeout(ctx, opts.duration)
+	defer cancel()
+	var wg sync.WaitGroup
+	for i := 0; i < opts.workers; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			run.worker(ctx, id, opts.requestTimeout)
+		}(i)
+	}
+
+	ticker := time.NewTicker(opts.restartInterval)
+	defer ticker.Stop()
+	for ctx.Err() == nil {
+		select {
+		case <-ctx.Done():
+			continue
+		case <-ticker.C:
+		}
+
+		killStart := time.Now()
+		run.mu.Lock()
+		restart := restartResult{Index: len(run.report.Restarts), AtMs: sinceMs(run.start)}
+		run.report.Restarts = append(run.report.Restarts, restart)
+		// A previous restart that hasn't recovered by now never will
+		run.open, run.openAt = restart.Index, killStart
+		run.mu.Unlock()
+
+		logger.Info("💥 restarting server", "restart", restart.Index, "kill_mode", opts.killMode)
+		run.finishSampler(sampler)
+		sampler = nil
+		if client != nil {
+			stopServer(client, opts.killMode)
+		}
+		client = nil
+		killMs := sinceMs(killStart)
+
+		startStart := time.Now()
+		newClient, newKV, err := startPluginKV(opts.serverCmd, opts.env, opts.startTimeout, logger)
+		startMs := sinceMs(startStart)
+		run.mu.Lock()
+		r := &run.report.Restarts[restart.Index]
+		r.KillMs, r.StartMs = killMs, startMs
+		if err != nil {
+			r.Error = err.Error()
+		}
+		run.mu.Unlock()
+		if err != nil {
+			logger.Error("💥 server failed to restart", "restart", restart.Index, "error", err)
+		} else {
+			sampler = sampleServer(newClient, restart.Index+1, opts.leak)
+		}
+		client = newClient
+		// Swap even on failure so generations line up with restart indexes
+		run.swap(newKV)
+	}
+	wg.Wait()
+	settleCtx, cancelSettle := context.WithTimeout(context.Background(), opts.startTimeout)
+	run.settle(settleCtx, opts.workers, opts.requestTimeout)
+	cancelSettle()
+	run.finishSampler(sampler)
+
+	run.mu.Lock()
+	defer run.mu.Unlock()
+	report := run.report
+	report.DurationMs = sinceMs(run.start)
+	var recoveries []float64
+	for _, r := range report.Restarts {
+		if r.RecoveryMs != nil {
+			recoveries = append(recoveries, *r.RecoveryMs)
+		}
+	}
+	report.Recovered = len(recoveries)
+	if len(recoveries) > 0 {
+		sort.Float64s(recoveries)
+		stats := &recoveryStats{MinMs: recoveries[0], MaxMs: recoveries[len(recoveries)-1], P50Ms: recoveries[len(recoveries)/2]}
+		for _, ms := range recoveries {
+			stats.AvgMs += ms / float64(len(recoveries))
+		}
+		report.Recovery = stats
+	}
+	return report, nil
+}
+
+func initHarnessScenarioRestartCmd() *cobra.Command {
+	var serverCmd string
+	var opts scenarioOptions
+	var maxRecovery time.Duration
+	var fixturesDir string
+	var outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   "restart-under-load",
+		Short: "Kill and restart a KV server while clients keep calling it",
+		Long: `Start a KV plugin server with --server-cmd, drive continuous Put and Get
+traffic from --workers goroutines, and kill and restart the server every
+--restart-interval until --duration has passed. Workers keep calling
+whichever server is current, so calls in flight during a kill fail the way
+they would for a real client.
+
+The report gives the distribution of client errors by gRPC status code and,
+for each restart, how long the kill and the new server's handshake took and
+the recovery latency: the time from the kill to the first successful call
+on the new server. All servers share one KV_STORAGE_DIR, so NotFound errors
+after a restart point to lost writes.
+
+--kill-mode graceful shuts down through the go-plugin controller; sigkill
+kills the process outright. Exits non-zero if any restart failed or never
+recovered, or if a recovery took longer than --max-recovery.
+
+--leak-check samples each server's resident memory while it runs and fails
+if any server's fitted growth exceeds --leak-threshold-mb. Restarts reset a
+server's memory, so to soak one long-running server, make
+--restart-interval longer than --duration.
+
+--fixtures-dir serves a directory over HTTP for the run, for servers that
+fetch inputs from the URL in SOUP_FIXTURES_URL; the report includes every
+request they made.`,
+		Example: `  soup-go harness scenario restart-under-load --server-cmd "soup rpc kv server --tls-mode auto"
+  soup-go harness scenario restart-under-load --server-cmd "soup-go rpc kv server" --kill-mode sigkill --duration 1m --output-format json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.serverCmd = strings.Fields(serverCmd)
+			if len(opts.serverCmd) == 0 {
+				return fmt.Errorf("--server-cmd is required")
+			}
+			if opts.killMode != scenarioKillGraceful && opts.killMode != scenarioKillSignal {
+				return fmt.Errorf("unknown --kill-mode %q (expected %s or %s)", opts.killMode, scenarioKillGraceful, scenarioKillSignal)
+			}
+			if opts.workers < 1 {
+				return fmt.Errorf("--workers must be at least 1")
+			}
+			if opts.restartInterval <= 0 || opts.duration <= 0 {
+				return fmt.Errorf("--duration and --restart-interval must be positive")
+			}
+			if err := opts.leak.validate(); err != nil {
+				return err
+			}
+
+			storageDir, err := os.MkdirTemp("", "soup-scenario-")
+			if err != nil {
+				return fmt.Errorf("failed to create storage dir: %w", err)
+			}
+			defer os.RemoveAll(storageDir)
+			fixtures, env, err := startScenarioFixtures(fixturesDir, pluginServerEnv(storageDir))
+			if err != nil {
+				return err
+			}
+			if fixtures != nil {
+				defer fixtures.stop()
+			}
+			opts.env = env
+
+			report, err := runRestartUnderLoad(cmd.Context(), logger.Named("scenario"), opts)
+			if err != nil {
+				return err
+			}
+			if fixtures != nil {
+				report.Fixtures = fixtures.stop()
+			}
+
+			if outputFormat == "json" {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				if err := encoder.Encode(report); err != nil {
+					return fmt.Errorf("failed to encode JSON: %w", err)
+				}
+			} else {
+				fmt.Printf("restart-under-load: %d requests, %d succeeded, %d failed over %.1fs (%d workers, kill mode %s)\n",
+					report.Requests, report.Succeeded, report.Failed, report.DurationMs/1000, report.Workers, report.KillMode)
+				codes := make([]string, 0, len(report.Errors))
+				for code := range report.Errors {
+					codes = append(codes, code)
+				}
+				sort.Strings(codes)
+				for _, code := range codes {
+					fmt.Printf("  %-20s %d\n", code, report.Errors[code])
+				}
+				fmt.Println()
+				for _, r := range report.Restarts {
+					recovery := "never recovered"
+					if r.RecoveryMs != nil {
+						recovery = fmt.Sprintf("recovered in %.1fms", *r.RecoveryMs)
+					}
+					if r.Error != "" {
+						recovery = "restart failed: " + r.Error
+					}
+					fmt.Printf("  restart %d at %.1fs: kill %.1fms, start %.1fms, %d errors, %s\n",
+						r.Index, r.AtMs/1000, r.KillMs, r.StartMs, r.Errors, recovery)
+				}
+				if report.Recovery != nil {
+					fmt.Printf("\nRecovery: min %.1fms, p50 %.1fms, avg %.1fms, max %.1fms (%d/%d restarts)\n",
+						report.Recovery.MinMs, report.Recovery.P50Ms, report.Recovery.AvgMs, report.Recovery.MaxMs,
+						report.Recovered, len(report.Restarts))
+				}
+				if len(report.LeakCheck) > 0 {
+					fmt.Println("\nLeak check:")
+					for _, r := range report.LeakCheck {
+						fmt.Printf("  %s\n", leakSummary(r))
+					}
+				}
+				if report.Fixtures != nil {
+					fmt.Printf("\n%s\n", fixturesSummary(report.Fixtures))
+				}
+			}
+
+			if report.Recovered < len(report.Restarts) {
+				return fmt.Errorf("%d of %d restarts did not recover", len(report.Restarts)-report.Recovered, len(report.Restarts))
+			}
+			if maxRecovery > 0 && report.Recovery != nil && report.Recovery.MaxMs > float64(maxRecovery.Milliseconds()) {
+				return fmt.Errorf("slowest recovery %.1fms exceeds --max-recovery %s", report.Recovery.MaxMs, maxRecovery)
+			}
+			return leakCheckResult(report.LeakCheck)
+		},
+	}
+
+	cmd.Flags().StringVar(&serverCmd, "server-cmd", "", "Command that starts the server in plugin mode (split on whitespace)")
+	cmd.Flags().DurationVar(&opts.duration, "duration", 30*time.Second, "How long to run traffic")
+	cmd.Flags().DurationVar(&opts.restartInterval, "restart-interval", 5*time.Second, "Time between server restarts")
+	cmd.Flags().IntVar(&opts.workers, "workers", 4, "Concurrent client goroutines")
+	cmd.Flags().StringVar(&opts.killMode, "kill-mode", scenarioKillGraceful, "How to stop the server: graceful (go-plugin controller shutdown) or sigkill")
+	cmd.Flags().DurationVar(&opts.startTimeout, "start-timeout", 10*time.Second, "Timeout for each server to start and handshake")
+	cmd.Flags().DurationVar(&opts.requestTimeout, "request-timeout", 2*time.Second, "Timeout for each Put or Get")
+	cmd.Flags().DurationVar(&maxRecovery, "max-recovery", 0, "Fail if any recovery takes longer than this (0 is unlimited)")
+	cmd.Flags().StringVar(&outputFormat, "output-format", "text", "Output format (text, json)")
+	addLeakCheckFlags(cmd, &opts.leak, "each server's resident memory")
+	addFixturesDirFlag(cmd, &fixturesDir)
+	return cmd
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/provide-io/tofusoup/proto/kv"
+)
+
+// deadlineCheck is one step of the deadline scenario
+type deadlineCheck struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Status      string  `json:"status"`
+	DurationMs  float64 `json:"duration_ms"`
+	// ReceivedDeadlineMs is what the server echoed in kv-received-deadline-ms
+	ReceivedDeadlineMs string `json:"received_deadline_ms,omitempty"`
+	Error              string `json:"error,omitempty"`
+}
+
+// deadlineReport is the result of 'harness scenario deadline'
+type deadlineReport struct {
+	Harness    string          `json:"harness"`
+	Version    string          `json:"version"`
+	Scenario   string          `json:"scenario"`
+	ServerCmd  []string        `json:"server_cmd"`
+	FaultDelay string          `json:"fault_delay"`
+	ShortMs    int64           `json:"short_deadline_ms"`
+	LongMs     int64           `json:"long_deadline_ms"`
+	Passed     int             `json:"passed"`
+	Failed     int             `json:"failed"`
+	Checks     []deadlineCheck `json:"checks"`
+	// Fixtures is the fixture server's access log, with --fixtures-dir
+	Fixtures *fixturesReport `json:"fixtures,omitempty"`
+}
+
+// deadlineScenario holds the connection and settings the checks share
+type deadlineScenario struct {
+	kv         proto.KVClient
+	faultDelay time.Duration
+	short      time.Duration
+	long       time.Duration
+	key        string
+}
+
+// call runs fn under a deadline timeout away and returns the server's
+// kv-received-deadline-ms trailer
+func (s *deadlineScenario) call(ctx context.Context, timeout time.Duration, fn func(context.Context, ...grpc.CallOption) error) (string, time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	var trailer metadata.MD
+	start := time.Now()
+	err := fn(ctx, grpc.Trailer(&trailer))
+	received := ""
+	if values := trailer.Get(deadlineTrailer); len(values) > 0 {
+		received = values[0]
+	}
+	return received, time.Since(start), err
+}
+
+func (s *deadlineScenario) put(key string) func(context.Context, ...grpc.CallOption) error {
+	return func(ctx context.Context, opts ...grpc.CallOption) error {
+		_, err := s.kv.Put(ctx, &proto.PutRequest{Key: key, Value: []byte("deadline scenario")}, opts...)
+		return err
+	}
+}
+
+func (s *deadlineScenario) get(key string) func(context.Context, ...grpc.CallOption) error {
+	return func(ctx context.Context, opts ...grpc.CallOption) error {
+		_, err := s.kv.Get(ctx, &proto.GetRequest{Key: key}, opts...)
+		return err
+	}
+}
+
+// expectDeadlineExceeded fails unless err is DeadlineExceeded and arrived
+// around the deadline rather than after the slow handler finished
+func (s *deadlineScenario) expectDeadlineExceeded(err error, elapsed time.Duration) error {
+	if code := status.Code(err); code != codes.DeadlineExceeded {
+		return fmt.Errorf("got %s (%v), want DeadlineExceeded", code, err)
+	}
+	if elapsed >= s.faultDelay {
+		return fmt.Errorf("DeadlineExceeded took %s, no sooner than the %s handler delay", elapsed.Round(time.Millisecond), s.faultDelay)
+	}
+	return nil
+}
+
+// deadlineChecks run in order against a server delaying every call by
+// faultDelay; each returns the echoed deadline, if any
+var deadlineChecks = []struct {
+	Name        string
+	Description string
+	Run         func(ctx context.Context, s *deadlineScenario) (string, error)
+}{
+	{"propagated", "A Put with a deadline past the delay succeeds and the server reports receiving that deadline",
+		func(ctx context.Context, s *deadlineScenario) (string, error) {
+			received, _, err := s.call(ctx, s.long, s.put(s.key+"-ok"))
+			if err != nil {
+				return received, fmt.Errorf("put failed: %w", err)
+			}
+			if received == "" {
+				return received, fmt.Errorf("server sent no %s trailer", deadlineTrailer)
+			}
+			ms, err := strconv.ParseInt(received, 10, 64)
+			if err != nil {
+				return received, fmt.Errorf("server received no deadline (%s: %q)", deadlineTrailer, received)
+			}
+			if ms <= 0 || ms > s.long.Milliseconds() {
+				return received, fmt.Errorf("server saw %dms remaining, want between 0 and %dms", ms, s.long.Milliseconds())
+			}
+			return received, nil
+		}},
+	{"put-deadline-exceeded", "A Put with a deadline shorter than the delay fails with DeadlineExceeded",
+		func(ctx context.Context, s *deadlineScenario) (string, error) {
+			received, elapsed, err := s.call(ctx, s.short, s.put(s.key+"-abandoned"))
+			return received, s.expectDeadlineExceeded(err, elapsed)
+		}},
+	{"get-deadline-exceeded", "A Get with a deadline shorter than the delay fails with DeadlineExceeded",
+		func(ctx context.Context, s *deadlineScenario) (string, error) {
+			received, elapsed, err := s.call(ctx, s.short, s.get(s.key+"-ok"))
+			return received, s.expectDeadlineExceeded(err, elapsed)
+		}},
+	{"put-abandoned", "The server drops a Put whose deadline passed instead of applying it late",
+		func(ctx context.Context, s *deadlineScenario) (string, error) {
+			// Give a server that ignored the deadline time to apply the write
+			time.Sleep(s.faultDelay)
+			received, _, err := s.call(ctx, s.long, s.get(s.key+"-abandoned"))
+			if err == nil {
+				return received, fmt.Errorf("key written by the timed-out Put exists")
+			}
+			if code := status.Code(err); code != codes.NotFound {
+				return received, fmt.Errorf("got %s (%v), want NotFound", code, err)
+			}
+			return received, nil
+		}},
+	{"recovers", "Calls with enough time still succeed after deadlines were exceeded",
+		func(ctx context.Context, s *deadlineScenario) (string, error) {
+			received, _, err := s.call(ctx, s.long, s.get(s.key+"-ok"))
+			return received, err
+		}},
+}
+
+func runDeadlineScenario(ctx context.Context, logger hclog.Logger, serverCmd, env []string, startTimeout time.Duration, s *deadlineScenario) (*deadlineReport, error) {
+	client, kv, err := startPluginKV(serverCmd, env, startTimeout, logger)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Kill()
+	s.kv = kv
+
+	report := &deadlineReport{
+		Harness:    "soup-go",
+		Version:    version,
+		Scenario:   "deadline",
+		ServerCmd:  serverCmd,
+		FaultDelay: s.faultDelay.String(),
+		ShortMs:    s.short.Milliseconds(),
+		LongMs:     s.long.Milliseconds(),
+	}
+	for _, check := range deadlineChecks {
+		logger.Debug("⏱️ running deadline check", "check", check.Name)
+		start := time.Now()
+		received, err := check.Run(ctx, s)
+		result := deadlineCheck{
+			Name:               check.Name,
+			Description:        check.Description,
+			Status:             lintPass,
+			DurationMs:         sinceMs(start),
+			ReceivedDeadlineMs: received,
+		}
+		if err != nil {
+			result.Status = lintFail
+			result.Error = err.Error()
+			report.Failed++
+		} else {
+			report.Passed++
+		}
+		report.Checks = append(report.Checks, result)
+	}
+	return report, nil
+}
+
+func initHarnessScenarioDeadlineCmd() *cobra.Command {
+	var serverCmd string
+	var delay time.Duration
+	var shortMs int64
+	var startTimeout time.Duration
+	var fixturesDir string
+	var outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   "deadline",
+		Short: "Check that a KV server receives deadlines and surfaces DeadlineExceeded",
+		Long: `Start a KV plugin server with --server-cmd and KV_FAULT_DELAY set, so it
+holds every call for --fault-delay, then check deadline handling end to end:
+
+  propagated             a call with time to spare succeeds and the server
+                         echoes the deadline it received in the
+                         kv-received-deadline-ms trailer
+  put-deadline-exceeded  a Put with a --deadline-ms deadline shorter than
+  get-deadline-exceeded  the delay fails with DeadlineExceeded, around the
+                         deadline rather than after the delay
+  put-abandoned          the timed-out Put was not applied late
+  recovers               calls with enough time still succeed afterwards
+
+Servers must honor KV_FAULT_DELAY for the scenario to be meaningful; the Go
+and Python harness servers do. Exits non-zero if any check fails.
+
+--fixtures-dir serves a directory over HTTP for the run, passing its URL to
+the server as SOUP_FIXTURES_URL; the report includes every request made.`,
+		Example: `  soup-go harness scenario deadline --server-cmd "soup rpc kv server --tls-mode auto"
+  soup-go harness scenario deadline --server-cmd "soup-go rpc kv server" --fault-delay 1s --deadline-ms 200`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			argv := strings.Fields(serverCmd)
+			if len(argv) == 0 {
+				return fmt.Errorf("--server-cmd is required")
+			}
+			short := time.Duration(shortMs) * time.Millisecond
+			if short <= 0 || short >= delay {
+				return fmt.Errorf("--deadline-ms must be positive and shorter than --fault-delay")
+			}
+
+			storageDir, err := os.MkdirTemp("", "soup-deadline-")
+			if err != nil {
+				return fmt.Errorf("failed to create storage dir: %w", err)
+			}
+			defer os.RemoveAll(storageDir)
+			fixtures, env, err := startScenarioFixtures(fixturesDir,
+				append(pluginServerEnv(storageDir), faultDelayEnv+"="+delay.String()))
+			if err != nil {
+				return err
+			}
+			if fixtures != nil {
+				defer fixtures.stop()
+			}
+
+			s := &deadlineScenario{
+				faultDelay: delay,
+				short:      short,
+				long:       delay + 5*time.Second,
+				key:        fmt.Sprintf("deadline-%d", time.Now().UnixNano()),
+			}
+			report, err := runDeadlineScenario(cmd.Context(), logger.Named("scenario"), argv, env, startTimeout, s)
+			if err != nil {
+				return err
+			}
+			if fixtures != nil {
+				report.Fixtures = fixtures.stop()
+			}
+
+			if outputFormat == "json" {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				if err := encoder.Encode(report); err != nil {
+					return fmt.Errorf("failed to encode JSON: %w", err)
+				}
+			} else {
+				for _, c := range report.Checks {
+					icon := map[string]string{lintPass: "✅", lintFail: "❌"}[c.Status]
+					fmt.Printf("%s %-22s %s\n", icon, c.Name, c.Description)
+					if c.ReceivedDeadlineMs != "" {
+						fmt.Printf("   server received deadline: %sms\n", c.ReceivedDeadlineMs)
+					}
+					if c.Error != "" {
+						fmt.Printf("   %s\n", c.Error)
+					}
+				}
+				fmt.Printf("\n%d passed, %d failed (fault delay %s, deadlines %dms and %dms)\n",
+					report.Passed, report.Failed, report.FaultDelay, report.ShortMs, report.LongMs)
+				if report.Fixtures != nil {
+					fmt.Println(fixturesSummary(report.Fixtures))
+				}
+			}
+
+			if report.Failed > 0 {
+				return fmt.Errorf("%d deadline checks failed", report.Failed)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&serverCmd, "server-cmd", "", "Command that starts the server in plugin mode (split on whitespace)")
+	cmd.Flags().DurationVar(&delay, "fault-delay", 500*time.Millisecond, "Delay the server injects into every call")
+	cmd.Flags().Int64Var(&shortMs, "deadline-ms", 100, "Deadline for the calls expected to time out, in milliseconds")
+	cmd.Flags().DurationVar(&startTimeout, "start-timeout", 10*time.Second, "Timeout for the server to start and handshake")
+	cmd.Flags().StringVar(&outputFormat, "output-format", "text", "Output format (text, json)")
+	addFixturesDirFlag(cmd, &fixturesDir)
+	return cmd
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bufio"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -18,6 +19,7 @@ import (
 // HCL output format flag
 var hclOutputFormat string
 var hclConvertOutputFormat string
+var hclStream streamOptions
 
 // Override the convert command with real implementation
 func initHclConvertCmd() *cobra.Command {
@@ -29,6 +31,15 @@ func initHclConvertCmd() *cobra.Command {
 			inputPath := args[0]
 			outputPath := args[1]
 
+			if hclStream.Enabled {
+				if err := hclStream.apply(); err != nil {
+					return err
+				}
+				if err := hclStream.checkSourceSize(inputPath); err != nil {
+					return err
+				}
+			}
+
 			// Read the HCL file
 			content, err := os.ReadFile(inputPath)
 			if err != nil {
@@ -43,11 +54,27 @@ func initHclConvertCmd() *cobra.Command {
 			}
 
 			// Convert to JSON representation first
-			jsonResult, err := hclFileToJSON(file)
+			var jsonResult interface{}
+			err = evalLimit.run(inputPath, func() (err error) {
+				jsonResult, err = hclFileToJSON(file)
+				return err
+			})
 			if err != nil {
 				return fmt.Errorf("failed to convert HCL to intermediate JSON: %w", err)
 			}
 
+			// In streaming mode JSON is encoded straight to the output
+			if hclStream.Enabled && hclConvertOutputFormat == "json" {
+				return writeStreamOutput(outputPath, false, func(w *bufio.Writer) error {
+					encoder := json.NewEncoder(w)
+					encoder.SetIndent("", "  ")
+					if err := encoder.Encode(jsonResult); err != nil {
+						return fmt.Errorf("failed to marshal to JSON: %w", err)
+					}
+					return nil
+				})
+			}
+
 			// Marshal to final output format
 			var outputData []byte
 			switch hclConvertOutputFormat {
@@ -100,29 +127,66 @@ func initHclConvertCmd() *cobra.Command {
 	
 	// Add flags
 	cmd.Flags().StringVar(&hclConvertOutputFormat, "output-format", "json", "Output format (json, msgpack)")
+	addStreamFlags(cmd, &hclStream)
+	addEvalLimitFlags(cmd, &evalLimit)
 	
 	return cmd
 }
 
 // Override the parse command with real implementation
 func initHclViewCmd() *cobra.Command {
+	var fragmentKind string
+
 	cmd := &cobra.Command{
 		Use:   "view [file]",
 		Short: "Parse an HCL file and view its structure",
-		Args:  cobra.ExactArgs(1),
+		Long: `Parse an HCL file and print its structure. JSON output includes the
+evaluated body and a document tree in which every attribute expression is
+classified (literal, template, function-call, reference, collection,
+operation, conditional, for, splat) with its references, the functions it
+calls and, when it has no references, its evaluated value and type. Use
+--output-format tree for the same tree as indented text.
+
+--fragment expr or template parses the input as a single expression or
+template, whose tree is the one expression node and whose body is null;
+--fragment body parses attributes and blocks without a file around them.
+Pass - to read the input from stdin.`,
+		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			filename := args[0]
+			if err := checkFragmentKind(fragmentKind); err != nil {
+				return err
+			}
+
+			if hclStream.Enabled {
+				if err := hclStream.apply(); err != nil {
+					return err
+				}
+				if args[0] != "-" {
+					if err := hclStream.checkSourceSize(args[0]); err != nil {
+						return err
+					}
+				}
+			}
 
 			// Read the file
-			content, err := os.ReadFile(filename)
+			content, filename, err := readHCLSource(args[0])
 			if err != nil {
-				return fmt.Errorf("failed to read file: %w", err)
+				return err
 			}
 
-			// Parse the HCL file
-			parser := hclparse.NewParser()
-			file, diags := parser.ParseHCL(content, filename)
+			// Parse the HCL file, or the fragment
+			fragment, diags := parseHCLFragment(content, filename, fragmentKind)
 			
+			if hclOutputFormat == "github" {
+				if err := writeHCLDiagnosticsGitHub(os.Stdout, diags, filename); err != nil {
+					return fmt.Errorf("failed to write annotations: %w", err)
+				}
+				if diags.HasErrors() {
+					return fmt.Errorf("parse errors occurred")
+				}
+				return nil
+			}
+
 			if diags.HasErrors() {
 				if hclOutputFormat == "diagnostic" {
 					for _, diag := range diags {
@@ -139,17 +203,37 @@ func initHclViewCmd() *cobra.Command {
 				return nil
 			}
 
-			// Convert to JSON representation
-			result, err := hclFileToJSON(file)
+			// Convert to JSON representation and build the document tree,
+			// both of which evaluate expressions
+			var result interface{}
+			var tree *hclTreeNode
+			err = evalLimit.run(filename, func() (err error) {
+				if fragment.File != nil {
+					if result, err = hclFileToJSON(fragment.File); err != nil {
+						return fmt.Errorf("failed to convert HCL to JSON: %w", err)
+					}
+				}
+				if tree, err = fragment.tree(); err != nil {
+					return fmt.Errorf("failed to build document tree: %w", err)
+				}
+				return nil
+			})
 			if err != nil {
-				return fmt.Errorf("failed to convert HCL to JSON: %w", err)
+				return err
 			}
 
 			// Output the result
-			if hclOutputFormat == "json" {
+			switch hclOutputFormat {
+			case "tree":
+				writeHCLTree(os.Stdout, filename, tree)
+			case "json":
 				output := map[string]interface{}{
 					"success": true,
 					"body":    result,
+					"tree":    tree,
+				}
+				if fragmentKind != hclFragmentFile {
+					output["fragment"] = fragmentKind
 				}
 				if err := json.NewEncoder(os.Stdout).Encode(output); err != nil {
 					return fmt.Errorf("failed to encode JSON: %w", err)
@@ -161,33 +245,78 @@ func initHclViewCmd() *cobra.Command {
 	}
 	
 	// Add flags
-	cmd.Flags().StringVar(&hclOutputFormat, "output-format", "json", "Output format (json, diagnostic)")
+	cmd.Flags().StringVar(&hclOutputFormat, "output-format", "json", "Output format (json, tree, diagnostic, github)")
+	addFragmentFlag(cmd, &fragmentKind)
+	addStreamFlags(cmd, &hclStream)
+	addEvalLimitFlags(cmd, &evalLimit)
 	
 	return cmd
 }
 
 // Override the validate command with real implementation
 func initHclValidateCmd() *cobra.Command {
+	var outputFormat string
+	var fragmentKind string
+
 	cmd := &cobra.Command{
 		Use:   "validate [file]",
 		Short: "Validate HCL syntax",
-		Args:  cobra.ExactArgs(1),
+		Long: `Validate HCL syntax. JSON output reports validity and any errors; with
+--output-format github each diagnostic is printed as a GitHub Actions
+workflow annotation instead, and the command fails if there are errors.
+
+--fragment validates a snippet as a body, a single expr or a template
+rather than a whole file, with error ranges relative to the snippet. Pass
+- to read the input from stdin.`,
+		Example: `  soup-go hcl validate main.tf
+  echo '[for s in var.list : upper(s)]' | soup-go hcl validate --fragment expr -
+  soup-go hcl validate --fragment template greeting.tmpl`,
+		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			filename := args[0]
+			if err := checkFragmentKind(fragmentKind); err != nil {
+				return err
+			}
+
+			if hclStream.Enabled {
+				if err := hclStream.apply(); err != nil {
+					return err
+				}
+				if args[0] != "-" {
+					if err := hclStream.checkSourceSize(args[0]); err != nil {
+						return err
+					}
+				}
+			}
 
 			// Read the file
-			content, err := os.ReadFile(filename)
+			content, filename, err := readHCLSource(args[0])
 			if err != nil {
-				return fmt.Errorf("failed to read file: %w", err)
+				return err
 			}
 
-			// Parse the HCL file for validation
-			parser := hclparse.NewParser()
-			_, diags := parser.ParseHCL(content, filename)
+			// Parse the HCL file, or the fragment, for validation
+			_, diags := parseHCLFragment(content, filename, fragmentKind)
+
+			switch outputFormat {
+			case "json":
+			case "github":
+				if err := writeHCLDiagnosticsGitHub(os.Stdout, diags, filename); err != nil {
+					return fmt.Errorf("failed to write annotations: %w", err)
+				}
+				if diags.HasErrors() {
+					return fmt.Errorf("HCL validation failed")
+				}
+				return nil
+			default:
+				return fmt.Errorf("unsupported output format: %s", outputFormat)
+			}
 
 			result := map[string]interface{}{
 				"valid": !diags.HasErrors(),
 			}
+			if fragmentKind != hclFragmentFile {
+				result["fragment"] = fragmentKind
+			}
 
 			if diags.HasErrors() {
 				result["errors"] = diagnosticsToJSON(diags)
@@ -202,6 +331,10 @@ func initHclValidateCmd() *cobra.Command {
 		},
 	}
 	
+	cmd.Flags().StringVar(&outputFormat, "output-format", "json", "Output format (json, github)")
+	addFragmentFlag(cmd, &fragmentKind)
+	addStreamFlags(cmd, &hclStream)
+
 	return cmd
 }
 
@@ -219,7 +352,7 @@ func hclFileToJSON(file *hcl.File) (interface{}, error) {
 				Variables: map[string]cty.Value{},
 				Functions: map[string]function.Function{},
 			})
-			if !diags.HasErrors() {
+			if !diags.HasErrors() && evalLimit.checkValue(name, val) == nil {
 				jsonVal, err := ctyjson.Marshal(val, val.Type())
 				if err == nil {
 					var v interface{}
@@ -265,7 +398,7 @@ func hclBlockToJSON(body hcl.Body) (interface{}, error) {
 				Variables: map[string]cty.Value{},
 				Functions: map[string]function.Function{},
 			})
-			if !diags.HasErrors() {
+			if !diags.HasErrors() && evalLimit.checkValue(name, val) == nil {
 				jsonVal, err := ctyjson.Marshal(val, val.Type())
 				if err == nil {
 					var v interface{}
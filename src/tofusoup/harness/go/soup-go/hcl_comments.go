@@ -0,0 +1,260 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/spf13/cobra"
+)
+
+// Comment attachments reported by hcl comments
+const (
+	commentLeading  = "leading"  // on the lines before a node
+	commentTrailing = "trailing" // after a node, on its last line
+	commentInline   = "inline"   // inside a node's range
+	commentDangling = "dangling" // after the last node in a body
+)
+
+// hclComment is a comment and the node it belongs to
+type hclComment struct {
+	Text       string       `json:"text"`
+	Style      string       `json:"style"`
+	Range      hclTreeRange `json:"range"`
+	Attachment string       `json:"attachment"`
+	Node       string       `json:"node"`
+	NodeKind   string       `json:"node_kind"`
+}
+
+// commentRoundTrip reports whether hclwrite keeps every comment
+type commentRoundTrip struct {
+	BytesIdentical          bool     `json:"bytes_identical"`
+	CommentsPreserved       bool     `json:"comments_preserved"`
+	FormatCommentsPreserved bool     `json:"format_comments_preserved"`
+	Missing                 []string `json:"missing,omitempty"`
+	MissingAfterFormat      []string `json:"missing_after_format,omitempty"`
+}
+
+// commentNode is an attribute or block that comments can attach to
+type commentNode struct {
+	path     string
+	kind     string
+	rng      hcl.Range
+	children []*commentNode
+}
+
+// commentNodes returns the attributes and blocks of body in source order
+func commentNodes(body *hclsyntax.Body, prefix string) []*commentNode {
+	var nodes []*commentNode
+	for name, attr := range body.Attributes {
+		nodes = append(nodes, &commentNode{path: prefix + name, kind: "attribute", rng: attr.SrcRange})
+	}
+	for _, block := range body.Blocks {
+		path := prefix + strings.Join(append([]string{block.Type}, block.Labels...), ".")
+		nodes = append(nodes, &commentNode{
+			path:     path,
+			kind:     "block",
+			rng:      block.Range(),
+			children: commentNodes(block.Body, path+"."),
+		})
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].rng.Start.Byte < nodes[j].rng.Start.Byte })
+	return nodes
+}
+
+// attachComment finds the node a comment belongs to among siblings, whose
+// enclosing node is parent
+func attachComment(rng hcl.Range, siblings []*commentNode, parent *commentNode) (string, *commentNode) {
+	for _, node := range siblings {
+		if rng.Start.Byte >= node.rng.Start.Byte && rng.Start.Byte < node.rng.End.Byte {
+			if node.kind == "block" {
+				return attachComment(rng, node.children, node)
+			}
+			return commentInline, node
+		}
+	}
+
+	// The last node ending on the comment's first line, before it
+	var trailing *commentNode
+	for _, node := range siblings {
+		if node.rng.End.Line == rng.Start.Line && node.rng.End.Byte <= rng.Start.Byte {
+			trailing = node
+		}
+	}
+	if trailing != nil {
+		return commentTrailing, trailing
+	}
+
+	for _, node := range siblings {
+		if node.rng.Start.Byte >= rng.End.Byte {
+			return commentLeading, node
+		}
+	}
+	return commentDangling, parent
+}
+
+// lexComments returns the comment tokens in src
+func lexComments(src []byte, filename string) ([]hclsyntax.Token, hcl.Diagnostics) {
+	tokens, diags := hclsyntax.LexConfig(src, filename, hcl.Pos{Line: 1, Column: 1, Byte: 0})
+	var comments []hclsyntax.Token
+	for _, tok := range tokens {
+		if tok.Type == hclsyntax.TokenComment {
+			comments = append(comments, tok)
+		}
+	}
+	return comments, diags
+}
+
+// commentText returns a comment's text without the line break that ends
+// line comments
+func commentText(tok hclsyntax.Token) string {
+	return strings.TrimRight(string(tok.Bytes), "\r\n")
+}
+
+// extractComments lists the comments in a parsed file with their attachments
+func extractComments(file *hcl.File, filename string) ([]hclComment, error) {
+	body, ok := file.Body.(*hclsyntax.Body)
+	if !ok {
+		return nil, fmt.Errorf("unsupported body type %T", file.Body)
+	}
+	root := &commentNode{path: "", kind: "file", rng: body.SrcRange, children: commentNodes(body, "")}
+
+	tokens, diags := lexComments(file.Bytes, filename)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("failed to lex: %s", diags.Error())
+	}
+
+	comments := make([]hclComment, 0, len(tokens))
+	for _, tok := range tokens {
+		text := commentText(tok)
+		style := "line"
+		if strings.HasPrefix(text, "/*") {
+			style = "block"
+		}
+		attachment, node := attachComment(tok.Range, root.children, root)
+		comments = append(comments, hclComment{
+			Text:       text,
+			Style:      style,
+			Range:      treeRange(tok.Range),
+			Attachment: attachment,
+			Node:       node.path,
+			NodeKind:   node.kind,
+		})
+	}
+	return comments, nil
+}
+
+// missingComments returns the comments of want that don't appear, in order,
+// in got. Trailing whitespace is ignored since formatting may strip it.
+func missingComments(want []hclComment, got []hclsyntax.Token) []string {
+	var missing []string
+	next := 0
+	for _, c := range want {
+		text := strings.TrimRight(c.Text, " \t")
+		found := false
+		for i := next; i < len(got); i++ {
+			if strings.TrimRight(commentText(got[i]), " \t") == text {
+				next = i + 1
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, c.Text)
+		}
+	}
+	return missing
+}
+
+// checkCommentRoundTrip parses src with hclwrite and checks that writing it
+// back, both as-is and formatted, keeps every comment
+func checkCommentRoundTrip(src []byte, filename string, comments []hclComment) (*commentRoundTrip, error) {
+	f, diags := hclwrite.ParseConfig(src, filename, hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("hclwrite failed to parse: %s", diags.Error())
+	}
+	written := f.Bytes()
+
+	result := &commentRoundTrip{BytesIdentical: string(written) == string(src)}
+
+	writtenComments, diags := lexComments(written, filename)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("failed to lex hclwrite output: %s", diags.Error())
+	}
+	result.Missing = missingComments(comments, writtenComments)
+	result.CommentsPreserved = len(result.Missing) == 0
+
+	formatted := hclwrite.Format(src)
+	formattedComments, diags := lexComments(formatted, filename)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("failed to lex formatted output: %s", diags.Error())
+	}
+	result.MissingAfterFormat = missingComments(comments, formattedComments)
+	result.FormatCommentsPreserved = len(result.MissingAfterFormat) == 0
+
+	return result, nil
+}
+
+func initHclCommentsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "comments [file]",
+		Short: "List HCL comments with their attachment and check hclwrite preserves them",
+		Long: `List every comment in an HCL file with its range, style (line or block) and
+attachment: leading (before a node), trailing (after a node on its last
+line), inline (inside a node) or dangling (after the last node of a body).
+The file is then round-tripped through hclwrite, as-is and formatted, and
+any comments lost along the way are reported. Exits non-zero if a comment
+is lost.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			filename := args[0]
+
+			content, err := os.ReadFile(filename)
+			if err != nil {
+				return fmt.Errorf("failed to read file: %w", err)
+			}
+
+			file, diags := hclparse.NewParser().ParseHCL(content, filename)
+			if diags.HasErrors() {
+				json.NewEncoder(os.Stdout).Encode(map[string]interface{}{
+					"success": false,
+					"errors":  diagnosticsToJSON(diags),
+				})
+				return fmt.Errorf("parse errors occurred")
+			}
+
+			comments, err := extractComments(file, filename)
+			if err != nil {
+				return err
+			}
+			roundTrip, err := checkCommentRoundTrip(content, filename, comments)
+			if err != nil {
+				return err
+			}
+
+			if err := json.NewEncoder(os.Stdout).Encode(map[string]interface{}{
+				"success":   true,
+				"file":      filename,
+				"comments":  comments,
+				"count":     len(comments),
+				"roundtrip": roundTrip,
+			}); err != nil {
+				return fmt.Errorf("failed to encode JSON: %w", err)
+			}
+
+			if !roundTrip.CommentsPreserved || !roundTrip.FormatCommentsPreserved {
+				return fmt.Errorf("hclwrite lost %d comment(s), %d after formatting",
+					len(roundTrip.Missing), len(roundTrip.MissingAfterFormat))
+			}
+			return nil
+		},
+	}
+
+	return cmd
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const hclCommentsTestConfig = `# file header
+region = "us-east-1" # trailing region
+
+resource "aws_instance" "web" {
+  /* leading ami */
+  ami = "ami-123"
+  tags = {
+    # inline tag comment
+    env = "test"
+  }
+  // dangling in block
+}
+
+# dangling at end
+`
+
+func TestHCLCommentsCmd(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.tf")
+	if err := os.WriteFile(path, []byte(hclCommentsTestConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cmd := initHclCommentsCmd()
+	cmd.SetArgs([]string{path})
+	out, err := captureStdout(t, cmd.Execute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var output struct {
+		Success   bool             `json:"success"`
+		Count     int              `json:"count"`
+		Comments  []hclComment     `json:"comments"`
+		RoundTrip commentRoundTrip `json:"roundtrip"`
+	}
+	if err := json.Unmarshal(out, &output); err != nil {
+		t.Fatalf("%v: %s", err, out)
+	}
+	if !output.Success || !output.RoundTrip.BytesIdentical || !output.RoundTrip.CommentsPreserved ||
+		!output.RoundTrip.FormatCommentsPreserved {
+		t.Errorf("got %s, want every comment preserved", out)
+	}
+
+	want := []hclComment{
+		{Text: "# file header", Style: "line", Attachment: commentLeading, Node: "region", NodeKind: "attribute"},
+		{Text: "# trailing region", Style: "line", Attachment: commentTrailing, Node: "region", NodeKind: "attribute"},
+		{Text: "/* leading ami */", Style: "block", Attachment: commentLeading, Node: "resource.aws_instance.web.ami", NodeKind: "attribute"},
+		{Text: "# inline tag comment", Style: "line", Attachment: commentInline, Node: "resource.aws_instance.web.tags", NodeKind: "attribute"},
+		{Text: "// dangling in block", Style: "line", Attachment: commentDangling, Node: "resource.aws_instance.web", NodeKind: "block"},
+		{Text: "# dangling at end", Style: "line", Attachment: commentDangling, Node: "", NodeKind: "file"},
+	}
+	if output.Count != len(want) || len(output.Comments) != len(want) {
+		t.Fatalf("got %d comments, want %d: %s", len(output.Comments), len(want), out)
+	}
+	for i, w := range want {
+		got := output.Comments[i]
+		got.Range = hclTreeRange{}
+		if got != w {
+			t.Errorf("comment %d: got %+v, want %+v", i, got, w)
+		}
+	}
+	if r := output.Comments[1].Range; r.Start != "2:22" {
+		t.Errorf("got trailing comment range %+v, want it to start at 2:22", r)
+	}
+}
+
+func TestMissingComments(t *testing.T) {
+	want := []hclComment{{Text: "# one"}, {Text: "# two  "}, {Text: "# three"}}
+	got, diags := lexComments([]byte("# one\na = 1 # two\n"), "test.tf")
+	if diags.HasErrors() {
+		t.Fatal(diags.Error())
+	}
+	if missing := missingComments(want, got); strings.Join(missing, ",") != "# three" {
+		t.Errorf("got missing %q, want only # three", missing)
+	}
+}
@@ -0,0 +1,604 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/spf13/cobra"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Dependency graphs
+//
+// hcl deps graph is the reference for building a graph from a
+// configuration's references, as Terraform does before planning. Top-level
+// blocks are nodes, addressed the way references name them (var.region,
+// aws_instance.web, data.aws_ami.ubuntu, module.vpc), as are each local
+// value and any top-level attribute. A traversal in a node's body is an
+// edge to the node with the longest address it starts with; depends_on
+// and provider arguments are edges of their own kinds.
+
+// Edge kinds
+const (
+	depsEdgeReference = "reference"
+	depsEdgeDependsOn = "depends_on"
+	depsEdgeProvider  = "provider"
+)
+
+// depsBuiltinRoots are the roots Terraform defines itself, which no block
+// declares
+var depsBuiltinRoots = map[string]bool{"count": true, "each": true, "self": true, "path": true, "terraform": true}
+
+// hclDepsGraph is the output of hcl deps graph
+type hclDepsGraph struct {
+	Success    bool                `json:"success"`
+	Files      []string            `json:"files"`
+	Nodes      []hclDepsNode       `json:"nodes"`
+	Edges      []hclDepsEdge       `json:"edges"`
+	Unresolved []hclDepsUnresolved `json:"unresolved"`
+	// Cycles are the strongly connected components with more than one
+	// node, and nodes that refer to themselves, each sorted
+	Cycles  [][]string `json:"cycles"`
+	Acyclic bool       `json:"acyclic"`
+	// Order lists the nodes dependencies first, ties broken by address;
+	// it is empty when the graph has a cycle
+	Order []string `json:"order"`
+	// Errors are duplicate definitions, which unset Success; the first
+	// definition is kept
+	Errors []string `json:"errors,omitempty"`
+}
+
+// hclDepsNode is a block, local value or top-level attribute
+type hclDepsNode struct {
+	Address string       `json:"address"`
+	Kind    string       `json:"kind"`
+	File    string       `json:"file"`
+	Range   hclTreeRange `json:"range"`
+}
+
+// hclDepsEdge is a dependency of From on To, with every reference that
+// makes it
+type hclDepsEdge struct {
+	From       string             `json:"from"`
+	To         string             `json:"to"`
+	Kind       string             `json:"kind"`
+	References []hclDepsReference `json:"references"`
+}
+
+// hclDepsReference is one traversal. Attribute is its attribute's path in
+// the node, with nested blocks' types and labels (ebs.volume_size), and
+// empty for local values and top-level attributes.
+type hclDepsReference struct {
+	Attribute string       `json:"attribute,omitempty"`
+	Traversal string       `json:"traversal"`
+	File      string       `json:"file"`
+	Range     hclTreeRange `json:"range"`
+}
+
+// hclDepsUnresolved is a traversal that names no node: builtin for
+// count, each, self, path and terraform, otherwise undeclared
+type hclDepsUnresolved struct {
+	From      string           `json:"from"`
+	Reason    string           `json:"reason"`
+	Reference hclDepsReference `json:"reference"`
+}
+
+// depsBody is a node's body, or the single attribute that is the node
+type depsBody struct {
+	node  string
+	file  string
+	body  *hclsyntax.Body
+	attrs []*hclsyntax.Attribute
+}
+
+// depsBlockNode is a top-level block's address and kind
+func depsBlockNode(block *hclsyntax.Block) (string, string) {
+	labels := strings.Join(block.Labels, ".")
+	switch block.Type {
+	case "resource":
+		return labels, "resource"
+	case "data":
+		return "data." + labels, "data"
+	case "module":
+		return "module." + labels, "module"
+	case "variable":
+		return "var." + labels, "variable"
+	case "output":
+		return "output." + labels, "output"
+	case "provider":
+		address := "provider." + labels
+		if alias, ok := block.Body.Attributes["alias"]; ok {
+			if val, diags := alias.Expr.Value(nil); !diags.HasErrors() && val.Type() == cty.String && val.IsKnown() && !val.IsNull() {
+				address += "." + val.AsString()
+			}
+		}
+		return address, "provider"
+	}
+	if labels == "" {
+		return block.Type, "block"
+	}
+	return block.Type + "." + labels, "block"
+}
+
+// depsTraversalNames are the names a traversal starts with, up to its first
+// index or splat
+func depsTraversalNames(traversal hcl.Traversal) []string {
+	var names []string
+	for _, step := range traversal {
+		switch s := step.(type) {
+		case hcl.TraverseRoot:
+			names = append(names, s.Name)
+		case hcl.TraverseAttr:
+			names = append(names, s.Name)
+		default:
+			return names
+		}
+	}
+	return names
+}
+
+// hclDepsBuilder accumulates nodes and edges across files
+type hclDepsBuilder struct {
+	graph  *hclDepsGraph
+	nodes  map[string]hclDepsNode
+	bodies []depsBody
+	edges  map[[3]string]*hclDepsEdge
+}
+
+func newHCLDepsBuilder() *hclDepsBuilder {
+	return &hclDepsBuilder{
+		graph: &hclDepsGraph{
+			Success:    true,
+			Files:      []string{},
+			Nodes:      []hclDepsNode{},
+			Edges:      []hclDepsEdge{},
+			Unresolved: []hclDepsUnresolved{},
+			Cycles:     [][]string{},
+			Order:      []string{},
+		},
+		nodes: map[string]hclDepsNode{},
+		edges: map[[3]string]*hclDepsEdge{},
+	}
+}
+
+// declare adds a node unless its address is taken
+func (b *hclDepsBuilder) declare(node hclDepsNode) bool {
+	if first, ok := b.nodes[node.Address]; ok {
+		b.graph.Errors = append(b.graph.Errors, fmt.Sprintf("duplicate definition of %s at %s:%s (first defined at %s:%s)",
+			node.Address, node.File, node.Range.Start, first.File, first.Range.Start))
+		return false
+	}
+	b.nodes[node.Address] = node
+	return true
+}
+
+// addFile declares the nodes in one file's body
+func (b *hclDepsBuilder) addFile(filename string, body *hclsyntax.Body) {
+	b.graph.Files = append(b.graph.Files, filename)
+	for _, attr := range sortedAttributes(body) {
+		node := hclDepsNode{Address: attr.Name, Kind: "attribute", File: filename, Range: treeRange(attr.SrcRange)}
+		if b.declare(node) {
+			b.bodies = append(b.bodies, depsBody{node: node.Address, file: filename, attrs: []*hclsyntax.Attribute{attr}})
+		}
+	}
+	for _, block := range body.Blocks {
+		if block.Type == "locals" {
+			for _, attr := range sortedAttributes(block.Body) {
+				node := hclDepsNode{Address: "local." + attr.Name, Kind: "local", File: filename, Range: treeRange(attr.SrcRange)}
+				if b.declare(node) {
+					b.bodies = append(b.bodies, depsBody{node: node.Address, file: filename, attrs: []*hclsyntax.Attribute{attr}})
+				}
+			}
+			continue
+		}
+		address, kind := depsBlockNode(block)
+		node := hclDepsNode{Address: address, Kind: kind, File: filename, Range: treeRange(block.DefRange())}
+		if b.declare(node) {
+			b.bodies = append(b.bodies, depsBody{node: address, file: filename, body: block.Body})
+		}
+	}
+}
+
+// sortedAttributes are body's attributes in source order
+func sortedAttributes(body *hclsyntax.Body) []*hclsyntax.Attribute {
+	attrs := make([]*hclsyntax.Attribute, 0, len(body.Attributes))
+	for _, attr := range body.Attributes {
+		attrs = append(attrs, attr)
+	}
+	sort.Slice(attrs, func(i, j int) bool {
+		return attrs[i].SrcRange.Start.Byte < attrs[j].SrcRange.Start.Byte
+	})
+	return attrs
+}
+
+// resolve returns the node a traversal refers to, or "" with the reason
+// it refers to none
+func (b *hclDepsBuilder) resolve(names []string, prefix string) (string, string) {
+	for n := len(names); n > 0; n-- {
+		address := prefix + strings.Join(names[:n], ".")
+		if _, ok := b.nodes[address]; ok {
+			return address, ""
+		}
+	}
+	if prefix == "" && len(names) > 0 && depsBuiltinRoots[names[0]] {
+		return "", "builtin"
+	}
+	return "", "undeclared"
+}
+
+// link records the edges of every traversal in attr
+func (b *hclDepsBuilder) link(from, file, path string, attr *hclsyntax.Attribute, bound map[string]bool) {
+	kind, prefix := depsEdgeReference, ""
+	switch {
+	case path == "depends_on":
+		kind = depsEdgeDependsOn
+	case path == "provider" || path == "providers":
+		kind, prefix = depsEdgeProvider, "provider."
+	}
+	for _, traversal := range attr.Expr.Variables() {
+		names := depsTraversalNames(traversal)
+		if len(names) == 0 || bound[names[0]] {
+			continue
+		}
+		ref := hclDepsReference{Attribute: path, Traversal: traversalString(traversal), File: file, Range: treeRange(traversal.SourceRange())}
+		// providers = { aws = aws.west } maps the module's names to ours;
+		// only the values are references
+		to, reason := b.resolve(names, prefix)
+		if to == "" {
+			b.graph.Unresolved = append(b.graph.Unresolved, hclDepsUnresolved{From: from, Reason: reason, Reference: ref})
+			continue
+		}
+		key := [3]string{from, to, kind}
+		edge, ok := b.edges[key]
+		if !ok {
+			edge = &hclDepsEdge{From: from, To: to, Kind: kind}
+			b.edges[key] = edge
+		}
+		edge.References = append(edge.References, ref)
+	}
+}
+
+// linkBody records the edges of a block body and its nested blocks. The
+// iterator of a dynamic block is bound in its content.
+func (b *hclDepsBuilder) linkBody(from, file string, body *hclsyntax.Body, prefix string, bound map[string]bool) {
+	for _, attr := range sortedAttributes(body) {
+		b.link(from, file, prefix+attr.Name, attr, bound)
+	}
+	for _, block := range body.Blocks {
+		blockPrefix := prefix + strings.Join(append([]string{block.Type}, block.Labels...), ".") + "."
+		if block.Type != "dynamic" || len(block.Labels) != 1 {
+			b.linkBody(from, file, block.Body, blockPrefix, bound)
+			continue
+		}
+		iterator := block.Labels[0]
+		if attr, ok := block.Body.Attributes["iterator"]; ok {
+			if name := hcl.ExprAsKeyword(attr.Expr); name != "" {
+				iterator = name
+			}
+		}
+		inner := map[string]bool{iterator: true}
+		for name := range bound {
+			inner[name] = true
+		}
+		for _, attr := range sortedAttributes(block.Body) {
+			// iterator names the variable; it isn't a reference
+			if attr.Name != "iterator" {
+				b.link(from, file, blockPrefix+attr.Name, attr, bound)
+			}
+		}
+		for _, nested := range block.Body.Blocks {
+			b.linkBody(from, file, nested.Body, blockPrefix+nested.Type+".", inner)
+		}
+	}
+}
+
+// build links every node and returns the finished graph
+func (b *hclDepsBuilder) build() *hclDepsGraph {
+	for _, body := range b.bodies {
+		if body.body != nil {
+			b.linkBody(body.node, body.file, body.body, "", nil)
+		}
+		for _, attr := range body.attrs {
+			b.link(body.node, body.file, "", attr, nil)
+		}
+	}
+
+	g := b.graph
+	g.Success = len(g.Errors) == 0
+	for _, node := range b.nodes {
+		g.Nodes = append(g.Nodes, node)
+	}
+	sort.Slice(g.Nodes, func(i, j int) bool { return g.Nodes[i].Address < g.Nodes[j].Address })
+	for _, edge := range b.edges {
+		g.Edges = append(g.Edges, *edge)
+	}
+	sort.Slice(g.Edges, func(i, j int) bool {
+		a, c := g.Edges[i], g.Edges[j]
+		if a.From != c.From {
+			return a.From < c.From
+		}
+		if a.To != c.To {
+			return a.To < c.To
+		}
+		return a.Kind < c.Kind
+	})
+
+	g.Cycles = depsCycles(g)
+	g.Acyclic = len(g.Cycles) == 0
+	if g.Acyclic {
+		g.Order = depsOrder(g)
+	}
+	return g
+}
+
+// depsAdjacency is each node's dependencies, sorted, each once whatever
+// the kinds of its edges
+func depsAdjacency(g *hclDepsGraph) map[string][]string {
+	adj := map[string][]string{}
+	for _, node := range g.Nodes {
+		adj[node.Address] = nil
+	}
+	for _, edge := range g.Edges {
+		deps := adj[edge.From]
+		if len(deps) == 0 || deps[len(deps)-1] != edge.To {
+			adj[edge.From] = append(deps, edge.To)
+		}
+	}
+	return adj
+}
+
+// depsCycles finds the graph's cycles with Tarjan's algorithm
+func depsCycles(g *hclDepsGraph) [][]string {
+	adj := depsAdjacency(g)
+	index := map[string]int{}
+	low := map[string]int{}
+	onStack := map[string]bool{}
+	var stack []string
+	cycles := [][]string{}
+
+	var connect func(v string)
+	connect = func(v string) {
+		index[v], low[v] = len(index), len(index)
+		stack = append(stack, v)
+		onStack[v] = true
+		selfLoop := false
+		for _, w := range adj[v] {
+			if w == v {
+				selfLoop = true
+			}
+			if _, seen := index[w]; !seen {
+				connect(w)
+				low[v] = min(low[v], low[w])
+			} else if onStack[w] {
+				low[v] = min(low[v], index[w])
+			}
+		}
+		if low[v] != index[v] {
+			return
+		}
+		var component []string
+		for {
+			w := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[w] = false
+			component = append(component, w)
+			if w == v {
+				break
+			}
+		}
+		if len(component) > 1 || selfLoop {
+			sort.Strings(component)
+			cycles = append(cycles, component)
+		}
+	}
+	for _, node := range g.Nodes {
+		if _, seen := index[node.Address]; !seen {
+			connect(node.Address)
+		}
+	}
+	sort.Slice(cycles, func(i, j int) bool { return cycles[i][0] < cycles[j][0] })
+	return cycles
+}
+
+// depsOrder sorts an acyclic graph's nodes dependencies first, taking the
+// lowest address among the nodes ready at each step
+func depsOrder(g *hclDepsGraph) []string {
+	adj := depsAdjacency(g)
+	waiting := map[string]int{}
+	dependents := map[string][]string{}
+	for from, deps := range adj {
+		waiting[from] = len(deps)
+		for _, to := range deps {
+			dependents[to] = append(dependents[to], from)
+		}
+	}
+	var ready []string
+	for _, node := range g.Nodes {
+		if waiting[node.Address] == 0 {
+			ready = append(ready, node.Address)
+		}
+	}
+	order := []string{}
+	for len(ready) > 0 {
+		sort.Strings(ready)
+		next := ready[0]
+		ready = ready[1:]
+		order = append(order, next)
+		for _, dependent := range dependents[next] {
+			waiting[dependent]--
+			if waiting[dependent] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+	}
+	return order
+}
+
+// writeDepsDOT writes the graph in Graphviz DOT. Edges point from a node
+// to what it depends on; depends_on edges are dashed, provider edges
+// dotted, and edges within a cycle red.
+func writeDepsDOT(w io.Writer, g *hclDepsGraph) error {
+	inCycle := map[string]int{}
+	for i, cycle := range g.Cycles {
+		for _, address := range cycle {
+			inCycle[address] = i + 1
+		}
+	}
+	var b strings.Builder
+	b.WriteString("digraph deps {\n  rankdir = \"RL\";\n  node [shape = \"box\"];\n")
+	for _, node := range g.Nodes {
+		fmt.Fprintf(&b, "  %s [kind = %s];\n", strconv.Quote(node.Address), strconv.Quote(node.Kind))
+	}
+	for _, edge := range g.Edges {
+		var attrs []string
+		switch edge.Kind {
+		case depsEdgeDependsOn:
+			attrs = append(attrs, `style = "dashed"`)
+		case depsEdgeProvider:
+			attrs = append(attrs, `style = "dotted"`)
+		}
+		if c := inCycle[edge.From]; c != 0 && c == inCycle[edge.To] {
+			attrs = append(attrs, `color = "red"`)
+		}
+		line := fmt.Sprintf("  %s -> %s", strconv.Quote(edge.From), strconv.Quote(edge.To))
+		if len(attrs) > 0 {
+			line += " [" + strings.Join(attrs, ", ") + "]"
+		}
+		b.WriteString(line + ";\n")
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func initHclDepsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "deps",
+		Short: "Reference graphs between blocks",
+	}
+	cmd.AddCommand(initHclDepsGraphCmd())
+	return cmd
+}
+
+func initHclDepsGraphCmd() *cobra.Command {
+	var outputFormat string
+	var dotPath string
+	var failOnCycle bool
+
+	cmd := &cobra.Command{
+		Use:   "graph [file...]",
+		Short: "Build the dependency graph between blocks from their references",
+		Long: `Build the graph of references between the blocks of one or more HCL
+files, as Terraform does before planning, so harnesses that build graphs
+can compare their node and edge sets against it.
+
+Nodes are top-level blocks addressed as references name them: var.NAME
+for variable blocks, TYPE.NAME for resource, data.TYPE.NAME, module.NAME,
+output.NAME, provider.NAME (with .ALIAS for an alias), and TYPE.LABELS
+for any other block. Each local value is a node local.NAME, and each
+top-level attribute a node of its own name. A traversal anywhere in a
+node's body, nested blocks included, is an edge from the node to the one
+with the longest address the traversal starts with, so
+module.vpc.subnet_ids[0] is an edge to module.vpc. Traversals in
+depends_on are depends_on edges, and those in provider and providers are
+provider edges to provider.NAME. The iterator of a dynamic block is bound
+in its content, and traversals of count, each, self, path and terraform,
+or of names nothing declares, are listed as unresolved.
+
+Cycles are the strongly connected components of more than one node, plus
+nodes that refer to themselves. An acyclic graph also gets an order,
+dependencies first with ties broken by address. A duplicate definition is
+an error in the output; the first one is kept.`,
+		Example: `  soup-go hcl deps graph main.tf variables.tf
+  soup-go hcl deps graph main.tf --output-format dot | dot -Tsvg > deps.svg
+  soup-go hcl deps graph main.tf --dot deps.dot --fail-on-cycle`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if outputFormat != "json" && outputFormat != "dot" && outputFormat != "text" {
+				return fmt.Errorf("unknown --output-format %q (expected json, dot or text)", outputFormat)
+			}
+			parser := hclparse.NewParser()
+			builder := newHCLDepsBuilder()
+			for _, filename := range args {
+				content, err := os.ReadFile(filename)
+				if err != nil {
+					return fmt.Errorf("failed to read file: %w", err)
+				}
+				file, diags := parser.ParseHCL(content, filename)
+				if diags.HasErrors() {
+					json.NewEncoder(os.Stdout).Encode(map[string]interface{}{
+						"success": false,
+						"errors":  diagnosticsToJSON(diags),
+					})
+					return fmt.Errorf("parse errors occurred")
+				}
+				body, ok := file.Body.(*hclsyntax.Body)
+				if !ok {
+					return fmt.Errorf("unsupported body type %T", file.Body)
+				}
+				builder.addFile(filename, body)
+			}
+			graph := builder.build()
+
+			if dotPath != "" {
+				f, err := os.Create(dotPath)
+				if err != nil {
+					return fmt.Errorf("failed to create DOT file: %w", err)
+				}
+				err = writeDepsDOT(f, graph)
+				if cerr := f.Close(); err == nil {
+					err = cerr
+				}
+				if err != nil {
+					return fmt.Errorf("failed to write DOT file: %w", err)
+				}
+			}
+
+			switch outputFormat {
+			case "dot":
+				if err := writeDepsDOT(os.Stdout, graph); err != nil {
+					return err
+				}
+			case "text":
+				for _, node := range graph.Nodes {
+					fmt.Printf("%s (%s)\n", node.Address, node.Kind)
+					for _, edge := range graph.Edges {
+						if edge.From == node.Address {
+							fmt.Printf("  -> %s (%s, %d references)\n", edge.To, edge.Kind, len(edge.References))
+						}
+					}
+				}
+				for _, u := range graph.Unresolved {
+					fmt.Printf("unresolved: %s in %s (%s)\n", u.Reference.Traversal, u.From, u.Reason)
+				}
+				for _, cycle := range graph.Cycles {
+					fmt.Printf("cycle: %s\n", strings.Join(cycle, ", "))
+				}
+				for _, e := range graph.Errors {
+					fmt.Printf("error: %s\n", e)
+				}
+			default:
+				if err := json.NewEncoder(os.Stdout).Encode(graph); err != nil {
+					return fmt.Errorf("failed to encode JSON: %w", err)
+				}
+			}
+			if failOnCycle && !graph.Acyclic {
+				return fmt.Errorf("dependency graph has %d cycle(s)", len(graph.Cycles))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&outputFormat, "output-format", "json", "Output format (json, dot, text)")
+	cmd.Flags().StringVar(&dotPath, "dot", "", "Also write the graph in Graphviz DOT to this file")
+	cmd.Flags().BoolVar(&failOnCycle, "fail-on-cycle", false, "Exit non-zero if the graph has a cycle")
+	return cmd
+}
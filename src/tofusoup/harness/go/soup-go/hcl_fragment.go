@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/spf13/cobra"
+)
+
+// What hcl validate and hcl view parse their input as, chosen with
+// --fragment. Conformance cases are mostly snippets, and parsing them as
+// what they are keeps diagnostics ranges relative to the snippet instead of
+// a synthetic file wrapped around it.
+const (
+	hclFragmentFile     = "file"
+	hclFragmentBody     = "body"
+	hclFragmentExpr     = "expr"
+	hclFragmentTemplate = "template"
+)
+
+// hclStdinName is the filename diagnostics give input read from stdin
+const hclStdinName = "<stdin>"
+
+// hclFragment is parsed input: File is set for file and body fragments,
+// Expr for expr and template ones
+type hclFragment struct {
+	Kind string
+	File *hcl.File
+	Expr hclsyntax.Expression
+	Src  []byte
+}
+
+func addFragmentFlag(cmd *cobra.Command, kind *string) {
+	cmd.Flags().StringVar(kind, "fragment", hclFragmentFile,
+		"Parse the input as a whole file, a body (attributes and blocks), a single expr, or a template")
+}
+
+func checkFragmentKind(kind string) error {
+	switch kind {
+	case hclFragmentFile, hclFragmentBody, hclFragmentExpr, hclFragmentTemplate:
+		return nil
+	}
+	return fmt.Errorf("unknown --fragment %q (expected file, body, expr or template)", kind)
+}
+
+// readHCLSource reads path, or stdin if path is "-", returning the name
+// diagnostics should use for it
+func readHCLSource(path string) ([]byte, string, error) {
+	if path == "-" {
+		content, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read stdin: %w", err)
+		}
+		return content, hclStdinName, nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read file: %w", err)
+	}
+	return content, path, nil
+}
+
+// parseHCLFragment parses src as kind. Positions in diagnostics start at
+// line 1, column 1 of src whatever the kind.
+func parseHCLFragment(src []byte, filename, kind string) (*hclFragment, hcl.Diagnostics) {
+	fragment := &hclFragment{Kind: kind, Src: src}
+	var diags hcl.Diagnostics
+	switch kind {
+	case hclFragmentBody:
+		fragment.File, diags = hclsyntax.ParseConfig(src, filename, hcl.InitialPos)
+	case hclFragmentExpr:
+		fragment.Expr, diags = hclsyntax.ParseExpression(src, filename, hcl.InitialPos)
+	case hclFragmentTemplate:
+		fragment.Expr, diags = hclsyntax.ParseTemplate(src, filename, hcl.InitialPos)
+	default:
+		fragment.File, diags = hclparse.NewParser().ParseHCL(src, filename)
+	}
+	return fragment, diags
+}
+
+// tree builds the document tree of the fragment. An expression's tree is
+// a body holding the one expression node.
+func (f *hclFragment) tree() (*hclTreeNode, error) {
+	if f.File != nil {
+		return buildHCLTree(f.File)
+	}
+	r := f.Expr.Range()
+	return &hclTreeNode{
+		Kind:  "body",
+		Range: treeRange(r),
+		Children: []*hclTreeNode{{
+			Kind:       "expression",
+			Range:      treeRange(r),
+			Expression: classifyExpression(f.Expr, f.Src),
+		}},
+	}, nil
+}
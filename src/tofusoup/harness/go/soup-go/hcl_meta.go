@@ -0,0 +1,444 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/spf13/cobra"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+	"github.com/zclconf/go-cty/cty/function"
+	"github.com/zclconf/go-cty/cty/function/stdlib"
+	"github.com/zclconf/go-cty/cty/gocty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// Expansion modes of a block, after the meta-argument that set it
+const (
+	metaModeSingle  = "single"
+	metaModeCount   = "count"
+	metaModeForEach = "for_each"
+)
+
+// metaExpandableBlocks are the block types Terraform expands with count
+// and for_each
+var metaExpandableBlocks = map[string]bool{"resource": true, "data": true, "module": true}
+
+// metaArguments are the attributes and nested blocks Terraform handles
+// itself rather than passing on in an instance's body
+var (
+	metaAttributes = map[string]bool{"count": true, "for_each": true, "depends_on": true, "provider": true, "providers": true}
+	metaBlocks     = map[string]bool{"lifecycle": true}
+)
+
+// hclExpandReport is the output of hcl meta expand
+type hclExpandReport struct {
+	Success bool           `json:"success"`
+	File    string         `json:"file"`
+	Blocks  []hclMetaBlock `json:"blocks"`
+}
+
+// hclMetaBlock is one resource, data or module block and its instances
+type hclMetaBlock struct {
+	Address string   `json:"address"`
+	Type    string   `json:"type"`
+	Labels  []string `json:"labels"`
+	Mode    string   `json:"mode"`
+	// Error is why the block couldn't be expanded, in Terraform's words;
+	// Instances is then empty
+	Error     string            `json:"error,omitempty"`
+	Instances []hclMetaInstance `json:"instances"`
+}
+
+// hclMetaInstance is one expanded instance. Key is its instance key: a
+// number for count, a string for for_each, absent for a single instance.
+type hclMetaInstance struct {
+	Address    string               `json:"address"`
+	Key        json.RawMessage      `json:"key,omitempty"`
+	Each       *hclMetaEach         `json:"each,omitempty"`
+	Attributes []hclScopedAttribute `json:"attributes"`
+}
+
+// hclMetaEach is the each object a for_each instance's body sees
+type hclMetaEach struct {
+	Key   string          `json:"key"`
+	Value json.RawMessage `json:"value"`
+	Type  json.RawMessage `json:"type"`
+}
+
+// metaExpandFunctions are the hcl view functions plus Terraform's type
+// conversion functions, which for_each expressions lean on (toset)
+func metaExpandFunctions() map[string]function.Function {
+	funcs := hclViewFunctions()
+	funcs["tobool"] = stdlib.MakeToFunc(cty.Bool)
+	funcs["tonumber"] = stdlib.MakeToFunc(cty.Number)
+	funcs["tostring"] = stdlib.MakeToFunc(cty.String)
+	funcs["tolist"] = stdlib.MakeToFunc(cty.List(cty.DynamicPseudoType))
+	funcs["toset"] = stdlib.MakeToFunc(cty.Set(cty.DynamicPseudoType))
+	funcs["tomap"] = stdlib.MakeToFunc(cty.Map(cty.DynamicPseudoType))
+	return funcs
+}
+
+// metaBlockAddress is a block's address as Terraform writes it:
+// aws_instance.web, data.aws_ami.ubuntu, module.vpc
+func metaBlockAddress(block *hclsyntax.Block) string {
+	labels := strings.Join(block.Labels, ".")
+	if block.Type == "resource" {
+		return labels
+	}
+	return block.Type + "." + labels
+}
+
+// metaInstanceKey formats key for an instance address: [0] or ["a"]
+func metaInstanceKey(key cty.Value) string {
+	if key.Type() == cty.Number {
+		return "[" + key.AsBigFloat().Text('f', -1) + "]"
+	}
+	return "[" + strconv.Quote(key.AsString()) + "]"
+}
+
+// evaluateCount evaluates a count argument with Terraform's rules: it must
+// be known, not null, convertible to a whole number and not negative
+func evaluateCount(expr hcl.Expression, ctx *hcl.EvalContext) (int, error) {
+	val, diags := expr.Value(ctx)
+	if diags.HasErrors() {
+		return 0, diags
+	}
+	const summary = "Invalid count argument: "
+	if val.IsNull() {
+		return 0, errors.New(summary + `The given "count" argument value is null. An integer is required.`)
+	}
+	if !val.IsKnown() {
+		return 0, errors.New(summary + `The "count" value depends on resource attributes that cannot be determined until apply, so Terraform cannot predict how many instances will be created.`)
+	}
+	val, err := convert.Convert(val, cty.Number)
+	if err != nil {
+		return 0, fmt.Errorf(summary+`The given "count" argument value is unsuitable: %s.`, err)
+	}
+	var count int
+	if err := gocty.FromCtyValue(val, &count); err != nil {
+		return 0, fmt.Errorf(summary+`The given "count" argument value is unsuitable: %s.`, err)
+	}
+	if count < 0 {
+		return 0, errors.New(summary + `The given "count" argument value is unsuitable: must be greater than or equal to zero.`)
+	}
+	return count, nil
+}
+
+// evaluateForEach evaluates a for_each argument with Terraform's rules: it
+// must be a known map, object or set of strings, and sets must not contain
+// nulls. It returns the elements by key, in key order.
+func evaluateForEach(expr hcl.Expression, ctx *hcl.EvalContext) ([]string, map[string]cty.Value, error) {
+	val, diags := expr.Value(ctx)
+	if diags.HasErrors() {
+		return nil, nil, diags
+	}
+	const summary = "Invalid for_each argument: "
+	unsuitable := summary + `The given "for_each" argument value is unsuitable: `
+	ty := val.Type()
+	if val.IsNull() {
+		return nil, nil, errors.New(unsuitable + `the given "for_each" argument value is null. A map, or set of strings is allowed.`)
+	}
+	if !val.IsKnown() {
+		return nil, nil, errors.New(summary + `The "for_each" value depends on resource attributes that cannot be determined until apply, so Terraform cannot predict how many instances will be created.`)
+	}
+	switch {
+	case ty.IsMapType() || ty.IsObjectType():
+	case ty.IsSetType():
+		// An empty set expands to nothing, whatever its element type
+		if val.LengthInt() == 0 {
+			return nil, nil, nil
+		}
+		if !ty.ElementType().Equals(cty.String) {
+			return nil, nil, fmt.Errorf(unsuitable+`"for_each" supports maps and sets of strings, but you have provided a set containing type %s.`, ty.ElementType().FriendlyName())
+		}
+		if !val.IsWhollyKnown() {
+			return nil, nil, errors.New(summary + `The "for_each" set includes values derived from resource attributes that cannot be determined until apply, so Terraform cannot determine the full set of keys that will identify the instances of this resource.`)
+		}
+	default:
+		return nil, nil, fmt.Errorf(unsuitable+`the "for_each" argument must be a map, or set of strings, and you have provided a value of type %s.`, ty.FriendlyName())
+	}
+
+	elements := map[string]cty.Value{}
+	for it := val.ElementIterator(); it.Next(); {
+		key, value := it.Element()
+		if ty.IsSetType() {
+			if value.IsNull() {
+				return nil, nil, errors.New(unsuitable + `"for_each" sets must not contain null values.`)
+			}
+			key = value
+		}
+		elements[key.AsString()] = value
+	}
+	return sortedKeys(elements), elements, nil
+}
+
+// metaMisuse returns Terraform's error for an attribute referring to
+// count or each in a block that doesn't set it, or ""
+func metaMisuse(attr *hclsyntax.Attribute, mode string) string {
+	for _, traversal := range attr.Expr.Variables() {
+		switch root := traversal.RootName(); {
+		case root == "count" && mode != metaModeCount:
+			return `Reference to "count" in non-counted context: The "count" object can only be used in "module", "resource", and "data" blocks, and only when the "count" argument is set.`
+		case root == "each" && mode != metaModeForEach:
+			return `Reference to "each" in context without for_each: The "each" object can be used only in "module" or "resource" blocks, and only when the "for_each" argument is set.`
+		}
+	}
+	return ""
+}
+
+// metaBodyAttributes returns the attributes an instance's body passes on:
+// everything but the meta-arguments, with nested blocks' attributes named
+// by path as hcl eval does
+func metaBodyAttributes(body *hclsyntax.Body) []namedAttribute {
+	trimmed := &hclsyntax.Body{Attributes: hclsyntax.Attributes{}}
+	for name, attr := range body.Attributes {
+		if !metaAttributes[name] {
+			trimmed.Attributes[name] = attr
+		}
+	}
+	for _, block := range body.Blocks {
+		if !metaBlocks[block.Type] {
+			trimmed.Blocks = append(trimmed.Blocks, block)
+		}
+	}
+	return collectAttributes(trimmed, "")
+}
+
+// expandMetaBlock expands block in root and evaluates each instance's body
+// with the count or each object it sees
+func expandMetaBlock(block *hclsyntax.Block, root *hclScope) hclMetaBlock {
+	result := hclMetaBlock{
+		Address:   metaBlockAddress(block),
+		Type:      block.Type,
+		Labels:    append([]string{}, block.Labels...),
+		Mode:      metaModeSingle,
+		Instances: []hclMetaInstance{},
+	}
+	countAttr, hasCount := block.Body.Attributes["count"]
+	forEachAttr, hasForEach := block.Body.Attributes["for_each"]
+
+	type instance struct {
+		keyed bool
+		key   cty.Value
+		vars  map[string]cty.Value
+		each  *hclMetaEach
+	}
+	var instances []instance
+	switch {
+	case hasCount && hasForEach:
+		result.Error = `Invalid combination of "count" and "for_each": The "count" and "for_each" meta-arguments are mutually-exclusive, only one should be used to be explicit about the number of resources to be created.`
+		return result
+	case hasCount:
+		result.Mode = metaModeCount
+		count, err := evaluateCount(countAttr.Expr, root.Ctx)
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		for i := 0; i < count; i++ {
+			index := cty.NumberIntVal(int64(i))
+			instances = append(instances, instance{
+				keyed: true,
+				key:   index,
+				vars:  map[string]cty.Value{"count": cty.ObjectVal(map[string]cty.Value{"index": index})},
+			})
+		}
+	case hasForEach:
+		result.Mode = metaModeForEach
+		keys, elements, err := evaluateForEach(forEachAttr.Expr, root.Ctx)
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		for _, key := range keys {
+			value := elements[key]
+			each := &hclMetaEach{Key: key}
+			var err error
+			if each.Value, err = ctyjson.Marshal(value, value.Type()); err != nil {
+				result.Error = err.Error()
+				return result
+			}
+			if each.Type, err = ctyjson.MarshalType(value.Type()); err != nil {
+				result.Error = err.Error()
+				return result
+			}
+			instances = append(instances, instance{
+				keyed: true,
+				key:   cty.StringVal(key),
+				vars:  map[string]cty.Value{"each": cty.ObjectVal(map[string]cty.Value{"key": cty.StringVal(key), "value": value})},
+				each:  each,
+			})
+		}
+	default:
+		instances = []instance{{}}
+	}
+
+	attrs := metaBodyAttributes(block.Body)
+	for _, inst := range instances {
+		scope := &hclScope{Path: result.Address, Parent: root, Ctx: root.Ctx.NewChild(), Variables: inst.vars}
+		scope.Ctx.Variables = inst.vars
+		out := hclMetaInstance{Address: result.Address, Each: inst.each, Attributes: make([]hclScopedAttribute, 0, len(attrs))}
+		if inst.keyed {
+			out.Address += metaInstanceKey(inst.key)
+			out.Key, _ = ctyjson.Marshal(inst.key, inst.key.Type())
+			scope.Path = out.Address
+		}
+		for _, attr := range attrs {
+			if misuse := metaMisuse(attr.Attr, result.Mode); misuse != "" {
+				out.Attributes = append(out.Attributes, hclScopedAttribute{Name: attr.Name, Error: misuse})
+				continue
+			}
+			out.Attributes = append(out.Attributes, evaluateInScope(scope, attr))
+		}
+		result.Instances = append(result.Instances, out)
+	}
+	return result
+}
+
+// loadMetaVars reads a --vars file: a JSON object whose members become
+// variables typed by their implied cty type
+func loadMetaVars(path string) (map[string]cty.Value, error) {
+	vars := map[string]cty.Value{}
+	if path == "" {
+		return vars, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vars file: %w", err)
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse vars file: %w", err)
+	}
+	for name, value := range raw {
+		ty, err := ctyjson.ImpliedType(value)
+		if err != nil {
+			return nil, fmt.Errorf("variable %s: %w", name, err)
+		}
+		if vars[name], err = ctyjson.Unmarshal(value, ty); err != nil {
+			return nil, fmt.Errorf("variable %s: %w", name, err)
+		}
+	}
+	return vars, nil
+}
+
+func initHclMetaCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "meta",
+		Short: "Reference semantics for Terraform's block meta-arguments",
+	}
+	cmd.AddCommand(initHclMetaExpandCmd())
+	return cmd
+}
+
+func initHclMetaExpandCmd() *cobra.Command {
+	var varsPath string
+	var outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   "expand [file]",
+		Short: "Expand count and for_each on resource, data and module blocks",
+		Long: `Expand every resource, data and module block in an HCL file the way
+Terraform does, and evaluate each instance's body.
+
+count must be a known, non-null whole number of zero or more; instances are
+keyed 0 to count-1 and see count.index. for_each must be a known map,
+object or set of strings without nulls; instances are keyed by map key or
+set element, in lexical order, and see each.key and each.value. A block
+with neither has one unkeyed instance, and a block with both is an error.
+Errors use Terraform's summaries and details, so harnesses can compare
+them. An expansion error is reported on its block, and an evaluation error
+on its attribute, not as a failure.
+
+Variables come from --vars, a JSON object whose members are typed by
+their implied cty type, e.g. {"var": {"names": ["a", "b"]}}. Expressions
+can call the hcl view functions and Terraform's tobool, tonumber,
+tostring, tolist, toset and tomap. The meta-arguments themselves (count,
+for_each, depends_on, provider, providers and lifecycle blocks) are left
+out of the evaluated bodies.`,
+		Example: `  soup-go hcl meta expand main.tf --vars vars.json
+  soup-go hcl meta expand main.tf --vars vars.json --output-format text`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			filename := args[0]
+			if outputFormat != "json" && outputFormat != "text" {
+				return fmt.Errorf("unknown --output-format %q (expected json or text)", outputFormat)
+			}
+			vars, err := loadMetaVars(varsPath)
+			if err != nil {
+				return err
+			}
+			funcs := metaExpandFunctions()
+			root := &hclScope{
+				Path:      "root",
+				Ctx:       &hcl.EvalContext{Variables: vars, Functions: evalLimit.functions(funcs)},
+				Variables: vars,
+				Functions: funcs,
+			}
+
+			content, err := os.ReadFile(filename)
+			if err != nil {
+				return fmt.Errorf("failed to read file: %w", err)
+			}
+			file, diags := hclparse.NewParser().ParseHCL(content, filename)
+			if diags.HasErrors() {
+				json.NewEncoder(os.Stdout).Encode(map[string]interface{}{
+					"success": false,
+					"errors":  diagnosticsToJSON(diags),
+				})
+				return fmt.Errorf("parse errors occurred")
+			}
+			body, ok := file.Body.(*hclsyntax.Body)
+			if !ok {
+				return fmt.Errorf("unsupported body type %T", file.Body)
+			}
+
+			report := hclExpandReport{Success: true, File: filename, Blocks: []hclMetaBlock{}}
+			err = evalLimit.run(filename, func() error {
+				for _, block := range body.Blocks {
+					if metaExpandableBlocks[block.Type] {
+						report.Blocks = append(report.Blocks, expandMetaBlock(block, root))
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+
+			if outputFormat == "text" {
+				for _, block := range report.Blocks {
+					fmt.Printf("%s (%s)\n", block.Address, block.Mode)
+					if block.Error != "" {
+						fmt.Printf("  error: %s\n", oneLine(block.Error))
+					}
+					for _, inst := range block.Instances {
+						fmt.Printf("  %s\n", inst.Address)
+						for _, attr := range inst.Attributes {
+							if attr.Error != "" {
+								fmt.Printf("    %s: error: %s\n", attr.Name, oneLine(attr.Error))
+							} else {
+								fmt.Printf("    %s = %s\n", attr.Name, attr.Value)
+							}
+						}
+					}
+				}
+				return nil
+			}
+			return json.NewEncoder(os.Stdout).Encode(report)
+		},
+	}
+
+	cmd.Flags().StringVar(&varsPath, "vars", "", "JSON object of variables the expressions can refer to")
+	cmd.Flags().StringVar(&outputFormat, "output-format", "json", "Output format (json, text)")
+	addEvalLimitFlags(cmd, &evalLimit)
+	return cmd
+}
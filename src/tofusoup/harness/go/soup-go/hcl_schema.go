@@ -0,0 +1,473 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/spf13/cobra"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// Block nesting modes in a spec file, each mapping to an hcldec block spec
+const (
+	hclNestingSingle = "single" // hcldec.BlockSpec
+	hclNestingList   = "list"   // hcldec.BlockListSpec
+	hclNestingMap    = "map"    // hcldec.BlockMapSpec, keyed by the labels
+)
+
+// hclSpecJSON is an hcldec object spec in JSON form: the attributes and
+// block types a body may contain. It's the format hcl schema infer writes
+// and hcl decode --spec reads.
+type hclSpecJSON struct {
+	Attributes map[string]*hclAttrSpecJSON  `json:"attributes,omitempty"`
+	Blocks     map[string]*hclBlockSpecJSON `json:"blocks,omitempty"`
+}
+
+type hclAttrSpecJSON struct {
+	Type     json.RawMessage `json:"type"`
+	Required bool            `json:"required,omitempty"`
+}
+
+type hclBlockSpecJSON struct {
+	Nesting string `json:"nesting"`
+	// Labels names the block's labels; only map blocks have them
+	Labels   []string     `json:"labels,omitempty"`
+	Required bool         `json:"required,omitempty"`
+	Body     *hclSpecJSON `json:"body"`
+}
+
+// loadHCLSpec reads a spec file
+func loadHCLSpec(path string) (*hclSpecJSON, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read spec file: %w", err)
+	}
+	var spec hclSpecJSON
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("failed to parse spec file: %w", err)
+	}
+	return &spec, nil
+}
+
+// decoderSpec builds the hcldec spec for a body. path names the body in
+// errors, e.g. resource.lifecycle.
+func (s *hclSpecJSON) decoderSpec(path string) (hcldec.ObjectSpec, error) {
+	spec := hcldec.ObjectSpec{}
+	if s == nil {
+		return spec, nil
+	}
+	for name, attr := range s.Attributes {
+		if attr == nil || len(attr.Type) == 0 {
+			return nil, fmt.Errorf("%s: attribute %q has no type", hclSpecPath(path, name), name)
+		}
+		ty, err := ctyjson.UnmarshalType(attr.Type)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid type: %w", hclSpecPath(path, name), err)
+		}
+		spec[name] = &hcldec.AttrSpec{Name: name, Type: ty, Required: attr.Required}
+	}
+	for name, block := range s.Blocks {
+		blockPath := hclSpecPath(path, name)
+		if _, ok := spec[name]; ok {
+			return nil, fmt.Errorf("%s: declared as both an attribute and a block", blockPath)
+		}
+		if block == nil {
+			return nil, fmt.Errorf("%s: empty block spec", blockPath)
+		}
+		nested, err := block.Body.decoderSpec(blockPath)
+		if err != nil {
+			return nil, err
+		}
+		if (block.Nesting == hclNestingMap) != (len(block.Labels) > 0) {
+			return nil, fmt.Errorf("%s: map blocks, and only map blocks, have labels", blockPath)
+		}
+		switch block.Nesting {
+		case hclNestingSingle:
+			spec[name] = &hcldec.BlockSpec{TypeName: name, Nested: nested, Required: block.Required}
+		case hclNestingList:
+			spec[name] = &hcldec.BlockListSpec{TypeName: name, Nested: nested}
+		case hclNestingMap:
+			spec[name] = &hcldec.BlockMapSpec{TypeName: name, LabelNames: block.Labels, Nested: nested}
+		default:
+			return nil, fmt.Errorf("%s: unknown nesting %q (expected single, list or map)", blockPath, block.Nesting)
+		}
+	}
+	return spec, nil
+}
+
+func hclSpecPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// hclBodyStats accumulates what was seen in every body of one kind (the
+// top level of each file, or every block of one type at one path) while
+// inferring a spec
+type hclBodyStats struct {
+	bodies int
+	attrs  map[string]*hclAttrStats
+	blocks map[string]*hclBlockStats
+}
+
+type hclAttrStats struct {
+	// bodies counts the bodies that set the attribute
+	bodies int
+	// ty is the type guessed so far; DynamicPseudoType until a value is seen
+	ty cty.Type
+}
+
+type hclBlockStats struct {
+	labels     int
+	bodies     int
+	maxPerBody int
+	// duplicate is set when a body has two blocks with the same labels,
+	// which a map block can't decode
+	duplicate bool
+	body      *hclBodyStats
+}
+
+func newHCLBodyStats() *hclBodyStats {
+	return &hclBodyStats{attrs: map[string]*hclAttrStats{}, blocks: map[string]*hclBlockStats{}}
+}
+
+// add merges a body into the stats. path names the body in errors.
+func (s *hclBodyStats) add(body *hclsyntax.Body, path string) error {
+	s.bodies++
+	ctx := &hcl.EvalContext{Functions: evalLimit.functions(hclViewFunctions())}
+	for name, attr := range body.Attributes {
+		if s.blocks[name] != nil {
+			return fmt.Errorf("%s: used as both an attribute and a block", hclSpecPath(path, name))
+		}
+		stats := s.attrs[name]
+		if stats == nil {
+			stats = &hclAttrStats{ty: cty.DynamicPseudoType}
+			s.attrs[name] = stats
+		}
+		stats.bodies++
+		// Expressions with references can't be evaluated without variables,
+		// so they say nothing about the type
+		val, diags := attr.Expr.Value(ctx)
+		if diags.HasErrors() {
+			continue
+		}
+		if err := evalLimit.checkValue(name, val); err != nil {
+			return err
+		}
+		stats.ty = mergeGuessedTypes(stats.ty, guessAttributeType(val.Type()))
+	}
+
+	counts := map[string]int{}
+	keys := map[string]bool{}
+	for _, block := range body.Blocks {
+		blockPath := hclSpecPath(path, block.Type)
+		if _, ok := body.Attributes[block.Type]; ok || s.attrs[block.Type] != nil {
+			return fmt.Errorf("%s: used as both an attribute and a block", blockPath)
+		}
+		stats := s.blocks[block.Type]
+		if stats == nil {
+			stats = &hclBlockStats{labels: len(block.Labels), body: newHCLBodyStats()}
+			s.blocks[block.Type] = stats
+		}
+		if len(block.Labels) != stats.labels {
+			return fmt.Errorf("%s: blocks have %d labels at %s but %d elsewhere",
+				blockPath, len(block.Labels), block.DefRange().String(), stats.labels)
+		}
+		counts[block.Type]++
+		key := block.Type + "\x00" + strings.Join(block.Labels, "\x00")
+		if stats.labels > 0 && keys[key] {
+			stats.duplicate = true
+		}
+		keys[key] = true
+		if err := stats.body.add(block.Body, blockPath); err != nil {
+			return err
+		}
+	}
+	for name, n := range counts {
+		stats := s.blocks[name]
+		stats.bodies++
+		if n > stats.maxPerBody {
+			stats.maxPerBody = n
+		}
+	}
+	return nil
+}
+
+// spec turns the stats into a spec. Attributes and single blocks present in
+// every body are required. Blocks with labels are map blocks, others are
+// list blocks if any body had more than one.
+func (s *hclBodyStats) spec(path string) *hclSpecJSON {
+	spec := &hclSpecJSON{}
+	for name, stats := range s.attrs {
+		if spec.Attributes == nil {
+			spec.Attributes = map[string]*hclAttrSpecJSON{}
+		}
+		ty, _ := ctyjson.MarshalType(stats.ty)
+		spec.Attributes[name] = &hclAttrSpecJSON{Type: ty, Required: stats.bodies == s.bodies}
+	}
+	for name, stats := range s.blocks {
+		if spec.Blocks == nil {
+			spec.Blocks = map[string]*hclBlockSpecJSON{}
+		}
+		blockPath := hclSpecPath(path, name)
+		block := &hclBlockSpecJSON{Nesting: hclNestingSingle, Body: stats.body.spec(blockPath)}
+		switch {
+		case stats.labels > 0:
+			block.Nesting = hclNestingMap
+			for i := 1; i <= stats.labels; i++ {
+				block.Labels = append(block.Labels, fmt.Sprintf("label%d", i))
+			}
+			if stats.duplicate {
+				logger.Warn("blocks repeat the same labels; a map block can't decode them", "block", blockPath)
+			}
+		case stats.maxPerBody > 1:
+			block.Nesting = hclNestingList
+		default:
+			block.Required = stats.bodies == s.bodies
+		}
+		spec.Blocks[name] = block
+	}
+	return spec
+}
+
+// guessAttributeType turns the type of a literal into the type a schema
+// would likely declare for it: tuples become lists, and objects whose
+// attributes all have one type become maps. Empty collections have
+// dynamic elements.
+func guessAttributeType(ty cty.Type) cty.Type {
+	switch {
+	case ty.IsTupleType():
+		elem := cty.DynamicPseudoType
+		for _, et := range ty.TupleElementTypes() {
+			elem = mergeGuessedTypes(elem, guessAttributeType(et))
+		}
+		return cty.List(elem)
+	case ty.IsObjectType():
+		attrs := map[string]cty.Type{}
+		var elem cty.Type
+		uniform := true
+		for name, at := range ty.AttributeTypes() {
+			attrs[name] = guessAttributeType(at)
+			if elem == cty.NilType {
+				elem = attrs[name]
+			} else if !elem.Equals(attrs[name]) {
+				uniform = false
+			}
+		}
+		if !uniform {
+			return cty.Object(attrs)
+		}
+		if elem == cty.NilType {
+			elem = cty.DynamicPseudoType
+		}
+		return cty.Map(elem)
+	}
+	return ty
+}
+
+// mergeGuessedTypes combines the types guessed from two values of one
+// attribute. Dynamic means nothing is known yet, so the other type wins;
+// types that can't be unified give dynamic.
+func mergeGuessedTypes(a, b cty.Type) cty.Type {
+	switch {
+	case a == cty.DynamicPseudoType:
+		return b
+	case b == cty.DynamicPseudoType, a.Equals(b):
+		return a
+	case a.IsListType() && b.IsListType():
+		return cty.List(mergeGuessedTypes(a.ElementType(), b.ElementType()))
+	case a.IsMapType() && b.IsMapType():
+		return cty.Map(mergeGuessedTypes(a.ElementType(), b.ElementType()))
+	case a.IsObjectType() && b.IsObjectType():
+		attrs := map[string]cty.Type{}
+		for name, at := range a.AttributeTypes() {
+			attrs[name] = at
+		}
+		for name, bt := range b.AttributeTypes() {
+			if at, ok := attrs[name]; ok {
+				bt = mergeGuessedTypes(at, bt)
+			}
+			attrs[name] = bt
+		}
+		return cty.Object(attrs)
+	}
+	if unified, _ := convert.Unify([]cty.Type{a, b}); unified != cty.NilType {
+		return unified
+	}
+	return cty.DynamicPseudoType
+}
+
+// inferHCLSpec infers one spec for the top-level bodies of the given files
+func inferHCLSpec(filenames []string) (*hclSpecJSON, error) {
+	parser := hclparse.NewParser()
+	stats := newHCLBodyStats()
+	for _, filename := range filenames {
+		content, err := os.ReadFile(filename)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file: %w", err)
+		}
+		file, diags := parser.ParseHCL(content, filename)
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("HCL parse errors: %s", diags.Error())
+		}
+		body, ok := file.Body.(*hclsyntax.Body)
+		if !ok {
+			return nil, fmt.Errorf("%s: unsupported body type", filename)
+		}
+		if err := evalLimit.run(filename, func() error { return stats.add(body, "") }); err != nil {
+			return nil, err
+		}
+	}
+	return stats.spec(""), nil
+}
+
+func initHclSchemaCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "schema",
+		Short: "HCL schema (hcldec spec) operations",
+	}
+	cmd.AddCommand(initHclSchemaInferCmd())
+	return cmd
+}
+
+func initHclSchemaInferCmd() *cobra.Command {
+	var outputPath string
+
+	cmd := &cobra.Command{
+		Use:   "infer [files...]",
+		Short: "Infer an hcldec spec from example HCL files",
+		Long: `Infer a spec from example HCL files, for use with hcl decode --spec.
+
+Every block type seen becomes a block spec: blocks with labels are map
+blocks keyed by their labels, blocks that appear more than once in a body
+are list blocks, and others are single blocks. Attribute types are guessed
+from the values seen, unified across files: tuples become lists, objects
+whose attributes share one type become maps, and attributes whose values
+can't be evaluated (references, for one) are dynamic. Attributes and single
+blocks present every time their body is are required.
+
+The result is a starting point; label names in particular are placeholders.
+Experimental: needs --enable-feature hcl-schema.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := requireFeature("hcl-schema", "hcl schema infer"); err != nil {
+				return err
+			}
+			spec, err := inferHCLSpec(args)
+			if err != nil {
+				return err
+			}
+
+			data, err := json.MarshalIndent(spec, "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to marshal spec: %w", err)
+			}
+			data = append(data, '\n')
+			if outputPath == "-" {
+				_, err = os.Stdout.Write(data)
+			} else {
+				err = os.WriteFile(outputPath, data, 0644)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to write spec: %w", err)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&outputPath, "output", "o", "-", "Write the spec to this file (- for stdout)")
+	addEvalLimitFlags(cmd, &evalLimit)
+
+	return cmd
+}
+
+func initHclDecodeCmd() *cobra.Command {
+	var specPath string
+
+	cmd := &cobra.Command{
+		Use:   "decode [file]",
+		Short: "Decode an HCL file with an hcldec spec",
+		Long: `Decode an HCL file against a spec (see hcl schema infer) using hcldec,
+and print the resulting value and its type as JSON. Expressions can use the
+same functions as hcl view, but no variables. Diagnostics are printed as
+JSON and fail the command. Experimental: needs --enable-feature hcl-schema.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			filename := args[0]
+			if err := requireFeature("hcl-schema", "hcl decode"); err != nil {
+				return err
+			}
+
+			specJSON, err := loadHCLSpec(specPath)
+			if err != nil {
+				return err
+			}
+			spec, err := specJSON.decoderSpec("")
+			if err != nil {
+				return fmt.Errorf("invalid spec: %w", err)
+			}
+
+			content, err := os.ReadFile(filename)
+			if err != nil {
+				return fmt.Errorf("failed to read file: %w", err)
+			}
+			file, diags := hclparse.NewParser().ParseHCL(content, filename)
+
+			var val cty.Value
+			if !diags.HasErrors() {
+				err = evalLimit.run(filename, func() error {
+					ctx := &hcl.EvalContext{Functions: evalLimit.functions(hclViewFunctions())}
+					var decodeDiags hcl.Diagnostics
+					val, decodeDiags = hcldec.Decode(file.Body, spec, ctx)
+					diags = append(diags, decodeDiags...)
+					if decodeDiags.HasErrors() {
+						return nil
+					}
+					return evalLimit.checkValue(filename, val)
+				})
+				if err != nil {
+					return err
+				}
+			}
+			if diags.HasErrors() {
+				json.NewEncoder(os.Stdout).Encode(map[string]interface{}{
+					"success": false,
+					"errors":  diagnosticsToJSON(diags),
+				})
+				return fmt.Errorf("decode errors occurred")
+			}
+
+			valueJSON, err := ctyjson.Marshal(val, val.Type())
+			if err != nil {
+				return fmt.Errorf("failed to marshal value: %w", err)
+			}
+			typeJSON, err := ctyjson.MarshalType(val.Type())
+			if err != nil {
+				return fmt.Errorf("failed to marshal type: %w", err)
+			}
+			if err := json.NewEncoder(os.Stdout).Encode(map[string]interface{}{
+				"success": true,
+				"type":    json.RawMessage(typeJSON),
+				"value":   json.RawMessage(valueJSON),
+			}); err != nil {
+				return fmt.Errorf("failed to encode JSON: %w", err)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&specPath, "spec", "", "Spec file, as written by hcl schema infer (required)")
+	cmd.MarkFlagRequired("spec")
+	addEvalLimitFlags(cmd, &evalLimit)
+
+	return cmd
+}
@@ -0,0 +1,398 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/spf13/cobra"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// hclScopeSpec is one evaluation context in a --scopes file. Variables are
+// plain JSON values typed by their implied cty type; functions name entries
+// from the hcl view function set. Children see everything their ancestors
+// define unless they shadow it.
+type hclScopeSpec struct {
+	Name      string                     `json:"name"`
+	Variables map[string]json.RawMessage `json:"variables,omitempty"`
+	Functions []string                   `json:"functions,omitempty"`
+	Children  []*hclScopeSpec            `json:"children,omitempty"`
+}
+
+// hclScope is a built evaluation context and where it sits in the tree
+type hclScope struct {
+	Path      string
+	Parent    *hclScope
+	Ctx       *hcl.EvalContext
+	Variables map[string]cty.Value
+	Functions map[string]function.Function
+}
+
+// buildHCLScopes builds spec and its descendants as child contexts of
+// parent, returning them depth first
+func buildHCLScopes(spec *hclScopeSpec, parent *hclScope) ([]*hclScope, error) {
+	if spec.Name == "" {
+		return nil, fmt.Errorf("every scope needs a name")
+	}
+	scope := &hclScope{
+		Path:      spec.Name,
+		Parent:    parent,
+		Variables: map[string]cty.Value{},
+		Functions: map[string]function.Function{},
+	}
+	if parent != nil {
+		scope.Path = parent.Path + "/" + spec.Name
+	}
+
+	for name, raw := range spec.Variables {
+		ty, err := ctyjson.ImpliedType(raw)
+		if err != nil {
+			return nil, fmt.Errorf("scope %s: variable %s: %w", scope.Path, name, err)
+		}
+		val, err := ctyjson.Unmarshal(raw, ty)
+		if err != nil {
+			return nil, fmt.Errorf("scope %s: variable %s: %w", scope.Path, name, err)
+		}
+		scope.Variables[name] = val
+	}
+	available := hclViewFunctions()
+	for _, name := range spec.Functions {
+		f, ok := available[name]
+		if !ok {
+			return nil, fmt.Errorf("scope %s: unknown function %q", scope.Path, name)
+		}
+		scope.Functions[name] = f
+	}
+
+	// Leave maps nil when a scope defines nothing, as HCL callers
+	// usually do, so lookups fall through to the parent either way
+	ctx := &hcl.EvalContext{}
+	if parent != nil {
+		ctx = parent.Ctx.NewChild()
+	}
+	if len(scope.Variables) > 0 {
+		ctx.Variables = scope.Variables
+	}
+	if len(scope.Functions) > 0 {
+		ctx.Functions = hclTrace.functions(evalLimit.functions(scope.Functions))
+	}
+	scope.Ctx = ctx
+
+	scopes := []*hclScope{scope}
+	seen := map[string]bool{}
+	for _, child := range spec.Children {
+		if seen[child.Name] {
+			return nil, fmt.Errorf("scope %s has two children named %q", scope.Path, child.Name)
+		}
+		seen[child.Name] = true
+		descendants, err := buildHCLScopes(child, scope)
+		if err != nil {
+			return nil, err
+		}
+		scopes = append(scopes, descendants...)
+	}
+	return scopes, nil
+}
+
+// hclResolution says which scope supplied a variable or function, walking
+// from the evaluating scope towards the root as HCL does
+type hclResolution struct {
+	Name string `json:"name"`
+	// ResolvedFrom is the nearest scope defining Name, empty if none does
+	ResolvedFrom string `json:"resolved_from"`
+	// Shadows lists ancestors of ResolvedFrom that also define Name
+	Shadows []string `json:"shadows,omitempty"`
+}
+
+func (s *hclScope) resolve(name string, defines func(*hclScope) bool) hclResolution {
+	res := hclResolution{Name: name}
+	for scope := s; scope != nil; scope = scope.Parent {
+		if !defines(scope) {
+			continue
+		}
+		if res.ResolvedFrom == "" {
+			res.ResolvedFrom = scope.Path
+		} else {
+			res.Shadows = append(res.Shadows, scope.Path)
+		}
+	}
+	return res
+}
+
+func (s *hclScope) resolveVariable(name string) hclResolution {
+	return s.resolve(name, func(scope *hclScope) bool {
+		_, ok := scope.Variables[name]
+		return ok
+	})
+}
+
+func (s *hclScope) resolveFunction(name string) hclResolution {
+	return s.resolve(name, func(scope *hclScope) bool {
+		_, ok := scope.Functions[name]
+		return ok
+	})
+}
+
+// hclScopedAttribute is an attribute evaluated in one scope
+type hclScopedAttribute struct {
+	Name       string          `json:"name"`
+	Value      json.RawMessage `json:"value,omitempty"`
+	Type       json.RawMessage `json:"type,omitempty"`
+	References []hclResolution `json:"references,omitempty"`
+	Functions  []hclResolution `json:"functions,omitempty"`
+	Error      string          `json:"error,omitempty"`
+}
+
+// hclScopeResult holds a scope's own definitions and its evaluations
+type hclScopeResult struct {
+	Scope      string               `json:"scope"`
+	Variables  []string             `json:"variables"`
+	Functions  []string             `json:"functions"`
+	Attributes []hclScopedAttribute `json:"attributes"`
+}
+
+// namedAttribute is an attribute with its address in the file, e.g.
+// locals.region or resource.aws_instance.web.ami
+type namedAttribute struct {
+	Name string
+	Attr *hclsyntax.Attribute
+}
+
+// collectAttributes returns the attributes in body and its blocks in
+// source order
+func collectAttributes(body *hclsyntax.Body, prefix string) []namedAttribute {
+	var attrs []namedAttribute
+	for _, attr := range body.Attributes {
+		attrs = append(attrs, namedAttribute{Name: prefix + attr.Name, Attr: attr})
+	}
+	sort.Slice(attrs, func(i, j int) bool {
+		return attrs[i].Attr.SrcRange.Start.Byte < attrs[j].Attr.SrcRange.Start.Byte
+	})
+	for _, block := range body.Blocks {
+		blockPrefix := prefix + strings.Join(append([]string{block.Type}, block.Labels...), ".") + "."
+		attrs = append(attrs, collectAttributes(block.Body, blockPrefix)...)
+	}
+	return attrs
+}
+
+// evaluateInScope evaluates attr in scope, reporting where each reference
+// and function call resolved from
+func evaluateInScope(scope *hclScope, attr namedAttribute) hclScopedAttribute {
+	result := hclScopedAttribute{Name: attr.Name}
+	expr := attr.Attr.Expr
+
+	seenVars := map[string]bool{}
+	for _, traversal := range expr.Variables() {
+		root := traversal.RootName()
+		if !seenVars[root] {
+			seenVars[root] = true
+			result.References = append(result.References, scope.resolveVariable(root))
+		}
+	}
+	seenFuncs := map[string]bool{}
+	hclsyntax.VisitAll(expr, func(node hclsyntax.Node) hcl.Diagnostics {
+		if call, ok := node.(*hclsyntax.FunctionCallExpr); ok && !seenFuncs[call.Name] {
+			seenFuncs[call.Name] = true
+			result.Functions = append(result.Functions, scope.resolveFunction(call.Name))
+		}
+		return nil
+	})
+
+	hclTrace.enter(scope.Path, attr.Name)
+	val, diags := expr.Value(scope.Ctx)
+	if diags.HasErrors() {
+		result.Error = diags.Error()
+		return result
+	}
+	if err := evalLimit.checkValue(attr.Name, val); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if !val.IsWhollyKnown() {
+		result.Error = "value is not known"
+		return result
+	}
+	var err error
+	if result.Value, err = ctyjson.Marshal(val, val.Type()); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if result.Type, err = ctyjson.MarshalType(val.Type()); err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func initHclEvalCmd() *cobra.Command {
+	var scopesPath string
+	var only string
+	var outputFormat string
+	var traceCalls string
+
+	cmd := &cobra.Command{
+		Use:   "eval [file]",
+		Short: "Evaluate an HCL file's attributes in nested evaluation contexts",
+		Long: `Evaluate every attribute of an HCL file (including those in blocks) in
+each scope of a --scopes file and report, for each reference and function
+call, the scope it resolved from and the ancestor scopes it shadows.
+
+A scopes file is a tree of evaluation contexts built with HCL's
+EvalContext.NewChild, so lookups fall through to parents:
+
+  {"name": "root",
+   "variables": {"region": "us-east-1", "env": "prod"},
+   "functions": ["upper"],
+   "children": [{"name": "module",
+                 "variables": {"region": "eu-west-1"},
+                 "functions": ["lower"]}]}
+
+Variables take their implied type from the JSON value. Functions name
+members of the hcl view function set. Use --scope root/module to evaluate
+in one scope only. Evaluation errors (an undefined variable, a function a
+scope can't see) are reported per attribute, not as a failure.
+
+--trace-calls writes every function call made to a file as NDJSON, one
+line per call in the order they ran: the scope and attribute, the
+function, its arguments' and result's types and values, any error, and
+how long it took. Comparing traces finds the first call where two
+implementations disagree. Use - to write the trace to stderr.`,
+		Example: `  soup-go hcl eval main.hcl --scopes scopes.json
+  soup-go hcl eval main.hcl --scopes scopes.json --scope root/module --output-format text
+  soup-go hcl eval main.hcl --scopes scopes.json --trace-calls calls.ndjson`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			filename := args[0]
+
+			specData, err := os.ReadFile(scopesPath)
+			if err != nil {
+				return fmt.Errorf("failed to read scopes file: %w", err)
+			}
+			var spec hclScopeSpec
+			if err := json.Unmarshal(specData, &spec); err != nil {
+				return fmt.Errorf("failed to parse scopes file: %w", err)
+			}
+			if spec.Name == "" {
+				spec.Name = "root"
+			}
+			// The trace must be open before the scopes' functions are built
+			if traceCalls != "" {
+				if err := hclTrace.open(traceCalls); err != nil {
+					return err
+				}
+				defer hclTrace.close()
+			}
+			scopes, err := buildHCLScopes(&spec, nil)
+			if err != nil {
+				return err
+			}
+			if only != "" {
+				var selected []*hclScope
+				for _, scope := range scopes {
+					if scope.Path == only {
+						selected = append(selected, scope)
+					}
+				}
+				if len(selected) == 0 {
+					return fmt.Errorf("no scope %q in %s", only, scopesPath)
+				}
+				scopes = selected
+			}
+
+			content, err := os.ReadFile(filename)
+			if err != nil {
+				return fmt.Errorf("failed to read file: %w", err)
+			}
+			file, diags := hclparse.NewParser().ParseHCL(content, filename)
+			if diags.HasErrors() {
+				json.NewEncoder(os.Stdout).Encode(map[string]interface{}{
+					"success": false,
+					"errors":  diagnosticsToJSON(diags),
+				})
+				return fmt.Errorf("parse errors occurred")
+			}
+			body, ok := file.Body.(*hclsyntax.Body)
+			if !ok {
+				return fmt.Errorf("unsupported body type %T", file.Body)
+			}
+			attrs := collectAttributes(body, "")
+
+			results := make([]hclScopeResult, 0, len(scopes))
+			err = evalLimit.run(filename, func() error {
+				for _, scope := range scopes {
+					result := hclScopeResult{
+						Scope:      scope.Path,
+						Variables:  sortedKeys(scope.Variables),
+						Functions:  sortedKeys(scope.Functions),
+						Attributes: make([]hclScopedAttribute, 0, len(attrs)),
+					}
+					for _, attr := range attrs {
+						result.Attributes = append(result.Attributes, evaluateInScope(scope, attr))
+					}
+					results = append(results, result)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			if err := hclTrace.close(); err != nil {
+				return fmt.Errorf("failed to write call trace: %w", err)
+			}
+
+			if outputFormat == "text" {
+				for _, result := range results {
+					fmt.Printf("%s\n", result.Scope)
+					for _, attr := range result.Attributes {
+						if attr.Error != "" {
+							fmt.Printf("  %s: error: %s\n", attr.Name, oneLine(attr.Error))
+						} else {
+							fmt.Printf("  %s = %s\n", attr.Name, attr.Value)
+						}
+						for _, res := range append(attr.References, attr.Functions...) {
+							from := res.ResolvedFrom
+							if from == "" {
+								from = "(unresolved)"
+							}
+							line := fmt.Sprintf("    %s <- %s", res.Name, from)
+							if len(res.Shadows) > 0 {
+								line += " (shadows " + strings.Join(res.Shadows, ", ") + ")"
+							}
+							fmt.Println(line)
+						}
+					}
+				}
+				return nil
+			}
+			return json.NewEncoder(os.Stdout).Encode(map[string]interface{}{
+				"success": true,
+				"file":    filename,
+				"scopes":  results,
+			})
+		},
+	}
+
+	cmd.Flags().StringVar(&scopesPath, "scopes", "", "JSON file describing the tree of evaluation scopes")
+	cmd.Flags().StringVar(&only, "scope", "", "Evaluate in this scope only, by path (e.g. root/module)")
+	cmd.Flags().StringVar(&outputFormat, "output-format", "json", "Output format (json, text)")
+	cmd.Flags().StringVar(&traceCalls, "trace-calls", "", "Write every function call made to this file as NDJSON (- for stderr)")
+	cmd.MarkFlagRequired("scopes")
+	addEvalLimitFlags(cmd, &evalLimit)
+	return cmd
+}
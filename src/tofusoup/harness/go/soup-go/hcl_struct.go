@@ -0,0 +1,546 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/spf13/cobra"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/gocty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// hclStructDesc describes Go structs with gohcl tags, either parsed from Go
+// source or written by hand. Other harnesses can read it with
+// hcl decode-struct --describe to drive their own tag- or annotation-driven
+// decoders from the same declarations.
+type hclStructDesc struct {
+	Root string `json:"root"`
+	// Types are the struct types by name, fields in declaration order
+	Types map[string][]hclStructFieldDesc `json:"types"`
+}
+
+type hclStructFieldDesc struct {
+	Name string `json:"name"`
+	// Type is a Go type expression: a builtin, cty.Value, hcl.Expression,
+	// hcl.Body, hcl.Attributes or a struct in Types, under any of *, []
+	// and map[string]
+	Type string `json:"type"`
+	// Tag is the field's hcl struct tag, e.g. "name,optional"; fields
+	// without one are ignored, as gohcl ignores them
+	Tag string `json:"tag,omitempty"`
+}
+
+// hclStructBuiltins are the non-struct types descriptions can use
+var hclStructBuiltins = map[string]reflect.Type{
+	"string":         reflect.TypeOf(""),
+	"bool":           reflect.TypeOf(false),
+	"int":            reflect.TypeOf(int(0)),
+	"int8":           reflect.TypeOf(int8(0)),
+	"int16":          reflect.TypeOf(int16(0)),
+	"int32":          reflect.TypeOf(int32(0)),
+	"int64":          reflect.TypeOf(int64(0)),
+	"uint":           reflect.TypeOf(uint(0)),
+	"uint8":          reflect.TypeOf(uint8(0)),
+	"uint16":         reflect.TypeOf(uint16(0)),
+	"uint32":         reflect.TypeOf(uint32(0)),
+	"uint64":         reflect.TypeOf(uint64(0)),
+	"float32":        reflect.TypeOf(float32(0)),
+	"float64":        reflect.TypeOf(float64(0)),
+	"cty.Value":      reflect.TypeOf(cty.Value{}),
+	"hcl.Expression": reflect.TypeOf((*hcl.Expression)(nil)).Elem(),
+	"hcl.Body":       reflect.TypeOf((*hcl.Body)(nil)).Elem(),
+	"hcl.Attributes": reflect.TypeOf(hcl.Attributes(nil)),
+}
+
+var (
+	ctyValueType      = hclStructBuiltins["cty.Value"]
+	hclExpressionType = hclStructBuiltins["hcl.Expression"]
+	hclBodyType       = hclStructBuiltins["hcl.Body"]
+	hclAttributeType  = reflect.TypeOf(&hcl.Attribute{})
+)
+
+// loadHCLStructDesc reads a JSON struct description
+func loadHCLStructDesc(path string) (*hclStructDesc, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read struct description: %w", err)
+	}
+	var desc hclStructDesc
+	if err := json.Unmarshal(data, &desc); err != nil {
+		return nil, fmt.Errorf("failed to parse struct description: %w", err)
+	}
+	return &desc, nil
+}
+
+// parseHCLStructTags describes the struct types declared in a Go source
+// file. The root is the first struct with an hcl tag unless one is named.
+func parseHCLStructTags(path string) (*hclStructDesc, error) {
+	file, err := parser.ParseFile(token.NewFileSet(), path, nil, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Go source: %w", err)
+	}
+	desc := &hclStructDesc{Types: map[string][]hclStructFieldDesc{}}
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			typeSpec := spec.(*ast.TypeSpec)
+			st, ok := typeSpec.Type.(*ast.StructType)
+			if !ok {
+				continue
+			}
+			var fields []hclStructFieldDesc
+			tagged := false
+			for _, field := range st.Fields.List {
+				tag := ""
+				if field.Tag != nil {
+					raw, err := strconv.Unquote(field.Tag.Value)
+					if err != nil {
+						return nil, fmt.Errorf("%s: invalid struct tag %s", typeSpec.Name.Name, field.Tag.Value)
+					}
+					tag = reflect.StructTag(raw).Get("hcl")
+				}
+				ty := types.ExprString(field.Type)
+				if len(field.Names) == 0 {
+					if tag != "" {
+						return nil, fmt.Errorf("%s: embedded field %s can't have an hcl tag", typeSpec.Name.Name, ty)
+					}
+					continue
+				}
+				for _, name := range field.Names {
+					fields = append(fields, hclStructFieldDesc{Name: name.Name, Type: ty, Tag: tag})
+				}
+				tagged = tagged || tag != ""
+			}
+			desc.Types[typeSpec.Name.Name] = fields
+			if desc.Root == "" && tagged {
+				desc.Root = typeSpec.Name.Name
+			}
+		}
+	}
+	if desc.Root == "" {
+		return nil, fmt.Errorf("%s declares no struct with hcl tags", path)
+	}
+	return desc, nil
+}
+
+// hclStructBuilder turns a description into reflect types gohcl can decode
+// into
+type hclStructBuilder struct {
+	desc     *hclStructDesc
+	built    map[string]reflect.Type
+	building map[string]bool
+	// order lists the struct types reachable from the root as they were
+	// built, so the report follows the declarations outward from the root
+	order []string
+}
+
+func (b *hclStructBuilder) typeOf(expr string) (reflect.Type, error) {
+	switch {
+	case strings.HasPrefix(expr, "*"):
+		elem, err := b.typeOf(expr[1:])
+		if err != nil {
+			return nil, err
+		}
+		return reflect.PointerTo(elem), nil
+	case strings.HasPrefix(expr, "[]"):
+		elem, err := b.typeOf(expr[2:])
+		if err != nil {
+			return nil, err
+		}
+		return reflect.SliceOf(elem), nil
+	case strings.HasPrefix(expr, "map[string]"):
+		elem, err := b.typeOf(expr[len("map[string]"):])
+		if err != nil {
+			return nil, err
+		}
+		return reflect.MapOf(reflect.TypeOf(""), elem), nil
+	}
+	if ty, ok := hclStructBuiltins[expr]; ok {
+		return ty, nil
+	}
+	if _, ok := b.desc.Types[expr]; ok {
+		return b.structOf(expr)
+	}
+	return nil, fmt.Errorf("unsupported type %q", expr)
+}
+
+func (b *hclStructBuilder) structOf(name string) (reflect.Type, error) {
+	if ty, ok := b.built[name]; ok {
+		return ty, nil
+	}
+	if b.building[name] {
+		return nil, fmt.Errorf("%s: recursive struct types aren't supported", name)
+	}
+	b.building[name] = true
+	defer delete(b.building, name)
+
+	var fields []reflect.StructField
+	for _, field := range b.desc.Types[name] {
+		if field.Tag == "" {
+			continue
+		}
+		if !token.IsExported(field.Name) {
+			return nil, fmt.Errorf("%s.%s: gohcl can only decode into exported fields", name, field.Name)
+		}
+		ty, err := b.typeOf(field.Type)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", name, field.Name, err)
+		}
+		fields = append(fields, reflect.StructField{
+			Name: field.Name,
+			Type: ty,
+			Tag:  reflect.StructTag(`hcl:` + strconv.Quote(field.Tag)),
+		})
+	}
+	ty := reflect.StructOf(fields)
+	b.built[name] = ty
+	b.order = append(b.order, name)
+	return ty, nil
+}
+
+// hclFieldReport is how gohcl maps one field
+type hclFieldReport struct {
+	Field  string `json:"field"`
+	GoType string `json:"go_type"`
+	// Kind is the tag's kind (attr, optional, block, label, remain, body),
+	// or ignored for fields without a tag
+	Kind    string `json:"kind"`
+	HCLName string `json:"hcl_name,omitempty"`
+	// Required is what gohcl puts in the body schema for attributes, and
+	// whether exactly one block must be present for blocks
+	Required bool `json:"required"`
+	// CtyType is the type attribute values are converted to
+	CtyType  json.RawMessage `json:"cty_type,omitempty"`
+	Decision string          `json:"decision"`
+}
+
+// reportHCLStruct explains gohcl's mapping decisions for one struct type,
+// taking attribute requiredness from gohcl's own implied schema
+func reportHCLStruct(desc []hclStructFieldDesc, ty reflect.Type) []hclFieldReport {
+	schema, _ := gohcl.ImpliedBodySchema(reflect.New(ty).Interface())
+	required := map[string]bool{}
+	for _, attr := range schema.Attributes {
+		required[attr.Name] = attr.Required
+	}
+
+	reports := make([]hclFieldReport, 0, len(desc))
+	labels := 0
+	for _, field := range desc {
+		report := hclFieldReport{Field: field.Name, GoType: field.Type, Kind: "ignored"}
+		if field.Tag == "" {
+			report.Decision = "no hcl tag; gohcl leaves the field alone"
+			reports = append(reports, report)
+			continue
+		}
+		sf, _ := ty.FieldByName(field.Name)
+		report.HCLName, report.Kind, _ = strings.Cut(field.Tag, ",")
+		if report.Kind == "" {
+			report.Kind = "attr"
+		}
+		switch report.Kind {
+		case "attr", "optional":
+			report.Required = required[report.HCLName]
+			report.CtyType, report.Decision = hclAttrDecision(sf.Type, report.Required)
+		case "block":
+			report.Required = sf.Type.Kind() == reflect.Struct
+			switch sf.Type.Kind() {
+			case reflect.Slice:
+				report.Decision = "any number of blocks, in source order; none leaves the slice nil"
+			case reflect.Ptr:
+				report.Decision = "at most one block; absent leaves nil"
+			default:
+				report.Decision = "exactly one block"
+			}
+		case "label":
+			labels++
+			report.Required = true
+			report.Decision = fmt.Sprintf("block label %d", labels)
+		case "remain":
+			report.Decision = hclBodyDecision(sf.Type, "content no other field matched")
+		case "body":
+			report.Decision = hclBodyDecision(sf.Type, "the whole body")
+		}
+		reports = append(reports, report)
+	}
+	return reports
+}
+
+func hclAttrDecision(ty reflect.Type, required bool) (json.RawMessage, string) {
+	presence := "optional; absent leaves the zero value"
+	switch {
+	case required:
+		presence = "required"
+	case ty.Kind() == reflect.Ptr:
+		presence = "optional; absent leaves nil"
+	}
+	switch ty {
+	case hclExpressionType:
+		return nil, "kept as an unevaluated expression; absent gives a null expression"
+	case ctyValueType:
+		ctyType, _ := ctyjson.MarshalType(cty.DynamicPseudoType)
+		return ctyType, presence + "; kept as a cty value of any type"
+	}
+	implied, err := gocty.ImpliedType(reflect.New(ty).Elem().Interface())
+	if err != nil {
+		return nil, presence + "; no cty type for " + ty.String()
+	}
+	ctyType, _ := ctyjson.MarshalType(implied)
+	return ctyType, presence + "; converted to " + implied.FriendlyName()
+}
+
+func hclBodyDecision(ty reflect.Type, what string) string {
+	switch ty {
+	case hclBodyType:
+		return what + ", kept as an undecoded body"
+	case hclStructBuiltins["hcl.Attributes"]:
+		return what + ", as attributes; blocks there are an error"
+	}
+	return what + ", decoded into " + ty.String()
+}
+
+// hclStructValueJSON renders a decoded value for comparison: structs by Go
+// field name, cty values as plain JSON, and expressions and bodies by
+// where they are in the source
+func hclStructValueJSON(v reflect.Value) (interface{}, error) {
+	switch v.Type() {
+	case ctyValueType:
+		val := v.Interface().(cty.Value)
+		data, err := ctyjson.SimpleJSONValue{Value: val}.MarshalJSON()
+		if err != nil {
+			return nil, err
+		}
+		return json.RawMessage(data), nil
+	case hclAttributeType:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return map[string]interface{}{"expression": v.Interface().(*hcl.Attribute).Expr.Range().String()}, nil
+	}
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			return nil, nil
+		}
+		switch x := v.Interface().(type) {
+		case hcl.Expression:
+			return map[string]interface{}{"expression": x.Range().String()}, nil
+		case hcl.Body:
+			attrs, diags := x.JustAttributes()
+			if diags.HasErrors() {
+				return map[string]interface{}{"body": x.MissingItemRange().String()}, nil
+			}
+			names := make([]string, 0, len(attrs))
+			for name := range attrs {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return map[string]interface{}{"attributes": names}, nil
+		}
+		return hclStructValueJSON(v.Elem())
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return hclStructValueJSON(v.Elem())
+	case reflect.Struct:
+		out := map[string]interface{}{}
+		for i := 0; i < v.NumField(); i++ {
+			field, err := hclStructValueJSON(v.Field(i))
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", v.Type().Field(i).Name, err)
+			}
+			out[v.Type().Field(i).Name] = field
+		}
+		return out, nil
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil, nil
+		}
+		out := make([]interface{}, v.Len())
+		for i := range out {
+			elem, err := hclStructValueJSON(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			out[i] = elem
+		}
+		return out, nil
+	case reflect.Map:
+		if v.IsNil() {
+			return nil, nil
+		}
+		out := map[string]interface{}{}
+		iter := v.MapRange()
+		for iter.Next() {
+			elem, err := hclStructValueJSON(iter.Value())
+			if err != nil {
+				return nil, err
+			}
+			out[iter.Key().String()] = elem
+		}
+		return out, nil
+	}
+	return v.Interface(), nil
+}
+
+// decodeHCLStruct decodes body into a new root struct. gohcl panics on tags
+// it can't map, so those come back as errors.
+func decodeHCLStruct(body hcl.Body, ty reflect.Type, ctx *hcl.EvalContext) (val reflect.Value, diags hcl.Diagnostics, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("gohcl can't decode into this struct: %v", r)
+		}
+	}()
+	val = reflect.New(ty)
+	diags = gohcl.DecodeBody(body, ctx, val.Interface())
+	return val.Elem(), diags, nil
+}
+
+func initHclDecodeStructCmd() *cobra.Command {
+	var (
+		tagsPath   string
+		structPath string
+		root       string
+		describe   bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "decode-struct [file]",
+		Short: "Decode an HCL file into Go structs with gohcl tags and report the mapping",
+		Long: `Decode an HCL file the way gohcl decodes into tagged Go structs, for
+comparing tag- or annotation-driven decoders in other languages.
+
+The structs come from a Go source file (--schema-from-tags), whose struct
+declarations and hcl:"..." tags are read without compiling it, or from a JSON
+description (--struct) in the form --describe prints. Field types can be Go
+builtins, cty.Value, hcl.Expression, hcl.Body, hcl.Attributes and other
+described structs, under *, [] and map[string]. --root picks the struct to
+decode into; it defaults to the first struct with hcl tags.
+
+The output reports, for every struct reachable from the root, how gohcl maps
+each field: its kind, HCL name, whether it is required, the cty type
+attribute values convert to, and a short decision. The value follows, keyed
+by Go field name. Decode diagnostics are printed as JSON with the report and
+fail the command; tags gohcl can't map are errors.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var desc *hclStructDesc
+			var err error
+			if tagsPath != "" {
+				desc, err = parseHCLStructTags(tagsPath)
+			} else {
+				desc, err = loadHCLStructDesc(structPath)
+			}
+			if err != nil {
+				return err
+			}
+			if root != "" {
+				desc.Root = root
+			}
+			if _, ok := desc.Types[desc.Root]; !ok {
+				return fmt.Errorf("no struct type %q", desc.Root)
+			}
+
+			if describe {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(desc)
+			}
+			if len(args) == 0 {
+				return fmt.Errorf("an HCL file is required unless --describe is set")
+			}
+			filename := args[0]
+
+			builder := &hclStructBuilder{desc: desc, built: map[string]reflect.Type{}, building: map[string]bool{}}
+			rootType, err := builder.structOf(desc.Root)
+			if err != nil {
+				return fmt.Errorf("invalid struct description: %w", err)
+			}
+			report := map[string][]hclFieldReport{}
+			err = func() (err error) {
+				defer func() {
+					if r := recover(); r != nil {
+						err = fmt.Errorf("gohcl can't map these structs: %v", r)
+					}
+				}()
+				for _, name := range builder.order {
+					report[name] = reportHCLStruct(desc.Types[name], builder.built[name])
+				}
+				return nil
+			}()
+			if err != nil {
+				return err
+			}
+
+			content, err := os.ReadFile(filename)
+			if err != nil {
+				return fmt.Errorf("failed to read file: %w", err)
+			}
+			file, diags := hclparse.NewParser().ParseHCL(content, filename)
+
+			var val reflect.Value
+			if !diags.HasErrors() {
+				err = evalLimit.run(filename, func() error {
+					ctx := &hcl.EvalContext{Functions: evalLimit.functions(hclViewFunctions())}
+					var decodeDiags hcl.Diagnostics
+					var decodeErr error
+					val, decodeDiags, decodeErr = decodeHCLStruct(file.Body, rootType, ctx)
+					diags = append(diags, decodeDiags...)
+					return decodeErr
+				})
+				if err != nil {
+					return err
+				}
+			}
+			if diags.HasErrors() {
+				json.NewEncoder(os.Stdout).Encode(map[string]interface{}{
+					"success": false,
+					"root":    desc.Root,
+					"types":   report,
+					"errors":  diagnosticsToJSON(diags),
+				})
+				return fmt.Errorf("decode errors occurred")
+			}
+
+			value, err := hclStructValueJSON(val)
+			if err != nil {
+				return fmt.Errorf("failed to render value: %w", err)
+			}
+			if err := json.NewEncoder(os.Stdout).Encode(map[string]interface{}{
+				"success": true,
+				"root":    desc.Root,
+				"types":   report,
+				"value":   value,
+			}); err != nil {
+				return fmt.Errorf("failed to encode JSON: %w", err)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&tagsPath, "schema-from-tags", "", "Go source file declaring the structs, with hcl tags")
+	cmd.Flags().StringVar(&structPath, "struct", "", "JSON struct description, as --describe prints")
+	cmd.Flags().StringVar(&root, "root", "", "Struct to decode into (default: the first with hcl tags)")
+	cmd.Flags().BoolVar(&describe, "describe", false, "Print the struct description as JSON instead of decoding")
+	cmd.MarkFlagsOneRequired("schema-from-tags", "struct")
+	cmd.MarkFlagsMutuallyExclusive("schema-from-tags", "struct")
+	addEvalLimitFlags(cmd, &evalLimit)
+
+	return cmd
+}
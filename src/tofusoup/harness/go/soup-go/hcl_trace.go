@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// hclTracedValue is a function argument or result in a call trace. Value
+// is left out of unknown values.
+type hclTracedValue struct {
+	Type    json.RawMessage `json:"type"`
+	Value   json.RawMessage `json:"value,omitempty"`
+	Unknown bool            `json:"unknown,omitempty"`
+	Marked  bool            `json:"marked,omitempty"`
+}
+
+// hclCallRecord is one line of a --trace-calls file: a function call made
+// while evaluating an attribute in a scope
+type hclCallRecord struct {
+	Seq        int              `json:"seq"`
+	Scope      string           `json:"scope"`
+	Attribute  string           `json:"attribute"`
+	Function   string           `json:"function"`
+	Args       []hclTracedValue `json:"args"`
+	Result     *hclTracedValue  `json:"result,omitempty"`
+	Error      string           `json:"error,omitempty"`
+	DurationMs float64          `json:"duration_ms"`
+}
+
+// hclCallTracer writes every function call hcl eval makes to --trace-calls
+// as NDJSON. Calls are numbered in the order they run, so nested calls come
+// before the call they're an argument of.
+type hclCallTracer struct {
+	mu        sync.Mutex
+	w         io.Writer
+	file      *os.File
+	seq       int
+	scope     string
+	attribute string
+	err       error
+}
+
+// hclTrace is the tracer for hcl eval; its writer is nil unless
+// --trace-calls is given
+var hclTrace = &hclCallTracer{}
+
+// open starts a trace at path, or on stderr if path is "-"
+func (t *hclCallTracer) open(path string) error {
+	if path == "-" {
+		t.w = os.Stderr
+		return nil
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create call trace: %w", err)
+	}
+	t.w, t.file = file, file
+	return nil
+}
+
+// close finishes the trace, returning the first error writing it
+func (t *hclCallTracer) close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.file != nil {
+		if err := t.file.Close(); t.err == nil {
+			t.err = err
+		}
+		t.file = nil
+	}
+	return t.err
+}
+
+// enter sets the scope and attribute following calls are recorded against
+func (t *hclCallTracer) enter(scope, attribute string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.scope, t.attribute = scope, attribute
+}
+
+func (t *hclCallTracer) record(rec hclCallRecord) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.err != nil {
+		return
+	}
+	t.seq++
+	rec.Seq, rec.Scope, rec.Attribute = t.seq, t.scope, t.attribute
+	line, err := json.Marshal(rec)
+	if err == nil {
+		_, err = t.w.Write(append(line, '\n'))
+	}
+	t.err = err
+}
+
+// traceValue renders val with its type, whether or not it's known
+func traceValue(val cty.Value) hclTracedValue {
+	val, marks := val.UnmarkDeep()
+	traced := hclTracedValue{Marked: len(marks) > 0}
+	traced.Type, _ = ctyjson.MarshalType(val.Type())
+	if !val.IsWhollyKnown() {
+		traced.Unknown = true
+		return traced
+	}
+	traced.Value, _ = ctyjson.Marshal(val, val.Type())
+	return traced
+}
+
+// functions wraps funcs so each call is recorded, or returns them as they
+// are if no trace is open
+func (t *hclCallTracer) functions(funcs map[string]function.Function) map[string]function.Function {
+	if t.w == nil {
+		return funcs
+	}
+	wrapped := make(map[string]function.Function, len(funcs))
+	for name, f := range funcs {
+		wrapped[name] = t.wrapFunction(name, f)
+	}
+	return wrapped
+}
+
+func (t *hclCallTracer) wrapFunction(name string, f function.Function) function.Function {
+	spec := &function.Spec{
+		Description: f.Description(),
+		Params:      f.Params(),
+		VarParam:    f.VarParam(),
+		Type: func(args []cty.Value) (cty.Type, error) {
+			return f.ReturnTypeForValues(args)
+		},
+		Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+			rec := hclCallRecord{Function: name, Args: make([]hclTracedValue, 0, len(args))}
+			for _, arg := range args {
+				rec.Args = append(rec.Args, traceValue(arg))
+			}
+			start := time.Now()
+			val, err := f.Call(args)
+			rec.DurationMs = float64(time.Since(start).Microseconds()) / 1000
+			if err != nil {
+				rec.Error = err.Error()
+			} else {
+				result := traceValue(val)
+				rec.Result = &result
+			}
+			t.record(rec)
+			return val, err
+		},
+	}
+	return function.New(spec)
+}
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+	"github.com/zclconf/go-cty/cty/function/stdlib"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// Expression classes reported by hcl view
+const (
+	exprClassLiteral      = "literal"
+	exprClassTemplate     = "template"
+	exprClassFunctionCall = "function-call"
+	exprClassReference    = "reference"
+	exprClassCollection   = "collection"
+	exprClassOperation    = "operation"
+	exprClassConditional  = "conditional"
+	exprClassFor          = "for"
+	exprClassSplat        = "splat"
+)
+
+// hclTreeNode is a body, block or attribute in the document tree, or the
+// expression an expr or template fragment holds
+type hclTreeNode struct {
+	Kind       string         `json:"kind"`
+	Name       string         `json:"name,omitempty"`
+	Labels     []string       `json:"labels,omitempty"`
+	Range      hclTreeRange   `json:"range"`
+	Expression *hclExprInfo   `json:"expression,omitempty"`
+	Children   []*hclTreeNode `json:"children,omitempty"`
+}
+
+// hclTreeRange is a source range as 1-based line:column positions
+type hclTreeRange struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// hclExprInfo classifies an attribute expression and, when it can be
+// evaluated without variables, its value
+type hclExprInfo struct {
+	Class      string          `json:"class"`
+	Source     string          `json:"source"`
+	References []string        `json:"references,omitempty"`
+	Functions  []string        `json:"functions,omitempty"`
+	Evaluated  bool            `json:"evaluated"`
+	Value      json.RawMessage `json:"value,omitempty"`
+	Type       json.RawMessage `json:"type,omitempty"`
+	EvalError  string          `json:"eval_error,omitempty"`
+}
+
+// hclViewFunctions are the pure functions available when evaluating
+// expressions for hcl view
+func hclViewFunctions() map[string]function.Function {
+	return map[string]function.Function{
+		"abs":        stdlib.AbsoluteFunc,
+		"ceil":       stdlib.CeilFunc,
+		"chomp":      stdlib.ChompFunc,
+		"coalesce":   stdlib.CoalesceFunc,
+		"concat":     stdlib.ConcatFunc,
+		"contains":   stdlib.ContainsFunc,
+		"distinct":   stdlib.DistinctFunc,
+		"flatten":    stdlib.FlattenFunc,
+		"floor":      stdlib.FloorFunc,
+		"format":     stdlib.FormatFunc,
+		"formatlist": stdlib.FormatListFunc,
+		"join":       stdlib.JoinFunc,
+		"jsondecode": stdlib.JSONDecodeFunc,
+		"jsonencode": stdlib.JSONEncodeFunc,
+		"keys":       stdlib.KeysFunc,
+		"length":     stdlib.LengthFunc,
+		"lookup":     stdlib.LookupFunc,
+		"lower":      stdlib.LowerFunc,
+		"max":        stdlib.MaxFunc,
+		"merge":      stdlib.MergeFunc,
+		"min":        stdlib.MinFunc,
+		"range":      stdlib.RangeFunc,
+		"replace":    stdlib.ReplaceFunc,
+		"reverse":    stdlib.ReverseListFunc,
+		"sort":       stdlib.SortFunc,
+		"split":      stdlib.SplitFunc,
+		"substr":     stdlib.SubstrFunc,
+		"title":      stdlib.TitleFunc,
+		"trimspace":  stdlib.TrimSpaceFunc,
+		"upper":      stdlib.UpperFunc,
+		"values":     stdlib.ValuesFunc,
+		"zipmap":     stdlib.ZipmapFunc,
+	}
+}
+
+// buildHCLTree builds the document tree for a parsed native-syntax file
+func buildHCLTree(file *hcl.File) (*hclTreeNode, error) {
+	body, ok := file.Body.(*hclsyntax.Body)
+	if !ok {
+		return nil, fmt.Errorf("unsupported body type %T", file.Body)
+	}
+	root := &hclTreeNode{Kind: "body", Range: treeRange(body.SrcRange)}
+	root.Children = bodyTreeChildren(body, file.Bytes)
+	return root, nil
+}
+
+// bodyTreeChildren returns the attributes and blocks of body in source order
+func bodyTreeChildren(body *hclsyntax.Body, src []byte) []*hclTreeNode {
+	type positioned struct {
+		offset int
+		node   *hclTreeNode
+	}
+	var items []positioned
+
+	for name, attr := range body.Attributes {
+		items = append(items, positioned{attr.SrcRange.Start.Byte, &hclTreeNode{
+			Kind:       "attribute",
+			Name:       name,
+			Range:      treeRange(attr.SrcRange),
+			Expression: classifyExpression(attr.Expr, src),
+		}})
+	}
+	for _, block := range body.Blocks {
+		node := &hclTreeNode{
+			Kind:   "block",
+			Name:   block.Type,
+			Labels: block.Labels,
+			Range:  treeRange(block.Range()),
+		}
+		node.Children = bodyTreeChildren(block.Body, src)
+		items = append(items, positioned{block.TypeRange.Start.Byte, node})
+	}
+
+	sort.Slice(items, func(i, j int) bool { return items[i].offset < items[j].offset })
+	nodes := make([]*hclTreeNode, len(items))
+	for i, item := range items {
+		nodes[i] = item.node
+	}
+	return nodes
+}
+
+// classifyExpression tags expr with its class and evaluates it if it has no
+// references
+func classifyExpression(expr hclsyntax.Expression, src []byte) *hclExprInfo {
+	info := &hclExprInfo{
+		Class:  expressionClass(expr),
+		Source: string(expr.Range().SliceBytes(src)),
+	}
+
+	for _, traversal := range expr.Variables() {
+		info.References = append(info.References, traversalString(traversal))
+	}
+	seen := map[string]bool{}
+	hclsyntax.VisitAll(expr, func(node hclsyntax.Node) hcl.Diagnostics {
+		if call, ok := node.(*hclsyntax.FunctionCallExpr); ok && !seen[call.Name] {
+			seen[call.Name] = true
+			info.Functions = append(info.Functions, call.Name)
+		}
+		return nil
+	})
+
+	if len(info.References) > 0 {
+		info.EvalError = "expression has references"
+		return info
+	}
+	val, diags := expr.Value(&hcl.EvalContext{Functions: evalLimit.functions(hclViewFunctions())})
+	if diags.HasErrors() {
+		info.EvalError = diags.Error()
+		return info
+	}
+	if err := evalLimit.checkValue("expression", val); err != nil {
+		info.EvalError = err.Error()
+		return info
+	}
+	if !val.IsWhollyKnown() {
+		info.EvalError = "value is not known"
+		return info
+	}
+	valueJSON, err := ctyjson.Marshal(val, val.Type())
+	if err != nil {
+		info.EvalError = err.Error()
+		return info
+	}
+	typeJSON, err := ctyjson.MarshalType(val.Type())
+	if err != nil {
+		info.EvalError = err.Error()
+		return info
+	}
+	info.Evaluated = true
+	info.Value = valueJSON
+	info.Type = typeJSON
+	return info
+}
+
+// expressionClass returns the class of the outermost expression. Tuples
+// and objects built only from literals count as literals.
+func expressionClass(expr hclsyntax.Expression) string {
+	switch e := expr.(type) {
+	case *hclsyntax.LiteralValueExpr:
+		return exprClassLiteral
+	case *hclsyntax.TemplateExpr:
+		if e.IsStringLiteral() {
+			return exprClassLiteral
+		}
+		return exprClassTemplate
+	case *hclsyntax.TemplateWrapExpr, *hclsyntax.TemplateJoinExpr:
+		return exprClassTemplate
+	case *hclsyntax.FunctionCallExpr:
+		return exprClassFunctionCall
+	case *hclsyntax.ScopeTraversalExpr, *hclsyntax.RelativeTraversalExpr, *hclsyntax.IndexExpr:
+		return exprClassReference
+	case *hclsyntax.TupleConsExpr:
+		for _, item := range e.Exprs {
+			if expressionClass(item) != exprClassLiteral {
+				return exprClassCollection
+			}
+		}
+		return exprClassLiteral
+	case *hclsyntax.ObjectConsExpr:
+		for _, item := range e.Items {
+			if expressionClass(item.KeyExpr) != exprClassLiteral || expressionClass(item.ValueExpr) != exprClassLiteral {
+				return exprClassCollection
+			}
+		}
+		return exprClassLiteral
+	case *hclsyntax.ObjectConsKeyExpr:
+		// Bare identifiers as object keys are strings, not references
+		if !e.ForceNonLiteral && hcl.ExprAsKeyword(e.Wrapped) != "" {
+			return exprClassLiteral
+		}
+		return expressionClass(e.Wrapped)
+	case *hclsyntax.BinaryOpExpr, *hclsyntax.UnaryOpExpr:
+		return exprClassOperation
+	case *hclsyntax.ConditionalExpr:
+		return exprClassConditional
+	case *hclsyntax.ForExpr:
+		return exprClassFor
+	case *hclsyntax.SplatExpr:
+		return exprClassSplat
+	case *hclsyntax.ParenthesesExpr:
+		return expressionClass(e.Expression)
+	}
+	return exprClassOperation
+}
+
+// traversalString renders a traversal like var.foo[0].bar
+func traversalString(traversal hcl.Traversal) string {
+	var b strings.Builder
+	for _, step := range traversal {
+		switch s := step.(type) {
+		case hcl.TraverseRoot:
+			b.WriteString(s.Name)
+		case hcl.TraverseAttr:
+			b.WriteString("." + s.Name)
+		case hcl.TraverseIndex:
+			if s.Key.Type() == cty.String {
+				fmt.Fprintf(&b, "[%q]", s.Key.AsString())
+			} else if s.Key.Type() == cty.Number {
+				b.WriteString("[" + s.Key.AsBigFloat().Text('f', -1) + "]")
+			} else {
+				b.WriteString("[?]")
+			}
+		case hcl.TraverseSplat:
+			b.WriteString("[*]")
+		}
+	}
+	return b.String()
+}
+
+func treeRange(r hcl.Range) hclTreeRange {
+	return hclTreeRange{
+		Start: fmt.Sprintf("%d:%d", r.Start.Line, r.Start.Column),
+		End:   fmt.Sprintf("%d:%d", r.End.Line, r.End.Column),
+	}
+}
+
+// writeHCLTree prints the tree with box-drawing guides, one node per line
+func writeHCLTree(w io.Writer, title string, root *hclTreeNode) {
+	fmt.Fprintln(w, title)
+	writeTreeChildren(w, root.Children, "")
+}
+
+func writeTreeChildren(w io.Writer, nodes []*hclTreeNode, prefix string) {
+	for i, node := range nodes {
+		branch, indent := "├── ", "│   "
+		if i == len(nodes)-1 {
+			branch, indent = "└── ", "    "
+		}
+
+		switch node.Kind {
+		case "block":
+			line := node.Name
+			for _, label := range node.Labels {
+				line += fmt.Sprintf(" %q", label)
+			}
+			fmt.Fprintf(w, "%s%s%s\n", prefix, branch, line)
+			writeTreeChildren(w, node.Children, prefix+indent)
+		case "attribute":
+			fmt.Fprintf(w, "%s%s%s = %s\n", prefix, branch, node.Name, exprTreeLine(node.Expression))
+		case "expression":
+			fmt.Fprintf(w, "%s%s%s\n", prefix, branch, exprTreeLine(node.Expression))
+		}
+	}
+}
+
+// exprTreeLine is an expression's source, class, value and references for
+// the text tree
+func exprTreeLine(expr *hclExprInfo) string {
+	line := fmt.Sprintf("%s  [%s]", oneLine(expr.Source), expr.Class)
+	if expr.Evaluated && expr.Class != exprClassLiteral {
+		line += " => " + string(expr.Value)
+	}
+	if len(expr.References) > 0 {
+		line += " refs: " + strings.Join(expr.References, ", ")
+	}
+	return line
+}
+
+var lineBreaks = regexp.MustCompile(`\s*\n\s*`)
+
+// oneLine collapses a multi-line source snippet for the text tree
+func oneLine(source string) string {
+	line := lineBreaks.ReplaceAllString(source, " ")
+	if runes := []rune(line); len(runes) > 80 {
+		line = string(runes[:77]) + "..."
+	}
+	return line
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const hclTreeTestConfig = `region = "us-east-1"
+name   = "${var.prefix}-web"
+count  = length(["a", "b"])
+ids    = aws_instance.web[*].id
+size   = 2 * 3
+mode   = var.prod ? "ha" : "single"
+tags   = { env = "test" }
+upper  = [for s in ["x"] : upper(s)]
+
+resource "aws_instance" "web" {
+  ami = local.ami
+  ebs {
+    size = 10
+  }
+}
+`
+
+func runHCLView(t *testing.T, src string, args ...string) []byte {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "main.tf")
+	if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cmd := initHclViewCmd()
+	cmd.SetArgs(append([]string{path}, args...))
+	out, err := captureStdout(t, cmd.Execute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestHCLViewTreeJSON(t *testing.T) {
+	var output struct {
+		Success bool         `json:"success"`
+		Tree    *hclTreeNode `json:"tree"`
+	}
+	out := runHCLView(t, hclTreeTestConfig)
+	if err := json.Unmarshal(out, &output); err != nil {
+		t.Fatalf("%v: %s", err, out)
+	}
+	if !output.Success || output.Tree.Kind != "body" {
+		t.Fatalf("got %s", out)
+	}
+
+	children := output.Tree.Children
+	var names []string
+	for _, child := range children {
+		names = append(names, child.Name)
+	}
+	if got := strings.Join(names, ","); got != "region,name,count,ids,size,mode,tags,upper,resource" {
+		t.Fatalf("got children %s, want source order", got)
+	}
+
+	tests := []struct {
+		index      int
+		class      string
+		value      string
+		references string
+		functions  string
+	}{
+		{index: 0, class: exprClassLiteral, value: `"us-east-1"`},
+		{index: 1, class: exprClassTemplate, references: "var.prefix"},
+		{index: 2, class: exprClassFunctionCall, value: "2", functions: "length"},
+		{index: 3, class: exprClassSplat, references: "aws_instance.web"},
+		{index: 4, class: exprClassOperation, value: "6"},
+		{index: 5, class: exprClassConditional, references: "var.prod"},
+		{index: 6, class: exprClassLiteral, value: `{"env":"test"}`},
+		{index: 7, class: exprClassFor, value: `["X"]`, functions: "upper"},
+	}
+	for _, tt := range tests {
+		expr := children[tt.index].Expression
+		if expr.Class != tt.class {
+			t.Errorf("%s: got class %s, want %s", children[tt.index].Name, expr.Class, tt.class)
+		}
+		if string(expr.Value) != tt.value || expr.Evaluated != (tt.value != "") {
+			t.Errorf("%s: got value %s (evaluated %v), want %s", children[tt.index].Name, expr.Value, expr.Evaluated, tt.value)
+		}
+		if got := strings.Join(expr.References, ","); got != tt.references {
+			t.Errorf("%s: got references %q, want %q", children[tt.index].Name, got, tt.references)
+		}
+		if got := strings.Join(expr.Functions, ","); got != tt.functions {
+			t.Errorf("%s: got functions %q, want %q", children[tt.index].Name, got, tt.functions)
+		}
+	}
+
+	resource := children[8]
+	if resource.Kind != "block" || strings.Join(resource.Labels, ",") != "aws_instance,web" {
+		t.Errorf("got resource node %+v", resource)
+	}
+	if resource.Range.Start != "10:1" || resource.Range.End != "15:2" {
+		t.Errorf("got resource range %+v, want 10:1 to 15:2", resource.Range)
+	}
+	ebs := resource.Children[1]
+	if ebs.Kind != "block" || ebs.Name != "ebs" || ebs.Children[0].Expression.Source != "10" {
+		t.Errorf("got nested block %+v", ebs)
+	}
+}
+
+func TestHCLViewTreeText(t *testing.T) {
+	out := string(runHCLView(t, hclTreeTestConfig, "--output-format", "tree"))
+	for _, want := range []string{
+		"├── region = \"us-east-1\"  [literal]\n",
+		"├── count = length([\"a\", \"b\"])  [function-call] => 2\n",
+		"├── ids = aws_instance.web[*].id  [splat] refs: aws_instance.web\n",
+		"└── resource \"aws_instance\" \"web\"\n",
+		"    ├── ami = local.ami  [reference] refs: local.ami\n",
+		"    └── ebs\n",
+		"        └── size = 10  [literal]\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("tree is missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestHCLViewTreeFragment(t *testing.T) {
+	var output struct {
+		Fragment string       `json:"fragment"`
+		Body     interface{}  `json:"body"`
+		Tree     *hclTreeNode `json:"tree"`
+	}
+	out := runHCLView(t, "max(1, 5) + var.n", "--fragment", "expr")
+	if err := json.Unmarshal(out, &output); err != nil {
+		t.Fatalf("%v: %s", err, out)
+	}
+	if output.Fragment != "expr" || output.Body != nil || len(output.Tree.Children) != 1 {
+		t.Fatalf("got %s", out)
+	}
+	expr := output.Tree.Children[0]
+	if expr.Kind != "expression" || expr.Expression.Class != exprClassOperation ||
+		expr.Expression.Evaluated || expr.Expression.EvalError != "expression has references" {
+		t.Errorf("got expression node %+v", expr.Expression)
+	}
+}
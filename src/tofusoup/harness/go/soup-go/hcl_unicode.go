@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/spf13/cobra"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function/stdlib"
+)
+
+// hclUnicodeForm is one way of writing a case as an HCL string literal and
+// what evaluating it produced
+type hclUnicodeForm struct {
+	Source string `json:"source"`
+	Value  string `json:"value,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// hclUnicodeResult is what HCL makes of a string written with escapes and
+// as raw UTF-8, and what its string functions return for the raw form
+type hclUnicodeResult struct {
+	Escaped    hclUnicodeForm `json:"escaped"`
+	Raw        hclUnicodeForm `json:"raw"`
+	FormsMatch bool           `json:"forms_match"`
+	Normalized bool           `json:"normalized"`
+	Length     int64          `json:"length"`
+	First      string         `json:"first"`
+	Upper      string         `json:"upper"`
+	Lower      string         `json:"lower"`
+	Error      string         `json:"error,omitempty"`
+}
+
+// hclRawReplacer escapes what HCL would otherwise read as syntax in a
+// quoted template
+var hclRawReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "${", "$${", "%{", "%%{")
+
+// hclEscapedSource rewrites a case's JSON string body as an HCL quoted
+// string. HCL has no surrogate pairs, so a pair becomes one \U escape of
+// the character it encodes; unpaired surrogates stay \u escapes, which
+// HCL rejects.
+func hclEscapedSource(input string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(input); {
+		unit, ok := jsonEscapeUnit(input, i)
+		if !ok {
+			b.WriteByte(input[i])
+			i++
+			continue
+		}
+		if utf16.IsSurrogate(rune(unit)) {
+			if low, ok := jsonEscapeUnit(input, i+6); ok {
+				if r := utf16.DecodeRune(rune(unit), rune(low)); r != utf8.RuneError {
+					fmt.Fprintf(&b, `\U%08x`, r)
+					i += 12
+					continue
+				}
+			}
+		}
+		fmt.Fprintf(&b, `\u%04x`, unit)
+		i += 6
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// jsonEscapeUnit reads the UTF-16 unit of a \u escape at s[i], if there is
+// one
+func jsonEscapeUnit(s string, i int) (uint16, bool) {
+	if i+6 > len(s) || s[i] != '\\' || s[i+1] != 'u' {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(s[i+2:i+6], 16, 16)
+	return uint16(n), err == nil
+}
+
+// evalHCLUnicode evaluates an HCL expression of type want with the hcl
+// view functions plus strlen, which counts grapheme clusters
+func evalHCLUnicode(src string, want cty.Type) (cty.Value, error) {
+	expr, diags := hclsyntax.ParseExpression([]byte(src), "unicode.hcl", hcl.InitialPos)
+	if diags.HasErrors() {
+		return cty.NilVal, diags
+	}
+	functions := hclViewFunctions()
+	functions["strlen"] = stdlib.StrlenFunc
+	val, diags := expr.Value(&hcl.EvalContext{Functions: functions})
+	if diags.HasErrors() {
+		return cty.NilVal, diags
+	}
+	if !val.Type().Equals(want) || !val.IsKnown() || val.IsNull() {
+		return cty.NilVal, fmt.Errorf("expected a known %s, got %s", want.FriendlyName(), val.Type().FriendlyName())
+	}
+	return val, nil
+}
+
+// evalHCLForm evaluates a quoted string source, reporting it as a form
+func evalHCLForm(src string) (hclUnicodeForm, cty.Value, bool) {
+	form := hclUnicodeForm{Source: asciiEscape(src)}
+	val, err := evalHCLUnicode(src, cty.String)
+	if err != nil {
+		form.Error = err.Error()
+		return form, cty.NilVal, false
+	}
+	form.Value = asciiEscape(val.AsString())
+	return form, val, true
+}
+
+// probeHCLString runs a case through HCL string literals and the string
+// functions
+func probeHCLString(input, decoded string) *hclUnicodeResult {
+	result := &hclUnicodeResult{}
+	var escaped, raw cty.Value
+	var escapedOK, rawOK bool
+	result.Escaped, escaped, escapedOK = evalHCLForm(hclEscapedSource(input))
+	rawSource := `"` + hclRawReplacer.Replace(decoded) + `"`
+	result.Raw, raw, rawOK = evalHCLForm(rawSource)
+	if !rawOK {
+		result.Error = result.Raw.Error
+		return result
+	}
+	result.FormsMatch = escapedOK && escaped.RawEquals(raw)
+	result.Normalized = raw.AsString() != decoded
+
+	length, err := evalHCLUnicode("strlen("+rawSource+")", cty.Number)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Length, _ = length.AsBigFloat().Int64()
+	calls := []struct {
+		out  *string
+		expr string
+	}{
+		{&result.First, "substr(" + rawSource + ", 0, 1)"},
+		{&result.Upper, "upper(" + rawSource + ")"},
+		{&result.Lower, "lower(" + rawSource + ")"},
+	}
+	for _, c := range calls {
+		out, err := evalHCLUnicode(c.expr, cty.String)
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		*c.out = asciiEscape(out.AsString())
+	}
+	return result
+}
+
+func initHclUnicodeCmd() *cobra.Command {
+	var cases []string
+
+	cmd := &cobra.Command{
+		Use:   "unicode",
+		Short: "Report how HCL parses, normalizes and measures a suite of unicode strings",
+		Long: `Run the same suite of strings as 'cty unicode' through HCL string
+literals and report, for each:
+
+  measures  the decoded string's UTF-8 bytes, UTF-16 units, code points,
+            grapheme clusters and normalization forms
+  hcl       the string written with HCL escapes (\u and \U; HCL has no
+            surrogate pairs, so unpaired surrogates are errors) and as raw
+            UTF-8, whether both evaluate to the same value, whether HCL
+            normalized it, and strlen, substr(0, 1), upper and lower
+
+Compare with 'soup hcl unicode' to find where HCL implementations
+disagree on escapes, normalization and string length.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			reports, err := runUnicodeProbe(cases, func(c unicodeProbeCase, decoded string, report *unicodeCaseReport) {
+				report.HCL = probeHCLString(c.Input, decoded)
+			})
+			if err != nil {
+				return err
+			}
+			return encodeUnicodeReport("hcl", reports)
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&cases, "case", nil, "Only run these cases (default all)")
+	return cmd
+}
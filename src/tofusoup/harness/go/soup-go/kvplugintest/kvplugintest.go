@@ -0,0 +1,190 @@
+// Package kvplugintest runs a TofuSoup KV plugin server inside a Go test.
+//
+// StartServerT serves KV over the go-plugin gRPC protocol in the test
+// process and returns a client reattached to it, so suites in other repos
+// can exercise KV clients and servers under 'go test' without building or
+// shelling out to soup-go:
+//
+//	func TestClient(t *testing.T) {
+//		srv := kvplugintest.StartServerT(t, kvplugintest.Options{})
+//		kvplugintest.RequireRoundTrip(t, srv.Client)
+//	}
+//
+// By default the server is a MemoryServer. Set Options.Server to test your
+// own proto.KVServer behind the same plugin plumbing.
+package kvplugintest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/go-plugin"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/provide-io/tofusoup/proto/kv"
+)
+
+// Handshake is the handshake the soup-go harness and Python server use
+var Handshake = plugin.HandshakeConfig{
+	ProtocolVersion:  1,
+	MagicCookieKey:   "BASIC_PLUGIN",
+	MagicCookieValue: "hello",
+}
+
+// PluginName is the name the KV service is dispensed under
+const PluginName = "kv_grpc"
+
+// Options configures StartServerT
+type Options struct {
+	// Server handles KV calls. Defaults to NewMemoryServer().
+	Server proto.KVServer
+	// Logger receives go-plugin logs. Defaults to a logger that discards
+	// everything.
+	Logger hclog.Logger
+	// StartTimeout bounds how long the server may take to come up.
+	// Defaults to 10s.
+	StartTimeout time.Duration
+}
+
+// Server is a KV plugin server running in the test process
+type Server struct {
+	// Client is connected to the server through go-plugin
+	Client proto.KVClient
+	// Reattach is the server's reattach config, for pointing other go-plugin
+	// clients (or soup-go --address) at it
+	Reattach *plugin.ReattachConfig
+	// Impl is the proto.KVServer handling calls
+	Impl proto.KVServer
+}
+
+// kvPlugin serves and dispenses the KV service as a go-plugin plugin
+type kvPlugin struct {
+	plugin.Plugin
+	impl proto.KVServer
+}
+
+func (p *kvPlugin) GRPCServer(broker *plugin.GRPCBroker, s *grpc.Server) error {
+	proto.RegisterKVServer(s, p.impl)
+	return nil
+}
+
+func (p *kvPlugin) GRPCClient(ctx context.Context, broker *plugin.GRPCBroker, c *grpc.ClientConn) (interface{}, error) {
+	return proto.NewKVClient(c), nil
+}
+
+// StartServerT starts a KV plugin server in-process and connects a client
+// to it. The server and client are shut down when the test finishes.
+// Failures to start stop the test with t.Fatal.
+func StartServerT(t testing.TB, opts Options) *Server {
+	t.Helper()
+	if opts.Server == nil {
+		opts.Server = NewMemoryServer()
+	}
+	if opts.Logger == nil {
+		opts.Logger = hclog.NewNullLogger()
+	}
+	if opts.StartTimeout == 0 {
+		opts.StartTimeout = 10 * time.Second
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	reattachCh := make(chan *plugin.ReattachConfig, 1)
+	closeCh := make(chan struct{})
+	go plugin.Serve(&plugin.ServeConfig{
+		HandshakeConfig: Handshake,
+		Plugins:         plugin.PluginSet{PluginName: &kvPlugin{impl: opts.Server}},
+		GRPCServer:      plugin.DefaultGRPCServer,
+		Logger:          opts.Logger,
+		Test: &plugin.ServeTestConfig{
+			Context:          ctx,
+			ReattachConfigCh: reattachCh,
+			CloseCh:          closeCh,
+		},
+	})
+	t.Cleanup(func() {
+		cancel()
+		<-closeCh
+	})
+
+	var reattach *plugin.ReattachConfig
+	select {
+	case reattach = <-reattachCh:
+	case <-closeCh:
+		t.Fatal("kvplugintest: server exited before it was ready")
+	case <-time.After(opts.StartTimeout):
+		t.Fatalf("kvplugintest: server didn't start within %s", opts.StartTimeout)
+	}
+
+	client := plugin.NewClient(&plugin.ClientConfig{
+		HandshakeConfig:  Handshake,
+		Plugins:          plugin.PluginSet{PluginName: &kvPlugin{}},
+		Reattach:         reattach,
+		Logger:           opts.Logger,
+		AllowedProtocols: []plugin.Protocol{plugin.ProtocolGRPC},
+	})
+	t.Cleanup(client.Kill)
+
+	rpcClient, err := client.Client()
+	if err != nil {
+		t.Fatalf("kvplugintest: failed to connect: %v", err)
+	}
+	raw, err := rpcClient.Dispense(PluginName)
+	if err != nil {
+		t.Fatalf("kvplugintest: failed to dispense %s: %v", PluginName, err)
+	}
+
+	return &Server{
+		Client:   raw.(proto.KVClient),
+		Reattach: reattach,
+		Impl:     opts.Server,
+	}
+}
+
+// roundTrips numbers RequireRoundTrip keys so repeated calls don't collide
+var roundTrips atomic.Int64
+
+// RequireRoundTrip puts a value under a fresh key, reads it back and fails
+// the test unless it comes back unchanged. It returns the key used.
+func RequireRoundTrip(t testing.TB, client proto.KVClient) string {
+	t.Helper()
+	key := fmt.Sprintf("kvplugintest-%d-%d", time.Now().UnixNano(), roundTrips.Add(1))
+	value := []byte("kvplugintest value for " + key)
+	RequirePut(t, client, key, value)
+	if got := RequireGet(t, client, key); !bytes.Equal(got, value) {
+		t.Fatalf("kvplugintest: Get %s returned %q, want %q", key, got, value)
+	}
+	return key
+}
+
+// RequirePut stores value under key, failing the test on error
+func RequirePut(t testing.TB, client proto.KVClient, key string, value []byte) {
+	t.Helper()
+	if _, err := client.Put(context.Background(), &proto.PutRequest{Key: key, Value: value}); err != nil {
+		t.Fatalf("kvplugintest: Put %s failed: %v", key, err)
+	}
+}
+
+// RequireGet returns the value stored under key, failing the test on error
+func RequireGet(t testing.TB, client proto.KVClient, key string) []byte {
+	t.Helper()
+	resp, err := client.Get(context.Background(), &proto.GetRequest{Key: key})
+	if err != nil {
+		t.Fatalf("kvplugintest: Get %s failed: %v", key, err)
+	}
+	return resp.Value
+}
+
+// RequireCode fails the test unless err carries the gRPC status code want
+func RequireCode(t testing.TB, err error, want codes.Code) {
+	t.Helper()
+	if got := status.Code(err); got != want {
+		t.Fatalf("kvplugintest: got status %s (%v), want %s", got, err, want)
+	}
+}
@@ -0,0 +1,97 @@
+package kvplugintest_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+
+	"github.com/provide-io/tofusoup/harness/soup-go/kvplugintest"
+	"github.com/provide-io/tofusoup/proto/kv"
+)
+
+func TestStartServerTRoundTrip(t *testing.T) {
+	srv := kvplugintest.StartServerT(t, kvplugintest.Options{})
+	if srv.Reattach == nil || srv.Reattach.Addr == nil {
+		t.Fatal("StartServerT returned no reattach config")
+	}
+	if _, ok := srv.Impl.(*kvplugintest.MemoryServer); !ok {
+		t.Fatalf("default server is %T, want *MemoryServer", srv.Impl)
+	}
+
+	first := kvplugintest.RequireRoundTrip(t, srv.Client)
+	second := kvplugintest.RequireRoundTrip(t, srv.Client)
+	if first == second {
+		t.Errorf("RequireRoundTrip reused key %s", first)
+	}
+
+	value := []byte(`{"binary":"\u0000ÿ"}`)
+	kvplugintest.RequirePut(t, srv.Client, "exact", value)
+	if got := kvplugintest.RequireGet(t, srv.Client, "exact"); string(got) != string(value) {
+		t.Errorf("got %q, want the value unchanged: %q", got, value)
+	}
+}
+
+func TestMemoryServerStatusCodes(t *testing.T) {
+	srv := kvplugintest.StartServerT(t, kvplugintest.Options{})
+	ctx := context.Background()
+
+	_, err := srv.Client.Get(ctx, &proto.GetRequest{Key: "missing"})
+	kvplugintest.RequireCode(t, err, codes.NotFound)
+
+	for _, key := range []string{"", "a/b", `a\b`, "a\x00b", strings.Repeat("k", 256)} {
+		_, err := srv.Client.Put(ctx, &proto.PutRequest{Key: key, Value: []byte("v")})
+		kvplugintest.RequireCode(t, err, codes.InvalidArgument)
+		_, err = srv.Client.Get(ctx, &proto.GetRequest{Key: key})
+		kvplugintest.RequireCode(t, err, codes.InvalidArgument)
+	}
+	kvplugintest.RequirePut(t, srv.Client, strings.Repeat("k", 255), []byte("v"))
+}
+
+func TestStartServerTCustomServer(t *testing.T) {
+	impl := kvplugintest.NewMemoryServer()
+	impl.ReadOnly = true
+	srv := kvplugintest.StartServerT(t, kvplugintest.Options{Server: impl})
+	if srv.Impl != impl {
+		t.Fatalf("got server %p, want the one passed in Options", srv.Impl)
+	}
+
+	_, err := srv.Client.Put(context.Background(), &proto.PutRequest{Key: "k", Value: []byte("v")})
+	kvplugintest.RequireCode(t, err, codes.FailedPrecondition)
+}
+
+func TestMemoryServerListPages(t *testing.T) {
+	srv := kvplugintest.StartServerT(t, kvplugintest.Options{})
+	ctx := context.Background()
+	for i := 0; i < 5; i++ {
+		kvplugintest.RequirePut(t, srv.Client, fmt.Sprintf("page-%d", i), []byte("v"))
+	}
+	kvplugintest.RequirePut(t, srv.Client, "other", []byte("v"))
+
+	var keys []string
+	req := &proto.ListRequest{Prefix: "page-", PageSize: 2}
+	for pages := 1; ; pages++ {
+		resp, err := srv.Client.List(ctx, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, resp.Keys...)
+		if resp.NextPageToken == "" {
+			if pages != 3 {
+				t.Errorf("got %d pages, want 3", pages)
+			}
+			break
+		}
+		req.PageToken = resp.NextPageToken
+	}
+	if got, want := strings.Join(keys, ","), "page-0,page-1,page-2,page-3,page-4"; got != want {
+		t.Errorf("got keys %s, want %s", got, want)
+	}
+
+	_, err := srv.Client.List(ctx, &proto.ListRequest{PageToken: "not a token"})
+	kvplugintest.RequireCode(t, err, codes.InvalidArgument)
+	_, err = srv.Client.List(ctx, &proto.ListRequest{PageSize: -1})
+	kvplugintest.RequireCode(t, err, codes.InvalidArgument)
+}
@@ -0,0 +1,130 @@
+package kvplugintest
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/provide-io/tofusoup/proto/kv"
+)
+
+// maxKeyLength matches the soup-go server's key limit
+const maxKeyLength = 255
+
+// List page sizes, matching the soup-go server
+const (
+	defaultPageSize = 100
+	maxPageSize     = 1000
+)
+
+// MemoryServer is an in-memory KV server with the same status codes as the
+// soup-go harness server: InvalidArgument for empty, over-long or
+// path-like keys, NotFound for missing keys and FailedPrecondition for
+// writes when read-only. Values come back exactly as written; unlike the
+// harness it doesn't add server_handshake to JSON values.
+type MemoryServer struct {
+	proto.UnimplementedKVServer
+
+	// ReadOnly rejects Puts with FailedPrecondition
+	ReadOnly bool
+
+	mu     sync.RWMutex
+	values map[string][]byte
+}
+
+// NewMemoryServer returns an empty MemoryServer
+func NewMemoryServer() *MemoryServer {
+	return &MemoryServer{values: map[string][]byte{}}
+}
+
+// validKey returns an InvalidArgument error for keys the harness rejects
+func validKey(key string) error {
+	switch {
+	case key == "":
+		return status.Error(codes.InvalidArgument, "invalid key: key must not be empty")
+	case len(key) > maxKeyLength:
+		return status.Errorf(codes.InvalidArgument, "invalid key: key must be at most %d bytes, got %d", maxKeyLength, len(key))
+	case strings.ContainsAny(key, "/\\"):
+		return status.Error(codes.InvalidArgument, "invalid key: key must not contain path separators")
+	case strings.ContainsRune(key, 0):
+		return status.Error(codes.InvalidArgument, "invalid key: key must not contain NUL bytes")
+	}
+	return nil
+}
+
+func (m *MemoryServer) Put(ctx context.Context, req *proto.PutRequest) (*proto.Empty, error) {
+	if err := validKey(req.Key); err != nil {
+		return nil, err
+	}
+	if m.ReadOnly {
+		return nil, status.Error(codes.FailedPrecondition, "server is read-only: Put not permitted")
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.values[req.Key] = append([]byte(nil), req.Value...)
+	return &proto.Empty{}, nil
+}
+
+func (m *MemoryServer) Get(ctx context.Context, req *proto.GetRequest) (*proto.GetResponse, error) {
+	if err := validKey(req.Key); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	value, ok := m.values[req.Key]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "key not found: %s", req.Key)
+	}
+	return &proto.GetResponse{Value: append([]byte(nil), value...)}, nil
+}
+
+// List returns keys in ascending order. Page tokens are the offset of the
+// next key, so unlike the harness's they aren't tied to a snapshot.
+func (m *MemoryServer) List(ctx context.Context, req *proto.ListRequest) (*proto.ListResponse, error) {
+	pageSize := int(req.PageSize)
+	switch {
+	case pageSize < 0:
+		return nil, status.Errorf(codes.InvalidArgument, "invalid page_size: must not be negative, got %d", pageSize)
+	case pageSize == 0:
+		pageSize = defaultPageSize
+	case pageSize > maxPageSize:
+		pageSize = maxPageSize
+	}
+	offset := 0
+	if req.PageToken != "" {
+		decoded, err := base64.RawURLEncoding.DecodeString(req.PageToken)
+		if err == nil {
+			offset, err = strconv.Atoi(string(decoded))
+		}
+		if err != nil || offset < 0 {
+			return nil, status.Error(codes.InvalidArgument, "invalid page_token")
+		}
+	}
+
+	m.mu.RLock()
+	keys := make([]string, 0, len(m.values))
+	for key := range m.values {
+		if strings.HasPrefix(key, req.Prefix) {
+			keys = append(keys, key)
+		}
+	}
+	m.mu.RUnlock()
+	sort.Strings(keys)
+
+	resp := &proto.ListResponse{Keys: []string{}}
+	if offset < len(keys) {
+		end := min(offset+pageSize, len(keys))
+		resp.Keys = keys[offset:end]
+		if end < len(keys) {
+			resp.NextPageToken = base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprint(end)))
+		}
+	}
+	return resp, nil
+}
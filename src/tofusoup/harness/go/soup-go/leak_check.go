@@ -0,0 +1,236 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Leak checks
+//
+// With --leak-check, long runs sample memory every --leak-interval, fit a
+// least-squares line through the samples taken after --leak-warmup, and
+// fail if the fitted growth over the run exceeds --leak-threshold-mb. A fit
+// over every sample rides out the sawtooth of GC cycles and allocator
+// caches that comparing the first and last sample would trip on.
+
+// leakMinSamples is the fewest samples a trend is fitted to
+const leakMinSamples = 3
+
+// leakOptions holds the --leak-* flags
+type leakOptions struct {
+	enabled     bool
+	interval    time.Duration
+	warmup      time.Duration
+	thresholdMB float64
+}
+
+func addLeakCheckFlags(cmd *cobra.Command, opts *leakOptions, what string) {
+	cmd.Flags().BoolVar(&opts.enabled, "leak-check", false, "Sample "+what+" during the run and fail if it keeps growing")
+	cmd.Flags().DurationVar(&opts.interval, "leak-interval", time.Second, "Time between --leak-check samples")
+	cmd.Flags().DurationVar(&opts.warmup, "leak-warmup", 2*time.Second, "Samples taken this soon after the start are left out of the trend")
+	cmd.Flags().Float64Var(&opts.thresholdMB, "leak-threshold-mb", 8, "Fail --leak-check if fitted growth over the run exceeds this many MiB")
+}
+
+func (o leakOptions) validate() error {
+	if !o.enabled {
+		return nil
+	}
+	if o.interval <= 0 {
+		return fmt.Errorf("--leak-interval must be positive")
+	}
+	if o.warmup < 0 || o.thresholdMB <= 0 {
+		return fmt.Errorf("--leak-warmup must not be negative and --leak-threshold-mb must be positive")
+	}
+	return nil
+}
+
+// leakSample is one memory reading, from the start of sampling
+type leakSample struct {
+	AtMs  float64 `json:"at_ms"`
+	Bytes int64   `json:"bytes"`
+}
+
+// leakReport is the trend fitted to one sampled process or heap
+type leakReport struct {
+	Source string `json:"source"`
+	// Metric is go_heap_alloc (live heap after a forced GC) or rss
+	Metric         string       `json:"metric"`
+	IntervalMs     float64      `json:"interval_ms"`
+	WarmupMs       float64      `json:"warmup_ms"`
+	ThresholdBytes int64        `json:"threshold_bytes"`
+	Samples        []leakSample `json:"samples"`
+	// FittedSamples are the samples after the warmup the trend is fitted to
+	FittedSamples    int     `json:"fitted_samples"`
+	SlopeBytesPerSec float64 `json:"slope_bytes_per_sec"`
+	// GrowthBytes is the fitted line's rise from the first to the last
+	// fitted sample
+	GrowthBytes float64 `json:"growth_bytes"`
+	Leaking     bool    `json:"leaking"`
+	// Note says why no trend was fitted, or why sampling stopped early
+	Note string `json:"note,omitempty"`
+}
+
+// leakSampler reads memory on an interval until finish
+type leakSampler struct {
+	source string
+	metric string
+	read   func() (int64, error)
+	opts   leakOptions
+	start  time.Time
+
+	mu      sync.Mutex
+	samples []leakSample
+	readErr error
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// startLeakSampler takes a sample now and then one every opts.interval
+func startLeakSampler(source, metric string, read func() (int64, error), opts leakOptions) *leakSampler {
+	s := &leakSampler{
+		source:  source,
+		metric:  metric,
+		read:    read,
+		opts:    opts,
+		start:   time.Now(),
+		samples: []leakSample{},
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+func (s *leakSampler) run() {
+	defer close(s.done)
+	ticker := time.NewTicker(s.opts.interval)
+	defer ticker.Stop()
+	for {
+		if !s.sample() {
+			return
+		}
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sample records one reading, returning false once reading fails
+func (s *leakSampler) sample() bool {
+	bytes, err := s.read()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		s.readErr = err
+		return false
+	}
+	s.samples = append(s.samples, leakSample{AtMs: sinceMs(s.start), Bytes: bytes})
+	return true
+}
+
+// finish stops sampling and fits the trend
+func (s *leakSampler) finish() *leakReport {
+	close(s.stop)
+	<-s.done
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	report := &leakReport{
+		Source:         s.source,
+		Metric:         s.metric,
+		IntervalMs:     float64(s.opts.interval.Microseconds()) / 1000,
+		WarmupMs:       float64(s.opts.warmup.Microseconds()) / 1000,
+		ThresholdBytes: int64(s.opts.thresholdMB * (1 << 20)),
+		Samples:        s.samples,
+	}
+	if s.readErr != nil {
+		report.Note = fmt.Sprintf("sampling stopped: %v", s.readErr)
+	}
+	warmupMs := report.WarmupMs
+	var fitted []leakSample
+	for _, sample := range s.samples {
+		if sample.AtMs >= warmupMs {
+			fitted = append(fitted, sample)
+		}
+	}
+	report.FittedSamples = len(fitted)
+	if len(fitted) < leakMinSamples {
+		note := fmt.Sprintf("%d sample(s) after the warmup, too few to fit a trend", len(fitted))
+		if report.Note != "" {
+			note = report.Note + "; " + note
+		}
+		report.Note = note
+		return report
+	}
+	report.SlopeBytesPerSec = leakSlope(fitted)
+	report.GrowthBytes = report.SlopeBytesPerSec * (fitted[len(fitted)-1].AtMs - fitted[0].AtMs) / 1000
+	report.Leaking = report.GrowthBytes > float64(report.ThresholdBytes)
+	if report.Leaking {
+		logger.Warn("🚰 memory keeps growing", "source", s.source, "growth_bytes", int64(report.GrowthBytes),
+			"slope_bytes_per_sec", int64(report.SlopeBytesPerSec))
+	}
+	return report
+}
+
+// leakSlope is the least-squares slope of bytes over time, in bytes per
+// second
+func leakSlope(samples []leakSample) float64 {
+	var meanX, meanY float64
+	for _, s := range samples {
+		meanX += s.AtMs / 1000
+		meanY += float64(s.Bytes)
+	}
+	n := float64(len(samples))
+	meanX, meanY = meanX/n, meanY/n
+	var cov, variance float64
+	for _, s := range samples {
+		dx := s.AtMs/1000 - meanX
+		cov += dx * (float64(s.Bytes) - meanY)
+		variance += dx * dx
+	}
+	if variance == 0 {
+		return 0
+	}
+	return cov / variance
+}
+
+// goHeapRetained is the live heap after a forced GC, so garbage waiting to
+// be collected doesn't read as growth
+func goHeapRetained() (int64, error) {
+	runtime.GC()
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	return int64(ms.HeapAlloc), nil
+}
+
+// leakSummary is the one-line text form of a leak report
+func leakSummary(r *leakReport) string {
+	line := fmt.Sprintf("%s %s: %d samples, ", r.Source, r.Metric, len(r.Samples))
+	if r.FittedSamples < leakMinSamples {
+		return line + "no trend (" + r.Note + ")"
+	}
+	verdict := "✅ stable"
+	if r.Leaking {
+		verdict = fmt.Sprintf("❌ leaking (over %.3g MiB)", float64(r.ThresholdBytes)/(1<<20))
+	}
+	return line + fmt.Sprintf("%+.2f MiB over the run (%+.1f KiB/s), %s",
+		r.GrowthBytes/(1<<20), r.SlopeBytesPerSec/(1<<10), verdict)
+}
+
+// leakCheckResult fails a command whose leak reports show growth
+func leakCheckResult(reports []*leakReport) error {
+	for _, r := range reports {
+		if r.Leaking {
+			return fmt.Errorf("--leak-check: %s %s grew %.1f MiB over the run, over the %.3g MiB threshold",
+				r.Source, r.Metric, r.GrowthBytes/(1<<20), float64(r.ThresholdBytes)/(1<<20))
+		}
+	}
+	return nil
+}
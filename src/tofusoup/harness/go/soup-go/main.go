@@ -1,9 +1,12 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/go-plugin"
@@ -14,9 +17,10 @@ const version = "0.1.0"
 
 var (
 	// Global flags
-	verbose  bool
-	logLevel string
-	logger   hclog.Logger
+	verbose        bool
+	logLevel       string
+	storageDirFlag string
+	logger         hclog.Logger
 )
 
 // Root command
@@ -26,12 +30,28 @@ var rootCmd = &cobra.Command{
 	Long: `soup-go is a unified Go harness for TofuSoup that provides
 CTY, HCL, Wire, and RPC functionality for cross-language testing.`,
 	Version: version,
-	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 		// Reinitialize logger if log level was changed via flag
 		if cmd.Flags().Changed("log-level") {
 			initLogger()
 		}
 		logger.Debug("executing command", "cmd", cmd.Name(), "args", args)
+		if err := applyFeatures(); err != nil {
+			return err
+		}
+		if err := applyDigestAlg(); err != nil {
+			return err
+		}
+		if err := applyClockSkew(); err != nil {
+			return err
+		}
+		if err := applyCertTTL(); err != nil {
+			return err
+		}
+		if err := applyRedact(); err != nil {
+			return err
+		}
+		return startProfiling(logger)
 	},
 }
 
@@ -45,6 +65,12 @@ var ctyCmd = &cobra.Command{
 // These will be initialized with real implementations
 var ctyValidateCmd *cobra.Command
 var ctyConvertCmd *cobra.Command
+var ctyVerifyNestingCmd *cobra.Command
+var ctyPresenceCmd *cobra.Command
+var ctyLawsCmd *cobra.Command
+var ctyUnicodeCmd *cobra.Command
+var ctyCollectionsCmd *cobra.Command
+var ctyMarksCmd *cobra.Command
 
 // HCL command
 var hclCmd = &cobra.Command{
@@ -57,6 +83,14 @@ var hclCmd = &cobra.Command{
 var hclViewCmd *cobra.Command
 var hclValidateCmd *cobra.Command
 var hclConvertCmd *cobra.Command
+var hclCommentsCmd *cobra.Command
+var hclEvalCmd *cobra.Command
+var hclUnicodeCmd *cobra.Command
+var hclSchemaCmd *cobra.Command
+var hclDecodeCmd *cobra.Command
+var hclDecodeStructCmd *cobra.Command
+var hclMetaCmd *cobra.Command
+var hclDepsCmd *cobra.Command
 
 // Wire command
 var wireCmd = &cobra.Command{
@@ -68,6 +102,10 @@ var wireCmd = &cobra.Command{
 // These will be initialized with real implementations
 var wireEncodeCmd *cobra.Command
 var wireDecodeCmd *cobra.Command
+var wireStateCmd *cobra.Command
+var wirePlanCmd *cobra.Command
+var wireBenchCmd *cobra.Command
+var wireFidelityCmd *cobra.Command
 
 // RPC command
 var rpcCmd = &cobra.Command{
@@ -81,6 +119,26 @@ var kvCmd = &cobra.Command{
 	Short: "Key-Value store operations",
 }
 
+var kvAdminCmd = &cobra.Command{
+	Use:   "admin",
+	Short: "KV store maintenance operations",
+}
+
+var counterCmd = &cobra.Command{
+	Use:   "counter",
+	Short: "Streaming counter operations",
+}
+
+var echoCmd = &cobra.Command{
+	Use:   "echo",
+	Short: "Bidirectional streaming echo operations",
+}
+
+var channelzCmd = &cobra.Command{
+	Use:   "channelz",
+	Short: "gRPC channelz connection state",
+}
+
 var validateCmd = &cobra.Command{
 	Use:   "validate",
 	Short: "Validation operations",
@@ -94,6 +152,26 @@ var (
 	rpcCertFile   string
 	rpcKeyFile    string
 	rpcStandalone bool
+	rpcPprofAddr  string
+	rpcReadOnly   bool
+	rpcRateLimit  float64
+	rpcBurst      int
+	rpcMaxKeys    int
+	rpcMaxBytes   int64
+	rpcEviction   string
+	rpcCounterBuf int
+	rpcEchoDelay  time.Duration
+	rpcPipe       string
+	rpcSocket     string
+	rpcSocketMode string
+	rpcAllowUIDs  []uint
+	rpcLogPeers   bool
+	rpcEntryTTL   time.Duration
+	rpcGCInterval time.Duration
+	rpcMaxValue   int64
+	rpcMaxMemory  int64
+	rpcMockFile   string
+	rpcMock       *kvMock
 )
 
 var serverCmd = &cobra.Command{
@@ -103,10 +181,70 @@ var serverCmd = &cobra.Command{
 which is suitable for spawning by plugin clients. Use --standalone flag to run as
 a standalone gRPC server on a specific port for manual testing.`,
 	Run: func(cmd *cobra.Command, args []string) {
+		delay, err := faultDelay()
+		if err != nil {
+			logger.Error("invalid fault delay", "error", err)
+			os.Exit(1)
+		}
+		rpcFaultDelay = delay
+
+		ttl, err := entryTTL(rpcEntryTTL)
+		if err != nil {
+			logger.Error("invalid entry TTL", "error", err)
+			os.Exit(1)
+		}
+		rpcEntryTTL = ttl
+
+		maxValue, err := maxValueBytes(rpcMaxValue)
+		if err != nil {
+			logger.Error("invalid max value size", "error", err)
+			os.Exit(1)
+		}
+		rpcMaxValue = maxValue
+
+		maxMemory, err := maxValueMemory(rpcMaxMemory)
+		if err != nil {
+			logger.Error("invalid value memory ceiling", "error", err)
+			os.Exit(1)
+		}
+		rpcMaxMemory = maxMemory
+
+		window, err := idempotencyWindow(rpcIdempotencyWindow)
+		if err != nil {
+			logger.Error("invalid idempotency window", "error", err)
+			os.Exit(1)
+		}
+		rpcIdempotencyWindow = window
+
+		if path := mockPath(rpcMockFile); path != "" {
+			mock, err := loadKVMock(path)
+			if err != nil {
+				logger.Error("invalid mock responses", "error", err)
+				os.Exit(1)
+			}
+			rpcMock = mock
+		}
+
+		if err := armCrashHook(); err != nil {
+			logger.Error("invalid crash point", "error", err)
+			os.Exit(1)
+		}
+
+		startDiagnostics(logger)
+
+		if rpcPprofAddr != "" {
+			if err := startPprofServer(logger, rpcPprofAddr); err != nil {
+				logger.Error("failed to start pprof listener", "error", err)
+				os.Exit(1)
+			}
+		}
+
 		if rpcStandalone {
 			// Standalone mode - run as standalone gRPC server
 			logger.Info("Starting RPC server in standalone mode",
 				"port", rpcPort,
+				"pipe", rpcPipe,
+				"socket", rpcSocket,
 				"tls_mode", rpcTLSMode,
 				"tls_key_type", rpcTLSKeyType,
 				"tls_curve", rpcTLSCurve,
@@ -114,8 +252,16 @@ a standalone gRPC server on a specific port for manual testing.`,
 				"key_file", rpcKeyFile,
 				"log_level", logLevel)
 
-			if err := startRPCServer(logger, rpcPort, rpcTLSMode, rpcTLSKeyType, rpcTLSCurve, rpcCertFile, rpcKeyFile); err != nil {
+			socketMode, err := parseSocketMode(rpcSocketMode)
+			if err != nil {
+				logger.Error("invalid socket mode", "error", err)
+				os.Exit(1)
+			}
+			socket := UnixSocketOptions{Path: rpcSocket, Mode: socketMode, AllowedUIDs: rpcAllowUIDs, LogPeers: rpcLogPeers}
+
+			if err := startRPCServer(logger, rpcPort, rpcPipe, socket, rpcTLSMode, rpcTLSKeyType, rpcTLSCurve, rpcCertFile, rpcKeyFile, kvServerOptions(), rpcCounterBuf, rpcEchoDelay); err != nil {
 				logger.Error("RPC server failed", "error", err)
+				stopProfiling(logger)
 				os.Exit(1)
 			}
 		} else {
@@ -134,10 +280,17 @@ a standalone gRPC server on a specific port for manual testing.`,
 				HandshakeConfig: Handshake,
 				Plugins: map[string]plugin.Plugin{
 					"kv_grpc": &KVGRPCPlugin{
-						Impl: NewKVImpl(logger.Named("kv"), storageDir),
+						Impl:    NewKVImpl(logger.Named("kv"), storageDir),
+						Options: kvServerOptions(),
+					},
+					"counter_grpc": &CounterGRPCPlugin{
+						Impl: NewCounterServer(logger.Named("counter"), rpcCounterBuf),
+					},
+					"echo_grpc": &EchoGRPCPlugin{
+						Impl: NewEchoServer(logger.Named("echo"), rpcEchoDelay),
 					},
 				},
-				GRPCServer: plugin.DefaultGRPCServer,
+				GRPCServer: channelzGRPCServer(diagGRPCServer(authGRPCServer(authToken(), logger))),
 			}
 
 		// Configure TLS: only use custom TLSProvider for specific curves
@@ -147,6 +300,11 @@ a standalone gRPC server on a specific port for manual testing.`,
 			logger.Info("Configuring go-plugin TLSProvider for custom curve support", "curve", rpcTLSCurve)
 			provider := createTLSProvider(logger.Named("tls"), rpcTLSCurve)
 			serveConfig.TLSProvider = provider
+		} else if rpcTLSMode == "auto" && rpcClientCertHandoff != "" {
+			// Native AutoMTLS only trusts PLUGIN_CLIENT_CERT, so a handed-off
+			// certificate needs our provider, on AutoMTLS's curve
+			logger.Info("Configuring go-plugin TLSProvider for client certificate handoff", "curve", "secp521r1")
+			serveConfig.TLSProvider = createTLSProvider(logger.Named("tls"), "secp521r1")
 		} else if rpcTLSMode == "auto" {
 			// No TLSProvider = go-plugin uses native AutoMTLS (P-521)
 			logger.Info("Using go-plugin native AutoMTLS (P-521 - no custom TLSProvider)")
@@ -157,9 +315,45 @@ a standalone gRPC server on a specific port for manual testing.`,
 	},
 }
 
+// kvServerOptions collects the server behavior flags into KVServerOptions
+func kvServerOptions() KVServerOptions {
+	return KVServerOptions{
+		ReadOnly:          rpcReadOnly,
+		RateLimit:         rpcRateLimit,
+		Burst:             rpcBurst,
+		MaxKeys:           rpcMaxKeys,
+		MaxBytes:          rpcMaxBytes,
+		EvictionPolicy:    rpcEviction,
+		FaultDelay:        rpcFaultDelay,
+		EntryTTL:          rpcEntryTTL,
+		GCInterval:        rpcGCInterval,
+		MaxValueBytes:     rpcMaxValue,
+		MaxValueMemory:    rpcMaxMemory,
+		Mock:              rpcMock,
+		IdempotencyWindow: rpcIdempotencyWindow,
+	}
+}
+
 var getCmd *cobra.Command
 var putCmd *cobra.Command
+var listCmd *cobra.Command
+var kvStatCmd *cobra.Command
+var kvCpCmd *cobra.Command
+var kvSessionCmd *cobra.Command
+var kvAdminCompactCmd *cobra.Command
+var kvAdminFsckCmd *cobra.Command
+var counterIncrementCmd *cobra.Command
+var counterSubscribeCmd *cobra.Command
+var echoChatCmd *cobra.Command
+var echoInfoCmd *cobra.Command
 var connectionCmd *cobra.Command
+var transportCmd *cobra.Command
+var lintServerCmd *cobra.Command
+var trustCmd *cobra.Command
+var trustExportCmd *cobra.Command
+var trustImportCmd *cobra.Command
+var channelzDumpCmd *cobra.Command
+var proxyCmd *cobra.Command
 
 
 
@@ -194,15 +388,18 @@ var harnessListCmd = &cobra.Command{
 var harnessTestCmd = &cobra.Command{
 	Use:   "test [harness]",
 	Short: "Test a specific harness",
+	Long:  `Run the selftest suite for a harness. Only soup-go (this binary) is supported.`,
 	Args:  cobra.MaximumNArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		harness := "soup-go"
 		if len(args) > 0 {
 			harness = args[0]
 		}
+		if harness != "soup-go" {
+			return fmt.Errorf("unknown harness %q (available: soup-go)", harness)
+		}
 		logger.Info("testing harness", "harness", harness)
-		fmt.Printf("Testing harness: %s\n", harness)
-		fmt.Println("All tests passed")
+		return printSelftestReport(runSelftest(context.Background(), logger.Named("selftest"), nil, nil))
 	},
 }
 
@@ -217,9 +414,14 @@ var configShowCmd = &cobra.Command{
 	Short: "Show current configuration",
 	Run: func(cmd *cobra.Command, args []string) {
 		config := map[string]interface{}{
-			"version":   version,
-			"log_level": logLevel,
-			"verbose":   verbose,
+			"version":           version,
+			"log_level":         logLevel,
+			"verbose":           verbose,
+			"features":          enabledFeatures(),
+			"digest_algorithm":  currentDigest.Name(),
+			"digest_algorithms": digestAlgNames(),
+			"redact":            redactOn,
+			"redact_stdout":     redactStdoutOn,
 		}
 		
 		if outputJSON, _ := cmd.Flags().GetBool("json"); outputJSON {
@@ -229,6 +431,8 @@ var configShowCmd = &cobra.Command{
 			fmt.Printf("  Version: %s\n", version)
 			fmt.Printf("  Log Level: %s\n", logLevel)
 			fmt.Printf("  Verbose: %v\n", verbose)
+			fmt.Printf("  Features: %s\n", strings.Join(enabledFeatures(), ", "))
+			fmt.Printf("  Redact: %v (stdout: %v)\n", redactOn, redactStdoutOn)
 		}
 	},
 }
@@ -236,27 +440,121 @@ var configShowCmd = &cobra.Command{
 var generateCmd = &cobra.Command{
 	Use:   "generate",
 	Short: "Generate test data or configurations",
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("generate command is a placeholder")
-	},
 }
 
+var generateSchemaValuesCmd *cobra.Command
+var generateHCLCmd *cobra.Command
+var generateTimeVectorsCmd *cobra.Command
+var generateHCLOperatorsCmd *cobra.Command
+var harnessVerifyVectorsCmd *cobra.Command
+var harnessScenarioCmd *cobra.Command
+var harnessScenarioRestartCmd *cobra.Command
+var harnessScenarioDeadlineCmd *cobra.Command
+var harnessAssertCmd *cobra.Command
+var harnessReplayCmd *cobra.Command
+var harnessCasesCmd *cobra.Command
+var harnessCorpusCmd *cobra.Command
+var harnessFixturesCmd *cobra.Command
+
+var debugCmd = &cobra.Command{
+	Use:   "debug",
+	Short: "Diagnostics for troubleshooting interop issues",
+}
+
+var debugBundleCmd *cobra.Command
+var selftestCmd *cobra.Command
+var doctorCmd *cobra.Command
+var schemaCmd *cobra.Command
+
 func init() {
 	// Initialize commands with real implementations
 	ctyValidateCmd = initCtyValidateCmd()
 	ctyConvertCmd = initCtyConvertCmd()
+	ctyVerifyNestingCmd = initCtyVerifyNestingCmd()
+	ctyPresenceCmd = initCtyPresenceCmd()
+	ctyLawsCmd = initCtyLawsCmd()
+	ctyUnicodeCmd = initCtyUnicodeCmd()
+	ctyCollectionsCmd = initCtyCollectionsCmd()
+	ctyMarksCmd = initCtyMarksCmd()
 	hclViewCmd = initHclViewCmd()
 	hclValidateCmd = initHclValidateCmd()
 	hclConvertCmd = initHclConvertCmd()
+	hclCommentsCmd = initHclCommentsCmd()
+	hclEvalCmd = initHclEvalCmd()
+	hclUnicodeCmd = initHclUnicodeCmd()
+	hclSchemaCmd = initHclSchemaCmd()
+	hclDecodeCmd = initHclDecodeCmd()
+	hclDecodeStructCmd = initHclDecodeStructCmd()
+	hclMetaCmd = initHclMetaCmd()
+	hclDepsCmd = initHclDepsCmd()
 	wireEncodeCmd = initWireEncodeCmd()
 	wireDecodeCmd = initWireDecodeCmd()
+	wireStateCmd = initWireStateCmd()
+	wirePlanCmd = initWirePlanCmd()
+	wireBenchCmd = initWireBenchCmd()
+	wireFidelityCmd = initWireFidelityCmd()
 	getCmd = initKVGetCmd()
 	putCmd = initKVPutCmd()
+	listCmd = initKVListCmd()
+	kvStatCmd = initKVStatCmd()
+	kvCpCmd = initKVCpCmd()
+	kvSessionCmd = initKVSessionCmd()
+	kvAdminCompactCmd = initKVAdminCompactCmd()
+	kvAdminFsckCmd = initKVAdminFsckCmd()
+	counterIncrementCmd = initCounterIncrementCmd()
+	counterSubscribeCmd = initCounterSubscribeCmd()
+	echoChatCmd = initEchoChatCmd()
+	echoInfoCmd = initEchoInfoCmd()
 	connectionCmd = initValidateConnectionCmd()
+	transportCmd = initValidateTransportCmd()
+	lintServerCmd = initRPCLintServerCmd()
+	channelzDumpCmd = initRPCChannelzDumpCmd()
+	proxyCmd = initRPCProxyCmd()
+	trustCmd = &cobra.Command{
+		Use:   "trust",
+		Short: "Share CA trust bundles between hosts",
+	}
+	trustExportCmd = initRPCTrustExportCmd()
+	trustImportCmd = initRPCTrustImportCmd()
+	generateSchemaValuesCmd = initGenerateSchemaValuesCmd()
+	generateHCLCmd = initGenerateHCLCmd()
+	generateTimeVectorsCmd = initGenerateTimeVectorsCmd()
+	generateHCLOperatorsCmd = initGenerateHCLOperatorsCmd()
+	harnessVerifyVectorsCmd = initHarnessVerifyVectorsCmd()
+	harnessScenarioCmd = &cobra.Command{
+		Use:   "scenario",
+		Short: "Run interop resilience scenarios against a KV server",
+	}
+	harnessScenarioRestartCmd = initHarnessScenarioRestartCmd()
+	harnessScenarioDeadlineCmd = initHarnessScenarioDeadlineCmd()
+	harnessAssertCmd = initHarnessAssertCmd()
+	harnessReplayCmd = initHarnessReplayCmd()
+	harnessCasesCmd = initHarnessCasesCmd()
+	harnessCorpusCmd = initHarnessCorpusCmd()
+	harnessFixturesCmd = initHarnessFixturesCmd()
+	debugBundleCmd = initDebugBundleCmd()
+	selftestCmd = initSelftestCmd()
+	doctorCmd = initDoctorCmd()
+	schemaCmd = initSchemaCmd()
 	
+	// Cobra's error and usage messages are redacted like the logs
+	rootCmd.SetErr(logOutput)
+
 	// Global flags
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose output")
 	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "info", "Set log level (trace, debug, info, warn, error)")
+	rootCmd.PersistentFlags().StringVar(&cpuProfilePath, "cpuprofile", "", "Write a CPU profile to this file")
+	rootCmd.PersistentFlags().StringVar(&memProfilePath, "memprofile", "", "Write a heap profile to this file on exit")
+	rootCmd.PersistentFlags().StringVar(&storageDirFlag, "storage-dir", "", "KV storage directory (overrides KV_STORAGE_DIR and the cache dir default)")
+	rootCmd.PersistentFlags().StringVar(&tracePath, "trace", "", "Write a runtime execution trace to this file")
+	rootCmd.PersistentFlags().BoolVar(&commandStats, "stats", false, "Print wall time, CPU, max RSS and GC stats as a final {\"stats\": ...} JSON line")
+	rootCmd.PersistentFlags().StringVar(&commandStatsFile, "stats-file", "", "Write the --stats line to this file instead of stdout (implies --stats)")
+	rootCmd.PersistentFlags().StringSliceVar(&enabledFeatureNames, "enable-feature", nil, "Enable experimental features (comma-separated; see features list)")
+	rootCmd.PersistentFlags().StringVar(&digestAlgName, "digest-alg", "", "Algorithm for certificate fingerprints and redaction digests: "+strings.Join(digestAlgNames(), ", ")+" (default $SOUP_GO_DIGEST_ALG or sha256)")
+	rootCmd.PersistentFlags().StringVar(&clockSkewFlag, "clock-skew", "", "Offset the clock certificates are generated and verified against by this signed duration, e.g. +2h, -30m or +400d (default $SOUP_GO_CLOCK_SKEW)")
+	rootCmd.PersistentFlags().StringVar(&certTTLFlag, "cert-ttl", "", "How long generated server and client certificates stay valid, e.g. 30s, 2h or 90d (default $SOUP_GO_CERT_TTL or 365d)")
+	rootCmd.PersistentFlags().StringVar(&redactMode, "redact", "", "Mask private keys, bearer tokens, the magic cookie and --redact-value values: on (logs and error output), all (stdout too), off (default $SOUP_GO_REDACT or on)")
+	rootCmd.PersistentFlags().StringArrayVar(&redactValues, "redact-value", nil, "A secret to mask wherever it appears in logs and output; repeatable")
 	
 	// Add JSON output flag to relevant commands
 	harnessListCmd.Flags().Bool("json", false, "Output in JSON format")
@@ -265,11 +563,35 @@ func init() {
 	// RPC server flags
 	serverCmd.Flags().BoolVar(&rpcStandalone, "standalone", false, "Run in standalone mode instead of plugin mode")
 	serverCmd.Flags().IntVar(&rpcPort, "port", 50051, "The server port (only used in standalone mode)")
+	serverCmd.Flags().StringVar(&rpcPipe, "pipe", "", "Listen on a Windows named pipe (npipe://name or \\\\.\\pipe\\name) instead of --port (only used in standalone mode)")
+	serverCmd.Flags().StringVar(&rpcSocket, "socket", "", "Listen on a unix socket at this path instead of --port (only used in standalone mode)")
+	serverCmd.Flags().StringVar(&rpcSocketMode, "socket-mode", defaultSocketMode, "Octal permission bits for the --socket file")
+	serverCmd.Flags().UintSliceVar(&rpcAllowUIDs, "allowed-uids", nil, "Close --socket connections whose peer UID (SO_PEERCRED) isn't listed (linux only; empty allows all)")
+	serverCmd.Flags().BoolVar(&rpcLogPeers, "log-peer-creds", false, "Log the peer UID, GID and PID of every --socket connection")
 	serverCmd.Flags().StringVar(&rpcTLSMode, "tls-mode", "disabled", "TLS mode: disabled, auto, manual (only used in standalone mode)")
 	serverCmd.Flags().StringVar(&rpcTLSKeyType, "tls-key-type", "ec", "Key type for auto TLS: 'ec' or 'rsa' (only used in standalone mode)")
 	serverCmd.Flags().StringVar(&rpcTLSCurve, "tls-curve", "secp384r1", "Elliptic curve for EC key type: 'secp256r1', 'secp384r1', 'secp521r1', or 'auto' (AutoMTLS P-521) - default secp384r1 for Python compatibility")
+	serverCmd.Flags().StringArrayVar(&rpcTLSDNSNames, "tls-dns-name", nil, "Add this DNS name to generated TLS certificates, for clients that --resolve it to this server; repeatable")
 	serverCmd.Flags().StringVar(&rpcCertFile, "cert-file", "", "Path to certificate file (required for manual TLS, only used in standalone mode)")
 	serverCmd.Flags().StringVar(&rpcKeyFile, "key-file", "", "Path to private key file (required for manual TLS, only used in standalone mode)")
+	serverCmd.Flags().BoolVar(&rpcReadOnly, "readonly", false, "Reject writes with FailedPrecondition and structured error details")
+	serverCmd.Flags().Float64Var(&rpcRateLimit, "rate-limit", 0, "Maximum sustained requests per second; excess requests get ResourceExhausted with RetryInfo (0 disables)")
+	serverCmd.Flags().IntVar(&rpcBurst, "burst", 1, "Number of requests allowed in a burst above --rate-limit")
+	serverCmd.Flags().IntVar(&rpcMaxKeys, "max-keys", 0, "Maximum keys stored (0 is unlimited)")
+	serverCmd.Flags().Int64Var(&rpcMaxBytes, "max-bytes", 0, "Maximum value bytes stored (0 is unlimited)")
+	serverCmd.Flags().Int64Var(&rpcMaxValue, "max-value-bytes", 0, "Reject Puts of values larger than this with InvalidArgument (default $KV_MAX_VALUE_BYTES, 0 is unlimited)")
+	serverCmd.Flags().Int64Var(&rpcMaxMemory, "max-value-memory", 0, "Most bytes of a value a call may hold in memory: larger Gets fail with ResourceExhausted and must use GetStream, whose chunks are no larger (default $KV_MAX_VALUE_MEMORY, 0 is unlimited)")
+	serverCmd.Flags().StringVar(&rpcEviction, "eviction-policy", evictLRU, "What a write past --max-keys/--max-bytes does: lru (evict least recently used keys) or reject (fail with ResourceExhausted)")
+	serverCmd.Flags().StringVar(&rpcDiagFile, "diag-file", "", "Append the diagnostic dump taken on SIGUSR1 (or SIGQUIT, before exiting) to this file instead of stderr (default $KV_DIAG_FILE)")
+	serverCmd.Flags().StringVar(&rpcMockFile, "mock", "", "Answer KV calls from scripted responses in this YAML file (values, errors, delays, sequences) (default $KV_MOCK)")
+	serverCmd.Flags().IntVar(&rpcCounterBuf, "counter-buffer", defaultCounterBuffer, "Events queued per counter subscriber before it is disconnected as too slow")
+	serverCmd.Flags().DurationVar(&rpcEchoDelay, "echo-delay", 0, "Default delay before echoing each Chat frame")
+	serverCmd.Flags().DurationVar(&rpcFaultDelay, "fault-delay", 0, "Fault injection: hold every KV call this long before handling it (default $KV_FAULT_DELAY)")
+	serverCmd.Flags().DurationVar(&rpcIdempotencyWindow, "idempotency-window", 0, "How long an applied Put's idempotency token dedupes retries (default $KV_IDEMPOTENCY_WINDOW or 10m)")
+	serverCmd.Flags().DurationVar(&rpcEntryTTL, "entry-ttl", 0, "Expire entries not written for this long; compaction removes them (default $KV_ENTRY_TTL, 0 never expires)")
+	serverCmd.Flags().DurationVar(&rpcGCInterval, "gc-interval", 0, "Compact the store this often in the background (0 disables)")
+	serverCmd.Flags().StringVar(&rpcCrashAfter, "crash-after", "", "Debug: exit with status 99 at a write path point: lock-acquired, flush, or put:N (after the Nth Put) (default $KV_CRASH_AFTER)")
+	serverCmd.Flags().StringVar(&rpcPprofAddr, "pprof-addr", "", "Serve net/http/pprof endpoints and /debug/vars metrics on this address (e.g., 127.0.0.1:6060)")
 	
 	// Build command tree
 	rootCmd.AddCommand(ctyCmd)
@@ -279,48 +601,139 @@ func init() {
 	rootCmd.AddCommand(harnessCmd)
 	rootCmd.AddCommand(configCmd)
 	rootCmd.AddCommand(generateCmd)
+	rootCmd.AddCommand(debugCmd)
+	rootCmd.AddCommand(selftestCmd)
+	rootCmd.AddCommand(doctorCmd)
+	rootCmd.AddCommand(schemaCmd)
+	rootCmd.AddCommand(initFeaturesCmd())
 	
 	// CTY subcommands
 	ctyCmd.AddCommand(ctyValidateCmd)
 	ctyCmd.AddCommand(ctyConvertCmd)
+	ctyCmd.AddCommand(ctyVerifyNestingCmd)
+	ctyCmd.AddCommand(ctyPresenceCmd)
+	ctyCmd.AddCommand(ctyLawsCmd)
+	ctyCmd.AddCommand(ctyUnicodeCmd)
+	ctyCmd.AddCommand(ctyCollectionsCmd)
+	ctyCmd.AddCommand(ctyMarksCmd)
 	
 	// HCL subcommands
 	hclCmd.AddCommand(hclViewCmd)
 	hclCmd.AddCommand(hclValidateCmd)
 	hclCmd.AddCommand(hclConvertCmd)
+	hclCmd.AddCommand(hclCommentsCmd)
+	hclCmd.AddCommand(hclEvalCmd)
+	hclCmd.AddCommand(hclUnicodeCmd)
+	hclCmd.AddCommand(hclSchemaCmd)
+	hclCmd.AddCommand(hclDecodeCmd)
+	hclCmd.AddCommand(hclDecodeStructCmd)
+	hclCmd.AddCommand(hclMetaCmd)
+	hclCmd.AddCommand(hclDepsCmd)
 	
 	// Wire subcommands
 	wireCmd.AddCommand(wireEncodeCmd)
 	wireCmd.AddCommand(wireDecodeCmd)
+	wireCmd.AddCommand(wireStateCmd)
+	wireCmd.AddCommand(wirePlanCmd)
+	wireCmd.AddCommand(wireBenchCmd)
+	wireCmd.AddCommand(wireFidelityCmd)
+	wireCmd.PersistentFlags().IntVar(&wireBufferPoolSize, "buffer-pool-size", defaultWireBufferPoolSize, "Idle wire buffers kept for reuse (0 disables pooling)")
 	
 	// RPC subcommands
+	rpcCmd.PersistentFlags().StringVar(&rpcAuthToken, "auth-token", "", "Bearer token servers require and clients send in 'authorization' metadata (default $KV_AUTH_TOKEN)")
+	rpcCmd.PersistentFlags().BoolVar(&rpcTiming, "timing", false, "Client: record per-call latency and print p50/p95/p99 and a histogram as a final {\"timing\": ...} JSON line")
+	rpcCmd.PersistentFlags().BoolVar(&rpcNegotiation, "negotiation", false, "Client: print the negotiated plugin version, protocol, address, AutoMTLS status and server PID as a final {\"negotiation\": ...} JSON line per plugin client")
+	rpcCmd.PersistentFlags().StringVar(&rpcClientCertHandoff, "client-cert-handoff", "", "File a reattaching client writes its mTLS client certificate to and a TLS server requires connections to present (stands in for PLUGIN_CLIENT_CERT for servers started out of band)")
+	rpcCmd.PersistentFlags().StringVar(&rpcTranscript, "transcript", "", "Client: record each unary call's request, response and status (secrets redacted) to this NDJSON file for harness replay")
+	rpcCmd.PersistentFlags().DurationVar(&rpcChildWatchdog, "child-watchdog", 0, "Client: kill a spawned server that takes longer than this to handshake or to answer its first RPC, printing its stacks and output as a {\"watchdog\": ...} JSON line (0 disables)")
+	rpcCmd.PersistentFlags().BoolVar(&rpcChannelz, "channelz", false, "Client: print the client's channels, subchannels and sockets from channelz as a final {\"channelz\": ...} JSON line")
+	rpcCmd.PersistentFlags().StringArrayVar(&rpcResolve, "resolve", nil, "Client: dial NAME at ADDR instead of resolving it, still verifying TLS certificates against NAME (NAME:ADDR, like curl; repeatable)")
+	rpcCmd.PersistentFlags().Int64Var(&rpcDeadlineMs, "deadline-ms", 0, "Client: give each unary call a deadline this many milliseconds away (0 sets none)")
+	rpcCmd.PersistentFlags().StringVar(&rpcAuthNegative, "auth-negative", "", "Client negative test: send a missing or wrong token and succeed only if the server rejects it with Unauthenticated")
 	rpcCmd.AddCommand(kvCmd)
+	rpcCmd.AddCommand(counterCmd)
+	rpcCmd.AddCommand(echoCmd)
 	rpcCmd.AddCommand(validateCmd)
+	rpcCmd.AddCommand(lintServerCmd)
+	rpcCmd.AddCommand(trustCmd)
+	rpcCmd.AddCommand(channelzCmd)
+	rpcCmd.AddCommand(proxyCmd)
 
 
 	// KV subcommands
 	kvCmd.AddCommand(getCmd)
 	kvCmd.AddCommand(putCmd)
+	kvCmd.AddCommand(listCmd)
+	kvCmd.AddCommand(kvStatCmd)
+	kvCmd.AddCommand(kvCpCmd)
+	kvCmd.AddCommand(kvSessionCmd)
 	kvCmd.AddCommand(serverCmd)
+	kvCmd.AddCommand(kvAdminCmd)
+	kvAdminCmd.AddCommand(kvAdminCompactCmd)
+	kvAdminCmd.AddCommand(kvAdminFsckCmd)
+
+	// Counter subcommands
+	counterCmd.AddCommand(counterIncrementCmd)
+	counterCmd.AddCommand(counterSubscribeCmd)
+
+	// Echo subcommands
+	echoCmd.AddCommand(echoChatCmd)
+	echoCmd.AddCommand(echoInfoCmd)
 
 	// Validate subcommands
 	validateCmd.AddCommand(connectionCmd)
+	validateCmd.AddCommand(transportCmd)
+
+	// Trust subcommands
+	trustCmd.AddCommand(trustExportCmd)
+	trustCmd.AddCommand(trustImportCmd)
+
+	// Channelz subcommands
+	channelzCmd.AddCommand(channelzDumpCmd)
 	
 	// Harness subcommands
 	harnessCmd.AddCommand(harnessListCmd)
 	harnessCmd.AddCommand(harnessTestCmd)
+	harnessCmd.AddCommand(harnessVerifyVectorsCmd)
+	harnessCmd.AddCommand(harnessScenarioCmd)
+	harnessCmd.AddCommand(harnessAssertCmd)
+	harnessCmd.AddCommand(harnessReplayCmd)
+	harnessCmd.AddCommand(harnessCasesCmd)
+	harnessCmd.AddCommand(harnessCorpusCmd)
+	harnessCmd.AddCommand(harnessFixturesCmd)
+	harnessScenarioCmd.AddCommand(harnessScenarioRestartCmd)
+	harnessScenarioCmd.AddCommand(harnessScenarioDeadlineCmd)
 	
 	// Config subcommands
 	configCmd.AddCommand(configShowCmd)
+
+	// Generate subcommands
+	generateCmd.AddCommand(generateSchemaValuesCmd)
+	generateCmd.AddCommand(generateHCLCmd)
+	generateCmd.AddCommand(generateTimeVectorsCmd)
+	generateCmd.AddCommand(generateHCLOperatorsCmd)
+
+	// Debug subcommands
+	debugCmd.AddCommand(debugBundleCmd)
 }
 
 func main() {
 	// Initialize logger early
 	initLogger()
 	
-	if err := rootCmd.Execute(); err != nil {
+	cmd, err := rootCmd.ExecuteC()
+	err = authNegativeResult(err)
+	err = watchdogResult(err)
+	printNegotiation()
+	printTimings()
+	printChannelz()
+	clientTranscript.close()
+	printCommandStats(cmd, err)
+	redactStdout.stop()
+	stopProfiling(logger)
+	if err != nil {
 		logger.Error("command execution failed", "error", err)
-		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(logOutput, err)
 		os.Exit(1)
 	}
 }
@@ -349,6 +762,7 @@ func initLogger() {
 	logger = hclog.New(&hclog.LoggerOptions{
 		Name:       "soup-go",
 		Level:      level,
+		Output:     logOutput,
 		Color:      hclog.AutoColor,
 		TimeFormat: "15:04:05.000",
 	})
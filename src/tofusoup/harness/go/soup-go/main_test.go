@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"os"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"google.golang.org/grpc"
+
+	"github.com/provide-io/tofusoup/proto/kv"
+)
+
+// testMainEnv makes the test binary run as soup-go, so tests can start it
+// as a plugin server
+const testMainEnv = "SOUP_GO_TEST_MAIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(testMainEnv) != "" {
+		main()
+		os.Exit(0)
+	}
+	// Commands log through the global logger, which main sets up
+	logger = hclog.NewNullLogger()
+	os.Exit(m.Run())
+}
+
+// captureStdout runs fn and returns what it wrote to os.Stdout
+func captureStdout(t *testing.T, fn func() error) ([]byte, error) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	done := make(chan []byte)
+	go func() {
+		var buf bytes.Buffer
+		io.Copy(&buf, r)
+		done <- buf.Bytes()
+	}()
+	err = fn()
+	os.Stdout = stdout
+	w.Close()
+	return <-done, err
+}
+
+// serveTestRPC serves the services register adds on a loopback port without
+// TLS, like a standalone 'rpc server-start --tls-mode disabled', and returns
+// the address to pass to --address
+func serveTestRPC(t *testing.T, register func(*grpc.Server), opts ...grpc.ServerOption) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer(opts...)
+	register(server)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+	return listener.Addr().String()
+}
+
+// serveTestKV serves a KV server storing keys in a temporary directory and
+// returns it and its address
+func serveTestKV(t *testing.T, opts KVServerOptions) (*GRPCServer, string) {
+	t.Helper()
+	server := newGRPCServer(NewKVImpl(hclog.NewNullLogger(), t.TempDir()), opts, hclog.NewNullLogger())
+	if err := server.applyStorageLimits(); err != nil {
+		t.Fatal(err)
+	}
+	address := serveTestRPC(t, func(s *grpc.Server) { proto.RegisterKVServer(s, server) })
+	return server, address
+}
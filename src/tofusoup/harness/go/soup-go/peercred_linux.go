@@ -0,0 +1,33 @@
+//go:build linux
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+)
+
+// readPeerCreds reads SO_PEERCRED from a unix socket connection
+func readPeerCreds(conn net.Conn) (*peerCreds, error) {
+	unixConn, ok := conn.(*net.UnixConn)
+	if !ok {
+		return nil, fmt.Errorf("peer credentials need a unix socket, not %T", conn)
+	}
+	raw, err := unixConn.SyscallConn()
+	if err != nil {
+		return nil, err
+	}
+
+	var ucred *syscall.Ucred
+	var sockErr error
+	if err := raw.Control(func(fd uintptr) {
+		ucred, sockErr = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	}); err != nil {
+		return nil, err
+	}
+	if sockErr != nil {
+		return nil, fmt.Errorf("SO_PEERCRED: %w", sockErr)
+	}
+	return &peerCreds{UID: ucred.Uid, GID: ucred.Gid, PID: ucred.Pid}, nil
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package main
+
+import "net"
+
+func readPeerCreds(conn net.Conn) (*peerCreds, error) {
+	return nil, errPeerCredsUnsupported
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"expvar"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"runtime"
+	runtimepprof "runtime/pprof"
+	"runtime/trace"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+// Profiling flags (global)
+var (
+	cpuProfilePath string
+	memProfilePath string
+	tracePath      string
+)
+
+// Open profiling outputs, closed by stopProfiling
+var (
+	cpuProfileFile *os.File
+	traceFile      *os.File
+)
+
+// startProfiling starts CPU profiling and execution tracing if requested.
+// It must be paired with a call to stopProfiling before the process exits.
+func startProfiling(logger hclog.Logger) error {
+	if cpuProfilePath != "" {
+		f, err := os.Create(cpuProfilePath)
+		if err != nil {
+			return fmt.Errorf("failed to create CPU profile: %w", err)
+		}
+		if err := runtimepprof.StartCPUProfile(f); err != nil {
+			f.Close()
+			return fmt.Errorf("failed to start CPU profile: %w", err)
+		}
+		cpuProfileFile = f
+		logger.Debug("CPU profiling enabled", "path", cpuProfilePath)
+	}
+
+	if tracePath != "" {
+		f, err := os.Create(tracePath)
+		if err != nil {
+			return fmt.Errorf("failed to create trace file: %w", err)
+		}
+		if err := trace.Start(f); err != nil {
+			f.Close()
+			return fmt.Errorf("failed to start trace: %w", err)
+		}
+		traceFile = f
+		logger.Debug("Execution tracing enabled", "path", tracePath)
+	}
+
+	return nil
+}
+
+// stopProfiling flushes any active CPU profile and trace, and writes the heap
+// profile if one was requested. It is safe to call when profiling is disabled.
+func stopProfiling(logger hclog.Logger) {
+	if cpuProfileFile != nil {
+		runtimepprof.StopCPUProfile()
+		cpuProfileFile.Close()
+		cpuProfileFile = nil
+		logger.Debug("CPU profile written", "path", cpuProfilePath)
+	}
+
+	if traceFile != nil {
+		trace.Stop()
+		traceFile.Close()
+		traceFile = nil
+		logger.Debug("Execution trace written", "path", tracePath)
+	}
+
+	if memProfilePath != "" {
+		f, err := os.Create(memProfilePath)
+		if err != nil {
+			logger.Error("failed to create memory profile", "error", err)
+			return
+		}
+		defer f.Close()
+
+		// Get up-to-date statistics before writing the heap profile
+		runtime.GC()
+		if err := runtimepprof.WriteHeapProfile(f); err != nil {
+			logger.Error("failed to write memory profile", "error", err)
+			return
+		}
+		logger.Debug("Memory profile written", "path", memProfilePath)
+	}
+}
+
+// startPprofServer serves the net/http/pprof endpoints and expvar metrics on
+// addr in the background. It has a mux of its own so nothing else leaks
+// onto the debug listener.
+func startPprofServer(logger hclog.Logger, addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen for pprof on %s: %w", addr, err)
+	}
+
+	logger.Info("🔬 pprof listener started", "address", listener.Addr().String())
+
+	go func() {
+		if err := http.Serve(listener, newPprofMux()); err != nil {
+			logger.Error("pprof listener failed", "error", err)
+		}
+	}()
+
+	return nil
+}
+
+// newPprofMux routes the pprof endpoints and /debug/vars, and nothing else
+func newPprofMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+	return mux
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+// gzipMagic starts every pprof profile
+var gzipMagic = []byte{0x1f, 0x8b}
+
+func TestProfilingWritesOutputs(t *testing.T) {
+	dir := t.TempDir()
+	cpuProfilePath = filepath.Join(dir, "cpu.pprof")
+	memProfilePath = filepath.Join(dir, "mem.pprof")
+	tracePath = filepath.Join(dir, "run.trace")
+	t.Cleanup(func() { cpuProfilePath, memProfilePath, tracePath = "", "", "" })
+
+	logger := hclog.NewNullLogger()
+	if err := startProfiling(logger); err != nil {
+		t.Fatal(err)
+	}
+	var sink []string
+	for i := 0; i < 10000; i++ {
+		sink = append(sink, strings.Repeat("x", i%64))
+	}
+	stopProfiling(logger)
+	if cpuProfileFile != nil || traceFile != nil {
+		t.Error("stopProfiling left a profiling output open")
+	}
+
+	for path, magic := range map[string][]byte{
+		cpuProfilePath: gzipMagic,
+		memProfilePath: gzipMagic,
+		tracePath:      []byte("go 1."),
+	} {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.HasPrefix(data, magic) {
+			t.Errorf("%s starts with %q, want %q", filepath.Base(path), data[:min(len(data), 8)], magic)
+		}
+	}
+}
+
+func TestStartProfilingReportsUnwritablePath(t *testing.T) {
+	cpuProfilePath = filepath.Join(t.TempDir(), "missing", "cpu.pprof")
+	t.Cleanup(func() { cpuProfilePath = "" })
+
+	err := startProfiling(hclog.NewNullLogger())
+	if err == nil || !strings.Contains(err.Error(), "failed to create CPU profile") {
+		t.Fatalf("got %v, want a CPU profile creation error", err)
+	}
+	if cpuProfileFile != nil {
+		t.Error("a failed start left the CPU profile open")
+	}
+}
+
+func TestPprofMux(t *testing.T) {
+	server := httptest.NewServer(newPprofMux())
+	defer server.Close()
+
+	get := func(path string) (int, []byte) {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, body
+	}
+
+	if code, body := get("/debug/pprof/"); code != http.StatusOK || !bytes.Contains(body, []byte("goroutine")) {
+		t.Errorf("/debug/pprof/: got %d, want the profile index", code)
+	}
+	if code, body := get("/debug/pprof/heap?debug=1"); code != http.StatusOK || !bytes.Contains(body, []byte("heap profile")) {
+		t.Errorf("/debug/pprof/heap: got %d, want a text heap profile", code)
+	}
+	code, body := get("/debug/vars")
+	var vars map[string]json.RawMessage
+	if code != http.StatusOK || json.Unmarshal(body, &vars) != nil || vars["memstats"] == nil {
+		t.Errorf("/debug/vars: got %d, want expvar JSON with memstats", code)
+	}
+	if code, _ := get("/"); code != http.StatusNotFound {
+		t.Errorf("/: got %d, want only the debug endpoints to be served", code)
+	}
+}
+
+func TestStartPprofServerReportsListenError(t *testing.T) {
+	err := startPprofServer(hclog.NewNullLogger(), "256.0.0.1:0")
+	if err == nil || !strings.Contains(err.Error(), "failed to listen for pprof") {
+		t.Fatalf("got %v, want a listen error", err)
+	}
+}
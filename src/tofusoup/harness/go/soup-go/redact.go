@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"sync"
+)
+
+// redactEnv supplies --redact when the flag isn't given
+const redactEnv = "SOUP_GO_REDACT"
+
+var (
+	// redactMode holds --redact
+	redactMode string
+	// redactValues holds --redact-value
+	redactValues []string
+	// redactOn is whether logs and error output are redacted. It follows
+	// SOUP_GO_REDACT until applyRedact reads --redact, so early log lines
+	// are covered too.
+	redactOn = os.Getenv(redactEnv) != "off"
+	// redactStdoutOn is whether all of stdout is redacted too, which only
+	// --redact=all does. Stdout carries command data such as stored values,
+	// which must come through byte for byte by default.
+	redactStdoutOn bool
+)
+
+var (
+	// privateKeyPattern matches a PEM private key block of any type. It
+	// spans lines, including hclog's "  | " continuation prefixes and the
+	// \n escapes of a JSON string.
+	privateKeyPattern = regexp.MustCompile(`-----BEGIN [A-Z0-9 ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z0-9 ]*PRIVATE KEY-----`)
+	// bearerPattern matches an authorization header value. It's case
+	// sensitive so messages like "missing bearer token" are left alone.
+	bearerPattern = regexp.MustCompile(`\bBearer\s+[A-Za-z0-9._~+/=-]+`)
+	// cookiePattern matches the magic cookie as an environment assignment
+	// or as the debug bundle's magic_cookie_value field
+	cookiePattern = regexp.MustCompile(`(\b` + regexp.QuoteMeta(Handshake.MagicCookieKey) + `=)[^\s"]+|("magic_cookie_value"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+	// logSecretPattern matches an hclog key=value pair whose key names a
+	// secret. Only logs are redacted by key name; command output is data.
+	logSecretPattern = regexp.MustCompile(`(?i)\b([a-z0-9_.-]*(?:token|secret|password|passwd|authorization|private_key|api_key|cookie_value)[a-z0-9_.-]*=)("(?:[^"\\]|\\.)*"|\S+)`)
+)
+
+// applyRedact reads --redact, falling back to SOUP_GO_REDACT, and starts
+// redacting stdout when it's all
+func applyRedact() error {
+	mode := redactMode
+	if mode == "" {
+		mode = os.Getenv(redactEnv)
+	}
+	switch mode {
+	case "", "on":
+		redactOn, redactStdoutOn = true, false
+		return nil
+	case "all":
+		redactOn, redactStdoutOn = true, true
+	case "off":
+		redactOn, redactStdoutOn = false, false
+		return nil
+	default:
+		return fmt.Errorf("invalid --redact %q (want on, all or off)", mode)
+	}
+	return redactStdout.start()
+}
+
+// redactLiterals returns the secret values known to this process: the auth
+// token, which may have been generated for a spawned server, and every
+// --redact-value
+func redactLiterals() []string {
+	literals := append([]string(nil), redactValues...)
+	if token := authToken(); token != "" {
+		literals = append(literals, token)
+	}
+	return literals
+}
+
+// redactOutput masks private keys, bearer tokens, the magic cookie and
+// known secret values in b
+func redactOutput(b []byte) []byte {
+	b = privateKeyPattern.ReplaceAll(b, []byte("[REDACTED PRIVATE KEY]"))
+	b = bearerPattern.ReplaceAll(b, []byte("Bearer "+redactedValue))
+	b = cookiePattern.ReplaceAllFunc(b, func(m []byte) []byte {
+		sub := cookiePattern.FindSubmatch(m)
+		if len(sub[1]) > 0 {
+			return append(append([]byte(nil), sub[1]...), redactedValue...)
+		}
+		return append(append([]byte(nil), sub[2]...), strconv.Quote(redactedValue)...)
+	})
+	for _, literal := range redactLiterals() {
+		if literal != "" {
+			b = bytes.ReplaceAll(b, []byte(literal), []byte(redactedValue))
+		}
+	}
+	return b
+}
+
+// redactDiagnostic redacts an error or diagnostic written to stdout, unless
+// redaction is off or stdout is redacted whole already
+func redactDiagnostic(b []byte) []byte {
+	if !redactOn || redactStdoutOn {
+		return b
+	}
+	return redactOutput(b)
+}
+
+// redactLog is redactOutput plus masking the values of secret-named log
+// fields. Flags and counts such as has_token=true are left alone.
+func redactLog(b []byte) []byte {
+	b = redactOutput(b)
+	return logSecretPattern.ReplaceAllFunc(b, func(m []byte) []byte {
+		sub := logSecretPattern.FindSubmatch(m)
+		value := string(sub[2])
+		if value == "true" || value == "false" || value == redactedValue {
+			return m
+		}
+		if _, err := strconv.ParseFloat(value, 64); err == nil {
+			return m
+		}
+		return append(append([]byte(nil), sub[1]...), redactedValue...)
+	})
+}
+
+// redactingWriter redacts log entries on their way to w. hclog writes each
+// entry, continuation lines included, in one Write.
+type redactingWriter struct {
+	w *os.File
+}
+
+func (r *redactingWriter) Write(p []byte) (int, error) {
+	if !redactOn {
+		return r.w.Write(p)
+	}
+	if _, err := r.w.Write(redactLog(p)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Fd lets hclog's AutoColor check whether the log file is a terminal
+func (r *redactingWriter) Fd() uintptr {
+	return r.w.Fd()
+}
+
+// logOutput is where the logger and the final error go
+var logOutput = &redactingWriter{w: os.Stderr}
+
+// stdoutRedactor passes stdout through redactOutput a line at a time for
+// --redact=all. A private key block is held until its END line so it's
+// redacted whole.
+type stdoutRedactor struct {
+	stdout *os.File
+	pipe   *os.File
+	done   chan struct{}
+	once   sync.Once
+}
+
+var redactStdout = &stdoutRedactor{}
+
+var privateKeyBegin = regexp.MustCompile(`-----BEGIN [A-Z0-9 ]*PRIVATE KEY-----`)
+
+func (s *stdoutRedactor) start() error {
+	var err error
+	s.once.Do(func() {
+		var r *os.File
+		r, s.pipe, err = os.Pipe()
+		if err != nil {
+			return
+		}
+		s.stdout, os.Stdout = os.Stdout, s.pipe
+		s.done = make(chan struct{})
+		go s.copy(r)
+	})
+	return err
+}
+
+func (s *stdoutRedactor) copy(r *os.File) {
+	defer close(s.done)
+	defer r.Close()
+	in := bufio.NewReader(r)
+	var block []byte
+	for {
+		line, err := in.ReadBytes('\n')
+		if block != nil || privateKeyBegin.Match(line) {
+			block = append(block, line...)
+			if !privateKeyPattern.Match(block) && err == nil {
+				continue
+			}
+			line, block = block, nil
+		}
+		if len(line) > 0 {
+			s.stdout.Write(redactOutput(line))
+		}
+		if err != nil {
+			if err != io.EOF {
+				fmt.Fprintf(os.Stderr, "failed to redact stdout: %v\n", err)
+			}
+			return
+		}
+	}
+}
+
+// stop restores stdout once everything written to it has been redacted
+func (s *stdoutRedactor) stop() {
+	if s.pipe == nil {
+		return
+	}
+	os.Stdout = s.stdout
+	s.pipe.Close()
+	<-s.done
+	s.pipe = nil
+}
@@ -1,7 +1,10 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
+	"os"
 	"strings"
 
 	"github.com/hashicorp/go-plugin"
@@ -12,6 +15,13 @@ import (
 func initKVGetCmd() *cobra.Command {
 	var address string
 	var tlsCurve string
+	var protoV2 bool
+	var wait bool
+	var waitTimeoutMs int64
+	var stream bool
+	var chunkSize int32
+	var output string
+	var hedge hedgeOptions
 
 	cmd := &cobra.Command{
 		Use:   "get [key]",
@@ -19,6 +29,15 @@ func initKVGetCmd() *cobra.Command {
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			key := args[0]
+			if stream && (wait || protoV2) {
+				return fmt.Errorf("--stream can't be combined with --wait or --proto-v2")
+			}
+			if err := hedge.validate(); err != nil {
+				return err
+			}
+			if hedge.enabled() && (stream || wait || protoV2) {
+				return fmt.Errorf("--hedge can't be combined with --stream, --wait or --proto-v2")
+			}
 
 			var client *plugin.Client
 			var err error
@@ -49,8 +68,58 @@ func initKVGetCmd() *cobra.Command {
 			}
 			kv := raw.(KV)
 
-			value, err := kv.Get(key)
+			if protoV2 {
+				conn, err := pluginConn(rpcClient)
+				if err != nil {
+					return err
+				}
+				report, err := getV2(cmd.Context(), conn, key)
+				if err != nil {
+					printStatusJSON(err)
+					return fmt.Errorf("failed to get key %s: %w", key, err)
+				}
+				return printProtoV2Report(report)
+			}
+
+			if stream {
+				out := io.Writer(os.Stdout)
+				if output != "" {
+					file, err := os.Create(output)
+					if err != nil {
+						return err
+					}
+					defer file.Close()
+					out = file
+				}
+				if _, err := getStreaming(cmd.Context(), rpcClient, key, chunkSize, out); err != nil {
+					printStatusJSON(err)
+					return fmt.Errorf("failed to stream key %s: %w", key, err)
+				}
+				return nil
+			}
+
+			if hedge.enabled() {
+				report, value, err := hedgedGet(cmd.Context(), rpcClient, key, hedge)
+				if err != nil {
+					return err
+				}
+				if report.Winner != 0 {
+					fmt.Printf("%s\n", value)
+				}
+				if err := printHedgeReport(report); err != nil {
+					return err
+				}
+				return hedgeError(report)
+			}
+
+			var value []byte
+			if wait {
+				value, err = getWaiting(cmd.Context(), rpcClient, key, waitTimeoutMs)
+			} else {
+				value, err = kv.Get(key)
+			}
 			if err != nil {
+				printStatusJSON(err)
 				return fmt.Errorf("failed to get key %s: %w", key, err)
 			}
 
@@ -61,6 +130,13 @@ func initKVGetCmd() *cobra.Command {
 
 	cmd.Flags().StringVar(&address, "address", "", "Address of existing server (e.g., 127.0.0.1:50051)")
 	cmd.Flags().StringVar(&tlsCurve, "tls-curve", "auto", "Client cert curve: auto (detect from server), secp256r1, secp384r1, secp521r1")
+	cmd.Flags().BoolVar(&protoV2, "proto-v2", false, "Send a GetRequestV2 with extra optional fields and report, as JSON, which fields the server didn't recognize and which v2 response fields came back")
+	cmd.Flags().BoolVar(&wait, "wait", false, "Ask the server to block until the key has a value instead of failing with NotFound")
+	addHedgeFlags(cmd, &hedge)
+	cmd.Flags().Int64Var(&waitTimeoutMs, "wait-timeout-ms", 0, "With --wait, fail with DeadlineExceeded (WAIT_TIMEOUT) after this many milliseconds (0 waits until the call's deadline)")
+	cmd.Flags().BoolVar(&stream, "stream", false, "Read the value with GetStream and write its raw bytes as they arrive, without holding it in memory")
+	cmd.Flags().Int32Var(&chunkSize, "chunk-size", 0, "With --stream, bytes per chunk (0 is the server default of 1 MiB)")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "With --stream, write the value to this file instead of stdout")
 	return cmd
 }
 
@@ -68,6 +144,10 @@ func initKVGetCmd() *cobra.Command {
 func initKVPutCmd() *cobra.Command {
 	var address string
 	var tlsCurve string
+	var verify bool
+	var protoV2 bool
+	var idempotencyToken string
+	var hedge hedgeOptions
 
 	cmd := &cobra.Command{
 		Use:   "put [key] [value]",
@@ -76,6 +156,12 @@ func initKVPutCmd() *cobra.Command {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			key := args[0]
 			value := []byte(args[1])
+			if err := hedge.validate(); err != nil {
+				return err
+			}
+			if hedge.enabled() && protoV2 {
+				return fmt.Errorf("--hedge can't be combined with --proto-v2")
+			}
 
 			var client *plugin.Client
 			var err error
@@ -106,10 +192,76 @@ func initKVPutCmd() *cobra.Command {
 			}
 			kv := raw.(KV)
 
-			if err := kv.Put(key, value); err != nil {
+			if protoV2 {
+				conn, err := pluginConn(rpcClient)
+				if err != nil {
+					return err
+				}
+				report, err := putV2(cmd.Context(), conn, key, value)
+				if err != nil {
+					printStatusJSON(err)
+					return fmt.Errorf("failed to put key %s: %w", key, err)
+				}
+				return printProtoV2Report(report)
+			}
+
+			idempotencyResult := ""
+			if hedge.enabled() {
+				// Copies of one Put share a token, so the server can
+				// dedupe them
+				token := idempotencyToken
+				if token == "" {
+					if token, err = newHedgeToken(); err != nil {
+						return err
+					}
+				}
+				report, err := hedgedPut(cmd.Context(), rpcClient, key, value, token, hedge)
+				if err != nil {
+					return err
+				}
+				if err := printHedgeReport(report); err != nil {
+					return err
+				}
+				if err := hedgeError(report); err != nil {
+					return err
+				}
+			} else if idempotencyToken != "" {
+				idempotencyResult, err = putIdempotent(cmd.Context(), rpcClient, key, value, idempotencyToken)
+			} else {
+				err = kv.Put(key, value)
+			}
+			if err != nil {
+				printStatusJSON(err)
 				return fmt.Errorf("failed to put key %s: %w", key, err)
 			}
 
+			// The idempotency line comes first, so it's there for callers
+			// whether or not --verify adds a line after it
+			if idempotencyToken != "" && !hedge.enabled() {
+				if err := printIdempotencyResult(key, idempotencyToken, idempotencyResult); err != nil {
+					return err
+				}
+			}
+
+			if verify {
+				read, err := kv.Get(key)
+				if err != nil {
+					printStatusJSON(err)
+					return fmt.Errorf("failed to read back key %s: %w", key, err)
+				}
+				result := verifyRoundTrip(key, value, read)
+				if err := json.NewEncoder(os.Stdout).Encode(result); err != nil {
+					return fmt.Errorf("failed to encode verification result: %w", err)
+				}
+				if !result.Verified {
+					return fmt.Errorf("round-trip verification failed for key %s", key)
+				}
+				return nil
+			}
+
+			if hedge.enabled() || idempotencyToken != "" {
+				return nil
+			}
 			fmt.Printf("Key %s put successfully.\n", key)
 			return nil
 		},
@@ -117,6 +269,10 @@ func initKVPutCmd() *cobra.Command {
 
 	cmd.Flags().StringVar(&address, "address", "", "Address of existing server (e.g., 127.0.0.1:50051)")
 	cmd.Flags().StringVar(&tlsCurve, "tls-curve", "auto", "Client cert curve: auto (detect from server), secp256r1, secp384r1, secp521r1")
+	cmd.Flags().BoolVar(&verify, "verify", false, "Read the key back and compare SHA-256 digests, printing them as JSON")
+	cmd.Flags().BoolVar(&protoV2, "proto-v2", false, "Send a PutRequestV2 with extra optional fields and report, as JSON, which fields the server didn't recognize and whether it preserved them")
+	cmd.Flags().StringVar(&idempotencyToken, "idempotency-token", "", "Send this idempotency token and print whether the server applied or deduped the Put as a {\"idempotency\": ...} JSON line")
+	addHedgeFlags(cmd, &hedge)
 	return cmd
 }
 
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/go-hclog"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// authTokenEnv supplies the bearer token when --auth-token isn't given. It is
+// also how a client hands the token to a server it spawns.
+const authTokenEnv = "KV_AUTH_TOKEN"
+
+// authMetadataKey is the metadata key carrying "Bearer <token>"
+const authMetadataKey = "authorization"
+
+// Negative test modes for --auth-negative
+const (
+	// authNegativeMissing sends no token
+	authNegativeMissing = "missing"
+	// authNegativeWrong sends a token the server doesn't expect
+	authNegativeWrong = "wrong"
+)
+
+var (
+	rpcAuthToken    string
+	rpcAuthNegative string
+)
+
+// authToken returns the token from --auth-token or KV_AUTH_TOKEN
+func authToken() string {
+	if rpcAuthToken != "" {
+		return rpcAuthToken
+	}
+	return os.Getenv(authTokenEnv)
+}
+
+// authExempt reports whether method is go-plugin or health plumbing, which
+// clients call before they can attach a token and which carries no data
+func authExempt(method string) bool {
+	return strings.HasPrefix(method, "/plugin.") || strings.HasPrefix(method, "/grpc.health.v1.")
+}
+
+// errUnauthenticated builds the Unauthenticated status for a call with a
+// missing or incorrect token
+func errUnauthenticated(method, reason, msg string) error {
+	return withDetails(status.New(codes.Unauthenticated, msg),
+		&errdetails.ErrorInfo{
+			Reason:   reason,
+			Domain:   errorDomain,
+			Metadata: map[string]string{"method": method},
+		},
+	)
+}
+
+// bearerAuth checks the bearer token on incoming calls
+type bearerAuth struct {
+	token  []byte
+	logger hclog.Logger
+}
+
+// check returns an Unauthenticated error unless ctx carries the token
+func (a *bearerAuth) check(ctx context.Context, method string) error {
+	if authExempt(method) {
+		return nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get(authMetadataKey)
+	if len(values) == 0 {
+		kvMetrics.Add("auth_failures_total", 1)
+		a.logger.Warn("🔐🚫 rejecting call without a token", "method", method)
+		return errUnauthenticated(method, "MISSING_TOKEN", "missing bearer token")
+	}
+	got, ok := strings.CutPrefix(values[0], "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(got), a.token) != 1 {
+		kvMetrics.Add("auth_failures_total", 1)
+		a.logger.Warn("🔐🚫 rejecting call with an invalid token", "method", method)
+		return errUnauthenticated(method, "INVALID_TOKEN", "invalid bearer token")
+	}
+	return nil
+}
+
+// authServerOptions returns interceptors requiring token on every KV,
+// counter and echo call, or nil if token is empty
+func authServerOptions(token string, logger hclog.Logger) []grpc.ServerOption {
+	if token == "" {
+		return nil
+	}
+	logger.Info("🔐 requiring bearer token on RPC calls")
+	auth := &bearerAuth{token: []byte(token), logger: logger}
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			if err := auth.check(ctx, info.FullMethod); err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.ChainStreamInterceptor(func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if err := auth.check(ss.Context(), info.FullMethod); err != nil {
+				return err
+			}
+			return handler(srv, ss)
+		}),
+	}
+}
+
+// authGRPCServer wraps go-plugin's server factory to add the token
+// interceptors in plugin mode
+func authGRPCServer(token string, logger hclog.Logger) func([]grpc.ServerOption) *grpc.Server {
+	return func(opts []grpc.ServerOption) *grpc.Server {
+		return grpc.NewServer(append(opts, authServerOptions(token, logger)...)...)
+	}
+}
+
+// bearerCredentials attaches "authorization: Bearer <token>" to every call
+type bearerCredentials string
+
+func (c bearerCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{authMetadataKey: "Bearer " + string(c)}, nil
+}
+
+// RequireTransportSecurity is false so tokens can be tested without TLS
+func (c bearerCredentials) RequireTransportSecurity() bool {
+	return false
+}
+
+// clientAuthToken returns the token a client should send, honoring
+// --auth-negative, and whether to send one at all
+func clientAuthToken() (string, bool, error) {
+	switch rpcAuthNegative {
+	case "":
+		token := authToken()
+		return token, token != "", nil
+	case authNegativeMissing:
+		return "", false, nil
+	case authNegativeWrong:
+		return "wrong-" + authToken(), true, nil
+	}
+	return "", false, fmt.Errorf("unknown --auth-negative mode %q (expected %s or %s)", rpcAuthNegative, authNegativeMissing, authNegativeWrong)
+}
+
+// authDialOptions returns the dial options for the client's token
+func authDialOptions(logger hclog.Logger) ([]grpc.DialOption, error) {
+	token, send, err := clientAuthToken()
+	if err != nil || !send {
+		return nil, err
+	}
+	logger.Debug("🔐 sending bearer token", "negative", rpcAuthNegative)
+	return []grpc.DialOption{grpc.WithPerRPCCredentials(bearerCredentials(token))}, nil
+}
+
+// spawnedServerAuthToken returns the token a spawned server should require.
+// Negative runs need the server to require one, so if none was given a
+// random token is generated.
+func spawnedServerAuthToken() (string, error) {
+	if token := authToken(); token != "" || rpcAuthNegative == "" {
+		return token, nil
+	}
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate auth token: %w", err)
+	}
+	rpcAuthToken = hex.EncodeToString(buf)
+	return rpcAuthToken, nil
+}
+
+// authNegativeResult interprets a command's result under --auth-negative: a
+// call the server rejected with Unauthenticated is the expected outcome, and
+// anything else is a failure
+func authNegativeResult(err error) error {
+	if rpcAuthNegative == "" {
+		return err
+	}
+	if err == nil {
+		return fmt.Errorf("server accepted a call with a %s token", rpcAuthNegative)
+	}
+	if status.Code(err) == codes.Unauthenticated {
+		fmt.Printf("✅ server rejected a call with a %s token: %v\n", rpcAuthNegative, err)
+		return nil
+	}
+	return fmt.Errorf("expected Unauthenticated with a %s token, got: %w", rpcAuthNegative, err)
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"google.golang.org/grpc"
+
+	"github.com/provide-io/tofusoup/proto/counter"
+	"github.com/provide-io/tofusoup/proto/kv"
+)
+
+// serveTestAuthKV serves KV and counter requiring token
+func serveTestAuthKV(t *testing.T, token string) (*GRPCServer, string) {
+	t.Helper()
+	server := newGRPCServer(NewKVImpl(hclog.NewNullLogger(), t.TempDir()), KVServerOptions{}, hclog.NewNullLogger())
+	impl := NewCounterServer(hclog.NewNullLogger(), 0)
+	t.Cleanup(impl.Stop)
+	address := serveTestRPC(t, func(s *grpc.Server) {
+		proto.RegisterKVServer(s, server)
+		counter.RegisterCounterServer(s, impl)
+	}, authServerOptions(token, hclog.NewNullLogger())...)
+	return server, address
+}
+
+// runRPCAuth runs an rpc command through the root command, as main does,
+// including its --auth-negative handling
+func runRPCAuth(t *testing.T, args ...string) ([]byte, error) {
+	t.Helper()
+	// Persistent flags keep their values between executions
+	rpcAuthToken, rpcAuthNegative = "", ""
+	t.Cleanup(func() { rpcAuthToken, rpcAuthNegative = "", "" })
+	rootCmd.SetArgs(append([]string{"rpc"}, args...))
+	rootCmd.SilenceUsage, rootCmd.SilenceErrors = true, true
+	t.Cleanup(func() { rootCmd.SetArgs(nil) })
+	return captureStdout(t, func() error { return authNegativeResult(rootCmd.Execute()) })
+}
+
+// printedErrorReason returns the ErrorInfo reason of a printed status
+func printedErrorReason(t *testing.T, out []byte) (string, string) {
+	t.Helper()
+	var printed struct {
+		Error struct {
+			Code    string `json:"code"`
+			Details []struct {
+				Reason string `json:"reason"`
+			} `json:"details"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(out, &printed); err != nil {
+		t.Fatalf("%v: %s", err, out)
+	}
+	if len(printed.Error.Details) == 0 {
+		return printed.Error.Code, ""
+	}
+	return printed.Error.Code, printed.Error.Details[0].Reason
+}
+
+func TestAuthTokenRequired(t *testing.T) {
+	server, address := serveTestAuthKV(t, "s3cret")
+
+	if out, err := runRPCAuth(t, "kv", "put", "k", "v", "--address", address, "--auth-token", "s3cret"); err != nil {
+		t.Fatalf("%v: %s", err, out)
+	}
+	if value, err := server.Impl.Get("k"); err != nil || string(value) != "v" {
+		t.Errorf("got %q (%v), want the authenticated Put stored", value, err)
+	}
+
+	out, err := runRPCAuth(t, "kv", "put", "k", "v2", "--address", address)
+	if err == nil {
+		t.Fatal("a Put without a token succeeded")
+	}
+	if code, reason := printedErrorReason(t, out); code != "Unauthenticated" || reason != "MISSING_TOKEN" {
+		t.Errorf("got %s %s, want Unauthenticated MISSING_TOKEN", code, reason)
+	}
+
+	t.Setenv(authTokenEnv, "not-it")
+	out, err = runRPCAuth(t, "kv", "put", "k", "v3", "--address", address)
+	if err == nil {
+		t.Fatal("a Put with the wrong token succeeded")
+	}
+	if code, reason := printedErrorReason(t, out); code != "Unauthenticated" || reason != "INVALID_TOKEN" {
+		t.Errorf("got %s %s, want Unauthenticated INVALID_TOKEN", code, reason)
+	}
+	if value, _ := server.Impl.Get("k"); string(value) != "v" {
+		t.Errorf("got %q, want rejected Puts not stored", value)
+	}
+}
+
+func TestAuthTokenRequiredOnStreams(t *testing.T) {
+	_, address := serveTestAuthKV(t, "s3cret")
+	out, err := runRPCAuth(t, "counter", "subscribe", "--address", address, "--count", "1")
+	if err == nil {
+		t.Fatal("a Subscribe without a token succeeded")
+	}
+	if code, _ := printedErrorReason(t, out); code != "Unauthenticated" {
+		t.Errorf("got %s, want Unauthenticated", code)
+	}
+}
+
+func TestAuthNegative(t *testing.T) {
+	_, address := serveTestAuthKV(t, "s3cret")
+	for _, mode := range []string{authNegativeMissing, authNegativeWrong} {
+		out, err := runRPCAuth(t, "kv", "put", "k", "v", "--address", address, "--auth-token", "s3cret", "--auth-negative", mode)
+		if err != nil {
+			t.Errorf("%s: got %v, want the rejection to count as success", mode, err)
+		}
+		if !strings.Contains(string(out), "✅ server rejected a call with a "+mode+" token") {
+			t.Errorf("%s: got %s", mode, out)
+		}
+	}
+
+	// A server that doesn't check tokens fails a negative run
+	_, open := serveTestKV(t, KVServerOptions{})
+	_, err := runRPCAuth(t, "kv", "put", "k", "v", "--address", open, "--auth-negative", authNegativeWrong)
+	if err == nil || err.Error() != "server accepted a call with a wrong token" {
+		t.Errorf("got %v, want a negative run against an open server to fail", err)
+	}
+}
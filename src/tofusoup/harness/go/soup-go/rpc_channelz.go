@@ -0,0 +1,449 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-plugin"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+	channelzpb "google.golang.org/grpc/channelz/grpc_channelz_v1"
+	channelzsvc "google.golang.org/grpc/channelz/service"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// rpcChannelz makes RPC client commands print the client's own channels
+// from channelz as a final {"channelz": ...} line
+var rpcChannelz bool
+
+// channelzReport is a process's channelz state with sockets inlined
+type channelzReport struct {
+	Servers  []channelzServer  `json:"servers"`
+	Channels []channelzChannel `json:"channels"`
+}
+
+type channelzServer struct {
+	ID            int64            `json:"id"`
+	Name          string           `json:"name,omitempty"`
+	Calls         channelzCalls    `json:"calls"`
+	ListenSockets []channelzSocket `json:"listen_sockets"`
+	// Sockets are the server's open connections
+	Sockets []channelzSocket `json:"sockets"`
+}
+
+// channelzChannel is a top-level channel or, in Subchannels, a subchannel
+type channelzChannel struct {
+	ID          int64             `json:"id"`
+	Name        string            `json:"name,omitempty"`
+	Target      string            `json:"target,omitempty"`
+	State       string            `json:"state"`
+	Calls       channelzCalls     `json:"calls"`
+	Subchannels []channelzChannel `json:"subchannels,omitempty"`
+	Sockets     []channelzSocket  `json:"sockets,omitempty"`
+	Trace       []channelzEvent   `json:"trace,omitempty"`
+}
+
+type channelzCalls struct {
+	Started     int64      `json:"started"`
+	Succeeded   int64      `json:"succeeded"`
+	Failed      int64      `json:"failed"`
+	LastStarted *time.Time `json:"last_started,omitempty"`
+}
+
+type channelzSocket struct {
+	ID               int64  `json:"id"`
+	Name             string `json:"name,omitempty"`
+	Local            string `json:"local,omitempty"`
+	Remote           string `json:"remote,omitempty"`
+	Security         string `json:"security,omitempty"`
+	StreamsStarted   int64  `json:"streams_started"`
+	StreamsSucceeded int64  `json:"streams_succeeded"`
+	StreamsFailed    int64  `json:"streams_failed"`
+	MessagesSent     int64  `json:"messages_sent"`
+	MessagesReceived int64  `json:"messages_received"`
+	KeepAlivesSent   int64  `json:"keep_alives_sent"`
+}
+
+// channelzEvent is a channel trace entry: connectivity changes, subchannels
+// created and deleted, address updates
+type channelzEvent struct {
+	Time        time.Time `json:"time"`
+	Severity    string    `json:"severity"`
+	Description string    `json:"description"`
+}
+
+// channelzSource answers channelz queries, from a remote server or from
+// this process
+type channelzSource interface {
+	GetTopChannels(context.Context, *channelzpb.GetTopChannelsRequest) (*channelzpb.GetTopChannelsResponse, error)
+	GetServers(context.Context, *channelzpb.GetServersRequest) (*channelzpb.GetServersResponse, error)
+	GetServerSockets(context.Context, *channelzpb.GetServerSocketsRequest) (*channelzpb.GetServerSocketsResponse, error)
+	GetSubchannel(context.Context, *channelzpb.GetSubchannelRequest) (*channelzpb.GetSubchannelResponse, error)
+	GetSocket(context.Context, *channelzpb.GetSocketRequest) (*channelzpb.GetSocketResponse, error)
+}
+
+// remoteChannelz queries a server's channelz service
+type remoteChannelz struct {
+	client channelzpb.ChannelzClient
+}
+
+func (r remoteChannelz) GetTopChannels(ctx context.Context, req *channelzpb.GetTopChannelsRequest) (*channelzpb.GetTopChannelsResponse, error) {
+	return r.client.GetTopChannels(ctx, req)
+}
+
+func (r remoteChannelz) GetServers(ctx context.Context, req *channelzpb.GetServersRequest) (*channelzpb.GetServersResponse, error) {
+	return r.client.GetServers(ctx, req)
+}
+
+func (r remoteChannelz) GetServerSockets(ctx context.Context, req *channelzpb.GetServerSocketsRequest) (*channelzpb.GetServerSocketsResponse, error) {
+	return r.client.GetServerSockets(ctx, req)
+}
+
+func (r remoteChannelz) GetSubchannel(ctx context.Context, req *channelzpb.GetSubchannelRequest) (*channelzpb.GetSubchannelResponse, error) {
+	return r.client.GetSubchannel(ctx, req)
+}
+
+func (r remoteChannelz) GetSocket(ctx context.Context, req *channelzpb.GetSocketRequest) (*channelzpb.GetSocketResponse, error) {
+	return r.client.GetSocket(ctx, req)
+}
+
+// localChannelzRegistrar captures the channelz service implementation
+// instead of serving it, so a process can query its own state without
+// opening a channel that would show up in it
+type localChannelzRegistrar struct {
+	impl channelzpb.ChannelzServer
+}
+
+func (r *localChannelzRegistrar) RegisterService(_ *grpc.ServiceDesc, impl interface{}) {
+	r.impl = impl.(channelzpb.ChannelzServer)
+}
+
+// localChannelz returns this process's channelz service. Importing the
+// service turned channelz on, so every soup-go process tracks its channels,
+// subchannels, servers and sockets.
+func localChannelz() channelzSource {
+	registrar := &localChannelzRegistrar{}
+	channelzsvc.RegisterChannelzServiceToServer(registrar)
+	return registrar.impl
+}
+
+// channelzGRPCServer registers the channelz service on plugin-mode servers
+func channelzGRPCServer(next func([]grpc.ServerOption) *grpc.Server) func([]grpc.ServerOption) *grpc.Server {
+	return func(opts []grpc.ServerOption) *grpc.Server {
+		server := next(opts)
+		channelzsvc.RegisterChannelzServiceToServer(server)
+		return server
+	}
+}
+
+// collectChannelz walks source's servers and top-level channels, paging
+// through each list, and fetches the sockets they reference
+func collectChannelz(ctx context.Context, source channelzSource) (*channelzReport, error) {
+	report := &channelzReport{Servers: []channelzServer{}, Channels: []channelzChannel{}}
+
+	for start := int64(0); ; {
+		resp, err := source.GetServers(ctx, &channelzpb.GetServersRequest{StartServerId: start})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list channelz servers: %w", err)
+		}
+		for _, server := range resp.Server {
+			entry, err := collectChannelzServer(ctx, source, server)
+			if err != nil {
+				return nil, err
+			}
+			report.Servers = append(report.Servers, entry)
+			start = server.GetRef().GetServerId() + 1
+		}
+		if resp.End || len(resp.Server) == 0 {
+			break
+		}
+	}
+
+	for start := int64(0); ; {
+		resp, err := source.GetTopChannels(ctx, &channelzpb.GetTopChannelsRequest{StartChannelId: start})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list channelz channels: %w", err)
+		}
+		for _, channel := range resp.Channel {
+			entry, err := collectChannelzChannel(ctx, source, channel.GetRef().GetChannelId(), channel.GetRef().GetName(),
+				channel.Data, channel.SubchannelRef, channel.SocketRef)
+			if err != nil {
+				return nil, err
+			}
+			report.Channels = append(report.Channels, entry)
+			start = channel.GetRef().GetChannelId() + 1
+		}
+		if resp.End || len(resp.Channel) == 0 {
+			break
+		}
+	}
+	return report, nil
+}
+
+func collectChannelzServer(ctx context.Context, source channelzSource, server *channelzpb.Server) (channelzServer, error) {
+	data := server.GetData()
+	entry := channelzServer{
+		ID:   server.GetRef().GetServerId(),
+		Name: server.GetRef().GetName(),
+		Calls: channelzCalls{
+			Started:     data.GetCallsStarted(),
+			Succeeded:   data.GetCallsSucceeded(),
+			Failed:      data.GetCallsFailed(),
+			LastStarted: channelzTime(data.GetLastCallStartedTimestamp()),
+		},
+		ListenSockets: []channelzSocket{},
+		Sockets:       []channelzSocket{},
+	}
+	listen, err := collectChannelzSockets(ctx, source, server.ListenSocket)
+	if err != nil {
+		return entry, err
+	}
+	entry.ListenSockets = append(entry.ListenSockets, listen...)
+
+	for start := int64(0); ; {
+		resp, err := source.GetServerSockets(ctx, &channelzpb.GetServerSocketsRequest{ServerId: entry.ID, StartSocketId: start})
+		if err != nil {
+			return entry, fmt.Errorf("failed to list sockets of channelz server %d: %w", entry.ID, err)
+		}
+		sockets, err := collectChannelzSockets(ctx, source, resp.SocketRef)
+		if err != nil {
+			return entry, err
+		}
+		entry.Sockets = append(entry.Sockets, sockets...)
+		if resp.End || len(resp.SocketRef) == 0 {
+			break
+		}
+		start = resp.SocketRef[len(resp.SocketRef)-1].GetSocketId() + 1
+	}
+	return entry, nil
+}
+
+func collectChannelzChannel(ctx context.Context, source channelzSource, id int64, name string, data *channelzpb.ChannelData,
+	subchannels []*channelzpb.SubchannelRef, sockets []*channelzpb.SocketRef) (channelzChannel, error) {
+	entry := channelzChannel{
+		ID:     id,
+		Name:   name,
+		Target: data.GetTarget(),
+		State:  data.GetState().GetState().String(),
+		Calls: channelzCalls{
+			Started:     data.GetCallsStarted(),
+			Succeeded:   data.GetCallsSucceeded(),
+			Failed:      data.GetCallsFailed(),
+			LastStarted: channelzTime(data.GetLastCallStartedTimestamp()),
+		},
+	}
+	for _, event := range data.GetTrace().GetEvents() {
+		entry.Trace = append(entry.Trace, channelzEvent{
+			Time:        event.GetTimestamp().AsTime().UTC(),
+			Severity:    strings.TrimPrefix(event.GetSeverity().String(), "CT_"),
+			Description: event.GetDescription(),
+		})
+	}
+	for _, ref := range subchannels {
+		resp, err := source.GetSubchannel(ctx, &channelzpb.GetSubchannelRequest{SubchannelId: ref.GetSubchannelId()})
+		if err != nil {
+			return entry, fmt.Errorf("failed to get channelz subchannel %d: %w", ref.GetSubchannelId(), err)
+		}
+		sub := resp.GetSubchannel()
+		subEntry, err := collectChannelzChannel(ctx, source, ref.GetSubchannelId(), ref.GetName(),
+			sub.GetData(), sub.GetSubchannelRef(), sub.GetSocketRef())
+		if err != nil {
+			return entry, err
+		}
+		entry.Subchannels = append(entry.Subchannels, subEntry)
+	}
+	socketEntries, err := collectChannelzSockets(ctx, source, sockets)
+	if err != nil {
+		return entry, err
+	}
+	entry.Sockets = socketEntries
+	return entry, nil
+}
+
+func collectChannelzSockets(ctx context.Context, source channelzSource, refs []*channelzpb.SocketRef) ([]channelzSocket, error) {
+	var sockets []channelzSocket
+	for _, ref := range refs {
+		resp, err := source.GetSocket(ctx, &channelzpb.GetSocketRequest{SocketId: ref.GetSocketId()})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get channelz socket %d: %w", ref.GetSocketId(), err)
+		}
+		socket := resp.GetSocket()
+		data := socket.GetData()
+		sockets = append(sockets, channelzSocket{
+			ID:               ref.GetSocketId(),
+			Name:             ref.GetName(),
+			Local:            channelzAddress(socket.GetLocal()),
+			Remote:           channelzAddress(socket.GetRemote()),
+			Security:         channelzSecurity(socket.GetSecurity()),
+			StreamsStarted:   data.GetStreamsStarted(),
+			StreamsSucceeded: data.GetStreamsSucceeded(),
+			StreamsFailed:    data.GetStreamsFailed(),
+			MessagesSent:     data.GetMessagesSent(),
+			MessagesReceived: data.GetMessagesReceived(),
+			KeepAlivesSent:   data.GetKeepAlivesSent(),
+		})
+	}
+	return sockets, nil
+}
+
+func channelzTime(ts *timestamppb.Timestamp) *time.Time {
+	if ts == nil {
+		return nil
+	}
+	t := ts.AsTime().UTC()
+	return &t
+}
+
+func channelzAddress(addr *channelzpb.Address) string {
+	switch {
+	case addr.GetTcpipAddress() != nil:
+		tcp := addr.GetTcpipAddress()
+		return net.JoinHostPort(net.IP(tcp.IpAddress).String(), strconv.Itoa(int(tcp.Port)))
+	case addr.GetUdsAddress() != nil:
+		return "unix:" + addr.GetUdsAddress().Filename
+	case addr.GetOtherAddress() != nil:
+		return addr.GetOtherAddress().Name
+	}
+	return ""
+}
+
+func channelzSecurity(security *channelzpb.Security) string {
+	if tls := security.GetTls(); tls != nil {
+		if name := tls.GetStandardName(); name != "" {
+			return "tls " + name
+		}
+		return "tls " + tls.GetOtherName()
+	}
+	if other := security.GetOther(); other != nil {
+		return other.Name
+	}
+	return ""
+}
+
+// clientChannelz keeps the latest snapshot of every channel this process
+// has made calls on. Channels leave channelz when they close, which plugin
+// clients do before --channelz is printed, so the snapshot is taken after
+// each call instead.
+var clientChannelz struct {
+	sync.Mutex
+	channels map[int64]channelzChannel
+}
+
+// recordChannelz snapshots this process's channels
+func recordChannelz() {
+	report, err := collectChannelz(context.Background(), localChannelz())
+	if err != nil {
+		logger.Warn("failed to collect channelz", "error", err)
+		return
+	}
+	clientChannelz.Lock()
+	defer clientChannelz.Unlock()
+	if clientChannelz.channels == nil {
+		clientChannelz.channels = make(map[int64]channelzChannel)
+	}
+	for _, channel := range report.Channels {
+		clientChannelz.channels[channel.ID] = channel
+	}
+}
+
+// channelzDialOptions returns interceptors snapshotting channelz after each
+// unary call and stream open when --channelz is set
+func channelzDialOptions() []grpc.DialOption {
+	if !rpcChannelz {
+		return nil
+	}
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(
+			func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+				err := invoker(ctx, method, req, reply, cc, opts...)
+				recordChannelz()
+				return err
+			},
+		),
+		grpc.WithChainStreamInterceptor(
+			func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+				stream, err := streamer(ctx, desc, cc, method, opts...)
+				recordChannelz()
+				return stream, err
+			},
+		),
+	}
+}
+
+// printChannelz writes the channels this process made calls on, each as of
+// its last call, as a final {"channelz": ...} line when --channelz is set
+func printChannelz() {
+	if !rpcChannelz {
+		return
+	}
+	recordChannelz()
+	clientChannelz.Lock()
+	defer clientChannelz.Unlock()
+	report := channelzReport{Servers: []channelzServer{}, Channels: []channelzChannel{}}
+	for _, channel := range clientChannelz.channels {
+		report.Channels = append(report.Channels, channel)
+	}
+	sort.Slice(report.Channels, func(i, j int) bool { return report.Channels[i].ID < report.Channels[j].ID })
+	if err := json.NewEncoder(os.Stdout).Encode(map[string]interface{}{"channelz": report}); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to encode channelz: %v\n", err)
+	}
+}
+
+func initRPCChannelzDumpCmd() *cobra.Command {
+	var address string
+	var tlsCurve string
+
+	cmd := &cobra.Command{
+		Use:   "dump",
+		Short: "Dump a server's channels, subchannels and sockets as JSON",
+		Long: `Query a soup-go server's channelz service and print its servers, their
+listen sockets and open connections, and any channels it has made, with
+subchannels, sockets, call and stream counts and recent trace events.
+Comparing dumps taken during a soak or rotation test shows connections
+being opened, dropped and reused. The dump's own connection is one of the
+server's sockets.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var client *plugin.Client
+			var err error
+			if address != "" {
+				client, err = newReattachClient(address, tlsCurve, logger)
+			} else {
+				client, err = newRPCClient(logger)
+			}
+			if err != nil {
+				return err
+			}
+			defer client.Kill()
+
+			rpcClient, err := client.Client()
+			if err != nil {
+				return fmt.Errorf("failed to create RPC client: %w", err)
+			}
+			conn, err := pluginConn(rpcClient)
+			if err != nil {
+				return err
+			}
+			report, err := collectChannelz(cmd.Context(), remoteChannelz{channelzpb.NewChannelzClient(conn)})
+			if err != nil {
+				printStatusJSON(err)
+				return err
+			}
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(report)
+		},
+	}
+
+	cmd.Flags().StringVar(&address, "address", "", "Address of existing server (e.g., 127.0.0.1:50051)")
+	cmd.Flags().StringVar(&tlsCurve, "tls-curve", "auto", "Client cert curve: auto (detect from server), secp256r1, secp384r1, secp521r1")
+	return cmd
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"fmt"
@@ -50,6 +51,15 @@ func newRPCClient(logger hclog.Logger) (*plugin.Client, error) {
 		logger.Info("Spawning server without TLS (disabled mode)")
 	}
 
+	serverToken, err := spawnedServerAuthToken()
+	if err != nil {
+		return nil, err
+	}
+	dialOpts, err := clientDialOptions(logger)
+	if err != nil {
+		return nil, err
+	}
+
 	cmd := exec.Command(serverPath, cmdArgs...)
 	cmd.Env = append(os.Environ(),
 		"PLUGIN_AUTO_MTLS=true",                            // Explicitly enable AutoMTLS for Go servers
@@ -58,24 +68,46 @@ func newRPCClient(logger hclog.Logger) (*plugin.Client, error) {
 		"PLUGIN_MAGIC_COOKIE_KEY=BASIC_PLUGIN",
 		"BASIC_PLUGIN=hello",
 	)
+	if serverToken != "" {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", authTokenEnv, serverToken))
+	}
 
 	// Create client
-	client := plugin.NewClient(&plugin.ClientConfig{
+	config := &plugin.ClientConfig{
 		HandshakeConfig:  Handshake,
 		VersionedPlugins: map[int]plugin.PluginSet{
 			1: {
-				"kv_grpc": &KVGRPCPlugin{},
+				"kv_grpc":      &KVGRPCPlugin{},
+				"counter_grpc": &CounterGRPCPlugin{},
+				"echo_grpc":    &EchoGRPCPlugin{},
 			},
 		},
 		Cmd:             cmd,
 		Logger:          logger,
 		AutoMTLS:        true,
 		AllowedProtocols: []plugin.Protocol{plugin.ProtocolGRPC},
-	})
+		GRPCDialOptions:  dialOpts,
+	}
+	watchChild(config, logger)
+	client := plugin.NewClient(config)
+	trackNegotiation(client, config, negotiationSpawned, false)
 
 	return client, nil
 }
 
+// clientDialOptions returns the dial options for the client-side --auth-*,
+// --deadline-ms, --timing, --channelz and --transcript flags
+func clientDialOptions(logger hclog.Logger) ([]grpc.DialOption, error) {
+	opts, err := authDialOptions(logger)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, deadlineDialOptions(logger)...)
+	opts = append(opts, timingDialOptions()...)
+	opts = append(opts, channelzDialOptions()...)
+	return append(opts, transcriptDialOptions(logger)...), nil
+}
+
 // parseHandshakeOrAddress parses either a simple address or a full go-plugin handshake line
 // Returns the ReattachConfig, optional TLS config, optional server certificate, and the hostname for SNI
 func parseHandshakeOrAddress(addressOrHandshake string, logger hclog.Logger) (*plugin.ReattachConfig, *tls.Config, *x509.Certificate, string, error) {
@@ -100,7 +132,14 @@ func parseHandshakeOrAddress(addressOrHandshake string, logger hclog.Logger) (*p
 		var hostname string
 		var err error
 
-		if network == "unix" {
+		if network == "npipe" {
+			// Windows named pipe
+			addr, err = parsePipeAddress(address)
+			if err != nil {
+				return nil, nil, nil, "", err
+			}
+			hostname = "localhost"
+		} else if network == "unix" {
 			// Unix domain socket
 			addr, err = net.ResolveUnixAddr("unix", address)
 			if err != nil {
@@ -109,12 +148,12 @@ func parseHandshakeOrAddress(addressOrHandshake string, logger hclog.Logger) (*p
 			hostname = "localhost" // Unix sockets don't have hostnames, use localhost for SNI
 		} else {
 			// TCP address (default)
-			tcpAddr, tcpErr := net.ResolveTCPAddr("tcp", address)
+			tcpAddr, name, tcpErr := resolveTCPAddr(address, logger)
 			if tcpErr != nil {
 				return nil, nil, nil, "", fmt.Errorf("failed to parse tcp address from handshake: %w", tcpErr)
 			}
 			addr = tcpAddr
-			hostname = tcpAddr.IP.String()
+			hostname = name
 		}
 
 		// Check if certificate is provided (field 6)
@@ -135,14 +174,38 @@ func parseHandshakeOrAddress(addressOrHandshake string, logger hclog.Logger) (*p
 		}, tlsConfig, serverCert, hostname, nil
 	}
 
+	// Named pipe address (no TLS)
+	if isPipeAddress(addressOrHandshake) {
+		addr, err := parsePipeAddress(addressOrHandshake)
+		if err != nil {
+			return nil, nil, nil, "", err
+		}
+		return &plugin.ReattachConfig{
+			Protocol:        plugin.ProtocolGRPC,
+			ProtocolVersion: 1,
+			Addr:            addr,
+		}, nil, nil, "localhost", nil
+	}
+
+	// Unix socket address (no TLS)
+	if isUnixAddress(addressOrHandshake) {
+		addr, err := net.ResolveUnixAddr("unix", strings.TrimPrefix(addressOrHandshake, unixScheme))
+		if err != nil {
+			return nil, nil, nil, "", fmt.Errorf("failed to parse unix address %s: %w", addressOrHandshake, err)
+		}
+		return &plugin.ReattachConfig{
+			Protocol:        plugin.ProtocolGRPC,
+			ProtocolVersion: 1,
+			Addr:            addr,
+		}, nil, nil, "localhost", nil
+	}
+
 	// Simple address format (no TLS)
-	tcpAddr, err := net.ResolveTCPAddr("tcp", addressOrHandshake)
+	tcpAddr, hostname, err := resolveTCPAddr(addressOrHandshake, logger)
 	if err != nil {
 		return nil, nil, nil, "", fmt.Errorf("failed to resolve address %s: %w", addressOrHandshake, err)
 	}
 
-	hostname := tcpAddr.IP.String()
-
 	return &plugin.ReattachConfig{
 		Protocol:        plugin.ProtocolGRPC,
 		ProtocolVersion: 1,
@@ -171,15 +234,31 @@ func newReattachClient(addressOrHandshake string, tlsCurve string, logger hclog.
 		"has_tls", tlsConfig != nil,
 		"has_server_cert", serverCert != nil)
 
+	// Servers using a TLSProvider leave their certificate out of the
+	// handshake line; with a handoff file they publish it beside it instead
+	if tlsConfig == nil && rpcClientCertHandoff != "" {
+		tlsConfig, serverCert, err = handedOffServerCert(rpcClientCertHandoff, hostname, logger)
+		if err != nil {
+			return nil, err
+		}
+		if tlsConfig != nil {
+			logger.Info("✅ Using handed-off server certificate", "path", serverCertHandoffPath(rpcClientCertHandoff))
+		}
+	}
+
 	// Build client config
 	clientConfig := &plugin.ClientConfig{
 		HandshakeConfig: Handshake,
 		Plugins: map[string]plugin.Plugin{
-			"kv_grpc": &KVGRPCPlugin{},
+			"kv_grpc":      &KVGRPCPlugin{},
+			"counter_grpc": &CounterGRPCPlugin{},
+			"echo_grpc":    &EchoGRPCPlugin{},
 		},
 		VersionedPlugins: map[int]plugin.PluginSet{
 			1: {
-				"kv_grpc": &KVGRPCPlugin{},
+				"kv_grpc":      &KVGRPCPlugin{},
+				"counter_grpc": &CounterGRPCPlugin{},
+				"echo_grpc":    &EchoGRPCPlugin{},
 			},
 		},
 		Reattach:         reattachConfig,
@@ -230,6 +309,15 @@ func newReattachClient(addressOrHandshake string, tlsCurve string, logger hclog.
 		tlsConfig.Certificates = []tls.Certificate{clientCert}
 		logger.Info("✅ Client certificate added to TLS config")
 
+		// A server started out of band never saw PLUGIN_CLIENT_CERT, so hand
+		// it the certificate through the agreed file before dialing
+		if rpcClientCertHandoff != "" {
+			if err := writeHandoff(rpcClientCertHandoff, clientCertPEM); err != nil {
+				return nil, err
+			}
+			logger.Info("✅ Client certificate handed off", "path", rpcClientCertHandoff)
+		}
+
 		logger.Info("🔐 Enabling mTLS with custom client certificate",
 			"hostname", hostname,
 			"client_curve", clientCurve,
@@ -247,8 +335,25 @@ func newReattachClient(addressOrHandshake string, tlsCurve string, logger hclog.
 		logger.Info("ℹ️  No TLS config found, using insecure connection")
 	}
 
+	// go-plugin dials reattach addresses with net.Dial, which can't open
+	// named pipes, so supply our own dialer for them
+	if pipe, ok := reattachConfig.Addr.(pipeAddr); ok {
+		logger.Info("🔌 Dialing over Windows named pipe", "pipe", pipe.path)
+		clientConfig.GRPCDialOptions = append(clientConfig.GRPCDialOptions,
+			grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+				return dialPipe(ctx, pipe)
+			}))
+	}
+
+	dialOpts, err := clientDialOptions(logger)
+	if err != nil {
+		return nil, err
+	}
+	clientConfig.GRPCDialOptions = append(clientConfig.GRPCDialOptions, dialOpts...)
+
 	// Create client with reattach config
 	client := plugin.NewClient(clientConfig)
+	trackNegotiation(client, clientConfig, negotiationReattach, tlsConfig != nil)
 
 	logger.Info("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 	logger.Info("✅ Reattach client created successfully!")
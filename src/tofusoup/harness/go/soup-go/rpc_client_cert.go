@@ -0,0 +1,111 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+// rpcClientCertHandoff is a file both sides agree on for the client
+// certificate. go-plugin hands a spawned server the client certificate in
+// PLUGIN_CLIENT_CERT, which a server started out of band never gets; with
+// this set a reattaching client writes its generated certificate here and
+// the server reads it when a connection arrives.
+//
+// The exchange runs the other way too: go-plugin leaves the server
+// certificate out of the handshake line when a TLSProvider is set, so the
+// server writes it next to the handoff file (serverCertHandoffPath) for the
+// client to trust.
+var rpcClientCertHandoff string
+
+// serverCertHandoffPath is where a server publishes its certificate for
+// clients using the handoff file at path
+func serverCertHandoffPath(path string) string {
+	return path + ".server"
+}
+
+// writeHandoff replaces a handoff file with a rename so the other side
+// never reads half a certificate
+func writeHandoff(path string, certPEM []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".handoff-*")
+	if err != nil {
+		return fmt.Errorf("failed to hand off certificate: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(certPEM); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to hand off certificate: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to hand off certificate: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to hand off certificate: %w", err)
+	}
+	return nil
+}
+
+// handedOffServerCert builds a client TLS config trusting the certificate a
+// server published for the handoff file at path. It returns nil when the
+// server hasn't published one, i.e. it isn't serving TLS.
+func handedOffServerCert(path, hostname string, logger hclog.Logger) (*tls.Config, *x509.Certificate, error) {
+	certPEM, err := os.ReadFile(serverCertHandoffPath(path))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read handed-off server certificate: %w", err)
+	}
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return nil, nil, fmt.Errorf("handed-off server certificate at %s is not PEM", serverCertHandoffPath(path))
+	}
+	return parseCertificateFromHandshake(base64.StdEncoding.EncodeToString(block.Bytes), hostname, logger)
+}
+
+// readClientCertHandoff loads the certificate pool a client handed off
+func readClientCertHandoff(path string) (*x509.CertPool, error) {
+	certPEM, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("no client certificate has been handed off at %s", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read handed-off client certificate: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(certPEM) {
+		return nil, fmt.Errorf("handed-off client certificate at %s is not PEM", path)
+	}
+	return pool, nil
+}
+
+// requireHandedOffClientCert publishes the server certificate certPEM and
+// makes tlsConfig require the client certificate at path. The file is read
+// again for every connection, since clients hand off a new certificate each
+// time they reattach; until one has, handshakes fail.
+func requireHandedOffClientCert(tlsConfig *tls.Config, certPEM []byte, path string, logger hclog.Logger) error {
+	if err := writeHandoff(serverCertHandoffPath(path), certPEM); err != nil {
+		return err
+	}
+	tlsConfig.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		pool, err := readClientCertHandoff(path)
+		if err != nil {
+			logger.Warn("rejecting connection", "error", err)
+			return nil, err
+		}
+		config := tlsConfig.Clone()
+		config.GetConfigForClient = nil
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+		return config, nil
+	}
+	logger.Info("🔐 Requiring handed-off client certificate", "path", path, "server_cert", serverCertHandoffPath(path))
+	return nil
+}
@@ -0,0 +1,393 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gofrs/flock"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/provide-io/tofusoup/proto/kv"
+)
+
+// entryTTLEnv supplies --entry-ttl to servers a client spawns and to local
+// compaction, as a Go duration ("1h")
+const entryTTLEnv = "KV_ENTRY_TTL"
+
+// compacter is implemented by stores that can garbage collect expired
+// entries and idempotency records
+type compacter interface {
+	Compact(ttl, tokenWindow time.Duration, dryRun bool) (*kvCompaction, error)
+}
+
+// kvCompaction reports one compaction pass. Sizes are file sizes on disk.
+type kvCompaction struct {
+	ScannedKeys    int64    `json:"scanned_keys"`
+	RemovedKeys    []string `json:"removed_keys"`
+	ReclaimedBytes int64    `json:"reclaimed_bytes"`
+	LiveKeys       int64    `json:"live_keys"`
+	LiveBytes      int64    `json:"live_bytes"`
+	EvictedKeys    int64    `json:"evicted_keys"`
+	EvictedBytes   int64    `json:"evicted_bytes"`
+	DryRun         bool     `json:"dry_run"`
+	// ExpiredTokens counts idempotency records removed. Their bytes are in
+	// ReclaimedBytes; the Compact RPC has no field for the count, so only
+	// local compaction reports it.
+	ExpiredTokens int64 `json:"expired_tokens,omitempty"`
+}
+
+// entryTTL returns the TTL from flag if set, or else KV_ENTRY_TTL
+func entryTTL(flag time.Duration) (time.Duration, error) {
+	if flag != 0 {
+		return flag, nil
+	}
+	value := os.Getenv(entryTTLEnv)
+	if value == "" {
+		return 0, nil
+	}
+	ttl, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q: %w", entryTTLEnv, value, err)
+	}
+	return ttl, nil
+}
+
+// Compact removes entries not written for longer than ttl (none if ttl is
+// 0) and idempotency records older than tokenWindow. Each candidate is
+// locked and re-checked before it is removed, so a concurrent Put that
+// refreshes it keeps it.
+func (k *KVImpl) Compact(ttl, tokenWindow time.Duration, dryRun bool) (*kvCompaction, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	report := &kvCompaction{RemovedKeys: []string{}, DryRun: dryRun}
+	entries, err := os.ReadDir(k.storageDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return report, nil
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	for _, entry := range entries {
+		key, ok := strings.CutPrefix(entry.Name(), kvDataPrefix)
+		if !ok || entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		report.ScannedKeys++
+
+		if ttl <= 0 || now.Sub(info.ModTime()) <= ttl {
+			report.LiveKeys++
+			report.LiveBytes += info.Size()
+			continue
+		}
+		if dryRun {
+			report.RemovedKeys = append(report.RemovedKeys, key)
+			report.ReclaimedBytes += info.Size()
+			continue
+		}
+
+		removed, size, err := k.removeExpired(key, ttl, now)
+		if err != nil {
+			return nil, fmt.Errorf("failed to remove expired key %s: %w", key, err)
+		}
+		if removed {
+			report.RemovedKeys = append(report.RemovedKeys, key)
+			report.ReclaimedBytes += size
+		} else {
+			report.LiveKeys++
+			report.LiveBytes += size
+		}
+	}
+
+	tokens, tokenBytes, err := k.expireTokens(tokenWindow, now, dryRun)
+	if err != nil {
+		return nil, err
+	}
+	report.ExpiredTokens = tokens
+	report.ReclaimedBytes += tokenBytes
+
+	k.logger.Debug("🗄️🧹 compacted storage",
+		"scanned", report.ScannedKeys,
+		"removed", len(report.RemovedKeys),
+		"expired_tokens", report.ExpiredTokens,
+		"reclaimed_bytes", report.ReclaimedBytes,
+		"dry_run", dryRun)
+	return report, nil
+}
+
+// removeExpired removes key if it is still older than ttl once locked. It
+// returns whether it did and the file's size.
+func (k *KVImpl) removeExpired(key string, ttl time.Duration, now time.Time) (bool, int64, error) {
+	filePath := k.keyPath(key)
+	lock := flock.New(filePath)
+	if err := lock.Lock(); err != nil {
+		return false, 0, fmt.Errorf("failed to acquire lock: %w", err)
+	}
+	defer func() {
+		if err := lock.Unlock(); err != nil {
+			k.logger.Error("failed to unlock file", "key", key, "error", err)
+		}
+	}()
+
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return false, 0, err
+	}
+	if now.Sub(info.ModTime()) <= ttl {
+		return false, info.Size(), nil
+	}
+	if err := os.Remove(filePath); err != nil {
+		return false, 0, err
+	}
+	if err := os.Remove(k.metaPath(key)); err != nil && !os.IsNotExist(err) {
+		return false, 0, err
+	}
+	return true, info.Size(), nil
+}
+
+// forget drops keys removed behind the quota's back
+func (q *storageQuota) forget(keys []string) {
+	if q == nil {
+		return
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, key := range keys {
+		if elem, ok := q.entries[key]; ok {
+			q.bytes -= elem.Value.(*quotaEntry).size
+			q.order.Remove(elem)
+			delete(q.entries, key)
+		}
+	}
+	q.publish()
+}
+
+// metricValue returns an integer kvMetrics counter, 0 if never set
+func metricValue(name string) int64 {
+	if v, ok := kvMetrics.Get(name).(*expvar.Int); ok {
+		return v.Value()
+	}
+	return 0
+}
+
+// compact runs one compaction pass over m's store with --entry-ttl
+func (m *GRPCServer) compact(dryRun bool) (*kvCompaction, error) {
+	store, ok := m.Impl.(compacter)
+	if !ok {
+		return nil, status.Errorf(codes.Unimplemented, "store %T does not support compaction", m.Impl)
+	}
+	window := m.Options.IdempotencyWindow
+	if window == 0 {
+		window = defaultIdempotencyWindow
+	}
+	report, err := store.Compact(m.Options.EntryTTL, window, dryRun)
+	if err != nil {
+		return nil, err
+	}
+	if !dryRun {
+		m.quota.forget(report.RemovedKeys)
+		kvMetrics.Add("compactions_total", 1)
+		kvMetrics.Add("expired_keys_total", int64(len(report.RemovedKeys)))
+		kvMetrics.Add("reclaimed_bytes_total", report.ReclaimedBytes)
+	}
+	report.EvictedKeys = metricValue("evictions_total")
+	report.EvictedBytes = metricValue("evicted_bytes_total")
+	return report, nil
+}
+
+// startCompaction garbage collects expired entries every --gc-interval in
+// the background until the process exits
+func (m *GRPCServer) startCompaction() {
+	if m.Options.GCInterval <= 0 {
+		return
+	}
+	m.logger.Info("🗄️⏲️ automatic compaction enabled",
+		"interval", m.Options.GCInterval,
+		"entry_ttl", m.Options.EntryTTL)
+	go func() {
+		ticker := time.NewTicker(m.Options.GCInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			report, err := m.compact(false)
+			if err != nil {
+				m.logger.Error("🗄️❌ automatic compaction failed", "error", err)
+				continue
+			}
+			if len(report.RemovedKeys) > 0 {
+				m.logger.Info("🗄️🧹 automatic compaction reclaimed space",
+					"removed", len(report.RemovedKeys),
+					"reclaimed_bytes", report.ReclaimedBytes,
+					"live_keys", report.LiveKeys)
+			}
+		}
+	}()
+}
+
+func (m *GRPCServer) Compact(ctx context.Context, req *proto.CompactRequest) (*proto.CompactResponse, error) {
+	m.logger.Debug("📡🧹 handling Compact request", "dry_run", req.DryRun)
+	m.observeDeadline(ctx, "Compact")
+	if err := m.injectFault(ctx, "Compact"); err != nil {
+		return nil, err
+	}
+
+	if err := m.throttle("Compact"); err != nil {
+		return nil, err
+	}
+
+	if m.Options.ReadOnly && !req.DryRun {
+		countRequest("Compact", "rejected")
+		m.logger.Warn("📡🔒 rejecting Compact on read-only server")
+		return nil, errReadOnly("Compact", "*")
+	}
+
+	report, err := m.compact(req.DryRun)
+	if err != nil {
+		countRequest("Compact", "failed")
+		m.logger.Error("📡❌ Compact operation failed", "error", err)
+		return nil, err
+	}
+	countRequest("Compact", "ok")
+
+	m.logger.Debug("📡✅ Compact operation completed successfully",
+		"removed", len(report.RemovedKeys),
+		"reclaimed_bytes", report.ReclaimedBytes)
+	return &proto.CompactResponse{
+		ScannedKeys:    report.ScannedKeys,
+		RemovedKeys:    report.RemovedKeys,
+		ReclaimedBytes: report.ReclaimedBytes,
+		LiveKeys:       report.LiveKeys,
+		LiveBytes:      report.LiveBytes,
+		EvictedKeys:    report.EvictedKeys,
+		EvictedBytes:   report.EvictedBytes,
+		DryRun:         report.DryRun,
+	}, nil
+}
+
+// Compact asks the server to compact its store
+func (m *GRPCClient) Compact(dryRun bool) (*kvCompaction, error) {
+	resp, err := m.client.Compact(context.Background(), &proto.CompactRequest{DryRun: dryRun})
+	if err != nil {
+		m.logger.Error("🌐❌ Compact request failed", "error", err)
+		return nil, err
+	}
+	removed := resp.RemovedKeys
+	if removed == nil {
+		removed = []string{}
+	}
+	return &kvCompaction{
+		ScannedKeys:    resp.ScannedKeys,
+		RemovedKeys:    removed,
+		ReclaimedBytes: resp.ReclaimedBytes,
+		LiveKeys:       resp.LiveKeys,
+		LiveBytes:      resp.LiveBytes,
+		EvictedKeys:    resp.EvictedKeys,
+		EvictedBytes:   resp.EvictedBytes,
+		DryRun:         resp.DryRun,
+	}, nil
+}
+
+func initKVAdminCompactCmd() *cobra.Command {
+	var address string
+	var tlsCurve string
+	var dryRun bool
+	var ttlFlag time.Duration
+	var outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   "compact",
+		Short: "Garbage collect expired KV entries and report reclaimed bytes",
+		Long: `Remove KV entries not written for longer than the entry TTL and report
+how many bytes that reclaimed.
+
+By default the storage directory (--storage-dir, KV_STORAGE_DIR) is
+compacted in place with --entry-ttl (default $KV_ENTRY_TTL); with no TTL
+no entries expire and only the live totals are reported. Idempotency
+records older than $KV_IDEMPOTENCY_WINDOW (default 10m) are removed too. With --address the
+running server compacts its own store with its own --entry-ttl through the
+Compact RPC, and the report also counts entries evicted by --max-keys and
+--max-bytes since it started.
+
+Only the file backend is supported.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if outputFormat != "text" && outputFormat != "json" {
+				return fmt.Errorf("unknown --output-format %q (expected text or json)", outputFormat)
+			}
+
+			var report *kvCompaction
+			if address != "" {
+				if cmd.Flags().Changed("entry-ttl") {
+					return fmt.Errorf("--entry-ttl applies to local compaction; a server uses its own")
+				}
+				client, raw, err := dispensePlugin(address, tlsCurve, "kv_grpc")
+				if err != nil {
+					return err
+				}
+				defer client.Kill()
+
+				grpcClient, ok := raw.(*GRPCClient)
+				if !ok {
+					return fmt.Errorf("unexpected KV client type %T", raw)
+				}
+				report, err = grpcClient.Compact(dryRun)
+				if err != nil {
+					printStatusJSON(err)
+					return fmt.Errorf("failed to compact: %w", err)
+				}
+			} else {
+				ttl, err := entryTTL(ttlFlag)
+				if err != nil {
+					return err
+				}
+				window, err := idempotencyWindow(0)
+				if err != nil {
+					return err
+				}
+				report, err = NewKVImpl(logger.Named("kv"), GetKVStorageDir()).Compact(ttl, window, dryRun)
+				if err != nil {
+					return fmt.Errorf("failed to compact: %w", err)
+				}
+			}
+
+			if outputFormat == "json" {
+				return json.NewEncoder(os.Stdout).Encode(report)
+			}
+			verb := "removed"
+			if report.DryRun {
+				verb = "would remove"
+			}
+			fmt.Printf("scanned %d keys, %s %d expired (%d bytes reclaimed), %d live keys (%d bytes)\n",
+				report.ScannedKeys, verb, len(report.RemovedKeys), report.ReclaimedBytes, report.LiveKeys, report.LiveBytes)
+			if report.ExpiredTokens > 0 {
+				fmt.Printf("%s %d expired idempotency records\n", verb, report.ExpiredTokens)
+			}
+			if report.EvictedKeys > 0 {
+				fmt.Printf("evicted %d keys (%d bytes) since the server started\n", report.EvictedKeys, report.EvictedBytes)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&address, "address", "", "Compact a running server through the Compact RPC (e.g., 127.0.0.1:50051)")
+	cmd.Flags().StringVar(&tlsCurve, "tls-curve", "auto", "Client cert curve: auto (detect from server), secp256r1, secp384r1, secp521r1")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Report what would be removed without removing it")
+	cmd.Flags().DurationVar(&ttlFlag, "entry-ttl", 0, "Local compaction: remove entries not written for this long (default $KV_ENTRY_TTL)")
+	cmd.Flags().StringVar(&outputFormat, "output-format", "text", "Output format: text or json")
+	return cmd
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/provide-io/tofusoup/proto/kv"
+)
+
+// copyKeyError is a key kv cp failed to copy
+type copyKeyError struct {
+	Key string `json:"key"`
+	// Side is the server that failed: from or to
+	Side  string      `json:"side"`
+	Error interface{} `json:"error"`
+}
+
+// copyReport is what kv cp prints when it's done
+type copyReport struct {
+	Prefix string `json:"prefix"`
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Keys   int    `json:"keys"`
+	Copied int    `json:"copied"`
+	// Skipped counts keys --no-clobber left alone because the destination
+	// had them
+	Skipped int `json:"skipped"`
+	Failed  int `json:"failed"`
+	// Stripped counts JSON values the source returned with its
+	// server_handshake enrichment, which isn't copied
+	Stripped  int            `json:"stripped"`
+	Bytes     int64          `json:"bytes"`
+	Verified  int            `json:"verified,omitempty"`
+	ElapsedMs float64        `json:"elapsed_ms"`
+	Errors    []copyKeyError `json:"errors,omitempty"`
+}
+
+// copyOptions are kv cp's per-key behaviors
+type copyOptions struct {
+	NoClobber bool
+	Verify    bool
+}
+
+// copyOutcome is what happened to one key
+type copyOutcome struct {
+	copied, skipped, stripped, verified bool
+	bytes                               int
+	err                                 *copyKeyError
+}
+
+func copyKey(from, to KV, key string, opts copyOptions) copyOutcome {
+	fail := func(side string, err error) copyOutcome {
+		var rendered interface{} = err.Error()
+		if st := statusToJSON(err); st != nil {
+			rendered = st
+		}
+		return copyOutcome{err: &copyKeyError{Key: key, Side: side, Error: rendered}}
+	}
+
+	if opts.NoClobber {
+		_, err := to.Get(key)
+		if err == nil {
+			return copyOutcome{skipped: true}
+		}
+		if kvErrorCode(err) != proto.KVErrorCode_NOT_FOUND {
+			return fail("to", err)
+		}
+	}
+
+	value, err := from.Get(key)
+	if err != nil {
+		return fail("from", err)
+	}
+	// The source adds server_handshake to JSON objects on Get; the
+	// destination adds its own, so only the stored value is copied
+	outcome := copyOutcome{}
+	var obj map[string]json.RawMessage
+	if json.Unmarshal(value, &obj) == nil && obj[enrichmentField] != nil {
+		if canonical, ok := canonicalJSONObject(value, enrichmentField); ok {
+			value = canonical
+			outcome.stripped = true
+		}
+	}
+	if err := to.Put(key, value); err != nil {
+		return fail("to", err)
+	}
+	outcome.copied = true
+	outcome.bytes = len(value)
+
+	if opts.Verify {
+		read, err := to.Get(key)
+		if err != nil {
+			return fail("to", err)
+		}
+		if result := verifyRoundTrip(key, value, read); !result.Verified {
+			return fail("to", fmt.Errorf("read back %s, wrote %s", result.ReadSHA256, result.WrittenSHA256))
+		}
+		outcome.verified = true
+	}
+	return outcome
+}
+
+// copyKeys copies keys with a pool of workers, calling progress after each
+// key with the report so far
+func copyKeys(from, to KV, keys []string, concurrency int, opts copyOptions, report *copyReport, progress func(*copyReport)) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	work := make(chan string)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for key := range work {
+				outcome := copyKey(from, to, key, opts)
+				mu.Lock()
+				switch {
+				case outcome.err != nil:
+					report.Failed++
+					report.Errors = append(report.Errors, *outcome.err)
+				case outcome.skipped:
+					report.Skipped++
+				case outcome.copied:
+					report.Copied++
+					report.Bytes += int64(outcome.bytes)
+				}
+				if outcome.stripped {
+					report.Stripped++
+				}
+				if outcome.verified {
+					report.Verified++
+				}
+				progress(report)
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, key := range keys {
+		work <- key
+	}
+	close(work)
+	wg.Wait()
+	sort.Slice(report.Errors, func(i, j int) bool { return report.Errors[i].Key < report.Errors[j].Key })
+}
+
+// copyEndpoint names a side of the copy in the report
+func copyEndpoint(address string) string {
+	if address == "" {
+		return "spawned"
+	}
+	return address
+}
+
+func initKVCpCmd() *cobra.Command {
+	var (
+		fromAddress   string
+		toAddress     string
+		tlsCurve      string
+		concurrency   int
+		progressEvery int
+		opts          copyOptions
+	)
+
+	cmd := &cobra.Command{
+		Use:   "cp [prefix]",
+		Short: "Copy keys matching a prefix from one KV server to another",
+		Long: `Copy every key under a prefix (all keys if none is given) from the --from
+server to the --to server, each an address or handshake line as --address
+takes. Leaving one side out spawns a server for it from PLUGIN_SERVER_PATH.
+Both plugin clients are open in the same process for the whole copy.
+
+Keys are copied by --concurrency workers. Every --progress-every keys a
+{"progress": ...} JSON line goes to stderr; the report printed at the end
+counts copied, skipped and failed keys, and lists the failures with their
+status. JSON objects lose the server_handshake field the source added on
+Get, since the destination adds its own. The command fails if any key did.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			prefix := ""
+			if len(args) > 0 {
+				prefix = args[0]
+			}
+			if fromAddress == "" && toAddress == "" {
+				return fmt.Errorf("--from and --to can't both spawn a server; they would share one store")
+			}
+			if fromAddress == toAddress {
+				return fmt.Errorf("--from and --to are the same server")
+			}
+
+			fromClient, fromRaw, err := dispensePlugin(fromAddress, tlsCurve, "kv_grpc")
+			if err != nil {
+				return fmt.Errorf("source: %w", err)
+			}
+			defer fromClient.Kill()
+			toClient, toRaw, err := dispensePlugin(toAddress, tlsCurve, "kv_grpc")
+			if err != nil {
+				return fmt.Errorf("destination: %w", err)
+			}
+			defer toClient.Kill()
+			from, to := fromRaw.(KV), toRaw.(KV)
+
+			start := time.Now()
+			keys, err := from.List(prefix)
+			if err != nil {
+				printStatusJSON(err)
+				return fmt.Errorf("failed to list source keys: %w", err)
+			}
+
+			report := &copyReport{Prefix: prefix, From: copyEndpoint(fromAddress), To: copyEndpoint(toAddress), Keys: len(keys)}
+			progressEncoder := json.NewEncoder(os.Stderr)
+			progress := func(r *copyReport) {
+				done := r.Copied + r.Skipped + r.Failed
+				if progressEvery > 0 && (done%progressEvery == 0 || done == r.Keys) {
+					progressEncoder.Encode(map[string]interface{}{"progress": map[string]int{
+						"done": done, "total": r.Keys, "copied": r.Copied, "skipped": r.Skipped, "failed": r.Failed,
+					}})
+				}
+			}
+			copyKeys(from, to, keys, concurrency, opts, report, progress)
+			report.ElapsedMs = float64(time.Since(start).Microseconds()) / 1000
+
+			if err := json.NewEncoder(os.Stdout).Encode(report); err != nil {
+				return fmt.Errorf("failed to encode JSON: %w", err)
+			}
+			if report.Failed > 0 {
+				return fmt.Errorf("failed to copy %d of %d keys", report.Failed, report.Keys)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&fromAddress, "from", "", "Address or handshake of the source server (empty spawns one)")
+	cmd.Flags().StringVar(&toAddress, "to", "", "Address or handshake of the destination server (empty spawns one)")
+	cmd.Flags().StringVar(&tlsCurve, "tls-curve", "auto", "Client cert curve: auto (detect from server), secp256r1, secp384r1, secp521r1")
+	cmd.Flags().IntVar(&concurrency, "concurrency", 4, "Keys copied at once")
+	cmd.Flags().IntVar(&progressEvery, "progress-every", 100, "Print a progress line to stderr every this many keys (0 disables)")
+	cmd.Flags().BoolVar(&opts.NoClobber, "no-clobber", false, "Skip keys the destination already has")
+	cmd.Flags().BoolVar(&opts.Verify, "verify", false, "Read each key back from the destination and compare it with what was written")
+	return cmd
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/go-plugin"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/provide-io/tofusoup/proto/counter"
+)
+
+// defaultCounterBuffer is the number of events queued per subscriber before
+// it is considered too slow and disconnected.
+const defaultCounterBuffer = 64
+
+// CounterGRPCPlugin serves the counter service, which exercises
+// server-streaming ordering and backpressure between harness implementations.
+type CounterGRPCPlugin struct {
+	plugin.Plugin
+	Impl *CounterServer
+}
+
+func (p *CounterGRPCPlugin) GRPCClient(ctx context.Context, broker *plugin.GRPCBroker, c *grpc.ClientConn) (interface{}, error) {
+	if c == nil {
+		return nil, fmt.Errorf("nil gRPC connection")
+	}
+	return counter.NewCounterClient(c), nil
+}
+
+func (p *CounterGRPCPlugin) GRPCServer(broker *plugin.GRPCBroker, s *grpc.Server) error {
+	impl := p.Impl
+	if impl == nil {
+		impl = NewCounterServer(logger.Named("counter"), defaultCounterBuffer)
+	}
+	counter.RegisterCounterServer(s, impl)
+	return nil
+}
+
+// counterSubscriber is a single Subscribe stream. Events are queued on a
+// bounded channel; when it fills up the subscriber is dropped.
+type counterSubscriber struct {
+	name     string
+	events   chan *counter.CounterEvent
+	overflow chan struct{}
+}
+
+// CounterServer is an in-memory set of named counters. Every increment gets a
+// server-wide sequence number and is fanned out to subscribers in order.
+type CounterServer struct {
+	counter.UnimplementedCounterServer
+	logger      hclog.Logger
+	bufferSize  int
+	mu          sync.Mutex
+	values      map[string]int64
+	sequence    uint64
+	subscribers map[*counterSubscriber]struct{}
+	stopOnce    sync.Once
+	stopped     chan struct{}
+}
+
+// NewCounterServer creates a CounterServer queuing up to bufferSize events per subscriber
+func NewCounterServer(logger hclog.Logger, bufferSize int) *CounterServer {
+	if bufferSize < 1 {
+		bufferSize = defaultCounterBuffer
+	}
+	return &CounterServer{
+		logger:      logger,
+		bufferSize:  bufferSize,
+		values:      make(map[string]int64),
+		subscribers: make(map[*counterSubscriber]struct{}),
+		stopped:     make(chan struct{}),
+	}
+}
+
+// Stop ends all open subscriptions so a graceful server stop doesn't wait on
+// streams that would otherwise never finish.
+func (s *CounterServer) Stop() {
+	s.stopOnce.Do(func() { close(s.stopped) })
+}
+
+func (s *CounterServer) Increment(ctx context.Context, req *counter.IncrementRequest) (*counter.IncrementResponse, error) {
+	if req.Name == "" {
+		return nil, status.Error(codes.InvalidArgument, "counter name must not be empty")
+	}
+
+	// Publishing under the lock keeps subscriber queues in sequence order
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.values[req.Name] += req.Delta
+	s.sequence++
+	event := &counter.CounterEvent{
+		Name:     req.Name,
+		Value:    s.values[req.Name],
+		Delta:    req.Delta,
+		Sequence: s.sequence,
+	}
+
+	for sub := range s.subscribers {
+		if sub.name != "" && sub.name != req.Name {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			s.logger.Warn("🔢🐢 dropping slow subscriber", "name", sub.name, "buffer", s.bufferSize)
+			delete(s.subscribers, sub)
+			close(sub.overflow)
+		}
+	}
+
+	s.logger.Debug("🔢➕ incremented counter", "name", req.Name, "value", event.Value, "sequence", event.Sequence)
+	return &counter.IncrementResponse{Value: event.Value, Sequence: event.Sequence}, nil
+}
+
+func (s *CounterServer) Subscribe(req *counter.SubscribeRequest, stream counter.Counter_SubscribeServer) error {
+	sub := &counterSubscriber{
+		name:     req.Name,
+		events:   make(chan *counter.CounterEvent, s.bufferSize),
+		overflow: make(chan struct{}),
+	}
+
+	s.mu.Lock()
+	s.subscribers[sub] = struct{}{}
+	s.mu.Unlock()
+	s.logger.Debug("🔢📡 subscriber attached", "name", req.Name)
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.subscribers, sub)
+		s.mu.Unlock()
+		s.logger.Debug("🔢📡 subscriber detached", "name", req.Name)
+	}()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-s.stopped:
+			return status.Error(codes.Unavailable, "server is shutting down")
+		case event := <-sub.events:
+			if err := stream.Send(event); err != nil {
+				return err
+			}
+		case <-sub.overflow:
+			// Deliver what was queued before the overflow so the client sees
+			// an unbroken sequence up to the point it fell behind.
+			for len(sub.events) > 0 {
+				if err := stream.Send(<-sub.events); err != nil {
+					return err
+				}
+			}
+			return status.Errorf(codes.ResourceExhausted,
+				"subscriber fell behind: more than %d events queued", s.bufferSize)
+		}
+	}
+}
+
+// dispensePlugin connects to a server (reattaching if address is set) and
+// dispenses the named plugin. The caller must kill the returned client.
+func dispensePlugin(address, tlsCurve, name string) (*plugin.Client, interface{}, error) {
+	var client *plugin.Client
+	var err error
+	if address != "" {
+		client, err = newReattachClient(address, tlsCurve, logger)
+	} else {
+		client, err = newRPCClient(logger)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rpcClient, err := client.Client()
+	if err != nil {
+		client.Kill()
+		return nil, nil, fmt.Errorf("failed to create RPC client: %w", err)
+	}
+
+	raw, err := rpcClient.Dispense(name)
+	if err != nil {
+		client.Kill()
+		return nil, nil, fmt.Errorf("failed to dispense plugin: %w", err)
+	}
+	return client, raw, nil
+}
+
+func initCounterIncrementCmd() *cobra.Command {
+	var address string
+	var tlsCurve string
+	var delta int64
+
+	cmd := &cobra.Command{
+		Use:   "increment [name]",
+		Short: "Increment a counter on the RPC server",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, raw, err := dispensePlugin(address, tlsCurve, "counter_grpc")
+			if err != nil {
+				return err
+			}
+			defer client.Kill()
+			counterClient := raw.(counter.CounterClient)
+
+			resp, err := counterClient.Increment(context.Background(), &counter.IncrementRequest{
+				Name:  args[0],
+				Delta: delta,
+			})
+			if err != nil {
+				printStatusJSON(err)
+				return fmt.Errorf("failed to increment counter %s: %w", args[0], err)
+			}
+
+			return json.NewEncoder(os.Stdout).Encode(map[string]interface{}{
+				"name":     args[0],
+				"value":    resp.Value,
+				"sequence": resp.Sequence,
+			})
+		},
+	}
+
+	cmd.Flags().StringVar(&address, "address", "", "Address of existing server (e.g., 127.0.0.1:50051)")
+	cmd.Flags().StringVar(&tlsCurve, "tls-curve", "auto", "Client cert curve: auto (detect from server), secp256r1, secp384r1, secp521r1")
+	cmd.Flags().Int64Var(&delta, "delta", 1, "Amount to add to the counter")
+	return cmd
+}
+
+func initCounterSubscribeCmd() *cobra.Command {
+	var address string
+	var tlsCurve string
+	var count int
+
+	cmd := &cobra.Command{
+		Use:   "subscribe [name]",
+		Short: "Stream counter events from the RPC server as JSON lines",
+		Long: `Subscribe to counter events and print each one as a JSON line. With no
+name, events for all counters are streamed. Exits with an error if the
+sequence numbers received are not strictly increasing.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := ""
+			if len(args) > 0 {
+				name = args[0]
+			}
+
+			client, raw, err := dispensePlugin(address, tlsCurve, "counter_grpc")
+			if err != nil {
+				return err
+			}
+			defer client.Kill()
+			counterClient := raw.(counter.CounterClient)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			stream, err := counterClient.Subscribe(ctx, &counter.SubscribeRequest{Name: name})
+			if err != nil {
+				printStatusJSON(err)
+				return fmt.Errorf("failed to subscribe: %w", err)
+			}
+
+			encoder := json.NewEncoder(os.Stdout)
+			var lastSequence uint64
+			for received := 0; count <= 0 || received < count; received++ {
+				event, err := stream.Recv()
+				if err == io.EOF {
+					return nil
+				}
+				if err != nil {
+					printStatusJSON(err)
+					return fmt.Errorf("subscription ended after %d events: %w", received, err)
+				}
+				if event.Sequence <= lastSequence {
+					return fmt.Errorf("out-of-order event: sequence %d after %d", event.Sequence, lastSequence)
+				}
+				lastSequence = event.Sequence
+
+				encoder.Encode(map[string]interface{}{
+					"name":     event.Name,
+					"value":    event.Value,
+					"delta":    event.Delta,
+					"sequence": event.Sequence,
+				})
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&address, "address", "", "Address of existing server (e.g., 127.0.0.1:50051)")
+	cmd.Flags().StringVar(&tlsCurve, "tls-curve", "auto", "Client cert curve: auto (detect from server), secp256r1, secp384r1, secp521r1")
+	cmd.Flags().IntVar(&count, "count", 0, "Stop after this many events (0 streams until the server closes)")
+	return cmd
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"google.golang.org/grpc"
+
+	"github.com/provide-io/tofusoup/proto/counter"
+)
+
+func serveTestCounter(t *testing.T, bufferSize int) (*CounterServer, string) {
+	t.Helper()
+	impl := NewCounterServer(hclog.NewNullLogger(), bufferSize)
+	t.Cleanup(impl.Stop)
+	address := serveTestRPC(t, func(s *grpc.Server) { counter.RegisterCounterServer(s, impl) })
+	return impl, address
+}
+
+// waitForSubscribers blocks until n Subscribe streams are attached
+func waitForSubscribers(t *testing.T, s *CounterServer, n int) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		s.mu.Lock()
+		attached := len(s.subscribers)
+		s.mu.Unlock()
+		if attached >= n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d of %d subscribers attached", attached, n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestCounterIncrementCmd(t *testing.T) {
+	_, address := serveTestCounter(t, 0)
+
+	var got []map[string]interface{}
+	for _, delta := range []string{"2", "5"} {
+		cmd := initCounterIncrementCmd()
+		cmd.SetArgs([]string{"hits", "--address", address, "--delta", delta})
+		out, err := captureStdout(t, cmd.Execute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var resp map[string]interface{}
+		if err := json.Unmarshal(out, &resp); err != nil {
+			t.Fatalf("%v: %s", err, out)
+		}
+		got = append(got, resp)
+	}
+	if got[0]["value"] != 2.0 || got[1]["value"] != 7.0 || got[1]["sequence"] != 2.0 {
+		t.Errorf("got %v, want values 2 and 7 at sequences 1 and 2", got)
+	}
+}
+
+func TestCounterSubscribeCmd(t *testing.T) {
+	impl, address := serveTestCounter(t, 0)
+
+	go func() {
+		waitForSubscribers(t, impl, 1)
+		for _, name := range []string{"a", "b", "a", "a"} {
+			impl.Increment(context.Background(), &counter.IncrementRequest{Name: name, Delta: 1})
+		}
+	}()
+
+	cmd := initCounterSubscribeCmd()
+	cmd.SetArgs([]string{"a", "--address", address, "--count", "3"})
+	out, err := captureStdout(t, cmd.Execute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var values, sequences []float64
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		var event map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("%v: %s", err, scanner.Bytes())
+		}
+		if event["name"] != "a" {
+			t.Errorf("got an event for %v on a subscription to a", event["name"])
+		}
+		values = append(values, event["value"].(float64))
+		sequences = append(sequences, event["sequence"].(float64))
+	}
+	if len(values) != 3 || values[0] != 1 || values[2] != 3 {
+		t.Errorf("got values %v, want 1, 2, 3", values)
+	}
+	if len(sequences) != 3 || sequences[0] != 1 || sequences[1] != 3 || sequences[2] != 4 {
+		t.Errorf("got sequences %v, want 1, 3, 4", sequences)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// crashAfterEnv supplies --crash-after to servers a client spawns
+const crashAfterEnv = "KV_CRASH_AFTER"
+
+// crashExitCode is the status a server exits with at a crash point, so
+// tests can tell an injected crash from a failure
+const crashExitCode = 99
+
+// Crash points on the Put write path, in the order a Put reaches them
+const (
+	// crashLockAcquired is after the key's lock is taken (which creates an
+	// empty data file for a new key) and before anything is written
+	crashLockAcquired = "lock-acquired"
+	// crashFlush is after the value is written and before it is fsynced
+	crashFlush = "flush"
+	// crashPut is after the Nth Put is durable, before it is acknowledged
+	crashPut = "put"
+)
+
+var (
+	// rpcCrashAfter is the raw --crash-after value
+	rpcCrashAfter string
+	// crashHook is the parsed crash point, nil if none is armed
+	crashHook *crashPoint
+)
+
+// crashPoint kills the server the first time the write path reaches it
+type crashPoint struct {
+	point string
+	// puts is the Put count that triggers crashPut
+	puts int64
+	// done counts completed Puts
+	done atomic.Int64
+}
+
+// parseCrashAfter parses "lock-acquired", "flush" or "put:N"
+func parseCrashAfter(value string) (*crashPoint, error) {
+	switch {
+	case value == "":
+		return nil, nil
+	case value == crashLockAcquired, value == crashFlush:
+		return &crashPoint{point: value}, nil
+	case strings.HasPrefix(value, crashPut+":"):
+		n, err := strconv.ParseInt(strings.TrimPrefix(value, crashPut+":"), 10, 64)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid crash point %q: put:N needs a count of at least 1", value)
+		}
+		return &crashPoint{point: crashPut, puts: n}, nil
+	}
+	return nil, fmt.Errorf("unknown crash point %q (expected %s, %s or %s:N)", value, crashLockAcquired, crashFlush, crashPut)
+}
+
+// armCrashHook sets crashHook from --crash-after or KV_CRASH_AFTER
+func armCrashHook() error {
+	value := rpcCrashAfter
+	if value == "" {
+		value = os.Getenv(crashAfterEnv)
+	}
+	hook, err := parseCrashAfter(value)
+	if err != nil {
+		return err
+	}
+	crashHook = hook
+	if hook != nil {
+		logger.Warn("💥 crash injection armed", "crash_after", value, "exit_code", crashExitCode)
+	}
+	return nil
+}
+
+// crashAt kills the process without cleanup if point is the armed crash
+// point. Deferred unlocks and syncs don't run, as in a real crash.
+func crashAt(point, key string) {
+	if crashHook == nil {
+		return
+	}
+	if point == crashPut {
+		if crashHook.point != crashPut || crashHook.done.Add(1) != crashHook.puts {
+			return
+		}
+	} else if crashHook.point != point {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "💥 injected crash after %s (key %q)\n", point, key)
+	os.Exit(crashExitCode)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// faultDelayEnv supplies --fault-delay to servers a client spawns, as a Go
+// duration ("500ms")
+const faultDelayEnv = "KV_FAULT_DELAY"
+
+// deadlineTrailer echoes how many milliseconds the server had left before
+// the call's deadline when its handler started, or "none" if the call had
+// no deadline
+const deadlineTrailer = "kv-received-deadline-ms"
+
+var (
+	// rpcDeadlineMs is the per-call deadline clients set (0 sets none)
+	rpcDeadlineMs int64
+	// rpcFaultDelay delays every KV call on the server
+	rpcFaultDelay time.Duration
+)
+
+// faultDelay returns the delay from --fault-delay or KV_FAULT_DELAY
+func faultDelay() (time.Duration, error) {
+	if rpcFaultDelay != 0 {
+		return rpcFaultDelay, nil
+	}
+	value := os.Getenv(faultDelayEnv)
+	if value == "" {
+		return 0, nil
+	}
+	delay, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q: %w", faultDelayEnv, value, err)
+	}
+	return delay, nil
+}
+
+// observeDeadline logs the deadline a call arrived with and echoes it to the
+// client in the kv-received-deadline-ms trailer
+func (m *GRPCServer) observeDeadline(ctx context.Context, method string) {
+	received := "none"
+	if deadline, ok := ctx.Deadline(); ok {
+		remaining := time.Until(deadline)
+		received = strconv.FormatInt(remaining.Milliseconds(), 10)
+		m.logger.Info("📡⏱️ call has a deadline", "method", method, "remaining", remaining)
+	}
+	grpc.SetTrailer(ctx, metadata.Pairs(deadlineTrailer, received))
+}
+
+// injectFault holds a call for Options.FaultDelay, simulating a slow
+// handler. A call whose deadline passes or which is cancelled meanwhile
+// fails with DeadlineExceeded or Canceled, as a real handler honoring its
+// context would.
+func (m *GRPCServer) injectFault(ctx context.Context, method string) error {
+	if m.Options.FaultDelay <= 0 {
+		return nil
+	}
+	m.logger.Debug("📡🐢 delaying call", "method", method, "delay", m.Options.FaultDelay)
+	timer := time.NewTimer(m.Options.FaultDelay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		countRequest(method, "deadline")
+		m.logger.Info("📡⏱️ call ended during injected delay", "method", method, "error", ctx.Err())
+		return status.FromContextError(ctx.Err()).Err()
+	}
+}
+
+// deadlineDialOptions returns an interceptor giving each unary call the
+// --deadline-ms deadline, unless the caller set one, and logging the
+// deadline the server says it received
+func deadlineDialOptions(logger hclog.Logger) []grpc.DialOption {
+	if rpcDeadlineMs <= 0 {
+		return nil
+	}
+	timeout := time.Duration(rpcDeadlineMs) * time.Millisecond
+	return []grpc.DialOption{grpc.WithChainUnaryInterceptor(
+		func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			if _, ok := ctx.Deadline(); !ok {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, timeout)
+				defer cancel()
+			}
+			var trailer metadata.MD
+			start := time.Now()
+			err := invoker(ctx, method, req, reply, cc, append(opts, grpc.Trailer(&trailer))...)
+			logger.Debug("⏱️ call finished", "method", method, "deadline_ms", rpcDeadlineMs,
+				"elapsed", time.Since(start), "code", status.Code(err))
+			if received := trailer.Get(deadlineTrailer); len(received) > 0 {
+				logger.Info("⏱️ server received deadline", "method", method, "sent_ms", rpcDeadlineMs, "received_ms", received[0])
+			}
+			return err
+		},
+	)}
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/stats"
+)
+
+// diagFileEnv supplies --diag-file to servers a client spawns
+const diagFileEnv = "KV_DIAG_FILE"
+
+// rpcDiagFile is the file servers append diagnostic dumps to; empty writes
+// them to stderr, which go-plugin relays to the client's log
+var rpcDiagFile string
+
+// diagExitCode is the status a server exits with after a SIGQUIT dump,
+// matching the Go runtime's own SIGQUIT exit
+const diagExitCode = 2
+
+// diagConn is what a server knows about one open connection
+type diagConn struct {
+	remote string
+	local  string
+	since  time.Time
+	rpcs   atomic.Int64
+	active atomic.Int64
+
+	mu  sync.Mutex
+	tls *tls.ConnectionState
+}
+
+// diagConns tracks the server's open connections for diagnostic dumps
+type diagConns struct {
+	mu    sync.Mutex
+	conns map[*diagConn]struct{}
+}
+
+var serverConns = &diagConns{conns: map[*diagConn]struct{}{}}
+
+type diagConnKey struct{}
+
+// diagStatsHandler records connections and their RPCs in serverConns
+type diagStatsHandler struct{}
+
+func (diagStatsHandler) TagConn(ctx context.Context, info *stats.ConnTagInfo) context.Context {
+	conn := &diagConn{since: time.Now()}
+	if info.RemoteAddr != nil {
+		conn.remote = info.RemoteAddr.String()
+	}
+	if info.LocalAddr != nil {
+		conn.local = info.LocalAddr.String()
+	}
+	return context.WithValue(ctx, diagConnKey{}, conn)
+}
+
+func (diagStatsHandler) HandleConn(ctx context.Context, s stats.ConnStats) {
+	conn, ok := ctx.Value(diagConnKey{}).(*diagConn)
+	if !ok {
+		return
+	}
+	serverConns.mu.Lock()
+	defer serverConns.mu.Unlock()
+	switch s.(type) {
+	case *stats.ConnBegin:
+		serverConns.conns[conn] = struct{}{}
+	case *stats.ConnEnd:
+		delete(serverConns.conns, conn)
+	}
+}
+
+// TagRPC counts the call against its connection and notes the
+// connection's TLS state, which is only known once a call arrives
+func (diagStatsHandler) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context {
+	conn, ok := ctx.Value(diagConnKey{}).(*diagConn)
+	if !ok {
+		return ctx
+	}
+	conn.rpcs.Add(1)
+	conn.active.Add(1)
+	if p, ok := peer.FromContext(ctx); ok {
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			conn.mu.Lock()
+			conn.tls = &info.State
+			conn.mu.Unlock()
+		}
+	}
+	return ctx
+}
+
+func (diagStatsHandler) HandleRPC(ctx context.Context, s stats.RPCStats) {
+	if _, ok := s.(*stats.End); !ok {
+		return
+	}
+	if conn, ok := ctx.Value(diagConnKey{}).(*diagConn); ok {
+		conn.active.Add(-1)
+	}
+}
+
+// diagServerOptions tracks connections for diagnostic dumps
+func diagServerOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{grpc.StatsHandler(diagStatsHandler{})}
+}
+
+// diagGRPCServer wraps a go-plugin GRPCServer factory to track connections
+func diagGRPCServer(next func([]grpc.ServerOption) *grpc.Server) func([]grpc.ServerOption) *grpc.Server {
+	return func(opts []grpc.ServerOption) *grpc.Server {
+		return next(append(opts, diagServerOptions()...))
+	}
+}
+
+// describeTLS summarizes a connection's TLS session
+func describeTLS(state *tls.ConnectionState) string {
+	if state == nil {
+		return "none"
+	}
+	parts := []string{tls.VersionName(state.Version), tls.CipherSuiteName(state.CipherSuite)}
+	if state.ServerName != "" {
+		parts = append(parts, "sni="+state.ServerName)
+	}
+	if state.DidResume {
+		parts = append(parts, "resumed")
+	}
+	if len(state.PeerCertificates) > 0 {
+		cert := state.PeerCertificates[0]
+		parts = append(parts, fmt.Sprintf("peer=%q", cert.Subject.String()),
+			"peer_expires="+cert.NotAfter.UTC().Format(time.RFC3339))
+	} else {
+		parts = append(parts, "no client cert")
+	}
+	return strings.Join(parts, " ")
+}
+
+// writeDiagConns lists open connections, oldest first
+func writeDiagConns(w io.Writer, now time.Time) {
+	serverConns.mu.Lock()
+	conns := make([]*diagConn, 0, len(serverConns.conns))
+	for conn := range serverConns.conns {
+		conns = append(conns, conn)
+	}
+	serverConns.mu.Unlock()
+	sort.Slice(conns, func(i, j int) bool { return conns[i].since.Before(conns[j].since) })
+
+	fmt.Fprintf(w, "-- connections (%d open)\n", len(conns))
+	for _, conn := range conns {
+		conn.mu.Lock()
+		tlsState := conn.tls
+		conn.mu.Unlock()
+		fmt.Fprintf(w, "  %s -> %s open %s, %d rpcs (%d active), tls: %s\n",
+			conn.remote, conn.local, now.Sub(conn.since).Round(time.Millisecond),
+			conn.rpcs.Load(), conn.active.Load(), describeTLS(tlsState))
+	}
+}
+
+// writeDiagStorage summarizes the values in the storage directory
+func writeDiagStorage(w io.Writer, dir string) {
+	fmt.Fprintf(w, "-- storage %s\n", dir)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		fmt.Fprintf(w, "  unreadable: %v\n", err)
+		return
+	}
+	keys, other := 0, 0
+	var bytes int64
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), "kv-data-") {
+			other++
+			continue
+		}
+		keys++
+		if info, err := entry.Info(); err == nil {
+			bytes += info.Size()
+		}
+	}
+	fmt.Fprintf(w, "  %d keys, %d value bytes, %d other files\n", keys, bytes, other)
+}
+
+// diagnosticDump renders the server's state: runtime figures, open
+// connections with their TLS sessions, storage, and every goroutine's stack
+func diagnosticDump(reason string, started time.Time, storageDir string) []byte {
+	now := time.Now()
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "=== soup-go diagnostic dump %s (%s, pid %d, up %s) ===\n",
+		now.UTC().Format(time.RFC3339Nano), reason, os.Getpid(), now.Sub(started).Round(time.Millisecond))
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	fmt.Fprintf(&buf, "-- runtime %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	fmt.Fprintf(&buf, "  %d goroutines, %d heap bytes in use, %d GC cycles\n",
+		runtime.NumGoroutine(), mem.HeapInuse, mem.NumGC)
+
+	writeDiagConns(&buf, now)
+	writeDiagStorage(&buf, storageDir)
+
+	fmt.Fprintf(&buf, "-- goroutines\n")
+	if err := pprof.Lookup("goroutine").WriteTo(&buf, 2); err != nil {
+		fmt.Fprintf(&buf, "  failed to dump goroutines: %v\n", err)
+	}
+	fmt.Fprintf(&buf, "=== end of dump ===\n")
+	return buf.Bytes()
+}
+
+// writeDiagnosticDump appends a dump to --diag-file, or writes it to stderr.
+// It writes where the logs go: in plugin mode go-plugin points os.Stderr at
+// its stdio stream, which clients don't read.
+func writeDiagnosticDump(logger hclog.Logger, dump []byte) {
+	if rpcDiagFile == "" {
+		logOutput.Write(dump)
+		return
+	}
+	path, err := filepath.Abs(rpcDiagFile)
+	if err != nil {
+		path = rpcDiagFile
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err == nil {
+		_, err = f.Write(dump)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		logger.Error("🩺 failed to write diagnostic dump, writing it to stderr", "file", path, "error", err)
+		logOutput.Write(dump)
+		return
+	}
+	logger.Info("🩺 wrote diagnostic dump", "file", path, "bytes", len(dump))
+}
+
+// startDiagnostics makes the server dump its state on SIGUSR1, and on
+// SIGQUIT before exiting, where the platform has those signals
+func startDiagnostics(logger hclog.Logger) {
+	if rpcDiagFile == "" {
+		rpcDiagFile = os.Getenv(diagFileEnv)
+	}
+	started := time.Now()
+	storageDir := GetKVStorageDir()
+	notifyDiagSignals(logger, func(reason string) {
+		writeDiagnosticDump(logger, diagnosticDump(reason, started, storageDir))
+	})
+}
@@ -0,0 +1,407 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/go-plugin"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+
+	"github.com/provide-io/tofusoup/proto/echo"
+)
+
+// EchoGRPCPlugin serves the bidirectional Chat RPC used for flow-control tests.
+type EchoGRPCPlugin struct {
+	plugin.Plugin
+	Impl *EchoServer
+}
+
+func (p *EchoGRPCPlugin) GRPCClient(ctx context.Context, broker *plugin.GRPCBroker, c *grpc.ClientConn) (interface{}, error) {
+	if c == nil {
+		return nil, fmt.Errorf("nil gRPC connection")
+	}
+	return echo.NewEchoClient(c), nil
+}
+
+func (p *EchoGRPCPlugin) GRPCServer(broker *plugin.GRPCBroker, s *grpc.Server) error {
+	impl := p.Impl
+	if impl == nil {
+		impl = NewEchoServer(logger.Named("echo"), 0)
+	}
+	echo.RegisterEchoServer(s, impl)
+	return nil
+}
+
+// EchoServer echoes each Chat frame back in the order received, after an
+// optional delay, stamping it with a per-stream server sequence number and
+// the caller's peer credentials.
+type EchoServer struct {
+	echo.UnimplementedEchoServer
+	logger hclog.Logger
+	delay  time.Duration
+}
+
+// NewEchoServer creates an EchoServer that waits delay before each echo
+func NewEchoServer(logger hclog.Logger, delay time.Duration) *EchoServer {
+	return &EchoServer{
+		logger: logger,
+		delay:  delay,
+	}
+}
+
+func (s *EchoServer) Chat(stream echo.Echo_ChatServer) error {
+	peerCreds := peerCredentials(stream.Context())
+	s.logger.Debug("💬📡 chat stream opened", "peer_uid", peerCreds.Uid, "peer_available", peerCreds.Available)
+	var serverSequence uint64
+
+	for {
+		frame, err := stream.Recv()
+		if err == io.EOF {
+			s.logger.Debug("💬✅ chat stream closed by client", "frames", serverSequence)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		received := time.Now()
+
+		delay := s.delay
+		if frame.DelayMs > 0 {
+			delay = time.Duration(frame.DelayMs) * time.Millisecond
+		}
+		if delay > 0 {
+			select {
+			case <-time.After(delay):
+			case <-stream.Context().Done():
+				return stream.Context().Err()
+			}
+		}
+
+		serverSequence++
+		if err := stream.Send(&echo.ChatEcho{
+			Sequence:         frame.Sequence,
+			ServerSequence:   serverSequence,
+			Payload:          frame.Payload,
+			ReceivedUnixNano: received.UnixNano(),
+			Peer:             peerCreds,
+		}); err != nil {
+			return err
+		}
+	}
+}
+
+// GetServerInfo reports the listener the call arrived on and who made it
+func (s *EchoServer) GetServerInfo(ctx context.Context, req *echo.ServerInfoRequest) (*echo.ServerInfo, error) {
+	return serverInfo(ctx), nil
+}
+
+// peerCredsJSON is echo.PeerCredentials as reported by the echo commands
+type peerCredsJSON struct {
+	Available bool   `json:"available"`
+	UID       uint32 `json:"uid"`
+	GID       uint32 `json:"gid"`
+	PID       int32  `json:"pid"`
+}
+
+func peerCredsToJSON(creds *echo.PeerCredentials) *peerCredsJSON {
+	if creds == nil {
+		return nil
+	}
+	return &peerCredsJSON{Available: creds.Available, UID: creds.Uid, GID: creds.Gid, PID: creds.Pid}
+}
+
+// chatReport summarizes a Chat run
+type chatReport struct {
+	Frames             int            `json:"frames"`
+	FrameSize          int            `json:"frame_size"`
+	Window             int            `json:"window"`
+	Sent               int            `json:"sent"`
+	Received           int            `json:"received"`
+	InOrder            bool           `json:"in_order"`
+	OutOfOrder         int            `json:"out_of_order"`
+	PayloadMismatches  int            `json:"payload_mismatches"`
+	ServerSequenceGaps int            `json:"server_sequence_gaps"`
+	MaxInFlight        int            `json:"max_in_flight"`
+	WindowStalls       int            `json:"window_stalls"`
+	DurationMs         float64        `json:"duration_ms"`
+	FramesPerSecond    float64        `json:"frames_per_second"`
+	BytesPerSecond     float64        `json:"bytes_per_second"`
+	Latency            latencySummary `json:"latency_ms"`
+	Peer               *peerCredsJSON `json:"peer,omitempty"`
+	Error              string         `json:"error,omitempty"`
+}
+
+// latencySummary holds round-trip latency percentiles in milliseconds
+type latencySummary struct {
+	Min float64 `json:"min"`
+	Avg float64 `json:"avg"`
+	P50 float64 `json:"p50"`
+	P90 float64 `json:"p90"`
+	P95 float64 `json:"p95"`
+	P99 float64 `json:"p99"`
+	Max float64 `json:"max"`
+}
+
+// summarizeLatencies computes a latencySummary from round-trip durations
+func summarizeLatencies(samples []time.Duration) latencySummary {
+	if len(samples) == 0 {
+		return latencySummary{}
+	}
+	sorted := append([]time.Duration(nil), samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	ms := func(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
+	percentile := func(p float64) float64 {
+		idx := int(p * float64(len(sorted)-1))
+		return ms(sorted[idx])
+	}
+
+	var total time.Duration
+	for _, d := range sorted {
+		total += d
+	}
+	return latencySummary{
+		Min: ms(sorted[0]),
+		Avg: ms(total / time.Duration(len(sorted))),
+		P50: percentile(0.50),
+		P90: percentile(0.90),
+		P95: percentile(0.95),
+		P99: percentile(0.99),
+		Max: ms(sorted[len(sorted)-1]),
+	}
+}
+
+// runChat drives a Chat stream, keeping at most window frames in flight, and
+// reports on ordering, latency and flow control.
+func runChat(ctx context.Context, client echo.EchoClient, frames, frameSize, window int, delay time.Duration) (*chatReport, error) {
+	report := &chatReport{
+		Frames:    frames,
+		FrameSize: frameSize,
+		Window:    window,
+		InOrder:   true,
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := client.Chat(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open chat stream: %w", err)
+	}
+
+	payloads := make([][]byte, frames)
+	sentAt := make([]time.Time, frames)
+	credits := make(chan struct{}, window)
+	var mu sync.Mutex
+	inFlight := 0
+
+	start := time.Now()
+	sendErr := make(chan error, 1)
+	go func() {
+		defer close(sendErr)
+		for i := 0; i < frames; i++ {
+			select {
+			case credits <- struct{}{}:
+			default:
+				// Window is full, wait for an echo to free a slot
+				mu.Lock()
+				report.WindowStalls++
+				mu.Unlock()
+				select {
+				case credits <- struct{}{}:
+				case <-ctx.Done():
+					sendErr <- ctx.Err()
+					return
+				}
+			}
+
+			payload := make([]byte, frameSize)
+			rand.Read(payload)
+
+			mu.Lock()
+			payloads[i] = payload
+			sentAt[i] = time.Now()
+			inFlight++
+			if inFlight > report.MaxInFlight {
+				report.MaxInFlight = inFlight
+			}
+			report.Sent++
+			mu.Unlock()
+
+			if err := stream.Send(&echo.ChatFrame{
+				Sequence: uint64(i + 1),
+				Payload:  payload,
+				DelayMs:  uint32(delay / time.Millisecond),
+			}); err != nil {
+				sendErr <- fmt.Errorf("failed to send frame %d: %w", i+1, err)
+				return
+			}
+		}
+		if err := stream.CloseSend(); err != nil {
+			sendErr <- fmt.Errorf("failed to close send side: %w", err)
+		}
+	}()
+
+	var latencies []time.Duration
+	var lastSequence, lastServerSequence uint64
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			report.Error = err.Error()
+			// Unblock the sender if it is waiting on the window
+			cancel()
+			break
+		}
+		now := time.Now()
+		<-credits
+		if report.Peer == nil {
+			report.Peer = peerCredsToJSON(resp.Peer)
+		}
+
+		if resp.Sequence <= lastSequence {
+			report.InOrder = false
+			report.OutOfOrder++
+		}
+		lastSequence = resp.Sequence
+		if resp.ServerSequence != lastServerSequence+1 {
+			report.ServerSequenceGaps++
+		}
+		lastServerSequence = resp.ServerSequence
+
+		mu.Lock()
+		inFlight--
+		idx := int(resp.Sequence) - 1
+		if idx < 0 || idx >= frames || string(payloads[idx]) != string(resp.Payload) {
+			report.PayloadMismatches++
+		} else {
+			latencies = append(latencies, now.Sub(sentAt[idx]))
+		}
+		mu.Unlock()
+		report.Received++
+	}
+
+	if err := <-sendErr; err != nil && report.Error == "" {
+		report.Error = err.Error()
+	}
+
+	elapsed := time.Since(start)
+	report.DurationMs = float64(elapsed) / float64(time.Millisecond)
+	if elapsed > 0 {
+		report.FramesPerSecond = float64(report.Received) / elapsed.Seconds()
+		report.BytesPerSecond = float64(report.Received*frameSize) / elapsed.Seconds()
+	}
+	report.Latency = summarizeLatencies(latencies)
+	if report.Received != frames {
+		report.InOrder = false
+	}
+	return report, nil
+}
+
+func initEchoChatCmd() *cobra.Command {
+	var address string
+	var tlsCurve string
+	var frames int
+	var frameSize int
+	var window int
+	var delay time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "chat",
+		Short: "Drive a bidirectional Chat stream and report on ordering and flow control",
+		Long: `Send frames over the bidirectional Chat RPC, keeping at most --window frames
+unacknowledged, and print a JSON report covering echo ordering, payload
+integrity, round-trip latency and how often the window stalled the sender.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if frames < 1 || frameSize < 0 || window < 1 {
+				return fmt.Errorf("--frames and --window must be positive and --frame-size non-negative")
+			}
+
+			client, raw, err := dispensePlugin(address, tlsCurve, "echo_grpc")
+			if err != nil {
+				return err
+			}
+			defer client.Kill()
+
+			report, err := runChat(context.Background(), raw.(echo.EchoClient), frames, frameSize, window, delay)
+			if err != nil {
+				printStatusJSON(err)
+				return err
+			}
+
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(report); err != nil {
+				return fmt.Errorf("failed to encode report: %w", err)
+			}
+			if report.Error != "" || !report.InOrder || report.PayloadMismatches > 0 {
+				return fmt.Errorf("chat run failed: %d/%d frames received, %d out of order, %d payload mismatches",
+					report.Received, frames, report.OutOfOrder, report.PayloadMismatches)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&address, "address", "", "Address of existing server (e.g., 127.0.0.1:50051)")
+	cmd.Flags().StringVar(&tlsCurve, "tls-curve", "auto", "Client cert curve: auto (detect from server), secp256r1, secp384r1, secp521r1")
+	cmd.Flags().IntVar(&frames, "frames", 100, "Number of frames to send")
+	cmd.Flags().IntVar(&frameSize, "frame-size", 1024, "Payload size of each frame in bytes")
+	cmd.Flags().IntVar(&window, "window", 8, "Maximum frames in flight before the sender waits for echoes")
+	cmd.Flags().DurationVar(&delay, "delay", 0, "Ask the server to delay each echo by this long (overrides the server default)")
+	return cmd
+}
+
+func initEchoInfoCmd() *cobra.Command {
+	var address string
+	var tlsCurve string
+
+	cmd := &cobra.Command{
+		Use:   "info",
+		Short: "Show the server's transport and the peer credentials it sees for this client",
+		Long: `Call GetServerInfo and print the listener network and address, the socket
+file's permission bits for unix sockets, the server's pid and uid, and this
+client's credentials as the server read them (SO_PEERCRED). Use
+--address unix:///path/to/socket for a standalone server on --socket.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, raw, err := dispensePlugin(address, tlsCurve, "echo_grpc")
+			if err != nil {
+				return err
+			}
+			defer client.Kill()
+
+			info, err := raw.(echo.EchoClient).GetServerInfo(context.Background(), &echo.ServerInfoRequest{})
+			if err != nil {
+				printStatusJSON(err)
+				return fmt.Errorf("failed to get server info: %w", err)
+			}
+
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(map[string]interface{}{
+				"transport":   info.Transport,
+				"address":     info.Address,
+				"socket_mode": info.SocketMode,
+				"pid":         info.Pid,
+				"uid":         info.Uid,
+				"peer":        peerCredsToJSON(info.Peer),
+			})
+		},
+	}
+
+	cmd.Flags().StringVar(&address, "address", "", "Address of existing server (e.g., 127.0.0.1:50051 or unix:///tmp/kv.sock)")
+	cmd.Flags().StringVar(&tlsCurve, "tls-curve", "auto", "Client cert curve: auto (detect from server), secp256r1, secp384r1, secp521r1")
+	return cmd
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"google.golang.org/grpc"
+
+	"github.com/provide-io/tofusoup/proto/echo"
+)
+
+// swappingEchoServer echoes frames in pairs, second frame first
+type swappingEchoServer struct {
+	echo.UnimplementedEchoServer
+}
+
+func (swappingEchoServer) Chat(stream echo.Echo_ChatServer) error {
+	var held *echo.ChatFrame
+	var serverSequence uint64
+	send := func(frame *echo.ChatFrame) error {
+		serverSequence++
+		return stream.Send(&echo.ChatEcho{Sequence: frame.Sequence, ServerSequence: serverSequence, Payload: frame.Payload})
+	}
+	for {
+		frame, err := stream.Recv()
+		if err == io.EOF {
+			if held != nil {
+				return send(held)
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if held == nil {
+			held = frame
+			continue
+		}
+		if err := send(frame); err != nil {
+			return err
+		}
+		if err := send(held); err != nil {
+			return err
+		}
+		held = nil
+	}
+}
+
+func runChatCmd(t *testing.T, impl echo.EchoServer, args ...string) (*chatReport, error) {
+	t.Helper()
+	address := serveTestRPC(t, func(s *grpc.Server) { echo.RegisterEchoServer(s, impl) })
+	cmd := initEchoChatCmd()
+	cmd.SetArgs(append([]string{"--address", address}, args...))
+	cmd.SilenceUsage, cmd.SilenceErrors = true, true
+	out, err := captureStdout(t, cmd.Execute)
+	var report chatReport
+	if jerr := json.Unmarshal(out, &report); jerr != nil {
+		t.Fatalf("%v (command error %v): %s", jerr, err, out)
+	}
+	return &report, err
+}
+
+func TestEchoChatCmd(t *testing.T) {
+	report, err := runChatCmd(t, NewEchoServer(hclog.NewNullLogger(), 0),
+		"--frames", "50", "--frame-size", "64", "--window", "4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Sent != 50 || report.Received != 50 || !report.InOrder {
+		t.Errorf("got %d sent, %d received, in order %v", report.Sent, report.Received, report.InOrder)
+	}
+	if report.PayloadMismatches != 0 || report.ServerSequenceGaps != 0 {
+		t.Errorf("got %d payload mismatches, %d server sequence gaps", report.PayloadMismatches, report.ServerSequenceGaps)
+	}
+	if report.MaxInFlight < 1 || report.MaxInFlight > 4 {
+		t.Errorf("got %d frames in flight, want at most the window of 4", report.MaxInFlight)
+	}
+}
+
+func TestEchoChatCmdDelay(t *testing.T) {
+	report, err := runChatCmd(t, NewEchoServer(hclog.NewNullLogger(), 0),
+		"--frames", "3", "--window", "1", "--delay", "20ms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Latency.Min < 20 {
+		t.Errorf("got a minimum latency of %.2fms, want the 20ms delay applied to every frame", report.Latency.Min)
+	}
+	if report.MaxInFlight != 1 || report.WindowStalls != 2 {
+		t.Errorf("got %d in flight and %d stalls with a window of 1, want 1 and 2", report.MaxInFlight, report.WindowStalls)
+	}
+	if report.DurationMs < float64(3*20*time.Millisecond/time.Millisecond) {
+		t.Errorf("got a %.2fms run, want the echoes serialised by the window", report.DurationMs)
+	}
+}
+
+func TestEchoChatCmdReportsReordering(t *testing.T) {
+	report, err := runChatCmd(t, swappingEchoServer{}, "--frames", "4", "--window", "4")
+	if err == nil {
+		t.Fatal("chat accepted out-of-order echoes")
+	}
+	if report.InOrder || report.OutOfOrder != 2 || report.Received != 4 || report.PayloadMismatches != 0 {
+		t.Errorf("got in order %v, %d out of order, %d received, %d mismatches",
+			report.InOrder, report.OutOfOrder, report.Received, report.PayloadMismatches)
+	}
+}
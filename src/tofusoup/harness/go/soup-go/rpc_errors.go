@@ -0,0 +1,285 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/provide-io/tofusoup/proto/kv"
+)
+
+// errorDomain is the ErrorInfo domain for errors raised by the KV service
+const errorDomain = "tofusoup.kv"
+
+// maxKeyLength bounds keys so they always fit in a single path component
+const maxKeyLength = 255
+
+// withDetails attaches error details to a status, falling back to the bare
+// status if the details cannot be marshaled.
+func withDetails(st *status.Status, details ...protoadapt.MessageV1) error {
+	detailed, err := st.WithDetails(details...)
+	if err != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
+
+// kvErrorStatusCodes is the server's mapping table: the gRPC code a status
+// carrying each KVError code has
+var kvErrorStatusCodes = map[proto.KVErrorCode]codes.Code{
+	proto.KVErrorCode_NOT_FOUND:           codes.NotFound,
+	proto.KVErrorCode_INVALID_KEY:         codes.InvalidArgument,
+	proto.KVErrorCode_TOO_LARGE:           codes.InvalidArgument,
+	proto.KVErrorCode_READONLY:            codes.FailedPrecondition,
+	proto.KVErrorCode_THROTTLED:           codes.ResourceExhausted,
+	proto.KVErrorCode_WAIT_TIMEOUT:        codes.DeadlineExceeded,
+	proto.KVErrorCode_OVER_MEMORY_CEILING: codes.ResourceExhausted,
+}
+
+// kvErrorFallbackCodes is the client's mapping table for statuses without
+// a KVError detail, from servers that predate it. Only gRPC codes a single
+// KVError code uses are mapped; InvalidArgument could be either
+// INVALID_KEY or TOO_LARGE.
+var kvErrorFallbackCodes = map[codes.Code]proto.KVErrorCode{
+	codes.NotFound:           proto.KVErrorCode_NOT_FOUND,
+	codes.FailedPrecondition: proto.KVErrorCode_READONLY,
+}
+
+// kvError builds a status with the gRPC code kvErrorStatusCodes gives code
+// and a KVError detail, followed by any other details
+func kvError(code proto.KVErrorCode, key, msg string, details ...protoadapt.MessageV1) error {
+	st := status.New(kvErrorStatusCodes[code], msg)
+	return withDetails(st, append([]protoadapt.MessageV1{&proto.KVError{Code: code, Key: key}}, details...)...)
+}
+
+// kvErrorCode classifies a client-side error: the code of its KVError
+// detail, or else the fallback for its gRPC code. Errors it can't classify
+// are KV_ERROR_CODE_UNSPECIFIED.
+func kvErrorCode(err error) proto.KVErrorCode {
+	st, ok := status.FromError(err)
+	if !ok || err == nil {
+		return proto.KVErrorCode_KV_ERROR_CODE_UNSPECIFIED
+	}
+	for _, detail := range st.Details() {
+		if kvErr, ok := detail.(*proto.KVError); ok {
+			return kvErr.Code
+		}
+	}
+	return kvErrorFallbackCodes[st.Code()]
+}
+
+// errNotFound builds the NotFound status for a Get of a key with no value
+func errNotFound(key string) error {
+	return kvError(proto.KVErrorCode_NOT_FOUND, key, fmt.Sprintf("key not found: %s", key))
+}
+
+// windowsReservedKeyChars can't appear in file names on Windows
+const windowsReservedKeyChars = `:*?"<>|`
+
+// validateKey checks that a key can be stored as a file name in the storage
+// directory. Invalid keys are rejected with InvalidArgument and a BadRequest
+// field violation describing the problem.
+func validateKey(key string) error {
+	var reason string
+	switch {
+	case key == "":
+		reason = "key must not be empty"
+	case len(key) > maxKeyLength:
+		reason = fmt.Sprintf("key must be at most %d bytes, got %d", maxKeyLength, len(key))
+	case strings.ContainsAny(key, "/\\"):
+		reason = "key must not contain path separators"
+	case strings.ContainsRune(key, 0):
+		reason = "key must not contain NUL bytes"
+	case runtime.GOOS == "windows" && strings.ContainsAny(key, windowsReservedKeyChars):
+		reason = fmt.Sprintf("key must not contain any of %s on windows", windowsReservedKeyChars)
+	default:
+		return nil
+	}
+
+	return kvError(proto.KVErrorCode_INVALID_KEY, key, fmt.Sprintf("invalid key: %s", reason),
+		badRequest("key", reason),
+		&errdetails.ErrorInfo{
+			Reason:   "INVALID_KEY",
+			Domain:   errorDomain,
+			Metadata: map[string]string{"key": key},
+		},
+	)
+}
+
+// badRequest is a BadRequest detail with a violation of a single field
+func badRequest(field, reason string) *errdetails.BadRequest {
+	return &errdetails.BadRequest{
+		FieldViolations: []*errdetails.BadRequest_FieldViolation{
+			{Field: field, Description: reason},
+		},
+	}
+}
+
+// errInvalidField builds an InvalidArgument status with a BadRequest field
+// violation for a single request field.
+func errInvalidField(field, value, reason string) error {
+	return withDetails(status.Newf(codes.InvalidArgument, "invalid %s: %s", field, reason),
+		badRequest(field, reason),
+		&errdetails.ErrorInfo{
+			Reason:   "INVALID_" + strings.ToUpper(field),
+			Domain:   errorDomain,
+			Metadata: map[string]string{field: value},
+		},
+	)
+}
+
+// errValueTooLarge builds the InvalidArgument status for a Put whose value
+// is over --max-value-bytes. Besides the BadRequest field violation, the
+// ErrorInfo metadata carries the sizes so clients needn't parse the message.
+func errValueTooLarge(key string, size, limit int64) error {
+	reason := fmt.Sprintf("value is %d bytes, more than the %d byte limit", size, limit)
+	return kvError(proto.KVErrorCode_TOO_LARGE, key, fmt.Sprintf("invalid value: %s", reason),
+		badRequest("value", reason),
+		&errdetails.ErrorInfo{
+			Reason: "VALUE_TOO_LARGE",
+			Domain: errorDomain,
+			Metadata: map[string]string{
+				"key":             key,
+				"size":            fmt.Sprint(size),
+				"max_value_bytes": fmt.Sprint(limit),
+			},
+		},
+	)
+}
+
+// errReadOnly builds the status returned for writes to a read-only server.
+// The status carries PreconditionFailure and ErrorInfo details so clients can
+// decompose it without matching on the message text.
+func errReadOnly(method, key string) error {
+	return kvError(proto.KVErrorCode_READONLY, key, fmt.Sprintf("server is read-only: %s not permitted", method),
+		&errdetails.PreconditionFailure{
+			Violations: []*errdetails.PreconditionFailure_Violation{
+				{
+					Type:        "READONLY",
+					Subject:     "kv/" + key,
+					Description: "the server was started with --readonly",
+				},
+			},
+		},
+		&errdetails.ErrorInfo{
+			Reason: "NOT_WRITABLE",
+			Domain: errorDomain,
+			Metadata: map[string]string{
+				"method": method,
+				"key":    key,
+			},
+		},
+	)
+}
+
+// errThrottled builds a ResourceExhausted status telling the client how long
+// to back off before retrying.
+func errThrottled(method string, retryAfter time.Duration) error {
+	return kvError(proto.KVErrorCode_THROTTLED, "", fmt.Sprintf("%s throttled: retry after %s", method, retryAfter),
+		&errdetails.RetryInfo{
+			RetryDelay: durationpb.New(retryAfter),
+		},
+		&errdetails.ErrorInfo{
+			Reason:   "THROTTLED",
+			Domain:   errorDomain,
+			Metadata: map[string]string{"method": method},
+		},
+	)
+}
+
+// errWaitTimeout builds the DeadlineExceeded status for a waiting Get whose
+// wait_timeout_ms passed first. The KVError detail tells it apart from the
+// call's own deadline expiring, which has none.
+func errWaitTimeout(key string, timeout time.Duration) error {
+	return kvError(proto.KVErrorCode_WAIT_TIMEOUT, key, fmt.Sprintf("key %s had no value within %s", key, timeout),
+		&errdetails.ErrorInfo{
+			Reason: "WAIT_TIMEOUT",
+			Domain: errorDomain,
+			Metadata: map[string]string{
+				"key":             key,
+				"wait_timeout_ms": fmt.Sprint(timeout.Milliseconds()),
+			},
+		},
+	)
+}
+
+// errOverMemoryCeiling builds the ResourceExhausted status for a Get of a
+// value over --max-value-memory, which the client can still read with
+// GetStream
+func errOverMemoryCeiling(key string, size, ceiling int64) error {
+	return kvError(proto.KVErrorCode_OVER_MEMORY_CEILING, key,
+		fmt.Sprintf("value of %s is %d bytes, more than the %d byte memory ceiling: use GetStream", key, size, ceiling),
+		&errdetails.ErrorInfo{
+			Reason: "OVER_MEMORY_CEILING",
+			Domain: errorDomain,
+			Metadata: map[string]string{
+				"key":              key,
+				"size":             fmt.Sprint(size),
+				"max_value_memory": fmt.Sprint(ceiling),
+			},
+		},
+	)
+}
+
+// statusToJSON renders a gRPC error as a JSON-friendly map, including any
+// google.rpc error details in their canonical protojson form. Returns nil if
+// err does not carry a gRPC status.
+func statusToJSON(err error) map[string]interface{} {
+	st, ok := status.FromError(err)
+	if !ok {
+		return nil
+	}
+
+	details := make([]interface{}, 0, len(st.Proto().GetDetails()))
+	for _, detail := range st.Proto().GetDetails() {
+		data, err := protojson.Marshal(detail)
+		if err != nil {
+			details = append(details, map[string]interface{}{
+				"@type": detail.GetTypeUrl(),
+				"error": err.Error(),
+			})
+			continue
+		}
+		var rendered map[string]interface{}
+		if err := json.Unmarshal(data, &rendered); err != nil {
+			continue
+		}
+		details = append(details, rendered)
+	}
+
+	rendered := map[string]interface{}{
+		"code":    st.Code().String(),
+		"message": st.Message(),
+		"details": details,
+	}
+	if code := kvErrorCode(err); code != proto.KVErrorCode_KV_ERROR_CODE_UNSPECIFIED {
+		rendered["kv_code"] = code.String()
+	}
+	return rendered
+}
+
+// printStatusJSON writes a gRPC error and its details to stdout as JSON so
+// harness consumers can compare error details across implementations.
+func printStatusJSON(err error) {
+	rendered := statusToJSON(err)
+	if rendered == nil {
+		return
+	}
+	data, err := json.Marshal(map[string]interface{}{
+		"error": rendered,
+	})
+	if err != nil {
+		return
+	}
+	os.Stdout.Write(redactDiagnostic(append(data, '\n')))
+}
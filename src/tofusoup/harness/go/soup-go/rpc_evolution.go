@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/go-plugin"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protowire"
+	protobuf "google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/provide-io/tofusoup/proto/kv"
+)
+
+// Trailer metadata in which KV servers report fields of a request they
+// didn't recognize, for schema-evolution tests
+const (
+	// unknownFieldsTrailer lists the unknown field numbers, comma separated;
+	// empty if there were none
+	unknownFieldsTrailer = "kv-unknown-fields"
+	// unknownPreservedTrailer is "true" if the unknown fields survived
+	// re-encoding the request, as protobuf runtimes are meant to ensure
+	unknownPreservedTrailer = "kv-unknown-fields-preserved"
+)
+
+// unknownFieldNumbers returns the field numbers in raw, in wire order
+// without duplicates
+func unknownFieldNumbers(raw protoreflect.RawFields) []int32 {
+	numbers := []int32{}
+	seen := map[protowire.Number]bool{}
+	for len(raw) > 0 {
+		num, typ, n := protowire.ConsumeTag(raw)
+		if n < 0 {
+			break
+		}
+		m := protowire.ConsumeFieldValue(num, typ, raw[n:])
+		if m < 0 {
+			break
+		}
+		raw = raw[n+m:]
+		if !seen[num] {
+			seen[num] = true
+			numbers = append(numbers, int32(num))
+		}
+	}
+	return numbers
+}
+
+// reportUnknownFields sets trailers listing the fields of req this server's
+// schema doesn't know and whether they are kept when req is re-encoded.
+// Newer clients send v2 messages with extra fields to see how each
+// language's runtime treats them.
+func reportUnknownFields(ctx context.Context, method string, req protoreflect.ProtoMessage, logger hclog.Logger) {
+	unknown := req.ProtoReflect().GetUnknown()
+	numbers := unknownFieldNumbers(unknown)
+
+	preserved := true
+	if len(unknown) > 0 {
+		kvMetrics.Add("unknown_fields_total", int64(len(numbers)))
+		reparsed := req.ProtoReflect().New().Interface()
+		encoded, err := protobuf.Marshal(req)
+		if err == nil {
+			err = protobuf.Unmarshal(encoded, reparsed)
+		}
+		preserved = err == nil && bytes.Equal(reparsed.ProtoReflect().GetUnknown(), unknown)
+		logger.Info("📡🧬 request carried unknown fields", "method", method, "fields", numbers, "preserved", preserved)
+	}
+
+	// Fails only outside a server call, where there's nobody to tell
+	grpc.SetTrailer(ctx, metadata.Pairs(
+		unknownFieldsTrailer, joinFieldNumbers(numbers),
+		unknownPreservedTrailer, strconv.FormatBool(preserved),
+	))
+}
+
+func joinFieldNumbers(numbers []int32) string {
+	parts := make([]string, len(numbers))
+	for i, num := range numbers {
+		parts[i] = strconv.Itoa(int(num))
+	}
+	return strings.Join(parts, ",")
+}
+
+// protoV2Report is what kv put and kv get print with --proto-v2
+type protoV2Report struct {
+	Method string `json:"method"`
+	Key    string `json:"key"`
+	// SentFields are the v2-only fields the client populated
+	SentFields []int32 `json:"sent_fields"`
+	// ServerReported is false if the server sent no kv-unknown-fields
+	// trailer, in which case the next two fields are empty
+	ServerReported      bool    `json:"server_reported"`
+	ServerUnknownFields []int32 `json:"server_unknown_fields"`
+	ServerPreserved     *bool   `json:"server_preserved,omitempty"`
+	// ResponseOptionalFields says which optional v2 response fields were
+	// present (Get only)
+	ResponseOptionalFields map[string]bool `json:"response_optional_fields,omitempty"`
+	// ResponseUnknownFields are response fields the v2 schema doesn't know
+	ResponseUnknownFields []int32 `json:"response_unknown_fields"`
+	Value                 string  `json:"value,omitempty"`
+}
+
+// readServerReport fills in what the server reported in trailer
+func (r *protoV2Report) readServerReport(trailer metadata.MD) {
+	r.ServerUnknownFields = []int32{}
+	values := trailer.Get(unknownFieldsTrailer)
+	if len(values) == 0 {
+		return
+	}
+	r.ServerReported = true
+	for _, part := range strings.Split(values[0], ",") {
+		if num, err := strconv.Atoi(strings.TrimSpace(part)); err == nil {
+			r.ServerUnknownFields = append(r.ServerUnknownFields, int32(num))
+		}
+	}
+	if preserved := trailer.Get(unknownPreservedTrailer); len(preserved) > 0 {
+		ok := preserved[0] == "true"
+		r.ServerPreserved = &ok
+	}
+}
+
+// pluginConn returns the gRPC connection under a go-plugin client, for
+// calls the dispensed KV interface can't make
+func pluginConn(rpcClient plugin.ClientProtocol) (*grpc.ClientConn, error) {
+	grpcClient, ok := rpcClient.(*plugin.GRPCClient)
+	if !ok {
+		return nil, fmt.Errorf("server negotiated %T, want a gRPC client", rpcClient)
+	}
+	return grpcClient.Conn, nil
+}
+
+// putV2 sends a PutRequestV2 with every new field set on the v1 Put method
+func putV2(ctx context.Context, conn grpc.ClientConnInterface, key string, value []byte) (*protoV2Report, error) {
+	contentType := "text/plain"
+	ttl := int64(3600)
+	req := &proto.PutRequestV2{
+		Key:         key,
+		Value:       value,
+		ContentType: &contentType,
+		TtlSeconds:  &ttl,
+		Tags:        []string{"soup-go", "proto-v2"},
+	}
+	resp := &proto.Empty{}
+	var trailer metadata.MD
+	if err := conn.Invoke(ctx, proto.KV_Put_FullMethodName, req, resp, grpc.Trailer(&trailer)); err != nil {
+		return nil, err
+	}
+	report := &protoV2Report{
+		Method:                "Put",
+		Key:                   key,
+		SentFields:            []int32{3, 4, 5},
+		ResponseUnknownFields: unknownFieldNumbers(resp.ProtoReflect().GetUnknown()),
+	}
+	report.readServerReport(trailer)
+	return report, nil
+}
+
+// getV2 sends a GetRequestV2 asking for metadata on the v1 Get method and
+// decodes the response as a GetResponseV2
+func getV2(ctx context.Context, conn grpc.ClientConnInterface, key string) (*protoV2Report, error) {
+	includeMetadata := true
+	req := &proto.GetRequestV2{Key: key, IncludeMetadata: &includeMetadata}
+	resp := &proto.GetResponseV2{}
+	var trailer metadata.MD
+	if err := conn.Invoke(ctx, proto.KV_Get_FullMethodName, req, resp, grpc.Trailer(&trailer)); err != nil {
+		return nil, err
+	}
+	report := &protoV2Report{
+		Method:     "Get",
+		Key:        key,
+		SentFields: []int32{2},
+		ResponseOptionalFields: map[string]bool{
+			"content_type":   resp.ContentType != nil,
+			"stored_at_unix": resp.StoredAtUnix != nil,
+		},
+		ResponseUnknownFields: unknownFieldNumbers(resp.ProtoReflect().GetUnknown()),
+		Value:                 string(resp.Value),
+	}
+	report.readServerReport(trailer)
+	return report, nil
+}
+
+// printProtoV2Report writes report to stdout as JSON
+func printProtoV2Report(report *protoV2Report) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return fmt.Errorf("failed to encode JSON: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// File name prefixes in a KV storage directory. Each key has a data file
+// holding its value and a metadata file indexing the value's size and
+// digest; metadata is written through a temporary file and renamed into
+// place.
+const (
+	kvDataPrefix = "kv-data-"
+	kvMetaPrefix = "kv-meta-"
+	kvTempPrefix = "kv-tmp-"
+)
+
+// kvEntryMeta is the contents of a key's metadata file
+type kvEntryMeta struct {
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Problems fsck finds in a storage directory
+const (
+	// fsckOrphanedLock is an empty data file with no metadata, left by a
+	// lock taken on a key that was never written (Put and Delete lock the
+	// data file, which creates it)
+	fsckOrphanedLock = "orphaned_lock"
+	// fsckOrphanedTemp is a metadata file that was never renamed into place
+	fsckOrphanedTemp = "orphaned_temp"
+	// fsckOrphanedMeta is metadata for a key with no data file
+	fsckOrphanedMeta = "orphaned_metadata"
+	// fsckMissingMeta is a value with no metadata, e.g. written before the
+	// index existed
+	fsckMissingMeta = "missing_metadata"
+	// fsckCorruptMeta is a metadata file that doesn't parse
+	fsckCorruptMeta = "corrupt_metadata"
+	// fsckTruncated is a value shorter than its metadata says, from a write
+	// cut short
+	fsckTruncated = "truncated_value"
+	// fsckDigestMismatch is a value whose digest disagrees with its metadata
+	fsckDigestMismatch = "digest_mismatch"
+)
+
+// Repairs fsck plans
+const (
+	// fsckRemoveFile deletes the offending file
+	fsckRemoveFile = "remove_file"
+	// fsckRemoveEntry deletes the key's value and metadata; its last write
+	// never completed
+	fsckRemoveEntry = "remove_entry"
+	// fsckReindex rewrites the metadata from the value on disk
+	fsckReindex = "reindex"
+)
+
+// fsckIssue is one problem and its planned repair
+type fsckIssue struct {
+	Key      string `json:"key"`
+	File     string `json:"file"`
+	Kind     string `json:"kind"`
+	Detail   string `json:"detail"`
+	Action   string `json:"action"`
+	Repaired bool   `json:"repaired"`
+}
+
+// fsckReport is the result of checking a storage directory
+type fsckReport struct {
+	StorageDir  string      `json:"storage_dir"`
+	ScannedKeys int         `json:"scanned_keys"`
+	OKKeys      int         `json:"ok_keys"`
+	Issues      []fsckIssue `json:"issues"`
+	Repair      bool        `json:"repair"`
+	Clean       bool        `json:"clean"`
+}
+
+// writeMeta atomically records value's size and digest as key's metadata
+func (k *KVImpl) writeMeta(key string, value []byte) error {
+	data, err := json.Marshal(kvEntryMeta{Size: int64(len(value)), SHA256: sha256Hex(value)})
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(k.storageDir, kvTempPrefix+key)
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, k.metaPath(key))
+}
+
+// Fsck checks every key's value against its metadata and looks for files
+// left behind by interrupted writes. With repair it carries out the plan.
+// The directory should not be in use by a server while it is checked.
+func (k *KVImpl) Fsck(repair bool) (*fsckReport, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	report := &fsckReport{StorageDir: k.storageDir, Issues: []fsckIssue{}, Repair: repair}
+	entries, err := os.ReadDir(k.storageDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			report.Clean = true
+			return report, nil
+		}
+		return nil, err
+	}
+
+	data := map[string]os.DirEntry{}
+	meta := map[string]bool{}
+	var temps []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		name := entry.Name()
+		if key, ok := strings.CutPrefix(name, kvDataPrefix); ok {
+			data[key] = entry
+		} else if key, ok := strings.CutPrefix(name, kvMetaPrefix); ok {
+			meta[key] = true
+		} else if key, ok := strings.CutPrefix(name, kvTempPrefix); ok {
+			temps = append(temps, key)
+		}
+	}
+
+	for _, key := range temps {
+		report.Issues = append(report.Issues, fsckIssue{
+			Key: key, File: kvTempPrefix + key, Kind: fsckOrphanedTemp,
+			Detail: "metadata write was never renamed into place", Action: fsckRemoveFile,
+		})
+	}
+	for key := range meta {
+		if _, ok := data[key]; !ok {
+			report.Issues = append(report.Issues, fsckIssue{
+				Key: key, File: kvMetaPrefix + key, Kind: fsckOrphanedMeta,
+				Detail: "metadata for a key with no value", Action: fsckRemoveFile,
+			})
+		}
+	}
+	for key := range data {
+		report.ScannedKeys++
+		issue, err := k.checkEntry(key, meta[key])
+		if err != nil {
+			return nil, err
+		}
+		if issue == nil {
+			report.OKKeys++
+			continue
+		}
+		report.Issues = append(report.Issues, *issue)
+	}
+	sort.Slice(report.Issues, func(i, j int) bool {
+		if report.Issues[i].Key != report.Issues[j].Key {
+			return report.Issues[i].Key < report.Issues[j].Key
+		}
+		return report.Issues[i].File < report.Issues[j].File
+	})
+
+	report.Clean = len(report.Issues) == 0
+	if !repair {
+		return report, nil
+	}
+	for i := range report.Issues {
+		if err := k.repairIssue(&report.Issues[i]); err != nil {
+			return nil, fmt.Errorf("failed to repair %s %s: %w", report.Issues[i].Kind, report.Issues[i].Key, err)
+		}
+		k.logger.Info("🗄️🔧 repaired storage issue",
+			"key", report.Issues[i].Key,
+			"kind", report.Issues[i].Kind,
+			"action", report.Issues[i].Action)
+	}
+	report.Clean = true
+	return report, nil
+}
+
+// checkEntry compares key's value with its metadata, returning nil if they
+// agree
+func (k *KVImpl) checkEntry(key string, hasMeta bool) (*fsckIssue, error) {
+	value, err := os.ReadFile(k.keyPath(key))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key %s: %w", key, err)
+	}
+	issue := &fsckIssue{Key: key, File: kvDataPrefix + key}
+
+	if !hasMeta {
+		if len(value) == 0 {
+			issue.Kind, issue.Action = fsckOrphanedLock, fsckRemoveFile
+			issue.Detail = "empty value with no metadata, created by a lock on a key that was never written"
+		} else {
+			issue.Kind, issue.Action = fsckMissingMeta, fsckReindex
+			issue.Detail = fmt.Sprintf("%d byte value has no metadata", len(value))
+		}
+		return issue, nil
+	}
+
+	raw, err := os.ReadFile(k.metaPath(key))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metadata for key %s: %w", key, err)
+	}
+	var m kvEntryMeta
+	if err := json.Unmarshal(raw, &m); err != nil || m.SHA256 == "" {
+		issue.File = kvMetaPrefix + key
+		issue.Kind, issue.Action = fsckCorruptMeta, fsckReindex
+		issue.Detail = "metadata is not a size and sha256 object"
+		return issue, nil
+	}
+
+	switch {
+	case int64(len(value)) < m.Size:
+		issue.Kind, issue.Action = fsckTruncated, fsckRemoveEntry
+		issue.Detail = fmt.Sprintf("value is %d bytes, metadata says %d", len(value), m.Size)
+	case sha256Hex(value) != m.SHA256:
+		issue.Kind, issue.Action = fsckDigestMismatch, fsckRemoveEntry
+		issue.Detail = fmt.Sprintf("value sha256 %s, metadata says %s", sha256Hex(value)[:16], m.SHA256[:min(16, len(m.SHA256))])
+	default:
+		return nil, nil
+	}
+	return issue, nil
+}
+
+// repairIssue carries out issue's planned action
+func (k *KVImpl) repairIssue(issue *fsckIssue) error {
+	switch issue.Action {
+	case fsckRemoveFile:
+		if err := os.Remove(filepath.Join(k.storageDir, issue.File)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	case fsckRemoveEntry:
+		for _, path := range []string{k.keyPath(issue.Key), k.metaPath(issue.Key)} {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	case fsckReindex:
+		value, err := os.ReadFile(k.keyPath(issue.Key))
+		if err != nil {
+			return err
+		}
+		if err := k.writeMeta(issue.Key, value); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown repair %q", issue.Action)
+	}
+	issue.Repaired = true
+	return nil
+}
+
+func initKVAdminFsckCmd() *cobra.Command {
+	var repair bool
+	var outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   "fsck",
+		Short: "Check KV storage for interrupted writes and index mismatches",
+		Long: `Check the storage directory (--storage-dir, KV_STORAGE_DIR) for damage
+an interrupted server can leave behind, and print a repair plan:
+
+  orphaned_lock      empty value with no metadata         remove_file
+  orphaned_temp      metadata never renamed into place    remove_file
+  orphaned_metadata  metadata for a key with no value     remove_file
+  missing_metadata   value with no metadata               reindex
+  corrupt_metadata   metadata that doesn't parse          reindex
+  truncated_value    value shorter than its metadata      remove_entry
+  digest_mismatch    value digest differs from metadata   remove_entry
+
+A truncated or mismatched value is from a write that never completed, so
+the entry is removed rather than trusted. --repair carries out the plan.
+
+Stop any server using the directory first. Exits non-zero if issues
+remain, so crash tests can assert post-crash integrity. Only the file
+backend is supported.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if outputFormat != "text" && outputFormat != "json" {
+				return fmt.Errorf("unknown --output-format %q (expected text or json)", outputFormat)
+			}
+
+			report, err := NewKVImpl(logger.Named("kv"), GetKVStorageDir()).Fsck(repair)
+			if err != nil {
+				return fmt.Errorf("failed to check storage: %w", err)
+			}
+
+			if outputFormat == "json" {
+				if err := json.NewEncoder(os.Stdout).Encode(report); err != nil {
+					return err
+				}
+			} else {
+				for _, issue := range report.Issues {
+					done := ""
+					if issue.Repaired {
+						done = " (done)"
+					}
+					fmt.Printf("%-17s %-12s %s: %s%s\n", issue.Kind, issue.Action, issue.Key, issue.Detail, done)
+				}
+				fmt.Printf("checked %d keys in %s: %d ok, %d issues\n",
+					report.ScannedKeys, report.StorageDir, report.OKKeys, len(report.Issues))
+			}
+
+			if !report.Clean {
+				return fmt.Errorf("storage has %d issues; rerun with --repair to fix them", len(report.Issues))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&repair, "repair", false, "Carry out the repair plan")
+	cmd.Flags().StringVar(&outputFormat, "output-format", "text", "Output format: text or json")
+	return cmd
+}
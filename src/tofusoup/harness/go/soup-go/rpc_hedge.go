@@ -0,0 +1,286 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-plugin"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/provide-io/tofusoup/proto/kv"
+)
+
+// Request hedging
+//
+// Plugin hosts are starting to hedge: when a call hasn't answered within a
+// delay, they send the same call again and take whichever response comes
+// first. That is only safe if the server dedupes the copies. --hedge sends
+// up to N copies of a Put or Get, --hedge-delay apart, until one succeeds,
+// and reports every copy: hedged Puts share one idempotency token and must
+// be applied once, with the rest deduped; hedged Gets must all return the
+// same value.
+
+// hedgeAttempt is one copy of a hedged call
+type hedgeAttempt struct {
+	Attempt int `json:"attempt"`
+	// SentMs and DoneMs are from when the first copy was sent
+	SentMs float64 `json:"sent_ms"`
+	DoneMs float64 `json:"done_ms"`
+	Code   string  `json:"code"`
+	// Result is a Put's kv-idempotency-result: applied, deduped, or
+	// unknown when the server sent none
+	Result string `json:"result,omitempty"`
+	// SHA256 is the digest of the value a Get returned
+	SHA256 string `json:"sha256,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// hedgeReport is the {"hedge": ...} line a hedged call prints
+type hedgeReport struct {
+	Method  string  `json:"method"`
+	Key     string  `json:"key"`
+	Token   string  `json:"token,omitempty"`
+	Hedge   int     `json:"hedge"`
+	DelayMs float64 `json:"delay_ms"`
+	// Cancel is set when copies still in flight were cancelled once one
+	// succeeded
+	Cancel   bool           `json:"cancel"`
+	Sent     int            `json:"sent"`
+	Winner   int            `json:"winner"`
+	WinnerMs float64        `json:"winner_ms"`
+	Attempts []hedgeAttempt `json:"attempts"`
+	Applied  int            `json:"applied"`
+	Deduped  int            `json:"deduped"`
+	// Consistent is set when every successful copy of a Get returned the
+	// same value
+	Consistent bool `json:"consistent"`
+	// Duplicate says how the server mishandled the copies, if it did
+	Duplicate string `json:"duplicate,omitempty"`
+}
+
+// hedgeOptions are the --hedge flags
+type hedgeOptions struct {
+	copies int
+	delay  time.Duration
+	cancel bool
+}
+
+func addHedgeFlags(cmd *cobra.Command, opts *hedgeOptions) {
+	cmd.Flags().IntVar(&opts.copies, "hedge", 0, "Send up to this many copies of the call, --hedge-delay apart, until one succeeds, and print every copy's outcome as a {\"hedge\": ...} JSON line (0 or 1 disables)")
+	cmd.Flags().DurationVar(&opts.delay, "hedge-delay", 50*time.Millisecond, "With --hedge, how long to wait for an answer before sending the next copy")
+	cmd.Flags().BoolVar(&opts.cancel, "hedge-cancel", false, "With --hedge, cancel copies still in flight once one succeeds, as a production client would, instead of waiting for their outcome")
+}
+
+// enabled reports whether --hedge asks for more than one copy
+func (o hedgeOptions) enabled() bool {
+	return o.copies > 1
+}
+
+func (o hedgeOptions) validate() error {
+	if o.copies < 0 {
+		return fmt.Errorf("--hedge must not be negative, got %d", o.copies)
+	}
+	if o.delay < 0 {
+		return fmt.Errorf("--hedge-delay must not be negative, got %s", o.delay)
+	}
+	return nil
+}
+
+// newHedgeToken is an idempotency token for hedged Puts sent without one
+func newHedgeToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate idempotency token: %w", err)
+	}
+	return "hedge-" + hex.EncodeToString(b), nil
+}
+
+// runHedged sends copies of call until one succeeds or all opts.copies
+// have been sent, starting the next copy opts.delay after the previous
+// one. Once a copy succeeds, no more are sent; those in flight are
+// cancelled with --hedge-cancel, and otherwise awaited.
+func runHedged(ctx context.Context, opts hedgeOptions, call func(ctx context.Context, attempt *hedgeAttempt)) *hedgeReport {
+	start := time.Now()
+	report := &hedgeReport{Hedge: opts.copies, DelayMs: float64(opts.delay.Microseconds()) / 1000, Cancel: opts.cancel}
+	callCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	won := make(chan struct{})
+	var wonOnce sync.Once
+	attempts := make([]*hedgeAttempt, 0, opts.copies)
+
+	send := func(n int) {
+		attempt := &hedgeAttempt{Attempt: n, SentMs: sinceMs(start)}
+		mu.Lock()
+		attempts = append(attempts, attempt)
+		mu.Unlock()
+		logger.Debug("🌐🔀 sending hedged copy", "attempt", n)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			call(callCtx, attempt)
+			mu.Lock()
+			defer mu.Unlock()
+			attempt.DoneMs = sinceMs(start)
+			if attempt.Code == "OK" && report.Winner == 0 {
+				report.Winner, report.WinnerMs = n, attempt.DoneMs
+				wonOnce.Do(func() { close(won) })
+			}
+		}()
+	}
+
+	send(1)
+	for n := 2; n <= opts.copies; n++ {
+		timer := time.NewTimer(opts.delay)
+		select {
+		case <-won:
+			timer.Stop()
+		case <-ctx.Done():
+			timer.Stop()
+		case <-timer.C:
+			send(n)
+			continue
+		}
+		break
+	}
+	if opts.cancel {
+		select {
+		case <-won:
+			cancel()
+		case <-ctx.Done():
+		case <-waitChan(&wg):
+		}
+	}
+	wg.Wait()
+
+	report.Sent = len(attempts)
+	for _, attempt := range attempts {
+		report.Attempts = append(report.Attempts, *attempt)
+	}
+	return report
+}
+
+// waitChan is closed once wg is done
+func waitChan(wg *sync.WaitGroup) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	return done
+}
+
+// hedgedPut sends hedged copies of a Put sharing token and checks that
+// the server applied it at most once
+func hedgedPut(ctx context.Context, rpcClient plugin.ClientProtocol, key string, value []byte, token string, opts hedgeOptions) (*hedgeReport, error) {
+	conn, err := pluginConn(rpcClient)
+	if err != nil {
+		return nil, err
+	}
+	client := proto.NewKVClient(conn)
+	report := runHedged(ctx, opts, func(ctx context.Context, attempt *hedgeAttempt) {
+		var trailer metadata.MD
+		req := &proto.PutRequest{Key: key, Value: value, IdempotencyToken: token}
+		_, err := client.Put(ctx, req, grpc.Trailer(&trailer))
+		attempt.Code = status.Code(err).String()
+		if err != nil {
+			attempt.Error = status.Convert(err).Message()
+			return
+		}
+		attempt.Result = "unknown"
+		if result := trailer.Get(idempotencyTrailer); len(result) > 0 {
+			attempt.Result = result[0]
+		}
+	})
+	report.Method, report.Key, report.Token = "/proto.KV/Put", key, token
+	report.Consistent = true
+	unknown := 0
+	for _, attempt := range report.Attempts {
+		switch attempt.Result {
+		case idempotencyApplied:
+			report.Applied++
+		case idempotencyDeduped:
+			report.Deduped++
+		case "unknown":
+			unknown++
+		}
+	}
+	switch {
+	case report.Applied > 1:
+		report.Duplicate = fmt.Sprintf("the server applied %d copies of one Put", report.Applied)
+	case unknown > 1:
+		report.Duplicate = fmt.Sprintf("the server sent no idempotency result for %d copies, so each may have been applied", unknown)
+	}
+	return report, nil
+}
+
+// hedgedGet sends hedged copies of a Get and checks that every copy that
+// succeeded returned the same value
+func hedgedGet(ctx context.Context, rpcClient plugin.ClientProtocol, key string, opts hedgeOptions) (*hedgeReport, []byte, error) {
+	conn, err := pluginConn(rpcClient)
+	if err != nil {
+		return nil, nil, err
+	}
+	client := proto.NewKVClient(conn)
+	var mu sync.Mutex
+	values := map[int][]byte{}
+	report := runHedged(ctx, opts, func(ctx context.Context, attempt *hedgeAttempt) {
+		resp, err := client.Get(ctx, &proto.GetRequest{Key: key})
+		attempt.Code = status.Code(err).String()
+		if err != nil {
+			attempt.Error = status.Convert(err).Message()
+			return
+		}
+		digest := sha256.Sum256(resp.Value)
+		attempt.SHA256 = hex.EncodeToString(digest[:])
+		mu.Lock()
+		values[attempt.Attempt] = resp.Value
+		mu.Unlock()
+	})
+	report.Method, report.Key = "/proto.KV/Get", key
+	report.Consistent = true
+	digests := map[string]bool{}
+	for _, attempt := range report.Attempts {
+		if attempt.SHA256 != "" {
+			digests[attempt.SHA256] = true
+		}
+	}
+	if len(digests) > 1 {
+		report.Consistent = false
+		report.Duplicate = fmt.Sprintf("copies of one Get returned %d different values", len(digests))
+	}
+	return report, values[report.Winner], nil
+}
+
+// printHedgeReport writes report as a {"hedge": ...} JSON line
+func printHedgeReport(report *hedgeReport) error {
+	return json.NewEncoder(os.Stdout).Encode(map[string]*hedgeReport{"hedge": report})
+}
+
+// hedgeError is the error a hedged call fails with: the winner's absence
+// or the duplicate the server let through
+func hedgeError(report *hedgeReport) error {
+	if report.Duplicate != "" {
+		return fmt.Errorf("hedged %s of %s: %s", report.Method, report.Key, report.Duplicate)
+	}
+	if report.Winner == 0 {
+		if len(report.Attempts) > 0 {
+			first := report.Attempts[0]
+			return fmt.Errorf("every hedged copy of %s failed; the first with %s: %s", report.Method, first.Code, first.Error)
+		}
+		return fmt.Errorf("no hedged copy of %s was sent", report.Method)
+	}
+	return nil
+}
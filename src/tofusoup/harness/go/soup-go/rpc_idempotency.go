@@ -0,0 +1,273 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gofrs/flock"
+	"github.com/hashicorp/go-plugin"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/provide-io/tofusoup/proto/kv"
+)
+
+// idempotencyWindowEnv supplies --idempotency-window to servers a client
+// spawns, as a Go duration ("10m")
+const idempotencyWindowEnv = "KV_IDEMPOTENCY_WINDOW"
+
+// defaultIdempotencyWindow is how long an applied Put's token dedupes
+// retries unless --idempotency-window says otherwise
+const defaultIdempotencyWindow = 10 * time.Minute
+
+// idempotencyTrailer tells a client whether a Put with an idempotency token
+// was "applied" or "deduped"
+const idempotencyTrailer = "kv-idempotency-result"
+
+// kvIdempotencyPrefix names the file recording an applied token, after the
+// token's SHA-256 (hex). The Python server reads and writes the same files,
+// so servers sharing a storage directory dedupe each other's retries.
+const kvIdempotencyPrefix = "kv-idem-"
+
+const (
+	idempotencyApplied = "applied"
+	idempotencyDeduped = "deduped"
+)
+
+var rpcIdempotencyWindow time.Duration
+
+// idempotencyWindow returns the window from flag if set, or else
+// KV_IDEMPOTENCY_WINDOW, or else the default
+func idempotencyWindow(flag time.Duration) (time.Duration, error) {
+	window := flag
+	if window == 0 {
+		value := os.Getenv(idempotencyWindowEnv)
+		if value == "" {
+			return defaultIdempotencyWindow, nil
+		}
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s %q: %w", idempotencyWindowEnv, value, err)
+		}
+		window = parsed
+	}
+	if window <= 0 {
+		return 0, fmt.Errorf("idempotency window must be positive, got %s", window)
+	}
+	return window, nil
+}
+
+// idempotencyRecord is what a kv-idem- file holds: the Put a token applied
+type idempotencyRecord struct {
+	Token     string `json:"token"`
+	Key       string `json:"key"`
+	SHA256    string `json:"sha256"`
+	AppliedMs int64  `json:"applied_unix_ms"`
+}
+
+// idempotentStore is implemented by stores that can record applied tokens
+type idempotentStore interface {
+	// lockToken locks token's record and returns it, or nil if there is
+	// none. Unlock before the next call for the same token.
+	lockToken(token string) (record *idempotencyRecord, unlock func(), err error)
+	recordToken(record *idempotencyRecord) error
+}
+
+func (k *KVImpl) tokenPath(token string) string {
+	digest := sha256.Sum256([]byte(token))
+	return filepath.Join(k.storageDir, kvIdempotencyPrefix+hex.EncodeToString(digest[:]))
+}
+
+func (k *KVImpl) lockToken(token string) (*idempotencyRecord, func(), error) {
+	if err := os.MkdirAll(k.storageDir, 0755); err != nil {
+		return nil, nil, fmt.Errorf("failed to create storage directory: %w", err)
+	}
+	// Locking creates the file empty, which reads as no record
+	path := k.tokenPath(token)
+	lock := flock.New(path)
+	if err := lock.Lock(); err != nil {
+		return nil, nil, fmt.Errorf("failed to lock idempotency token: %w", err)
+	}
+	unlock := func() {
+		if err := lock.Unlock(); err != nil {
+			k.logger.Error("failed to unlock idempotency token", "error", err)
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || len(data) == 0 {
+		return nil, unlock, nil
+	}
+	var record idempotencyRecord
+	if err := json.Unmarshal(data, &record); err != nil || record.Token != token {
+		// A damaged record can't dedupe anything
+		k.logger.Warn("🗄️⚠️ ignoring unreadable idempotency record", "file", filepath.Base(path))
+		return nil, unlock, nil
+	}
+	return &record, unlock, nil
+}
+
+func (k *KVImpl) recordToken(record *idempotencyRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(k.tokenPath(record.Token), data, 0644)
+}
+
+// expireTokens removes idempotency records applied longer than window ago,
+// which can no longer dedupe anything. Records left empty or unreadable, as
+// by a Put that failed, expire by their modification time. Each record is
+// locked and re-checked first, so one a concurrent Put just wrote is kept.
+// It returns how many records and bytes were removed, or would be on a dry
+// run.
+func (k *KVImpl) expireTokens(window time.Duration, now time.Time, dryRun bool) (int64, int64, error) {
+	entries, err := os.ReadDir(k.storageDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, 0, nil
+		}
+		return 0, 0, err
+	}
+	var removed, reclaimed int64
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasPrefix(entry.Name(), kvIdempotencyPrefix) {
+			continue
+		}
+		path := filepath.Join(k.storageDir, entry.Name())
+		expired, size, err := k.removeExpiredToken(path, window, now, dryRun)
+		if err != nil {
+			return removed, reclaimed, fmt.Errorf("failed to expire idempotency record %s: %w", entry.Name(), err)
+		}
+		if expired {
+			removed++
+			reclaimed += size
+		}
+	}
+	return removed, reclaimed, nil
+}
+
+// removeExpiredToken removes the record at path if it is older than window
+// once locked, returning whether it was and its size
+func (k *KVImpl) removeExpiredToken(path string, window time.Duration, now time.Time, dryRun bool) (bool, int64, error) {
+	lock := flock.New(path)
+	if err := lock.Lock(); err != nil {
+		return false, 0, fmt.Errorf("failed to acquire lock: %w", err)
+	}
+	defer func() {
+		if err := lock.Unlock(); err != nil {
+			k.logger.Error("failed to unlock idempotency token", "error", err)
+		}
+	}()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, 0, nil
+		}
+		return false, 0, err
+	}
+	applied := info.ModTime()
+	if data, err := os.ReadFile(path); err == nil {
+		var record idempotencyRecord
+		if json.Unmarshal(data, &record) == nil && record.AppliedMs > 0 {
+			applied = time.UnixMilli(record.AppliedMs)
+		}
+	}
+	if now.Sub(applied) < window {
+		return false, 0, nil
+	}
+	if dryRun {
+		return true, info.Size(), nil
+	}
+	if err := os.Remove(path); err != nil {
+		return false, 0, err
+	}
+	return true, info.Size(), nil
+}
+
+// applyOnce runs apply for a Put with an idempotency token, unless a Put
+// with the same token, key and value was applied within the window. The
+// outcome goes in the kv-idempotency-result trailer. Reusing a token for a
+// different Put is InvalidArgument. Stores that can't record tokens apply
+// every Put.
+func (m *GRPCServer) applyOnce(ctx context.Context, req *proto.PutRequest, apply func() error) error {
+	store, ok := m.Impl.(idempotentStore)
+	if !ok {
+		m.logger.Debug("📡🔁 store can't record idempotency tokens", "key", req.Key)
+		return apply()
+	}
+	record, unlock, err := store.lockToken(req.IdempotencyToken)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	digest := sha256.Sum256(req.Value)
+	current := &idempotencyRecord{
+		Token:     req.IdempotencyToken,
+		Key:       req.Key,
+		SHA256:    hex.EncodeToString(digest[:]),
+		AppliedMs: time.Now().UnixMilli(),
+	}
+	window := m.Options.IdempotencyWindow
+	if window == 0 {
+		window = defaultIdempotencyWindow
+	}
+	if record != nil && time.Since(time.UnixMilli(record.AppliedMs)) < window {
+		if record.Key != current.Key || record.SHA256 != current.SHA256 {
+			return errInvalidField("idempotency_token", req.IdempotencyToken,
+				fmt.Sprintf("was used for a different Put (key %s) within the idempotency window", record.Key))
+		}
+		m.logger.Info("📡🔁 deduplicated Put", "key", req.Key, "applied_at", time.UnixMilli(record.AppliedMs))
+		grpc.SetTrailer(ctx, metadata.Pairs(idempotencyTrailer, idempotencyDeduped))
+		return nil
+	}
+
+	if err := apply(); err != nil {
+		return err
+	}
+	if err := store.recordToken(current); err != nil {
+		// The Put happened; only a retry of it would be applied again
+		m.logger.Error("📡❌ failed to record idempotency token", "key", req.Key, "error", err)
+	}
+	grpc.SetTrailer(ctx, metadata.Pairs(idempotencyTrailer, idempotencyApplied))
+	return nil
+}
+
+// putIdempotent sends a Put carrying token and returns the server's
+// kv-idempotency-result, or "" if the server didn't send one
+func putIdempotent(ctx context.Context, rpcClient plugin.ClientProtocol, key string, value []byte, token string) (string, error) {
+	conn, err := pluginConn(rpcClient)
+	if err != nil {
+		return "", err
+	}
+	var trailer metadata.MD
+	req := &proto.PutRequest{Key: key, Value: value, IdempotencyToken: token}
+	if _, err := proto.NewKVClient(conn).Put(ctx, req, grpc.Trailer(&trailer)); err != nil {
+		return "", err
+	}
+	if result := trailer.Get(idempotencyTrailer); len(result) > 0 {
+		return result[0], nil
+	}
+	return "", nil
+}
+
+// printIdempotencyResult writes a Put's outcome as a
+// {"idempotency": ...} JSON line. A server that predates idempotency
+// tokens sends no result; its Put was applied without deduplication.
+func printIdempotencyResult(key, token, result string) error {
+	if result == "" {
+		logger.Warn("🌐⚠️ server sent no idempotency result; it may not support tokens", "key", key)
+		result = "unknown"
+	}
+	return json.NewEncoder(os.Stdout).Encode(map[string]interface{}{
+		"idempotency": map[string]string{"key": key, "token": token, "result": result},
+	})
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+func tokenFiles(t *testing.T, dir string) int {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), kvIdempotencyPrefix) {
+			n++
+		}
+	}
+	return n
+}
+
+func TestCompactExpiresIdempotencyRecords(t *testing.T) {
+	dir := t.TempDir()
+	impl := NewKVImpl(hclog.NewNullLogger(), dir)
+	if err := impl.Put("k", []byte("v")); err != nil {
+		t.Fatal(err)
+	}
+	hourAgo := time.Now().Add(-time.Hour)
+	for token, applied := range map[string]time.Time{"old": hourAgo, "fresh": time.Now()} {
+		if err := impl.recordToken(&idempotencyRecord{Token: token, Key: "k", AppliedMs: applied.UnixMilli()}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A failed Put leaves its record empty; it expires by modification time
+	_, unlock, err := impl.lockToken("failed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	unlock()
+	if err := os.Chtimes(impl.tokenPath("failed"), hourAgo, hourAgo); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := impl.Compact(0, time.Minute, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.ExpiredTokens != 2 || tokenFiles(t, dir) != 3 {
+		t.Errorf("dry run: got %d expired and %d files, want 2 counted and none removed", report.ExpiredTokens, tokenFiles(t, dir))
+	}
+
+	report, err = impl.Compact(0, time.Minute, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.ExpiredTokens != 2 || report.ReclaimedBytes == 0 {
+		t.Errorf("got %+v, want 2 records expired and their bytes reclaimed", report)
+	}
+	if _, err := os.Stat(impl.tokenPath("fresh")); err != nil || tokenFiles(t, dir) != 1 {
+		t.Errorf("got %d records (%v), want only the fresh one kept", tokenFiles(t, dir), err)
+	}
+	if report.LiveKeys != 1 || len(report.RemovedKeys) != 0 {
+		t.Errorf("got %+v, want the data entry untouched", report)
+	}
+	if _, err := os.Stat(filepath.Join(dir, kvDataPrefix+"k")); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,501 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/go-plugin"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/provide-io/tofusoup/proto/kv"
+)
+
+// Lint check statuses
+const (
+	lintPass = "pass"
+	lintFail = "fail"
+	lintSkip = "skip"
+)
+
+// lintKeyPrefix namespaces the keys lint-server writes
+const lintKeyPrefix = "lint-"
+
+// lintSession is the server under test, shared by the checks
+type lintSession struct {
+	serverCmd []string
+	env       []string
+	timeout   time.Duration
+	logger    hclog.Logger
+
+	client *plugin.Client
+	kv     proto.KVClient
+}
+
+// lintCheck is one item on the server checklist. Checks that need a
+// connection are skipped if connecting failed.
+type lintCheck struct {
+	Name        string
+	Description string
+	Weight      int
+	NeedsConn   bool
+	Run         func(ctx context.Context, s *lintSession) error
+}
+
+// lintResult is the outcome of one lintCheck
+type lintResult struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Status      string  `json:"status"`
+	Weight      int     `json:"weight"`
+	DurationMs  float64 `json:"duration_ms"`
+	Error       string  `json:"error,omitempty"`
+}
+
+// lintReport is the structured output of 'rpc lint-server'. Score is the
+// percentage of check weight that passed; skipped checks count as failed.
+type lintReport struct {
+	Harness   string       `json:"harness"`
+	Version   string       `json:"version"`
+	ServerCmd []string     `json:"server_cmd"`
+	Score     float64      `json:"score"`
+	Points    int          `json:"points"`
+	MaxPoints int          `json:"max_points"`
+	Passed    int          `json:"passed"`
+	Failed    int          `json:"failed"`
+	Skipped   int          `json:"skipped"`
+	Results   []lintResult `json:"results"`
+}
+
+// lintChecks is the checklist, run in order
+var lintChecks = []lintCheck{
+	{"handshake", "First stdout line is a well-formed go-plugin gRPC handshake", 3, false, lintHandshake},
+	{"connect", "A go-plugin client connects with AutoMTLS and dispenses kv_grpc", 3, false, lintConnect},
+	{"put-get", "A value written with Put is returned unchanged by Get", 3, true, lintPutGet},
+	{"not-found", "Get of a missing key fails with NotFound", 2, true, lintNotFound},
+	{"empty-key", "An empty key round-trips or is rejected with InvalidArgument", 1, true, lintEmptyKey},
+	{"large-key", "A 200-byte key round-trips; a 4096-byte key round-trips or is rejected with InvalidArgument", 1, true, lintLargeKey},
+	{"unicode", "Unicode values round-trip; unicode keys round-trip or are rejected with InvalidArgument", 2, true, lintUnicode},
+	{"binary-value", "Values containing every byte 0x00-0xff round-trip", 1, true, lintBinaryValue},
+	{"concurrent-puts", "Concurrent Puts to distinct keys and to one key leave a consistent store", 2, true, lintConcurrentPuts},
+	{"shutdown", "The server exits on a go-plugin controller shutdown and stops listening", 2, true, lintShutdown},
+}
+
+// lintRoundTrip puts value under key and checks Get returns it. A server may
+// instead reject the key with InvalidArgument if allowReject is set.
+func lintRoundTrip(ctx context.Context, s *lintSession, key string, value []byte, allowReject bool) error {
+	if _, err := s.kv.Put(ctx, &proto.PutRequest{Key: key, Value: value}); err != nil {
+		if allowReject && status.Code(err) == codes.InvalidArgument {
+			return nil
+		}
+		return fmt.Errorf("Put %q failed: %w", truncate(key, 40), err)
+	}
+	resp, err := s.kv.Get(ctx, &proto.GetRequest{Key: key})
+	if err != nil {
+		return fmt.Errorf("Put %q succeeded but Get failed: %w", truncate(key, 40), err)
+	}
+	if !bytes.Equal(resp.Value, value) {
+		return fmt.Errorf("Put %q succeeded but Get returned %d bytes, want %d", truncate(key, 40), len(resp.Value), len(value))
+	}
+	return nil
+}
+
+// truncate shortens s for error messages
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
+
+func lintHandshake(ctx context.Context, s *lintSession) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, s.serverCmd[0], s.serverCmd[1:]...)
+	cmd.Env = s.env
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start server: %w", err)
+	}
+	defer func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	}()
+
+	lines := make(chan string, 1)
+	go func() {
+		line, _ := bufio.NewReader(stdout).ReadString('\n')
+		lines <- line
+	}()
+	var line string
+	select {
+	case line = <-lines:
+	case <-ctx.Done():
+		return fmt.Errorf("no handshake on stdout within %s", s.timeout)
+	}
+	return checkHandshakeLine(strings.TrimSpace(line))
+}
+
+// checkHandshakeLine validates CORE|APP|NETWORK|ADDR|PROTOCOL[|CERT]
+func checkHandshakeLine(line string) error {
+	if line == "" {
+		return fmt.Errorf("empty handshake line")
+	}
+	parts := strings.Split(line, "|")
+	if len(parts) < 5 || len(parts) > 6 {
+		return fmt.Errorf("handshake %q has %d fields, want 5 or 6", truncate(line, 80), len(parts))
+	}
+	if parts[0] != strconv.Itoa(plugin.CoreProtocolVersion) {
+		return fmt.Errorf("core protocol version is %q, want %d", parts[0], plugin.CoreProtocolVersion)
+	}
+	if parts[1] != strconv.Itoa(int(Handshake.ProtocolVersion)) {
+		return fmt.Errorf("app protocol version is %q, want %d", parts[1], Handshake.ProtocolVersion)
+	}
+	switch parts[2] {
+	case "tcp":
+		if _, err := net.ResolveTCPAddr("tcp", parts[3]); err != nil {
+			return fmt.Errorf("invalid tcp address %q: %w", parts[3], err)
+		}
+	case "unix":
+		if parts[3] == "" {
+			return fmt.Errorf("empty unix socket path")
+		}
+	default:
+		return fmt.Errorf("network is %q, want tcp or unix", parts[2])
+	}
+	if parts[4] != string(plugin.ProtocolGRPC) {
+		return fmt.Errorf("protocol is %q, want grpc", parts[4])
+	}
+	if len(parts) == 6 && parts[5] != "" {
+		der, err := base64.RawStdEncoding.DecodeString(parts[5])
+		if err != nil {
+			return fmt.Errorf("certificate is not unpadded standard base64: %w", err)
+		}
+		if _, err := x509.ParseCertificate(der); err != nil {
+			return fmt.Errorf("certificate is not valid DER: %w", err)
+		}
+	}
+	return nil
+}
+
+// pluginServerEnv is the environment for a KV plugin server started by
+// this harness, storing data in storageDir
+func pluginServerEnv(storageDir string) []string {
+	return append(os.Environ(),
+		"PLUGIN_MAGIC_COOKIE_KEY="+Handshake.MagicCookieKey,
+		Handshake.MagicCookieKey+"="+Handshake.MagicCookieValue,
+		fmt.Sprintf("PLUGIN_PROTOCOL_VERSIONS=%d", Handshake.ProtocolVersion),
+		"KV_STORAGE_DIR="+storageDir,
+	)
+}
+
+// startPluginKV starts serverCmd as a go-plugin server with AutoMTLS and
+// returns the plugin client and a raw KV client connected to it
+func startPluginKV(serverCmd, env []string, timeout time.Duration, logger hclog.Logger) (*plugin.Client, proto.KVClient, error) {
+	cmd := exec.Command(serverCmd[0], serverCmd[1:]...)
+	cmd.Env = env
+	client := plugin.NewClient(&plugin.ClientConfig{
+		HandshakeConfig: Handshake,
+		Plugins: map[string]plugin.Plugin{
+			"kv_grpc": &KVGRPCPlugin{},
+		},
+		Cmd:              cmd,
+		Logger:           logger,
+		AutoMTLS:         true,
+		StartTimeout:     timeout,
+		AllowedProtocols: []plugin.Protocol{plugin.ProtocolGRPC},
+	})
+	rpcClient, err := client.Client()
+	if err != nil {
+		client.Kill()
+		return nil, nil, fmt.Errorf("failed to connect: %w", err)
+	}
+	if _, err := rpcClient.Dispense("kv_grpc"); err != nil {
+		client.Kill()
+		return nil, nil, fmt.Errorf("failed to dispense kv_grpc: %w", err)
+	}
+	grpcClient, ok := rpcClient.(*plugin.GRPCClient)
+	if !ok {
+		client.Kill()
+		return nil, nil, fmt.Errorf("server negotiated %T, want a gRPC client", rpcClient)
+	}
+	return client, proto.NewKVClient(grpcClient.Conn), nil
+}
+
+func lintConnect(ctx context.Context, s *lintSession) error {
+	client, kv, err := startPluginKV(s.serverCmd, s.env, s.timeout, s.logger)
+	if err != nil {
+		return err
+	}
+	s.client = client
+	s.kv = kv
+	return nil
+}
+
+func lintPutGet(ctx context.Context, s *lintSession) error {
+	return lintRoundTrip(ctx, s, lintKeyPrefix+"put-get", []byte("tofusoup lint value"), false)
+}
+
+func lintNotFound(ctx context.Context, s *lintSession) error {
+	_, err := s.kv.Get(ctx, &proto.GetRequest{Key: lintKeyPrefix + "missing-" + strconv.FormatInt(time.Now().UnixNano(), 36)})
+	if err == nil {
+		return fmt.Errorf("Get of a missing key succeeded")
+	}
+	if code := status.Code(err); code != codes.NotFound {
+		return fmt.Errorf("Get of a missing key failed with %s, want NotFound", code)
+	}
+	return nil
+}
+
+func lintEmptyKey(ctx context.Context, s *lintSession) error {
+	return lintRoundTrip(ctx, s, "", []byte("empty-key"), true)
+}
+
+func lintLargeKey(ctx context.Context, s *lintSession) error {
+	if err := lintRoundTrip(ctx, s, lintKeyPrefix+strings.Repeat("k", 200-len(lintKeyPrefix)), []byte("large-key"), false); err != nil {
+		return err
+	}
+	return lintRoundTrip(ctx, s, lintKeyPrefix+strings.Repeat("K", 4096-len(lintKeyPrefix)), []byte("huge-key"), true)
+}
+
+func lintUnicode(ctx context.Context, s *lintSession) error {
+	value := []byte("ünïcödé 🥣 値")
+	if err := lintRoundTrip(ctx, s, lintKeyPrefix+"unicode-value", value, false); err != nil {
+		return err
+	}
+	return lintRoundTrip(ctx, s, lintKeyPrefix+"ключ-🔑", value, true)
+}
+
+func lintBinaryValue(ctx context.Context, s *lintSession) error {
+	value := make([]byte, 256)
+	for i := range value {
+		value[i] = byte(i)
+	}
+	return lintRoundTrip(ctx, s, lintKeyPrefix+"binary", value, false)
+}
+
+func lintConcurrentPuts(ctx context.Context, s *lintSession) error {
+	const writers = 16
+	errs := make(chan error, 2*writers)
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			key := fmt.Sprintf("%sconcurrent-%02d", lintKeyPrefix, i)
+			if _, err := s.kv.Put(ctx, &proto.PutRequest{Key: key, Value: []byte(key)}); err != nil {
+				errs <- fmt.Errorf("Put %s failed: %w", key, err)
+			}
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			value := []byte(fmt.Sprintf("writer-%02d", i))
+			if _, err := s.kv.Put(ctx, &proto.PutRequest{Key: lintKeyPrefix + "contended", Value: value}); err != nil {
+				errs <- fmt.Errorf("contended Put from writer %d failed: %w", i, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	if err := <-errs; err != nil {
+		return err
+	}
+
+	for i := 0; i < writers; i++ {
+		key := fmt.Sprintf("%sconcurrent-%02d", lintKeyPrefix, i)
+		resp, err := s.kv.Get(ctx, &proto.GetRequest{Key: key})
+		if err != nil {
+			return fmt.Errorf("Get %s failed: %w", key, err)
+		}
+		if string(resp.Value) != key {
+			return fmt.Errorf("Get %s returned %q, want %q", key, truncate(string(resp.Value), 40), key)
+		}
+	}
+	resp, err := s.kv.Get(ctx, &proto.GetRequest{Key: lintKeyPrefix + "contended"})
+	if err != nil {
+		return fmt.Errorf("Get of contended key failed: %w", err)
+	}
+	var n int
+	if _, err := fmt.Sscanf(string(resp.Value), "writer-%02d", &n); err != nil || len(resp.Value) != len("writer-00") || n >= writers {
+		return fmt.Errorf("contended key holds %q, not any one writer's value", truncate(string(resp.Value), 40))
+	}
+	return nil
+}
+
+// lintShutdown asks the server to stop through the go-plugin controller.
+// go-plugin waits 2s for the process to exit before killing it, so taking
+// that long means the server ignored the shutdown.
+func lintShutdown(ctx context.Context, s *lintSession) error {
+	addr := s.client.ReattachConfig().Addr
+	start := time.Now()
+	s.client.Kill()
+	elapsed := time.Since(start)
+	s.client = nil
+	if elapsed >= 2*time.Second {
+		return fmt.Errorf("server didn't exit after controller shutdown and was killed after %s", elapsed.Round(time.Millisecond))
+	}
+	if conn, err := net.DialTimeout(addr.Network(), addr.String(), time.Second); err == nil {
+		conn.Close()
+		return fmt.Errorf("server exited but %s still accepts connections", addr)
+	}
+	return nil
+}
+
+// runLint runs the checklist against serverCmd, using storageDir as the
+// server's KV_STORAGE_DIR
+func runLint(ctx context.Context, logger hclog.Logger, serverCmd []string, storageDir string, timeout time.Duration, only map[string]bool) lintReport {
+	s := &lintSession{
+		serverCmd: serverCmd,
+		env:       pluginServerEnv(storageDir),
+		timeout:   timeout,
+		logger:    logger,
+	}
+	defer func() {
+		if s.client != nil {
+			s.client.Kill()
+		}
+	}()
+
+	report := lintReport{Harness: "soup-go", Version: version, ServerCmd: serverCmd}
+	for _, check := range lintChecks {
+		if len(only) > 0 && !only[check.Name] && check.Name != "connect" {
+			continue
+		}
+		result := lintResult{Name: check.Name, Description: check.Description, Weight: check.Weight}
+		start := time.Now()
+		if check.NeedsConn && s.kv == nil {
+			result.Status = lintSkip
+			result.Error = "not connected"
+		} else {
+			logger.Debug("🔍 running lint check", "check", check.Name)
+			checkCtx, cancel := context.WithTimeout(ctx, timeout)
+			err := check.Run(checkCtx, s)
+			cancel()
+			if err != nil {
+				result.Status = lintFail
+				result.Error = err.Error()
+			} else {
+				result.Status = lintPass
+			}
+		}
+		result.DurationMs = float64(time.Since(start).Microseconds()) / 1000
+
+		report.MaxPoints += check.Weight
+		switch result.Status {
+		case lintPass:
+			report.Passed++
+			report.Points += check.Weight
+		case lintFail:
+			report.Failed++
+		case lintSkip:
+			report.Skipped++
+		}
+		report.Results = append(report.Results, result)
+	}
+	if report.MaxPoints > 0 {
+		report.Score = float64(report.Points*1000/report.MaxPoints) / 10
+	}
+	return report
+}
+
+func initRPCLintServerCmd() *cobra.Command {
+	var serverCmd string
+	var checks []string
+	var timeout time.Duration
+	var minScore float64
+	var outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   "lint-server",
+		Short: "Score a KV server implementation against the protocol checklist",
+		Long: `Run the protocol conformance checklist against an arbitrary KV plugin
+server, started with --server-cmd the way a go-plugin client would start it.
+The checklist covers the handshake line, NotFound status codes, empty,
+large and unicode keys, binary values, concurrent Puts and shutdown through
+the go-plugin controller. Each check has a weight and the report gives the
+percentage of weight that passed.
+
+Keys that a server can't represent may be rejected with InvalidArgument
+instead of round-tripping; any other error fails the check. The server runs
+with KV_STORAGE_DIR set to a fresh temporary directory.
+
+Exits non-zero if the score is below --min-score, so new language harnesses
+can be gated on it.`,
+		Example: `  soup-go rpc lint-server --server-cmd "soup rpc kv server --tls-mode auto"
+  soup-go rpc lint-server --server-cmd "./my-kv-server" --output-format json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			argv := strings.Fields(serverCmd)
+			if len(argv) == 0 {
+				return fmt.Errorf("--server-cmd is required")
+			}
+			only := map[string]bool{}
+			for _, name := range checks {
+				found := false
+				for _, check := range lintChecks {
+					found = found || check.Name == name
+				}
+				if !found {
+					return fmt.Errorf("unknown check %q", name)
+				}
+				only[name] = true
+			}
+
+			storageDir, err := os.MkdirTemp("", "soup-lint-")
+			if err != nil {
+				return fmt.Errorf("failed to create storage dir: %w", err)
+			}
+			defer os.RemoveAll(storageDir)
+
+			report := runLint(cmd.Context(), logger.Named("lint"), argv, storageDir, timeout, only)
+
+			if outputFormat == "json" {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				if err := encoder.Encode(report); err != nil {
+					return fmt.Errorf("failed to encode JSON: %w", err)
+				}
+			} else {
+				for _, r := range report.Results {
+					icon := map[string]string{lintPass: "✅", lintFail: "❌", lintSkip: "➖"}[r.Status]
+					fmt.Printf("%s %-16s %dpt  %s\n", icon, r.Name, r.Weight, r.Description)
+					if r.Error != "" {
+						fmt.Printf("   %s\n", r.Error)
+					}
+				}
+				fmt.Printf("\nScore: %.1f%% (%d/%d points; %d passed, %d failed, %d skipped)\n",
+					report.Score, report.Points, report.MaxPoints, report.Passed, report.Failed, report.Skipped)
+			}
+
+			if report.Score < minScore {
+				return fmt.Errorf("score %.1f%% is below --min-score %.1f%%", report.Score, minScore)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&serverCmd, "server-cmd", "", "Command that starts the server in plugin mode (split on whitespace)")
+	cmd.Flags().StringSliceVar(&checks, "check", nil, "Only run these checks (connect always runs)")
+	cmd.Flags().DurationVar(&timeout, "timeout", 10*time.Second, "Timeout for starting the server and for each check")
+	cmd.Flags().Float64Var(&minScore, "min-score", 100, "Minimum passing score in percent")
+	cmd.Flags().StringVar(&outputFormat, "output-format", "text", "Output format (text, json)")
+	return cmd
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+)
+
+// runLintServer runs 'rpc lint-server' against this test binary started as
+// 'soup-go rpc kv server' with serverArgs
+func runLintServer(t *testing.T, serverArgs string, args ...string) (*lintReport, error) {
+	t.Helper()
+	t.Setenv(testMainEnv, "1")
+	cmd := initRPCLintServerCmd()
+	cmd.SetArgs(append([]string{"--server-cmd", os.Args[0] + " rpc kv server " + serverArgs, "--output-format", "json"}, args...))
+	cmd.SilenceUsage, cmd.SilenceErrors = true, true
+	out, err := captureStdout(t, cmd.Execute)
+	var report lintReport
+	if jerr := json.Unmarshal(out, &report); jerr != nil {
+		t.Fatalf("%v (command error %v): %s", jerr, err, out)
+	}
+	return &report, err
+}
+
+func lintStatuses(report *lintReport) string {
+	var statuses []string
+	for _, result := range report.Results {
+		statuses = append(statuses, result.Name+"="+result.Status)
+	}
+	return strings.Join(statuses, ",")
+}
+
+func TestLintServerScoresConformingServer(t *testing.T) {
+	report, err := runLintServer(t, "")
+	if err != nil {
+		t.Fatalf("%v: %s", err, lintStatuses(report))
+	}
+	if report.Score != 100 || report.Passed != len(lintChecks) || report.Points != report.MaxPoints {
+		t.Errorf("got score %.1f (%s), want every check passed", report.Score, lintStatuses(report))
+	}
+}
+
+func TestLintServerScoresReadOnlyServer(t *testing.T) {
+	report, err := runLintServer(t, "--readonly", "--check", "put-get,not-found")
+	if err == nil || !strings.Contains(err.Error(), "is below --min-score 100.0%") {
+		t.Errorf("got %v, want the score gated by --min-score", err)
+	}
+	if got := lintStatuses(report); got != "connect=pass,put-get=fail,not-found=pass" {
+		t.Errorf("got %s, want only Puts failing", got)
+	}
+	// connect 3 + not-found 2 of connect 3 + put-get 3 + not-found 2
+	if report.Points != 5 || report.MaxPoints != 8 || report.Score != 62.5 {
+		t.Errorf("got %d/%d points, score %.1f", report.Points, report.MaxPoints, report.Score)
+	}
+
+	if _, err := runLintServer(t, "--readonly", "--check", "not-found", "--min-score", "60"); err != nil {
+		t.Errorf("got %v, want a score over --min-score to pass", err)
+	}
+}
+
+func TestLintServerRejectsBadFlags(t *testing.T) {
+	for _, args := range [][]string{
+		{},
+		{"--server-cmd", "soup", "--check", "handshake,teardown"},
+	} {
+		cmd := initRPCLintServerCmd()
+		cmd.SetArgs(args)
+		cmd.SilenceUsage, cmd.SilenceErrors = true, true
+		if _, err := captureStdout(t, cmd.Execute); err == nil {
+			t.Errorf("%v: got no error", args)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/provide-io/tofusoup/proto/kv"
+)
+
+// listPageToken is the decoded form of a List page token. Tokens are bound to
+// the prefix they were issued for and resume after the last key returned.
+type listPageToken struct {
+	Prefix string `json:"p"`
+	After  string `json:"a"`
+}
+
+// encodeListPageToken returns an opaque token resuming after key
+func encodeListPageToken(prefix, after string) string {
+	data, _ := json.Marshal(listPageToken{Prefix: prefix, After: after})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeListPageToken parses a token issued by encodeListPageToken
+func decodeListPageToken(token string) (*listPageToken, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("malformed page token")
+	}
+	var decoded listPageToken
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, fmt.Errorf("malformed page token")
+	}
+	return &decoded, nil
+}
+
+func (m *GRPCServer) List(ctx context.Context, req *proto.ListRequest) (*proto.ListResponse, error) {
+	m.logger.Debug("📡📋 handling List request",
+		"prefix", req.Prefix,
+		"page_size", req.PageSize,
+		"has_token", req.PageToken != "")
+	m.observeDeadline(ctx, "List")
+	if err := m.injectFault(ctx, "List"); err != nil {
+		return nil, err
+	}
+
+	if err := m.throttle("List"); err != nil {
+		return nil, err
+	}
+
+	pageSize := int(req.PageSize)
+	switch {
+	case pageSize < 0:
+		countRequest("List", "rejected")
+		return nil, errInvalidField("page_size", fmt.Sprint(req.PageSize), "page_size must not be negative")
+	case pageSize == 0:
+		pageSize = DefaultListPageSize
+	case pageSize > MaxListPageSize:
+		pageSize = MaxListPageSize
+	}
+
+	after := ""
+	if req.PageToken != "" {
+		token, err := decodeListPageToken(req.PageToken)
+		if err != nil {
+			countRequest("List", "rejected")
+			return nil, errInvalidField("page_token", req.PageToken, err.Error())
+		}
+		if token.Prefix != req.Prefix {
+			countRequest("List", "rejected")
+			return nil, errInvalidField("page_token", req.PageToken, "page token was issued for a different prefix")
+		}
+		after = token.After
+	}
+
+	keys, err := m.Impl.List(req.Prefix)
+	if err != nil {
+		countRequest("List", "failed")
+		m.logger.Error("📡❌ List operation failed", "prefix", req.Prefix, "error", err)
+		return nil, err
+	}
+
+	// Resume after the last key of the previous page. Keys deleted or added
+	// between pages don't shift the position, so pages never overlap.
+	start := 0
+	if req.PageToken != "" {
+		start = sort.Search(len(keys), func(i int) bool { return keys[i] > after })
+	}
+	end := start + pageSize
+	if end > len(keys) {
+		end = len(keys)
+	}
+
+	resp := &proto.ListResponse{Keys: keys[start:end]}
+	if end < len(keys) {
+		resp.NextPageToken = encodeListPageToken(req.Prefix, keys[end-1])
+	}
+	countRequest("List", "ok")
+
+	m.logger.Debug("📡✅ List operation completed successfully",
+		"prefix", req.Prefix,
+		"returned", len(resp.Keys),
+		"more", resp.NextPageToken != "")
+	return resp, nil
+}
+
+// List returns every key under prefix, walking all pages
+func (m *GRPCClient) List(prefix string) ([]string, error) {
+	keys, _, err := m.ListPages(prefix, 0)
+	return keys, err
+}
+
+// ListPages walks all List pages of up to pageSize keys, verifying the
+// pagination contract as it goes: pages respect the requested size, keys
+// ascend strictly across page boundaries, and tokens are never reused.
+// It returns the keys and the number of pages fetched.
+func (m *GRPCClient) ListPages(prefix string, pageSize int) ([]string, int, error) {
+	keys := []string{}
+	seenTokens := map[string]bool{}
+	token := ""
+	pages := 0
+
+	for {
+		resp, err := m.client.List(context.Background(), &proto.ListRequest{
+			Prefix:    prefix,
+			PageSize:  int32(pageSize),
+			PageToken: token,
+		})
+		if err != nil {
+			m.logger.Error("🌐❌ List request failed", "prefix", prefix, "page", pages+1, "error", err)
+			return keys, pages, err
+		}
+		pages++
+
+		if pageSize > 0 && len(resp.Keys) > pageSize {
+			return keys, pages, fmt.Errorf("page %d has %d keys, more than page_size %d", pages, len(resp.Keys), pageSize)
+		}
+		for _, key := range resp.Keys {
+			if !strings.HasPrefix(key, prefix) {
+				return keys, pages, fmt.Errorf("page %d returned key %q outside prefix %q", pages, key, prefix)
+			}
+			if len(keys) > 0 && key <= keys[len(keys)-1] {
+				return keys, pages, fmt.Errorf("page %d returned key %q out of order after %q", pages, key, keys[len(keys)-1])
+			}
+			keys = append(keys, key)
+		}
+
+		if resp.NextPageToken == "" {
+			break
+		}
+		if len(resp.Keys) == 0 {
+			return keys, pages, fmt.Errorf("page %d is empty but has a next page token", pages)
+		}
+		if seenTokens[resp.NextPageToken] {
+			return keys, pages, fmt.Errorf("page %d repeated a page token", pages)
+		}
+		seenTokens[resp.NextPageToken] = true
+		token = resp.NextPageToken
+	}
+
+	m.logger.Debug("🌐✅ List completed successfully", "prefix", prefix, "keys", len(keys), "pages", pages)
+	return keys, pages, nil
+}
+
+func initKVListCmd() *cobra.Command {
+	var address string
+	var tlsCurve string
+	var pageSize int
+
+	cmd := &cobra.Command{
+		Use:   "list [prefix]",
+		Short: "List keys in the RPC KV server, walking all pages",
+		Long: `List keys in the RPC KV server. All pages are fetched and the pagination
+contract is verified along the way (page sizes, ordering across pages and
+token reuse). Prints a JSON object with the keys and the number of pages.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			prefix := ""
+			if len(args) > 0 {
+				prefix = args[0]
+			}
+
+			client, raw, err := dispensePlugin(address, tlsCurve, "kv_grpc")
+			if err != nil {
+				return err
+			}
+			defer client.Kill()
+
+			grpcClient, ok := raw.(*GRPCClient)
+			if !ok {
+				return fmt.Errorf("unexpected KV client type %T", raw)
+			}
+
+			keys, pages, err := grpcClient.ListPages(prefix, pageSize)
+			if err != nil {
+				printStatusJSON(err)
+				return fmt.Errorf("failed to list keys: %w", err)
+			}
+
+			return json.NewEncoder(os.Stdout).Encode(map[string]interface{}{
+				"prefix": prefix,
+				"keys":   keys,
+				"count":  len(keys),
+				"pages":  pages,
+			})
+		},
+	}
+
+	cmd.Flags().StringVar(&address, "address", "", "Address of existing server (e.g., 127.0.0.1:50051)")
+	cmd.Flags().StringVar(&tlsCurve, "tls-curve", "auto", "Client cert curve: auto (detect from server), secp256r1, secp384r1, secp521r1")
+	cmd.Flags().IntVar(&pageSize, "page-size", 0, "Keys per page (0 uses the server default)")
+	return cmd
+}
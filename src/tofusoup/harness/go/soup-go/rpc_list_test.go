@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/provide-io/tofusoup/proto/kv"
+)
+
+func runKVList(t *testing.T, address string, args ...string) map[string]interface{} {
+	t.Helper()
+	cmd := initKVListCmd()
+	cmd.SetArgs(append([]string{"--address", address}, args...))
+	out, err := captureStdout(t, cmd.Execute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result map[string]interface{}
+	if err := json.Unmarshal(out, &result); err != nil {
+		t.Fatalf("%v: %s", err, out)
+	}
+	return result
+}
+
+func TestKVListCmdPages(t *testing.T) {
+	server, address := serveTestKV(t, KVServerOptions{})
+	for _, key := range []string{"app-3", "app-1", "app-5", "other", "app-2", "app-4"} {
+		if err := server.Impl.Put(key, []byte("v")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		args  []string
+		keys  string
+		pages float64
+	}{
+		{args: []string{"app-", "--page-size", "2"}, keys: "app-1,app-2,app-3,app-4,app-5", pages: 3},
+		{args: []string{"app-", "--page-size", "5"}, keys: "app-1,app-2,app-3,app-4,app-5", pages: 1},
+		{args: []string{"app-"}, keys: "app-1,app-2,app-3,app-4,app-5", pages: 1},
+		{args: []string{"--page-size", "4"}, keys: "app-1,app-2,app-3,app-4,app-5,other", pages: 2},
+		{args: []string{"none-"}, keys: "", pages: 1},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			result := runKVList(t, address, tt.args...)
+			var keys []string
+			for _, key := range result["keys"].([]interface{}) {
+				keys = append(keys, key.(string))
+			}
+			if got := strings.Join(keys, ","); got != tt.keys {
+				t.Errorf("got keys %s, want %s", got, tt.keys)
+			}
+			if result["pages"] != tt.pages || result["count"] != float64(len(keys)) {
+				t.Errorf("got %v pages and count %v, want %v pages", result["pages"], result["count"], tt.pages)
+			}
+		})
+	}
+}
+
+func TestKVListPagesSurviveDeletes(t *testing.T) {
+	server, _ := serveTestKV(t, KVServerOptions{})
+	for i := 0; i < 6; i++ {
+		if err := server.Impl.Put(fmt.Sprintf("k%d", i), []byte("v")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx := context.Background()
+
+	first, err := server.List(ctx, &proto.ListRequest{PageSize: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Removing a key already returned mustn't repeat or skip later keys
+	if err := server.Impl.(*KVImpl).Delete("k1"); err != nil {
+		t.Fatal(err)
+	}
+	second, err := server.List(ctx, &proto.ListRequest{PageSize: 3, PageToken: first.NextPageToken})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(second.Keys, ","); got != "k3,k4,k5" || second.NextPageToken != "" {
+		t.Errorf("got second page %s (token %q), want k3,k4,k5 and no token", got, second.NextPageToken)
+	}
+}
+
+func TestKVListRejectsBadRequests(t *testing.T) {
+	server, _ := serveTestKV(t, KVServerOptions{})
+	for i := 0; i < 3; i++ {
+		if err := server.Impl.Put(fmt.Sprintf("a%d", i), []byte("v")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx := context.Background()
+	first, err := server.List(ctx, &proto.ListRequest{Prefix: "a", PageSize: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, req := range map[string]*proto.ListRequest{
+		"negative page size":       {PageSize: -1},
+		"malformed token":          {PageToken: "%%%"},
+		"token for another prefix": {Prefix: "b", PageToken: first.NextPageToken},
+	} {
+		_, err := server.List(ctx, req)
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("%s: got %v, want InvalidArgument", name, err)
+		}
+	}
+}
@@ -0,0 +1,386 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"gopkg.in/yaml.v3"
+
+	"github.com/provide-io/tofusoup/proto/kv"
+)
+
+// mockEnv supplies --mock to servers a client spawns
+const mockEnv = "KV_MOCK"
+
+// What a mock server does with a call no rule matches
+const (
+	// mockFallbackUnimplemented fails the call with Unimplemented
+	mockFallbackUnimplemented = "unimplemented"
+	// mockFallbackStore serves the call from storage like a normal server
+	mockFallbackStore = "store"
+)
+
+// mockFile is the YAML document given to rpc kv server --mock
+type mockFile struct {
+	Fallback  string     `yaml:"fallback"`
+	Responses []mockRule `yaml:"responses"`
+}
+
+// mockRule scripts the responses to calls of one method, optionally for one
+// key (the prefix, for List)
+type mockRule struct {
+	Method string  `yaml:"method"`
+	Key    *string `yaml:"key"`
+	// Sequence is played in order, one response per call; a rule may
+	// instead give a single response inline
+	Sequence     []mockResponse `yaml:"sequence"`
+	mockResponse `yaml:",inline"`
+	// Cycle restarts the sequence once it's played; otherwise its last
+	// response repeats
+	Cycle bool `yaml:"cycle"`
+
+	calls int
+}
+
+// mockResponse is one scripted response
+type mockResponse struct {
+	Value       *string    `yaml:"value"`
+	ValueBase64 *string    `yaml:"value_base64"`
+	Keys        []string   `yaml:"keys"`
+	Error       *mockError `yaml:"error"`
+	Delay       string     `yaml:"delay"`
+	// Times repeats this response before moving on (default 1)
+	Times int `yaml:"times"`
+
+	delay time.Duration
+	value []byte
+}
+
+// mockError is a scripted gRPC error
+type mockError struct {
+	Code    string `yaml:"code"`
+	Message string `yaml:"message"`
+	// Reason adds an ErrorInfo detail in the tofusoup.kv domain
+	Reason string `yaml:"reason"`
+	// RetryAfter adds a RetryInfo detail
+	RetryAfter string `yaml:"retry_after"`
+
+	code       codes.Code
+	retryAfter time.Duration
+}
+
+// kvMock serves KV calls from scripted responses
+type kvMock struct {
+	path     string
+	fallback string
+	rules    []*mockRule
+	mu       sync.Mutex
+}
+
+// grpcCodesByName maps lower-cased code names without underscores
+// ("notfound", "unavailable") to codes
+var grpcCodesByName = func() map[string]codes.Code {
+	byName := make(map[string]codes.Code)
+	for c := codes.OK; c <= codes.Unauthenticated; c++ {
+		byName[strings.ToLower(c.String())] = c
+	}
+	return byName
+}()
+
+// mockPath returns the responses file from flag if set, or else KV_MOCK
+func mockPath(flag string) string {
+	if flag != "" {
+		return flag
+	}
+	return os.Getenv(mockEnv)
+}
+
+// loadKVMock reads and validates a mock responses file
+func loadKVMock(path string) (*kvMock, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mock responses: %w", err)
+	}
+	var file mockFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse mock responses %s: %w", path, err)
+	}
+
+	mock := &kvMock{path: path, fallback: file.Fallback}
+	if mock.fallback == "" {
+		mock.fallback = mockFallbackUnimplemented
+	}
+	if mock.fallback != mockFallbackUnimplemented && mock.fallback != mockFallbackStore {
+		return nil, fmt.Errorf("unknown fallback %q (expected %s or %s)", mock.fallback, mockFallbackUnimplemented, mockFallbackStore)
+	}
+	for i := range file.Responses {
+		rule := &file.Responses[i]
+		if err := rule.validate(); err != nil {
+			return nil, fmt.Errorf("responses[%d]: %w", i, err)
+		}
+		mock.rules = append(mock.rules, rule)
+	}
+	return mock, nil
+}
+
+func (r *mockRule) validate() error {
+	switch r.Method {
+	case "Get", "Put", "List":
+	default:
+		return fmt.Errorf("unknown method %q (expected Get, Put or List)", r.Method)
+	}
+	if len(r.Sequence) == 0 {
+		r.Sequence = []mockResponse{r.mockResponse}
+	} else if !r.mockResponse.empty() {
+		return fmt.Errorf("give either sequence or a single response, not both")
+	}
+	for i := range r.Sequence {
+		if err := r.Sequence[i].validate(r.Method); err != nil {
+			return fmt.Errorf("sequence[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func (resp *mockResponse) empty() bool {
+	return resp.Value == nil && resp.ValueBase64 == nil && resp.Keys == nil && resp.Error == nil && resp.Delay == "" && resp.Times == 0
+}
+
+func (resp *mockResponse) validate(method string) error {
+	if resp.Delay != "" {
+		d, err := time.ParseDuration(resp.Delay)
+		if err != nil || d < 0 {
+			return fmt.Errorf("invalid delay %q", resp.Delay)
+		}
+		resp.delay = d
+	}
+	if resp.Times < 0 {
+		return fmt.Errorf("times must not be negative")
+	}
+	if resp.Times == 0 {
+		resp.Times = 1
+	}
+	if resp.Value != nil && resp.ValueBase64 != nil {
+		return fmt.Errorf("give either value or value_base64, not both")
+	}
+	if resp.Value != nil {
+		resp.value = []byte(*resp.Value)
+	}
+	if resp.ValueBase64 != nil {
+		value, err := base64.StdEncoding.DecodeString(*resp.ValueBase64)
+		if err != nil {
+			return fmt.Errorf("invalid value_base64: %w", err)
+		}
+		resp.value = value
+	}
+	if resp.Error != nil {
+		return resp.Error.validate()
+	}
+	switch {
+	case method == "Get" && resp.value == nil:
+		return fmt.Errorf("a Get response needs a value, value_base64 or error")
+	case method == "List" && resp.value != nil:
+		return fmt.Errorf("a List response has keys, not a value")
+	case method != "List" && resp.Keys != nil:
+		return fmt.Errorf("only List responses have keys")
+	}
+	return nil
+}
+
+func (e *mockError) validate() error {
+	code, ok := grpcCodesByName[strings.ToLower(strings.ReplaceAll(e.Code, "_", ""))]
+	if !ok || code == codes.OK {
+		return fmt.Errorf("unknown error code %q", e.Code)
+	}
+	e.code = code
+	if e.RetryAfter != "" {
+		d, err := time.ParseDuration(e.RetryAfter)
+		if err != nil {
+			return fmt.Errorf("invalid retry_after %q", e.RetryAfter)
+		}
+		e.retryAfter = d
+	}
+	if e.Message == "" {
+		e.Message = "mock " + code.String()
+	}
+	return nil
+}
+
+// err is the status the scripted error stands for
+func (e *mockError) err() error {
+	st := status.New(e.code, e.Message)
+	var details []protoadapt.MessageV1
+	if e.Reason != "" {
+		details = append(details, &errdetails.ErrorInfo{Reason: e.Reason, Domain: errorDomain})
+	}
+	if e.RetryAfter != "" {
+		details = append(details, &errdetails.RetryInfo{RetryDelay: durationpb.New(e.retryAfter)})
+	}
+	if len(details) == 0 {
+		return st.Err()
+	}
+	return withDetails(st, details...)
+}
+
+// next picks the response for a call of method with key, advancing the
+// matching rule's sequence; nil if no rule matches
+func (m *kvMock) next(method, key string) *mockResponse {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, rule := range m.rules {
+		if rule.Method != method || (rule.Key != nil && *rule.Key != key) {
+			continue
+		}
+		return rule.advance()
+	}
+	return nil
+}
+
+// advance returns the response for the rule's next call
+func (r *mockRule) advance() *mockResponse {
+	total := 0
+	for _, resp := range r.Sequence {
+		total += resp.Times
+	}
+	n := r.calls
+	r.calls++
+	if n >= total {
+		if !r.Cycle {
+			return &r.Sequence[len(r.Sequence)-1]
+		}
+		n %= total
+	}
+	for i := range r.Sequence {
+		if n < r.Sequence[i].Times {
+			return &r.Sequence[i]
+		}
+		n -= r.Sequence[i].Times
+	}
+	return &r.Sequence[len(r.Sequence)-1]
+}
+
+// mockKVServer answers KV calls from a kvMock, passing calls no rule
+// matches to fallback (nil fails them with Unimplemented)
+type mockKVServer struct {
+	proto.UnimplementedKVServer
+	mock     *kvMock
+	fallback proto.KVServer
+	logger   hclog.Logger
+}
+
+// newMockKVServer serves mock, falling back to server if the mock says so
+func newMockKVServer(mock *kvMock, server proto.KVServer, logger hclog.Logger) *mockKVServer {
+	s := &mockKVServer{mock: mock, logger: logger.Named("mock")}
+	if mock.fallback == mockFallbackStore {
+		s.fallback = server
+	}
+	logger.Info("🎭 serving scripted KV responses", "file", mock.path, "rules", len(mock.rules), "fallback", mock.fallback)
+	return s
+}
+
+// kvService is the KV service to register: server itself, or a mock in
+// front of it when opts.Mock is set
+func kvService(server *GRPCServer, opts KVServerOptions, logger hclog.Logger) proto.KVServer {
+	if opts.Mock == nil {
+		return server
+	}
+	return newMockKVServer(opts.Mock, server, logger)
+}
+
+// respond waits out the response's delay and returns its error, if any
+func (s *mockKVServer) respond(ctx context.Context, method, key string, resp *mockResponse) error {
+	s.logger.Debug("🎭 scripted response", "method", method, "key", key, "delay", resp.delay, "error", resp.Error != nil)
+	if resp.delay > 0 {
+		timer := time.NewTimer(resp.delay)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case <-timer.C:
+		}
+	}
+	if resp.Error != nil {
+		return resp.Error.err()
+	}
+	return nil
+}
+
+// unmatched handles a call no rule matches
+func (s *mockKVServer) unmatched(method, key string) error {
+	s.logger.Debug("🎭 no scripted response", "method", method, "key", key)
+	return status.Errorf(codes.Unimplemented, "mock has no response for %s %q", method, key)
+}
+
+func (s *mockKVServer) Get(ctx context.Context, req *proto.GetRequest) (*proto.GetResponse, error) {
+	resp := s.mock.next("Get", req.Key)
+	if resp == nil {
+		if s.fallback != nil {
+			return s.fallback.Get(ctx, req)
+		}
+		return nil, s.unmatched("Get", req.Key)
+	}
+	if err := s.respond(ctx, "Get", req.Key, resp); err != nil {
+		return nil, err
+	}
+	return &proto.GetResponse{Value: resp.value}, nil
+}
+
+func (s *mockKVServer) GetStream(req *proto.GetStreamRequest, stream proto.KV_GetStreamServer) error {
+	if s.fallback != nil {
+		return s.fallback.GetStream(req, stream)
+	}
+	return s.unmatched("GetStream", req.Key)
+}
+
+func (s *mockKVServer) Put(ctx context.Context, req *proto.PutRequest) (*proto.Empty, error) {
+	resp := s.mock.next("Put", req.Key)
+	if resp == nil {
+		if s.fallback != nil {
+			return s.fallback.Put(ctx, req)
+		}
+		return nil, s.unmatched("Put", req.Key)
+	}
+	if err := s.respond(ctx, "Put", req.Key, resp); err != nil {
+		return nil, err
+	}
+	return &proto.Empty{}, nil
+}
+
+func (s *mockKVServer) List(ctx context.Context, req *proto.ListRequest) (*proto.ListResponse, error) {
+	resp := s.mock.next("List", req.Prefix)
+	if resp == nil {
+		if s.fallback != nil {
+			return s.fallback.List(ctx, req)
+		}
+		return nil, s.unmatched("List", req.Prefix)
+	}
+	if err := s.respond(ctx, "List", req.Prefix, resp); err != nil {
+		return nil, err
+	}
+	return &proto.ListResponse{Keys: resp.Keys}, nil
+}
+
+func (s *mockKVServer) Compact(ctx context.Context, req *proto.CompactRequest) (*proto.CompactResponse, error) {
+	if s.fallback != nil {
+		return s.fallback.Compact(ctx, req)
+	}
+	return nil, s.unmatched("Compact", "")
+}
+
+func (s *mockKVServer) Stat(ctx context.Context, req *proto.StatRequest) (*proto.StatResponse, error) {
+	if s.fallback != nil {
+		return s.fallback.Stat(ctx, req)
+	}
+	return nil, s.unmatched("Stat", req.Prefix)
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/hashicorp/go-plugin"
+)
+
+// rpcNegotiation makes RPC client commands print what each plugin client
+// negotiated as a {"negotiation": ...} line when they finish
+var rpcNegotiation bool
+
+// Ways a client reaches its server
+const (
+	negotiationSpawned  = "spawned"
+	negotiationReattach = "reattach"
+)
+
+// negotiationReport is what a plugin client ended up with after connecting
+type negotiationReport struct {
+	Mode string `json:"mode"`
+	// Started is false when the client never reached a server; the fields
+	// below are then unset
+	Started bool `json:"started"`
+	// PluginVersion is the negotiated application protocol version
+	PluginVersion int `json:"plugin_version,omitempty"`
+	// Protocol is grpc or netrpc
+	Protocol string `json:"protocol,omitempty"`
+	Network  string `json:"network,omitempty"`
+	Address  string `json:"address,omitempty"`
+	// AutoMTLS is true when go-plugin's automatic mTLS was negotiated, i.e.
+	// the server answered the client certificate with its own
+	AutoMTLS bool `json:"auto_mtls"`
+	TLS      bool `json:"tls"`
+	// ServerPID is only known for servers the client spawned
+	ServerPID int `json:"server_pid,omitempty"`
+}
+
+// negotiatedClient is a plugin client a command created, with the
+// configuration go-plugin doesn't report back
+type negotiatedClient struct {
+	client *plugin.Client
+	config *plugin.ClientConfig
+	mode   string
+	// tls is set for reattach clients given a server certificate, whose TLS
+	// goes through dial options rather than the client config
+	tls bool
+}
+
+// negotiatedClients are the clients to report, in the order they were made
+var negotiatedClients struct {
+	sync.Mutex
+	clients []negotiatedClient
+}
+
+func trackNegotiation(client *plugin.Client, config *plugin.ClientConfig, mode string, tls bool) {
+	negotiatedClients.Lock()
+	defer negotiatedClients.Unlock()
+	negotiatedClients.clients = append(negotiatedClients.clients, negotiatedClient{client: client, config: config, mode: mode, tls: tls})
+}
+
+func (n negotiatedClient) report() negotiationReport {
+	report := negotiationReport{Mode: n.mode}
+	// The reattach config outlives Kill, so this works after the command
+	// has closed its client
+	reattach := n.client.ReattachConfig()
+	if reattach == nil {
+		return report
+	}
+	report.Started = true
+	report.PluginVersion = n.client.NegotiatedVersion()
+	if report.PluginVersion == 0 {
+		// go-plugin only records a reattached version for test reattaches;
+		// otherwise it's the one the address or handshake line gave
+		report.PluginVersion = reattach.ProtocolVersion
+	}
+	report.Protocol = string(reattach.Protocol)
+	if reattach.Addr != nil {
+		report.Network, report.Address = reattach.Addr.Network(), reattach.Addr.String()
+	}
+	report.ServerPID = reattach.Pid
+	// go-plugin only loads a server certificate into RootCAs when the
+	// handshake carried one
+	report.AutoMTLS = n.config.AutoMTLS && n.config.TLSConfig != nil && n.config.TLSConfig.RootCAs != nil
+	report.TLS = n.tls || n.config.TLSConfig != nil
+	return report
+}
+
+// printNegotiation writes one {"negotiation": ...} line per plugin client
+// to stdout after the command's own output when --negotiation is set
+func printNegotiation() {
+	if !rpcNegotiation {
+		return
+	}
+	negotiatedClients.Lock()
+	defer negotiatedClients.Unlock()
+	encoder := json.NewEncoder(os.Stdout)
+	for _, n := range negotiatedClients.clients {
+		if err := encoder.Encode(map[string]interface{}{"negotiation": n.report()}); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to encode negotiation: %v\n", err)
+		}
+	}
+}
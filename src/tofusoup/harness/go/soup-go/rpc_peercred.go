@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/go-hclog"
+	"google.golang.org/grpc/peer"
+
+	"github.com/provide-io/tofusoup/proto/echo"
+)
+
+// unixScheme prefixes a unix socket path given as --address
+const unixScheme = "unix://"
+
+// defaultSocketMode is what --socket-mode leaves a standalone socket at
+const defaultSocketMode = "0600"
+
+// errPeerCredsUnsupported is returned where the platform has no SO_PEERCRED
+var errPeerCredsUnsupported = errors.New("peer credentials are not supported on this platform")
+
+// UnixSocketOptions configures a standalone server listening on --socket
+type UnixSocketOptions struct {
+	Path string
+	Mode os.FileMode
+	// AllowedUIDs, when not empty, closes connections from any other UID
+	AllowedUIDs []uint
+	// LogPeers logs the credentials of every accepted connection
+	LogPeers bool
+}
+
+// parseSocketMode parses an octal --socket-mode such as 0600 or 660
+func parseSocketMode(s string) (os.FileMode, error) {
+	mode, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || mode > 0o777 {
+		return 0, fmt.Errorf("invalid socket mode %q: want octal permission bits such as 0600", s)
+	}
+	return os.FileMode(mode), nil
+}
+
+// formatSocketMode renders permission bits the way --socket-mode takes them
+func formatSocketMode(mode os.FileMode) string {
+	return fmt.Sprintf("%04o", mode.Perm())
+}
+
+// peerCreds are the kernel-reported credentials of a unix socket peer
+type peerCreds struct {
+	UID uint32
+	GID uint32
+	PID int32
+}
+
+// peerCredAddr is the remote address of an accepted unix connection. It
+// carries the peer's credentials so handlers can read them from the gRPC peer.
+type peerCredAddr struct {
+	net.Addr
+	creds *peerCreds
+}
+
+// peerCredConn reports a peerCredAddr as its remote address
+type peerCredConn struct {
+	net.Conn
+	addr peerCredAddr
+}
+
+func (c *peerCredConn) RemoteAddr() net.Addr { return c.addr }
+
+// peerCredListener reads SO_PEERCRED on each accepted connection, logs it if
+// asked, and closes connections from UIDs that aren't allowed.
+type peerCredListener struct {
+	net.Listener
+	logger  hclog.Logger
+	allowed map[uint32]bool
+	logPeer bool
+}
+
+func (l *peerCredListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+
+		creds, err := readPeerCreds(conn)
+		if err != nil {
+			if len(l.allowed) > 0 {
+				// Enforcement can't pass a peer it can't identify
+				l.logger.Warn("🔒🚫 rejected connection, peer credentials unavailable", "error", err)
+				conn.Close()
+				continue
+			}
+			l.logger.Debug("🔒 peer credentials unavailable", "error", err)
+		} else {
+			if l.logPeer {
+				l.logger.Info("🔒 accepted connection", "uid", creds.UID, "gid", creds.GID, "pid", creds.PID)
+			}
+			if len(l.allowed) > 0 && !l.allowed[creds.UID] {
+				l.logger.Warn("🔒🚫 rejected connection from disallowed uid", "uid", creds.UID, "gid", creds.GID, "pid", creds.PID)
+				conn.Close()
+				continue
+			}
+		}
+
+		remote := conn.RemoteAddr()
+		if remote == nil {
+			// Accepted unix connections from unbound clients have no address
+			remote = &net.UnixAddr{Net: "unix"}
+		}
+		return &peerCredConn{Conn: conn, addr: peerCredAddr{Addr: remote, creds: creds}}, nil
+	}
+}
+
+// listenUnixSocket listens on opts.Path, sets its permissions and wraps the
+// listener to check peer credentials.
+func listenUnixSocket(logger hclog.Logger, opts UnixSocketOptions) (net.Listener, error) {
+	listener, err := net.Listen("unix", opts.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", opts.Path, err)
+	}
+	if err := os.Chmod(opts.Path, opts.Mode); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to set mode of %s: %w", opts.Path, err)
+	}
+
+	allowed := make(map[uint32]bool, len(opts.AllowedUIDs))
+	for _, uid := range opts.AllowedUIDs {
+		allowed[uint32(uid)] = true
+	}
+	logger.Info("🔒 unix socket ready",
+		"path", opts.Path,
+		"mode", formatSocketMode(opts.Mode),
+		"allowed_uids", opts.AllowedUIDs,
+		"log_peers", opts.LogPeers)
+	return &peerCredListener{Listener: listener, logger: logger, allowed: allowed, logPeer: opts.LogPeers}, nil
+}
+
+// peerCredentials returns the caller's credentials for an RPC. They are only
+// available when the server listens through a peerCredListener.
+func peerCredentials(ctx context.Context) *echo.PeerCredentials {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return &echo.PeerCredentials{}
+	}
+	addr, ok := p.Addr.(peerCredAddr)
+	if !ok || addr.creds == nil {
+		return &echo.PeerCredentials{}
+	}
+	return &echo.PeerCredentials{
+		Available: true,
+		Uid:       addr.creds.UID,
+		Gid:       addr.creds.GID,
+		Pid:       addr.creds.PID,
+	}
+}
+
+// serverInfo describes the listener an RPC arrived on
+func serverInfo(ctx context.Context) *echo.ServerInfo {
+	info := &echo.ServerInfo{
+		Pid:  int32(os.Getpid()),
+		Uid:  uint32(os.Getuid()),
+		Peer: peerCredentials(ctx),
+	}
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.LocalAddr == nil {
+		return info
+	}
+	info.Transport = p.LocalAddr.Network()
+	info.Address = p.LocalAddr.String()
+	if info.Transport == "unix" {
+		if stat, err := os.Stat(info.Address); err == nil {
+			info.SocketMode = formatSocketMode(stat.Mode())
+		}
+	}
+	return info
+}
+
+// isUnixAddress reports whether address names a unix socket
+func isUnixAddress(address string) bool {
+	return strings.HasPrefix(address, unixScheme)
+}
@@ -0,0 +1,694 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/url"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+	"google.golang.org/grpc/codes"
+)
+
+// Frame capture proxy
+//
+// Interop failures below the protobuf layer, such as a peer that ignores
+// flow-control windows, mishandles GOAWAY or races RST_STREAM against a
+// response, never show up in a --transcript, which sees decoded calls.
+// rpc proxy sits between a client and a plaintext server, forwards bytes
+// unchanged, and parses the HTTP/2 frames going each way: every frame is
+// logged with its timestamp, direction, flags and the fields that matter
+// for those bugs, and the frames are also folded back into the RPCs they
+// carried. --pcap records the same bytes for Wireshark.
+
+// proxyDirections names the two directions, indexed by the sending side
+var proxyDirections = [2]string{"c2s", "s2c"}
+
+// proxyHeader is one decoded header field
+type proxyHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// proxyFrame is one HTTP/2 frame as seen by the proxy, or a note about a
+// direction whose frames couldn't be parsed
+type proxyFrame struct {
+	// AtMs is when the frame was parsed, from the start of proxying
+	AtMs      float64  `json:"at_ms"`
+	Conn      int      `json:"conn"`
+	Direction string   `json:"direction"`
+	Type      string   `json:"type,omitempty"`
+	Flags     []string `json:"flags,omitempty"`
+	Stream    uint32   `json:"stream"`
+	Length    uint32   `json:"length"`
+	// Headers are decoded when a header block ends, on its HEADERS or last
+	// CONTINUATION frame; secret values are redacted
+	Headers         []proxyHeader     `json:"headers,omitempty"`
+	Settings        map[string]uint32 `json:"settings,omitempty"`
+	WindowIncrement uint32            `json:"window_increment,omitempty"`
+	Ping            string            `json:"ping,omitempty"`
+	LastStream      *uint32           `json:"last_stream,omitempty"`
+	ErrorCode       string            `json:"error_code,omitempty"`
+	Debug           string            `json:"debug,omitempty"`
+	Error           string            `json:"error,omitempty"`
+}
+
+// proxyRPC is a call reassembled from its stream's frames
+type proxyRPC struct {
+	Conn          int     `json:"conn"`
+	Stream        uint32  `json:"stream"`
+	Method        string  `json:"method"`
+	AtMs          float64 `json:"at_ms"`
+	DurationMs    float64 `json:"duration_ms"`
+	RequestBytes  int64   `json:"request_bytes"`
+	ResponseBytes int64   `json:"response_bytes"`
+	GRPCStatus    *int    `json:"grpc_status,omitempty"`
+	GRPCCode      string  `json:"grpc_code,omitempty"`
+	GRPCMessage   string  `json:"grpc_message,omitempty"`
+	// Reset is the RST_STREAM error code and who sent it, e.g. "c2s CANCEL"
+	Reset string `json:"reset,omitempty"`
+	// Complete is set once the server ended the stream or either side
+	// reset it
+	Complete bool `json:"complete"`
+}
+
+// proxyConnReport is one proxied connection
+type proxyConnReport struct {
+	ID       int     `json:"id"`
+	Client   string  `json:"client"`
+	OpenedMs float64 `json:"opened_ms"`
+	ClosedMs float64 `json:"closed_ms"`
+	// TLS is set when the connection carried TLS, whose frames can't be
+	// decoded
+	TLS      bool   `json:"tls"`
+	Frames   int    `json:"frames"`
+	BytesC2S int64  `json:"bytes_c2s"`
+	BytesS2C int64  `json:"bytes_s2c"`
+	Error    string `json:"error,omitempty"`
+}
+
+// proxyReport is what rpc proxy saw
+type proxyReport struct {
+	Listen      string            `json:"listen"`
+	Target      string            `json:"target"`
+	DurationMs  float64           `json:"duration_ms"`
+	Connections []proxyConnReport `json:"connections"`
+	Frames      int               `json:"frames"`
+	FrameTypes  map[string]int    `json:"frame_types"`
+	RPCs        []proxyRPC        `json:"rpcs"`
+	GoAways     []proxyFrame      `json:"goaways"`
+	Resets      []proxyFrame      `json:"resets"`
+	FrameLog    string            `json:"frame_log,omitempty"`
+	Pcap        string            `json:"pcap,omitempty"`
+}
+
+// rpcProxy forwards connections to a target and records their frames
+type rpcProxy struct {
+	Address string
+
+	target   string
+	listener net.Listener
+	start    time.Time
+	frameLog io.Writer
+	pcap     *pcapWriter
+	wg       sync.WaitGroup
+
+	mu         sync.Mutex
+	conns      []*proxyConn
+	frames     int
+	frameTypes map[string]int
+	rpcs       []*proxyRPC
+	goaways    []proxyFrame
+	resets     []proxyFrame
+
+	stopOnce sync.Once
+	report   *proxyReport
+}
+
+// proxyConn is the state of one proxied connection. Index 0 of its
+// per-direction fields is client to server.
+type proxyConn struct {
+	id     int
+	client net.Conn
+	server net.Conn
+	flow   *pcapFlow
+	report proxyConnReport
+
+	decoders    [2]*hpack.Decoder
+	block       [2][]byte
+	blockStream [2]uint32
+	blockEnd    [2]bool
+	rpcs        map[uint32]*proxyRPC
+}
+
+// proxyFlagNames names the flags each frame type defines
+var proxyFlagNames = map[http2.FrameType][]struct {
+	flag http2.Flags
+	name string
+}{
+	http2.FrameData:         {{http2.FlagDataEndStream, "END_STREAM"}, {http2.FlagDataPadded, "PADDED"}},
+	http2.FrameHeaders:      {{http2.FlagHeadersEndStream, "END_STREAM"}, {http2.FlagHeadersEndHeaders, "END_HEADERS"}, {http2.FlagHeadersPadded, "PADDED"}, {http2.FlagHeadersPriority, "PRIORITY"}},
+	http2.FrameSettings:     {{http2.FlagSettingsAck, "ACK"}},
+	http2.FramePing:         {{http2.FlagPingAck, "ACK"}},
+	http2.FrameContinuation: {{http2.FlagContinuationEndHeaders, "END_HEADERS"}},
+	http2.FramePushPromise:  {{http2.FlagPushPromiseEndHeaders, "END_HEADERS"}, {http2.FlagPushPromisePadded, "PADDED"}},
+}
+
+// splitProxyAddress splits a unix:// address from a TCP one
+func splitProxyAddress(address string) (network, addr string) {
+	if path, ok := strings.CutPrefix(address, "unix://"); ok {
+		return "unix", path
+	}
+	return "tcp", address
+}
+
+// startRPCProxy listens on listen and forwards each connection to target
+func startRPCProxy(listen, target string, frameLog io.Writer, pcap *pcapWriter) (*rpcProxy, error) {
+	network, addr := splitProxyAddress(listen)
+	listener, err := net.Listen(network, addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", listen, err)
+	}
+	p := &rpcProxy{
+		Address:    listener.Addr().String(),
+		target:     target,
+		listener:   listener,
+		start:      time.Now(),
+		frameLog:   frameLog,
+		pcap:       pcap,
+		frameTypes: map[string]int{},
+	}
+	if network == "unix" {
+		p.Address = "unix://" + p.Address
+	}
+	p.wg.Add(1)
+	go p.accept()
+	logger.Info("🔀 proxying", "listen", p.Address, "target", target)
+	return p, nil
+}
+
+func (p *rpcProxy) accept() {
+	defer p.wg.Done()
+	for id := 1; ; id++ {
+		client, err := p.listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				logger.Error("proxy stopped accepting", "error", err)
+			}
+			return
+		}
+		c := &proxyConn{
+			id:       id,
+			client:   client,
+			decoders: [2]*hpack.Decoder{hpack.NewDecoder(4096, nil), hpack.NewDecoder(4096, nil)},
+			rpcs:     map[uint32]*proxyRPC{},
+			report: proxyConnReport{
+				ID:       id,
+				Client:   client.RemoteAddr().String(),
+				OpenedMs: sinceMs(p.start),
+			},
+		}
+		p.mu.Lock()
+		p.conns = append(p.conns, c)
+		p.mu.Unlock()
+		p.wg.Add(1)
+		go p.handle(c)
+	}
+}
+
+// handle dials the target for c and forwards both ways until both sides
+// have closed
+func (p *rpcProxy) handle(c *proxyConn) {
+	defer p.wg.Done()
+	network, addr := splitProxyAddress(p.target)
+	server, err := net.DialTimeout(network, addr, 10*time.Second)
+	if err != nil {
+		logger.Error("proxy failed to reach target", "conn", c.id, "target", p.target, "error", err)
+		p.mu.Lock()
+		c.report.Error = err.Error()
+		c.report.ClosedMs = sinceMs(p.start)
+		p.mu.Unlock()
+		c.client.Close()
+		return
+	}
+	p.mu.Lock()
+	c.server = server
+	p.mu.Unlock()
+	if p.pcap != nil {
+		c.flow = p.pcap.open(
+			pcapEndpointOf(c.client.RemoteAddr(), uint16(40000+c.id%20000)),
+			pcapEndpointOf(p.listener.Addr(), 50051),
+		)
+	}
+	logger.Debug("proxy connection opened", "conn", c.id, "client", c.report.Client)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() { defer wg.Done(); p.forward(c, 0, c.client, server) }()
+	go func() { defer wg.Done(); p.forward(c, 1, server, c.client) }()
+	wg.Wait()
+	c.client.Close()
+	server.Close()
+
+	p.mu.Lock()
+	c.report.ClosedMs = sinceMs(p.start)
+	p.mu.Unlock()
+	logger.Debug("proxy connection closed", "conn", c.id)
+}
+
+// forward copies src to dst unchanged, feeding the same bytes to the
+// frame parser and the pcap flow, then half-closes dst
+func (p *rpcProxy) forward(c *proxyConn, dir int, src, dst net.Conn) {
+	pr, pw := io.Pipe()
+	parsed := make(chan struct{})
+	go func() {
+		p.parse(c, dir, pr)
+		io.Copy(io.Discard, pr)
+		close(parsed)
+	}()
+
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := src.Read(buf)
+		if n > 0 {
+			if _, werr := dst.Write(buf[:n]); werr != nil {
+				break
+			}
+			p.mu.Lock()
+			if dir == 0 {
+				c.report.BytesC2S += int64(n)
+			} else {
+				c.report.BytesS2C += int64(n)
+			}
+			p.mu.Unlock()
+			if c.flow != nil {
+				c.flow.data(dir, buf[:n])
+			}
+			pw.Write(buf[:n])
+		}
+		if err != nil {
+			break
+		}
+	}
+	pw.Close()
+	<-parsed
+	if c.flow != nil {
+		c.flow.fin(dir)
+	}
+	if closer, ok := dst.(interface{ CloseWrite() error }); ok {
+		closer.CloseWrite()
+	} else {
+		dst.Close()
+	}
+}
+
+// parse reads the frames one direction of c carries. It returns at the
+// end of the stream or on the first thing that isn't valid HTTP/2; the
+// caller drains what is left.
+func (p *rpcProxy) parse(c *proxyConn, dir int, r io.Reader) {
+	br := bufio.NewReader(r)
+	first, err := br.Peek(1)
+	if err != nil {
+		return
+	}
+	// A TLS handshake record
+	if first[0] == 0x16 {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		c.report.TLS = true
+		p.note(c, dir, "TLS: frames are encrypted and not decoded")
+		return
+	}
+	if dir == 0 {
+		preface := make([]byte, len(http2.ClientPreface))
+		if _, err := io.ReadFull(br, preface); err != nil || string(preface) != http2.ClientPreface {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			p.note(c, dir, "connection does not start with the HTTP/2 client preface")
+			return
+		}
+	}
+
+	framer := http2.NewFramer(io.Discard, br)
+	framer.SetMaxReadFrameSize(1<<24 - 1)
+	for {
+		f, err := framer.ReadFrame()
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				p.mu.Lock()
+				p.note(c, dir, err.Error())
+				p.mu.Unlock()
+			}
+			return
+		}
+		p.frame(c, dir, f)
+	}
+}
+
+// note records something about a direction that isn't a frame; the
+// caller holds p.mu
+func (p *rpcProxy) note(c *proxyConn, dir int, message string) {
+	if c.report.Error == "" {
+		c.report.Error = proxyDirections[dir] + ": " + message
+	}
+	p.log(proxyFrame{AtMs: sinceMs(p.start), Conn: c.id, Direction: proxyDirections[dir], Error: message})
+}
+
+// frame records one frame and applies it to the RPC on its stream
+func (p *rpcProxy) frame(c *proxyConn, dir int, f http2.Frame) {
+	h := f.Header()
+	rec := proxyFrame{
+		AtMs:      sinceMs(p.start),
+		Conn:      c.id,
+		Direction: proxyDirections[dir],
+		Type:      h.Type.String(),
+		Stream:    h.StreamID,
+		Length:    h.Length,
+	}
+	for _, flag := range proxyFlagNames[h.Type] {
+		if h.Flags.Has(flag.flag) {
+			rec.Flags = append(rec.Flags, flag.name)
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	rpc := c.rpcs[h.StreamID]
+	switch f := f.(type) {
+	case *http2.DataFrame:
+		if rpc != nil {
+			if dir == 0 {
+				rpc.RequestBytes += int64(len(f.Data()))
+			} else {
+				rpc.ResponseBytes += int64(len(f.Data()))
+				if f.StreamEnded() {
+					p.finish(rpc)
+				}
+			}
+		}
+	case *http2.HeadersFrame:
+		c.block[dir] = append(c.block[dir][:0], f.HeaderBlockFragment()...)
+		c.blockStream[dir], c.blockEnd[dir] = h.StreamID, f.StreamEnded()
+		if f.HeadersEnded() {
+			p.headers(c, dir, &rec)
+		}
+	case *http2.ContinuationFrame:
+		c.block[dir] = append(c.block[dir], f.HeaderBlockFragment()...)
+		if f.HeadersEnded() {
+			p.headers(c, dir, &rec)
+		}
+	case *http2.SettingsFrame:
+		f.ForeachSetting(func(s http2.Setting) error {
+			if rec.Settings == nil {
+				rec.Settings = map[string]uint32{}
+			}
+			rec.Settings[s.ID.String()] = s.Val
+			// The peer's table size bounds what this side's encoder uses
+			if s.ID == http2.SettingHeaderTableSize {
+				c.decoders[1-dir].SetAllowedMaxDynamicTableSize(s.Val)
+			}
+			return nil
+		})
+	case *http2.WindowUpdateFrame:
+		rec.WindowIncrement = f.Increment
+	case *http2.PingFrame:
+		rec.Ping = hex.EncodeToString(f.Data[:])
+	case *http2.GoAwayFrame:
+		last := f.LastStreamID
+		rec.LastStream = &last
+		rec.ErrorCode = f.ErrCode.String()
+		rec.Debug = string(f.DebugData())
+		p.goaways = append(p.goaways, rec)
+	case *http2.RSTStreamFrame:
+		rec.ErrorCode = f.ErrCode.String()
+		p.resets = append(p.resets, rec)
+		// A reset after the server's trailers only releases the stream
+		if rpc != nil && !rpc.Complete {
+			rpc.Reset = proxyDirections[dir] + " " + rec.ErrorCode
+			p.finish(rpc)
+		}
+	}
+
+	c.report.Frames++
+	p.frames++
+	p.frameTypes[rec.Type]++
+	p.log(rec)
+}
+
+// headers decodes the header block that ended with rec's frame into rec,
+// starting an RPC on a request's :path and filling in its status from the
+// response's trailers. The caller holds p.mu.
+func (p *rpcProxy) headers(c *proxyConn, dir int, rec *proxyFrame) {
+	stream, end := c.blockStream[dir], c.blockEnd[dir]
+	fields, err := c.decoders[dir].DecodeFull(c.block[dir])
+	c.block[dir] = c.block[dir][:0]
+	if err != nil {
+		rec.Error = "hpack: " + err.Error()
+		return
+	}
+	rpc := c.rpcs[stream]
+	for _, field := range fields {
+		value := field.Value
+		if secretMetadataKey(field.Name) {
+			value = redactedValue
+		}
+		rec.Headers = append(rec.Headers, proxyHeader{Name: field.Name, Value: value})
+
+		switch {
+		case dir == 0 && field.Name == ":path" && rpc == nil:
+			rpc = &proxyRPC{Conn: c.id, Stream: stream, Method: field.Value, AtMs: rec.AtMs}
+			c.rpcs[stream] = rpc
+			p.rpcs = append(p.rpcs, rpc)
+		case dir == 1 && rpc != nil && field.Name == "grpc-status":
+			if code, err := strconv.Atoi(field.Value); err == nil {
+				rpc.GRPCStatus = &code
+				rpc.GRPCCode = codes.Code(code).String()
+			}
+		case dir == 1 && rpc != nil && field.Name == "grpc-message":
+			rpc.GRPCMessage = field.Value
+			if unescaped, err := url.PathUnescape(field.Value); err == nil {
+				rpc.GRPCMessage = unescaped
+			}
+		}
+	}
+	if dir == 1 && end && rpc != nil {
+		p.finish(rpc)
+	}
+}
+
+// finish marks rpc complete; the caller holds p.mu
+func (p *rpcProxy) finish(rpc *proxyRPC) {
+	if !rpc.Complete {
+		rpc.Complete = true
+		rpc.DurationMs = math.Round((sinceMs(p.start)-rpc.AtMs)*1000) / 1000
+	}
+}
+
+// log writes rec to the frame log; the caller holds p.mu
+func (p *rpcProxy) log(rec proxyFrame) {
+	if p.frameLog == nil {
+		return
+	}
+	if line, err := json.Marshal(rec); err == nil {
+		p.frameLog.Write(append(line, '\n'))
+	}
+}
+
+// stop closes the listener and every open connection and returns the
+// report. Later calls return the same report.
+func (p *rpcProxy) stop() *proxyReport {
+	p.stopOnce.Do(func() {
+		p.listener.Close()
+		p.mu.Lock()
+		for _, c := range p.conns {
+			c.client.Close()
+			if c.server != nil {
+				c.server.Close()
+			}
+		}
+		p.mu.Unlock()
+		p.wg.Wait()
+		if p.pcap != nil {
+			if err := p.pcap.close(); err != nil {
+				logger.Error("failed to write pcap file", "error", err)
+			}
+		}
+
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		report := &proxyReport{
+			Listen:      p.Address,
+			Target:      p.target,
+			DurationMs:  sinceMs(p.start),
+			Connections: []proxyConnReport{},
+			Frames:      p.frames,
+			FrameTypes:  p.frameTypes,
+			RPCs:        []proxyRPC{},
+			GoAways:     append([]proxyFrame{}, p.goaways...),
+			Resets:      append([]proxyFrame{}, p.resets...),
+		}
+		for _, c := range p.conns {
+			report.Connections = append(report.Connections, c.report)
+		}
+		for _, rpc := range p.rpcs {
+			report.RPCs = append(report.RPCs, *rpc)
+		}
+		p.report = report
+	})
+	return p.report
+}
+
+// proxySummary is a one-line account of what the proxy saw
+func proxySummary(r *proxyReport) string {
+	types := make([]string, 0, len(r.FrameTypes))
+	for name, n := range r.FrameTypes {
+		types = append(types, fmt.Sprintf("%s %d", name, n))
+	}
+	sort.Strings(types)
+	summary := fmt.Sprintf("Proxy: %d connections, %d RPCs, %d frames", len(r.Connections), len(r.RPCs), r.Frames)
+	if len(types) > 0 {
+		summary += " (" + strings.Join(types, ", ") + ")"
+	}
+	return summary + fmt.Sprintf(", %d GOAWAY, %d RST_STREAM", len(r.GoAways), len(r.Resets))
+}
+
+func initRPCProxyCmd() *cobra.Command {
+	var listen string
+	var target string
+	var frameLogPath string
+	var pcapPath string
+	var addressFile string
+	var duration time.Duration
+	var outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   "proxy",
+		Short: "Forward RPCs to a server, capturing their HTTP/2 frames",
+		Long: `Listen on --listen and forward each connection to --target unchanged,
+recording the HTTP/2 frames that pass in each direction, until interrupted or
+--duration has passed. Point a client at the proxy's address with --address.
+
+--frame-log writes every frame as an NDJSON line as it is parsed: its time,
+connection, direction (c2s or s2c), type, flags, stream and length, plus
+decoded headers (secret values redacted), SETTINGS values, WINDOW_UPDATE
+increments, PING data, and the last stream, error code and debug data of
+GOAWAY and RST_STREAM. --pcap writes the forwarded bytes as a libpcap file
+of synthetic IPv4/TCP flows between each client and the proxy's port, for
+Wireshark's HTTP/2 dissector.
+
+The report printed on exit has the connections, a count of each frame type,
+the RPCs reassembled from the frames with their method, sizes, duration,
+grpc-status and any reset, and every GOAWAY and RST_STREAM. Frames are
+decoded only for plaintext servers (--tls-mode disabled); TLS connections
+are forwarded and captured, but marked and not decoded.
+
+The proxy's address is printed on startup with text output, and written to
+--address-file if given; with --output-format json, stdout holds only the
+report.`,
+		Example: `  soup-go rpc kv server --standalone --port 50051 &
+  soup-go rpc proxy --target 127.0.0.1:50051 --listen 127.0.0.1:50052 --frame-log frames.ndjson --pcap kv.pcap &
+  soup-go rpc kv put greeting hello --address 127.0.0.1:50052`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if target == "" {
+				return fmt.Errorf("--target is required")
+			}
+			if outputFormat != "text" && outputFormat != "json" {
+				return fmt.Errorf("unsupported output format: %s", outputFormat)
+			}
+			var frameLog io.Writer
+			if frameLogPath != "" {
+				f, err := os.Create(frameLogPath)
+				if err != nil {
+					return fmt.Errorf("failed to create frame log: %w", err)
+				}
+				defer f.Close()
+				frameLog = f
+			}
+			var pcap *pcapWriter
+			if pcapPath != "" {
+				var err error
+				if pcap, err = newPcapWriter(pcapPath); err != nil {
+					return err
+				}
+			}
+
+			proxy, err := startRPCProxy(listen, target, frameLog, pcap)
+			if err != nil {
+				if pcap != nil {
+					pcap.close()
+				}
+				return err
+			}
+			if addressFile != "" {
+				if err := os.WriteFile(addressFile, []byte(proxy.Address+"\n"), 0644); err != nil {
+					proxy.stop()
+					return fmt.Errorf("failed to write --address-file: %w", err)
+				}
+			}
+			if outputFormat == "text" {
+				fmt.Printf("Proxying %s to %s\n", proxy.Address, target)
+			}
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			if duration > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, duration)
+				defer cancel()
+			}
+			<-ctx.Done()
+
+			report := proxy.stop()
+			report.FrameLog, report.Pcap = frameLogPath, pcapPath
+			if outputFormat == "json" {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				if err := encoder.Encode(report); err != nil {
+					return fmt.Errorf("failed to encode JSON: %w", err)
+				}
+				return nil
+			}
+			for _, rpc := range report.RPCs {
+				status := "incomplete"
+				if rpc.GRPCStatus != nil {
+					status = rpc.GRPCCode
+				}
+				if rpc.Reset != "" {
+					status = "reset " + rpc.Reset
+				}
+				fmt.Printf("  conn %d stream %d %s %s, %d bytes out, %d bytes in, %.1fms\n",
+					rpc.Conn, rpc.Stream, rpc.Method, status, rpc.RequestBytes, rpc.ResponseBytes, rpc.DurationMs)
+			}
+			for _, g := range report.GoAways {
+				fmt.Printf("  conn %d %s GOAWAY last stream %d %s %s\n", g.Conn, g.Direction, *g.LastStream, g.ErrorCode, g.Debug)
+			}
+			fmt.Println(proxySummary(report))
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&listen, "listen", "127.0.0.1:0", "Address to listen on: host:port (:0 picks a free port) or unix://path")
+	cmd.Flags().StringVar(&target, "target", "", "Server to forward to: host:port or unix://path")
+	cmd.Flags().StringVar(&frameLogPath, "frame-log", "", "Write each HTTP/2 frame to this file as NDJSON as it is parsed")
+	cmd.Flags().StringVar(&pcapPath, "pcap", "", "Write the forwarded bytes to this file as a libpcap capture")
+	cmd.Flags().StringVar(&addressFile, "address-file", "", "Write the proxy's address to this file once listening")
+	cmd.Flags().DurationVar(&duration, "duration", 0, "Stop proxying after this long (0 proxies until interrupted)")
+	cmd.Flags().StringVar(&outputFormat, "output-format", "text", "Output format (text, json)")
+	return cmd
+}
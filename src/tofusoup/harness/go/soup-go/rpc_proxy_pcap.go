@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// pcap capture
+//
+// rpc proxy --pcap writes the bytes it forwards as a libpcap file of raw
+// IPv4 packets, so Wireshark's HTTP/2 dissector (Decode As... HTTP2 on the
+// proxy's port) can show the conversation. Each proxied connection becomes
+// one synthetic TCP flow between the client's address and the proxy's:
+// a three-way handshake when it opens, one segment per read with real
+// sequence numbers, and a FIN from each side as it closes.
+
+const (
+	pcapMagic     = 0xa1b2c3d4
+	pcapSnapLen   = 65535
+	linkTypeRaw   = 101
+	pcapMaxData   = pcapSnapLen - 40
+	tcpFlagFIN    = 0x01
+	tcpFlagSYN    = 0x02
+	tcpFlagPSH    = 0x08
+	tcpFlagACK    = 0x10
+	ipProtocolTCP = 6
+)
+
+// pcapWriter writes packets to a pcap file; it is safe for concurrent use
+type pcapWriter struct {
+	mu   sync.Mutex
+	file *os.File
+	w    *bufio.Writer
+	err  error
+}
+
+// pcapEndpoint is one end of a synthetic flow
+type pcapEndpoint struct {
+	ip   [4]byte
+	port uint16
+}
+
+// pcapFlow is one proxied connection's TCP flow; index 0 is the client
+type pcapFlow struct {
+	p    *pcapWriter
+	ends [2]pcapEndpoint
+	seq  [2]uint32
+}
+
+func newPcapWriter(path string) (*pcapWriter, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pcap file: %w", err)
+	}
+	p := &pcapWriter{file: f, w: bufio.NewWriter(f)}
+	header := make([]byte, 24)
+	binary.LittleEndian.PutUint32(header[0:], pcapMagic)
+	binary.LittleEndian.PutUint16(header[4:], 2)
+	binary.LittleEndian.PutUint16(header[6:], 4)
+	binary.LittleEndian.PutUint32(header[16:], pcapSnapLen)
+	binary.LittleEndian.PutUint32(header[20:], linkTypeRaw)
+	if _, err := p.w.Write(header); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to write pcap header: %w", err)
+	}
+	return p, nil
+}
+
+// close flushes and closes the file, returning the first write error
+func (p *pcapWriter) close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.w.Flush(); err != nil && p.err == nil {
+		p.err = err
+	}
+	if err := p.file.Close(); err != nil && p.err == nil {
+		p.err = err
+	}
+	return p.err
+}
+
+// pcapEndpointOf is addr as a pcap endpoint. Addresses that aren't IPv4,
+// such as unix sockets, are shown as loopback with fallbackPort.
+func pcapEndpointOf(addr net.Addr, fallbackPort uint16) pcapEndpoint {
+	end := pcapEndpoint{ip: [4]byte{127, 0, 0, 1}, port: fallbackPort}
+	if tcp, ok := addr.(*net.TCPAddr); ok {
+		if ip4 := tcp.IP.To4(); ip4 != nil {
+			copy(end.ip[:], ip4)
+		}
+		end.port = uint16(tcp.Port)
+	}
+	return end
+}
+
+// open starts a flow from client to server with a three-way handshake
+func (p *pcapWriter) open(client, server pcapEndpoint) *pcapFlow {
+	flow := &pcapFlow{p: p, ends: [2]pcapEndpoint{client, server}}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	flow.segment(now, 0, tcpFlagSYN, nil)
+	flow.seq[0]++
+	flow.segment(now, 1, tcpFlagSYN|tcpFlagACK, nil)
+	flow.seq[1]++
+	flow.segment(now, 0, tcpFlagACK, nil)
+	return flow
+}
+
+// data records payload sent by side (0 client, 1 server)
+func (f *pcapFlow) data(side int, payload []byte) {
+	f.p.mu.Lock()
+	defer f.p.mu.Unlock()
+	now := time.Now()
+	for len(payload) > 0 {
+		n := min(len(payload), pcapMaxData)
+		f.segment(now, side, tcpFlagPSH|tcpFlagACK, payload[:n])
+		f.seq[side] += uint32(n)
+		payload = payload[n:]
+	}
+}
+
+// fin records side closing its half of the connection
+func (f *pcapFlow) fin(side int) {
+	f.p.mu.Lock()
+	defer f.p.mu.Unlock()
+	f.segment(time.Now(), side, tcpFlagFIN|tcpFlagACK, nil)
+	f.seq[side]++
+}
+
+// segment writes one IPv4/TCP packet; the caller holds the writer's lock
+func (f *pcapFlow) segment(at time.Time, side int, flags byte, payload []byte) {
+	p := f.p
+	if p.err != nil {
+		return
+	}
+	src, dst := f.ends[side], f.ends[1-side]
+	packet := make([]byte, 40+len(payload))
+
+	ip := packet[:20]
+	ip[0] = 0x45
+	binary.BigEndian.PutUint16(ip[2:], uint16(len(packet)))
+	ip[6] = 0x40 // don't fragment
+	ip[8] = 64
+	ip[9] = ipProtocolTCP
+	copy(ip[12:16], src.ip[:])
+	copy(ip[16:20], dst.ip[:])
+	binary.BigEndian.PutUint16(ip[10:], inetChecksum(ip))
+
+	tcp := packet[20:]
+	binary.BigEndian.PutUint16(tcp[0:], src.port)
+	binary.BigEndian.PutUint16(tcp[2:], dst.port)
+	binary.BigEndian.PutUint32(tcp[4:], f.seq[side])
+	if flags&tcpFlagACK != 0 {
+		binary.BigEndian.PutUint32(tcp[8:], f.seq[1-side])
+	}
+	tcp[12] = 5 << 4
+	tcp[13] = flags
+	binary.BigEndian.PutUint16(tcp[14:], 65535)
+	copy(tcp[20:], payload)
+	pseudo := make([]byte, 12, 12+len(tcp))
+	copy(pseudo[0:4], src.ip[:])
+	copy(pseudo[4:8], dst.ip[:])
+	pseudo[9] = ipProtocolTCP
+	binary.BigEndian.PutUint16(pseudo[10:], uint16(len(tcp)))
+	binary.BigEndian.PutUint16(tcp[16:], inetChecksum(append(pseudo, tcp...)))
+
+	record := make([]byte, 16)
+	binary.LittleEndian.PutUint32(record[0:], uint32(at.Unix()))
+	binary.LittleEndian.PutUint32(record[4:], uint32(at.Nanosecond()/1000))
+	binary.LittleEndian.PutUint32(record[8:], uint32(len(packet)))
+	binary.LittleEndian.PutUint32(record[12:], uint32(len(packet)))
+	if _, err := p.w.Write(record); err != nil {
+		p.err = err
+		return
+	}
+	if _, err := p.w.Write(packet); err != nil {
+		p.err = err
+	}
+}
+
+// inetChecksum is the Internet checksum (RFC 1071) of b
+func inetChecksum(b []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(b); i += 2 {
+		sum += uint32(b[i])<<8 | uint32(b[i+1])
+	}
+	if len(b)%2 == 1 {
+		sum += uint32(b[len(b)-1]) << 8
+	}
+	for sum > 0xffff {
+		sum = sum>>16 + sum&0xffff
+	}
+	return ^uint16(sum)
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"container/list"
+	"expvar"
+	"fmt"
+	"sync"
+
+	"github.com/hashicorp/go-hclog"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Eviction policies for a bounded store
+const (
+	// evictLRU makes room for a write by deleting the least recently used keys
+	evictLRU = "lru"
+	// evictReject refuses writes that would exceed a limit
+	evictReject = "reject"
+)
+
+// deleter is implemented by stores that can remove keys, which LRU eviction
+// needs
+type deleter interface {
+	Delete(key string) error
+}
+
+// quotaEntry is a stored key and the size of its value
+type quotaEntry struct {
+	key  string
+	size int64
+}
+
+// storageQuota enforces --max-keys and --max-bytes on a store. It tracks
+// every key's value size in least-recently-used order (Get and Put both
+// count as a use); sizes are value bytes, not file sizes on disk.
+type storageQuota struct {
+	mu       sync.Mutex
+	maxKeys  int
+	maxBytes int64
+	policy   string
+	logger   hclog.Logger
+
+	order   *list.List // of *quotaEntry, most recently used at the front
+	entries map[string]*list.Element
+	bytes   int64
+}
+
+// newStorageQuota returns a quota for impl seeded with the keys already in
+// it, or nil if no limit is set. Existing keys start out in key order, the
+// first being treated as least recently used.
+func newStorageQuota(impl KV, opts KVServerOptions, logger hclog.Logger) (*storageQuota, error) {
+	if opts.MaxKeys <= 0 && opts.MaxBytes <= 0 {
+		return nil, nil
+	}
+	policy := opts.EvictionPolicy
+	if policy == "" {
+		policy = evictLRU
+	}
+	switch policy {
+	case evictLRU:
+		if _, ok := impl.(deleter); !ok {
+			return nil, fmt.Errorf("store %T does not support deletes, so can't evict with %q", impl, policy)
+		}
+	case evictReject:
+	default:
+		return nil, fmt.Errorf("unknown eviction policy %q (expected %s or %s)", policy, evictLRU, evictReject)
+	}
+
+	q := &storageQuota{
+		maxKeys:  opts.MaxKeys,
+		maxBytes: opts.MaxBytes,
+		policy:   policy,
+		logger:   logger,
+		order:    list.New(),
+		entries:  map[string]*list.Element{},
+	}
+	keys, err := impl.List("")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list existing keys: %w", err)
+	}
+	for _, key := range keys {
+		value, err := impl.Get(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to size existing key %s: %w", key, err)
+		}
+		q.entries[key] = q.order.PushFront(&quotaEntry{key: key, size: int64(len(value))})
+		q.bytes += int64(len(value))
+	}
+	q.publish()
+	logger.Info("🗄️📏 storage limits enabled",
+		"max_keys", opts.MaxKeys,
+		"max_bytes", opts.MaxBytes,
+		"policy", policy,
+		"keys", len(q.entries),
+		"bytes", q.bytes)
+	return q, nil
+}
+
+// expvarInt wraps n for kvMetrics.Set
+func expvarInt(n int64) *expvar.Int {
+	v := new(expvar.Int)
+	v.Set(n)
+	return v
+}
+
+// applyStorageLimits enables --max-keys/--max-bytes enforcement on m's
+// store if its options set either limit
+func (m *GRPCServer) applyStorageLimits() error {
+	quota, err := newStorageQuota(m.Impl, m.Options, m.logger)
+	if err != nil {
+		return err
+	}
+	m.quota = quota
+	return nil
+}
+
+// publish updates the stored size gauges
+func (q *storageQuota) publish() {
+	kvMetrics.Set("stored_keys", expvarInt(int64(len(q.entries))))
+	kvMetrics.Set("stored_bytes", expvarInt(q.bytes))
+}
+
+// touch marks key as most recently used
+func (q *storageQuota) touch(key string) {
+	if q == nil {
+		return
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if elem, ok := q.entries[key]; ok {
+		q.order.MoveToFront(elem)
+	}
+}
+
+// fits reports whether the store is within its limits with keys and bytes
+func (q *storageQuota) fits(keys int, bytes int64) bool {
+	return (q.maxKeys <= 0 || keys <= q.maxKeys) && (q.maxBytes <= 0 || bytes <= q.maxBytes)
+}
+
+// admit makes room for key's new value of size bytes and then calls store
+// to write it. Under the lru policy least recently used keys (never key
+// itself) are deleted from impl until the write fits; under reject, or if
+// the value is larger than --max-bytes on its own, the write fails with a
+// storage-full error and nothing is deleted.
+func (q *storageQuota) admit(impl KV, key string, size int64, store func() error) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	keys, bytes := len(q.entries), q.bytes+size
+	if elem, ok := q.entries[key]; ok {
+		bytes -= elem.Value.(*quotaEntry).size
+	} else {
+		keys++
+	}
+
+	if !q.fits(keys, bytes) {
+		if q.policy == evictReject || (q.maxBytes > 0 && size > q.maxBytes) {
+			kvMetrics.Add("storage_full_total", 1)
+			q.logger.Warn("🗄️🚫 rejecting write: storage full", "key", key, "size", size, "keys", len(q.entries), "bytes", q.bytes)
+			return q.errFull(key, size, q.maxKeys > 0 && keys > q.maxKeys, q.maxBytes > 0 && bytes > q.maxBytes)
+		}
+
+		// Pick victims oldest first, skipping the key being written
+		var victims []*list.Element
+		for elem := q.order.Back(); elem != nil && !q.fits(keys, bytes); elem = elem.Prev() {
+			entry := elem.Value.(*quotaEntry)
+			if entry.key == key {
+				continue
+			}
+			victims = append(victims, elem)
+			keys--
+			bytes -= entry.size
+		}
+		for _, elem := range victims {
+			entry := elem.Value.(*quotaEntry)
+			if err := impl.(deleter).Delete(entry.key); err != nil {
+				return fmt.Errorf("failed to evict %s: %w", entry.key, err)
+			}
+			q.order.Remove(elem)
+			delete(q.entries, entry.key)
+			q.bytes -= entry.size
+			kvMetrics.Add("evictions_total", 1)
+			kvMetrics.Add("evicted_bytes_total", entry.size)
+			q.logger.Info("🗄️🧹 evicted key", "key", entry.key, "size", entry.size, "reason", evictLRU, "for", key)
+		}
+		q.publish()
+	}
+
+	if err := store(); err != nil {
+		return err
+	}
+	if elem, ok := q.entries[key]; ok {
+		entry := elem.Value.(*quotaEntry)
+		q.bytes += size - entry.size
+		entry.size = size
+		q.order.MoveToFront(elem)
+	} else {
+		q.entries[key] = q.order.PushFront(&quotaEntry{key: key, size: size})
+		q.bytes += size
+	}
+	q.publish()
+	return nil
+}
+
+// errFull builds the ResourceExhausted status for a write that doesn't fit.
+// A QuotaFailure detail names each limit the write exceeds: overKeys for
+// --max-keys and overBytes for --max-bytes.
+func (q *storageQuota) errFull(key string, size int64, overKeys, overBytes bool) error {
+	var violations []*errdetails.QuotaFailure_Violation
+	if overKeys {
+		violations = append(violations, &errdetails.QuotaFailure_Violation{
+			Subject:     "kv/keys",
+			Description: fmt.Sprintf("%d of %d keys stored", len(q.entries), q.maxKeys),
+		})
+	}
+	if overBytes {
+		violations = append(violations, &errdetails.QuotaFailure_Violation{
+			Subject:     "kv/bytes",
+			Description: fmt.Sprintf("%d of %d bytes stored, write needs %d", q.bytes, q.maxBytes, size),
+		})
+	}
+	return withDetails(status.Newf(codes.ResourceExhausted, "storage full: cannot store %s (%d bytes)", key, size),
+		&errdetails.QuotaFailure{Violations: violations},
+		&errdetails.ErrorInfo{
+			Reason: "STORAGE_FULL",
+			Domain: errorDomain,
+			Metadata: map[string]string{
+				"key":    key,
+				"policy": q.policy,
+			},
+		},
+	)
+}
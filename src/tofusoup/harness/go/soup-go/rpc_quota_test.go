@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+
+	"github.com/provide-io/tofusoup/proto/kv"
+)
+
+// runKVPut runs 'rpc kv put' against address, returning the printed status
+// JSON of a failed Put
+func runKVPut(t *testing.T, address, key, value string) (map[string]interface{}, error) {
+	t.Helper()
+	cmd := initKVPutCmd()
+	cmd.SetArgs([]string{key, value, "--address", address})
+	cmd.SilenceUsage, cmd.SilenceErrors = true, true
+	out, err := captureStdout(t, cmd.Execute)
+	if err == nil {
+		return nil, nil
+	}
+	var printed struct {
+		Error map[string]interface{} `json:"error"`
+	}
+	if jerr := json.Unmarshal(out, &printed); jerr != nil {
+		t.Fatalf("%v (command error %v): %s", jerr, err, out)
+	}
+	return printed.Error, err
+}
+
+func storedKeys(t *testing.T, server *GRPCServer) string {
+	t.Helper()
+	keys, err := server.Impl.List("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Join(keys, ",")
+}
+
+func TestStorageLimitLRUEvictsLeastRecentlyUsed(t *testing.T) {
+	server, address := serveTestKV(t, KVServerOptions{MaxKeys: 2})
+	for _, key := range []string{"a", "b"} {
+		if _, err := runKVPut(t, address, key, "value-"+key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Reading a makes b the least recently used
+	if _, err := server.Get(context.Background(), &proto.GetRequest{Key: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runKVPut(t, address, "c", "value-c"); err != nil {
+		t.Fatal(err)
+	}
+	if got := storedKeys(t, server); got != "a,c" {
+		t.Errorf("got keys %s, want b evicted", got)
+	}
+
+	// Overwriting a stored key needs no room
+	if _, err := runKVPut(t, address, "a", "value-a2"); err != nil {
+		t.Fatal(err)
+	}
+	if got := storedKeys(t, server); got != "a,c" {
+		t.Errorf("got keys %s after an overwrite, want a,c", got)
+	}
+}
+
+func TestStorageLimitRejectReportsStorageFull(t *testing.T) {
+	server, address := serveTestKV(t, KVServerOptions{MaxKeys: 5, MaxBytes: 10, EvictionPolicy: evictReject})
+	if _, err := runKVPut(t, address, "a", "12345"); err != nil {
+		t.Fatal(err)
+	}
+	status, err := runKVPut(t, address, "b", "123456")
+	if err == nil {
+		t.Fatal("a write past --max-bytes succeeded under the reject policy")
+	}
+	if status["code"] != "ResourceExhausted" {
+		t.Errorf("got status %v, want ResourceExhausted", status)
+	}
+
+	details, _ := json.Marshal(status["details"])
+	for _, want := range []string{
+		`"subject":"kv/bytes"`,
+		`"description":"5 of 10 bytes stored, write needs 6"`,
+		`"reason":"STORAGE_FULL"`,
+		`"policy":"reject"`,
+	} {
+		if !strings.Contains(string(details), want) {
+			t.Errorf("details are missing %s: %s", want, details)
+		}
+	}
+	// Only the limit the write exceeds is reported
+	if strings.Contains(string(details), `"kv/keys"`) {
+		t.Errorf("got a --max-keys violation for a write within it: %s", details)
+	}
+	if got := storedKeys(t, server); got != "a" {
+		t.Errorf("got keys %s, want the rejected write not stored", got)
+	}
+}
+
+func TestStorageLimitRejectReportsExceededKeys(t *testing.T) {
+	_, address := serveTestKV(t, KVServerOptions{MaxKeys: 1, MaxBytes: 100, EvictionPolicy: evictReject})
+	if _, err := runKVPut(t, address, "a", "1"); err != nil {
+		t.Fatal(err)
+	}
+	status, err := runKVPut(t, address, "b", "2")
+	if err == nil {
+		t.Fatal("a write past --max-keys succeeded under the reject policy")
+	}
+	details, _ := json.Marshal(status["details"])
+	if !strings.Contains(string(details), `"description":"1 of 1 keys stored"`) || strings.Contains(string(details), `"kv/bytes"`) {
+		t.Errorf("got details %s, want only the --max-keys violation", details)
+	}
+}
+
+func TestStorageLimitLRURejectsValueLargerThanLimit(t *testing.T) {
+	server, address := serveTestKV(t, KVServerOptions{MaxBytes: 4})
+	if _, err := runKVPut(t, address, "a", "1234"); err != nil {
+		t.Fatal(err)
+	}
+	if status, err := runKVPut(t, address, "b", "12345"); err == nil || status["code"] != "ResourceExhausted" {
+		t.Errorf("got %v (%v), want a value over --max-bytes rejected", status, err)
+	}
+	if got := storedKeys(t, server); got != "a" {
+		t.Errorf("got keys %s, want nothing evicted for a value that can never fit", got)
+	}
+}
+
+func TestStorageQuotaCountsExistingKeys(t *testing.T) {
+	impl := NewKVImpl(hclog.NewNullLogger(), t.TempDir())
+	for _, key := range []string{"x", "y"} {
+		if err := impl.Put(key, []byte("123")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	quota, err := newStorageQuota(impl, KVServerOptions{MaxKeys: 2, MaxBytes: 100}, hclog.NewNullLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(quota.entries) != 2 || quota.bytes != 6 {
+		t.Errorf("got %d keys and %d bytes, want the 2 stored keys of 3 bytes", len(quota.entries), quota.bytes)
+	}
+
+	// The first key in order is the least recently used
+	if err := quota.admit(impl, "z", 1, func() error { return impl.Put("z", []byte("1")) }); err != nil {
+		t.Fatal(err)
+	}
+	if keys, _ := impl.List(""); strings.Join(keys, ",") != "y,z" {
+		t.Errorf("got keys %v, want x evicted", keys)
+	}
+
+	if _, err := newStorageQuota(impl, KVServerOptions{MaxKeys: 1, EvictionPolicy: "fifo"}, hclog.NewNullLogger()); err == nil {
+		t.Error("got no error for an unknown eviction policy")
+	}
+}
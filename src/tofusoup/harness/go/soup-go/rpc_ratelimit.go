@@ -0,0 +1,71 @@
+package main
+
+import (
+	"expvar"
+	"math"
+	"sync"
+	"time"
+)
+
+// kvMetrics holds the KV server counters, published at /debug/vars on the
+// pprof listener.
+var kvMetrics = expvar.NewMap("kv")
+
+// countRequest records a request for method and its outcome
+func countRequest(method, outcome string) {
+	kvMetrics.Add("requests_total", 1)
+	kvMetrics.Add("requests_"+method, 1)
+	kvMetrics.Add(outcome+"_total", 1)
+}
+
+// tokenBucket is a token bucket rate limiter. Tokens refill continuously at
+// rate per second up to burst; each request takes one token.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+// newTokenBucket returns a full bucket, or nil if rate is not positive
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if rate <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	b := &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		now:    time.Now,
+	}
+	b.last = b.now()
+	return b
+}
+
+// take consumes a token if one is available. Otherwise it reports how long
+// until the next token becomes available. A nil bucket never limits.
+func (b *tokenBucket) take() (bool, time.Duration) {
+	if b == nil {
+		return true, 0
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	wait := (1 - b.tokens) / b.rate
+	return false, time.Duration(math.Ceil(wait * float64(time.Second)))
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+// rpcResolve holds curl-style --resolve NAME:ADDR overrides. A client
+// dialing NAME connects to ADDR without looking NAME up, but still verifies
+// the server certificate against NAME, so hostname verification can be
+// tested with real DNS names without editing /etc/hosts.
+var rpcResolve []string
+
+// rpcTLSDNSNames are extra DNS SANs for generated certificates, so a
+// server can answer for the names clients --resolve to it
+var rpcTLSDNSNames []string
+
+// parseResolve maps each --resolve name, lowercased, to its address
+func parseResolve(entries []string) (map[string]net.IP, error) {
+	overrides := make(map[string]net.IP, len(entries))
+	for _, entry := range entries {
+		name, addr, ok := strings.Cut(entry, ":")
+		if !ok || name == "" || addr == "" {
+			return nil, fmt.Errorf("invalid --resolve %q: expected NAME:ADDR", entry)
+		}
+		ip := net.ParseIP(strings.TrimSuffix(strings.TrimPrefix(addr, "["), "]"))
+		if ip == nil {
+			return nil, fmt.Errorf("invalid --resolve %q: %s is not an IP address", entry, addr)
+		}
+		overrides[strings.ToLower(name)] = ip
+	}
+	return overrides, nil
+}
+
+// resolveTCPAddr resolves a host:port address as net.ResolveTCPAddr does,
+// unless --resolve overrides its host. It also returns the hostname TLS
+// verifies: the overridden name, or else the resolved IP.
+func resolveTCPAddr(address string, logger hclog.Logger) (*net.TCPAddr, string, error) {
+	overrides, err := parseResolve(rpcResolve)
+	if err != nil {
+		return nil, "", err
+	}
+	if host, port, err := net.SplitHostPort(address); err == nil && len(overrides) > 0 {
+		if ip, ok := overrides[strings.ToLower(host)]; ok {
+			portNum, err := net.LookupPort("tcp", port)
+			if err != nil {
+				return nil, "", err
+			}
+			logger.Info("🔌 Dialing --resolve override", "name", host, "addr", ip.String())
+			return &net.TCPAddr{IP: ip, Port: portNum}, host, nil
+		}
+		logger.Warn("⚠️  No --resolve override matches address", "host", host)
+	}
+	tcpAddr, err := net.ResolveTCPAddr("tcp", address)
+	if err != nil {
+		return nil, "", err
+	}
+	return tcpAddr, tcpAddr.IP.String(), nil
+}
@@ -11,14 +11,20 @@ import (
 
 	"github.com/hashicorp/go-hclog"
 	"google.golang.org/grpc"
+	channelzsvc "google.golang.org/grpc/channelz/service"
 	"google.golang.org/grpc/credentials"
 
+	"github.com/provide-io/tofusoup/proto/counter"
+	"github.com/provide-io/tofusoup/proto/echo"
 	proto "github.com/provide-io/tofusoup/proto/kv"
 )
 
-func startRPCServer(logger hclog.Logger, port int, tlsMode, tlsKeyType, tlsCurve, certFile, keyFile string) error {
+func startRPCServer(logger hclog.Logger, port int, pipe string, socket UnixSocketOptions, tlsMode, tlsKeyType, tlsCurve, certFile, keyFile string, opts KVServerOptions, counterBuffer int, echoDelay time.Duration) error {
 	logger.Info("🗄️✨ starting standalone RPC server",
 		"port", port,
+		"pipe", pipe,
+		"socket", socket.Path,
+		"readonly", opts.ReadOnly,
 		"tls_mode", tlsMode,
 		"tls_key_type", tlsKeyType,
 		"tls_curve", tlsCurve,
@@ -77,31 +83,67 @@ func startRPCServer(logger hclog.Logger, port int, tlsMode, tlsKeyType, tlsCurve
 			Certificates: []tls.Certificate{cert},
 			MinVersion:   tls.VersionTLS12,
 			ClientAuth:   tls.NoClientCert, // Standalone doesn't require client certs
+			Time:         certNow,
+		}
+
+		clientAuth := "none"
+		if rpcClientCertHandoff != "" {
+			if err := requireHandedOffClientCert(tlsConfig, certPEM, rpcClientCertHandoff, logger); err != nil {
+				return err
+			}
+			clientAuth = "handoff"
 		}
 
 		serverOpts = append(serverOpts, grpc.Creds(credentials.NewTLS(tlsConfig)))
-		logger.Info("🔐 TLS enabled", "client_auth", "none")
+		logger.Info("🔐 TLS enabled", "client_auth", clientAuth)
 	} else if tlsMode == "disabled" {
 		logger.Info("🔐 TLS disabled - no encryption")
 	} else {
 		logger.Warn("⚠️  Unknown TLS mode, running without TLS", "mode", tlsMode)
 	}
 
+	serverOpts = append(serverOpts, authServerOptions(authToken(), logger)...)
+	serverOpts = append(serverOpts, diagServerOptions()...)
+
 	// Create the gRPC server
 	grpcServer := grpc.NewServer(serverOpts...)
 
 	// Register our KV service
-	proto.RegisterKVServer(grpcServer, &GRPCServer{
-		Impl:      kv,
-		logger:    logger,
-		startTime: time.Now(),
-	})
+	kvServer := newGRPCServer(kv, opts, logger)
+	if err := kvServer.applyStorageLimits(); err != nil {
+		return err
+	}
+	kvServer.startCompaction()
+	proto.RegisterKVServer(grpcServer, kvService(kvServer, opts, logger))
+	counterServer := NewCounterServer(logger.Named("counter"), counterBuffer)
+	counter.RegisterCounterServer(grpcServer, counterServer)
+	echo.RegisterEchoServer(grpcServer, NewEchoServer(logger.Named("echo"), echoDelay))
+	channelzsvc.RegisterChannelzServiceToServer(grpcServer)
 
 	// Start listening
-	addr := fmt.Sprintf(":%d", port)
-	listener, err := net.Listen("tcp", addr)
-	if err != nil {
-		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	var listener net.Listener
+	if socket.Path != "" {
+		var err error
+		listener, err = listenUnixSocket(logger.Named("socket"), socket)
+		if err != nil {
+			return err
+		}
+	} else if pipe != "" {
+		addr, err := parsePipeAddress(pipe)
+		if err != nil {
+			return err
+		}
+		listener, err = listenPipe(addr)
+		if err != nil {
+			return fmt.Errorf("failed to listen on %s: %w", addr, err)
+		}
+	} else {
+		addr := fmt.Sprintf(":%d", port)
+		var err error
+		listener, err = net.Listen("tcp", addr)
+		if err != nil {
+			return fmt.Errorf("failed to listen on %s: %w", addr, err)
+		}
 	}
 
 	logger.Info("🗄️🎧 Server listening", "address", listener.Addr().String())
@@ -111,6 +153,7 @@ func startRPCServer(logger hclog.Logger, port int, tlsMode, tlsKeyType, tlsCurve
 	go func() {
 		sig := <-shutdown
 		logger.Info("🗄️🛑 shutting down server", "signal", sig)
+		counterServer.Stop()
 		grpcServer.GracefulStop()
 	}()
 
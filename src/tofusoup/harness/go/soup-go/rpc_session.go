@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/hashicorp/go-plugin"
+	"github.com/spf13/cobra"
+)
+
+// sessionOp is one line of a kv session script
+type sessionOp struct {
+	Op     string  `json:"op"`
+	Key    string  `json:"key,omitempty"`
+	Value  *string `json:"value,omitempty"`
+	Prefix string  `json:"prefix,omitempty"`
+}
+
+// sessionResult is printed for every op a session runs
+type sessionResult struct {
+	Index     int         `json:"index"`
+	Op        string      `json:"op"`
+	Key       string      `json:"key,omitempty"`
+	OK        bool        `json:"ok"`
+	ElapsedMs float64     `json:"elapsed_ms"`
+	Value     *string     `json:"value,omitempty"`
+	Keys      []string    `json:"keys,omitempty"`
+	Error     interface{} `json:"error,omitempty"`
+}
+
+// sessionReport is printed when a session ends. Setup covers creating the
+// plugin client through Dispense; op latency covers the calls alone.
+type sessionReport struct {
+	Ops         int            `json:"ops"`
+	Failed      int            `json:"failed"`
+	Connections int            `json:"connections"`
+	SetupMs     float64        `json:"setup_ms"`
+	Setup       latencySummary `json:"setup_latency_ms"`
+	// FirstOpMs is the first op's latency, which may include connection
+	// work the transport deferred until the first call
+	FirstOpMs float64        `json:"first_op_ms"`
+	OpMs      float64        `json:"op_ms"`
+	Latency   latencySummary `json:"op_latency_ms"`
+	ElapsedMs float64        `json:"elapsed_ms"`
+}
+
+// readSessionScript parses an NDJSON script, skipping blank lines, and
+// checks every op before anything is dialed
+func readSessionScript(r io.Reader) ([]sessionOp, error) {
+	var ops []sessionOp
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := scanner.Bytes()
+		if len(bytes.TrimSpace(text)) == 0 {
+			continue
+		}
+		var op sessionOp
+		if err := json.Unmarshal(text, &op); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		switch op.Op {
+		case "put":
+			if op.Key == "" || op.Value == nil {
+				return nil, fmt.Errorf("line %d: put needs key and value", line)
+			}
+		case "get":
+			if op.Key == "" {
+				return nil, fmt.Errorf("line %d: get needs key", line)
+			}
+		case "list":
+		default:
+			return nil, fmt.Errorf("line %d: unknown op %q (want put, get or list)", line, op.Op)
+		}
+		ops = append(ops, op)
+	}
+	return ops, scanner.Err()
+}
+
+// runSessionOp performs one op and times the call
+func runSessionOp(kv KV, index int, op sessionOp) (sessionResult, time.Duration) {
+	result := sessionResult{Index: index, Op: op.Op, Key: op.Key}
+	start := time.Now()
+	var err error
+	switch op.Op {
+	case "put":
+		err = kv.Put(op.Key, []byte(*op.Value))
+	case "get":
+		var value []byte
+		if value, err = kv.Get(op.Key); err == nil {
+			s := string(value)
+			result.Value = &s
+		}
+	case "list":
+		if result.Keys, err = kv.List(op.Prefix); err == nil && result.Keys == nil {
+			result.Keys = []string{}
+		}
+	}
+	elapsed := time.Since(start)
+	result.ElapsedMs = float64(elapsed.Microseconds()) / 1000
+	result.OK = err == nil
+	if err != nil {
+		result.Error = err.Error()
+		if st := statusToJSON(err); st != nil {
+			result.Error = st
+		}
+	}
+	return result, elapsed
+}
+
+func totalMs(samples []time.Duration) float64 {
+	var total time.Duration
+	for _, d := range samples {
+		total += d
+	}
+	return float64(total.Microseconds()) / 1000
+}
+
+func initKVSessionCmd() *cobra.Command {
+	var (
+		address     string
+		tlsCurve    string
+		script      string
+		reconnect   bool
+		stopOnError bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "session",
+		Short: "Run a script of KV operations over one connection",
+		Long: `Run the operations in an NDJSON --script ("-" reads stdin) over a single
+plugin client, one JSON object per line:
+
+  {"op": "put", "key": "k", "value": "v"}
+  {"op": "get", "key": "k"}
+  {"op": "list", "prefix": "k"}
+
+Each op prints a result line with its latency. The session line printed at
+the end reports connection setup (plugin client through Dispense) apart from
+op latency, so connection reuse can be compared across harnesses.
+--reconnect dials a new client for every op instead. The whole script is
+checked before anything is dialed; the command fails if any op did.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			in := io.Reader(os.Stdin)
+			if script != "-" {
+				f, err := os.Open(script)
+				if err != nil {
+					return fmt.Errorf("failed to open script: %w", err)
+				}
+				defer f.Close()
+				in = f
+			}
+			ops, err := readSessionScript(in)
+			if err != nil {
+				return fmt.Errorf("invalid script: %w", err)
+			}
+
+			var client *plugin.Client
+			var kv KV
+			var setups, latencies []time.Duration
+			defer func() {
+				if client != nil {
+					client.Kill()
+				}
+			}()
+			connect := func() error {
+				if client != nil {
+					// Kill waits for a reattached server to exit, which a
+					// standalone one never does; closing the connection is
+					// enough to drop it
+					if rpcClient, err := client.Client(); address != "" && err == nil {
+						rpcClient.Close()
+					} else {
+						client.Kill()
+					}
+					client = nil
+				}
+				start := time.Now()
+				c, raw, err := dispensePlugin(address, tlsCurve, "kv_grpc")
+				if err != nil {
+					return err
+				}
+				setups = append(setups, time.Since(start))
+				client, kv = c, raw.(KV)
+				return nil
+			}
+
+			start := time.Now()
+			report := sessionReport{Ops: len(ops)}
+			encoder := json.NewEncoder(os.Stdout)
+			for i, op := range ops {
+				if client == nil || reconnect {
+					if err := connect(); err != nil {
+						return fmt.Errorf("op %d: %w", i, err)
+					}
+				}
+				result, elapsed := runSessionOp(kv, i, op)
+				latencies = append(latencies, elapsed)
+				if err := encoder.Encode(result); err != nil {
+					return fmt.Errorf("failed to encode JSON: %w", err)
+				}
+				if !result.OK {
+					report.Failed++
+					if stopOnError {
+						report.Ops = i + 1
+						break
+					}
+				}
+			}
+
+			report.ElapsedMs = float64(time.Since(start).Microseconds()) / 1000
+			report.Connections = len(setups)
+			report.Setup = summarizeLatencies(setups)
+			report.Latency = summarizeLatencies(latencies)
+			report.SetupMs = totalMs(setups)
+			report.OpMs = totalMs(latencies)
+			if len(latencies) > 0 {
+				report.FirstOpMs = float64(latencies[0].Microseconds()) / 1000
+			}
+			if err := encoder.Encode(map[string]interface{}{"session": report}); err != nil {
+				return fmt.Errorf("failed to encode JSON: %w", err)
+			}
+			if report.Failed > 0 {
+				return fmt.Errorf("%d of %d ops failed", report.Failed, report.Ops)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&address, "address", "", "Address or handshake of a running server (empty spawns one)")
+	cmd.Flags().StringVar(&tlsCurve, "tls-curve", "auto", "Client cert curve: auto (detect from server), secp256r1, secp384r1, secp521r1")
+	cmd.Flags().StringVar(&script, "script", "", "NDJSON file of ops to run, or - for stdin")
+	cmd.Flags().BoolVar(&reconnect, "reconnect", false, "Dial a new client for every op instead of reusing one")
+	cmd.Flags().BoolVar(&stopOnError, "stop-on-error", false, "Stop at the first failed op")
+	cmd.MarkFlagRequired("script")
+	return cmd
+}
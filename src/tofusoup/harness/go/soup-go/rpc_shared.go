@@ -2,11 +2,12 @@ package main
 
 import (
 	"context"
-	"crypto/sha256"
-	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -40,6 +41,44 @@ func getEnvOrDefault(key, defaultValue string) string {
 type KV interface {
 	Put(key string, value []byte) error
 	Get(key string) ([]byte, error)
+	List(prefix string) ([]string, error)
+}
+
+// KVServerOptions controls optional server-side behaviors of the KV service.
+type KVServerOptions struct {
+	// ReadOnly rejects all writes with FailedPrecondition
+	ReadOnly bool
+	// RateLimit is the sustained requests per second allowed (0 disables)
+	RateLimit float64
+	// Burst is the number of requests allowed above RateLimit at once
+	Burst int
+	// MaxKeys is the most keys the store may hold (0 is unlimited)
+	MaxKeys int
+	// MaxBytes is the most value bytes the store may hold (0 is unlimited)
+	MaxBytes int64
+	// EvictionPolicy is what happens to a write past a limit: "lru" evicts
+	// least recently used keys to make room, "reject" fails the write
+	EvictionPolicy string
+	// FaultDelay holds every KV call this long before handling it, to
+	// exercise client deadlines
+	FaultDelay time.Duration
+	// EntryTTL expires entries not written for this long; compaction removes
+	// them (0 never expires)
+	EntryTTL time.Duration
+	// GCInterval runs compaction this often in the background (0 disables)
+	GCInterval time.Duration
+	// MaxValueBytes rejects Puts of larger values with InvalidArgument (0 is
+	// unlimited)
+	MaxValueBytes int64
+	// Mock answers calls from scripted responses instead of storage
+	Mock *kvMock
+	// MaxValueMemory is the most bytes of a value a call may hold in memory:
+	// Gets of larger values fail with ResourceExhausted, and GetStream
+	// chunks are no larger (0 is unlimited)
+	MaxValueMemory int64
+	// IdempotencyWindow is how long an applied Put's idempotency token
+	// dedupes retries (0 is the default window)
+	IdempotencyWindow time.Duration
 }
 
 // KVGRPCPlugin is the implementation of plugin.GRPCPlugin so we can serve/consume this.
@@ -47,12 +86,15 @@ type KVGRPCPlugin struct {
 	plugin.Plugin
 	// Concrete implementation, written in Go.
 	Impl KV
+	// Options for the server side of the plugin
+	Options KVServerOptions
 }
 
 func (p *KVGRPCPlugin) GRPCClient(ctx context.Context, broker *plugin.GRPCBroker, c *grpc.ClientConn) (interface{}, error) {
 	logger := hclog.New(&hclog.LoggerOptions{
-		Name:  "🔌🌐 kv-grpc-client",
-		Level: hclog.Debug,
+		Name:   "🔌🌐 kv-grpc-client",
+		Level:  hclog.Debug,
+		Output: logOutput,
 	})
 
 	if c == nil {
@@ -76,8 +118,9 @@ func (p *KVGRPCPlugin) GRPCClient(ctx context.Context, broker *plugin.GRPCBroker
 
 func (p *KVGRPCPlugin) GRPCServer(broker *plugin.GRPCBroker, s *grpc.Server) error {
 	logger := hclog.New(&hclog.LoggerOptions{
-		Name:  "🔌📡 kv-grpc-server",
-		Level: hclog.Debug,
+		Name:   "🔌📡 kv-grpc-server",
+		Level:  hclog.Debug,
+		Output: logOutput,
 	})
 
 	logger.Debug("📡🔄 initializing gRPC server registration")
@@ -89,13 +132,13 @@ func (p *KVGRPCPlugin) GRPCServer(broker *plugin.GRPCBroker, s *grpc.Server) err
 		p.Impl = NewKVImpl(logger.Named("kv"), storageDir)
 	}
 
-	server := &GRPCServer{
-		Impl:      p.Impl,
-		logger:    logger,
-		startTime: time.Now(),
+	server := newGRPCServer(p.Impl, p.Options, logger)
+	if err := server.applyStorageLimits(); err != nil {
+		return err
 	}
+	server.startCompaction()
 
-	proto.RegisterKVServer(s, server)
+	proto.RegisterKVServer(s, kvService(server, p.Options, logger))
 	logger.Info("📡✅ gRPC server registered successfully",
 		"server_type", fmt.Sprintf("%T", server))
 	return nil
@@ -148,8 +191,38 @@ func (m *GRPCClient) Get(key string) ([]byte, error) {
 type GRPCServer struct {
 	proto.UnimplementedKVServer
 	Impl      KV
+	Options   KVServerOptions
 	logger    hclog.Logger
 	startTime time.Time
+	limiter   *tokenBucket
+	quota     *storageQuota
+	stats     *keyStats
+	puts      *putSignal
+}
+
+// newGRPCServer creates a GRPCServer serving impl with the given options
+func newGRPCServer(impl KV, opts KVServerOptions, logger hclog.Logger) *GRPCServer {
+	return &GRPCServer{
+		Impl:      impl,
+		Options:   opts,
+		logger:    logger,
+		startTime: time.Now(),
+		limiter:   newTokenBucket(opts.RateLimit, opts.Burst),
+		stats:     newKeyStats(),
+		puts:      newPutSignal(),
+	}
+}
+
+// throttle returns a ResourceExhausted error if the request exceeds the
+// configured rate limit.
+func (m *GRPCServer) throttle(method string) error {
+	ok, retryAfter := m.limiter.take()
+	if ok {
+		return nil
+	}
+	countRequest(method, "throttled")
+	m.logger.Warn("📡🚦 throttling request", "method", method, "retry_after", retryAfter)
+	return errThrottled(method, retryAfter)
 }
 
 // enrichJSONWithHandshake enriches JSON values with server handshake information.
@@ -220,8 +293,8 @@ func (m *GRPCServer) enrichJSONWithHandshake(ctx context.Context, value []byte)
 	if serverCertPath != "" {
 		certData, err := os.ReadFile(serverCertPath)
 		if err == nil {
-			hash := sha256.Sum256(certData)
-			serverHandshake["cert_fingerprint"] = hex.EncodeToString(hash[:])
+			serverHandshake["cert_fingerprint"] = fingerprint(certData)
+			serverHandshake["cert_fingerprint_algorithm"] = currentDigest.Name()
 		} else {
 			serverHandshake["cert_fingerprint"] = nil
 		}
@@ -249,33 +322,126 @@ func (m *GRPCServer) Put(ctx context.Context, req *proto.PutRequest) (*proto.Emp
 	m.logger.Debug("📡📤 handling Put request",
 		"key", req.Key,
 		"value_size", len(req.Value))
+	reportUnknownFields(ctx, "Put", req, m.logger)
+	m.observeDeadline(ctx, "Put")
+	if err := m.injectFault(ctx, "Put"); err != nil {
+		return nil, err
+	}
+
+	if err := m.throttle("Put"); err != nil {
+		return nil, err
+	}
+
+	if err := validateKey(req.Key); err != nil {
+		countRequest("Put", "rejected")
+		m.logger.Warn("📡❌ rejecting Put with invalid key", "key", req.Key)
+		return nil, err
+	}
+
+	resp := &proto.Empty{}
+	outcome := statFailed
+	defer func() { m.stats.record("Put", req.Key, req, resp, outcome) }()
+
+	if limit := m.Options.MaxValueBytes; limit > 0 && int64(len(req.Value)) > limit {
+		outcome = statTooLarge
+		countRequest("Put", "rejected")
+		m.logger.Warn("📡❌ rejecting Put with oversized value", "key", req.Key, "size", len(req.Value), "max_value_bytes", limit)
+		return nil, errValueTooLarge(req.Key, int64(len(req.Value)), limit)
+	}
+
+	if m.Options.ReadOnly {
+		countRequest("Put", "rejected")
+		m.logger.Warn("📡🔒 rejecting Put on read-only server", "key", req.Key)
+		return nil, errReadOnly("Put", req.Key)
+	}
 
 	// Store raw value without enrichment (enrichment happens on Get)
-	if err := m.Impl.Put(req.Key, req.Value); err != nil {
+	store := func() error { return m.Impl.Put(req.Key, req.Value) }
+	apply := store
+	if m.quota != nil {
+		apply = func() error { return m.quota.admit(m.Impl, req.Key, int64(len(req.Value)), store) }
+	}
+	var err error
+	if req.IdempotencyToken != "" {
+		err = m.applyOnce(ctx, req, apply)
+	} else {
+		err = apply()
+	}
+	if status.Code(err) == codes.InvalidArgument {
+		countRequest("Put", "rejected")
+		m.logger.Warn("📡❌ rejecting Put reusing an idempotency token", "key", req.Key)
+		return nil, err
+	}
+	if status.Code(err) == codes.ResourceExhausted {
+		countRequest("Put", "rejected")
+		return nil, err
+	}
+	if err != nil {
+		countRequest("Put", "failed")
 		m.logger.Error("📡❌ Put operation failed",
 			"key", req.Key,
 			"error", err)
 		return nil, err
 	}
+	countRequest("Put", "ok")
+	outcome = statOK
+	m.puts.broadcast()
 
 	m.logger.Debug("📡✅ Put operation completed successfully",
 		"key", req.Key,
 		"stored_size", len(req.Value))
-	return &proto.Empty{}, nil
+	return resp, nil
 }
 
 func (m *GRPCServer) Get(ctx context.Context, req *proto.GetRequest) (*proto.GetResponse, error) {
 	m.logger.Debug("📡📥 handling Get request",
 		"key", req.Key)
+	reportUnknownFields(ctx, "Get", req, m.logger)
+	m.observeDeadline(ctx, "Get")
+	if err := m.injectFault(ctx, "Get"); err != nil {
+		return nil, err
+	}
+
+	if err := m.throttle("Get"); err != nil {
+		return nil, err
+	}
+
+	if err := validateKey(req.Key); err != nil {
+		countRequest("Get", "rejected")
+		m.logger.Warn("📡❌ rejecting Get with invalid key", "key", req.Key)
+		return nil, err
+	}
+	if err := validateWait(req); err != nil {
+		countRequest("Get", "rejected")
+		m.logger.Warn("📡❌ rejecting Get with invalid wait timeout", "key", req.Key, "wait_timeout_ms", req.WaitTimeoutMs)
+		return nil, err
+	}
 
-	rawValue, err := m.Impl.Get(req.Key)
+	resp := &proto.GetResponse{}
+	outcome := statFailed
+	defer func() { m.stats.record("Get", req.Key, req, resp, outcome) }()
+
+	rawValue, err := m.getValue(req.Key)
+	if req.Wait && os.IsNotExist(err) {
+		// The wait's own failures are statuses, already counted
+		rawValue, err = m.waitForKey(ctx, req)
+		if _, isStatus := status.FromError(err); err != nil && isStatus {
+			return nil, err
+		}
+	}
+	if status.Code(err) == codes.ResourceExhausted {
+		// Over the memory ceiling, already counted
+		return nil, err
+	}
 	if err != nil {
 		// Check if this is a file not found error (key doesn't exist)
 		if os.IsNotExist(err) {
+			countRequest("Get", "not_found")
 			m.logger.Debug("📡📥 key not found",
 				"key", req.Key)
-			return nil, status.Errorf(codes.NotFound, "key not found: %s", req.Key)
+			return nil, errNotFound(req.Key)
 		}
+		countRequest("Get", "failed")
 		m.logger.Error("📡❌ Get operation failed",
 			"key", req.Key,
 			"error", err)
@@ -285,17 +451,22 @@ func (m *GRPCServer) Get(ctx context.Context, req *proto.GetRequest) (*proto.Get
 	// Enrich JSON values with server handshake information on Get
 	enrichedValue, err := m.enrichJSONWithHandshake(ctx, rawValue)
 	if err != nil {
+		countRequest("Get", "failed")
 		m.logger.Error("📡❌ Failed to enrich value",
 			"key", req.Key,
 			"error", err)
 		return nil, err
 	}
+	countRequest("Get", "ok")
+	m.quota.touch(req.Key)
+	resp.Value = enrichedValue
+	outcome = statOK
 
 	m.logger.Debug("📡✅ Get operation completed successfully",
 		"key", req.Key,
 		"raw_size", len(rawValue),
 		"enriched_size", len(enrichedValue))
-	return &proto.GetResponse{Value: enrichedValue}, nil
+	return resp, nil
 }
 
 // KVImpl provides a simple file-based KV implementation
@@ -318,12 +489,26 @@ func NewKVImpl(logger hclog.Logger, storageDir string) *KVImpl {
 	}
 }
 
+// keyPath returns the file that stores key
+func (k *KVImpl) keyPath(key string) string {
+	return filepath.Join(k.storageDir, kvDataPrefix+key)
+}
+
+// metaPath returns the file that indexes key's value size and digest
+func (k *KVImpl) metaPath(key string) string {
+	return filepath.Join(k.storageDir, kvMetaPrefix+key)
+}
+
 func (k *KVImpl) Put(key string, value []byte) error {
 	if key == "" {
 		return nil
 	}
 
-	filePath := k.storageDir + "/kv-data-" + key
+	if err := os.MkdirAll(k.storageDir, 0755); err != nil {
+		return fmt.Errorf("failed to create storage directory: %w", err)
+	}
+
+	filePath := k.keyPath(key)
 	lock := flock.New(filePath)
 
 	if err := lock.Lock(); err != nil {
@@ -334,6 +519,13 @@ func (k *KVImpl) Put(key string, value []byte) error {
 			k.logger.Error("failed to unlock file", "key", key, "error", err)
 		}
 	}()
+	crashAt(crashLockAcquired, key)
+
+	// Index the value before writing it, so a write cut short by a crash
+	// shows up as a truncated value rather than a valid shorter one
+	if err := k.writeMeta(key, value); err != nil {
+		return fmt.Errorf("failed to index key %s: %w", key, err)
+	}
 
 	// Write the file
 	if err := os.WriteFile(filePath, value, 0644); err != nil {
@@ -347,10 +539,34 @@ func (k *KVImpl) Put(key string, value []byte) error {
 	}
 	defer file.Close()
 
+	crashAt(crashFlush, key)
 	if err := file.Sync(); err != nil {
 		return err
 	}
 
+	crashAt(crashPut, key)
+	return nil
+}
+
+// Delete removes key; deleting a missing key is not an error
+func (k *KVImpl) Delete(key string) error {
+	filePath := k.keyPath(key)
+	lock := flock.New(filePath)
+	if err := lock.Lock(); err != nil {
+		return fmt.Errorf("failed to acquire lock for key %s: %w", key, err)
+	}
+	defer func() {
+		if err := lock.Unlock(); err != nil {
+			k.logger.Error("failed to unlock file", "key", key, "error", err)
+		}
+	}()
+
+	if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Remove(k.metaPath(key)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
 	return nil
 }
 
@@ -363,6 +579,33 @@ func (k *KVImpl) Get(key string) ([]byte, error) {
 	}
 
 	k.logger.Debug("🗄️📥 getting value", "key", key)
-	filePath := k.storageDir + "/kv-data-" + key
-	return os.ReadFile(filePath)
-}
\ No newline at end of file
+	return os.ReadFile(k.keyPath(key))
+}
+
+// List returns all stored keys starting with prefix, sorted ascending
+func (k *KVImpl) List(prefix string) ([]string, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	entries, err := os.ReadDir(k.storageDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []string{}, nil
+		}
+		return nil, err
+	}
+
+	keys := []string{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		key, ok := strings.CutPrefix(entry.Name(), kvDataPrefix)
+		if !ok || !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
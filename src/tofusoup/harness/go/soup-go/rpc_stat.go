@@ -0,0 +1,319 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+	protobuf "google.golang.org/protobuf/proto"
+
+	"github.com/provide-io/tofusoup/proto/kv"
+)
+
+// maxValueBytesEnv supplies --max-value-bytes to servers a client spawns
+const maxValueBytesEnv = "KV_MAX_VALUE_BYTES"
+
+// maxValueBytes returns the limit from flag if set, or else
+// KV_MAX_VALUE_BYTES
+func maxValueBytes(flag int64) (int64, error) {
+	return byteLimit(flag, maxValueBytesEnv)
+}
+
+// byteLimit returns flag if set, or else the byte count in env
+func byteLimit(flag int64, env string) (int64, error) {
+	if flag != 0 {
+		return flag, nil
+	}
+	value := os.Getenv(env)
+	if value == "" {
+		return 0, nil
+	}
+	limit, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || limit < 0 {
+		return 0, fmt.Errorf("invalid %s %q: expected a byte count", env, value)
+	}
+	return limit, nil
+}
+
+// kvKeyStat is one key's entry in a stat report. The call counters cover
+// every Put and Get of the key with a valid key since the server started;
+// response bytes only count responses that were sent.
+type kvKeyStat struct {
+	Key           string `json:"key"`
+	Stored        bool   `json:"stored"`
+	Size          int64  `json:"size"`
+	SHA256        string `json:"sha256"`
+	Puts          int64  `json:"puts"`
+	Gets          int64  `json:"gets"`
+	RejectedPuts  int64  `json:"rejected_puts"`
+	RequestBytes  int64  `json:"request_bytes"`
+	ResponseBytes int64  `json:"response_bytes"`
+}
+
+// kvStatReport is the result of a Stat call
+type kvStatReport struct {
+	Prefix        string      `json:"prefix"`
+	Keys          []kvKeyStat `json:"keys"`
+	MaxValueBytes int64       `json:"max_value_bytes"`
+	StoredBytes   int64       `json:"stored_bytes"`
+	RequestBytes  int64       `json:"request_bytes"`
+	ResponseBytes int64       `json:"response_bytes"`
+}
+
+// Outcomes keyStats.record distinguishes
+const (
+	statOK       = "ok"
+	statFailed   = "failed"
+	statTooLarge = "too_large"
+)
+
+// keyStats accumulates per-key call counts and message sizes for Stat
+type keyStats struct {
+	mu   sync.Mutex
+	keys map[string]*kvKeyStat
+}
+
+func newKeyStats() *keyStats {
+	return &keyStats{keys: map[string]*kvKeyStat{}}
+}
+
+// record counts a Put or Get of key. resp is only sized if outcome is
+// statOK.
+func (s *keyStats) record(method, key string, req, resp protobuf.Message, outcome string) {
+	var respBytes int64
+	if outcome == statOK {
+		respBytes = int64(protobuf.Size(resp))
+	}
+	s.recordBytes(method, key, int64(protobuf.Size(req)), respBytes, outcome)
+}
+
+// recordStream counts a GetStream of key as a Get whose response was the
+// sent bytes of chunks, even if the stream then failed
+func (s *keyStats) recordStream(key string, req protobuf.Message, sent int64, outcome string) {
+	s.recordBytes("Get", key, int64(protobuf.Size(req)), sent, outcome)
+}
+
+func (s *keyStats) recordBytes(method, key string, reqBytes, respBytes int64, outcome string) {
+	kvMetrics.Add("request_bytes_total", reqBytes)
+	kvMetrics.Add("response_bytes_total", respBytes)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stat, ok := s.keys[key]
+	if !ok {
+		stat = &kvKeyStat{Key: key}
+		s.keys[key] = stat
+	}
+	switch method {
+	case "Put":
+		stat.Puts++
+	case "Get":
+		stat.Gets++
+	}
+	if outcome == statTooLarge {
+		stat.RejectedPuts++
+	}
+	stat.RequestBytes += reqBytes
+	stat.ResponseBytes += respBytes
+}
+
+// snapshot copies the counters for every key seen
+func (s *keyStats) snapshot() map[string]kvKeyStat {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make(map[string]kvKeyStat, len(s.keys))
+	for key, stat := range s.keys {
+		out[key] = *stat
+	}
+	return out
+}
+
+// stat reports keys under prefix that are stored or have been called,
+// along with totals over all keys
+func (m *GRPCServer) stat(prefix string) (*kvStatReport, error) {
+	report := &kvStatReport{Prefix: prefix, Keys: []kvKeyStat{}, MaxValueBytes: m.Options.MaxValueBytes}
+	counters := m.stats.snapshot()
+
+	stored, err := m.Impl.List("")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list keys: %w", err)
+	}
+	values := map[string]kvKeyStat{}
+	for _, key := range stored {
+		value, err := m.Impl.Get(key)
+		if os.IsNotExist(err) {
+			// Evicted or compacted since it was listed
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read key %s: %w", key, err)
+		}
+		values[key] = kvKeyStat{Stored: true, Size: int64(len(value)), SHA256: sha256Hex(value)}
+		report.StoredBytes += int64(len(value))
+	}
+
+	for key, stat := range counters {
+		report.RequestBytes += stat.RequestBytes
+		report.ResponseBytes += stat.ResponseBytes
+		if _, ok := values[key]; !ok && strings.HasPrefix(key, prefix) {
+			report.Keys = append(report.Keys, stat)
+		}
+	}
+	for key, value := range values {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		stat := counters[key]
+		stat.Key, stat.Stored, stat.Size, stat.SHA256 = key, true, value.Size, value.SHA256
+		report.Keys = append(report.Keys, stat)
+	}
+	sort.Slice(report.Keys, func(i, j int) bool { return report.Keys[i].Key < report.Keys[j].Key })
+	return report, nil
+}
+
+func (m *GRPCServer) Stat(ctx context.Context, req *proto.StatRequest) (*proto.StatResponse, error) {
+	m.logger.Debug("📡📊 handling Stat request", "prefix", req.Prefix)
+	m.observeDeadline(ctx, "Stat")
+	if err := m.injectFault(ctx, "Stat"); err != nil {
+		return nil, err
+	}
+
+	if err := m.throttle("Stat"); err != nil {
+		return nil, err
+	}
+
+	report, err := m.stat(req.Prefix)
+	if err != nil {
+		countRequest("Stat", "failed")
+		m.logger.Error("📡❌ Stat operation failed", "prefix", req.Prefix, "error", err)
+		return nil, err
+	}
+	countRequest("Stat", "ok")
+
+	resp := &proto.StatResponse{
+		MaxValueBytes: report.MaxValueBytes,
+		StoredBytes:   report.StoredBytes,
+		RequestBytes:  report.RequestBytes,
+		ResponseBytes: report.ResponseBytes,
+	}
+	for _, stat := range report.Keys {
+		resp.Keys = append(resp.Keys, &proto.KeyStat{
+			Key:           stat.Key,
+			Stored:        stat.Stored,
+			Size:          stat.Size,
+			Sha256:        stat.SHA256,
+			Puts:          stat.Puts,
+			Gets:          stat.Gets,
+			RejectedPuts:  stat.RejectedPuts,
+			RequestBytes:  stat.RequestBytes,
+			ResponseBytes: stat.ResponseBytes,
+		})
+	}
+
+	m.logger.Debug("📡✅ Stat operation completed successfully",
+		"prefix", req.Prefix,
+		"keys", len(resp.Keys))
+	return resp, nil
+}
+
+// Stat asks the server for size stats of the keys under prefix
+func (m *GRPCClient) Stat(prefix string) (*kvStatReport, error) {
+	resp, err := m.client.Stat(context.Background(), &proto.StatRequest{Prefix: prefix})
+	if err != nil {
+		m.logger.Error("🌐❌ Stat request failed", "prefix", prefix, "error", err)
+		return nil, err
+	}
+	report := &kvStatReport{
+		Prefix:        prefix,
+		Keys:          make([]kvKeyStat, 0, len(resp.Keys)),
+		MaxValueBytes: resp.MaxValueBytes,
+		StoredBytes:   resp.StoredBytes,
+		RequestBytes:  resp.RequestBytes,
+		ResponseBytes: resp.ResponseBytes,
+	}
+	for _, stat := range resp.Keys {
+		report.Keys = append(report.Keys, kvKeyStat{
+			Key:           stat.Key,
+			Stored:        stat.Stored,
+			Size:          stat.Size,
+			SHA256:        stat.Sha256,
+			Puts:          stat.Puts,
+			Gets:          stat.Gets,
+			RejectedPuts:  stat.RejectedPuts,
+			RequestBytes:  stat.RequestBytes,
+			ResponseBytes: stat.ResponseBytes,
+		})
+	}
+	return report, nil
+}
+
+func initKVStatCmd() *cobra.Command {
+	var address string
+	var tlsCurve string
+	var outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   "stat [prefix]",
+		Short: "Show per-key value sizes and request/response bytes",
+		Long: `Show each key under prefix with its stored value size and SHA-256, and
+the Puts and Gets made for it since the server started: how many, how many
+Puts were rejected by --max-value-bytes, and the serialized request and
+response bytes they carried. Keys that were called but never stored are
+included. Totals cover all keys, not just those under prefix.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if outputFormat != "text" && outputFormat != "json" {
+				return fmt.Errorf("unknown --output-format %q (expected text or json)", outputFormat)
+			}
+			prefix := ""
+			if len(args) > 0 {
+				prefix = args[0]
+			}
+
+			client, raw, err := dispensePlugin(address, tlsCurve, "kv_grpc")
+			if err != nil {
+				return err
+			}
+			defer client.Kill()
+
+			grpcClient, ok := raw.(*GRPCClient)
+			if !ok {
+				return fmt.Errorf("unexpected KV client type %T", raw)
+			}
+
+			report, err := grpcClient.Stat(prefix)
+			if err != nil {
+				printStatusJSON(err)
+				return fmt.Errorf("failed to stat keys: %w", err)
+			}
+
+			if outputFormat == "json" {
+				return json.NewEncoder(os.Stdout).Encode(report)
+			}
+			fmt.Printf("%-24s %6s %10s %6s %6s %8s %10s %10s\n",
+				"KEY", "STORED", "SIZE", "PUTS", "GETS", "REJECTED", "REQ_BYTES", "RESP_BYTES")
+			for _, stat := range report.Keys {
+				fmt.Printf("%-24s %6t %10d %6d %6d %8d %10d %10d\n",
+					stat.Key, stat.Stored, stat.Size, stat.Puts, stat.Gets, stat.RejectedPuts, stat.RequestBytes, stat.ResponseBytes)
+			}
+			limit := "unlimited"
+			if report.MaxValueBytes > 0 {
+				limit = fmt.Sprintf("%d bytes", report.MaxValueBytes)
+			}
+			fmt.Printf("stored %d bytes; %d request bytes, %d response bytes; max value size %s\n",
+				report.StoredBytes, report.RequestBytes, report.ResponseBytes, limit)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&address, "address", "", "Address of existing server (e.g., 127.0.0.1:50051)")
+	cmd.Flags().StringVar(&tlsCurve, "tls-curve", "auto", "Client cert curve: auto (detect from server), secp256r1, secp384r1, secp521r1")
+	cmd.Flags().StringVar(&outputFormat, "output-format", "text", "Output format: text or json")
+	return cmd
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/gofrs/flock"
+	"github.com/hashicorp/go-plugin"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	protobuf "google.golang.org/protobuf/proto"
+
+	"github.com/provide-io/tofusoup/proto/kv"
+)
+
+const (
+	// streamChunkDefault is the chunk size of a GetStream that asks for none
+	streamChunkDefault = 1 << 20
+	// streamChunkMax keeps chunks well under gRPC's default 4 MiB message
+	// limit
+	streamChunkMax = 3 << 20
+	// maxValueMemoryEnv supplies --max-value-memory to servers a client
+	// spawns
+	maxValueMemoryEnv = "KV_MAX_VALUE_MEMORY"
+)
+
+// maxValueMemory returns the ceiling from flag if set, or else
+// KV_MAX_VALUE_MEMORY
+func maxValueMemory(flag int64) (int64, error) {
+	return byteLimit(flag, maxValueMemoryEnv)
+}
+
+// streamingKV is storage that can serve a value without loading it whole
+type streamingKV interface {
+	// OpenValue opens key's value for reading and returns its size. Writes
+	// to key wait until the reader is closed.
+	OpenValue(key string) (io.ReadCloser, int64, error)
+}
+
+// lockedValue is a value file read under a shared lock on it
+type lockedValue struct {
+	*os.File
+	lock *flock.Flock
+}
+
+func (v *lockedValue) Close() error {
+	err := v.File.Close()
+	if unlockErr := v.lock.Unlock(); err == nil {
+		err = unlockErr
+	}
+	return err
+}
+
+// OpenValue opens key's value under a shared lock, so a Put of key waits
+// for the reader rather than truncating the file under it
+func (k *KVImpl) OpenValue(key string) (io.ReadCloser, int64, error) {
+	filePath := k.keyPath(key)
+	// Opened without O_CREATE, so locking a missing key fails instead of
+	// creating an empty value
+	lock := flock.New(filePath, flock.SetFlag(os.O_RDONLY))
+	if err := lock.RLock(); err != nil {
+		return nil, 0, err
+	}
+	file, err := os.Open(filePath)
+	if err != nil {
+		lock.Unlock()
+		return nil, 0, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		lock.Unlock()
+		return nil, 0, err
+	}
+	k.logger.Debug("🗄️📥 opened value for streaming", "key", key, "size", info.Size())
+	return &lockedValue{File: file, lock: lock}, info.Size(), nil
+}
+
+// getValue reads key's value for a Get, refusing values over the memory
+// ceiling with OVER_MEMORY_CEILING, counted as rejected. The value is read
+// from the handle its size came from, and never past the ceiling, so a Put
+// racing the Get can't slip a larger value through.
+func (m *GRPCServer) getValue(key string) ([]byte, error) {
+	ceiling := m.Options.MaxValueMemory
+	opener, ok := m.Impl.(streamingKV)
+	if !ok || ceiling <= 0 {
+		return m.Impl.Get(key)
+	}
+
+	value, size, err := opener.OpenValue(key)
+	if err != nil {
+		return nil, err
+	}
+	defer value.Close()
+	var data []byte
+	if size <= ceiling {
+		if data, err = io.ReadAll(io.LimitReader(value, ceiling+1)); err != nil {
+			return nil, err
+		}
+		size = max(size, int64(len(data)))
+	}
+	if size > ceiling {
+		countRequest("Get", "rejected")
+		m.logger.Warn("📡❌ rejecting Get of a value over the memory ceiling", "key", key, "size", size, "max_value_memory", ceiling)
+		return nil, errOverMemoryCeiling(key, size, ceiling)
+	}
+	return data, nil
+}
+
+// streamChunkSize is the chunk size a GetStream gets for the size it asked
+// for: the default if none, and never over streamChunkMax or the memory
+// ceiling
+func (m *GRPCServer) streamChunkSize(requested int32) int {
+	size := int64(requested)
+	if size == 0 {
+		size = streamChunkDefault
+	}
+	size = min(size, streamChunkMax)
+	if ceiling := m.Options.MaxValueMemory; ceiling > 0 {
+		size = min(size, ceiling)
+	}
+	return int(size)
+}
+
+func (m *GRPCServer) GetStream(req *proto.GetStreamRequest, stream proto.KV_GetStreamServer) error {
+	ctx := stream.Context()
+	m.logger.Debug("📡📥 handling GetStream request", "key", req.Key, "chunk_size", req.ChunkSize)
+	reportUnknownFields(ctx, "GetStream", req, m.logger)
+	m.observeDeadline(ctx, "GetStream")
+	if err := m.injectFault(ctx, "GetStream"); err != nil {
+		return err
+	}
+
+	if err := m.throttle("GetStream"); err != nil {
+		return err
+	}
+
+	if err := validateKey(req.Key); err != nil {
+		countRequest("GetStream", "rejected")
+		m.logger.Warn("📡❌ rejecting GetStream with invalid key", "key", req.Key)
+		return err
+	}
+	if req.ChunkSize < 0 {
+		countRequest("GetStream", "rejected")
+		return errInvalidField("chunk_size", fmt.Sprint(req.ChunkSize), "must not be negative")
+	}
+	opener, ok := m.Impl.(streamingKV)
+	if !ok {
+		countRequest("GetStream", "failed")
+		return status.Errorf(codes.Unimplemented, "storage %T can't stream values", m.Impl)
+	}
+
+	outcome := statFailed
+	var sent int64
+	defer func() { m.stats.recordStream(req.Key, req, sent, outcome) }()
+
+	value, size, err := opener.OpenValue(req.Key)
+	if err != nil {
+		if os.IsNotExist(err) {
+			countRequest("GetStream", "not_found")
+			m.logger.Debug("📡📥 key not found", "key", req.Key)
+			return errNotFound(req.Key)
+		}
+		countRequest("GetStream", "failed")
+		m.logger.Error("📡❌ GetStream operation failed", "key", req.Key, "error", err)
+		return err
+	}
+	defer value.Close()
+
+	// One buffer serves every chunk: Send has encoded a chunk by the time
+	// it returns, so memory use is the chunk size whatever the value's
+	chunkSize := m.streamChunkSize(req.ChunkSize)
+	buf := make([]byte, chunkSize)
+	var offset int64
+	for {
+		n, readErr := io.ReadFull(value, buf)
+		// An empty value still gets one (empty) chunk carrying its size
+		if n > 0 || offset == 0 {
+			chunk := &proto.GetChunk{Data: buf[:n], Offset: offset, TotalSize: size}
+			if err := stream.Send(chunk); err != nil {
+				countRequest("GetStream", "failed")
+				m.logger.Warn("📡❌ GetStream send failed", "key", req.Key, "offset", offset, "error", err)
+				return err
+			}
+			sent += int64(protobuf.Size(chunk))
+			offset += int64(n)
+		}
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		}
+		if readErr != nil {
+			countRequest("GetStream", "failed")
+			m.logger.Error("📡❌ GetStream read failed", "key", req.Key, "offset", offset, "error", readErr)
+			return readErr
+		}
+	}
+	countRequest("GetStream", "ok")
+	m.quota.touch(req.Key)
+	outcome = statOK
+
+	m.logger.Debug("📡✅ GetStream operation completed successfully", "key", req.Key, "size", size, "chunk_size", chunkSize)
+	return nil
+}
+
+// getStreaming reads key with GetStream, writing each chunk to w as it
+// arrives, and returns the value's size. Chunks must arrive in order and
+// add up to the size they report.
+func getStreaming(ctx context.Context, rpcClient plugin.ClientProtocol, key string, chunkSize int32, w io.Writer) (int64, error) {
+	conn, err := pluginConn(rpcClient)
+	if err != nil {
+		return 0, err
+	}
+	logger.Debug("🌐📥 streaming key", "key", key, "chunk_size", chunkSize)
+	stream, err := proto.NewKVClient(conn).GetStream(ctx, &proto.GetStreamRequest{Key: key, ChunkSize: chunkSize})
+	if err != nil {
+		return 0, err
+	}
+	var offset, total int64
+	chunks := 0
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return offset, err
+		}
+		if chunk.Offset != offset {
+			return offset, fmt.Errorf("chunk %d starts at offset %d, expected %d", chunks, chunk.Offset, offset)
+		}
+		if _, err := w.Write(chunk.Data); err != nil {
+			return offset, err
+		}
+		offset += int64(len(chunk.Data))
+		total = chunk.TotalSize
+		chunks++
+	}
+	if chunks == 0 || offset != total {
+		return offset, fmt.Errorf("stream ended after %d of %d bytes", offset, total)
+	}
+	logger.Debug("🌐✅ streamed key", "key", key, "size", total, "chunks", chunks)
+	return total, nil
+}
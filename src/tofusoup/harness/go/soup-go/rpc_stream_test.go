@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/provide-io/tofusoup/proto/kv"
+)
+
+// growingKV reports each value as smaller than it is, as a Put racing
+// between a size check and the read would make it
+type growingKV struct {
+	*KVImpl
+}
+
+func (g growingKV) OpenValue(key string) (io.ReadCloser, int64, error) {
+	value, _, err := g.KVImpl.OpenValue(key)
+	return value, 1, err
+}
+
+func TestGetEnforcesMemoryCeiling(t *testing.T) {
+	server, _ := serveTestKV(t, KVServerOptions{MaxValueMemory: 8})
+	for key, value := range map[string]string{"small": "12345678", "large": "123456789"} {
+		if err := server.Impl.Put(key, []byte(value)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	resp, err := server.Get(context.Background(), &proto.GetRequest{Key: "small"})
+	if err != nil || string(resp.Value) != "12345678" {
+		t.Errorf("got %v (%v), want a value at the ceiling returned", resp, err)
+	}
+	if _, err := server.Get(context.Background(), &proto.GetRequest{Key: "large"}); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("got %v, want a value over the ceiling rejected", err)
+	}
+}
+
+func TestGetMemoryCeilingCountsBytesRead(t *testing.T) {
+	impl := NewKVImpl(hclog.NewNullLogger(), t.TempDir())
+	if err := impl.Put("k", []byte(strings.Repeat("x", 64))); err != nil {
+		t.Fatal(err)
+	}
+	server := newGRPCServer(growingKV{impl}, KVServerOptions{MaxValueMemory: 8}, hclog.NewNullLogger())
+	_, err := server.getValue("k")
+	if status.Code(err) != codes.ResourceExhausted || !strings.Contains(err.Error(), "is 9 bytes") {
+		t.Errorf("got %v, want the read stopped one byte past the ceiling and rejected", err)
+	}
+}
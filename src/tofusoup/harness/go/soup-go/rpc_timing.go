@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// rpcTiming makes RPC client commands record per-call latency and print a
+// {"timing": ...} summary when they finish
+var rpcTiming bool
+
+// timingBucketsMs are the upper bounds of the latency histogram buckets
+var timingBucketsMs = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+
+// timingBucket is a non-empty histogram bucket: Count calls took at most
+// LeMs milliseconds and more than the previous bucket's bound. The last
+// bucket's bound is "+Inf".
+type timingBucket struct {
+	LeMs  interface{} `json:"le_ms"`
+	Count int         `json:"count"`
+}
+
+// methodTiming summarizes the calls to one method
+type methodTiming struct {
+	Calls     int            `json:"calls"`
+	Errors    int            `json:"errors"`
+	Latency   latencySummary `json:"latency_ms"`
+	Histogram []timingBucket `json:"histogram"`
+}
+
+// timingReport is printed after an RPC client command run with --timing
+type timingReport struct {
+	methodTiming
+	Methods map[string]methodTiming `json:"methods"`
+}
+
+// callTimings collects the latency of every unary call a command makes
+type callTimings struct {
+	mu      sync.Mutex
+	samples map[string][]time.Duration
+	errors  map[string]int
+}
+
+var clientTimings = &callTimings{samples: map[string][]time.Duration{}, errors: map[string]int{}}
+
+func (t *callTimings) record(method string, d time.Duration, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.samples[method] = append(t.samples[method], d)
+	if err != nil {
+		t.errors[method]++
+	}
+}
+
+// sparseHistogram counts samples into timingBucketsMs, leaving out empty
+// buckets
+func sparseHistogram(samples []time.Duration) []timingBucket {
+	counts := make([]int, len(timingBucketsMs)+1)
+	for _, d := range samples {
+		ms := float64(d) / float64(time.Millisecond)
+		i := sort.SearchFloat64s(timingBucketsMs, ms)
+		counts[i]++
+	}
+	buckets := []timingBucket{}
+	for i, n := range counts {
+		if n == 0 {
+			continue
+		}
+		var le interface{} = "+Inf"
+		if i < len(timingBucketsMs) {
+			le = timingBucketsMs[i]
+		}
+		buckets = append(buckets, timingBucket{LeMs: le, Count: n})
+	}
+	return buckets
+}
+
+func summarizeMethod(samples []time.Duration, errors int) methodTiming {
+	return methodTiming{
+		Calls:     len(samples),
+		Errors:    errors,
+		Latency:   summarizeLatencies(samples),
+		Histogram: sparseHistogram(samples),
+	}
+}
+
+// report summarizes all calls and each method's calls
+func (t *callTimings) report() timingReport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	report := timingReport{Methods: map[string]methodTiming{}}
+	var all []time.Duration
+	errors := 0
+	for method, samples := range t.samples {
+		report.Methods[method] = summarizeMethod(samples, t.errors[method])
+		all = append(all, samples...)
+		errors += t.errors[method]
+	}
+	report.methodTiming = summarizeMethod(all, errors)
+	return report
+}
+
+// timingDialOptions returns an interceptor recording the latency of every
+// unary call but go-plugin's own when --timing is set
+func timingDialOptions() []grpc.DialOption {
+	if !rpcTiming {
+		return nil
+	}
+	return []grpc.DialOption{grpc.WithChainUnaryInterceptor(
+		func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			if authExempt(method) {
+				return invoker(ctx, method, req, reply, cc, opts...)
+			}
+			start := time.Now()
+			err := invoker(ctx, method, req, reply, cc, opts...)
+			clientTimings.record(method, time.Since(start), err)
+			return err
+		},
+	)}
+}
+
+// printTimings writes the --timing summary to stdout as one JSON line after
+// the command's own output. Commands that made no calls print nothing.
+func printTimings() {
+	if !rpcTiming {
+		return
+	}
+	report := clientTimings.report()
+	if report.Calls == 0 {
+		return
+	}
+	if err := json.NewEncoder(os.Stdout).Encode(map[string]interface{}{"timing": report}); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to encode timing: %v\n", err)
+	}
+}
@@ -14,7 +14,6 @@ import (
 	"net"
 	"os"
 	"strings"
-	"time"
 
 	"github.com/hashicorp/go-hclog"
 )
@@ -54,19 +53,21 @@ func generateCertWithCurve(logger hclog.Logger, curveName string) ([]byte, []byt
 		return nil, nil, fmt.Errorf("failed to generate serial number: %w", err)
 	}
 
-	// Create certificate template
+	// Create certificate template, valid for --cert-ttl from the
+	// --clock-skew adjusted time
+	now := certNow()
 	template := &x509.Certificate{
 		SerialNumber: serialNumber,
 		Subject: pkix.Name{
 			CommonName:   "tofusoup.rpc.server",
 			Organization: []string{"TofuSoup"},
 		},
-		NotBefore:             time.Now(),
-		NotAfter:              time.Now().Add(365 * 24 * time.Hour),
+		NotBefore:             now,
+		NotAfter:              now.Add(certTTL),
 		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
 		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
 		BasicConstraintsValid: true,
-		DNSNames:              []string{"localhost"},
+		DNSNames:              append([]string{"localhost"}, rpcTLSDNSNames...),
 		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
 	}
 
@@ -119,10 +120,17 @@ func createTLSProvider(logger hclog.Logger, curveName string) func() (*tls.Confi
 		tlsConfig := &tls.Config{
 			Certificates: []tls.Certificate{cert},
 			MinVersion:   tls.VersionTLS12,
+			Time:         certNow,
 		}
 
-		// If client certificate is provided, configure mTLS
-		if clientCertPEM != "" {
+		// A handed-off client certificate takes the place of the one go-plugin
+		// would have passed a spawned server
+		if rpcClientCertHandoff != "" {
+			if err := requireHandedOffClientCert(tlsConfig, certPEM, rpcClientCertHandoff, logger); err != nil {
+				return nil, err
+			}
+		} else if clientCertPEM != "" {
+			// If client certificate is provided, configure mTLS
 			logger.Debug("Client certificate found, configuring mTLS")
 			certPool := x509.NewCertPool()
 			if !certPool.AppendCertsFromPEM([]byte(clientCertPEM)) {
@@ -132,7 +140,7 @@ func createTLSProvider(logger hclog.Logger, curveName string) func() (*tls.Confi
 			tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
 		}
 
-		logger.Info("TLS configuration created successfully", "curve", curveName, "mtls", clientCertPEM != "")
+		logger.Info("TLS configuration created successfully", "curve", curveName, "mtls", clientCertPEM != "" || rpcClientCertHandoff != "")
 		return tlsConfig, nil
 	}
 }
@@ -210,6 +218,7 @@ func parseCertificateFromHandshake(certBase64 string, hostname string, logger hc
 		InsecureSkipVerify: false,  // We're properly verifying with the cert pool
 		MinVersion:         tls.VersionTLS12,
 		ServerName:         serverName,  // Set to a DNS name that matches the cert SANs
+		Time:               certNow,     // Verify validity against the --clock-skew adjusted time
 	}
 
 	logger.Info("Created TLS config with server certificate for mTLS",
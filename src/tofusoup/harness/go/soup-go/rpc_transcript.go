@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+// rpcTranscript is the file RPC client commands record their calls to
+var rpcTranscript string
+
+// redactedValue replaces secret metadata values in transcripts
+const redactedValue = "[REDACTED]"
+
+// transcriptStatus is a call's gRPC status
+type transcriptStatus struct {
+	Code    string `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+// transcriptEntry is one line of a transcript: a unary call's request, the
+// metadata it was sent with, and what came back
+type transcriptEntry struct {
+	Seq       int                 `json:"seq"`
+	Method    string              `json:"method"`
+	Metadata  map[string][]string `json:"metadata,omitempty"`
+	Request   json.RawMessage     `json:"request"`
+	Response  json.RawMessage     `json:"response,omitempty"`
+	Status    transcriptStatus    `json:"status"`
+	ElapsedMs float64             `json:"elapsed_ms"`
+}
+
+// transcriptWriter appends entries to the --transcript file, creating it on
+// the first call
+type transcriptWriter struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+	seq  int
+}
+
+var clientTranscript = &transcriptWriter{}
+
+func (w *transcriptWriter) write(entry transcriptEntry) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		file, err := os.Create(w.path)
+		if err != nil {
+			return fmt.Errorf("failed to create transcript: %w", err)
+		}
+		w.file = file
+	}
+	w.seq++
+	entry.Seq = w.seq
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode transcript entry: %w", err)
+	}
+	_, err = w.file.Write(append(line, '\n'))
+	return err
+}
+
+// close closes the transcript file, if any call opened it
+func (w *transcriptWriter) close() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file != nil {
+		w.file.Close()
+		w.file = nil
+	}
+}
+
+// secretMetadataKey reports whether a metadata key carries a secret whose
+// value must not be written to a transcript
+func secretMetadataKey(key string) bool {
+	key = strings.ToLower(key)
+	if key == authMetadataKey || key == "cookie" {
+		return true
+	}
+	for _, word := range []string{"token", "secret", "password", "api-key"} {
+		if strings.Contains(key, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// transcriptMetadata returns the metadata a call is sent with, secrets
+// redacted. The bearer token is added by per-RPC credentials after
+// interceptors run, so it's listed here if the client sends one.
+func transcriptMetadata(ctx context.Context) map[string][]string {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	out := map[string][]string{}
+	for key, values := range md {
+		if secretMetadataKey(key) {
+			values = []string{redactedValue}
+		}
+		out[key] = values
+	}
+	if _, send, _ := clientAuthToken(); send {
+		out[authMetadataKey] = []string{redactedValue}
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+// marshalTranscriptMessage renders a request or response as protojson
+func marshalTranscriptMessage(msg interface{}) (json.RawMessage, error) {
+	m, ok := msg.(protobuf.Message)
+	if !ok {
+		return nil, fmt.Errorf("%T is not a protobuf message", msg)
+	}
+	return protojson.Marshal(m)
+}
+
+// transcriptDialOptions returns an interceptor recording every unary call
+// but go-plugin's own to the --transcript file
+func transcriptDialOptions(logger hclog.Logger) []grpc.DialOption {
+	if rpcTranscript == "" {
+		return nil
+	}
+	clientTranscript.path = rpcTranscript
+	return []grpc.DialOption{grpc.WithChainUnaryInterceptor(
+		func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			if authExempt(method) {
+				return invoker(ctx, method, req, reply, cc, opts...)
+			}
+			start := time.Now()
+			err := invoker(ctx, method, req, reply, cc, opts...)
+			entry := transcriptEntry{
+				Method:    method,
+				Metadata:  transcriptMetadata(ctx),
+				ElapsedMs: float64(time.Since(start).Microseconds()) / 1000,
+			}
+			st := status.Convert(err)
+			entry.Status = transcriptStatus{Code: st.Code().String(), Message: st.Message()}
+			var encodeErr error
+			if entry.Request, encodeErr = marshalTranscriptMessage(req); encodeErr == nil && err == nil {
+				entry.Response, encodeErr = marshalTranscriptMessage(reply)
+			}
+			if encodeErr == nil {
+				encodeErr = clientTranscript.write(entry)
+			}
+			if encodeErr != nil {
+				logger.Warn("📼 failed to record call", "method", method, "error", encodeErr)
+			}
+			return err
+		},
+	)}
+}
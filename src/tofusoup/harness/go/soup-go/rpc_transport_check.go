@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/provide-io/tofusoup/proto/kv"
+)
+
+// transportModes are the transports 'rpc validate transport' knows how to
+// check
+var transportModes = []string{"tcp", "unix", "npipe"}
+
+// Transport check statuses. A transport is unsupported when the server
+// can't listen on it at all (e.g. named pipes off Windows) and failed when
+// it listens but a client can't complete a round trip over it.
+const (
+	transportSupported   = "supported"
+	transportUnsupported = "unsupported"
+	transportFailed      = "failed"
+)
+
+// transportResult is the outcome of checking one transport
+type transportResult struct {
+	Transport  string  `json:"transport"`
+	Status     string  `json:"status"`
+	Address    string  `json:"address,omitempty"`
+	DurationMs float64 `json:"duration_ms"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// transportReport is the structured output of 'rpc validate transport'
+type transportReport struct {
+	Harness    string            `json:"harness"`
+	Version    string            `json:"version"`
+	OS         string            `json:"os"`
+	Transports []transportResult `json:"transports"`
+}
+
+// listenTransport opens a server listener for mode in dir, returning the
+// dial target and any extra dial options
+func listenTransport(mode, dir string) (net.Listener, string, []grpc.DialOption, error) {
+	switch mode {
+	case "tcp":
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			return nil, "", nil, err
+		}
+		return listener, listener.Addr().String(), nil, nil
+	case "unix":
+		path := filepath.Join(dir, "kv.sock")
+		listener, err := net.Listen("unix", path)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		return listener, "unix://" + path, nil, nil
+	case "npipe":
+		addr, err := parsePipeAddress(fmt.Sprintf("soup-go-transport-%d", os.Getpid()))
+		if err != nil {
+			return nil, "", nil, err
+		}
+		listener, err := listenPipe(addr)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		dialer := grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return dialPipe(ctx, addr)
+		})
+		return listener, "passthrough:///" + addr.String(), []grpc.DialOption{dialer}, nil
+	}
+	return nil, "", nil, fmt.Errorf("unknown transport %q", mode)
+}
+
+// checkTransport serves KV over mode in-process and does a Put/Get round
+// trip through a client connected over the same transport
+func checkTransport(ctx context.Context, logger hclog.Logger, mode string, timeout time.Duration) transportResult {
+	result := transportResult{Transport: mode}
+	start := time.Now()
+	fail := func(status string, err error) transportResult {
+		result.Status = status
+		result.Error = err.Error()
+		result.DurationMs = float64(time.Since(start).Microseconds()) / 1000
+		return result
+	}
+
+	// Unix socket paths are length-limited, so keep the directory short
+	dir, err := os.MkdirTemp("", "soup-tr-")
+	if err != nil {
+		return fail(transportFailed, fmt.Errorf("failed to create temp dir: %w", err))
+	}
+	defer os.RemoveAll(dir)
+
+	listener, target, dialOpts, err := listenTransport(mode, dir)
+	if err != nil {
+		return fail(transportUnsupported, fmt.Errorf("failed to listen: %w", err))
+	}
+	result.Address = strings.TrimPrefix(strings.TrimPrefix(target, "unix://"), "passthrough:///")
+
+	server := grpc.NewServer()
+	proto.RegisterKVServer(server, newGRPCServer(NewKVImpl(logger, dir), KVServerOptions{}, logger))
+	go server.Serve(listener)
+	defer server.Stop()
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	dialOpts = append(dialOpts, grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithBlock())
+	conn, err := grpc.DialContext(ctx, target, dialOpts...)
+	if err != nil {
+		return fail(transportFailed, fmt.Errorf("failed to connect: %w", err))
+	}
+	defer conn.Close()
+
+	client := proto.NewKVClient(conn)
+	want := []byte("transport-" + mode)
+	if _, err := client.Put(ctx, &proto.PutRequest{Key: "transport-check", Value: want}); err != nil {
+		return fail(transportFailed, fmt.Errorf("Put failed: %w", err))
+	}
+	resp, err := client.Get(ctx, &proto.GetRequest{Key: "transport-check"})
+	if err != nil {
+		return fail(transportFailed, fmt.Errorf("Get failed: %w", err))
+	}
+	if !bytes.Equal(resp.Value, want) {
+		return fail(transportFailed, fmt.Errorf("Get returned %q, want %q", resp.Value, want))
+	}
+
+	result.Status = transportSupported
+	result.DurationMs = float64(time.Since(start).Microseconds()) / 1000
+	return result
+}
+
+func initValidateTransportCmd() *cobra.Command {
+	var modes []string
+	var timeout time.Duration
+	var outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   "transport",
+		Short: "Check which RPC transports this harness can serve and connect over",
+		Long: `Start the KV server in-process on each transport in --modes (tcp, unix,
+npipe) and do a Put/Get round trip with a client connected over the same
+transport. Each transport is reported as supported, unsupported (the
+server can't listen on it on this platform) or failed (it listens but
+the round trip fails). With --output-format json the report can be used
+by the matrix to skip combinations a harness can't run.
+
+Exits non-zero if any requested transport is not supported.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			known := map[string]bool{}
+			for _, mode := range transportModes {
+				known[mode] = true
+			}
+			for _, mode := range modes {
+				if !known[mode] {
+					return fmt.Errorf("unknown transport %q (expected one of: %s)", mode, strings.Join(transportModes, ", "))
+				}
+			}
+
+			report := transportReport{Harness: "soup-go", Version: version, OS: runtime.GOOS}
+			unsupported := 0
+			for _, mode := range modes {
+				logger.Debug("🔌 checking transport", "transport", mode)
+				result := checkTransport(cmd.Context(), logger, mode, timeout)
+				if result.Status != transportSupported {
+					unsupported++
+				}
+				report.Transports = append(report.Transports, result)
+			}
+
+			if outputFormat == "json" {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				if err := encoder.Encode(report); err != nil {
+					return fmt.Errorf("failed to encode JSON: %w", err)
+				}
+			} else {
+				for _, r := range report.Transports {
+					icon := map[string]string{transportSupported: "✅", transportUnsupported: "➖"}[r.Status]
+					if icon == "" {
+						icon = "❌"
+					}
+					fmt.Printf("%s %-6s %-12s %7.1fms", icon, r.Transport, r.Status, r.DurationMs)
+					if r.Error != "" {
+						fmt.Printf("  %s", r.Error)
+					}
+					fmt.Println()
+				}
+			}
+
+			if unsupported > 0 {
+				return fmt.Errorf("%d of %d transports not supported", unsupported, len(modes))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&modes, "modes", []string{"tcp", "unix"}, "Transports to check: "+strings.Join(transportModes, ","))
+	cmd.Flags().DurationVar(&timeout, "timeout", 10*time.Second, "Per-transport connect and round-trip timeout")
+	cmd.Flags().StringVar(&outputFormat, "output-format", "text", "Output format (text, json)")
+	return cmd
+}
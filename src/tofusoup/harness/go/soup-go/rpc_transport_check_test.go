@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func runValidateTransport(t *testing.T, args ...string) ([]byte, error) {
+	t.Helper()
+	cmd := initValidateTransportCmd()
+	cmd.SetArgs(args)
+	cmd.SilenceUsage, cmd.SilenceErrors = true, true
+	return captureStdout(t, cmd.Execute)
+}
+
+func TestValidateTransportCmd(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix sockets are checked on unix platforms")
+	}
+	out, err := runValidateTransport(t, "--modes", "tcp,unix", "--output-format", "json")
+	if err != nil {
+		t.Fatalf("%v: %s", err, out)
+	}
+	var report transportReport
+	if err := json.Unmarshal(out, &report); err != nil {
+		t.Fatalf("%v: %s", err, out)
+	}
+	if report.Harness != "soup-go" || report.OS != runtime.GOOS || len(report.Transports) != 2 {
+		t.Fatalf("got %s", out)
+	}
+	for _, result := range report.Transports {
+		if result.Status != transportSupported || result.Address == "" || result.Error != "" {
+			t.Errorf("%s: got %+v, want a successful round trip", result.Transport, result)
+		}
+	}
+	if addr := report.Transports[1].Address; !strings.HasSuffix(addr, "kv.sock") {
+		t.Errorf("got unix address %q, want the socket path", addr)
+	}
+}
+
+func TestValidateTransportCmdUnsupportedPipe(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("named pipes are supported on Windows")
+	}
+	out, err := runValidateTransport(t, "--modes", "tcp,npipe")
+	if err == nil || err.Error() != "1 of 2 transports not supported" {
+		t.Errorf("got %v, want npipe reported as not supported", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "✅ tcp    supported") ||
+		!strings.HasPrefix(lines[1], "➖ npipe  unsupported") {
+		t.Errorf("got text report:\n%s", out)
+	}
+}
+
+func TestValidateTransportCmdRejectsUnknownMode(t *testing.T) {
+	if _, err := runValidateTransport(t, "--modes", "quic"); err == nil || !strings.Contains(err.Error(), `unknown transport "quic"`) {
+		t.Errorf("got %v, want an unknown transport error", err)
+	}
+}
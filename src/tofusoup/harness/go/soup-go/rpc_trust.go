@@ -0,0 +1,402 @@
+package main
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// trustBundleVersion is bumped when trustBundle changes incompatibly
+const trustBundleVersion = 1
+
+// trustBundle carries the CA certificate clients need to trust a server on
+// another host, written to a shared directory or stored in the KV store. It
+// never holds a private key.
+type trustBundle struct {
+	Version int    `json:"version"`
+	Name    string `json:"name"`
+	CAPEM   string `json:"ca_pem"`
+	// FingerprintSHA256 is the hex SHA-256 of the certificate's DER bytes
+	FingerprintSHA256 string `json:"fingerprint_sha256"`
+	// Fingerprint is the certificate's digest in FingerprintAlgorithm, the
+	// exporter's --digest-alg. Older bundles omit both.
+	Fingerprint          string    `json:"fingerprint,omitempty"`
+	FingerprintAlgorithm string    `json:"fingerprint_algorithm,omitempty"`
+	Subject              string    `json:"subject"`
+	DNSNames             []string  `json:"dns_names,omitempty"`
+	IPAddresses          []string  `json:"ip_addresses,omitempty"`
+	NotAfter             time.Time `json:"not_after"`
+	Curve                string    `json:"curve,omitempty"`
+	Host                 string    `json:"host"`
+	ExportedBy           string    `json:"exported_by"`
+	CreatedAt            time.Time `json:"created_at"`
+}
+
+// newTrustBundle describes cert as a bundle called name
+func newTrustBundle(name string, cert *x509.Certificate) *trustBundle {
+	host, _ := os.Hostname()
+	bundle := &trustBundle{
+		Version:              trustBundleVersion,
+		Name:                 name,
+		CAPEM:                string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})),
+		FingerprintSHA256:    sha256Hex(cert.Raw),
+		Fingerprint:          fingerprint(cert.Raw),
+		FingerprintAlgorithm: currentDigest.Name(),
+		Subject:              cert.Subject.String(),
+		DNSNames:             cert.DNSNames,
+		NotAfter:             cert.NotAfter.UTC(),
+		Host:                 host,
+		ExportedBy:           "soup-go " + version,
+		CreatedAt:            time.Now().UTC(),
+	}
+	for _, ip := range cert.IPAddresses {
+		bundle.IPAddresses = append(bundle.IPAddresses, ip.String())
+	}
+	if curve, err := detectCurveFromCert(cert, logger); err == nil {
+		bundle.Curve = curve
+	}
+	return bundle
+}
+
+// verify checks that the bundle is one this version reads, that its
+// certificate parses and matches the recorded fingerprint, and that it
+// hasn't expired. It returns the certificate.
+func (b *trustBundle) verify(now time.Time) (*x509.Certificate, error) {
+	if b.Version != trustBundleVersion {
+		return nil, fmt.Errorf("unsupported trust bundle version %d (want %d)", b.Version, trustBundleVersion)
+	}
+	block, _ := pem.Decode([]byte(b.CAPEM))
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("trust bundle %q has no PEM certificate", b.Name)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse trust bundle certificate: %w", err)
+	}
+	if got := sha256Hex(cert.Raw); !strings.EqualFold(got, b.FingerprintSHA256) {
+		return nil, fmt.Errorf("trust bundle %q fingerprint mismatch: recorded %s, certificate is %s", b.Name, b.FingerprintSHA256, got)
+	}
+	// The SHA-256 check already binds the certificate, so an algorithm this
+	// build doesn't have is skipped rather than failing the import
+	if alg, ok := digestAlgs[b.FingerprintAlgorithm]; ok {
+		if got := digestHex(alg, cert.Raw); !strings.EqualFold(got, b.Fingerprint) {
+			return nil, fmt.Errorf("trust bundle %q %s fingerprint mismatch: recorded %s, certificate is %s", b.Name, alg.Name(), b.Fingerprint, got)
+		}
+	}
+	if now.After(cert.NotAfter) {
+		return nil, fmt.Errorf("trust bundle %q certificate expired at %s", b.Name, cert.NotAfter.UTC().Format(time.RFC3339))
+	}
+	return cert, nil
+}
+
+// trustCertFromFile reads the first PEM certificate in path
+func trustCertFromFile(path string) (*x509.Certificate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read certificate: %w", err)
+	}
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("no PEM certificate in %s", path)
+		}
+		if block.Type == "CERTIFICATE" {
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse certificate in %s: %w", path, err)
+			}
+			return cert, nil
+		}
+	}
+}
+
+// trustCertFromHandshake reads the server certificate from field 6 of a
+// go-plugin handshake line
+func trustCertFromHandshake(handshake string) (*x509.Certificate, error) {
+	parts := strings.Split(strings.TrimSpace(handshake), "|")
+	if len(parts) < 6 || parts[5] == "" {
+		return nil, fmt.Errorf("handshake has no server certificate (is the server running with TLS?)")
+	}
+	// go-plugin strips the base64 padding
+	der, err := base64.RawStdEncoding.DecodeString(strings.TrimRight(parts[5], "="))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode handshake certificate: %w", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse handshake certificate: %w", err)
+	}
+	return cert, nil
+}
+
+// trustBundlePath is where a bundle called name lives in dir
+func trustBundlePath(dir, name string) string {
+	return filepath.Join(dir, name+".trust.json")
+}
+
+// writeTrustBundleFile writes the bundle to dir, replacing any older bundle
+// of the same name atomically so readers on other hosts never see half a file
+func writeTrustBundleFile(dir string, bundle *trustBundle) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create trust directory: %w", err)
+	}
+	data, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode trust bundle: %w", err)
+	}
+	path := trustBundlePath(dir, bundle.Name)
+	tmp, err := os.CreateTemp(dir, "."+bundle.Name+".trust-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to write trust bundle: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("failed to write trust bundle: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("failed to write trust bundle: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return "", fmt.Errorf("failed to write trust bundle: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", fmt.Errorf("failed to write trust bundle: %w", err)
+	}
+	return path, nil
+}
+
+// decodeTrustBundle parses a bundle read from a file or the KV store
+func decodeTrustBundle(data []byte, source string) (*trustBundle, error) {
+	var bundle trustBundle
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		return nil, fmt.Errorf("failed to decode trust bundle from %s: %w", source, err)
+	}
+	return &bundle, nil
+}
+
+// trustKVKey is the KV key a bundle called name is stored under
+func trustKVKey(prefix, name string) string {
+	return prefix + name
+}
+
+func initRPCTrustExportCmd() *cobra.Command {
+	var certFile, handshake, name, dir, kvPrefix, address, tlsCurve, outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Publish a server's CA certificate as a trust bundle",
+		Long: `Write a trust bundle holding a CA certificate and metadata about it
+(fingerprint, subject, SANs, expiry, curve, exporting host) so clients on
+other hosts can trust the server. The certificate comes from --cert-file or
+from the server certificate in a go-plugin --handshake line.
+
+The bundle goes to <dir>/<name>.trust.json with --dir, which suits a shared
+or synced directory, or into the KV store under <kv-prefix><name> with
+--kv-store, using --address or a spawned server as 'kv put' does.`,
+		Example: `  soup-go rpc trust export --cert-file server.pem --dir /shared/trust --name go-server
+  soup-go rpc trust export --handshake "$(cat handshake.txt)" --kv-store --address 10.0.0.5:50051`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			toKV := cmd.Flags().Changed("kv-store")
+			if (dir == "") == !toKV {
+				return fmt.Errorf("exactly one of --dir or --kv-store is required")
+			}
+
+			var cert *x509.Certificate
+			var err error
+			switch {
+			case certFile != "" && handshake != "":
+				return fmt.Errorf("--cert-file and --handshake are mutually exclusive")
+			case certFile != "":
+				cert, err = trustCertFromFile(certFile)
+			case handshake != "":
+				cert, err = trustCertFromHandshake(handshake)
+			default:
+				return fmt.Errorf("one of --cert-file or --handshake is required")
+			}
+			if err != nil {
+				return err
+			}
+
+			bundle := newTrustBundle(name, cert)
+			location := ""
+			if toKV {
+				data, err := json.Marshal(bundle)
+				if err != nil {
+					return fmt.Errorf("failed to encode trust bundle: %w", err)
+				}
+				client, raw, err := dispensePlugin(address, tlsCurve, "kv_grpc")
+				if err != nil {
+					return err
+				}
+				defer client.Kill()
+				key := trustKVKey(kvPrefix, name)
+				if err := raw.(KV).Put(key, data); err != nil {
+					printStatusJSON(err)
+					return fmt.Errorf("failed to put trust bundle: %w", err)
+				}
+				location = "kv:" + key
+			} else {
+				location, err = writeTrustBundleFile(dir, bundle)
+				if err != nil {
+					return err
+				}
+			}
+			logger.Info("🔐📤 exported trust bundle", "name", name, "location", location,
+				"fingerprint", bundle.Fingerprint, "fingerprint_algorithm", bundle.FingerprintAlgorithm)
+
+			if outputFormat == "json" {
+				return json.NewEncoder(os.Stdout).Encode(map[string]interface{}{
+					"name":                  bundle.Name,
+					"location":              location,
+					"fingerprint_sha256":    bundle.FingerprintSHA256,
+					"fingerprint":           bundle.Fingerprint,
+					"fingerprint_algorithm": bundle.FingerprintAlgorithm,
+					"not_after":             bundle.NotAfter,
+				})
+			}
+			fmt.Printf("Exported trust bundle %q to %s (%s %s)\n", bundle.Name, location, bundle.FingerprintAlgorithm, bundle.Fingerprint)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&certFile, "cert-file", "", "PEM file holding the CA (or self-signed server) certificate")
+	cmd.Flags().StringVar(&handshake, "handshake", "", "go-plugin handshake line whose server certificate to export")
+	cmd.Flags().StringVar(&name, "name", "default", "Bundle name, so one directory or store can hold bundles for several servers")
+	cmd.Flags().StringVar(&dir, "dir", "", "Write the bundle to this shared directory")
+	cmd.Flags().Bool("kv-store", false, "Store the bundle in the KV store instead of a directory")
+	cmd.Flags().StringVar(&kvPrefix, "kv-prefix", "trust.", "KV key prefix for --kv-store")
+	cmd.Flags().StringVar(&address, "address", "", "Address of existing server for --kv-store (e.g., 127.0.0.1:50051)")
+	cmd.Flags().StringVar(&tlsCurve, "tls-curve", "auto", "Client cert curve: auto (detect from server), secp256r1, secp384r1, secp521r1")
+	cmd.Flags().StringVar(&outputFormat, "output-format", "text", "Output format (text, json)")
+	return cmd
+}
+
+func initRPCTrustImportCmd() *cobra.Command {
+	var name, dir, kvPrefix, address, tlsCurve, out, want, outputFormat string
+	var wait time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "import",
+		Short: "Read a trust bundle and write its CA certificate for clients",
+		Long: `Read the trust bundle called --name from --dir or the KV store, check
+that its certificate parses, matches the recorded fingerprint (and
+--fingerprint, if given) and hasn't expired, then write the CA certificate
+as PEM to --out, or to stdout without it.
+
+Point clients at the written file, e.g. GRPC_DEFAULT_SSL_ROOTS_FILE_PATH
+for the Python client's manual TLS mode. With --wait, a missing bundle is
+retried until it appears, so a client host can start before the server
+host has exported.`,
+		Example: `  soup-go rpc trust import --dir /shared/trust --name go-server --out ca.pem
+  soup-go rpc trust import --kv-store --address 10.0.0.5:50051 --out ca.pem --wait 30s`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fromKV := cmd.Flags().Changed("kv-store")
+			if (dir == "") == !fromKV {
+				return fmt.Errorf("exactly one of --dir or --kv-store is required")
+			}
+
+			// get reads the bundle, reporting whether a failure only means it
+			// hasn't been exported yet
+			var get func() ([]byte, string, bool, error)
+			if fromKV {
+				client, raw, err := dispensePlugin(address, tlsCurve, "kv_grpc")
+				if err != nil {
+					return err
+				}
+				defer client.Kill()
+				key := trustKVKey(kvPrefix, name)
+				get = func() ([]byte, string, bool, error) {
+					data, err := raw.(KV).Get(key)
+					return data, "kv:" + key, status.Code(err) == codes.NotFound, err
+				}
+			} else {
+				path := trustBundlePath(dir, name)
+				get = func() ([]byte, string, bool, error) {
+					data, err := os.ReadFile(path)
+					return data, path, errors.Is(err, fs.ErrNotExist), err
+				}
+			}
+
+			deadline := time.Now().Add(wait)
+			data, source, missing, err := get()
+			for missing && time.Now().Before(deadline) {
+				logger.Debug("🔐⏳ waiting for trust bundle", "source", source)
+				time.Sleep(500 * time.Millisecond)
+				data, source, missing, err = get()
+			}
+			if err != nil {
+				return fmt.Errorf("failed to read trust bundle from %s: %w", source, err)
+			}
+
+			bundle, err := decodeTrustBundle(data, source)
+			if err != nil {
+				return err
+			}
+			cert, err := bundle.verify(certNow())
+			if err != nil {
+				return err
+			}
+			certFingerprint := fingerprint(cert.Raw)
+			if want != "" && !strings.EqualFold(strings.ReplaceAll(want, ":", ""), certFingerprint) {
+				return fmt.Errorf("trust bundle %q has %s fingerprint %s, want %s", bundle.Name, currentDigest.Name(), certFingerprint, want)
+			}
+			logger.Info("🔐📥 imported trust bundle", "name", bundle.Name, "source", source,
+				"host", bundle.Host, "subject", cert.Subject.String(), "not_after", cert.NotAfter)
+
+			if out == "" {
+				fmt.Print(bundle.CAPEM)
+				return nil
+			}
+			if err := os.WriteFile(out, []byte(bundle.CAPEM), 0o644); err != nil {
+				return fmt.Errorf("failed to write CA certificate: %w", err)
+			}
+			if outputFormat == "json" {
+				// Describe the verified certificate; bundles written by soup
+				// don't record its subject, expiry or curve
+				curve, _ := detectCurveFromCert(cert, logger)
+				return json.NewEncoder(os.Stdout).Encode(map[string]interface{}{
+					"name":                  bundle.Name,
+					"source":                source,
+					"ca_file":               out,
+					"fingerprint_sha256":    bundle.FingerprintSHA256,
+					"fingerprint":           certFingerprint,
+					"fingerprint_algorithm": currentDigest.Name(),
+					"subject":               cert.Subject.String(),
+					"host":                  bundle.Host,
+					"curve":                 curve,
+					"not_after":             cert.NotAfter.UTC(),
+				})
+			}
+			fmt.Printf("Imported trust bundle %q from %s (exported on %s) to %s\n", bundle.Name, source, bundle.Host, out)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&name, "name", "default", "Bundle name to read")
+	cmd.Flags().StringVar(&dir, "dir", "", "Read the bundle from this shared directory")
+	cmd.Flags().Bool("kv-store", false, "Read the bundle from the KV store instead of a directory")
+	cmd.Flags().StringVar(&kvPrefix, "kv-prefix", "trust.", "KV key prefix for --kv-store")
+	cmd.Flags().StringVar(&address, "address", "", "Address of existing server for --kv-store (e.g., 127.0.0.1:50051)")
+	cmd.Flags().StringVar(&tlsCurve, "tls-curve", "auto", "Client cert curve: auto (detect from server), secp256r1, secp384r1, secp521r1")
+	cmd.Flags().StringVar(&out, "out", "", "Write the CA certificate PEM here (default stdout)")
+	cmd.Flags().StringVar(&want, "fingerprint", "", "Fail unless the certificate has this fingerprint in --digest-alg (hex, colons allowed)")
+	cmd.Flags().DurationVar(&wait, "wait", 0, "Keep retrying a missing bundle for this long")
+	cmd.Flags().StringVar(&outputFormat, "output-format", "text", "Output format with --out (text, json)")
+	return cmd
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// enrichmentField is the key servers add to JSON object values on Get
+const enrichmentField = "server_handshake"
+
+// roundTripVerification describes a Put followed by a Get of the same key
+type roundTripVerification struct {
+	Key           string `json:"key"`
+	Verified      bool   `json:"verified"`
+	WrittenSHA256 string `json:"written_sha256"`
+	ReadSHA256    string `json:"read_sha256"`
+	WrittenBytes  int    `json:"written_bytes"`
+	ReadBytes     int    `json:"read_bytes"`
+	Enriched      bool   `json:"enriched"`
+	// Digests of the canonical JSON compared when the server enriched the value
+	NormalizedWrittenSHA256 string `json:"normalized_written_sha256,omitempty"`
+	NormalizedReadSHA256    string `json:"normalized_read_sha256,omitempty"`
+}
+
+// canonicalJSONObject re-encodes a JSON object with sorted keys, dropping the
+// given fields. It returns false if data is not a JSON object. Numbers go
+// through float64, as they do when the server enriches a value, so 1.0 and
+// 1e2 compare equal to the 1 and 100 the server writes back.
+func canonicalJSONObject(data []byte, drop ...string) ([]byte, bool) {
+	var obj map[string]interface{}
+	if err := json.Unmarshal(data, &obj); err != nil || obj == nil {
+		return nil, false
+	}
+	for _, field := range drop {
+		delete(obj, field)
+	}
+	canonical, err := json.Marshal(obj)
+	if err != nil {
+		return nil, false
+	}
+	return canonical, true
+}
+
+// verifyRoundTrip compares written and read bytes. JSON objects come back
+// with a server_handshake field added, so when the read value carries one the
+// comparison is made on canonical JSON with that field removed.
+func verifyRoundTrip(key string, written, read []byte) *roundTripVerification {
+	result := &roundTripVerification{
+		Key:           key,
+		WrittenSHA256: sha256Hex(written),
+		ReadSHA256:    sha256Hex(read),
+		WrittenBytes:  len(written),
+		ReadBytes:     len(read),
+	}
+	if bytes.Equal(written, read) {
+		result.Verified = true
+		return result
+	}
+
+	var readObj map[string]json.RawMessage
+	if err := json.Unmarshal(read, &readObj); err != nil {
+		return result
+	}
+	if _, ok := readObj[enrichmentField]; !ok {
+		return result
+	}
+
+	normalizedWritten, ok := canonicalJSONObject(written)
+	if !ok {
+		return result
+	}
+	normalizedRead, ok := canonicalJSONObject(read, enrichmentField)
+	if !ok {
+		return result
+	}
+
+	result.Enriched = true
+	result.NormalizedWrittenSHA256 = sha256Hex(normalizedWritten)
+	result.NormalizedReadSHA256 = sha256Hex(normalizedRead)
+	result.Verified = result.NormalizedWrittenSHA256 == result.NormalizedReadSHA256
+	return result
+}
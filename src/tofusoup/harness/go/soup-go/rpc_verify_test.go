@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func runKVPutVerify(t *testing.T, address, key, value string) (*roundTripVerification, error) {
+	t.Helper()
+	cmd := initKVPutCmd()
+	cmd.SetArgs([]string{key, value, "--address", address, "--verify"})
+	cmd.SilenceUsage, cmd.SilenceErrors = true, true
+	out, err := captureStdout(t, cmd.Execute)
+	var result roundTripVerification
+	if jerr := json.Unmarshal(out, &result); jerr != nil {
+		t.Fatalf("%v (command error %v): %s", jerr, err, out)
+	}
+	return &result, err
+}
+
+func TestKVPutVerify(t *testing.T) {
+	_, address := serveTestKV(t, KVServerOptions{})
+
+	result, err := runKVPutVerify(t, address, "plain", "not json")
+	if err != nil || !result.Verified || result.Enriched {
+		t.Errorf("got %+v (%v), want a byte-identical round trip", result, err)
+	}
+
+	// The server re-encodes enriched objects, rewriting number literals
+	result, err = runKVPutVerify(t, address, "j2", `{"a":1.0,"b":1e2,"c":[2.50]}`)
+	if err != nil || !result.Verified || !result.Enriched {
+		t.Errorf("got %+v (%v), want non-canonical numbers to verify", result, err)
+	}
+}
+
+func TestVerifyRoundTripDetectsChangedValues(t *testing.T) {
+	result := verifyRoundTrip("k", []byte(`{"a":1.0}`), []byte(`{"a":1.5,"server_handshake":{}}`))
+	if result.Verified || !result.Enriched {
+		t.Errorf("got %+v, want a changed number detected", result)
+	}
+	result = verifyRoundTrip("k", []byte(`{"a":1}`), []byte(`{"a":1}`+"\n"))
+	if result.Verified || result.Enriched {
+		t.Errorf("got %+v, want unenriched values compared byte for byte", result)
+	}
+}
+
+func TestKVPutVerifyWithIdempotencyToken(t *testing.T) {
+	_, address := serveTestKV(t, KVServerOptions{})
+	for _, want := range []string{idempotencyApplied, idempotencyDeduped} {
+		cmd := initKVPutCmd()
+		cmd.SetArgs([]string{"k", "v", "--address", address, "--verify", "--idempotency-token", "req-1"})
+		out, err := captureStdout(t, cmd.Execute)
+		if err != nil {
+			t.Fatalf("%v: %s", err, out)
+		}
+
+		var idempotency struct {
+			Idempotency map[string]string `json:"idempotency"`
+		}
+		var result roundTripVerification
+		decoder := json.NewDecoder(bytes.NewReader(out))
+		if err := decoder.Decode(&idempotency); err != nil {
+			t.Fatalf("%v: %s", err, out)
+		}
+		if err := decoder.Decode(&result); err != nil {
+			t.Fatalf("%v: %s", err, out)
+		}
+		if idempotency.Idempotency["result"] != want || !result.Verified {
+			t.Errorf("got %s, want the %s idempotency line and then a verified round trip", out, want)
+		}
+	}
+}
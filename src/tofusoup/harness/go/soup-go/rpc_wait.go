@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-plugin"
+	"google.golang.org/grpc/status"
+
+	"github.com/provide-io/tofusoup/proto/kv"
+)
+
+// waitPollInterval is how often a waiting Get rereads storage, to see
+// values written by other servers sharing the storage directory
+const waitPollInterval = 100 * time.Millisecond
+
+// putSignal wakes waiting Gets when this server stores a value
+type putSignal struct {
+	mu      sync.Mutex
+	changed chan struct{}
+}
+
+func newPutSignal() *putSignal {
+	return &putSignal{changed: make(chan struct{})}
+}
+
+// wait returns a channel closed by the next broadcast
+func (s *putSignal) wait() <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.changed
+}
+
+// broadcast wakes everything waiting
+func (s *putSignal) broadcast() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// validateWait rejects a negative wait timeout, whether or not the key has
+// a value yet
+func validateWait(req *proto.GetRequest) error {
+	if req.Wait && req.WaitTimeoutMs < 0 {
+		return errInvalidField("wait_timeout_ms", fmt.Sprint(req.WaitTimeoutMs), "must not be negative")
+	}
+	return nil
+}
+
+// waitForKey blocks a Get with wait set until req.Key has a value. It
+// fails with WAIT_TIMEOUT once req.WaitTimeoutMs passes, or with the
+// call's own status if its deadline passes or it is cancelled first.
+func (m *GRPCServer) waitForKey(ctx context.Context, req *proto.GetRequest) ([]byte, error) {
+	timeout := time.Duration(req.WaitTimeoutMs) * time.Millisecond
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	poll := time.NewTicker(waitPollInterval)
+	defer poll.Stop()
+
+	m.logger.Debug("📡⏳ waiting for key", "key", req.Key, "timeout", timeout)
+	start := time.Now()
+	for {
+		changed := m.puts.wait()
+		value, err := m.getValue(req.Key)
+		if !os.IsNotExist(err) {
+			m.logger.Debug("📡⏳ waited for key", "key", req.Key, "waited", time.Since(start))
+			return value, err
+		}
+		select {
+		case <-changed:
+		case <-poll.C:
+		case <-expired:
+			countRequest("Get", "wait_timeout")
+			m.logger.Info("📡⏳ key had no value before the wait timeout", "key", req.Key, "timeout", timeout)
+			return nil, errWaitTimeout(req.Key, timeout)
+		case <-ctx.Done():
+			countRequest("Get", "deadline")
+			m.logger.Info("📡⏱️ call ended while waiting for key", "key", req.Key, "error", ctx.Err())
+			return nil, status.FromContextError(ctx.Err()).Err()
+		}
+	}
+}
+
+// getWaiting sends a Get with wait set, so the server blocks until key has
+// a value or waitTimeoutMs passes
+func getWaiting(ctx context.Context, rpcClient plugin.ClientProtocol, key string, waitTimeoutMs int64) ([]byte, error) {
+	conn, err := pluginConn(rpcClient)
+	if err != nil {
+		return nil, err
+	}
+	logger.Debug("🌐⏳ waiting for key", "key", key, "wait_timeout_ms", waitTimeoutMs)
+	resp, err := proto.NewKVClient(conn).Get(ctx, &proto.GetRequest{Key: key, Wait: true, WaitTimeoutMs: waitTimeoutMs})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Value, nil
+}
@@ -0,0 +1,335 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/go-plugin"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/stats"
+)
+
+// rpcChildWatchdog is how long a spawned server may take to get through
+// each watched phase before it's killed (0 disables the watchdog)
+var rpcChildWatchdog time.Duration
+
+const (
+	// watchdogGrace is the longest a server gets to dump its stacks, and
+	// then to exit after being killed
+	watchdogGrace = 2 * time.Second
+	// watchdogQuiet is how long the server's output must stay unchanged
+	// for its stack dump to count as finished
+	watchdogQuiet = 250 * time.Millisecond
+	// watchdogTailLines is how much of the server's output an event keeps
+	watchdogTailLines = 1000
+)
+
+// Phases a spawned server is watched through
+const (
+	// watchdogHandshake runs from spawn until the gRPC connection to the
+	// server is up
+	watchdogHandshake = "handshake"
+	// watchdogFirstRPC runs from the first RPC starting until it returns or,
+	// for a stream, until its first message arrives
+	watchdogFirstRPC = "first_rpc"
+)
+
+// watchdogEvent is printed as {"watchdog": ...} when a spawned server is
+// killed for taking too long
+type watchdogEvent struct {
+	Phase     string   `json:"phase"`
+	Method    string   `json:"method,omitempty"`
+	TimeoutMs int64    `json:"timeout_ms"`
+	ElapsedMs float64  `json:"elapsed_ms"`
+	Server    []string `json:"server"`
+	PID       int      `json:"pid,omitempty"`
+	// StackDump is whether the server was sent SIGUSR1, which the soup-go
+	// and Python servers answer by printing their stacks to stderr
+	StackDump bool `json:"stack_dump_requested"`
+	// Exited is how the server went: "exited" if it exited by itself after
+	// the stack dump request, "killed" if it had to be killed, or "unknown"
+	Exited string   `json:"exited"`
+	Output []string `json:"output"`
+}
+
+// outputTail keeps the last lines a spawned server wrote to stderr
+type outputTail struct {
+	mu      sync.Mutex
+	lines   []string
+	partial strings.Builder
+	written int
+}
+
+func (t *outputTail) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.written += len(p)
+	for _, b := range p {
+		if b != '\n' {
+			t.partial.WriteByte(b)
+			continue
+		}
+		t.lines = append(t.lines, t.partial.String())
+		t.partial.Reset()
+		if len(t.lines) > watchdogTailLines {
+			t.lines = t.lines[len(t.lines)-watchdogTailLines:]
+		}
+	}
+	return len(p), nil
+}
+
+// waitQuiet waits until nothing has been written for watchdogQuiet, or
+// for at most limit
+func (t *outputTail) waitQuiet(limit time.Duration) {
+	deadline := time.Now().Add(limit)
+	last, quietSince := -1, time.Now()
+	for time.Now().Before(deadline) {
+		t.mu.Lock()
+		written := t.written
+		t.mu.Unlock()
+		if written != last {
+			last, quietSince = written, time.Now()
+		} else if time.Since(quietSince) >= watchdogQuiet {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func (t *outputTail) snapshot() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	lines := append([]string{}, t.lines...)
+	if t.partial.Len() > 0 {
+		lines = append(lines, t.partial.String())
+	}
+	return lines
+}
+
+// childWatchdog watches one spawned server through its handshake and first
+// RPC. Nothing is watched between the two, which is the client's own time,
+// or after the first RPC; --deadline-ms covers later calls.
+//
+// go-plugin holds the client's lock for the whole handshake, so the
+// watchdog checks on the server process rather than the client.
+type childWatchdog struct {
+	timeout time.Duration
+	cmd     *exec.Cmd
+	output  *outputTail
+	logger  hclog.Logger
+
+	mu      sync.Mutex
+	start   time.Time
+	timer   *time.Timer
+	rpcSeen bool
+	done    bool
+}
+
+// firedWatchdogs are the watchdogs that killed a server, for main to fail
+// the command with. firing tracks the ones still capturing and killing.
+var firedWatchdogs struct {
+	sync.Mutex
+	events []watchdogEvent
+	firing sync.WaitGroup
+}
+
+// watchChild adds a watchdog to a server spawned by config, if
+// --child-watchdog is set, and starts watching the handshake. It must be
+// called just before plugin.NewClient.
+func watchChild(config *plugin.ClientConfig, logger hclog.Logger) {
+	if rpcChildWatchdog <= 0 {
+		return
+	}
+	w := &childWatchdog{timeout: rpcChildWatchdog, cmd: config.Cmd, output: &outputTail{}, logger: logger}
+	config.Stderr = w.output
+	// go-plugin gives up on the handshake by itself; let the watchdog go
+	// first so the stacks are captured
+	if minimum := w.timeout + 2*watchdogGrace + time.Second; config.StartTimeout < minimum {
+		config.StartTimeout = minimum
+	}
+	config.GRPCDialOptions = append(config.GRPCDialOptions,
+		grpc.WithStatsHandler(watchdogConnStats{w}),
+		grpc.WithChainUnaryInterceptor(w.unaryInterceptor),
+		grpc.WithChainStreamInterceptor(w.streamInterceptor),
+	)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.start = time.Now()
+	w.timer = time.AfterFunc(w.timeout, func() { w.fire(watchdogHandshake, "") })
+	logger.Debug("🐕 child watchdog armed", "timeout", w.timeout)
+}
+
+// connected ends the handshake phase
+func (w *childWatchdog) connected() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.rpcSeen && !w.done {
+		w.timer.Stop()
+	}
+}
+
+// rpcStarted moves the watchdog onto the first RPC and returns the function
+// that disarms it when the call is through. Later calls get a no-op.
+func (w *childWatchdog) rpcStarted(method string) func() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.rpcSeen || w.done {
+		return func() {}
+	}
+	w.rpcSeen = true
+	w.timer.Stop()
+	w.start = time.Now()
+	w.timer = time.AfterFunc(w.timeout, func() { w.fire(watchdogFirstRPC, method) })
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			w.mu.Lock()
+			defer w.mu.Unlock()
+			w.timer.Stop()
+			w.done = true
+			w.logger.Debug("🐕 child watchdog disarmed after the first RPC", "method", method)
+		})
+	}
+}
+
+// watchdogConnStats tells the watchdog when the connection is up
+type watchdogConnStats struct {
+	w *childWatchdog
+}
+
+func (s watchdogConnStats) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context {
+	return ctx
+}
+
+func (s watchdogConnStats) HandleRPC(context.Context, stats.RPCStats) {}
+
+func (s watchdogConnStats) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+func (s watchdogConnStats) HandleConn(_ context.Context, st stats.ConnStats) {
+	if _, ok := st.(*stats.ConnBegin); ok {
+		s.w.connected()
+	}
+}
+
+func (w *childWatchdog) unaryInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if authExempt(method) {
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+	done := w.rpcStarted(method)
+	defer done()
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+func (w *childWatchdog) streamInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	if authExempt(method) {
+		return streamer(ctx, desc, cc, method, opts...)
+	}
+	done := w.rpcStarted(method)
+	stream, err := streamer(ctx, desc, cc, method, opts...)
+	if err != nil {
+		done()
+		return nil, err
+	}
+	return &watchedStream{ClientStream: stream, done: done}, nil
+}
+
+// watchedStream disarms the watchdog when the first message arrives
+type watchedStream struct {
+	grpc.ClientStream
+	done func()
+}
+
+func (s *watchedStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	s.done()
+	return err
+}
+
+// fire captures the server's stacks and output, kills it, and reports the
+// event. It does nothing if the watchdog was disarmed or moved on to
+// another phase while the timer was firing.
+func (w *childWatchdog) fire(phase, method string) {
+	w.mu.Lock()
+	// The runner sets cmd.Process when it starts the server, well before
+	// any timeout
+	process := w.cmd.Process
+	if w.done || (phase == watchdogHandshake && w.rpcSeen) || (process != nil && processExited(process)) {
+		w.mu.Unlock()
+		return
+	}
+	w.done = true
+	firedWatchdogs.firing.Add(1)
+	defer firedWatchdogs.firing.Done()
+	event := watchdogEvent{
+		Phase:     phase,
+		Method:    method,
+		TimeoutMs: w.timeout.Milliseconds(),
+		ElapsedMs: float64(time.Since(w.start).Microseconds()) / 1000,
+		Server:    w.cmd.Args,
+		Exited:    "unknown",
+	}
+	w.mu.Unlock()
+
+	w.logger.Error("🐕 spawned server timed out; capturing stacks and killing it", "phase", phase, "method", method, "timeout", w.timeout)
+	if process != nil {
+		event.PID = process.Pid
+		if err := requestStackDump(process); err == nil {
+			event.StackDump = true
+			w.output.waitQuiet(watchdogGrace)
+		}
+		if processExited(process) {
+			event.Exited = "exited"
+		} else if process.Kill() == nil {
+			event.Exited = "killed"
+			// Give go-plugin time to read the rest of the output
+			waitExited(process)
+		}
+	}
+	event.Output = w.output.snapshot()
+
+	firedWatchdogs.Lock()
+	firedWatchdogs.events = append(firedWatchdogs.events, event)
+	firedWatchdogs.Unlock()
+	if err := json.NewEncoder(os.Stdout).Encode(map[string]interface{}{"watchdog": event}); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to encode watchdog event: %v\n", err)
+	}
+}
+
+// waitExited waits up to watchdogGrace for the server to exit. go-plugin
+// reaps it only after reading all of its output.
+func waitExited(process *os.Process) bool {
+	deadline := time.Now().Add(watchdogGrace)
+	for time.Now().Before(deadline) {
+		if processExited(process) {
+			return true
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	return processExited(process)
+}
+
+// watchdogResult fails a command whose spawned server the watchdog killed,
+// whatever error the kill caused along the way
+func watchdogResult(err error) error {
+	firedWatchdogs.firing.Wait()
+	firedWatchdogs.Lock()
+	defer firedWatchdogs.Unlock()
+	if len(firedWatchdogs.events) == 0 {
+		return err
+	}
+	event := firedWatchdogs.events[0]
+	what := event.Phase
+	if event.Method != "" {
+		what += " (" + event.Method + ")"
+	}
+	return fmt.Errorf("child watchdog killed the spawned server after %s in %s", time.Duration(event.TimeoutMs)*time.Millisecond, what)
+}
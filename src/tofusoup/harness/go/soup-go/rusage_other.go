@@ -0,0 +1,64 @@
+//go:build !windows
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// processUsage is the getrusage figures --stats reports
+type processUsage struct {
+	UserCPUMs   float64
+	SysCPUMs    float64
+	MaxRSSBytes int64
+}
+
+// processRusage reads this process's resource usage
+func processRusage() (processUsage, bool) {
+	var ru syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &ru); err != nil {
+		return processUsage{}, false
+	}
+	// ru_maxrss is bytes on darwin and kilobytes elsewhere
+	maxRSS := int64(ru.Maxrss)
+	if runtime.GOOS != "darwin" {
+		maxRSS *= 1024
+	}
+	return processUsage{
+		UserCPUMs:   float64(time.Duration(ru.Utime.Nano())) / float64(time.Millisecond),
+		SysCPUMs:    float64(time.Duration(ru.Stime.Nano())) / float64(time.Millisecond),
+		MaxRSSBytes: maxRSS,
+	}, true
+}
+
+// processRSS reads another process's resident set size, from /proc where
+// there is one and from ps elsewhere
+func processRSS(pid int) (int64, error) {
+	if data, err := os.ReadFile(fmt.Sprintf("/proc/%d/statm", pid)); err == nil {
+		fields := strings.Fields(string(data))
+		if len(fields) < 2 {
+			return 0, fmt.Errorf("unexpected /proc/%d/statm: %q", pid, data)
+		}
+		pages, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("unexpected /proc/%d/statm: %q", pid, data)
+		}
+		return pages * int64(os.Getpagesize()), nil
+	}
+	out, err := exec.Command("ps", "-o", "rss=", "-p", strconv.Itoa(pid)).Output()
+	if err != nil {
+		return 0, fmt.Errorf("process %d is gone or unreadable: %w", pid, err)
+	}
+	kb, err := strconv.ParseInt(strings.TrimSpace(string(out)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("unexpected ps output for process %d: %q", pid, out)
+	}
+	return kb * 1024, nil
+}
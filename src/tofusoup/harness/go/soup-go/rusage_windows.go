@@ -0,0 +1,24 @@
+//go:build windows
+
+package main
+
+import "errors"
+
+// processUsage is the getrusage figures --stats reports
+type processUsage struct {
+	UserCPUMs   float64
+	SysCPUMs    float64
+	MaxRSSBytes int64
+}
+
+// processRusage reports nothing on windows, which has no getrusage; --stats
+// leaves CPU and RSS null there
+func processRusage() (processUsage, bool) {
+	return processUsage{}, false
+}
+
+// processRSS isn't implemented on windows, so --leak-check can't sample
+// server processes there
+func processRSS(pid int) (int64, error) {
+	return 0, errors.New("reading another process's memory is not supported on windows")
+}
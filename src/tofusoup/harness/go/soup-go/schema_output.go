@@ -0,0 +1,892 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// jsonSchemaDialect is the JSON Schema version output schemas are written in
+const jsonSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// outputSchemaCommands are the commands whose JSON output has a schema,
+// with the Go type each one encodes. cty convert is handled apart: its
+// output is a value of --type.
+var outputSchemaCommands = []struct {
+	Command string
+	Type    reflect.Type
+}{
+	{"doctor", reflect.TypeOf(doctorReport{})},
+	{"selftest", reflect.TypeOf(selftestReport{})},
+	{"generate time-vectors", reflect.TypeOf(timeVectorFile{})},
+	{"generate hcl-operators", reflect.TypeOf(hclOperatorFile{})},
+	{"hcl meta expand", reflect.TypeOf(hclExpandReport{})},
+	{"hcl deps graph", reflect.TypeOf(hclDepsGraph{})},
+	{"wire fidelity", reflect.TypeOf(fidelityReport{})},
+	{"rpc kv stat", reflect.TypeOf(kvStatReport{})},
+	{"rpc kv admin compact", reflect.TypeOf(kvCompaction{})},
+	{"rpc kv admin fsck", reflect.TypeOf(fsckReport{})},
+	{"rpc lint-server", reflect.TypeOf(lintReport{})},
+	{"rpc validate transport", reflect.TypeOf(transportReport{})},
+	{"rpc channelz dump", reflect.TypeOf(channelzReport{})},
+	{"rpc proxy", reflect.TypeOf(proxyReport{})},
+	{"harness verify-vectors", reflect.TypeOf(vectorVerifyReport{})},
+	{"harness assert", reflect.TypeOf(assertReport{})},
+	{"harness replay", reflect.TypeOf(replayReport{})},
+	{"harness scenario restart-under-load", reflect.TypeOf(scenarioReport{})},
+	{"harness scenario deadline", reflect.TypeOf(deadlineReport{})},
+	{"harness cases list", reflect.TypeOf(harnessCaseList{})},
+	{"harness corpus sync", reflect.TypeOf(corpusSyncReport{})},
+	{"harness fixtures serve", reflect.TypeOf(fixturesReport{})},
+	{"schema validate-output", reflect.TypeOf(outputValidationReport{})},
+}
+
+// ctyConvertCommand is the command whose schema is built from a cty type
+const ctyConvertCommand = "cty convert"
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+	marshalerType  = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// outputSchemaType returns the Go type command encodes
+func outputSchemaType(command string) (reflect.Type, bool) {
+	for _, c := range outputSchemaCommands {
+		if c.Command == command {
+			return c.Type, true
+		}
+	}
+	return nil, false
+}
+
+// outputSchemaFile is the file name a command's schema is shipped under
+func outputSchemaFile(command string) string {
+	return strings.ReplaceAll(command, " ", "-") + ".schema.json"
+}
+
+// jsonSchema is a JSON Schema document or subschema
+type jsonSchema = map[string]interface{}
+
+// schemaBuilder derives JSON Schemas from Go types the way encoding/json
+// encodes them. Every struct becomes a $defs entry, so recursive types
+// terminate.
+type schemaBuilder struct {
+	defs map[string]interface{}
+}
+
+// buildOutputSchema returns the schema document for values of t
+func buildOutputSchema(command string, t reflect.Type) jsonSchema {
+	b := &schemaBuilder{defs: map[string]interface{}{}}
+	root := b.schemaFor(t)
+	root["$schema"] = jsonSchemaDialect
+	root["title"] = "soup-go " + command + " JSON output"
+	if len(b.defs) > 0 {
+		root["$defs"] = b.defs
+	}
+	return root
+}
+
+func (b *schemaBuilder) schemaFor(t reflect.Type) jsonSchema {
+	switch {
+	case t == timeType:
+		return jsonSchema{"type": "string", "format": "date-time"}
+	case t == rawMessageType, t.Kind() == reflect.Interface:
+		return jsonSchema{}
+	case t.Implements(marshalerType), reflect.PointerTo(t).Implements(marshalerType):
+		// Custom encodings can't be described from the type
+		return jsonSchema{}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return nullableSchema(b.schemaFor(t.Elem()))
+	case reflect.Bool:
+		return jsonSchema{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return jsonSchema{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return jsonSchema{"type": "number"}
+	case reflect.String:
+		return jsonSchema{"type": "string"}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// base64
+			return jsonSchema{"type": []interface{}{"string", "null"}}
+		}
+		return jsonSchema{"type": []interface{}{"array", "null"}, "items": b.schemaFor(t.Elem())}
+	case reflect.Array:
+		return jsonSchema{"type": "array", "items": b.schemaFor(t.Elem()), "minItems": t.Len(), "maxItems": t.Len()}
+	case reflect.Map:
+		return jsonSchema{"type": []interface{}{"object", "null"}, "additionalProperties": b.schemaFor(t.Elem())}
+	case reflect.Struct:
+		name := t.Name()
+		if t.PkgPath() != "main" {
+			name = strings.ReplaceAll(t.String(), ".", "_")
+		}
+		if _, ok := b.defs[name]; !ok {
+			b.defs[name] = jsonSchema{}
+			b.defs[name] = b.structSchema(t)
+		}
+		return jsonSchema{"$ref": "#/$defs/" + name}
+	}
+	return jsonSchema{}
+}
+
+// structSchema describes a struct's fields as encoding/json writes them:
+// embedded structs are flattened, omitempty fields are optional, and a
+// field at a shallower depth hides one of the same name
+func (b *schemaBuilder) structSchema(t reflect.Type) jsonSchema {
+	properties := jsonSchema{}
+	depths := map[string]int{}
+	var required []string
+	var walk func(t reflect.Type, depth int)
+	walk = func(t reflect.Type, depth int) {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			tag := field.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+			name, opts, _ := strings.Cut(tag, ",")
+			ft := field.Type
+			if field.Anonymous && name == "" {
+				if ft.Kind() == reflect.Pointer {
+					ft = ft.Elem()
+				}
+				if ft.Kind() == reflect.Struct {
+					walk(ft, depth+1)
+					continue
+				}
+			}
+			if !field.IsExported() {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			if d, ok := depths[name]; ok && d <= depth {
+				continue
+			}
+			depths[name] = depth
+			schema := b.schemaFor(ft)
+			if strings.Contains(","+opts+",", ",string,") {
+				schema = jsonSchema{"type": "string"}
+			}
+			properties[name] = schema
+			if !strings.Contains(","+opts+",", ",omitempty,") {
+				required = append(required, name)
+			}
+		}
+	}
+	walk(t, 0)
+	schema := jsonSchema{"type": "object", "properties": properties, "additionalProperties": false}
+	if len(required) > 0 {
+		sort.Strings(required)
+		schema["required"] = dedupeSorted(required)
+	}
+	return schema
+}
+
+func dedupeSorted(names []string) []string {
+	out := names[:0]
+	for i, name := range names {
+		if i == 0 || name != names[i-1] {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+// nullableSchema also allows null, as a nil pointer encodes
+func nullableSchema(s jsonSchema) jsonSchema {
+	switch ty := s["type"].(type) {
+	case string:
+		s["type"] = []interface{}{ty, "null"}
+		return s
+	case []interface{}:
+		for _, t := range ty {
+			if t == "null" {
+				return s
+			}
+		}
+		s["type"] = append(ty, "null")
+		return s
+	}
+	if len(s) == 0 {
+		return s
+	}
+	return jsonSchema{"anyOf": []interface{}{s, jsonSchema{"type": "null"}}}
+}
+
+// ctyTypeSchema describes the JSON ctyjson.Marshal writes for a value of
+// ty. Any value may be null.
+func ctyTypeSchema(ty cty.Type) jsonSchema {
+	nullable := func(name string) []interface{} { return []interface{}{name, "null"} }
+	switch {
+	case ty == cty.String:
+		return jsonSchema{"type": nullable("string")}
+	case ty == cty.Number:
+		return jsonSchema{"type": nullable("number")}
+	case ty == cty.Bool:
+		return jsonSchema{"type": nullable("boolean")}
+	case ty == cty.DynamicPseudoType:
+		return jsonSchema{
+			"type":                 nullable("object"),
+			"properties":           jsonSchema{"value": jsonSchema{}, "type": jsonSchema{}},
+			"required":             []string{"type", "value"},
+			"additionalProperties": false,
+		}
+	case ty.IsListType() || ty.IsSetType():
+		return jsonSchema{"type": nullable("array"), "items": ctyTypeSchema(ty.ElementType())}
+	case ty.IsMapType():
+		return jsonSchema{"type": nullable("object"), "additionalProperties": ctyTypeSchema(ty.ElementType())}
+	case ty.IsObjectType():
+		properties := jsonSchema{}
+		required := []string{}
+		for name, aty := range ty.AttributeTypes() {
+			properties[name] = ctyTypeSchema(aty)
+			required = append(required, name)
+		}
+		sort.Strings(required)
+		return jsonSchema{"type": nullable("object"), "properties": properties, "required": required, "additionalProperties": false}
+	case ty.IsTupleType():
+		items := []interface{}{}
+		for _, ety := range ty.TupleElementTypes() {
+			items = append(items, ctyTypeSchema(ety))
+		}
+		return jsonSchema{"type": nullable("array"), "prefixItems": items, "items": false, "minItems": len(items)}
+	}
+	return jsonSchema{}
+}
+
+// commandOutputSchema returns the schema for command. cty convert needs
+// the --type it was run with; without one its output may be any JSON.
+func commandOutputSchema(command, typeJSON string) (jsonSchema, error) {
+	if command == ctyConvertCommand {
+		schema := jsonSchema{}
+		if typeJSON != "" {
+			ty, err := parseCtyType(json.RawMessage(typeJSON))
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse --type: %w", err)
+			}
+			schema = ctyTypeSchema(ty)
+		}
+		schema["$schema"] = jsonSchemaDialect
+		schema["title"] = "soup-go " + command + " JSON output"
+		return schema, nil
+	}
+	if typeJSON != "" {
+		return nil, fmt.Errorf("--type only applies to %q", ctyConvertCommand)
+	}
+	t, ok := outputSchemaType(command)
+	if !ok {
+		return nil, fmt.Errorf("no output schema for %q (see 'soup-go schema list')", command)
+	}
+	return buildOutputSchema(command, t), nil
+}
+
+// schemaViolation is one way a document breaks its schema
+type schemaViolation struct {
+	Document int    `json:"document"`
+	Path     string `json:"path"`
+	Message  string `json:"message"`
+}
+
+// schemaValidator checks decoded JSON (numbers as json.Number) against the
+// subset of JSON Schema output schemas use
+type schemaValidator struct {
+	root       jsonSchema
+	document   int
+	violations []schemaViolation
+}
+
+func (v *schemaValidator) fail(path, format string, args ...interface{}) {
+	v.violations = append(v.violations, schemaViolation{Document: v.document, Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// jsonKindOf names a decoded value's JSON Schema type
+func jsonKindOf(value interface{}) string {
+	switch val := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		if f, ok := new(big.Float).SetString(val.String()); ok && f.IsInt() {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return "unknown"
+}
+
+// check reports where value breaks schema. It returns false if it did.
+func (v *schemaValidator) check(schema interface{}, value interface{}, path string) bool {
+	before := len(v.violations)
+	switch s := schema.(type) {
+	case bool:
+		if !s {
+			v.fail(path, "not allowed here")
+		}
+		return s
+	case jsonSchema:
+		v.checkSchema(s, value, path)
+	default:
+		v.fail(path, "invalid schema %T", schema)
+	}
+	return len(v.violations) == before
+}
+
+func (v *schemaValidator) checkSchema(s jsonSchema, value interface{}, path string) {
+	if ref, ok := s["$ref"].(string); ok {
+		name, found := strings.CutPrefix(ref, "#/$defs/")
+		defs, _ := v.root["$defs"].(jsonSchema)
+		def, exists := defs[name]
+		if !found || !exists {
+			v.fail(path, "unresolvable $ref %q", ref)
+			return
+		}
+		if !v.check(def, value, path) {
+			return
+		}
+	}
+	if anyOf, ok := s["anyOf"].([]interface{}); ok {
+		matched := false
+		for _, option := range anyOf {
+			trial := &schemaValidator{root: v.root, document: v.document}
+			if trial.check(option, value, path) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			v.fail(path, "matches none of the allowed shapes")
+			return
+		}
+	}
+	if types := schemaTypes(s["type"]); len(types) > 0 {
+		kind := jsonKindOf(value)
+		allowed := false
+		for _, t := range types {
+			if t == kind || (t == "number" && kind == "integer") {
+				allowed = true
+			}
+		}
+		if !allowed {
+			v.fail(path, "expected %s, got %s", strings.Join(types, " or "), kind)
+			return
+		}
+	}
+
+	switch val := value.(type) {
+	case map[string]interface{}:
+		properties, _ := s["properties"].(jsonSchema)
+		for _, name := range schemaStrings(s["required"]) {
+			if _, ok := val[name]; !ok {
+				v.fail(path, "missing required property %q", name)
+			}
+		}
+		keys := make([]string, 0, len(val))
+		for key := range val {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			child := path + "." + key
+			if prop, ok := properties[key]; ok {
+				v.check(prop, val[key], child)
+				continue
+			}
+			if additional, ok := s["additionalProperties"]; ok {
+				if allowed, isBool := additional.(bool); isBool && !allowed {
+					v.fail(child, "unexpected property %q", key)
+					continue
+				}
+				v.check(additional, val[key], child)
+			}
+		}
+	case []interface{}:
+		prefix, _ := s["prefixItems"].([]interface{})
+		if min, ok := schemaInt(s["minItems"]); ok && len(val) < min {
+			v.fail(path, "expected at least %d items, got %d", min, len(val))
+		}
+		if max, ok := schemaInt(s["maxItems"]); ok && len(val) > max {
+			v.fail(path, "expected at most %d items, got %d", max, len(val))
+		}
+		for i, item := range val {
+			child := path + "[" + strconv.Itoa(i) + "]"
+			if i < len(prefix) {
+				v.check(prefix[i], item, child)
+			} else if items, ok := s["items"]; ok {
+				v.check(items, item, child)
+			}
+		}
+	}
+}
+
+// schemaTypes reads a type keyword, which is a name or a list of names
+func schemaTypes(t interface{}) []string {
+	switch t := t.(type) {
+	case string:
+		return []string{t}
+	default:
+		return schemaStrings(t)
+	}
+}
+
+func schemaStrings(list interface{}) []string {
+	switch list := list.(type) {
+	case []string:
+		return list
+	case []interface{}:
+		out := make([]string, 0, len(list))
+		for _, item := range list {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func schemaInt(n interface{}) (int, bool) {
+	switch n := n.(type) {
+	case int:
+		return n, true
+	case float64:
+		return int(n), true
+	case json.Number:
+		i, err := n.Int64()
+		return int(i), err == nil
+	}
+	return 0, false
+}
+
+// normalizeSchema round-trips a built schema through JSON, so it has the
+// same Go types as a schema read from a file
+func normalizeSchema(schema jsonSchema) (jsonSchema, error) {
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return nil, err
+	}
+	var out jsonSchema
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return out, decoder.Decode(&out)
+}
+
+// outputValidationReport is what schema validate-output prints
+type outputValidationReport struct {
+	Command    string            `json:"command"`
+	Documents  int               `json:"documents"`
+	Valid      bool              `json:"valid"`
+	Violations []schemaViolation `json:"violations"`
+}
+
+// validateOutput checks every JSON document in r (one, or NDJSON) against
+// schema
+func validateOutput(command string, schema jsonSchema, r io.Reader) (*outputValidationReport, error) {
+	schema, err := normalizeSchema(schema)
+	if err != nil {
+		return nil, err
+	}
+	report := &outputValidationReport{Command: command, Violations: []schemaViolation{}}
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	validator := &schemaValidator{root: schema}
+	for {
+		var doc interface{}
+		if err := decoder.Decode(&doc); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("document %d is not JSON: %w", report.Documents+1, err)
+		}
+		report.Documents++
+		validator.document = report.Documents
+		validator.check(schema, doc, "$")
+	}
+	if report.Documents == 0 {
+		return nil, errors.New("no JSON document to validate")
+	}
+	report.Violations = append(report.Violations, validator.violations...)
+	report.Valid = len(report.Violations) == 0
+	return report, nil
+}
+
+// fillSample populates every field of v, so the sample exercises every
+// part of its schema. depth stops recursive types.
+func fillSample(v reflect.Value, depth int) {
+	if depth > 4 {
+		return
+	}
+	t := v.Type()
+	switch {
+	case t == timeType:
+		v.Set(reflect.ValueOf(time.Unix(0, 0).UTC()))
+		return
+	case t == rawMessageType:
+		v.Set(reflect.ValueOf(json.RawMessage(`{"sample":true}`)))
+		return
+	}
+	switch t.Kind() {
+	case reflect.Interface:
+		if t.NumMethod() == 0 {
+			v.Set(reflect.ValueOf("sample"))
+		}
+	case reflect.Pointer:
+		elem := reflect.New(t.Elem())
+		fillSample(elem.Elem(), depth+1)
+		v.Set(elem)
+	case reflect.Bool:
+		v.SetBool(true)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(1)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v.SetUint(1)
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(1.5)
+	case reflect.String:
+		v.SetString("sample")
+	case reflect.Slice:
+		s := reflect.MakeSlice(t, 1, 1)
+		fillSample(s.Index(0), depth+1)
+		v.Set(s)
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			fillSample(v.Index(i), depth+1)
+		}
+	case reflect.Map:
+		m := reflect.MakeMap(t)
+		key := reflect.New(t.Key()).Elem()
+		fillSample(key, depth+1)
+		elem := reflect.New(t.Elem()).Elem()
+		fillSample(elem, depth+1)
+		m.SetMapIndex(key, elem)
+		v.Set(m)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if t.Field(i).IsExported() || t.Field(i).Anonymous {
+				if field := v.Field(i); field.CanSet() {
+					fillSample(field, depth+1)
+				}
+			}
+		}
+	}
+}
+
+// schemaSelfCheck is the self-check result for one command
+type schemaSelfCheck struct {
+	Command string `json:"command"`
+	// Samples is whether a zero value and a fully populated value of the
+	// command's Go type both validate against its schema
+	Samples bool `json:"samples"`
+	// Shipped is "match", "stale" or "missing" for the schema file in
+	// --schemas-dir, or empty without one
+	Shipped    string            `json:"shipped,omitempty"`
+	Violations []schemaViolation `json:"violations,omitempty"`
+}
+
+// schemaSelfCheckReport is what schema validate-output --self-check prints
+type schemaSelfCheckReport struct {
+	OK       bool              `json:"ok"`
+	Commands []schemaSelfCheck `json:"commands"`
+	// Unknown are schema files in --schemas-dir no command has
+	Unknown []string `json:"unknown,omitempty"`
+}
+
+// encodeOutputSchema renders a schema as shipped
+func encodeOutputSchema(schema jsonSchema) ([]byte, error) {
+	data, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// selfCheckOutputSchemas validates samples of every command's Go type
+// against its schema and, with schemasDir, compares the shipped files
+func selfCheckOutputSchemas(schemasDir string) (*schemaSelfCheckReport, error) {
+	report := &schemaSelfCheckReport{OK: true, Commands: []schemaSelfCheck{}}
+	known := map[string]bool{}
+	for _, c := range outputSchemaCommands {
+		check := schemaSelfCheck{Command: c.Command, Samples: true}
+		schema := buildOutputSchema(c.Command, c.Type)
+
+		var docs bytes.Buffer
+		zero := reflect.New(c.Type)
+		full := reflect.New(c.Type)
+		fillSample(full.Elem(), 0)
+		for _, sample := range []reflect.Value{zero, full} {
+			data, err := json.Marshal(sample.Interface())
+			if err != nil {
+				return nil, fmt.Errorf("%s: failed to encode sample: %w", c.Command, err)
+			}
+			docs.Write(append(data, '\n'))
+		}
+		result, err := validateOutput(c.Command, schema, &docs)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", c.Command, err)
+		}
+		if !result.Valid {
+			check.Samples = false
+			check.Violations = result.Violations
+		}
+
+		if schemasDir != "" {
+			name := outputSchemaFile(c.Command)
+			known[name] = true
+			want, err := encodeOutputSchema(schema)
+			if err != nil {
+				return nil, err
+			}
+			got, err := os.ReadFile(filepath.Join(schemasDir, name))
+			switch {
+			case errors.Is(err, os.ErrNotExist):
+				check.Shipped = "missing"
+			case err != nil:
+				return nil, fmt.Errorf("failed to read shipped schema: %w", err)
+			case bytes.Equal(got, want):
+				check.Shipped = "match"
+			default:
+				check.Shipped = "stale"
+			}
+		}
+		if !check.Samples || (check.Shipped != "" && check.Shipped != "match") {
+			report.OK = false
+		}
+		report.Commands = append(report.Commands, check)
+	}
+
+	if schemasDir != "" {
+		files, err := filepath.Glob(filepath.Join(schemasDir, "*.schema.json"))
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			if name := filepath.Base(file); !known[name] {
+				report.Unknown = append(report.Unknown, name)
+				report.OK = false
+			}
+		}
+	}
+	return report, nil
+}
+
+func initSchemaCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "schema",
+		Short: "Describe and check the JSON that soup-go commands print",
+		Long: `Every command with a JSON report has a JSON Schema (draft 2020-12),
+derived from the Go type it encodes. Reports reject properties the schema
+doesn't list, so a driver validating against the shipped schemas finds out
+about an output change before it misreads one.`,
+	}
+	cmd.AddCommand(initSchemaListCmd(), initSchemaShowCmd(), initSchemaExportCmd(), initSchemaValidateOutputCmd())
+	return cmd
+}
+
+func initSchemaListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List the commands that have an output schema",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fmt.Printf("%s (with --type)\n", ctyConvertCommand)
+			for _, c := range outputSchemaCommands {
+				fmt.Println(c.Command)
+			}
+			return nil
+		},
+	}
+}
+
+func initSchemaShowCmd() *cobra.Command {
+	var command, typeJSON string
+	cmd := &cobra.Command{
+		Use:   "show",
+		Short: "Print a command's output schema",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			schema, err := commandOutputSchema(command, typeJSON)
+			if err != nil {
+				return err
+			}
+			data, err := encodeOutputSchema(schema)
+			if err != nil {
+				return fmt.Errorf("failed to encode schema: %w", err)
+			}
+			_, err = os.Stdout.Write(data)
+			return err
+		},
+	}
+	cmd.Flags().StringVar(&command, "command", "", `Command path, e.g. "rpc kv admin fsck"`)
+	cmd.Flags().StringVar(&typeJSON, "type", "", "For cty convert, the --type it was run with")
+	cmd.MarkFlagRequired("command")
+	return cmd
+}
+
+func initSchemaExportCmd() *cobra.Command {
+	var dir string
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Write every command's output schema to a directory",
+		Long: `Write <command>.schema.json for every command in 'schema list' but cty
+convert, whose schema depends on --type. Run it after changing a report
+type; 'schema validate-output --self-check --schemas-dir' fails until the
+shipped files are updated.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return fmt.Errorf("failed to create schema directory: %w", err)
+			}
+			for _, c := range outputSchemaCommands {
+				data, err := encodeOutputSchema(buildOutputSchema(c.Command, c.Type))
+				if err != nil {
+					return fmt.Errorf("failed to encode schema: %w", err)
+				}
+				if err := os.WriteFile(filepath.Join(dir, outputSchemaFile(c.Command)), data, 0644); err != nil {
+					return fmt.Errorf("failed to write schema: %w", err)
+				}
+			}
+			logger.Info("📐 exported output schemas", "dir", dir, "count", len(outputSchemaCommands))
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&dir, "dir", "", "Directory to write the schemas to")
+	cmd.MarkFlagRequired("dir")
+	return cmd
+}
+
+func initSchemaValidateOutputCmd() *cobra.Command {
+	var command, typeJSON, schemasDir, outputFormat string
+	var selfCheck bool
+
+	cmd := &cobra.Command{
+		Use:   "validate-output [file]",
+		Short: "Check a command's JSON output against its schema",
+		Long: `Check JSON a command printed against the command's schema. The input (a
+file, or stdin by default) may hold one document or NDJSON. Each
+violation is reported with its document number and path, and the command
+fails if there are any.
+
+--self-check validates a zero and a fully populated value of every
+command's Go type against its own schema. With --schemas-dir it also fails
+if a shipped schema file is missing, stale or unknown.
+
+Examples:
+  soup-go cty convert in.json - --type '["list","string"]' | soup-go schema validate-output --command "cty convert" --type '["list","string"]'
+  soup-go schema validate-output --command "rpc kv admin fsck" fsck.json
+  soup-go schema validate-output --self-check --schemas-dir src/tofusoup/harness/go/soup-go/schemas`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if outputFormat != "text" && outputFormat != "json" {
+				return fmt.Errorf("unknown --output-format %q (expected text or json)", outputFormat)
+			}
+			if selfCheck {
+				if command != "" || len(args) > 0 {
+					return fmt.Errorf("--self-check takes no --command or input")
+				}
+				return runSchemaSelfCheck(schemasDir, outputFormat)
+			}
+			if command == "" {
+				return fmt.Errorf("--command is required unless --self-check is set")
+			}
+			schema, err := commandOutputSchema(command, typeJSON)
+			if err != nil {
+				return err
+			}
+			in := io.Reader(os.Stdin)
+			if len(args) == 1 && args[0] != "-" {
+				f, err := os.Open(args[0])
+				if err != nil {
+					return fmt.Errorf("failed to open input: %w", err)
+				}
+				defer f.Close()
+				in = f
+			}
+			report, err := validateOutput(command, schema, in)
+			if err != nil {
+				return err
+			}
+
+			if outputFormat == "json" {
+				if err := json.NewEncoder(os.Stdout).Encode(report); err != nil {
+					return fmt.Errorf("failed to encode report: %w", err)
+				}
+			} else {
+				for _, v := range report.Violations {
+					fmt.Printf("document %d: %s: %s\n", v.Document, v.Path, v.Message)
+				}
+				if report.Valid {
+					fmt.Printf("%d document(s) match the %s schema\n", report.Documents, command)
+				}
+			}
+			if !report.Valid {
+				return fmt.Errorf("%d schema violation(s)", len(report.Violations))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&command, "command", "", `Command that printed the output, e.g. "cty convert"`)
+	cmd.Flags().StringVar(&typeJSON, "type", "", "For cty convert, the --type it was run with")
+	cmd.Flags().BoolVar(&selfCheck, "self-check", false, "Check every schema against samples of its Go type instead")
+	cmd.Flags().StringVar(&schemasDir, "schemas-dir", "", "With --self-check, also compare the shipped schema files here")
+	cmd.Flags().StringVar(&outputFormat, "output-format", "text", "Output format (text, json)")
+	return cmd
+}
+
+// runSchemaSelfCheck prints a self-check and fails if any part failed
+func runSchemaSelfCheck(schemasDir, outputFormat string) error {
+	report, err := selfCheckOutputSchemas(schemasDir)
+	if err != nil {
+		return err
+	}
+	if outputFormat == "json" {
+		if err := json.NewEncoder(os.Stdout).Encode(report); err != nil {
+			return fmt.Errorf("failed to encode report: %w", err)
+		}
+	} else {
+		for _, c := range report.Commands {
+			status := "ok"
+			if !c.Samples {
+				status = "samples fail"
+			}
+			if c.Shipped != "" {
+				status += ", shipped " + c.Shipped
+			}
+			fmt.Printf("%-40s %s\n", c.Command, status)
+			for _, v := range c.Violations {
+				fmt.Printf("  sample %d: %s: %s\n", v.Document, v.Path, v.Message)
+			}
+		}
+		for _, name := range report.Unknown {
+			fmt.Printf("%-40s unknown schema file\n", name)
+		}
+	}
+	if !report.OK {
+		return fmt.Errorf("output schema self-check failed")
+	}
+	return nil
+}
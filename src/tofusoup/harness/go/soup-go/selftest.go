@@ -0,0 +1,618 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/spf13/cobra"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+	"github.com/zclconf/go-cty/cty/function/stdlib"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+	ctymsgpack "github.com/zclconf/go-cty/cty/msgpack"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/provide-io/tofusoup/proto/counter"
+	"github.com/provide-io/tofusoup/proto/echo"
+	"github.com/provide-io/tofusoup/proto/kv"
+)
+
+// selftestCase is a single check in the internal suite. Description,
+// Prerequisites and ExpectedMs are what `harness cases list` reports.
+type selftestCase struct {
+	Subsystem     string
+	Name          string
+	Description   string
+	Prerequisites []string
+	ExpectedMs    int64
+	Run           func(ctx context.Context) error
+}
+
+// ID names the case as `selftest --case` takes it
+func (c selftestCase) ID() string {
+	return c.Subsystem + "/" + c.Name
+}
+
+// selftestResult is the outcome of one selftestCase
+type selftestResult struct {
+	Subsystem  string  `json:"subsystem"`
+	Name       string  `json:"name"`
+	Passed     bool    `json:"passed"`
+	DurationMs float64 `json:"duration_ms"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// selftestReport is the structured output of a selftest run
+type selftestReport struct {
+	Harness    string           `json:"harness"`
+	Version    string           `json:"version"`
+	Passed     int              `json:"passed"`
+	Failed     int              `json:"failed"`
+	DurationMs float64          `json:"duration_ms"`
+	Results    []selftestResult `json:"results"`
+}
+
+// ctyRoundTripCases are type/value pairs that must survive JSON and msgpack
+// encoding unchanged
+var ctyRoundTripCases = []struct {
+	name  string
+	typ   string
+	value string
+}{
+	{"string", `"string"`, `"hello 🍲"`},
+	{"number_int", `"number"`, `42`},
+	{"number_big", `"number"`, `123456789012345678901234567890`},
+	{"number_fraction", `"number"`, `-0.125`},
+	{"bool", `"bool"`, `true`},
+	{"null_string", `"string"`, `null`},
+	{"list", `["list","string"]`, `["a","b","c"]`},
+	{"set", `["set","number"]`, `[3,1,2]`},
+	{"map", `["map","bool"]`, `{"x":true,"y":false}`},
+	{"tuple", `["tuple",["string","number","bool"]]`, `["a",1,false]`},
+	{"object", `["object",{"name":"string","tags":["list","string"]}]`, `{"name":"n","tags":["t1"]}`},
+	{"nested", `["list",["object",{"id":"number","meta":["map","string"]}]]`, `[{"id":1,"meta":{"k":"v"}},{"id":2,"meta":{}}]`},
+}
+
+// wireCanonicalVectors are values with a fixed msgpack encoding shared by all
+// harnesses
+var wireCanonicalVectors = []struct {
+	name  string
+	value cty.Value
+	hex   string
+}{
+	{"null", cty.NullVal(cty.String), "c0"},
+	{"unknown", cty.UnknownVal(cty.String), "d40000"},
+	{"true", cty.True, "c3"},
+	{"false", cty.False, "c2"},
+	{"int_small", cty.NumberIntVal(1), "01"},
+	{"int_negative", cty.NumberIntVal(-1), "ff"},
+	{"int_large", cty.NumberIntVal(1 << 40), "cf0000010000000000"},
+	{"float", cty.NumberFloatVal(1.5), "cb3ff8000000000000"},
+	{"string", cty.StringVal("hello"), "a568656c6c6f"},
+	{"list", cty.ListVal([]cty.Value{cty.NumberIntVal(1), cty.NumberIntVal(2)}), "920102"},
+	{"object", cty.ObjectVal(map[string]cty.Value{"a": cty.StringVal("b")}), "81a161a162"},
+}
+
+// hclEvalCases are expressions with the value they must evaluate to
+var hclEvalCases = []struct {
+	name string
+	expr string
+	want cty.Value
+}{
+	{"arithmetic", `a + b * 2`, cty.NumberIntVal(7)},
+	{"template", `"${name}-${a}"`, cty.StringVal("soup-1")},
+	{"conditional", `a > b ? "gt" : "le"`, cty.StringVal("le")},
+	{"for_expr", `[for s in list : upper(s)]`, cty.TupleVal([]cty.Value{cty.StringVal("X"), cty.StringVal("Y")})},
+	{"index", `obj.items[1]`, cty.StringVal("second")},
+	{"splat", `length(objs[*].id)`, cty.NumberIntVal(2)},
+}
+
+const selftestHCLConfig = `
+region = "us-east-1"
+count  = 3
+
+resource "aws_instance" "web" {
+  ami  = "ami-123"
+  tags = { env = "test" }
+
+  ebs_block_device {
+    size = 10
+  }
+}
+`
+
+// selftestCases returns the internal suite
+func selftestCases(logger hclog.Logger) []selftestCase {
+	var cases []selftestCase
+
+	for _, tc := range ctyRoundTripCases {
+		tc := tc
+		cases = append(cases, selftestCase{
+			Subsystem:   "cty",
+			Name:        "roundtrip/" + tc.name,
+			Description: "A " + tc.name + " value survives JSON and msgpack re-encoding unchanged",
+			ExpectedMs:  5,
+			Run: func(ctx context.Context) error {
+				return selftestCtyRoundTrip(tc.typ, tc.value)
+			},
+		})
+	}
+
+	for _, tc := range hclEvalCases {
+		tc := tc
+		cases = append(cases, selftestCase{
+			Subsystem:   "hcl",
+			Name:        "eval/" + tc.name,
+			Description: "The expression " + tc.expr + " evaluates to the expected value",
+			ExpectedMs:  5,
+			Run: func(ctx context.Context) error {
+				return selftestHCLEval(tc.expr, tc.want)
+			},
+		})
+	}
+	cases = append(cases,
+		selftestCase{Subsystem: "hcl", Name: "parse/config", Description: "A configuration with attributes and nested blocks parses", ExpectedMs: 20, Run: func(ctx context.Context) error { return selftestHCLParse() }},
+		selftestCase{Subsystem: "hcl", Name: "parse/invalid", Description: "Invalid syntax is reported as diagnostics", ExpectedMs: 20, Run: func(ctx context.Context) error { return selftestHCLInvalid() }},
+	)
+
+	for _, tc := range wireCanonicalVectors {
+		tc := tc
+		cases = append(cases, selftestCase{
+			Subsystem:   "wire",
+			Name:        "canonical/" + tc.name,
+			Description: "A " + tc.name + " value encodes to the msgpack bytes shared by all harnesses",
+			ExpectedMs:  5,
+			Run: func(ctx context.Context) error {
+				return selftestWireVector(tc.value, tc.hex)
+			},
+		})
+	}
+	for _, nesting := range []string{"list", "set", "map", "single", "group"} {
+		nesting := nesting
+		cases = append(cases, selftestCase{
+			Subsystem:   "wire",
+			Name:        "nesting/" + nesting,
+			Description: "A generated block with " + nesting + " nesting decodes the same from JSON and msgpack",
+			ExpectedMs:  5,
+			Run: func(ctx context.Context) error {
+				return selftestNestingVector(nesting)
+			},
+		})
+	}
+
+	for _, useTLS := range []bool{false, true} {
+		useTLS := useTLS
+		name := "loopback/plaintext"
+		description := "KV, counter and echo calls succeed against an in-process server without TLS"
+		if useTLS {
+			name = "loopback/tls"
+			description = "KV, counter and echo calls succeed against an in-process server over TLS"
+		}
+		cases = append(cases, selftestCase{
+			Subsystem:     "rpc",
+			Name:          name,
+			Description:   description,
+			Prerequisites: []string{prereqLoopbackTCP},
+			ExpectedMs:    100,
+			Run: func(ctx context.Context) error {
+				return selftestRPCLoopback(ctx, logger, useTLS)
+			},
+		})
+	}
+
+	return cases
+}
+
+// selftestCtyRoundTrip checks that a value decodes from JSON and survives
+// msgpack and JSON re-encoding unchanged
+func selftestCtyRoundTrip(typeJSON, valueJSON string) error {
+	ty, err := parseCtyType(json.RawMessage(typeJSON))
+	if err != nil {
+		return fmt.Errorf("failed to parse type: %w", err)
+	}
+	val, err := buildCtyValueFromJSON(ty, []byte(valueJSON))
+	if err != nil {
+		return fmt.Errorf("failed to build value: %w", err)
+	}
+
+	packed, err := ctymsgpack.Marshal(val, ty)
+	if err != nil {
+		return fmt.Errorf("failed to encode msgpack: %w", err)
+	}
+	fromMsgpack, err := ctymsgpack.Unmarshal(packed, ty)
+	if err != nil {
+		return fmt.Errorf("failed to decode msgpack: %w", err)
+	}
+	if !fromMsgpack.RawEquals(val) {
+		return fmt.Errorf("msgpack round-trip changed value: %#v != %#v", fromMsgpack, val)
+	}
+
+	encoded, err := ctyjson.Marshal(val, ty)
+	if err != nil {
+		return fmt.Errorf("failed to encode JSON: %w", err)
+	}
+	fromJSON, err := ctyjson.Unmarshal(encoded, ty)
+	if err != nil {
+		return fmt.Errorf("failed to decode JSON: %w", err)
+	}
+	if !fromJSON.RawEquals(val) {
+		return fmt.Errorf("JSON round-trip changed value: %#v != %#v", fromJSON, val)
+	}
+	return nil
+}
+
+// selftestHCLEval evaluates expr against a fixed context and compares the result
+func selftestHCLEval(expr string, want cty.Value) error {
+	parsed, diags := hclsyntax.ParseExpression([]byte(expr), "selftest.hcl", hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return fmt.Errorf("failed to parse expression: %s", diags.Error())
+	}
+
+	ctx := &hcl.EvalContext{
+		Variables: map[string]cty.Value{
+			"a":    cty.NumberIntVal(1),
+			"b":    cty.NumberIntVal(3),
+			"name": cty.StringVal("soup"),
+			"list": cty.ListVal([]cty.Value{cty.StringVal("x"), cty.StringVal("y")}),
+			"obj": cty.ObjectVal(map[string]cty.Value{
+				"items": cty.TupleVal([]cty.Value{cty.StringVal("first"), cty.StringVal("second")}),
+			}),
+			"objs": cty.ListVal([]cty.Value{
+				cty.ObjectVal(map[string]cty.Value{"id": cty.NumberIntVal(1)}),
+				cty.ObjectVal(map[string]cty.Value{"id": cty.NumberIntVal(2)}),
+			}),
+		},
+		Functions: selftestFunctions(),
+	}
+
+	got, diags := parsed.Value(ctx)
+	if diags.HasErrors() {
+		return fmt.Errorf("failed to evaluate expression: %s", diags.Error())
+	}
+	if !got.RawEquals(want) {
+		return fmt.Errorf("got %#v, want %#v", got, want)
+	}
+	return nil
+}
+
+// selftestFunctions is the small function table available to eval cases
+func selftestFunctions() map[string]function.Function {
+	return map[string]function.Function{
+		"upper":  stdlib.UpperFunc,
+		"length": stdlib.LengthFunc,
+	}
+}
+
+// selftestHCLParse parses a small configuration and checks its JSON form
+func selftestHCLParse() error {
+	file, diags := hclparse.NewParser().ParseHCL([]byte(selftestHCLConfig), "selftest.hcl")
+	if diags.HasErrors() {
+		return fmt.Errorf("failed to parse: %s", diags.Error())
+	}
+	converted, err := hclFileToJSON(file)
+	if err != nil {
+		return fmt.Errorf("failed to convert: %w", err)
+	}
+
+	result, ok := converted.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("unexpected result type %T", converted)
+	}
+	if result["region"] != "us-east-1" {
+		return fmt.Errorf("region = %v, want us-east-1", result["region"])
+	}
+	if result["count"] != float64(3) {
+		return fmt.Errorf("count = %v, want 3", result["count"])
+	}
+	blocks, ok := result["blocks"].([]map[string]interface{})
+	if !ok || len(blocks) != 1 {
+		return fmt.Errorf("expected 1 top-level block, got %v", result["blocks"])
+	}
+	if blocks[0]["type"] != "resource" {
+		return fmt.Errorf("block type = %v, want resource", blocks[0]["type"])
+	}
+	body, _ := blocks[0]["body"].(map[string]interface{})
+	if nested, _ := body["blocks"].([]map[string]interface{}); len(nested) != 1 {
+		return fmt.Errorf("expected 1 nested block, got %v", body["blocks"])
+	}
+	return nil
+}
+
+// selftestHCLInvalid checks that malformed input produces positioned diagnostics
+func selftestHCLInvalid() error {
+	_, diags := hclparse.NewParser().ParseHCL([]byte("a = \nb = {"), "invalid.hcl")
+	if !diags.HasErrors() {
+		return fmt.Errorf("expected parse errors, got none")
+	}
+	for _, d := range diagnosticsToJSON(diags) {
+		if _, ok := d["range"]; !ok {
+			return fmt.Errorf("diagnostic %q has no source range", d["summary"])
+		}
+	}
+	return nil
+}
+
+// selftestWireVector checks a value encodes to the expected msgpack bytes and
+// decodes back to itself
+func selftestWireVector(val cty.Value, wantHex string) error {
+	packed, err := ctymsgpack.Marshal(val, val.Type())
+	if err != nil {
+		return fmt.Errorf("failed to encode: %w", err)
+	}
+	if got := hex.EncodeToString(packed); got != wantHex {
+		return fmt.Errorf("encoded %s, want %s", got, wantHex)
+	}
+	decoded, err := ctymsgpack.Unmarshal(packed, val.Type())
+	if err != nil {
+		return fmt.Errorf("failed to decode: %w", err)
+	}
+	if !decoded.RawEquals(val) {
+		return fmt.Errorf("decoded %#v, want %#v", decoded, val)
+	}
+	return nil
+}
+
+// selftestNestingVector builds a nesting-mode vector and checks its msgpack
+// and JSON encodings agree
+func selftestNestingVector(nesting string) error {
+	count := 2
+	if nesting == "single" || nesting == "group" {
+		count = 1
+	}
+	config, err := generateNestingConfig(nesting, count, false, rand.New(rand.NewSource(1)))
+	if err != nil {
+		return err
+	}
+	configJSON, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+	vector, err := buildSchemaValuesVector(nestingTestSchema(nesting), configJSON)
+	if err != nil {
+		return err
+	}
+
+	ty, err := ctyjson.UnmarshalType(vector.Type)
+	if err != nil {
+		return fmt.Errorf("failed to decode type: %w", err)
+	}
+	fromJSON, err := ctyjson.Unmarshal(vector.ValueJSON, ty)
+	if err != nil {
+		return fmt.Errorf("failed to decode JSON value: %w", err)
+	}
+	packed, err := ctymsgpack.Marshal(fromJSON, ty)
+	if err != nil {
+		return fmt.Errorf("failed to re-encode msgpack: %w", err)
+	}
+	fromMsgpack, err := ctymsgpack.Unmarshal(packed, ty)
+	if err != nil {
+		return fmt.Errorf("failed to decode msgpack: %w", err)
+	}
+	if !fromMsgpack.RawEquals(fromJSON) {
+		return fmt.Errorf("msgpack and JSON encodings disagree")
+	}
+	return nil
+}
+
+// selftestRPCLoopback serves KV, counter and echo on a loopback listener in
+// this process and exercises each service over a real gRPC connection.
+func selftestRPCLoopback(ctx context.Context, logger hclog.Logger, useTLS bool) error {
+	storageDir, err := os.MkdirTemp("", "soup-go-selftest-")
+	if err != nil {
+		return fmt.Errorf("failed to create storage dir: %w", err)
+	}
+	defer os.RemoveAll(storageDir)
+
+	var serverOpts []grpc.ServerOption
+	dialCreds := insecure.NewCredentials()
+	if useTLS {
+		certPEM, keyPEM, err := generateCertWithCurve(logger, "secp256r1")
+		if err != nil {
+			return err
+		}
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return fmt.Errorf("failed to load certificate: %w", err)
+		}
+		serverOpts = append(serverOpts, grpc.Creds(credentials.NewTLS(&tls.Config{
+			Certificates: []tls.Certificate{cert},
+			MinVersion:   tls.VersionTLS12,
+			Time:         certNow,
+		})))
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(certPEM) {
+			return fmt.Errorf("failed to add certificate to pool")
+		}
+		dialCreds = credentials.NewTLS(&tls.Config{
+			RootCAs:    pool,
+			ServerName: "localhost",
+			MinVersion: tls.VersionTLS12,
+			Time:       certNow,
+		})
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+
+	server := grpc.NewServer(serverOpts...)
+	proto.RegisterKVServer(server, newGRPCServer(NewKVImpl(logger, storageDir), KVServerOptions{}, logger))
+	counterServer := NewCounterServer(logger, defaultCounterBuffer)
+	counter.RegisterCounterServer(server, counterServer)
+	echo.RegisterEchoServer(server, NewEchoServer(logger, 0))
+	go server.Serve(listener)
+	defer func() {
+		counterServer.Stop()
+		server.Stop()
+	}()
+
+	conn, err := grpc.Dial(listener.Addr().String(), grpc.WithTransportCredentials(dialCreds))
+	if err != nil {
+		return fmt.Errorf("failed to dial: %w", err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	kvClient := proto.NewKVClient(conn)
+	keys := []string{"selftest-a", "selftest-b", "selftest-c"}
+	for i, key := range keys {
+		if _, err := kvClient.Put(ctx, &proto.PutRequest{Key: key, Value: []byte(fmt.Sprintf("value-%d", i))}); err != nil {
+			return fmt.Errorf("Put %s failed: %w", key, err)
+		}
+	}
+	resp, err := kvClient.Get(ctx, &proto.GetRequest{Key: "selftest-b"})
+	if err != nil {
+		return fmt.Errorf("Get failed: %w", err)
+	}
+	if !bytes.Equal(resp.Value, []byte("value-1")) {
+		return fmt.Errorf("Get returned %q, want %q", resp.Value, "value-1")
+	}
+	list := &GRPCClient{client: kvClient, logger: logger}
+	listed, pages, err := list.ListPages("selftest-", 2)
+	if err != nil {
+		return fmt.Errorf("List failed: %w", err)
+	}
+	if strings.Join(listed, ",") != strings.Join(keys, ",") || pages != 2 {
+		return fmt.Errorf("List returned %v in %d pages, want %v in 2", listed, pages, keys)
+	}
+
+	counterClient := counter.NewCounterClient(conn)
+	for i := 1; i <= 3; i++ {
+		inc, err := counterClient.Increment(ctx, &counter.IncrementRequest{Name: "selftest", Delta: 2})
+		if err != nil {
+			return fmt.Errorf("Increment failed: %w", err)
+		}
+		if inc.Value != int64(2*i) {
+			return fmt.Errorf("Increment returned %d, want %d", inc.Value, 2*i)
+		}
+	}
+
+	report, err := runChat(ctx, echo.NewEchoClient(conn), 16, 256, 4, 0)
+	if err != nil {
+		return err
+	}
+	if report.Error != "" || !report.InOrder || report.PayloadMismatches > 0 {
+		return fmt.Errorf("chat failed: %d/16 frames, %d out of order, %d mismatches %s",
+			report.Received, report.OutOfOrder, report.PayloadMismatches, report.Error)
+	}
+	return nil
+}
+
+// runSelftest runs the cases whose subsystem is in subsystems and whose ID
+// is in caseIDs, each list selecting all cases when empty
+func runSelftest(ctx context.Context, logger hclog.Logger, subsystems, caseIDs []string) *selftestReport {
+	selected := map[string]bool{}
+	for _, s := range subsystems {
+		selected[s] = true
+	}
+	selectedCases := map[string]bool{}
+	for _, id := range caseIDs {
+		selectedCases[id] = true
+	}
+
+	report := &selftestReport{Harness: "soup-go", Version: version, Results: []selftestResult{}}
+	start := time.Now()
+	for _, tc := range selftestCases(logger) {
+		if len(selected) > 0 && !selected[tc.Subsystem] {
+			continue
+		}
+		if len(selectedCases) > 0 && !selectedCases[tc.ID()] {
+			continue
+		}
+		caseStart := time.Now()
+		err := tc.Run(ctx)
+		result := selftestResult{
+			Subsystem:  tc.Subsystem,
+			Name:       tc.Name,
+			Passed:     err == nil,
+			DurationMs: float64(time.Since(caseStart)) / float64(time.Millisecond),
+		}
+		if err != nil {
+			result.Error = err.Error()
+			report.Failed++
+			logger.Warn("🧪❌ selftest case failed", "subsystem", tc.Subsystem, "name", tc.Name, "error", err)
+		} else {
+			report.Passed++
+			logger.Debug("🧪✅ selftest case passed", "subsystem", tc.Subsystem, "name", tc.Name)
+		}
+		report.Results = append(report.Results, result)
+	}
+	report.DurationMs = float64(time.Since(start)) / float64(time.Millisecond)
+	return report
+}
+
+// printSelftestReport writes the report as JSON and returns an error if any
+// case failed
+func printSelftestReport(report *selftestReport) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return fmt.Errorf("failed to encode report: %w", err)
+	}
+	if report.Failed > 0 {
+		return fmt.Errorf("%d of %d selftest cases failed", report.Failed, report.Passed+report.Failed)
+	}
+	return nil
+}
+
+func initSelftestCmd() *cobra.Command {
+	var subsystems []string
+	var caseIDs []string
+
+	cmd := &cobra.Command{
+		Use:   "selftest",
+		Short: "Run the internal cty, hcl, wire and RPC self-test suite",
+		Long: `Run an internal suite of checks against this binary: cty JSON/msgpack
+round-trips, hcl parse and evaluation cases, canonical wire vectors and an
+in-process RPC loopback with TLS off and on. Prints a JSON report with one
+result per case and exits non-zero if any case fails.
+
+--case runs single cases by the IDs 'harness cases list' reports, with or
+without their selftest/ prefix, so a scheduler can shard the suite.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			for _, s := range subsystems {
+				switch s {
+				case "cty", "hcl", "wire", "rpc":
+				default:
+					return fmt.Errorf("unknown subsystem %q (expected cty, hcl, wire, rpc)", s)
+				}
+			}
+			known := map[string]bool{}
+			for _, tc := range selftestCases(logger) {
+				known[tc.ID()] = true
+			}
+			for i, id := range caseIDs {
+				caseIDs[i] = strings.TrimPrefix(id, "selftest/")
+				if !known[caseIDs[i]] {
+					return fmt.Errorf("unknown selftest case %q (see 'soup-go harness cases list')", id)
+				}
+			}
+			return printSelftestReport(runSelftest(context.Background(), logger.Named("selftest"), subsystems, caseIDs))
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&subsystems, "subsystem", nil, "Only run these subsystems: cty, hcl, wire, rpc (default all)")
+	cmd.Flags().StringArrayVar(&caseIDs, "case", nil, "Only run the case with this ID, e.g. cty/roundtrip/string; repeatable")
+	return cmd
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func runSelftestCmd(t *testing.T, args ...string) (*selftestReport, error) {
+	t.Helper()
+	cmd := initSelftestCmd()
+	cmd.SetArgs(args)
+	cmd.SilenceUsage, cmd.SilenceErrors = true, true
+	out, err := captureStdout(t, cmd.Execute)
+	if len(out) == 0 {
+		return nil, err
+	}
+	var report selftestReport
+	if jerr := json.Unmarshal(out, &report); jerr != nil {
+		t.Fatalf("%v: %s", jerr, out)
+	}
+	return &report, err
+}
+
+func TestSelftestCmdPasses(t *testing.T) {
+	report, err := runSelftestCmd(t)
+	if err != nil {
+		for _, result := range report.Results {
+			if !result.Passed {
+				t.Errorf("%s/%s: %s", result.Subsystem, result.Name, result.Error)
+			}
+		}
+		t.Fatal(err)
+	}
+
+	subsystems := map[string]int{}
+	for _, result := range report.Results {
+		subsystems[result.Subsystem]++
+	}
+	if report.Passed != len(selftestCases(logger)) || report.Failed != 0 {
+		t.Errorf("got %d passed and %d failed, want all %d cases to pass",
+			report.Passed, report.Failed, len(selftestCases(logger)))
+	}
+	for _, subsystem := range []string{"cty", "hcl", "wire", "rpc"} {
+		if subsystems[subsystem] == 0 {
+			t.Errorf("no %s cases ran", subsystem)
+		}
+	}
+}
+
+func TestSelftestCmdSelectsCases(t *testing.T) {
+	report, err := runSelftestCmd(t, "--subsystem", "cty,wire")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, result := range report.Results {
+		if result.Subsystem != "cty" && result.Subsystem != "wire" {
+			t.Errorf("--subsystem cty,wire ran %s/%s", result.Subsystem, result.Name)
+		}
+	}
+
+	report, err = runSelftestCmd(t, "--case", "selftest/cty/roundtrip/string", "--case", "wire/canonical/null")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, result := range report.Results {
+		ids = append(ids, result.Subsystem+"/"+result.Name)
+	}
+	if got := strings.Join(ids, ","); got != "cty/roundtrip/string,wire/canonical/null" {
+		t.Errorf("got cases %s", got)
+	}
+}
+
+func TestSelftestCmdRejectsUnknownSelections(t *testing.T) {
+	for _, args := range [][]string{
+		{"--subsystem", "tls"},
+		{"--case", "cty/roundtrip/nope"},
+	} {
+		if _, err := runSelftestCmd(t, args...); err == nil || !strings.Contains(err.Error(), "unknown") {
+			t.Errorf("%v: got %v, want an unknown selection error", args, err)
+		}
+	}
+}
+
+func TestPrintSelftestReportFailsOnFailedCase(t *testing.T) {
+	report := &selftestReport{Passed: 2, Failed: 1}
+	_, err := captureStdout(t, func() error { return printSelftestReport(report) })
+	if err == nil || err.Error() != "1 of 3 selftest cases failed" {
+		t.Errorf("got %v, want a failed case count", err)
+	}
+}
@@ -0,0 +1,871 @@
+package main
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"os"
+	"runtime/debug"
+	"sort"
+	"strconv"
+
+	"github.com/spf13/cobra"
+	"github.com/vmihailenco/msgpack/v5"
+	"github.com/vmihailenco/msgpack/v5/msgpcode"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+	ctymsgpack "github.com/zclconf/go-cty/cty/msgpack"
+)
+
+// Constrained mode (--stream-parse)
+//
+// The default code paths read the whole input, decode it into an
+// intermediate tree and then build the cty value, so peak memory is several
+// times the input size. With --stream-parse, input is read in
+// streamChunkSize chunks and decoded token by token straight into the cty
+// value, and output is encoded straight to the destination. The value
+// itself is the only full-size structure kept in memory.
+//
+// The target is to stay under --max-memory-mb (default 64 MiB) of heap for
+// inputs whose decoded value fits in roughly half of it. The limit is applied
+// as the Go runtime's soft memory limit, so the GC works harder rather than
+// the process failing when it gets close. HCL sources can't be parsed
+// incrementally, so hcl commands instead refuse sources larger than a
+// quarter of the target.
+const (
+	// defaultStreamMaxMemoryMB is the default --max-memory-mb
+	defaultStreamMaxMemoryMB = 64
+
+	// streamChunkSize is the read and write buffer size in streaming mode
+	streamChunkSize = 64 * 1024
+)
+
+// streamWriter is what the value encoders write to: a *bufio.Writer in
+// streaming mode, a pooled *bytes.Buffer on the wire fast path
+type streamWriter interface {
+	io.Writer
+	io.ByteWriter
+	io.StringWriter
+	AvailableBuffer() []byte
+}
+
+// streamOptions holds the --stream-parse flags shared by cty, hcl and wire
+type streamOptions struct {
+	Enabled     bool
+	MaxMemoryMB int
+}
+
+// addStreamFlags registers --stream-parse and --max-memory-mb on cmd
+func addStreamFlags(cmd *cobra.Command, opts *streamOptions) {
+	cmd.Flags().BoolVar(&opts.Enabled, "stream-parse", false, "Decode input incrementally to keep peak memory bounded (for small CI runners and embedded targets)")
+	cmd.Flags().IntVar(&opts.MaxMemoryMB, "max-memory-mb", defaultStreamMaxMemoryMB, "Memory target in MiB for --stream-parse (applied as the runtime soft memory limit)")
+}
+
+// apply sets the runtime memory limit for a streaming run
+func (o *streamOptions) apply() error {
+	if o.MaxMemoryMB < 1 {
+		return fmt.Errorf("--max-memory-mb must be positive")
+	}
+	debug.SetMemoryLimit(int64(o.MaxMemoryMB) << 20)
+	logger.Debug("🌊 streaming mode enabled", "max_memory_mb", o.MaxMemoryMB)
+	return nil
+}
+
+// checkSourceSize rejects in-memory sources too large for the memory target
+func (o *streamOptions) checkSourceSize(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	if limit := int64(o.MaxMemoryMB) << 20 / 4; info.Size() > limit {
+		return fmt.Errorf("%s is %d bytes; HCL sources are parsed in memory and --stream-parse allows at most %d with --max-memory-mb %d",
+			path, info.Size(), limit, o.MaxMemoryMB)
+	}
+	return nil
+}
+
+// openStreamInput opens path ("-" for stdin) for chunked reading
+func openStreamInput(path string) (*bufio.Reader, func() error, error) {
+	if path == "-" {
+		return bufio.NewReaderSize(os.Stdin, streamChunkSize), func() error { return nil }, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read input file: %w", err)
+	}
+	return bufio.NewReaderSize(f, streamChunkSize), f.Close, nil
+}
+
+// openStreamOutput opens path ("-" for stdout) for buffered writing. The
+// returned close function flushes the buffer.
+func openStreamOutput(path string) (*bufio.Writer, func() error, error) {
+	if path == "-" {
+		w := bufio.NewWriterSize(os.Stdout, streamChunkSize)
+		return w, w.Flush, nil
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to write output: %w", err)
+	}
+	w := bufio.NewWriterSize(f, streamChunkSize)
+	return w, func() error {
+		if err := w.Flush(); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	}, nil
+}
+
+// decodeStreamValue decodes a single value of type ty from r
+func decodeStreamValue(r *bufio.Reader, ty cty.Type, format string) (cty.Value, error) {
+	switch format {
+	case "json":
+		dec := json.NewDecoder(r)
+		dec.UseNumber()
+		val, err := decodeJSONStream(dec, ty, cty.Path{})
+		if err != nil {
+			return cty.NilVal, err
+		}
+		if _, err := dec.Token(); err != io.EOF {
+			return cty.NilVal, fmt.Errorf("unexpected data after the value")
+		}
+		return val, nil
+	case "msgpack":
+		dec := msgpack.NewDecoder(r)
+		val, err := decodeMsgpackStream(dec, ty, newValuePath())
+		if err != nil {
+			return cty.NilVal, err
+		}
+		if _, err := dec.PeekCode(); err != io.EOF {
+			return cty.NilVal, fmt.Errorf("unexpected data after the value")
+		}
+		return val, nil
+	default:
+		return cty.NilVal, fmt.Errorf("unsupported input format: %s", format)
+	}
+}
+
+// conformValue converts val to ty if its type doesn't already conform, as
+// ctyjson.Marshal and ctymsgpack.Marshal do before encoding; the encoders
+// below assume a conforming value
+func conformValue(val cty.Value, ty cty.Type) (cty.Value, error) {
+	if errs := val.Type().TestConformance(ty); errs != nil {
+		return convert.Convert(val, ty)
+	}
+	return val, nil
+}
+
+// encodeStreamValue encodes val as ty to w
+func encodeStreamValue(w streamWriter, val cty.Value, ty cty.Type, format string) error {
+	val, err := conformValue(val, ty)
+	if err != nil {
+		return err
+	}
+	switch format {
+	case "json":
+		return encodeJSONStream(w, val, ty, newValuePath())
+	case "msgpack":
+		enc := msgpack.NewEncoder(w)
+		enc.UseCompactInts(true)
+		enc.UseCompactFloats(true)
+		return encodeMsgpackStream(w, enc, val, ty, newValuePath())
+	default:
+		return fmt.Errorf("unsupported output format: %s", format)
+	}
+}
+
+// decodeJSONStream reads one JSON value of type ty from dec
+func decodeJSONStream(dec *json.Decoder, ty cty.Type, path cty.Path) (cty.Value, error) {
+	if ty == cty.DynamicPseudoType {
+		// The type has to be inferred from the whole subtree, so buffer it
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return cty.NilVal, path.NewError(err)
+		}
+		return buildCtyValueFromJSON(ty, raw)
+	}
+
+	tok, err := dec.Token()
+	if err != nil {
+		return cty.NilVal, path.NewError(err)
+	}
+	if tok == nil {
+		return cty.NullVal(ty), nil
+	}
+
+	switch {
+	case ty == cty.String:
+		if s, ok := tok.(string); ok {
+			return cty.StringVal(s), nil
+		}
+		return cty.NilVal, path.NewErrorf("expected string")
+	case ty == cty.Number:
+		var text string
+		switch v := tok.(type) {
+		case json.Number:
+			text = string(v)
+		case string:
+			text = v
+		default:
+			return cty.NilVal, path.NewErrorf("expected number")
+		}
+		val, err := cty.ParseNumberVal(text)
+		if err != nil {
+			return cty.NilVal, path.NewErrorf("invalid number %q", text)
+		}
+		return val, nil
+	case ty == cty.Bool:
+		if b, ok := tok.(bool); ok {
+			return cty.BoolVal(b), nil
+		}
+		return cty.NilVal, path.NewErrorf("expected bool")
+	case ty.IsListType() || ty.IsSetType() || ty.IsTupleType():
+		if tok != json.Delim('[') {
+			return cty.NilVal, path.NewErrorf("expected array")
+		}
+		var vals []cty.Value
+		for i := 0; dec.More(); i++ {
+			var ety cty.Type
+			switch {
+			case ty.IsTupleType():
+				if i >= ty.Length() {
+					return cty.NilVal, path.NewErrorf("tuple has more than %d elements", ty.Length())
+				}
+				ety = ty.TupleElementType(i)
+			default:
+				ety = ty.ElementType()
+			}
+			val, err := decodeJSONStream(dec, ety, path.Index(cty.NumberIntVal(int64(i))))
+			if err != nil {
+				return cty.NilVal, err
+			}
+			vals = append(vals, val)
+		}
+		if _, err := dec.Token(); err != nil {
+			return cty.NilVal, path.NewError(err)
+		}
+
+		switch {
+		case ty.IsTupleType():
+			if len(vals) != ty.Length() {
+				return cty.NilVal, path.NewErrorf("tuple requires %d elements, got %d", ty.Length(), len(vals))
+			}
+			return cty.TupleVal(vals), nil
+		case len(vals) == 0 && ty.IsListType():
+			return cty.ListValEmpty(ty.ElementType()), nil
+		case len(vals) == 0:
+			return cty.SetValEmpty(ty.ElementType()), nil
+		case ty.IsListType():
+			return cty.ListVal(vals), nil
+		default:
+			return cty.SetVal(vals), nil
+		}
+	case ty.IsMapType() || ty.IsObjectType():
+		if tok != json.Delim('{') {
+			return cty.NilVal, path.NewErrorf("expected object")
+		}
+		vals := map[string]cty.Value{}
+		for dec.More() {
+			keyTok, err := dec.Token()
+			if err != nil {
+				return cty.NilVal, path.NewError(err)
+			}
+			key := keyTok.(string)
+
+			var ety cty.Type
+			if ty.IsObjectType() {
+				if !ty.HasAttribute(key) {
+					return cty.NilVal, path.GetAttr(key).NewErrorf("unsupported attribute %q", key)
+				}
+				ety = ty.AttributeType(key)
+			} else {
+				ety = ty.ElementType()
+			}
+			val, err := decodeJSONStream(dec, ety, path.Index(cty.StringVal(key)))
+			if err != nil {
+				return cty.NilVal, err
+			}
+			vals[key] = val
+		}
+		if _, err := dec.Token(); err != nil {
+			return cty.NilVal, path.NewError(err)
+		}
+
+		if ty.IsMapType() {
+			if len(vals) == 0 {
+				return cty.MapValEmpty(ty.ElementType()), nil
+			}
+			return cty.MapVal(vals), nil
+		}
+		// Absent attributes are null, as in ctyjson
+		for name, aty := range ty.AttributeTypes() {
+			if _, ok := vals[name]; !ok {
+				vals[name] = cty.NullVal(aty)
+			}
+		}
+		return cty.ObjectVal(vals), nil
+	}
+
+	return cty.NilVal, path.NewErrorf("cannot decode type %s", ty.FriendlyName())
+}
+
+// decodeMsgpackStream reads one cty msgpack value of type ty from dec
+func decodeMsgpackStream(dec *msgpack.Decoder, ty cty.Type, path cty.Path) (cty.Value, error) {
+	code, err := dec.PeekCode()
+	if err != nil {
+		return cty.NilVal, path.NewError(err)
+	}
+	if code == msgpcode.Nil {
+		dec.DecodeNil()
+		return cty.NullVal(ty), nil
+	}
+	if ty == cty.DynamicPseudoType || msgpcode.IsExt(code) {
+		// Dynamic values and (refined) unknowns are small; let cty decode
+		// just this subtree
+		raw, err := dec.DecodeRaw()
+		if err != nil {
+			return cty.NilVal, path.NewError(err)
+		}
+		return ctymsgpack.Unmarshal(raw, ty)
+	}
+
+	switch {
+	case ty == cty.String:
+		s, err := dec.DecodeString()
+		if err != nil {
+			return cty.NilVal, path.NewErrorf("string is required")
+		}
+		return cty.StringVal(s), nil
+	case ty == cty.Bool:
+		b, err := dec.DecodeBool()
+		if err != nil {
+			return cty.NilVal, path.NewErrorf("bool is required")
+		}
+		return cty.BoolVal(b), nil
+	case ty == cty.Number:
+		switch {
+		case msgpcode.IsString(code):
+			s, err := dec.DecodeString()
+			if err != nil {
+				return cty.NilVal, path.NewErrorf("number is required")
+			}
+			val, err := cty.ParseNumberVal(s)
+			if err != nil {
+				return cty.NilVal, path.NewErrorf("invalid number %q", s)
+			}
+			return val, nil
+		case code == msgpcode.Float || code == msgpcode.Double:
+			f, err := dec.DecodeFloat64()
+			if err != nil {
+				return cty.NilVal, path.NewErrorf("number is required")
+			}
+			return cty.NumberFloatVal(f), nil
+		case code == msgpcode.Uint8 || code == msgpcode.Uint16 || code == msgpcode.Uint32 || code == msgpcode.Uint64:
+			u, err := dec.DecodeUint64()
+			if err != nil {
+				return cty.NilVal, path.NewErrorf("number is required")
+			}
+			return cty.NumberUIntVal(u), nil
+		default:
+			i, err := dec.DecodeInt64()
+			if err != nil {
+				return cty.NilVal, path.NewErrorf("number is required")
+			}
+			return cty.NumberIntVal(i), nil
+		}
+	case ty.IsListType() || ty.IsSetType() || ty.IsTupleType():
+		n, err := dec.DecodeArrayLen()
+		if err != nil {
+			return cty.NilVal, path.NewErrorf("an array is required")
+		}
+		if ty.IsTupleType() && n != ty.Length() {
+			return cty.NilVal, path.NewErrorf("tuple requires %d elements, got %d", ty.Length(), n)
+		}
+		vals := make([]cty.Value, 0, n)
+		path := append(path, nil)
+		for i := 0; i < n; i++ {
+			var ety cty.Type
+			if ty.IsTupleType() {
+				ety = ty.TupleElementType(i)
+			} else {
+				ety = ty.ElementType()
+			}
+			val, err := decodeMsgpackStream(dec, ety, path)
+			if err != nil {
+				return cty.NilVal, fillPathStep(err, len(path)-1, cty.IndexStep{Key: cty.NumberIntVal(int64(i))})
+			}
+			vals = append(vals, val)
+		}
+
+		switch {
+		case ty.IsTupleType():
+			return cty.TupleVal(vals), nil
+		case n == 0 && ty.IsListType():
+			return cty.ListValEmpty(ty.ElementType()), nil
+		case n == 0:
+			return cty.SetValEmpty(ty.ElementType()), nil
+		case ty.IsListType():
+			return cty.ListVal(vals), nil
+		default:
+			return cty.SetVal(vals), nil
+		}
+	case ty.IsMapType() || ty.IsObjectType():
+		n, err := dec.DecodeMapLen()
+		if err != nil {
+			return cty.NilVal, path.NewErrorf("a map is required")
+		}
+		if ty.IsObjectType() && n == 0 {
+			// ctymsgpack takes an empty map as an empty object whatever the
+			// type's attributes; converting it to ty is left to the caller
+			return cty.EmptyObjectVal, nil
+		}
+		if ty.IsObjectType() && n != len(ty.AttributeTypes()) {
+			return cty.NilVal, path.NewErrorf("an object with %d attributes is required (%d given)", len(ty.AttributeTypes()), n)
+		}
+		vals := make(map[string]cty.Value, n)
+		elemPath := append(path, nil)
+		for i := 0; i < n; i++ {
+			key, err := dec.DecodeString()
+			if err != nil {
+				return cty.NilVal, path.NewErrorf("all keys must be strings")
+			}
+			var ety cty.Type
+			if ty.IsObjectType() {
+				if !ty.HasAttribute(key) {
+					return cty.NilVal, path.GetAttr(key).NewErrorf("unsupported attribute %q", key)
+				}
+				ety = ty.AttributeType(key)
+			} else {
+				ety = ty.ElementType()
+			}
+			val, err := decodeMsgpackStream(dec, ety, elemPath)
+			if err != nil {
+				return cty.NilVal, fillPathStep(err, len(elemPath)-1, cty.IndexStep{Key: cty.StringVal(key)})
+			}
+			vals[key] = val
+		}
+
+		if ty.IsObjectType() {
+			return cty.ObjectVal(vals), nil
+		}
+		if n == 0 {
+			return cty.MapValEmpty(ty.ElementType()), nil
+		}
+		return cty.MapVal(vals), nil
+	}
+
+	return cty.NilVal, path.NewErrorf("cannot decode type %s", ty.FriendlyName())
+}
+
+// newValuePath returns an empty path with room for the steps of a typical
+// value. The msgpack and JSON encoders and the msgpack decoder extend it in
+// place at each level and only fill in their own step when an error passes
+// through (see fillPathStep), so walking a value allocates no paths.
+func newValuePath() cty.Path {
+	return make(cty.Path, 0, 16)
+}
+
+// fillPathStep sets step i of err's path, if err is a cty.PathError. The
+// error owns a copy of the path, so this doesn't touch the caller's.
+func fillPathStep(err error, i int, step cty.PathStep) error {
+	if perr, ok := err.(cty.PathError); ok && i < len(perr.Path) {
+		perr.Path[i] = step
+	}
+	return err
+}
+
+// minNormalFloat64 is the smallest positive normal float64. Below it
+// float64 has fewer than 53 significant bits and strconv's shortest form
+// can differ from big.Float's.
+const minNormalFloat64 = 0x1p-1022
+
+// appendJSONNumber appends bf as big.Float.Text('f', -1) formats it, which
+// is what ctyjson writes. Integers small enough that their precision covers
+// every unit, and normal float64 values, take the much cheaper strconv path,
+// which gives the same digits.
+func appendJSONNumber(dst []byte, bf *big.Float) []byte {
+	if bf.Sign() != 0 {
+		if bf.IsInt() && bf.MantExp(nil) <= int(bf.Prec()) {
+			if iv, acc := bf.Int64(); acc == big.Exact {
+				return strconv.AppendInt(dst, iv, 10)
+			}
+		}
+		if bf.Prec() == 53 {
+			if fv, acc := bf.Float64(); acc == big.Exact && math.Abs(fv) >= minNormalFloat64 {
+				return strconv.AppendFloat(dst, fv, 'f', -1, 64)
+			}
+		}
+	}
+	return bf.Append(dst, 'f', -1)
+}
+
+// writeJSONString writes s quoted as encoding/json does. Plain ASCII is
+// quoted directly; anything encoding/json would escape goes through it.
+func writeJSONString(w streamWriter, s string) error {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c < 0x20 || c >= 0x80 || c == '"' || c == '\\' || c == '<' || c == '>' || c == '&' {
+			data, err := json.Marshal(s)
+			if err != nil {
+				return err
+			}
+			_, err = w.Write(data)
+			return err
+		}
+	}
+	buf := append(w.AvailableBuffer(), '"')
+	buf = append(buf, s...)
+	buf = append(buf, '"')
+	_, err := w.Write(buf)
+	return err
+}
+
+// encodeJSONStream writes val as ty in the same form as ctyjson.Marshal
+func encodeJSONStream(w streamWriter, val cty.Value, ty cty.Type, path cty.Path) error {
+	if val.IsMarked() {
+		return path.NewErrorf("value has marks, so it cannot be serialized as JSON")
+	}
+	if ty == cty.DynamicPseudoType || !val.IsKnown() {
+		// Dynamic wrappers are small, and unknowns are an error either way
+		data, err := ctyjson.Marshal(val, ty)
+		if err != nil {
+			return path.NewError(err)
+		}
+		_, err = w.Write(data)
+		return err
+	}
+	if val.IsNull() {
+		_, err := w.WriteString("null")
+		return err
+	}
+
+	switch {
+	case ty == cty.String:
+		return writeJSONString(w, val.AsString())
+	case ty == cty.Number:
+		bf := val.AsBigFloat()
+		if bf.IsInf() {
+			return path.NewErrorf("cannot serialize infinity as JSON")
+		}
+		_, err := w.Write(appendJSONNumber(w.AvailableBuffer(), bf))
+		return err
+	case ty == cty.Bool:
+		if val.True() {
+			_, err := w.WriteString("true")
+			return err
+		}
+		_, err := w.WriteString("false")
+		return err
+	case ty.IsListType() || ty.IsSetType() || ty.IsTupleType():
+		w.WriteByte('[')
+		path := append(path, nil)
+		i := 0
+		for it := val.ElementIterator(); it.Next(); i++ {
+			key, elem := it.Element()
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			var ety cty.Type
+			if ty.IsTupleType() {
+				ety = ty.TupleElementType(i)
+			} else {
+				ety = ty.ElementType()
+			}
+			if err := encodeJSONStream(w, elem, ety, path); err != nil {
+				return fillPathStep(err, len(path)-1, cty.IndexStep{Key: key})
+			}
+		}
+		return w.WriteByte(']')
+	case ty.IsMapType() || ty.IsObjectType():
+		w.WriteByte('{')
+		var names []string
+		if ty.IsObjectType() {
+			for name := range ty.AttributeTypes() {
+				names = append(names, name)
+			}
+		} else {
+			for it := val.ElementIterator(); it.Next(); {
+				key, _ := it.Element()
+				names = append(names, key.AsString())
+			}
+		}
+		sort.Strings(names)
+		path := append(path, nil)
+		for i, name := range names {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			writeJSONString(w, name)
+			w.WriteByte(':')
+
+			var elem cty.Value
+			var ety cty.Type
+			var step cty.PathStep
+			if ty.IsObjectType() {
+				elem, ety = val.GetAttr(name), ty.AttributeType(name)
+				step = cty.GetAttrStep{Name: name}
+			} else {
+				elem, ety = val.Index(cty.StringVal(name)), ty.ElementType()
+				step = cty.IndexStep{Key: cty.StringVal(name)}
+			}
+			if err := encodeJSONStream(w, elem, ety, path); err != nil {
+				return fillPathStep(err, len(path)-1, step)
+			}
+		}
+		return w.WriteByte('}')
+	}
+
+	return path.NewErrorf("cannot serialize %s as JSON", ty.FriendlyName())
+}
+
+// encodeMsgpackStream writes val as ty in the same form as ctymsgpack.Marshal
+func encodeMsgpackStream(w streamWriter, enc *msgpack.Encoder, val cty.Value, ty cty.Type, path cty.Path) error {
+	if val.IsMarked() {
+		return path.NewErrorf("value has marks, so it cannot be serialized")
+	}
+	if ty == cty.DynamicPseudoType || !val.IsKnown() {
+		// Dynamic wrappers and unknowns are small; the encoder writes
+		// straight through to w, so the raw bytes land in order
+		data, err := ctymsgpack.Marshal(val, ty)
+		if err != nil {
+			return path.NewError(err)
+		}
+		_, err = w.Write(data)
+		return err
+	}
+	if val.IsNull() {
+		return enc.EncodeNil()
+	}
+
+	switch {
+	case ty == cty.String:
+		return enc.EncodeString(val.AsString())
+	case ty == cty.Number:
+		bf := val.AsBigFloat()
+		if bf.IsInf() {
+			data, err := ctymsgpack.Marshal(val, ty)
+			if err != nil {
+				return path.NewError(err)
+			}
+			_, err = w.Write(data)
+			return err
+		}
+		if iv, acc := bf.Int64(); acc == big.Exact {
+			return enc.EncodeInt(iv)
+		}
+		if fv, acc := bf.Float64(); acc == big.Exact {
+			return enc.EncodeFloat64(fv)
+		}
+		return enc.EncodeString(bf.Text('f', -1))
+	case ty == cty.Bool:
+		return enc.EncodeBool(val.True())
+	case ty.IsListType() || ty.IsSetType() || ty.IsTupleType():
+		if err := enc.EncodeArrayLen(val.LengthInt()); err != nil {
+			return err
+		}
+		path := append(path, nil)
+		i := 0
+		for it := val.ElementIterator(); it.Next(); i++ {
+			key, elem := it.Element()
+			var ety cty.Type
+			if ty.IsTupleType() {
+				ety = ty.TupleElementType(i)
+			} else {
+				ety = ty.ElementType()
+			}
+			if err := encodeMsgpackStream(w, enc, elem, ety, path); err != nil {
+				return fillPathStep(err, len(path)-1, cty.IndexStep{Key: key})
+			}
+		}
+		return nil
+	case ty.IsMapType():
+		if err := enc.EncodeMapLen(val.LengthInt()); err != nil {
+			return err
+		}
+		path := append(path, nil)
+		for it := val.ElementIterator(); it.Next(); {
+			key, elem := it.Element()
+			if err := enc.EncodeString(key.AsString()); err != nil {
+				return err
+			}
+			if err := encodeMsgpackStream(w, enc, elem, ty.ElementType(), path); err != nil {
+				return fillPathStep(err, len(path)-1, cty.IndexStep{Key: key})
+			}
+		}
+		return nil
+	case ty.IsObjectType():
+		names := make([]string, 0, len(ty.AttributeTypes()))
+		for name := range ty.AttributeTypes() {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if err := enc.EncodeMapLen(len(names)); err != nil {
+			return err
+		}
+		path := append(path, nil)
+		for _, name := range names {
+			if err := enc.EncodeString(name); err != nil {
+				return err
+			}
+			if err := encodeMsgpackStream(w, enc, val.GetAttr(name), ty.AttributeType(name), path); err != nil {
+				return fillPathStep(err, len(path)-1, cty.GetAttrStep{Name: name})
+			}
+		}
+		return nil
+	}
+
+	return path.NewErrorf("cannot msgpack-serialize %s", ty.FriendlyName())
+}
+
+// streamConvert decodes inputPath as ty in inFormat and writes it to
+// outputPath in outFormat without buffering either side in full
+func streamConvert(inputPath, outputPath string, ty cty.Type, inFormat, outFormat string, base64Out bool) error {
+	r, closeIn, err := openStreamInput(inputPath)
+	if err != nil {
+		return err
+	}
+	defer closeIn()
+
+	if inFormat == "msgpack" && inputPath == "-" {
+		r = maybeBase64Input(r)
+	}
+
+	value, err := decodeStreamValue(r, ty, inFormat)
+	if err != nil {
+		return fmt.Errorf("failed to decode: %w", err)
+	}
+
+	return writeStreamOutput(outputPath, base64Out, func(w *bufio.Writer) error {
+		if err := encodeStreamValue(w, value, ty, outFormat); err != nil {
+			return fmt.Errorf("failed to encode: %w", err)
+		}
+		return nil
+	})
+}
+
+// streamGenericEncode is the untyped wire encode path: JSON in, plain
+// msgpack out
+func streamGenericEncode(inputPath, outputPath string, base64Out bool) error {
+	r, closeIn, err := openStreamInput(inputPath)
+	if err != nil {
+		return err
+	}
+	defer closeIn()
+
+	var data interface{}
+	if err := json.NewDecoder(r).Decode(&data); err != nil {
+		return fmt.Errorf("failed to parse JSON: %w", err)
+	}
+
+	return writeStreamOutput(outputPath, base64Out, func(w *bufio.Writer) error {
+		if err := msgpack.NewEncoder(w).Encode(data); err != nil {
+			return fmt.Errorf("failed to encode msgpack: %w", err)
+		}
+		return nil
+	})
+}
+
+// streamGenericDecode is the untyped wire decode path: plain msgpack in,
+// indented JSON out
+func streamGenericDecode(inputPath, outputPath, inFormat string) error {
+	r, closeIn, err := openStreamInput(inputPath)
+	if err != nil {
+		return err
+	}
+	defer closeIn()
+
+	if inFormat == "msgpack" && inputPath == "-" {
+		r = maybeBase64Input(r)
+	}
+
+	var data interface{}
+	if err := msgpack.NewDecoder(r).Decode(&data); err != nil {
+		return fmt.Errorf("failed to decode msgpack: %w", err)
+	}
+
+	return writeStreamOutput(outputPath, false, func(w *bufio.Writer) error {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(data); err != nil {
+			return fmt.Errorf("failed to encode JSON: %w", err)
+		}
+		return nil
+	})
+}
+
+// writeStreamOutput opens outputPath, optionally base64-wrapping it, runs
+// write against it and flushes everything
+func writeStreamOutput(outputPath string, base64Out bool, write func(w *bufio.Writer) error) error {
+	out, closeOut, err := openStreamOutput(outputPath)
+	if err != nil {
+		return err
+	}
+
+	if !base64Out {
+		if err := write(out); err != nil {
+			closeOut()
+			return err
+		}
+	} else {
+		b64 := base64.NewEncoder(base64.StdEncoding, out)
+		w := bufio.NewWriterSize(b64, streamChunkSize)
+		if err := write(w); err != nil {
+			closeOut()
+			return err
+		}
+		if err := w.Flush(); err != nil {
+			closeOut()
+			return fmt.Errorf("failed to write output: %w", err)
+		}
+		b64.Close()
+	}
+
+	if err := closeOut(); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	return nil
+}
+
+// maybeBase64Input decodes r as base64 if its first chunk looks like base64
+// text, matching the non-streaming decode path's handling of encode output
+// piped from stdout.
+func maybeBase64Input(r *bufio.Reader) *bufio.Reader {
+	peek, _ := r.Peek(512)
+	if len(peek) == 0 {
+		return r
+	}
+	for _, c := range peek {
+		isB64 := (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') ||
+			c == '+' || c == '/' || c == '=' || c == '\n' || c == '\r'
+		if !isB64 {
+			return r
+		}
+	}
+	return bufio.NewReaderSize(base64.NewDecoder(base64.StdEncoding, newlineStripper{r}), streamChunkSize)
+}
+
+// newlineStripper drops line breaks so wrapped base64 decodes
+type newlineStripper struct {
+	r io.Reader
+}
+
+func (n newlineStripper) Read(p []byte) (int, error) {
+	for {
+		count, err := n.r.Read(p)
+		kept := 0
+		for _, c := range p[:count] {
+			if c != '\n' && c != '\r' {
+				p[kept] = c
+				kept++
+			}
+		}
+		if kept > 0 || err != nil {
+			return kept, err
+		}
+	}
+}
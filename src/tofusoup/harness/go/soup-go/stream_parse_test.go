@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestDecodeStreamValueUnknownAttribute(t *testing.T) {
+	flat := cty.Object(map[string]cty.Type{"a": cty.String, "b": cty.String})
+	nested := cty.Object(map[string]cty.Type{"o": flat})
+
+	tests := []struct {
+		name  string
+		ty    cty.Type
+		value map[string]interface{}
+		json  string
+		path  cty.Path
+	}{
+		{
+			name:  "top level",
+			ty:    flat,
+			value: map[string]interface{}{"a": "x", "zzz": 1},
+			json:  `{"a":"x","zzz":1}`,
+			path:  cty.GetAttrPath("zzz"),
+		},
+		{
+			name:  "nested",
+			ty:    nested,
+			value: map[string]interface{}{"o": map[string]interface{}{"a": "x", "zzz": 1}},
+			json:  `{"o":{"a":"x","zzz":1}}`,
+			path:  cty.Path{}.Index(cty.StringVal("o")).GetAttr("zzz"),
+		},
+	}
+
+	for _, tt := range tests {
+		packed, err := msgpack.Marshal(tt.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		inputs := map[string][]byte{"json": []byte(tt.json), "msgpack": packed}
+		for format, input := range inputs {
+			t.Run(tt.name+"/"+format, func(t *testing.T) {
+				_, err := decodeStreamValue(bufio.NewReader(bytes.NewReader(input)), tt.ty, format)
+				perr, ok := err.(cty.PathError)
+				if !ok {
+					t.Fatalf("got error %v, want a cty.PathError", err)
+				}
+				if !perr.Path.Equals(tt.path) {
+					t.Errorf("got path %#v, want %#v", perr.Path, tt.path)
+				}
+				if want := `unsupported attribute "zzz"`; perr.Error() != want {
+					t.Errorf("got %q, want %q", perr.Error(), want)
+				}
+			})
+		}
+	}
+}
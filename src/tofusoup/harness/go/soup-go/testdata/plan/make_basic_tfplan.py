@@ -0,0 +1,153 @@
+#!/usr/bin/env python3
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""Writes basic.tfplan, the plan archive wire_plan_test.go inspects.
+
+The protobuf and msgpack are encoded by hand from Terraform's
+planfile.proto (plan format version 3) and cty's msgpack encoding, so the
+fixture doesn't share any code with the decoder it tests.
+"""
+
+from pathlib import Path
+import zipfile
+
+
+def varint(n: int) -> bytes:
+    out = bytearray()
+    while True:
+        byte = n & 0x7F
+        n >>= 7
+        if n:
+            out.append(byte | 0x80)
+        else:
+            out.append(byte)
+            return bytes(out)
+
+
+def field_varint(num: int, value: int) -> bytes:
+    return varint(num << 3) + varint(value)
+
+
+def field_bytes(num: int, value: bytes | str) -> bytes:
+    if isinstance(value, str):
+        value = value.encode()
+    return varint(num << 3 | 2) + varint(len(value)) + value
+
+
+# msgpack, as much of it as cty writes here
+NIL = b"\xc0"
+UNKNOWN = b"\xc7\x00\x00"  # ext 0, no payload
+
+
+def mp_str(s: str) -> bytes:
+    data = s.encode()
+    assert len(data) < 32
+    return bytes([0xA0 | len(data)]) + data
+
+
+def mp_bin(data: bytes) -> bytes:
+    return b"\xc4" + bytes([len(data)]) + data
+
+
+def mp_int(n: int) -> bytes:
+    assert 0 <= n < 128
+    return bytes([n])
+
+
+def mp_map(items: list[tuple[str, bytes]]) -> bytes:
+    # cty writes object attributes in sorted order
+    return bytes([0x80 | len(items)]) + b"".join(mp_str(k) + v for k, v in sorted(items))
+
+
+def mp_array(items: list[bytes]) -> bytes:
+    return bytes([0x90 | len(items)]) + b"".join(items)
+
+
+def dynamic(type_json: str, value: bytes) -> bytes:
+    """A DynamicPseudoType value: [type JSON, value]"""
+    return mp_array([mp_bin(type_json.encode()), value])
+
+
+# planfile.proto field numbers
+PLAN_VERSION, PLAN_VARIABLES, PLAN_RESOURCE_CHANGES, PLAN_OUTPUT_CHANGES = 1, 2, 3, 4
+PLAN_TERRAFORM_VERSION, PLAN_UI_MODE, PLAN_ERRORED = 14, 17, 20
+RIC_DEPOSED_KEY, RIC_PROVIDER, RIC_CHANGE, RIC_ADDR, RIC_PREV_RUN_ADDR = 7, 8, 9, 13, 14
+CHANGE_ACTION, CHANGE_VALUES = 1, 2
+OUTPUT_NAME, OUTPUT_CHANGE, OUTPUT_SENSITIVE = 1, 2, 3
+DYNAMIC_VALUE_MSGPACK = 1
+CREATE, DELETE = 1, 5
+
+PROVIDER = 'provider["registry.terraform.io/hashicorp/example"]'
+
+
+def dynamic_value(packed: bytes) -> bytes:
+    return field_bytes(DYNAMIC_VALUE_MSGPACK, packed)
+
+
+def change(action: int, *values: bytes) -> bytes:
+    return field_varint(CHANGE_ACTION, action) + b"".join(
+        field_bytes(CHANGE_VALUES, dynamic_value(v)) for v in values
+    )
+
+
+web_after = mp_map(
+    [
+        ("id", UNKNOWN),
+        ("name", mp_str("web")),
+        ("listener", mp_array([mp_map([("port", b"\xcd\x01\xbb"), ("protocol", mp_str("https"))])])),
+        ("settings", NIL),
+        ("timeouts", NIL),
+    ]
+)
+old_before = mp_map(
+    [
+        ("id", mp_str("svc-0")),
+        ("name", mp_str("old")),
+        ("listener", NIL),
+        ("settings", mp_map([("labels", NIL), ("replicas", mp_int(2))])),
+        ("timeouts", NIL),
+    ]
+)
+
+plan = b"".join(
+    [
+        field_varint(PLAN_VERSION, 3),
+        field_bytes(
+            PLAN_VARIABLES,
+            field_bytes(1, "region") + field_bytes(2, dynamic_value(dynamic('"string"', mp_str("eu-west-1")))),
+        ),
+        field_bytes(
+            PLAN_RESOURCE_CHANGES,
+            field_bytes(RIC_ADDR, "example_service.web")
+            + field_bytes(RIC_PROVIDER, PROVIDER)
+            + field_bytes(RIC_CHANGE, change(CREATE, NIL, web_after)),
+        ),
+        field_bytes(
+            PLAN_RESOURCE_CHANGES,
+            field_bytes(RIC_ADDR, "example_service.old")
+            + field_bytes(RIC_PREV_RUN_ADDR, "example_service.legacy")
+            + field_bytes(RIC_DEPOSED_KEY, "00000001")
+            + field_bytes(RIC_PROVIDER, PROVIDER)
+            + field_bytes(RIC_CHANGE, change(DELETE, old_before, NIL)),
+        ),
+        field_bytes(
+            PLAN_OUTPUT_CHANGES,
+            field_bytes(OUTPUT_NAME, "endpoint")
+            + field_bytes(OUTPUT_CHANGE, change(CREATE, NIL, dynamic('"string"', mp_str("https://web"))))
+            + field_varint(OUTPUT_SENSITIVE, 1),
+        ),
+        field_bytes(PLAN_TERRAFORM_VERSION, "1.9.0"),
+        field_varint(PLAN_UI_MODE, 0),
+        field_varint(PLAN_ERRORED, 0),
+    ]
+)
+
+out = Path(__file__).with_name("basic.tfplan")
+with zipfile.ZipFile(out, "w") as archive:
+    for name, data in [("tfplan", plan), ("tfstate", b"{}"), ("tfstate-prev", b"{}")]:
+        archive.writestr(zipfile.ZipInfo(name, date_time=(2025, 1, 1, 0, 0, 0)), data)
+
+# 🥣🔬🔚
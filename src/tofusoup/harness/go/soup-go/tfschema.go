@@ -0,0 +1,190 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+// providerSchemasJSON mirrors the output of `terraform providers schema -json`
+type providerSchemasJSON struct {
+	FormatVersion   string                         `json:"format_version"`
+	ProviderSchemas map[string]*providerSchemaJSON `json:"provider_schemas"`
+}
+
+type providerSchemaJSON struct {
+	Provider          *schemaJSON            `json:"provider,omitempty"`
+	ResourceSchemas   map[string]*schemaJSON `json:"resource_schemas,omitempty"`
+	DataSourceSchemas map[string]*schemaJSON `json:"data_source_schemas,omitempty"`
+}
+
+type schemaJSON struct {
+	Version int64            `json:"version"`
+	Block   *schemaBlockJSON `json:"block"`
+}
+
+type schemaBlockJSON struct {
+	Attributes map[string]*schemaAttributeJSON `json:"attributes,omitempty"`
+	BlockTypes map[string]*schemaBlockTypeJSON `json:"block_types,omitempty"`
+}
+
+// schemaAttributeJSON is an attribute with either a type or, for protocol 6
+// nested attributes, a nested_type
+type schemaAttributeJSON struct {
+	Type        json.RawMessage       `json:"type,omitempty"`
+	NestedType  *schemaNestedTypeJSON `json:"nested_type,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Required    bool                  `json:"required,omitempty"`
+	Optional    bool                  `json:"optional,omitempty"`
+	Computed    bool                  `json:"computed,omitempty"`
+	Sensitive   bool                  `json:"sensitive,omitempty"`
+}
+
+// schemaNestedTypeJSON is the object type of a nested attribute and how its
+// objects are collected
+type schemaNestedTypeJSON struct {
+	Attributes  map[string]*schemaAttributeJSON `json:"attributes"`
+	NestingMode string                          `json:"nesting_mode"`
+	MinItems    int                             `json:"min_items,omitempty"`
+	MaxItems    int                             `json:"max_items,omitempty"`
+}
+
+type schemaBlockTypeJSON struct {
+	NestingMode string           `json:"nesting_mode"`
+	Block       *schemaBlockJSON `json:"block"`
+	MinItems    int              `json:"min_items,omitempty"`
+	MaxItems    int              `json:"max_items,omitempty"`
+}
+
+// loadProviderSchemas reads a provider schema document from disk
+func loadProviderSchemas(path string) (*providerSchemasJSON, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema file: %w", err)
+	}
+
+	var schemas providerSchemasJSON
+	if err := json.Unmarshal(data, &schemas); err != nil {
+		return nil, fmt.Errorf("failed to parse schema file: %w", err)
+	}
+	if len(schemas.ProviderSchemas) == 0 {
+		return nil, fmt.Errorf("schema file contains no provider_schemas")
+	}
+	return &schemas, nil
+}
+
+// lookupResourceSchema finds the schema for a resource type. Mode is "managed"
+// for resources and "data" for data sources, matching tfstate conventions.
+// If provider is empty, all providers are searched.
+func (s *providerSchemasJSON) lookupResourceSchema(provider, mode, typeName string) (*schemaJSON, error) {
+	// Sort provider names so lookups are deterministic when a type is ambiguous
+	names := make([]string, 0, len(s.ProviderSchemas))
+	for name := range s.ProviderSchemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if provider != "" && name != provider {
+			continue
+		}
+		ps := s.ProviderSchemas[name]
+		schemas := ps.ResourceSchemas
+		if mode == "data" {
+			schemas = ps.DataSourceSchemas
+		}
+		if schema, ok := schemas[typeName]; ok && schema.Block != nil {
+			return schema, nil
+		}
+	}
+	return nil, fmt.Errorf("no schema found for %s resource type %q", mode, typeName)
+}
+
+// impliedType returns the cty type that values conforming to the block have
+func (b *schemaBlockJSON) impliedType() (cty.Type, error) {
+	if b == nil {
+		return cty.EmptyObject, nil
+	}
+
+	attrTypes := make(map[string]cty.Type)
+	for name, attr := range b.Attributes {
+		ty, err := attr.impliedType()
+		if err != nil {
+			return cty.NilType, fmt.Errorf("attribute %q: %w", name, err)
+		}
+		attrTypes[name] = ty
+	}
+
+	for name, blockType := range b.BlockTypes {
+		ty, err := blockType.impliedType()
+		if err != nil {
+			return cty.NilType, fmt.Errorf("block %q: %w", name, err)
+		}
+		attrTypes[name] = ty
+	}
+
+	return cty.Object(attrTypes), nil
+}
+
+// impliedType returns the cty type of a nested block collection
+func (bt *schemaBlockTypeJSON) impliedType() (cty.Type, error) {
+	elemType, err := bt.Block.impliedType()
+	if err != nil {
+		return cty.NilType, err
+	}
+
+	switch bt.NestingMode {
+	case "single", "group":
+		return elemType, nil
+	case "list":
+		return cty.List(elemType), nil
+	case "set":
+		return cty.Set(elemType), nil
+	case "map":
+		return cty.Map(elemType), nil
+	default:
+		return cty.NilType, fmt.Errorf("unsupported nesting mode %q", bt.NestingMode)
+	}
+}
+
+// impliedType returns the attribute's type, derived from nested_type when
+// it has one
+func (a *schemaAttributeJSON) impliedType() (cty.Type, error) {
+	if a.NestedType != nil {
+		return a.NestedType.impliedType()
+	}
+	if len(a.Type) == 0 {
+		return cty.NilType, fmt.Errorf("attribute has neither type nor nested_type")
+	}
+	return parseCtyType(a.Type)
+}
+
+// impliedType returns the cty type of a nested attribute. As in Terraform's
+// state, optional nested attributes are plain object attributes.
+func (n *schemaNestedTypeJSON) impliedType() (cty.Type, error) {
+	attrTypes := make(map[string]cty.Type)
+	for name, attr := range n.Attributes {
+		ty, err := attr.impliedType()
+		if err != nil {
+			return cty.NilType, fmt.Errorf("attribute %q: %w", name, err)
+		}
+		attrTypes[name] = ty
+	}
+	elemType := cty.Object(attrTypes)
+
+	switch n.NestingMode {
+	case "single":
+		return elemType, nil
+	case "list":
+		return cty.List(elemType), nil
+	case "set":
+		return cty.Set(elemType), nil
+	case "map":
+		return cty.Map(elemType), nil
+	default:
+		return cty.NilType, fmt.Errorf("unsupported nesting mode %q", n.NestingMode)
+	}
+}
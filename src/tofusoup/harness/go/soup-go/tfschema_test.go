@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestNestedAttributeImpliedType(t *testing.T) {
+	schemas, err := loadProviderSchemas("testdata/tfschema/nested_schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema, err := schemas.lookupResourceSchema("", "managed", "example_service")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := schema.Block.impliedType()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := cty.Object(map[string]cty.Type{
+		"id":   cty.String,
+		"name": cty.String,
+		"listener": cty.List(cty.Object(map[string]cty.Type{
+			"port":     cty.Number,
+			"protocol": cty.String,
+		})),
+		"settings": cty.Object(map[string]cty.Type{
+			"replicas": cty.Number,
+			"labels":   cty.Map(cty.Object(map[string]cty.Type{"value": cty.String})),
+		}),
+		"timeouts": cty.Object(map[string]cty.Type{"create": cty.String}),
+	})
+	if !got.Equals(want) {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+}
+
+func TestNestedAttributeWithoutType(t *testing.T) {
+	attr := &schemaAttributeJSON{Optional: true}
+	if _, err := attr.impliedType(); err == nil {
+		t.Error("an attribute with neither type nor nested_type has no type")
+	}
+	attr.NestedType = &schemaNestedTypeJSON{NestingMode: "group"}
+	if _, err := attr.impliedType(); err == nil {
+		t.Error("nested attributes can't use the group nesting mode")
+	}
+}
+
+func TestWireStateRoundTripNestedAttributes(t *testing.T) {
+	const schemaPath = "testdata/tfschema/nested_schema.json"
+	const statePath = "testdata/tfschema/nested_state.json"
+	dir := t.TempDir()
+	typed := filepath.Join(dir, "typed.json")
+	back := filepath.Join(dir, "back.json")
+
+	for _, run := range []struct {
+		name string
+		cmd  func() error
+	}{
+		{name: "decode", cmd: func() error {
+			cmd := initWireStateDecodeCmd()
+			cmd.SetArgs([]string{statePath, typed, "--schema", schemaPath})
+			return cmd.Execute()
+		}},
+		{name: "encode", cmd: func() error {
+			cmd := initWireStateEncodeCmd()
+			cmd.SetArgs([]string{typed, back, "--schema", schemaPath})
+			return cmd.Execute()
+		}},
+	} {
+		if err := run.cmd(); err != nil {
+			t.Fatalf("wire state %s: %v", run.name, err)
+		}
+	}
+
+	attributes := func(path string) interface{} {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var state struct {
+			Resources []struct {
+				Instances []struct {
+					Attributes interface{} `json:"attributes"`
+				} `json:"instances"`
+			} `json:"resources"`
+		}
+		if err := json.Unmarshal(data, &state); err != nil {
+			t.Fatal(err)
+		}
+		return state.Resources[0].Instances[0].Attributes
+	}
+	if got, want := attributes(back), attributes(statePath); !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip changed the attributes:\ngot  %v\nwant %v", got, want)
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// pipeScheme prefixes a named pipe address given as --pipe or --address
+const pipeScheme = "npipe://"
+
+// pipeAddr is a Windows named pipe address. It implements net.Addr so it can
+// be carried in a go-plugin ReattachConfig.
+type pipeAddr struct {
+	path string
+}
+
+func (a pipeAddr) Network() string { return "npipe" }
+func (a pipeAddr) String() string  { return a.path }
+
+// isPipeAddress reports whether address names a Windows named pipe
+func isPipeAddress(address string) bool {
+	return strings.HasPrefix(address, pipeScheme) || strings.HasPrefix(address, `\\.\pipe\`)
+}
+
+// parsePipeAddress normalizes npipe://name, npipe://\\.\pipe\name and
+// \\.\pipe\name to a full pipe path.
+func parsePipeAddress(address string) (pipeAddr, error) {
+	name := strings.TrimPrefix(address, pipeScheme)
+	if !strings.HasPrefix(name, `\\.\pipe\`) {
+		name = `\\.\pipe\` + name
+	}
+	if strings.TrimPrefix(name, `\\.\pipe\`) == "" {
+		return pipeAddr{}, fmt.Errorf("named pipe address %q has no pipe name", address)
+	}
+	return pipeAddr{path: name}, nil
+}
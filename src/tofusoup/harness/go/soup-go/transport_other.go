@@ -0,0 +1,18 @@
+//go:build !windows
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"runtime"
+)
+
+func listenPipe(addr pipeAddr) (net.Listener, error) {
+	return nil, fmt.Errorf("named pipes are only supported on windows, not %s", runtime.GOOS)
+}
+
+func dialPipe(ctx context.Context, addr pipeAddr) (net.Conn, error) {
+	return nil, fmt.Errorf("named pipes are only supported on windows, not %s", runtime.GOOS)
+}
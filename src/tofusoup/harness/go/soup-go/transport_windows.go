@@ -0,0 +1,23 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"net"
+
+	"github.com/Microsoft/go-winio"
+)
+
+// listenPipe serves on a named pipe, restricted to the current user
+func listenPipe(addr pipeAddr) (net.Listener, error) {
+	return winio.ListenPipe(addr.path, &winio.PipeConfig{
+		// Full access for the owner and SYSTEM only
+		SecurityDescriptor: "D:P(A;;GA;;;OW)(A;;GA;;;SY)",
+	})
+}
+
+// dialPipe connects to a named pipe
+func dialPipe(ctx context.Context, addr pipeAddr) (net.Conn, error) {
+	return winio.DialPipeContext(ctx, addr.path)
+}
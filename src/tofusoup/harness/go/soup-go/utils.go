@@ -47,9 +47,14 @@ func GetCacheDir() string {
 
 // GetKVStorageDir returns the directory for KV storage.
 // Priority (highest to lowest):
-// 1. KV_STORAGE_DIR environment variable (explicit override, for backward compatibility)
-// 2. Subdirectory within cache directory
+// 1. --storage-dir flag
+// 2. KV_STORAGE_DIR environment variable (explicit override, for backward compatibility)
+// 3. Subdirectory within cache directory
 func GetKVStorageDir() string {
+	if storageDirFlag != "" {
+		return filepath.Clean(storageDirFlag)
+	}
+
 	// Check explicit override first (backward compatibility)
 	if storageDir := os.Getenv(EnvKVStorageDir); storageDir != "" {
 		return storageDir
@@ -0,0 +1,459 @@
+package main
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/spf13/cobra"
+)
+
+// Signed golden vectors
+//
+// Generators given --sign key.pem record every file they emit in a
+// MANIFEST.json in the output directory (path, size and SHA-256 digest) and
+// write a detached signature of the manifest to MANIFEST.json.sig. Repeated
+// runs into the same directory merge into the existing manifest and re-sign
+// it. 'harness verify-vectors' checks the signature and every digest, and
+// reports files that were edited, deleted or added since signing.
+const (
+	vectorManifestName  = "MANIFEST.json"
+	vectorSignatureName = "MANIFEST.json.sig"
+	vectorManifestVer   = 1
+)
+
+// vectorManifest lists the signed files in a vector directory
+type vectorManifest struct {
+	Version int                   `json:"version"`
+	Files   []vectorManifestEntry `json:"files"`
+}
+
+// vectorManifestEntry is one signed file, by slash-separated path relative
+// to the manifest
+type vectorManifestEntry struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// vectorSignature is the detached signature over MANIFEST.json's bytes
+type vectorSignature struct {
+	Algorithm string `json:"algorithm"`
+	KeyID     string `json:"key_id"`
+	PublicKey string `json:"public_key"`
+	Signature string `json:"signature"`
+}
+
+// vectorVerifyReport is the result of verify-vectors
+type vectorVerifyReport struct {
+	Dir      string   `json:"dir"`
+	Valid    bool     `json:"valid"`
+	Signed   bool     `json:"signed"`
+	Pinned   bool     `json:"pinned"`
+	KeyID    string   `json:"key_id,omitempty"`
+	Files    int      `json:"files"`
+	Modified []string `json:"modified"`
+	Missing  []string `json:"missing"`
+	Unlisted []string `json:"unlisted"`
+	Problems []string `json:"problems"`
+}
+
+// loadSigningKey reads a PEM private key (PKCS#8, PKCS#1 RSA or SEC 1 EC)
+func loadSigningKey(path string) (crypto.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("signing key %s is not PEM", path)
+	}
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("unsupported signing key type %T", key)
+		}
+		return signer, nil
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("signing key %s is not a PKCS#8, PKCS#1 or EC private key", path)
+}
+
+// loadVerifyKey reads a PEM public key or certificate
+func loadVerifyKey(path string) (crypto.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read public key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("public key %s is not PEM", path)
+	}
+	if block.Type == "CERTIFICATE" {
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse certificate: %w", err)
+		}
+		return cert.PublicKey, nil
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key: %w", err)
+	}
+	return key, nil
+}
+
+// vectorKeyID is the hex SHA-256 of a public key's PKIX encoding
+func vectorKeyID(pub crypto.PublicKey) (string, []byte, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to encode public key: %w", err)
+	}
+	return sha256Hex(der), der, nil
+}
+
+// signVectorManifest signs data with signer
+func signVectorManifest(data []byte, signer crypto.Signer) (*vectorSignature, error) {
+	keyID, der, err := vectorKeyID(signer.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	var algorithm string
+	var sig []byte
+	switch signer.Public().(type) {
+	case ed25519.PublicKey:
+		algorithm = "ed25519"
+		sig, err = signer.Sign(rand.Reader, data, crypto.Hash(0))
+	case *ecdsa.PublicKey:
+		algorithm = "ecdsa-sha256"
+		digest := sha256.Sum256(data)
+		sig, err = signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	case *rsa.PublicKey:
+		algorithm = "rsa-pss-sha256"
+		digest := sha256.Sum256(data)
+		sig, err = signer.Sign(rand.Reader, digest[:], &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: crypto.SHA256})
+	default:
+		return nil, fmt.Errorf("unsupported signing key type %T", signer.Public())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign manifest: %w", err)
+	}
+
+	return &vectorSignature{
+		Algorithm: algorithm,
+		KeyID:     keyID,
+		PublicKey: string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})),
+		Signature: base64.StdEncoding.EncodeToString(sig),
+	}, nil
+}
+
+// checkVectorSignature verifies sig over data with pub
+func checkVectorSignature(data []byte, sig *vectorSignature, pub crypto.PublicKey) error {
+	raw, err := base64.StdEncoding.DecodeString(sig.Signature)
+	if err != nil {
+		return fmt.Errorf("signature is not base64: %w", err)
+	}
+	digest := sha256.Sum256(data)
+	switch key := pub.(type) {
+	case ed25519.PublicKey:
+		if sig.Algorithm != "ed25519" || !ed25519.Verify(key, data, raw) {
+			return errors.New("signature does not match")
+		}
+	case *ecdsa.PublicKey:
+		if sig.Algorithm != "ecdsa-sha256" || !ecdsa.VerifyASN1(key, digest[:], raw) {
+			return errors.New("signature does not match")
+		}
+	case *rsa.PublicKey:
+		if sig.Algorithm != "rsa-pss-sha256" {
+			return errors.New("signature does not match")
+		}
+		if err := rsa.VerifyPSS(key, crypto.SHA256, digest[:], raw, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}); err != nil {
+			return errors.New("signature does not match")
+		}
+	default:
+		return fmt.Errorf("unsupported public key type %T", pub)
+	}
+	return nil
+}
+
+// digestVectorFile returns a file's size and hex SHA-256
+func digestVectorFile(path string) (int64, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, "", err
+	}
+	defer f.Close()
+	h := sha256Digest.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return 0, "", err
+	}
+	return n, hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// readVectorManifest reads dir's manifest, returning an empty one if there
+// isn't one yet
+func readVectorManifest(dir string) (*vectorManifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, vectorManifestName))
+	if errors.Is(err, fs.ErrNotExist) {
+		return &vectorManifest{Version: vectorManifestVer}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	var manifest vectorManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	return &manifest, nil
+}
+
+// signVectorFiles adds files (in dir) to dir's manifest, replacing entries
+// for the same path, then rewrites and re-signs the manifest
+func signVectorFiles(dir string, files []string, keyPath string) error {
+	signer, err := loadSigningKey(keyPath)
+	if err != nil {
+		return err
+	}
+	manifest, err := readVectorManifest(dir)
+	if err != nil {
+		return err
+	}
+
+	entries := map[string]vectorManifestEntry{}
+	for _, e := range manifest.Files {
+		entries[e.Path] = e
+	}
+	for _, file := range files {
+		rel, err := filepath.Rel(dir, file)
+		if err != nil || rel == ".." || filepath.IsAbs(rel) || (len(rel) > 3 && rel[:3] == ".."+string(filepath.Separator)) {
+			return fmt.Errorf("%s is outside the vector directory %s", file, dir)
+		}
+		size, sum, err := digestVectorFile(file)
+		if err != nil {
+			return fmt.Errorf("failed to digest %s: %w", file, err)
+		}
+		entries[filepath.ToSlash(rel)] = vectorManifestEntry{Path: filepath.ToSlash(rel), Size: size, SHA256: sum}
+	}
+
+	manifest.Version = vectorManifestVer
+	manifest.Files = manifest.Files[:0]
+	for _, e := range entries {
+		manifest.Files = append(manifest.Files, e)
+	}
+	sort.Slice(manifest.Files, func(i, j int) bool { return manifest.Files[i].Path < manifest.Files[j].Path })
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	data = append(data, '\n')
+	sig, err := signVectorManifest(data, signer)
+	if err != nil {
+		return err
+	}
+	sigData, err := json.MarshalIndent(sig, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode signature: %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, vectorManifestName), data, 0644); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, vectorSignatureName), append(sigData, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write signature: %w", err)
+	}
+	logger.Info("🔏 signed vector manifest", "dir", dir, "files", len(manifest.Files), "key_id", sig.KeyID)
+	return nil
+}
+
+// verifyVectorDir checks dir's manifest signature and every file against it.
+// With pinned set, the signature must be by that key; otherwise the key
+// embedded in the signature is used, which proves integrity but not origin.
+func verifyVectorDir(dir string, pinned crypto.PublicKey) (*vectorVerifyReport, error) {
+	report := &vectorVerifyReport{
+		Dir:      dir,
+		Pinned:   pinned != nil,
+		Modified: []string{},
+		Missing:  []string{},
+		Unlisted: []string{},
+		Problems: []string{},
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, vectorManifestName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	var manifest vectorManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	if manifest.Version != vectorManifestVer {
+		report.Problems = append(report.Problems, fmt.Sprintf("unsupported manifest version %d", manifest.Version))
+	}
+	report.Files = len(manifest.Files)
+
+	// Signature
+	sigData, err := os.ReadFile(filepath.Join(dir, vectorSignatureName))
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		report.Problems = append(report.Problems, "manifest is not signed")
+	case err != nil:
+		return nil, fmt.Errorf("failed to read signature: %w", err)
+	default:
+		var sig vectorSignature
+		if err := json.Unmarshal(sigData, &sig); err != nil {
+			report.Problems = append(report.Problems, fmt.Sprintf("failed to parse signature: %v", err))
+			break
+		}
+		report.KeyID = sig.KeyID
+		pub := pinned
+		if pub == nil {
+			block, _ := pem.Decode([]byte(sig.PublicKey))
+			if block == nil {
+				report.Problems = append(report.Problems, "signature has no public key")
+				break
+			}
+			if pub, err = x509.ParsePKIXPublicKey(block.Bytes); err != nil {
+				report.Problems = append(report.Problems, fmt.Sprintf("failed to parse embedded public key: %v", err))
+				break
+			}
+		}
+		if err := checkVectorSignature(data, &sig, pub); err != nil {
+			report.Problems = append(report.Problems, err.Error())
+			break
+		}
+		report.Signed = true
+	}
+
+	// Files
+	listed := map[string]bool{}
+	for _, e := range manifest.Files {
+		listed[e.Path] = true
+		size, sum, err := digestVectorFile(filepath.Join(dir, filepath.FromSlash(e.Path)))
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			report.Missing = append(report.Missing, e.Path)
+		case err != nil:
+			return nil, fmt.Errorf("failed to digest %s: %w", e.Path, err)
+		case size != e.Size || sum != e.SHA256:
+			report.Modified = append(report.Modified, e.Path)
+		}
+	}
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel != vectorManifestName && rel != vectorSignatureName && !listed[rel] {
+			report.Unlisted = append(report.Unlisted, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", dir, err)
+	}
+
+	report.Valid = report.Signed && len(report.Problems) == 0 &&
+		len(report.Modified) == 0 && len(report.Missing) == 0 && len(report.Unlisted) == 0
+	return report, nil
+}
+
+func initHarnessVerifyVectorsCmd() *cobra.Command {
+	var keyPath string
+	var outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   "verify-vectors [dir]",
+		Short: "Check a signed golden vector directory before a run",
+		Long: `Verify the MANIFEST.json signature in a vector directory written by a
+generator with --sign, and check every listed file's size and SHA-256.
+Fails if the signature is missing or wrong, or if any file was modified,
+deleted or added since signing. Pass --key (a PEM public key or
+certificate) to require a particular signer; without it the key embedded
+in the signature is used, which detects edits but not re-signing.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var pinned crypto.PublicKey
+			if keyPath != "" {
+				var err error
+				if pinned, err = loadVerifyKey(keyPath); err != nil {
+					return err
+				}
+			}
+
+			report, err := verifyVectorDir(args[0], pinned)
+			if err != nil {
+				return err
+			}
+
+			if outputFormat == "json" {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				if err := encoder.Encode(report); err != nil {
+					return fmt.Errorf("failed to encode JSON: %w", err)
+				}
+			} else {
+				var b bytes.Buffer
+				fmt.Fprintf(&b, "%s: %d files", report.Dir, report.Files)
+				if report.Signed {
+					fmt.Fprintf(&b, ", signed by %s", report.KeyID)
+					if !report.Pinned {
+						b.WriteString(" (signer not pinned; pass --key)")
+					}
+				}
+				fmt.Println(b.String())
+				for _, p := range report.Problems {
+					fmt.Printf("  ❌ %s\n", p)
+				}
+				for _, f := range report.Modified {
+					fmt.Printf("  ✏️  modified: %s\n", f)
+				}
+				for _, f := range report.Missing {
+					fmt.Printf("  🗑️  missing:  %s\n", f)
+				}
+				for _, f := range report.Unlisted {
+					fmt.Printf("  ➕ unlisted: %s\n", f)
+				}
+				if report.Valid {
+					fmt.Println("  ✅ all vectors verified")
+				}
+			}
+
+			if !report.Valid {
+				return fmt.Errorf("vector verification failed for %s", report.Dir)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&keyPath, "key", "", "PEM public key or certificate the manifest must be signed by")
+	cmd.Flags().StringVar(&outputFormat, "output-format", "text", "Output format (text, json)")
+	return cmd
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTestKeys writes signer's private key as PKCS#8 PEM and its public
+// key as PKIX PEM, returning both paths
+func writeTestKeys(t *testing.T, signer crypto.Signer) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	der, err := x509.MarshalPKCS8PrivateKey(signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(signer.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPath, pubPath := filepath.Join(dir, "key.pem"), filepath.Join(dir, "key.pub.pem")
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(pubPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return keyPath, pubPath
+}
+
+func runVerifyVectors(t *testing.T, dir string, args ...string) (*vectorVerifyReport, error) {
+	t.Helper()
+	cmd := initHarnessVerifyVectorsCmd()
+	cmd.SetArgs(append([]string{dir, "--output-format", "json"}, args...))
+	cmd.SilenceUsage, cmd.SilenceErrors = true, true
+	out, err := captureStdout(t, cmd.Execute)
+	var report vectorVerifyReport
+	if jerr := json.Unmarshal(out, &report); jerr != nil {
+		t.Fatalf("%v (command error %v): %s", jerr, err, out)
+	}
+	return &report, err
+}
+
+// writeSignedVectors generates two schema-values vectors into a directory,
+// signing each into the same manifest
+func writeSignedVectors(t *testing.T, keyPath string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, nesting := range []string{"list", "map"} {
+		cmd := initGenerateSchemaValuesCmd()
+		cmd.SetArgs([]string{"--nesting", nesting, "--output", filepath.Join(dir, nesting+".json"), "--sign", keyPath})
+		if err := cmd.Execute(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestVerifyVectorsSignedDirectory(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, signer := range map[string]crypto.Signer{"ed25519": edKey, "ecdsa": ecKey} {
+		t.Run(name, func(t *testing.T) {
+			keyPath, pubPath := writeTestKeys(t, signer)
+			dir := writeSignedVectors(t, keyPath)
+
+			report, err := runVerifyVectors(t, dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !report.Valid || !report.Signed || report.Pinned || report.Files != 2 {
+				t.Errorf("got %+v, want 2 files verified by the embedded key", report)
+			}
+
+			report, err = runVerifyVectors(t, dir, "--key", pubPath)
+			if err != nil || !report.Pinned || !report.Valid {
+				t.Errorf("got %+v (%v), want the pinned signer accepted", report, err)
+			}
+		})
+	}
+}
+
+func TestVerifyVectorsDetectsChanges(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPath, _ := writeTestKeys(t, key)
+	dir := writeSignedVectors(t, keyPath)
+
+	if err := os.WriteFile(filepath.Join(dir, "list.json"), []byte("{}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(dir, "map.json")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "extra.json"), []byte("{}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := runVerifyVectors(t, dir)
+	if err == nil || report.Valid {
+		t.Fatal("verify-vectors accepted a changed directory")
+	}
+	if !report.Signed {
+		t.Errorf("got problems %v, want the manifest signature itself to still verify", report.Problems)
+	}
+	got := strings.Join(report.Modified, ",") + "|" + strings.Join(report.Missing, ",") + "|" + strings.Join(report.Unlisted, ",")
+	if got != "list.json|map.json|extra.json" {
+		t.Errorf("got modified|missing|unlisted %s", got)
+	}
+}
+
+func TestVerifyVectorsRejectsBadSignatures(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, other, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPath, _ := writeTestKeys(t, key)
+	_, otherPub := writeTestKeys(t, other)
+
+	// Signed by a key other than the pinned one
+	dir := writeSignedVectors(t, keyPath)
+	if report, err := runVerifyVectors(t, dir, "--key", otherPub); err == nil || report.Signed {
+		t.Errorf("got %+v, want the signature rejected for another key", report)
+	}
+
+	// Any edit to the manifest breaks its signature
+	manifestPath := filepath.Join(dir, vectorManifestName)
+	manifest, err := os.ReadFile(manifestPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(manifestPath, append(manifest, ' '), 0644); err != nil {
+		t.Fatal(err)
+	}
+	report, err := runVerifyVectors(t, dir)
+	if err == nil || report.Signed || strings.Join(report.Problems, ",") != "signature does not match" {
+		t.Errorf("got %+v, want an edited manifest's signature rejected", report)
+	}
+
+	// No signature at all
+	if err := os.Remove(filepath.Join(dir, vectorSignatureName)); err != nil {
+		t.Fatal(err)
+	}
+	report, err = runVerifyVectors(t, dir)
+	if err == nil || strings.Join(report.Problems, ",") != "manifest is not signed" {
+		t.Errorf("got %+v, want an unsigned manifest rejected", report)
+	}
+}
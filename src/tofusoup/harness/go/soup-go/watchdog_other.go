@@ -0,0 +1,20 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// requestStackDump sends SIGUSR1, which the soup-go and Python servers
+// answer with a diagnostic dump of their stacks and keep running
+func requestStackDump(process *os.Process) error {
+	return process.Signal(syscall.SIGUSR1)
+}
+
+// processExited reports whether the server is gone. Signal fails once
+// go-plugin has reaped it.
+func processExited(process *os.Process) bool {
+	return process.Signal(syscall.Signal(0)) != nil
+}
@@ -0,0 +1,20 @@
+//go:build windows
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// requestStackDump fails on windows, which has no SIGUSR1; the watchdog
+// kills the server without its stacks
+func requestStackDump(process *os.Process) error {
+	return errors.New("stack dumps need SIGUSR1, which windows lacks")
+}
+
+// processExited can't probe a process on windows without waiting on it, so
+// the server is taken to be running and waits run their full grace period
+func processExited(process *os.Process) bool {
+	return false
+}
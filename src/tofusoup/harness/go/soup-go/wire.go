@@ -1,17 +1,11 @@
 package main
 
 import (
-	"encoding/base64"
 	"encoding/json"
 	"fmt"
-	"io"
-	"os"
 
 	"github.com/spf13/cobra"
-	"github.com/vmihailenco/msgpack/v5"
 	"github.com/zclconf/go-cty/cty"
-	ctyjson "github.com/zclconf/go-cty/cty/json"
-	ctymsgpack "github.com/zclconf/go-cty/cty/msgpack"
 )
 
 // Override the encode command with real implementation
@@ -21,12 +15,24 @@ func initWireEncodeCmd() *cobra.Command {
 		wireInputFormat  string
 		wireOutputFormat string
 		wireTypeJSON     string
+		emitTypeFile     string
+		stream           streamOptions
+		version          wireVersionOptions
 	)
 
 	cmd := &cobra.Command{
 		Use:   "encode [input] [output]",
 		Short: "Encode data to wire format",
-		Args:  cobra.RangeArgs(1, 2),
+		Long: `Encode a JSON value to msgpack, or to cty JSON with --output-format json.
+
+With --type the value is encoded as that cty type, as a DynamicValue is.
+--emit-type-file also writes the type to a separate JSON file, the way a
+DynamicValue's schema travels out-of-band. The type file records the SHA-256
+of the type and of the payload, and 'wire decode --type-file' checks both
+before decoding.`,
+		Example: `  soup-go wire encode value.json value.msgpack --type "$TYPE" --emit-type-file value.type.json
+  soup-go wire decode value.msgpack --type-file value.type.json`,
+		Args: cobra.RangeArgs(1, 2),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			inputPath := args[0]
 			outputPath := "-"
@@ -34,72 +40,68 @@ func initWireEncodeCmd() *cobra.Command {
 				outputPath = args[1]
 			}
 
-			// Read input
-			var inputData []byte
-			var err error
-			if inputPath == "-" {
-				inputData, err = io.ReadAll(os.Stdin)
-			} else {
-				inputData, err = os.ReadFile(inputPath)
-			}
+			framed, err := version.checkEncode(wireOutputFormat, stream.Enabled)
 			if err != nil {
-				return fmt.Errorf("failed to read input: %w", err)
+				return err
+			}
+			if emitTypeFile != "" && (wireTypeJSON == "" || stream.Enabled) {
+				return fmt.Errorf("--emit-type-file needs --type and can't be combined with --stream-parse")
+			}
+			if framed {
+				if err := requireFeature("wire-v2", "--wire-version 2"); err != nil {
+					return err
+				}
 			}
 
-			var outputData []byte
-
-			// If a type is specified, use CTY encoding
-			if wireTypeJSON != "" {
+			if stream.Enabled {
+				if err := stream.apply(); err != nil {
+					return err
+				}
+				base64Out := outputPath == "-" && wireOutputFormat == "msgpack"
+				if wireTypeJSON == "" {
+					return streamGenericEncode(inputPath, outputPath, base64Out)
+				}
 				ctyType, err := parseCtyType(json.RawMessage(wireTypeJSON))
 				if err != nil {
 					return fmt.Errorf("failed to parse type: %w", err)
 				}
+				return streamConvert(inputPath, outputPath, ctyType, "json", wireOutputFormat, base64Out)
+			}
 
-				// Parse input as JSON and build CTY value
-				value, err := buildCtyValueFromJSON(ctyType, inputData)
-				if err != nil {
-					return fmt.Errorf("failed to build value: %w", err)
+			ctyType := cty.NilType
+			if wireTypeJSON != "" {
+				var err error
+				if ctyType, err = parseCtyType(json.RawMessage(wireTypeJSON)); err != nil {
+					return fmt.Errorf("failed to parse type: %w", err)
 				}
+			}
 
-				// Encode to wire format
-				switch wireOutputFormat {
-				case "msgpack":
-					outputData, err = ctymsgpack.Marshal(value, ctyType)
-				case "json":
-					outputData, err = ctyjson.Marshal(value, ctyType)
-				default:
-					return fmt.Errorf("unsupported output format: %s", wireOutputFormat)
-				}
-				if err != nil {
-					return fmt.Errorf("failed to encode: %w", err)
-				}
-			} else {
-				// Generic msgpack encoding without CTY type
-				var data interface{}
-				if err := json.Unmarshal(inputData, &data); err != nil {
-					return fmt.Errorf("failed to parse JSON: %w", err)
-				}
+			pool := wireBuffers()
+			in, out := pool.get(), pool.get()
+			defer pool.put(in)
+			defer pool.put(out)
 
-				outputData, err = msgpack.Marshal(data)
-				if err != nil {
-					return fmt.Errorf("failed to encode msgpack: %w", err)
-				}
+			if err := readWireInput(inputPath, in); err != nil {
+				return err
+			}
+			if framed {
+				out.Write(appendWireHeader(nil, ctyType != cty.NilType))
+			}
+			if err := wireEncodeTo(out, in.Bytes(), ctyType, wireOutputFormat); err != nil {
+				return err
 			}
 
-			// Write output
-			if outputPath == "-" {
-				// For stdout with msgpack output, encode as base64 for safe text transmission
-				if wireOutputFormat == "msgpack" {
-					encoded := base64.StdEncoding.EncodeToString(outputData)
-					_, err = os.Stdout.WriteString(encoded)
-				} else {
-					_, err = os.Stdout.Write(outputData)
-				}
-			} else {
-				err = os.WriteFile(outputPath, outputData, 0644)
+			// For stdout with msgpack output, encode as base64 for safe text transmission
+			base64Out := outputPath == "-" && wireOutputFormat == "msgpack"
+			if err := writeWireOutput(outputPath, out.Bytes(), base64Out); err != nil {
+				return err
 			}
-			if err != nil {
-				return fmt.Errorf("failed to write output: %w", err)
+			if emitTypeFile != "" {
+				wireVersion := wireVersion1
+				if framed {
+					wireVersion = wireVersion2
+				}
+				return writeWireTypeFile(emitTypeFile, ctyType, out.Bytes(), outputPath, wireOutputFormat, wireVersion)
 			}
 
 			return nil
@@ -110,6 +112,9 @@ func initWireEncodeCmd() *cobra.Command {
 	cmd.Flags().StringVar(&wireInputFormat, "input-format", "json", "Input format (json)")
 	cmd.Flags().StringVar(&wireOutputFormat, "output-format", "msgpack", "Output format (msgpack, json)")
 	cmd.Flags().StringVar(&wireTypeJSON, "type", "", "Type specification as JSON (optional)")
+	cmd.Flags().StringVar(&emitTypeFile, "emit-type-file", "", "Also write the --type to this JSON file, with digests of the type and the payload")
+	addStreamFlags(cmd, &stream)
+	addWireEncodeVersionFlags(cmd, &version)
 	
 	return cmd
 }
@@ -121,6 +126,9 @@ func initWireDecodeCmd() *cobra.Command {
 		wireInputFormat  string
 		wireOutputFormat string
 		wireTypeJSON     string
+		typeFilePath     string
+		stream           streamOptions
+		version          wireVersionOptions
 	)
 
 	cmd := &cobra.Command{
@@ -134,83 +142,74 @@ func initWireDecodeCmd() *cobra.Command {
 				outputPath = args[1]
 			}
 
-			// Read input
-			var inputData []byte
-			var err error
-			if inputPath == "-" {
-				inputData, err = io.ReadAll(os.Stdin)
-			} else {
-				inputData, err = os.ReadFile(inputPath)
+			if err := version.checkDecode(stream.Enabled); err != nil {
+				return err
 			}
-			if err != nil {
-				return fmt.Errorf("failed to read input: %w", err)
+			if typeFilePath != "" && (wireTypeJSON != "" || stream.Enabled) {
+				return fmt.Errorf("--type-file can't be combined with --type or --stream-parse")
 			}
 
-			// If input looks like base64 (no binary bytes), try to decode it
-			// This handles the case where encode outputs base64 to stdout
-			if wireInputFormat == "msgpack" && inputPath == "-" {
-				// Try to decode as base64 if it looks like text
-				if decoded, err := base64.StdEncoding.DecodeString(string(inputData)); err == nil {
-					inputData = decoded
+			if stream.Enabled {
+				if err := stream.apply(); err != nil {
+					return err
+				}
+				if wireTypeJSON == "" {
+					return streamGenericDecode(inputPath, outputPath, wireInputFormat)
 				}
-			}
-
-			var outputData []byte
-
-			// If a type is specified, use CTY decoding
-			if wireTypeJSON != "" {
 				ctyType, err := parseCtyType(json.RawMessage(wireTypeJSON))
 				if err != nil {
 					return fmt.Errorf("failed to parse type: %w", err)
 				}
+				return streamConvert(inputPath, outputPath, ctyType, wireInputFormat, wireOutputFormat, false)
+			}
 
-				// Decode from wire format
-				var value cty.Value
-				switch wireInputFormat {
-				case "msgpack":
-					value, err = ctymsgpack.Unmarshal(inputData, ctyType)
-				case "json":
-					value, err = ctyjson.Unmarshal(inputData, ctyType)
-				default:
-					return fmt.Errorf("unsupported input format: %s", wireInputFormat)
+			ctyType := cty.NilType
+			if wireTypeJSON != "" {
+				var err error
+				if ctyType, err = parseCtyType(json.RawMessage(wireTypeJSON)); err != nil {
+					return fmt.Errorf("failed to parse type: %w", err)
 				}
-				if err != nil {
-					return fmt.Errorf("failed to decode: %w", err)
+			}
+			var typeFile *wireTypeFile
+			if typeFilePath != "" {
+				var err error
+				if typeFile, ctyType, err = readWireTypeFile(typeFilePath); err != nil {
+					return err
 				}
+			}
 
-				// Encode to output format
-				switch wireOutputFormat {
-				case "json":
-					outputData, err = ctyjson.Marshal(value, ctyType)
-				case "msgpack":
-					outputData, err = ctymsgpack.Marshal(value, ctyType)
-				default:
-					return fmt.Errorf("unsupported output format: %s", wireOutputFormat)
-				}
-				if err != nil {
-					return fmt.Errorf("failed to encode output: %w", err)
-				}
-			} else {
-				// Generic msgpack decoding without CTY type
-				var data interface{}
-				if err := msgpack.Unmarshal(inputData, &data); err != nil {
-					return fmt.Errorf("failed to decode msgpack: %w", err)
-				}
+			pool := wireBuffers()
+			in, scratch, out := pool.get(), pool.get(), pool.get()
+			defer pool.put(in)
+			defer pool.put(scratch)
+			defer pool.put(out)
 
-				outputData, err = json.MarshalIndent(data, "", "  ")
-				if err != nil {
-					return fmt.Errorf("failed to encode JSON: %w", err)
-				}
+			if err := readWireInput(inputPath, in); err != nil {
+				return err
 			}
+			inputData := in.Bytes()
 
-			// Write output
-			if outputPath == "-" {
-				_, err = os.Stdout.Write(outputData)
-			} else {
-				err = os.WriteFile(outputPath, outputData, 0644)
+			// If input looks like base64 (no binary bytes), try to decode it
+			// This handles the case where encode outputs base64 to stdout
+			if wireInputFormat == "msgpack" && inputPath == "-" {
+				inputData = decodeBase64Into(inputData, scratch)
 			}
+			if typeFile != nil {
+				if err := typeFile.checkPayload(inputData); err != nil {
+					return err
+				}
+			}
+
+			inputData, err := version.unframe(inputData)
 			if err != nil {
-				return fmt.Errorf("failed to write output: %w", err)
+				return err
+			}
+
+			if err := wireDecodeTo(out, inputData, ctyType, wireInputFormat, wireOutputFormat); err != nil {
+				return err
+			}
+			if err := writeWireOutput(outputPath, out.Bytes(), false); err != nil {
+				return err
 			}
 
 			return nil
@@ -221,6 +220,9 @@ func initWireDecodeCmd() *cobra.Command {
 	cmd.Flags().StringVar(&wireInputFormat, "input-format", "msgpack", "Input format (msgpack)")
 	cmd.Flags().StringVar(&wireOutputFormat, "output-format", "json", "Output format (json)")
 	cmd.Flags().StringVar(&wireTypeJSON, "type", "", "Type specification as JSON (optional)")
+	cmd.Flags().StringVar(&typeFilePath, "type-file", "", "Decode with the type from a file written by 'wire encode --emit-type-file', checking the payload's digest")
+	addStreamFlags(cmd, &stream)
+	addWireDecodeVersionFlags(cmd, &version)
 	
 	return cmd
 }
\ No newline at end of file
@@ -0,0 +1,332 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+	ctymsgpack "github.com/zclconf/go-cty/cty/msgpack"
+)
+
+// wireBenchCase is a payload shape for wire bench
+type wireBenchCase struct {
+	Name    string
+	Records int
+}
+
+var wireBenchCases = []wireBenchCase{
+	{Name: "small", Records: 1},
+	{Name: "medium", Records: 1000},
+	{Name: "large", Records: 50000},
+}
+
+// wireBenchRecordType is the element type of every bench payload
+var wireBenchRecordType = cty.Object(map[string]cty.Type{
+	"id":      cty.String,
+	"name":    cty.String,
+	"enabled": cty.Bool,
+	"count":   cty.Number,
+	"ratio":   cty.Number,
+	"tags":    cty.List(cty.String),
+	"labels":  cty.Map(cty.String),
+})
+
+// wireBenchStats is one measured variant of one direction of a case
+type wireBenchStats struct {
+	Iterations  int     `json:"iterations"`
+	NsPerOp     int64   `json:"ns_per_op"`
+	MBPerSec    float64 `json:"mb_per_sec"`
+	AllocsPerOp uint64  `json:"allocs_per_op"`
+	BytesPerOp  uint64  `json:"bytes_per_op"`
+}
+
+// wireBenchResult compares the reference and pooled paths for one case and
+// direction
+type wireBenchResult struct {
+	Case       string         `json:"case"`
+	Direction  string         `json:"direction"`
+	InputBytes int            `json:"input_bytes"`
+	Reference  wireBenchStats `json:"reference"`
+	Pooled     wireBenchStats `json:"pooled"`
+	Speedup    float64        `json:"speedup"`
+	Identical  bool           `json:"identical"`
+}
+
+// wireBenchPayload returns the JSON input for n records
+func wireBenchPayload(n int) []byte {
+	var b bytes.Buffer
+	b.WriteByte('[')
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `{"id":"rec-%06d","name":"record number %d","enabled":%t,"count":%d,"ratio":%d.%d,`,
+			i, i, i%3 == 0, i*7, i%100, i%10)
+		fmt.Fprintf(&b, `"tags":["alpha","beta-%d","gamma"],"labels":{"env":"soak","shard":"%d","owner":"team-%d"}}`,
+			i%5, i%16, i%7)
+	}
+	b.WriteByte(']')
+	return b.Bytes()
+}
+
+// wireEncodeReference is the encode path as it was before pooling: whole
+// slices at every step and base64 built as a string
+func wireEncodeReference(in []byte, ty cty.Type, outFormat string, w io.Writer) error {
+	value, err := buildCtyValueFromJSON(ty, in)
+	if err != nil {
+		return err
+	}
+	var out []byte
+	switch outFormat {
+	case "msgpack":
+		out, err = ctymsgpack.Marshal(value, ty)
+	default:
+		out, err = ctyjson.Marshal(value, ty)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, base64.StdEncoding.EncodeToString(out))
+	return err
+}
+
+// wireDecodeReference is the decode path as it was before pooling
+func wireDecodeReference(in []byte, ty cty.Type, w io.Writer) error {
+	if decoded, err := base64.StdEncoding.DecodeString(string(in)); err == nil {
+		in = decoded
+	}
+	value, err := ctymsgpack.Unmarshal(in, ty)
+	if err != nil {
+		return err
+	}
+	out, err := ctyjson.Marshal(value, ty)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
+
+// wireEncodePooled is the encode path wire encode now takes
+func wireEncodePooled(in []byte, ty cty.Type, outFormat string, w io.Writer) error {
+	pool := wireBuffers()
+	out := pool.get()
+	defer pool.put(out)
+	if err := wireEncodeTo(out, in, ty, outFormat); err != nil {
+		return err
+	}
+	b64 := base64.NewEncoder(base64.StdEncoding, w)
+	if _, err := b64.Write(out.Bytes()); err != nil {
+		return err
+	}
+	return b64.Close()
+}
+
+// wireDecodePooled is the decode path wire decode now takes
+func wireDecodePooled(in []byte, ty cty.Type, w io.Writer) error {
+	pool := wireBuffers()
+	scratch, out := pool.get(), pool.get()
+	defer pool.put(scratch)
+	defer pool.put(out)
+	if err := wireDecodeTo(out, decodeBase64Into(in, scratch), ty, "msgpack", "json"); err != nil {
+		return err
+	}
+	_, err := w.Write(out.Bytes())
+	return err
+}
+
+// measureWireBench runs op repeatedly for at least minTime
+func measureWireBench(inputBytes int, minTime time.Duration, op func() error) (wireBenchStats, error) {
+	if err := op(); err != nil { // warm up, and fill the pool
+		return wireBenchStats{}, err
+	}
+	runtime.GC()
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+	n := 0
+	for n == 0 || time.Since(start) < minTime {
+		if err := op(); err != nil {
+			return wireBenchStats{}, err
+		}
+		n++
+	}
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+
+	nsPerOp := elapsed.Nanoseconds() / int64(n)
+	return wireBenchStats{
+		Iterations:  n,
+		NsPerOp:     nsPerOp,
+		MBPerSec:    float64(inputBytes) / (1 << 20) / (float64(nsPerOp) / 1e9),
+		AllocsPerOp: (after.Mallocs - before.Mallocs) / uint64(n),
+		BytesPerOp:  (after.TotalAlloc - before.TotalAlloc) / uint64(n),
+	}, nil
+}
+
+// runWireBenchCase measures encode and decode for one case
+func runWireBenchCase(bc wireBenchCase, minTime time.Duration) ([]wireBenchResult, error) {
+	ty := cty.List(wireBenchRecordType)
+	payload := wireBenchPayload(bc.Records)
+
+	var refEncoded, pooledEncoded bytes.Buffer
+	if err := wireEncodeReference(payload, ty, "msgpack", &refEncoded); err != nil {
+		return nil, fmt.Errorf("%s: reference encode: %w", bc.Name, err)
+	}
+	if err := wireEncodePooled(payload, ty, "msgpack", &pooledEncoded); err != nil {
+		return nil, fmt.Errorf("%s: pooled encode: %w", bc.Name, err)
+	}
+	var refDecoded, pooledDecoded bytes.Buffer
+	if err := wireDecodeReference(refEncoded.Bytes(), ty, &refDecoded); err != nil {
+		return nil, fmt.Errorf("%s: reference decode: %w", bc.Name, err)
+	}
+	if err := wireDecodePooled(refEncoded.Bytes(), ty, &pooledDecoded); err != nil {
+		return nil, fmt.Errorf("%s: pooled decode: %w", bc.Name, err)
+	}
+
+	encode := wireBenchResult{
+		Case:       bc.Name,
+		Direction:  "encode",
+		InputBytes: len(payload),
+		Identical:  bytes.Equal(refEncoded.Bytes(), pooledEncoded.Bytes()),
+	}
+	decode := wireBenchResult{
+		Case:       bc.Name,
+		Direction:  "decode",
+		InputBytes: refEncoded.Len(),
+		Identical:  bytes.Equal(refDecoded.Bytes(), pooledDecoded.Bytes()),
+	}
+
+	var err error
+	if encode.Reference, err = measureWireBench(len(payload), minTime, func() error {
+		return wireEncodeReference(payload, ty, "msgpack", io.Discard)
+	}); err != nil {
+		return nil, err
+	}
+	if encode.Pooled, err = measureWireBench(len(payload), minTime, func() error {
+		return wireEncodePooled(payload, ty, "msgpack", io.Discard)
+	}); err != nil {
+		return nil, err
+	}
+	encoded := refEncoded.Bytes()
+	if decode.Reference, err = measureWireBench(len(encoded), minTime, func() error {
+		return wireDecodeReference(encoded, ty, io.Discard)
+	}); err != nil {
+		return nil, err
+	}
+	if decode.Pooled, err = measureWireBench(len(encoded), minTime, func() error {
+		return wireDecodePooled(encoded, ty, io.Discard)
+	}); err != nil {
+		return nil, err
+	}
+
+	for _, r := range []*wireBenchResult{&encode, &decode} {
+		r.Speedup = float64(r.Reference.NsPerOp) / float64(r.Pooled.NsPerOp)
+	}
+	return []wireBenchResult{encode, decode}, nil
+}
+
+func initWireBenchCmd() *cobra.Command {
+	var (
+		cases        []string
+		minTime      time.Duration
+		outputFormat string
+		leak         leakOptions
+	)
+
+	names := make([]string, len(wireBenchCases))
+	for i, bc := range wireBenchCases {
+		names[i] = bc.Name
+	}
+
+	cmd := &cobra.Command{
+		Use:   "bench",
+		Short: "Measure wire encode/decode throughput, pooled vs reference",
+		Long: `Encode and decode generated payloads (a list of records: small is 1, medium
+1,000 and large 50,000) through both the pooled wire path and the reference
+path it replaced, and report throughput, allocations and speedup. Both
+paths include the base64 step used when piping through stdout, and their
+outputs are compared byte for byte.
+
+With --leak-check the live heap is sampled after a forced GC throughout the
+run, and the command fails if the trend fitted to it grows by more than
+--leak-threshold-mb. The forced GCs cost some throughput, so compare
+timings only between runs with the same flags.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := leak.validate(); err != nil {
+				return err
+			}
+			known := map[string]wireBenchCase{}
+			for _, bc := range wireBenchCases {
+				known[bc.Name] = bc
+			}
+
+			var sampler *leakSampler
+			if leak.enabled {
+				sampler = startLeakSampler("soup-go", "go_heap_alloc", goHeapRetained, leak)
+			}
+			var results []wireBenchResult
+			for _, name := range cases {
+				bc, ok := known[name]
+				if !ok {
+					return fmt.Errorf("unknown case %q (expected one of: %s)", name, strings.Join(names, ", "))
+				}
+				logger.Debug("⏱️ running wire bench case", "case", name, "records", bc.Records)
+				caseResults, err := runWireBenchCase(bc, minTime)
+				if err != nil {
+					return err
+				}
+				results = append(results, caseResults...)
+			}
+			var leakReports []*leakReport
+			if sampler != nil {
+				leakReports = append(leakReports, sampler.finish())
+			}
+
+			if outputFormat == "json" {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				output := map[string]interface{}{
+					"buffer_pool_size": wireBufferPoolSize,
+					"results":          results,
+				}
+				if len(leakReports) > 0 {
+					output["leak_check"] = leakReports[0]
+				}
+				if err := encoder.Encode(output); err != nil {
+					return err
+				}
+				return leakCheckResult(leakReports)
+			}
+
+			fmt.Printf("%-8s %-7s %12s %12s %12s %14s %14s %8s %s\n",
+				"case", "dir", "input", "ref MB/s", "pooled MB/s", "ref allocs/op", "pool allocs/op", "speedup", "identical")
+			for _, r := range results {
+				fmt.Printf("%-8s %-7s %12d %12.1f %12.1f %14d %14d %7.2fx %t\n",
+					r.Case, r.Direction, r.InputBytes, r.Reference.MBPerSec, r.Pooled.MBPerSec,
+					r.Reference.AllocsPerOp, r.Pooled.AllocsPerOp, r.Speedup, r.Identical)
+			}
+			for _, r := range leakReports {
+				fmt.Printf("\nLeak check: %s\n", leakSummary(r))
+			}
+			return leakCheckResult(leakReports)
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&cases, "case", names, "Cases to run: "+strings.Join(names, "|"))
+	cmd.Flags().DurationVar(&minTime, "min-time", time.Second, "Minimum time to run each variant")
+	cmd.Flags().StringVar(&outputFormat, "output-format", "text", "Output format (text, json)")
+	addLeakCheckFlags(cmd, &leak, "the live Go heap")
+	return cmd
+}
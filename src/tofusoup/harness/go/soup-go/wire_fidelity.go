@@ -0,0 +1,635 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// fidelityUnknownKey marks an object in a --value file as an unknown value:
+// {"$unknown": true}, optionally with "refinements" as refine takes them
+const fidelityUnknownKey = "$unknown"
+
+// Kinds of fidelity differences
+const (
+	fidelityNumberPrecision = "number_precision"
+	fidelityUnknownLost     = "unknown_lost"
+	fidelityRefinements     = "refinements_changed"
+	fidelityNullChanged     = "null_changed"
+	fidelityTypeChanged     = "type_changed"
+	fidelityValueChanged    = "value_changed"
+	fidelityLengthChanged   = "length_changed"
+	fidelityUnsupported     = "unsupported"
+)
+
+// fidelityDifference is one way a value changed on its way through an
+// encoding
+type fidelityDifference struct {
+	Path     string `json:"path"`
+	Kind     string `json:"kind"`
+	Original string `json:"original,omitempty"`
+	Decoded  string `json:"decoded,omitempty"`
+}
+
+// fidelityLeg is what happened to the value through one encoding
+type fidelityLeg struct {
+	OK          bool                 `json:"ok"`
+	Bytes       int                  `json:"bytes,omitempty"`
+	Error       string               `json:"error,omitempty"`
+	Skipped     string               `json:"skipped,omitempty"`
+	Differences []fidelityDifference `json:"differences"`
+}
+
+// fidelityNumber records how msgpack encoded one number
+type fidelityNumber struct {
+	Path    string `json:"path"`
+	Value   string `json:"value"`
+	Msgpack string `json:"msgpack"`
+}
+
+// fidelityKeyOrder records an object whose keys the input listed in a
+// different order than both encodings write them (sorted)
+type fidelityKeyOrder struct {
+	Path    string   `json:"path"`
+	Input   []string `json:"input"`
+	Encoded []string `json:"encoded"`
+}
+
+type fidelityReport struct {
+	Type json.RawMessage `json:"type"`
+	// Read is the value as wire encode reads the JSON input, against the
+	// input read exactly
+	Read     *fidelityLeg       `json:"read"`
+	JSON     *fidelityLeg       `json:"json"`
+	Msgpack  *fidelityLeg       `json:"msgpack"`
+	Numbers  []fidelityNumber   `json:"numbers"`
+	KeyOrder []fidelityKeyOrder `json:"key_order"`
+	// Agree is true if both encodings decoded to the same value
+	Agree bool `json:"agree"`
+}
+
+// jsonObject is a JSON object with its keys in input order
+type jsonObject struct {
+	Keys   []string
+	Values map[string]interface{}
+}
+
+func (o *jsonObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, k := range o.Keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(k)
+		buf.Write(key)
+		buf.WriteByte(':')
+		val, err := json.Marshal(o.Values[k])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(val)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// decodeOrderedJSON decodes data with objects as *jsonObject and numbers
+// as json.Number, so neither key order nor precision is lost
+func decodeOrderedJSON(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	val, err := decodeOrderedValue(dec)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("unexpected data after the value")
+	}
+	return val, nil
+}
+
+func decodeOrderedValue(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok {
+	case json.Delim('{'):
+		obj := &jsonObject{Values: map[string]interface{}{}}
+		for dec.More() {
+			keyTok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			key := keyTok.(string)
+			val, err := decodeOrderedValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := obj.Values[key]; !ok {
+				obj.Keys = append(obj.Keys, key)
+			}
+			obj.Values[key] = val
+		}
+		_, err := dec.Token()
+		return obj, err
+	case json.Delim('['):
+		arr := []interface{}{}
+		for dec.More() {
+			val, err := decodeOrderedValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, val)
+		}
+		_, err := dec.Token()
+		return arr, err
+	}
+	return tok, nil
+}
+
+// fidelityBuilder builds the exact value a --value file describes,
+// recording objects whose keys aren't in sorted order
+type fidelityBuilder struct {
+	hasUnknowns bool
+	keyOrder    []fidelityKeyOrder
+}
+
+func (b *fidelityBuilder) value(ty cty.Type, raw interface{}, path cty.Path) (cty.Value, error) {
+	if raw == nil {
+		return cty.NullVal(ty), nil
+	}
+	if obj, ok := raw.(*jsonObject); ok && obj.Values[fidelityUnknownKey] == true {
+		b.hasUnknowns = true
+		refinements, ok := obj.Values["refinements"]
+		if !ok {
+			return cty.UnknownVal(ty), nil
+		}
+		// buildRefinedUnknown takes numbers as encoding/json decodes them
+		data, err := json.Marshal(refinements)
+		if err != nil {
+			return cty.NilVal, path.NewError(err)
+		}
+		var plain interface{}
+		if err := json.Unmarshal(data, &plain); err != nil {
+			return cty.NilVal, path.NewError(err)
+		}
+		val, err := buildRefinedUnknown(ty, plain)
+		if err != nil {
+			return cty.NilVal, path.NewError(err)
+		}
+		return val, nil
+	}
+
+	if ty == cty.DynamicPseudoType {
+		data, err := json.Marshal(raw)
+		if err != nil {
+			return cty.NilVal, path.NewError(err)
+		}
+		implied, err := ctyjson.ImpliedType(data)
+		if err != nil {
+			return cty.NilVal, path.NewError(err)
+		}
+		val, err := ctyjson.Unmarshal(data, implied)
+		if err != nil {
+			return cty.NilVal, path.NewError(err)
+		}
+		return val, nil
+	}
+
+	switch {
+	case ty == cty.String:
+		if s, ok := raw.(string); ok {
+			return cty.StringVal(s), nil
+		}
+		return cty.NilVal, path.NewErrorf("expected string")
+	case ty == cty.Number:
+		var text string
+		switch v := raw.(type) {
+		case json.Number:
+			text = string(v)
+		case string:
+			text = v
+		default:
+			return cty.NilVal, path.NewErrorf("expected number")
+		}
+		val, err := cty.ParseNumberVal(text)
+		if err != nil {
+			return cty.NilVal, path.NewError(err)
+		}
+		return val, nil
+	case ty == cty.Bool:
+		if v, ok := raw.(bool); ok {
+			return cty.BoolVal(v), nil
+		}
+		return cty.NilVal, path.NewErrorf("expected bool")
+	case ty.IsListType() || ty.IsSetType() || ty.IsTupleType():
+		arr, ok := raw.([]interface{})
+		if !ok {
+			return cty.NilVal, path.NewErrorf("expected array")
+		}
+		if ty.IsTupleType() && len(arr) != len(ty.TupleElementTypes()) {
+			return cty.NilVal, path.NewErrorf("expected %d elements, got %d", len(ty.TupleElementTypes()), len(arr))
+		}
+		vals := make([]cty.Value, len(arr))
+		for i, elem := range arr {
+			ety := cty.NilType
+			if ty.IsTupleType() {
+				ety = ty.TupleElementType(i)
+			} else {
+				ety = ty.ElementType()
+			}
+			val, err := b.value(ety, elem, path.Index(cty.NumberIntVal(int64(i))))
+			if err != nil {
+				return cty.NilVal, err
+			}
+			vals[i] = val
+		}
+		switch {
+		case ty.IsTupleType():
+			return cty.TupleVal(vals), nil
+		case len(vals) == 0 && ty.IsListType():
+			return cty.ListValEmpty(ty.ElementType()), nil
+		case len(vals) == 0:
+			return cty.SetValEmpty(ty.ElementType()), nil
+		case ty.IsListType():
+			return cty.ListVal(vals), nil
+		}
+		return cty.SetVal(vals), nil
+	case ty.IsMapType() || ty.IsObjectType():
+		obj, ok := raw.(*jsonObject)
+		if !ok {
+			return cty.NilVal, path.NewErrorf("expected object")
+		}
+		b.recordKeyOrder(path, obj.Keys)
+		vals := map[string]cty.Value{}
+		if ty.IsObjectType() {
+			for _, key := range obj.Keys {
+				if !ty.HasAttribute(key) {
+					return cty.NilVal, path.NewErrorf("unsupported attribute %q", key)
+				}
+			}
+			for name, aty := range ty.AttributeTypes() {
+				val, err := b.value(aty, obj.Values[name], path.GetAttr(name))
+				if err != nil {
+					return cty.NilVal, err
+				}
+				vals[name] = val
+			}
+			return cty.ObjectVal(vals), nil
+		}
+		for _, key := range obj.Keys {
+			val, err := b.value(ty.ElementType(), obj.Values[key], path.Index(cty.StringVal(key)))
+			if err != nil {
+				return cty.NilVal, err
+			}
+			vals[key] = val
+		}
+		if len(vals) == 0 {
+			return cty.MapValEmpty(ty.ElementType()), nil
+		}
+		return cty.MapVal(vals), nil
+	}
+	return cty.NilVal, path.NewErrorf("cannot build a value of type %s", ty.FriendlyName())
+}
+
+func (b *fidelityBuilder) recordKeyOrder(path cty.Path, keys []string) {
+	sorted := append([]string{}, keys...)
+	sort.Strings(sorted)
+	for i := range keys {
+		if keys[i] != sorted[i] {
+			b.keyOrder = append(b.keyOrder, fidelityKeyOrder{Path: formatCtyPath(path), Input: keys, Encoded: sorted})
+			return
+		}
+	}
+}
+
+// compareFidelity appends the differences between an original value and
+// the value decoded from its encoding
+func compareFidelity(path cty.Path, orig, got cty.Value, out *[]fidelityDifference) {
+	diff := func(kind string) {
+		*out = append(*out, fidelityDifference{
+			Path:     formatCtyPath(path),
+			Kind:     kind,
+			Original: fidelityValueString(orig),
+			Decoded:  fidelityValueString(got),
+		})
+	}
+	switch {
+	case !orig.Type().Equals(got.Type()):
+		diff(fidelityTypeChanged)
+	case orig.IsKnown() != got.IsKnown():
+		diff(fidelityUnknownLost)
+	case !orig.IsKnown():
+		if !orig.RawEquals(got) {
+			diff(fidelityRefinements)
+		}
+	case orig.IsNull() != got.IsNull():
+		diff(fidelityNullChanged)
+	case orig.IsNull():
+	case orig.Type() == cty.Number:
+		if !orig.RawEquals(got) {
+			diff(fidelityNumberPrecision)
+		}
+	case orig.Type().IsPrimitiveType():
+		if !orig.RawEquals(got) {
+			diff(fidelityValueChanged)
+		}
+	case orig.Type().IsSetType():
+		if !orig.RawEquals(got) {
+			diff(fidelityValueChanged)
+		}
+	case orig.LengthInt() != got.LengthInt():
+		diff(fidelityLengthChanged)
+	case orig.Type().IsObjectType():
+		for name := range orig.Type().AttributeTypes() {
+			compareFidelity(path.GetAttr(name), orig.GetAttr(name), got.GetAttr(name), out)
+		}
+	default:
+		// Lists, tuples and maps of equal length
+		for it := orig.ElementIterator(); it.Next(); {
+			key, elem := it.Element()
+			if !got.HasIndex(key).True() {
+				diff(fidelityValueChanged)
+				return
+			}
+			compareFidelity(path.Index(key), elem, got.Index(key), out)
+		}
+	}
+}
+
+// fidelityValueString renders a leaf for a difference; collections are
+// described by type only
+func fidelityValueString(val cty.Value) string {
+	switch {
+	case !val.IsKnown():
+		return val.GoString()
+	case val.IsNull():
+		return "null"
+	case val.Type() == cty.Number:
+		return val.AsBigFloat().Text('f', -1)
+	case val.Type() == cty.String:
+		return fmt.Sprintf("%q", val.AsString())
+	case val.Type() == cty.Bool:
+		return fmt.Sprintf("%t", val.True())
+	}
+	return val.Type().FriendlyName()
+}
+
+// msgpackNumberFormat names the msgpack format of an encoded number from
+// its first byte
+func msgpackNumberFormat(data []byte) string {
+	if len(data) == 0 {
+		return "none"
+	}
+	switch b := data[0]; {
+	case b <= 0x7f, b >= 0xe0, b >= 0xcc && b <= 0xd3:
+		return "int"
+	case b == 0xca:
+		return "float32"
+	case b == 0xcb:
+		return "float64"
+	case b >= 0xa0 && b <= 0xbf, b >= 0xd9 && b <= 0xdb:
+		return "string"
+	case b >= 0xd4 && b <= 0xd8, b >= 0xc7 && b <= 0xc9:
+		return "ext"
+	}
+	return fmt.Sprintf("0x%02x", data[0])
+}
+
+// walkFidelityLeaves calls fn for every unknown, null and primitive value
+// in val, attributes in sorted order
+func walkFidelityLeaves(path cty.Path, val cty.Value, fn func(cty.Path, cty.Value)) {
+	switch {
+	case !val.IsKnown() || val.IsNull() || val.Type().IsPrimitiveType():
+		fn(path, val)
+	case val.Type().IsObjectType():
+		names := make([]string, 0, len(val.Type().AttributeTypes()))
+		for name := range val.Type().AttributeTypes() {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			walkFidelityLeaves(path.GetAttr(name), val.GetAttr(name), fn)
+		}
+	case val.CanIterateElements():
+		for it := val.ElementIterator(); it.Next(); {
+			key, elem := it.Element()
+			walkFidelityLeaves(path.Index(key), elem, fn)
+		}
+	}
+}
+
+// fidelityNumbers records how msgpack encodes every known number in val
+func fidelityNumbers(val cty.Value) []fidelityNumber {
+	numbers := []fidelityNumber{}
+	walkFidelityLeaves(nil, val, func(path cty.Path, leaf cty.Value) {
+		if !leaf.IsKnown() || leaf.IsNull() || leaf.Type() != cty.Number {
+			return
+		}
+		var buf bytes.Buffer
+		if err := wireEncodeValue(&buf, leaf, cty.Number, "msgpack"); err != nil {
+			return
+		}
+		numbers = append(numbers, fidelityNumber{
+			Path:    formatCtyPath(path),
+			Value:   leaf.AsBigFloat().Text('f', -1),
+			Msgpack: msgpackNumberFormat(buf.Bytes()),
+		})
+	})
+	return numbers
+}
+
+// fidelityRoundTrip encodes val in format with the wire encoder, decodes
+// it again and compares
+func fidelityRoundTrip(val cty.Value, ty cty.Type, format string) *fidelityLeg {
+	leg := &fidelityLeg{Differences: []fidelityDifference{}}
+	var buf bytes.Buffer
+	if err := wireEncodeValue(&buf, val, ty, format); err != nil {
+		leg.Error = err.Error()
+		if val.IsWhollyKnown() {
+			leg.Differences = append(leg.Differences, fidelityDifference{Kind: fidelityUnsupported})
+			return leg
+		}
+		// The encoding can't carry unknowns, so every one of them is lost
+		walkFidelityLeaves(nil, val, func(path cty.Path, leaf cty.Value) {
+			if !leaf.IsKnown() {
+				leg.Differences = append(leg.Differences, fidelityDifference{
+					Path:     formatCtyPath(path),
+					Kind:     fidelityUnknownLost,
+					Original: fidelityValueString(leaf),
+				})
+			}
+		})
+		return leg
+	}
+	leg.Bytes = buf.Len()
+	got, err := wireDecodeValue(buf.Bytes(), ty, format)
+	if err != nil {
+		leg.Error = err.Error()
+		return leg
+	}
+	leg.OK = true
+	compareFidelity(nil, val, got, &leg.Differences)
+	return leg
+}
+
+// buildFidelityReport compares the exact value in valueData with the value
+// wire encode reads from it and with its JSON and msgpack round trips
+func buildFidelityReport(valueData []byte, ty cty.Type) (*fidelityReport, error) {
+	raw, err := decodeOrderedJSON(valueData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse value: %w", err)
+	}
+	builder := &fidelityBuilder{}
+	val, err := builder.value(ty, raw, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build value: %w", err)
+	}
+	typeJSON, _ := ctyjson.MarshalType(ty)
+
+	report := &fidelityReport{
+		Type:     typeJSON,
+		JSON:     fidelityRoundTrip(val, ty, "json"),
+		Msgpack:  fidelityRoundTrip(val, ty, "msgpack"),
+		Numbers:  fidelityNumbers(val),
+		KeyOrder: builder.keyOrder,
+	}
+	if report.KeyOrder == nil {
+		report.KeyOrder = []fidelityKeyOrder{}
+	}
+
+	report.Read = &fidelityLeg{Differences: []fidelityDifference{}}
+	if builder.hasUnknowns {
+		report.Read.Skipped = "the value has unknowns, which JSON input can't carry"
+	} else {
+		read, ok := scanCtyValueFromJSON(ty, valueData)
+		if !ok {
+			read, err = buildCtyValueFromJSON(ty, valueData)
+		}
+		if err != nil {
+			report.Read.Error = err.Error()
+		} else if read, err = conformValue(read, ty); err != nil {
+			report.Read.Error = err.Error()
+		} else {
+			report.Read.OK = true
+			compareFidelity(nil, val, read, &report.Read.Differences)
+		}
+	}
+
+	report.Agree = report.JSON.OK && report.Msgpack.OK &&
+		len(report.JSON.Differences) == 0 && len(report.Msgpack.Differences) == 0
+	return report, nil
+}
+
+func initWireFidelityCmd() *cobra.Command {
+	var valuePath, typePath, outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   "fidelity",
+		Short: "Report what JSON and msgpack encoding change about a value",
+		Long: `Encode a value as JSON and as msgpack with the wire encoder, decode both,
+and report every difference from the original value, for reference
+documentation on what each encoding preserves.
+
+The --value file is read exactly: numbers keep every digit and object keys
+keep their order. {"$unknown": true} (with optional "refinements") stands for
+an unknown value. The report has a leg for each encoding, one for how wire
+encode itself reads the JSON file (it reads numbers as float64), how msgpack
+encodes each number (int, float32, float64 or string), and the objects whose
+keys both encodings reorder. Differences have a path, a kind
+(number_precision, unknown_lost, refinements_changed, null_changed,
+type_changed, value_changed, length_changed, unsupported) and the original
+and decoded values.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			typeData, err := os.ReadFile(typePath)
+			if err != nil {
+				return fmt.Errorf("failed to read type file: %w", err)
+			}
+			ty, err := parseCtyType(json.RawMessage(typeData))
+			if err != nil {
+				return fmt.Errorf("failed to parse type: %w", err)
+			}
+			valueData, err := os.ReadFile(valuePath)
+			if err != nil {
+				return fmt.Errorf("failed to read value file: %w", err)
+			}
+
+			report, err := buildFidelityReport(valueData, ty)
+			if err != nil {
+				return err
+			}
+
+			switch outputFormat {
+			case "json":
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				if err := encoder.Encode(report); err != nil {
+					return fmt.Errorf("failed to encode JSON: %w", err)
+				}
+			case "text":
+				writeFidelityText(os.Stdout, report)
+			default:
+				return fmt.Errorf("unsupported output format: %s", outputFormat)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&valuePath, "value", "", "JSON file with the value (required)")
+	cmd.Flags().StringVar(&typePath, "type", "", "JSON file with the cty type (required)")
+	cmd.Flags().StringVar(&outputFormat, "output-format", "json", "Output format (json, text)")
+	cmd.MarkFlagRequired("value")
+	cmd.MarkFlagRequired("type")
+
+	return cmd
+}
+
+func writeFidelityText(w io.Writer, report *fidelityReport) {
+	for _, leg := range []struct {
+		name string
+		leg  *fidelityLeg
+	}{{"read", report.Read}, {"json", report.JSON}, {"msgpack", report.Msgpack}} {
+		switch {
+		case leg.leg.Skipped != "":
+			fmt.Fprintf(w, "%s: skipped (%s)\n", leg.name, leg.leg.Skipped)
+		case leg.leg.Error != "" && len(leg.leg.Differences) == 0:
+			fmt.Fprintf(w, "%s: error: %s\n", leg.name, leg.leg.Error)
+		case len(leg.leg.Differences) == 0:
+			fmt.Fprintf(w, "%s: exact\n", leg.name)
+		default:
+			fmt.Fprintf(w, "%s: %d difference(s)\n", leg.name, len(leg.leg.Differences))
+		}
+		for _, d := range leg.leg.Differences {
+			decoded := d.Decoded
+			if decoded == "" {
+				decoded = "(not encoded)"
+			}
+			fmt.Fprintf(w, "  %s %s: %s -> %s\n", fidelityPathText(d.Path), d.Kind, d.Original, decoded)
+		}
+	}
+	for _, k := range report.KeyOrder {
+		fmt.Fprintf(w, "keys reordered at %s: %s -> %s\n", fidelityPathText(k.Path),
+			strings.Join(k.Input, ", "), strings.Join(k.Encoded, ", "))
+	}
+	for _, n := range report.Numbers {
+		fmt.Fprintf(w, "number %s = %s: msgpack %s\n", fidelityPathText(n.Path), n.Value, n.Msgpack)
+	}
+}
+
+func fidelityPathText(path string) string {
+	if path == "" {
+		return "(root)"
+	}
+	return path
+}
@@ -0,0 +1,509 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/vmihailenco/msgpack/v5"
+	"github.com/vmihailenco/msgpack/v5/msgpcode"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+	ctymsgpack "github.com/zclconf/go-cty/cty/msgpack"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// planFileEntry is the name of the protobuf plan inside a plan archive
+const planFileEntry = "tfplan"
+
+// Field numbers from Terraform's planfile.proto (plan format version 3).
+// Only the fields needed for inspection are decoded; everything else is skipped.
+const (
+	planFieldVersion          protowire.Number = 1
+	planFieldVariables        protowire.Number = 2
+	planFieldResourceChanges  protowire.Number = 3
+	planFieldOutputChanges    protowire.Number = 4
+	planFieldTerraformVersion protowire.Number = 14
+	planFieldUIMode           protowire.Number = 17
+	planFieldResourceDrift    protowire.Number = 18
+	planFieldErrored          protowire.Number = 20
+
+	resourceChangeFieldDeposedKey  protowire.Number = 7
+	resourceChangeFieldProvider    protowire.Number = 8
+	resourceChangeFieldChange      protowire.Number = 9
+	resourceChangeFieldAddr        protowire.Number = 13
+	resourceChangeFieldPrevRunAddr protowire.Number = 14
+
+	outputChangeFieldName      protowire.Number = 1
+	outputChangeFieldChange    protowire.Number = 2
+	outputChangeFieldSensitive protowire.Number = 3
+
+	changeFieldAction protowire.Number = 1
+	changeFieldValues protowire.Number = 2
+
+	dynamicValueFieldMsgpack protowire.Number = 1
+
+	mapEntryFieldKey   protowire.Number = 1
+	mapEntryFieldValue protowire.Number = 2
+)
+
+// planActions maps planfile.proto Action enum values to their names
+var planActions = map[uint64]string{
+	0: "no-op",
+	1: "create",
+	2: "read",
+	3: "update",
+	5: "delete",
+	6: "delete-then-create",
+	7: "create-then-delete",
+	8: "forget",
+}
+
+// planUIModes maps planfile.proto Mode enum values to their names
+var planUIModes = map[uint64]string{
+	0: "normal",
+	1: "destroy",
+	2: "refresh-only",
+}
+
+// msgpackUnknown stands in for cty's unknown-value extension when msgpack is
+// decoded without a type
+type msgpackUnknown struct {
+	Refined bool
+}
+
+func (u msgpackUnknown) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]interface{}{"unknown": true, "refined": u.Refined})
+}
+
+// decodeUntypedMsgpack decodes one msgpack value as DecodeInterface does,
+// except that cty's unknown-value extensions, ext 0 for unknowns and ext 12
+// for refined ones, become msgpackUnknown. Registering them with the
+// msgpack package instead would change decoding for every command.
+func decodeUntypedMsgpack(dec *msgpack.Decoder) (interface{}, error) {
+	code, err := dec.PeekCode()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case msgpcode.IsExt(code):
+		id, extLen, err := dec.DecodeExtHeader()
+		if err != nil {
+			return nil, err
+		}
+		if err := dec.ReadFull(make([]byte, extLen)); err != nil {
+			return nil, err
+		}
+		switch id {
+		case 0:
+			return msgpackUnknown{}, nil
+		case 0x0c:
+			return msgpackUnknown{Refined: true}, nil
+		}
+		return nil, fmt.Errorf("unsupported msgpack extension %d", id)
+
+	case msgpcode.IsFixedMap(code) || code == msgpcode.Map16 || code == msgpcode.Map32:
+		n, err := dec.DecodeMapLen()
+		if err != nil {
+			return nil, err
+		}
+		m := make(map[string]interface{}, n)
+		for i := 0; i < n; i++ {
+			key, err := dec.DecodeString()
+			if err != nil {
+				return nil, err
+			}
+			if m[key], err = decodeUntypedMsgpack(dec); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+
+	case msgpcode.IsFixedArray(code) || code == msgpcode.Array16 || code == msgpcode.Array32:
+		n, err := dec.DecodeArrayLen()
+		if err != nil {
+			return nil, err
+		}
+		s := make([]interface{}, n)
+		for i := range s {
+			if s[i], err = decodeUntypedMsgpack(dec); err != nil {
+				return nil, err
+			}
+		}
+		return s, nil
+
+	default:
+		return dec.DecodeInterface()
+	}
+}
+
+// protoField is a single raw field occurrence in a protobuf message
+type protoField struct {
+	wireType protowire.Type
+	varint   uint64
+	bytes    []byte
+}
+
+// parseProtoFields splits a protobuf message into its fields, keyed by field
+// number, preserving the order of repeated occurrences
+func parseProtoFields(b []byte) (map[protowire.Number][]protoField, error) {
+	fields := make(map[protowire.Number][]protoField)
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		field := protoField{wireType: typ}
+		switch typ {
+		case protowire.VarintType:
+			field.varint, n = protowire.ConsumeVarint(b)
+		case protowire.BytesType:
+			field.bytes, n = protowire.ConsumeBytes(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+		fields[num] = append(fields[num], field)
+	}
+	return fields, nil
+}
+
+func firstBytes(fields map[protowire.Number][]protoField, num protowire.Number) []byte {
+	if f := fields[num]; len(f) > 0 {
+		return f[len(f)-1].bytes
+	}
+	return nil
+}
+
+func firstVarint(fields map[protowire.Number][]protoField, num protowire.Number) uint64 {
+	if f := fields[num]; len(f) > 0 {
+		return f[len(f)-1].varint
+	}
+	return 0
+}
+
+// planResourceAddr is the part of a resource instance address needed to find its schema
+type planResourceAddr struct {
+	Mode string
+	Type string
+}
+
+// parsePlanResourceAddr extracts the mode and type from an instance address
+// such as module.foo[0].data.aws_ami.example["key"]
+func parsePlanResourceAddr(addr string) (planResourceAddr, error) {
+	rest := addr
+	for strings.HasPrefix(rest, "module.") {
+		rest = strings.TrimPrefix(rest, "module.")
+		idx := strings.Index(rest, ".")
+		if idx < 0 {
+			return planResourceAddr{}, fmt.Errorf("invalid resource address %q", addr)
+		}
+		rest = rest[idx+1:]
+	}
+
+	mode := "managed"
+	if strings.HasPrefix(rest, "data.") {
+		mode = "data"
+		rest = strings.TrimPrefix(rest, "data.")
+	}
+
+	idx := strings.Index(rest, ".")
+	if idx <= 0 {
+		return planResourceAddr{}, fmt.Errorf("invalid resource address %q", addr)
+	}
+	return planResourceAddr{Mode: mode, Type: rest[:idx]}, nil
+}
+
+// decodeDynamicValue decodes a DynamicValue message. If ty is known the value
+// is decoded as cty and rendered with ctyjson; otherwise it is decoded as
+// generic msgpack.
+func decodeDynamicValue(raw []byte, ty cty.Type) (interface{}, error) {
+	fields, err := parseProtoFields(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DynamicValue: %w", err)
+	}
+	packed := firstBytes(fields, dynamicValueFieldMsgpack)
+	if packed == nil {
+		return nil, nil
+	}
+
+	if ty == cty.NilType {
+		data, err := decodeUntypedMsgpack(msgpack.NewDecoder(bytes.NewReader(packed)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode msgpack: %w", err)
+		}
+		return data, nil
+	}
+
+	value, err := ctymsgpack.Unmarshal(packed, ty)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode msgpack: %w", err)
+	}
+	if !value.IsWhollyKnown() {
+		// JSON cannot carry unknowns, so fall back to the generic decoding
+		return decodeDynamicValue(raw, cty.NilType)
+	}
+	jsonData, err := ctyjson.Marshal(value, ty)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode JSON: %w", err)
+	}
+	return json.RawMessage(jsonData), nil
+}
+
+// decodePlanChange decodes a Change message into action, before and after
+func decodePlanChange(raw []byte, ty cty.Type) (map[string]interface{}, error) {
+	fields, err := parseProtoFields(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Change: %w", err)
+	}
+
+	actionNum := firstVarint(fields, changeFieldAction)
+	action, ok := planActions[actionNum]
+	if !ok {
+		action = fmt.Sprintf("unknown(%d)", actionNum)
+	}
+
+	var values []interface{}
+	for _, f := range fields[changeFieldValues] {
+		v, err := decodeDynamicValue(f.bytes, ty)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+
+	// Terraform only stores the values that are meaningful for each action
+	result := map[string]interface{}{
+		"action": action,
+		"before": nil,
+		"after":  nil,
+	}
+	switch {
+	case action == "create" && len(values) == 1:
+		result["after"] = values[0]
+	case (action == "delete" || action == "forget") && len(values) == 1:
+		result["before"] = values[0]
+	case action == "no-op" && len(values) == 1:
+		result["before"] = values[0]
+		result["after"] = values[0]
+	case len(values) == 2:
+		result["before"] = values[0]
+		result["after"] = values[1]
+	}
+	return result, nil
+}
+
+// decodePlanResourceChange decodes a ResourceInstanceChange message
+func decodePlanResourceChange(raw []byte, schemas *providerSchemasJSON) (map[string]interface{}, error) {
+	fields, err := parseProtoFields(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ResourceInstanceChange: %w", err)
+	}
+
+	addr := string(firstBytes(fields, resourceChangeFieldAddr))
+	provider := string(firstBytes(fields, resourceChangeFieldProvider))
+
+	ty := cty.NilType
+	if schemas != nil {
+		parsed, err := parsePlanResourceAddr(addr)
+		if err != nil {
+			return nil, err
+		}
+		res := &tfstateResourceJSON{Provider: provider}
+		schema, err := schemas.lookupResourceSchema(res.providerSource(), parsed.Mode, parsed.Type)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", addr, err)
+		}
+		ty, err = schema.Block.impliedType()
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid schema: %w", addr, err)
+		}
+	}
+
+	change, err := decodePlanChange(firstBytes(fields, resourceChangeFieldChange), ty)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", addr, err)
+	}
+
+	result := map[string]interface{}{
+		"address":  addr,
+		"provider": provider,
+		"typed":    ty != cty.NilType,
+		"change":   change,
+	}
+	if prev := string(firstBytes(fields, resourceChangeFieldPrevRunAddr)); prev != "" && prev != addr {
+		result["previous_address"] = prev
+	}
+	if deposed := string(firstBytes(fields, resourceChangeFieldDeposedKey)); deposed != "" {
+		result["deposed"] = deposed
+	}
+	return result, nil
+}
+
+// inspectPlan decodes the protobuf plan from a plan archive
+func inspectPlan(planData []byte, schemas *providerSchemasJSON) (map[string]interface{}, error) {
+	fields, err := parseProtoFields(planData)
+	if err != nil {
+		return nil, fmt.Errorf("invalid plan protobuf: %w", err)
+	}
+
+	uiModeNum := firstVarint(fields, planFieldUIMode)
+	uiMode, ok := planUIModes[uiModeNum]
+	if !ok {
+		uiMode = fmt.Sprintf("unknown(%d)", uiModeNum)
+	}
+
+	// Variables and output values are always dynamically typed
+	variables := make(map[string]interface{})
+	for _, f := range fields[planFieldVariables] {
+		entry, err := parseProtoFields(f.bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid variables entry: %w", err)
+		}
+		name := string(firstBytes(entry, mapEntryFieldKey))
+		value, err := decodeDynamicValue(firstBytes(entry, mapEntryFieldValue), cty.DynamicPseudoType)
+		if err != nil {
+			return nil, fmt.Errorf("variable %q: %w", name, err)
+		}
+		variables[name] = value
+	}
+
+	resourceChanges := make([]interface{}, 0, len(fields[planFieldResourceChanges]))
+	for _, f := range fields[planFieldResourceChanges] {
+		change, err := decodePlanResourceChange(f.bytes, schemas)
+		if err != nil {
+			return nil, err
+		}
+		resourceChanges = append(resourceChanges, change)
+	}
+
+	resourceDrift := make([]interface{}, 0, len(fields[planFieldResourceDrift]))
+	for _, f := range fields[planFieldResourceDrift] {
+		change, err := decodePlanResourceChange(f.bytes, schemas)
+		if err != nil {
+			return nil, err
+		}
+		resourceDrift = append(resourceDrift, change)
+	}
+
+	outputChanges := make([]interface{}, 0, len(fields[planFieldOutputChanges]))
+	for _, f := range fields[planFieldOutputChanges] {
+		oc, err := parseProtoFields(f.bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid OutputChange: %w", err)
+		}
+		name := string(firstBytes(oc, outputChangeFieldName))
+		change, err := decodePlanChange(firstBytes(oc, outputChangeFieldChange), cty.DynamicPseudoType)
+		if err != nil {
+			return nil, fmt.Errorf("output %q: %w", name, err)
+		}
+		outputChanges = append(outputChanges, map[string]interface{}{
+			"name":      name,
+			"sensitive": firstVarint(oc, outputChangeFieldSensitive) != 0,
+			"change":    change,
+		})
+	}
+
+	return map[string]interface{}{
+		"plan_format_version": firstVarint(fields, planFieldVersion),
+		"terraform_version":   string(firstBytes(fields, planFieldTerraformVersion)),
+		"ui_mode":             uiMode,
+		"errored":             firstVarint(fields, planFieldErrored) != 0,
+		"variables":           variables,
+		"resource_changes":    resourceChanges,
+		"resource_drift":      resourceDrift,
+		"output_changes":      outputChanges,
+	}, nil
+}
+
+func initWirePlanCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "plan",
+		Short: "Terraform plan file operations",
+	}
+	cmd.AddCommand(initWirePlanInspectCmd())
+	return cmd
+}
+
+func initWirePlanInspectCmd() *cobra.Command {
+	var schemaPath string
+
+	cmd := &cobra.Command{
+		Use:   "inspect [plan-file]",
+		Short: "Dump resource changes from a saved Terraform plan",
+		Long: `Open a saved plan archive (terraform plan -out=...), decode the protobuf
+plan and print its resource changes with decoded DynamicValues as JSON.
+
+Without --schema, resource values are decoded as untyped msgpack. With a
+provider schema (terraform providers schema -json) they are decoded as cty
+values of the type implied by each resource schema.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var schemas *providerSchemasJSON
+			if schemaPath != "" {
+				var err error
+				schemas, err = loadProviderSchemas(schemaPath)
+				if err != nil {
+					return err
+				}
+			}
+
+			archive, err := zip.OpenReader(args[0])
+			if err != nil {
+				return fmt.Errorf("failed to open plan archive: %w", err)
+			}
+			defer archive.Close()
+
+			var planData []byte
+			entries := make([]string, 0, len(archive.File))
+			for _, f := range archive.File {
+				entries = append(entries, f.Name)
+				if f.Name != planFileEntry {
+					continue
+				}
+				r, err := f.Open()
+				if err != nil {
+					return fmt.Errorf("failed to open %s: %w", planFileEntry, err)
+				}
+				planData, err = io.ReadAll(r)
+				r.Close()
+				if err != nil {
+					return fmt.Errorf("failed to read %s: %w", planFileEntry, err)
+				}
+			}
+			sort.Strings(entries)
+
+			if planData == nil {
+				return fmt.Errorf("plan archive has no %s entry", planFileEntry)
+			}
+
+			result, err := inspectPlan(planData, schemas)
+			if err != nil {
+				return err
+			}
+			result["archive_entries"] = entries
+
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(result); err != nil {
+				return fmt.Errorf("failed to encode JSON: %w", err)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&schemaPath, "schema", "", "Provider schema JSON for typed decoding (optional)")
+	return cmd
+}
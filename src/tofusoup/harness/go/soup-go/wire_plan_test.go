@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// testdata/plan/basic.tfplan is written by make_basic_tfplan.py, which
+// encodes the plan by hand from planfile.proto
+
+func inspectTestPlan(t *testing.T, args ...string) map[string]interface{} {
+	t.Helper()
+	cmd := initWirePlanInspectCmd()
+	cmd.SetArgs(append([]string{"testdata/plan/basic.tfplan"}, args...))
+	out, err := captureStdout(t, cmd.Execute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var plan map[string]interface{}
+	if err := json.Unmarshal(out, &plan); err != nil {
+		t.Fatalf("%v: %s", err, out)
+	}
+	return plan
+}
+
+func TestWirePlanInspect(t *testing.T) {
+	plan := inspectTestPlan(t)
+
+	for key, want := range map[string]interface{}{
+		"plan_format_version": 3.0,
+		"terraform_version":   "1.9.0",
+		"ui_mode":             "normal",
+		"errored":             false,
+	} {
+		if plan[key] != want {
+			t.Errorf("%s: got %v, want %v", key, plan[key], want)
+		}
+	}
+	region := plan["variables"].(map[string]interface{})["region"].(map[string]interface{})
+	if region["value"] != "eu-west-1" || region["type"] != "string" {
+		t.Errorf("variables.region: got %v", region)
+	}
+
+	changes := plan["resource_changes"].([]interface{})
+	if len(changes) != 2 {
+		t.Fatalf("got %d resource changes, want 2", len(changes))
+	}
+	web := changes[0].(map[string]interface{})
+	webChange := web["change"].(map[string]interface{})
+	if web["address"] != "example_service.web" || webChange["action"] != "create" || webChange["before"] != nil {
+		t.Errorf("first change: got %v", web)
+	}
+	after := webChange["after"].(map[string]interface{})
+	if id := after["id"].(map[string]interface{}); id["unknown"] != true || id["refined"] != false {
+		t.Errorf("unknown id: got %v", after["id"])
+	}
+	if port := after["listener"].([]interface{})[0].(map[string]interface{})["port"]; port != 443.0 {
+		t.Errorf("listener port: got %v", port)
+	}
+
+	old := changes[1].(map[string]interface{})
+	if old["previous_address"] != "example_service.legacy" || old["deposed"] != "00000001" {
+		t.Errorf("second change: got %v", old)
+	}
+	if action := old["change"].(map[string]interface{})["action"]; action != "delete" {
+		t.Errorf("second change action: got %v", action)
+	}
+
+	output := plan["output_changes"].([]interface{})[0].(map[string]interface{})
+	if output["name"] != "endpoint" || output["sensitive"] != true {
+		t.Errorf("output change: got %v", output)
+	}
+	if entries := plan["archive_entries"].([]interface{}); len(entries) != 3 || entries[0] != "tfplan" {
+		t.Errorf("archive entries: got %v", entries)
+	}
+}
+
+func TestWirePlanInspectWithSchema(t *testing.T) {
+	plan := inspectTestPlan(t, "--schema", "testdata/tfschema/nested_schema.json")
+
+	for _, change := range plan["resource_changes"].([]interface{}) {
+		if typed := change.(map[string]interface{})["typed"]; typed != true {
+			t.Errorf("%v: want it decoded with the schema", change)
+		}
+	}
+	before := plan["resource_changes"].([]interface{})[1].(map[string]interface{})["change"].(map[string]interface{})["before"]
+	settings := before.(map[string]interface{})["settings"].(map[string]interface{})
+	if settings["replicas"] != 2.0 || settings["labels"] != nil {
+		t.Errorf("typed settings: got %v", settings)
+	}
+}
+
+func TestPlanUnknownsAreDecodedLocally(t *testing.T) {
+	refined := []byte{0xc7, 0x02, 0x0c, 0x01, 0xc3}
+	got, err := decodeUntypedMsgpack(msgpack.NewDecoder(bytes.NewReader(refined)))
+	if err != nil || got != (msgpackUnknown{Refined: true}) {
+		t.Errorf("got %#v, %v, want a refined unknown", got, err)
+	}
+
+	// Other commands decode msgpack without the plan's extensions
+	var data interface{}
+	if err := msgpack.Unmarshal([]byte{0xc7, 0x00, 0x00}, &data); err == nil {
+		if _, ok := data.(msgpackUnknown); ok {
+			t.Error("the unknown-value extension is registered with the msgpack package")
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// Wire fast path
+//
+// wire encode and decode read their input into a pooled buffer, decode the
+// value straight from it and encode into a second pooled buffer, which is
+// written to the destination as-is. msgpack encoders and decoders come from
+// the msgpack package's own pools, and base64 output is streamed rather
+// than built as a string. A soak run that encodes the same shapes over and
+// over therefore reuses the same few buffers instead of allocating and
+// copying input-sized slices at every step.
+const (
+	// defaultWireBufferPoolSize is the default --buffer-pool-size
+	defaultWireBufferPoolSize = 8
+
+	// wireMaxPooledBuffer is the largest buffer kept for reuse; bigger ones
+	// are left to the GC so a single huge payload doesn't pin its memory
+	wireMaxPooledBuffer = 64 << 20
+)
+
+// wireBufferPoolSize is --buffer-pool-size, the number of idle buffers the
+// wire commands keep for reuse (0 disables pooling)
+var wireBufferPoolSize = defaultWireBufferPoolSize
+
+// wireBufferPool is a bounded free list of byte buffers
+type wireBufferPool struct {
+	free chan *bytes.Buffer
+}
+
+func newWireBufferPool(size int) *wireBufferPool {
+	if size < 0 {
+		size = 0
+	}
+	return &wireBufferPool{free: make(chan *bytes.Buffer, size)}
+}
+
+// get returns an empty buffer, reusing an idle one if there is one
+func (p *wireBufferPool) get() *bytes.Buffer {
+	select {
+	case buf := <-p.free:
+		return buf
+	default:
+		return new(bytes.Buffer)
+	}
+}
+
+// put returns buf to the pool, dropping it if the pool is full or the
+// buffer has grown too large to keep
+func (p *wireBufferPool) put(buf *bytes.Buffer) {
+	if buf.Cap() > wireMaxPooledBuffer {
+		return
+	}
+	buf.Reset()
+	select {
+	case p.free <- buf:
+	default:
+	}
+}
+
+var (
+	wirePoolOnce sync.Once
+	wirePool     *wireBufferPool
+)
+
+// wireBuffers returns the process-wide pool, sized from --buffer-pool-size
+// on first use
+func wireBuffers() *wireBufferPool {
+	wirePoolOnce.Do(func() {
+		wirePool = newWireBufferPool(wireBufferPoolSize)
+	})
+	return wirePool
+}
+
+// readWireInput reads path ("-" for stdin) into buf, sizing it from the
+// file up front so it is filled in a single allocation
+func readWireInput(path string, buf *bytes.Buffer) error {
+	if path == "-" {
+		if _, err := buf.ReadFrom(os.Stdin); err != nil {
+			return fmt.Errorf("failed to read input: %w", err)
+		}
+		return nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to read input: %w", err)
+	}
+	defer f.Close()
+	if info, err := f.Stat(); err == nil {
+		buf.Grow(int(info.Size()) + bytes.MinRead)
+	}
+	if _, err := buf.ReadFrom(f); err != nil {
+		return fmt.Errorf("failed to read input: %w", err)
+	}
+	return nil
+}
+
+// writeWireOutput writes data to path ("-" for stdout), base64-encoding it
+// on the way if asked
+func writeWireOutput(path string, data []byte, base64Out bool) error {
+	var w io.Writer = os.Stdout
+	if path != "-" {
+		f, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("failed to write output: %w", err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	var err error
+	if base64Out {
+		b64 := base64.NewEncoder(base64.StdEncoding, w)
+		if _, err = b64.Write(data); err == nil {
+			err = b64.Close()
+		}
+	} else {
+		_, err = w.Write(data)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	return nil
+}
+
+// decodeBase64Into decodes in into scratch if it is valid base64, returning
+// the decoded bytes, or in itself if it isn't
+func decodeBase64Into(in []byte, scratch *bytes.Buffer) []byte {
+	scratch.Grow(base64.StdEncoding.DecodedLen(len(in)))
+	dst := scratch.Bytes()[:base64.StdEncoding.DecodedLen(len(in))]
+	n, err := base64.StdEncoding.Decode(dst, in)
+	if err != nil {
+		return in
+	}
+	return dst[:n]
+}
+
+// wireEncodeTo encodes JSON input to outFormat, appending to out. Without a
+// type (ty == cty.NilType) the input is encoded as plain msgpack.
+func wireEncodeTo(out *bytes.Buffer, in []byte, ty cty.Type, outFormat string) error {
+	if ty == cty.NilType {
+		var data interface{}
+		if err := json.Unmarshal(in, &data); err != nil {
+			return fmt.Errorf("failed to parse JSON: %w", err)
+		}
+		enc := msgpack.GetEncoder()
+		defer msgpack.PutEncoder(enc)
+		enc.Reset(out)
+		if err := enc.Encode(data); err != nil {
+			return fmt.Errorf("failed to encode msgpack: %w", err)
+		}
+		return nil
+	}
+
+	if outFormat != "msgpack" && outFormat != "json" {
+		return fmt.Errorf("unsupported output format: %s", outFormat)
+	}
+
+	value, ok := scanCtyValueFromJSON(ty, in)
+	if !ok {
+		var err error
+		if value, err = buildCtyValueFromJSON(ty, in); err != nil {
+			return fmt.Errorf("failed to build value: %w", err)
+		}
+	}
+	if err := wireEncodeValue(out, value, ty, outFormat); err != nil {
+		return fmt.Errorf("failed to encode: %w", err)
+	}
+	return nil
+}
+
+// wireDecodeTo decodes inFormat input to outFormat, appending to out.
+// Without a type the input is plain msgpack and is written as indented JSON.
+func wireDecodeTo(out *bytes.Buffer, in []byte, ty cty.Type, inFormat, outFormat string) error {
+	if ty == cty.NilType {
+		var data interface{}
+		dec := msgpack.GetDecoder()
+		defer msgpack.PutDecoder(dec)
+		dec.Reset(bytes.NewReader(in))
+		if err := dec.Decode(&data); err != nil {
+			return fmt.Errorf("failed to decode msgpack: %w", err)
+		}
+
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(data); err != nil {
+			return fmt.Errorf("failed to encode JSON: %w", err)
+		}
+		// json.MarshalIndent, which this replaces, has no trailing newline
+		out.Truncate(out.Len() - 1)
+		return nil
+	}
+
+	value, err := wireDecodeValue(in, ty, inFormat)
+	if err != nil {
+		return err
+	}
+	if outFormat != "msgpack" && outFormat != "json" {
+		return fmt.Errorf("unsupported output format: %s", outFormat)
+	}
+	if err := wireEncodeValue(out, value, ty, outFormat); err != nil {
+		return fmt.Errorf("failed to encode output: %w", err)
+	}
+	return nil
+}
+
+// wireDecodeValue decodes format input as ty
+func wireDecodeValue(in []byte, ty cty.Type, format string) (cty.Value, error) {
+	var value cty.Value
+	var err error
+	switch format {
+	case "msgpack":
+		dec := msgpack.GetDecoder()
+		defer msgpack.PutDecoder(dec)
+		dec.Reset(bytes.NewReader(in))
+		value, err = decodeMsgpackStream(dec, ty, newValuePath())
+	case "json":
+		value, err = ctyjson.Unmarshal(in, ty)
+	default:
+		return cty.NilVal, fmt.Errorf("unsupported input format: %s", format)
+	}
+	if err != nil {
+		return cty.NilVal, fmt.Errorf("failed to decode: %w", err)
+	}
+	return value, nil
+}
+
+// wireEncodeValue writes value as ty in format to out
+func wireEncodeValue(out *bytes.Buffer, value cty.Value, ty cty.Type, format string) error {
+	value, err := conformValue(value, ty)
+	if err != nil {
+		return err
+	}
+	switch format {
+	case "msgpack":
+		enc := msgpack.GetEncoder()
+		defer msgpack.PutEncoder(enc)
+		enc.Reset(out)
+		enc.UseCompactInts(true)
+		enc.UseCompactFloats(true)
+		return encodeMsgpackStream(out, enc, value, ty, newValuePath())
+	case "json":
+		return encodeJSONStream(out, value, ty, newValuePath())
+	default:
+		return fmt.Errorf("unsupported output format: %s", format)
+	}
+}
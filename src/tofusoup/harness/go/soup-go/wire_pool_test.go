@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+	ctymsgpack "github.com/zclconf/go-cty/cty/msgpack"
+)
+
+// The wire fast path has codecs of its own, so it is checked against
+// go-cty's on random values and on hand-built msgpack edge cases
+
+func TestWireFastPathMatchesUpstream(t *testing.T) {
+	r := rand.New(rand.NewSource(664))
+	for i := 0; i < 2000; i++ {
+		ty := genLawType(r, 3)
+		val := genLawValue(r, ty, true)
+
+		wantPacked, err := ctymsgpack.Marshal(val, ty)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wantJSON, err := ctyjson.Marshal(val, ty)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var packed, encoded bytes.Buffer
+		if err := wireEncodeValue(&packed, val, ty, "msgpack"); err != nil {
+			t.Fatalf("%#v: msgpack encode: %v", val, err)
+		}
+		if !bytes.Equal(packed.Bytes(), wantPacked) {
+			t.Errorf("%#v: msgpack encoding differs from ctymsgpack", val)
+		}
+		if err := wireEncodeValue(&encoded, val, ty, "json"); err != nil {
+			t.Fatalf("%#v: JSON encode: %v", val, err)
+		}
+		if !bytes.Equal(encoded.Bytes(), wantJSON) {
+			t.Errorf("%#v: JSON encoding differs from ctyjson:\ngot  %s\nwant %s", val, encoded.Bytes(), wantJSON)
+		}
+
+		got, err := wireDecodeValue(wantPacked, ty, "msgpack")
+		if err != nil {
+			t.Fatalf("%#v: msgpack decode: %v", val, err)
+		}
+		if want, _ := ctymsgpack.Unmarshal(wantPacked, ty); !got.RawEquals(want) {
+			t.Errorf("msgpack decode differs from ctymsgpack:\ngot  %#v\nwant %#v", got, want)
+		}
+
+		// JSON input goes through the scanner, which stands in for
+		// buildCtyValueFromJSON
+		var ref, pooled bytes.Buffer
+		refErr := wireEncodeReference(wantJSON, ty, "msgpack", &ref)
+		pooledErr := wireEncodePooled(wantJSON, ty, "msgpack", &pooled)
+		if (refErr == nil) != (pooledErr == nil) || !bytes.Equal(ref.Bytes(), pooled.Bytes()) {
+			t.Errorf("%s as %#v: pooled encode gave %q (%v), reference %q (%v)",
+				wantJSON, ty, pooled.Bytes(), pooledErr, ref.Bytes(), refErr)
+		}
+	}
+}
+
+func TestWireMsgpackDecodeEdgeCases(t *testing.T) {
+	pack := func(v interface{}) []byte {
+		b, err := msgpack.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	object := cty.Object(map[string]cty.Type{"a": cty.String, "b": cty.Number})
+
+	tests := []struct {
+		name string
+		ty   cty.Type
+		in   []byte
+	}{
+		{"empty map as object", object, pack(map[string]interface{}{})},
+		{"empty map as empty object", cty.EmptyObject, pack(map[string]interface{}{})},
+		{"empty map as map", cty.Map(cty.String), pack(map[string]interface{}{})},
+		{"nil as object", object, pack(nil)},
+		{"nil as list", cty.List(cty.String), pack(nil)},
+		{"empty array as list", cty.List(cty.String), pack([]interface{}{})},
+		{"empty array as set", cty.Set(cty.Number), pack([]interface{}{})},
+		{"empty array as empty tuple", cty.EmptyTuple, pack([]interface{}{})},
+		{"short tuple", cty.Tuple([]cty.Type{cty.String, cty.String}), pack([]interface{}{"a"})},
+		{"missing attribute", object, pack(map[string]interface{}{"a": "x"})},
+		{"unknown attribute", object, pack(map[string]interface{}{"a": "x", "c": 1})},
+		{"number as string", cty.String, pack(12)},
+		{"string as number", cty.Number, pack("12.5")},
+		{"bad number string", cty.Number, pack("twelve")},
+		{"uint64", cty.Number, pack(uint64(1 << 63))},
+		{"float", cty.Number, pack(0.1)},
+		{"bool as string", cty.String, pack(true)},
+		{"dynamic", cty.DynamicPseudoType, pack([]interface{}{[]byte(`"string"`), "x"})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want, wantErr := ctymsgpack.Unmarshal(tt.in, tt.ty)
+			got, err := wireDecodeValue(tt.in, tt.ty, "msgpack")
+			switch {
+			case (err == nil) != (wantErr == nil):
+				t.Errorf("got error %v, ctymsgpack gave %v", err, wantErr)
+			case err == nil && !got.RawEquals(want):
+				t.Errorf("got %#v, ctymsgpack gave %#v", got, want)
+			}
+		})
+	}
+}
+
+// The large bench case is the one the pooled path has to be at least twice
+// as fast on; compare the pairs with benchstat or by eye
+func benchmarkWire(b *testing.B, op func(payload []byte, ty cty.Type) error, encoded bool) {
+	ty := cty.List(wireBenchRecordType)
+	payload := wireBenchPayload(wireBenchCases[len(wireBenchCases)-1].Records)
+	if encoded {
+		var buf bytes.Buffer
+		if err := wireEncodeReference(payload, ty, "msgpack", &buf); err != nil {
+			b.Fatal(err)
+		}
+		payload = buf.Bytes()
+	}
+	b.SetBytes(int64(len(payload)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := op(payload, ty); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkWireEncodeLargeReference(b *testing.B) {
+	benchmarkWire(b, func(in []byte, ty cty.Type) error {
+		return wireEncodeReference(in, ty, "msgpack", io.Discard)
+	}, false)
+}
+
+func BenchmarkWireEncodeLargePooled(b *testing.B) {
+	benchmarkWire(b, func(in []byte, ty cty.Type) error {
+		return wireEncodePooled(in, ty, "msgpack", io.Discard)
+	}, false)
+}
+
+func BenchmarkWireDecodeLargeReference(b *testing.B) {
+	benchmarkWire(b, func(in []byte, ty cty.Type) error {
+		return wireDecodeReference(in, ty, io.Discard)
+	}, true)
+}
+
+func BenchmarkWireDecodeLargePooled(b *testing.B) {
+	benchmarkWire(b, func(in []byte, ty cty.Type) error {
+		return wireDecodePooled(in, ty, io.Discard)
+	}, true)
+}
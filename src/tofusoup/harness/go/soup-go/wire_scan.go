@@ -0,0 +1,305 @@
+package main
+
+import (
+	"encoding/json"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+// scanCtyValueFromJSON builds the same value buildCtyValueFromJSON would,
+// reading straight from data rather than through an intermediate
+// interface{} tree. It only handles well-formed input it can match exactly
+// (numbers as float64, as encoding/json gives the builder, and the same
+// null, missing-attribute and empty-collection handling); it returns false
+// for anything else, including every error case, and the caller falls back
+// to buildCtyValueFromJSON, which also produces the error message.
+func scanCtyValueFromJSON(ty cty.Type, data []byte) (cty.Value, bool) {
+	if ty == cty.DynamicPseudoType {
+		return cty.NilVal, false
+	}
+	s := &jsonScanner{data: data}
+	val, ok := s.value(ty)
+	if !ok {
+		return cty.NilVal, false
+	}
+	s.skipSpace()
+	if s.pos != len(s.data) {
+		return cty.NilVal, false
+	}
+	return val, true
+}
+
+// jsonScanner is a minimal JSON reader over an in-memory document
+type jsonScanner struct {
+	data []byte
+	pos  int
+}
+
+func (s *jsonScanner) skipSpace() {
+	for s.pos < len(s.data) {
+		switch s.data[s.pos] {
+		case ' ', '\t', '\n', '\r':
+			s.pos++
+		default:
+			return
+		}
+	}
+}
+
+// consume skips whitespace and then lit, reporting whether it was there
+func (s *jsonScanner) consume(lit string) bool {
+	s.skipSpace()
+	if len(s.data)-s.pos < len(lit) || string(s.data[s.pos:s.pos+len(lit)]) != lit {
+		return false
+	}
+	s.pos += len(lit)
+	return true
+}
+
+func (s *jsonScanner) peek() byte {
+	s.skipSpace()
+	if s.pos >= len(s.data) {
+		return 0
+	}
+	return s.data[s.pos]
+}
+
+func (s *jsonScanner) value(ty cty.Type) (cty.Value, bool) {
+	if s.peek() == 'n' {
+		if !s.consume("null") {
+			return cty.NilVal, false
+		}
+		return cty.NullVal(ty), true
+	}
+
+	switch {
+	case ty == cty.String:
+		str, ok := s.string()
+		if !ok {
+			return cty.NilVal, false
+		}
+		return cty.StringVal(str), true
+	case ty == cty.Number:
+		f, ok := s.number()
+		if !ok {
+			return cty.NilVal, false
+		}
+		return cty.NumberFloatVal(f), true
+	case ty == cty.Bool:
+		switch {
+		case s.consume("true"):
+			return cty.True, true
+		case s.consume("false"):
+			return cty.False, true
+		}
+		return cty.NilVal, false
+	case ty.IsListType() || ty.IsSetType() || ty.IsTupleType():
+		return s.sequence(ty)
+	case ty.IsMapType() || ty.IsObjectType():
+		return s.mapping(ty)
+	}
+	return cty.NilVal, false
+}
+
+func (s *jsonScanner) sequence(ty cty.Type) (cty.Value, bool) {
+	if !s.consume("[") {
+		return cty.NilVal, false
+	}
+	var vals []cty.Value
+	if !s.consume("]") {
+		for {
+			var ety cty.Type
+			if ty.IsTupleType() {
+				if len(vals) >= ty.Length() {
+					return cty.NilVal, false
+				}
+				ety = ty.TupleElementType(len(vals))
+			} else {
+				ety = ty.ElementType()
+			}
+			val, ok := s.value(ety)
+			if !ok {
+				return cty.NilVal, false
+			}
+			vals = append(vals, val)
+			if s.consume("]") {
+				break
+			}
+			if !s.consume(",") {
+				return cty.NilVal, false
+			}
+		}
+	}
+
+	switch {
+	case ty.IsTupleType():
+		if len(vals) != ty.Length() {
+			return cty.NilVal, false
+		}
+		return cty.TupleVal(vals), true
+	case len(vals) == 0 && ty.IsListType():
+		return cty.ListValEmpty(ty.ElementType()), true
+	case len(vals) == 0:
+		return cty.SetValEmpty(ty.ElementType()), true
+	case !sameElementTypes(vals):
+		return cty.NilVal, false
+	case ty.IsListType():
+		return cty.ListVal(vals), true
+	default:
+		return cty.SetVal(vals), true
+	}
+}
+
+func (s *jsonScanner) mapping(ty cty.Type) (cty.Value, bool) {
+	if !s.consume("{") {
+		return cty.NilVal, false
+	}
+	vals := map[string]cty.Value{}
+	if !s.consume("}") {
+		for {
+			if s.peek() != '"' {
+				return cty.NilVal, false
+			}
+			key, ok := s.string()
+			if !ok || !s.consume(":") {
+				return cty.NilVal, false
+			}
+			var ety cty.Type
+			if ty.IsObjectType() {
+				if !ty.HasAttribute(key) {
+					return cty.NilVal, false
+				}
+				ety = ty.AttributeType(key)
+			} else {
+				ety = ty.ElementType()
+			}
+			val, ok := s.value(ety)
+			if !ok {
+				return cty.NilVal, false
+			}
+			vals[key] = val
+			if s.consume("}") {
+				break
+			}
+			if !s.consume(",") {
+				return cty.NilVal, false
+			}
+		}
+	}
+
+	if ty.IsObjectType() {
+		return cty.ObjectVal(vals), true
+	}
+	if len(vals) == 0 {
+		return cty.MapValEmpty(ty.ElementType()), true
+	}
+	elems := make([]cty.Value, 0, len(vals))
+	for _, val := range vals {
+		elems = append(elems, val)
+	}
+	if !sameElementTypes(elems) {
+		return cty.NilVal, false
+	}
+	return cty.MapVal(vals), true
+}
+
+// sameElementTypes reports whether ListVal, SetVal and MapVal will accept
+// vals; the builder panics where they don't, and the fallback keeps that
+// behaviour rather than this path hiding it
+func sameElementTypes(vals []cty.Value) bool {
+	for _, val := range vals[1:] {
+		if !val.Type().Equals(vals[0].Type()) {
+			return false
+		}
+	}
+	return true
+}
+
+// string reads a JSON string. Strings with escapes are rare enough to hand
+// to encoding/json; invalid UTF-8, which encoding/json replaces, is left to
+// the fallback.
+func (s *jsonScanner) string() (string, bool) {
+	if s.peek() != '"' {
+		return "", false
+	}
+	start := s.pos
+	s.pos++
+	escaped, ascii := false, true
+	for s.pos < len(s.data) {
+		c := s.data[s.pos]
+		switch {
+		case c == '"':
+			s.pos++
+			raw := s.data[start:s.pos]
+			if escaped {
+				var str string
+				if err := json.Unmarshal(raw, &str); err != nil {
+					return "", false
+				}
+				return str, true
+			}
+			body := raw[1 : len(raw)-1]
+			if !ascii && !utf8.Valid(body) {
+				return "", false
+			}
+			return string(body), true
+		case c == '\\':
+			escaped = true
+			s.pos += 2
+		case c < 0x20:
+			return "", false
+		default:
+			if c >= utf8.RuneSelf {
+				ascii = false
+			}
+			s.pos++
+		}
+	}
+	return "", false
+}
+
+// number reads a JSON number as encoding/json decodes it into interface{}
+func (s *jsonScanner) number() (float64, bool) {
+	s.skipSpace()
+	start := s.pos
+	if s.pos < len(s.data) && s.data[s.pos] == '-' {
+		s.pos++
+	}
+	switch {
+	case s.pos < len(s.data) && s.data[s.pos] == '0':
+		s.pos++
+	case s.digits() == 0:
+		return 0, false
+	}
+	if s.pos < len(s.data) && s.data[s.pos] == '.' {
+		s.pos++
+		if s.digits() == 0 {
+			return 0, false
+		}
+	}
+	if s.pos < len(s.data) && (s.data[s.pos] == 'e' || s.data[s.pos] == 'E') {
+		s.pos++
+		if s.pos < len(s.data) && (s.data[s.pos] == '+' || s.data[s.pos] == '-') {
+			s.pos++
+		}
+		if s.digits() == 0 {
+			return 0, false
+		}
+	}
+	f, err := strconv.ParseFloat(string(s.data[start:s.pos]), 64)
+	if err != nil {
+		return 0, false
+	}
+	return f, true
+}
+
+func (s *jsonScanner) digits() int {
+	n := 0
+	for s.pos < len(s.data) && s.data[s.pos] >= '0' && s.data[s.pos] <= '9' {
+		s.pos++
+		n++
+	}
+	return n
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+	ctymsgpack "github.com/zclconf/go-cty/cty/msgpack"
+)
+
+// tfstateVersion is the only state file format version we understand
+const tfstateVersion = 4
+
+// tfstateJSON is the tfstate v4 envelope. Decoded ("typed") state uses the
+// same envelope, with each instance's attributes replaced by a msgpack
+// payload and its cty type.
+type tfstateJSON struct {
+	Version          int                        `json:"version"`
+	TerraformVersion string                     `json:"terraform_version"`
+	Serial           int64                      `json:"serial"`
+	Lineage          string                     `json:"lineage"`
+	Outputs          map[string]json.RawMessage `json:"outputs"`
+	Resources        []*tfstateResourceJSON     `json:"resources"`
+	CheckResults     json.RawMessage            `json:"check_results,omitempty"`
+}
+
+type tfstateResourceJSON struct {
+	Module    string                 `json:"module,omitempty"`
+	Mode      string                 `json:"mode"`
+	Type      string                 `json:"type"`
+	Name      string                 `json:"name"`
+	EachMode  string                 `json:"each,omitempty"`
+	Provider  string                 `json:"provider"`
+	Instances []*tfstateInstanceJSON `json:"instances"`
+}
+
+type tfstateInstanceJSON struct {
+	IndexKey            json.RawMessage `json:"index_key,omitempty"`
+	Status              string          `json:"status,omitempty"`
+	Deposed             string          `json:"deposed,omitempty"`
+	SchemaVersion       int64           `json:"schema_version"`
+	Attributes          json.RawMessage `json:"attributes,omitempty"`
+	AttributesFlat      json.RawMessage `json:"attributes_flat,omitempty"`
+	SensitiveAttributes json.RawMessage `json:"sensitive_attributes,omitempty"`
+	Private             string          `json:"private,omitempty"`
+	Dependencies        []string        `json:"dependencies,omitempty"`
+	CreateBeforeDestroy bool            `json:"create_before_destroy,omitempty"`
+
+	// Typed representation, only present in decoded state
+	AttributesType    json.RawMessage `json:"attributes_type,omitempty"`
+	AttributesMsgpack string          `json:"attributes_msgpack,omitempty"`
+}
+
+// address returns the resource address in Terraform's display syntax
+func (r *tfstateResourceJSON) address() string {
+	addr := r.Type + "." + r.Name
+	if r.Mode == "data" {
+		addr = "data." + addr
+	}
+	if r.Module != "" {
+		addr = r.Module + "." + addr
+	}
+	return addr
+}
+
+// providerSource extracts the provider source address from a state provider
+// reference such as provider["registry.terraform.io/hashicorp/aws"]
+func (r *tfstateResourceJSON) providerSource() string {
+	start := strings.Index(r.Provider, "[\"")
+	end := strings.LastIndex(r.Provider, "\"]")
+	if start < 0 || end <= start {
+		return ""
+	}
+	return r.Provider[start+2 : end]
+}
+
+// instanceAddress returns the full address of a single resource instance
+func (r *tfstateResourceJSON) instanceAddress(inst *tfstateInstanceJSON) string {
+	addr := r.address()
+	if len(inst.IndexKey) > 0 {
+		addr += "[" + string(inst.IndexKey) + "]"
+	}
+	if inst.Deposed != "" {
+		addr += " (deposed " + inst.Deposed + ")"
+	}
+	return addr
+}
+
+// readStateInput reads a state document from a file or stdin
+func readStateInput(inputPath string) (*tfstateJSON, error) {
+	var data []byte
+	var err error
+	if inputPath == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(inputPath)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read input: %w", err)
+	}
+
+	var state tfstateJSON
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse state JSON: %w", err)
+	}
+	if state.Version != tfstateVersion {
+		return nil, fmt.Errorf("unsupported state version %d (expected %d)", state.Version, tfstateVersion)
+	}
+	return &state, nil
+}
+
+// writeStateOutput writes a state document to a file or stdout
+func writeStateOutput(outputPath string, state *tfstateJSON) error {
+	outputData, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode state JSON: %w", err)
+	}
+	outputData = append(outputData, '\n')
+
+	if outputPath == "-" {
+		_, err = os.Stdout.Write(outputData)
+	} else {
+		err = os.WriteFile(outputPath, outputData, 0644)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	return nil
+}
+
+// forEachStateInstance resolves the schema type for every instance in the state
+// and calls fn with it. Errors are annotated with the instance address.
+func forEachStateInstance(state *tfstateJSON, schemas *providerSchemasJSON, fn func(inst *tfstateInstanceJSON, ty cty.Type) error) error {
+	for _, res := range state.Resources {
+		schema, err := schemas.lookupResourceSchema(res.providerSource(), res.Mode, res.Type)
+		if err != nil {
+			return fmt.Errorf("%s: %w", res.address(), err)
+		}
+		ty, err := schema.Block.impliedType()
+		if err != nil {
+			return fmt.Errorf("%s: invalid schema: %w", res.address(), err)
+		}
+
+		for _, inst := range res.Instances {
+			if inst.SchemaVersion != schema.Version {
+				logger.Warn("state instance schema version differs from provider schema",
+					"address", res.instanceAddress(inst),
+					"state_version", inst.SchemaVersion,
+					"schema_version", schema.Version)
+			}
+			if err := fn(inst, ty); err != nil {
+				return fmt.Errorf("%s: %w", res.instanceAddress(inst), err)
+			}
+		}
+	}
+	return nil
+}
+
+func initWireStateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "state",
+		Short: "Convert Terraform state attributes to and from typed wire values",
+	}
+	cmd.AddCommand(initWireStateDecodeCmd())
+	cmd.AddCommand(initWireStateEncodeCmd())
+	return cmd
+}
+
+func initWireStateDecodeCmd() *cobra.Command {
+	var schemaPath string
+
+	cmd := &cobra.Command{
+		Use:   "decode [state] [output]",
+		Short: "Decode tfstate attributes into msgpack-encoded cty values",
+		Long: `Decode a tfstate (v4) document, converting each resource instance's
+attributes into a cty value of the type implied by the resource schema.
+The output keeps the state envelope but replaces "attributes" with
+"attributes_type" and base64 "attributes_msgpack".`,
+		Args: cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			outputPath := "-"
+			if len(args) > 1 {
+				outputPath = args[1]
+			}
+
+			schemas, err := loadProviderSchemas(schemaPath)
+			if err != nil {
+				return err
+			}
+			state, err := readStateInput(args[0])
+			if err != nil {
+				return err
+			}
+
+			err = forEachStateInstance(state, schemas, func(inst *tfstateInstanceJSON, ty cty.Type) error {
+				if len(inst.Attributes) == 0 {
+					if len(inst.AttributesFlat) > 0 {
+						return fmt.Errorf("legacy flatmap attributes are not supported")
+					}
+					return fmt.Errorf("instance has no attributes")
+				}
+
+				value, err := ctyjson.Unmarshal(inst.Attributes, ty)
+				if err != nil {
+					return fmt.Errorf("attributes do not conform to schema: %w", err)
+				}
+				packed, err := ctymsgpack.Marshal(value, ty)
+				if err != nil {
+					return fmt.Errorf("failed to encode msgpack: %w", err)
+				}
+				typeJSON, err := ctyjson.MarshalType(ty)
+				if err != nil {
+					return fmt.Errorf("failed to encode type: %w", err)
+				}
+
+				inst.Attributes = nil
+				inst.AttributesType = typeJSON
+				inst.AttributesMsgpack = base64.StdEncoding.EncodeToString(packed)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+
+			return writeStateOutput(outputPath, state)
+		},
+	}
+
+	cmd.Flags().StringVar(&schemaPath, "schema", "", "Provider schema JSON (output of 'terraform providers schema -json')")
+	cmd.MarkFlagRequired("schema")
+	return cmd
+}
+
+func initWireStateEncodeCmd() *cobra.Command {
+	var schemaPath string
+
+	cmd := &cobra.Command{
+		Use:   "encode [typed-state] [output]",
+		Short: "Encode msgpack-encoded cty values back into tfstate attributes",
+		Long: `Encode a typed state document produced by 'wire state decode' back into a
+tfstate (v4) document, decoding each instance's msgpack payload with the
+type implied by the resource schema.`,
+		Args: cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			outputPath := "-"
+			if len(args) > 1 {
+				outputPath = args[1]
+			}
+
+			schemas, err := loadProviderSchemas(schemaPath)
+			if err != nil {
+				return err
+			}
+			state, err := readStateInput(args[0])
+			if err != nil {
+				return err
+			}
+
+			err = forEachStateInstance(state, schemas, func(inst *tfstateInstanceJSON, ty cty.Type) error {
+				if inst.AttributesMsgpack == "" {
+					return fmt.Errorf("instance has no attributes_msgpack")
+				}
+
+				packed, err := base64.StdEncoding.DecodeString(inst.AttributesMsgpack)
+				if err != nil {
+					return fmt.Errorf("failed to decode base64: %w", err)
+				}
+				value, err := ctymsgpack.Unmarshal(packed, ty)
+				if err != nil {
+					return fmt.Errorf("failed to decode msgpack: %w", err)
+				}
+				attrs, err := ctyjson.Marshal(value, ty)
+				if err != nil {
+					return fmt.Errorf("failed to encode attributes: %w", err)
+				}
+
+				inst.Attributes = attrs
+				inst.AttributesType = nil
+				inst.AttributesMsgpack = ""
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+
+			return writeStateOutput(outputPath, state)
+		},
+	}
+
+	cmd.Flags().StringVar(&schemaPath, "schema", "", "Provider schema JSON (output of 'terraform providers schema -json')")
+	cmd.MarkFlagRequired("schema")
+	return cmd
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// Type files
+//
+// In the plugin protocol a DynamicValue carries only msgpack; its type
+// comes out-of-band from the schema. wire encode --emit-type-file writes
+// the type next to the payload in the same way, and the two reference each
+// other by digest: the type file records the payload's SHA-256 and its own
+// type's, and wire decode --type-file checks both before decoding, so a
+// payload is never read with a type it wasn't encoded with.
+
+// wireTypeFileVersion is the version of the type file layout
+const wireTypeFileVersion = 1
+
+// wireTypeFile is the artifact --emit-type-file writes
+type wireTypeFile struct {
+	Version int `json:"version"`
+	// Type is the type constraint in cty's JSON type notation, as
+	// ctyjson.MarshalType writes it; TypeSHA256 is the digest of those bytes
+	Type       json.RawMessage     `json:"type"`
+	TypeSHA256 string              `json:"type_sha256"`
+	Payload    wireTypeFilePayload `json:"payload"`
+}
+
+// wireTypeFilePayload identifies the payload encoded with the type
+type wireTypeFilePayload struct {
+	// Path is the payload's path relative to the type file, empty when it
+	// was written to stdout
+	Path        string `json:"path,omitempty"`
+	Format      string `json:"format"`
+	WireVersion int    `json:"wire_version"`
+	Size        int    `json:"size"`
+	// SHA256 is the digest of the payload bytes, header included and
+	// before any base64 wrapping on stdout
+	SHA256 string `json:"sha256"`
+}
+
+// writeWireTypeFile writes the type file for payload, encoded with ty and
+// written to payloadPath
+func writeWireTypeFile(path string, ty cty.Type, payload []byte, payloadPath, format string, wireVersion int) error {
+	typeJSON, err := ctyjson.MarshalType(ty)
+	if err != nil {
+		return fmt.Errorf("failed to marshal type: %w", err)
+	}
+	file := wireTypeFile{
+		Version:    wireTypeFileVersion,
+		Type:       typeJSON,
+		TypeSHA256: sha256Hex(typeJSON),
+		Payload: wireTypeFilePayload{
+			Path:        relativePayloadPath(path, payloadPath),
+			Format:      format,
+			WireVersion: wireVersion,
+			Size:        len(payload),
+			SHA256:      sha256Hex(payload),
+		},
+	}
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode type file: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write type file: %w", err)
+	}
+	logger.Debug("wrote type file", "path", path, "type_sha256", file.TypeSHA256, "payload_sha256", file.Payload.SHA256)
+	return nil
+}
+
+// relativePayloadPath is payloadPath as seen from the type file's
+// directory, so the pair can be moved together
+func relativePayloadPath(typeFilePath, payloadPath string) string {
+	if payloadPath == "-" {
+		return ""
+	}
+	base, err := filepath.Abs(filepath.Dir(typeFilePath))
+	if err != nil {
+		return payloadPath
+	}
+	target, err := filepath.Abs(payloadPath)
+	if err != nil {
+		return payloadPath
+	}
+	rel, err := filepath.Rel(base, target)
+	if err != nil {
+		return payloadPath
+	}
+	return filepath.ToSlash(rel)
+}
+
+// readWireTypeFile reads a type file and returns its type, after checking
+// the type against its recorded digest
+func readWireTypeFile(path string) (*wireTypeFile, cty.Type, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, cty.NilType, fmt.Errorf("failed to read type file: %w", err)
+	}
+	var file wireTypeFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, cty.NilType, fmt.Errorf("failed to parse type file %s: %w", path, err)
+	}
+	if file.Version != wireTypeFileVersion {
+		return nil, cty.NilType, fmt.Errorf("type file %s has version %d; this harness reads version %d", path, file.Version, wireTypeFileVersion)
+	}
+	ty, err := ctyjson.UnmarshalType(file.Type)
+	if err != nil {
+		return nil, cty.NilType, fmt.Errorf("type file %s: invalid type: %w", path, err)
+	}
+	// The digest is of the type as MarshalType writes it, whatever
+	// whitespace the file was saved with
+	canonical, err := ctyjson.MarshalType(ty)
+	if err != nil {
+		return nil, cty.NilType, fmt.Errorf("type file %s: %w", path, err)
+	}
+	if got := sha256Hex(canonical); got != file.TypeSHA256 {
+		return nil, cty.NilType, fmt.Errorf("type file %s: type has sha256 %s, but type_sha256 is %s", path, got, file.TypeSHA256)
+	}
+	return &file, ty, nil
+}
+
+// checkPayload fails unless payload is the one the type file was written
+// for
+func (f *wireTypeFile) checkPayload(payload []byte) error {
+	if got := sha256Hex(payload); got != f.Payload.SHA256 {
+		return fmt.Errorf("payload has sha256 %s, but the type file was written for %s (%d bytes)",
+			got, f.Payload.SHA256, f.Payload.Size)
+	}
+	return nil
+}
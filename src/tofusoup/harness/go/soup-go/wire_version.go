@@ -0,0 +1,211 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// Wire format versions. Version 1 is bare msgpack, as Terraform sends it.
+// Version 2 prefixes the payload with an 8-byte header:
+//
+//	0     0xc1, a byte msgpack never uses, so no v1 payload starts with it
+//	1-2   "TW"
+//	3     version
+//	4-5   header length in bytes, big-endian, including these fields
+//	6     flags (bit 0: payload is cty msgpack encoded with a type)
+//	7     reserved, zero
+//
+// The header length lets a later version add fields after these while
+// staying skippable by readers that opt into --allow-future-versions.
+const (
+	wireVersion1       = 1
+	wireVersion2       = 2
+	wireVersionLatest  = wireVersion2
+	wireHeaderMarker   = 0xc1
+	wireHeaderV2Length = 8
+	wireFlagTyped      = 0x01
+)
+
+var wireHeaderMagic = [3]byte{wireHeaderMarker, 'T', 'W'}
+
+// wireHeader is what decode detected about its input
+type wireHeader struct {
+	Version      int  `json:"wire_version"`
+	Framed       bool `json:"framed"`
+	HeaderLength int  `json:"header_length"`
+	Typed        bool `json:"typed"`
+	// AssumedVersion is set when a newer version than this harness knows
+	// was decoded as the latest one under --allow-future-versions
+	AssumedVersion int `json:"assumed_version,omitempty"`
+}
+
+// wireVersionError reports input framed with a version this harness
+// doesn't support, or a malformed header
+type wireVersionError struct {
+	Code      string `json:"-"`
+	Version   int    `json:"version,omitempty"`
+	Supported []int  `json:"supported"`
+	Reason    string `json:"reason"`
+}
+
+func (e *wireVersionError) Error() string {
+	return fmt.Sprintf("unsupported wire input: %s", e.Reason)
+}
+
+// printWireVersionJSON writes err as {"error": {...}}, like resource limit
+// errors, so a runner can tell a version mismatch from a decode failure
+func printWireVersionJSON(w io.Writer, err *wireVersionError) {
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":    err.Code,
+			"message": err.Error(),
+			"details": err,
+		},
+	})
+}
+
+// appendWireHeader appends a version 2 header to out
+func appendWireHeader(out []byte, typed bool) []byte {
+	var flags byte
+	if typed {
+		flags |= wireFlagTyped
+	}
+	out = append(out, wireHeaderMagic[:]...)
+	out = append(out, wireVersion2)
+	out = binary.BigEndian.AppendUint16(out, wireHeaderV2Length)
+	return append(out, flags, 0)
+}
+
+// parseWireHeader detects the version of in and returns the payload after
+// any header. Versions newer than wireVersionLatest are rejected unless
+// allowFuture, in which case their header is skipped by its length and the
+// payload is decoded as the latest version.
+func parseWireHeader(in []byte, allowFuture bool) (wireHeader, []byte, error) {
+	if len(in) == 0 || in[0] != wireHeaderMarker {
+		return wireHeader{Version: wireVersion1}, in, nil
+	}
+	malformed := func(reason string) error {
+		return &wireVersionError{Code: "INVALID_WIRE_HEADER", Supported: []int{wireVersion1, wireVersion2}, Reason: reason}
+	}
+	if len(in) < 6 || in[1] != wireHeaderMagic[1] || in[2] != wireHeaderMagic[2] {
+		return wireHeader{}, nil, malformed("input starts with 0xc1 but has no wire header")
+	}
+
+	h := wireHeader{Version: int(in[3]), Framed: true, HeaderLength: int(binary.BigEndian.Uint16(in[4:6]))}
+	switch {
+	case h.Version < wireVersion2:
+		return h, nil, malformed(fmt.Sprintf("header claims version %d; only version 2 and later are framed", h.Version))
+	case h.HeaderLength < wireHeaderV2Length:
+		return h, nil, malformed(fmt.Sprintf("header length %d is shorter than the %d-byte version 2 header", h.HeaderLength, wireHeaderV2Length))
+	case h.HeaderLength > len(in):
+		return h, nil, malformed(fmt.Sprintf("header length %d exceeds the %d-byte input", h.HeaderLength, len(in)))
+	}
+
+	if h.Version > wireVersionLatest {
+		if !allowFuture {
+			return h, nil, &wireVersionError{
+				Code:      "UNSUPPORTED_WIRE_VERSION",
+				Version:   h.Version,
+				Supported: []int{wireVersion1, wireVersion2},
+				Reason:    fmt.Sprintf("version %d is newer than this harness supports (latest %d)", h.Version, wireVersionLatest),
+			}
+		}
+		// Fields a future version adds to the header can't be interpreted
+		h.AssumedVersion = wireVersionLatest
+		h.Typed = in[6]&wireFlagTyped != 0
+		logger.Warn("⚠️ decoding a newer wire version as the latest known", "version", h.Version, "assumed", wireVersionLatest)
+		return h, in[h.HeaderLength:], nil
+	}
+
+	flags := in[6]
+	if flags&^wireFlagTyped != 0 || in[7] != 0 || h.HeaderLength != wireHeaderV2Length {
+		return h, nil, malformed(fmt.Sprintf("version 2 header has unknown flags 0x%02x, reserved byte 0x%02x or length %d", flags, in[7], h.HeaderLength))
+	}
+	h.Typed = flags&wireFlagTyped != 0
+	return h, in[h.HeaderLength:], nil
+}
+
+// wireVersionOptions configures versioned framing for wire encode and decode
+type wireVersionOptions struct {
+	// Encode: 1 or 2. Decode: "auto", "1" or "2".
+	Version     string
+	AllowFuture bool
+	Report      bool
+}
+
+// addWireEncodeVersionFlags registers --wire-version on wire encode
+func addWireEncodeVersionFlags(cmd *cobra.Command, o *wireVersionOptions) {
+	cmd.Flags().StringVar(&o.Version, "wire-version", "1", "Wire format version: 1 (bare msgpack) or 2 (msgpack with a versioned header)")
+}
+
+// addWireDecodeVersionFlags registers the version detection flags on wire decode
+func addWireDecodeVersionFlags(cmd *cobra.Command, o *wireVersionOptions) {
+	cmd.Flags().StringVar(&o.Version, "wire-version", "auto", "Expected wire format version: auto, 1 or 2")
+	cmd.Flags().BoolVar(&o.AllowFuture, "allow-future-versions", false, "Decode versions newer than this harness supports as the latest one instead of failing")
+	cmd.Flags().BoolVar(&o.Report, "report-version", false, "Write the detected wire version as JSON to stderr")
+}
+
+// checkEncode rejects combinations version 2 framing can't honor
+func (o *wireVersionOptions) checkEncode(outputFormat string, streaming bool) (bool, error) {
+	switch o.Version {
+	case "1":
+		return false, nil
+	case "2":
+	default:
+		return false, fmt.Errorf("unsupported --wire-version %q: use 1 or 2", o.Version)
+	}
+	switch {
+	case outputFormat != "msgpack":
+		return false, fmt.Errorf("--wire-version 2 frames msgpack output; use --output-format msgpack")
+	case streaming:
+		return false, fmt.Errorf("--wire-version 2 can't be combined with --stream-parse")
+	}
+	return true, nil
+}
+
+// checkDecode validates --wire-version before any input is read
+func (o *wireVersionOptions) checkDecode(streaming bool) error {
+	switch o.Version {
+	case "auto", "1", "2":
+	default:
+		return fmt.Errorf("unsupported --wire-version %q: use auto, 1 or 2", o.Version)
+	}
+	if streaming && (o.Version == "2" || o.AllowFuture || o.Report) {
+		return fmt.Errorf("wire version detection can't be combined with --stream")
+	}
+	return nil
+}
+
+// unframe detects in's version, checks it against --wire-version, reports
+// it if asked, and returns the payload
+func (o *wireVersionOptions) unframe(in []byte) ([]byte, error) {
+	h, payload, err := parseWireHeader(in, o.AllowFuture)
+	var versionErr *wireVersionError
+	if errors.As(err, &versionErr) {
+		printWireVersionJSON(os.Stdout, versionErr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	logger.Debug("🔎 detected wire version", "version", h.Version, "framed", h.Framed, "typed", h.Typed)
+
+	detected := h.Version
+	if h.AssumedVersion != 0 {
+		detected = h.AssumedVersion
+	}
+	if o.Version != "auto" && o.Version != fmt.Sprint(detected) {
+		return nil, fmt.Errorf("expected wire version %s but input is version %d", o.Version, h.Version)
+	}
+	if o.Report {
+		if err := json.NewEncoder(os.Stderr).Encode(h); err != nil {
+			return nil, fmt.Errorf("failed to report wire version: %w", err)
+		}
+	}
+	return payload, nil
+}
@@ -0,0 +1,273 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""Self-contained HTML reports for matrix and conformance runs.
+
+The report is a single file with inline CSS and SVG and no scripts, so it can
+be attached to a ticket or mailed around and opened anywhere. For each report
+it shows:
+
+- a compatibility grid: one row per test (or matrix case) and one column per
+  parameter combination, each cell linking to that case's drill-down
+- a timing chart of the slowest cases
+- a drill-down per case with its outcome, message, failure detail (with
+  assertion diffs highlighted), the tail of its log and, when a results
+  database is given, its duration trend across recorded runs"""
+
+from collections.abc import Callable
+from datetime import UTC, datetime
+import html
+import pathlib
+
+from .results_db import FAIL_OUTCOMES, PASS_OUTCOMES, CaseHistoryEntry, CaseResult, RunReport
+
+# Slowest cases shown in the timing chart
+TIMING_CHART_CASES = 25
+
+# Lines of a case's log file embedded in its drill-down
+LOG_TAIL_LINES = 200
+
+STYLE = """
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #222; }
+h1 { margin-bottom: 0.2em; }
+h2 { border-bottom: 1px solid #ddd; padding-bottom: 0.2em; margin-top: 2em; }
+.meta { color: #666; }
+.totals span { display: inline-block; margin-right: 1.5em; font-weight: bold; }
+table.grid { border-collapse: collapse; margin: 1em 0; }
+table.grid th, table.grid td { border: 1px solid #ddd; padding: 0.3em 0.6em; text-align: center; }
+table.grid th.row { text-align: left; font-family: monospace; font-weight: normal; }
+table.grid th.col { font-family: monospace; font-size: 0.85em; writing-mode: vertical-rl; }
+td a { display: block; color: inherit; text-decoration: none; }
+.passed { background: #d4f7d4; }
+.failed { background: #f9d0d0; }
+.skipped { background: #f3f3c8; }
+.empty { background: #fafafa; }
+details { border: 1px solid #ddd; border-radius: 4px; margin: 0.4em 0; padding: 0.3em 0.6em; }
+details summary { cursor: pointer; font-family: monospace; }
+pre { background: #f6f8fa; padding: 0.6em; overflow-x: auto; font-size: 0.85em; }
+pre .add { background: #e6ffed; color: #22863a; }
+pre .del { background: #ffeef0; color: #b31d28; }
+svg text { font-family: monospace; font-size: 11px; }
+"""
+
+
+def outcome_class(outcome: str) -> str:
+    """CSS class for an outcome."""
+    if outcome in PASS_OUTCOMES:
+        return "passed"
+    if outcome in FAIL_OUTCOMES:
+        return "failed"
+    return "skipped"
+
+
+def split_case_id(case_id: str) -> tuple[str, str]:
+    """Split a case ID into its grid row and column.
+
+    Parametrized pytest IDs (``file.py::test[params]``) become the test and
+    its parameters; anything else is its own row with a single column."""
+    base, sep, params = case_id.partition("[")
+    if sep and params.endswith("]"):
+        return base.rpartition("/")[2], params[:-1]
+    return case_id.rpartition("/")[2], "result"
+
+
+def _e(text: object) -> str:
+    return html.escape(str(text))
+
+
+def _duration(seconds: float | None) -> str:
+    return "-" if seconds is None else f"{seconds:.2f}s"
+
+
+def render_detail(text: str) -> str:
+    """Render failure detail as preformatted text, highlighting diff lines."""
+    lines = []
+    for line in text.splitlines():
+        # pytest prefixes assertion output with "E   "
+        body = line[1:].lstrip() if line.startswith("E ") else line
+        if body.startswith("+") and not body.startswith("+++"):
+            lines.append(f'<span class="add">{_e(line)}</span>')
+        elif body.startswith("-") and not body.startswith("---"):
+            lines.append(f'<span class="del">{_e(line)}</span>')
+        else:
+            lines.append(_e(line))
+    return "<pre>" + "\n".join(lines) + "</pre>"
+
+
+def read_log_tail(path: str, lines: int = LOG_TAIL_LINES) -> str | None:
+    """The last lines of a log file, or None if it can't be read."""
+    try:
+        content = pathlib.Path(path).read_text(errors="replace").splitlines()
+    except OSError:
+        return None
+    return "\n".join(content[-lines:])
+
+
+def render_grid(report: RunReport, anchor: Callable[[int], str]) -> str:
+    """Render the compatibility grid for a report."""
+    rows: dict[str, dict[str, int]] = {}
+    columns: list[str] = []
+    for index, case in enumerate(report.cases):
+        row, column = split_case_id(case.case_id)
+        rows.setdefault(row, {})[column] = index
+        if column not in columns:
+            columns.append(column)
+
+    out = ['<table class="grid"><tr><th></th>']
+    out.extend(f'<th class="col">{_e(column)}</th>' for column in columns)
+    out.append("</tr>")
+    for row, cells in rows.items():
+        out.append(f'<tr><th class="row">{_e(row)}</th>')
+        for column in columns:
+            index = cells.get(column)
+            if index is None:
+                out.append('<td class="empty"></td>')
+                continue
+            case = report.cases[index]
+            title = f"{case.outcome} {_duration(case.duration_seconds)}"
+            symbol = {"passed": "✔", "failed": "✘"}.get(outcome_class(case.outcome), "–")
+            out.append(
+                f'<td class="{outcome_class(case.outcome)}" title="{_e(title)}">'
+                f'<a href="#{anchor(index)}">{symbol}</a></td>'
+            )
+        out.append("</tr>")
+    out.append("</table>")
+    return "".join(out)
+
+
+def render_timing_chart(cases: list[CaseResult]) -> str:
+    """Render a horizontal bar chart of the slowest cases as inline SVG."""
+    timed = sorted(
+        (c for c in cases if c.duration_seconds is not None),
+        key=lambda c: c.duration_seconds or 0.0,
+        reverse=True,
+    )[:TIMING_CHART_CASES]
+    if not timed:
+        return '<p class="meta">No timing information.</p>'
+
+    longest = max(c.duration_seconds or 0.0 for c in timed) or 1.0
+    label_width, bar_width, row_height = 420, 360, 18
+    height = row_height * len(timed) + 4
+    out = [f'<svg width="{label_width + bar_width + 80}" height="{height}" role="img">']
+    for i, case in enumerate(timed):
+        y = i * row_height + 2
+        seconds = case.duration_seconds or 0.0
+        width = max(1, round(seconds / longest * bar_width))
+        label = case.case_id.rpartition("/")[2]
+        if len(label) > 60:
+            label = "…" + label[-59:]
+        colour = {"passed": "#5cb85c", "failed": "#d9534f"}.get(outcome_class(case.outcome), "#c0b030")
+        out.append(
+            f'<text x="{label_width - 6}" y="{y + 12}" text-anchor="end">{_e(label)}</text>'
+            f'<rect x="{label_width}" y="{y}" width="{width}" height="{row_height - 4}" fill="{colour}">'
+            f"<title>{_e(case.case_id)}: {seconds:.3f}s</title></rect>"
+            f'<text x="{label_width + width + 4}" y="{y + 12}">{seconds:.2f}s</text>'
+        )
+    out.append("</svg>")
+    return "".join(out)
+
+
+def render_sparkline(entries: list[CaseHistoryEntry]) -> str:
+    """Render a case's duration across runs as a small inline SVG line."""
+    points: list[tuple[CaseHistoryEntry, float]] = [
+        (e, e.duration_seconds) for e in entries if e.duration_seconds is not None
+    ]
+    if len(points) < 2:
+        return ""
+    width, height = 240, 40
+    longest = max(d for _, d in points) or 1.0
+    step = width / (len(points) - 1)
+    coords = [(i * step, height - 4 - d / longest * (height - 8)) for i, (_, d) in enumerate(points)]
+    path = " ".join(f"{x:.1f},{y:.1f}" for x, y in coords)
+    dots = "".join(
+        f'<circle cx="{x:.1f}" cy="{y:.1f}" r="2.5" '
+        f'fill="{"#d9534f" if outcome_class(e.outcome) == "failed" else "#337ab7"}">'
+        f"<title>run {e.run_id} ({e.recorded_at[:10]}): {d:.3f}s {e.outcome}</title></circle>"
+        for (x, y), (e, d) in zip(coords, points, strict=True)
+    )
+    return (
+        f'<p>Duration across runs:</p><svg width="{width + 10}" height="{height}" role="img">'
+        f'<polyline points="{path}" fill="none" stroke="#337ab7" stroke-width="1.5"/>{dots}</svg>'
+    )
+
+
+def render_case(
+    case: CaseResult, anchor: str, history: Callable[[str], list[CaseHistoryEntry]] | None
+) -> str:
+    """Render the drill-down for one case."""
+    out = [
+        f'<details id="{anchor}"{" open" if outcome_class(case.outcome) == "failed" else ""}>',
+        f'<summary><span class="{outcome_class(case.outcome)}">&nbsp;{_e(case.outcome)}&nbsp;</span> '
+        f"{_e(case.case_id)} ({_duration(case.duration_seconds)})</summary>",
+    ]
+    if case.message:
+        out.append(f"<p><strong>Message:</strong> {_e(case.message)}</p>")
+    if case.detail:
+        out.append(render_detail(case.detail))
+    if case.log_file and outcome_class(case.outcome) != "passed":
+        tail = read_log_tail(case.log_file)
+        if tail is not None:
+            out.append(f"<p>Log tail ({_e(case.log_file)}):</p><pre>{_e(tail)}</pre>")
+    if history is not None:
+        out.append(render_sparkline(history(case.case_id)))
+    out.append("</details>")
+    return "".join(out)
+
+
+def render_html_report(
+    reports: list[RunReport],
+    history: Callable[[str], list[CaseHistoryEntry]] | None = None,
+    title: str = "TofuSoup Test Report",
+) -> str:
+    """Render reports as one self-contained HTML document.
+
+    `history`, if given, returns a case's recorded results (oldest first) and
+    adds a duration trend to each drill-down."""
+    generated = datetime.now(tz=UTC).strftime("%Y-%m-%d %H:%M:%S UTC")
+    out = [
+        "<!DOCTYPE html>",
+        '<html lang="en"><head><meta charset="utf-8">',
+        f"<title>{_e(title)}</title><style>{STYLE}</style></head><body>",
+        f"<h1>{_e(title)}</h1>",
+        f'<p class="meta">Generated {generated} from {len(reports)} report(s)</p>',
+    ]
+
+    for report_index, report in enumerate(reports):
+        passed = sum(1 for c in report.cases if outcome_class(c.outcome) == "passed")
+        failed = sum(1 for c in report.cases if outcome_class(c.outcome) == "failed")
+        skipped = len(report.cases) - passed - failed
+
+        def anchor(case_index: int, r: int = report_index) -> str:
+            return f"r{r}-c{case_index}"
+
+        out.append(f"<h2>{_e(report.kind)}: {_e(report.source)}</h2>")
+        meta = []
+        if report.started_at:
+            meta.append(f"started {_e(report.started_at)}")
+        if report.duration_seconds is not None:
+            meta.append(f"took {_duration(report.duration_seconds)}")
+        if meta:
+            out.append(f'<p class="meta">{", ".join(meta)}</p>')
+        out.append(
+            f'<p class="totals"><span class="passed">&nbsp;{passed} passed&nbsp;</span>'
+            f'<span class="failed">&nbsp;{failed} failed&nbsp;</span>'
+            f'<span class="skipped">&nbsp;{skipped} other&nbsp;</span></p>'
+        )
+        out.append("<h3>Compatibility matrix</h3>")
+        out.append(render_grid(report, anchor))
+        out.append("<h3>Timing</h3>")
+        out.append(render_timing_chart(report.cases))
+        out.append("<h3>Cases</h3>")
+        # Failures first, then in report order
+        order = sorted(
+            range(len(report.cases)), key=lambda i: outcome_class(report.cases[i].outcome) != "failed"
+        )
+        out.extend(render_case(report.cases[i], anchor(i), history) for i in order)
+
+    out.append("</body></html>")
+    return "\n".join(out)
+
+
+# 🥣🔬🔚
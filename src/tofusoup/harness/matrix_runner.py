@@ -0,0 +1,482 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""Cross-language K/V round trips on local binaries or container images.
+
+Every client/server pair of harness sides puts a key through the client and
+reads it back. With the local runner each side is the binary built from
+this checkout. With a container runner (docker, or podman, whose CLI is
+compatible) each side runs inside the image `--image-map` names for it, so
+released images can be checked against each other without building them.
+
+The server's container owns the network namespace and the client's
+container joins it with `--network container:<server>`, so a server
+listening on 127.0.0.1, as go-plugin servers do, is reachable without
+publishing ports. Each pair's directory is mounted at /artifacts in the
+server's container, which keeps its K/V storage there. The logs of every
+step are written next to it, whichever runner is used.
+
+A side whose runtime needs setting up, such as a Python harness run through
+`uv run` or a Node one needing `--experimental-*` flags, gets a profile
+rather than a wrapper script: environment variables, a working directory
+and a wrapper command put in front of its command line. Profiles come from
+``[harness.matrix.profiles.<side>]`` in soup.toml or ``[profiles.<side>]``
+in a file passed with ``--profiles``::
+
+    [profiles.python]
+    wrapper = ["uv", "run", "--frozen"]
+    cwd = "."                    # relative to soup.toml or the profiles file
+    env = { PYTHONWARNINGS = "error", PATH = "/opt/py/bin:${PATH}" }
+
+With the local runner, env values expand ``$VAR`` from the environment the
+matrix runs in. In containers the wrapper replaces the image's entrypoint,
+env is passed with ``-e`` and cwd, which must be absolute there, with
+``-w``."""
+
+from collections.abc import Callable
+from dataclasses import asdict, dataclass, field
+import json
+import os
+import pathlib
+import queue
+import re
+import shlex
+import socket
+import subprocess
+import threading
+import time
+import tomllib
+from typing import Any
+import uuid
+
+from tofusoup.common.exceptions import TofuSoupError
+
+SIDES = ("go", "python")
+RUNNERS = ("local", "docker", "podman")
+
+# Where each pair's artifact directory is mounted inside containers
+ARTIFACTS_MOUNT = "/artifacts"
+
+# Port the Go server listens on; every container server has its own
+# network namespace, so it can't clash
+CONTAINER_GO_PORT = 50051
+
+# go-plugin handshake line of a TCP server: CORE|APP|tcp|ADDR|grpc[|CERT]
+HANDSHAKE = re.compile(r"^\d+\|\d+\|tcp\|([^|]+)\|grpc")
+
+# Magic cookie go-plugin servers check before serving
+PLUGIN_ENV = {"PLUGIN_MAGIC_COOKIE_KEY": "BASIC_PLUGIN", "BASIC_PLUGIN": "hello"}
+
+
+@dataclass(frozen=True)
+class HarnessSide:
+    """How one harness language runs the K/V server and client."""
+
+    name: str
+    # Binary the side's container image has on PATH
+    executable: str
+    # True if the server announces its address with a handshake line
+    # rather than listening on the port it is given
+    handshake: bool
+    # Text a successful put prints on stdout
+    put_confirmation: str
+
+    def server_args(self, port: int) -> list[str]:
+        if self.handshake:
+            return ["rpc", "kv", "server", "--transport", "tcp"]
+        return ["rpc", "kv", "server", "--standalone", "--port", str(port)]
+
+    def client_args(self, op: str, key: str, value: str | None, address: str) -> list[str]:
+        args = [key] if value is None else [key, value]
+        return ["rpc", "kv", op, "--address", address, *args]
+
+
+HARNESS_SIDES = {
+    "go": HarnessSide("go", "soup-go", handshake=False, put_confirmation="put successfully"),
+    "python": HarnessSide("python", "soup", handshake=True, put_confirmation="Successfully put key"),
+}
+
+
+@dataclass(frozen=True)
+class HarnessProfile:
+    """Runtime setup for one side's server and client processes."""
+
+    env: dict[str, str] = field(default_factory=dict)
+    # Working directory as configured; relative ones are resolved against
+    # base by the local runner
+    cwd: str | None = None
+    # Command line put in front of the side's own
+    wrapper: list[str] = field(default_factory=list)
+    base: pathlib.Path = field(default=pathlib.Path("."), compare=False)
+
+    def local_cwd(self) -> str | None:
+        return str(self.base / self.cwd) if self.cwd else None
+
+    def local_env(self) -> dict[str, str]:
+        return {key: os.path.expandvars(value) for key, value in self.env.items()}
+
+    def describe(self) -> dict[str, Any]:
+        """The profile as recorded in results.json. Only the names of env
+        variables are kept, as their values may be secrets."""
+        return {"env": sorted(self.env), "cwd": self.cwd, "wrapper": self.wrapper}
+
+
+def _profile(side: str, table: Any, base: pathlib.Path) -> HarnessProfile:
+    if side not in SIDES:
+        raise TofuSoupError(f"Profile for unknown harness side '{side}' (known: {', '.join(SIDES)})")
+    if not isinstance(table, dict):
+        raise TofuSoupError(f"Profile '{side}' must be a table")
+    unknown = sorted(set(table) - {"env", "cwd", "wrapper"})
+    if unknown:
+        raise TofuSoupError(
+            f"Profile '{side}': unknown key(s) {', '.join(unknown)} (expected env, cwd, wrapper)"
+        )
+    env = table.get("env", {})
+    if not isinstance(env, dict) or not all(isinstance(v, str) for v in env.values()):
+        raise TofuSoupError(f"Profile '{side}': env must be a table of strings")
+    cwd = table.get("cwd")
+    if cwd is not None and not isinstance(cwd, str):
+        raise TofuSoupError(f"Profile '{side}': cwd must be a string")
+    wrapper = table.get("wrapper", [])
+    if isinstance(wrapper, str):
+        wrapper = shlex.split(wrapper)
+    if not isinstance(wrapper, list) or not all(isinstance(arg, str) for arg in wrapper):
+        raise TofuSoupError(f"Profile '{side}': wrapper must be a string or a list of strings")
+    return HarnessProfile(env=dict(env), cwd=cwd, wrapper=list(wrapper), base=base)
+
+
+def load_profiles(
+    soup_config: dict[str, Any] | None, project_root: pathlib.Path, config_file: pathlib.Path | None = None
+) -> dict[str, HarnessProfile]:
+    """Profiles by side: soup.toml's ``[harness.matrix.profiles]``, then
+    ``[profiles]`` from config_file, which replace them side by side.
+    Relative working directories are relative to the project root, or to
+    config_file's directory for its profiles."""
+    profiles = {}
+    if soup_config:
+        for side, table in soup_config.get("harness", {}).get("matrix", {}).get("profiles", {}).items():
+            profiles[side] = _profile(side, table, project_root)
+    if config_file is not None:
+        try:
+            with config_file.open("rb") as f:
+                data = tomllib.load(f)
+        except (OSError, tomllib.TOMLDecodeError) as e:
+            raise TofuSoupError(f"Failed to read profiles from {config_file}: {e}") from e
+        for side, table in data.get("profiles", {}).items():
+            profiles[side] = _profile(side, table, config_file.parent)
+    return profiles
+
+
+def parse_image_map(spec: str) -> dict[str, str]:
+    """Parse `go=IMAGE,python=IMAGE` into a map of side to image."""
+    images: dict[str, str] = {}
+    for entry in filter(None, (part.strip() for part in spec.split(","))):
+        side, sep, image = entry.partition("=")
+        side = side.strip()
+        if not sep or not image.strip():
+            raise TofuSoupError(f"Invalid image map entry '{entry}': expected SIDE=IMAGE")
+        if side not in SIDES:
+            raise TofuSoupError(f"Unknown harness side '{side}' in image map (known: {', '.join(SIDES)})")
+        if side in images:
+            raise TofuSoupError(f"Harness side '{side}' is mapped twice in image map")
+        images[side] = image.strip()
+    return images
+
+
+def _free_port() -> int:
+    with socket.socket() as sock:
+        sock.bind(("127.0.0.1", 0))
+        return sock.getsockname()[1]
+
+
+class LocalRunner:
+    """Runs each side as a local process, from the given command prefixes
+    and any profile for it."""
+
+    name = "local"
+
+    def __init__(
+        self, commands: dict[str, list[str]], profiles: dict[str, HarnessProfile] | None = None
+    ) -> None:
+        self.commands = commands
+        self.profiles = profiles or {}
+
+    def _command(self, side: str) -> list[str]:
+        return [*self.profiles.get(side, HarnessProfile()).wrapper, *self.commands[side]]
+
+    def describe(self, side: str) -> str:
+        return " ".join(self._command(side))
+
+    def server_port(self) -> int:
+        return _free_port()
+
+    def server(self, side: HarnessSide, port: int, pair_dir: pathlib.Path, server_id: str) -> list[str]:
+        return [*self._command(side.name), *side.server_args(port)]
+
+    def server_env(self, pair_dir: pathlib.Path) -> dict[str, str]:
+        # Absolute, since a profile may run the server in another directory
+        return {**PLUGIN_ENV, "KV_STORAGE_DIR": str((pair_dir / "kv-storage").absolute())}
+
+    def side_env(self, side: str) -> dict[str, str]:
+        return self.profiles.get(side, HarnessProfile()).local_env()
+
+    def side_cwd(self, side: str) -> str | None:
+        return self.profiles.get(side, HarnessProfile()).local_cwd()
+
+    def client(self, side: HarnessSide, args: list[str], server_id: str) -> list[str]:
+        return [*self._command(side.name), *args]
+
+    def cleanup(self, server_id: str) -> None:
+        """Nothing outlives the server process."""
+
+
+class ContainerRunner:
+    """Runs each side inside its image with a docker-compatible CLI, set up
+    by any profile for it."""
+
+    def __init__(
+        self, cli: str, images: dict[str, str], profiles: dict[str, HarnessProfile] | None = None
+    ) -> None:
+        self.name = cli
+        self.cli = cli
+        self.images = images
+        self.profiles = profiles or {}
+        for side, profile in self.profiles.items():
+            if profile.cwd and not pathlib.PurePosixPath(profile.cwd).is_absolute():
+                raise TofuSoupError(
+                    f"Profile '{side}': cwd must be absolute inside a container, not {profile.cwd!r}"
+                )
+
+    def _run_options(self, side: HarnessSide, env: dict[str, str] | None = None) -> list[str]:
+        """Options setting up side's profile, with env on top of the
+        profile's, then its entrypoint, image and the wrapper's arguments."""
+        profile = self.profiles.get(side.name, HarnessProfile())
+        merged = {**profile.env, **(env or {})}
+        options = [arg for key, value in merged.items() for arg in ("-e", f"{key}={value}")]
+        if profile.cwd:
+            options += ["-w", profile.cwd]
+        if not profile.wrapper:
+            return [*options, "--entrypoint", side.executable, self.images[side.name]]
+        entrypoint, *wrapper_args = profile.wrapper
+        return [*options, "--entrypoint", entrypoint, self.images[side.name], *wrapper_args, side.executable]
+
+    def describe(self, side: str) -> str:
+        return self.images[side]
+
+    def server_port(self) -> int:
+        return CONTAINER_GO_PORT
+
+    def server(self, side: HarnessSide, port: int, pair_dir: pathlib.Path, server_id: str) -> list[str]:
+        env = {**PLUGIN_ENV, "KV_STORAGE_DIR": f"{ARTIFACTS_MOUNT}/kv-storage"}
+        return [
+            self.cli,
+            "run",
+            "--rm",
+            "--name",
+            server_id,
+            "-v",
+            f"{pair_dir.resolve()}:{ARTIFACTS_MOUNT}",
+            *self._run_options(side, env),
+            *side.server_args(port),
+        ]
+
+    def server_env(self, pair_dir: pathlib.Path) -> dict[str, str]:
+        return {}
+
+    def side_env(self, side: str) -> dict[str, str]:
+        return {}
+
+    def side_cwd(self, side: str) -> str | None:
+        return None
+
+    def client(self, side: HarnessSide, args: list[str], server_id: str) -> list[str]:
+        return [
+            self.cli,
+            "run",
+            "--rm",
+            "--network",
+            f"container:{server_id}",
+            *self._run_options(side),
+            *args,
+        ]
+
+    def cleanup(self, server_id: str) -> None:
+        subprocess.run([self.cli, "rm", "-f", server_id], capture_output=True, check=False)
+
+
+Runner = LocalRunner | ContainerRunner
+
+
+@dataclass
+class PairResult:
+    """Outcome of one client/server pair, in the RPC matrix runner's format."""
+
+    node_id: str
+    status: str  # "passed" or "failed"
+    duration_seconds: float
+    log_file: str
+    client: str = ""
+    server: str = ""
+    error: str | None = None
+    artifacts: list[str] = field(default_factory=list)
+
+
+def _watch_server(process: subprocess.Popen, log: pathlib.Path, addresses: queue.Queue[str]) -> None:
+    """Copy the server's output to its log, reporting handshake addresses."""
+    assert process.stdout is not None
+    with log.open("w") as out:
+        for line in process.stdout:
+            out.write(line)
+            out.flush()
+            match = HANDSHAKE.match(line.strip())
+            if match:
+                addresses.put(match.group(1))
+
+
+def _log_step(log: pathlib.Path, cmd: list[str], result: subprocess.CompletedProcess) -> None:
+    with log.open("a") as out:
+        out.write(f"# {' '.join(cmd)}\n# exit {result.returncode}\n")
+        out.write(f"## stdout\n{result.stdout}\n## stderr\n{result.stderr}\n")
+
+
+def run_pair(
+    runner: Runner,
+    client: HarnessSide,
+    server: HarnessSide,
+    out_dir: pathlib.Path,
+    timeout: float,
+) -> PairResult:
+    """Start `server`, put and get a key through `client`, then stop the server."""
+    node_id = f"{client.name}-client->{server.name}-server"
+    pair_dir = out_dir / f"{client.name}-to-{server.name}"
+    pair_dir.mkdir(parents=True, exist_ok=True)
+    server_log, client_log = pair_dir / "server.log", pair_dir / "client.log"
+    server_id = f"soup-matrix-{uuid.uuid4().hex[:12]}"
+    key, value = "matrix", f"{client.name}-to-{server.name}"
+    port = runner.server_port()
+
+    start = time.monotonic()
+    deadline = start + timeout
+    error: str | None = None
+    cmd = runner.server(server, port, pair_dir, server_id)
+    process = subprocess.Popen(
+        cmd,
+        env={**os.environ, **runner.side_env(server.name), **runner.server_env(pair_dir)},
+        cwd=runner.side_cwd(server.name),
+        stdin=subprocess.DEVNULL,
+        stdout=subprocess.PIPE,
+        stderr=subprocess.STDOUT,
+        text=True,
+    )
+    addresses: queue.Queue[str] = queue.Queue()
+    watcher = threading.Thread(target=_watch_server, args=(process, server_log, addresses), daemon=True)
+    watcher.start()
+    try:
+        address = f"127.0.0.1:{port}"
+        if server.handshake:
+            try:
+                address = addresses.get(timeout=max(deadline - time.monotonic(), 0))
+            except queue.Empty:
+                raise TofuSoupError("server printed no handshake line") from None
+
+        def step(op: str, *op_value: str) -> subprocess.CompletedProcess:
+            args = client.client_args(op, key, op_value[0] if op_value else None, address)
+            step_cmd = runner.client(client, args, server_id)
+            try:
+                result = subprocess.run(
+                    step_cmd,
+                    env={**os.environ, **runner.side_env(client.name)},
+                    cwd=runner.side_cwd(client.name),
+                    capture_output=True,
+                    text=True,
+                    timeout=max(deadline - time.monotonic(), 1),
+                )
+            except subprocess.TimeoutExpired as e:
+                raise TofuSoupError(f"{op} timed out") from e
+            _log_step(client_log, step_cmd, result)
+            return result
+
+        # The first put doubles as the readiness check, since a standalone
+        # server prints nothing a client could wait for
+        while True:
+            put = step("put", value)
+            if put.returncode == 0 and client.put_confirmation in put.stdout:
+                break
+            if process.poll() is not None:
+                raise TofuSoupError(f"server exited with {process.returncode}")
+            if time.monotonic() >= deadline:
+                raise TofuSoupError("put never succeeded")
+            time.sleep(0.5)
+        get = step("get")
+        if get.returncode != 0 or value not in get.stdout.splitlines():
+            raise TofuSoupError(f"get returned {get.stdout.strip()!r}, expected {value!r}")
+    except (TofuSoupError, OSError) as e:
+        error = str(e)
+    finally:
+        runner.cleanup(server_id)
+        if process.poll() is None:
+            process.terminate()
+            try:
+                process.wait(timeout=10)
+            except subprocess.TimeoutExpired:
+                process.kill()
+                process.wait()
+        watcher.join(timeout=10)
+
+    return PairResult(
+        node_id=node_id,
+        status="failed" if error else "passed",
+        duration_seconds=time.monotonic() - start,
+        log_file=str(client_log),
+        client=runner.describe(client.name),
+        server=runner.describe(server.name),
+        error=error,
+        artifacts=sorted(str(path.relative_to(out_dir)) for path in pair_dir.rglob("*") if path.is_file()),
+    )
+
+
+def run_matrix(
+    runner: Runner,
+    out_dir: pathlib.Path,
+    clients: tuple[str, ...] = SIDES,
+    servers: tuple[str, ...] = SIDES,
+    timeout: float = 60.0,
+    progress: Callable[[PairResult], None] | None = None,
+) -> list[PairResult]:
+    """Run every client/server pair in turn."""
+    results = []
+    for client in clients:
+        for server in servers:
+            result = run_pair(runner, HARNESS_SIDES[client], HARNESS_SIDES[server], out_dir, timeout)
+            if progress is not None:
+                progress(result)
+            results.append(result)
+    return results
+
+
+def write_results(
+    results: list[PairResult],
+    runner: Runner,
+    sides: tuple[str, ...],
+    started: float,
+    wall_seconds: float,
+    out_dir: pathlib.Path,
+) -> pathlib.Path:
+    """Write results.json, which `soup harness report` records like the RPC matrix runner's."""
+    path = out_dir / "results.json"
+    document = {
+        "kind": "rpc-matrix",
+        "runner": runner.name,
+        "sides": {side: runner.describe(side) for side in sides},
+        "profiles": {side: runner.profiles[side].describe() for side in sides if side in runner.profiles},
+        "created": started,
+        "duration": wall_seconds,
+        "results": [asdict(r) for r in results],
+    }
+    path.write_text(json.dumps(document, indent=2))
+    return path
+
+
+# 🥣🔬🔚
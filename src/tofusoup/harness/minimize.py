@@ -0,0 +1,297 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""Minimizing inputs that make harnesses diverge.
+
+A divergence found in a large corpus entry is easier to report and fix
+from a small reproducer. `minimize` shrinks an input one reduction at a
+time, keeping each reduction only if the caller's check says the
+divergence is still there, until no reduction is accepted or the check
+budget runs out.
+
+JSON (cty) and msgpack (wire) inputs are reduced as trees: object
+attributes and map keys are deleted, lists lose runs of elements (all of
+them first, then halves, quarters and so on down to single elements), and
+strings and bytes are truncated to nothing, then to half their length.
+Larger reductions are tried first. HCL inputs are reduced as text so that
+every candidate still parses: whole attributes, blocks and list elements
+(spans of lines whose brackets balance) are deleted, largest first, and
+plain string literals are truncated."""
+
+from collections.abc import Callable, Iterator
+import copy
+from dataclasses import dataclass, field
+import json
+import re
+from typing import Any
+
+import msgpack
+
+KINDS = ("json", "hcl", "wire")
+
+# Case file suffixes that imply a kind
+SUFFIX_KINDS = {
+    ".json": "json",
+    ".hcl": "hcl",
+    ".tf": "hcl",
+    ".tfvars": "hcl",
+    ".msgpack": "wire",
+    ".mp": "wire",
+    ".wire": "wire",
+}
+
+
+def kind_for_suffix(suffix: str) -> str | None:
+    """The kind of input a case file's suffix implies, if any."""
+    return SUFFIX_KINDS.get(suffix.lower())
+
+
+@dataclass
+class MinimizeResult:
+    """The smallest input found and how it was reached."""
+
+    data: bytes
+    original_size: int
+    checks: int = 0
+    # Description of each reduction kept, in order
+    reductions: list[str] = field(default_factory=list)
+    # True if the check budget ran out before no reduction was accepted
+    exhausted: bool = False
+
+
+class MinimizeError(Exception):
+    """The case can't be minimized: it doesn't diverge, or can't be read."""
+
+
+def _path(path: tuple[Any, ...]) -> str:
+    """A tree path in the $.a[0] notation harness diff uses."""
+    out = "$"
+    for step in path:
+        out += f"[{step}]" if isinstance(step, int) else f".{step}"
+    return out
+
+
+def _nodes(tree: Any, path: tuple[Any, ...] = ()) -> Iterator[tuple[tuple[Any, ...], Any]]:
+    yield path, tree
+    if isinstance(tree, dict):
+        for key, value in tree.items():
+            yield from _nodes(value, (*path, key))
+    elif isinstance(tree, list):
+        for i, item in enumerate(tree):
+            yield from _nodes(item, (*path, i))
+
+
+def _replace(tree: Any, path: tuple[Any, ...], value: Any) -> Any:
+    """A copy of tree with the node at path replaced, sharing the rest."""
+    if not path:
+        return value
+    new = copy.copy(tree)
+    new[path[0]] = _replace(tree[path[0]], path[1:], value)
+    return new
+
+
+def _chunk_sizes(n: int) -> list[int]:
+    sizes = []
+    size = n
+    while size >= 1:
+        sizes.append(size)
+        size //= 2
+    return sizes
+
+
+def tree_candidates(tree: Any) -> Iterator[tuple[str, Any]]:
+    """Reductions of a decoded JSON or msgpack value, largest first."""
+    nodes = list(_nodes(tree))
+    for path, node in nodes:
+        if isinstance(node, dict):
+            for key in node:
+                reduced = {k: v for k, v in node.items() if k != key}
+                yield f"delete {_path((*path, key))}", _replace(tree, path, reduced)
+    for path, node in nodes:
+        if isinstance(node, list) and node:
+            for size in _chunk_sizes(len(node)):
+                for start in range(0, len(node), size):
+                    reduced = node[:start] + node[start + size :]
+                    yield f"delete {_path(path)}[{start}:{start + size}]", _replace(tree, path, reduced)
+    for path, node in nodes:
+        if isinstance(node, (str, bytes)) and node:
+            for length in dict.fromkeys((0, len(node) // 2)):
+                yield f"truncate {_path(path)} to {length}", _replace(tree, path, node[:length])
+
+
+def _encode_json(tree: Any) -> bytes:
+    return (json.dumps(tree, indent=2, ensure_ascii=False) + "\n").encode()
+
+
+def _decode_json(data: bytes) -> Any:
+    return json.loads(data)
+
+
+def _encode_wire(tree: Any) -> bytes:
+    return msgpack.packb(tree, use_bin_type=True)
+
+
+def _decode_wire(data: bytes) -> Any:
+    # Unknown values and other extension types stay ExtType leaves
+    return msgpack.unpackb(data, raw=False, strict_map_key=False)
+
+
+# Brackets that open and close HCL blocks, tuples, objects and calls
+_OPENERS, _CLOSERS = "{[(", "}])"
+_HEREDOC = re.compile(r"<<-?([A-Za-z_][A-Za-z0-9_]*)\s*$")
+# Quoted strings without interpolation or directives
+_PLAIN_STRING = re.compile(r'"((?:[^"\\$%\n]|\\.)*)"')
+
+
+def _line_depths(lines: list[str]) -> list[int]:
+    """The bracket depth change of each line, ignoring brackets in strings,
+    comments and heredoc bodies, which count as part of the line that
+    opened them."""
+    deltas = [0] * len(lines)
+    heredoc: str | None = None
+    for i, line in enumerate(lines):
+        if heredoc is not None:
+            if line.strip() == heredoc:
+                heredoc = None
+            continue
+        depth, quoted, escaped = 0, False, False
+        for j, ch in enumerate(line):
+            if quoted:
+                if escaped:
+                    escaped = False
+                elif ch == "\\":
+                    escaped = True
+                elif ch == '"':
+                    quoted = False
+            elif ch == '"':
+                quoted = True
+            elif ch == "#" or line.startswith("//", j):
+                break
+            elif ch in _OPENERS:
+                depth += 1
+            elif ch in _CLOSERS:
+                depth -= 1
+        deltas[i] = depth
+        if match := _HEREDOC.search(line):
+            heredoc = match.group(1)
+    return deltas
+
+
+def hcl_units(text: str) -> list[tuple[int, int]]:
+    """Line spans [start, end) that can be deleted from HCL source and leave
+    it balanced: a line and the lines it opens brackets (or a heredoc) over,
+    up to where they close."""
+    lines = text.splitlines(keepends=True)
+    deltas = _line_depths(lines)
+    # Heredoc bodies belong to the line that opened them
+    body = [False] * len(lines)
+    heredoc: str | None = None
+    for i, line in enumerate(lines):
+        if heredoc is not None:
+            body[i] = True
+            if line.strip() == heredoc:
+                heredoc = None
+        elif match := _HEREDOC.search(line):
+            heredoc = match.group(1)
+
+    units = []
+    for start, line in enumerate(lines):
+        if body[start] or not line.strip() or line.strip()[0] in _CLOSERS:
+            continue
+        depth = 0
+        for end in range(start, len(lines)):
+            depth += deltas[end]
+            if depth < 0:
+                break
+            following_body = end + 1 < len(lines) and body[end + 1]
+            if depth == 0 and not following_body:
+                units.append((start, end + 1))
+                break
+    return units
+
+
+def hcl_candidates(text: str) -> Iterator[tuple[str, str]]:
+    """Reductions of HCL source, largest first."""
+    lines = text.splitlines(keepends=True)
+    units = sorted(hcl_units(text), key=lambda span: (span[0] - span[1], span[0]))
+    for start, end in units:
+        label = f"line {start + 1}" if end - start == 1 else f"lines {start + 1}-{end}"
+        yield f"delete {label}", "".join(lines[:start] + lines[end:])
+    for match in _PLAIN_STRING.finditer(text):
+        content = match.group(1)
+        if not content:
+            continue
+        line = text.count("\n", 0, match.start()) + 1
+        for length in dict.fromkeys((0, len(content) // 2)):
+            kept = content[:length]
+            if kept.endswith("\\") and not kept.endswith("\\\\"):
+                kept = kept[:-1]
+            reduced = text[: match.start(1)] + kept + text[match.end(1) :]
+            yield f"truncate string on line {line} to {len(kept)}", reduced
+
+
+def minimize(
+    data: bytes,
+    kind: str,
+    diverges: Callable[[bytes], bool],
+    max_checks: int = 500,
+    on_reduction: Callable[[str, int], None] | None = None,
+) -> MinimizeResult:
+    """Shrink data while diverges(candidate) stays true.
+
+    The input is re-encoded first (JSON is re-indented, for one) and must
+    still diverge. on_reduction is called with each reduction kept and the
+    new size. Raises MinimizeError if the input can't be decoded or doesn't
+    diverge."""
+    if kind not in KINDS:
+        raise MinimizeError(f"unknown kind {kind!r}; expected one of {', '.join(KINDS)}")
+    result = MinimizeResult(data=data, original_size=len(data))
+
+    if kind == "hcl":
+        try:
+            current: Any = data.decode()
+        except UnicodeDecodeError as e:
+            raise MinimizeError(f"HCL case is not UTF-8: {e}") from e
+        encode: Callable[[Any], bytes] = str.encode
+        candidates: Callable[[Any], Iterator[tuple[str, Any]]] = hcl_candidates
+    else:
+        decode, encode = (_decode_json, _encode_json) if kind == "json" else (_decode_wire, _encode_wire)
+        try:
+            current = decode(data)
+        except ValueError as e:
+            raise MinimizeError(f"can't decode {kind} case: {e}") from e
+        candidates = tree_candidates
+
+    def check(candidate: bytes) -> bool:
+        result.checks += 1
+        return diverges(candidate)
+
+    if not check(data):
+        raise MinimizeError("the case doesn't diverge")
+    encoded = encode(current)
+    if encoded != data and not check(encoded):
+        raise MinimizeError(f"the case doesn't diverge once re-encoded as {kind}")
+    result.data = encoded
+
+    progress = True
+    while progress:
+        progress = False
+        for description, candidate in candidates(current):
+            if result.checks >= max_checks:
+                result.exhausted = True
+                return result
+            encoded = encode(candidate)
+            if len(encoded) >= len(result.data) or not check(encoded):
+                continue
+            current, result.data = candidate, encoded
+            result.reductions.append(description)
+            if on_reduction is not None:
+                on_reduction(description, len(encoded))
+            progress = True
+            break
+    return result
+
+
+# 🥣🔬🔚
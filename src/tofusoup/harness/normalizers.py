@@ -0,0 +1,204 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""Output normalizers applied before comparing harness output.
+
+Go, Python and other harnesses often agree on a value but not on how they
+print it: key order, float spelling, log timestamps, the go-plugin handshake
+line. A normalizer rewrites text so those differences disappear, and a
+pipeline applies several in order. Built in:
+
+- ``sort-keys``: re-serialise JSON (a whole document or JSON lines) with
+  sorted keys and two-space indentation
+- ``float-format``: spell floats one way (integral values without a fraction,
+  others to 15 significant digits)
+- ``strip-timestamps``: replace ISO 8601 timestamps and log clock times with
+  ``<timestamp>``
+- ``strip-handshake``: drop go-plugin handshake lines (``1|6|tcp|...|grpc``)
+
+Custom normalizers are regular-expression substitutions or Python callables
+declared in TOML, either under ``[harness.normalizers.<name>]`` in soup.toml
+or under ``[normalizers.<name>]`` in a file passed with ``--normalizers``::
+
+    [normalizers.strip-pids]
+    pattern = "pid=\\\\d+"
+    replacement = "pid=<pid>"
+    flags = ["multiline"]
+
+    [normalizers.my-fixups]
+    function = "mypackage.normalize:fixups"
+
+A custom normalizer with a built-in's name replaces it."""
+
+from collections.abc import Callable
+import importlib
+import json
+import pathlib
+import re
+import tomllib
+from typing import Any
+
+from tofusoup.common.exceptions import TofuSoupError
+
+Normalizer = Callable[[str], str]
+
+# Significant digits kept by float-format; enough to hide last-bit
+# differences between float printers without merging distinct values
+FLOAT_DIGITS = 15
+
+FLOAT_PATTERN = re.compile(r"(?<![\w.])-?(?:\d+\.\d+(?:[eE][+-]?\d+)?|\d+[eE][+-]?\d+)(?![\w.])")
+
+TIMESTAMP_PATTERN = re.compile(
+    r"\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(?:[.,]\d+)?(?:Z|[+-]\d{2}:?\d{2})?"
+    r"|\b\d{2}:\d{2}:\d{2}\.\d{3,9}\b"
+)
+
+HANDSHAKE_PATTERN = re.compile(r"^\d+\|\d+\|(?:tcp|unix)\|\S+\|(?:grpc|netrpc)(?:\|\S*)?$")
+
+REGEX_FLAGS = {
+    "ignorecase": re.IGNORECASE,
+    "multiline": re.MULTILINE,
+    "dotall": re.DOTALL,
+}
+
+
+def _format_float(value: float) -> str:
+    if value.is_integer() and abs(value) < 10**FLOAT_DIGITS:
+        return str(int(value))
+    return format(value, f".{FLOAT_DIGITS}g")
+
+
+def _map_json(text: str, transform: Callable[[Any], str]) -> str | None:
+    """Apply transform to text as a JSON document, or line by line as JSON
+    lines. Returns None if the text is neither."""
+    try:
+        return transform(json.loads(text)) + ("\n" if text.endswith("\n") else "")
+    except ValueError:
+        pass
+    lines = text.splitlines()
+    if not any(line.strip() for line in lines):
+        return None
+    out = []
+    for line in lines:
+        if not line.strip():
+            out.append(line)
+            continue
+        try:
+            out.append(transform(json.loads(line)))
+        except ValueError:
+            return None
+    return "\n".join(out) + ("\n" if text.endswith("\n") else "")
+
+
+def sort_keys(text: str) -> str:
+    """Re-serialise JSON with sorted keys; non-JSON text is left alone."""
+    result = _map_json(text, lambda doc: json.dumps(doc, sort_keys=True, indent=2, ensure_ascii=False))
+    return text if result is None else result
+
+
+def float_format(text: str) -> str:
+    """Spell every float literal the same way."""
+    return FLOAT_PATTERN.sub(lambda m: _format_float(float(m.group())), text)
+
+
+def strip_timestamps(text: str) -> str:
+    """Replace timestamps with a placeholder."""
+    return TIMESTAMP_PATTERN.sub("<timestamp>", text)
+
+
+def strip_handshake(text: str) -> str:
+    """Drop go-plugin handshake lines."""
+    lines = text.splitlines(keepends=True)
+    return "".join(line for line in lines if not HANDSHAKE_PATTERN.match(line.strip()))
+
+
+BUILTIN_NORMALIZERS: dict[str, Normalizer] = {
+    "sort-keys": sort_keys,
+    "float-format": float_format,
+    "strip-timestamps": strip_timestamps,
+    "strip-handshake": strip_handshake,
+}
+
+
+def _regex_normalizer(name: str, spec: dict[str, Any]) -> Normalizer:
+    flags = 0
+    for flag in spec.get("flags", []):
+        if flag not in REGEX_FLAGS:
+            raise TofuSoupError(
+                f"Normalizer '{name}': unknown flag '{flag}' (expected one of: {', '.join(REGEX_FLAGS)})"
+            )
+        flags |= REGEX_FLAGS[flag]
+    try:
+        pattern = re.compile(spec["pattern"], flags)
+    except re.error as e:
+        raise TofuSoupError(f"Normalizer '{name}': invalid pattern: {e}") from e
+    replacement = spec.get("replacement", "")
+    return lambda text: pattern.sub(replacement, text)
+
+
+def _function_normalizer(name: str, target: str) -> Normalizer:
+    module_name, _, attr = target.partition(":")
+    if not attr:
+        raise TofuSoupError(f"Normalizer '{name}': function must be 'module:attribute', got '{target}'")
+    try:
+        func = getattr(importlib.import_module(module_name), attr)
+    except (ImportError, AttributeError) as e:
+        raise TofuSoupError(f"Normalizer '{name}': cannot load {target}: {e}") from e
+    if not callable(func):
+        raise TofuSoupError(f"Normalizer '{name}': {target} is not callable")
+    return func  # type: ignore[no-any-return]
+
+
+def custom_normalizers(table: dict[str, Any]) -> dict[str, Normalizer]:
+    """Build normalizers from a ``normalizers`` TOML table."""
+    normalizers: dict[str, Normalizer] = {}
+    for name, spec in table.items():
+        if not isinstance(spec, dict):
+            raise TofuSoupError(f"Normalizer '{name}' must be a table")
+        if "pattern" in spec:
+            normalizers[name] = _regex_normalizer(name, spec)
+        elif "function" in spec:
+            normalizers[name] = _function_normalizer(name, spec["function"])
+        else:
+            raise TofuSoupError(f"Normalizer '{name}' needs a 'pattern' or a 'function'")
+    return normalizers
+
+
+def load_normalizers(
+    soup_config: dict[str, Any] | None = None, config_file: pathlib.Path | None = None
+) -> dict[str, Normalizer]:
+    """All available normalizers: built-ins, then soup.toml's
+    ``[harness.normalizers]``, then ``[normalizers]`` from config_file."""
+    normalizers = dict(BUILTIN_NORMALIZERS)
+    if soup_config:
+        normalizers.update(custom_normalizers(soup_config.get("harness", {}).get("normalizers", {})))
+    if config_file is not None:
+        try:
+            with config_file.open("rb") as f:
+                data = tomllib.load(f)
+        except (OSError, tomllib.TOMLDecodeError) as e:
+            raise TofuSoupError(f"Failed to read normalizers from {config_file}: {e}") from e
+        normalizers.update(custom_normalizers(data.get("normalizers", {})))
+    return normalizers
+
+
+def build_pipeline(names: list[str], available: dict[str, Normalizer]) -> list[tuple[str, Normalizer]]:
+    """Look up normalizers by name, in the order given."""
+    unknown = [name for name in names if name not in available]
+    if unknown:
+        raise TofuSoupError(
+            f"Unknown normalizer(s): {', '.join(unknown)} (available: {', '.join(sorted(available))})"
+        )
+    return [(name, available[name]) for name in names]
+
+
+def normalize(text: str, pipeline: list[tuple[str, Normalizer]]) -> str:
+    """Apply each normalizer in the pipeline in turn."""
+    for _, normalizer in pipeline:
+        text = normalizer(text)
+    return text
+
+
+# 🥣🔬🔚
@@ -0,0 +1,177 @@
+//
+// tofusoup/harness/proto/counter/counter_grpc.pb.go
+//
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: proto/counter.proto
+
+package counter
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	Counter_Increment_FullMethodName = "/counter.Counter/Increment"
+	Counter_Subscribe_FullMethodName = "/counter.Counter/Subscribe"
+)
+
+// CounterClient is the client API for Counter service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type CounterClient interface {
+	Increment(ctx context.Context, in *IncrementRequest, opts ...grpc.CallOption) (*IncrementResponse, error)
+	Subscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (Counter_SubscribeClient, error)
+}
+
+type counterClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewCounterClient(cc grpc.ClientConnInterface) CounterClient {
+	return &counterClient{cc}
+}
+
+func (c *counterClient) Increment(ctx context.Context, in *IncrementRequest, opts ...grpc.CallOption) (*IncrementResponse, error) {
+	out := new(IncrementResponse)
+	err := c.cc.Invoke(ctx, Counter_Increment_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *counterClient) Subscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (Counter_SubscribeClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Counter_ServiceDesc.Streams[0], Counter_Subscribe_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &counterSubscribeClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Counter_SubscribeClient interface {
+	Recv() (*CounterEvent, error)
+	grpc.ClientStream
+}
+
+type counterSubscribeClient struct {
+	grpc.ClientStream
+}
+
+func (x *counterSubscribeClient) Recv() (*CounterEvent, error) {
+	m := new(CounterEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// CounterServer is the server API for Counter service.
+// All implementations should embed UnimplementedCounterServer
+// for forward compatibility
+type CounterServer interface {
+	Increment(context.Context, *IncrementRequest) (*IncrementResponse, error)
+	Subscribe(*SubscribeRequest, Counter_SubscribeServer) error
+}
+
+// UnimplementedCounterServer should be embedded to have forward compatible implementations.
+type UnimplementedCounterServer struct {
+}
+
+func (UnimplementedCounterServer) Increment(context.Context, *IncrementRequest) (*IncrementResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Increment not implemented")
+}
+func (UnimplementedCounterServer) Subscribe(*SubscribeRequest, Counter_SubscribeServer) error {
+	return status.Errorf(codes.Unimplemented, "method Subscribe not implemented")
+}
+
+// UnsafeCounterServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to CounterServer will
+// result in compilation errors.
+type UnsafeCounterServer interface {
+	mustEmbedUnimplementedCounterServer()
+}
+
+func RegisterCounterServer(s grpc.ServiceRegistrar, srv CounterServer) {
+	s.RegisterService(&Counter_ServiceDesc, srv)
+}
+
+func _Counter_Increment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IncrementRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CounterServer).Increment(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Counter_Increment_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CounterServer).Increment(ctx, req.(*IncrementRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Counter_Subscribe_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CounterServer).Subscribe(m, &counterSubscribeServer{stream})
+}
+
+type Counter_SubscribeServer interface {
+	Send(*CounterEvent) error
+	grpc.ServerStream
+}
+
+type counterSubscribeServer struct {
+	grpc.ServerStream
+}
+
+func (x *counterSubscribeServer) Send(m *CounterEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// Counter_ServiceDesc is the grpc.ServiceDesc for Counter service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Counter_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "counter.Counter",
+	HandlerType: (*CounterServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Increment",
+			Handler:    _Counter_Increment_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Subscribe",
+			Handler:       _Counter_Subscribe_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "proto/counter.proto",
+}
+
+// 🍲🥄📄🪄
@@ -0,0 +1,182 @@
+//
+// tofusoup/harness/proto/echo/echo_grpc.pb.go
+//
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: proto/echo.proto
+
+package echo
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	Echo_Chat_FullMethodName          = "/echo.Echo/Chat"
+	Echo_GetServerInfo_FullMethodName = "/echo.Echo/GetServerInfo"
+)
+
+// EchoClient is the client API for Echo service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type EchoClient interface {
+	Chat(ctx context.Context, opts ...grpc.CallOption) (Echo_ChatClient, error)
+	GetServerInfo(ctx context.Context, in *ServerInfoRequest, opts ...grpc.CallOption) (*ServerInfo, error)
+}
+
+type echoClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewEchoClient(cc grpc.ClientConnInterface) EchoClient {
+	return &echoClient{cc}
+}
+
+func (c *echoClient) Chat(ctx context.Context, opts ...grpc.CallOption) (Echo_ChatClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Echo_ServiceDesc.Streams[0], Echo_Chat_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &echoChatClient{stream}
+	return x, nil
+}
+
+type Echo_ChatClient interface {
+	Send(*ChatFrame) error
+	Recv() (*ChatEcho, error)
+	grpc.ClientStream
+}
+
+type echoChatClient struct {
+	grpc.ClientStream
+}
+
+func (x *echoChatClient) Send(m *ChatFrame) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *echoChatClient) Recv() (*ChatEcho, error) {
+	m := new(ChatEcho)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *echoClient) GetServerInfo(ctx context.Context, in *ServerInfoRequest, opts ...grpc.CallOption) (*ServerInfo, error) {
+	out := new(ServerInfo)
+	err := c.cc.Invoke(ctx, Echo_GetServerInfo_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// EchoServer is the server API for Echo service.
+// All implementations should embed UnimplementedEchoServer
+// for forward compatibility
+type EchoServer interface {
+	Chat(Echo_ChatServer) error
+	GetServerInfo(context.Context, *ServerInfoRequest) (*ServerInfo, error)
+}
+
+// UnimplementedEchoServer should be embedded to have forward compatible implementations.
+type UnimplementedEchoServer struct {
+}
+
+func (UnimplementedEchoServer) Chat(Echo_ChatServer) error {
+	return status.Errorf(codes.Unimplemented, "method Chat not implemented")
+}
+func (UnimplementedEchoServer) GetServerInfo(context.Context, *ServerInfoRequest) (*ServerInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetServerInfo not implemented")
+}
+
+// UnsafeEchoServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to EchoServer will
+// result in compilation errors.
+type UnsafeEchoServer interface {
+	mustEmbedUnimplementedEchoServer()
+}
+
+func RegisterEchoServer(s grpc.ServiceRegistrar, srv EchoServer) {
+	s.RegisterService(&Echo_ServiceDesc, srv)
+}
+
+func _Echo_Chat_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(EchoServer).Chat(&echoChatServer{stream})
+}
+
+type Echo_ChatServer interface {
+	Send(*ChatEcho) error
+	Recv() (*ChatFrame, error)
+	grpc.ServerStream
+}
+
+type echoChatServer struct {
+	grpc.ServerStream
+}
+
+func (x *echoChatServer) Send(m *ChatEcho) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *echoChatServer) Recv() (*ChatFrame, error) {
+	m := new(ChatFrame)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _Echo_GetServerInfo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ServerInfoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EchoServer).GetServerInfo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Echo_GetServerInfo_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EchoServer).GetServerInfo(ctx, req.(*ServerInfoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Echo_ServiceDesc is the grpc.ServiceDesc for Echo service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Echo_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "echo.Echo",
+	HandlerType: (*EchoServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetServerInfo",
+			Handler:    _Echo_GetServerInfo_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Chat",
+			Handler:       _Echo_Chat_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "proto/echo.proto",
+}
+
+// 🍲🥄📄🪄
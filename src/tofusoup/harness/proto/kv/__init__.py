@@ -4,9 +4,9 @@
 #
 
 
-from . import kv_pb2, kv_pb2_grpc
+from . import kv_pb2, kv_pb2_grpc, kv_v2_pb2
 from .kv_protocol import KVProtocol
 
-__all__ = ["KVProtocol", "kv_pb2", "kv_pb2_grpc"]
+__all__ = ["KVProtocol", "kv_pb2", "kv_pb2_grpc", "kv_v2_pb2"]
 
 # 🥣🔬🔚
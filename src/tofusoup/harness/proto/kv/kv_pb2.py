@@ -20,7 +20,7 @@ _sym_db = _symbol_database.Default()
 
 
 DESCRIPTOR = _descriptor_pool.Default().AddSerializedFile(
-    b'\n\x08kv.proto\x12\x05proto"\x19\n\nGetRequest\x12\x0b\n\x03key\x18\x01 \x01(\t"\x1c\n\x0bGetResponse\x12\r\n\x05value\x18\x01 \x01(\x0c"(\n\nPutRequest\x12\x0b\n\x03key\x18\x01 \x01(\t\x12\r\n\x05value\x18\x02 \x01(\x0c"\x07\n\x05\x45mpty2Z\n\x02KV\x12,\n\x03Get\x12\x11.proto.GetRequest\x1a\x12.proto.GetResponse\x12&\n\x03Put\x12\x11.proto.PutRequest\x1a\x0c.proto.EmptyB\tZ\x07./protob\x06proto3'
+    b'\n\x08kv.proto\x12\x05proto"F\n\nGetRequest\x12\x0b\n\x03key\x18\x01 \x01(\t\x12\x0c\n\x04wait\x18\x03 \x01(\x08\x12\x17\n\x0fwait_timeout_ms\x18\x04 \x01(\x03J\x04\x08\x02\x10\x03"\x1c\n\x0bGetResponse\x12\r\n\x05value\x18\x01 \x01(\x0c"I\n\nPutRequest\x12\x0b\n\x03key\x18\x01 \x01(\t\x12\r\n\x05value\x18\x02 \x01(\x0c\x12\x19\n\x11idempotency_token\x18\x06 \x01(\tJ\x04\x08\x03\x10\x06"3\n\x10GetStreamRequest\x12\x0b\n\x03key\x18\x01 \x01(\t\x12\x12\n\nchunk_size\x18\x02 \x01(\x05"<\n\x08GetChunk\x12\x0c\n\x04\x64\x61ta\x18\x01 \x01(\x0c\x12\x0e\n\x06offset\x18\x02 \x01(\x03\x12\x12\n\ntotal_size\x18\x03 \x01(\x03"\x07\n\x05\x45mpty"D\n\x0bListRequest\x12\x0e\n\x06prefix\x18\x01 \x01(\t\x12\x11\n\tpage_size\x18\x02 \x01(\x05\x12\x12\n\npage_token\x18\x03 \x01(\t"5\n\x0cListResponse\x12\x0c\n\x04keys\x18\x01 \x03(\t\x12\x17\n\x0fnext_page_token\x18\x02 \x01(\t"!\n\x0e\x43ompactRequest\x12\x0f\n\x07\x64ry_run\x18\x01 \x01(\x08"\xbb\x01\n\x0f\x43ompactResponse\x12\x14\n\x0cscanned_keys\x18\x01 \x01(\x03\x12\x14\n\x0cremoved_keys\x18\x02 \x03(\t\x12\x17\n\x0freclaimed_bytes\x18\x03 \x01(\x03\x12\x11\n\tlive_keys\x18\x04 \x01(\x03\x12\x12\n\nlive_bytes\x18\x05 \x01(\x03\x12\x14\n\x0c\x65victed_keys\x18\x06 \x01(\x03\x12\x15\n\revicted_bytes\x18\x07 \x01(\x03\x12\x0f\n\x07\x64ry_run\x18\x08 \x01(\x08"\x1d\n\x0bStatRequest\x12\x0e\n\x06prefix\x18\x01 \x01(\t"\xa6\x01\n\x07KeyStat\x12\x0b\n\x03key\x18\x01 \x01(\t\x12\x0e\n\x06stored\x18\x02 \x01(\x08\x12\x0c\n\x04size\x18\x03 \x01(\x03\x12\x0e\n\x06sha256\x18\x04 \x01(\t\x12\x0c\n\x04puts\x18\x05 \x01(\x03\x12\x0c\n\x04gets\x18\x06 \x01(\x03\x12\x15\n\rrejected_puts\x18\x07 \x01(\x03\x12\x15\n\rrequest_bytes\x18\x08 \x01(\x03\x12\x16\n\x0eresponse_bytes\x18\t \x01(\x03"\x8a\x01\n\x0cStatResponse\x12\x1c\n\x04keys\x18\x01 \x03(\x0b\x32\x0e.proto.KeyStat\x12\x17\n\x0fmax_value_bytes\x18\x02 \x01(\x03\x12\x14\n\x0cstored_bytes\x18\x03 \x01(\x03\x12\x15\n\rrequest_bytes\x18\x04 \x01(\x03\x12\x16\n\x0eresponse_bytes\x18\x05 \x01(\x03"8\n\x07KVError\x12 \n\x04\x63ode\x18\x01 \x01(\x0e\x32\x12.proto.KVErrorCode\x12\x0b\n\x03key\x18\x02 \x01(\t*\xa3\x01\n\x0bKVErrorCode\x12\x1d\n\x19KV_ERROR_CODE_UNSPECIFIED\x10\x00\x12\r\n\tNOT_FOUND\x10\x01\x12\x0f\n\x0bINVALID_KEY\x10\x02\x12\r\n\tTOO_LARGE\x10\x03\x12\x0c\n\x08READONLY\x10\x04\x12\r\n\tTHROTTLED\x10\x05\x12\x10\n\x0cWAIT_TIMEOUT\x10\x06\x12\x17\n\x13OVER_MEMORY_CEILING\x10\x07\x32\xaf\x02\n\x02KV\x12,\n\x03Get\x12\x11.proto.GetRequest\x1a\x12.proto.GetResponse\x12\x37\n\tGetStream\x12\x17.proto.GetStreamRequest\x1a\x0f.proto.GetChunk0\x01\x12&\n\x03Put\x12\x11.proto.PutRequest\x1a\x0c.proto.Empty\x12/\n\x04List\x12\x12.proto.ListRequest\x1a\x13.proto.ListResponse\x12\x38\n\x07\x43ompact\x12\x15.proto.CompactRequest\x1a\x16.proto.CompactResponse\x12/\n\x04Stat\x12\x12.proto.StatRequest\x1a\x13.proto.StatResponseB\tZ\x07./protob\x06proto3'
 )
 
 _globals = globals()
@@ -29,16 +29,38 @@ _builder.BuildTopDescriptorsAndMessages(DESCRIPTOR, "kv_pb2", _globals)
 if not _descriptor._USE_C_DESCRIPTORS:
     _globals["DESCRIPTOR"]._loaded_options = None
     _globals["DESCRIPTOR"]._serialized_options = b"Z\007./proto"
+    _globals["_KVERRORCODE"]._serialized_start = 1070
+    _globals["_KVERRORCODE"]._serialized_end = 1233
     _globals["_GETREQUEST"]._serialized_start = 19
-    _globals["_GETREQUEST"]._serialized_end = 44
-    _globals["_GETRESPONSE"]._serialized_start = 46
-    _globals["_GETRESPONSE"]._serialized_end = 74
-    _globals["_PUTREQUEST"]._serialized_start = 76
-    _globals["_PUTREQUEST"]._serialized_end = 116
-    _globals["_EMPTY"]._serialized_start = 118
-    _globals["_EMPTY"]._serialized_end = 125
-    _globals["_KV"]._serialized_start = 127
-    _globals["_KV"]._serialized_end = 217
+    _globals["_GETREQUEST"]._serialized_end = 89
+    _globals["_GETRESPONSE"]._serialized_start = 91
+    _globals["_GETRESPONSE"]._serialized_end = 119
+    _globals["_PUTREQUEST"]._serialized_start = 121
+    _globals["_PUTREQUEST"]._serialized_end = 194
+    _globals["_GETSTREAMREQUEST"]._serialized_start = 196
+    _globals["_GETSTREAMREQUEST"]._serialized_end = 247
+    _globals["_GETCHUNK"]._serialized_start = 249
+    _globals["_GETCHUNK"]._serialized_end = 309
+    _globals["_EMPTY"]._serialized_start = 311
+    _globals["_EMPTY"]._serialized_end = 318
+    _globals["_LISTREQUEST"]._serialized_start = 320
+    _globals["_LISTREQUEST"]._serialized_end = 388
+    _globals["_LISTRESPONSE"]._serialized_start = 390
+    _globals["_LISTRESPONSE"]._serialized_end = 443
+    _globals["_COMPACTREQUEST"]._serialized_start = 445
+    _globals["_COMPACTREQUEST"]._serialized_end = 478
+    _globals["_COMPACTRESPONSE"]._serialized_start = 481
+    _globals["_COMPACTRESPONSE"]._serialized_end = 668
+    _globals["_STATREQUEST"]._serialized_start = 670
+    _globals["_STATREQUEST"]._serialized_end = 699
+    _globals["_KEYSTAT"]._serialized_start = 702
+    _globals["_KEYSTAT"]._serialized_end = 868
+    _globals["_STATRESPONSE"]._serialized_start = 871
+    _globals["_STATRESPONSE"]._serialized_end = 1009
+    _globals["_KVERROR"]._serialized_start = 1011
+    _globals["_KVERROR"]._serialized_end = 1067
+    _globals["_KV"]._serialized_start = 1236
+    _globals["_KV"]._serialized_end = 1539
 # @@protoc_insertion_point(module_scope)
 
 # 🥣🔬🔚
@@ -1,14 +1,40 @@
-from typing import ClassVar as _ClassVar
+from collections.abc import Iterable as _Iterable, Mapping as _Mapping
+from typing import ClassVar as _ClassVar, Union as _Union
 
 from google.protobuf import descriptor as _descriptor, message as _message
+from google.protobuf.internal import containers as _containers, enum_type_wrapper as _enum_type_wrapper
 
 DESCRIPTOR: _descriptor.FileDescriptor
 
+class KVErrorCode(int, metaclass=_enum_type_wrapper.EnumTypeWrapper):
+    __slots__ = ()
+    KV_ERROR_CODE_UNSPECIFIED: _ClassVar[KVErrorCode]
+    NOT_FOUND: _ClassVar[KVErrorCode]
+    INVALID_KEY: _ClassVar[KVErrorCode]
+    TOO_LARGE: _ClassVar[KVErrorCode]
+    READONLY: _ClassVar[KVErrorCode]
+    THROTTLED: _ClassVar[KVErrorCode]
+    WAIT_TIMEOUT: _ClassVar[KVErrorCode]
+    OVER_MEMORY_CEILING: _ClassVar[KVErrorCode]
+
+KV_ERROR_CODE_UNSPECIFIED: KVErrorCode
+NOT_FOUND: KVErrorCode
+INVALID_KEY: KVErrorCode
+TOO_LARGE: KVErrorCode
+READONLY: KVErrorCode
+THROTTLED: KVErrorCode
+WAIT_TIMEOUT: KVErrorCode
+OVER_MEMORY_CEILING: KVErrorCode
+
 class GetRequest(_message.Message):
-    __slots__ = ("key",)
+    __slots__ = ("key", "wait", "wait_timeout_ms")
     KEY_FIELD_NUMBER: _ClassVar[int]
+    WAIT_FIELD_NUMBER: _ClassVar[int]
+    WAIT_TIMEOUT_MS_FIELD_NUMBER: _ClassVar[int]
     key: str
-    def __init__(self, key: str | None = ...) -> None: ...
+    wait: bool
+    wait_timeout_ms: int
+    def __init__(self, key: str | None = ..., wait: bool | None = ..., wait_timeout_ms: int | None = ...) -> None: ...
 
 class GetResponse(_message.Message):
     __slots__ = ("value",)
@@ -17,13 +43,174 @@ class GetResponse(_message.Message):
     def __init__(self, value: bytes | None = ...) -> None: ...
 
 class PutRequest(_message.Message):
-    __slots__ = ("key", "value")
+    __slots__ = ("idempotency_token", "key", "value")
     KEY_FIELD_NUMBER: _ClassVar[int]
     VALUE_FIELD_NUMBER: _ClassVar[int]
+    IDEMPOTENCY_TOKEN_FIELD_NUMBER: _ClassVar[int]
     key: str
     value: bytes
-    def __init__(self, key: str | None = ..., value: bytes | None = ...) -> None: ...
+    idempotency_token: str
+    def __init__(self, key: str | None = ..., value: bytes | None = ..., idempotency_token: str | None = ...) -> None: ...
+
+class GetStreamRequest(_message.Message):
+    __slots__ = ("chunk_size", "key")
+    KEY_FIELD_NUMBER: _ClassVar[int]
+    CHUNK_SIZE_FIELD_NUMBER: _ClassVar[int]
+    key: str
+    chunk_size: int
+    def __init__(self, key: str | None = ..., chunk_size: int | None = ...) -> None: ...
+
+class GetChunk(_message.Message):
+    __slots__ = ("data", "offset", "total_size")
+    DATA_FIELD_NUMBER: _ClassVar[int]
+    OFFSET_FIELD_NUMBER: _ClassVar[int]
+    TOTAL_SIZE_FIELD_NUMBER: _ClassVar[int]
+    data: bytes
+    offset: int
+    total_size: int
+    def __init__(self, data: bytes | None = ..., offset: int | None = ..., total_size: int | None = ...) -> None: ...
 
 class Empty(_message.Message):
     __slots__ = ()
     def __init__(self) -> None: ...
+
+class ListRequest(_message.Message):
+    __slots__ = ("page_size", "page_token", "prefix")
+    PREFIX_FIELD_NUMBER: _ClassVar[int]
+    PAGE_SIZE_FIELD_NUMBER: _ClassVar[int]
+    PAGE_TOKEN_FIELD_NUMBER: _ClassVar[int]
+    prefix: str
+    page_size: int
+    page_token: str
+    def __init__(self, prefix: str | None = ..., page_size: int | None = ..., page_token: str | None = ...) -> None: ...
+
+class ListResponse(_message.Message):
+    __slots__ = ("keys", "next_page_token")
+    KEYS_FIELD_NUMBER: _ClassVar[int]
+    NEXT_PAGE_TOKEN_FIELD_NUMBER: _ClassVar[int]
+    keys: _containers.RepeatedScalarFieldContainer[str]
+    next_page_token: str
+    def __init__(self, keys: _Iterable[str] | None = ..., next_page_token: str | None = ...) -> None: ...
+
+class CompactRequest(_message.Message):
+    __slots__ = ("dry_run",)
+    DRY_RUN_FIELD_NUMBER: _ClassVar[int]
+    dry_run: bool
+    def __init__(self, dry_run: bool | None = ...) -> None: ...
+
+class CompactResponse(_message.Message):
+    __slots__ = (
+        "dry_run",
+        "evicted_bytes",
+        "evicted_keys",
+        "live_bytes",
+        "live_keys",
+        "reclaimed_bytes",
+        "removed_keys",
+        "scanned_keys",
+    )
+    SCANNED_KEYS_FIELD_NUMBER: _ClassVar[int]
+    REMOVED_KEYS_FIELD_NUMBER: _ClassVar[int]
+    RECLAIMED_BYTES_FIELD_NUMBER: _ClassVar[int]
+    LIVE_KEYS_FIELD_NUMBER: _ClassVar[int]
+    LIVE_BYTES_FIELD_NUMBER: _ClassVar[int]
+    EVICTED_KEYS_FIELD_NUMBER: _ClassVar[int]
+    EVICTED_BYTES_FIELD_NUMBER: _ClassVar[int]
+    DRY_RUN_FIELD_NUMBER: _ClassVar[int]
+    scanned_keys: int
+    removed_keys: _containers.RepeatedScalarFieldContainer[str]
+    reclaimed_bytes: int
+    live_keys: int
+    live_bytes: int
+    evicted_keys: int
+    evicted_bytes: int
+    dry_run: bool
+    def __init__(
+        self,
+        scanned_keys: int | None = ...,
+        removed_keys: _Iterable[str] | None = ...,
+        reclaimed_bytes: int | None = ...,
+        live_keys: int | None = ...,
+        live_bytes: int | None = ...,
+        evicted_keys: int | None = ...,
+        evicted_bytes: int | None = ...,
+        dry_run: bool | None = ...,
+    ) -> None: ...
+
+class StatRequest(_message.Message):
+    __slots__ = ("prefix",)
+    PREFIX_FIELD_NUMBER: _ClassVar[int]
+    prefix: str
+    def __init__(self, prefix: str | None = ...) -> None: ...
+
+class KeyStat(_message.Message):
+    __slots__ = (
+        "gets",
+        "key",
+        "puts",
+        "rejected_puts",
+        "request_bytes",
+        "response_bytes",
+        "sha256",
+        "size",
+        "stored",
+    )
+    KEY_FIELD_NUMBER: _ClassVar[int]
+    STORED_FIELD_NUMBER: _ClassVar[int]
+    SIZE_FIELD_NUMBER: _ClassVar[int]
+    SHA256_FIELD_NUMBER: _ClassVar[int]
+    PUTS_FIELD_NUMBER: _ClassVar[int]
+    GETS_FIELD_NUMBER: _ClassVar[int]
+    REJECTED_PUTS_FIELD_NUMBER: _ClassVar[int]
+    REQUEST_BYTES_FIELD_NUMBER: _ClassVar[int]
+    RESPONSE_BYTES_FIELD_NUMBER: _ClassVar[int]
+    key: str
+    stored: bool
+    size: int
+    sha256: str
+    puts: int
+    gets: int
+    rejected_puts: int
+    request_bytes: int
+    response_bytes: int
+    def __init__(
+        self,
+        key: str | None = ...,
+        stored: bool | None = ...,
+        size: int | None = ...,
+        sha256: str | None = ...,
+        puts: int | None = ...,
+        gets: int | None = ...,
+        rejected_puts: int | None = ...,
+        request_bytes: int | None = ...,
+        response_bytes: int | None = ...,
+    ) -> None: ...
+
+class StatResponse(_message.Message):
+    __slots__ = ("keys", "max_value_bytes", "request_bytes", "response_bytes", "stored_bytes")
+    KEYS_FIELD_NUMBER: _ClassVar[int]
+    MAX_VALUE_BYTES_FIELD_NUMBER: _ClassVar[int]
+    STORED_BYTES_FIELD_NUMBER: _ClassVar[int]
+    REQUEST_BYTES_FIELD_NUMBER: _ClassVar[int]
+    RESPONSE_BYTES_FIELD_NUMBER: _ClassVar[int]
+    keys: _containers.RepeatedCompositeFieldContainer[KeyStat]
+    max_value_bytes: int
+    stored_bytes: int
+    request_bytes: int
+    response_bytes: int
+    def __init__(
+        self,
+        keys: _Iterable[_Union[KeyStat, _Mapping]] | None = ...,
+        max_value_bytes: int | None = ...,
+        stored_bytes: int | None = ...,
+        request_bytes: int | None = ...,
+        response_bytes: int | None = ...,
+    ) -> None: ...
+
+class KVError(_message.Message):
+    __slots__ = ("code", "key")
+    CODE_FIELD_NUMBER: _ClassVar[int]
+    KEY_FIELD_NUMBER: _ClassVar[int]
+    code: KVErrorCode
+    key: str
+    def __init__(self, code: KVErrorCode | str | None = ..., key: str | None = ...) -> None: ...
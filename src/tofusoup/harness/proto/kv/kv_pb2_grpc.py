@@ -48,12 +48,36 @@ class KVStub:
             response_deserializer=kv__pb2.GetResponse.FromString,
             _registered_method=True,
         )
+        self.GetStream = channel.unary_stream(
+            "/proto.KV/GetStream",
+            request_serializer=kv__pb2.GetStreamRequest.SerializeToString,
+            response_deserializer=kv__pb2.GetChunk.FromString,
+            _registered_method=True,
+        )
         self.Put = channel.unary_unary(
             "/proto.KV/Put",
             request_serializer=kv__pb2.PutRequest.SerializeToString,
             response_deserializer=kv__pb2.Empty.FromString,
             _registered_method=True,
         )
+        self.List = channel.unary_unary(
+            "/proto.KV/List",
+            request_serializer=kv__pb2.ListRequest.SerializeToString,
+            response_deserializer=kv__pb2.ListResponse.FromString,
+            _registered_method=True,
+        )
+        self.Compact = channel.unary_unary(
+            "/proto.KV/Compact",
+            request_serializer=kv__pb2.CompactRequest.SerializeToString,
+            response_deserializer=kv__pb2.CompactResponse.FromString,
+            _registered_method=True,
+        )
+        self.Stat = channel.unary_unary(
+            "/proto.KV/Stat",
+            request_serializer=kv__pb2.StatRequest.SerializeToString,
+            response_deserializer=kv__pb2.StatResponse.FromString,
+            _registered_method=True,
+        )
 
 
 class KVServicer:
@@ -65,12 +89,38 @@ class KVServicer:
         context.set_details("Method not implemented!")
         raise NotImplementedError("Method not implemented!")
 
+    def GetStream(self, request, context) -> Never:
+        """Streams a value from disk in chunks, so values too large to hold in
+        memory can be read.
+        """
+        context.set_code(grpc.StatusCode.UNIMPLEMENTED)
+        context.set_details("Method not implemented!")
+        raise NotImplementedError("Method not implemented!")
+
     def Put(self, request, context) -> Never:
         """Missing associated documentation comment in .proto file."""
         context.set_code(grpc.StatusCode.UNIMPLEMENTED)
         context.set_details("Method not implemented!")
         raise NotImplementedError("Method not implemented!")
 
+    def List(self, request, context) -> Never:
+        """Missing associated documentation comment in .proto file."""
+        context.set_code(grpc.StatusCode.UNIMPLEMENTED)
+        context.set_details("Method not implemented!")
+        raise NotImplementedError("Method not implemented!")
+
+    def Compact(self, request, context) -> Never:
+        """Missing associated documentation comment in .proto file."""
+        context.set_code(grpc.StatusCode.UNIMPLEMENTED)
+        context.set_details("Method not implemented!")
+        raise NotImplementedError("Method not implemented!")
+
+    def Stat(self, request, context) -> Never:
+        """Missing associated documentation comment in .proto file."""
+        context.set_code(grpc.StatusCode.UNIMPLEMENTED)
+        context.set_details("Method not implemented!")
+        raise NotImplementedError("Method not implemented!")
+
 
 def add_KVServicer_to_server(servicer, server) -> None:
     rpc_method_handlers = {
@@ -79,11 +129,31 @@ def add_KVServicer_to_server(servicer, server) -> None:
             request_deserializer=kv__pb2.GetRequest.FromString,
             response_serializer=kv__pb2.GetResponse.SerializeToString,
         ),
+        "GetStream": grpc.unary_stream_rpc_method_handler(
+            servicer.GetStream,
+            request_deserializer=kv__pb2.GetStreamRequest.FromString,
+            response_serializer=kv__pb2.GetChunk.SerializeToString,
+        ),
         "Put": grpc.unary_unary_rpc_method_handler(
             servicer.Put,
             request_deserializer=kv__pb2.PutRequest.FromString,
             response_serializer=kv__pb2.Empty.SerializeToString,
         ),
+        "List": grpc.unary_unary_rpc_method_handler(
+            servicer.List,
+            request_deserializer=kv__pb2.ListRequest.FromString,
+            response_serializer=kv__pb2.ListResponse.SerializeToString,
+        ),
+        "Compact": grpc.unary_unary_rpc_method_handler(
+            servicer.Compact,
+            request_deserializer=kv__pb2.CompactRequest.FromString,
+            response_serializer=kv__pb2.CompactResponse.SerializeToString,
+        ),
+        "Stat": grpc.unary_unary_rpc_method_handler(
+            servicer.Stat,
+            request_deserializer=kv__pb2.StatRequest.FromString,
+            response_serializer=kv__pb2.StatResponse.SerializeToString,
+        ),
     }
     generic_handler = grpc.method_handlers_generic_handler("proto.KV", rpc_method_handlers)
     server.add_generic_rpc_handlers((generic_handler,))
@@ -124,6 +194,36 @@ class KV:
             _registered_method=True,
         )
 
+    @staticmethod
+    def GetStream(
+        request,
+        target,
+        options=(),
+        channel_credentials=None,
+        call_credentials=None,
+        insecure=False,
+        compression=None,
+        wait_for_ready=None,
+        timeout=None,
+        metadata=None,
+    ):
+        return grpc.experimental.unary_stream(
+            request,
+            target,
+            "/proto.KV/GetStream",
+            kv__pb2.GetStreamRequest.SerializeToString,
+            kv__pb2.GetChunk.FromString,
+            options,
+            channel_credentials,
+            insecure,
+            call_credentials,
+            compression,
+            wait_for_ready,
+            timeout,
+            metadata,
+            _registered_method=True,
+        )
+
     @staticmethod
     def Put(
         request,
@@ -154,5 +254,95 @@ class KV:
             _registered_method=True,
         )
 
+    @staticmethod
+    def List(
+        request,
+        target,
+        options=(),
+        channel_credentials=None,
+        call_credentials=None,
+        insecure=False,
+        compression=None,
+        wait_for_ready=None,
+        timeout=None,
+        metadata=None,
+    ):
+        return grpc.experimental.unary_unary(
+            request,
+            target,
+            "/proto.KV/List",
+            kv__pb2.ListRequest.SerializeToString,
+            kv__pb2.ListResponse.FromString,
+            options,
+            channel_credentials,
+            insecure,
+            call_credentials,
+            compression,
+            wait_for_ready,
+            timeout,
+            metadata,
+            _registered_method=True,
+        )
+
+    @staticmethod
+    def Compact(
+        request,
+        target,
+        options=(),
+        channel_credentials=None,
+        call_credentials=None,
+        insecure=False,
+        compression=None,
+        wait_for_ready=None,
+        timeout=None,
+        metadata=None,
+    ):
+        return grpc.experimental.unary_unary(
+            request,
+            target,
+            "/proto.KV/Compact",
+            kv__pb2.CompactRequest.SerializeToString,
+            kv__pb2.CompactResponse.FromString,
+            options,
+            channel_credentials,
+            insecure,
+            call_credentials,
+            compression,
+            wait_for_ready,
+            timeout,
+            metadata,
+            _registered_method=True,
+        )
+
+    @staticmethod
+    def Stat(
+        request,
+        target,
+        options=(),
+        channel_credentials=None,
+        call_credentials=None,
+        insecure=False,
+        compression=None,
+        wait_for_ready=None,
+        timeout=None,
+        metadata=None,
+    ):
+        return grpc.experimental.unary_unary(
+            request,
+            target,
+            "/proto.KV/Stat",
+            kv__pb2.StatRequest.SerializeToString,
+            kv__pb2.StatResponse.FromString,
+            options,
+            channel_credentials,
+            insecure,
+            call_credentials,
+            compression,
+            wait_for_ready,
+            timeout,
+            metadata,
+            _registered_method=True,
+        )
+
 
 # 🥣🔬🔚
@@ -0,0 +1,40 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""Generated protocol buffer code."""
+
+from google.protobuf import (
+    descriptor as _descriptor,
+    descriptor_pool as _descriptor_pool,
+    runtime_version as _runtime_version,
+    symbol_database as _symbol_database,
+)
+from google.protobuf.internal import builder as _builder
+
+_runtime_version.ValidateProtobufRuntimeVersion(_runtime_version.Domain.PUBLIC, 6, 31, 0, "", "kv_v2.proto")
+# @@protoc_insertion_point(imports)
+
+_sym_db = _symbol_database.Default()
+
+
+DESCRIPTOR = _descriptor_pool.Default().AddSerializedFile(
+    b'\n\x0bkv_v2.proto\x12\x05proto"\x8e\x01\n\x0cPutRequestV2\x12\x0b\n\x03key\x18\x01 \x01(\t\x12\r\n\x05value\x18\x02 \x01(\x0c\x12\x19\n\x0c\x63ontent_type\x18\x03 \x01(\tH\x00\x88\x01\x01\x12\x18\n\x0bttl_seconds\x18\x04 \x01(\x03H\x01\x88\x01\x01\x12\x0c\n\x04tags\x18\x05 \x03(\tB\x0f\n\r_content_typeB\x0e\n\x0c_ttl_seconds"O\n\x0cGetRequestV2\x12\x0b\n\x03key\x18\x01 \x01(\t\x12\x1d\n\x10include_metadata\x18\x02 \x01(\x08H\x00\x88\x01\x01\x42\x13\n\x11_include_metadata"z\n\rGetResponseV2\x12\r\n\x05value\x18\x01 \x01(\x0c\x12\x19\n\x0c\x63ontent_type\x18\x02 \x01(\tH\x00\x88\x01\x01\x12\x1b\n\x0estored_at_unix\x18\x03 \x01(\x03H\x01\x88\x01\x01\x42\x0f\n\r_content_typeB\x11\n\x0f_stored_at_unixB\tZ\x07./protob\x06proto3'
+)
+
+_globals = globals()
+_builder.BuildMessageAndEnumDescriptors(DESCRIPTOR, _globals)
+_builder.BuildTopDescriptorsAndMessages(DESCRIPTOR, "kv_v2_pb2", _globals)
+if not _descriptor._USE_C_DESCRIPTORS:
+    _globals["DESCRIPTOR"]._loaded_options = None
+    _globals["DESCRIPTOR"]._serialized_options = b"Z\007./proto"
+    _globals["_PUTREQUESTV2"]._serialized_start = 23
+    _globals["_PUTREQUESTV2"]._serialized_end = 165
+    _globals["_GETREQUESTV2"]._serialized_start = 167
+    _globals["_GETREQUESTV2"]._serialized_end = 246
+    _globals["_GETRESPONSEV2"]._serialized_start = 248
+    _globals["_GETRESPONSEV2"]._serialized_end = 370
+# @@protoc_insertion_point(module_scope)
+
+# 🥣🔬🔚
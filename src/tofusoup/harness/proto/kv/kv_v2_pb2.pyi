@@ -0,0 +1,48 @@
+from collections.abc import Iterable as _Iterable
+from typing import ClassVar as _ClassVar
+
+from google.protobuf import descriptor as _descriptor, message as _message
+from google.protobuf.internal import containers as _containers
+
+DESCRIPTOR: _descriptor.FileDescriptor
+
+class PutRequestV2(_message.Message):
+    __slots__ = ("content_type", "key", "tags", "ttl_seconds", "value")
+    KEY_FIELD_NUMBER: _ClassVar[int]
+    VALUE_FIELD_NUMBER: _ClassVar[int]
+    CONTENT_TYPE_FIELD_NUMBER: _ClassVar[int]
+    TTL_SECONDS_FIELD_NUMBER: _ClassVar[int]
+    TAGS_FIELD_NUMBER: _ClassVar[int]
+    key: str
+    value: bytes
+    content_type: str
+    ttl_seconds: int
+    tags: _containers.RepeatedScalarFieldContainer[str]
+    def __init__(
+        self,
+        key: str | None = ...,
+        value: bytes | None = ...,
+        content_type: str | None = ...,
+        ttl_seconds: int | None = ...,
+        tags: _Iterable[str] | None = ...,
+    ) -> None: ...
+
+class GetRequestV2(_message.Message):
+    __slots__ = ("include_metadata", "key")
+    KEY_FIELD_NUMBER: _ClassVar[int]
+    INCLUDE_METADATA_FIELD_NUMBER: _ClassVar[int]
+    key: str
+    include_metadata: bool
+    def __init__(self, key: str | None = ..., include_metadata: bool | None = ...) -> None: ...
+
+class GetResponseV2(_message.Message):
+    __slots__ = ("content_type", "stored_at_unix", "value")
+    VALUE_FIELD_NUMBER: _ClassVar[int]
+    CONTENT_TYPE_FIELD_NUMBER: _ClassVar[int]
+    STORED_AT_UNIX_FIELD_NUMBER: _ClassVar[int]
+    value: bytes
+    content_type: str
+    stored_at_unix: int
+    def __init__(
+        self, value: bytes | None = ..., content_type: str | None = ..., stored_at_unix: int | None = ...
+    ) -> None: ...
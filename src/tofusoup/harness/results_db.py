@@ -0,0 +1,329 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""SQLite storage for matrix and conformance results.
+
+Each ingested report becomes one row in ``runs`` (when, where and from which
+commit it ran, plus pass/fail totals) and one row per test case in
+``case_results``. Reports are kept across runs so pass/fail and latency trends
+can be queried per case long after the CI artifact that produced them is gone.
+
+Understood report formats:
+
+- pytest-json-report output, as written by ``soup test`` to
+  ``soup/output/test-reports``
+- ``VersionMatrix.save_results`` output (``soup stir --matrix``)
+- the ``results.json`` written by ``conformance/rpc/run_matrix_tests.py``"""
+
+from dataclasses import dataclass, field
+from datetime import UTC, datetime
+import json
+import os
+import pathlib
+import platform
+import socket
+import sqlite3
+import statistics
+import subprocess
+from typing import Any
+
+from tofusoup.common.exceptions import TofuSoupError
+
+SCHEMA_VERSION = 1
+
+SCHEMA = """
+CREATE TABLE IF NOT EXISTS runs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    recorded_at TEXT NOT NULL,
+    started_at TEXT,
+    kind TEXT NOT NULL,
+    source TEXT NOT NULL,
+    label TEXT,
+    git_commit TEXT,
+    hostname TEXT,
+    platform TEXT,
+    python_version TEXT,
+    duration_seconds REAL,
+    passed INTEGER NOT NULL DEFAULT 0,
+    failed INTEGER NOT NULL DEFAULT 0,
+    skipped INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS case_results (
+    run_id INTEGER NOT NULL REFERENCES runs(id) ON DELETE CASCADE,
+    case_id TEXT NOT NULL,
+    outcome TEXT NOT NULL,
+    duration_seconds REAL,
+    message TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_case_results_case ON case_results(case_id);
+CREATE INDEX IF NOT EXISTS idx_case_results_run ON case_results(run_id);
+"""
+
+# Outcomes counted as a pass or a failure; anything else counts as skipped
+PASS_OUTCOMES = frozenset({"passed", "xfailed"})
+FAIL_OUTCOMES = frozenset({"failed", "error", "timeout", "xpassed"})
+
+
+@dataclass
+class CaseResult:
+    """A single test case outcome within a run."""
+
+    case_id: str
+    outcome: str
+    duration_seconds: float | None = None
+    message: str | None = None
+    # Not stored in the database; used by the HTML report's drill-down
+    detail: str | None = None
+    log_file: str | None = None
+
+
+@dataclass
+class RunReport:
+    """A parsed report, ready to be stored as one run."""
+
+    kind: str
+    source: str
+    cases: list[CaseResult] = field(default_factory=list)
+    started_at: str | None = None
+    duration_seconds: float | None = None
+
+
+@dataclass
+class CaseHistoryEntry:
+    """One run's outcome for a case, as returned by `case_history`."""
+
+    run_id: int
+    recorded_at: str
+    git_commit: str | None
+    label: str | None
+    case_id: str
+    outcome: str
+    duration_seconds: float | None
+
+
+def _timestamp(value: Any) -> str | None:
+    if isinstance(value, int | float):
+        return datetime.fromtimestamp(value, tz=UTC).isoformat()
+    if isinstance(value, str):
+        return value
+    return None
+
+
+def _pytest_case_duration(test: dict[str, Any]) -> float | None:
+    phases = [test.get(phase) for phase in ("setup", "call", "teardown")]
+    durations = [p["duration"] for p in phases if isinstance(p, dict) and "duration" in p]
+    return sum(durations) if durations else None
+
+
+def _pytest_failed_phase(test: dict[str, Any]) -> dict[str, Any] | None:
+    for phase in ("call", "setup", "teardown"):
+        info = test.get(phase)
+        if isinstance(info, dict) and info.get("outcome") in ("failed", "error"):
+            return info
+    return None
+
+
+def _pytest_case_message(test: dict[str, Any]) -> str | None:
+    info = _pytest_failed_phase(test)
+    if info is None:
+        return None
+    crash = info.get("crash") or {}
+    return crash.get("message") or info.get("longrepr")
+
+
+def parse_report(data: dict[str, Any], source: str) -> RunReport:
+    """Parse a report document into a `RunReport`, detecting its format."""
+    if isinstance(data.get("tests"), list):
+        # pytest-json-report
+        report = RunReport(
+            kind="conformance",
+            source=source,
+            started_at=_timestamp(data.get("created")),
+            duration_seconds=data.get("duration"),
+        )
+        for test in data["tests"]:
+            report.cases.append(
+                CaseResult(
+                    case_id=test["nodeid"],
+                    outcome=test.get("outcome", "unknown"),
+                    duration_seconds=_pytest_case_duration(test),
+                    message=_pytest_case_message(test),
+                    detail=(_pytest_failed_phase(test) or {}).get("longrepr"),
+                )
+            )
+        return report
+
+    results = data.get("results")
+    if isinstance(results, list):
+        report = RunReport(
+            kind=data.get("kind", "matrix"),
+            source=source,
+            started_at=_timestamp(data.get("created")),
+            duration_seconds=data.get("duration"),
+        )
+        for result in results:
+            if "combination" in result:
+                # VersionMatrix.save_results
+                tools = result["combination"].get("tools", {})
+                report.cases.append(
+                    CaseResult(
+                        case_id=",".join(f"{tool}:{version}" for tool, version in sorted(tools.items())),
+                        outcome="passed" if result.get("success") else "failed",
+                        duration_seconds=result.get("duration_seconds"),
+                        message=result.get("error_message"),
+                    )
+                )
+            elif "node_id" in result:
+                # run_matrix_tests.py
+                outcome = result.get("status", "unknown")
+                report.cases.append(
+                    CaseResult(
+                        case_id=result["node_id"],
+                        outcome=outcome,
+                        duration_seconds=result.get("duration_seconds"),
+                        message=None if outcome == "passed" else f"see {result.get('log_file')}",
+                        log_file=result.get("log_file"),
+                    )
+                )
+            else:
+                raise TofuSoupError(f"Unrecognised result entry in {source}: {sorted(result)}")
+        return report
+
+    raise TofuSoupError(f"Unrecognised report format in {source}")
+
+
+def load_report(path: pathlib.Path) -> RunReport:
+    """Read and parse a report file."""
+    try:
+        data = json.loads(path.read_text())
+    except (OSError, json.JSONDecodeError) as e:
+        raise TofuSoupError(f"Failed to read report {path}: {e}") from e
+    if not isinstance(data, dict):
+        raise TofuSoupError(f"Unrecognised report format in {path}")
+    return parse_report(data, str(path))
+
+
+def _git_commit() -> str | None:
+    if sha := os.environ.get("GITHUB_SHA"):
+        return sha
+    try:
+        result = subprocess.run(["git", "rev-parse", "HEAD"], capture_output=True, text=True, timeout=5)
+    except (OSError, subprocess.SubprocessError):
+        return None
+    if result.returncode != 0:
+        return None
+    return result.stdout.strip() or None
+
+
+def connect(db_path: pathlib.Path) -> sqlite3.Connection:
+    """Open (creating if needed) a results database."""
+    conn = sqlite3.connect(db_path)
+    conn.row_factory = sqlite3.Row
+    conn.execute("PRAGMA foreign_keys = ON")
+    version = conn.execute("PRAGMA user_version").fetchone()[0]
+    if version > SCHEMA_VERSION:
+        conn.close()
+        raise TofuSoupError(
+            f"{db_path} uses results schema v{version}, newer than the supported v{SCHEMA_VERSION}"
+        )
+    conn.executescript(SCHEMA)
+    conn.execute(f"PRAGMA user_version = {SCHEMA_VERSION}")
+    return conn
+
+
+def record_run(conn: sqlite3.Connection, report: RunReport, label: str | None = None) -> int:
+    """Append a report to the database as a new run, returning its id."""
+    passed = sum(1 for c in report.cases if c.outcome in PASS_OUTCOMES)
+    failed = sum(1 for c in report.cases if c.outcome in FAIL_OUTCOMES)
+    with conn:
+        cursor = conn.execute(
+            """
+            INSERT INTO runs (
+                recorded_at, started_at, kind, source, label, git_commit, hostname,
+                platform, python_version, duration_seconds, passed, failed, skipped
+            ) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+            """,
+            (
+                datetime.now(tz=UTC).isoformat(),
+                report.started_at,
+                report.kind,
+                report.source,
+                label,
+                _git_commit(),
+                socket.gethostname(),
+                platform.platform(),
+                platform.python_version(),
+                report.duration_seconds,
+                passed,
+                failed,
+                len(report.cases) - passed - failed,
+            ),
+        )
+        run_id = cursor.lastrowid
+        conn.executemany(
+            "INSERT INTO case_results (run_id, case_id, outcome, duration_seconds, message) "
+            "VALUES (?, ?, ?, ?, ?)",
+            [(run_id, c.case_id, c.outcome, c.duration_seconds, c.message) for c in report.cases],
+        )
+    assert run_id is not None
+    return run_id
+
+
+def case_history(conn: sqlite3.Connection, case: str, limit: int | None = None) -> list[CaseHistoryEntry]:
+    """Return a case's results across runs, oldest first.
+
+    `case` matches case IDs exactly, or as a glob when it contains `*` or `?`.
+    Brackets always match literally, since parametrized test IDs contain them."""
+    op = "="
+    if "*" in case or "?" in case:
+        op = "GLOB"
+        case = case.replace("[", "[[]")
+    query = f"""
+        SELECT r.id AS run_id, r.recorded_at, r.git_commit, r.label,
+               c.case_id, c.outcome, c.duration_seconds
+        FROM case_results c JOIN runs r ON r.id = c.run_id
+        WHERE c.case_id {op} ?
+        ORDER BY r.id DESC, c.case_id
+    """
+    params: list[Any] = [case]
+    if limit:
+        query += " LIMIT ?"
+        params.append(limit)
+    rows = conn.execute(query, params).fetchall()
+    return [CaseHistoryEntry(**dict(row)) for row in reversed(rows)]
+
+
+def summarize_history(entries: list[CaseHistoryEntry]) -> dict[str, Any]:
+    """Pass rate and latency statistics for a list of history entries."""
+    passed = sum(1 for e in entries if e.outcome in PASS_OUTCOMES)
+    failed = sum(1 for e in entries if e.outcome in FAIL_OUTCOMES)
+    durations = [e.duration_seconds for e in entries if e.duration_seconds is not None]
+    summary: dict[str, Any] = {
+        "runs": len({e.run_id for e in entries}),
+        "results": len(entries),
+        "passed": passed,
+        "failed": failed,
+        "pass_rate": passed / (passed + failed) if passed + failed else None,
+        "latest_outcome": entries[-1].outcome if entries else None,
+    }
+    if durations:
+        summary["duration_seconds"] = {
+            "min": min(durations),
+            "median": statistics.median(durations),
+            "max": max(durations),
+            "latest": durations[-1],
+        }
+        # Latest against the median of everything before it
+        if len(durations) > 1:
+            previous = statistics.median(durations[:-1])
+            summary["duration_seconds"]["latest_vs_median"] = (
+                durations[-1] / previous if previous else None
+            )
+    return summary
+
+
+# 🥣🔬🔚
@@ -0,0 +1,280 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""Matching set elements for harness diff.
+
+A cty set has no order of its own. Runtimes write its elements in whatever
+order they keep them, so comparing two plan-like values index by index
+reports every element of a reordered set as changed. Elements holding
+unknowns ({"unknown": type}) or redacted marks make it worse: they don't
+sort the same way in any two runtimes.
+
+`--set-match` chooses how elements of the lists that are sets get paired:
+
+    positional   by index, as for any other list
+    digest       elements equal after sorting keys are paired, whatever
+                 their position; the rest are added or removed
+    best-effort  as digest, then the most similar of the remaining
+                 elements are paired and diffed, with an unknown matching
+                 anything
+
+Which lists are sets comes from `--set-path` patterns, in diff path
+notation with `[*]` for any index and `*` for any key
+(`$.resources[*].values.ingress`), and from the type of a typed JSON value
+({"value": ..., "type": ...}, as `soup-go cty convert --encoding
+ctyjson-typed` writes), whose `["set", T]` types mark them. Every set
+compared is recorded as a SetMatch, so the diff says how its elements were
+paired."""
+
+from dataclasses import dataclass
+import hashlib
+import json
+import re
+from typing import Any
+
+from rich.markup import escape
+
+POSITIONAL = "positional"
+DIGEST = "digest"
+BEST_EFFORT = "best-effort"
+STRATEGIES = (BEST_EFFORT, POSITIONAL, DIGEST)
+
+# A leaf redacted by `soup-go cty convert --redact-marked`
+REDACTED_PATTERN = re.compile(r"^\(sensitive value [a-z0-9-]+:[0-9a-f]+\)$")
+
+
+def is_unknown(value: Any) -> bool:
+    """Whether value is an unknown: {"unknown": type}, or a trace's
+    {"unknown": true, ...} with no "value"."""
+    if not isinstance(value, dict) or "unknown" not in value:
+        return False
+    return len(value) == 1 or (value["unknown"] is True and "value" not in value)
+
+
+def is_redacted(value: Any) -> bool:
+    """Whether value is a redacted marked leaf."""
+    return isinstance(value, str) and REDACTED_PATTERN.match(value) is not None
+
+
+def contains(value: Any, predicate: Any) -> bool:
+    """Whether predicate holds for value or anything inside it."""
+    if predicate(value):
+        return True
+    if isinstance(value, dict):
+        return any(contains(child, predicate) for child in value.values())
+    if isinstance(value, list):
+        return any(contains(child, predicate) for child in value)
+    return False
+
+
+def element_digest(value: Any) -> str:
+    """SHA-256 of value as JSON with sorted keys, so equal elements have
+    equal digests whatever order their keys were written in."""
+    canonical = json.dumps(value, sort_keys=True, separators=(",", ":"), ensure_ascii=False)
+    return hashlib.sha256(canonical.encode()).hexdigest()
+
+
+def leaves(value: Any) -> int:
+    """The number of scalars and unknowns in value, at least 1."""
+    if is_unknown(value):
+        return 1
+    if isinstance(value, dict):
+        return max(1, sum(leaves(child) for child in value.values()))
+    if isinstance(value, list):
+        return max(1, sum(leaves(child) for child in value))
+    return 1
+
+
+def mismatch(left: Any, right: Any) -> int:
+    """How many leaves of left and right differ, with an unknown on either
+    side matching anything."""
+    if is_unknown(left) or is_unknown(right):
+        return 0
+    if isinstance(left, dict) and isinstance(right, dict):
+        both = left.keys() & right.keys()
+        shared = sum(mismatch(left[key], right[key]) for key in both)
+        return shared + sum(leaves(value) for key, value in {**left, **right}.items() if key not in both)
+    if isinstance(left, list) and isinstance(right, list):
+        shared = sum(mismatch(a, b) for a, b in zip(left, right, strict=False))
+        return shared + sum(leaves(extra) for extra in left[len(right) :] + right[len(left) :])
+    if left == right and isinstance(left, bool) == isinstance(right, bool):
+        return 0
+    return max(leaves(left), leaves(right))
+
+
+def set_paths_from_type(ty: Any, path: str = "$.value") -> list[str]:
+    """Patterns for the sets in a value of cty type ty (in cty's JSON type
+    notation) found at path."""
+    if not isinstance(ty, list) or len(ty) != 2:
+        return []
+    kind, inner = ty
+    if kind == "set":
+        return [path, *set_paths_from_type(inner, f"{path}[*]")]
+    if kind == "list":
+        return set_paths_from_type(inner, f"{path}[*]")
+    if kind == "map":
+        return set_paths_from_type(inner, f"{path}.*")
+    if kind == "object" and isinstance(inner, dict):
+        return [found for key, attr in inner.items() for found in set_paths_from_type(attr, f"{path}.{key}")]
+    if kind == "tuple" and isinstance(inner, list):
+        return [found for i, elem in enumerate(inner) for found in set_paths_from_type(elem, f"{path}[{i}]")]
+    return []
+
+
+def envelope_set_paths(value: Any) -> list[str]:
+    """Set patterns from the type of a typed JSON value, or none if value
+    isn't one."""
+    if isinstance(value, dict) and value.keys() == {"value", "type"}:
+        return set_paths_from_type(value["type"])
+    return []
+
+
+def compile_pattern(pattern: str) -> re.Pattern[str]:
+    """A regex for a set path pattern. [*] matches any index, including the
+    [i~j] of elements paired at different indexes, and * any key."""
+    parts = re.split(r"(\[\*\]|\*)", pattern)
+    regex = "".join(
+        r"\[\d+(?:~\d+)?\]" if part == "[*]" else r"[^.\[]+" if part == "*" else re.escape(part)
+        for part in parts
+    )
+    return re.compile(f"^{regex}$")
+
+
+@dataclass(frozen=True)
+class SetMatch:
+    """How the elements of one set were paired."""
+
+    path: str
+    strategy: str
+    left: int
+    right: int
+    # Elements paired with an equal element
+    identical: int
+    # Elements paired with a differing element: by index, or by similarity
+    # with best-effort
+    paired: int
+    removed: int
+    added: int
+    unknowns: bool
+    marked: bool
+
+    def __str__(self) -> str:
+        notes = [note for note, present in (("unknowns", self.unknowns), ("marks", self.marked)) if present]
+        held = f"; holds {' and '.join(notes)}" if notes else ""
+        return (
+            f"{self.path} ({self.strategy}): {self.identical} identical, {self.paired} paired, "
+            f"{self.removed} removed, {self.added} added{held}"
+        )
+
+
+class SetMatcher:
+    """A json_diff list matcher that pairs the elements of lists at set
+    paths by a strategy, recording a SetMatch for each."""
+
+    def __init__(self, strategy: str, patterns: list[str] | tuple[str, ...] = ()) -> None:
+        if strategy not in STRATEGIES:
+            raise ValueError(f"unknown set matching strategy {strategy!r} (expected {', '.join(STRATEGIES)})")
+        self.strategy = strategy
+        self.patterns: list[str] = []
+        self._compiled: list[re.Pattern[str]] = []
+        self.matches: list[SetMatch] = []
+        self.add_patterns(patterns)
+
+    def add_patterns(self, patterns: list[str] | tuple[str, ...]) -> None:
+        """Treat lists at these paths as sets too."""
+        for pattern in patterns:
+            if pattern not in self.patterns:
+                self.patterns.append(pattern)
+                self._compiled.append(compile_pattern(pattern))
+
+    def is_set(self, path: str) -> bool:
+        return any(regex.match(path) for regex in self._compiled)
+
+    def __call__(
+        self, path: str, left: list[Any], right: list[Any]
+    ) -> list[tuple[int | None, int | None]] | None:
+        if not self.is_set(path):
+            return None
+        if self.strategy == POSITIONAL:
+            pairs = [
+                (i if i < len(left) else None, i if i < len(right) else None)
+                for i in range(max(len(left), len(right)))
+            ]
+            identical = sum(1 for i, j in pairs if i is not None and j is not None and left[i] == right[j])
+            paired = sum(1 for i, j in pairs if i is not None and j is not None) - identical
+        else:
+            matched = self._match_digests(left, right)
+            identical = len(matched)
+            if self.strategy == BEST_EFFORT:
+                similar = self._match_similar(left, right, matched)
+                matched.update(similar)
+            paired = len(matched) - identical
+            used = set(matched.values())
+            pairs = [(i, matched.get(i)) for i in range(len(left))]
+            pairs += [(None, j) for j in range(len(right)) if j not in used]
+
+        self.matches.append(
+            SetMatch(
+                path=path,
+                strategy=self.strategy,
+                left=len(left),
+                right=len(right),
+                identical=identical,
+                paired=paired,
+                removed=sum(1 for _, j in pairs if j is None),
+                added=sum(1 for i, _ in pairs if i is None),
+                unknowns=contains([left, right], is_unknown),
+                marked=contains([left, right], is_redacted),
+            )
+        )
+        return pairs
+
+    @staticmethod
+    def _match_digests(left: list[Any], right: list[Any]) -> dict[int, int]:
+        """Left index to right index for elements with equal digests, each
+        element used once."""
+        unused: dict[str, list[int]] = {}
+        for j, element in enumerate(right):
+            unused.setdefault(element_digest(element), []).append(j)
+        matched = {}
+        for i, element in enumerate(left):
+            candidates = unused.get(element_digest(element))
+            if candidates:
+                matched[i] = candidates.pop(0)
+        return matched
+
+    @staticmethod
+    def _match_similar(left: list[Any], right: list[Any], matched: dict[int, int]) -> dict[int, int]:
+        """Pairs of the elements left over from digest matching, least
+        different first. Elements with nothing in common stay unpaired."""
+        rest_left = [i for i in range(len(left)) if i not in matched]
+        taken = set(matched.values())
+        rest_right = [j for j in range(len(right)) if j not in taken]
+        candidates = []
+        for i in rest_left:
+            for j in rest_right:
+                cost = mismatch(left[i], right[j])
+                if cost < max(leaves(left[i]), leaves(right[j])):
+                    candidates.append((cost, i, j))
+        candidates.sort()
+        similar: dict[int, int] = {}
+        used: set[int] = set()
+        for _, i, j in candidates:
+            if i not in similar and j not in used:
+                similar[i] = j
+                used.add(j)
+        return similar
+
+
+def render_set_matches(matches: list[SetMatch]) -> list[str]:
+    """Markup lines saying how each set was matched, for rich.print."""
+    if not matches:
+        return []
+    return [f"[bold]Set matching:[/bold] {len(matches)} set{'s' * (len(matches) != 1)} compared"] + [
+        f"  [cyan]{escape(str(match))}[/cyan]" for match in matches
+    ]
+
+
+# 🥣🔬🔚
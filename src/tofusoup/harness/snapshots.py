@@ -0,0 +1,205 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""Snapshot (golden output) tests for harness commands.
+
+A snapshot is a command whose normalized output and exit code are stored
+in a file and compared on later runs, so goldens are recorded by running
+the command rather than written by hand. Snapshots are declared in TOML,
+under ``[harness.snapshots.<name>]`` in soup.toml or ``[snapshots.<name>]``
+in a file passed with ``--snapshots``::
+
+    [snapshots.hcl-convert-basic]
+    command = "soup-go hcl convert conformance/hcl/testdata/basic.hcl - --output-format json"
+    normalize = ["sort-keys", "float-format"]
+    stdin = "path/to/input"      # optional, fed to the command
+    stream = "stdout"            # or "stderr" or "combined"
+    timeout = 60
+
+Each is stored as ``<name>.json`` in the snapshot directory. Output that is
+JSON after normalizing is stored as a JSON value, so the file reads and
+diffs like any other golden; other output is stored as text. JSON outputs
+are compared by value, text outputs line by line."""
+
+from dataclasses import dataclass, field
+import difflib
+import json
+import pathlib
+import tomllib
+from typing import Any
+
+from tofusoup.common.exceptions import TofuSoupError
+from tofusoup.common.pretty_diff import ADD, CHANGE, REMOVE, json_diff, render_pretty_diff
+
+STREAMS = ("stdout", "stderr", "combined")
+
+# Snapshot output formats
+FORMAT_JSON = "json"
+FORMAT_TEXT = "text"
+
+
+@dataclass(frozen=True)
+class SnapshotSpec:
+    """A configured snapshot: the command to run and how to normalize it."""
+
+    name: str
+    command: str
+    normalize: list[str] = field(default_factory=list)
+    stdin: pathlib.Path | None = None
+    stream: str = "stdout"
+    timeout: float = 60.0
+
+
+@dataclass
+class Snapshot:
+    """A command's normalized output and exit code, as stored."""
+
+    name: str
+    command: str
+    normalize: list[str]
+    exit_code: int
+    output: Any
+    output_format: str
+
+    @classmethod
+    def from_output(cls, spec: SnapshotSpec, text: str, exit_code: int) -> "Snapshot":
+        """A snapshot of normalized output, JSON if it parses as JSON."""
+        try:
+            output, output_format = json.loads(text), FORMAT_JSON
+        except ValueError:
+            output, output_format = text, FORMAT_TEXT
+        return cls(spec.name, spec.command, list(spec.normalize), exit_code, output, output_format)
+
+    @classmethod
+    def load(cls, path: pathlib.Path) -> "Snapshot":
+        try:
+            data = json.loads(path.read_text())
+            return cls(
+                data["name"],
+                data["command"],
+                data.get("normalize", []),
+                data["exit_code"],
+                data["output"],
+                data.get("output_format", FORMAT_TEXT),
+            )
+        except (OSError, ValueError, KeyError) as e:
+            raise TofuSoupError(f"Failed to read snapshot {path}: {e}") from e
+
+    def save(self, path: pathlib.Path) -> None:
+        path.parent.mkdir(parents=True, exist_ok=True)
+        data = {
+            "name": self.name,
+            "command": self.command,
+            "normalize": self.normalize,
+            "exit_code": self.exit_code,
+            "output_format": self.output_format,
+            "output": self.output,
+        }
+        path.write_text(json.dumps(data, indent=2, ensure_ascii=False) + "\n")
+
+    def text(self) -> str:
+        """The output as text, JSON re-serialized with two-space indents."""
+        if self.output_format == FORMAT_JSON:
+            return json.dumps(self.output, indent=2, ensure_ascii=False) + "\n"
+        return str(self.output)
+
+
+def _spec(name: str, table: Any, base: pathlib.Path) -> SnapshotSpec:
+    if not isinstance(table, dict):
+        raise TofuSoupError(f"Snapshot '{name}' must be a table")
+    if not isinstance(table.get("command"), str):
+        raise TofuSoupError(f"Snapshot '{name}' needs a 'command'")
+    stream = table.get("stream", "stdout")
+    if stream not in STREAMS:
+        raise TofuSoupError(
+            f"Snapshot '{name}': unknown stream '{stream}' (expected one of: {', '.join(STREAMS)})"
+        )
+    normalize = table.get("normalize", [])
+    if isinstance(normalize, str):
+        normalize = [n.strip() for n in normalize.split(",") if n.strip()]
+    stdin = table.get("stdin")
+    return SnapshotSpec(
+        name=name,
+        command=table["command"],
+        normalize=list(normalize),
+        stdin=base / stdin if stdin else None,
+        stream=stream,
+        timeout=float(table.get("timeout", 60.0)),
+    )
+
+
+def load_snapshot_specs(
+    soup_config: dict[str, Any] | None, project_root: pathlib.Path, config_file: pathlib.Path | None = None
+) -> dict[str, SnapshotSpec]:
+    """All configured snapshots: soup.toml's ``[harness.snapshots]``, then
+    ``[snapshots]`` from config_file. stdin paths are relative to the
+    project root, or to config_file's directory for its snapshots."""
+    specs = {}
+    if soup_config:
+        for name, table in soup_config.get("harness", {}).get("snapshots", {}).items():
+            specs[name] = _spec(name, table, project_root)
+    if config_file is not None:
+        try:
+            with config_file.open("rb") as f:
+                data = tomllib.load(f)
+        except (OSError, tomllib.TOMLDecodeError) as e:
+            raise TofuSoupError(f"Failed to read snapshots from {config_file}: {e}") from e
+        for name, table in data.get("snapshots", {}).items():
+            specs[name] = _spec(name, table, config_file.parent)
+    return specs
+
+
+def snapshot_path(directory: pathlib.Path, name: str) -> pathlib.Path:
+    return directory / f"{name}.json"
+
+
+def matches(stored: Snapshot, current: Snapshot) -> bool:
+    """Whether a fresh snapshot matches the stored one."""
+    if stored.exit_code != current.exit_code or stored.output_format != current.output_format:
+        return False
+    if current.output_format == FORMAT_JSON:
+        return not json_diff(stored.output, current.output)
+    return bool(stored.output == current.output)
+
+
+def summarize_change(stored: Snapshot | None, current: Snapshot) -> str:
+    """One line saying how a snapshot changed, e.g. "3 differences: 1
+    changed, 2 added, 0 removed"."""
+    if stored is None:
+        return "new snapshot"
+    parts = []
+    if stored.exit_code != current.exit_code:
+        parts.append(f"exit code {stored.exit_code} → {current.exit_code}")
+    if stored.output_format == current.output_format == FORMAT_JSON:
+        changes = json_diff(stored.output, current.output)
+        if changes:
+            counts = {kind: sum(1 for c in changes if c[0] == kind) for kind in (CHANGE, ADD, REMOVE)}
+            plural = "s" * (len(changes) != 1)
+            parts.append(
+                f"{len(changes)} difference{plural}: {counts[CHANGE]} changed, "
+                f"{counts[ADD]} added, {counts[REMOVE]} removed"
+            )
+    elif stored.output_format != current.output_format:
+        parts.append(f"output {stored.output_format} → {current.output_format}")
+    elif stored.output != current.output:
+        added = removed = 0
+        for line in difflib.ndiff(stored.text().splitlines(), current.text().splitlines()):
+            added += line.startswith("+ ")
+            removed += line.startswith("- ")
+        parts.append(f"{added} line{'s' * (added != 1)} added, {removed} removed")
+    if stored.command != current.command or stored.normalize != current.normalize:
+        parts.append("command or normalizers changed")
+    return "; ".join(parts) or "unchanged"
+
+
+def render_snapshot_diff(stored: Snapshot, current: Snapshot) -> list[str]:
+    """Markup lines showing how a fresh snapshot differs from the stored
+    one, with long strings and collections summarized."""
+    return render_pretty_diff(
+        stored.text(), current.text(), f"{stored.name} (stored)", f"{current.name} (current)"
+    )
+
+
+# 🥣🔬🔚
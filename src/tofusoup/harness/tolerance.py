@@ -0,0 +1,156 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""Floating point tolerance for harness diff.
+
+Runtimes format and round floats differently (0.30000000000000004 against
+0.3, 1e-07 against 1.0000000000000001e-07), so `--float-tolerance
+abs=1e-9,rel=1e-6` lets near-equal numbers match. Lines that differ only in
+numbers are compared number by number; where every differing pair is within
+tolerance the lines match. A pair is within tolerance when
+
+    |a - b| <= max(rel * max(|a|, |b|), abs)
+
+as in math.isclose. Tolerance only applies to pairs where at least one side
+is written as a float; differing integers never match. Every pair compared
+is recorded as a ToleranceDecision so the diff can show what was accepted
+and what wasn't."""
+
+from dataclasses import dataclass
+import difflib
+import math
+import re
+
+from rich.markup import escape
+
+# Numbers as they appear in harness output, integers included; the group
+# makes re.split keep them
+NUMBER_PATTERN = re.compile(r"(?<![\w.])(-?\d+(?:\.\d+)?(?:[eE][+-]?\d+)?)(?![\w.])")
+
+
+@dataclass(frozen=True)
+class FloatTolerance:
+    """Absolute and relative tolerance for comparing floats."""
+
+    abs: float = 0.0
+    rel: float = 0.0
+
+    @classmethod
+    def parse(cls, spec: str) -> "FloatTolerance":
+        """Parse "abs=1e-9,rel=1e-6"; either part may be left out."""
+        values: dict[str, float] = {}
+        for part in spec.split(","):
+            key, sep, value = part.strip().partition("=")
+            if not sep or key not in ("abs", "rel"):
+                raise ValueError(f"expected abs=<number> and/or rel=<number>, got {part.strip()!r}")
+            if key in values:
+                raise ValueError(f"{key} given twice")
+            try:
+                values[key] = float(value)
+            except ValueError:
+                raise ValueError(f"{key} must be a number, got {value!r}") from None
+            if not math.isfinite(values[key]) or values[key] < 0:
+                raise ValueError(f"{key} must be a finite, non-negative number, got {value!r}")
+        return cls(**values)
+
+    def close(self, a: float, b: float) -> bool:
+        """Whether a and b are equal within this tolerance."""
+        return math.isclose(a, b, rel_tol=self.rel, abs_tol=self.abs)
+
+    def __str__(self) -> str:
+        return f"abs={self.abs:g},rel={self.rel:g}"
+
+
+@dataclass(frozen=True)
+class ToleranceDecision:
+    """A pair of differing numbers and whether tolerance accepted them."""
+
+    location: str
+    left: str
+    right: str
+    abs_diff: float
+    rel_diff: float
+    accepted: bool
+
+    def __str__(self) -> str:
+        sign = "≈" if self.accepted else "≠"
+        return (
+            f"{sign} {self.location}: {self.left} vs {self.right} "
+            f"(abs {self.abs_diff:.3g}, rel {self.rel_diff:.3g})"
+        )
+
+
+def is_float_literal(text: str) -> bool:
+    """Whether a number as written is a float rather than an integer."""
+    return any(char in text for char in ".eE")
+
+
+def decide(location: str, left: str, right: str, tolerance: FloatTolerance) -> ToleranceDecision:
+    """Compare two numbers as written."""
+    a, b = float(left), float(right)
+    abs_diff = abs(a - b)
+    scale = max(abs(a), abs(b))
+    rel_diff = abs_diff / scale if scale else 0.0
+    return ToleranceDecision(location, left, right, abs_diff, rel_diff, tolerance.close(a, b))
+
+
+def compare_line(
+    left: str, right: str, tolerance: FloatTolerance, location: str
+) -> list[ToleranceDecision] | None:
+    """Decisions for each differing pair of numbers in two lines, or None if
+    the lines differ in more than floats."""
+    left_parts, right_parts = NUMBER_PATTERN.split(left), NUMBER_PATTERN.split(right)
+    if len(left_parts) != len(right_parts) or left_parts[::2] != right_parts[::2]:
+        return None
+    decisions = []
+    for left_number, right_number in zip(left_parts[1::2], right_parts[1::2], strict=True):
+        if left_number == right_number:
+            continue
+        if not (is_float_literal(left_number) or is_float_literal(right_number)):
+            return None
+        decisions.append(decide(location, left_number, right_number, tolerance))
+    return decisions
+
+
+def apply_float_tolerance(
+    left_text: str, right_text: str, tolerance: FloatTolerance
+) -> tuple[str, list[ToleranceDecision]]:
+    """right_text with each line that matches its left counterpart within
+    tolerance replaced by that line, and the decisions made. Lines are
+    paired where a diff replaces a run of lines with as many others;
+    locations are right_text line numbers."""
+    left_lines = left_text.splitlines(keepends=True)
+    right_lines = right_text.splitlines(keepends=True)
+    adjusted = list(right_lines)
+    decisions: list[ToleranceDecision] = []
+    matcher = difflib.SequenceMatcher(a=left_lines, b=right_lines, autojunk=False)
+    for tag, i1, i2, j1, j2 in matcher.get_opcodes():
+        if tag != "replace" or i2 - i1 != j2 - j1:
+            continue
+        for offset in range(i2 - i1):
+            left_line, right_line = left_lines[i1 + offset], right_lines[j1 + offset]
+            line_decisions = compare_line(left_line, right_line, tolerance, f"line {j1 + offset + 1}")
+            if not line_decisions:
+                continue
+            decisions.extend(line_decisions)
+            if all(decision.accepted for decision in line_decisions):
+                adjusted[j1 + offset] = left_line
+    return "".join(adjusted), decisions
+
+
+def render_decisions(tolerance: FloatTolerance, decisions: list[ToleranceDecision]) -> list[str]:
+    """Markup lines summarizing the decisions, for rich.print."""
+    accepted = sum(1 for decision in decisions if decision.accepted)
+    lines = [
+        f"[bold]Float tolerance {tolerance}:[/bold] [green]{accepted} accepted[/green], "
+        f"[red]{len(decisions) - accepted} rejected[/red]"
+    ]
+    for decision in decisions:
+        color = "green" if decision.accepted else "red"
+        lines.append(f"  [{color}]{escape(str(decision))}[/{color}]")
+    return lines
+
+
+# 🥣🔬🔚
@@ -6,6 +6,7 @@
 
 """CLI commands for HCL operations."""
 
+import json
 import pathlib
 import sys
 
@@ -191,4 +192,22 @@ def _handle_stdout_output(content_or_none: str | bytes | None) -> None:
         sys.stdout.buffer.flush()
 
 
+@hcl_cli.command("unicode")
+@click.option("--case", "cases", multiple=True, help="Only run these cases (repeatable or comma-separated).")
+def unicode_command(cases: tuple[str, ...]) -> None:
+    """Report how HCL parses, normalizes and measures a suite of unicode strings.
+
+    Runs the same suite as 'soup-go hcl unicode' and prints a report of the
+    same shape; see tofusoup.common.unicode_probe for where Python and Go
+    are expected to differ."""
+    from tofusoup.common.unicode_probe import probe_hcl_string, run_unicode_probe
+
+    names = [name for case in cases for name in case.split(",") if name]
+    try:
+        report = run_unicode_probe("hcl", names, probe_hcl_string)
+    except ValueError as e:
+        raise click.BadParameter(str(e), param_hint="--case") from e
+    click.echo(json.dumps(report, indent=2))
+
+
 # 🥣🔬🔚
@@ -16,7 +16,9 @@ from tofusoup.config.defaults import DEFAULT_GRPC_ADDRESS
 
 # Use correct relative import for generated protobuf modules.
 from ..harness.proto.kv import kv_pb2, kv_pb2_grpc
+from .errors import kv_error_name
 from .server import serve_plugin
+from .timing import CallTimings
 from .validation import (
     CurveNotSupportedError,
     LanguagePairNotSupportedError,
@@ -39,36 +41,143 @@ def kv_cli() -> None:
     """Commands for the Key-Value store."""
 
 
+def _auth_metadata(auth_token: str | None) -> list[tuple[str, str]] | None:
+    """Metadata carrying the bearer token for servers run with --auth-token."""
+    return [("authorization", f"Bearer {auth_token}")] if auth_token else None
+
+
+auth_token_option = click.option(
+    "--auth-token",
+    envvar="KV_AUTH_TOKEN",
+    default=None,
+    help="Bearer token to send in 'authorization' metadata (default $KV_AUTH_TOKEN).",
+)
+
+deadline_ms_option = click.option(
+    "--deadline-ms",
+    type=int,
+    default=None,
+    help="Give the call a deadline this many milliseconds away.",
+)
+
+timing_option = click.option(
+    "--timing",
+    is_flag=True,
+    help='Print per-call latency percentiles and a histogram as a final {"timing": ...} JSON line.',
+)
+
+
+def _timeout(deadline_ms: int | None) -> float | None:
+    return deadline_ms / 1000 if deadline_ms else None
+
+
+def _echo_received_deadline(call: grpc.Call, deadline_ms: int | None) -> None:
+    """Report the deadline the server says it received, if one was sent."""
+    if not deadline_ms:
+        return
+    received = dict(call.trailing_metadata() or ()).get("kv-received-deadline-ms")
+    if received is not None:
+        click.echo(f"Server received deadline: {received}ms (sent {deadline_ms}ms)", err=True)
+
+
+def _rpc_error_message(error: grpc.RpcError) -> str:
+    """An RPC error's message, prefixed with its KVError code if it has one."""
+    name = kv_error_name(error)
+    if name is None:
+        return f"RPC Error: {error.details()}"
+    return f"RPC Error [{name}]: {error.details()}"
+
+
 @kv_cli.command("put")
 @click.option("--address", default=DEFAULT_GRPC_ADDRESS, help="Address of the gRPC server.")
+@auth_token_option
+@deadline_ms_option
+@timing_option
+@click.option(
+    "--idempotency-token",
+    default="",
+    help="Send this idempotency token; the server says whether it applied or deduped the Put.",
+)
 @click.argument("key")
 @click.argument("value")
-def kv_put(address: str, key: str, value: str) -> None:
+def kv_put(
+    address: str,
+    auth_token: str | None,
+    deadline_ms: int | None,
+    timing: bool,
+    idempotency_token: str,
+    key: str,
+    value: str,
+) -> None:
     """Puts a key-value pair into the KV store."""
+    timings = CallTimings()
     try:
         with grpc.insecure_channel(address) as channel:
             stub = kv_pb2_grpc.KVStub(channel)
-            stub.Put(kv_pb2.PutRequest(key=key.encode(), value=value.encode()))
-            click.echo(f"Successfully put key '{key}'")
+            with timings.measure("/proto.KV/Put"):
+                _, call = stub.Put.with_call(
+                    kv_pb2.PutRequest(
+                        key=key.encode(), value=value.encode(), idempotency_token=idempotency_token
+                    ),
+                    metadata=_auth_metadata(auth_token),
+                    timeout=_timeout(deadline_ms),
+                )
+            _echo_received_deadline(call, deadline_ms)
+            result = dict(call.trailing_metadata() or ()).get("kv-idempotency-result")
+            if idempotency_token and result:
+                click.echo(f"Successfully put key '{key}' ({result})")
+            else:
+                click.echo(f"Successfully put key '{key}'")
     except grpc.RpcError as e:
-        click.echo(f"RPC Error: {e.details()}", err=True)
+        click.echo(_rpc_error_message(e), err=True)
+    finally:
+        if timing:
+            click.echo(timings.to_json())
 
 
 @kv_cli.command("get")
 @click.option("--address", default=DEFAULT_GRPC_ADDRESS, help="Address of the gRPC server.")
+@auth_token_option
+@deadline_ms_option
+@timing_option
+@click.option("--wait", is_flag=True, help="Ask the server to block until the key has a value.")
+@click.option(
+    "--wait-timeout-ms",
+    type=int,
+    default=0,
+    help="With --wait, fail with WAIT_TIMEOUT after this many milliseconds (0 waits until the deadline).",
+)
 @click.argument("key")
-def kv_get(address: str, key: str) -> None:
+def kv_get(
+    address: str,
+    auth_token: str | None,
+    deadline_ms: int | None,
+    timing: bool,
+    wait: bool,
+    wait_timeout_ms: int,
+    key: str,
+) -> None:
     """Gets a value from the KV store by key."""
+    timings = CallTimings()
     try:
         with grpc.insecure_channel(address) as channel:
             stub = kv_pb2_grpc.KVStub(channel)
-            response = stub.Get(kv_pb2.GetRequest(key=key.encode()))
+            with timings.measure("/proto.KV/Get"):
+                response, call = stub.Get.with_call(
+                    kv_pb2.GetRequest(key=key.encode(), wait=wait, wait_timeout_ms=wait_timeout_ms),
+                    metadata=_auth_metadata(auth_token),
+                    timeout=_timeout(deadline_ms),
+                )
+            _echo_received_deadline(call, deadline_ms)
             if response.value:
                 click.echo(response.value.decode())
             else:
                 click.echo(f"Key '{key}' not found.", err=True)
     except grpc.RpcError as e:
-        click.echo(f"RPC Error: {e.details()}", err=True)
+        click.echo(_rpc_error_message(e), err=True)
+    finally:
+        if timing:
+            click.echo(timings.to_json())
 
 
 @kv_cli.command("server")
@@ -159,6 +268,189 @@ def server_start(tls_mode: str, tls_key_type: str, tls_curve: str, transport: st
     )
 
 
+@kv_cli.command("stat")
+@click.option("--address", default=DEFAULT_GRPC_ADDRESS, help="Address of the gRPC server.")
+@auth_token_option
+@click.option(
+    "--output-format",
+    type=click.Choice(["text", "json"]),
+    default="text",
+    show_default=True,
+    help="Output format.",
+)
+@click.argument("prefix", default="")
+def kv_stat(address: str, auth_token: str | None, output_format: str, prefix: str) -> None:
+    """Show per-key value sizes and request/response bytes.
+
+    Lists each key under PREFIX with its stored size and SHA-256 and the
+    Puts and Gets made for it since the server started. The JSON report
+    matches `soup-go rpc kv stat --output-format json`."""
+    import json
+
+    try:
+        with grpc.insecure_channel(address) as channel:
+            response = kv_pb2_grpc.KVStub(channel).Stat(
+                kv_pb2.StatRequest(prefix=prefix), metadata=_auth_metadata(auth_token)
+            )
+    except grpc.RpcError as e:
+        raise click.ClickException(_rpc_error_message(e)) from e
+    report = {"prefix": prefix}
+    report.update({field.name: getattr(response, field.name) for field in response.DESCRIPTOR.fields})
+    report["keys"] = [
+        {field.name: getattr(stat, field.name) for field in stat.DESCRIPTOR.fields} for stat in response.keys
+    ]
+
+    if output_format == "json":
+        click.echo(json.dumps(report))
+        return
+    click.echo(
+        f"{'KEY':<24} {'STORED':>6} {'SIZE':>10} {'PUTS':>6} {'GETS':>6} "
+        f"{'REJECTED':>8} {'REQ_BYTES':>10} {'RESP_BYTES':>10}"
+    )
+    for stat in report["keys"]:
+        click.echo(
+            f"{stat['key']:<24} {str(stat['stored']).lower():>6} {stat['size']:>10} {stat['puts']:>6} "
+            f"{stat['gets']:>6} {stat['rejected_puts']:>8} {stat['request_bytes']:>10} "
+            f"{stat['response_bytes']:>10}"
+        )
+    limit = f"{report['max_value_bytes']} bytes" if report["max_value_bytes"] else "unlimited"
+    click.echo(
+        f"stored {report['stored_bytes']} bytes; {report['request_bytes']} request bytes, "
+        f"{report['response_bytes']} response bytes; max value size {limit}"
+    )
+
+
+@kv_cli.group("admin")
+def kv_admin_cli() -> None:
+    """KV store maintenance operations."""
+
+
+@kv_admin_cli.command("compact")
+@click.option(
+    "--address",
+    default=None,
+    help="Compact a running server through the Compact RPC instead of the local storage directory.",
+)
+@auth_token_option
+@click.option(
+    "--storage-dir",
+    envvar="KV_STORAGE_DIR",
+    type=click.Path(file_okay=False, path_type=Path),
+    default=None,
+    help="Local storage directory (default $KV_STORAGE_DIR or the cache dir).",
+)
+@click.option(
+    "--entry-ttl",
+    envvar="KV_ENTRY_TTL",
+    default="",
+    help="Local compaction: remove entries not written for this long, e.g. 1h (default $KV_ENTRY_TTL).",
+)
+@click.option("--dry-run", is_flag=True, help="Report what would be removed without removing it.")
+@click.option(
+    "--output-format",
+    type=click.Choice(["text", "json"]),
+    default="text",
+    show_default=True,
+    help="Output format.",
+)
+def kv_admin_compact(
+    address: str | None,
+    auth_token: str | None,
+    storage_dir: Path | None,
+    entry_ttl: str,
+    dry_run: bool,
+    output_format: str,
+) -> None:
+    """Garbage collect expired KV entries and report reclaimed bytes.
+
+    Entries expire once not written for longer than the entry TTL. With
+    --address the server compacts its own store with its own TTL. The JSON
+    report matches `soup-go rpc kv admin compact --output-format json`."""
+    import json
+
+    from tofusoup.common.utils import get_cache_dir
+
+    from .compaction import compact_storage
+    from .server import parse_go_duration
+
+    if address:
+        try:
+            with grpc.insecure_channel(address) as channel:
+                response = kv_pb2_grpc.KVStub(channel).Compact(
+                    kv_pb2.CompactRequest(dry_run=dry_run), metadata=_auth_metadata(auth_token)
+                )
+        except grpc.RpcError as e:
+            raise click.ClickException(_rpc_error_message(e)) from e
+        report = {field.name: getattr(response, field.name) for field in response.DESCRIPTOR.fields}
+        report["removed_keys"] = list(response.removed_keys)
+    else:
+        try:
+            ttl = parse_go_duration(entry_ttl)
+        except ValueError as e:
+            raise click.BadParameter(str(e), param_hint="--entry-ttl") from e
+        report = compact_storage(storage_dir or get_cache_dir() / "kv-store", ttl, dry_run=dry_run)
+
+    if output_format == "json":
+        click.echo(json.dumps(report))
+        return
+    verb = "would remove" if report["dry_run"] else "removed"
+    click.echo(
+        f"scanned {report['scanned_keys']} keys, {verb} {len(report['removed_keys'])} expired "
+        f"({report['reclaimed_bytes']} bytes reclaimed), "
+        f"{report['live_keys']} live keys ({report['live_bytes']} bytes)"
+    )
+    if report["evicted_keys"]:
+        click.echo(
+            f"evicted {report['evicted_keys']} keys ({report['evicted_bytes']} bytes) since the server started"
+        )
+
+
+@kv_admin_cli.command("fsck")
+@click.option(
+    "--storage-dir",
+    envvar="KV_STORAGE_DIR",
+    type=click.Path(file_okay=False, path_type=Path),
+    default=None,
+    help="Storage directory to check (default $KV_STORAGE_DIR or the cache dir).",
+)
+@click.option("--repair", is_flag=True, help="Carry out the repair plan.")
+@click.option(
+    "--output-format",
+    type=click.Choice(["text", "json"]),
+    default="text",
+    show_default=True,
+    help="Output format.",
+)
+def kv_admin_fsck(storage_dir: Path | None, repair: bool, output_format: str) -> None:
+    """Check KV storage for interrupted writes and index mismatches.
+
+    Prints a repair plan for orphaned lock, temporary and metadata files,
+    values missing or disagreeing with their metadata, and truncated values.
+    Stop any server using the directory first. Exits non-zero if issues
+    remain. The JSON report matches `soup-go rpc kv admin fsck`."""
+    import json
+
+    from tofusoup.common.utils import get_cache_dir
+
+    from .storage import fsck_storage
+
+    report = fsck_storage(storage_dir or get_cache_dir() / "kv-store", repair=repair)
+    if output_format == "json":
+        click.echo(json.dumps(report))
+    else:
+        for issue in report["issues"]:
+            done = " (done)" if issue["repaired"] else ""
+            click.echo(f"{issue['kind']:<17} {issue['action']:<12} {issue['key']}: {issue['detail']}{done}")
+        click.echo(
+            f"checked {report['scanned_keys']} keys in {report['storage_dir']}: "
+            f"{report['ok_keys']} ok, {len(report['issues'])} issues"
+        )
+    if not report["clean"]:
+        raise click.ClickException(
+            f"storage has {len(report['issues'])} issues; rerun with --repair to fix them"
+        )
+
+
 @click.group("validate")
 def validate_cli() -> None:
     """Commands for validation."""
@@ -203,6 +495,59 @@ def validate_connection(client: str, server: str, curve: str) -> None:
     _print_validation_summary(errors, warnings)
 
 
+@validate_cli.command("transport")
+@click.option(
+    "--modes",
+    default="tcp,unix",
+    show_default=True,
+    help="Comma-separated transports to check: tcp, unix, npipe.",
+)
+@click.option("--timeout", default=10.0, show_default=True, help="Per-transport timeout in seconds.")
+@click.option(
+    "--output-format",
+    type=click.Choice(["text", "json"]),
+    default="text",
+    show_default=True,
+    help="Output format.",
+)
+def validate_transport(modes: str, timeout: float, output_format: str) -> None:
+    """Check which transports the Python KV server and client support.
+
+    Starts the server in-process on each transport and does a Put/Get round
+    trip over it. Each transport is reported as supported, unsupported (it
+    can't be served here) or failed. The JSON report matches
+    `soup-go rpc validate transport --output-format json`.
+
+    Exits non-zero if any requested transport is not supported."""
+    import json
+
+    from provide.foundation import perr, pout
+
+    from .transport_check import SUPPORTED, UNSUPPORTED, check_transports
+
+    try:
+        report = check_transports([m.strip() for m in modes.split(",") if m.strip()], timeout)
+    except ValueError as e:
+        raise click.UsageError(str(e)) from e
+
+    if output_format == "json":
+        click.echo(json.dumps(report, indent=2))
+    else:
+        for result in report["transports"]:
+            line = f"{result['transport']:<6} {result['status']:<12} {result['duration_ms']:7.1f}ms"
+            if error := result.get("error"):
+                line += f"  {error}"
+            if result["status"] == SUPPORTED:
+                pout(f"✓ {line}", color="green")
+            elif result["status"] == UNSUPPORTED:
+                pout(f"- {line}", color="yellow")
+            else:
+                perr(f"✗ {line}", color="red")
+
+    if any(result["status"] != SUPPORTED for result in report["transports"]):
+        sys.exit(1)
+
+
 def _detect_server_language(server: str) -> tuple[str, str]:
     from provide.foundation import perr, pout
 
@@ -296,7 +641,57 @@ def _print_validation_summary(errors: list[str], warnings: list[str]) -> None:
         sys.exit(0)
 
 
+@click.group("trust")
+def trust_cli() -> None:
+    """Share CA trust bundles between hosts (same format as soup-go rpc trust)."""
+
+
+@trust_cli.command("export")
+@click.option(
+    "--cert-file",
+    type=click.Path(exists=True, dir_okay=False, path_type=Path),
+    required=True,
+    help="PEM file holding the CA (or self-signed server) certificate.",
+)
+@click.option("--dir", "directory", type=click.Path(file_okay=False, path_type=Path), required=True)
+@click.option("--name", default="default", show_default=True, help="Bundle name.")
+def trust_export(cert_file: Path, directory: Path, name: str) -> None:
+    """Write <dir>/<name>.trust.json for clients on other hosts to import."""
+    from provide.foundation import pout
+
+    from .trust import TrustBundleError, make_trust_bundle, write_trust_bundle
+
+    try:
+        bundle = make_trust_bundle(name, cert_file.read_text())
+    except TrustBundleError as e:
+        raise click.ClickException(str(e)) from e
+    path = write_trust_bundle(directory, bundle)
+    pout(f"Exported trust bundle {name!r} to {path} (sha256 {bundle['fingerprint_sha256']})")
+
+
+@trust_cli.command("import")
+@click.option("--dir", "directory", type=click.Path(file_okay=False, path_type=Path), required=True)
+@click.option("--name", default="default", show_default=True, help="Bundle name.")
+@click.option(
+    "--out", type=click.Path(dir_okay=False, path_type=Path), required=True, help="Write the CA PEM here."
+)
+@click.option("--wait", default=0.0, show_default=True, help="Seconds to wait for the bundle to appear.")
+def trust_import(directory: Path, name: str, out: Path, wait: float) -> None:
+    """Verify a trust bundle and write its CA certificate to --out.
+
+    Prints the environment variable that points the client at it."""
+    from .trust import TrustBundleError, import_trust_bundle
+
+    try:
+        env = import_trust_bundle(directory, name, out, wait)
+    except TrustBundleError as e:
+        raise click.ClickException(str(e)) from e
+    for key, value in env.items():
+        click.echo(f"{key}={value}")
+
+
 rpc_cli.add_command(kv_cli)
 rpc_cli.add_command(validate_cli)
+rpc_cli.add_command(trust_cli)
 
 # 🥣🔬🔚
@@ -0,0 +1,66 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""Garbage collection of expired entries in the file-backed KV store.
+
+An entry expires once its file hasn't been written for longer than the
+entry TTL (KV_ENTRY_TTL, a Go duration). The report has the same shape as
+soup-go's `rpc kv admin compact --output-format json`:
+
+    {"scanned_keys": 3, "removed_keys": ["a"], "reclaimed_bytes": 7,
+     "live_keys": 2, "live_bytes": 14, "evicted_keys": 0, "evicted_bytes": 0,
+     "dry_run": false}
+
+Sizes are file sizes on disk. This store has no storage limits, so nothing
+is ever evicted and the eviction counts are always 0."""
+
+from pathlib import Path
+import time
+from typing import Any
+
+from tofusoup.rpc.storage import DATA_PREFIX, remove_entry
+
+
+def compact_storage(
+    storage_dir: str | Path, ttl_seconds: float, dry_run: bool = False, now: float | None = None
+) -> dict[str, Any]:
+    """Remove entries not written for longer than ttl_seconds (none if 0)."""
+    now = time.time() if now is None else now
+    report: dict[str, Any] = {
+        "scanned_keys": 0,
+        "removed_keys": [],
+        "reclaimed_bytes": 0,
+        "live_keys": 0,
+        "live_bytes": 0,
+        "evicted_keys": 0,
+        "evicted_bytes": 0,
+        "dry_run": dry_run,
+    }
+    directory = Path(storage_dir)
+    if not directory.is_dir():
+        return report
+
+    for path in sorted(directory.iterdir()):
+        if not path.name.startswith(DATA_PREFIX) or not path.is_file():
+            continue
+        try:
+            info = path.stat()
+        except FileNotFoundError:
+            continue
+        report["scanned_keys"] += 1
+
+        if ttl_seconds <= 0 or now - info.st_mtime <= ttl_seconds:
+            report["live_keys"] += 1
+            report["live_bytes"] += info.st_size
+            continue
+        key = path.name.removeprefix(DATA_PREFIX)
+        if not dry_run:
+            remove_entry(directory, key)
+        report["removed_keys"].append(key)
+        report["reclaimed_bytes"] += info.st_size
+    return report
+
+
+# 🥣🔬🔚
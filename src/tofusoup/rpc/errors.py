@@ -0,0 +1,89 @@
+#!/usr/bin/env python3
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""Typed KV application errors.
+
+KV servers attach a proto.KVError detail to the statuses they return, so
+harnesses classify failures by code instead of matching English messages
+like "key not found". The tables here match soup-go's rpc_errors.go: the
+server's maps each KVError code to the gRPC code its status carries, and
+the client's classifies statuses from servers that send no detail."""
+
+from google.protobuf import any_pb2
+from google.rpc import status_pb2  # googleapis-common-protos, via the OTLP exporters
+import grpc
+
+from tofusoup.harness.proto.kv import kv_pb2
+
+# Trailer gRPC carries a status's details in, as a serialized google.rpc.Status
+STATUS_DETAILS_TRAILER = "grpc-status-details-bin"
+
+# Server mapping table: the gRPC code of a status carrying each KVError code
+KV_ERROR_STATUS_CODES: dict[int, grpc.StatusCode] = {
+    kv_pb2.NOT_FOUND: grpc.StatusCode.NOT_FOUND,
+    kv_pb2.INVALID_KEY: grpc.StatusCode.INVALID_ARGUMENT,
+    kv_pb2.TOO_LARGE: grpc.StatusCode.INVALID_ARGUMENT,
+    kv_pb2.READONLY: grpc.StatusCode.FAILED_PRECONDITION,
+    kv_pb2.THROTTLED: grpc.StatusCode.RESOURCE_EXHAUSTED,
+    kv_pb2.WAIT_TIMEOUT: grpc.StatusCode.DEADLINE_EXCEEDED,
+}
+
+# Client mapping table for statuses without a KVError detail. Only gRPC codes
+# a single KVError code uses are mapped; INVALID_ARGUMENT could be either
+# INVALID_KEY or TOO_LARGE.
+KV_ERROR_FALLBACK_CODES: dict[grpc.StatusCode, int] = {
+    grpc.StatusCode.NOT_FOUND: kv_pb2.NOT_FOUND,
+    grpc.StatusCode.FAILED_PRECONDITION: kv_pb2.READONLY,
+}
+
+
+def status_details(code: int, key: bytes | str, message: str) -> bytes:
+    """A serialized google.rpc.Status carrying a KVError detail."""
+    if isinstance(key, bytes):
+        key = key.decode("utf-8", errors="replace")
+    detail = any_pb2.Any()
+    detail.Pack(kv_pb2.KVError(code=code, key=key))
+    status = status_pb2.Status(
+        code=KV_ERROR_STATUS_CODES[code].value[0],
+        message=message,
+        details=[detail],
+    )
+    return status.SerializeToString()
+
+
+def abort_kv_error(context: grpc.ServicerContext, code: int, key: bytes | str, message: str) -> None:
+    """Set the call's status from a KVError code, keeping the trailers the
+    handler already set. The handler returns an empty response after."""
+    trailers = tuple(context.trailing_metadata() or ())
+    context.set_trailing_metadata((*trailers, (STATUS_DETAILS_TRAILER, status_details(code, key, message))))
+    context.set_code(KV_ERROR_STATUS_CODES[code])
+    context.set_details(message)
+
+
+def kv_error_code(error: grpc.RpcError) -> int:
+    """Classify a client-side error: the code of its KVError detail, or else
+    the fallback for its gRPC code. Unclassified errors are
+    KV_ERROR_CODE_UNSPECIFIED."""
+    for key, value in error.trailing_metadata() or ():
+        if key != STATUS_DETAILS_TRAILER:
+            continue
+        status = status_pb2.Status.FromString(value)
+        for detail in status.details:
+            if detail.Is(kv_pb2.KVError.DESCRIPTOR):
+                kv_error = kv_pb2.KVError()
+                detail.Unpack(kv_error)
+                return kv_error.code
+    return KV_ERROR_FALLBACK_CODES.get(error.code(), kv_pb2.KV_ERROR_CODE_UNSPECIFIED)
+
+
+def kv_error_name(error: grpc.RpcError) -> str | None:
+    """The KVErrorCode name classifying error, or None if it's unclassified."""
+    code = kv_error_code(error)
+    if code == kv_pb2.KV_ERROR_CODE_UNSPECIFIED:
+        return None
+    return kv_pb2.KVErrorCode.Name(code)
+
+
+# 🥣🔬🔚
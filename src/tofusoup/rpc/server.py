@@ -24,22 +24,98 @@ Usage:
 
 import asyncio
 from datetime import datetime
+import faulthandler
 import hashlib
 import json
 import os
 from pathlib import Path
 import re
+import signal
+import sys
+import threading
 import time
 from typing import Any
 
+from google.protobuf import message as _message, unknown_fields
 import grpc
 from provide.foundation import logger
 
 from pyvider.rpcplugin.protocol.base import RPCPluginProtocol
 from pyvider.rpcplugin.server import RPCPluginServer
 from tofusoup.common.utils import get_cache_dir
-from tofusoup.config.defaults import DEFAULT_GRPC_PORT, ENV_KV_STORAGE_DIR
+from tofusoup.config.defaults import (
+    DEFAULT_GRPC_PORT,
+    ENV_KV_CRASH_AFTER,
+    ENV_KV_DIAG_FILE,
+    ENV_KV_ENTRY_TTL,
+    ENV_KV_FAULT_DELAY,
+    ENV_KV_IDEMPOTENCY_WINDOW,
+    ENV_KV_MAX_VALUE_BYTES,
+    ENV_KV_STORAGE_DIR,
+)
 from tofusoup.harness.proto.kv import kv_pb2, kv_pb2_grpc
+from tofusoup.rpc.compaction import compact_storage
+from tofusoup.rpc.errors import abort_kv_error
+from tofusoup.rpc.stats import (
+    STAT_FAILED,
+    STAT_OK,
+    STAT_TOO_LARGE,
+    KeyStats,
+    stat_storage,
+    value_too_large_message,
+)
+from tofusoup.rpc.storage import (
+    CRASH_FLUSH,
+    CRASH_LOCK_ACQUIRED,
+    CRASH_PUT,
+    CrashPoint,
+    crash_at,
+    locked_token,
+    record_token,
+    write_entry_metadata,
+)
+
+# Trailer metadata reporting fields of a request the server's schema doesn't
+# know, matching the soup-go server, so v2 clients can check how each
+# protobuf runtime treats them
+UNKNOWN_FIELDS_TRAILER = "kv-unknown-fields"
+UNKNOWN_PRESERVED_TRAILER = "kv-unknown-fields-preserved"
+# Trailer echoing the milliseconds left before the call's deadline when the
+# handler started, or "none"
+DEADLINE_TRAILER = "kv-received-deadline-ms"
+# Trailer telling a client whether a Put with an idempotency token was
+# "applied" or "deduped"
+IDEMPOTENCY_TRAILER = "kv-idempotency-result"
+# How long an applied Put's idempotency token dedupes retries by default
+DEFAULT_IDEMPOTENCY_WINDOW = 600.0
+# How often a waiting Get rereads storage, to see values written by other
+# servers sharing the storage directory
+WAIT_POLL_INTERVAL = 0.1
+
+_DURATION_UNITS = {"ns": 1e-9, "us": 1e-6, "µs": 1e-6, "ms": 1e-3, "s": 1.0, "m": 60.0, "h": 3600.0}
+_DURATION_PART = re.compile(r"(\d+(?:\.\d*)?)(ns|us|µs|ms|s|m|h)")
+
+
+def parse_go_duration(value: str) -> float:
+    """Parse a Go duration such as "500ms" or "1m30s" into seconds."""
+    if value in ("", "0"):
+        return 0.0
+    parts = _DURATION_PART.findall(value)
+    if not parts or "".join(n + u for n, u in parts) != value:
+        raise ValueError(f"invalid duration {value!r}")
+    return sum(float(n) * _DURATION_UNITS[u] for n, u in parts)
+
+
+def unknown_field_report(request: _message.Message) -> tuple[list[int], bool]:
+    """Return the unknown field numbers in request, in wire order without
+    duplicates, and whether they survive re-encoding the request."""
+    fields = [(f.field_number, f.wire_type, f.data) for f in unknown_fields.UnknownFieldSet(request)]
+    numbers = list(dict.fromkeys(number for number, _, _ in fields))
+    if not fields:
+        return numbers, True
+    reparsed = type(request).FromString(request.SerializeToString())
+    kept = [(f.field_number, f.wire_type, f.data) for f in unknown_fields.UnknownFieldSet(reparsed)]
+    return numbers, kept == fields
 
 
 class KV(kv_pb2_grpc.KVServicer):
@@ -56,12 +132,99 @@ class KV(kv_pb2_grpc.KVServicer):
         self.storage_dir = storage_dir
         self.key_pattern = re.compile(r"^[a-zA-Z0-9._-]+$")
         self.start_time = time.time()
-        logger.debug("Initialized KV servicer", storage_dir=storage_dir)
+        # Fault injection: hold every call this long, to exercise client deadlines
+        self.fault_delay = parse_go_duration(os.environ.get(ENV_KV_FAULT_DELAY, ""))
+        # Entries not written for this long are removed by Compact
+        self.entry_ttl = parse_go_duration(os.environ.get(ENV_KV_ENTRY_TTL, ""))
+        # Debug hook: exit at a point on the Put write path, for crash tests
+        self.crash_hook = CrashPoint.parse(os.environ.get(ENV_KV_CRASH_AFTER, ""))
+        # Puts of larger values are rejected with INVALID_ARGUMENT (0 is unlimited)
+        self.max_value_bytes = int(os.environ.get(ENV_KV_MAX_VALUE_BYTES) or 0)
+        # An applied Put's idempotency token dedupes retries for this long
+        self.idempotency_window = (
+            parse_go_duration(os.environ.get(ENV_KV_IDEMPOTENCY_WINDOW, "")) or DEFAULT_IDEMPOTENCY_WINDOW
+        )
+        self.stats = KeyStats()
+        # Notified by every Put, to wake waiting Gets
+        self.put_signal = threading.Condition()
+        logger.debug(
+            "Initialized KV servicer",
+            storage_dir=storage_dir,
+            fault_delay=self.fault_delay,
+            entry_ttl=self.entry_ttl,
+            max_value_bytes=self.max_value_bytes,
+        )
 
     def _validate_key(self, key: str) -> bool:
         """Validate that key contains only allowed characters [a-zA-Z0-9._-]"""
         return bool(self.key_pattern.match(key))
 
+    def _begin_call(self, method: str, request: _message.Message, context: grpc.ServicerContext) -> bool:
+        """Report request's unknown fields and deadline in the call's trailers,
+        then apply any injected delay.
+
+        Returns False if the call's deadline passed during the delay, in
+        which case the status is already set and the handler must return.
+        """
+        numbers, preserved = unknown_field_report(request)
+        if numbers:
+            logger.info("Request carried unknown fields", method=method, fields=numbers, preserved=preserved)
+        remaining = context.time_remaining()
+        if remaining is not None:
+            logger.info("Call has a deadline", method=method, remaining_ms=int(remaining * 1000))
+        context.set_trailing_metadata(
+            (
+                (UNKNOWN_FIELDS_TRAILER, ",".join(str(n) for n in numbers)),
+                (UNKNOWN_PRESERVED_TRAILER, "true" if preserved else "false"),
+                (DEADLINE_TRAILER, "none" if remaining is None else str(int(remaining * 1000))),
+            )
+        )
+
+        if self.fault_delay <= 0:
+            return True
+        if remaining is not None and remaining < self.fault_delay:
+            time.sleep(max(remaining, 0))
+            logger.info("Call deadline passed during injected delay", method=method)
+            context.set_code(grpc.StatusCode.DEADLINE_EXCEEDED)
+            context.set_details("deadline exceeded during injected delay")
+            return False
+        time.sleep(self.fault_delay)
+        return True
+
+    def _wait_for_value(self, request: kv_pb2.GetRequest, context: grpc.ServicerContext) -> bytes | None:
+        """Block a Get with wait set until its key has a value, as soup-go does.
+
+        Returns None with a WAIT_TIMEOUT status set once wait_timeout_ms
+        passes, or None once the call's deadline passes or it is cancelled.
+        Zero waits until the call ends.
+        """
+        give_up = time.monotonic() + request.wait_timeout_ms / 1000 if request.wait_timeout_ms else None
+        path = Path(self._get_file_path(request.key))
+        logger.debug("Waiting for key", key=request.key, wait_timeout_ms=request.wait_timeout_ms)
+        with self.put_signal:
+            while True:
+                try:
+                    return path.read_bytes()
+                except FileNotFoundError:
+                    pass
+                if not context.is_active():
+                    logger.info("Call ended while waiting for key", key=request.key)
+                    return None
+                interval = WAIT_POLL_INTERVAL
+                if give_up is not None:
+                    remaining = give_up - time.monotonic()
+                    if remaining <= 0:
+                        logger.info("Key had no value before the wait timeout", key=request.key)
+                        abort_kv_error(
+                            context,
+                            kv_pb2.WAIT_TIMEOUT,
+                            request.key,
+                            f"Key {request.key} had no value within {request.wait_timeout_ms}ms",
+                        )
+                        return None
+                    interval = min(interval, remaining)
+                self.put_signal.wait(interval)
+
     def _get_file_path(self, key: str) -> str:
         """Get the file path for a given key"""
         return f"{self.storage_dir}/kv-data-{key}"
@@ -130,6 +293,7 @@ class KV(kv_pb2_grpc.KVServicer):
                         cert_bytes = f.read()
                         cert_fingerprint = hashlib.sha256(cert_bytes).hexdigest()
                         server_handshake["cert_fingerprint"] = cert_fingerprint
+                        server_handshake["cert_fingerprint_algorithm"] = "sha256"
                 except Exception:
                     server_handshake["cert_fingerprint"] = None
 
@@ -151,20 +315,35 @@ class KV(kv_pb2_grpc.KVServicer):
             return value_bytes
 
     def Get(self, request: kv_pb2.GetRequest, context: grpc.ServicerContext) -> kv_pb2.GetResponse:
+        if not self._begin_call("Get", request, context):
+            return kv_pb2.GetResponse()
         if not self._validate_key(request.key):
             logger.error("Invalid key for Get operation", key=request.key)
-            context.set_code(grpc.StatusCode.INVALID_ARGUMENT)
-            context.set_details(
-                f'Key "{request.key}" contains invalid characters, only [a-zA-Z0-9._-] are allowed'
+            abort_kv_error(
+                context,
+                kv_pb2.INVALID_KEY,
+                request.key,
+                f'Key "{request.key}" contains invalid characters, only [a-zA-Z0-9._-] are allowed',
             )
             return kv_pb2.GetResponse()
+        if request.wait and request.wait_timeout_ms < 0:
+            logger.error("Invalid wait timeout for Get operation", wait_timeout_ms=request.wait_timeout_ms)
+            context.set_code(grpc.StatusCode.INVALID_ARGUMENT)
+            context.set_details("invalid wait_timeout_ms: must not be negative")
+            return kv_pb2.GetResponse()
 
         file_path = self._get_file_path(request.key)
         logger.debug("Retrieving value from file", key=request.key, file=file_path)
 
         try:
-            with Path(file_path).open("rb") as f:
-                raw_value = f.read()
+            if request.wait:
+                raw_value = self._wait_for_value(request, context)
+                if raw_value is None:
+                    self.stats.record("Get", request.key, request, None, STAT_FAILED)
+                    return kv_pb2.GetResponse()
+            else:
+                with Path(file_path).open("rb") as f:
+                    raw_value = f.read()
 
             # Enrich JSON values with server handshake information on Get
             enriched_value = self._enrich_json_with_handshake(raw_value, context)
@@ -176,13 +355,16 @@ class KV(kv_pb2_grpc.KVServicer):
                 raw_bytes=len(raw_value),
                 enriched_bytes=len(enriched_value),
             )
-            return kv_pb2.GetResponse(value=enriched_value)
+            response = kv_pb2.GetResponse(value=enriched_value)
+            self.stats.record("Get", request.key, request, response, STAT_OK)
+            return response
         except FileNotFoundError:
+            self.stats.record("Get", request.key, request, None, STAT_FAILED)
             logger.warning("Key not found during Get operation", key=request.key, file=file_path)
-            context.set_code(grpc.StatusCode.NOT_FOUND)
-            context.set_details(f"Key not found: {request.key}")
+            abort_kv_error(context, kv_pb2.NOT_FOUND, request.key, f"Key not found: {request.key}")
             return kv_pb2.GetResponse()
         except Exception as e:
+            self.stats.record("Get", request.key, request, None, STAT_FAILED)
             logger.error(
                 "Failed to read value from file",
                 key=request.key,
@@ -194,29 +376,102 @@ class KV(kv_pb2_grpc.KVServicer):
             return kv_pb2.GetResponse()
 
     def Put(self, request: kv_pb2.PutRequest, context: grpc.ServicerContext) -> kv_pb2.Empty:
+        if not self._begin_call("Put", request, context):
+            return kv_pb2.Empty()
         if not self._validate_key(request.key):
             logger.error("Invalid key for Put operation", key=request.key)
-            context.set_code(grpc.StatusCode.INVALID_ARGUMENT)
-            context.set_details(
-                f'Key "{request.key}" contains invalid characters, only [a-zA-Z0-9._-] are allowed'
+            abort_kv_error(
+                context,
+                kv_pb2.INVALID_KEY,
+                request.key,
+                f'Key "{request.key}" contains invalid characters, only [a-zA-Z0-9._-] are allowed',
+            )
+            return kv_pb2.Empty()
+
+        if 0 < self.max_value_bytes < len(request.value):
+            self.stats.record("Put", request.key, request, None, STAT_TOO_LARGE)
+            logger.warning(
+                "Rejecting Put with oversized value",
+                key=request.key,
+                size=len(request.value),
+                max_value_bytes=self.max_value_bytes,
+            )
+            abort_kv_error(
+                context,
+                kv_pb2.TOO_LARGE,
+                request.key,
+                value_too_large_message(len(request.value), self.max_value_bytes),
             )
             return kv_pb2.Empty()
 
+        if request.idempotency_token:
+            self._put_once(request, context)
+        else:
+            self._store(request, context)
+        return kv_pb2.Empty()
+
+    def _put_once(self, request: kv_pb2.PutRequest, context: grpc.ServicerContext) -> None:
+        """Store a Put carrying an idempotency token, unless one with the same
+        token, key and value was applied within the window, as soup-go does.
+
+        The kv-idempotency-result trailer says which happened. Reusing a
+        token for a different Put is INVALID_ARGUMENT.
+        """
+        token = request.idempotency_token
+        with locked_token(self.storage_dir, token) as record:
+            if record and time.time() * 1000 - record["applied_unix_ms"] < self.idempotency_window * 1000:
+                digest = hashlib.sha256(request.value).hexdigest()
+                if (record["key"], record["sha256"]) != (request.key, digest):
+                    logger.warning("Rejecting Put reusing an idempotency token", key=request.key)
+                    context.set_code(grpc.StatusCode.INVALID_ARGUMENT)
+                    context.set_details(
+                        f"invalid idempotency_token: was used for a different Put (key {record['key']}) "
+                        "within the idempotency window"
+                    )
+                    return
+                logger.info("Deduplicated Put", key=request.key, applied_unix_ms=record["applied_unix_ms"])
+                self.stats.record("Put", request.key, request, kv_pb2.Empty(), STAT_OK)
+                result = "deduped"
+            else:
+                if not self._store(request, context):
+                    return
+                record_token(self.storage_dir, token, request.key, request.value)
+                result = "applied"
+        trailers = tuple(context.trailing_metadata() or ())
+        context.set_trailing_metadata((*trailers, (IDEMPOTENCY_TRAILER, result)))
+
+    def _store(self, request: kv_pb2.PutRequest, context: grpc.ServicerContext) -> bool:
+        """Write a Put's value, returning False with INTERNAL set if it fails."""
         file_path = self._get_file_path(request.key)
         logger.debug("Storing value to file", key=request.key, file=file_path)
 
         try:
-            # Store raw value without enrichment (enrichment happens on Get)
-            with Path(file_path).open("wb") as f:
+            # Create the file without truncating it first, as soup-go's lock
+            # does, so both leave the same files at each crash point
+            with Path(file_path).open("ab") as f:
+                crash_at(self.crash_hook, CRASH_LOCK_ACQUIRED, request.key)
+                # Index the value before writing it, as soup-go does, so a
+                # write cut short shows up in fsck as a truncated value
+                write_entry_metadata(self.storage_dir, request.key, request.value)
+                # Store raw value without enrichment (enrichment happens on Get)
+                f.truncate(0)
                 f.write(request.value)
+                f.flush()
+                crash_at(self.crash_hook, CRASH_FLUSH, request.key)
+                os.fsync(f.fileno())
+            crash_at(self.crash_hook, CRASH_PUT, request.key)
             logger.info(
                 "Successfully stored value",
                 key=request.key,
                 file=file_path,
                 bytes=len(request.value),
             )
-            return kv_pb2.Empty()
+            with self.put_signal:
+                self.put_signal.notify_all()
+            self.stats.record("Put", request.key, request, kv_pb2.Empty(), STAT_OK)
+            return True
         except Exception as e:
+            self.stats.record("Put", request.key, request, None, STAT_FAILED)
             logger.error(
                 "Failed to write value to file",
                 key=request.key,
@@ -225,7 +480,60 @@ class KV(kv_pb2_grpc.KVServicer):
             )
             context.set_code(grpc.StatusCode.INTERNAL)
             context.set_details(f'Failed to write key "{request.key}" to file: {e}')
-            return kv_pb2.Empty()
+            return False
+
+    def Compact(
+        self, request: kv_pb2.CompactRequest, context: grpc.ServicerContext
+    ) -> kv_pb2.CompactResponse:
+        if not self._begin_call("Compact", request, context):
+            return kv_pb2.CompactResponse()
+        try:
+            report = compact_storage(self.storage_dir, self.entry_ttl, dry_run=request.dry_run)
+        except OSError as e:
+            logger.error("Failed to compact storage", storage_dir=self.storage_dir, error=str(e))
+            context.set_code(grpc.StatusCode.INTERNAL)
+            context.set_details(f"Failed to compact storage: {e}")
+            return kv_pb2.CompactResponse()
+        logger.info(
+            "Compacted storage",
+            removed=len(report["removed_keys"]),
+            reclaimed_bytes=report["reclaimed_bytes"],
+            dry_run=request.dry_run,
+        )
+        return kv_pb2.CompactResponse(**report)
+
+    def Stat(self, request: kv_pb2.StatRequest, context: grpc.ServicerContext) -> kv_pb2.StatResponse:
+        if not self._begin_call("Stat", request, context):
+            return kv_pb2.StatResponse()
+        try:
+            report = stat_storage(
+                self.storage_dir, self.stats.snapshot(), request.prefix, self.max_value_bytes
+            )
+        except OSError as e:
+            logger.error("Failed to stat storage", storage_dir=self.storage_dir, error=str(e))
+            context.set_code(grpc.StatusCode.INTERNAL)
+            context.set_details(f"Failed to stat storage: {e}")
+            return kv_pb2.StatResponse()
+        del report["prefix"]
+        report["keys"] = [kv_pb2.KeyStat(**stat) for stat in report["keys"]]
+        return kv_pb2.StatResponse(**report)
+
+
+def register_diagnostic_dump() -> None:
+    """Dump every thread's stack on SIGUSR1, to KV_DIAG_FILE or stderr.
+
+    soup-go servers also report connections and storage; the Python server
+    only has faulthandler's stack traces. Platforms without SIGUSR1 get
+    nothing.
+    """
+    if not hasattr(signal, "SIGUSR1"):
+        return
+    path = os.environ.get(ENV_KV_DIAG_FILE)
+    # faulthandler writes to the file descriptor when the signal arrives, so
+    # the file stays open for the life of the server
+    target = Path(path).open("a") if path else sys.stderr  # noqa: SIM115
+    faulthandler.register(signal.SIGUSR1, file=target, all_threads=True, chain=False)
+    logger.debug("Diagnostic dumps armed", signal="SIGUSR1", file=path or "stderr")
 
 
 def serve(server: grpc.aio.Server, storage_dir: str | None = None) -> None:
@@ -312,6 +620,8 @@ async def serve_plugin(
         storage_dir=storage_dir,
     )
 
+    register_diagnostic_dump()
+
     # Create protocol and handler
     protocol = KVProtocol(storage_dir=storage_dir)
     handler = KV(storage_dir=storage_dir)
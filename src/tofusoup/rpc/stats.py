@@ -0,0 +1,133 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""Per-key size accounting for the KV Stat RPC.
+
+KeyStats counts the Puts and Gets of each key and the serialized bytes of
+their request and response messages. stat_storage joins those counters
+with the stored values into the same report as soup-go's
+`rpc kv stat --output-format json`:
+
+    {"prefix": "", "keys": [{"key": "a", "stored": true, "size": 5,
+      "sha256": "...", "puts": 1, "gets": 1, "rejected_puts": 0,
+      "request_bytes": 13, "response_bytes": 7}],
+     "max_value_bytes": 10, "stored_bytes": 5, "request_bytes": 13,
+     "response_bytes": 7}
+
+Totals cover all keys, not just those under the prefix."""
+
+import hashlib
+from pathlib import Path
+import threading
+from typing import Any
+
+from google.protobuf import message as _message
+
+from tofusoup.rpc.storage import DATA_PREFIX
+
+STAT_OK = "ok"
+STAT_FAILED = "failed"
+STAT_TOO_LARGE = "too_large"
+
+
+def value_too_large_message(size: int, limit: int) -> str:
+    """The status message for a Put over the value size limit, as soup-go
+    words it."""
+    return f"invalid value: value is {size} bytes, more than the {limit} byte limit"
+
+
+class KeyStats:
+    """Call counts and message sizes per key since the server started."""
+
+    def __init__(self) -> None:
+        self._lock = threading.Lock()
+        self._keys: dict[str, dict[str, Any]] = {}
+
+    def record(
+        self,
+        method: str,
+        key: str,
+        request: _message.Message,
+        response: _message.Message | None,
+        outcome: str,
+    ) -> None:
+        """Count a Put or Get of key. The response is only sized if outcome
+        is STAT_OK."""
+        request_bytes = request.ByteSize()
+        response_bytes = response.ByteSize() if outcome == STAT_OK and response is not None else 0
+        with self._lock:
+            stat = self._keys.setdefault(
+                key,
+                {
+                    "key": key,
+                    "puts": 0,
+                    "gets": 0,
+                    "rejected_puts": 0,
+                    "request_bytes": 0,
+                    "response_bytes": 0,
+                },
+            )
+            if method == "Put":
+                stat["puts"] += 1
+            elif method == "Get":
+                stat["gets"] += 1
+            if outcome == STAT_TOO_LARGE:
+                stat["rejected_puts"] += 1
+            stat["request_bytes"] += request_bytes
+            stat["response_bytes"] += response_bytes
+
+    def snapshot(self) -> dict[str, dict[str, Any]]:
+        with self._lock:
+            return {key: dict(stat) for key, stat in self._keys.items()}
+
+
+def stat_storage(
+    storage_dir: str | Path, counters: dict[str, dict[str, Any]], prefix: str = "", max_value_bytes: int = 0
+) -> dict[str, Any]:
+    """Report keys under prefix that are stored or have counters."""
+    report: dict[str, Any] = {
+        "prefix": prefix,
+        "keys": [],
+        "max_value_bytes": max_value_bytes,
+        "stored_bytes": 0,
+        "request_bytes": sum(stat["request_bytes"] for stat in counters.values()),
+        "response_bytes": sum(stat["response_bytes"] for stat in counters.values()),
+    }
+
+    stored: dict[str, bytes] = {}
+    directory = Path(storage_dir)
+    if directory.is_dir():
+        for path in directory.iterdir():
+            if not path.name.startswith(DATA_PREFIX) or not path.is_file():
+                continue
+            try:
+                stored[path.name.removeprefix(DATA_PREFIX)] = path.read_bytes()
+            except FileNotFoundError:
+                continue
+
+    for key in sorted(stored.keys() | counters.keys()):
+        value = stored.get(key)
+        if value is not None:
+            report["stored_bytes"] += len(value)
+        if not key.startswith(prefix):
+            continue
+        stat = counters.get(key, {})
+        report["keys"].append(
+            {
+                "key": key,
+                "stored": value is not None,
+                "size": 0 if value is None else len(value),
+                "sha256": "" if value is None else hashlib.sha256(value).hexdigest(),
+                "puts": stat.get("puts", 0),
+                "gets": stat.get("gets", 0),
+                "rejected_puts": stat.get("rejected_puts", 0),
+                "request_bytes": stat.get("request_bytes", 0),
+                "response_bytes": stat.get("response_bytes", 0),
+            }
+        )
+    return report
+
+
+# 🥣🔬🔚
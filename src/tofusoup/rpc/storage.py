@@ -0,0 +1,274 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""The file-backed KV store layout shared with soup-go, and its checker.
+
+Each key has a data file holding its value (kv-data-<key>) and a metadata
+file indexing the value's size and SHA-256 (kv-meta-<key>). Metadata is
+written through kv-tmp-<key> and renamed into place, before the value, so
+a write cut short by a crash leaves a value shorter than its metadata says.
+
+fsck_storage reports the same issues and repairs, in the same JSON shape,
+as `soup-go rpc kv admin fsck --output-format json`.
+
+Puts with an idempotency token record it in kv-idem-<sha256 of token>,
+which both servers lock while deciding whether to apply a Put again.
+
+CrashPoint and crash_at are the KV_CRASH_AFTER debug hooks: a server
+exits with CRASH_EXIT_CODE at the same write path points as soup-go's
+`--crash-after`, so both storage layers can be crash tested alike."""
+
+from collections.abc import Iterator
+from contextlib import contextmanager
+import hashlib
+import json
+import os
+from pathlib import Path
+import sys
+import time
+from typing import Any
+
+try:
+    import fcntl
+except ImportError:  # Windows
+    fcntl = None
+
+DATA_PREFIX = "kv-data-"
+META_PREFIX = "kv-meta-"
+TEMP_PREFIX = "kv-tmp-"
+IDEMPOTENCY_PREFIX = "kv-idem-"
+
+REMOVE_FILE = "remove_file"
+REMOVE_ENTRY = "remove_entry"
+REINDEX = "reindex"
+
+CRASH_EXIT_CODE = 99
+# Points on the Put write path, in the order a Put reaches them
+CRASH_LOCK_ACQUIRED = "lock-acquired"
+CRASH_FLUSH = "flush"
+CRASH_PUT = "put"
+
+
+class CrashPoint:
+    """A parsed crash point: "lock-acquired", "flush" or "put:N"."""
+
+    def __init__(self, value: str) -> None:
+        self.point = value
+        self.puts = 0
+        self.done = 0
+        if value.startswith(f"{CRASH_PUT}:"):
+            count = value.removeprefix(f"{CRASH_PUT}:")
+            if not count.isdigit() or int(count) < 1:
+                raise ValueError(f"invalid crash point {value!r}: put:N needs a count of at least 1")
+            self.point, self.puts = CRASH_PUT, int(count)
+        elif value not in (CRASH_LOCK_ACQUIRED, CRASH_FLUSH):
+            expected = f"{CRASH_LOCK_ACQUIRED}, {CRASH_FLUSH} or {CRASH_PUT}:N"
+            raise ValueError(f"unknown crash point {value!r} (expected {expected})")
+
+    @classmethod
+    def parse(cls, value: str) -> "CrashPoint | None":
+        return cls(value) if value else None
+
+    def reached(self, point: str) -> bool:
+        """Whether the write path reaching point should crash; counts Puts."""
+        if point == CRASH_PUT:
+            if self.point != CRASH_PUT:
+                return False
+            self.done += 1
+            return self.done == self.puts
+        return self.point == point
+
+
+def crash_at(hook: CrashPoint | None, point: str, key: str) -> None:
+    """Exit at once, skipping cleanup as a real crash would, if hook is armed
+    for point."""
+    if hook is None or not hook.reached(point):
+        return
+    sys.stderr.write(f"💥 injected crash after {point} (key {key!r})\n")
+    sys.stderr.flush()
+    os._exit(CRASH_EXIT_CODE)
+
+
+def write_entry_metadata(storage_dir: str | Path, key: str, value: bytes) -> None:
+    """Atomically record value's size and digest as key's metadata."""
+    directory = Path(storage_dir)
+    meta = {"size": len(value), "sha256": hashlib.sha256(value).hexdigest()}
+    tmp = directory / f"{TEMP_PREFIX}{key}"
+    tmp.write_text(json.dumps(meta, separators=(",", ":")))
+    tmp.replace(directory / f"{META_PREFIX}{key}")
+
+
+def _token_path(storage_dir: str | Path, token: str) -> Path:
+    return Path(storage_dir) / f"{IDEMPOTENCY_PREFIX}{hashlib.sha256(token.encode()).hexdigest()}"
+
+
+@contextmanager
+def locked_token(storage_dir: str | Path, token: str) -> Iterator[dict[str, Any] | None]:
+    """Lock token's record, as soup-go does, and yield the Put it applied:
+    its token, key, sha256 and applied_unix_ms. Yields None if there is no
+    readable record."""
+    Path(storage_dir).mkdir(parents=True, exist_ok=True)
+    # Locking creates the file empty, which reads as no record
+    with _token_path(storage_dir, token).open("a+b") as f:
+        if fcntl is not None:
+            fcntl.flock(f.fileno(), fcntl.LOCK_EX)
+        f.seek(0)
+        try:
+            record = json.loads(f.read() or b"null")
+        except ValueError:
+            record = None
+        if not isinstance(record, dict) or record.get("token") != token:
+            record = None
+        yield record
+
+
+def record_token(storage_dir: str | Path, token: str, key: str, value: bytes) -> None:
+    """Record that the Put of value to key with token was applied. Call it
+    while holding locked_token."""
+    record = {
+        "token": token,
+        "key": key,
+        "sha256": hashlib.sha256(value).hexdigest(),
+        "applied_unix_ms": int(time.time() * 1000),
+    }
+    _token_path(storage_dir, token).write_text(json.dumps(record, separators=(",", ":")))
+
+
+def remove_entry(storage_dir: str | Path, key: str) -> None:
+    """Delete key's value and metadata."""
+    for prefix in (DATA_PREFIX, META_PREFIX):
+        (Path(storage_dir) / f"{prefix}{key}").unlink(missing_ok=True)
+
+
+def _check_entry(directory: Path, key: str, has_meta: bool) -> dict[str, Any] | None:
+    value = (directory / f"{DATA_PREFIX}{key}").read_bytes()
+    issue: dict[str, Any] = {"key": key, "file": f"{DATA_PREFIX}{key}"}
+    if not has_meta:
+        if not value:
+            issue.update(
+                kind="orphaned_lock",
+                action=REMOVE_FILE,
+                detail="empty value with no metadata, created by a lock on a key that was never written",
+            )
+        else:
+            issue.update(
+                kind="missing_metadata", action=REINDEX, detail=f"{len(value)} byte value has no metadata"
+            )
+        return issue
+
+    try:
+        meta = json.loads((directory / f"{META_PREFIX}{key}").read_text())
+        size, digest = int(meta["size"]), str(meta["sha256"])
+    except (ValueError, KeyError, TypeError):
+        size, digest = 0, ""
+    if not digest:
+        issue.update(
+            file=f"{META_PREFIX}{key}",
+            kind="corrupt_metadata",
+            action=REINDEX,
+            detail="metadata is not a size and sha256 object",
+        )
+        return issue
+
+    actual = hashlib.sha256(value).hexdigest()
+    if len(value) < size:
+        issue.update(
+            kind="truncated_value",
+            action=REMOVE_ENTRY,
+            detail=f"value is {len(value)} bytes, metadata says {size}",
+        )
+    elif actual != digest:
+        issue.update(
+            kind="digest_mismatch",
+            action=REMOVE_ENTRY,
+            detail=f"value sha256 {actual[:16]}, metadata says {digest[:16]}",
+        )
+    else:
+        return None
+    return issue
+
+
+def _repair(directory: Path, issue: dict[str, Any]) -> None:
+    if issue["action"] == REMOVE_FILE:
+        (directory / issue["file"]).unlink(missing_ok=True)
+    elif issue["action"] == REMOVE_ENTRY:
+        remove_entry(directory, issue["key"])
+    else:
+        key = issue["key"]
+        write_entry_metadata(directory, key, (directory / f"{DATA_PREFIX}{key}").read_bytes())
+    issue["repaired"] = True
+
+
+def fsck_storage(storage_dir: str | Path, repair: bool = False) -> dict[str, Any]:
+    """Check every value against its metadata and look for files left by
+    interrupted writes; with repair, carry out the plan. The directory
+    should not be in use by a server while it is checked."""
+    directory = Path(storage_dir)
+    report: dict[str, Any] = {
+        "storage_dir": str(storage_dir),
+        "scanned_keys": 0,
+        "ok_keys": 0,
+        "issues": [],
+        "repair": repair,
+        "clean": True,
+    }
+    if not directory.is_dir():
+        return report
+
+    data, meta, temps = set(), set(), []
+    for path in directory.iterdir():
+        if path.is_dir():
+            continue
+        name = path.name
+        if name.startswith(DATA_PREFIX):
+            data.add(name.removeprefix(DATA_PREFIX))
+        elif name.startswith(META_PREFIX):
+            meta.add(name.removeprefix(META_PREFIX))
+        elif name.startswith(TEMP_PREFIX):
+            temps.append(name.removeprefix(TEMP_PREFIX))
+
+    issues = [
+        {
+            "key": key,
+            "file": f"{TEMP_PREFIX}{key}",
+            "kind": "orphaned_temp",
+            "detail": "metadata write was never renamed into place",
+            "action": REMOVE_FILE,
+        }
+        for key in temps
+    ]
+    issues += [
+        {
+            "key": key,
+            "file": f"{META_PREFIX}{key}",
+            "kind": "orphaned_metadata",
+            "detail": "metadata for a key with no value",
+            "action": REMOVE_FILE,
+        }
+        for key in meta - data
+    ]
+    for key in data:
+        report["scanned_keys"] += 1
+        issue = _check_entry(directory, key, key in meta)
+        if issue is None:
+            report["ok_keys"] += 1
+        else:
+            issues.append(issue)
+
+    # Key order matching soup-go, with the same field order in each issue
+    fields = ("key", "file", "kind", "detail", "action")
+    report["issues"] = [
+        {**{f: issue[f] for f in fields}, "repaired": False}
+        for issue in sorted(issues, key=lambda i: (i["key"], i["file"]))
+    ]
+    report["clean"] = not report["issues"]
+    if repair:
+        for issue in report["issues"]:
+            _repair(directory, issue)
+        report["clean"] = True
+    return report
+
+
+# 🥣🔬🔚
@@ -0,0 +1,102 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""Per-call latency summaries for RPC client commands run with --timing.
+
+The report has the same shape as soup-go's, so the matrix report can read
+timing from either client:
+
+    {"timing": {"calls": 2, "errors": 0,
+                "latency_ms": {"min": ..., "avg": ..., "p50": ..., "p90": ...,
+                               "p95": ..., "p99": ..., "max": ...},
+                "histogram": [{"le_ms": 0.5, "count": 1}, ...],
+                "methods": {"/proto.KV/Put": {...}}}}"""
+
+from bisect import bisect_left
+from collections.abc import Iterator
+from contextlib import contextmanager
+import json
+import time
+from typing import Any
+
+# Upper bounds of the histogram buckets, matching soup-go
+TIMING_BUCKETS_MS = (0.1, 0.25, 0.5, 1, 2.5, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000)
+
+
+def summarize_latencies(samples_ms: list[float]) -> dict[str, float]:
+    """Min, average, percentiles and max, using soup-go's index rule."""
+    if not samples_ms:
+        return dict.fromkeys(("min", "avg", "p50", "p90", "p95", "p99", "max"), 0.0)
+    ordered = sorted(samples_ms)
+
+    def percentile(p: float) -> float:
+        return ordered[int(p * (len(ordered) - 1))]
+
+    return {
+        "min": ordered[0],
+        "avg": sum(ordered) / len(ordered),
+        "p50": percentile(0.50),
+        "p90": percentile(0.90),
+        "p95": percentile(0.95),
+        "p99": percentile(0.99),
+        "max": ordered[-1],
+    }
+
+
+def sparse_histogram(samples_ms: list[float]) -> list[dict[str, Any]]:
+    """Count samples into TIMING_BUCKETS_MS, leaving out empty buckets."""
+    counts = [0] * (len(TIMING_BUCKETS_MS) + 1)
+    for ms in samples_ms:
+        counts[bisect_left(TIMING_BUCKETS_MS, ms)] += 1
+    return [
+        {"le_ms": TIMING_BUCKETS_MS[i] if i < len(TIMING_BUCKETS_MS) else "+Inf", "count": n}
+        for i, n in enumerate(counts)
+        if n
+    ]
+
+
+class CallTimings:
+    """Collects the latency of each call a command makes."""
+
+    def __init__(self) -> None:
+        self.samples: dict[str, list[float]] = {}
+        self.errors: dict[str, int] = {}
+
+    @contextmanager
+    def measure(self, method: str) -> Iterator[None]:
+        """Time the enclosed call, counting it as an error if it raises."""
+        start = time.perf_counter()
+        failed = False
+        try:
+            yield
+        except BaseException:
+            failed = True
+            raise
+        finally:
+            self.samples.setdefault(method, []).append((time.perf_counter() - start) * 1000)
+            if failed:
+                self.errors[method] = self.errors.get(method, 0) + 1
+
+    def report(self) -> dict[str, Any]:
+        def summary(samples: list[float], errors: int) -> dict[str, Any]:
+            return {
+                "calls": len(samples),
+                "errors": errors,
+                "latency_ms": summarize_latencies(samples),
+                "histogram": sparse_histogram(samples),
+            }
+
+        every = [ms for samples in self.samples.values() for ms in samples]
+        report = summary(every, sum(self.errors.values()))
+        report["methods"] = {
+            method: summary(samples, self.errors.get(method, 0)) for method, samples in self.samples.items()
+        }
+        return report
+
+    def to_json(self) -> str:
+        return json.dumps({"timing": self.report()})
+
+
+# 🥣🔬🔚
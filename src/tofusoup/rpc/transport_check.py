@@ -0,0 +1,112 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""In-process transport checks for the Python KV server.
+
+Each transport is served by an in-process gRPC server with the KV servicer
+and exercised with a Put/Get round trip from a client over the same
+transport. Results use the same shape as ``soup-go rpc validate transport
+--output-format json`` so the matrix can read both harnesses' capabilities
+the same way."""
+
+from concurrent import futures
+import pathlib
+import sys
+import tempfile
+import time
+from typing import Any
+
+import grpc
+
+from tofusoup import __version__
+
+from ..harness.proto.kv import kv_pb2, kv_pb2_grpc
+from .server import KV
+
+TRANSPORT_MODES = ("tcp", "unix", "npipe")
+
+SUPPORTED = "supported"
+UNSUPPORTED = "unsupported"
+FAILED = "failed"
+
+
+def _bind(server: grpc.Server, mode: str, work_dir: pathlib.Path) -> tuple[str, str]:
+    """Bind server to mode, returning the client target and display address.
+
+    Raises OSError if the transport isn't available here."""
+    if mode == "tcp":
+        port = server.add_insecure_port("127.0.0.1:0")
+        if not port:
+            raise OSError("failed to bind a TCP port")
+        return f"127.0.0.1:{port}", f"127.0.0.1:{port}"
+    if mode == "unix":
+        path = work_dir / "kv.sock"
+        if not server.add_insecure_port(f"unix:{path}"):
+            raise OSError(f"failed to bind {path}")
+        return f"unix:{path}", str(path)
+    if mode == "npipe":
+        raise OSError("grpcio does not support named pipes")
+    raise ValueError(f"Unknown transport '{mode}'")
+
+
+def check_transport(mode: str, timeout: float = 10.0) -> dict[str, Any]:
+    """Serve KV over one transport and check a client round trip."""
+    result: dict[str, Any] = {"transport": mode}
+    start = time.monotonic()
+
+    def finish(status: str, error: str | None = None) -> dict[str, Any]:
+        result["status"] = status
+        result["duration_ms"] = round((time.monotonic() - start) * 1000, 3)
+        if error:
+            result["error"] = error
+        return result
+
+    # Unix socket paths are length-limited, so keep the directory short
+    with tempfile.TemporaryDirectory(prefix="soup-tr-") as tmp:
+        work_dir = pathlib.Path(tmp)
+        server = grpc.server(futures.ThreadPoolExecutor(max_workers=2))
+        kv_pb2_grpc.add_KVServicer_to_server(KV(storage_dir=str(work_dir)), server)
+        try:
+            target, address = _bind(server, mode, work_dir)
+        except (OSError, RuntimeError) as e:
+            return finish(UNSUPPORTED, f"failed to listen: {e}")
+        result["address"] = address
+
+        server.start()
+        try:
+            with grpc.insecure_channel(target) as channel:
+                grpc.channel_ready_future(channel).result(timeout=timeout)
+                stub = kv_pb2_grpc.KVStub(channel)
+                want = f"transport-{mode}".encode()
+                stub.Put(kv_pb2.PutRequest(key="transport-check", value=want), timeout=timeout)
+                got = stub.Get(kv_pb2.GetRequest(key="transport-check"), timeout=timeout).value
+                if got != want:
+                    return finish(FAILED, f"Get returned {got!r}, want {want!r}")
+        except grpc.FutureTimeoutError:
+            return finish(FAILED, f"failed to connect within {timeout}s")
+        except grpc.RpcError as e:
+            return finish(FAILED, f"{e.code().name}: {e.details()}")
+        finally:
+            server.stop(grace=None)
+
+    return finish(SUPPORTED)
+
+
+def check_transports(modes: list[str], timeout: float = 10.0) -> dict[str, Any]:
+    """Check each transport, returning a report in the soup-go format."""
+    unknown = [mode for mode in modes if mode not in TRANSPORT_MODES]
+    if unknown:
+        raise ValueError(
+            f"Unknown transport(s): {', '.join(unknown)} (expected one of: {', '.join(TRANSPORT_MODES)})"
+        )
+    return {
+        "harness": "python",
+        "version": __version__,
+        "os": sys.platform,
+        "transports": [check_transport(mode, timeout) for mode in modes],
+    }
+
+
+# 🥣🔬🔚
@@ -0,0 +1,156 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""Trust bundles for runs where the KV client and server are on different hosts.
+
+A bundle is a JSON file holding the CA certificate clients need to trust a
+server, plus metadata about it. It never holds a private key. It uses the same
+format as `soup-go rpc trust export`, so either side can export and the other
+import:
+
+    {"version": 1, "name": "go-server", "ca_pem": "-----BEGIN CERTIFICATE-----...",
+     "fingerprint_sha256": "<hex sha256 of the DER certificate>",
+     "fingerprint": "<hex digest of the DER certificate>", "fingerprint_algorithm": "sha256",
+     "not_after": "2026-10-18T03:42:20Z", "host": "...", "exported_by": "..."}
+
+Bundles live at <dir>/<name>.trust.json. soup-go can also keep them in the KV
+store under trust.<name>. fingerprint_sha256 is always SHA-256; fingerprint is
+in whatever algorithm the exporter chose (soup-go --digest-alg) and is checked
+when hashlib has that algorithm."""
+
+from datetime import UTC, datetime
+import hashlib
+import json
+import os
+from pathlib import Path
+import socket
+import ssl
+import tempfile
+import time
+from typing import Any
+
+from tofusoup.config.defaults import ENV_GRPC_DEFAULT_SSL_ROOTS_FILE_PATH
+
+TRUST_BUNDLE_VERSION = 1
+
+
+class TrustBundleError(Exception):
+    """A trust bundle is missing, malformed, tampered with or expired."""
+
+
+def trust_bundle_path(directory: Path, name: str) -> Path:
+    return directory / f"{name}.trust.json"
+
+
+def certificate_fingerprint(ca_pem: str, algorithm: str = "sha256") -> str:
+    """Hex digest of the first certificate in ca_pem, as soup-go computes it."""
+    try:
+        der = ssl.PEM_cert_to_DER_cert(ca_pem)
+    except ValueError as e:
+        raise TrustBundleError(f"not a PEM certificate: {e}") from e
+    return hashlib.new(algorithm, der).hexdigest()
+
+
+def make_trust_bundle(name: str, ca_pem: str, not_after: datetime | None = None) -> dict[str, Any]:
+    """Describe ca_pem as a bundle called name.
+
+    Without the cryptography package the certificate isn't parsed, so pass
+    not_after to record its expiry."""
+    from tofusoup import __version__
+
+    bundle: dict[str, Any] = {
+        "version": TRUST_BUNDLE_VERSION,
+        "name": name,
+        "ca_pem": ca_pem if ca_pem.endswith("\n") else ca_pem + "\n",
+        "fingerprint_sha256": certificate_fingerprint(ca_pem),
+        "fingerprint": certificate_fingerprint(ca_pem),
+        "fingerprint_algorithm": "sha256",
+        "host": socket.gethostname(),
+        "exported_by": f"soup {__version__}",
+        "created_at": datetime.now(UTC).isoformat().replace("+00:00", "Z"),
+    }
+    if not_after is not None:
+        bundle["not_after"] = not_after.astimezone(UTC).isoformat().replace("+00:00", "Z")
+    return bundle
+
+
+def verify_trust_bundle(bundle: dict[str, Any], now: datetime | None = None) -> None:
+    """Raise TrustBundleError unless the bundle is readable, untampered and unexpired."""
+    name = bundle.get("name", "?")
+    if bundle.get("version") != TRUST_BUNDLE_VERSION:
+        raise TrustBundleError(
+            f"unsupported trust bundle version {bundle.get('version')} (want {TRUST_BUNDLE_VERSION})"
+        )
+    ca_pem = bundle.get("ca_pem")
+    if not isinstance(ca_pem, str):
+        raise TrustBundleError(f"trust bundle {name!r} has no PEM certificate")
+    recorded = str(bundle.get("fingerprint_sha256", ""))
+    actual = certificate_fingerprint(ca_pem)
+    if actual != recorded.lower():
+        raise TrustBundleError(
+            f"trust bundle {name!r} fingerprint mismatch: recorded {recorded}, certificate is {actual}"
+        )
+    # The SHA-256 check already binds the certificate, so an algorithm hashlib
+    # doesn't have (blake3) is skipped
+    algorithm = bundle.get("fingerprint_algorithm")
+    if algorithm in hashlib.algorithms_available:
+        recorded = str(bundle.get("fingerprint", ""))
+        actual = certificate_fingerprint(ca_pem, algorithm)
+        if actual != recorded.lower():
+            raise TrustBundleError(
+                f"trust bundle {name!r} {algorithm} fingerprint mismatch: "
+                f"recorded {recorded}, certificate is {actual}"
+            )
+    if not_after := bundle.get("not_after"):
+        expires = datetime.fromisoformat(not_after.replace("Z", "+00:00"))
+        if (now or datetime.now(UTC)) > expires:
+            raise TrustBundleError(f"trust bundle {name!r} certificate expired at {not_after}")
+
+
+def write_trust_bundle(directory: Path, bundle: dict[str, Any]) -> Path:
+    """Write the bundle to directory, replacing an older one of the same name atomically."""
+    directory.mkdir(parents=True, exist_ok=True)
+    path = trust_bundle_path(directory, bundle["name"])
+    fd, tmp = tempfile.mkstemp(dir=directory, prefix=f".{bundle['name']}.trust-")
+    try:
+        with os.fdopen(fd, "w") as f:
+            json.dump(bundle, f, indent=2)
+            f.write("\n")
+        os.chmod(tmp, 0o644)
+        os.replace(tmp, path)
+    except BaseException:
+        Path(tmp).unlink(missing_ok=True)
+        raise
+    return path
+
+
+def read_trust_bundle(directory: Path, name: str, wait: float = 0.0) -> dict[str, Any]:
+    """Read and verify the bundle called name, waiting up to wait seconds for it to appear."""
+    path = trust_bundle_path(directory, name)
+    deadline = time.monotonic() + wait
+    while not path.exists() and time.monotonic() < deadline:
+        time.sleep(0.5)
+    try:
+        bundle = json.loads(path.read_text())
+    except FileNotFoundError as e:
+        raise TrustBundleError(f"no trust bundle at {path}") from e
+    except json.JSONDecodeError as e:
+        raise TrustBundleError(f"failed to decode trust bundle {path}: {e}") from e
+    verify_trust_bundle(bundle)
+    return bundle
+
+
+def import_trust_bundle(directory: Path, name: str, ca_file: Path, wait: float = 0.0) -> dict[str, str]:
+    """Write the CA from the bundle called name to ca_file.
+
+    Returns the environment that points the Python client's manual TLS mode
+    at it, for a runner to merge into a client's environment."""
+    bundle = read_trust_bundle(directory, name, wait)
+    ca_file.parent.mkdir(parents=True, exist_ok=True)
+    ca_file.write_text(bundle["ca_pem"])
+    return {ENV_GRPC_DEFAULT_SSL_ROOTS_FILE_PATH: str(ca_file)}
+
+
+# 🥣🔬🔚
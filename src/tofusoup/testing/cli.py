@@ -5,6 +5,8 @@
 
 
 import asyncio
+import datetime
+import pathlib
 import sys
 from typing import Any
 
@@ -15,15 +17,90 @@ from tofusoup.common.exceptions import TofuSoupError
 
 from .logic import (
     TEST_SUITE_CONFIG,
+    TestSuiteResult,
+    github_annotations,
     run_all_test_suites,
     run_test_suite,
 )
+from .tags import tag_pytest_args
+from .xfail import DEFAULT_MANIFEST, expired_entries, find_entry, load_xfail_manifest
+
+FORMAT_OPTION = click.option(
+    "--format",
+    "output_format",
+    type=click.Choice(["text", "github"]),
+    default="text",
+    show_default=True,
+    help="github also prints each failure as a GitHub Actions ::error annotation.",
+)
+
+XFAIL_MANIFEST_OPTION = click.option(
+    "--xfail-manifest",
+    type=click.Path(exists=True, dir_okay=False, path_type=pathlib.Path),
+    help=f"Known failures with owners and expiry dates (default: {DEFAULT_MANIFEST} if present).",
+)
+
+
+def tag_options(func: Any) -> Any:
+    """Adds the case tag filtering options (see tofusoup.testing.tags)."""
+    func = click.option(
+        "--allow-known-failures",
+        is_flag=True,
+        help="Run cases tagged known-failure as expected failures instead of skipping them.",
+    )(func)
+    func = click.option(
+        "--exclude-tags", help="Skip cases matching any of these key[:value] tags, e.g. severity:minor."
+    )(func)
+    return click.option(
+        "--include-tags", help="Run only cases matching one of these key[:value] tags, e.g. subsystem:cty."
+    )(func)
 
 
 def _print_results_report(results: list[Any]) -> None:
     """Prints a summary table and detailed failure report."""
 
 
+def _print_skipped_by_tags(results: list[TestSuiteResult]) -> None:
+    """Prints the cases skipped by tag filtering and why."""
+    skipped = [(result.suite_name, case) for result in results for case in result.skipped_by_tags]
+    if not skipped:
+        return
+    click.echo(f"Skipped by tags ({len(skipped)}):")
+    for suite_name, case in skipped:
+        click.echo(f"  {suite_name}: {case['nodeid']} ({case['reason']})")
+
+
+def _xfail_manifest_args(manifest: pathlib.Path | None) -> list[str]:
+    return [f"--xfail-manifest={manifest}"] if manifest is not None else []
+
+
+def _print_xfail_report(
+    results: list[TestSuiteResult], project_root: Any, manifest: pathlib.Path | None
+) -> None:
+    """Prints xfails that passed and expired xfail manifest entries."""
+    if manifest is None and (pathlib.Path(project_root) / DEFAULT_MANIFEST).exists():
+        manifest = pathlib.Path(project_root) / DEFAULT_MANIFEST
+    entries = load_xfail_manifest(manifest) if manifest is not None else []
+    xpassed = [(result.suite_name, nodeid) for result in results for nodeid in result.unexpected_passes]
+    if xpassed:
+        click.echo(f"Unexpected passes ({len(xpassed)}):")
+        for suite_name, nodeid in xpassed:
+            entry = find_entry(entries, nodeid)
+            owner = f" (owner {entry.owner})" if entry else ""
+            click.echo(f"  {suite_name}: {nodeid}{owner}")
+    if expired := expired_entries(entries, datetime.date.today()):
+        click.echo(f"Expired xfail entries ({len(expired)}), failing the run:")
+        for entry in expired:
+            click.echo(f"  {entry.case}: {entry.describe()}")
+
+
+def _print_github_annotations(results: list[TestSuiteResult], project_root: Any) -> None:
+    """Prints failures as GitHub Actions workflow annotations."""
+    for result in results:
+        for annotation in github_annotations(result, project_root):
+            click.echo(annotation)
+
+
 @click.group("test")
 @click.pass_context
 def test_cli(ctx: click.Context) -> None:
@@ -33,8 +110,18 @@ def test_cli(ctx: click.Context) -> None:
 
 
 @test_cli.command("all")
+@FORMAT_OPTION
+@tag_options
+@XFAIL_MANIFEST_OPTION
 @click.pass_context
-def test_all_command(ctx: click.Context) -> None:
+def test_all_command(
+    ctx: click.Context,
+    output_format: str,
+    include_tags: str | None,
+    exclude_tags: str | None,
+    allow_known_failures: bool,
+    xfail_manifest: pathlib.Path | None,
+) -> None:
     """Runs all available conformance test suites (CTY, RPC, Wire, etc.)."""
     verbose = ctx.obj.get("VERBOSE", False)
     project_root = ctx.obj.get("PROJECT_ROOT")
@@ -44,7 +131,13 @@ def test_all_command(ctx: click.Context) -> None:
 
     try:
         loaded_config = ctx.obj.get("TOFUSOUP_CONFIG", {})
-        asyncio.run(run_all_test_suites(project_root, loaded_config, verbose))
+        tag_args = tag_pytest_args(include_tags, exclude_tags, allow_known_failures)
+        pytest_args = [*tag_args, *_xfail_manifest_args(xfail_manifest)]
+        results = asyncio.run(run_all_test_suites(project_root, loaded_config, verbose, pytest_args))
+        _print_skipped_by_tags(results)
+        _print_xfail_report(results, project_root, xfail_manifest)
+        if output_format == "github":
+            _print_github_annotations(results, project_root)
 
     except TofuSoupError as e:
         logger.error(f"Error running all test suites: {e}", exc_info=verbose)
@@ -63,9 +156,21 @@ for suite_name_key in TEST_SUITE_CONFIG:
         help=f"Runs the {suite_config_data['description']}. Pass additional options after -- for pytest.",
         context_settings=dict(ignore_unknown_options=True),
     )
+    @FORMAT_OPTION
+    @tag_options
+    @XFAIL_MANIFEST_OPTION
     @click.argument("pytest_options", nargs=-1, type=click.UNPROCESSED)
     @click.pass_context
-    def _suite_command(ctx: click.Context, pytest_options: tuple[str, ...], snk: str = suite_name_key) -> None:
+    def _suite_command(
+        ctx: click.Context,
+        output_format: str,
+        include_tags: str | None,
+        exclude_tags: str | None,
+        allow_known_failures: bool,
+        xfail_manifest: pathlib.Path | None,
+        pytest_options: tuple[str, ...],
+        snk: str = suite_name_key,
+    ) -> None:
         verbose = ctx.obj.get("VERBOSE", False)
         project_root = ctx.obj.get("PROJECT_ROOT")
         if not project_root:
@@ -74,7 +179,13 @@ for suite_name_key in TEST_SUITE_CONFIG:
 
         try:
             loaded_config = ctx.obj.get("TOFUSOUP_CONFIG", {})
-            asyncio.run(run_test_suite(snk, project_root, loaded_config, verbose, list(pytest_options)))
+            tag_args = tag_pytest_args(include_tags, exclude_tags, allow_known_failures)
+            pytest_args = [*tag_args, *_xfail_manifest_args(xfail_manifest), *pytest_options]
+            result = asyncio.run(run_test_suite(snk, project_root, loaded_config, verbose, pytest_args))
+            _print_skipped_by_tags([result])
+            _print_xfail_report([result], project_root, xfail_manifest)
+            if output_format == "github":
+                _print_github_annotations([result], project_root)
         except TofuSoupError as e:
             logger.error(f"Error running test suite '{snk}': {e}", exc_info=verbose)
             sys.exit(1)
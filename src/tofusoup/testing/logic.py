@@ -17,6 +17,8 @@ from provide.foundation.errors import ResourceError, ValidationError, error_boun
 
 from tofusoup.common.exceptions import TofuSoupError
 from tofusoup.harness.logic import ensure_go_harness_build
+from tofusoup.testing.tags import skipped_by_tags
+from tofusoup.testing.xfail import unexpected_passes
 
 
 @attrs.define(frozen=True)
@@ -29,6 +31,8 @@ class TestSuiteResult:
     skipped: int
     errors: int
     failures: list[dict[str, Any]] = attrs.field(factory=list)
+    skipped_by_tags: list[dict[str, str]] = attrs.field(factory=list)
+    unexpected_passes: list[str] = attrs.field(factory=list)
 
 
 TEST_SUITE_CONFIG = {
@@ -132,6 +136,8 @@ async def _run_pytest_suite(
             skipped=summary.get("skipped", 0),
             errors=len(report.get("errors", [])),
             failures=failures,
+            skipped_by_tags=skipped_by_tags(report.get("tests", [])),
+            unexpected_passes=unexpected_passes(report.get("tests", [])),
         )
 
     try:
@@ -150,6 +156,55 @@ async def _run_pytest_suite(
         logger.debug(f"Test report saved to {report_path}")
 
 
+def _github_escape(value: str, is_property: bool = False) -> str:
+    value = value.replace("%", "%25").replace("\r", "%0D").replace("\n", "%0A")
+    if is_property:
+        value = value.replace(":", "%3A").replace(",", "%2C")
+    return value
+
+
+def github_annotations(result: TestSuiteResult, project_root: pathlib.Path) -> list[str]:
+    """Format a suite's failures as GitHub Actions `::error` workflow commands.
+
+    Each failure is annotated at the line pytest reports for the crash, or at
+    the test itself when there is no crash location."""
+    annotations = []
+    for failure in result.failures:
+        nodeid = failure.get("nodeid", "")
+        file_path = nodeid.split("::", 1)[0] if "::" in nodeid else None
+        line = None
+        message = None
+
+        for phase in ("call", "setup", "teardown"):
+            info = failure.get(phase)
+            if isinstance(info, dict) and info.get("outcome") in ("failed", "error"):
+                crash = info.get("crash") or {}
+                if crash.get("path") and crash.get("lineno"):
+                    file_path, line = crash["path"], crash["lineno"]
+                message = crash.get("message") or info.get("longrepr")
+                break
+        if line is None and isinstance(failure.get("lineno"), int):
+            # pytest-json-report's test lineno is 0-based
+            line = failure["lineno"] + 1
+        if message is None:
+            message = failure.get("longrepr") or "Test failed"
+
+        props = []
+        if file_path:
+            path = pathlib.Path(file_path)
+            if path.is_absolute():
+                try:
+                    path = path.relative_to(project_root)
+                except ValueError:
+                    pass
+            props.append(f"file={_github_escape(path.as_posix(), True)}")
+            if line:
+                props.append(f"line={line}")
+        props.append(f"title={_github_escape(f'{result.suite_name}: {nodeid}', True)}")
+        annotations.append(f"::error {','.join(props)}::{_github_escape(str(message))}")
+    return annotations
+
+
 async def run_test_suite(
     suite_name: str,
     project_root: pathlib.Path,
@@ -182,9 +237,15 @@ async def run_test_suite(
 
 
 async def run_all_test_suites(
-    project_root: pathlib.Path, loaded_config: dict[str, Any], verbose: bool
+    project_root: pathlib.Path,
+    loaded_config: dict[str, Any],
+    verbose: bool,
+    pytest_options: list[str] | None = None,
 ) -> list[TestSuiteResult]:
-    tasks = [run_test_suite(name, project_root, loaded_config, verbose, None) for name in TEST_SUITE_CONFIG]
+    tasks = [
+        run_test_suite(name, project_root, loaded_config, verbose, pytest_options)
+        for name in TEST_SUITE_CONFIG
+    ]
     return await asyncio.gather(*tasks)
 
 
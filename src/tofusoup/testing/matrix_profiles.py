@@ -75,6 +75,11 @@ class ProfileMatrix:
         self.matrix_profiles = self.matrix_config.get("profiles", [])
         self.parallel_jobs = self.matrix_config.get("parallel_jobs", MATRIX_PARALLEL_JOBS)
         self.timeout_minutes = self.matrix_config.get("timeout_minutes", MATRIX_TIMEOUT_MINUTES)
+        # Shared directory where a server host publishes its trust bundle
+        # (`soup-go rpc trust export --dir`) for clients on this host
+        self.trust_dir = self.matrix_config.get("trust_dir")
+        self.trust_bundle = self.matrix_config.get("trust_bundle", "default")
+        self.trust_wait = self.matrix_config.get("trust_wait_seconds", 0.0)
 
     def get_test_profiles(self) -> list[str]:
         """
@@ -194,6 +199,8 @@ class ProfileMatrix:
             # Set up environment for this profile
             env = dict(os.environ)
             env["WORKENV_PROFILE"] = profile_name
+            if self.trust_dir:
+                env.update(self._import_trust(profile_name))
 
             # Run soup stir with this profile
             result = await self._run_stir_test(profile_name, stir_directory, env)
@@ -253,6 +260,17 @@ class ProfileMatrix:
                 False,  # not dry_run
             )
 
+    def _import_trust(self, profile_name: str) -> dict[str, str]:
+        """Import the matrix trust bundle so clients trust a server on another host."""
+        import tempfile
+
+        from tofusoup.rpc.trust import import_trust_bundle
+
+        ca_file = Path(tempfile.gettempdir()) / "tofusoup-trust" / f"{self.trust_bundle}.pem"
+        env = import_trust_bundle(Path(self.trust_dir), self.trust_bundle, ca_file, self.trust_wait)
+        console.print(f"Trusting CA from bundle '{self.trust_bundle}' for profile '{profile_name}'")
+        return env
+
     async def _run_stir_test(
         self, profile_name: str, stir_directory: Path, env: dict[str, str]
     ) -> dict[str, Any]:
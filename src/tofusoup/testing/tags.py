@@ -0,0 +1,125 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""Conformance case tags and tag filtering.
+
+Cases declare tags with the `tags` marker, on a test or a whole module:
+
+    pytestmark = pytest.mark.tags(feature="msgpack")
+
+    @pytest.mark.tags(severity="critical", known_failure="pyvider-rpcplugin can't dial Go servers")
+    def test_pyclient_goserver(): ...
+
+Tags are subsystem, feature, severity and known-failure. subsystem defaults
+to the case's directory under conformance/ (cty, rpc, ...), and a marker
+closer to the test overrides one on its module.
+
+`--include-tags` runs only cases matching at least one filter and
+`--exclude-tags` skips cases matching any; a filter is `key:value`, or a
+bare `key` matching any value. Known failures are skipped unless
+`--allow-known-failures` is given, in which case they run as non-strict
+xfails. A new harness can so adopt the suite a subsystem at a time, and
+mark what it doesn't pass yet, without forking it. Cases skipped by tags
+get reasons starting with SKIP_PREFIX so the runner can report them."""
+
+from collections.abc import Iterable, Mapping
+import pathlib
+import re
+
+TAG_KEYS = ("subsystem", "feature", "severity", "known-failure")
+
+# Skip reasons from tag filtering start with this
+SKIP_PREFIX = "tags: "
+
+_SKIPPED_REASON = re.compile(r"Skipped: (" + re.escape(SKIP_PREFIX) + r".*?)['\"]?\)?$")
+
+
+def case_tags(path: pathlib.Path, markers: Iterable[Mapping[str, str]]) -> dict[str, str]:
+    """A case's tags from its path and its `tags` markers' keyword
+    arguments, closest marker first."""
+    tags: dict[str, str] = {}
+    parts = path.parts
+    if "conformance" in parts:
+        index = len(parts) - 1 - parts[::-1].index("conformance")
+        if index + 2 < len(parts):
+            tags["subsystem"] = parts[index + 1]
+    for kwargs in reversed(list(markers)):
+        for key, value in kwargs.items():
+            name = key.replace("_", "-")
+            if name not in TAG_KEYS:
+                raise ValueError(f'unknown tag "{name}" (expected one of: {", ".join(TAG_KEYS)})')
+            tags[name] = str(value)
+    return tags
+
+
+def parse_tag_filters(spec: str | None) -> list[tuple[str, str | None]]:
+    """Parse "subsystem:cty,known-failure" into (key, value) filters, value
+    None for a bare key."""
+    filters = []
+    for item in (spec or "").split(","):
+        item = item.strip()
+        if not item:
+            continue
+        key, _, value = item.partition(":")
+        if key not in TAG_KEYS:
+            raise ValueError(f'unknown tag "{key}" (expected one of: {", ".join(TAG_KEYS)})')
+        filters.append((key, value or None))
+    return filters
+
+
+def _matches(tags: Mapping[str, str], filters: list[tuple[str, str | None]]) -> list[str]:
+    """The filters tags matches, as written."""
+    return [
+        key if value is None else f"{key}:{value}"
+        for key, value in filters
+        if key in tags and (value is None or tags[key] == value)
+    ]
+
+
+def skip_reason(
+    tags: Mapping[str, str],
+    include: list[tuple[str, str | None]],
+    exclude: list[tuple[str, str | None]],
+    allow_known_failures: bool,
+) -> str | None:
+    """Why a case with these tags is skipped, or None if it runs."""
+    if include and not _matches(tags, include):
+        return f"{SKIP_PREFIX}not in --include-tags"
+    if excluded := _matches(tags, exclude):
+        return f"{SKIP_PREFIX}excluded by {', '.join(excluded)}"
+    if "known-failure" in tags and not allow_known_failures:
+        return f"{SKIP_PREFIX}known failure: {tags['known-failure']}"
+    return None
+
+
+def tag_pytest_args(include: str | None, exclude: str | None, allow_known_failures: bool) -> list[str]:
+    """pytest options passing the tag filters on to the conformance conftest.
+    Raises ValueError for an unknown tag, before pytest starts."""
+    parse_tag_filters(include)
+    parse_tag_filters(exclude)
+    args = []
+    if include:
+        args.append(f"--include-tags={include}")
+    if exclude:
+        args.append(f"--exclude-tags={exclude}")
+    if allow_known_failures:
+        args.append("--allow-known-failures")
+    return args
+
+
+def skipped_by_tags(tests: Iterable[Mapping]) -> list[dict[str, str]]:
+    """The cases a pytest-json-report skipped because of their tags, as
+    {"nodeid", "reason"} dicts."""
+    skipped = []
+    for test in tests:
+        if test.get("outcome") != "skipped":
+            continue
+        longrepr = str((test.get("setup") or {}).get("longrepr") or "")
+        if match := _SKIPPED_REASON.search(longrepr):
+            skipped.append({"nodeid": test.get("nodeid", ""), "reason": match.group(1)})
+    return skipped
+
+
+# 🥣🔬🔚
@@ -0,0 +1,101 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""The xfail manifest: tracked known failures with an owner and an expiry.
+
+Cross-language gaps belong in conformance/xfail.toml rather than in skip
+markers, so they keep running and stay visible:
+
+    [[xfail]]
+    case = "conformance/rpc/souptest_simple_matrix.py::test_pyclient_goserver_no_mtls"
+    reason = "pyvider-rpcplugin can't complete the handshake with go-plugin"
+    owner = "rpc"
+    expires = 2026-12-31
+
+case is a pytest node id. It matches that case, every parametrization of
+it (`...::test_x[...]`), or everything below it when it names a file or a
+class. Matching cases run as non-strict xfails. One that passes is
+reported as an unexpected pass, so the entry can be removed. An entry
+whose expiry date has passed no longer marks its cases, and fails the run
+until it is renewed or removed, so a gap can't be forgotten."""
+
+from collections.abc import Iterable, Mapping
+import datetime
+import pathlib
+import tomllib
+from typing import Any
+
+import attrs
+
+from tofusoup.common.exceptions import TofuSoupError
+
+# Where the conformance conftest looks for the manifest, relative to the
+# project root
+DEFAULT_MANIFEST = pathlib.Path("conformance") / "xfail.toml"
+
+
+@attrs.define(frozen=True)
+class XfailEntry:
+    case: str
+    reason: str
+    owner: str
+    expires: datetime.date
+
+    def matches(self, nodeid: str) -> bool:
+        """Whether nodeid is this entry's case, a parametrization of it, or
+        below it."""
+        return nodeid == self.case or nodeid.startswith((f"{self.case}[", f"{self.case}::"))
+
+    def expired(self, today: datetime.date) -> bool:
+        return self.expires < today
+
+    def describe(self) -> str:
+        return f"{self.reason} (owner {self.owner}, expires {self.expires.isoformat()})"
+
+
+def _entry(index: int, raw: Mapping[str, Any]) -> XfailEntry:
+    missing = [key for key in ("case", "reason", "owner", "expires") if not raw.get(key)]
+    if missing:
+        raise TofuSoupError(f"xfail entry {index} is missing {', '.join(missing)}")
+    expires = raw["expires"]
+    if isinstance(expires, datetime.datetime):
+        expires = expires.date()
+    elif isinstance(expires, str):
+        try:
+            expires = datetime.date.fromisoformat(expires)
+        except ValueError as e:
+            raise TofuSoupError(f"xfail entry {index} has an invalid expires date: {e}") from e
+    elif not isinstance(expires, datetime.date):
+        raise TofuSoupError(f"xfail entry {index} has an invalid expires date: {expires!r}")
+    return XfailEntry(
+        case=str(raw["case"]), reason=str(raw["reason"]), owner=str(raw["owner"]), expires=expires
+    )
+
+
+def load_xfail_manifest(path: pathlib.Path) -> list[XfailEntry]:
+    """The entries of an xfail manifest, in file order."""
+    try:
+        with path.open("rb") as f:
+            data = tomllib.load(f)
+    except (OSError, tomllib.TOMLDecodeError) as e:
+        raise TofuSoupError(f"Failed to read xfail manifest {path}: {e}") from e
+    return [_entry(index, raw) for index, raw in enumerate(data.get("xfail", []), start=1)]
+
+
+def find_entry(entries: Iterable[XfailEntry], nodeid: str) -> XfailEntry | None:
+    """The first entry matching nodeid."""
+    return next((entry for entry in entries if entry.matches(nodeid)), None)
+
+
+def expired_entries(entries: Iterable[XfailEntry], today: datetime.date) -> list[XfailEntry]:
+    return [entry for entry in entries if entry.expired(today)]
+
+
+def unexpected_passes(tests: Iterable[Mapping]) -> list[str]:
+    """The node ids of the xfail cases a pytest-json-report shows passing."""
+    return [test.get("nodeid", "") for test in tests if test.get("outcome") == "xpassed"]
+
+
+# 🥣🔬🔚
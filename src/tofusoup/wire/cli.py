@@ -11,7 +11,7 @@ import click
 import msgpack  # type: ignore[import-untyped]
 from rich import print_json
 
-from .logic import convert_json_to_msgpack, convert_msgpack_to_json
+from .logic import WireVersionError, convert_json_to_msgpack, convert_msgpack_to_json
 
 
 @click.group()
@@ -31,10 +31,17 @@ def wire() -> None:
     type=click.Path(dir_okay=False, resolve_path=True, path_type=Path),
     help="Output file path. Defaults to the input file with a .msgpack extension.",
 )
-def to_msgpack(input_path: Path, output_path: Path | None) -> None:
+@click.option(
+    "--wire-version",
+    type=click.Choice(["1", "2"]),
+    default="1",
+    show_default=True,
+    help="Wire format version: 1 (bare msgpack) or 2 (msgpack with a versioned header).",
+)
+def to_msgpack(input_path: Path, output_path: Path | None, wire_version: str) -> None:
     """Converts a JSON file to the MessagePack wire format."""
     try:
-        convert_json_to_msgpack(input_path, output_path)
+        convert_json_to_msgpack(input_path, output_path, wire_version=int(wire_version))
     except (json.JSONDecodeError, msgpack.exceptions.PackException) as e:
         raise click.ClickException(f"Error during conversion: {e}") from e
     except Exception as e:
@@ -68,6 +75,8 @@ def to_json(input_path: Path, output_path: Path | None, pretty: bool) -> None:
             print_json(json_data)
     except msgpack.exceptions.UnpackException as e:
         raise click.ClickException(f"Error unpacking MessagePack file: {e}") from e
+    except WireVersionError as e:
+        raise click.ClickException(f"{e.code}: {e}") from e
     except Exception as e:
         raise click.ClickException(f"An unexpected error occurred: {e}") from e
 
@@ -4,13 +4,98 @@
 #
 
 
+from dataclasses import dataclass
 import json
 from pathlib import Path
+import struct
 
 import msgpack  # type: ignore[import-untyped]
 
+from tofusoup.common.exceptions import TofuSoupError
 
-def convert_json_to_msgpack(input_path: Path, output_path: Path | None) -> Path:
+# Version 1 is bare msgpack. Version 2 prefixes it with an 8-byte header:
+# 0xc1 (never used by msgpack), "TW", the version, the header length as a
+# big-endian uint16, flags (bit 0: typed cty msgpack) and a reserved zero.
+WIRE_VERSION_1 = 1
+WIRE_VERSION_2 = 2
+WIRE_VERSION_LATEST = WIRE_VERSION_2
+WIRE_HEADER_MAGIC = b"\xc1TW"
+WIRE_HEADER_V2_LENGTH = 8
+WIRE_FLAG_TYPED = 0x01
+
+
+class WireVersionError(TofuSoupError):
+    """Raised for a malformed wire header or an unsupported version."""
+
+    def __init__(self, code: str, reason: str, version: int | None = None) -> None:
+        super().__init__(f"unsupported wire input: {reason}")
+        self.code = code
+        self.reason = reason
+        self.version = version
+
+
+@dataclass(frozen=True)
+class WireHeader:
+    """What was detected about a wire payload; mirrors soup-go's --report-version."""
+
+    wire_version: int
+    framed: bool = False
+    header_length: int = 0
+    typed: bool = False
+    assumed_version: int | None = None
+
+
+def frame_wire_payload(payload: bytes, typed: bool = False) -> bytes:
+    """Prefixes a msgpack payload with a version 2 header."""
+    flags = WIRE_FLAG_TYPED if typed else 0
+    return WIRE_HEADER_MAGIC + struct.pack(">BHBB", WIRE_VERSION_2, WIRE_HEADER_V2_LENGTH, flags, 0) + payload
+
+
+def parse_wire_header(data: bytes, allow_future: bool = False) -> tuple[WireHeader, bytes]:
+    """
+    Detects the wire version of data and returns it with the payload.
+
+    Versions newer than WIRE_VERSION_LATEST raise WireVersionError with code
+    UNSUPPORTED_WIRE_VERSION unless allow_future, in which case the header
+    is skipped by its length and the payload is treated as the latest version.
+    """
+    if not data or data[0] != WIRE_HEADER_MAGIC[0]:
+        return WireHeader(wire_version=WIRE_VERSION_1), data
+    if len(data) < 6 or data[:3] != WIRE_HEADER_MAGIC:
+        raise WireVersionError("INVALID_WIRE_HEADER", "input starts with 0xc1 but has no wire header")
+
+    version, length = struct.unpack(">BH", data[3:6])
+    if version < WIRE_VERSION_2:
+        raise WireVersionError(
+            "INVALID_WIRE_HEADER", f"header claims version {version}; only version 2 and later are framed"
+        )
+    if length < WIRE_HEADER_V2_LENGTH or length > len(data):
+        raise WireVersionError("INVALID_WIRE_HEADER", f"header length {length} is out of range")
+
+    flags, reserved = data[6], data[7]
+    typed = bool(flags & WIRE_FLAG_TYPED)
+    if version > WIRE_VERSION_LATEST:
+        if not allow_future:
+            raise WireVersionError(
+                "UNSUPPORTED_WIRE_VERSION",
+                f"version {version} is newer than this harness supports (latest {WIRE_VERSION_LATEST})",
+                version=version,
+            )
+        header = WireHeader(version, True, length, typed, assumed_version=WIRE_VERSION_LATEST)
+        return header, data[length:]
+
+    if flags & ~WIRE_FLAG_TYPED or reserved or length != WIRE_HEADER_V2_LENGTH:
+        raise WireVersionError(
+            "INVALID_WIRE_HEADER",
+            f"version 2 header has unknown flags 0x{flags:02x}, "
+            f"reserved byte 0x{reserved:02x} or length {length}",
+        )
+    return WireHeader(version, True, length, typed), data[length:]
+
+
+def convert_json_to_msgpack(
+    input_path: Path, output_path: Path | None, wire_version: int = WIRE_VERSION_1
+) -> Path:
     """
     Reads a JSON file and writes its content as a MessagePack file.
 
@@ -18,6 +103,7 @@ def convert_json_to_msgpack(input_path: Path, output_path: Path | None) -> Path:
         input_path: The path to the source JSON file.
         output_path: The path to the destination MessagePack file. If None,
                      it defaults to the input path with a .msgpack extension.
+        wire_version: 1 for bare msgpack, 2 to prefix a versioned header.
 
     Returns:
         The path to the created MessagePack file.
@@ -30,6 +116,10 @@ def convert_json_to_msgpack(input_path: Path, output_path: Path | None) -> Path:
 
     data = json.loads(input_path.read_text("utf-8"))
     packed_data = msgpack.packb(data)
+    if wire_version == WIRE_VERSION_2:
+        packed_data = frame_wire_payload(packed_data)
+    elif wire_version != WIRE_VERSION_1:
+        raise ValueError(f"unsupported wire version: {wire_version}")
     output_path.write_bytes(packed_data)
     return output_path
 
@@ -38,6 +128,8 @@ def convert_msgpack_to_json(input_path: Path, output_path: Path | None) -> Path:
     """
     Reads a MessagePack file and writes its content as a JSON file.
 
+    Any wire header is detected and stripped; see parse_wire_header.
+
     Args:
         input_path: The path to the source MessagePack file.
         output_path: The path to the destination JSON file. If None,
@@ -52,7 +144,8 @@ def convert_msgpack_to_json(input_path: Path, output_path: Path | None) -> Path:
     if output_path is None:
         output_path = input_path.with_suffix(".json")
 
-    unpacked_data = msgpack.unpackb(input_path.read_bytes())
+    _, payload = parse_wire_header(input_path.read_bytes())
+    unpacked_data = msgpack.unpackb(payload)
     output_path.write_text(json.dumps(unpacked_data, indent=2), "utf-8")
     return output_path
 
@@ -0,0 +1,75 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""Tests for the --stats reports in tofusoup.common.command_stats."""
+
+import json
+from pathlib import Path
+import sys
+import time
+
+import pytest
+
+from tofusoup.common.command_stats import collect_command_stats, split_command_stats, write_command_stats
+
+# soup-go's report for `soup-go cty convert - -`, with its output before it
+GO_STDOUT = (
+    '{"a":1}{"stats":{"harness":"soup-go","language":"go","command":"cty convert","success":true,'
+    '"wall_ms":2.265,"user_cpu_ms":2.911,"sys_cpu_ms":2.911,"max_rss_bytes":17448960,'
+    '"gc":{"collections":0,"pause_total_ms":0,"heap_alloc_bytes":1151688,'
+    '"total_alloc_bytes":1151688,"mallocs":5159}}}\n'
+)
+SHARED_KEYS = {
+    "harness",
+    "language",
+    "command",
+    "success",
+    "wall_ms",
+    "user_cpu_ms",
+    "sys_cpu_ms",
+    "max_rss_bytes",
+    "gc",
+}
+
+
+def test_collect_matches_go_shape() -> None:
+    _, go_stats = split_command_stats(GO_STDOUT)
+    stats = collect_command_stats("cty convert", True, time.perf_counter() - 0.05)
+
+    assert go_stats is not None
+    assert set(stats) == set(go_stats) == SHARED_KEYS
+    assert stats["command"] == go_stats["command"]
+    assert (stats["harness"], stats["language"]) == ("soup", "python")
+    assert stats["wall_ms"] >= 50
+    assert "collections" in stats["gc"]
+
+
+@pytest.mark.skipif(sys.platform == "win32", reason="no getrusage on windows")
+def test_collect_reports_rusage() -> None:
+    stats = collect_command_stats("hcl view", False, time.perf_counter())
+    assert stats["success"] is False
+    assert stats["user_cpu_ms"] > 0
+    # Any running interpreter is well over a megabyte, so the units are bytes
+    assert stats["max_rss_bytes"] > 1024 * 1024
+
+
+def test_split_without_trailing_newline_in_output() -> None:
+    output, stats = split_command_stats(GO_STDOUT)
+    assert output == '{"a":1}'
+    assert stats is not None
+    assert stats["max_rss_bytes"] == 17448960
+
+
+def test_split_without_stats() -> None:
+    assert split_command_stats("plain output\n") == ("plain output\n", None)
+
+
+def test_write_to_file(tmp_path: Path) -> None:
+    stats_file = tmp_path / "stats.json"
+    write_command_stats({"command": "wire encode"}, stats_file)
+    assert json.loads(stats_file.read_text()) == {"stats": {"command": "wire encode"}}
+
+
+# 🥣🔬🔚
@@ -0,0 +1,80 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""Tests for harness diff's --float-tolerance in tofusoup.harness.tolerance."""
+
+import json
+
+import pytest
+
+from tofusoup.common.pretty_diff import CHANGE, json_diff
+from tofusoup.harness.tolerance import FloatTolerance, apply_float_tolerance, compare_line
+
+TOLERANCE = FloatTolerance(abs=1e-9, rel=1e-6)
+
+
+def test_parse_tolerance() -> None:
+    assert FloatTolerance.parse("abs=1e-9,rel=1e-6") == TOLERANCE
+    assert FloatTolerance.parse(" rel=0.01 ") == FloatTolerance(rel=0.01)
+    assert str(TOLERANCE) == "abs=1e-09,rel=1e-06"
+
+
+@pytest.mark.parametrize("spec", ["", "abs", "tol=1", "abs=x", "abs=-1", "rel=inf", "abs=1,abs=2"])
+def test_parse_tolerance_rejects_bad_specs(spec: str) -> None:
+    with pytest.raises(ValueError):
+        FloatTolerance.parse(spec)
+
+
+def test_compare_line_decides_each_differing_float() -> None:
+    decisions = compare_line("x = 0.3, y = 2.5\n", "x = 0.30000000000000004, y = 2.6\n", TOLERANCE, "line 1")
+
+    assert [(d.left, d.right, d.accepted) for d in decisions] == [
+        ("0.3", "0.30000000000000004", True),
+        ("2.5", "2.6", False),
+    ]
+    assert decisions[1].abs_diff == pytest.approx(0.1)
+    assert str(decisions[0]).startswith("≈ line 1: 0.3 vs 0.30000000000000004")
+
+
+def test_compare_line_needs_same_text_and_a_float() -> None:
+    assert compare_line("a = 1.0\n", "b = 1.0\n", TOLERANCE, "line 1") is None
+    assert compare_line("count = 3\n", "count = 4\n", TOLERANCE, "line 1") is None
+    assert compare_line("v1.2.3\n", "v1.2.4\n", TOLERANCE, "line 1") is None
+    assert [d.accepted for d in compare_line("n = 1\n", "n = 1.0000000001\n", TOLERANCE, "l")] == [True]
+
+
+def test_apply_tolerance_replaces_accepted_lines_only() -> None:
+    left = "a 1e-07\nb 0.5\nc 1.0\nsame\n"
+    right = "a 1.0000000000000001e-07\nb 0.6\nc 1.0000000001\nsame\n"
+
+    adjusted, decisions = apply_float_tolerance(left, right, TOLERANCE)
+
+    assert adjusted == "a 1e-07\nb 0.6\nc 1.0\nsame\n"
+    assert [(d.location, d.accepted) for d in decisions] == [
+        ("line 1", True),
+        ("line 2", False),
+        ("line 3", True),
+    ]
+
+
+def test_apply_tolerance_leaves_unpaired_lines_alone() -> None:
+    adjusted, decisions = apply_float_tolerance("x 0.1\n", "x 0.1000000001\nextra\n", TOLERANCE)
+
+    assert adjusted == "x 0.1000000001\nextra\n"
+    assert decisions == []
+
+
+def test_json_diff_with_tolerance() -> None:
+    left = json.loads('{"a": 0.1, "b": 2, "c": true, "d": 1.5}')
+    right = json.loads('{"a": 0.10000000000000002, "b": 3, "c": 1.0, "d": 1.6}')
+
+    assert json_diff(left, right, close=TOLERANCE.close) == [
+        (CHANGE, "$.b", 2, 3),
+        (CHANGE, "$.c", True, 1.0),
+        (CHANGE, "$.d", 1.5, 1.6),
+    ]
+
+
+# 🥣🔬🔚
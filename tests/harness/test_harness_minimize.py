@@ -0,0 +1,86 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""Tests for divergence case minimization in tofusoup.harness.minimize."""
+
+import json
+
+import msgpack
+import pytest
+
+from tofusoup.harness.minimize import MinimizeError, hcl_units, kind_for_suffix, minimize
+
+HCL = """variable "region" {
+  default = "us-east-1"
+}
+
+resource "aws_instance" "web" {
+  tags = [
+    "one",
+    "needle-two",
+  ]
+  user_data = <<EOT
+echo {
+EOT
+  meta = { owner = "ops" }
+}
+"""
+
+
+def test_kind_for_suffix() -> None:
+    assert kind_for_suffix(".json") == "json"
+    assert kind_for_suffix(".TF") == "hcl"
+    assert kind_for_suffix(".msgpack") == "wire"
+    assert kind_for_suffix(".txt") is None
+
+
+def test_json_case_keeps_only_what_diverges() -> None:
+    case = {"a": {"x": 1, "y": [1, 2, 3, {"bad": "needle-and-more"}], "z": "hello"}, "b": [5, 6, 7]}
+    result = minimize(json.dumps(case).encode(), "json", lambda data: b"needle" in data)
+
+    assert json.loads(result.data) == {"a": {"y": [{"bad": "needle-"}]}}
+    assert result.reductions[0] == "delete $.b"
+    assert result.original_size == len(json.dumps(case))
+    assert not result.exhausted
+
+
+def test_wire_case_is_reduced_as_a_tree() -> None:
+    case = msgpack.packb({"keep": b"needle", "drop": list(range(20))}, use_bin_type=True)
+    result = minimize(case, "wire", lambda data: b"needle" in data)
+
+    assert msgpack.unpackb(result.data) == {"keep": b"needle"}
+
+
+def test_hcl_units_balance_brackets_and_heredocs() -> None:
+    units = hcl_units(HCL)
+
+    assert (0, 3) in units  # variable block
+    assert (4, 14) in units  # resource block
+    assert (5, 9) in units  # tags list
+    assert (9, 12) in units  # heredoc attribute with its body
+    assert (10, 11) not in units  # heredoc body line
+
+
+def test_hcl_case_deletes_whole_units() -> None:
+    result = minimize(HCL.encode(), "hcl", lambda data: b"needle" in data)
+
+    words = result.data.decode().split()
+    assert words == ["resource", '""', '""', "{", "tags", "=", "[", '"needle-two",', "]", "}"]
+
+
+def test_case_must_diverge() -> None:
+    with pytest.raises(MinimizeError, match="doesn't diverge"):
+        minimize(b"{}", "json", lambda data: False)
+
+
+def test_check_budget_stops_minimizing() -> None:
+    case = json.dumps({str(i): i for i in range(50)}).encode()
+    result = minimize(case, "json", lambda data: True, max_checks=5)
+
+    assert result.exhausted
+    assert result.checks == 5
+
+
+# 🥣🔬🔚
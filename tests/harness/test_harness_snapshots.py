@@ -0,0 +1,89 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""Tests for golden output snapshots in tofusoup.harness.snapshots."""
+
+from pathlib import Path
+
+import pytest
+
+from tofusoup.common.exceptions import TofuSoupError
+from tofusoup.harness.snapshots import (
+    FORMAT_JSON,
+    FORMAT_TEXT,
+    Snapshot,
+    SnapshotSpec,
+    load_snapshot_specs,
+    matches,
+    snapshot_path,
+    summarize_change,
+)
+
+SPEC = SnapshotSpec(name="convert", command="soup-go hcl convert main.hcl -", normalize=["sort-keys"])
+
+
+def test_specs_come_from_soup_toml_and_file(tmp_path: Path) -> None:
+    snapshots_file = tmp_path / "snapshots.toml"
+    snapshots_file.write_text(
+        '[snapshots.view]\ncommand = "soup-go hcl view main.hcl"\nnormalize = "sort-keys, float-format"\n'
+        'stdin = "in.hcl"\nstream = "combined"\n'
+    )
+    convert = {"command": SPEC.command, "normalize": ["sort-keys"]}
+    soup_config = {"harness": {"snapshots": {"convert": convert}}}
+
+    specs = load_snapshot_specs(soup_config, Path("/project"), snapshots_file)
+
+    assert specs["convert"] == SPEC
+    assert specs["view"].normalize == ["sort-keys", "float-format"]
+    assert specs["view"].stdin == tmp_path / "in.hcl"
+    assert specs["view"].stream == "combined"
+
+
+@pytest.mark.parametrize(
+    ("table", "message"),
+    [({}, "needs a 'command'"), ({"command": "x", "stream": "stdlog"}, "unknown stream")],
+)
+def test_invalid_specs_are_rejected(table: dict, message: str) -> None:
+    with pytest.raises(TofuSoupError, match=message):
+        load_snapshot_specs({"harness": {"snapshots": {"bad": table}}}, Path("."))
+
+
+def test_json_output_is_stored_as_json(tmp_path: Path) -> None:
+    snapshot = Snapshot.from_output(SPEC, '{"a": 1}\n', 0)
+    path = snapshot_path(tmp_path, "convert")
+    snapshot.save(path)
+
+    loaded = Snapshot.load(path)
+    assert loaded.output_format == FORMAT_JSON
+    assert loaded.output == {"a": 1}
+    assert matches(loaded, Snapshot.from_output(SPEC, '{\n  "a": 1\n}', 0))
+
+
+def test_text_output_is_stored_as_text() -> None:
+    snapshot = Snapshot.from_output(SPEC, "line one\nline two\n", 0)
+
+    assert snapshot.output_format == FORMAT_TEXT
+    assert snapshot.text() == "line one\nline two\n"
+
+
+def test_exit_code_is_part_of_the_snapshot() -> None:
+    assert not matches(Snapshot.from_output(SPEC, "{}", 0), Snapshot.from_output(SPEC, "{}", 1))
+
+
+def test_summarize_change() -> None:
+    stored = Snapshot.from_output(SPEC, '{"a": 1, "b": 2}', 0)
+
+    assert summarize_change(None, stored) == "new snapshot"
+    assert summarize_change(stored, stored) == "unchanged"
+    current = Snapshot.from_output(SPEC, '{"a": 3, "c": 4}', 1)
+    assert summarize_change(stored, current) == (
+        "exit code 0 → 1; 3 differences: 1 changed, 1 added, 1 removed"
+    )
+    text = Snapshot.from_output(SPEC, "one\ntwo\n", 0)
+    edited = Snapshot.from_output(SPEC, "one\nthree\nfour\n", 0)
+    assert summarize_change(text, edited) == "2 lines added, 1 removed"
+
+
+# 🥣🔬🔚
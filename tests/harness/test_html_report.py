@@ -0,0 +1,54 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+
+from tofusoup.harness.html_report import render_html_report, split_case_id
+from tofusoup.harness.results_db import CaseHistoryEntry, CaseResult, RunReport
+
+
+def test_split_case_id() -> None:
+    """Verify parametrized IDs split into grid rows and columns."""
+    assert split_case_id("conformance/rpc/souptest_x.py::T::test_kv[go-ec_256]") == (
+        "souptest_x.py::T::test_kv",
+        "go-ec_256",
+    )
+    assert split_case_id("terraform:1.5.7,tofu:1.6.2") == ("terraform:1.5.7,tofu:1.6.2", "result")
+
+
+def test_render_html_report() -> None:
+    """Verify the report is self-contained and covers grid, diffs and trends."""
+    report = RunReport(
+        kind="conformance",
+        source="cty-report.json",
+        cases=[
+            CaseResult("a.py::test_x[go]", "passed", 0.2),
+            CaseResult(
+                "a.py::test_x[py]",
+                "failed",
+                0.5,
+                message="assert 'a' == 'b'",
+                detail="E   AssertionError\nE     - b\nE     + a",
+            ),
+        ],
+    )
+
+    def history(case_id: str) -> list[CaseHistoryEntry]:
+        return [
+            CaseHistoryEntry(run, f"2025-01-0{run}T00:00:00", None, None, case_id, "passed", 0.1 * run)
+            for run in (1, 2, 3)
+        ]
+
+    page = render_html_report([report], history)
+
+    assert page.startswith("<!DOCTYPE html>")
+    assert "<script" not in page and 'src="http' not in page
+    assert 'class="grid"' in page
+    assert '<span class="del">E     - b</span>' in page
+    assert '<span class="add">E     + a</span>' in page
+    assert "&#x27;a&#x27; == &#x27;b&#x27;" in page
+    assert page.count("<polyline") == 2
+
+
+# 🥣🔬🔚
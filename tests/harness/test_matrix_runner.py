@@ -0,0 +1,196 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+
+import json
+from pathlib import Path
+
+import pytest
+
+from tofusoup.common.exceptions import TofuSoupError
+from tofusoup.harness.matrix_runner import (
+    HANDSHAKE,
+    HARNESS_SIDES,
+    ContainerRunner,
+    HarnessProfile,
+    LocalRunner,
+    PairResult,
+    load_profiles,
+    parse_image_map,
+    write_results,
+)
+from tofusoup.harness.results_db import load_report
+
+
+def test_parse_image_map() -> None:
+    """Verify image maps are split per side, with images keeping their tags."""
+    images = parse_image_map("go=ghcr.io/org/soup-go:1.2, python=localhost:5000/tofusoup@sha256:ab")
+    assert images == {"go": "ghcr.io/org/soup-go:1.2", "python": "localhost:5000/tofusoup@sha256:ab"}
+    assert parse_image_map("") == {}
+
+
+@pytest.mark.parametrize(
+    ("spec", "message"),
+    [
+        ("go", "expected SIDE=IMAGE"),
+        ("go=", "expected SIDE=IMAGE"),
+        ("rust=soup-rs:1", "Unknown harness side 'rust'"),
+        ("go=a,go=b", "mapped twice"),
+    ],
+)
+def test_parse_image_map_rejects(spec: str, message: str) -> None:
+    """Verify malformed image maps are rejected before anything runs."""
+    with pytest.raises(TofuSoupError, match=message):
+        parse_image_map(spec)
+
+
+def test_container_client_joins_server_network(tmp_path: Path) -> None:
+    """Verify the client runs in its own image inside the server's network namespace."""
+    runner = ContainerRunner("podman", {"go": "soup-go:1", "python": "tofusoup:1"})
+
+    server = runner.server(HARNESS_SIDES["go"], 50051, tmp_path, "srv")
+    assert server[:5] == ["podman", "run", "--rm", "--name", "srv"]
+    assert f"{tmp_path.resolve()}:/artifacts" in server
+    assert "KV_STORAGE_DIR=/artifacts/kv-storage" in server
+    assert server[-8:] == ["soup-go", "soup-go:1", "rpc", "kv", "server", "--standalone", "--port", "50051"]
+
+    args = HARNESS_SIDES["python"].client_args("get", "k", None, "127.0.0.1:50051")
+    client = runner.client(HARNESS_SIDES["python"], args, "srv")
+    assert client[:5] == ["podman", "run", "--rm", "--network", "container:srv"]
+    assert client[5:8] == ["--entrypoint", "soup", "tofusoup:1"]
+    assert client[8:] == ["rpc", "kv", "get", "--address", "127.0.0.1:50051", "k"]
+
+
+def test_local_runner_uses_command_prefixes(tmp_path: Path) -> None:
+    """Verify local sides run from their command prefix with storage in the pair directory."""
+    runner = LocalRunner({"python": ["python3", "-m", "tofusoup.cli"]})
+
+    server = runner.server(HARNESS_SIDES["python"], 0, tmp_path, "srv")
+    assert server == ["python3", "-m", "tofusoup.cli", "rpc", "kv", "server", "--transport", "tcp"]
+    assert runner.server_env(tmp_path)["KV_STORAGE_DIR"] == str(tmp_path / "kv-storage")
+
+
+def test_handshake_address() -> None:
+    """Verify the server address is taken from a go-plugin TCP handshake line."""
+    match = HANDSHAKE.match("1|1|tcp|127.0.0.1:41234|grpc|")
+    assert match is not None and match.group(1) == "127.0.0.1:41234"
+    assert HANDSHAKE.match("1|1|unix|/tmp/plugin123|grpc|") is None
+
+
+def test_results_are_recorded_as_rpc_matrix(tmp_path: Path) -> None:
+    """Verify results.json loads as an RPC matrix report, one case per pair."""
+    runner = ContainerRunner("docker", {"go": "soup-go:1", "python": "tofusoup:1"})
+    results = [
+        PairResult("go-client->python-server", "passed", 1.5, "go-to-python/client.log"),
+        PairResult("python-client->go-server", "failed", 2.0, "python-to-go/client.log", error="timed out"),
+    ]
+
+    path = write_results(results, runner, ("go", "python"), 1700000000.0, 3.5, tmp_path)
+
+    report = load_report(path)
+    assert report.kind == "rpc-matrix"
+    assert [(c.case_id, c.outcome) for c in report.cases] == [
+        ("go-client->python-server", "passed"),
+        ("python-client->go-server", "failed"),
+    ]
+
+
+
+def test_load_profiles_from_config_and_file(tmp_path: Path) -> None:
+    """Verify profiles come from soup.toml, replaced side by side by a --profiles file."""
+    config = {
+        "harness": {
+            "matrix": {
+                "profiles": {
+                    "go": {"env": {"GODEBUG": "http2debug=1"}},
+                    "python": {"wrapper": "python3 -X dev"},
+                }
+            }
+        }
+    }
+    profiles_file = tmp_path / "profiles.toml"
+    profiles_file.write_text('[profiles.python]\nwrapper = ["uv", "run"]\ncwd = "py"\n')
+
+    profiles = load_profiles(config, Path("/repo"), profiles_file)
+
+    assert profiles["go"] == HarnessProfile(env={"GODEBUG": "http2debug=1"})
+    assert profiles["python"] == HarnessProfile(cwd="py", wrapper=["uv", "run"])
+    assert profiles["python"].local_cwd() == str(tmp_path / "py")
+    assert load_profiles(config, Path("/repo"))["python"].wrapper == ["python3", "-X", "dev"]
+
+
+@pytest.mark.parametrize(
+    ("table", "message"),
+    [
+        ({"rust": {}}, "unknown harness side 'rust'"),
+        ({"go": {"wrapper": 1}}, "wrapper must be a string or a list"),
+        ({"go": {"env": {"N": 1}}}, "env must be a table of strings"),
+        ({"go": {"command": "x"}}, "unknown key"),
+    ],
+)
+def test_load_profiles_rejects(table: dict, message: str) -> None:
+    """Verify malformed profiles are rejected before anything runs."""
+    with pytest.raises(TofuSoupError, match=message):
+        load_profiles({"harness": {"matrix": {"profiles": table}}}, Path("."))
+
+
+def test_local_runner_applies_profiles(tmp_path: Path, monkeypatch: pytest.MonkeyPatch) -> None:
+    """Verify a local side runs behind its wrapper, with its env and directory."""
+    monkeypatch.setenv("SOUP_TEST_HOME", "/opt/py")
+    profile = HarnessProfile(
+        env={"PATH": "$SOUP_TEST_HOME/bin"}, cwd="work", wrapper=["uv", "run"], base=tmp_path
+    )
+    runner = LocalRunner({"python": ["python3", "-m", "tofusoup.cli"], "go": ["soup-go"]}, {"python": profile})
+
+    server = runner.server(HARNESS_SIDES["python"], 0, tmp_path, "srv")
+    assert server[:5] == ["uv", "run", "python3", "-m", "tofusoup.cli"]
+    assert runner.describe("python") == "uv run python3 -m tofusoup.cli"
+    assert runner.side_env("python") == {"PATH": "/opt/py/bin"}
+    assert runner.side_cwd("python") == str(tmp_path / "work")
+    assert runner.client(HARNESS_SIDES["go"], ["rpc"], "srv") == ["soup-go", "rpc"]
+    assert runner.side_env("go") == {} and runner.side_cwd("go") is None
+
+
+def test_container_runner_applies_profiles(tmp_path: Path) -> None:
+    """Verify a container side's wrapper becomes its entrypoint, with its env and directory."""
+    profile = HarnessProfile(
+        env={"NODE_OPTIONS": "--experimental-vm-modules"}, cwd="/app", wrapper=["node", "--x"]
+    )
+    runner = ContainerRunner("docker", {"go": "soup-go:1", "python": "tofusoup:1"}, {"python": profile})
+
+    client = runner.client(HARNESS_SIDES["python"], ["rpc"], "srv")
+    assert client[5:] == [
+        "-e",
+        "NODE_OPTIONS=--experimental-vm-modules",
+        "-w",
+        "/app",
+        "--entrypoint",
+        "node",
+        "tofusoup:1",
+        "--x",
+        "soup",
+        "rpc",
+    ]
+    server = runner.server(HARNESS_SIDES["python"], 50051, tmp_path, "srv")
+    assert "NODE_OPTIONS=--experimental-vm-modules" in server
+    assert "KV_STORAGE_DIR=/artifacts/kv-storage" in server
+
+    with pytest.raises(TofuSoupError, match="cwd must be absolute"):
+        ContainerRunner("docker", {}, {"go": HarnessProfile(cwd="rel")})
+
+
+def test_results_record_profiles_without_env_values(tmp_path: Path) -> None:
+    """Verify results.json names a profile's env variables but not their values."""
+    profile = HarnessProfile(env={"TOKEN": "secret"}, wrapper=["uv", "run"])
+    runner = LocalRunner({"python": ["soup"], "go": ["soup-go"]}, {"python": profile})
+
+    path = write_results([], runner, ("go", "python"), 1700000000.0, 0.1, tmp_path)
+
+    document = json.loads(path.read_text())
+    assert document["profiles"] == {"python": {"env": ["TOKEN"], "cwd": None, "wrapper": ["uv", "run"]}}
+    assert "secret" not in path.read_text()
+
+
+# 🥣🔬🔚
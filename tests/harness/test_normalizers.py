@@ -0,0 +1,64 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+
+from pathlib import Path
+
+import pytest
+
+from tofusoup.common.exceptions import TofuSoupError
+from tofusoup.harness.normalizers import (
+    build_pipeline,
+    float_format,
+    load_normalizers,
+    normalize,
+    sort_keys,
+    strip_handshake,
+    strip_timestamps,
+)
+
+
+def test_sort_keys_documents_and_json_lines() -> None:
+    """Verify JSON documents and JSON lines compare equal regardless of key order."""
+    assert sort_keys('{"b": 1, "a": [2]}\n') == sort_keys('{"a":[2],"b":1}\n')
+    assert sort_keys('{"b":1,"a":2}\n{"d":3,"c":4}') == '{\n  "a": 2,\n  "b": 1\n}\n{\n  "c": 4,\n  "d": 3\n}'
+    assert sort_keys("not json {") == "not json {"
+
+
+def test_float_format() -> None:
+    """Verify float spellings from different languages converge."""
+    assert float_format("x=1.0 y=2.50 z=1e+21") == "x=1 y=2.5 z=1e+21"
+    assert float_format("0.30000000000000004") == "0.3"
+    # Version numbers and identifiers are not floats
+    assert float_format("v1.2.3 abc1.5") == "v1.2.3 abc1.5"
+
+
+def test_strip_timestamps_and_handshake() -> None:
+    """Verify timestamps and go-plugin handshake lines are removed."""
+    assert strip_timestamps("at 2025-01-02T03:04:05.123Z ok") == "at <timestamp> ok"
+    assert strip_timestamps("12:34:56.789 [INFO] x") == "<timestamp> [INFO] x"
+    text = "1|6|tcp|127.0.0.1:4000|grpc|AAAA\nvalue\n"
+    assert strip_handshake(text) == "value\n"
+
+
+def test_custom_normalizers(tmp_path: Path) -> None:
+    """Verify regex normalizers load from soup.toml and a normalizers file."""
+    config_file = tmp_path / "normalizers.toml"
+    config_file.write_text('[normalizers.strip-pids]\npattern = "pid=\\\\d+"\nreplacement = "pid=<pid>"\n')
+    soup_config = {"harness": {"normalizers": {"lower": {"function": "str.lower"}}}}
+
+    with pytest.raises(TofuSoupError, match="module:attribute"):
+        load_normalizers(soup_config, config_file)
+
+    soup_config["harness"]["normalizers"]["lower"] = {"pattern": "X", "replacement": "x"}
+    available = load_normalizers(soup_config, config_file)
+    pipeline = build_pipeline(["strip-pids", "lower"], available)
+    assert normalize("X pid=1234", pipeline) == "x pid=<pid>"
+
+    with pytest.raises(TofuSoupError, match="Unknown normalizer"):
+        build_pipeline(["nope"], available)
+
+
+# 🥣🔬🔚
@@ -0,0 +1,81 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""Tests for the --pretty diff rendering in tofusoup.common.pretty_diff."""
+
+import json
+
+from tofusoup.common.pretty_diff import (
+    ADD,
+    CHANGE,
+    REMOVE,
+    json_diff,
+    render_json_diff,
+    render_pretty_diff,
+    summarize,
+    truncate,
+)
+
+
+def test_json_diff_reports_paths_in_order() -> None:
+    left = {"a": 1, "b": {"c": [1, 2, 3], "d": "x"}, "gone": True}
+    right = {"a": 2, "b": {"c": [1, 2], "d": "x"}, "new": {"k": 1}}
+
+    assert json_diff(left, right) == [
+        (CHANGE, "$.a", 1, 2),
+        (REMOVE, "$.b.c[2]", 3, None),
+        (REMOVE, "$.gone", True, None),
+        (ADD, "$.new", None, {"k": 1}),
+    ]
+
+
+def test_json_diff_tells_bools_from_numbers() -> None:
+    assert json_diff([True, 1, 1], [1, 1.0, 1]) == [(CHANGE, "$[0]", True, 1)]
+
+
+def test_json_diff_type_change_is_one_change() -> None:
+    assert json_diff({"a": [1, 2]}, {"a": {"b": 1}}) == [(CHANGE, "$.a", [1, 2], {"b": 1})]
+
+
+def test_truncate_and_summarize() -> None:
+    assert truncate("abcdef", 4) == "abcd… (+2 chars)"
+    assert truncate("abc", 4) == "abc"
+    assert summarize("x" * 100, 10) == json.dumps("x" * 10 + "… (+90 chars)", ensure_ascii=False)
+    assert summarize(list(range(120))) == "[…] list of 120 items"
+    assert summarize({"k": 1}) == "{…} map of 1 key"
+    assert summarize(None) == "null"
+
+
+def test_render_json_diff_colors_and_counts() -> None:
+    changes = [(CHANGE, "$.a", "old", "new"), (ADD, "$.b", None, [1, 2]), (REMOVE, "$.c", 3, None)]
+    lines = render_json_diff(changes)
+
+    assert lines == [
+        '[yellow]~ $.a: "old" → "new"[/yellow]',
+        "[green]+ $.b: […] list of 2 items[/green]",
+        "[red]- $.c: 3[/red]",
+        "[bold]3 differences:[/bold] [yellow]1 changed[/yellow], [green]1 added[/green], [red]1 removed[/red]",
+    ]
+
+
+def test_pretty_diff_falls_back_to_unified_for_text() -> None:
+    lines = render_pretty_diff("same\nold\n", "same\nnew\n", "go", "py")
+
+    assert lines[:2] == ["[bold]--- go[/bold]", "[bold]+++ py[/bold]"]
+    assert "[red]-old[/red]" in lines
+    assert "[green]+new[/green]" in lines
+    assert "[dim] same[/dim]" in lines
+
+
+def test_pretty_diff_shows_formatting_only_differences() -> None:
+    lines = render_pretty_diff('{"a": 1}', '{"a":1}', "go", "py")
+    assert '[green]+{"a":1}[/green]' in lines
+
+
+def test_pretty_diff_empty_when_equal() -> None:
+    assert render_pretty_diff('{"a": 1}\n', '{"a": 1}\n', "go", "py") == []
+
+
+# 🥣🔬🔚
@@ -0,0 +1,118 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+
+import copy
+import json
+from pathlib import Path
+
+import pytest
+
+from tofusoup.common.exceptions import TofuSoupError
+from tofusoup.harness.results_db import (
+    case_history,
+    connect,
+    load_report,
+    parse_report,
+    record_run,
+    summarize_history,
+)
+
+PYTEST_REPORT = {
+    "created": 1700000000.0,
+    "duration": 1.5,
+    "exitcode": 1,
+    "tests": [
+        {
+            "nodeid": "conformance/cty/souptest_a.py::test_ok",
+            "outcome": "passed",
+            "setup": {"duration": 0.1, "outcome": "passed"},
+            "call": {"duration": 0.2, "outcome": "passed"},
+        },
+        {
+            "nodeid": "conformance/cty/souptest_a.py::test_bad",
+            "outcome": "failed",
+            "call": {"duration": 0.4, "outcome": "failed", "crash": {"message": "assert 1 == 2"}},
+        },
+    ],
+}
+
+
+def test_parse_pytest_json_report() -> None:
+    """Verify pytest-json-report documents are parsed case by case."""
+    report = parse_report(PYTEST_REPORT, "cty-report.json")
+
+    assert report.kind == "conformance"
+    assert [c.outcome for c in report.cases] == ["passed", "failed"]
+    assert report.cases[0].duration_seconds == pytest.approx(0.3)
+    assert report.cases[1].message == "assert 1 == 2"
+
+
+def test_parse_matrix_results() -> None:
+    """Verify stir matrix and RPC matrix runner results are both understood."""
+    stir = parse_report(
+        {"results": [{"combination": {"tools": {"tofu": "1.6.2", "terraform": "1.5.7"}}, "success": True}]},
+        "stir.json",
+    )
+    assert stir.cases[0].case_id == "terraform:1.5.7,tofu:1.6.2"
+    assert stir.cases[0].outcome == "passed"
+
+    rpc = parse_report(
+        {
+            "kind": "rpc-matrix",
+            "results": [{"node_id": "x::y[go]", "status": "timeout", "log_file": "/tmp/y.log"}],
+        },
+        "results.json",
+    )
+    assert rpc.kind == "rpc-matrix"
+    assert rpc.cases[0].outcome == "timeout"
+
+
+def test_parse_unknown_format() -> None:
+    """Verify unrecognised documents are rejected rather than recorded empty."""
+    with pytest.raises(TofuSoupError, match="Unrecognised report format"):
+        parse_report({"summary": {}}, "nope.json")
+
+
+def test_record_and_query_history(tmp_path: Path) -> None:
+    """Verify runs accumulate and history reports trends oldest first."""
+    report_path = tmp_path / "report.json"
+    db_path = tmp_path / "results.db"
+
+    conn = connect(db_path)
+    try:
+        for duration in (0.4, 0.5, 1.0):
+            document = copy.deepcopy(PYTEST_REPORT)
+            document["tests"][0]["call"]["duration"] = duration
+            report_path.write_text(json.dumps(document))
+            record_run(conn, load_report(report_path), label="ci")
+
+        run_row = conn.execute("SELECT passed, failed, label FROM runs WHERE id = 1").fetchone()
+        assert tuple(run_row) == (1, 1, "ci")
+
+        entries = case_history(conn, "*::test_ok")
+        assert [e.run_id for e in entries] == [1, 2, 3]
+        assert case_history(conn, "*::test_ok", limit=2)[0].run_id == 2
+    finally:
+        conn.close()
+
+    summary = summarize_history(entries)
+    assert summary["runs"] == 3
+    assert summary["pass_rate"] == 1.0
+    assert summary["duration_seconds"]["latest_vs_median"] == pytest.approx(1.1 / 0.55)
+
+
+def test_connect_rejects_newer_schema(tmp_path: Path) -> None:
+    """Verify a database written by a newer schema isn't silently modified."""
+    db_path = tmp_path / "results.db"
+    conn = connect(db_path)
+    conn.execute("PRAGMA user_version = 99")
+    conn.close()
+
+    with pytest.raises(TofuSoupError, match="newer than the supported"):
+        connect(db_path)
+
+
+# 🥣🔬🔚
@@ -0,0 +1,78 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""Tests for KV entry garbage collection in tofusoup.rpc.compaction."""
+
+import os
+from pathlib import Path
+
+from tofusoup.rpc.compaction import compact_storage
+
+NOW = 1_800_000_000.0
+
+
+def _entry(directory: Path, key: str, value: bytes, age: float) -> Path:
+    path = directory / f"kv-data-{key}"
+    path.write_bytes(value)
+    os.utime(path, (NOW - age, NOW - age))
+    return path
+
+
+def test_removes_only_expired_entries(tmp_path: Path) -> None:
+    _entry(tmp_path, "old", b"12345", 120)
+    live = _entry(tmp_path, "new", b"abc", 30)
+
+    report = compact_storage(tmp_path, 60, now=NOW)
+
+    assert report == {
+        "scanned_keys": 2,
+        "removed_keys": ["old"],
+        "reclaimed_bytes": 5,
+        "live_keys": 1,
+        "live_bytes": 3,
+        "evicted_keys": 0,
+        "evicted_bytes": 0,
+        "dry_run": False,
+    }
+    assert [p.name for p in tmp_path.iterdir()] == [live.name]
+
+
+def test_removes_expired_metadata(tmp_path: Path) -> None:
+    _entry(tmp_path, "old", b"12345", 120)
+    (tmp_path / "kv-meta-old").write_text('{"size":5,"sha256":""}')
+
+    compact_storage(tmp_path, 60, now=NOW)
+
+    assert list(tmp_path.iterdir()) == []
+
+
+def test_dry_run_keeps_files(tmp_path: Path) -> None:
+    old = _entry(tmp_path, "old", b"12345", 120)
+
+    report = compact_storage(tmp_path, 60, dry_run=True, now=NOW)
+
+    assert report["removed_keys"] == ["old"]
+    assert report["dry_run"] is True
+    assert old.exists()
+
+
+def test_no_ttl_never_expires(tmp_path: Path) -> None:
+    _entry(tmp_path, "ancient", b"x", 10**6)
+
+    report = compact_storage(tmp_path, 0, now=NOW)
+
+    assert report["removed_keys"] == []
+    assert (report["live_keys"], report["live_bytes"]) == (1, 1)
+
+
+def test_ignores_other_files_and_missing_dir(tmp_path: Path) -> None:
+    (tmp_path / "notes.txt").write_text("not a key")
+    (tmp_path / "kv-data-dir").mkdir()
+
+    assert compact_storage(tmp_path, 60, now=NOW)["scanned_keys"] == 0
+    assert compact_storage(tmp_path / "missing", 60)["scanned_keys"] == 0
+
+
+# 🥣🔬🔚
@@ -0,0 +1,105 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""Tests for KVError details in tofusoup.rpc.errors."""
+
+from google.rpc import status_pb2
+import grpc
+import pytest
+
+from tofusoup.harness.proto.kv import kv_pb2
+from tofusoup.rpc.errors import (
+    KV_ERROR_STATUS_CODES,
+    STATUS_DETAILS_TRAILER,
+    abort_kv_error,
+    kv_error_code,
+    kv_error_name,
+    status_details,
+)
+
+
+class _Context:
+    """Records what a handler sets on its grpc.ServicerContext."""
+
+    def __init__(self, trailers: tuple = ()) -> None:
+        self.trailers = trailers
+        self.code = None
+        self.details = None
+
+    def trailing_metadata(self) -> tuple:
+        return self.trailers
+
+    def set_trailing_metadata(self, trailers: tuple) -> None:
+        self.trailers = trailers
+
+    def set_code(self, code: grpc.StatusCode) -> None:
+        self.code = code
+
+    def set_details(self, details: str) -> None:
+        self.details = details
+
+
+class _RpcError(grpc.RpcError):
+    """A client-side error with a status code and trailers."""
+
+    def __init__(self, code: grpc.StatusCode, trailers: tuple = ()) -> None:
+        self._code = code
+        self._trailers = trailers
+
+    def code(self) -> grpc.StatusCode:
+        return self._code
+
+    def trailing_metadata(self) -> tuple:
+        return self._trailers
+
+
+def test_every_code_maps_to_a_status_code() -> None:
+    codes = [value.number for value in kv_pb2.KVErrorCode.DESCRIPTOR.values if value.number]
+    assert sorted(KV_ERROR_STATUS_CODES) == sorted(codes)
+
+
+def test_status_details_carries_kv_error() -> None:
+    status = status_pb2.Status.FromString(status_details(kv_pb2.TOO_LARGE, b"big", "too large"))
+
+    assert status.code == grpc.StatusCode.INVALID_ARGUMENT.value[0]
+    assert status.message == "too large"
+    kv_error = kv_pb2.KVError()
+    assert status.details[0].Unpack(kv_error)
+    assert kv_error.code == kv_pb2.TOO_LARGE
+    assert kv_error.key == "big"
+
+
+def test_abort_keeps_existing_trailers() -> None:
+    context = _Context(trailers=(("kv-received-deadline-ms", "none"),))
+    abort_kv_error(context, kv_pb2.NOT_FOUND, b"missing", "Key not found: missing")
+
+    assert context.code == grpc.StatusCode.NOT_FOUND
+    assert context.details == "Key not found: missing"
+    assert context.trailers[0] == ("kv-received-deadline-ms", "none")
+    assert context.trailers[1][0] == STATUS_DETAILS_TRAILER
+
+
+def test_kv_error_code_reads_detail() -> None:
+    trailers = ((STATUS_DETAILS_TRAILER, status_details(kv_pb2.INVALID_KEY, "a/b", "invalid key")),)
+    error = _RpcError(grpc.StatusCode.INVALID_ARGUMENT, trailers)
+
+    assert kv_error_code(error) == kv_pb2.INVALID_KEY
+    assert kv_error_name(error) == "INVALID_KEY"
+
+
+@pytest.mark.parametrize(
+    ("status_code", "expected"),
+    [
+        (grpc.StatusCode.NOT_FOUND, "NOT_FOUND"),
+        (grpc.StatusCode.FAILED_PRECONDITION, "READONLY"),
+        (grpc.StatusCode.INVALID_ARGUMENT, None),
+        (grpc.StatusCode.INTERNAL, None),
+    ],
+)
+def test_kv_error_code_falls_back_to_status_code(status_code: grpc.StatusCode, expected: str | None) -> None:
+    assert kv_error_name(_RpcError(status_code)) == expected
+
+
+# 🥣🔬🔚
@@ -0,0 +1,106 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""Tests for per-key size accounting in tofusoup.rpc.stats."""
+
+import hashlib
+from pathlib import Path
+
+from tofusoup.rpc.stats import (
+    STAT_FAILED,
+    STAT_OK,
+    STAT_TOO_LARGE,
+    KeyStats,
+    stat_storage,
+    value_too_large_message,
+)
+
+
+class _Message:
+    """Stands in for a protobuf message of a given serialized size."""
+
+    def __init__(self, size: int) -> None:
+        self.size = size
+
+    def ByteSize(self) -> int:
+        return self.size
+
+
+def test_record_counts_calls_and_bytes() -> None:
+    stats = KeyStats()
+    stats.record("Put", "a", _Message(13), _Message(0), STAT_OK)
+    stats.record("Get", "a", _Message(3), _Message(7), STAT_OK)
+    stats.record("Get", "a", _Message(3), None, STAT_FAILED)
+    stats.record("Put", "big", _Message(18), None, STAT_TOO_LARGE)
+
+    assert stats.snapshot() == {
+        "a": {"key": "a", "puts": 1, "gets": 2, "rejected_puts": 0, "request_bytes": 19, "response_bytes": 7},
+        "big": {
+            "key": "big",
+            "puts": 1,
+            "gets": 0,
+            "rejected_puts": 1,
+            "request_bytes": 18,
+            "response_bytes": 0,
+        },
+    }
+
+
+def test_failed_calls_send_no_response_bytes() -> None:
+    stats = KeyStats()
+    stats.record("Get", "a", _Message(3), _Message(50), STAT_FAILED)
+    assert stats.snapshot()["a"]["response_bytes"] == 0
+
+
+def test_stat_joins_counters_with_stored_values(tmp_path: Path) -> None:
+    (tmp_path / "kv-data-a").write_bytes(b"12345")
+    (tmp_path / "kv-data-b").write_bytes(b"xy")
+    (tmp_path / "kv-meta-a").write_text("{}")
+    stats = KeyStats()
+    stats.record("Put", "a", _Message(13), _Message(0), STAT_OK)
+    stats.record("Put", "big", _Message(18), None, STAT_TOO_LARGE)
+
+    report = stat_storage(tmp_path, stats.snapshot(), max_value_bytes=10)
+
+    assert [stat["key"] for stat in report["keys"]] == ["a", "b", "big"]
+    a, b, big = report["keys"]
+    assert a == {
+        "key": "a",
+        "stored": True,
+        "size": 5,
+        "sha256": hashlib.sha256(b"12345").hexdigest(),
+        "puts": 1,
+        "gets": 0,
+        "rejected_puts": 0,
+        "request_bytes": 13,
+        "response_bytes": 0,
+    }
+    assert (b["stored"], b["puts"]) == (True, 0)
+    assert (big["stored"], big["size"], big["sha256"], big["rejected_puts"]) == (False, 0, "", 1)
+    assert (report["max_value_bytes"], report["stored_bytes"], report["request_bytes"]) == (10, 7, 31)
+
+
+def test_stat_prefix_keeps_totals_for_all_keys(tmp_path: Path) -> None:
+    (tmp_path / "kv-data-a").write_bytes(b"12345")
+    (tmp_path / "kv-data-b").write_bytes(b"xy")
+    stats = KeyStats()
+    stats.record("Get", "a", _Message(3), _Message(7), STAT_OK)
+
+    report = stat_storage(tmp_path, stats.snapshot(), prefix="b")
+
+    assert [stat["key"] for stat in report["keys"]] == ["b"]
+    assert (report["prefix"], report["stored_bytes"], report["response_bytes"]) == ("b", 7, 7)
+
+
+def test_stat_missing_directory(tmp_path: Path) -> None:
+    report = stat_storage(tmp_path / "absent", {})
+    assert (report["keys"], report["stored_bytes"]) == ([], 0)
+
+
+def test_value_too_large_message_matches_go() -> None:
+    assert value_too_large_message(11, 10) == "invalid value: value is 11 bytes, more than the 10 byte limit"
+
+
+# 🥣🔬🔚
@@ -0,0 +1,136 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""Tests for the KV storage layout and checker in tofusoup.rpc.storage."""
+
+import hashlib
+import json
+from pathlib import Path
+import subprocess
+import sys
+
+import pytest
+
+from tofusoup.rpc.storage import (
+    CRASH_EXIT_CODE,
+    CrashPoint,
+    crash_at,
+    fsck_storage,
+    remove_entry,
+    write_entry_metadata,
+)
+
+
+def _put(directory: Path, key: str, value: bytes) -> None:
+    write_entry_metadata(directory, key, value)
+    (directory / f"kv-data-{key}").write_bytes(value)
+
+
+def _kinds(report: dict) -> dict[str, str]:
+    return {issue["key"]: issue["kind"] for issue in report["issues"]}
+
+
+def test_metadata_records_size_and_digest(tmp_path: Path) -> None:
+    write_entry_metadata(tmp_path, "k", b"hello")
+
+    meta = json.loads((tmp_path / "kv-meta-k").read_text())
+    assert meta == {"size": 5, "sha256": hashlib.sha256(b"hello").hexdigest()}
+    assert not (tmp_path / "kv-tmp-k").exists()
+
+
+def test_clean_store(tmp_path: Path) -> None:
+    _put(tmp_path, "a", b"one")
+    _put(tmp_path, "b", b"")
+
+    report = fsck_storage(tmp_path)
+
+    assert report["clean"] is True
+    assert (report["scanned_keys"], report["ok_keys"], report["issues"]) == (2, 2, [])
+
+
+def test_finds_crash_damage(tmp_path: Path) -> None:
+    _put(tmp_path, "trunc", b"a full value")
+    (tmp_path / "kv-data-trunc").write_bytes(b"a fu")
+    _put(tmp_path, "flipped", b"abc")
+    (tmp_path / "kv-data-flipped").write_bytes(b"abd")
+    (tmp_path / "kv-data-locked").write_bytes(b"")
+    (tmp_path / "kv-data-legacy").write_bytes(b"old")
+    (tmp_path / "kv-meta-gone").write_text('{"size":1,"sha256":"00"}')
+    (tmp_path / "kv-tmp-half").write_text('{"si')
+
+    report = fsck_storage(tmp_path)
+
+    assert report["clean"] is False
+    assert _kinds(report) == {
+        "trunc": "truncated_value",
+        "flipped": "digest_mismatch",
+        "locked": "orphaned_lock",
+        "legacy": "missing_metadata",
+        "gone": "orphaned_metadata",
+        "half": "orphaned_temp",
+    }
+    assert [issue["key"] for issue in report["issues"]] == sorted(_kinds(report))
+    assert len(list(tmp_path.iterdir())) == 8, "checking must not change anything"
+
+
+def test_repair_leaves_a_clean_store(tmp_path: Path) -> None:
+    _put(tmp_path, "trunc", b"a full value")
+    (tmp_path / "kv-data-trunc").write_bytes(b"a fu")
+    (tmp_path / "kv-data-legacy").write_bytes(b"old")
+    _put(tmp_path, "garbled", b"v")
+    (tmp_path / "kv-meta-garbled").write_text("not json")
+
+    report = fsck_storage(tmp_path, repair=True)
+
+    assert report["clean"] is True
+    assert all(issue["repaired"] for issue in report["issues"])
+    assert not (tmp_path / "kv-data-trunc").exists()
+    assert not (tmp_path / "kv-meta-trunc").exists()
+    assert fsck_storage(tmp_path)["ok_keys"] == 2
+
+
+def test_remove_entry_removes_both_files(tmp_path: Path) -> None:
+    _put(tmp_path, "k", b"v")
+    remove_entry(tmp_path, "k")
+    remove_entry(tmp_path, "missing")
+    assert list(tmp_path.iterdir()) == []
+
+
+
+def test_crash_point_parsing() -> None:
+    assert CrashPoint.parse("") is None
+    assert CrashPoint.parse("flush").point == "flush"
+    hook = CrashPoint.parse("put:3")
+    assert (hook.point, hook.puts) == ("put", 3)
+    for bad in ("put:0", "put:x", "explode"):
+        with pytest.raises(ValueError):
+            CrashPoint(bad)
+
+
+def test_put_crash_counts_puts() -> None:
+    hook = CrashPoint("put:2")
+    assert not hook.reached("flush")
+    assert not hook.reached("put")
+    assert hook.reached("put")
+    assert not CrashPoint("flush").reached("put")
+    crash_at(None, "put", "k")
+
+
+def test_crash_exits_without_cleanup(tmp_path: Path) -> None:
+    marker = tmp_path / "cleanup-ran"
+    script = (
+        "import atexit, pathlib\n"
+        "from tofusoup.rpc.storage import CrashPoint, crash_at\n"
+        f"atexit.register(pathlib.Path({str(marker)!r}).touch)\n"
+        "crash_at(CrashPoint('lock-acquired'), 'lock-acquired', 'k')\n"
+    )
+    result = subprocess.run([sys.executable, "-c", script], capture_output=True, text=True)
+
+    assert result.returncode == CRASH_EXIT_CODE
+    assert "injected crash after lock-acquired" in result.stderr
+    assert not marker.exists()
+
+
+# 🥣🔬🔚
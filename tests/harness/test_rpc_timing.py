@@ -0,0 +1,53 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""Tests for the --timing latency summaries in tofusoup.rpc.timing."""
+
+import json
+
+import pytest
+
+from tofusoup.rpc.timing import CallTimings, sparse_histogram, summarize_latencies
+
+
+def test_summarize_latencies_uses_index_percentiles() -> None:
+    samples = [float(ms) for ms in range(1, 101)]
+    summary = summarize_latencies(samples)
+    assert summary["min"] == 1.0
+    assert summary["max"] == 100.0
+    assert summary["avg"] == 50.5
+    assert summary["p50"] == 50.0
+    assert summary["p95"] == 95.0
+    assert summary["p99"] == 99.0
+
+
+def test_summarize_latencies_empty() -> None:
+    assert summarize_latencies([])["p99"] == 0.0
+
+
+def test_sparse_histogram_skips_empty_buckets() -> None:
+    assert sparse_histogram([0.05, 0.1, 0.3, 20000.0]) == [
+        {"le_ms": 0.1, "count": 2},
+        {"le_ms": 0.5, "count": 1},
+        {"le_ms": "+Inf", "count": 1},
+    ]
+
+
+def test_call_timings_counts_errors_per_method() -> None:
+    timings = CallTimings()
+    with timings.measure("/proto.KV/Put"):
+        pass
+    with pytest.raises(RuntimeError), timings.measure("/proto.KV/Get"):
+        raise RuntimeError("boom")
+
+    report = json.loads(timings.to_json())["timing"]
+    assert report["calls"] == 2
+    assert report["errors"] == 1
+    assert report["methods"]["/proto.KV/Get"]["errors"] == 1
+    assert report["methods"]["/proto.KV/Put"]["calls"] == 1
+    assert sum(bucket["count"] for bucket in report["histogram"]) == 2
+
+
+# 🥣🔬🔚
@@ -0,0 +1,107 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""Tests for the trust bundles in tofusoup.rpc.trust."""
+
+from datetime import UTC, datetime, timedelta
+import json
+from pathlib import Path
+
+import pytest
+
+from tofusoup.rpc.trust import (
+    TrustBundleError,
+    import_trust_bundle,
+    make_trust_bundle,
+    read_trust_bundle,
+    trust_bundle_path,
+    write_trust_bundle,
+)
+
+# A self-signed P-384 certificate, and its fingerprint as soup-go reports it
+CA_PEM = """-----BEGIN CERTIFICATE-----
+MIIBsDCCATagAwIBAgIUV0bTxZN+WUA8D6hjPEEx9YTSSpUwCgYIKoZIzj0EAwIw
+DzENMAsGA1UEAwwEdGVzdDAeFw0yNjEwMTYwMzQyMjBaFw0yNjEwMTgwMzQyMjBa
+MA8xDTALBgNVBAMMBHRlc3QwdjAQBgcqhkjOPQIBBgUrgQQAIgNiAARyUwPoL9nn
+y48c9lGkBSqIwdYxb9k4ynJu6YTXhbrEhBUSP58ZahEETAMQ8JgXRRuvqIwErdef
+Y/X+QkOUVCC/glN7lu5XhhReIWPB1qEolYQupxSZMkm4xbkGWhkr9MujUzBRMB0G
+A1UdDgQWBBREcKXimgHFPapfDvWL5Mp4DtMYdzAfBgNVHSMEGDAWgBREcKXimgHF
+PapfDvWL5Mp4DtMYdzAPBgNVHRMBAf8EBTADAQH/MAoGCCqGSM49BAMCA2gAMGUC
+MQCTVY2V1gcKFm4ymuuoGV/wlKimBN0x8bpnL90wB8tSI58PlrjyKw5v7cT7cUjt
+4iYCMHNt3CbP+6Nc3T+X/XpWt+BU8H93LAya1GBxzdTKGR0dklSHdCxDf+Dx4b9E
+kNSG9g==
+-----END CERTIFICATE-----
+"""
+CA_FINGERPRINT = "c80e2d69089b898d76faef8a384f6c60f728129977628f3cd0621c9cd6cc5c9c"
+CA_FINGERPRINT_SHA512 = (
+    "3734916008ae88d946fe1a4aad4e067699f6e43ee8e1a26f694685be2863ff89"
+    "2dec8dc5a9bca8a00e6fc0db9efd4cfc07472daefcea858d62ec5bd68d0095d9"
+)
+
+
+def test_bundle_round_trip(tmp_path: Path) -> None:
+    path = write_trust_bundle(tmp_path, make_trust_bundle("srv", CA_PEM))
+    assert path == trust_bundle_path(tmp_path, "srv")
+
+    bundle = read_trust_bundle(tmp_path, "srv")
+    assert bundle["fingerprint_sha256"] == CA_FINGERPRINT
+    assert bundle["ca_pem"] == CA_PEM
+
+    env = import_trust_bundle(tmp_path, "srv", tmp_path / "out" / "ca.pem")
+    assert Path(env["GRPC_DEFAULT_SSL_ROOTS_FILE_PATH"]).read_text() == CA_PEM
+
+
+def test_reads_bundles_written_by_soup_go(tmp_path: Path) -> None:
+    """soup-go's bundle carries extra metadata fields, which are ignored."""
+    go_bundle = {
+        "version": 1,
+        "name": "go-server",
+        "ca_pem": CA_PEM,
+        "fingerprint_sha256": CA_FINGERPRINT,
+        "subject": "CN=test",
+        "not_after": (datetime.now(UTC) + timedelta(days=1)).isoformat().replace("+00:00", "Z"),
+        "curve": "secp384r1",
+        "host": "server-host",
+        "exported_by": "soup-go 0.0.0",
+    }
+    trust_bundle_path(tmp_path, "go-server").write_text(json.dumps(go_bundle))
+    assert read_trust_bundle(tmp_path, "go-server")["curve"] == "secp384r1"
+
+
+def test_checks_the_exporters_digest_algorithm(tmp_path: Path) -> None:
+    """soup-go --digest-alg records a second fingerprint; blake3 isn't in hashlib."""
+    bundle = make_trust_bundle("srv", CA_PEM)
+    assert (bundle["fingerprint"], bundle["fingerprint_algorithm"]) == (CA_FINGERPRINT, "sha256")
+
+    bundle.update(fingerprint=CA_FINGERPRINT_SHA512, fingerprint_algorithm="sha512")
+    write_trust_bundle(tmp_path, bundle)
+    assert read_trust_bundle(tmp_path, "srv")["fingerprint_algorithm"] == "sha512"
+
+    bundle.update(fingerprint="0" * 128)
+    write_trust_bundle(tmp_path, bundle)
+    with pytest.raises(TrustBundleError, match="sha512 fingerprint mismatch"):
+        read_trust_bundle(tmp_path, "srv")
+
+    bundle.update(fingerprint="0" * 64, fingerprint_algorithm="blake3")
+    write_trust_bundle(tmp_path, bundle)
+    assert read_trust_bundle(tmp_path, "srv")["fingerprint_algorithm"] == "blake3"
+
+
+def test_rejects_tampered_expired_and_missing_bundles(tmp_path: Path) -> None:
+    bundle = make_trust_bundle("srv", CA_PEM)
+    bundle["fingerprint_sha256"] = "0" * 64
+    write_trust_bundle(tmp_path, bundle)
+    with pytest.raises(TrustBundleError, match="fingerprint mismatch"):
+        read_trust_bundle(tmp_path, "srv")
+
+    write_trust_bundle(tmp_path, make_trust_bundle("old", CA_PEM, not_after=datetime(2020, 1, 1, tzinfo=UTC)))
+    with pytest.raises(TrustBundleError, match="expired"):
+        read_trust_bundle(tmp_path, "old")
+
+    with pytest.raises(TrustBundleError, match="no trust bundle"):
+        read_trust_bundle(tmp_path, "missing")
+
+
+# 🥣🔬🔚
@@ -0,0 +1,143 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""Tests for harness diff's set matching in tofusoup.harness.set_match."""
+
+import pytest
+
+from tofusoup.common.pretty_diff import ADD, CHANGE, REMOVE, json_diff
+from tofusoup.harness.set_match import (
+    BEST_EFFORT,
+    DIGEST,
+    POSITIONAL,
+    SetMatcher,
+    compile_pattern,
+    envelope_set_paths,
+    mismatch,
+)
+
+UNKNOWN = {"unknown": "string"}
+SECRET = "(sensitive value sha256:0123456789abcdef)"
+
+LEFT = {
+    "ingress": [
+        {"port": 80, "cidr": "10.0.0.0/8"},
+        {"port": 443, "cidr": "0.0.0.0/0"},
+        {"port": 22, "cidr": UNKNOWN},
+    ]
+}
+# The same set in another order, with the unknown resolved on the right
+RIGHT = {
+    "ingress": [
+        {"port": 443, "cidr": "0.0.0.0/0"},
+        {"port": 22, "cidr": "192.168.0.0/16"},
+        {"port": 80, "cidr": "10.0.0.0/8"},
+    ]
+}
+
+
+def test_positional_matches_by_index() -> None:
+    matcher = SetMatcher(POSITIONAL, ["$.ingress"])
+    changes = json_diff(LEFT, RIGHT, match_lists=matcher)
+
+    assert len(changes) == 6
+    [match] = matcher.matches
+    assert (match.identical, match.paired, match.removed, match.added) == (0, 3, 0, 0)
+    assert match.unknowns is True
+
+
+def test_digest_pairs_equal_elements_wherever_they_are() -> None:
+    matcher = SetMatcher(DIGEST, ["$.ingress"])
+    changes = json_diff(LEFT, RIGHT, match_lists=matcher)
+
+    assert changes == [
+        (REMOVE, "$.ingress[2]", {"port": 22, "cidr": UNKNOWN}, None),
+        (ADD, "$.ingress[1]", None, {"port": 22, "cidr": "192.168.0.0/16"}),
+    ]
+    [match] = matcher.matches
+    assert (match.identical, match.paired, match.removed, match.added) == (2, 0, 1, 1)
+    assert str(match) == "$.ingress (digest): 2 identical, 0 paired, 1 removed, 1 added; holds unknowns"
+
+
+def test_best_effort_pairs_unknowns_with_the_closest_element() -> None:
+    matcher = SetMatcher(BEST_EFFORT, ["$.ingress"])
+    changes = json_diff(LEFT, RIGHT, match_lists=matcher)
+
+    # The unknown is reported as resolved, not as an element removed and
+    # another added
+    assert changes == [(CHANGE, "$.ingress[2~1].cidr", UNKNOWN, "192.168.0.0/16")]
+    [match] = matcher.matches
+    assert (match.identical, match.paired, match.removed, match.added) == (2, 1, 0, 0)
+
+
+def test_best_effort_leaves_unrelated_elements_unpaired() -> None:
+    matcher = SetMatcher(BEST_EFFORT, ["$.tags"])
+    changes = json_diff({"tags": ["a", "b"]}, {"tags": ["c", "a"]}, match_lists=matcher)
+
+    assert changes == [(REMOVE, "$.tags[1]", "b", None), (ADD, "$.tags[0]", None, "c")]
+
+
+def test_marked_elements_match_by_digest() -> None:
+    matcher = SetMatcher(DIGEST, ["$"])
+    assert json_diff([SECRET, "x"], ["x", SECRET], match_lists=matcher) == []
+    assert matcher.matches[0].marked is True
+
+
+def test_only_lists_at_set_paths_are_matched() -> None:
+    matcher = SetMatcher(DIGEST, ["$.sets[*].members"])
+    left = {"sets": [{"members": [1, 2]}], "order": [1, 2]}
+    right = {"sets": [{"members": [2, 1]}], "order": [2, 1]}
+
+    changes = json_diff(left, right, match_lists=matcher)
+
+    assert [path for _, path, _, _ in changes] == ["$.order[0]", "$.order[1]"]
+    assert [match.path for match in matcher.matches] == ["$.sets[0].members"]
+
+
+def test_compile_pattern() -> None:
+    pattern = compile_pattern("$.resources[*].values.*.rules")
+
+    assert pattern.match("$.resources[3].values.web.rules")
+    assert pattern.match("$.resources[0~2].values.web.rules")
+    assert not pattern.match("$.resources[3].values.web.rules[0]")
+    assert not pattern.match("$.resources.values.web.rules")
+
+
+def test_envelope_set_paths_follow_the_type() -> None:
+    envelope = {
+        "value": {},
+        "type": [
+            "object",
+            {
+                "names": ["set", "string"],
+                "rules": ["list", ["object", {"ports": ["set", "number"]}]],
+                "nested": ["map", ["set", ["set", "string"]]],
+                "plain": ["list", "string"],
+            },
+        ],
+    }
+
+    assert envelope_set_paths(envelope) == [
+        "$.value.names",
+        "$.value.rules[*].ports",
+        "$.value.nested.*",
+        "$.value.nested.*[*]",
+    ]
+    assert envelope_set_paths({"value": [], "type": ["set", "string"], "extra": 1}) == []
+
+
+def test_mismatch_treats_unknowns_as_wildcards() -> None:
+    assert mismatch({"a": UNKNOWN, "b": 1}, {"a": "x", "b": 1}) == 0
+    assert mismatch({"a": 1, "b": 2}, {"a": 1, "c": 2}) == 2
+    assert mismatch([1, 2, 3], [1]) == 2
+    assert mismatch(True, 1) == 1
+
+
+def test_unknown_strategy_is_rejected() -> None:
+    with pytest.raises(ValueError, match="unknown set matching strategy"):
+        SetMatcher("fuzzy")
+
+
+# 🥣🔬🔚
@@ -0,0 +1,78 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""Tests for the unicode conformance probe helpers in tofusoup.common.unicode_probe."""
+
+import pytest
+
+from tofusoup.common.unicode_probe import (
+    UNICODE_PROBE_SUITE,
+    ascii_escape,
+    decode_probe_input,
+    hcl_escaped_source,
+    hcl_raw_source,
+    measure_unicode,
+    run_unicode_probe,
+    select_unicode_cases,
+)
+
+
+def test_suite_inputs_are_ascii_json_escapes() -> None:
+    names = [name for name, _, _ in UNICODE_PROBE_SUITE]
+    assert len(names) == len(set(names))
+    for _, _, text in UNICODE_PROBE_SUITE:
+        assert text.isascii()
+        assert ascii_escape(decode_probe_input(text)) == text
+
+
+def test_measures_count_each_unit() -> None:
+    family = decode_probe_input(dict((n, t) for n, _, t in UNICODE_PROBE_SUITE)["emoji-zwj-family"])
+    assert measure_unicode(family) == {
+        "utf8_bytes": 18,
+        "utf16_units": 8,
+        "code_points": 5,
+        "graphemes": None,
+        "nfc": True,
+        "nfd": True,
+        "replacement_chars": 0,
+        "lone_surrogates": 0,
+    }
+    nfd = measure_unicode(decode_probe_input("cafe\\u0301"))
+    assert (nfd["code_points"], nfd["nfc"], nfd["nfd"]) == (5, False, True)
+
+
+def test_lone_surrogates_are_kept() -> None:
+    measures = measure_unicode(decode_probe_input("x\\udc00y"))
+    assert (measures["utf8_bytes"], measures["utf16_units"], measures["lone_surrogates"]) == (None, 3, 1)
+    assert measures["replacement_chars"] == 0
+
+
+def test_hcl_escaped_source_joins_surrogate_pairs() -> None:
+    assert hcl_escaped_source("\\ud834\\udd1e!") == '"\\U0001d11e!"'
+    assert hcl_escaped_source("x\\udc00y") == '"x\\udc00y"'
+    assert hcl_escaped_source("\\ud800") == '"\\ud800"'
+    assert hcl_escaped_source("caf\\u00e9") == '"caf\\u00e9"'
+
+
+def test_hcl_raw_source_escapes_template_syntax() -> None:
+    assert hcl_raw_source('a"b\\c${d}%{e}') == '"a\\"b\\\\c$${d}%%{e}"'
+
+
+def test_select_unknown_case() -> None:
+    assert [case[0] for case in select_unicode_cases(["ascii"])] == ["ascii"]
+    with pytest.raises(ValueError, match='unknown case "nope"'):
+        select_unicode_cases(["nope"])
+
+
+def test_run_unicode_probe_report_shape() -> None:
+    report = run_unicode_probe("cty", ["nfc-e-acute"], lambda text, decoded: {"seen": text})
+    assert report["probe"] == "cty"
+    assert report["harness"] == "soup"
+    (case,) = report["cases"]
+    assert (case["name"], case["decoded"]) == ("nfc-e-acute", "caf\\u00e9")
+    assert case["cty"] == {"seen": "caf\\u00e9"}
+
+
+# 🥣🔬🔚
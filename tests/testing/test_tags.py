@@ -0,0 +1,95 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""Tests for conformance case tags in tofusoup.testing.tags."""
+
+import pathlib
+
+import pytest
+
+from tofusoup.testing.tags import (
+    case_tags,
+    parse_tag_filters,
+    skip_reason,
+    skipped_by_tags,
+    tag_pytest_args,
+)
+
+CASE = pathlib.Path("/repo/conformance/rpc/souptest_simple_matrix.py")
+
+
+def test_case_tags_default_subsystem_and_closest_marker_wins() -> None:
+    markers = [{"known_failure": "no", "severity": "critical"}, {"severity": "minor", "feature": "tls"}]
+
+    assert case_tags(CASE, markers) == {
+        "subsystem": "rpc",
+        "severity": "critical",
+        "feature": "tls",
+        "known-failure": "no",
+    }
+    assert case_tags(pathlib.Path("/repo/conformance/souptest_polyglot.py"), []) == {}
+    assert case_tags(CASE, [{"subsystem": "kv"}])["subsystem"] == "kv"
+
+
+def test_case_tags_rejects_unknown_tags() -> None:
+    with pytest.raises(ValueError, match='unknown tag "owner"'):
+        case_tags(CASE, [{"owner": "me"}])
+
+
+def test_parse_tag_filters() -> None:
+    assert parse_tag_filters("subsystem:cty, known-failure,") == [
+        ("subsystem", "cty"),
+        ("known-failure", None),
+    ]
+    assert parse_tag_filters(None) == []
+    with pytest.raises(ValueError):
+        parse_tag_filters("flavor:spicy")
+
+
+def test_skip_reason() -> None:
+    tags = {"subsystem": "rpc", "severity": "minor"}
+    known = {**tags, "known-failure": "pyvider-rpcplugin"}
+
+    assert skip_reason(tags, [], [], False) is None
+    assert skip_reason(tags, [("subsystem", "cty")], [], False) == "tags: not in --include-tags"
+    assert skip_reason(tags, [("subsystem", "cty"), ("severity", None)], [], False) is None
+    assert skip_reason(tags, [], [("severity", "minor")], False) == "tags: excluded by severity:minor"
+    assert skip_reason(known, [], [], False) == "tags: known failure: pyvider-rpcplugin"
+    assert skip_reason(known, [], [], True) is None
+    assert skip_reason(known, [], [("known-failure", None)], True) == "tags: excluded by known-failure"
+
+
+def test_tag_pytest_args() -> None:
+    assert tag_pytest_args(None, None, False) == []
+    assert tag_pytest_args("subsystem:cty", "severity:minor", True) == [
+        "--include-tags=subsystem:cty",
+        "--exclude-tags=severity:minor",
+        "--allow-known-failures",
+    ]
+    with pytest.raises(ValueError):
+        tag_pytest_args("color:red", None, False)
+
+
+def test_skipped_by_tags_reads_json_report() -> None:
+    tests = [
+        {
+            "nodeid": "conformance/rpc/souptest_a.py::test_known",
+            "outcome": "skipped",
+            "setup": {"longrepr": "('souptest_a.py', 19, \"Skipped: tags: known failure: can't dial\")"},
+        },
+        {
+            "nodeid": "conformance/rpc/souptest_a.py::test_other",
+            "outcome": "skipped",
+            "setup": {"longrepr": "('souptest_a.py', 30, 'Skipped: Go harness executable not found.')"},
+        },
+        {"nodeid": "conformance/rpc/souptest_a.py::test_ok", "outcome": "passed"},
+    ]
+
+    assert skipped_by_tags(tests) == [
+        {"nodeid": "conformance/rpc/souptest_a.py::test_known", "reason": "tags: known failure: can't dial"}
+    ]
+
+
+# 🥣🔬🔚
@@ -0,0 +1,99 @@
+#
+# SPDX-FileCopyrightText: Copyright (c) 2025 provide.io llc. All rights reserved.
+# SPDX-License-Identifier: Apache-2.0
+#
+
+"""Tests for the xfail manifest in tofusoup.testing.xfail."""
+
+import datetime
+import pathlib
+
+import pytest
+
+from tofusoup.common.exceptions import TofuSoupError
+from tofusoup.testing.xfail import (
+    XfailEntry,
+    expired_entries,
+    find_entry,
+    load_xfail_manifest,
+    unexpected_passes,
+)
+
+MANIFEST = """
+[[xfail]]
+case = "conformance/rpc/souptest_a.py::test_kv"
+reason = "go-plugin handshake"
+owner = "rpc"
+expires = 2026-12-31
+
+[[xfail]]
+case = "conformance/cty/souptest_b.py"
+reason = "marks"
+owner = "cty"
+expires = "2026-01-31"
+"""
+
+
+def _write(tmp_path: pathlib.Path, text: str) -> pathlib.Path:
+    path = tmp_path / "xfail.toml"
+    path.write_text(text)
+    return path
+
+
+def test_load_manifest(tmp_path: pathlib.Path) -> None:
+    entries = load_xfail_manifest(_write(tmp_path, MANIFEST))
+
+    assert entries == [
+        XfailEntry(
+            "conformance/rpc/souptest_a.py::test_kv", "go-plugin handshake", "rpc", datetime.date(2026, 12, 31)
+        ),
+        XfailEntry("conformance/cty/souptest_b.py", "marks", "cty", datetime.date(2026, 1, 31)),
+    ]
+    assert entries[0].describe() == "go-plugin handshake (owner rpc, expires 2026-12-31)"
+    assert load_xfail_manifest(_write(tmp_path, "# nothing yet\n")) == []
+
+
+@pytest.mark.parametrize(
+    ("text", "message"),
+    [
+        ('[[xfail]]\ncase = "x"\nreason = "y"\nexpires = 2026-01-01\n', "entry 1 is missing owner"),
+        ('[[xfail]]\ncase = "x"\nreason = "y"\nowner = "z"\nexpires = "soon"\n', "invalid expires"),
+        ("[[xfail]\n", "Failed to read xfail manifest"),
+    ],
+)
+def test_load_manifest_rejects_bad_entries(tmp_path: pathlib.Path, text: str, message: str) -> None:
+    with pytest.raises(TofuSoupError, match=message):
+        load_xfail_manifest(_write(tmp_path, text))
+
+
+def test_entry_matching() -> None:
+    entries = [
+        XfailEntry("conformance/rpc/souptest_a.py::test_kv", "r", "o", datetime.date(2026, 1, 1)),
+        XfailEntry("conformance/cty/souptest_b.py", "r", "o", datetime.date(2026, 1, 1)),
+    ]
+
+    assert find_entry(entries, "conformance/rpc/souptest_a.py::test_kv") is entries[0]
+    assert find_entry(entries, "conformance/rpc/souptest_a.py::test_kv[go-ec_256]") is entries[0]
+    assert find_entry(entries, "conformance/rpc/souptest_a.py::test_kv_other") is None
+    assert find_entry(entries, "conformance/cty/souptest_b.py::TestB::test_x") is entries[1]
+    assert find_entry(entries, "conformance/cty/souptest_bc.py::test_x") is None
+
+
+def test_expired_entries() -> None:
+    entry = XfailEntry("c", "r", "o", datetime.date(2026, 3, 1))
+
+    assert expired_entries([entry], datetime.date(2026, 3, 1)) == []
+    assert expired_entries([entry], datetime.date(2026, 3, 2)) == [entry]
+
+
+def test_unexpected_passes() -> None:
+    tests = [
+        {"nodeid": "a::test_fixed", "outcome": "xpassed"},
+        {"nodeid": "a::test_broken", "outcome": "xfailed"},
+        {"nodeid": "a::test_ok", "outcome": "passed"},
+    ]
+
+    assert unexpected_passes(tests) == ["a::test_fixed"]
+
+
+# 🥣🔬🔚